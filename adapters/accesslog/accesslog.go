@@ -0,0 +1,56 @@
+// Package accesslog writes a JSON access log with one line per proxied request.
+// It is separate from the application logger and is not affected by its level.
+package accesslog
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Entry is a single access log record.
+type Entry struct {
+	Timestamp time.Time `json:"timestamp"`
+	KeyPrefix string    `json:"key_prefix,omitempty"`
+	UserID    string    `json:"user_id,omitempty"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Status    int       `json:"status"`
+	LatencyMs int64     `json:"latency_ms"`
+	BytesIn   int64     `json:"bytes_in"`
+	BytesOut  int64     `json:"bytes_out"`
+}
+
+// Logger writes access log entries as JSON lines.
+type Logger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// New creates a logger writing to w.
+func New(w io.Writer) *Logger {
+	return &Logger{w: w}
+}
+
+// Log writes e as one JSON line.
+func (l *Logger) Log(e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.w.Write(line)
+	return err
+}
+
+// Close closes the underlying writer if it is closable.
+func (l *Logger) Close() error {
+	if c, ok := l.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
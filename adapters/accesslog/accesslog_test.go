@@ -0,0 +1,144 @@
+package accesslog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/accesslog"
+)
+
+func TestLogger_Log(t *testing.T) {
+	var buf bytes.Buffer
+	log := accesslog.New(&buf)
+
+	entry := accesslog.Entry{
+		Timestamp: time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC),
+		KeyPrefix: "ak_0123456789",
+		UserID:    "user-1",
+		Method:    "POST",
+		Path:      "/v1/orders",
+		Status:    201,
+		LatencyMs: 42,
+		BytesIn:   128,
+		BytesOut:  512,
+	}
+	if err := log.Log(entry); err != nil {
+		t.Fatalf("Log: %v", err)
+	}
+	log.Log(accesslog.Entry{Method: "GET", Path: "/health", Status: 200})
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2: %q", len(lines), buf.String())
+	}
+	var got accesslog.Entry
+	if err := json.Unmarshal([]byte(lines[0]), &got); err != nil {
+		t.Fatalf("line is not JSON: %v", err)
+	}
+	if got != entry {
+		t.Errorf("entry = %+v, want %+v", got, entry)
+	}
+	if !strings.Contains(lines[0], `"timestamp":"2024-01-15T12:00:00Z"`) || !strings.Contains(lines[0], `"latency_ms":42`) {
+		t.Errorf("unexpected field names: %s", lines[0])
+	}
+	if strings.Contains(lines[1], "key_prefix") {
+		t.Errorf("unauthenticated entry has key_prefix: %s", lines[1])
+	}
+}
+
+func TestRotatingFile_Rotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	f, err := accesslog.OpenRotatingFile(path, 100, 2)
+	if err != nil {
+		t.Fatalf("OpenRotatingFile: %v", err)
+	}
+	defer f.Close()
+
+	// 30-byte lines: three fit in 100 bytes, the fourth rotates
+	line := func(n int) []byte { return []byte(fmt.Sprintf("%-29d\n", n)) }
+	for i := 1; i <= 3; i++ {
+		f.Write(line(i))
+	}
+	if _, err := os.Stat(path + ".1"); err == nil {
+		t.Fatal("rotated before reaching the size limit")
+	}
+
+	f.Write(line(4))
+	assertLines(t, path+".1", 1, 2, 3)
+	assertLines(t, path, 4)
+
+	// Keep writing until more files have been rotated than are retained
+	for i := 5; i <= 12; i++ {
+		f.Write(line(i))
+	}
+	assertLines(t, path, 10, 11, 12)
+	assertLines(t, path+".1", 7, 8, 9)
+	assertLines(t, path+".2", 4, 5, 6)
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("backup beyond retention count was kept: %v", err)
+	}
+}
+
+func TestRotatingFile_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	os.WriteFile(path, []byte(strings.Repeat("x", 95)+"\n"), 0o640)
+	// Backups from an earlier, larger retention count
+	os.WriteFile(path+".1", []byte("old 1\n"), 0o640)
+	os.WriteFile(path+".2", []byte("old 2\n"), 0o640)
+	os.WriteFile(path+".3", []byte("old 3\n"), 0o640)
+
+	f, err := accesslog.OpenRotatingFile(path, 100, 1)
+	if err != nil {
+		t.Fatalf("OpenRotatingFile: %v", err)
+	}
+	defer f.Close()
+
+	// The existing content counts towards the limit
+	if _, err := f.Write([]byte("new line\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "new line\n" {
+		t.Errorf("current file = %q, want only the new line", data)
+	}
+	if data, _ := os.ReadFile(path + ".1"); len(data) != 96 {
+		t.Errorf("backup has %d bytes, want the 96 bytes of the previous file", len(data))
+	}
+	for _, name := range []string{".2", ".3"} {
+		if _, err := os.Stat(path + name); !os.IsNotExist(err) {
+			t.Errorf("%s was not pruned", name)
+		}
+	}
+}
+
+func TestRotatingFile_Closed(t *testing.T) {
+	f, err := accesslog.OpenRotatingFile(filepath.Join(t.TempDir(), "access.log"), 0, 0)
+	if err != nil {
+		t.Fatalf("OpenRotatingFile: %v", err)
+	}
+	f.Close()
+	if _, err := f.Write([]byte("x\n")); err == nil {
+		t.Error("Write after Close succeeded")
+	}
+}
+
+// assertLines checks that the file at path holds exactly the given numbered lines.
+func assertLines(t *testing.T, path string, want ...int) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", filepath.Base(path), err)
+	}
+	var got []string
+	for _, l := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		got = append(got, strings.TrimSpace(l))
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("%s lines = %v, want %v", filepath.Base(path), got, want)
+	}
+}
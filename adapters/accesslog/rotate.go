@@ -0,0 +1,112 @@
+package accesslog
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// RotatingFile is a file writer that rotates the file once it reaches a
+// maximum size. Rotated files are named path.1, path.2, ... with path.1 the
+// most recent; files beyond the retention count are deleted.
+type RotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// OpenRotatingFile opens path for appending, rotating it when a write would
+// take it past maxSize bytes (0 = never) and keeping maxBackups rotated files.
+func OpenRotatingFile(path string, maxSize int64, maxBackups int) (*RotatingFile, error) {
+	f := &RotatingFile{path: path, maxSize: maxSize, maxBackups: max(maxBackups, 0)}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Write appends p to the file, rotating first if p would not fit.
+// A single write is never split across files.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close closes the current file.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return fmt.Errorf("open access log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("stat access log: %w", err)
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// rotate shifts the backups up by one, moves the current file to path.1,
+// prunes backups past the retention count and starts a new file.
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("close access log: %w", err)
+	}
+	f.file = nil
+
+	for i := f.maxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(f.backup(i), f.backup(i+1)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("rotate access log: %w", err)
+		}
+	}
+	if f.maxBackups > 0 {
+		if err := os.Rename(f.path, f.backup(1)); err != nil {
+			return fmt.Errorf("rotate access log: %w", err)
+		}
+	} else if err := os.Remove(f.path); err != nil {
+		return fmt.Errorf("rotate access log: %w", err)
+	}
+
+	// Also removes backups left over from a larger retention count
+	for i := f.maxBackups + 1; ; i++ {
+		if err := os.Remove(f.backup(i)); err != nil {
+			break
+		}
+	}
+	return f.open()
+}
+
+func (f *RotatingFile) backup(n int) string {
+	return fmt.Sprintf("%s.%d", f.path, n)
+}
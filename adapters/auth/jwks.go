@@ -0,0 +1,317 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/artpar/apigate/ports"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWKS defaults.
+const (
+	DefaultJWKSUserClaim       = "sub"
+	DefaultJWKSRefreshInterval = time.Hour
+	DefaultJWKSRefetchCooldown = 30 * time.Second
+)
+
+// JWKSConfig configures validation of externally issued JWTs.
+type JWKSConfig struct {
+	URL             string        // JWKS document URL of the identity provider
+	Issuer          string        // Required "iss" claim (empty = not checked)
+	Audience        string        // Required "aud" claim (empty = not checked)
+	UserClaim       string        // Claim holding the APIGate user ID (default "sub")
+	RefreshInterval time.Duration // How long fetched keys are cached (default 1h)
+	RefetchCooldown time.Duration // Minimum time between refetches for unknown key IDs (default 30s)
+	HTTPClient      *http.Client  // Client used to fetch the JWKS (default 10s timeout)
+}
+
+// JWKSValidator validates bearer JWTs against the signing keys published in
+// a JWKS document. Keys are cached and refetched periodically; a token signed
+// with an unknown key ID triggers an early refetch so provider key rotation
+// is picked up without waiting for the cache to expire.
+// Thread-safe and suitable for concurrent use. The JWKS is fetched without
+// holding the lock, concurrent refreshes share a single fetch, and cached keys
+// keep being served while a refresh is in flight.
+type JWKSValidator struct {
+	cfg    JWKSConfig
+	parser *jwt.Parser
+
+	mu          sync.Mutex
+	keys        map[string]crypto.PublicKey // Replaced on refresh, never modified
+	fetchedAt   time.Time
+	lastRefetch time.Time  // Last refetch triggered by an unknown key ID
+	inflight    *jwksFetch // Refresh in progress, if any
+}
+
+// jwksFetch is a JWKS refresh shared by all callers that need it.
+type jwksFetch struct {
+	done chan struct{}
+	err  error
+}
+
+// NewJWKSValidator creates a validator for tokens signed by the keys at cfg.URL.
+// Keys are fetched lazily on first use.
+func NewJWKSValidator(cfg JWKSConfig) *JWKSValidator {
+	if cfg.UserClaim == "" {
+		cfg.UserClaim = DefaultJWKSUserClaim
+	}
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = DefaultJWKSRefreshInterval
+	}
+	if cfg.RefetchCooldown <= 0 {
+		cfg.RefetchCooldown = DefaultJWKSRefetchCooldown
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithExpirationRequired(),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+
+	return &JWKSValidator{
+		cfg:    cfg,
+		parser: jwt.NewParser(opts...),
+	}
+}
+
+// ValidateBearer verifies the token's signature, expiry, issuer and audience
+// and returns the user ID from the configured claim.
+func (v *JWKSValidator) ValidateBearer(ctx context.Context, tokenString string) (string, error) {
+	claims := jwt.MapClaims{}
+	_, err := v.parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return v.key(ctx, kid)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	userID, _ := claims[v.cfg.UserClaim].(string)
+	if userID == "" {
+		return "", fmt.Errorf("token has no %q claim", v.cfg.UserClaim)
+	}
+	return userID, nil
+}
+
+// key returns the public key for kid, fetching the JWKS when the cache is
+// empty or expired, or when kid is unknown and the refetch cooldown allows.
+func (v *JWKSValidator) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	keys, fetchedAt, refreshing := v.keys, v.fetchedAt, v.inflight != nil
+	v.mu.Unlock()
+
+	now := time.Now()
+	if keys == nil || now.Sub(fetchedAt) >= v.cfg.RefreshInterval {
+		// Serve the cached key rather than waiting on another caller's refresh
+		if k, ok := lookupKey(keys, kid); ok && refreshing {
+			return k, nil
+		}
+		if err := v.refresh(ctx, now); err != nil && keys == nil {
+			return nil, err
+		}
+		keys = v.cachedKeys()
+	}
+
+	if k, ok := lookupKey(keys, kid); ok {
+		return k, nil
+	}
+
+	// Unknown key ID: the provider has probably rotated its keys
+	v.mu.Lock()
+	if now.Sub(v.lastRefetch) < v.cfg.RefetchCooldown {
+		v.mu.Unlock()
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	v.lastRefetch = now
+	v.mu.Unlock()
+
+	if err := v.refresh(ctx, now); err != nil {
+		return nil, err
+	}
+	if k, ok := lookupKey(v.cachedKeys(), kid); ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// cachedKeys returns the current key set. The map is never modified after
+// it is cached, so callers may read it without holding v.mu.
+func (v *JWKSValidator) cachedKeys() map[string]crypto.PublicKey {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.keys
+}
+
+// refresh fetches the JWKS and replaces the cached keys. If a refresh is
+// already in flight, it waits for that one instead of starting another.
+// On failure the previously cached keys are kept.
+func (v *JWKSValidator) refresh(ctx context.Context, now time.Time) error {
+	v.mu.Lock()
+	if f := v.inflight; f != nil {
+		v.mu.Unlock()
+		select {
+		case <-f.done:
+			return f.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	f := &jwksFetch{done: make(chan struct{})}
+	v.inflight = f
+	v.mu.Unlock()
+
+	keys, err := v.fetch(ctx)
+
+	v.mu.Lock()
+	if err == nil {
+		v.keys = keys
+		v.fetchedAt = now
+	}
+	f.err = err
+	v.inflight = nil
+	v.mu.Unlock()
+	close(f.done)
+	return err
+}
+
+// lookupKey finds kid in keys. Tokens without a key ID are accepted only when
+// the JWKS publishes a single key.
+func lookupKey(keys map[string]crypto.PublicKey, kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(keys) == 1 {
+		for _, k := range keys {
+			return k, true
+		}
+	}
+	k, ok := keys[kid]
+	return k, ok
+}
+
+// fetch downloads and parses the JWKS document. It doesn't touch the cache
+// and must be called without holding v.mu.
+func (v *JWKSValidator) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.cfg.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := v.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch jwks: unexpected status %d", resp.StatusCode)
+	}
+
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decode jwks: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(doc.Keys))
+	for _, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			// Skip keys we can't use rather than rejecting the whole document
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+	return keys, nil
+}
+
+// jwk is a single JSON Web Key (RFC 7517). Only RSA and EC signing keys are supported.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("EC point not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// Ensure interface compliance.
+var _ ports.BearerTokenValidator = (*JWKSValidator)(nil)
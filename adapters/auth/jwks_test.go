@@ -0,0 +1,282 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/auth"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwksServer serves a JWKS document for the current set of keys and counts fetches.
+type jwksServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	keys    map[string]*rsa.PrivateKey
+	fetches int
+}
+
+func newJWKSServer(t *testing.T, keys map[string]*rsa.PrivateKey) *jwksServer {
+	t.Helper()
+	s := &jwksServer{keys: keys}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.fetches++
+		var doc struct {
+			Keys []map[string]string `json:"keys"`
+		}
+		for kid, k := range s.keys {
+			doc.Keys = append(doc.Keys, map[string]string{
+				"kty": "RSA",
+				"kid": kid,
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+			})
+		}
+		json.NewEncoder(w).Encode(doc)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) setKeys(keys map[string]*rsa.PrivateKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+}
+
+func (s *jwksServer) fetchCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetches
+}
+
+func generateRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+	return k
+}
+
+func signJWT(t *testing.T, k *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(k)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return signed
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss": "https://idp.example.com",
+		"aud": "apigate",
+		"sub": "user-1",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func TestJWKSValidator_ValidateBearer(t *testing.T) {
+	key1 := generateRSAKey(t)
+	other := generateRSAKey(t)
+	srv := newJWKSServer(t, map[string]*rsa.PrivateKey{"key-1": key1})
+	v := auth.NewJWKSValidator(auth.JWKSConfig{URL: srv.URL, Issuer: "https://idp.example.com", Audience: "apigate"})
+
+	with := func(mutate func(jwt.MapClaims)) jwt.MapClaims {
+		c := validClaims()
+		mutate(c)
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"valid", signJWT(t, key1, "key-1", validClaims()), false},
+		{"audience list", signJWT(t, key1, "key-1", with(func(c jwt.MapClaims) { c["aud"] = []string{"other", "apigate"} })), false},
+		{"no kid with single key", signJWT(t, key1, "", validClaims()), false},
+		{"wrong issuer", signJWT(t, key1, "key-1", with(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" })), true},
+		{"wrong audience", signJWT(t, key1, "key-1", with(func(c jwt.MapClaims) { c["aud"] = "other" })), true},
+		{"expired", signJWT(t, key1, "key-1", with(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() })), true},
+		{"no expiry", signJWT(t, key1, "key-1", with(func(c jwt.MapClaims) { delete(c, "exp") })), true},
+		{"missing user claim", signJWT(t, key1, "key-1", with(func(c jwt.MapClaims) { delete(c, "sub") })), true},
+		{"signed by another key", signJWT(t, other, "key-1", validClaims()), true},
+		{"malformed", "not.a.jwt", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID, err := v.ValidateBearer(context.Background(), tt.token)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ValidateBearer() = %q, want error", userID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ValidateBearer() error = %v", err)
+			}
+			if userID != "user-1" {
+				t.Errorf("userID = %q, want user-1", userID)
+			}
+		})
+	}
+
+	if n := srv.fetchCount(); n != 1 {
+		t.Errorf("JWKS fetched %d times, want 1 (keys should be cached)", n)
+	}
+}
+
+func TestJWKSValidator_RejectsHMACTokens(t *testing.T) {
+	key1 := generateRSAKey(t)
+	srv := newJWKSServer(t, map[string]*rsa.PrivateKey{"key-1": key1})
+	v := auth.NewJWKSValidator(auth.JWKSConfig{URL: srv.URL})
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims())
+	token.Header["kid"] = "key-1"
+	signed, _ := token.SignedString(key1.N.Bytes())
+	if _, err := v.ValidateBearer(context.Background(), signed); err == nil {
+		t.Error("HS256 token accepted, want rejection")
+	}
+}
+
+func TestJWKSValidator_UserClaim(t *testing.T) {
+	key1 := generateRSAKey(t)
+	srv := newJWKSServer(t, map[string]*rsa.PrivateKey{"key-1": key1})
+	v := auth.NewJWKSValidator(auth.JWKSConfig{URL: srv.URL, UserClaim: "apigate_user"})
+
+	claims := validClaims()
+	claims["apigate_user"] = "user-42"
+	userID, err := v.ValidateBearer(context.Background(), signJWT(t, key1, "key-1", claims))
+	if err != nil {
+		t.Fatalf("ValidateBearer() error = %v", err)
+	}
+	if userID != "user-42" {
+		t.Errorf("userID = %q, want user-42", userID)
+	}
+}
+
+func TestJWKSValidator_KeyRotation(t *testing.T) {
+	key1, key2 := generateRSAKey(t), generateRSAKey(t)
+	srv := newJWKSServer(t, map[string]*rsa.PrivateKey{"key-1": key1})
+	v := auth.NewJWKSValidator(auth.JWKSConfig{URL: srv.URL})
+	ctx := context.Background()
+
+	if _, err := v.ValidateBearer(ctx, signJWT(t, key1, "key-1", validClaims())); err != nil {
+		t.Fatalf("token from original key: %v", err)
+	}
+
+	// The provider rotates to a new key; the unknown kid triggers a refetch
+	srv.setKeys(map[string]*rsa.PrivateKey{"key-2": key2})
+	if _, err := v.ValidateBearer(ctx, signJWT(t, key2, "key-2", validClaims())); err != nil {
+		t.Fatalf("token from rotated key: %v", err)
+	}
+	if n := srv.fetchCount(); n != 2 {
+		t.Errorf("JWKS fetched %d times, want 2", n)
+	}
+
+	// Keys removed from the JWKS stop validating
+	if _, err := v.ValidateBearer(ctx, signJWT(t, key1, "key-1", validClaims())); err == nil {
+		t.Error("token from retired key accepted")
+	}
+
+	// Unknown kids don't refetch again within the cooldown
+	for i := 0; i < 3; i++ {
+		v.ValidateBearer(ctx, signJWT(t, key2, "key-unknown", validClaims()))
+	}
+	if n := srv.fetchCount(); n != 2 {
+		t.Errorf("JWKS fetched %d times after unknown kids, want 2 (cooldown)", n)
+	}
+}
+
+func TestJWKSValidator_FetchError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	key1 := generateRSAKey(t)
+	v := auth.NewJWKSValidator(auth.JWKSConfig{URL: srv.URL})
+	if _, err := v.ValidateBearer(context.Background(), signJWT(t, key1, "key-1", validClaims())); err == nil {
+		t.Error("token accepted without a JWKS")
+	}
+}
+
+func TestJWKSValidator_ServesCachedKeysDuringRefresh(t *testing.T) {
+	key1 := generateRSAKey(t)
+	srv := newJWKSServer(t, map[string]*rsa.PrivateKey{"key-1": key1})
+
+	// The JWKS endpoint hangs after the first fetch until released
+	started, release := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	handler := srv.Config.Handler
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if srv.fetchCount() > 0 {
+			once.Do(func() { close(started) })
+			<-release
+		}
+		handler.ServeHTTP(w, r)
+	})
+
+	v := auth.NewJWKSValidator(auth.JWKSConfig{URL: srv.URL, RefreshInterval: time.Millisecond})
+	ctx := context.Background()
+	token := signJWT(t, key1, "key-1", validClaims())
+	if _, err := v.ValidateBearer(ctx, token); err != nil {
+		t.Fatalf("initial validation: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	// The cache has expired; this caller starts a refresh that hangs
+	refreshed := make(chan error, 1)
+	go func() {
+		_, err := v.ValidateBearer(ctx, token)
+		refreshed <- err
+	}()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("refresh never reached the JWKS endpoint")
+	}
+
+	// Other callers are served from the cache instead of waiting
+	done := make(chan error, 1)
+	go func() {
+		_, err := v.ValidateBearer(ctx, token)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("validation during refresh: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("validation blocked on the in-flight refresh")
+	}
+
+	close(release)
+	if err := <-refreshed; err != nil {
+		t.Errorf("validation that refreshed: %v", err)
+	}
+	if n := srv.fetchCount(); n != 2 {
+		t.Errorf("JWKS fetched %d times, want 2", n)
+	}
+}
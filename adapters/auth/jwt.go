@@ -16,9 +16,21 @@ type Claims struct {
 	UserID string `json:"uid"`
 	Email  string `json:"email"`
 	Role   string `json:"role"` // "admin" or "user"
+
+	// ImpersonatorID is the admin acting as this user. Empty for a user's own session.
+	ImpersonatorID string `json:"imp,omitempty"`
+
+	// SessionID is the stored session backing the token, so the token can be
+	// revoked before it expires. Empty for stateless tokens.
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// IsImpersonated reports whether the token was minted for an admin acting as the user.
+func (c *Claims) IsImpersonated() bool {
+	return c.ImpersonatorID != ""
+}
+
 // TokenService provides stateless JWT token operations.
 // Thread-safe and suitable for concurrent use.
 type TokenService struct {
@@ -51,19 +63,37 @@ func NewTokenService(secret string, expiration time.Duration) *TokenService {
 
 // GenerateToken creates a new JWT token for the given user.
 func (s *TokenService) GenerateToken(userID, email, role string) (string, time.Time, error) {
+	return s.sign(Claims{UserID: userID, Email: email, Role: role}, s.expiration)
+}
+
+// GenerateSessionToken creates a JWT token for a stored session lasting ttl.
+// The token is only honoured while the session exists.
+func (s *TokenService) GenerateSessionToken(userID, email, role, sessionID string, ttl time.Duration) (string, time.Time, error) {
+	if sessionID == "" {
+		return "", time.Time{}, errors.New("session ID is required")
+	}
+	return s.sign(Claims{UserID: userID, Email: email, Role: role, SessionID: sessionID}, ttl)
+}
+
+// GenerateImpersonationToken creates a short-lived user token for an admin
+// acting as the user. The token records the admin in ImpersonatorID.
+func (s *TokenService) GenerateImpersonationToken(userID, email, adminID string, ttl time.Duration) (string, time.Time, error) {
+	if adminID == "" {
+		return "", time.Time{}, errors.New("impersonator is required")
+	}
+	return s.sign(Claims{UserID: userID, Email: email, Role: "user", ImpersonatorID: adminID}, ttl)
+}
+
+// sign fills in the registered claims and signs the token.
+func (s *TokenService) sign(claims Claims, ttl time.Duration) (string, time.Time, error) {
 	now := time.Now().UTC()
-	expiresAt := now.Add(s.expiration)
-
-	claims := Claims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			Issuer:    s.issuer,
-			Subject:   userID,
-			IssuedAt:  jwt.NewNumericDate(now),
-			ExpiresAt: jwt.NewNumericDate(expiresAt),
-		},
+	expiresAt := now.Add(ttl)
+
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		Issuer:    s.issuer,
+		Subject:   claims.UserID,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -97,11 +127,19 @@ func (s *TokenService) ValidateToken(tokenString string) (*Claims, error) {
 }
 
 // RefreshToken creates a new token with extended expiration.
+// Impersonation tokens cannot be refreshed.
 func (s *TokenService) RefreshToken(tokenString string) (string, time.Time, error) {
 	claims, err := s.ValidateToken(tokenString)
 	if err != nil {
 		return "", time.Time{}, err
 	}
+	if claims.IsImpersonated() {
+		return "", time.Time{}, errors.New("impersonation tokens cannot be refreshed")
+	}
+	if claims.SessionID != "" {
+		// Stay tied to the session so revoking it still works
+		return s.GenerateSessionToken(claims.UserID, claims.Email, claims.Role, claims.SessionID, s.expiration)
+	}
 
 	return s.GenerateToken(claims.UserID, claims.Email, claims.Role)
 }
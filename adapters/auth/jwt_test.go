@@ -171,6 +171,71 @@ func TestTokenService_RefreshToken_InvalidToken(t *testing.T) {
 	}
 }
 
+func TestTokenService_GenerateImpersonationToken(t *testing.T) {
+	svc := auth.NewTokenService("test-secret", 24*time.Hour)
+
+	token, expiresAt, err := svc.GenerateImpersonationToken("user1", "user@example.com", "admin1", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateImpersonationToken failed: %v", err)
+	}
+	if time.Until(expiresAt) > 15*time.Minute {
+		t.Errorf("expiresAt = %v, want within 15 minutes", expiresAt)
+	}
+
+	claims, err := svc.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken failed: %v", err)
+	}
+	if claims.UserID != "user1" || claims.Role != "user" {
+		t.Errorf("claims = %+v, want user1 with role user", claims)
+	}
+	if !claims.IsImpersonated() || claims.ImpersonatorID != "admin1" {
+		t.Errorf("ImpersonatorID = %q, want admin1", claims.ImpersonatorID)
+	}
+
+	// Impersonation must not be extended into a regular session
+	if _, _, err := svc.RefreshToken(token); err == nil {
+		t.Error("expected error refreshing an impersonation token")
+	}
+
+	if _, _, err := svc.GenerateImpersonationToken("user1", "user@example.com", "", time.Minute); err == nil {
+		t.Error("expected error without an impersonator")
+	}
+}
+
+func TestTokenService_GenerateSessionToken(t *testing.T) {
+	svc := auth.NewTokenService("test-secret", 24*time.Hour)
+
+	token, expiresAt, err := svc.GenerateSessionToken("user1", "user@example.com", "user", "sess_1", 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateSessionToken failed: %v", err)
+	}
+	if time.Until(expiresAt) < 29*24*time.Hour {
+		t.Errorf("expiresAt = %v, want the session TTL rather than the default", expiresAt)
+	}
+
+	claims, err := svc.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken failed: %v", err)
+	}
+	if claims.SessionID != "sess_1" || claims.UserID != "user1" {
+		t.Errorf("claims = %+v, want user1 in sess_1", claims)
+	}
+
+	// Refreshing keeps the token tied to its session
+	refreshed, _, err := svc.RefreshToken(token)
+	if err != nil {
+		t.Fatalf("RefreshToken failed: %v", err)
+	}
+	if claims, _ := svc.ValidateToken(refreshed); claims == nil || claims.SessionID != "sess_1" {
+		t.Errorf("refreshed claims = %+v, want sess_1", claims)
+	}
+
+	if _, _, err := svc.GenerateSessionToken("user1", "user@example.com", "user", "", time.Hour); err == nil {
+		t.Error("expected error without a session ID")
+	}
+}
+
 func TestGenerateSecret(t *testing.T) {
 	secret1 := auth.GenerateSecret()
 	secret2 := auth.GenerateSecret()
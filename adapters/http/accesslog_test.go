@@ -0,0 +1,46 @@
+package http_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/artpar/apigate/adapters/accesslog"
+)
+
+func TestProxy_AccessLog(t *testing.T) {
+	const rawKey = "ak_2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b"
+	handler, _ := newBurstProxy(t, rawKey)
+	var buf bytes.Buffer
+	handler.SetAccessLog(accesslog.New(&buf))
+
+	req := httptest.NewRequest("POST", "/orders", strings.NewReader(`{"qty":1}`))
+	req.Header.Set("X-API-Key", rawKey)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	req = httptest.NewRequest("GET", "/orders", nil)
+	req.Header.Set("X-API-Key", "ak_ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("access log has %d lines, want 2: %q", len(lines), buf.String())
+	}
+	var ok, denied accesslog.Entry
+	json.Unmarshal([]byte(lines[0]), &ok)
+	json.Unmarshal([]byte(lines[1]), &denied)
+
+	if ok.KeyPrefix != rawKey[:12] || ok.UserID != "user-1" || ok.Method != "POST" || ok.Path != "/orders" ||
+		ok.Status != http.StatusOK || ok.BytesIn != 9 || ok.BytesOut != 2 || ok.Timestamp.IsZero() {
+		t.Errorf("proxied entry = %+v", ok)
+	}
+	if strings.Contains(lines[0], rawKey[12:]) {
+		t.Error("access log contains the full API key")
+	}
+	if denied.Status != http.StatusUnauthorized || denied.UserID != "" || denied.KeyPrefix != "ak_fffffffff" {
+		t.Errorf("rejected entry = %+v", denied)
+	}
+}
@@ -7,13 +7,18 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/artpar/apigate/adapters/auth"
+	"github.com/artpar/apigate/domain/audit"
 	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/domain/route"
+	"github.com/artpar/apigate/pkg/idempotency"
 	"github.com/artpar/apigate/pkg/jsonapi"
 	"github.com/artpar/apigate/ports"
 	"github.com/go-chi/chi/v5"
@@ -45,21 +50,33 @@ type Handler struct {
 	routesHandler  *RoutesHandler
 	meterHandler   *MeterHandler
 	reloadCallback func(context.Context) error // Called when explicit reload is requested
+	rotationGrace  time.Duration               // Default grace period for rotated-out keys
+	audit          ports.AuditRecorder         // Records admin mutations (optional)
+	auditStore     ports.AuditStore            // Serves the audit log (optional)
+	idempotency    ports.ResponseCache         // Replays repeated Idempotency-Key requests (optional)
+	requestTester  RequestTester               // Serves POST /admin/dry-run (optional)
+	upstreamHealth UpstreamHealthReporter      // Serves GET /admin/upstream-health (optional)
 }
 
 // Deps contains dependencies for the admin handler.
 type Deps struct {
-	Users          ports.UserStore
-	Keys           ports.KeyStore
-	Usage          ports.UsageStore
-	Routes         ports.RouteStore
-	Upstreams      ports.UpstreamStore
-	Plans          ports.PlanStore
-	Logger         zerolog.Logger
-	Hasher         ports.Hasher
-	JWTSecret      string                       // Optional JWT secret for Web UI session validation
-	OnRouteChange  func()                       // Optional callback when routes/upstreams change (for cache invalidation)
-	ReloadCallback func(context.Context) error  // Optional callback for explicit reload (POST /admin/reload)
+	Users            ports.UserStore
+	Keys             ports.KeyStore
+	Usage            ports.UsageStore
+	Routes           ports.RouteStore
+	Upstreams        ports.UpstreamStore
+	Plans            ports.PlanStore
+	Logger           zerolog.Logger
+	Hasher           ports.Hasher
+	JWTSecret        string                      // Optional JWT secret for Web UI session validation
+	OnRouteChange    func()                      // Optional callback when routes/upstreams change (for cache invalidation)
+	ReloadCallback   func(context.Context) error // Optional callback for explicit reload (POST /admin/reload)
+	KeyRotationGrace time.Duration               // Default grace period for rotated-out keys (default 24h)
+	Audit            ports.AuditRecorder         // Optional: records admin mutations without blocking
+	AuditStore       ports.AuditStore            // Optional: serves GET /admin/audit
+	Idempotency      ports.ResponseCache         // Optional: replays repeated Idempotency-Key requests
+	RequestTester    RequestTester               // Optional: serves POST /admin/dry-run
+	UpstreamHealth   UpstreamHealthReporter      // Optional: serves GET /admin/upstream-health
 }
 
 // NewHandler creates a new admin API handler.
@@ -75,6 +92,15 @@ func NewHandler(deps Deps) *Handler {
 		hasher:         deps.Hasher,
 		sessions:       NewSessionStore(),
 		reloadCallback: deps.ReloadCallback,
+		rotationGrace:  deps.KeyRotationGrace,
+		audit:          deps.Audit,
+		auditStore:     deps.AuditStore,
+		idempotency:    deps.Idempotency,
+		requestTester:  deps.RequestTester,
+		upstreamHealth: deps.UpstreamHealth,
+	}
+	if h.rotationGrace <= 0 {
+		h.rotationGrace = key.DefaultRotationGrace
 	}
 
 	// Create token service for Web UI session validation (if JWT secret provided)
@@ -120,6 +146,15 @@ func (h *Handler) Router() chi.Router {
 	// Protected endpoints (require auth)
 	r.Group(func(r chi.Router) {
 		r.Use(h.AuthMiddleware)
+		if h.idempotency != nil {
+			r.Use(idempotency.Middleware(idempotency.Config{
+				Store: h.idempotency,
+				Scope: func(r *http.Request) string {
+					userID, _ := r.Context().Value(ctxUserIDKey).(string)
+					return userID
+				},
+			}))
+		}
 
 		r.Get("/me", h.Me)
 		r.Post("/logout", h.Logout)
@@ -131,11 +166,16 @@ func (h *Handler) Router() chi.Router {
 		r.Put("/users/{id}", h.UpdateUser)
 		r.Patch("/users/{id}", h.UpdateUser)
 		r.Delete("/users/{id}", h.DeleteUser)
+		r.Post("/users/{id}/restore", h.RestoreUser)
+		r.Post("/users/{id}/impersonate", h.ImpersonateUser)
 
 		// Keys
 		r.Get("/keys", h.ListKeys)
 		r.Post("/keys", h.CreateKey)
+		r.Post("/keys/import", h.ImportKeys)
+		r.Get("/keys/export", h.ExportKeys)
 		r.Delete("/keys/{id}", h.RevokeKey)
+		r.Post("/keys/{id}/rotate", h.RotateKey)
 
 		// Plans
 		r.Get("/plans", h.ListPlans)
@@ -147,6 +187,10 @@ func (h *Handler) Router() chi.Router {
 
 		// Usage
 		r.Get("/usage", h.GetUsage)
+		r.Get("/usage/export", h.ExportUsage)
+
+		// Audit log
+		r.Get("/audit", h.ListAudit)
 
 		// Doctor (system health)
 		r.Get("/doctor", h.Doctor)
@@ -154,6 +198,12 @@ func (h *Handler) Router() chi.Router {
 		// Reload (hot-reload routes, upstreams, and config)
 		r.Post("/reload", h.Reload)
 
+		// Dry-run a request through routing, auth, rate limits and quota
+		r.Post("/dry-run", h.DryRun)
+
+		// Upstream health details, including probe errors
+		r.Get("/upstream-health", h.UpstreamHealth)
+
 		// Routes and Upstreams (if configured)
 		if h.routesHandler != nil {
 			h.routesHandler.RegisterRoutes(r)
@@ -177,6 +227,14 @@ func (h *Handler) MeterRouter() chi.Router {
 	return h.meterHandler.Router()
 }
 
+// SetReservedPaths sets the paths owned by built-in endpoints. Routes created
+// or updated through the admin API may not claim them.
+func (h *Handler) SetReservedPaths(reserved route.ReservedPaths) {
+	if h.routesHandler != nil {
+		h.routesHandler.SetReservedPaths(reserved)
+	}
+}
+
 // -----------------------------------------------------------------------------
 // Authentication
 // -----------------------------------------------------------------------------
@@ -410,7 +468,7 @@ func (h *Handler) authenticateByAPIKey(ctx context.Context, apiKey string) error
 	// Verify the key matches
 	for _, k := range keys {
 		if h.hasher.Compare(k.Hash, apiKey) {
-			if k.RevokedAt != nil {
+			if key.IsRevoked(k, time.Now()) {
 				return ErrInvalidCredentials
 			}
 			return nil
@@ -597,7 +655,7 @@ func (h *Handler) AuthMiddleware(next http.Handler) http.Handler {
 		// Try Web UI JWT token cookie (enables Admin UI to make Admin API calls)
 		if h.tokens != nil {
 			if cookie, err := r.Cookie("token"); err == nil {
-				if claims, err := h.tokens.ValidateToken(cookie.Value); err == nil {
+				if claims, err := h.validateAdminToken(cookie.Value); err == nil {
 					// JWT token is valid - allow access
 					ctx := context.WithValue(r.Context(), ctxSessionKey, "jwt_token")
 					ctx = context.WithValue(ctx, ctxUserIDKey, claims.UserID)
@@ -614,7 +672,7 @@ func (h *Handler) AuthMiddleware(next http.Handler) http.Handler {
 
 			// Try JWT validation first (if token service configured)
 			if h.tokens != nil {
-				if claims, err := h.tokens.ValidateToken(token); err == nil {
+				if claims, err := h.validateAdminToken(token); err == nil {
 					ctx := context.WithValue(r.Context(), ctxSessionKey, "jwt_token")
 					ctx = context.WithValue(ctx, ctxUserIDKey, claims.UserID)
 					next.ServeHTTP(w, r.WithContext(ctx))
@@ -653,6 +711,20 @@ func (h *Handler) AuthMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// validateAdminToken validates a JWT and requires the admin role. Portal and
+// impersonation tokens are signed with the same secret but must not grant
+// access to the admin API.
+func (h *Handler) validateAdminToken(token string) (*auth.Claims, error) {
+	claims, err := h.tokens.ValidateToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Role != "admin" || claims.IsImpersonated() {
+		return nil, ErrInvalidCredentials
+	}
+	return claims, nil
+}
+
 // Context keys
 type ctxKey string
 
@@ -701,21 +773,23 @@ type UpdateUserRequest struct {
 //	@Produce		json
 //	@Param			limit	query		int					false	"Max results"	default(100)
 //	@Param			offset	query		int					false	"Offset"		default(0)
+//	@Param			include_deleted	query	bool			false	"Include soft-deleted users"
 //	@Success		200		{object}	map[string]interface{}	"Users list"
 //	@Security		AdminAuth
 //	@Router			/admin/users [get]
 func (h *Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
 	page, perPage := jsonapi.ParsePaginationParams(r.URL.Query(), 20)
 	offset := (page - 1) * perPage
+	ctx := withDeletedUsersParam(r)
 
-	users, err := h.users.List(r.Context(), perPage, offset)
+	users, err := h.users.List(ctx, perPage, offset)
 	if err != nil {
 		h.logger.Error().Err(err).Msg("failed to list users")
 		jsonapi.WriteInternalError(w, "Failed to list users")
 		return
 	}
 
-	total, _ := h.users.Count(r.Context())
+	total, _ := h.users.Count(ctx)
 
 	resources := make([]jsonapi.Resource, len(users))
 	for i, u := range users {
@@ -790,6 +864,7 @@ func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	h.logger.Info().Str("user_id", user.ID).Str("email", user.Email).Msg("user created via admin api")
+	h.recordAudit(r, audit.ActionCreate, audit.ResourceUser, user.ID, nil, audit.Snapshot(user))
 	jsonapi.WriteCreated(w, userToResource(user), "/admin/users/"+user.ID)
 }
 
@@ -799,15 +874,16 @@ func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
 //	@Description	Get user by ID
 //	@Tags			Admin - Users
 //	@Produce		json
-//	@Param			id	path		string			true	"User ID"
-//	@Success		200	{object}	UserResponse	"User data"
-//	@Failure		404	{object}	ErrorResponse	"User not found"
+//	@Param			id				path		string			true	"User ID"
+//	@Param			include_deleted	query		bool			false	"Return the user even if soft-deleted"
+//	@Success		200				{object}	UserResponse	"User data"
+//	@Failure		404				{object}	ErrorResponse	"User not found"
 //	@Security		AdminAuth
 //	@Router			/admin/users/{id} [get]
 func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
-	user, err := h.users.Get(r.Context(), id)
+	user, err := h.users.Get(withDeletedUsersParam(r), id)
 	if err != nil {
 		jsonapi.WriteNotFound(w, "user")
 		return
@@ -844,6 +920,7 @@ func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		jsonapi.WriteBadRequest(w, "Invalid JSON body")
 		return
 	}
+	before := audit.Snapshot(user)
 
 	if req.Email != "" {
 		user.Email = req.Email
@@ -875,13 +952,15 @@ func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	h.logger.Info().Str("user_id", user.ID).Msg("user updated via admin api")
+	h.recordAudit(r, audit.ActionUpdate, audit.ResourceUser, user.ID, before, audit.Snapshot(user))
 	jsonapi.WriteResource(w, http.StatusOK, userToResource(user))
 }
 
-// DeleteUser deletes a user.
+// DeleteUser soft-deletes a user. The user's keys stop working and the user
+// is hidden from listings, but can be brought back with RestoreUser.
 //
 //	@Summary		Delete user
-//	@Description	Delete user by ID
+//	@Description	Soft-delete user by ID (restorable)
 //	@Tags			Admin - Users
 //	@Produce		json
 //	@Param			id	path		string				true	"User ID"
@@ -891,37 +970,86 @@ func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 //	@Router			/admin/users/{id} [delete]
 func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
+	before, _ := h.users.Get(r.Context(), id)
 
-	if _, err := h.users.Get(r.Context(), id); err != nil {
-		jsonapi.WriteNotFound(w, "user")
-		return
-	}
-
-	// Note: UserStore doesn't have Delete method yet, we'll update status to "deleted"
-	user, _ := h.users.Get(r.Context(), id)
-	user.Status = "deleted"
-	user.UpdatedAt = time.Now().UTC()
-
-	if err := h.users.Update(r.Context(), user); err != nil {
+	if err := h.users.Delete(r.Context(), id); err != nil {
+		if errors.Is(err, ports.ErrNotFound) {
+			jsonapi.WriteNotFound(w, "user")
+			return
+		}
 		h.logger.Error().Err(err).Msg("failed to delete user")
 		jsonapi.WriteInternalError(w, "Failed to delete user")
 		return
 	}
 
 	h.logger.Info().Str("user_id", id).Msg("user deleted via admin api")
+	h.recordAudit(r, audit.ActionDelete, audit.ResourceUser, id, audit.Snapshot(before), nil)
 	jsonapi.WriteNoContent(w)
 }
 
+// RestoreUser restores a soft-deleted user.
+//
+//	@Summary		Restore user
+//	@Description	Restore a soft-deleted user
+//	@Tags			Admin - Users
+//	@Produce		json
+//	@Param			id	path		string			true	"User ID"
+//	@Success		200	{object}	UserResponse	"Restored user"
+//	@Failure		404	{object}	ErrorResponse	"No deleted user with this ID"
+//	@Failure		409	{object}	ErrorResponse	"Email taken by another user"
+//	@Security		AdminAuth
+//	@Router			/admin/users/{id}/restore [post]
+func (h *Handler) RestoreUser(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if err := h.users.Restore(r.Context(), id); err != nil {
+		if errors.Is(err, ports.ErrNotFound) {
+			jsonapi.WriteNotFound(w, "user")
+			return
+		}
+		if errors.Is(err, ports.ErrConflict) {
+			jsonapi.WriteConflict(w, "Another user has this email")
+			return
+		}
+		h.logger.Error().Err(err).Msg("failed to restore user")
+		jsonapi.WriteInternalError(w, "Failed to restore user")
+		return
+	}
+
+	user, err := h.users.Get(r.Context(), id)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to get restored user")
+		jsonapi.WriteInternalError(w, "Failed to restore user")
+		return
+	}
+
+	h.logger.Info().Str("user_id", id).Msg("user restored via admin api")
+	h.recordAudit(r, audit.ActionRestore, audit.ResourceUser, id, nil, audit.Snapshot(user))
+	jsonapi.WriteResource(w, http.StatusOK, userToResource(user))
+}
+
+// withDeletedUsersParam returns the request context, opting in to
+// soft-deleted users when the include_deleted query parameter is true.
+func withDeletedUsersParam(r *http.Request) context.Context {
+	if include, _ := strconv.ParseBool(r.URL.Query().Get("include_deleted")); include {
+		return ports.WithDeletedUsers(r.Context())
+	}
+	return r.Context()
+}
+
 // userToResource converts a User to a JSON:API Resource.
 func userToResource(u ports.User) jsonapi.Resource {
-	return jsonapi.NewResource(TypeUser, u.ID).
+	rb := jsonapi.NewResource(TypeUser, u.ID).
 		Attr("email", u.Email).
 		Attr("name", u.Name).
 		Attr("status", u.Status).
 		Attr("created_at", u.CreatedAt.Format(time.RFC3339)).
 		Attr("updated_at", u.UpdatedAt.Format(time.RFC3339)).
-		BelongsTo("plan", "plans", u.PlanID).
-		Build()
+		BelongsTo("plan", "plans", u.PlanID)
+	if u.DeletedAt != nil {
+		rb.Attr("deleted_at", u.DeletedAt.Format(time.RFC3339))
+	}
+	return rb.Build()
 }
 
 func generateUserID() string {
@@ -948,9 +1076,18 @@ type KeyResponse struct {
 
 // CreateKeyRequest represents a request to create a key.
 type CreateKeyRequest struct {
-	UserID    string     `json:"user_id"`
-	Name      string     `json:"name,omitempty"`
-	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	UserID       string            `json:"user_id"`
+	Name         string            `json:"name,omitempty"`
+	ExpiresAt    *time.Time        `json:"expires_at,omitempty"`
+	AllowedCIDRs []string          `json:"allowed_cidrs,omitempty"` // Source IPs/CIDRs the key may be used from
+	Signing      bool              `json:"signing,omitempty"`       // Generate a secret for HMAC-signed requests
+	MonthlyQuota int64             `json:"monthly_quota,omitempty"` // Quota for this key alone; 0 = plan quota only
+	Labels       map[string]string `json:"labels,omitempty"`        // name=value tags, e.g. {"env": "prod"}
+}
+
+// RotateKeyRequest represents a request to rotate a key.
+type RotateKeyRequest struct {
+	GracePeriod string `json:"grace_period,omitempty"` // How long the old key keeps working, e.g. "1h" (default from auth.key_rotation_grace)
 }
 
 // CreateKeyResponse includes the raw key (only shown once).
@@ -970,16 +1107,25 @@ type CreateKeyResponse struct {
 //	@Tags			Admin - Keys
 //	@Produce		json
 //	@Param			user_id	query		string					false	"Filter by user ID"
+//	@Param			labels	query		string					false	"Filter by label selector, e.g. env=prod,service"
 //	@Success		200		{object}	map[string]interface{}	"Keys list"
+//	@Failure		400		{object}	ErrorResponse			"Invalid label selector"
 //	@Security		AdminAuth
 //	@Router			/admin/keys [get]
 func (h *Handler) ListKeys(w http.ResponseWriter, r *http.Request) {
 	userID := r.URL.Query().Get("user_id")
 
+	sel, err := key.ParseLabelSelector(r.URL.Query().Get("labels"))
+	if err != nil {
+		jsonapi.WriteValidationError(w, "labels", err.Error())
+		return
+	}
+
 	var keys []key.Key
-	var err error
 
-	if userID != "" {
+	if labelKeys, ok := h.keys.(ports.KeyLabelStore); ok && userID != "" {
+		keys, err = labelKeys.ListByUserLabels(r.Context(), userID, sel)
+	} else if userID != "" {
 		keys, err = h.keys.ListByUser(r.Context(), userID)
 	} else {
 		// List all keys - we need to get all users and their keys
@@ -1001,6 +1147,7 @@ func (h *Handler) ListKeys(w http.ResponseWriter, r *http.Request) {
 		jsonapi.WriteInternalError(w, "Failed to list keys")
 		return
 	}
+	keys = key.FilterByLabels(keys, sel)
 
 	resources := make([]jsonapi.Resource, len(keys))
 	for i, k := range keys {
@@ -1034,6 +1181,20 @@ func (h *Handler) CreateKey(w http.ResponseWriter, r *http.Request) {
 		jsonapi.WriteValidationError(w, "user_id", "user_id is required")
 		return
 	}
+	for _, cidr := range req.AllowedCIDRs {
+		if _, err := key.ParseCIDR(cidr); err != nil {
+			jsonapi.WriteValidationError(w, "allowed_cidrs", "invalid CIDR or IP address: "+cidr)
+			return
+		}
+	}
+	if req.MonthlyQuota < 0 {
+		jsonapi.WriteValidationError(w, "monthly_quota", "monthly_quota must not be negative")
+		return
+	}
+	if err := key.ValidateLabels(req.Labels); err != nil {
+		jsonapi.WriteValidationError(w, "labels", err.Error())
+		return
+	}
 
 	// Verify user exists
 	if _, err := h.users.Get(r.Context(), req.UserID); err != nil {
@@ -1047,6 +1208,14 @@ func (h *Handler) CreateKey(w http.ResponseWriter, r *http.Request) {
 	if req.ExpiresAt != nil {
 		keyData.ExpiresAt = req.ExpiresAt
 	}
+	keyData.AllowedCIDRs = req.AllowedCIDRs
+	keyData.MonthlyQuota = req.MonthlyQuota
+	if len(req.Labels) > 0 {
+		keyData.Labels = req.Labels
+	}
+	if req.Signing {
+		keyData.SigningSecret = key.GenerateSigningSecret()
+	}
 
 	if err := h.keys.Create(r.Context(), keyData); err != nil {
 		h.logger.Error().Err(err).Msg("failed to create key")
@@ -1055,16 +1224,20 @@ func (h *Handler) CreateKey(w http.ResponseWriter, r *http.Request) {
 	}
 
 	h.logger.Info().Str("key_id", keyData.ID).Str("user_id", req.UserID).Msg("key created via admin api")
+	h.recordAudit(r, audit.ActionCreate, audit.ResourceKey, keyData.ID, nil, audit.Snapshot(keyData))
 
 	// Return key resource with the raw key in meta (only shown once)
-	resource := jsonapi.NewResource(TypeKey, keyData.ID).
+	rb := jsonapi.NewResource(TypeKey, keyData.ID).
 		Attr("prefix", keyData.Prefix).
 		Attr("name", req.Name).
 		Attr("created_at", keyData.CreatedAt.Format(time.RFC3339)).
 		BelongsTo("user", TypeUser, req.UserID).
 		Meta("key", rawKey).
-		Meta("note", "Save this key securely. It will not be shown again.").
-		Build()
+		Meta("note", "Save this key securely. It will not be shown again.")
+	if keyData.SigningSecret != "" {
+		rb.Meta("signing_secret", keyData.SigningSecret)
+	}
+	resource := rb.Build()
 
 	jsonapi.WriteCreated(w, resource, "/admin/keys/"+keyData.ID)
 }
@@ -1083,16 +1256,117 @@ func (h *Handler) CreateKey(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) RevokeKey(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
-	if err := h.keys.Revoke(r.Context(), id, time.Now().UTC()); err != nil {
+	now := time.Now().UTC()
+	if err := h.keys.Revoke(r.Context(), id, now); err != nil {
 		h.logger.Error().Err(err).Str("key_id", id).Msg("failed to revoke key")
 		jsonapi.WriteNotFound(w, "key")
 		return
 	}
 
 	h.logger.Info().Str("key_id", id).Msg("key revoked via admin api")
+	h.recordAudit(r, audit.ActionRevoke, audit.ResourceKey, id, nil, audit.Snapshot(struct{ RevokedAt time.Time }{now}))
 	jsonapi.WriteNoContent(w)
 }
 
+// RotateKey issues a replacement for an API key and schedules the old key's
+// revocation after a grace period, so clients can switch without downtime.
+//
+//	@Summary		Rotate key
+//	@Description	Issue a replacement API key; the old key stays valid for the grace period
+//	@Tags			Admin - Keys
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string				true	"Key ID"
+//	@Param			request	body		RotateKeyRequest	false	"Rotation options"
+//	@Success		201		{object}	CreateKeyResponse	"Replacement key (save the key, shown once)"
+//	@Failure		400		{object}	ErrorResponse		"Invalid request"
+//	@Failure		404		{object}	ErrorResponse		"Key not found"
+//	@Failure		409		{object}	ErrorResponse		"Key is revoked, expired or already being rotated"
+//	@Security		AdminAuth
+//	@Router			/admin/keys/{id}/rotate [post]
+func (h *Handler) RotateKey(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req RotateKeyRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			jsonapi.WriteBadRequest(w, "Invalid JSON body")
+			return
+		}
+	}
+	grace := h.rotationGrace
+	if req.GracePeriod != "" {
+		d, err := time.ParseDuration(req.GracePeriod)
+		if err != nil || d < 0 {
+			jsonapi.WriteValidationError(w, "grace_period", "grace_period must be a non-negative duration such as 30m or 24h")
+			return
+		}
+		grace = d
+	}
+
+	old, ok := h.findKey(r.Context(), id)
+	if !ok {
+		jsonapi.WriteNotFound(w, "key")
+		return
+	}
+
+	now := time.Now().UTC()
+	if old.RevokedAt != nil {
+		jsonapi.WriteConflict(w, "Key is already revoked or being rotated")
+		return
+	}
+	if old.ExpiresAt != nil && !now.Before(*old.ExpiresAt) {
+		jsonapi.WriteConflict(w, "Key has expired")
+		return
+	}
+
+	rawKey, replacement, revokeAt := key.Rotate(old, "ak_", now, grace)
+	if err := h.keys.Create(r.Context(), replacement); err != nil {
+		h.logger.Error().Err(err).Str("key_id", id).Msg("failed to create replacement key")
+		jsonapi.WriteInternalError(w, "Failed to rotate key")
+		return
+	}
+	if err := h.keys.Revoke(r.Context(), old.ID, revokeAt); err != nil {
+		h.logger.Error().Err(err).Str("key_id", id).Msg("failed to schedule revocation of rotated key")
+		jsonapi.WriteInternalError(w, "Failed to rotate key")
+		return
+	}
+
+	h.logger.Info().Str("key_id", old.ID).Str("replacement_id", replacement.ID).
+		Time("revokes_at", revokeAt).Msg("key rotated via admin api")
+	h.recordAudit(r, audit.ActionRotate, audit.ResourceKey, old.ID, audit.Snapshot(old), audit.Snapshot(replacement))
+
+	rb := jsonapi.NewResource(TypeKey, replacement.ID).
+		Attr("prefix", replacement.Prefix).
+		Attr("old_prefix", old.Prefix).
+		Attr("name", replacement.Name).
+		Attr("rotated_from", old.ID).
+		Attr("old_key_revokes_at", revokeAt.Format(time.RFC3339)).
+		Attr("created_at", replacement.CreatedAt.Format(time.RFC3339)).
+		BelongsTo("user", TypeUser, replacement.UserID).
+		Meta("key", rawKey).
+		Meta("note", "Save this key securely. It will not be shown again.")
+	if replacement.SigningSecret != "" {
+		rb.Meta("signing_secret", replacement.SigningSecret)
+	}
+
+	jsonapi.WriteCreated(w, rb.Build(), "/admin/keys/"+replacement.ID)
+}
+
+// findKey looks up a key by ID across all users' keys.
+func (h *Handler) findKey(ctx context.Context, id string) (key.Key, bool) {
+	keys, err := h.allKeys(ctx)
+	if err != nil {
+		return key.Key{}, false
+	}
+	for _, k := range keys {
+		if k.ID == id {
+			return k, true
+		}
+	}
+	return key.Key{}, false
+}
+
 // keyToResource converts a Key to a JSON:API Resource.
 func keyToResource(k key.Key) jsonapi.Resource {
 	rb := jsonapi.NewResource(TypeKey, k.ID).
@@ -1110,6 +1384,22 @@ func keyToResource(k key.Key) jsonapi.Resource {
 	if k.LastUsed != nil {
 		rb.Attr("last_used", k.LastUsed.Format(time.RFC3339))
 	}
+	if k.LastUsedIP != "" {
+		rb.Attr("last_used_ip", k.LastUsedIP)
+	}
+	if len(k.AllowedCIDRs) > 0 {
+		rb.Attr("allowed_cidrs", k.AllowedCIDRs)
+	}
+	if k.RotatedFrom != "" {
+		rb.Attr("rotated_from", k.RotatedFrom)
+	}
+	if k.MonthlyQuota > 0 {
+		rb.Attr("monthly_quota", k.MonthlyQuota)
+	}
+	if len(k.Labels) > 0 {
+		rb.Attr("labels", k.Labels)
+	}
+	rb.Attr("signing_enabled", k.SigningSecret != "")
 	return rb.Build()
 }
 
@@ -8,10 +8,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/artpar/apigate/adapters/clock"
 	"github.com/artpar/apigate/adapters/hasher"
 	"github.com/artpar/apigate/adapters/http/admin"
 	"github.com/artpar/apigate/adapters/memory"
@@ -408,6 +410,49 @@ func TestCreateKey(t *testing.T) {
 	}
 }
 
+func TestCreateKey_IdempotencyKey(t *testing.T) {
+	userStore := memory.NewUserStore()
+	keyStore := memory.NewKeyStore()
+	userStore.Create(context.Background(), ports.User{ID: "user_admin", Email: "admin@test.com", Status: "active"})
+	rawKey, keyData := key.Generate("ak_")
+	keyStore.Create(context.Background(), keyData.WithUserID("user_admin"))
+
+	h := admin.NewHandler(admin.Deps{
+		Users:       userStore,
+		Keys:        keyStore,
+		Plans:       newMockPlanStore(),
+		Logger:      zerolog.Nop(),
+		Hasher:      hasher.NewBcrypt(4),
+		Idempotency: memory.NewResponseCache(clock.Real{}, 0),
+	})
+	router := h.Router()
+
+	post := func() *httptest.ResponseRecorder {
+		body := strings.NewReader(`{"user_id":"user_admin","name":"CI key"}`)
+		req := httptest.NewRequest("POST", "/keys", body)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", rawKey)
+		req.Header.Set("Idempotency-Key", "create-ci-key")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := post()
+	second := post()
+
+	if first.Code != http.StatusCreated || second.Code != http.StatusCreated {
+		t.Fatalf("Expected 201/201, got %d/%d", first.Code, second.Code)
+	}
+	if first.Body.String() != second.Body.String() {
+		t.Error("Expected duplicate request to replay the first response")
+	}
+	keys, _ := keyStore.ListByUser(context.Background(), "user_admin")
+	if len(keys) != 2 { // the admin key plus one created key
+		t.Errorf("Expected 2 keys, got %d", len(keys))
+	}
+}
+
 func TestListKeys(t *testing.T) {
 	h, rawKey := setupHandler(t)
 
@@ -1605,6 +1650,74 @@ func TestDeleteUser_NotFound(t *testing.T) {
 	}
 }
 
+func TestDeleteUser_Restore(t *testing.T) {
+	h, rawKey := setupHandler(t)
+	userID := createTestUser(t, h, rawKey, "restore@test.com")
+
+	countUsers := func(query string) int {
+		t.Helper()
+		resp := doRequest(t, h, "GET", "/users"+query, nil, rawKey)
+		var result map[string]any
+		json.NewDecoder(resp.Body).Decode(&result)
+		return len(getCollectionData(result))
+	}
+	before := countUsers("")
+
+	if resp := doRequest(t, h, "DELETE", "/users/"+userID, nil, rawKey); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Delete: expected 204, got %d", resp.StatusCode)
+	}
+
+	// Soft-deleted users are hidden unless include_deleted is set
+	if n := countUsers(""); n != before-1 {
+		t.Errorf("Expected %d users after delete, got %d", before-1, n)
+	}
+	if n := countUsers("?include_deleted=true"); n != before {
+		t.Errorf("Expected %d users with include_deleted, got %d", before, n)
+	}
+	if resp := doRequest(t, h, "GET", "/users/"+userID, nil, rawKey); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Get deleted user: expected 404, got %d", resp.StatusCode)
+	}
+	resp := doRequest(t, h, "GET", "/users/"+userID+"?include_deleted=true", nil, rawKey)
+	var deleted map[string]any
+	json.NewDecoder(resp.Body).Decode(&deleted)
+	if getResourceAttr(deleted, "deleted_at") == nil {
+		t.Error("Expected deleted_at on a deleted user")
+	}
+
+	resp = doRequest(t, h, "POST", "/users/"+userID+"/restore", nil, rawKey)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Restore: expected 200, got %d", resp.StatusCode)
+	}
+	if n := countUsers(""); n != before {
+		t.Errorf("Expected %d users after restore, got %d", before, n)
+	}
+
+	// Restoring a user that is not deleted is not found
+	if resp := doRequest(t, h, "POST", "/users/"+userID+"/restore", nil, rawKey); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Second restore: expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestDeleteUser_EmailReused(t *testing.T) {
+	h, rawKey := setupHandler(t)
+	oldID := createTestUser(t, h, rawKey, "reuse@test.com")
+
+	if resp := doRequest(t, h, "DELETE", "/users/"+oldID, nil, rawKey); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Delete: expected 204, got %d", resp.StatusCode)
+	}
+
+	// A new account can take the deleted user's email
+	resp := doRequest(t, h, "POST", "/users", map[string]string{"email": "reuse@test.com"}, rawKey)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Create with deleted user's email: expected 201, got %d", resp.StatusCode)
+	}
+
+	// The old account can no longer be restored under the same email
+	if resp := doRequest(t, h, "POST", "/users/"+oldID+"/restore", nil, rawKey); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Restore: expected 409, got %d", resp.StatusCode)
+	}
+}
+
 // ============================================================================
 // Keys API Additional Tests
 // ============================================================================
@@ -1672,6 +1785,193 @@ func TestCreateKey_WithExpiry(t *testing.T) {
 	}
 }
 
+func TestCreateKey_AllowedCIDRs(t *testing.T) {
+	h, rawKey := setupHandler(t)
+
+	userResp := doRequest(t, h, "POST", "/users", map[string]string{"email": "keycidrs@test.com"}, rawKey)
+	var user map[string]any
+	json.NewDecoder(userResp.Body).Decode(&user)
+	userID := getResourceID(user)
+
+	body := map[string]any{"user_id": userID, "allowed_cidrs": []string{"203.0.113.0/24", "198.51.100.7"}}
+	resp := doRequest(t, h, "POST", "/keys", body, rawKey)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", resp.StatusCode)
+	}
+
+	body["allowed_cidrs"] = []string{"203.0.113.0/33"}
+	resp = doRequest(t, h, "POST", "/keys", body, rawKey)
+	if resp.StatusCode != http.StatusBadRequest && resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid CIDR: expected 400 or 422, got %d", resp.StatusCode)
+	}
+}
+
+func TestCreateKey_MonthlyQuota(t *testing.T) {
+	h, rawKey := setupHandler(t)
+
+	userResp := doRequest(t, h, "POST", "/users", map[string]string{"email": "keyquota@test.com"}, rawKey)
+	var user map[string]any
+	json.NewDecoder(userResp.Body).Decode(&user)
+	userID := getResourceID(user)
+
+	resp := doRequest(t, h, "POST", "/keys", map[string]any{"user_id": userID, "monthly_quota": 500}, rawKey)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", resp.StatusCode)
+	}
+
+	resp = doRequest(t, h, "GET", "/keys?user_id="+userID, nil, rawKey)
+	var list map[string]any
+	json.NewDecoder(resp.Body).Decode(&list)
+	keys := list["data"].([]any)
+	if len(keys) != 1 {
+		t.Fatalf("keys = %d, want 1", len(keys))
+	}
+	attrs := keys[0].(map[string]any)["attributes"].(map[string]any)
+	if attrs["monthly_quota"] != float64(500) {
+		t.Errorf("monthly_quota = %v, want 500", attrs["monthly_quota"])
+	}
+
+	resp = doRequest(t, h, "POST", "/keys", map[string]any{"user_id": userID, "monthly_quota": -1}, rawKey)
+	if resp.StatusCode != http.StatusBadRequest && resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("negative quota: expected 400 or 422, got %d", resp.StatusCode)
+	}
+}
+
+func TestListKeys_Labels(t *testing.T) {
+	h, rawKey := setupHandler(t)
+
+	userResp := doRequest(t, h, "POST", "/users", map[string]string{"email": "keylabels@test.com"}, rawKey)
+	var user map[string]any
+	json.NewDecoder(userResp.Body).Decode(&user)
+	userID := getResourceID(user)
+
+	for _, labels := range []map[string]string{{"env": "prod"}, {"env": "dev"}} {
+		resp := doRequest(t, h, "POST", "/keys", map[string]any{"user_id": userID, "labels": labels}, rawKey)
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("Expected 201, got %d", resp.StatusCode)
+		}
+	}
+
+	resp := doRequest(t, h, "GET", "/keys?user_id="+userID+"&labels=env%3Dprod", nil, rawKey)
+	var list map[string]any
+	json.NewDecoder(resp.Body).Decode(&list)
+	keys := list["data"].([]any)
+	if len(keys) != 1 {
+		t.Fatalf("keys = %d, want 1", len(keys))
+	}
+	attrs := keys[0].(map[string]any)["attributes"].(map[string]any)
+	if labels, _ := attrs["labels"].(map[string]any); labels["env"] != "prod" {
+		t.Errorf("labels = %v, want env=prod", attrs["labels"])
+	}
+
+	resp = doRequest(t, h, "GET", "/keys?user_id="+userID+"&labels=bad%20label", nil, rawKey)
+	if resp.StatusCode != http.StatusBadRequest && resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid selector: expected 400 or 422, got %d", resp.StatusCode)
+	}
+
+	resp = doRequest(t, h, "POST", "/keys", map[string]any{"user_id": userID, "labels": map[string]string{"env": "has space"}}, rawKey)
+	if resp.StatusCode != http.StatusBadRequest && resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid labels: expected 400 or 422, got %d", resp.StatusCode)
+	}
+}
+
+func TestCreateKey_Signing(t *testing.T) {
+	h, rawKey := setupHandler(t)
+
+	userResp := doRequest(t, h, "POST", "/users", map[string]string{"email": "keysigning@test.com"}, rawKey)
+	var user map[string]any
+	json.NewDecoder(userResp.Body).Decode(&user)
+	userID := getResourceID(user)
+
+	resp := doRequest(t, h, "POST", "/keys", map[string]any{"user_id": userID, "signing": true}, rawKey)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", resp.StatusCode)
+	}
+	var created map[string]any
+	json.NewDecoder(resp.Body).Decode(&created)
+	data := created["data"].(map[string]any)
+	meta, _ := data["meta"].(map[string]any)
+	if secret, _ := meta["signing_secret"].(string); len(secret) != 64 {
+		t.Errorf("signing_secret = %q, want 64 hex chars", secret)
+	}
+}
+
+func TestRotateKey(t *testing.T) {
+	h, rawKey := setupHandler(t)
+
+	userResp := doRequest(t, h, "POST", "/users", map[string]string{"email": "keyrotate@test.com"}, rawKey)
+	var user map[string]any
+	json.NewDecoder(userResp.Body).Decode(&user)
+	userID := getResourceID(user)
+
+	createResp := doRequest(t, h, "POST", "/keys", map[string]any{"user_id": userID, "name": "Production"}, rawKey)
+	var created map[string]any
+	json.NewDecoder(createResp.Body).Decode(&created)
+	oldID := getResourceID(created)
+	oldPrefix := created["data"].(map[string]any)["attributes"].(map[string]any)["prefix"].(string)
+
+	before := time.Now()
+	resp := doRequest(t, h, "POST", "/keys/"+oldID+"/rotate", map[string]string{"grace_period": "2h"}, rawKey)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", resp.StatusCode)
+	}
+	var rotated map[string]any
+	json.NewDecoder(resp.Body).Decode(&rotated)
+	data := rotated["data"].(map[string]any)
+	attrs := data["attributes"].(map[string]any)
+	meta, _ := data["meta"].(map[string]any)
+
+	if attrs["old_prefix"] != oldPrefix {
+		t.Errorf("old_prefix = %v, want %s", attrs["old_prefix"], oldPrefix)
+	}
+	if prefix, _ := attrs["prefix"].(string); prefix == "" || prefix == oldPrefix {
+		t.Errorf("prefix = %q, want a new prefix", prefix)
+	}
+	if attrs["rotated_from"] != oldID || attrs["name"] != "Production" {
+		t.Errorf("attributes = %v, want rotated_from %s and name Production", attrs, oldID)
+	}
+	if newKey, _ := meta["key"].(string); !strings.HasPrefix(newKey, "ak_") {
+		t.Errorf("meta key = %q, want the new raw key", newKey)
+	}
+	revokesAt, err := time.Parse(time.RFC3339, attrs["old_key_revokes_at"].(string))
+	if err != nil || revokesAt.Before(before.Add(2*time.Hour-time.Second)) || revokesAt.After(time.Now().Add(2*time.Hour)) {
+		t.Errorf("old_key_revokes_at = %v, want about 2h from now", attrs["old_key_revokes_at"])
+	}
+
+	// The old key is scheduled for revocation, so it can't be rotated again
+	resp = doRequest(t, h, "POST", "/keys/"+oldID+"/rotate", nil, rawKey)
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("second rotation: expected 409, got %d", resp.StatusCode)
+	}
+
+	listResp := doRequest(t, h, "GET", "/keys?user_id="+userID, nil, rawKey)
+	var list map[string]any
+	json.NewDecoder(listResp.Body).Decode(&list)
+	if keys := getCollectionData(list); len(keys) != 2 {
+		t.Errorf("Expected 2 keys after rotation, got %d", len(keys))
+	}
+}
+
+func TestRotateKey_Errors(t *testing.T) {
+	h, rawKey := setupHandler(t)
+
+	if resp := doRequest(t, h, "POST", "/keys/key_missing/rotate", nil, rawKey); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("missing key: expected 404, got %d", resp.StatusCode)
+	}
+
+	userResp := doRequest(t, h, "POST", "/users", map[string]string{"email": "keyrotate2@test.com"}, rawKey)
+	var user map[string]any
+	json.NewDecoder(userResp.Body).Decode(&user)
+	createResp := doRequest(t, h, "POST", "/keys", map[string]any{"user_id": getResourceID(user)}, rawKey)
+	var created map[string]any
+	json.NewDecoder(createResp.Body).Decode(&created)
+
+	resp := doRequest(t, h, "POST", "/keys/"+getResourceID(created)+"/rotate", map[string]string{"grace_period": "soon"}, rawKey)
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("invalid grace_period: expected 422, got %d", resp.StatusCode)
+	}
+}
+
 func TestListKeys_FilterByUser(t *testing.T) {
 	h, rawKey := setupHandler(t)
 
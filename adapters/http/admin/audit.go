@@ -0,0 +1,79 @@
+package admin
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/artpar/apigate/domain/audit"
+	"github.com/artpar/apigate/pkg/jsonapi"
+)
+
+// TypeAuditEntry is the JSON:API type for audit log entries.
+const TypeAuditEntry = "audit_entries"
+
+// recordAudit queues an audit entry for an admin mutation. before is nil for
+// creates and after is nil for deletes. It never blocks the request.
+func (h *Handler) recordAudit(r *http.Request, action, resourceType, resourceID string, before, after map[string]any) {
+	if h.audit == nil {
+		return
+	}
+	actorID, _ := r.Context().Value(ctxUserIDKey).(string)
+	h.audit.Record(audit.NewEntry(actorID, action, resourceType, resourceID, before, after, time.Now().UTC()))
+}
+
+// ListAudit returns audit log entries, newest first.
+//
+//	@Summary		List audit log
+//	@Description	List admin actions, newest first, optionally filtered by actor and resource
+//	@Tags			Admin - Audit
+//	@Produce		json
+//	@Param			actor_id		query		string					false	"Admin who performed the action"
+//	@Param			resource_type	query		string					false	"Resource type (user, key, plan)"
+//	@Param			resource_id		query		string					false	"Resource ID"
+//	@Param			page[number]	query		int						false	"Page number"	default(1)
+//	@Param			page[size]		query		int						false	"Page size"		default(50)
+//	@Success		200				{object}	map[string]interface{}	"Audit entries"
+//	@Security		AdminAuth
+//	@Router			/admin/audit [get]
+func (h *Handler) ListAudit(w http.ResponseWriter, r *http.Request) {
+	if h.auditStore == nil {
+		jsonapi.WriteError(w, jsonapi.NewError(http.StatusNotImplemented, "not_implemented", "Not Implemented").
+			Detail("The audit log is not configured").Build())
+		return
+	}
+
+	q := r.URL.Query()
+	page, perPage := jsonapi.ParsePaginationParams(q, 50)
+	entries, err := h.auditStore.List(r.Context(), audit.Filter{
+		ActorID:      q.Get("actor_id"),
+		ResourceType: q.Get("resource_type"),
+		ResourceID:   q.Get("resource_id"),
+		Limit:        perPage,
+		Offset:       (page - 1) * perPage,
+	})
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to list audit log")
+		jsonapi.WriteInternalError(w, "Failed to list audit log")
+		return
+	}
+
+	resources := make([]jsonapi.Resource, len(entries))
+	for i, e := range entries {
+		resources[i] = auditEntryToResource(e)
+	}
+	jsonapi.WriteCollection(w, http.StatusOK, resources, nil)
+}
+
+// auditEntryToResource converts an audit entry to a JSON:API Resource.
+func auditEntryToResource(e audit.Entry) jsonapi.Resource {
+	return jsonapi.NewResource(TypeAuditEntry, e.ID).
+		Attr("actor_id", e.ActorID).
+		Attr("action", e.Action).
+		Attr("resource_type", e.ResourceType).
+		Attr("resource_id", e.ResourceID).
+		Attr("before", e.Before).
+		Attr("after", e.After).
+		Attr("changes", e.Changes).
+		Attr("created_at", e.CreatedAt.Format(time.RFC3339)).
+		Build()
+}
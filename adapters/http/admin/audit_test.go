@@ -0,0 +1,137 @@
+package admin_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/hasher"
+	"github.com/artpar/apigate/adapters/http/admin"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/domain/audit"
+	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+)
+
+// syncAuditRecorder writes entries straight to the store so tests can read
+// them back without waiting on the async writer.
+type syncAuditRecorder struct {
+	store *memory.AuditStore
+}
+
+func (r syncAuditRecorder) Record(e audit.Entry) {
+	r.store.Append(context.Background(), []audit.Entry{e})
+}
+
+func setupHandlerWithAudit(t *testing.T) (*admin.Handler, string) {
+	t.Helper()
+
+	userStore := memory.NewUserStore()
+	keyStore := memory.NewKeyStore()
+	auditStore := memory.NewAuditStore()
+	now := time.Now().UTC()
+
+	userStore.Create(context.Background(), ports.User{ID: "user_admin", Email: "admin@test.com", PlanID: "free", Status: "active", CreatedAt: now, UpdatedAt: now})
+	rawKey, keyData := key.Generate("ak_")
+	keyStore.Create(context.Background(), keyData.WithUserID("user_admin"))
+
+	planStore := newMockPlanStore()
+	planStore.Create(context.Background(), ports.Plan{ID: "free", Name: "Free", RateLimitPerMinute: 60, IsDefault: true, Enabled: true, CreatedAt: now, UpdatedAt: now})
+	planStore.Create(context.Background(), ports.Plan{ID: "pro", Name: "Pro", RateLimitPerMinute: 600, Enabled: true, CreatedAt: now, UpdatedAt: now})
+
+	h := admin.NewHandler(admin.Deps{
+		Users:      userStore,
+		Keys:       keyStore,
+		Plans:      planStore,
+		Logger:     zerolog.Nop(),
+		Hasher:     hasher.NewBcrypt(4),
+		Audit:      syncAuditRecorder{store: auditStore},
+		AuditStore: auditStore,
+	})
+	return h, rawKey
+}
+
+func listAudit(t *testing.T, h *admin.Handler, rawKey, query string) []any {
+	t.Helper()
+	resp := doRequest(t, h, "GET", "/audit"+query, nil, rawKey)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /audit%s: expected 200, got %d", query, resp.StatusCode)
+	}
+	var result map[string]any
+	json.NewDecoder(resp.Body).Decode(&result)
+	return getCollectionData(result)
+}
+
+func TestAudit_PlanUpdateRecordsDiff(t *testing.T) {
+	h, rawKey := setupHandlerWithAudit(t)
+
+	resp := doRequest(t, h, "PUT", "/plans/pro", map[string]any{"rate_limit_per_minute": 1200}, rawKey)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Update plan: expected 200, got %d", resp.StatusCode)
+	}
+
+	entries := listAudit(t, h, rawKey, "?resource_type=plan&resource_id=pro")
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 audit entry for the plan, got %d", len(entries))
+	}
+	attrs := entries[0].(map[string]any)["attributes"].(map[string]any)
+	if attrs["action"] != audit.ActionUpdate || attrs["actor_id"] != "admin" {
+		t.Errorf("entry = %v, want update by admin", attrs)
+	}
+
+	changes, _ := attrs["changes"].(map[string]any)
+	limit, ok := changes["RateLimitPerMinute"].(map[string]any)
+	if !ok {
+		t.Fatalf("changes = %v, want RateLimitPerMinute", changes)
+	}
+	if limit["before"] != float64(600) || limit["after"] != float64(1200) {
+		t.Errorf("RateLimitPerMinute change = %v, want 600 -> 1200", limit)
+	}
+	if _, ok := changes["Name"]; ok {
+		t.Error("Unchanged fields should not be in the diff")
+	}
+	if before, _ := attrs["before"].(map[string]any); before["Name"] != "Pro" {
+		t.Errorf("before = %v, want full snapshot", attrs["before"])
+	}
+}
+
+func TestAudit_FiltersAndRedaction(t *testing.T) {
+	h, rawKey := setupHandlerWithAudit(t)
+
+	userID := createTestUser(t, h, rawKey, "audited@test.com")
+	doRequest(t, h, "PUT", "/users/"+userID, map[string]any{"password": "new-password-123"}, rawKey)
+	doRequest(t, h, "POST", "/keys", map[string]any{"user_id": userID}, rawKey)
+	doRequest(t, h, "DELETE", "/plans/pro", nil, rawKey)
+
+	if n := len(listAudit(t, h, rawKey, "")); n != 4 {
+		t.Errorf("Expected 4 audit entries, got %d", n)
+	}
+	if n := len(listAudit(t, h, rawKey, "?resource_type=user&resource_id="+userID)); n != 2 {
+		t.Errorf("Expected 2 entries for the user, got %d", n)
+	}
+	if n := len(listAudit(t, h, rawKey, "?actor_id=admin&resource_type=key")); n != 1 {
+		t.Errorf("Expected 1 key entry by admin, got %d", n)
+	}
+	if n := len(listAudit(t, h, rawKey, "?actor_id=someone-else")); n != 0 {
+		t.Errorf("Expected no entries for another actor, got %d", n)
+	}
+
+	// Newest first, and secrets are never stored
+	entries := listAudit(t, h, rawKey, "")
+	first := entries[0].(map[string]any)["attributes"].(map[string]any)
+	if first["action"] != audit.ActionDelete || first["resource_type"] != audit.ResourcePlan {
+		t.Errorf("Newest entry = %v, want the plan delete", first)
+	}
+	for _, e := range entries {
+		attrs := e.(map[string]any)["attributes"].(map[string]any)
+		after, _ := attrs["after"].(map[string]any)
+		for _, secret := range []string{"PasswordHash", "Hash", "SigningSecret"} {
+			if _, ok := after[secret]; ok {
+				t.Errorf("%s %s entry stores %s", attrs["action"], attrs["resource_type"], secret)
+			}
+		}
+	}
+}
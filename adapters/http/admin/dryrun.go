@@ -0,0 +1,58 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/pkg/jsonapi"
+)
+
+// RequestTester evaluates synthetic requests without proxying them.
+type RequestTester interface {
+	DryRun(ctx context.Context, req app.DryRunRequest) app.DryRunResult
+}
+
+// DryRun reports how the proxy would route and authorize a request - the
+// matched route, resolved upstream, auth decision and rate limit and quota
+// state - without calling the upstream or spending rate limit or quota.
+//
+//	@Summary		Dry-run a request
+//	@Description	Evaluate routing, auth, rate limit and quota for a synthetic request without proxying it
+//	@Tags			Admin - Routes
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		app.DryRunRequest	true	"Synthetic request"
+//	@Success		200		{object}	app.DryRunResult	"How the request would be handled"
+//	@Failure		400		{object}	ErrorResponse		"Invalid JSON"
+//	@Failure		422		{object}	ErrorResponse		"Invalid path"
+//	@Failure		501		{object}	ErrorResponse		"Dry runs not available"
+//	@Security		AdminAuth
+//	@Router			/admin/dry-run [post]
+func (h *Handler) DryRun(w http.ResponseWriter, r *http.Request) {
+	if h.requestTester == nil {
+		jsonapi.WriteError(w, jsonapi.NewError(http.StatusNotImplemented, "not_implemented", "Not Implemented").
+			Detail("Dry runs are not available").Build())
+		return
+	}
+
+	var req app.DryRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonapi.WriteBadRequest(w, "Invalid JSON body")
+		return
+	}
+	if req.Path == "" || !strings.HasPrefix(req.Path, "/") {
+		jsonapi.WriteValidationError(w, "path", "path must start with /")
+		return
+	}
+	if req.Method == "" {
+		req.Method = http.MethodGet
+	}
+	req.Method = strings.ToUpper(req.Method)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.requestTester.DryRun(r.Context(), req))
+}
@@ -0,0 +1,81 @@
+package admin_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/artpar/apigate/adapters/hasher"
+	"github.com/artpar/apigate/adapters/http/admin"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+)
+
+// stubRequestTester records the last dry-run request and returns a fixed result.
+type stubRequestTester struct {
+	last app.DryRunRequest
+}
+
+func (s *stubRequestTester) DryRun(ctx context.Context, req app.DryRunRequest) app.DryRunResult {
+	s.last = req
+	return app.DryRunResult{
+		Decision: "allow",
+		Route:    &app.DryRunRoute{ID: "orders", Name: "Orders"},
+		Auth:     app.DryRunAuth{Required: true, Authenticated: true, KeyID: "key-1"},
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	userStore := memory.NewUserStore()
+	keyStore := memory.NewKeyStore()
+	userStore.Create(context.Background(), ports.User{ID: "user_admin", Email: "admin@test.com", Status: "active"})
+	rawKey, keyData := key.Generate("ak_")
+	keyStore.Create(context.Background(), keyData.WithUserID("user_admin"))
+
+	tester := &stubRequestTester{}
+	h := admin.NewHandler(admin.Deps{
+		Users:         userStore,
+		Keys:          keyStore,
+		Plans:         newMockPlanStore(),
+		Logger:        zerolog.Nop(),
+		Hasher:        hasher.NewBcrypt(4),
+		RequestTester: tester,
+	})
+
+	resp := doRequest(t, h, "POST", "/dry-run", map[string]any{
+		"method":  "get",
+		"path":    "/orders/42",
+		"host":    "api.example.com",
+		"api_key": "ak_test",
+	}, rawKey)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+
+	var result app.DryRunResult
+	json.NewDecoder(resp.Body).Decode(&result)
+	if result.Decision != "allow" || result.Route == nil || result.Route.ID != "orders" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if tester.last.Method != "GET" || tester.last.Host != "api.example.com" || tester.last.APIKey != "ak_test" {
+		t.Errorf("request not passed through: %+v", tester.last)
+	}
+
+	resp = doRequest(t, h, "POST", "/dry-run", map[string]any{"method": "GET", "path": "orders"}, rawKey)
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected validation error for relative path, got %d", resp.StatusCode)
+	}
+}
+
+func TestDryRun_NotConfigured(t *testing.T) {
+	h, rawKey := setupHandler(t)
+
+	resp := doRequest(t, h, "POST", "/dry-run", map[string]any{"path": "/orders"}, rawKey)
+	if resp.StatusCode != http.StatusNotImplemented {
+		t.Errorf("Expected 501, got %d", resp.StatusCode)
+	}
+}
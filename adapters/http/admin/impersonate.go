@@ -0,0 +1,72 @@
+package admin
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/artpar/apigate/domain/audit"
+	"github.com/artpar/apigate/pkg/jsonapi"
+	"github.com/go-chi/chi/v5"
+)
+
+// impersonationTTL is how long an impersonated portal session lasts.
+const impersonationTTL = 15 * time.Minute
+
+// ImpersonateUser mints a short-lived portal session for a user so support
+// staff can see the portal as the user does. The session is flagged as
+// impersonated and cannot be refreshed.
+//
+//	@Summary		Impersonate user
+//	@Description	Create a short-lived portal session for a user (requires auth.jwt_secret)
+//	@Tags			Admin - Users
+//	@Produce		json
+//	@Param			id	path		string					true	"User ID"
+//	@Success		201	{object}	map[string]interface{}	"Portal session token and login URL"
+//	@Failure		404	{object}	ErrorResponse			"User not found"
+//	@Failure		409	{object}	ErrorResponse			"User is not active"
+//	@Failure		501	{object}	ErrorResponse			"No JWT secret configured"
+//	@Security		AdminAuth
+//	@Router			/admin/users/{id}/impersonate [post]
+func (h *Handler) ImpersonateUser(w http.ResponseWriter, r *http.Request) {
+	if h.tokens == nil {
+		jsonapi.WriteError(w, jsonapi.NewError(http.StatusNotImplemented, "not_implemented", "Not Implemented").
+			Detail("Impersonation requires auth.jwt_secret to be set").Build())
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	user, err := h.users.Get(r.Context(), id)
+	if err != nil {
+		jsonapi.WriteNotFound(w, "user")
+		return
+	}
+	if user.Status != "active" {
+		jsonapi.WriteConflict(w, "Only active users can be impersonated")
+		return
+	}
+
+	adminID, _ := r.Context().Value(ctxUserIDKey).(string)
+	token, expiresAt, err := h.tokens.GenerateImpersonationToken(user.ID, user.Email, adminID, impersonationTTL)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to create impersonation token")
+		jsonapi.WriteInternalError(w, "Failed to impersonate user")
+		return
+	}
+
+	h.logger.Warn().
+		Str("audit", "impersonate").
+		Str("admin_id", adminID).
+		Str("target_user_id", user.ID).
+		Time("at", time.Now().UTC()).
+		Time("expires_at", expiresAt).
+		Msg("admin started impersonating user")
+	h.recordAudit(r, audit.ActionImpersonate, audit.ResourceUser, user.ID, nil, map[string]any{"expires_at": expiresAt.Format(time.RFC3339)})
+
+	jsonapi.WriteMeta(w, http.StatusCreated, jsonapi.Meta{
+		"user_id":    user.ID,
+		"token":      token,
+		"expires_at": expiresAt.Format(time.RFC3339),
+		"url":        "/portal/impersonate?token=" + url.QueryEscape(token),
+	})
+}
@@ -0,0 +1,130 @@
+package admin_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/auth"
+	"github.com/artpar/apigate/adapters/hasher"
+	"github.com/artpar/apigate/adapters/http/admin"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+)
+
+const testJWTSecret = "impersonation-test-secret"
+
+// setupHandlerWithJWT creates a handler that shares a JWT secret with the portal.
+func setupHandlerWithJWT(t *testing.T) (*admin.Handler, string, *memory.UserStore) {
+	t.Helper()
+
+	userStore := memory.NewUserStore()
+	keyStore := memory.NewKeyStore()
+	now := time.Now().UTC()
+
+	userStore.Create(context.Background(), ports.User{ID: "user_admin", Email: "admin@test.com", Status: "active", CreatedAt: now, UpdatedAt: now})
+	userStore.Create(context.Background(), ports.User{ID: "user_target", Email: "target@test.com", Status: "active", CreatedAt: now, UpdatedAt: now})
+	userStore.Create(context.Background(), ports.User{ID: "user_suspended", Email: "suspended@test.com", Status: "suspended", CreatedAt: now, UpdatedAt: now})
+
+	rawKey, keyData := key.Generate("ak_")
+	keyStore.Create(context.Background(), keyData.WithUserID("user_admin"))
+
+	h := admin.NewHandler(admin.Deps{
+		Users:     userStore,
+		Keys:      keyStore,
+		Plans:     newMockPlanStore(),
+		Logger:    zerolog.Nop(),
+		Hasher:    hasher.NewBcrypt(4),
+		JWTSecret: testJWTSecret,
+	})
+	return h, rawKey, userStore
+}
+
+func doBearerRequest(h *admin.Handler, method, path, token string) *http.Response {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	h.Router().ServeHTTP(rec, req)
+	return rec.Result()
+}
+
+func TestImpersonateUser(t *testing.T) {
+	h, rawKey, _ := setupHandlerWithJWT(t)
+
+	resp := doRequest(t, h, "POST", "/users/user_target/impersonate", nil, rawKey)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", resp.StatusCode)
+	}
+	var result map[string]any
+	json.NewDecoder(resp.Body).Decode(&result)
+	meta, _ := result["meta"].(map[string]any)
+	token, _ := meta["token"].(string)
+	if token == "" {
+		t.Fatalf("meta = %v, want a token", meta)
+	}
+	if meta["url"] != "/portal/impersonate?token="+token {
+		t.Errorf("url = %v", meta["url"])
+	}
+
+	// The minted session resolves to the target user and is flagged as impersonated
+	claims, err := auth.NewTokenService(testJWTSecret, time.Hour).ValidateToken(token)
+	if err != nil {
+		t.Fatalf("Minted token does not validate: %v", err)
+	}
+	if claims.UserID != "user_target" || claims.Role != "user" {
+		t.Errorf("claims = %+v, want user_target with role user", claims)
+	}
+	if claims.ImpersonatorID != "admin" {
+		t.Errorf("ImpersonatorID = %q, want admin", claims.ImpersonatorID)
+	}
+	if time.Until(claims.ExpiresAt.Time) > 15*time.Minute {
+		t.Errorf("Impersonation session expires at %v, want at most 15 minutes", claims.ExpiresAt.Time)
+	}
+
+	// The impersonated session does not grant admin access
+	if resp := doBearerRequest(h, "GET", "/users", token); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Admin API with impersonation token: expected 401, got %d", resp.StatusCode)
+	}
+}
+
+func TestImpersonateUser_RequiresAdmin(t *testing.T) {
+	h, _, _ := setupHandlerWithJWT(t)
+	tokens := auth.NewTokenService(testJWTSecret, time.Hour)
+
+	if resp := doRequest(t, h, "POST", "/users/user_target/impersonate", nil, ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Unauthenticated: expected 401, got %d", resp.StatusCode)
+	}
+
+	// A portal user's session is signed with the same secret but is not an admin
+	userToken, _, _ := tokens.GenerateToken("user_target", "target@test.com", "user")
+	if resp := doBearerRequest(h, "POST", "/users/user_admin/impersonate", userToken); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Portal user token: expected 401, got %d", resp.StatusCode)
+	}
+
+	adminToken, _, _ := tokens.GenerateToken("user_admin", "admin@test.com", "admin")
+	if resp := doBearerRequest(h, "POST", "/users/user_target/impersonate", adminToken); resp.StatusCode != http.StatusCreated {
+		t.Errorf("Admin token: expected 201, got %d", resp.StatusCode)
+	}
+}
+
+func TestImpersonateUser_Errors(t *testing.T) {
+	h, rawKey, _ := setupHandlerWithJWT(t)
+
+	if resp := doRequest(t, h, "POST", "/users/missing/impersonate", nil, rawKey); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Unknown user: expected 404, got %d", resp.StatusCode)
+	}
+	if resp := doRequest(t, h, "POST", "/users/user_suspended/impersonate", nil, rawKey); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Suspended user: expected 409, got %d", resp.StatusCode)
+	}
+
+	// Without a shared JWT secret the portal could not accept the session
+	noSecret, rawKey := setupHandler(t)
+	if resp := doRequest(t, noSecret, "POST", "/users/user_admin/impersonate", nil, rawKey); resp.StatusCode != http.StatusNotImplemented {
+		t.Errorf("No JWT secret: expected 501, got %d", resp.StatusCode)
+	}
+}
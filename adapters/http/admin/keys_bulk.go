@@ -0,0 +1,342 @@
+package admin
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/artpar/apigate/domain/audit"
+	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/pkg/jsonapi"
+	"github.com/artpar/apigate/ports"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// maxKeyImportBatch is the largest number of keys accepted in one import.
+const maxKeyImportBatch = 10000
+
+// keyExportPageSize is how many users are read per page while exporting keys.
+const keyExportPageSize = 100
+
+// keyCSVColumns is the column order used for CSV import and export.
+// Import reads columns by header name, so only prefix, hash and user_id are required.
+var keyCSVColumns = []string{"id", "prefix", "hash", "user_id", "name", "scopes", "expires_at", "revoked_at", "created_at", "last_used"}
+
+// KeyRecord is a single key in a bulk import or export. Hash is only read on
+// import; exports never include it.
+type KeyRecord struct {
+	ID        string     `json:"id,omitempty"`
+	Prefix    string     `json:"prefix"`
+	Hash      string     `json:"hash,omitempty"` // bcrypt hash of the raw key
+	UserID    string     `json:"user_id"`
+	Name      string     `json:"name,omitempty"`
+	Scopes    []string   `json:"scopes,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	LastUsed  *time.Time `json:"last_used,omitempty"`
+}
+
+// KeyBatch is the JSON body of a bulk import and the JSON export format.
+type KeyBatch struct {
+	Keys []KeyRecord `json:"keys"`
+}
+
+// ImportKeys creates a batch of keys with existing hashes, e.g. when
+// migrating from another gateway. The whole batch is rejected if any record
+// is invalid or any prefix is already in use.
+//
+//	@Summary		Import keys
+//	@Description	Create a batch of API keys from existing hashes (JSON or CSV). All or nothing.
+//	@Tags			Admin - Keys
+//	@Accept			json,text/csv
+//	@Produce		json
+//	@Param			request	body		KeyBatch				true	"Keys to import"
+//	@Success		201		{object}	map[string]interface{}	"Number of keys imported"
+//	@Failure		400		{object}	ErrorResponse			"Invalid request"
+//	@Failure		409		{object}	ErrorResponse			"Duplicate key prefix"
+//	@Failure		422		{object}	ErrorResponse			"Invalid key record"
+//	@Security		AdminAuth
+//	@Router			/admin/keys/import [post]
+func (h *Handler) ImportKeys(w http.ResponseWriter, r *http.Request) {
+	batchStore, ok := h.keys.(ports.KeyBatchStore)
+	if !ok {
+		jsonapi.WriteError(w, jsonapi.NewError(http.StatusNotImplemented, "not_implemented", "Not Implemented").
+			Detail("The configured key store does not support bulk import").Build())
+		return
+	}
+
+	var records []KeyRecord
+	var err error
+	if isCSV(r.Header.Get("Content-Type")) {
+		records, err = parseKeyCSV(r.Body)
+	} else {
+		var batch KeyBatch
+		err = json.NewDecoder(r.Body).Decode(&batch)
+		records = batch.Keys
+	}
+	if err != nil {
+		jsonapi.WriteBadRequest(w, "Invalid import body: "+err.Error())
+		return
+	}
+	if len(records) == 0 {
+		jsonapi.WriteValidationError(w, "keys", "no keys to import")
+		return
+	}
+	if len(records) > maxKeyImportBatch {
+		jsonapi.WriteValidationError(w, "keys", fmt.Sprintf("at most %d keys can be imported at once", maxKeyImportBatch))
+		return
+	}
+
+	keys, field, err := h.recordsToKeys(r.Context(), records)
+	if err != nil {
+		jsonapi.WriteValidationError(w, field, err.Error())
+		return
+	}
+
+	if err := batchStore.CreateBatch(r.Context(), keys); err != nil {
+		if errors.Is(err, ports.ErrDuplicateKeyPrefix) {
+			jsonapi.WriteConflict(w, err.Error()+"; no keys were imported")
+			return
+		}
+		h.logger.Error().Err(err).Msg("failed to import keys")
+		jsonapi.WriteInternalError(w, "Failed to import keys")
+		return
+	}
+
+	h.logger.Info().Int("count", len(keys)).Msg("keys imported via admin api")
+	for _, k := range keys {
+		h.recordAudit(r, audit.ActionImport, audit.ResourceKey, k.ID, nil, audit.Snapshot(k))
+	}
+	jsonapi.WriteMeta(w, http.StatusCreated, jsonapi.Meta{"imported": len(keys)})
+}
+
+// recordsToKeys validates import records and converts them to keys. On
+// failure it returns the offending field, prefixed with the record index.
+func (h *Handler) recordsToKeys(ctx context.Context, records []KeyRecord) ([]key.Key, string, error) {
+	now := time.Now().UTC()
+	users := make(map[string]bool)
+	prefixes := make(map[string]bool, len(records))
+	keys := make([]key.Key, 0, len(records))
+
+	for i, rec := range records {
+		field := func(name string) string { return fmt.Sprintf("keys/%d/%s", i, name) }
+
+		if len(rec.Prefix) != 12 {
+			return nil, field("prefix"), fmt.Errorf("prefix must be the first 12 characters of the key, got %q", rec.Prefix)
+		}
+		if prefixes[rec.Prefix] {
+			return nil, field("prefix"), fmt.Errorf("prefix %s appears more than once in the batch", rec.Prefix)
+		}
+		prefixes[rec.Prefix] = true
+
+		if _, err := bcrypt.Cost([]byte(rec.Hash)); err != nil {
+			return nil, field("hash"), errors.New("hash must be a bcrypt hash of the key")
+		}
+
+		if rec.UserID == "" {
+			return nil, field("user_id"), errors.New("user_id is required")
+		}
+		if !users[rec.UserID] {
+			if _, err := h.users.Get(ctx, rec.UserID); err != nil {
+				return nil, field("user_id"), fmt.Errorf("user %s not found", rec.UserID)
+			}
+			users[rec.UserID] = true
+		}
+
+		k := key.Key{
+			ID:        rec.ID,
+			UserID:    rec.UserID,
+			Hash:      []byte(rec.Hash),
+			Prefix:    rec.Prefix,
+			Name:      rec.Name,
+			Scopes:    rec.Scopes,
+			ExpiresAt: rec.ExpiresAt,
+			RevokedAt: rec.RevokedAt,
+			LastUsed:  rec.LastUsed,
+			CreatedAt: now,
+		}
+		if k.ID == "" {
+			k.ID = key.NewID()
+		}
+		if rec.CreatedAt != nil {
+			k.CreatedAt = *rec.CreatedAt
+		}
+		keys = append(keys, k)
+	}
+
+	return keys, "", nil
+}
+
+// ExportKeys returns the metadata of all keys as JSON or CSV. Key hashes and
+// signing secrets are never exported.
+//
+//	@Summary		Export keys
+//	@Description	Export metadata of all API keys (no hashes or secrets)
+//	@Tags			Admin - Keys
+//	@Produce		json,text/csv
+//	@Param			format	query		string		false	"Output format: json (default) or csv"
+//	@Success		200		{object}	KeyBatch	"Key metadata"
+//	@Security		AdminAuth
+//	@Router			/admin/keys/export [get]
+func (h *Handler) ExportKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.allKeys(r.Context())
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to list keys for export")
+		jsonapi.WriteInternalError(w, "Failed to export keys")
+		return
+	}
+
+	records := make([]KeyRecord, len(keys))
+	for i, k := range keys {
+		createdAt := k.CreatedAt
+		records[i] = KeyRecord{
+			ID:        k.ID,
+			Prefix:    k.Prefix,
+			UserID:    k.UserID,
+			Name:      k.Name,
+			Scopes:    k.Scopes,
+			ExpiresAt: k.ExpiresAt,
+			RevokedAt: k.RevokedAt,
+			CreatedAt: &createdAt,
+			LastUsed:  k.LastUsed,
+		}
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="api_keys.json"`)
+		json.NewEncoder(w).Encode(KeyBatch{Keys: records})
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="api_keys.csv"`)
+		writeKeyCSV(w, records)
+	default:
+		jsonapi.WriteValidationError(w, "format", "format must be json or csv")
+	}
+}
+
+// allKeys returns the keys of all users.
+func (h *Handler) allKeys(ctx context.Context) ([]key.Key, error) {
+	var keys []key.Key
+	for offset := 0; ; offset += keyExportPageSize {
+		users, err := h.users.List(ctx, keyExportPageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			userKeys, err := h.keys.ListByUser(ctx, u.ID)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, userKeys...)
+		}
+		if len(users) < keyExportPageSize {
+			return keys, nil
+		}
+	}
+}
+
+func isCSV(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "text/csv"
+}
+
+// parseKeyCSV reads key records from CSV with a header row. Scopes are
+// space-separated and timestamps are RFC 3339.
+func parseKeyCSV(r io.Reader) ([]KeyRecord, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read CSV header: %w", err)
+	}
+	col := make(map[string]int, len(header))
+	for i, name := range header {
+		col[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"prefix", "hash", "user_id"} {
+		if _, ok := col[required]; !ok {
+			return nil, fmt.Errorf("CSV header is missing the %s column", required)
+		}
+	}
+
+	var records []KeyRecord
+	for line := 2; ; line++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		get := func(name string) string {
+			if i, ok := col[name]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+		timeCol := func(name string) (*time.Time, error) {
+			v := get(name)
+			if v == "" {
+				return nil, nil
+			}
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s must be an RFC 3339 timestamp", line, name)
+			}
+			return &t, nil
+		}
+
+		rec := KeyRecord{
+			ID:     get("id"),
+			Prefix: get("prefix"),
+			Hash:   get("hash"),
+			UserID: get("user_id"),
+			Name:   get("name"),
+			Scopes: strings.Fields(get("scopes")),
+		}
+		if rec.ExpiresAt, err = timeCol("expires_at"); err != nil {
+			return nil, err
+		}
+		if rec.RevokedAt, err = timeCol("revoked_at"); err != nil {
+			return nil, err
+		}
+		if rec.CreatedAt, err = timeCol("created_at"); err != nil {
+			return nil, err
+		}
+		if rec.LastUsed, err = timeCol("last_used"); err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// writeKeyCSV writes key records as CSV in keyCSVColumns order.
+func writeKeyCSV(w io.Writer, records []KeyRecord) {
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write(keyCSVColumns)
+	for _, rec := range records {
+		cw.Write([]string{
+			rec.ID, rec.Prefix, rec.Hash, rec.UserID, rec.Name, strings.Join(rec.Scopes, " "),
+			formatTime(rec.ExpiresAt), formatTime(rec.RevokedAt), formatTime(rec.CreatedAt), formatTime(rec.LastUsed),
+		})
+	}
+	cw.Flush()
+}
@@ -0,0 +1,221 @@
+package admin_test
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/http/admin"
+	"github.com/artpar/apigate/ports"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// importedKey returns a raw key and its bcrypt hash, as exported by another gateway.
+func importedKey(t *testing.T, raw string) (string, string) {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(raw), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hash key: %v", err)
+	}
+	return raw, string(hash)
+}
+
+func createTestUser(t *testing.T, h *admin.Handler, rawKey, email string) string {
+	t.Helper()
+	resp := doRequest(t, h, "POST", "/users", map[string]string{"email": email}, rawKey)
+	var user map[string]any
+	json.NewDecoder(resp.Body).Decode(&user)
+	return getResourceID(user)
+}
+
+func countKeys(t *testing.T, h *admin.Handler, rawKey string) int {
+	t.Helper()
+	resp := doRequest(t, h, "GET", "/keys", nil, rawKey)
+	var result map[string]any
+	json.NewDecoder(resp.Body).Decode(&result)
+	return len(getCollectionData(result))
+}
+
+func TestImportKeys(t *testing.T) {
+	h, rawKey := setupHandler(t)
+	userID := createTestUser(t, h, rawKey, "imported@test.com")
+
+	raw1, hash1 := importedKey(t, "ak_import1aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	_, hash2 := importedKey(t, "ak_import2bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+	expires := time.Now().Add(30 * 24 * time.Hour).UTC().Truncate(time.Second)
+
+	resp := doRequest(t, h, "POST", "/keys/import", admin.KeyBatch{Keys: []admin.KeyRecord{
+		{Prefix: raw1[:12], Hash: hash1, UserID: userID, Name: "Legacy 1", Scopes: []string{"read"}},
+		{Prefix: "ak_import2bb", Hash: hash2, UserID: userID, ExpiresAt: &expires},
+	}}, rawKey)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", resp.StatusCode)
+	}
+	var result map[string]any
+	json.NewDecoder(resp.Body).Decode(&result)
+	if meta, _ := result["meta"].(map[string]any); meta["imported"] != float64(2) {
+		t.Errorf("meta = %v, want imported 2", result["meta"])
+	}
+
+	// The imported hash validates the original raw key
+	if resp := doRequest(t, h, "GET", "/keys?user_id="+userID, nil, raw1); resp.StatusCode != http.StatusOK {
+		t.Errorf("request with imported key: expected 200, got %d", resp.StatusCode)
+	}
+	if n := countKeys(t, h, rawKey); n != 3 {
+		t.Errorf("Expected 3 keys after import, got %d", n)
+	}
+}
+
+func TestImportKeys_DuplicatePrefixRejectsBatch(t *testing.T) {
+	h, rawKey := setupHandler(t)
+	userID := createTestUser(t, h, rawKey, "dupes@test.com")
+	_, hash := importedKey(t, "ak_dupe00000000")
+
+	// The admin key's prefix is already in use
+	resp := doRequest(t, h, "POST", "/keys/import", admin.KeyBatch{Keys: []admin.KeyRecord{
+		{Prefix: "ak_fresh0000", Hash: hash, UserID: userID},
+		{Prefix: rawKey[:12], Hash: hash, UserID: userID},
+	}}, rawKey)
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("Expected 409, got %d", resp.StatusCode)
+	}
+	if n := countKeys(t, h, rawKey); n != 1 {
+		t.Errorf("Expected no keys imported from a rejected batch, have %d keys", n)
+	}
+
+	// Duplicates within the batch are rejected before anything is stored
+	resp = doRequest(t, h, "POST", "/keys/import", admin.KeyBatch{Keys: []admin.KeyRecord{
+		{Prefix: "ak_twice0000", Hash: hash, UserID: userID},
+		{Prefix: "ak_twice0000", Hash: hash, UserID: userID},
+	}}, rawKey)
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("In-batch duplicate: expected 422, got %d", resp.StatusCode)
+	}
+	if n := countKeys(t, h, rawKey); n != 1 {
+		t.Errorf("Expected no keys imported, have %d keys", n)
+	}
+}
+
+func TestImportKeys_InvalidRecords(t *testing.T) {
+	h, rawKey := setupHandler(t)
+	userID := createTestUser(t, h, rawKey, "invalid@test.com")
+	_, hash := importedKey(t, "ak_invalid000000")
+
+	tests := []struct {
+		name   string
+		record admin.KeyRecord
+	}{
+		{"short prefix", admin.KeyRecord{Prefix: "ak_x", Hash: hash, UserID: userID}},
+		{"plain text hash", admin.KeyRecord{Prefix: "ak_plain0000", Hash: "not-a-hash", UserID: userID}},
+		{"unknown user", admin.KeyRecord{Prefix: "ak_nouser000", Hash: hash, UserID: "missing"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := doRequest(t, h, "POST", "/keys/import", admin.KeyBatch{Keys: []admin.KeyRecord{tt.record}}, rawKey)
+			if resp.StatusCode != http.StatusUnprocessableEntity {
+				t.Errorf("Expected 422, got %d", resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestExportKeys_RoundTrip(t *testing.T) {
+	h, rawKey := setupHandler(t)
+	userID := createTestUser(t, h, rawKey, "roundtrip@test.com")
+	_, hash := importedKey(t, "ak_csv0000000000")
+
+	csvBody := "prefix,hash,user_id,name,scopes,expires_at\n" +
+		"ak_csv000001," + hash + "," + userID + ",From CSV,read write,2030-01-02T03:04:05Z\n" +
+		"ak_csv000002," + hash + "," + userID + ",,,\n"
+	req := httptest.NewRequest("POST", "/keys/import", strings.NewReader(csvBody))
+	req.Header.Set("Content-Type", "text/csv")
+	req.Header.Set("X-API-Key", rawKey)
+	rec := httptest.NewRecorder()
+	h.Router().ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("CSV import: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// JSON export contains the imported metadata but no hashes
+	resp := doRequest(t, h, "GET", "/keys/export", nil, rawKey)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Export: expected 200, got %d", resp.StatusCode)
+	}
+	var exported admin.KeyBatch
+	json.NewDecoder(resp.Body).Decode(&exported)
+
+	byPrefix := make(map[string]admin.KeyRecord)
+	for _, k := range exported.Keys {
+		if k.Hash != "" {
+			t.Errorf("Export of %s includes its hash", k.Prefix)
+		}
+		byPrefix[k.Prefix] = k
+	}
+	got, ok := byPrefix["ak_csv000001"]
+	if !ok {
+		t.Fatalf("Exported keys %v missing ak_csv000001", exported.Keys)
+	}
+	if got.UserID != userID || got.Name != "From CSV" || strings.Join(got.Scopes, ",") != "read,write" {
+		t.Errorf("Exported record = %+v", got)
+	}
+	if got.ExpiresAt == nil || !got.ExpiresAt.Equal(time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("Exported expires_at = %v", got.ExpiresAt)
+	}
+	if _, ok := byPrefix["ak_csv000002"]; !ok {
+		t.Error("Exported keys missing ak_csv000002")
+	}
+
+	// CSV export has the same keys and an empty hash column
+	resp = doRequest(t, h, "GET", "/keys/export?format=csv", nil, rawKey)
+	rows, err := csv.NewReader(resp.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Parse CSV export: %v", err)
+	}
+	if len(rows) != len(exported.Keys)+1 {
+		t.Fatalf("CSV export has %d rows, want header + %d", len(rows), len(exported.Keys))
+	}
+	for _, row := range rows[1:] {
+		if row[2] != "" {
+			t.Errorf("CSV export of %s includes its hash", row[1])
+		}
+		if row[1] == "ak_csv000001" && row[0] != got.ID {
+			t.Errorf("CSV id = %s, JSON id = %s", row[0], got.ID)
+		}
+	}
+}
+
+func TestExportKeys_AllUserPages(t *testing.T) {
+	h, rawKey, users := setupHandlerWithJWT(t)
+
+	// The oldest user is listed last, beyond the first pages of users
+	now := time.Now().UTC()
+	for i := 0; i < 1000; i++ {
+		users.Create(context.Background(), ports.User{
+			ID: fmt.Sprintf("user_bulk_%04d", i), Email: fmt.Sprintf("bulk%d@test.com", i), Status: "active",
+			CreatedAt: now.Add(time.Duration(i) * time.Second), UpdatedAt: now,
+		})
+	}
+	users.Create(context.Background(), ports.User{
+		ID: "user_oldest", Email: "oldest@test.com", Status: "active", CreatedAt: now.Add(-time.Hour), UpdatedAt: now,
+	})
+	resp := doRequest(t, h, "POST", "/keys", map[string]string{"user_id": "user_oldest", "name": "Oldest"}, rawKey)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Create key: expected 201, got %d", resp.StatusCode)
+	}
+
+	resp = doRequest(t, h, "GET", "/keys/export", nil, rawKey)
+	var exported admin.KeyBatch
+	json.NewDecoder(resp.Body).Decode(&exported)
+	for _, k := range exported.Keys {
+		if k.UserID == "user_oldest" {
+			return
+		}
+	}
+	t.Errorf("Export of %d keys is missing the oldest user's key", len(exported.Keys))
+}
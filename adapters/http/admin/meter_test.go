@@ -43,6 +43,10 @@ func (m *mockUsageStore) GetRecentRequests(ctx context.Context, userID string, l
 	return result, nil
 }
 
+func (m *mockUsageStore) GetTopPaths(ctx context.Context, userID string, start, end time.Time, limit int) ([]usage.PathUsage, error) {
+	return nil, nil
+}
+
 // mockUserStore implements ports.UserStore for testing
 type mockUserStore struct {
 	users map[string]ports.User
@@ -95,6 +99,10 @@ func (m *mockUserStore) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+func (m *mockUserStore) Restore(ctx context.Context, id string) error {
+	return nil
+}
+
 func (m *mockUserStore) List(ctx context.Context, limit, offset int) ([]ports.User, error) {
 	var result []ports.User
 	for _, u := range m.users {
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/artpar/apigate/domain/audit"
 	"github.com/artpar/apigate/pkg/jsonapi"
 	"github.com/artpar/apigate/ports"
 	"github.com/go-chi/chi/v5"
@@ -19,9 +20,14 @@ type PlanResponse struct {
 	Name               string  `json:"name"`
 	Description        string  `json:"description,omitempty"`
 	RateLimitPerMinute int     `json:"rate_limit_per_minute"`
+	MaxConcurrent      int     `json:"max_concurrent"`
+	SpikeThreshold     float64 `json:"spike_threshold"`
+	SpikeMinRequests   int     `json:"spike_min_requests"`
+	SpikeThrottleRPM   int     `json:"spike_throttle_rpm"`
 	RequestsPerMonth   int64   `json:"requests_per_month"`
 	PriceMonthly       float64 `json:"price_monthly"`
 	OveragePrice       float64 `json:"overage_price"`
+	AllowOverage       bool    `json:"allow_overage"`
 	StripePriceID      string  `json:"stripe_price_id,omitempty"`
 	PaddlePriceID      string  `json:"paddle_price_id,omitempty"`
 	LemonVariantID     string  `json:"lemon_variant_id,omitempty"`
@@ -37,9 +43,14 @@ type CreatePlanRequest struct {
 	Name               string  `json:"name"`
 	Description        string  `json:"description,omitempty"`
 	RateLimitPerMinute int     `json:"rate_limit_per_minute"`
+	MaxConcurrent      int     `json:"max_concurrent"`
+	SpikeThreshold     float64 `json:"spike_threshold"`
+	SpikeMinRequests   int     `json:"spike_min_requests"`
+	SpikeThrottleRPM   int     `json:"spike_throttle_rpm"`
 	RequestsPerMonth   int64   `json:"requests_per_month"`
 	PriceMonthly       float64 `json:"price_monthly"`
 	OveragePrice       float64 `json:"overage_price"`
+	AllowOverage       bool    `json:"allow_overage"`
 	StripePriceID      string  `json:"stripe_price_id,omitempty"`
 	PaddlePriceID      string  `json:"paddle_price_id,omitempty"`
 	LemonVariantID     string  `json:"lemon_variant_id,omitempty"`
@@ -52,9 +63,14 @@ type UpdatePlanRequest struct {
 	Name               string   `json:"name,omitempty"`
 	Description        string   `json:"description,omitempty"`
 	RateLimitPerMinute *int     `json:"rate_limit_per_minute,omitempty"`
+	MaxConcurrent      *int     `json:"max_concurrent,omitempty"`
+	SpikeThreshold     *float64 `json:"spike_threshold,omitempty"`
+	SpikeMinRequests   *int     `json:"spike_min_requests,omitempty"`
+	SpikeThrottleRPM   *int     `json:"spike_throttle_rpm,omitempty"`
 	RequestsPerMonth   *int64   `json:"requests_per_month,omitempty"`
 	PriceMonthly       *float64 `json:"price_monthly,omitempty"`
 	OveragePrice       *float64 `json:"overage_price,omitempty"`
+	AllowOverage       *bool    `json:"allow_overage,omitempty"`
 	StripePriceID      *string  `json:"stripe_price_id,omitempty"`
 	PaddlePriceID      *string  `json:"paddle_price_id,omitempty"`
 	LemonVariantID     *string  `json:"lemon_variant_id,omitempty"`
@@ -157,6 +173,11 @@ func (h *Handler) CreatePlan(w http.ResponseWriter, r *http.Request) {
 		Name:               req.Name,
 		Description:        req.Description,
 		RateLimitPerMinute: req.RateLimitPerMinute,
+		MaxConcurrent:      req.MaxConcurrent,
+		SpikeThreshold:     req.SpikeThreshold,
+		SpikeMinRequests:   req.SpikeMinRequests,
+		SpikeThrottleRPM:   req.SpikeThrottleRPM,
+		AllowOverage:       req.AllowOverage,
 		RequestsPerMonth:   req.RequestsPerMonth,
 		PriceMonthly:       int64(req.PriceMonthly * 100), // Convert to cents
 		OveragePrice:       int64(req.OveragePrice * 10000), // Convert to hundredths of cents
@@ -190,6 +211,7 @@ func (h *Handler) CreatePlan(w http.ResponseWriter, r *http.Request) {
 	}
 
 	h.logger.Info().Str("plan_id", plan.ID).Msg("plan created via admin api")
+	h.recordAudit(r, audit.ActionCreate, audit.ResourcePlan, plan.ID, nil, audit.Snapshot(plan))
 	jsonapi.WriteCreated(w, planToResource(plan), "/admin/plans/"+plan.ID)
 }
 
@@ -222,6 +244,7 @@ func (h *Handler) UpdatePlan(w http.ResponseWriter, r *http.Request) {
 		jsonapi.WriteBadRequest(w, "Invalid JSON body")
 		return
 	}
+	before := audit.Snapshot(plan)
 
 	if req.Name != "" {
 		plan.Name = req.Name
@@ -232,6 +255,21 @@ func (h *Handler) UpdatePlan(w http.ResponseWriter, r *http.Request) {
 	if req.RateLimitPerMinute != nil {
 		plan.RateLimitPerMinute = *req.RateLimitPerMinute
 	}
+	if req.MaxConcurrent != nil {
+		plan.MaxConcurrent = *req.MaxConcurrent
+	}
+	if req.SpikeThreshold != nil {
+		plan.SpikeThreshold = *req.SpikeThreshold
+	}
+	if req.SpikeMinRequests != nil {
+		plan.SpikeMinRequests = *req.SpikeMinRequests
+	}
+	if req.SpikeThrottleRPM != nil {
+		plan.SpikeThrottleRPM = *req.SpikeThrottleRPM
+	}
+	if req.AllowOverage != nil {
+		plan.AllowOverage = *req.AllowOverage
+	}
 	if req.RequestsPerMonth != nil {
 		plan.RequestsPerMonth = *req.RequestsPerMonth
 	}
@@ -278,6 +316,7 @@ func (h *Handler) UpdatePlan(w http.ResponseWriter, r *http.Request) {
 	}
 
 	h.logger.Info().Str("plan_id", plan.ID).Msg("plan updated via admin api")
+	h.recordAudit(r, audit.ActionUpdate, audit.ResourcePlan, plan.ID, before, audit.Snapshot(plan))
 	jsonapi.WriteResource(w, http.StatusOK, planToResource(plan))
 }
 
@@ -297,7 +336,8 @@ func (h *Handler) DeletePlan(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	id := chi.URLParam(r, "id")
 
-	if _, err := h.plans.Get(ctx, id); err != nil {
+	plan, err := h.plans.Get(ctx, id)
+	if err != nil {
 		jsonapi.WriteNotFound(w, "plan")
 		return
 	}
@@ -318,6 +358,7 @@ func (h *Handler) DeletePlan(w http.ResponseWriter, r *http.Request) {
 	}
 
 	h.logger.Info().Str("plan_id", id).Msg("plan deleted via admin api")
+	h.recordAudit(r, audit.ActionDelete, audit.ResourcePlan, id, audit.Snapshot(plan), nil)
 	jsonapi.WriteNoContent(w)
 }
 
@@ -327,9 +368,14 @@ func planToResource(p ports.Plan) jsonapi.Resource {
 		Attr("name", p.Name).
 		Attr("description", p.Description).
 		Attr("rate_limit_per_minute", p.RateLimitPerMinute).
+		Attr("max_concurrent", p.MaxConcurrent).
+		Attr("spike_threshold", p.SpikeThreshold).
+		Attr("spike_min_requests", p.SpikeMinRequests).
+		Attr("spike_throttle_rpm", p.SpikeThrottleRPM).
 		Attr("requests_per_month", p.RequestsPerMonth).
 		Attr("price_monthly", float64(p.PriceMonthly)/100).
 		Attr("overage_price", float64(p.OveragePrice)/10000).
+		Attr("allow_overage", p.AllowOverage).
 		Attr("stripe_price_id", p.StripePriceID).
 		Attr("paddle_price_id", p.PaddlePriceID).
 		Attr("lemon_variant_id", p.LemonVariantID).
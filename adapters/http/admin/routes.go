@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/artpar/apigate/core/openapi"
 	"github.com/artpar/apigate/domain/route"
 	"github.com/artpar/apigate/pkg/jsonapi"
 	"github.com/artpar/apigate/ports"
@@ -25,7 +26,8 @@ type RoutesHandler struct {
 	routes        ports.RouteStore
 	upstreams     ports.UpstreamStore
 	logger        zerolog.Logger
-	onRouteChange func() // Called when routes or upstreams change
+	onRouteChange func()              // Called when routes or upstreams change
+	reserved      route.ReservedPaths // Paths owned by built-in endpoints
 }
 
 // RoutesHandlerConfig holds configuration for the routes handler.
@@ -55,6 +57,12 @@ func NewRoutesHandlerWithConfig(cfg RoutesHandlerConfig) *RoutesHandler {
 	}
 }
 
+// SetReservedPaths sets the paths owned by built-in endpoints, which routes
+// may not claim.
+func (h *RoutesHandler) SetReservedPaths(reserved route.ReservedPaths) {
+	h.reserved = reserved
+}
+
 // notifyChange calls the route change callback if set.
 func (h *RoutesHandler) notifyChange() {
 	if h.onRouteChange != nil {
@@ -87,28 +95,52 @@ func (h *RoutesHandler) RegisterRoutes(r chi.Router) {
 
 // RouteResponse represents a route in API responses.
 type RouteResponse struct {
-	ID                string           `json:"id"`
-	Name              string           `json:"name"`
-	Description       string           `json:"description,omitempty"`
-	HostPattern       string           `json:"host_pattern,omitempty"`
-	HostMatchType     string           `json:"host_match_type,omitempty"`
-	PathPattern       string           `json:"path_pattern"`
-	MatchType         string           `json:"match_type"`
-	Methods           []string         `json:"methods,omitempty"`
-	Headers           []HeaderMatchDTO `json:"headers,omitempty"`
-	UpstreamID        string           `json:"upstream_id,omitempty"`
-	PathRewrite       string           `json:"path_rewrite,omitempty"`
-	MethodOverride    string           `json:"method_override,omitempty"`
-	RequestTransform  *TransformDTO    `json:"request_transform,omitempty"`
-	ResponseTransform *TransformDTO    `json:"response_transform,omitempty"`
-	MeteringExpr      string           `json:"metering_expr,omitempty"`
-	MeteringMode      string           `json:"metering_mode,omitempty"`
-	Protocol          string           `json:"protocol"`
-	AuthRequired      bool             `json:"auth_required"`
-	Priority          int              `json:"priority"`
-	Enabled           bool             `json:"enabled"`
-	CreatedAt         string           `json:"created_at"`
-	UpdatedAt         string           `json:"updated_at"`
+	ID                  string                `json:"id"`
+	Name                string                `json:"name"`
+	Description         string                `json:"description,omitempty"`
+	HostPattern         string                `json:"host_pattern,omitempty"`
+	HostMatchType       string                `json:"host_match_type,omitempty"`
+	PathPattern         string                `json:"path_pattern"`
+	MatchType           string                `json:"match_type"`
+	Methods             []string              `json:"methods,omitempty"`
+	Headers             []HeaderMatchDTO      `json:"headers,omitempty"`
+	QueryMatch          []QueryMatchDTO       `json:"query_match,omitempty"`
+	StripQuery          []string              `json:"strip_query,omitempty"`
+	UpstreamID          string                `json:"upstream_id,omitempty"`
+	Upstreams           []WeightedUpstreamDTO `json:"upstreams,omitempty"`
+	Canary              *CanaryDTO            `json:"canary,omitempty"`
+	MirrorUpstreamID    string                `json:"mirror_upstream_id,omitempty"`
+	StripPrefix         string                `json:"strip_prefix,omitempty"`
+	RewritePath         *PathRewriteDTO       `json:"rewrite_path,omitempty"`
+	PathRewrite         string                `json:"path_rewrite,omitempty"`
+	MethodOverride      string                `json:"method_override,omitempty"`
+	RequestTransform    *TransformDTO         `json:"request_transform,omitempty"`
+	ResponseTransform   *TransformDTO         `json:"response_transform,omitempty"`
+	RequestHeaders      []HeaderRuleDTO       `json:"request_headers,omitempty"`
+	ResponseHeaders     []HeaderRuleDTO       `json:"response_headers,omitempty"`
+	Retry               *RetryPolicyDTO       `json:"retry,omitempty"`
+	CORS                *CORSPolicyDTO        `json:"cors,omitempty"`
+	RewriteResponseURLs bool                  `json:"rewrite_response_urls,omitempty"`
+	MeteringExpr        string                `json:"metering_expr,omitempty"`
+	MeteringMode        string                `json:"metering_mode,omitempty"`
+	CostMultiplier      float64               `json:"cost_multiplier,omitempty"`
+	MethodCosts         map[string]float64    `json:"method_cost_multipliers,omitempty"`
+	Protocol            string                `json:"protocol"`
+	AuthRequired        bool                  `json:"auth_required"`
+	AuthMethod          string                `json:"auth_method,omitempty"`
+	RequiredScopes      []string              `json:"required_scopes,omitempty"`
+	MaxRequestBody      int64                 `json:"max_request_body,omitempty"`
+	MaxResponseBody     int64                 `json:"max_response_body,omitempty"`
+	RequestSchema       string                `json:"request_schema,omitempty"`
+	DialTimeoutMs       int64                 `json:"dial_timeout_ms,omitempty"`
+	HeaderTimeoutMs     int64                 `json:"response_header_timeout_ms,omitempty"`
+	RequestTimeoutMs    int64                 `json:"request_timeout_ms,omitempty"`
+	CacheTTLMs          int64                 `json:"cache_ttl_ms,omitempty"`
+	CacheKeyHeaders     []string              `json:"cache_key_headers,omitempty"`
+	Priority            int                   `json:"priority"`
+	Enabled             bool                  `json:"enabled"`
+	CreatedAt           string                `json:"created_at"`
+	UpdatedAt           string                `json:"updated_at"`
 }
 
 // HeaderMatchDTO represents a header match condition.
@@ -119,6 +151,20 @@ type HeaderMatchDTO struct {
 	Required bool   `json:"required,omitempty"`
 }
 
+// QueryMatchDTO represents a query parameter match condition.
+type QueryMatchDTO struct {
+	Name     string `json:"name"`
+	Value    string `json:"value,omitempty"`
+	IsRegex  bool   `json:"is_regex,omitempty"`
+	Required bool   `json:"required,omitempty"`
+}
+
+// WeightedUpstreamDTO represents a member of a route's upstream pool.
+type WeightedUpstreamDTO struct {
+	UpstreamID string `json:"upstream_id"`
+	Weight     int    `json:"weight"`
+}
+
 // TransformDTO represents request/response transformation.
 type TransformDTO struct {
 	SetHeaders    map[string]string `json:"set_headers,omitempty"`
@@ -128,50 +174,137 @@ type TransformDTO struct {
 	DeleteQuery   []string          `json:"delete_query,omitempty"`
 }
 
+// CanaryDTO represents a route's canary routing policy.
+type CanaryDTO struct {
+	UpstreamID string `json:"upstream_id"`
+	Percent    int    `json:"percent"`
+	HashBy     string `json:"hash_by,omitempty"`
+}
+
+// PathRewriteDTO represents a regex rewrite of the upstream path.
+type PathRewriteDTO struct {
+	Regex       string `json:"regex"`
+	Replacement string `json:"replacement"`
+}
+
+// HeaderRuleDTO represents a request or response header rule.
+type HeaderRuleDTO struct {
+	Op    string `json:"op"`
+	Name  string `json:"name"`
+	Value string `json:"value,omitempty"`
+	To    string `json:"to,omitempty"`
+}
+
+// RetryPolicyDTO represents a route's retry policy.
+type RetryPolicyDTO struct {
+	Attempts  int      `json:"attempts"`
+	BackoffMs int64    `json:"backoff_ms,omitempty"`
+	Methods   []string `json:"methods,omitempty"`
+	Statuses  []int    `json:"statuses,omitempty"`
+}
+
+// CORSPolicyDTO represents a route's CORS policy.
+type CORSPolicyDTO struct {
+	AllowedOrigins   []string `json:"allowed_origins"`
+	AllowedMethods   []string `json:"allowed_methods,omitempty"`
+	AllowedHeaders   []string `json:"allowed_headers,omitempty"`
+	ExposedHeaders   []string `json:"exposed_headers,omitempty"`
+	AllowCredentials bool     `json:"allow_credentials,omitempty"`
+	MaxAgeSeconds    int      `json:"max_age_seconds,omitempty"`
+}
+
 // CreateRouteRequest represents a request to create a route.
 type CreateRouteRequest struct {
-	Name              string           `json:"name"`
-	Description       string           `json:"description,omitempty"`
-	HostPattern       string           `json:"host_pattern,omitempty"`
-	HostMatchType     string           `json:"host_match_type,omitempty"`
-	PathPattern       string           `json:"path_pattern"`
-	MatchType         string           `json:"match_type,omitempty"`
-	Methods           []string         `json:"methods,omitempty"`
-	Headers           []HeaderMatchDTO `json:"headers,omitempty"`
-	UpstreamID        string           `json:"upstream_id,omitempty"`
-	PathRewrite       string           `json:"path_rewrite,omitempty"`
-	MethodOverride    string           `json:"method_override,omitempty"`
-	RequestTransform  *TransformDTO    `json:"request_transform,omitempty"`
-	ResponseTransform *TransformDTO    `json:"response_transform,omitempty"`
-	MeteringExpr      string           `json:"metering_expr,omitempty"`
-	MeteringMode      string           `json:"metering_mode,omitempty"`
-	Protocol          string           `json:"protocol,omitempty"`
-	AuthRequired      *bool            `json:"auth_required,omitempty"`
-	Priority          int              `json:"priority,omitempty"`
-	Enabled           *bool            `json:"enabled,omitempty"`
+	Name                string                `json:"name"`
+	Description         string                `json:"description,omitempty"`
+	HostPattern         string                `json:"host_pattern,omitempty"`
+	HostMatchType       string                `json:"host_match_type,omitempty"`
+	PathPattern         string                `json:"path_pattern"`
+	MatchType           string                `json:"match_type,omitempty"`
+	Methods             []string              `json:"methods,omitempty"`
+	Headers             []HeaderMatchDTO      `json:"headers,omitempty"`
+	QueryMatch          []QueryMatchDTO       `json:"query_match,omitempty"`
+	StripQuery          []string              `json:"strip_query,omitempty"`
+	UpstreamID          string                `json:"upstream_id,omitempty"`
+	Upstreams           []WeightedUpstreamDTO `json:"upstreams,omitempty"`
+	Canary              *CanaryDTO            `json:"canary,omitempty"`
+	MirrorUpstreamID    string                `json:"mirror_upstream_id,omitempty"`
+	StripPrefix         string                `json:"strip_prefix,omitempty"`
+	RewritePath         *PathRewriteDTO       `json:"rewrite_path,omitempty"`
+	PathRewrite         string                `json:"path_rewrite,omitempty"`
+	MethodOverride      string                `json:"method_override,omitempty"`
+	RequestTransform    *TransformDTO         `json:"request_transform,omitempty"`
+	ResponseTransform   *TransformDTO         `json:"response_transform,omitempty"`
+	RequestHeaders      []HeaderRuleDTO       `json:"request_headers,omitempty"`
+	ResponseHeaders     []HeaderRuleDTO       `json:"response_headers,omitempty"`
+	Retry               *RetryPolicyDTO       `json:"retry,omitempty"`
+	CORS                *CORSPolicyDTO        `json:"cors,omitempty"`
+	RewriteResponseURLs bool                  `json:"rewrite_response_urls,omitempty"`
+	MeteringExpr        string                `json:"metering_expr,omitempty"`
+	MeteringMode        string                `json:"metering_mode,omitempty"`
+	CostMultiplier      float64               `json:"cost_multiplier,omitempty"`
+	MethodCosts         map[string]float64    `json:"method_cost_multipliers,omitempty"`
+	Protocol            string                `json:"protocol,omitempty"`
+	AuthRequired        *bool                 `json:"auth_required,omitempty"`
+	AuthMethod          string                `json:"auth_method,omitempty"`
+	RequiredScopes      []string              `json:"required_scopes,omitempty"`
+	MaxRequestBody      int64                 `json:"max_request_body,omitempty"`
+	MaxResponseBody     int64                 `json:"max_response_body,omitempty"`
+	RequestSchema       string                `json:"request_schema,omitempty"`
+	DialTimeoutMs       int64                 `json:"dial_timeout_ms,omitempty"`
+	HeaderTimeoutMs     int64                 `json:"response_header_timeout_ms,omitempty"`
+	RequestTimeoutMs    int64                 `json:"request_timeout_ms,omitempty"`
+	CacheTTLMs          int64                 `json:"cache_ttl_ms,omitempty"`
+	CacheKeyHeaders     []string              `json:"cache_key_headers,omitempty"`
+	Priority            int                   `json:"priority,omitempty"`
+	Enabled             *bool                 `json:"enabled,omitempty"`
 }
 
 // UpdateRouteRequest represents a request to update a route.
 type UpdateRouteRequest struct {
-	Name              *string          `json:"name,omitempty"`
-	Description       *string          `json:"description,omitempty"`
-	HostPattern       *string          `json:"host_pattern,omitempty"`
-	HostMatchType     *string          `json:"host_match_type,omitempty"`
-	PathPattern       *string          `json:"path_pattern,omitempty"`
-	MatchType         *string          `json:"match_type,omitempty"`
-	Methods           []string         `json:"methods,omitempty"`
-	Headers           []HeaderMatchDTO `json:"headers,omitempty"`
-	UpstreamID        *string          `json:"upstream_id,omitempty"`
-	PathRewrite       *string          `json:"path_rewrite,omitempty"`
-	MethodOverride    *string          `json:"method_override,omitempty"`
-	RequestTransform  *TransformDTO    `json:"request_transform,omitempty"`
-	ResponseTransform *TransformDTO    `json:"response_transform,omitempty"`
-	MeteringExpr      *string          `json:"metering_expr,omitempty"`
-	MeteringMode      *string          `json:"metering_mode,omitempty"`
-	Protocol          *string          `json:"protocol,omitempty"`
-	AuthRequired      *bool            `json:"auth_required,omitempty"`
-	Priority          *int             `json:"priority,omitempty"`
-	Enabled           *bool            `json:"enabled,omitempty"`
+	Name                *string               `json:"name,omitempty"`
+	Description         *string               `json:"description,omitempty"`
+	HostPattern         *string               `json:"host_pattern,omitempty"`
+	HostMatchType       *string               `json:"host_match_type,omitempty"`
+	PathPattern         *string               `json:"path_pattern,omitempty"`
+	MatchType           *string               `json:"match_type,omitempty"`
+	Methods             []string              `json:"methods,omitempty"`
+	Headers             []HeaderMatchDTO      `json:"headers,omitempty"`
+	QueryMatch          []QueryMatchDTO       `json:"query_match,omitempty"`
+	StripQuery          []string              `json:"strip_query,omitempty"`
+	UpstreamID          *string               `json:"upstream_id,omitempty"`
+	Upstreams           []WeightedUpstreamDTO `json:"upstreams,omitempty"`
+	Canary              *CanaryDTO            `json:"canary,omitempty"`
+	MirrorUpstreamID    *string               `json:"mirror_upstream_id,omitempty"`
+	StripPrefix         *string               `json:"strip_prefix,omitempty"`
+	RewritePath         *PathRewriteDTO       `json:"rewrite_path,omitempty"`
+	PathRewrite         *string               `json:"path_rewrite,omitempty"`
+	MethodOverride      *string               `json:"method_override,omitempty"`
+	RequestTransform    *TransformDTO         `json:"request_transform,omitempty"`
+	ResponseTransform   *TransformDTO         `json:"response_transform,omitempty"`
+	RequestHeaders      []HeaderRuleDTO       `json:"request_headers,omitempty"`
+	ResponseHeaders     []HeaderRuleDTO       `json:"response_headers,omitempty"`
+	Retry               *RetryPolicyDTO       `json:"retry,omitempty"`
+	CORS                *CORSPolicyDTO        `json:"cors,omitempty"`
+	RewriteResponseURLs *bool                 `json:"rewrite_response_urls,omitempty"`
+	MeteringExpr        *string               `json:"metering_expr,omitempty"`
+	MeteringMode        *string               `json:"metering_mode,omitempty"`
+	CostMultiplier      *float64              `json:"cost_multiplier,omitempty"`
+	MethodCosts         map[string]float64    `json:"method_cost_multipliers,omitempty"`
+	Protocol            *string               `json:"protocol,omitempty"`
+	AuthRequired        *bool                 `json:"auth_required,omitempty"`
+	AuthMethod          *string               `json:"auth_method,omitempty"`
+	RequiredScopes      []string              `json:"required_scopes,omitempty"`
+	MaxRequestBody      *int64                `json:"max_request_body,omitempty"`
+	MaxResponseBody     *int64                `json:"max_response_body,omitempty"`
+	RequestSchema       *string               `json:"request_schema,omitempty"`
+	DialTimeoutMs       *int64                `json:"dial_timeout_ms,omitempty"`
+	HeaderTimeoutMs     *int64                `json:"response_header_timeout_ms,omitempty"`
+	RequestTimeoutMs    *int64                `json:"request_timeout_ms,omitempty"`
+	CacheTTLMs          *int64                `json:"cache_ttl_ms,omitempty"`
+	CacheKeyHeaders     []string              `json:"cache_key_headers,omitempty"`
+	Priority            *int                  `json:"priority,omitempty"`
+	Enabled             *bool                 `json:"enabled,omitempty"`
 }
 
 // -----------------------------------------------------------------------------
@@ -236,26 +369,46 @@ func (h *RoutesHandler) CreateRoute(w http.ResponseWriter, r *http.Request) {
 
 	now := time.Now().UTC()
 	rt := route.Route{
-		ID:             generateRouteID(),
-		Name:           req.Name,
-		Description:    req.Description,
-		HostPattern:    req.HostPattern,
-		HostMatchType:  route.HostMatchType(req.HostMatchType),
-		PathPattern:    req.PathPattern,
-		MatchType:      route.MatchType(req.MatchType),
-		Methods:        req.Methods,
-		Headers:        dtoToHeaderMatches(req.Headers),
-		UpstreamID:     req.UpstreamID,
-		PathRewrite:    req.PathRewrite,
-		MethodOverride: req.MethodOverride,
-		MeteringExpr:   req.MeteringExpr,
-		MeteringMode:   req.MeteringMode,
-		Protocol:       route.Protocol(req.Protocol),
-		AuthRequired:   true, // Default to requiring authentication
-		Priority:       req.Priority,
-		Enabled:        true,
-		CreatedAt:      now,
-		UpdatedAt:      now,
+		ID:                    generateRouteID(),
+		Name:                  req.Name,
+		Description:           req.Description,
+		HostPattern:           req.HostPattern,
+		HostMatchType:         route.HostMatchType(req.HostMatchType),
+		PathPattern:           req.PathPattern,
+		MatchType:             route.MatchType(req.MatchType),
+		Methods:               req.Methods,
+		Headers:               dtoToHeaderMatches(req.Headers),
+		QueryMatch:            dtoToQueryMatches(req.QueryMatch),
+		StripQuery:            req.StripQuery,
+		UpstreamID:            req.UpstreamID,
+		Upstreams:             dtoToWeightedUpstreams(req.Upstreams),
+		Canary:                dtoToCanary(req.Canary),
+		MirrorUpstreamID:      req.MirrorUpstreamID,
+		StripPrefix:           req.StripPrefix,
+		RewritePath:           dtoToPathRewrite(req.RewritePath),
+		PathRewrite:           req.PathRewrite,
+		MethodOverride:        req.MethodOverride,
+		RewriteResponseURLs:   req.RewriteResponseURLs,
+		MeteringExpr:          req.MeteringExpr,
+		MeteringMode:          req.MeteringMode,
+		CostMultiplier:        req.CostMultiplier,
+		MethodCostMultipliers: req.MethodCosts,
+		Protocol:              route.Protocol(req.Protocol),
+		AuthRequired:          true, // Default to requiring authentication
+		AuthMethod:            route.AuthMethod(req.AuthMethod),
+		RequiredScopes:        req.RequiredScopes,
+		MaxRequestBody:        req.MaxRequestBody,
+		MaxResponseBody:       req.MaxResponseBody,
+		RequestSchema:         req.RequestSchema,
+		DialTimeout:           time.Duration(req.DialTimeoutMs) * time.Millisecond,
+		ResponseHeaderTimeout: time.Duration(req.HeaderTimeoutMs) * time.Millisecond,
+		RequestTimeout:        time.Duration(req.RequestTimeoutMs) * time.Millisecond,
+		CacheTTL:              time.Duration(req.CacheTTLMs) * time.Millisecond,
+		CacheKeyHeaders:       req.CacheKeyHeaders,
+		Priority:              req.Priority,
+		Enabled:               true,
+		CreatedAt:             now,
+		UpdatedAt:             now,
 	}
 
 	if req.AuthRequired != nil {
@@ -280,6 +433,13 @@ func (h *RoutesHandler) CreateRoute(w http.ResponseWriter, r *http.Request) {
 	if req.ResponseTransform != nil {
 		rt.ResponseTransform = dtoToTransform(req.ResponseTransform)
 	}
+	rt.RequestHeaders = dtoToHeaderRules(req.RequestHeaders)
+	rt.ResponseHeaders = dtoToHeaderRules(req.ResponseHeaders)
+	rt.Retry = dtoToRetryPolicy(req.Retry)
+	rt.CORS = dtoToCORSPolicy(req.CORS)
+	if !h.validateReservedPath(w, rt) || !validateHostPattern(w, rt) || !validatePathRewrite(w, rt) || !validateQueryMatches(w, rt) || !validateCanary(w, rt) || !validateRequestSchema(w, rt) || !validateHeaderRules(w, rt) || !validateRetryPolicy(w, rt) || !validateCORSPolicy(w, rt) || !validateCostMultipliers(w, rt) {
+		return
+	}
 
 	if err := h.routes.Create(r.Context(), rt); err != nil {
 		h.logger.Error().Err(err).Msg("failed to create route")
@@ -371,9 +531,30 @@ func (h *RoutesHandler) UpdateRoute(w http.ResponseWriter, r *http.Request) {
 	if req.Headers != nil {
 		rt.Headers = dtoToHeaderMatches(req.Headers)
 	}
+	if req.QueryMatch != nil {
+		rt.QueryMatch = dtoToQueryMatches(req.QueryMatch)
+	}
+	if req.StripQuery != nil {
+		rt.StripQuery = req.StripQuery
+	}
+	if req.Upstreams != nil {
+		rt.Upstreams = dtoToWeightedUpstreams(req.Upstreams)
+	}
+	if req.Canary != nil {
+		rt.Canary = dtoToCanary(req.Canary)
+	}
+	if req.MirrorUpstreamID != nil {
+		rt.MirrorUpstreamID = *req.MirrorUpstreamID
+	}
 	if req.UpstreamID != nil {
 		rt.UpstreamID = *req.UpstreamID
 	}
+	if req.StripPrefix != nil {
+		rt.StripPrefix = *req.StripPrefix
+	}
+	if req.RewritePath != nil {
+		rt.RewritePath = dtoToPathRewrite(req.RewritePath)
+	}
 	if req.PathRewrite != nil {
 		rt.PathRewrite = *req.PathRewrite
 	}
@@ -386,18 +567,69 @@ func (h *RoutesHandler) UpdateRoute(w http.ResponseWriter, r *http.Request) {
 	if req.ResponseTransform != nil {
 		rt.ResponseTransform = dtoToTransform(req.ResponseTransform)
 	}
+	if req.RequestHeaders != nil {
+		rt.RequestHeaders = dtoToHeaderRules(req.RequestHeaders)
+	}
+	if req.ResponseHeaders != nil {
+		rt.ResponseHeaders = dtoToHeaderRules(req.ResponseHeaders)
+	}
+	if req.Retry != nil {
+		rt.Retry = dtoToRetryPolicy(req.Retry)
+	}
+	if req.CORS != nil {
+		rt.CORS = dtoToCORSPolicy(req.CORS)
+	}
+	if req.RewriteResponseURLs != nil {
+		rt.RewriteResponseURLs = *req.RewriteResponseURLs
+	}
 	if req.MeteringExpr != nil {
 		rt.MeteringExpr = *req.MeteringExpr
 	}
 	if req.MeteringMode != nil {
 		rt.MeteringMode = *req.MeteringMode
 	}
+	if req.CostMultiplier != nil {
+		rt.CostMultiplier = *req.CostMultiplier
+	}
+	if req.MethodCosts != nil {
+		rt.MethodCostMultipliers = req.MethodCosts
+	}
 	if req.Protocol != nil {
 		rt.Protocol = route.Protocol(*req.Protocol)
 	}
 	if req.AuthRequired != nil {
 		rt.AuthRequired = *req.AuthRequired
 	}
+	if req.AuthMethod != nil {
+		rt.AuthMethod = route.AuthMethod(*req.AuthMethod)
+	}
+	if req.RequiredScopes != nil {
+		rt.RequiredScopes = req.RequiredScopes
+	}
+	if req.MaxRequestBody != nil {
+		rt.MaxRequestBody = *req.MaxRequestBody
+	}
+	if req.MaxResponseBody != nil {
+		rt.MaxResponseBody = *req.MaxResponseBody
+	}
+	if req.RequestSchema != nil {
+		rt.RequestSchema = *req.RequestSchema
+	}
+	if req.DialTimeoutMs != nil {
+		rt.DialTimeout = time.Duration(*req.DialTimeoutMs) * time.Millisecond
+	}
+	if req.HeaderTimeoutMs != nil {
+		rt.ResponseHeaderTimeout = time.Duration(*req.HeaderTimeoutMs) * time.Millisecond
+	}
+	if req.RequestTimeoutMs != nil {
+		rt.RequestTimeout = time.Duration(*req.RequestTimeoutMs) * time.Millisecond
+	}
+	if req.CacheTTLMs != nil {
+		rt.CacheTTL = time.Duration(*req.CacheTTLMs) * time.Millisecond
+	}
+	if req.CacheKeyHeaders != nil {
+		rt.CacheKeyHeaders = req.CacheKeyHeaders
+	}
 	if req.Priority != nil {
 		rt.Priority = *req.Priority
 	}
@@ -405,6 +637,10 @@ func (h *RoutesHandler) UpdateRoute(w http.ResponseWriter, r *http.Request) {
 		rt.Enabled = *req.Enabled
 	}
 
+	if !h.validateReservedPath(w, rt) || !validateHostPattern(w, rt) || !validatePathRewrite(w, rt) || !validateQueryMatches(w, rt) || !validateCanary(w, rt) || !validateRequestSchema(w, rt) || !validateHeaderRules(w, rt) || !validateRetryPolicy(w, rt) || !validateCORSPolicy(w, rt) || !validateCostMultipliers(w, rt) {
+		return
+	}
+
 	rt.UpdatedAt = time.Now().UTC()
 
 	if err := h.routes.Update(r.Context(), rt); err != nil {
@@ -449,46 +685,93 @@ func (h *RoutesHandler) DeleteRoute(w http.ResponseWriter, r *http.Request) {
 
 // UpstreamResponse represents an upstream in API responses.
 type UpstreamResponse struct {
-	ID              string `json:"id"`
-	Name            string `json:"name"`
-	Description     string `json:"description,omitempty"`
-	BaseURL         string `json:"base_url"`
-	TimeoutMs       int64  `json:"timeout_ms"`
-	MaxIdleConns    int    `json:"max_idle_conns"`
-	IdleConnTimeout int64  `json:"idle_conn_timeout_ms"`
-	AuthType        string `json:"auth_type"`
-	AuthHeader      string `json:"auth_header,omitempty"`
-	Enabled         bool   `json:"enabled"`
-	CreatedAt       string `json:"created_at"`
-	UpdatedAt       string `json:"updated_at"`
+	ID                        string  `json:"id"`
+	Name                      string  `json:"name"`
+	Description               string  `json:"description,omitempty"`
+	BaseURL                   string  `json:"base_url"`
+	TimeoutMs                 int64   `json:"timeout_ms"`
+	MaxIdleConns              int     `json:"max_idle_conns"`
+	IdleConnTimeout           int64   `json:"idle_conn_timeout_ms"`
+	FailureThreshold          int     `json:"failure_threshold"`
+	EjectionCooldownMs        int64   `json:"ejection_cooldown_ms"`
+	HealthCheckPath           string  `json:"health_check_path,omitempty"`
+	HealthCheckIntervalMs     int64   `json:"health_check_interval_ms"`
+	HealthCheckTimeoutMs      int64   `json:"health_check_timeout_ms"`
+	HealthCheckExpectedStatus int     `json:"health_check_expected_status"`
+	CircuitFailureRate        float64 `json:"circuit_failure_rate"`
+	CircuitWindowMs           int64   `json:"circuit_window_ms"`
+	CircuitMinRequests        int     `json:"circuit_min_requests"`
+	CircuitOpenMs             int64   `json:"circuit_open_ms"`
+	CircuitHalfOpenTrials     int     `json:"circuit_half_open_trials"`
+	AuthType                  string  `json:"auth_type"`
+	AuthHeader                string  `json:"auth_header,omitempty"`
+	TLSCACert                 string  `json:"tls_ca_cert,omitempty"`
+	TLSClientCert             string  `json:"tls_client_cert,omitempty"`
+	TLSServerName             string  `json:"tls_server_name,omitempty"`
+	TLSInsecureSkipVerify     bool    `json:"tls_insecure_skip_verify"`
+	Enabled                   bool    `json:"enabled"`
+	CreatedAt                 string  `json:"created_at"`
+	UpdatedAt                 string  `json:"updated_at"`
 }
 
 // CreateUpstreamRequest represents a request to create an upstream.
 type CreateUpstreamRequest struct {
-	Name            string `json:"name"`
-	Description     string `json:"description,omitempty"`
-	BaseURL         string `json:"base_url"`
-	TimeoutMs       int64  `json:"timeout_ms,omitempty"`
-	MaxIdleConns    int    `json:"max_idle_conns,omitempty"`
-	IdleConnTimeout int64  `json:"idle_conn_timeout_ms,omitempty"`
-	AuthType        string `json:"auth_type,omitempty"`
-	AuthHeader      string `json:"auth_header,omitempty"`
-	AuthValue       string `json:"auth_value,omitempty"`
-	Enabled         *bool  `json:"enabled,omitempty"`
+	Name                      string  `json:"name"`
+	Description               string  `json:"description,omitempty"`
+	BaseURL                   string  `json:"base_url"`
+	TimeoutMs                 int64   `json:"timeout_ms,omitempty"`
+	MaxIdleConns              int     `json:"max_idle_conns,omitempty"`
+	IdleConnTimeout           int64   `json:"idle_conn_timeout_ms,omitempty"`
+	FailureThreshold          *int    `json:"failure_threshold,omitempty"`
+	EjectionCooldownMs        int64   `json:"ejection_cooldown_ms,omitempty"`
+	HealthCheckPath           string  `json:"health_check_path,omitempty"`
+	HealthCheckIntervalMs     int64   `json:"health_check_interval_ms,omitempty"`
+	HealthCheckTimeoutMs      int64   `json:"health_check_timeout_ms,omitempty"`
+	HealthCheckExpectedStatus int     `json:"health_check_expected_status,omitempty"`
+	CircuitFailureRate        float64 `json:"circuit_failure_rate,omitempty"`
+	CircuitWindowMs           int64   `json:"circuit_window_ms,omitempty"`
+	CircuitMinRequests        int     `json:"circuit_min_requests,omitempty"`
+	CircuitOpenMs             int64   `json:"circuit_open_ms,omitempty"`
+	CircuitHalfOpenTrials     int     `json:"circuit_half_open_trials,omitempty"`
+	AuthType                  string  `json:"auth_type,omitempty"`
+	AuthHeader                string  `json:"auth_header,omitempty"`
+	AuthValue                 string  `json:"auth_value,omitempty"`
+	TLSCACert                 string  `json:"tls_ca_cert,omitempty"`
+	TLSClientCert             string  `json:"tls_client_cert,omitempty"`
+	TLSClientKey              string  `json:"tls_client_key,omitempty"`
+	TLSServerName             string  `json:"tls_server_name,omitempty"`
+	TLSInsecureSkipVerify     bool    `json:"tls_insecure_skip_verify,omitempty"`
+	Enabled                   *bool   `json:"enabled,omitempty"`
 }
 
 // UpdateUpstreamRequest represents a request to update an upstream.
 type UpdateUpstreamRequest struct {
-	Name            *string `json:"name,omitempty"`
-	Description     *string `json:"description,omitempty"`
-	BaseURL         *string `json:"base_url,omitempty"`
-	TimeoutMs       *int64  `json:"timeout_ms,omitempty"`
-	MaxIdleConns    *int    `json:"max_idle_conns,omitempty"`
-	IdleConnTimeout *int64  `json:"idle_conn_timeout_ms,omitempty"`
-	AuthType        *string `json:"auth_type,omitempty"`
-	AuthHeader      *string `json:"auth_header,omitempty"`
-	AuthValue       *string `json:"auth_value,omitempty"`
-	Enabled         *bool   `json:"enabled,omitempty"`
+	Name                      *string  `json:"name,omitempty"`
+	Description               *string  `json:"description,omitempty"`
+	BaseURL                   *string  `json:"base_url,omitempty"`
+	TimeoutMs                 *int64   `json:"timeout_ms,omitempty"`
+	MaxIdleConns              *int     `json:"max_idle_conns,omitempty"`
+	IdleConnTimeout           *int64   `json:"idle_conn_timeout_ms,omitempty"`
+	FailureThreshold          *int     `json:"failure_threshold,omitempty"`
+	EjectionCooldownMs        *int64   `json:"ejection_cooldown_ms,omitempty"`
+	HealthCheckPath           *string  `json:"health_check_path,omitempty"`
+	HealthCheckIntervalMs     *int64   `json:"health_check_interval_ms,omitempty"`
+	HealthCheckTimeoutMs      *int64   `json:"health_check_timeout_ms,omitempty"`
+	HealthCheckExpectedStatus *int     `json:"health_check_expected_status,omitempty"`
+	CircuitFailureRate        *float64 `json:"circuit_failure_rate,omitempty"`
+	CircuitWindowMs           *int64   `json:"circuit_window_ms,omitempty"`
+	CircuitMinRequests        *int     `json:"circuit_min_requests,omitempty"`
+	CircuitOpenMs             *int64   `json:"circuit_open_ms,omitempty"`
+	CircuitHalfOpenTrials     *int     `json:"circuit_half_open_trials,omitempty"`
+	AuthType                  *string  `json:"auth_type,omitempty"`
+	AuthHeader                *string  `json:"auth_header,omitempty"`
+	AuthValue                 *string  `json:"auth_value,omitempty"`
+	TLSCACert                 *string  `json:"tls_ca_cert,omitempty"`
+	TLSClientCert             *string  `json:"tls_client_cert,omitempty"`
+	TLSClientKey              *string  `json:"tls_client_key,omitempty"`
+	TLSServerName             *string  `json:"tls_server_name,omitempty"`
+	TLSInsecureSkipVerify     *bool    `json:"tls_insecure_skip_verify,omitempty"`
+	Enabled                   *bool    `json:"enabled,omitempty"`
 }
 
 // -----------------------------------------------------------------------------
@@ -553,19 +836,35 @@ func (h *RoutesHandler) CreateUpstream(w http.ResponseWriter, r *http.Request) {
 
 	now := time.Now().UTC()
 	u := route.Upstream{
-		ID:              generateUpstreamID(),
-		Name:            req.Name,
-		Description:     req.Description,
-		BaseURL:         req.BaseURL,
-		Timeout:         time.Duration(req.TimeoutMs) * time.Millisecond,
-		MaxIdleConns:    req.MaxIdleConns,
-		IdleConnTimeout: time.Duration(req.IdleConnTimeout) * time.Millisecond,
-		AuthType:        route.AuthType(req.AuthType),
-		AuthHeader:      req.AuthHeader,
-		AuthValue:       req.AuthValue,
-		Enabled:         true,
-		CreatedAt:       now,
-		UpdatedAt:       now,
+		ID:                        generateUpstreamID(),
+		Name:                      req.Name,
+		Description:               req.Description,
+		BaseURL:                   req.BaseURL,
+		Timeout:                   time.Duration(req.TimeoutMs) * time.Millisecond,
+		MaxIdleConns:              req.MaxIdleConns,
+		IdleConnTimeout:           time.Duration(req.IdleConnTimeout) * time.Millisecond,
+		FailureThreshold:          5,
+		EjectionCooldown:          time.Duration(req.EjectionCooldownMs) * time.Millisecond,
+		HealthCheckPath:           req.HealthCheckPath,
+		HealthCheckInterval:       time.Duration(req.HealthCheckIntervalMs) * time.Millisecond,
+		HealthCheckTimeout:        time.Duration(req.HealthCheckTimeoutMs) * time.Millisecond,
+		HealthCheckExpectedStatus: req.HealthCheckExpectedStatus,
+		CircuitFailureRate:        req.CircuitFailureRate,
+		CircuitWindow:             time.Duration(req.CircuitWindowMs) * time.Millisecond,
+		CircuitMinRequests:        req.CircuitMinRequests,
+		CircuitOpenDuration:       time.Duration(req.CircuitOpenMs) * time.Millisecond,
+		CircuitHalfOpenTrials:     req.CircuitHalfOpenTrials,
+		AuthType:                  route.AuthType(req.AuthType),
+		AuthHeader:                req.AuthHeader,
+		AuthValue:                 req.AuthValue,
+		TLSCACert:                 req.TLSCACert,
+		TLSClientCert:             req.TLSClientCert,
+		TLSClientKey:              req.TLSClientKey,
+		TLSServerName:             req.TLSServerName,
+		TLSInsecureSkipVerify:     req.TLSInsecureSkipVerify,
+		Enabled:                   true,
+		CreatedAt:                 now,
+		UpdatedAt:                 now,
 	}
 
 	if req.Enabled != nil {
@@ -580,9 +879,40 @@ func (h *RoutesHandler) CreateUpstream(w http.ResponseWriter, r *http.Request) {
 	if u.IdleConnTimeout == 0 {
 		u.IdleConnTimeout = 90 * time.Second
 	}
+	if req.FailureThreshold != nil {
+		u.FailureThreshold = *req.FailureThreshold
+	}
+	if u.EjectionCooldown == 0 {
+		u.EjectionCooldown = 30 * time.Second
+	}
+	if u.HealthCheckInterval == 0 {
+		u.HealthCheckInterval = 10 * time.Second
+	}
+	if u.HealthCheckTimeout == 0 {
+		u.HealthCheckTimeout = 2 * time.Second
+	}
+	if u.HealthCheckExpectedStatus == 0 {
+		u.HealthCheckExpectedStatus = 200
+	}
+	if u.CircuitWindow == 0 {
+		u.CircuitWindow = 10 * time.Second
+	}
+	if u.CircuitMinRequests == 0 {
+		u.CircuitMinRequests = 10
+	}
+	if u.CircuitOpenDuration == 0 {
+		u.CircuitOpenDuration = 30 * time.Second
+	}
+	if u.CircuitHalfOpenTrials == 0 {
+		u.CircuitHalfOpenTrials = 1
+	}
 	if u.AuthType == "" {
 		u.AuthType = route.AuthNone
 	}
+	if _, err := u.TLSConfig(); err != nil {
+		jsonapi.WriteValidationError(w, "tls", err.Error())
+		return
+	}
 
 	if err := h.upstreams.Create(r.Context(), u); err != nil {
 		h.logger.Error().Err(err).Msg("failed to create upstream")
@@ -668,6 +998,39 @@ func (h *RoutesHandler) UpdateUpstream(w http.ResponseWriter, r *http.Request) {
 	if req.IdleConnTimeout != nil {
 		u.IdleConnTimeout = time.Duration(*req.IdleConnTimeout) * time.Millisecond
 	}
+	if req.FailureThreshold != nil {
+		u.FailureThreshold = *req.FailureThreshold
+	}
+	if req.EjectionCooldownMs != nil {
+		u.EjectionCooldown = time.Duration(*req.EjectionCooldownMs) * time.Millisecond
+	}
+	if req.HealthCheckPath != nil {
+		u.HealthCheckPath = *req.HealthCheckPath
+	}
+	if req.HealthCheckIntervalMs != nil {
+		u.HealthCheckInterval = time.Duration(*req.HealthCheckIntervalMs) * time.Millisecond
+	}
+	if req.HealthCheckTimeoutMs != nil {
+		u.HealthCheckTimeout = time.Duration(*req.HealthCheckTimeoutMs) * time.Millisecond
+	}
+	if req.HealthCheckExpectedStatus != nil {
+		u.HealthCheckExpectedStatus = *req.HealthCheckExpectedStatus
+	}
+	if req.CircuitFailureRate != nil {
+		u.CircuitFailureRate = *req.CircuitFailureRate
+	}
+	if req.CircuitWindowMs != nil {
+		u.CircuitWindow = time.Duration(*req.CircuitWindowMs) * time.Millisecond
+	}
+	if req.CircuitMinRequests != nil {
+		u.CircuitMinRequests = *req.CircuitMinRequests
+	}
+	if req.CircuitOpenMs != nil {
+		u.CircuitOpenDuration = time.Duration(*req.CircuitOpenMs) * time.Millisecond
+	}
+	if req.CircuitHalfOpenTrials != nil {
+		u.CircuitHalfOpenTrials = *req.CircuitHalfOpenTrials
+	}
 	if req.AuthType != nil {
 		u.AuthType = route.AuthType(*req.AuthType)
 	}
@@ -677,9 +1040,28 @@ func (h *RoutesHandler) UpdateUpstream(w http.ResponseWriter, r *http.Request) {
 	if req.AuthValue != nil {
 		u.AuthValue = *req.AuthValue
 	}
+	if req.TLSCACert != nil {
+		u.TLSCACert = *req.TLSCACert
+	}
+	if req.TLSClientCert != nil {
+		u.TLSClientCert = *req.TLSClientCert
+	}
+	if req.TLSClientKey != nil {
+		u.TLSClientKey = *req.TLSClientKey
+	}
+	if req.TLSServerName != nil {
+		u.TLSServerName = *req.TLSServerName
+	}
+	if req.TLSInsecureSkipVerify != nil {
+		u.TLSInsecureSkipVerify = *req.TLSInsecureSkipVerify
+	}
 	if req.Enabled != nil {
 		u.Enabled = *req.Enabled
 	}
+	if _, err := u.TLSConfig(); err != nil {
+		jsonapi.WriteValidationError(w, "tls", err.Error())
+		return
+	}
 
 	u.UpdatedAt = time.Now().UTC()
 
@@ -734,12 +1116,30 @@ func routeToResource(rt route.Route) jsonapi.Resource {
 		Attr("match_type", string(rt.MatchType)).
 		Attr("methods", rt.Methods).
 		Attr("headers", headerMatchesToDTO(rt.Headers)).
+		Attr("query_match", queryMatchesToDTO(rt.QueryMatch)).
+		Attr("strip_query", rt.StripQuery).
+		Attr("upstreams", weightedUpstreamsToDTO(rt.Upstreams)).
+		Attr("mirror_upstream_id", rt.MirrorUpstreamID).
+		Attr("strip_prefix", rt.StripPrefix).
 		Attr("path_rewrite", rt.PathRewrite).
 		Attr("method_override", rt.MethodOverride).
+		Attr("rewrite_response_urls", rt.RewriteResponseURLs).
 		Attr("metering_expr", rt.MeteringExpr).
 		Attr("metering_mode", rt.MeteringMode).
+		Attr("cost_multiplier", rt.CostMultiplier).
+		Attr("method_cost_multipliers", rt.MethodCostMultipliers).
 		Attr("protocol", string(rt.Protocol)).
 		Attr("auth_required", rt.AuthRequired).
+		Attr("auth_method", string(rt.AuthMethod)).
+		Attr("required_scopes", rt.RequiredScopes).
+		Attr("max_request_body", rt.MaxRequestBody).
+		Attr("max_response_body", rt.MaxResponseBody).
+		Attr("request_schema", rt.RequestSchema).
+		Attr("dial_timeout_ms", rt.DialTimeout.Milliseconds()).
+		Attr("response_header_timeout_ms", rt.ResponseHeaderTimeout.Milliseconds()).
+		Attr("request_timeout_ms", rt.RequestTimeout.Milliseconds()).
+		Attr("cache_ttl_ms", rt.CacheTTL.Milliseconds()).
+		Attr("cache_key_headers", rt.CacheKeyHeaders).
 		Attr("priority", rt.Priority).
 		Attr("enabled", rt.Enabled).
 		Attr("created_at", rt.CreatedAt.Format(time.RFC3339)).
@@ -754,6 +1154,24 @@ func routeToResource(rt route.Route) jsonapi.Resource {
 	if rt.ResponseTransform != nil {
 		rb.Attr("response_transform", transformToDTO(rt.ResponseTransform))
 	}
+	if rt.RequestHeaders != nil {
+		rb.Attr("request_headers", headerRulesToDTO(rt.RequestHeaders))
+	}
+	if rt.ResponseHeaders != nil {
+		rb.Attr("response_headers", headerRulesToDTO(rt.ResponseHeaders))
+	}
+	if rt.Canary != nil {
+		rb.Attr("canary", canaryToDTO(rt.Canary))
+	}
+	if rt.RewritePath != nil {
+		rb.Attr("rewrite_path", pathRewriteToDTO(rt.RewritePath))
+	}
+	if rt.Retry != nil {
+		rb.Attr("retry", retryPolicyToDTO(rt.Retry))
+	}
+	if rt.CORS != nil {
+		rb.Attr("cors", corsPolicyToDTO(rt.CORS))
+	}
 
 	return rb.Build()
 }
@@ -767,8 +1185,23 @@ func upstreamToResource(u route.Upstream) jsonapi.Resource {
 		Attr("timeout_ms", u.Timeout.Milliseconds()).
 		Attr("max_idle_conns", u.MaxIdleConns).
 		Attr("idle_conn_timeout_ms", u.IdleConnTimeout.Milliseconds()).
+		Attr("failure_threshold", u.FailureThreshold).
+		Attr("ejection_cooldown_ms", u.EjectionCooldown.Milliseconds()).
+		Attr("health_check_path", u.HealthCheckPath).
+		Attr("health_check_interval_ms", u.HealthCheckInterval.Milliseconds()).
+		Attr("health_check_timeout_ms", u.HealthCheckTimeout.Milliseconds()).
+		Attr("health_check_expected_status", u.HealthCheckExpectedStatus).
+		Attr("circuit_failure_rate", u.CircuitFailureRate).
+		Attr("circuit_window_ms", u.CircuitWindow.Milliseconds()).
+		Attr("circuit_min_requests", u.CircuitMinRequests).
+		Attr("circuit_open_ms", u.CircuitOpenDuration.Milliseconds()).
+		Attr("circuit_half_open_trials", u.CircuitHalfOpenTrials).
 		Attr("auth_type", string(u.AuthType)).
 		Attr("auth_header", u.AuthHeader).
+		Attr("tls_ca_cert", u.TLSCACert).
+		Attr("tls_client_cert", u.TLSClientCert).
+		Attr("tls_server_name", u.TLSServerName).
+		Attr("tls_insecure_skip_verify", u.TLSInsecureSkipVerify).
 		Attr("enabled", u.Enabled).
 		Attr("created_at", u.CreatedAt.Format(time.RFC3339)).
 		Attr("updated_at", u.UpdatedAt.Format(time.RFC3339)).
@@ -777,25 +1210,49 @@ func upstreamToResource(u route.Upstream) jsonapi.Resource {
 
 func routeToResponse(rt route.Route) RouteResponse {
 	resp := RouteResponse{
-		ID:             rt.ID,
-		Name:           rt.Name,
-		Description:    rt.Description,
-		HostPattern:    rt.HostPattern,
-		HostMatchType:  string(rt.HostMatchType),
-		PathPattern:    rt.PathPattern,
-		MatchType:      string(rt.MatchType),
-		Methods:        rt.Methods,
-		Headers:        headerMatchesToDTO(rt.Headers),
-		UpstreamID:     rt.UpstreamID,
-		PathRewrite:    rt.PathRewrite,
-		MethodOverride: rt.MethodOverride,
-		MeteringExpr:   rt.MeteringExpr,
-		MeteringMode:   rt.MeteringMode,
-		Protocol:       string(rt.Protocol),
-		Priority:       rt.Priority,
-		Enabled:        rt.Enabled,
-		CreatedAt:      rt.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:      rt.UpdatedAt.Format(time.RFC3339),
+		ID:                  rt.ID,
+		Name:                rt.Name,
+		Description:         rt.Description,
+		HostPattern:         rt.HostPattern,
+		HostMatchType:       string(rt.HostMatchType),
+		PathPattern:         rt.PathPattern,
+		MatchType:           string(rt.MatchType),
+		Methods:             rt.Methods,
+		Headers:             headerMatchesToDTO(rt.Headers),
+		QueryMatch:          queryMatchesToDTO(rt.QueryMatch),
+		StripQuery:          rt.StripQuery,
+		UpstreamID:          rt.UpstreamID,
+		Upstreams:           weightedUpstreamsToDTO(rt.Upstreams),
+		Canary:              canaryToDTO(rt.Canary),
+		MirrorUpstreamID:    rt.MirrorUpstreamID,
+		StripPrefix:         rt.StripPrefix,
+		RewritePath:         pathRewriteToDTO(rt.RewritePath),
+		PathRewrite:         rt.PathRewrite,
+		MethodOverride:      rt.MethodOverride,
+		RewriteResponseURLs: rt.RewriteResponseURLs,
+		MeteringExpr:        rt.MeteringExpr,
+		MeteringMode:        rt.MeteringMode,
+		CostMultiplier:      rt.CostMultiplier,
+		MethodCosts:         rt.MethodCostMultipliers,
+		Protocol:            string(rt.Protocol),
+		AuthMethod:          string(rt.AuthMethod),
+		RequiredScopes:      rt.RequiredScopes,
+		MaxRequestBody:      rt.MaxRequestBody,
+		MaxResponseBody:     rt.MaxResponseBody,
+		RequestSchema:       rt.RequestSchema,
+		DialTimeoutMs:       rt.DialTimeout.Milliseconds(),
+		HeaderTimeoutMs:     rt.ResponseHeaderTimeout.Milliseconds(),
+		RequestTimeoutMs:    rt.RequestTimeout.Milliseconds(),
+		CacheTTLMs:          rt.CacheTTL.Milliseconds(),
+		CacheKeyHeaders:     rt.CacheKeyHeaders,
+		RequestHeaders:      headerRulesToDTO(rt.RequestHeaders),
+		ResponseHeaders:     headerRulesToDTO(rt.ResponseHeaders),
+		Retry:               retryPolicyToDTO(rt.Retry),
+		CORS:                corsPolicyToDTO(rt.CORS),
+		Priority:            rt.Priority,
+		Enabled:             rt.Enabled,
+		CreatedAt:           rt.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:           rt.UpdatedAt.Format(time.RFC3339),
 	}
 
 	if rt.RequestTransform != nil {
@@ -810,18 +1267,33 @@ func routeToResponse(rt route.Route) RouteResponse {
 
 func upstreamToResponse(u route.Upstream) UpstreamResponse {
 	return UpstreamResponse{
-		ID:              u.ID,
-		Name:            u.Name,
-		Description:     u.Description,
-		BaseURL:         u.BaseURL,
-		TimeoutMs:       u.Timeout.Milliseconds(),
-		MaxIdleConns:    u.MaxIdleConns,
-		IdleConnTimeout: u.IdleConnTimeout.Milliseconds(),
-		AuthType:        string(u.AuthType),
-		AuthHeader:      u.AuthHeader,
-		Enabled:         u.Enabled,
-		CreatedAt:       u.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:       u.UpdatedAt.Format(time.RFC3339),
+		ID:                        u.ID,
+		Name:                      u.Name,
+		Description:               u.Description,
+		BaseURL:                   u.BaseURL,
+		TimeoutMs:                 u.Timeout.Milliseconds(),
+		MaxIdleConns:              u.MaxIdleConns,
+		IdleConnTimeout:           u.IdleConnTimeout.Milliseconds(),
+		FailureThreshold:          u.FailureThreshold,
+		EjectionCooldownMs:        u.EjectionCooldown.Milliseconds(),
+		HealthCheckPath:           u.HealthCheckPath,
+		HealthCheckIntervalMs:     u.HealthCheckInterval.Milliseconds(),
+		HealthCheckTimeoutMs:      u.HealthCheckTimeout.Milliseconds(),
+		HealthCheckExpectedStatus: u.HealthCheckExpectedStatus,
+		CircuitFailureRate:        u.CircuitFailureRate,
+		CircuitWindowMs:           u.CircuitWindow.Milliseconds(),
+		CircuitMinRequests:        u.CircuitMinRequests,
+		CircuitOpenMs:             u.CircuitOpenDuration.Milliseconds(),
+		CircuitHalfOpenTrials:     u.CircuitHalfOpenTrials,
+		AuthType:                  string(u.AuthType),
+		AuthHeader:                u.AuthHeader,
+		TLSCACert:                 u.TLSCACert,
+		TLSClientCert:             u.TLSClientCert,
+		TLSServerName:             u.TLSServerName,
+		TLSInsecureSkipVerify:     u.TLSInsecureSkipVerify,
+		Enabled:                   u.Enabled,
+		CreatedAt:                 u.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:                 u.UpdatedAt.Format(time.RFC3339),
 	}
 }
 
@@ -841,6 +1313,261 @@ func headerMatchesToDTO(headers []route.HeaderMatch) []HeaderMatchDTO {
 	return result
 }
 
+func queryMatchesToDTO(conditions []route.QueryMatch) []QueryMatchDTO {
+	if conditions == nil {
+		return nil
+	}
+	result := make([]QueryMatchDTO, len(conditions))
+	for i, q := range conditions {
+		result[i] = QueryMatchDTO{
+			Name:     q.Name,
+			Value:    q.Value,
+			IsRegex:  q.IsRegex,
+			Required: q.Required,
+		}
+	}
+	return result
+}
+
+func headerRulesToDTO(rules []route.HeaderRule) []HeaderRuleDTO {
+	if rules == nil {
+		return nil
+	}
+	result := make([]HeaderRuleDTO, len(rules))
+	for i, r := range rules {
+		result[i] = HeaderRuleDTO{Op: string(r.Op), Name: r.Name, Value: r.Value, To: r.To}
+	}
+	return result
+}
+
+func dtoToHeaderRules(dto []HeaderRuleDTO) []route.HeaderRule {
+	if dto == nil {
+		return nil
+	}
+	result := make([]route.HeaderRule, len(dto))
+	for i, r := range dto {
+		result[i] = route.HeaderRule{Op: route.HeaderOp(r.Op), Name: r.Name, Value: r.Value, To: r.To}
+	}
+	return result
+}
+
+// validateReservedPath writes a validation error and returns false if the
+// route's path pattern claims a path owned by a built-in endpoint.
+func (h *RoutesHandler) validateReservedPath(w http.ResponseWriter, rt route.Route) bool {
+	if err := h.reserved.ValidateRoute(rt); err != nil {
+		jsonapi.WriteValidationError(w, "path_pattern", err.Error())
+		return false
+	}
+	return true
+}
+
+// validateHostPattern writes a validation error and returns false if the
+// route's host pattern is invalid for its match type.
+func validateHostPattern(w http.ResponseWriter, rt route.Route) bool {
+	if err := route.ValidateHostPattern(rt.HostPattern, rt.HostMatchType); err != nil {
+		jsonapi.WriteValidationError(w, "host_pattern", err.Error())
+		return false
+	}
+	return true
+}
+
+// validateQueryMatches writes a validation error and returns false if the
+// route's query conditions or stripped parameters are invalid.
+func validateQueryMatches(w http.ResponseWriter, rt route.Route) bool {
+	if err := route.ValidateQueryMatches(rt.QueryMatch, rt.StripQuery); err != nil {
+		jsonapi.WriteValidationError(w, "query_match", err.Error())
+		return false
+	}
+	return true
+}
+
+// validateHeaderRules writes a validation error and returns false if the
+// route's header rules are invalid.
+func validateHeaderRules(w http.ResponseWriter, rt route.Route) bool {
+	if err := route.ValidateHeaderRules(rt.RequestHeaders); err != nil {
+		jsonapi.WriteValidationError(w, "request_headers", err.Error())
+		return false
+	}
+	if err := route.ValidateHeaderRules(rt.ResponseHeaders); err != nil {
+		jsonapi.WriteValidationError(w, "response_headers", err.Error())
+		return false
+	}
+	return true
+}
+
+func canaryToDTO(p *route.CanaryPolicy) *CanaryDTO {
+	if p == nil {
+		return nil
+	}
+	return &CanaryDTO{UpstreamID: p.UpstreamID, Percent: p.Percent, HashBy: string(p.HashBy)}
+}
+
+// dtoToCanary converts a canary policy; an empty upstream ID removes it.
+func dtoToCanary(dto *CanaryDTO) *route.CanaryPolicy {
+	if dto == nil || dto.UpstreamID == "" {
+		return nil
+	}
+	return &route.CanaryPolicy{UpstreamID: dto.UpstreamID, Percent: dto.Percent, HashBy: route.CanaryHashBy(dto.HashBy)}
+}
+
+// validateCanary writes a validation error and returns false if the route's
+// canary policy is invalid.
+func validateCanary(w http.ResponseWriter, rt route.Route) bool {
+	if rt.Canary == nil {
+		return true
+	}
+	if err := route.ValidateCanaryPolicy(*rt.Canary); err != nil {
+		jsonapi.WriteValidationError(w, "canary", err.Error())
+		return false
+	}
+	return true
+}
+
+func validateRequestSchema(w http.ResponseWriter, rt route.Route) bool {
+	if rt.RequestSchema == "" {
+		return true
+	}
+	if _, err := openapi.ParseSchema(rt.RequestSchema); err != nil {
+		jsonapi.WriteValidationError(w, "request_schema", err.Error())
+		return false
+	}
+	return true
+}
+
+func pathRewriteToDTO(r *route.PathRewriteRule) *PathRewriteDTO {
+	if r == nil {
+		return nil
+	}
+	return &PathRewriteDTO{Regex: r.Regex, Replacement: r.Replacement}
+}
+
+// dtoToPathRewrite converts a rewrite rule; an empty regex removes it.
+func dtoToPathRewrite(dto *PathRewriteDTO) *route.PathRewriteRule {
+	if dto == nil || dto.Regex == "" {
+		return nil
+	}
+	return &route.PathRewriteRule{Regex: dto.Regex, Replacement: dto.Replacement}
+}
+
+// validatePathRewrite writes a validation error and returns false if the
+// route's strip prefix or rewrite rule is invalid.
+func validatePathRewrite(w http.ResponseWriter, rt route.Route) bool {
+	if err := route.ValidatePathRewrite(rt.StripPrefix, nil); err != nil {
+		jsonapi.WriteValidationError(w, "strip_prefix", err.Error())
+		return false
+	}
+	if err := route.ValidatePathRewrite("", rt.RewritePath); err != nil {
+		jsonapi.WriteValidationError(w, "rewrite_path", err.Error())
+		return false
+	}
+	return true
+}
+
+func retryPolicyToDTO(p *route.RetryPolicy) *RetryPolicyDTO {
+	if p == nil {
+		return nil
+	}
+	return &RetryPolicyDTO{Attempts: p.Attempts, BackoffMs: p.Backoff.Milliseconds(), Methods: p.Methods, Statuses: p.Statuses}
+}
+
+// dtoToRetryPolicy converts a retry policy; zero attempts disables retries.
+func dtoToRetryPolicy(dto *RetryPolicyDTO) *route.RetryPolicy {
+	if dto == nil || dto.Attempts == 0 {
+		return nil
+	}
+	return &route.RetryPolicy{
+		Attempts: dto.Attempts,
+		Backoff:  time.Duration(dto.BackoffMs) * time.Millisecond,
+		Methods:  dto.Methods,
+		Statuses: dto.Statuses,
+	}
+}
+
+// validateRetryPolicy writes a validation error and returns false if the
+// route's retry policy is invalid.
+func validateRetryPolicy(w http.ResponseWriter, rt route.Route) bool {
+	if rt.Retry == nil {
+		return true
+	}
+	if err := route.ValidateRetryPolicy(*rt.Retry); err != nil {
+		jsonapi.WriteValidationError(w, "retry", err.Error())
+		return false
+	}
+	return true
+}
+
+func validateCostMultipliers(w http.ResponseWriter, rt route.Route) bool {
+	if err := route.ValidateCostMultipliers(rt.CostMultiplier, rt.MethodCostMultipliers); err != nil {
+		jsonapi.WriteValidationError(w, "cost_multiplier", err.Error())
+		return false
+	}
+	return true
+}
+
+func corsPolicyToDTO(p *route.CORSPolicy) *CORSPolicyDTO {
+	if p == nil {
+		return nil
+	}
+	return &CORSPolicyDTO{
+		AllowedOrigins:   p.AllowedOrigins,
+		AllowedMethods:   p.AllowedMethods,
+		AllowedHeaders:   p.AllowedHeaders,
+		ExposedHeaders:   p.ExposedHeaders,
+		AllowCredentials: p.AllowCredentials,
+		MaxAgeSeconds:    int(p.MaxAge.Seconds()),
+	}
+}
+
+// dtoToCORSPolicy converts a CORS policy; no allowed origins removes it.
+func dtoToCORSPolicy(dto *CORSPolicyDTO) *route.CORSPolicy {
+	if dto == nil || len(dto.AllowedOrigins) == 0 {
+		return nil
+	}
+	return &route.CORSPolicy{
+		AllowedOrigins:   dto.AllowedOrigins,
+		AllowedMethods:   dto.AllowedMethods,
+		AllowedHeaders:   dto.AllowedHeaders,
+		ExposedHeaders:   dto.ExposedHeaders,
+		AllowCredentials: dto.AllowCredentials,
+		MaxAge:           time.Duration(dto.MaxAgeSeconds) * time.Second,
+	}
+}
+
+// validateCORSPolicy writes a validation error and returns false if the
+// route's CORS policy is invalid.
+func validateCORSPolicy(w http.ResponseWriter, rt route.Route) bool {
+	if rt.CORS == nil {
+		return true
+	}
+	if err := route.ValidateCORSPolicy(*rt.CORS); err != nil {
+		jsonapi.WriteValidationError(w, "cors", err.Error())
+		return false
+	}
+	return true
+}
+
+func weightedUpstreamsToDTO(pool []route.WeightedUpstream) []WeightedUpstreamDTO {
+	if pool == nil {
+		return nil
+	}
+	result := make([]WeightedUpstreamDTO, len(pool))
+	for i, u := range pool {
+		result[i] = WeightedUpstreamDTO{UpstreamID: u.UpstreamID, Weight: u.Weight}
+	}
+	return result
+}
+
+func dtoToWeightedUpstreams(dto []WeightedUpstreamDTO) []route.WeightedUpstream {
+	if dto == nil {
+		return nil
+	}
+	result := make([]route.WeightedUpstream, len(dto))
+	for i, u := range dto {
+		result[i] = route.WeightedUpstream{UpstreamID: u.UpstreamID, Weight: u.Weight}
+	}
+	return result
+}
+
 func dtoToHeaderMatches(dto []HeaderMatchDTO) []route.HeaderMatch {
 	if dto == nil {
 		return nil
@@ -857,6 +1584,22 @@ func dtoToHeaderMatches(dto []HeaderMatchDTO) []route.HeaderMatch {
 	return result
 }
 
+func dtoToQueryMatches(dto []QueryMatchDTO) []route.QueryMatch {
+	if dto == nil {
+		return nil
+	}
+	result := make([]route.QueryMatch, len(dto))
+	for i, q := range dto {
+		result[i] = route.QueryMatch{
+			Name:     q.Name,
+			Value:    q.Value,
+			IsRegex:  q.IsRegex,
+			Required: q.Required,
+		}
+	}
+	return result
+}
+
 func transformToDTO(t *route.Transform) *TransformDTO {
 	if t == nil {
 		return nil
@@ -366,6 +366,123 @@ func TestRoutesHandler_CreateRoute_MissingPathPattern(t *testing.T) {
 	}
 }
 
+func TestRoutesHandler_CreateRoute_ReservedPath(t *testing.T) {
+	handler, routeStore, _ := setupRoutesHandler()
+	handler.SetReservedPaths(route.NewReservedPaths("/admin", "/api/v1/meter"))
+	router := createRouter(handler)
+
+	create := func(pattern string) *httptest.ResponseRecorder {
+		body := `{"name": "Route", "path_pattern": "` + pattern + `"}`
+		req := httptest.NewRequest("POST", "/routes", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, pattern := range []string{"/api/v1/meter", "/api/v1/meter/*", "/admin/users/{id}"} {
+		w := create(pattern)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: status = %d, want 422", pattern, w.Code)
+		}
+		if !bytes.Contains(w.Body.Bytes(), []byte("reserved")) {
+			t.Errorf("%s: error should explain the path is reserved: %s", pattern, w.Body.String())
+		}
+	}
+	if len(routeStore.routes) != 0 {
+		t.Fatalf("created %d routes on reserved paths", len(routeStore.routes))
+	}
+
+	// Broader routes are allowed; reserved paths under them stay built-in
+	if w := create("/api/*"); w.Code != http.StatusCreated {
+		t.Errorf("/api/*: status = %d, want 201: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRoutesHandler_CreateRoute_InvalidHostPattern(t *testing.T) {
+	handler, routeStore, _ := setupRoutesHandler()
+	router := createRouter(handler)
+
+	body := `{"name": "Tenants", "path_pattern": "/*", "host_pattern": "api.*.example.com", "host_match_type": "wildcard"}`
+	req := httptest.NewRequest("POST", "/routes", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want 422", w.Code)
+	}
+	if len(routeStore.routes) != 0 {
+		t.Error("route with invalid host pattern should not be created")
+	}
+}
+
+func TestRoutesHandler_CreateRoute_PathRewrite(t *testing.T) {
+	handler, routeStore, _ := setupRoutesHandler()
+	router := createRouter(handler)
+
+	body := `{"name": "Orders", "path_pattern": "/api/v1/orders*", "strip_prefix": "/api/v1", "rewrite_path": {"regex": "^/orders/([0-9]+)$", "replacement": "/v2/orders/$1"}}`
+	req := httptest.NewRequest("POST", "/routes", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201: %s", w.Code, w.Body.String())
+	}
+	for _, rt := range routeStore.routes {
+		if rt.StripPrefix != "/api/v1" || rt.RewritePath == nil || rt.RewritePath.Replacement != "/v2/orders/$1" {
+			t.Errorf("route = %+v, want strip prefix and rewrite rule", rt)
+		}
+	}
+
+	body = `{"name": "Bad", "path_pattern": "/bad/*", "rewrite_path": {"regex": "^/bad/(", "replacement": "/x"}}`
+	req = httptest.NewRequest("POST", "/routes", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("invalid regex: status = %d, want 422", w.Code)
+	}
+	if len(routeStore.routes) != 1 {
+		t.Errorf("routes = %d, want 1", len(routeStore.routes))
+	}
+}
+
+func TestRoutesHandler_CreateRoute_RequestSchema(t *testing.T) {
+	handler, routeStore, _ := setupRoutesHandler()
+	router := createRouter(handler)
+
+	body := `{"name": "Orders", "path_pattern": "/orders", "request_schema": "{\"type\": \"object\", \"required\": [\"sku\"]}"}`
+	req := httptest.NewRequest("POST", "/routes", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201: %s", w.Code, w.Body.String())
+	}
+	for _, rt := range routeStore.routes {
+		if rt.RequestSchema != `{"type": "object", "required": ["sku"]}` {
+			t.Errorf("RequestSchema = %q", rt.RequestSchema)
+		}
+	}
+
+	body = `{"name": "Bad", "path_pattern": "/bad", "request_schema": "{\"type\": \"decimal\"}"}`
+	req = httptest.NewRequest("POST", "/routes", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("invalid schema: status = %d, want 422", w.Code)
+	}
+	if len(routeStore.routes) != 1 {
+		t.Errorf("routes = %d, want 1", len(routeStore.routes))
+	}
+}
+
 func TestRoutesHandler_GetRoute(t *testing.T) {
 	handler, routeStore, _ := setupRoutesHandler()
 	router := createRouter(handler)
@@ -0,0 +1,43 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/pkg/jsonapi"
+)
+
+// UpstreamHealthReporter reports per-upstream health.
+type UpstreamHealthReporter interface {
+	Status() []app.UpstreamStatus
+}
+
+// UpstreamHealthResponse is the body returned by GET /admin/upstream-health.
+type UpstreamHealthResponse struct {
+	Upstreams []app.UpstreamStatus `json:"upstreams"`
+}
+
+// UpstreamHealth returns the full health check state of every upstream,
+// including names and the last probe error. The public
+// /api/v1/upstream-health endpoint only reports whether each is healthy.
+//
+//	@Summary		Upstream health details
+//	@Description	Returns active and passive health check state for each upstream, including probe errors
+//	@Tags			Admin - Routes
+//	@Produce		json
+//	@Success		200	{object}	UpstreamHealthResponse	"Per-upstream health"
+//	@Failure		501	{object}	ErrorResponse			"Health checks not available"
+//	@Security		AdminAuth
+//	@Router			/admin/upstream-health [get]
+func (h *Handler) UpstreamHealth(w http.ResponseWriter, r *http.Request) {
+	if h.upstreamHealth == nil {
+		jsonapi.WriteError(w, jsonapi.NewError(http.StatusNotImplemented, "not_implemented", "Not Implemented").
+			Detail("Upstream health checks are not available").Build())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(UpstreamHealthResponse{Upstreams: h.upstreamHealth.Status()})
+}
@@ -0,0 +1,65 @@
+package admin_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/artpar/apigate/adapters/hasher"
+	"github.com/artpar/apigate/adapters/http/admin"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+)
+
+// stubUpstreamHealth returns a fixed upstream status.
+type stubUpstreamHealth []app.UpstreamStatus
+
+func (s stubUpstreamHealth) Status() []app.UpstreamStatus { return s }
+
+func TestUpstreamHealth(t *testing.T) {
+	userStore := memory.NewUserStore()
+	keyStore := memory.NewKeyStore()
+	userStore.Create(context.Background(), ports.User{ID: "user_admin", Email: "admin@test.com", Status: "active"})
+	rawKey, keyData := key.Generate("ak_")
+	keyStore.Create(context.Background(), keyData.WithUserID("user_admin"))
+
+	h := admin.NewHandler(admin.Deps{
+		Users:  userStore,
+		Keys:   keyStore,
+		Plans:  newMockPlanStore(),
+		Logger: zerolog.Nop(),
+		Hasher: hasher.NewBcrypt(4),
+		UpstreamHealth: stubUpstreamHealth{{
+			UpstreamID: "backend", Name: "billing-internal", Circuit: "closed",
+			LastError: "dial tcp 10.0.0.5:8080: connect: connection refused",
+		}},
+	})
+
+	resp := doRequest(t, h, "GET", "/upstream-health", nil, rawKey)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+	var body admin.UpstreamHealthResponse
+	json.NewDecoder(resp.Body).Decode(&body)
+	if len(body.Upstreams) != 1 || body.Upstreams[0].Name != "billing-internal" || body.Upstreams[0].LastError == "" {
+		t.Errorf("unexpected body %+v, want the name and last error", body)
+	}
+
+	// Admin auth is required
+	if resp := doRequest(t, h, "GET", "/upstream-health", nil, ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without credentials, got %d", resp.StatusCode)
+	}
+}
+
+func TestUpstreamHealth_NotConfigured(t *testing.T) {
+	h, rawKey := setupHandler(t)
+
+	resp := doRequest(t, h, "GET", "/upstream-health", nil, rawKey)
+	if resp.StatusCode != http.StatusNotImplemented {
+		t.Errorf("Expected 501, got %d", resp.StatusCode)
+	}
+}
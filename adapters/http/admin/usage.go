@@ -1,10 +1,12 @@
 package admin
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/artpar/apigate/pkg/jsonapi"
+	"github.com/artpar/apigate/pkg/usageexport"
 )
 
 // UsageResponse represents usage statistics.
@@ -194,3 +196,45 @@ func (h *Handler) GetUsage(w http.ResponseWriter, r *http.Request) {
 		"by_plan":    response.ByPlan,
 	})
 }
+
+// ExportUsage streams a user's raw usage events for a date range as CSV or
+// JSON. The range defaults to the last month.
+//
+//	@Summary		Export usage events
+//	@Description	Download a user's usage events (method, path, status, bytes, cost, timestamp) for a date range
+//	@Tags			Admin - Usage
+//	@Produce		text/csv,json
+//	@Param			user_id	query		string				true	"User ID"
+//	@Param			start	query		string				false	"Start (RFC3339 or YYYY-MM-DD)"
+//	@Param			end		query		string				false	"End (RFC3339, or YYYY-MM-DD to include that day)"
+//	@Param			format	query		string				false	"Output format: csv (default) or json"
+//	@Success		200		{array}		usageexport.Record	"Usage events"
+//	@Security		AdminAuth
+//	@Router			/admin/usage/export [get]
+func (h *Handler) ExportUsage(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		jsonapi.WriteValidationError(w, "user_id", "user_id query parameter is required")
+		return
+	}
+	if h.usage == nil {
+		jsonapi.WriteInternalError(w, "Usage store not configured")
+		return
+	}
+
+	now := time.Now().UTC()
+	start, end, err := usageexport.ParseRange(r.URL.Query(), now.AddDate(0, -1, 0), now)
+	if err != nil {
+		jsonapi.WriteValidationError(w, "range", err.Error())
+		return
+	}
+
+	err = usageexport.Write(r.Context(), w, h.usage, userID, start, end, r.URL.Query().Get("format"))
+	if errors.Is(err, usageexport.ErrUnknownFormat) {
+		jsonapi.WriteValidationError(w, "format", err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Str("user_id", userID).Msg("usage export failed")
+	}
+}
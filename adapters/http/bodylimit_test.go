@@ -0,0 +1,140 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/artpar/apigate/adapters/clock"
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/proxy"
+	"github.com/artpar/apigate/domain/route"
+	"github.com/rs/zerolog"
+)
+
+type staticRouteStore struct {
+	routes []route.Route
+}
+
+func (s *staticRouteStore) Get(ctx context.Context, id string) (route.Route, error) {
+	return route.Route{}, nil
+}
+func (s *staticRouteStore) List(ctx context.Context) ([]route.Route, error) { return s.routes, nil }
+func (s *staticRouteStore) ListEnabled(ctx context.Context) ([]route.Route, error) {
+	return s.routes, nil
+}
+func (s *staticRouteStore) Create(ctx context.Context, r route.Route) error { return nil }
+func (s *staticRouteStore) Update(ctx context.Context, r route.Route) error { return nil }
+func (s *staticRouteStore) Delete(ctx context.Context, id string) error     { return nil }
+
+type staticUpstreamStore struct {
+	upstreams []route.Upstream
+}
+
+func (s *staticUpstreamStore) Get(ctx context.Context, id string) (route.Upstream, error) {
+	return route.Upstream{}, nil
+}
+func (s *staticUpstreamStore) List(ctx context.Context) ([]route.Upstream, error) {
+	return s.upstreams, nil
+}
+func (s *staticUpstreamStore) ListEnabled(ctx context.Context) ([]route.Upstream, error) {
+	return s.upstreams, nil
+}
+func (s *staticUpstreamStore) Create(ctx context.Context, u route.Upstream) error { return nil }
+func (s *staticUpstreamStore) Update(ctx context.Context, u route.Upstream) error { return nil }
+func (s *staticUpstreamStore) Delete(ctx context.Context, id string) error        { return nil }
+
+// setupBodyLimitHandler builds a handler with public routes and a global request body limit.
+func setupBodyLimitHandler(t *testing.T, globalLimit int64, upstream *testUpstream) *apihttp.ProxyHandler {
+	t.Helper()
+
+	routes := []route.Route{
+		{ID: "small", Name: "small", PathPattern: "/api/small", MatchType: route.MatchExact,
+			MaxRequestBody: 8, Protocol: route.ProtocolHTTP, Enabled: true},
+		{ID: "default", Name: "default", PathPattern: "/api/*", MatchType: route.MatchPrefix,
+			Protocol: route.ProtocolHTTP, Enabled: true},
+	}
+
+	deps := app.ProxyDeps{
+		Keys:      memory.NewKeyStore(),
+		Users:     memory.NewUserStore(),
+		RateLimit: memory.NewRateLimitStore(),
+		Usage:     &testUsageRecorder{},
+		Upstream:  upstream,
+		Clock:     clock.NewFake(baseTime),
+		IDGen:     &testIDGen{},
+	}
+	service := app.NewProxyService(deps, app.ProxyConfig{
+		KeyPrefix:      "ak_",
+		MaxRequestBody: globalLimit,
+	})
+
+	routeService := app.NewRouteService(&staticRouteStore{routes: routes}, &staticUpstreamStore{},
+		clock.NewFake(baseTime), zerolog.Nop(), app.RouteServiceConfig{})
+	if err := routeService.Reload(context.Background()); err != nil {
+		t.Fatalf("reload routes: %v", err)
+	}
+	service.SetRouteService(routeService)
+
+	return apihttp.NewProxyHandler(service, zerolog.Nop())
+}
+
+func TestProxyHandler_RequestBodyLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		bodySize   int
+		wantStatus int
+	}{
+		{"global limit just under", "/api/data", 16, http.StatusOK},
+		{"global limit exceeded", "/api/data", 17, http.StatusRequestEntityTooLarge},
+		{"route limit just under", "/api/small", 8, http.StatusOK},
+		{"route limit exceeded", "/api/small", 9, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := setupBodyLimitHandler(t, 16, &testUpstream{healthy: true})
+
+			req := httptest.NewRequest("POST", tt.path, strings.NewReader(strings.Repeat("x", tt.bodySize)))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusRequestEntityTooLarge && !strings.Contains(rec.Body.String(), proxy.ErrRequestTooLarge.Code) {
+				t.Errorf("body = %s, want error code %s", rec.Body.String(), proxy.ErrRequestTooLarge.Code)
+			}
+		})
+	}
+}
+
+func TestProxyHandler_RequestBodyLimit_RouteOverridesLargerGlobal(t *testing.T) {
+	// Global allows 1KB but the route only allows 8 bytes
+	handler := setupBodyLimitHandler(t, 1024, &testUpstream{healthy: true})
+
+	req := httptest.NewRequest("POST", "/api/small", strings.NewReader(strings.Repeat("x", 100)))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want 413", rec.Code)
+	}
+}
+
+func TestProxyHandler_RequestBodyLimit_Unlimited(t *testing.T) {
+	handler := setupBodyLimitHandler(t, 0, &testUpstream{healthy: true})
+
+	req := httptest.NewRequest("POST", "/api/data", strings.NewReader(strings.Repeat("x", 1<<20)))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200", rec.Code)
+	}
+}
@@ -0,0 +1,141 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/artpar/apigate/adapters/clock"
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/domain/route"
+	"github.com/rs/zerolog"
+)
+
+func TestProxy_RequestSchema(t *testing.T) {
+	var forwarded int
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded++
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	upstreams := []route.Upstream{{ID: "orders-svc", Name: "Orders", BaseURL: backend.URL, Enabled: true}}
+	routes := []route.Route{
+		{
+			ID: "orders", Name: "orders", PathPattern: "/orders", MatchType: route.MatchExact,
+			RequestSchema: `{
+				"type": "object",
+				"required": ["sku", "quantity"],
+				"properties": {"sku": {"type": "string"}, "quantity": {"type": "integer", "minimum": 1}}
+			}`,
+			UpstreamID: "orders-svc", Protocol: route.ProtocolHTTP, Enabled: true,
+		},
+	}
+
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: backend.URL})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	defer client.Close()
+
+	clk := clock.NewFake(baseTime)
+	service := app.NewProxyService(app.ProxyDeps{
+		Keys:      memory.NewKeyStore(),
+		Users:     memory.NewUserStore(),
+		RateLimit: memory.NewRateLimitStore(),
+		Usage:     &testUsageRecorder{},
+		Upstream:  client,
+		Clock:     clk,
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100,
+		RateWindow: 60,
+		Plans:      []plan.Plan{{ID: "free", Name: "Free", RateLimitPerMinute: 600, RequestsPerMonth: -1}},
+	})
+	routeService := app.NewRouteService(&staticRouteStore{routes: routes}, &staticUpstreamStore{upstreams: upstreams},
+		clk, zerolog.Nop(), app.RouteServiceConfig{})
+	if err := routeService.Reload(context.Background()); err != nil {
+		t.Fatalf("reload routes: %v", err)
+	}
+	service.SetRouteService(routeService)
+	handler := apihttp.NewProxyHandler(service, zerolog.Nop())
+
+	type fieldError struct {
+		Code   string `json:"code"`
+		Detail string `json:"detail"`
+		Source struct {
+			Pointer string `json:"pointer"`
+		} `json:"source"`
+	}
+	serve := func(method, body string) (*httptest.ResponseRecorder, []fieldError) {
+		req := httptest.NewRequest(method, "/orders", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		var doc struct {
+			Errors []fieldError `json:"errors"`
+		}
+		if rec.Code != http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+				t.Fatalf("decode error response: %v: %s", err, rec.Body.String())
+			}
+		}
+		return rec, doc.Errors
+	}
+
+	t.Run("valid body is forwarded", func(t *testing.T) {
+		forwarded = 0
+		rec, _ := serve("POST", `{"sku": "ABC-1", "quantity": 2}`)
+		if rec.Code != http.StatusOK || forwarded != 1 {
+			t.Fatalf("status = %d, forwarded = %d, want 200 and 1: %s", rec.Code, forwarded, rec.Body.String())
+		}
+	})
+
+	t.Run("missing required field", func(t *testing.T) {
+		forwarded = 0
+		rec, errs := serve("POST", `{"sku": "ABC-1"}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("status = %d, want 400", rec.Code)
+		}
+		if len(errs) != 1 || errs[0].Code != "invalid_request_body" || errs[0].Source.Pointer != "/quantity" || errs[0].Detail != "is required" {
+			t.Errorf("errors = %+v", errs)
+		}
+		if forwarded != 0 {
+			t.Error("invalid body was forwarded upstream")
+		}
+	})
+
+	t.Run("wrong type", func(t *testing.T) {
+		rec, errs := serve("PUT", `{"sku": 42, "quantity": "two"}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("status = %d, want 400", rec.Code)
+		}
+		if len(errs) != 2 || errs[0].Source.Pointer != "/quantity" || errs[1].Source.Pointer != "/sku" {
+			t.Fatalf("errors = %+v", errs)
+		}
+		if errs[1].Detail != "must be a string, got a number" {
+			t.Errorf("sku detail = %q", errs[1].Detail)
+		}
+	})
+
+	t.Run("malformed JSON", func(t *testing.T) {
+		rec, errs := serve("POST", `{"sku":`)
+		if rec.Code != http.StatusBadRequest || len(errs) != 1 || errs[0].Detail != "request body is not valid JSON" {
+			t.Errorf("status = %d, errors = %+v", rec.Code, errs)
+		}
+	})
+
+	t.Run("GET is not validated", func(t *testing.T) {
+		rec, _ := serve("GET", "")
+		if rec.Code != http.StatusOK {
+			t.Errorf("status = %d, want 200", rec.Code)
+		}
+	})
+}
@@ -0,0 +1,226 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/route"
+	"github.com/rs/zerolog"
+)
+
+func TestProxy_CircuitBreaker(t *testing.T) {
+	const upstreamDelay = 200 * time.Millisecond
+
+	backend := newFlappingServer()
+	defer backend.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(upstreamDelay)
+		backend.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer slow.Close()
+
+	upstreams := []route.Upstream{{
+		ID: "backend", Name: "backend", BaseURL: slow.URL, Timeout: 5 * time.Second, Enabled: true,
+		CircuitFailureRate: 0.5, CircuitWindow: 10 * time.Second, CircuitMinRequests: 4,
+		CircuitOpenDuration: 30 * time.Second, CircuitHalfOpenTrials: 1,
+	}}
+	routes := []route.Route{{
+		ID: "api", Name: "api", PathPattern: "/api/*", MatchType: route.MatchPrefix,
+		Protocol: route.ProtocolHTTP, UpstreamID: "backend", Enabled: true,
+	}}
+
+	clk := clock.NewFake(baseTime)
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: slow.URL})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	defer client.Close()
+
+	service := app.NewProxyService(app.ProxyDeps{
+		Keys:      memory.NewKeyStore(),
+		Users:     memory.NewUserStore(),
+		RateLimit: memory.NewRateLimitStore(),
+		Usage:     &testUsageRecorder{},
+		Upstream:  client,
+		Clock:     clk,
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{KeyPrefix: "ak_"})
+
+	routeService := app.NewRouteService(&staticRouteStore{routes: routes}, &staticUpstreamStore{upstreams: upstreams},
+		clk, zerolog.Nop(), app.RouteServiceConfig{})
+	if err := routeService.Reload(context.Background()); err != nil {
+		t.Fatalf("reload routes: %v", err)
+	}
+	service.SetRouteService(routeService)
+	handler := apihttp.NewProxyHandler(service, zerolog.Nop())
+
+	send := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/api/data", nil))
+		return rec
+	}
+	state := func() route.CircuitState { return routeService.CircuitState(upstreams[0]) }
+
+	// Closed: failures pass through until the failure rate trips the circuit
+	backend.failing.Store(true)
+	for i := 0; i < 4; i++ {
+		if rec := send(); rec.Code != http.StatusBadGateway {
+			t.Fatalf("request %d status = %d, want 502 from upstream", i, rec.Code)
+		}
+	}
+	if got := state(); got != route.CircuitOpen {
+		t.Fatalf("state after 4 failures = %s, want open", got)
+	}
+
+	// Open: requests fail fast with 503 without reaching the upstream
+	start := time.Now()
+	rec := send()
+	if elapsed := time.Since(start); elapsed >= upstreamDelay {
+		t.Errorf("open circuit took %v, want fast rejection (< %v)", elapsed, upstreamDelay)
+	}
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "circuit_open") {
+		t.Fatalf("open circuit response = %d %s, want 503 circuit_open", rec.Code, rec.Body.String())
+	}
+	clk.Advance(29 * time.Second)
+	if rec := send(); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status 29s after opening = %d, want 503", rec.Code)
+	}
+	if got := backend.hits.Load(); got != 4 {
+		t.Errorf("upstream hits = %d, want 4 (no traffic while open)", got)
+	}
+
+	// Half-open: a failed trial re-opens the circuit
+	clk.Advance(time.Second)
+	if got := state(); got != route.CircuitHalfOpen {
+		t.Fatalf("state after open duration = %s, want half_open", got)
+	}
+	if rec := send(); rec.Code != http.StatusBadGateway {
+		t.Fatalf("trial status = %d, want 502 from upstream", rec.Code)
+	}
+	if got := state(); got != route.CircuitOpen {
+		t.Fatalf("state after failed trial = %s, want open", got)
+	}
+	if rec := send(); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status after failed trial = %d, want 503", rec.Code)
+	}
+
+	// Half-open: a trial cancelled by the client frees its slot without
+	// closing the circuit, so the next trial still reaches the broken upstream
+	clk.Advance(30 * time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(upstreamDelay/2, cancel)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/data", nil).WithContext(ctx))
+	if got := state(); got != route.CircuitHalfOpen {
+		t.Fatalf("state after cancelled trial = %s, want half_open", got)
+	}
+	if rec := send(); rec.Code != http.StatusBadGateway {
+		t.Fatalf("trial after cancellation status = %d, want 502 from upstream", rec.Code)
+	}
+	if got := state(); got != route.CircuitOpen {
+		t.Fatalf("state after failed trial = %s, want open", got)
+	}
+
+	// Half-open again: a successful trial closes the circuit
+	backend.failing.Store(false)
+	clk.Advance(30 * time.Second)
+	if rec := send(); rec.Code != http.StatusOK {
+		t.Fatalf("trial status = %d, want 200", rec.Code)
+	}
+	if got := state(); got != route.CircuitClosed {
+		t.Fatalf("state after successful trial = %s, want closed", got)
+	}
+	for i := 0; i < 3; i++ {
+		if rec := send(); rec.Code != http.StatusOK {
+			t.Errorf("status after closing = %d, want 200", rec.Code)
+		}
+	}
+	if got := backend.hits.Load(); got != 11 {
+		t.Errorf("upstream hits = %d, want 11", got)
+	}
+}
+
+func TestProxy_CircuitBreakerStreamingProtocols(t *testing.T) {
+	for _, tc := range []struct {
+		protocol route.Protocol
+		header   http.Header
+	}{
+		{route.ProtocolSSE, http.Header{"Accept": {"text/event-stream"}}},
+		{route.ProtocolWebSocket, http.Header{"Connection": {"Upgrade"}, "Upgrade": {"websocket"}, "Sec-Websocket-Version": {"13"}, "Sec-Websocket-Key": {"dGhlIHNhbXBsZSBub25jZQ=="}}},
+	} {
+		t.Run(string(tc.protocol), func(t *testing.T) {
+			backend := newFlappingServer()
+			defer backend.Close()
+			backend.failing.Store(true)
+
+			upstreams := []route.Upstream{{
+				ID: "backend", Name: "backend", BaseURL: backend.URL, Timeout: 5 * time.Second, Enabled: true,
+				CircuitFailureRate: 0.5, CircuitWindow: 10 * time.Second, CircuitMinRequests: 2,
+				CircuitOpenDuration: 30 * time.Second, CircuitHalfOpenTrials: 1,
+			}}
+			routes := []route.Route{{
+				ID: "stream", Name: "stream", PathPattern: "/stream", MatchType: route.MatchExact,
+				Protocol: tc.protocol, UpstreamID: "backend", Enabled: true,
+			}}
+
+			clk := clock.NewFake(baseTime)
+			client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: backend.URL})
+			if err != nil {
+				t.Fatalf("create upstream client: %v", err)
+			}
+			defer client.Close()
+
+			service := app.NewProxyService(app.ProxyDeps{
+				Keys:      memory.NewKeyStore(),
+				Users:     memory.NewUserStore(),
+				RateLimit: memory.NewRateLimitStore(),
+				Usage:     &testUsageRecorder{},
+				Upstream:  client,
+				Clock:     clk,
+				IDGen:     &testIDGen{},
+			}, app.ProxyConfig{KeyPrefix: "ak_"})
+			routeService := app.NewRouteService(&staticRouteStore{routes: routes}, &staticUpstreamStore{upstreams: upstreams},
+				clk, zerolog.Nop(), app.RouteServiceConfig{})
+			if err := routeService.Reload(context.Background()); err != nil {
+				t.Fatalf("reload routes: %v", err)
+			}
+			service.SetRouteService(routeService)
+			handler := apihttp.NewProxyHandler(service, zerolog.Nop())
+			handler.SetStreamingUpstream(client)
+			handler.SetWebSocketUpstream(client)
+
+			send := func() *httptest.ResponseRecorder {
+				req := httptest.NewRequest("GET", "/stream", nil)
+				for k, v := range tc.header {
+					req.Header[k] = v
+				}
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+				return rec
+			}
+
+			// Upstream failures trip the circuit as they do for buffered requests
+			for i := 0; i < 2; i++ {
+				if rec := send(); rec.Code != http.StatusBadGateway {
+					t.Fatalf("request %d status = %d, want 502 from upstream", i, rec.Code)
+				}
+			}
+			if got := routeService.CircuitState(upstreams[0]); got != route.CircuitOpen {
+				t.Fatalf("state after 2 failures = %s, want open", got)
+			}
+			if rec := send(); rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "circuit_open") {
+				t.Fatalf("open circuit response = %d %s, want 503 circuit_open", rec.Code, rec.Body.String())
+			}
+			if got := backend.hits.Load(); got != 2 {
+				t.Errorf("upstream hits = %d, want 2 (no traffic while open)", got)
+			}
+		})
+	}
+}
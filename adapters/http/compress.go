@@ -0,0 +1,88 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/artpar/apigate/domain/proxy"
+)
+
+// SetCompression configures compression of buffered proxy responses for
+// clients that accept it.
+func (h *ProxyHandler) SetCompression(cfg proxy.CompressionConfig) {
+	h.compression = cfg
+}
+
+// compressResponse returns the body to send for resp, compressed with the
+// best encoding the client accepts when the response is eligible. Headers
+// already copied to w are updated to describe the encoded body.
+func (h *ProxyHandler) compressResponse(w http.ResponseWriter, r *http.Request, resp proxy.Response) []byte {
+	if resp.Status == http.StatusNoContent || resp.Status == http.StatusNotModified ||
+		!proxy.IsCompressible(h.compression, resp.Headers, resp.Body) {
+		return resp.Body
+	}
+
+	// The representation depends on Accept-Encoding whether or not this
+	// client gets it compressed
+	addVary(w.Header(), "Accept-Encoding")
+
+	encoding := proxy.NegotiateEncoding(r.Header.Get("Accept-Encoding"))
+	if encoding == "" {
+		return resp.Body
+	}
+	compressed, err := encodeBody(encoding, resp.Body)
+	if err != nil {
+		h.logger.Error().Err(err).Str("encoding", encoding).Msg("failed to compress response")
+		return resp.Body
+	}
+
+	w.Header().Set("Content-Encoding", encoding)
+	w.Header().Del("Content-Length")
+	// The compressed bytes differ from the entity the strong ETag names
+	if etag := w.Header().Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		w.Header().Set("ETag", "W/"+etag)
+	}
+	return compressed
+}
+
+// encodeBody compresses body with the given content encoding.
+func encodeBody(encoding string, body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var zw io.WriteCloser
+	switch encoding {
+	case proxy.EncodingGzip:
+		zw = gzip.NewWriter(&buf)
+	case proxy.EncodingDeflate:
+		// HTTP's deflate is the zlib format (RFC 9110 section 8.4.1.2)
+		zw = zlib.NewWriter(&buf)
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", encoding)
+	}
+	if _, err := zw.Write(body); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// addVary adds name to the Vary header unless it is already listed.
+func addVary(header http.Header, name string) {
+	vary := header.Get("Vary")
+	for _, v := range strings.Split(vary, ",") {
+		if t := strings.TrimSpace(v); t == "*" || strings.EqualFold(t, name) {
+			return
+		}
+	}
+	if vary == "" {
+		header.Set("Vary", name)
+		return
+	}
+	header.Set("Vary", vary+", "+name)
+}
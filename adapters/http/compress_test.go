@@ -0,0 +1,185 @@
+package http_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/artpar/apigate/adapters/clock"
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/domain/proxy"
+	"github.com/artpar/apigate/domain/route"
+	"github.com/rs/zerolog"
+)
+
+func TestProxy_ResponseCompression(t *testing.T) {
+	largeJSON := `{"items":[` + strings.Repeat(`{"id":1,"name":"widget"},`, 200) + `{"id":2}]}`
+	var precompressed bytes.Buffer
+	zw := gzip.NewWriter(&precompressed)
+	zw.Write([]byte(largeJSON))
+	zw.Close()
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/large":
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("ETag", `"v1"`)
+			w.Write([]byte(largeJSON))
+		case "/api/small":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id":1}`))
+		case "/api/precompressed":
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(precompressed.Bytes())
+		case "/api/binary":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte(largeJSON))
+		}
+	}))
+	defer backend.Close()
+
+	upstreams := []route.Upstream{{ID: "backend", Name: "backend", BaseURL: backend.URL, Enabled: true}}
+	routes := []route.Route{{
+		ID: "api", Name: "api", PathPattern: "/api/*", MatchType: route.MatchPrefix,
+		UpstreamID: "backend", Protocol: route.ProtocolHTTP, Enabled: true,
+	}}
+
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: backend.URL})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	defer client.Close()
+
+	clk := clock.NewFake(baseTime)
+	service := app.NewProxyService(app.ProxyDeps{
+		Keys:      memory.NewKeyStore(),
+		Users:     memory.NewUserStore(),
+		RateLimit: memory.NewRateLimitStore(),
+		Usage:     &testUsageRecorder{},
+		Upstream:  client,
+		Clock:     clk,
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100,
+		RateWindow: 60,
+		Plans:      []plan.Plan{{ID: "free", Name: "Free", RateLimitPerMinute: 600, RequestsPerMonth: -1}},
+	})
+	routeService := app.NewRouteService(&staticRouteStore{routes: routes}, &staticUpstreamStore{upstreams: upstreams},
+		clk, zerolog.Nop(), app.RouteServiceConfig{})
+	if err := routeService.Reload(context.Background()); err != nil {
+		t.Fatalf("reload routes: %v", err)
+	}
+	service.SetRouteService(routeService)
+	handler := apihttp.NewProxyHandler(service, zerolog.Nop())
+	handler.SetCompression(proxy.CompressionConfig{Enabled: true, MinSize: 1024, Types: []string{"application/json"}})
+
+	get := func(t *testing.T, path, acceptEncoding string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest("GET", path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d, want 200: %s", path, rec.Code, rec.Body.String())
+		}
+		return rec
+	}
+	decode := func(t *testing.T, newReader func(io.Reader) (io.ReadCloser, error), body []byte) string {
+		t.Helper()
+		zr, err := newReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("open compressed body: %v", err)
+		}
+		out, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("decompress body: %v", err)
+		}
+		return string(out)
+	}
+
+	t.Run("large json is gzipped", func(t *testing.T) {
+		rec := get(t, "/api/large", "gzip, deflate, br")
+		if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+			t.Fatalf("Content-Encoding = %q, want gzip", got)
+		}
+		if rec.Body.Len() >= len(largeJSON) {
+			t.Errorf("compressed body is %d bytes, not smaller than %d", rec.Body.Len(), len(largeJSON))
+		}
+		gunzip := func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) }
+		if got := decode(t, gunzip, rec.Body.Bytes()); got != largeJSON {
+			t.Errorf("decompressed body differs from the upstream body")
+		}
+		if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+			t.Errorf("Vary = %q, want Accept-Encoding", got)
+		}
+		if got := rec.Header().Get("ETag"); got != `W/"v1"` {
+			t.Errorf("ETag = %q, want weakened", got)
+		}
+		if got := rec.Header().Get("Content-Length"); got != "" {
+			t.Errorf("Content-Length = %q, want the uncompressed length dropped", got)
+		}
+	})
+
+	t.Run("deflate when gzip is not accepted", func(t *testing.T) {
+		rec := get(t, "/api/large", "deflate")
+		if got := rec.Header().Get("Content-Encoding"); got != "deflate" {
+			t.Fatalf("Content-Encoding = %q, want deflate", got)
+		}
+		if got := decode(t, zlib.NewReader, rec.Body.Bytes()); got != largeJSON {
+			t.Errorf("decompressed body differs from the upstream body")
+		}
+	})
+
+	t.Run("small json is not compressed", func(t *testing.T) {
+		rec := get(t, "/api/small", "gzip")
+		if got := rec.Header().Get("Content-Encoding"); got != "" {
+			t.Errorf("Content-Encoding = %q, want none", got)
+		}
+		if rec.Body.String() != `{"id":1}` {
+			t.Errorf("body = %q", rec.Body.String())
+		}
+	})
+
+	t.Run("client without accept-encoding", func(t *testing.T) {
+		rec := get(t, "/api/large", "")
+		if got := rec.Header().Get("Content-Encoding"); got != "" {
+			t.Errorf("Content-Encoding = %q, want none", got)
+		}
+		if rec.Body.String() != largeJSON {
+			t.Error("body differs from the upstream body")
+		}
+		if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+			t.Errorf("Vary = %q, want Accept-Encoding", got)
+		}
+	})
+
+	t.Run("already compressed upstream response", func(t *testing.T) {
+		rec := get(t, "/api/precompressed", "gzip")
+		if !bytes.Equal(rec.Body.Bytes(), precompressed.Bytes()) {
+			t.Fatal("upstream gzip body was compressed again")
+		}
+		if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+			t.Errorf("Content-Encoding = %q, want the upstream's gzip", got)
+		}
+	})
+
+	t.Run("content type not in allowlist", func(t *testing.T) {
+		rec := get(t, "/api/binary", "gzip")
+		if got := rec.Header().Get("Content-Encoding"); got != "" {
+			t.Errorf("Content-Encoding = %q, want none", got)
+		}
+	})
+}
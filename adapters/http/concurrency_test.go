@@ -0,0 +1,163 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/domain/route"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestProxy_ConcurrencyLimit(t *testing.T) {
+	const limit = 2
+
+	var inFlight atomic.Int32
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inFlight.Add(1)
+		defer inFlight.Add(-1)
+		if r.URL.Path == "/slow" {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if r.URL.Path == "/hang" {
+			<-r.Context().Done()
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer backend.Close()
+
+	upstreams := []route.Upstream{{ID: "backend", Name: "backend", BaseURL: backend.URL, Enabled: true}}
+	routes := []route.Route{
+		{
+			ID: "api", Name: "api", PathPattern: "/*", MatchType: route.MatchPrefix,
+			UpstreamID: "backend", Protocol: route.ProtocolHTTP, Enabled: true, AuthRequired: true,
+		},
+		{
+			ID: "hang", Name: "hang", PathPattern: "/hang", MatchType: route.MatchExact, Priority: 10,
+			UpstreamID: "backend", Protocol: route.ProtocolHTTP, Enabled: true, AuthRequired: true,
+			ResponseHeaderTimeout: 100 * time.Millisecond,
+		},
+	}
+
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: backend.URL})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	defer client.Close()
+
+	keys := memory.NewKeyStore()
+	users := memory.NewUserStore()
+	rawKey := "ak_0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	keyHash, _ := bcrypt.GenerateFromPassword([]byte(rawKey), bcrypt.MinCost)
+	keys.Create(context.Background(), key.Key{ID: "key-1", UserID: "user-1", Hash: keyHash, Prefix: rawKey[:12], CreatedAt: baseTime.Add(-time.Hour)})
+	users.Create(context.Background(), ports.User{ID: "user-1", Email: "test@example.com", PlanID: "pro", Status: "active"})
+
+	clk := clock.NewFake(baseTime)
+	service := app.NewProxyService(app.ProxyDeps{
+		Keys:      keys,
+		Users:     users,
+		RateLimit: memory.NewRateLimitStore(),
+		Usage:     &testUsageRecorder{},
+		Upstream:  client,
+		Clock:     clk,
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100,
+		RateWindow: 60,
+		Plans:      []plan.Plan{{ID: "pro", Name: "Pro", RateLimitPerMinute: 6000, RateLimitBurst: 100, RequestsPerMonth: -1, MaxConcurrent: limit}},
+	})
+	routeService := app.NewRouteService(&staticRouteStore{routes: routes}, &staticUpstreamStore{upstreams: upstreams},
+		clk, zerolog.Nop(), app.RouteServiceConfig{})
+	if err := routeService.Reload(context.Background()); err != nil {
+		t.Fatalf("reload routes: %v", err)
+	}
+	service.SetRouteService(routeService)
+	handler := apihttp.NewProxyHandler(service, zerolog.Nop())
+
+	do := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("X-API-Key", rawKey)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	waitInFlight := func(n int32) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for inFlight.Load() != n {
+			if time.Now().After(deadline) {
+				t.Fatalf("upstream in-flight = %d, want %d", inFlight.Load(), n)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	// Fill the slots, then send more requests than the limit allows
+	const total = limit + 3
+	codes := make(chan int, total)
+	var wg sync.WaitGroup
+	for i := 0; i < limit; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- do("/slow").Code
+		}()
+	}
+	waitInFlight(limit)
+
+	for i := limit; i < total; i++ {
+		rec := do("/slow")
+		if rec.Code != http.StatusTooManyRequests || !strings.Contains(rec.Body.String(), "concurrency_limit") {
+			t.Errorf("excess request: got %d %s, want 429 concurrency_limit", rec.Code, rec.Body.String())
+		}
+		if got := rec.Header().Get("X-Concurrency-Limit"); got != "2" {
+			t.Errorf("X-Concurrency-Limit = %q, want 2", got)
+		}
+	}
+
+	close(release)
+	wg.Wait()
+	close(codes)
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("request within the limit: status %d, want 200", code)
+		}
+	}
+
+	// Slots are freed when requests complete, including timed-out ones
+	for i := 0; i < limit; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if rec := do("/hang"); rec.Code != http.StatusGatewayTimeout {
+				t.Errorf("hanging request: status %d, want 504", rec.Code)
+			}
+		}()
+	}
+	wg.Wait()
+	for i := 0; i < limit+1; i++ {
+		if rec := do("/fast"); rec.Code != http.StatusOK {
+			t.Fatalf("request after slots were freed: status %d, want 200: %s", rec.Code, rec.Body.String())
+		}
+	}
+}
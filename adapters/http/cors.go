@@ -0,0 +1,92 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/artpar/apigate/domain/proxy"
+	"github.com/artpar/apigate/domain/route"
+)
+
+// handleCORS applies the CORS policy of the route r matches. Preflights are
+// answered here without reaching the upstream; it returns false when the
+// request has been handled. Otherwise it returns the writer to use for the
+// response, which carries the policy's headers.
+func (h *ProxyHandler) handleCORS(w http.ResponseWriter, r *http.Request, req proxy.Request) (http.ResponseWriter, bool) {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return w, true
+	}
+	policy := h.service.CORSPolicy(req)
+	if policy == nil {
+		return w, true
+	}
+
+	requestMethod := r.Header.Get("Access-Control-Request-Method")
+	if route.IsPreflight(r.Method, origin, requestMethod) {
+		addVary(w.Header(), "Origin")
+		addVary(w.Header(), "Access-Control-Request-Method")
+		addVary(w.Header(), "Access-Control-Request-Headers")
+		headers, ok := policy.PreflightHeaders(origin, requestMethod, r.Header.Get("Access-Control-Request-Headers"))
+		if !ok {
+			writeError(w, &proxy.ErrCORSNotAllowed)
+			return w, false
+		}
+		for k, v := range headers {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return w, false
+	}
+
+	// Disallowed origins still get their response; without CORS headers the
+	// browser withholds it from the calling script
+	return &corsWriter{ResponseWriter: w, headers: policy.ResponseHeaders(origin)}, true
+}
+
+// corsWriter sets a route's CORS headers just before the status is written,
+// replacing any the upstream sent.
+type corsWriter struct {
+	http.ResponseWriter
+	headers map[string]string
+	wrote   bool
+}
+
+func (w *corsWriter) WriteHeader(status int) {
+	if !w.wrote {
+		w.wrote = true
+		header := w.Header()
+		for k := range header {
+			if strings.HasPrefix(k, "Access-Control-") {
+				header.Del(k)
+			}
+		}
+		for k, v := range w.headers {
+			header.Set(k, v)
+		}
+		addVary(header, "Origin")
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *corsWriter) Write(b []byte) (int, error) {
+	if !w.wrote {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush lets streamed responses through the wrapper.
+func (w *corsWriter) Flush() {
+	if !w.wrote {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (w *corsWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
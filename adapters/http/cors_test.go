@@ -0,0 +1,151 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/domain/route"
+	"github.com/rs/zerolog"
+)
+
+func TestProxy_CORS(t *testing.T) {
+	var hits atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		// The gateway's policy replaces whatever the upstream says
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer backend.Close()
+
+	upstreams := []route.Upstream{{ID: "backend", Name: "backend", BaseURL: backend.URL, Enabled: true}}
+	routes := []route.Route{{
+		ID: "api", Name: "api", PathPattern: "/api/*", MatchType: route.MatchPrefix,
+		Methods: []string{"GET", "PUT"}, UpstreamID: "backend", Protocol: route.ProtocolHTTP, Enabled: true,
+		CORS: &route.CORSPolicy{
+			AllowedOrigins:   []string{"https://app.example.com"},
+			AllowedMethods:   []string{"GET", "PUT"},
+			AllowedHeaders:   []string{"Content-Type", "X-API-Key"},
+			AllowCredentials: true,
+			MaxAge:           10 * time.Minute,
+		},
+	}}
+
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: backend.URL})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	defer client.Close()
+
+	clk := clock.NewFake(baseTime)
+	service := app.NewProxyService(app.ProxyDeps{
+		Keys:      memory.NewKeyStore(),
+		Users:     memory.NewUserStore(),
+		RateLimit: memory.NewRateLimitStore(),
+		Usage:     &testUsageRecorder{},
+		Upstream:  client,
+		Clock:     clk,
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100,
+		RateWindow: 60,
+		Plans:      []plan.Plan{{ID: "free", Name: "Free", RateLimitPerMinute: 600, RequestsPerMonth: -1}},
+	})
+	routeService := app.NewRouteService(&staticRouteStore{routes: routes}, &staticUpstreamStore{upstreams: upstreams},
+		clk, zerolog.Nop(), app.RouteServiceConfig{})
+	if err := routeService.Reload(context.Background()); err != nil {
+		t.Fatalf("reload routes: %v", err)
+	}
+	service.SetRouteService(routeService)
+	handler := apihttp.NewProxyHandler(service, zerolog.Nop())
+
+	do := func(method, origin string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/items", nil)
+		req.Header.Set("Origin", origin)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("allowed origin", func(t *testing.T) {
+		rec := do("GET", "https://app.example.com", nil)
+		if rec.Code != http.StatusOK || rec.Body.String() != `{"ok":true}` {
+			t.Fatalf("got %d %s, want the upstream response", rec.Code, rec.Body.String())
+		}
+		h := rec.Header()
+		if got := h.Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+			t.Errorf("Allow-Origin = %q, want the request origin", got)
+		}
+		if got := h.Get("Access-Control-Allow-Credentials"); got != "true" {
+			t.Errorf("Allow-Credentials = %q, want true", got)
+		}
+		if got := h.Get("Vary"); got != "Origin" {
+			t.Errorf("Vary = %q, want Origin", got)
+		}
+	})
+
+	t.Run("disallowed origin", func(t *testing.T) {
+		rec := do("GET", "https://evil.example.com", nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", rec.Code)
+		}
+		for _, name := range []string{"Access-Control-Allow-Origin", "Access-Control-Allow-Credentials"} {
+			if got := rec.Header().Get(name); got != "" {
+				t.Errorf("%s = %q, want none for a disallowed origin", name, got)
+			}
+		}
+	})
+
+	t.Run("preflight", func(t *testing.T) {
+		before := hits.Load()
+		rec := do("OPTIONS", "https://app.example.com", map[string]string{
+			"Access-Control-Request-Method":  "PUT",
+			"Access-Control-Request-Headers": "content-type",
+		})
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("status = %d, want 204: %s", rec.Code, rec.Body.String())
+		}
+		want := map[string]string{
+			"Access-Control-Allow-Origin":      "https://app.example.com",
+			"Access-Control-Allow-Methods":     "GET, PUT",
+			"Access-Control-Allow-Headers":     "content-type",
+			"Access-Control-Allow-Credentials": "true",
+			"Access-Control-Max-Age":           "600",
+		}
+		for k, v := range want {
+			if got := rec.Header().Get(k); got != v {
+				t.Errorf("%s = %q, want %q", k, got, v)
+			}
+		}
+		if hits.Load() != before {
+			t.Error("preflight reached the upstream")
+		}
+	})
+
+	t.Run("preflight from disallowed origin", func(t *testing.T) {
+		before := hits.Load()
+		rec := do("OPTIONS", "https://evil.example.com", map[string]string{"Access-Control-Request-Method": "PUT"})
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("status = %d, want 403", rec.Code)
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("Allow-Origin = %q, want none", got)
+		}
+		if hits.Load() != before {
+			t.Error("rejected preflight reached the upstream")
+		}
+	})
+}
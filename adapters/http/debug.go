@@ -0,0 +1,84 @@
+package http
+
+import (
+	"net/http"
+	"net/netip"
+
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/pkg/clientip"
+)
+
+// Debug headers explain how a request was routed. They are only added when
+// the client sends X-APIGate-Debug: 1 from a configured debug source.
+const (
+	headerDebug            = "X-APIGate-Debug"
+	headerDebugRoute       = "X-APIGate-Route"
+	headerDebugUpstream    = "X-APIGate-Upstream"
+	headerDebugMatchReason = "X-APIGate-Match-Reason"
+)
+
+// SetDebugSources sets the client IPs allowed to request route debug headers.
+// With no sources, X-APIGate-Debug is ignored.
+func (h *ProxyHandler) SetDebugSources(sources []netip.Prefix) {
+	h.debugSources = sources
+}
+
+// wantsDebug reports whether the request asked for debug headers and comes
+// from a debug source.
+func (h *ProxyHandler) wantsDebug(r *http.Request, remoteIP string) bool {
+	return r.Header.Get(headerDebug) == "1" && clientip.IsTrusted(remoteIP, h.debugSources)
+}
+
+// debugWriter adds route debug headers just before the status is written,
+// so they are present on upstream responses and gateway errors alike.
+type debugWriter struct {
+	http.ResponseWriter
+	trace *app.RouteTrace
+	wrote bool
+}
+
+func (w *debugWriter) WriteHeader(status int) {
+	if !w.wrote {
+		w.wrote = true
+		header := w.Header()
+		route, reason, upstream := "none", "no route matched", "default"
+		if w.trace.RouteID != "" {
+			route, reason = w.trace.RouteID, w.trace.MatchReason
+			if w.trace.RouteName != "" {
+				route = w.trace.RouteName
+			}
+		}
+		if w.trace.UpstreamID != "" {
+			upstream = w.trace.UpstreamID
+			if w.trace.Upstream != "" {
+				upstream = w.trace.Upstream
+			}
+		}
+		header.Set(headerDebugRoute, route)
+		header.Set(headerDebugMatchReason, reason)
+		header.Set(headerDebugUpstream, upstream)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *debugWriter) Write(b []byte) (int, error) {
+	if !w.wrote {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush lets streamed responses through the wrapper.
+func (w *debugWriter) Flush() {
+	if !w.wrote {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (w *debugWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
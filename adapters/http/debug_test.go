@@ -0,0 +1,141 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/artpar/apigate/adapters/clock"
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/domain/route"
+	"github.com/rs/zerolog"
+)
+
+func TestProxy_DebugHeaders(t *testing.T) {
+	var upstreamDebug string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamDebug = r.Header.Get("X-APIGate-Debug")
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	upstreams := []route.Upstream{{ID: "catalog-svc", Name: "Catalog Service", BaseURL: backend.URL, Enabled: true}}
+	routes := []route.Route{
+		{
+			ID: "catalog-item", Name: "catalog item", PathPattern: "/catalog/*", MatchType: route.MatchPrefix,
+			Methods: []string{"GET"}, UpstreamID: "catalog-svc", Protocol: route.ProtocolHTTP, Enabled: true,
+		},
+		{
+			ID: "catalog", Name: "catalog", PathPattern: "/catalog", MatchType: route.MatchExact,
+			UpstreamID: "catalog-svc", Protocol: route.ProtocolHTTP, Enabled: true,
+		},
+	}
+
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: backend.URL})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	defer client.Close()
+
+	clk := clock.NewFake(baseTime)
+	service := app.NewProxyService(app.ProxyDeps{
+		Keys:      memory.NewKeyStore(),
+		Users:     memory.NewUserStore(),
+		RateLimit: memory.NewRateLimitStore(),
+		Usage:     &testUsageRecorder{},
+		Upstream:  client,
+		Clock:     clk,
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100,
+		RateWindow: 60,
+		Plans:      []plan.Plan{{ID: "free", Name: "Free", RateLimitPerMinute: 600, RequestsPerMonth: -1}},
+	})
+	routeService := app.NewRouteService(&staticRouteStore{routes: routes}, &staticUpstreamStore{upstreams: upstreams},
+		clk, zerolog.Nop(), app.RouteServiceConfig{})
+	if err := routeService.Reload(context.Background()); err != nil {
+		t.Fatalf("reload routes: %v", err)
+	}
+	service.SetRouteService(routeService)
+	handler := apihttp.NewProxyHandler(service, zerolog.Nop())
+	handler.SetDebugSources([]netip.Prefix{netip.MustParsePrefix("10.1.0.0/16")})
+
+	serve := func(path, remoteAddr, debug string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = remoteAddr
+		if debug != "" {
+			req.Header.Set("X-APIGate-Debug", debug)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("trusted source gets route explanation", func(t *testing.T) {
+		rec := serve("/catalog/42", "10.1.2.3:5000", "1")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+		}
+		want := map[string]string{
+			"X-APIGate-Route":        "catalog item",
+			"X-APIGate-Upstream":     "Catalog Service",
+			"X-APIGate-Match-Reason": "path prefix /catalog/*; methods GET",
+		}
+		for name, value := range want {
+			if got := rec.Header().Get(name); got != value {
+				t.Errorf("%s = %q, want %q", name, got, value)
+			}
+		}
+		if upstreamDebug != "" {
+			t.Errorf("debug header forwarded upstream: %q", upstreamDebug)
+		}
+	})
+
+	t.Run("identifies the route that matched", func(t *testing.T) {
+		rec := serve("/catalog", "10.1.2.3:5000", "1")
+		if got := rec.Header().Get("X-APIGate-Route"); got != "catalog" {
+			t.Errorf("X-APIGate-Route = %q, want catalog", got)
+		}
+		if got := rec.Header().Get("X-APIGate-Match-Reason"); got != "path exact /catalog" {
+			t.Errorf("X-APIGate-Match-Reason = %q", got)
+		}
+	})
+
+	t.Run("gateway errors are explained too", func(t *testing.T) {
+		rec := serve("/unknown", "10.1.2.3:5000", "1")
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("status = %d, want 401", rec.Code)
+		}
+		if got := rec.Header().Get("X-APIGate-Route"); got != "none" {
+			t.Errorf("X-APIGate-Route = %q, want none", got)
+		}
+		if got := rec.Header().Get("X-APIGate-Upstream"); got != "default" {
+			t.Errorf("X-APIGate-Upstream = %q, want default", got)
+		}
+	})
+
+	t.Run("untrusted source gets no debug headers", func(t *testing.T) {
+		rec := serve("/catalog/42", "203.0.113.9:5000", "1")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", rec.Code)
+		}
+		for _, name := range []string{"X-APIGate-Route", "X-APIGate-Upstream", "X-APIGate-Match-Reason"} {
+			if got := rec.Header().Get(name); got != "" {
+				t.Errorf("%s = %q for untrusted source", name, got)
+			}
+		}
+	})
+
+	t.Run("trusted source without debug header", func(t *testing.T) {
+		rec := serve("/catalog/42", "10.1.2.3:5000", "")
+		if got := rec.Header().Get("X-APIGate-Route"); got != "" {
+			t.Errorf("X-APIGate-Route = %q without debug header", got)
+		}
+	})
+}
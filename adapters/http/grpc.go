@@ -0,0 +1,279 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/proxy"
+	"github.com/artpar/apigate/domain/route"
+)
+
+// GRPCUpstream forwards gRPC calls to an upstream over HTTP/2.
+type GRPCUpstream interface {
+	// ForwardGRPC sends a gRPC call with a streamed request body and returns the
+	// raw response so its body can be streamed and its trailers copied.
+	// A nil upstream uses the default upstream. The caller must close the response body.
+	ForwardGRPC(ctx context.Context, req proxy.Request, body io.Reader, upstream *route.Upstream) (*http.Response, error)
+}
+
+// gRPC status codes returned for gateway errors.
+// See https://grpc.github.io/grpc/core/md_doc_statuscodes.html
+const (
+	grpcInvalidArgument   = 3
+	grpcDeadlineExceeded  = 4
+	grpcPermissionDenied  = 7
+	grpcResourceExhausted = 8
+	grpcUnimplemented     = 12
+	grpcInternal          = 13
+	grpcUnavailable       = 14
+	grpcUnauthenticated   = 16
+)
+
+// ForwardGRPC sends a gRPC call to the upstream over HTTP/2.
+func (u *UpstreamClient) ForwardGRPC(ctx context.Context, req proxy.Request, body io.Reader, upstream *route.Upstream) (*http.Response, error) {
+	baseURL := u.baseURL
+	if upstream != nil {
+		parsed, err := url.Parse(upstream.BaseURL)
+		if err != nil {
+			return nil, fmt.Errorf("parse upstream URL: %w", err)
+		}
+		baseURL = parsed
+	}
+
+	upstreamURL := baseURL.ResolveReference(&url.URL{
+		Path:     req.Path,
+		RawQuery: req.Query,
+	})
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, upstreamURL.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("create grpc request: %w", err)
+	}
+
+	// Copy headers (gRPC metadata) except Host, which becomes :authority for the upstream
+	for k, v := range req.Headers {
+		if strings.EqualFold(k, "Host") {
+			continue
+		}
+		httpReq.Header.Set(k, v)
+	}
+
+	// Required by gRPC servers to signal trailer support
+	httpReq.Header.Set("Te", "trailers")
+
+	httpReq.Header.Set("X-Forwarded-For", req.RemoteIP)
+	if req.TraceID != "" {
+		httpReq.Header.Set("X-Request-ID", req.TraceID)
+	}
+
+	clients, err := u.clientsFor(upstream)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := clients.grpc.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("execute grpc request: %w", err)
+	}
+	return resp, nil
+}
+
+// handleGRPCRequest proxies a gRPC call. The request and response bodies are
+// streamed in both directions concurrently so bidirectional streaming works,
+// and upstream trailers (grpc-status, grpc-message) are passed through.
+func (h *ProxyHandler) handleGRPCRequest(w http.ResponseWriter, r *http.Request, ctx context.Context, req proxy.Request) {
+	start := time.Now()
+
+	// Auth, rate limiting and route resolution happen once per call
+	result := h.service.HandleStreaming(ctx, req, nil)
+	if result.Release != nil {
+		defer result.Release()
+	}
+	if result.Error != nil {
+		for k, v := range result.Headers {
+			w.Header().Set(k, v)
+		}
+		writeGRPCError(w, result.Error)
+		h.logAccess(req, result.Auth, result.Error.Status, 0, 0, time.Since(start))
+		return
+	}
+
+	grpcReq := req
+	if result.ModifiedRequest != nil {
+		grpcReq = *result.ModifiedRequest
+	}
+
+	// Long-lived streams must not be cut off by the server's read/write
+	// timeouts or the router deadline; the client ends them by disconnecting
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+	ctx, cancel := withoutRequestTimeout(ctx)
+	defer cancel()
+
+	reqBody := &countingReader{r: r.Body}
+	resp, err := h.grpcUpstream.ForwardGRPC(ctx, grpcReq, reqBody, result.RouteUpstream)
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	h.service.RecordUpstreamResult(result.RouteUpstream, app.UpstreamOutcome(status, err))
+	if err != nil {
+		h.logger.Error().
+			Err(err).
+			Str("path", grpcReq.Path).
+			Msg("grpc upstream error")
+		writeGRPCError(w, &proxy.ErrUpstreamError)
+		h.logAccess(req, result.Auth, proxy.ErrUpstreamError.Status, reqBody.n.Load(), 0, time.Since(start))
+		return
+	}
+	defer resp.Body.Close()
+
+	for k, vv := range resp.Header {
+		if isHopByHopHeader(k) {
+			continue
+		}
+		for _, v := range vv {
+			w.Header().Add(k, v)
+		}
+	}
+	for k, v := range result.Headers {
+		w.Header().Set(k, v)
+	}
+	w.WriteHeader(resp.StatusCode)
+
+	// Trailers-only responses (errors) carry grpc-status in the headers and must
+	// reach the client as a single end-of-stream HEADERS frame, so only flush
+	// early when a body follows.
+	if resp.Header.Get("Grpc-Status") == "" {
+		_ = rc.Flush()
+	}
+
+	var respBytes int64
+	buf := make([]byte, 32*1024)
+	for {
+		n, readErr := resp.Body.Read(buf)
+		if n > 0 {
+			respBytes += int64(n)
+			if _, writeErr := w.Write(buf[:n]); writeErr != nil {
+				h.logger.Error().Err(writeErr).Msg("failed to write grpc response")
+				break
+			}
+			_ = rc.Flush()
+		}
+		if readErr != nil {
+			if readErr != io.EOF {
+				h.logger.Error().Err(readErr).Msg("error reading grpc response")
+			}
+			break
+		}
+	}
+
+	// Trailers are only known once the upstream body has been fully read
+	for k, vv := range resp.Trailer {
+		for _, v := range vv {
+			w.Header().Add(http.TrailerPrefix+k, v)
+		}
+	}
+
+	latencyMs := time.Since(start).Milliseconds()
+	grpcStatus := resp.Trailer.Get("Grpc-Status")
+	if grpcStatus == "" {
+		// Trailers-only responses carry the status in the headers
+		grpcStatus = resp.Header.Get("Grpc-Status")
+	}
+
+	meteringValue := 1.0
+	if result.StreamingResponse != nil && result.StreamingResponse.MatchedRoute != nil {
+		if expr := result.StreamingResponse.MatchedRoute.MeteringExpr; expr != "" {
+			meteringValue = h.service.EvalStreamingMetering(ctx, expr, resp.StatusCode, respBytes, nil, nil, result.Auth)
+		}
+	}
+
+	if result.StreamingResponse != nil {
+		h.service.RecordStreamingUsage(
+			result.StreamingResponse,
+			resp.StatusCode,
+			reqBody.n.Load(),
+			respBytes,
+			latencyMs,
+			meteringValue,
+			req.RemoteIP,
+			req.UserAgent,
+		)
+	}
+
+	h.logAccess(req, result.Auth, resp.StatusCode, reqBody.n.Load(), respBytes, time.Since(start))
+	h.logger.Info().
+		Str("method", req.Method).
+		Str("path", req.Path).
+		Str("type", "grpc").
+		Int("status", resp.StatusCode).
+		Str("grpc_status", grpcStatus).
+		Int64("bytes", respBytes).
+		Int64("latency_ms", latencyMs).
+		Msg("grpc request completed")
+}
+
+// writeGRPCError writes a gateway error as a trailers-only gRPC response so
+// gRPC clients see a proper status instead of an HTTP error.
+func writeGRPCError(w http.ResponseWriter, err *proxy.ErrorResponse) {
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Grpc-Status", strconv.Itoa(grpcStatusCode(err.Status)))
+	w.Header().Set("Grpc-Message", url.PathEscape(err.Message))
+	w.WriteHeader(http.StatusOK)
+}
+
+// grpcStatusCode maps an HTTP status to the closest gRPC status code.
+func grpcStatusCode(status int) int {
+	switch status {
+	case http.StatusBadRequest:
+		return grpcInvalidArgument
+	case http.StatusUnauthorized:
+		return grpcUnauthenticated
+	case http.StatusForbidden:
+		return grpcPermissionDenied
+	case http.StatusNotFound:
+		return grpcUnimplemented
+	case http.StatusPaymentRequired, http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		return grpcResourceExhausted
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return grpcUnavailable
+	case http.StatusGatewayTimeout:
+		return grpcDeadlineExceeded
+	default:
+		return grpcInternal
+	}
+}
+
+// isHopByHopHeader returns true for headers that must not be forwarded by a proxy.
+func isHopByHopHeader(name string) bool {
+	switch strings.ToLower(name) {
+	case "connection", "keep-alive", "proxy-authenticate", "proxy-authorization",
+		"te", "trailer", "trailers", "transfer-encoding", "upgrade":
+		return true
+	}
+	return false
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// Ensure interface compliance.
+var _ GRPCUpstream = (*UpstreamClient)(nil)
@@ -0,0 +1,270 @@
+package http_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/domain/route"
+	"github.com/artpar/apigate/domain/usage"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	testpb "google.golang.org/grpc/interop/grpc_testing"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// echoTestServer echoes payloads back and can fail unary calls on request.
+type echoTestServer struct {
+	testpb.UnimplementedTestServiceServer
+
+	mu      sync.Mutex
+	apiKeys []string // x-api-key metadata seen by the upstream
+}
+
+func (s *echoTestServer) UnaryCall(ctx context.Context, in *testpb.SimpleRequest) (*testpb.SimpleResponse, error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		s.mu.Lock()
+		s.apiKeys = append(s.apiKeys, md.Get("x-api-key")...)
+		s.mu.Unlock()
+	}
+	if st := in.GetResponseStatus(); st != nil && st.GetCode() != 0 {
+		return nil, status.Error(codes.Code(st.GetCode()), st.GetMessage())
+	}
+	grpc.SetTrailer(ctx, metadata.Pairs("x-backend-trailer", "done"))
+	return &testpb.SimpleResponse{Payload: in.GetPayload()}, nil
+}
+
+func (s *echoTestServer) FullDuplexCall(stream testpb.TestService_FullDuplexCallServer) error {
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(&testpb.StreamingOutputCallResponse{Payload: req.GetPayload()}); err != nil {
+			return err
+		}
+	}
+}
+
+// syncUsageRecorder is a concurrency-safe usage recorder.
+type syncUsageRecorder struct {
+	mu     sync.Mutex
+	events []usage.Event
+}
+
+func (r *syncUsageRecorder) Record(e usage.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *syncUsageRecorder) Flush(ctx context.Context) error { return nil }
+func (r *syncUsageRecorder) Close() error                    { return nil }
+
+func (r *syncUsageRecorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.events)
+}
+
+func TestProxy_GRPC(t *testing.T) {
+	// In-process gRPC upstream (plaintext HTTP/2)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	backend := &echoTestServer{}
+	grpcServer := grpc.NewServer()
+	testpb.RegisterTestServiceServer(grpcServer, backend)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	backendURL := "http://" + lis.Addr().String()
+	upstreams := []route.Upstream{{ID: "grpc", Name: "grpc", BaseURL: backendURL, Enabled: true}}
+	routes := []route.Route{{
+		ID: "grpc", Name: "grpc", PathPattern: "/grpc.testing.TestService/*", MatchType: route.MatchPrefix,
+		UpstreamID: "grpc", Protocol: route.ProtocolGRPC, AuthRequired: true, Enabled: true,
+	}}
+
+	keys := memory.NewKeyStore()
+	users := memory.NewUserStore()
+	rawKey := "ak_fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"
+	keyHash, _ := bcrypt.GenerateFromPassword([]byte(rawKey), bcrypt.MinCost)
+	keys.Create(context.Background(), key.Key{
+		ID: "key-1", UserID: "user-1", Hash: keyHash, Prefix: rawKey[:12], CreatedAt: baseTime.Add(-time.Hour),
+	})
+	users.Create(context.Background(), ports.User{ID: "user-1", Email: "grpc@example.com", PlanID: "free", Status: "active"})
+
+	clk := clock.NewFake(baseTime)
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: backendURL})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	defer client.Close()
+
+	recorder := &syncUsageRecorder{}
+	service := app.NewProxyService(app.ProxyDeps{
+		Keys:      keys,
+		Users:     users,
+		RateLimit: memory.NewRateLimitStore(),
+		Usage:     recorder,
+		Upstream:  client,
+		Clock:     clk,
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100,
+		RateWindow: 60,
+		Plans:      []plan.Plan{{ID: "free", Name: "Free", RateLimitPerMinute: 600, RequestsPerMonth: 1000}},
+	})
+
+	routeService := app.NewRouteService(&staticRouteStore{routes: routes}, &staticUpstreamStore{upstreams: upstreams},
+		clk, zerolog.Nop(), app.RouteServiceConfig{})
+	if err := routeService.Reload(context.Background()); err != nil {
+		t.Fatalf("reload routes: %v", err)
+	}
+	service.SetRouteService(routeService)
+
+	handler := apihttp.NewProxyHandler(service, zerolog.Nop())
+	handler.SetGRPCUpstream(client)
+
+	// Gateway accepting plaintext HTTP/2 (h2c), as gRPC clients require. The
+	// router deadline is short so calls can be held open past it.
+	router := apihttp.NewRouterWithConfig(handler, apihttp.NewHealthHandler(nil), zerolog.Nop(),
+		apihttp.RouterConfig{RequestTimeout: 200 * time.Millisecond})
+	gateway := httptest.NewUnstartedServer(router)
+	gateway.Config.Protocols = new(http.Protocols)
+	gateway.Config.Protocols.SetHTTP1(true)
+	gateway.Config.Protocols.SetUnencryptedHTTP2(true)
+	gateway.Start()
+	defer gateway.Close()
+
+	conn, err := grpc.NewClient(strings.TrimPrefix(gateway.URL, "http://"),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial gateway: %v", err)
+	}
+	defer conn.Close()
+	tc := testpb.NewTestServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	authCtx := metadata.AppendToOutgoingContext(ctx, "x-api-key", rawKey)
+
+	t.Run("unary call with trailers", func(t *testing.T) {
+		var trailer metadata.MD
+		resp, err := tc.UnaryCall(authCtx, &testpb.SimpleRequest{Payload: &testpb.Payload{Body: []byte("hello")}},
+			grpc.Trailer(&trailer))
+		if err != nil {
+			t.Fatalf("UnaryCall: %v", err)
+		}
+		if got := string(resp.GetPayload().GetBody()); got != "hello" {
+			t.Errorf("payload = %q, want hello", got)
+		}
+		if got := trailer.Get("x-backend-trailer"); len(got) != 1 || got[0] != "done" {
+			t.Errorf("trailer x-backend-trailer = %v, want [done]", got)
+		}
+	})
+
+	t.Run("upstream status is passed through", func(t *testing.T) {
+		_, err := tc.UnaryCall(authCtx, &testpb.SimpleRequest{
+			ResponseStatus: &testpb.EchoStatus{Code: int32(codes.NotFound), Message: "no such widget"},
+		})
+		st, _ := status.FromError(err)
+		if st.Code() != codes.NotFound || st.Message() != "no such widget" {
+			t.Errorf("status = %v %q, want NotFound %q", st.Code(), st.Message(), "no such widget")
+		}
+	})
+
+	t.Run("bidirectional streaming", func(t *testing.T) {
+		stream, err := tc.FullDuplexCall(authCtx)
+		if err != nil {
+			t.Fatalf("FullDuplexCall: %v", err)
+		}
+		// Each reply must arrive before the next message is sent, proving the
+		// proxy streams in both directions rather than buffering the call.
+		for _, msg := range []string{"one", "two", "three"} {
+			if err := stream.Send(&testpb.StreamingOutputCallRequest{Payload: &testpb.Payload{Body: []byte(msg)}}); err != nil {
+				t.Fatalf("send %s: %v", msg, err)
+			}
+			resp, err := stream.Recv()
+			if err != nil {
+				t.Fatalf("recv %s: %v", msg, err)
+			}
+			if got := string(resp.GetPayload().GetBody()); got != msg {
+				t.Errorf("echo = %q, want %q", got, msg)
+			}
+		}
+		if err := stream.CloseSend(); err != nil {
+			t.Fatalf("close send: %v", err)
+		}
+		if _, err := stream.Recv(); err != io.EOF {
+			t.Errorf("final recv = %v, want io.EOF", err)
+		}
+	})
+
+	t.Run("stream outlives the router deadline", func(t *testing.T) {
+		stream, err := tc.FullDuplexCall(authCtx)
+		if err != nil {
+			t.Fatalf("FullDuplexCall: %v", err)
+		}
+		for i, msg := range []string{"before", "after"} {
+			if i > 0 {
+				time.Sleep(400 * time.Millisecond)
+			}
+			if err := stream.Send(&testpb.StreamingOutputCallRequest{Payload: &testpb.Payload{Body: []byte(msg)}}); err != nil {
+				t.Fatalf("send %s: %v", msg, err)
+			}
+			resp, err := stream.Recv()
+			if err != nil {
+				t.Fatalf("recv %s: %v", msg, err)
+			}
+			if got := string(resp.GetPayload().GetBody()); got != msg {
+				t.Errorf("echo = %q, want %q", got, msg)
+			}
+		}
+		if err := stream.CloseSend(); err != nil {
+			t.Fatalf("close send: %v", err)
+		}
+		if _, err := stream.Recv(); err != io.EOF {
+			t.Errorf("final recv = %v, want io.EOF", err)
+		}
+	})
+
+	t.Run("missing api key is unauthenticated", func(t *testing.T) {
+		_, err := tc.UnaryCall(ctx, &testpb.SimpleRequest{})
+		if st, _ := status.FromError(err); st.Code() != codes.Unauthenticated {
+			t.Errorf("status = %v, want Unauthenticated", st.Code())
+		}
+	})
+
+	// Every authenticated call is metered once; the rejected call is not
+	if got := recorder.count(); got != 4 {
+		t.Errorf("usage events = %d, want 4", got)
+	}
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+	if len(backend.apiKeys) != 0 {
+		t.Errorf("API key leaked to upstream metadata: %v", backend.apiKeys)
+	}
+}
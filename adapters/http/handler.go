@@ -4,18 +4,25 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
+	"net/netip"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/artpar/apigate/adapters/accesslog"
 	"github.com/artpar/apigate/adapters/metrics"
 	"github.com/artpar/apigate/app"
 	_ "github.com/artpar/apigate/docs/swagger" // swagger docs
 	"github.com/artpar/apigate/domain/proxy"
+	"github.com/artpar/apigate/domain/route"
 	"github.com/artpar/apigate/domain/streaming"
+	"github.com/artpar/apigate/pkg/clientip"
 	"github.com/artpar/apigate/pkg/jsonapi"
 	"github.com/artpar/apigate/ports"
 	"github.com/go-chi/chi/v5"
@@ -51,32 +58,69 @@ type HealthResponse struct {
 type ProxyHandler struct {
 	service           *app.ProxyService
 	streamingUpstream ports.StreamingUpstream
+	grpcUpstream      GRPCUpstream
+	wsUpstream        WebSocketUpstream
 	logger            zerolog.Logger
 	metrics           *metrics.Collector
+	accessLog         *accesslog.Logger
+	trustedProxies    []netip.Prefix
+	debugSources      []netip.Prefix // Client IPs allowed to request route debug headers
+	compression       proxy.CompressionConfig
+	maintenance       atomic.Pointer[proxy.Maintenance] // Swapped when settings change
+
+	// Open WebSocket connections, which http.Server.Shutdown doesn't wait for
+	wsMu      sync.Mutex
+	wsConns   sync.WaitGroup
+	wsClosed  bool
+	wsClosing chan struct{}
 }
 
 // NewProxyHandler creates a new HTTP proxy handler.
 func NewProxyHandler(service *app.ProxyService, logger zerolog.Logger) *ProxyHandler {
 	return &ProxyHandler{
-		service: service,
-		logger:  logger,
+		service:   service,
+		logger:    logger,
+		wsClosing: make(chan struct{}),
 	}
 }
 
 // NewProxyHandlerWithMetrics creates a new HTTP proxy handler with metrics.
 func NewProxyHandlerWithMetrics(service *app.ProxyService, logger zerolog.Logger, m *metrics.Collector) *ProxyHandler {
 	return &ProxyHandler{
-		service: service,
-		logger:  logger,
-		metrics: m,
+		service:   service,
+		logger:    logger,
+		metrics:   m,
+		wsClosing: make(chan struct{}),
 	}
 }
 
+// SetAccessLog sets the access log that receives one entry per proxied request.
+func (h *ProxyHandler) SetAccessLog(log *accesslog.Logger) {
+	h.accessLog = log
+}
+
+// SetTrustedProxies sets the proxies whose X-Forwarded-For and X-Real-IP
+// headers are honored when resolving the client IP. Requests from any other
+// peer are identified by their connection address.
+func (h *ProxyHandler) SetTrustedProxies(proxies []netip.Prefix) {
+	h.trustedProxies = proxies
+}
+
 // SetStreamingUpstream sets the streaming upstream for SSE/streaming support.
 func (h *ProxyHandler) SetStreamingUpstream(upstream ports.StreamingUpstream) {
 	h.streamingUpstream = upstream
 }
 
+// SetGRPCUpstream sets the upstream used for routes with protocol grpc.
+func (h *ProxyHandler) SetGRPCUpstream(upstream GRPCUpstream) {
+	h.grpcUpstream = upstream
+}
+
+// SetWebSocketUpstream sets the upstream used for WebSocket upgrades on routes with protocol websocket.
+func (h *ProxyHandler) SetWebSocketUpstream(upstream WebSocketUpstream) {
+	h.wsUpstream = upstream
+}
+
 // ServeHTTP handles incoming proxy requests.
 //
 //	@Summary		Proxy request to upstream
@@ -100,16 +144,73 @@ func (h *ProxyHandler) SetStreamingUpstream(upstream ports.StreamingUpstream) {
 func (h *ProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	// Maintenance mode pauses proxying before anything reaches an upstream
+	if m := h.maintenance.Load(); m != nil && m.Enabled {
+		writeMaintenance(w, r, *m)
+		return
+	}
+
 	// Extract auth token from header or query
 	// Note: Empty token is allowed for public routes (AuthRequired=false)
 	// The proxy service will detect if it's an API key or JWT session token by format
 	authToken := extractAPIKey(r)
 
-	// Read request body
-	var body []byte
+	// Build proxy request
+	req := proxy.Request{
+		APIKey:    authToken,
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Query:     r.URL.RawQuery,
+		Headers:   extractHeaders(r),
+		RemoteIP:  clientip.FromRequest(r, h.trustedProxies),
+		UserAgent: r.UserAgent(),
+		TraceID:   middleware.GetReqID(ctx),
+		Scheme:    "http",
+	}
+	if r.TLS != nil {
+		req.Scheme = "https"
+		req.ClientCerts = r.TLS.PeerCertificates
+	}
+
+	// gRPC calls stream the request body, so it must not be read up front
+	if h.grpcUpstream != nil && h.service.IsGRPC(req) {
+		h.handleGRPCRequest(w, r, ctx, req)
+		return
+	}
+
+	// WebSocket upgrades hand the connection over to the upstream
+	if h.wsUpstream != nil && isWebSocketUpgrade(r) && h.service.IsWebSocket(req) {
+		h.handleWebSocketRequest(w, r, ctx, req)
+		return
+	}
+
+	// Proxied requests are bounded by the upstream and route timeouts, not the
+	// router deadline, so event streams can stay open
+	ctx, cancel := withoutRequestTimeout(ctx)
+	defer cancel()
+
+	// Trusted clients can ask how the request was routed
+	if h.wantsDebug(r, req.RemoteIP) {
+		trace := &app.RouteTrace{}
+		ctx = app.WithRouteTrace(ctx, trace)
+		w = &debugWriter{ResponseWriter: w, trace: trace}
+		delete(req.Headers, http.CanonicalHeaderKey(headerDebug))
+	}
+
+	// Routes with a CORS policy answer preflights here and add CORS headers to responses
+	w, ok := h.handleCORS(w, r, req)
+	if !ok {
+		return
+	}
+
+	// Read request body, enforcing the route (or global) size limit
 	if r.Body != nil {
-		var err error
-		body, err = io.ReadAll(io.LimitReader(r.Body, 10<<20)) // 10MB limit
+		limit := h.service.RequestBodyLimit(req)
+		reader := io.Reader(r.Body)
+		if limit > 0 {
+			reader = io.LimitReader(r.Body, limit+1)
+		}
+		body, err := io.ReadAll(reader)
 		if err != nil {
 			h.logger.Error().Err(err).Msg("failed to read request body")
 			writeError(w, &proxy.ErrorResponse{
@@ -119,19 +220,11 @@ func (h *ProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			})
 			return
 		}
-	}
-
-	// Build proxy request
-	req := proxy.Request{
-		APIKey: authToken,
-		Method:       r.Method,
-		Path:         r.URL.Path,
-		Query:        r.URL.RawQuery,
-		Headers:      extractHeaders(r),
-		Body:         body,
-		RemoteIP:     extractIP(r),
-		UserAgent:    r.UserAgent(),
-		TraceID:      middleware.GetReqID(ctx),
+		if limit > 0 && int64(len(body)) > limit {
+			writeError(w, &proxy.ErrRequestTooLarge)
+			return
+		}
+		req.Body = body
 	}
 
 	// Check if this should be a streaming request
@@ -141,10 +234,11 @@ func (h *ProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Handle request (buffered)
+	start := time.Now()
 	result := h.service.Handle(ctx, req)
 
 	// Log request
-	h.logRequest(ctx, req, result)
+	h.logRequest(ctx, req, result, time.Since(start))
 
 	// Write response
 	if result.Error != nil {
@@ -161,10 +255,23 @@ func (h *ProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set(k, v)
 	}
 
+	// Event streams detected from the upstream's response are relayed as
+	// they arrive rather than buffered
+	if result.Response.Stream != nil {
+		defer result.Response.Stream.Close()
+		w.Header().Del("Content-Length")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(result.Response.Status)
+		h.relayStream(w, result.Response.Stream)
+		return
+	}
+
+	body := h.compressResponse(w, r, result.Response)
+
 	// Write response
 	w.WriteHeader(result.Response.Status)
-	if len(result.Response.Body) > 0 {
-		if _, err := w.Write(result.Response.Body); err != nil {
+	if len(body) > 0 {
+		if _, err := w.Write(body); err != nil {
 			h.logger.Error().Err(err).Msg("failed to write response body")
 		}
 	}
@@ -176,6 +283,9 @@ func (h *ProxyHandler) handleStreamingRequest(w http.ResponseWriter, r *http.Req
 
 	// Auth and rate limiting (reuse the streaming handle method)
 	result := h.service.HandleStreaming(ctx, req, nil)
+	if result.Release != nil {
+		defer result.Release()
+	}
 
 	if result.Error != nil {
 		// Add rate limit headers even on error
@@ -183,6 +293,7 @@ func (h *ProxyHandler) handleStreamingRequest(w http.ResponseWriter, r *http.Req
 			w.Header().Set(k, v)
 		}
 		writeError(w, result.Error)
+		h.logAccess(req, result.Auth, result.Error.Status, int64(len(req.Body)), 0, time.Since(start))
 		return
 	}
 
@@ -200,6 +311,7 @@ func (h *ProxyHandler) handleStreamingRequest(w http.ResponseWriter, r *http.Req
 	} else {
 		streamResp, err = h.streamingUpstream.ForwardStreaming(ctx, streamingReq)
 	}
+	h.service.RecordUpstreamResult(result.RouteUpstream, app.UpstreamOutcome(streamResp.Status, err))
 	if err != nil {
 		upstreamURL := ""
 		if result.RouteUpstream != nil {
@@ -211,7 +323,13 @@ func (h *ProxyHandler) handleStreamingRequest(w http.ResponseWriter, r *http.Req
 			Bool("has_route_upstream", result.RouteUpstream != nil).
 			Str("upstream_url", upstreamURL).
 			Msg("streaming upstream error")
-		writeError(w, &proxy.ErrUpstreamError)
+		errResp := &proxy.ErrUpstreamError
+		var timeout *proxy.UpstreamTimeoutError
+		if errors.As(err, &timeout) {
+			errResp = proxy.TimeoutResponse(timeout)
+		}
+		writeError(w, errResp)
+		h.logAccess(req, result.Auth, errResp.Status, int64(len(req.Body)), 0, time.Since(start))
 		return
 	}
 
@@ -227,6 +345,14 @@ func (h *ProxyHandler) handleStreamingRequest(w http.ResponseWriter, r *http.Req
 		}
 	}
 
+	// Rewrite upstream URLs as the body streams through
+	if result.StreamingResponse != nil {
+		if pairs := h.service.ResponseURLRewrites(result.StreamingResponse.MatchedRoute, result.RouteUpstream, streamingReq, streamResp.Headers); pairs != nil {
+			streamResp.Body = streaming.NewReplaceReader(streamResp.Body, pairs...)
+			delete(streamResp.Headers, "Content-Length")
+		}
+	}
+
 	// Wrap the body to track bytes (accumulate if metering needs it)
 	streamReader := streaming.NewStreamReader(streamResp.Body, needsAccumulation)
 	defer func() {
@@ -235,8 +361,12 @@ func (h *ProxyHandler) handleStreamingRequest(w http.ResponseWriter, r *http.Req
 		}
 	}()
 
-	// Copy response headers
-	for k, v := range streamResp.Headers {
+	// Copy response headers, after the route's response header rules
+	respHeaders := streamResp.Headers
+	if result.StreamingResponse != nil {
+		respHeaders = h.service.ApplyResponseHeaders(result.StreamingResponse.MatchedRoute, result.Auth, respHeaders)
+	}
+	for k, v := range respHeaders {
 		w.Header().Set(k, v)
 	}
 
@@ -257,27 +387,7 @@ func (h *ProxyHandler) handleStreamingRequest(w http.ResponseWriter, r *http.Req
 	w.WriteHeader(streamResp.Status)
 
 	// Stream the response
-	flusher, canFlush := w.(http.Flusher)
-
-	buf := make([]byte, 4096)
-	for {
-		n, readErr := streamReader.Read(buf)
-		if n > 0 {
-			if _, writeErr := w.Write(buf[:n]); writeErr != nil {
-				h.logger.Error().Err(writeErr).Msg("failed to write streaming response")
-				break
-			}
-			if canFlush {
-				flusher.Flush()
-			}
-		}
-		if readErr != nil {
-			if readErr != io.EOF {
-				h.logger.Error().Err(readErr).Msg("error reading stream")
-			}
-			break
-		}
-	}
+	h.relayStream(w, streamReader)
 
 	latencyMs := time.Since(start).Milliseconds()
 
@@ -310,6 +420,7 @@ func (h *ProxyHandler) handleStreamingRequest(w http.ResponseWriter, r *http.Req
 	)
 
 	// Log streaming request
+	h.logAccess(req, result.Auth, streamResp.Status, int64(len(req.Body)), streamMetrics.TotalBytes, time.Since(start))
 	h.logger.Info().
 		Str("method", req.Method).
 		Str("path", req.Path).
@@ -321,7 +432,33 @@ func (h *ProxyHandler) handleStreamingRequest(w http.ResponseWriter, r *http.Req
 		Msg("streaming request completed")
 }
 
-func (h *ProxyHandler) logRequest(ctx context.Context, req proxy.Request, result app.HandleResult) {
+// relayStream copies src to w, flushing after every chunk so the client
+// receives data as soon as the upstream sends it.
+func (h *ProxyHandler) relayStream(w http.ResponseWriter, src io.Reader) {
+	flusher, canFlush := w.(http.Flusher)
+
+	buf := make([]byte, 4096)
+	for {
+		n, readErr := src.Read(buf)
+		if n > 0 {
+			if _, writeErr := w.Write(buf[:n]); writeErr != nil {
+				h.logger.Error().Err(writeErr).Msg("failed to write streaming response")
+				return
+			}
+			if canFlush {
+				flusher.Flush()
+			}
+		}
+		if readErr != nil {
+			if readErr != io.EOF {
+				h.logger.Error().Err(readErr).Msg("error reading stream")
+			}
+			return
+		}
+	}
+}
+
+func (h *ProxyHandler) logRequest(ctx context.Context, req proxy.Request, result app.HandleResult, elapsed time.Duration) {
 	event := h.logger.Info()
 
 	planID := ""
@@ -331,6 +468,15 @@ func (h *ProxyHandler) logRequest(ctx context.Context, req proxy.Request, result
 		userID = result.Auth.UserID
 	}
 
+	if h.metrics != nil {
+		h.recordProxyMetrics(req, result, elapsed)
+	}
+	if result.Error != nil {
+		h.logAccess(req, result.Auth, result.Error.Status, int64(len(req.Body)), 0, elapsed)
+	} else {
+		h.logAccess(req, result.Auth, result.Response.Status, int64(len(req.Body)), int64(len(result.Response.Body)), elapsed)
+	}
+
 	if result.Error != nil {
 		event = h.logger.Warn()
 		event.Int("error_status", result.Error.Status)
@@ -351,6 +497,10 @@ func (h *ProxyHandler) logRequest(ctx context.Context, req proxy.Request, result
 				h.metrics.AuthFailures.WithLabelValues(result.Error.Code).Inc()
 			case "rate_limit_exceeded":
 				h.metrics.RateLimitHits.WithLabelValues(planID, userID).Inc()
+			case "quota_exceeded":
+				h.metrics.QuotaRejections.WithLabelValues(planID).Inc()
+			case "upstream_error", "upstream_timeout", "circuit_open", "response_too_large":
+				h.metrics.UpstreamErrors.WithLabelValues(result.Error.Code).Inc()
 			}
 		}
 	} else {
@@ -360,6 +510,8 @@ func (h *ProxyHandler) logRequest(ctx context.Context, req proxy.Request, result
 		// Record success metrics
 		if h.metrics != nil {
 			path := metrics.NormalizePath(req.Path)
+			h.metrics.UpstreamDuration.WithLabelValues(req.Method, statusLabel(result.Response.Status)).
+				Observe(float64(result.Response.LatencyMs) / 1000)
 			h.metrics.RequestsTotal.WithLabelValues(req.Method, path, "2xx", planID).Inc()
 			h.metrics.UsageRequests.WithLabelValues(userID, planID).Inc()
 
@@ -389,6 +541,47 @@ func (h *ProxyHandler) logRequest(ctx context.Context, req proxy.Request, result
 	event.Msg("proxy request")
 }
 
+// logAccess writes the access log entry for a proxied request. The key prefix
+// is the lookup prefix of the presented credential, never the full key.
+func (h *ProxyHandler) logAccess(req proxy.Request, auth *proxy.AuthContext, status int, bytesIn, bytesOut int64, elapsed time.Duration) {
+	if h.accessLog == nil {
+		return
+	}
+	entry := accesslog.Entry{
+		Timestamp: time.Now().UTC(),
+		Method:    req.Method,
+		Path:      req.Path,
+		Status:    status,
+		LatencyMs: elapsed.Milliseconds(),
+		BytesIn:   bytesIn,
+		BytesOut:  bytesOut,
+	}
+	if len(req.APIKey) >= 12 {
+		entry.KeyPrefix = req.APIKey[:12]
+	}
+	if auth != nil {
+		entry.UserID = auth.UserID
+	}
+	if err := h.accessLog.Log(entry); err != nil {
+		h.logger.Error().Err(err).Msg("failed to write access log")
+	}
+}
+
+// recordProxyMetrics records the request count and handling time for the route
+// a proxied request matched. Requests not matching a route are labelled "default".
+func (h *ProxyHandler) recordProxyMetrics(req proxy.Request, result app.HandleResult, elapsed time.Duration) {
+	routeName := h.service.RouteName(req)
+	if routeName == "" {
+		routeName = "default"
+	}
+	status := result.Response.Status
+	if result.Error != nil {
+		status = result.Error.Status
+	}
+	h.metrics.ProxyRequests.WithLabelValues(routeName, strconv.Itoa(status)).Inc()
+	h.metrics.ProxyDuration.WithLabelValues(routeName).Observe(elapsed.Seconds())
+}
+
 // extractAPIKey extracts the API key from the request.
 // Supports: Authorization header (Bearer token), X-API-Key header, api_key query param.
 func extractAPIKey(r *http.Request) string {
@@ -441,37 +634,29 @@ func extractHeaders(r *http.Request) map[string]string {
 	return headers
 }
 
-// extractIP extracts the client IP from the request.
-func extractIP(r *http.Request) string {
-	// Check X-Forwarded-For header
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		parts := strings.Split(xff, ",")
-		if len(parts) > 0 {
-			return strings.TrimSpace(parts[0])
-		}
-	}
-
-	// Check X-Real-IP header
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		return xri
+// writeError writes a JSON:API error response. Field errors become one
+// error object each, with the JSON pointer of the field as its source.
+func writeError(w http.ResponseWriter, err *proxy.ErrorResponse) {
+	if len(err.Fields) == 0 {
+		jsonapi.WriteError(w, jsonapi.Error{
+			Status: strconv.Itoa(err.Status),
+			Code:   err.Code,
+			Title:  err.Code,
+			Detail: err.Message,
+		})
+		return
 	}
-
-	// Fall back to RemoteAddr
-	addr := r.RemoteAddr
-	if idx := strings.LastIndex(addr, ":"); idx != -1 {
-		return addr[:idx]
+	errs := make([]jsonapi.Error, len(err.Fields))
+	for i, f := range err.Fields {
+		errs[i] = jsonapi.Error{
+			Status: strconv.Itoa(err.Status),
+			Code:   err.Code,
+			Title:  err.Message,
+			Detail: f.Message,
+			Source: &jsonapi.ErrorSource{Pointer: f.Pointer},
+		}
 	}
-	return addr
-}
-
-// writeError writes a JSON:API error response.
-func writeError(w http.ResponseWriter, err *proxy.ErrorResponse) {
-	jsonapi.WriteError(w, jsonapi.Error{
-		Status: strconv.Itoa(err.Status),
-		Code:   err.Code,
-		Title:  err.Code,
-		Detail: err.Message,
-	})
+	jsonapi.WriteError(w, errs...)
 }
 
 // HealthHandler provides health check endpoints.
@@ -551,20 +736,23 @@ func Version(w http.ResponseWriter, r *http.Request) {
 // RouterConfig holds optional configuration for the router.
 type RouterConfig struct {
 	Metrics               *metrics.Collector
-	MetricsHandler        http.Handler  // Optional metrics exporter handler (for /metrics endpoint)
+	MetricsHandler        http.Handler                    // Optional metrics exporter handler (for /metrics endpoint)
+	MetricsAuth           func(http.Handler) http.Handler // Optional middleware protecting /metrics (e.g. admin auth)
+	TrustedProxies        []netip.Prefix                  // Proxies whose X-Forwarded-For/X-Real-IP headers are honored
 	EnableOpenAPI         bool
-	AdminHandler          http.Handler  // Optional admin API handler
-	AuthHandler           http.Handler  // Optional auth API handler (mounted at /auth as alias for /admin auth endpoints)
-	WebHandler            http.Handler  // Optional web UI handler (enabled by default if provided, unless WebUIEnabled is set to false)
-	WebUIEnabled          *bool         // Whether to enable web UI (default: true if WebHandler provided, false otherwise). Use pointer to distinguish between "not set" and "explicitly false"
-	WebUIBasePath         string        // Base path to mount web UI (default: "" = root)
-	PortalHandler         http.Handler  // Optional user portal handler
-	PortalAuthHandler     http.Handler  // Optional JSON API auth handler (mounted at /api/portal/auth for SPA frontends)
-	DocsHandler           http.Handler  // Optional developer documentation portal handler
-	ModuleHandler         http.Handler  // Optional declarative module handler (mounted at /api/v2)
-	PaymentWebhookHandler http.Handler  // Optional payment webhook handler for Stripe/Paddle/LemonSqueezy
-	MeterHandler          http.Handler  // Optional metering API handler (mounted at /api/v1/meter)
-	RouteService          interface{}   // Optional route service for priority-based routing (uses reflection to avoid circular dependency)
+	AdminHandler          http.Handler // Optional admin API handler
+	AuthHandler           http.Handler // Optional auth API handler (mounted at /auth as alias for /admin auth endpoints)
+	WebHandler            http.Handler // Optional web UI handler (enabled by default if provided, unless WebUIEnabled is set to false)
+	WebUIEnabled          *bool        // Whether to enable web UI (default: true if WebHandler provided, false otherwise). Use pointer to distinguish between "not set" and "explicitly false"
+	WebUIBasePath         string       // Base path to mount web UI (default: "" = root)
+	PortalHandler         http.Handler // Optional user portal handler
+	PortalAuthHandler     http.Handler // Optional JSON API auth handler (mounted at /api/portal/auth for SPA frontends)
+	DocsHandler           http.Handler // Optional developer documentation portal handler
+	ModuleHandler         http.Handler // Optional declarative module handler (mounted at /api/v2)
+	PaymentWebhookHandler http.Handler // Optional payment webhook handler for Stripe/Paddle/LemonSqueezy (also mounted at /api/v1/webhooks)
+	MeterHandler          http.Handler // Optional metering API handler (mounted at /api/v1/meter)
+	UpstreamHealthHandler http.Handler // Optional upstream health handler (mounted at /api/v1/upstream-health)
+	RouteService          interface{}  // Optional route service for priority-based routing (uses reflection to avoid circular dependency)
 
 	// Configurable handler paths (backward compatible defaults if empty)
 	AdminBasePath          string // Default: /admin
@@ -577,12 +765,21 @@ type RouterConfig struct {
 	MeterBasePath          string // Default: /api/v1/meter
 
 	// Handler enable/disable flags
-	DocsEnabled            bool // Default: true (if DocsHandler provided)
-	ModuleEnabled          bool // Default: true (if ModuleHandler provided)
-	PaymentWebhookEnabled  bool // Default: true (if PaymentWebhookHandler provided)
-	MeterEnabled           bool // Default: true (if MeterHandler provided)
+	DocsEnabled           bool // Default: true (if DocsHandler provided)
+	ModuleEnabled         bool // Default: true (if ModuleHandler provided)
+	PaymentWebhookEnabled bool // Default: true (if PaymentWebhookHandler provided)
+	MeterEnabled          bool // Default: true (if MeterHandler provided)
+
+	// RequestTimeout bounds non-proxied handlers (default: 60s). Proxied
+	// requests use the upstream and route timeouts instead.
+	RequestTimeout time.Duration
 }
 
+// PaymentWebhookAPIPath is a fixed mount point for the payment webhook
+// endpoints alongside the configurable base path, e.g. /api/v1/webhooks/stripe.
+// It is handled before proxied /api/* routes.
+const PaymentWebhookAPIPath = "/api/v1/webhooks"
+
 // normalizeBasePath ensures base path starts with / and doesn't end with /.
 // Returns empty string if path is empty, "/", or invalid.
 func normalizeBasePath(path string) string {
@@ -613,10 +810,14 @@ func NewRouterWithConfig(proxyHandler *ProxyHandler, healthHandler *HealthHandle
 
 	// Middleware
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(clientip.Middleware(cfg.TrustedProxies))
 	r.Use(NewLoggingMiddleware(logger))
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(60 * time.Second))
+	requestTimeoutDuration := cfg.RequestTimeout
+	if requestTimeoutDuration <= 0 {
+		requestTimeoutDuration = defaultRequestTimeout
+	}
+	r.Use(requestTimeout(requestTimeoutDuration))
 
 	// Metrics middleware (if enabled)
 	if cfg.Metrics != nil {
@@ -635,10 +836,17 @@ func NewRouterWithConfig(proxyHandler *ProxyHandler, healthHandler *HealthHandle
 	r.Get("/health/ready", healthHandler.Readiness)
 
 	// Metrics endpoint (prefer new exporter handler, fall back to promhttp)
+	var metricsHandler http.Handler
 	if cfg.MetricsHandler != nil {
-		r.Handle("/metrics", cfg.MetricsHandler)
+		metricsHandler = cfg.MetricsHandler
 	} else if cfg.Metrics != nil {
-		r.Handle("/metrics", promhttp.Handler())
+		metricsHandler = promhttp.Handler()
+	}
+	if metricsHandler != nil {
+		if cfg.MetricsAuth != nil {
+			metricsHandler = cfg.MetricsAuth(metricsHandler)
+		}
+		r.Handle("/metrics", metricsHandler)
 	}
 
 	// OpenAPI/Swagger endpoints (if enabled)
@@ -734,6 +942,9 @@ func NewRouterWithConfig(proxyHandler *ProxyHandler, healthHandler *HealthHandle
 		}
 		logger.Debug().Str("path", webhookPath).Msg("mounting payment webhook handler")
 		r.Mount(webhookPath, cfg.PaymentWebhookHandler)
+		if webhookPath != PaymentWebhookAPIPath {
+			r.Mount(PaymentWebhookAPIPath, cfg.PaymentWebhookHandler)
+		}
 	} else if cfg.PaymentWebhookHandler != nil && !cfg.PaymentWebhookEnabled {
 		logger.Debug().Msg("payment webhook handler disabled via configuration")
 	}
@@ -750,6 +961,11 @@ func NewRouterWithConfig(proxyHandler *ProxyHandler, healthHandler *HealthHandle
 		logger.Debug().Msg("meter handler disabled via configuration")
 	}
 
+	// Upstream health (built-in, takes precedence over proxied /api/* routes)
+	if cfg.UpstreamHealthHandler != nil {
+		r.Handle(UpstreamHealthPath, cfg.UpstreamHealthHandler)
+	}
+
 	// Web UI (if enabled) - pass through specific paths to the web handler
 	// Default behavior: if WebHandler is provided, it's enabled (backward compatible)
 	// Explicit disable: set WebUIEnabled to false pointer
@@ -776,15 +992,12 @@ func NewRouterWithConfig(proxyHandler *ProxyHandler, healthHandler *HealthHandle
 	}
 
 	// Proxy handles /api/* and catch-all for unmatched routes
-	r.HandleFunc("/api/*", proxyHandler.ServeHTTP)
-
-	// Catch-all for proxy: routes not matched by web UI or other handlers
-	// This allows dynamic routes (from database) to work as a fallback
 	// IMPORTANT: Never proxy reserved paths (admin UI, health checks, etc.)
-	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+	reserved := ReservedPaths(cfg)
+	proxyUnreserved := func(w http.ResponseWriter, r *http.Request) {
 		// Double-check: if this is a reserved path, return 404 instead of proxying
 		// This prevents catch-all upstream routes from overriding built-in handlers
-		if isReservedPath(r.URL.Path, cfg) {
+		if reserved.Contains(r.URL.Path) {
 			logger.Warn().
 				Str("path", r.URL.Path).
 				Msg("reserved path not found in built-in routes - returning 404 instead of proxying")
@@ -794,7 +1007,12 @@ func NewRouterWithConfig(proxyHandler *ProxyHandler, healthHandler *HealthHandle
 
 		// Not a reserved path - safe to proxy to upstream
 		proxyHandler.ServeHTTP(w, r)
-	})
+	}
+	r.HandleFunc("/api/*", proxyUnreserved)
+
+	// Catch-all for proxy: routes not matched by web UI or other handlers
+	// This allows dynamic routes (from database) to work as a fallback
+	r.NotFound(proxyUnreserved)
 
 	return r
 }
@@ -981,140 +1199,81 @@ func (m *routeMatchResult) GetRoute() RouteInfo {
 	return m.Route
 }
 
-// isReservedPath checks if a path should be protected from database route overrides.
-// These paths are always handled by built-in handlers, never proxied to upstreams.
-func isReservedPath(path string, cfg RouterConfig) bool {
+// ReservedPaths returns the registry of path prefixes served by the built-in
+// handlers enabled in cfg. These paths are always handled by built-in
+// handlers, never proxied to upstreams, and proxy routes may not claim them.
+func ReservedPaths(cfg RouterConfig) route.ReservedPaths {
 	// System endpoints (always reserved)
-	if strings.HasPrefix(path, "/health") || path == "/metrics" || path == "/version" {
-		return true
-	}
+	reserved := route.NewReservedPaths("/health", "/metrics", "/version")
 
 	// OpenAPI endpoints (only reserved when enabled)
 	if cfg.EnableOpenAPI {
-		if strings.HasPrefix(path, "/swagger") || strings.HasPrefix(path, "/.well-known") {
-			return true
-		}
-	}
-
-	// Admin API (configurable path, default: /admin)
-	adminPath := normalizeBasePath(cfg.AdminBasePath)
-	if adminPath == "" {
-		adminPath = "/admin"
-	}
-	if path == adminPath || strings.HasPrefix(path, adminPath+"/") {
-		return true
+		reserved = reserved.With("/swagger", "/.well-known")
 	}
 
-	// Auth API (configurable path, default: /auth)
-	authPath := normalizeBasePath(cfg.AuthBasePath)
-	if authPath == "" {
-		authPath = "/auth"
-	}
-	if path == authPath || strings.HasPrefix(path, authPath+"/") {
-		return true
-	}
+	// Admin API and auth API (configurable paths, default: /admin and /auth)
+	reserved = reserved.With(basePathOr(cfg.AdminBasePath, "/admin"), basePathOr(cfg.AuthBasePath, "/auth"))
 
-	// Portal (configurable path, default: /portal)
 	if cfg.PortalHandler != nil {
-		portalPath := normalizeBasePath(cfg.PortalBasePath)
-		if portalPath == "" {
-			portalPath = "/portal"
-		}
-		if path == portalPath || strings.HasPrefix(path, portalPath+"/") {
-			return true
-		}
+		reserved = reserved.With(basePathOr(cfg.PortalBasePath, "/portal"))
 	}
-
-	// Portal Auth API (configurable path, default: /api/portal/auth)
 	if cfg.PortalAuthHandler != nil {
-		portalAuthPath := normalizeBasePath(cfg.PortalAuthBasePath)
-		if portalAuthPath == "" {
-			portalAuthPath = "/api/portal/auth"
-		}
-		if path == portalAuthPath || strings.HasPrefix(path, portalAuthPath+"/") {
-			return true
-		}
+		reserved = reserved.With(basePathOr(cfg.PortalAuthBasePath, "/api/portal/auth"))
 	}
-
-	// Docs (configurable path, default: /docs)
 	if cfg.DocsHandler != nil && cfg.DocsEnabled {
-		docsPath := normalizeBasePath(cfg.DocsBasePath)
-		if docsPath == "" {
-			docsPath = "/docs"
-		}
-		if path == docsPath || strings.HasPrefix(path, docsPath+"/") {
-			return true
-		}
+		reserved = reserved.With(basePathOr(cfg.DocsBasePath, "/docs"))
 	}
-
-	// Module API (configurable path, default: /mod)
 	if cfg.ModuleHandler != nil && cfg.ModuleEnabled {
-		modulePath := normalizeBasePath(cfg.ModuleBasePath)
-		if modulePath == "" {
-			modulePath = "/mod"
-		}
-		if path == modulePath || strings.HasPrefix(path, modulePath+"/") {
-			return true
-		}
+		reserved = reserved.With(basePathOr(cfg.ModuleBasePath, "/mod"))
 	}
-
-	// Payment webhooks (configurable path, default: /payment-webhooks)
 	if cfg.PaymentWebhookHandler != nil && cfg.PaymentWebhookEnabled {
-		webhookPath := normalizeBasePath(cfg.PaymentWebhookBasePath)
-		if webhookPath == "" {
-			webhookPath = "/payment-webhooks"
-		}
-		if path == webhookPath || strings.HasPrefix(path, webhookPath+"/") {
-			return true
-		}
+		reserved = reserved.With(basePathOr(cfg.PaymentWebhookBasePath, "/payment-webhooks"), PaymentWebhookAPIPath)
 	}
-
-	// Metering API (configurable path, default: /api/v1/meter)
 	if cfg.MeterHandler != nil && cfg.MeterEnabled {
-		meterPath := normalizeBasePath(cfg.MeterBasePath)
-		if meterPath == "" {
-			meterPath = "/api/v1/meter"
-		}
-		if path == meterPath || strings.HasPrefix(path, meterPath+"/") {
-			return true
-		}
+		reserved = reserved.With(basePathOr(cfg.MeterBasePath, "/api/v1/meter"))
+	}
+	if cfg.UpstreamHealthHandler != nil {
+		reserved = reserved.With(UpstreamHealthPath)
 	}
 
 	// Admin Web UI management pages (when mounted at root)
 	// These are admin-specific pages that should not be overridden by catch-all routes
 	webUIEnabled := cfg.WebUIEnabled == nil || *cfg.WebUIEnabled
 	if cfg.WebHandler != nil && webUIEnabled && cfg.WebUIBasePath == "" {
-		adminPages := []string{
+		reserved = reserved.With(
 			"/dashboard", "/users", "/keys", "/plans", "/usage", "/settings",
 			"/payments", "/email", "/webhooks", "/system",
 			"/invites", "/entitlements", "/routes", "/upstreams",
 			"/setup", // Initial setup wizard
-		}
-		for _, page := range adminPages {
-			if path == page || strings.HasPrefix(path, page+"/") {
-				return true
-			}
-		}
-		// UI helper API endpoints
-		if strings.HasPrefix(path, "/api/expr/") || strings.HasPrefix(path, "/api/routes/") {
-			return true
-		}
+			// UI helper API endpoints
+			"/api/expr", "/api/routes",
+		)
 	}
 
 	// Note: Generic Web UI paths (/, /login, /terms, /privacy) are NOT reserved.
 	// They can be overridden by custom routes with higher priority.
 	// This allows users to deploy their own frontend while still accessing admin pages.
 
-	return false
+	return reserved
+}
+
+// basePathOr returns the normalized base path, or def if it is empty.
+func basePathOr(path, def string) string {
+	if p := normalizeBasePath(path); p != "" {
+		return p
+	}
+	return def
 }
 
 // NewPriorityRouteMiddleware creates middleware that checks database routes before chi routing.
 // This allows database routes with priority > 0 to override built-in routes.
+// Reserved paths are never overridden, whatever the route's priority.
 func NewPriorityRouteMiddleware(proxyHandler *ProxyHandler, routeService interface{}, logger zerolog.Logger, cfg RouterConfig) func(next http.Handler) http.Handler {
+	reserved := ReservedPaths(cfg)
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Skip reserved paths - these should always use built-in handlers
-			if isReservedPath(r.URL.Path, cfg) {
+			if reserved.Contains(r.URL.Path) {
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -743,7 +743,7 @@ func TestExtractIP(t *testing.T) {
 		{
 			name:       "IPv6 with port",
 			remoteAddr: "[::1]:8080",
-			want:       "[::1]",
+			want:       "::1",
 		},
 	}
 
@@ -828,6 +828,35 @@ func TestNewRouterWithConfig_ModuleHandler(t *testing.T) {
 	}
 }
 
+func TestNewRouterWithConfig_PaymentWebhookHandler(t *testing.T) {
+	handler, _ := setupTestHandler()
+	upstream := &testUpstream{healthy: true}
+	healthHandler := apihttp.NewHealthHandler(upstream)
+	logger := zerolog.Nop()
+
+	webhookHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+		w.Write([]byte("webhook"))
+	})
+
+	cfg := apihttp.RouterConfig{
+		PaymentWebhookHandler: webhookHandler,
+		PaymentWebhookEnabled: true,
+	}
+	router := apihttp.NewRouterWithConfig(handler, healthHandler, logger, cfg)
+
+	// Served at the configurable base path and at /api/v1/webhooks, never proxied
+	for _, path := range []string{"/payment-webhooks/stripe", apihttp.PaymentWebhookAPIPath + "/stripe"} {
+		req := httptest.NewRequest("POST", path, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Result().StatusCode != 200 || rec.Body.String() != "webhook" {
+			t.Errorf("POST %s: status = %d, body = %q, want the webhook handler", path, rec.Result().StatusCode, rec.Body.String())
+		}
+	}
+}
+
 func TestNewRouterWithConfig_MetricsHandler(t *testing.T) {
 	handler, _ := setupTestHandler()
 	upstream := &testUpstream{healthy: true}
@@ -0,0 +1,188 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/domain/route"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestProxy_HeaderRules(t *testing.T) {
+	const rawKey = "ak_5e4f9a8b7c6d5e4f9a8b7c6d5e4f9a8b7c6d5e4f9a8b7c6d5e4f9a8b7c6d5e4f"
+	t.Setenv("HEADER_RULES_TOKEN", "internal-secret")
+
+	var upstreamHeaders http.Header
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamHeaders = r.Header.Clone()
+		w.Header().Set("X-Powered-By", "legacy-stack")
+		w.Header().Set("X-Upstream-Version", "2.3.1")
+		w.Header().Set("Vary", "Accept-Encoding")
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	keys := memory.NewKeyStore()
+	users := memory.NewUserStore()
+	users.Create(context.Background(), ports.User{ID: "user-1", Email: "dev@example.com", PlanID: "free", Status: "active"})
+	keyHash, _ := bcrypt.GenerateFromPassword([]byte(rawKey), bcrypt.MinCost)
+	keys.Create(context.Background(), key.Key{
+		ID: "key-1", UserID: "user-1", Hash: keyHash, Prefix: rawKey[:12], CreatedAt: baseTime.Add(-time.Hour),
+	})
+
+	requestRules := []route.HeaderRule{
+		{Op: route.HeaderSet, Name: "X-Internal-Auth", Value: "Token ${HEADER_RULES_TOKEN}"},
+		{Op: route.HeaderSet, Name: "X-Caller", Value: "${key.user_id}/${key.prefix}"},
+		{Op: route.HeaderRemove, Name: "cookie"},
+		{Op: route.HeaderRename, Name: "X-Client-Trace", To: "X-Trace-Id"},
+		{Op: route.HeaderAdd, Name: "Accept-Language", Value: "en"},
+	}
+	responseRules := []route.HeaderRule{
+		{Op: route.HeaderRemove, Name: "X-Powered-By"},
+		{Op: route.HeaderRename, Name: "X-Upstream-Version", To: "X-Api-Version"},
+		{Op: route.HeaderAdd, Name: "Vary", Value: "Accept"},
+	}
+
+	upstreams := []route.Upstream{{ID: "backend", Name: "backend", BaseURL: backend.URL, Enabled: true}}
+	routes := []route.Route{
+		{
+			ID: "orders", Name: "orders", PathPattern: "/orders/*", MatchType: route.MatchPrefix,
+			UpstreamID: "backend", Protocol: route.ProtocolHTTP, AuthRequired: true, Enabled: true,
+			RequestHeaders: requestRules, ResponseHeaders: responseRules,
+		},
+		{
+			ID: "catalog", Name: "catalog", PathPattern: "/catalog", MatchType: route.MatchExact,
+			UpstreamID: "backend", Protocol: route.ProtocolHTTP, AuthRequired: false, Enabled: true,
+			RequestHeaders: requestRules, ResponseHeaders: responseRules,
+		},
+		{
+			ID: "plain", Name: "plain", PathPattern: "/plain", MatchType: route.MatchExact,
+			UpstreamID: "backend", Protocol: route.ProtocolHTTP, AuthRequired: false, Enabled: true,
+		},
+	}
+
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: backend.URL})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	defer client.Close()
+
+	clk := clock.NewFake(baseTime)
+	service := app.NewProxyService(app.ProxyDeps{
+		Keys:      keys,
+		Users:     users,
+		RateLimit: memory.NewRateLimitStore(),
+		Usage:     &testUsageRecorder{},
+		Upstream:  client,
+		Clock:     clk,
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100,
+		RateWindow: 60,
+		Plans:      []plan.Plan{{ID: "free", Name: "Free", RateLimitPerMinute: 600, RequestsPerMonth: -1}},
+	})
+	routeService := app.NewRouteService(&staticRouteStore{routes: routes}, &staticUpstreamStore{upstreams: upstreams},
+		clk, zerolog.Nop(), app.RouteServiceConfig{})
+	if err := routeService.Reload(context.Background()); err != nil {
+		t.Fatalf("reload routes: %v", err)
+	}
+	service.SetRouteService(routeService)
+	handler := apihttp.NewProxyHandler(service, zerolog.Nop())
+
+	serve := func(t *testing.T, path string, apiKey bool) *httptest.ResponseRecorder {
+		t.Helper()
+		upstreamHeaders = nil
+		req := httptest.NewRequest("GET", path, nil)
+		if apiKey {
+			req.Header.Set("X-API-Key", rawKey)
+		}
+		req.Header.Set("Cookie", "session=browser-session")
+		req.Header.Set("X-Client-Trace", "trace-123")
+		req.Header.Set("X-Internal-Auth", "forged")
+		req.Header.Set("Accept-Language", "de")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+		}
+		if upstreamHeaders == nil {
+			t.Fatal("request did not reach the upstream")
+		}
+		return rec
+	}
+
+	t.Run("request headers added, renamed and removed", func(t *testing.T) {
+		serve(t, "/orders/42", true)
+
+		want := map[string]string{
+			"X-Internal-Auth": "Token internal-secret",
+			"X-Caller":        "user-1/" + rawKey[:12],
+			"X-Trace-Id":      "trace-123",
+			"Accept-Language": "de, en",
+		}
+		for name, value := range want {
+			if got := upstreamHeaders.Get(name); got != value {
+				t.Errorf("upstream %s = %q, want %q", name, got, value)
+			}
+		}
+		for _, name := range []string{"Cookie", "X-Client-Trace"} {
+			if got := upstreamHeaders.Values(name); len(got) != 0 {
+				t.Errorf("removed header %s leaked upstream: %q", name, got)
+			}
+		}
+	})
+
+	t.Run("response headers added, renamed and removed", func(t *testing.T) {
+		rec := serve(t, "/orders/42", true)
+
+		if got := rec.Header().Get("X-Powered-By"); got != "" {
+			t.Errorf("X-Powered-By = %q, want removed", got)
+		}
+		if got := rec.Header().Get("X-Upstream-Version"); got != "" {
+			t.Errorf("X-Upstream-Version = %q, want renamed", got)
+		}
+		if got := rec.Header().Get("X-Api-Version"); got != "2.3.1" {
+			t.Errorf("X-Api-Version = %q, want 2.3.1", got)
+		}
+		if got := rec.Header().Get("Vary"); got != "Accept-Encoding, Accept" {
+			t.Errorf("Vary = %q, want %q", got, "Accept-Encoding, Accept")
+		}
+	})
+
+	t.Run("public route has no key references", func(t *testing.T) {
+		serve(t, "/catalog", false)
+
+		if got := upstreamHeaders.Get("X-Caller"); got != "/" {
+			t.Errorf("upstream X-Caller = %q, want key references expanded empty", got)
+		}
+		if got := upstreamHeaders.Get("Cookie"); got != "" {
+			t.Errorf("removed Cookie leaked upstream: %q", got)
+		}
+	})
+
+	t.Run("route without rules forwards headers unchanged", func(t *testing.T) {
+		rec := serve(t, "/plain", false)
+
+		if got := upstreamHeaders.Get("Cookie"); got != "session=browser-session" {
+			t.Errorf("upstream Cookie = %q, want it forwarded", got)
+		}
+		if got := upstreamHeaders.Get("X-Internal-Auth"); got != "forged" {
+			t.Errorf("upstream X-Internal-Auth = %q, want client value", got)
+		}
+		if got := rec.Header().Get("X-Powered-By"); got != "legacy-stack" {
+			t.Errorf("X-Powered-By = %q, want upstream value", got)
+		}
+	})
+}
@@ -0,0 +1,145 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	pinnedKey = "ak_c1d2e3f4a5b6c1d2e3f4a5b6c1d2e3f4a5b6c1d2e3f4a5b6c1d2e3f4a5b6c1d2"
+	openKey   = "ak_e5f6a7b8c9d0e5f6a7b8c9d0e5f6a7b8c9d0e5f6a7b8c9d0e5f6a7b8c9d0e5f6"
+)
+
+// newAllowlistProxy returns a proxy handler trusting proxies in 10.0.0.0/8, with
+// pinnedKey restricted to 203.0.113.0/24 and 2001:db8::/32 and openKey unrestricted.
+func newAllowlistProxy(t *testing.T) (*apihttp.ProxyHandler, *testUsageRecorder) {
+	t.Helper()
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	t.Cleanup(backend.Close)
+
+	keys := memory.NewKeyStore()
+	users := memory.NewUserStore()
+	users.Create(context.Background(), ports.User{ID: "user-1", Email: "cidr@example.com", PlanID: "free", Status: "active"})
+	for rawKey, cidrs := range map[string][]string{
+		pinnedKey: {"203.0.113.0/24", "2001:db8::/32"},
+		openKey:   nil,
+	} {
+		keyHash, _ := bcrypt.GenerateFromPassword([]byte(rawKey), bcrypt.MinCost)
+		keys.Create(context.Background(), key.Key{
+			ID: rawKey[3:12], UserID: "user-1", Hash: keyHash, Prefix: rawKey[:12], AllowedCIDRs: cidrs, CreatedAt: baseTime.Add(-time.Hour),
+		})
+	}
+
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: backend.URL})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	recorder := &testUsageRecorder{}
+	service := app.NewProxyService(app.ProxyDeps{
+		Keys:      keys,
+		Users:     users,
+		RateLimit: memory.NewRateLimitStore(),
+		Usage:     recorder,
+		Upstream:  client,
+		Clock:     clock.NewFake(baseTime),
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100,
+		RateWindow: 60,
+		Plans:      []plan.Plan{{ID: "free", Name: "Free", RateLimitPerMinute: 600, RequestsPerMonth: -1}},
+	})
+	handler := apihttp.NewProxyHandler(service, zerolog.Nop())
+	handler.SetTrustedProxies([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")})
+	return handler, recorder
+}
+
+func TestProxy_KeyAllowedCIDRs(t *testing.T) {
+	handler, _ := newAllowlistProxy(t)
+
+	tests := []struct {
+		name       string
+		rawKey     string
+		remoteAddr string
+		xff        string
+		wantStatus int
+	}{
+		{"in range", pinnedKey, "203.0.113.10:41000", "", http.StatusOK},
+		{"in range ipv6", pinnedKey, "[2001:db8::7]:41000", "", http.StatusOK},
+		{"out of range", pinnedKey, "198.51.100.10:41000", "", http.StatusForbidden},
+		{"spoofed xff from untrusted peer", pinnedKey, "198.51.100.10:41000", "203.0.113.10", http.StatusForbidden},
+		{"spoofed entry before trusted proxy", pinnedKey, "10.1.2.3:41000", "203.0.113.10, 198.51.100.10", http.StatusForbidden},
+		{"xff from trusted proxy", pinnedKey, "10.1.2.3:41000", "203.0.113.10", http.StatusOK},
+		{"trusted proxy out of range client", pinnedKey, "10.1.2.3:41000", "198.51.100.10", http.StatusForbidden},
+		{"unrestricted key", openKey, "198.51.100.10:41000", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/data", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-API-Key", tt.rawKey)
+			if tt.xff != "" {
+				req.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusForbidden && !strings.Contains(rec.Body.String(), "ip_not_allowed") {
+				t.Errorf("body = %s, want ip_not_allowed error", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRouter_ClientIPBehindTrustedProxies(t *testing.T) {
+	handler, recorder := newAllowlistProxy(t)
+	router := apihttp.NewRouterWithConfig(handler, apihttp.NewHealthHandler(&testUpstream{healthy: true}), zerolog.Nop(),
+		apihttp.RouterConfig{TrustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}})
+
+	send := func(remoteAddr, xff string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/data", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-API-Key", pinnedKey)
+		req.Header.Set("X-Forwarded-For", xff)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Chained trusted proxies: the client is the first untrusted hop from the right
+	if rec := send("10.0.0.5:41000", "198.51.100.66, 203.0.113.10, 10.0.0.9"); rec.Code != http.StatusOK {
+		t.Fatalf("via trusted proxies status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	if len(recorder.events) != 1 || recorder.events[0].IPAddress != "203.0.113.10" {
+		t.Fatalf("usage events = %+v, want one event from 203.0.113.10", recorder.events)
+	}
+
+	// An untrusted peer can't claim an allowed address
+	if rec := send("198.51.100.66:41000", "203.0.113.10"); rec.Code != http.StatusForbidden {
+		t.Errorf("spoofed X-Forwarded-For status = %d, want 403", rec.Code)
+	}
+}
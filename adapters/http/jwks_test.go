@@ -0,0 +1,102 @@
+package http_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/auth"
+	"github.com/artpar/apigate/adapters/clock"
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/ports"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog"
+)
+
+func TestProxy_JWKSBearerTokens(t *testing.T) {
+	signingKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+	idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "idp-1",
+			"n":   base64.RawURLEncoding.EncodeToString(signingKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(signingKey.E)).Bytes()),
+		}}})
+	}))
+	defer idp.Close()
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	users := memory.NewUserStore()
+	users.Create(context.Background(), ports.User{ID: "user-1", Email: "oidc@example.com", PlanID: "free", Status: "active"})
+
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: backend.URL})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	defer client.Close()
+
+	recorder := &testUsageRecorder{}
+	service := app.NewProxyService(app.ProxyDeps{
+		Keys:      memory.NewKeyStore(),
+		Users:     users,
+		RateLimit: memory.NewRateLimitStore(),
+		Usage:     recorder,
+		Upstream:  client,
+		Clock:     clock.NewFake(baseTime),
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100,
+		RateWindow: 60,
+		Plans:      []plan.Plan{{ID: "free", Name: "Free", RateLimitPerMinute: 600, RequestsPerMonth: -1}},
+	})
+	service.SetBearerValidator(auth.NewJWKSValidator(auth.JWKSConfig{URL: idp.URL, Issuer: "https://idp.example.com", Audience: "apigate"}))
+	handler := apihttp.NewProxyHandler(service, zerolog.Nop())
+
+	bearer := func(sub string, exp time.Time) *httptest.ResponseRecorder {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss": "https://idp.example.com", "aud": "apigate", "sub": sub, "exp": exp.Unix(),
+		})
+		token.Header["kid"] = "idp-1"
+		signed, err := token.SignedString(signingKey)
+		if err != nil {
+			t.Fatalf("sign token: %v", err)
+		}
+		req := httptest.NewRequest("GET", "/data", nil)
+		req.Header.Set("Authorization", "Bearer "+signed)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := bearer("user-1", time.Now().Add(time.Hour)); rec.Code != http.StatusOK {
+		t.Fatalf("valid token status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	if len(recorder.events) != 1 || recorder.events[0].UserID != "user-1" {
+		t.Fatalf("usage events = %+v, want one event for user-1", recorder.events)
+	}
+
+	if rec := bearer("user-1", time.Now().Add(-time.Minute)); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expired token status = %d, want 401", rec.Code)
+	}
+	if rec := bearer("unknown-user", time.Now().Add(time.Hour)); rec.Code != http.StatusUnauthorized {
+		t.Errorf("unknown user status = %d, want 401", rec.Code)
+	}
+}
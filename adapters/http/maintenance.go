@@ -0,0 +1,36 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/artpar/apigate/domain/proxy"
+)
+
+// SetMaintenance switches maintenance mode. It is safe to call while the
+// handler is serving requests.
+func (h *ProxyHandler) SetMaintenance(m proxy.Maintenance) {
+	h.maintenance.Store(&m)
+}
+
+// writeMaintenance answers a proxy request with the maintenance response:
+// the custom HTML page for clients that prefer HTML, otherwise the custom
+// JSON body or the standard error.
+func writeMaintenance(w http.ResponseWriter, r *http.Request, m proxy.Maintenance) {
+	if v := m.RetryAfterValue(); v != "" {
+		w.Header().Set("Retry-After", v)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+
+	switch {
+	case m.HTMLBody != "" && proxy.PrefersHTML(r.Header.Get("Accept")):
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(m.HTMLBody))
+	case m.JSONBody != "":
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(m.JSONBody))
+	default:
+		writeError(w, m.Error())
+	}
+}
@@ -0,0 +1,106 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/domain/proxy"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestMaintenanceMode(t *testing.T) {
+	proxyHandler, stores := setupTestHandler()
+
+	rawKey := "ak_0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	keyHash, _ := bcrypt.GenerateFromPassword([]byte(rawKey), bcrypt.DefaultCost)
+	stores.keys.Create(context.Background(), key.Key{
+		ID: "key-1", UserID: "user-1", Hash: keyHash, Prefix: rawKey[:12], CreatedAt: baseTime.Add(-time.Hour),
+	})
+	stores.users.Create(context.Background(), ports.User{ID: "user-1", Email: "test@example.com", PlanID: "free", Status: "active"})
+
+	portalHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("PORTAL"))
+	})
+	adminHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ADMIN"))
+	})
+	router := apihttp.NewRouterWithConfig(proxyHandler, apihttp.NewHealthHandler(nil), zerolog.Nop(), apihttp.RouterConfig{
+		PortalHandler: portalHandler,
+		AdminHandler:  adminHandler,
+	})
+
+	do := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("X-API-Key", rawKey)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	proxyHandler.SetMaintenance(proxy.Maintenance{
+		Enabled:    true,
+		RetryAfter: 90 * time.Second,
+		HTMLBody:   "<h1>Back soon</h1>",
+	})
+
+	t.Run("proxy routes return 503", func(t *testing.T) {
+		rec := do("/api/test", "")
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("status = %d, want 503", rec.Code)
+		}
+		if got := rec.Header().Get("Retry-After"); got != "90" {
+			t.Errorf("Retry-After = %q, want 90", got)
+		}
+		if body := rec.Body.String(); !strings.Contains(body, `"maintenance"`) {
+			t.Errorf("body = %s, want the maintenance error", body)
+		}
+	})
+
+	t.Run("browsers get the HTML page", func(t *testing.T) {
+		rec := do("/api/test", "text/html,application/xhtml+xml,*/*;q=0.8")
+		if rec.Code != http.StatusServiceUnavailable || rec.Body.String() != "<h1>Back soon</h1>" {
+			t.Fatalf("got %d %q, want 503 with the HTML page", rec.Code, rec.Body.String())
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+			t.Errorf("Content-Type = %q, want text/html", ct)
+		}
+	})
+
+	t.Run("portal and admin stay reachable", func(t *testing.T) {
+		for path, want := range map[string]string{"/portal/login": "PORTAL", "/admin/settings": "ADMIN"} {
+			rec := do(path, "")
+			if rec.Code != http.StatusOK || rec.Body.String() != want {
+				t.Errorf("%s: got %d %q, want 200 %q", path, rec.Code, rec.Body.String(), want)
+			}
+		}
+	})
+
+	t.Run("custom JSON body", func(t *testing.T) {
+		proxyHandler.SetMaintenance(proxy.Maintenance{Enabled: true, JSONBody: `{"status":"down"}`})
+		rec := do("/api/test", "")
+		if rec.Code != http.StatusServiceUnavailable || rec.Body.String() != `{"status":"down"}` {
+			t.Fatalf("got %d %q, want 503 with the custom body", rec.Code, rec.Body.String())
+		}
+		if got := rec.Header().Get("Retry-After"); got != "" {
+			t.Errorf("Retry-After = %q, want none without a delay", got)
+		}
+	})
+
+	t.Run("disabling resumes proxying", func(t *testing.T) {
+		proxyHandler.SetMaintenance(proxy.Maintenance{})
+		if rec := do("/api/test", ""); rec.Code != http.StatusOK {
+			t.Errorf("status = %d, want 200", rec.Code)
+		}
+	})
+}
@@ -0,0 +1,174 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/adapters/metrics"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/domain/route"
+	"github.com/artpar/apigate/ports"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestRouter_PrometheusMetrics(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	const (
+		quotaKey = "ak_3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c"
+		rateKey  = "ak_7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d"
+	)
+	keys := memory.NewKeyStore()
+	users := memory.NewUserStore()
+	for i, k := range []struct{ raw, userID, planID string }{
+		{quotaKey, "user-quota", "tiny-quota"},
+		{rateKey, "user-rate", "tiny-rate"},
+	} {
+		hash, _ := bcrypt.GenerateFromPassword([]byte(k.raw), bcrypt.MinCost)
+		keys.Create(context.Background(), key.Key{
+			ID: []string{"key-quota", "key-rate"}[i], UserID: k.userID, Hash: hash, Prefix: k.raw[:12], CreatedAt: baseTime.Add(-time.Hour),
+		})
+		users.Create(context.Background(), ports.User{ID: k.userID, Email: k.userID + "@example.com", PlanID: k.planID, Status: "active"})
+	}
+
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: backend.URL})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	defer client.Close()
+	quotas := memory.NewQuotaStore(memory.QuotaStoreConfig{})
+	defer quotas.Close()
+
+	clk := clock.NewFake(baseTime)
+	service := app.NewProxyService(app.ProxyDeps{
+		Keys:      keys,
+		Users:     users,
+		RateLimit: memory.NewRateLimitStore(),
+		Quota:     quotas,
+		Usage:     &testUsageRecorder{},
+		Upstream:  client,
+		Clock:     clk,
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100,
+		RateWindow: 60,
+		Plans: []plan.Plan{
+			{ID: "tiny-quota", Name: "Tiny quota", RateLimitPerMinute: 600, RequestsPerMonth: 1, QuotaEnforceMode: plan.QuotaEnforceHard},
+			{ID: "tiny-rate", Name: "Tiny rate", RateLimitPerMinute: 60, RateLimitBurst: 1, RequestsPerMonth: -1},
+		},
+	})
+
+	upstreams := []route.Upstream{
+		{ID: "catalog", Name: "catalog", BaseURL: backend.URL, Enabled: true},
+		{ID: "broken", Name: "broken", BaseURL: dead.URL, Enabled: true},
+	}
+	routes := []route.Route{
+		{ID: "catalog", Name: "catalog", PathPattern: "/catalog/*", MatchType: route.MatchPrefix, UpstreamID: "catalog", Protocol: route.ProtocolHTTP, AuthRequired: true, Enabled: true},
+		{ID: "broken", Name: "broken", PathPattern: "/broken", MatchType: route.MatchExact, UpstreamID: "broken", Protocol: route.ProtocolHTTP, AuthRequired: true, Enabled: true},
+	}
+	routeService := app.NewRouteService(&staticRouteStore{routes: routes}, &staticUpstreamStore{upstreams: upstreams},
+		clk, zerolog.Nop(), app.RouteServiceConfig{})
+	if err := routeService.Reload(context.Background()); err != nil {
+		t.Fatalf("reload routes: %v", err)
+	}
+	service.SetRouteService(routeService)
+
+	reg := prometheus.NewRegistry()
+	m := metrics.NewWithRegistry(reg)
+	router := apihttp.NewRouterWithConfig(
+		apihttp.NewProxyHandlerWithMetrics(service, zerolog.Nop(), m),
+		apihttp.NewHealthHandler(&testUpstream{healthy: true}),
+		zerolog.Nop(),
+		apihttp.RouterConfig{
+			Metrics:        m,
+			MetricsHandler: promhttp.HandlerFor(reg, promhttp.HandlerOpts{}),
+			MetricsAuth: func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.Header.Get("Authorization") != "Bearer admin" {
+						http.Error(w, "unauthorized", http.StatusUnauthorized)
+						return
+					}
+					next.ServeHTTP(w, r)
+				})
+			},
+		},
+	)
+
+	send := func(path, apiKey string, wantStatus int) {
+		t.Helper()
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("X-API-Key", apiKey)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != wantStatus {
+			t.Fatalf("GET %s status = %d, want %d", path, rec.Code, wantStatus)
+		}
+	}
+	send("/catalog/items", quotaKey, http.StatusOK)
+	send("/catalog/items", quotaKey, http.StatusPaymentRequired)
+	send("/catalog/items", rateKey, http.StatusOK)
+	send("/catalog/items", rateKey, http.StatusTooManyRequests)
+	clk.Advance(time.Minute)
+	send("/broken", rateKey, http.StatusBadGateway)
+	clk.Advance(time.Minute)
+	send("/other", rateKey, http.StatusOK)
+
+	// The endpoint is protected by the configured auth middleware
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("unauthenticated /metrics status = %d, want 401", rec.Code)
+	}
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer admin")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/metrics status = %d, want 200", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type = %q, want text exposition format", ct)
+	}
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE apigate_proxy_requests_total counter",
+		`apigate_proxy_requests_total{route="catalog",status="200"} 2`,
+		`apigate_proxy_requests_total{route="catalog",status="402"} 1`,
+		`apigate_proxy_requests_total{route="catalog",status="429"} 1`,
+		`apigate_proxy_requests_total{route="broken",status="502"} 1`,
+		`apigate_proxy_requests_total{route="default",status="200"} 1`,
+		"# TYPE apigate_proxy_duration_seconds histogram",
+		`apigate_proxy_duration_seconds_count{route="catalog"} 4`,
+		`apigate_rate_limit_hits_total{plan_id="tiny-rate",user_id="user-rate"} 1`,
+		`apigate_quota_rejections_total{plan_id="tiny-quota"} 1`,
+		`apigate_upstream_errors_total{type="upstream_error"} 1`,
+		"# TYPE apigate_upstream_duration_seconds histogram",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output missing %q", want)
+		}
+	}
+	if strings.Contains(body, `path="/metrics"`) {
+		t.Error("scrapes of /metrics were recorded as requests")
+	}
+}
@@ -0,0 +1,144 @@
+package http_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/domain/route"
+	"github.com/rs/zerolog"
+)
+
+type mirroredRequest struct {
+	method, path, body, header string
+}
+
+func TestProxy_Mirror(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Served-By", "primary")
+		w.Write([]byte("primary"))
+	}))
+	defer primary.Close()
+
+	mirrored := make(chan mirroredRequest, 1)
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mirrored <- mirroredRequest{r.Method, r.URL.Path, string(body), r.Header.Get("X-Request-Tag")}
+		w.Write([]byte("shadow"))
+	}))
+	defer shadow.Close()
+
+	release := make(chan struct{})
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		http.Error(w, "shadow exploded", http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	downURL := down.URL
+	down.Close()
+
+	upstreams := []route.Upstream{
+		{ID: "primary", Name: "Primary", BaseURL: primary.URL, Enabled: true},
+		{ID: "shadow", Name: "Shadow", BaseURL: shadow.URL, Enabled: true},
+		{ID: "failing", Name: "Failing", BaseURL: failing.URL, Enabled: true},
+		{ID: "down", Name: "Down", BaseURL: downURL, Enabled: true},
+	}
+	routes := []route.Route{
+		{
+			ID: "orders", Name: "orders", PathPattern: "/orders/*", MatchType: route.MatchPrefix,
+			UpstreamID: "primary", MirrorUpstreamID: "shadow", Protocol: route.ProtocolHTTP, Enabled: true,
+		},
+		{
+			ID: "slow-mirror", Name: "slow mirror", PathPattern: "/slow/*", MatchType: route.MatchPrefix,
+			UpstreamID: "primary", MirrorUpstreamID: "failing", Protocol: route.ProtocolHTTP, Enabled: true,
+		},
+		{
+			ID: "down-mirror", Name: "down mirror", PathPattern: "/down/*", MatchType: route.MatchPrefix,
+			UpstreamID: "primary", MirrorUpstreamID: "down", Protocol: route.ProtocolHTTP, Enabled: true,
+		},
+	}
+
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: primary.URL})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	defer client.Close()
+
+	clk := clock.NewFake(baseTime)
+	service := app.NewProxyService(app.ProxyDeps{
+		Keys:      memory.NewKeyStore(),
+		Users:     memory.NewUserStore(),
+		RateLimit: memory.NewRateLimitStore(),
+		Usage:     &testUsageRecorder{},
+		Upstream:  client,
+		Clock:     clk,
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100,
+		RateWindow: 60,
+		Plans:      []plan.Plan{{ID: "free", Name: "Free", RateLimitPerMinute: 600, RequestsPerMonth: -1}},
+	})
+	routeService := app.NewRouteService(&staticRouteStore{routes: routes}, &staticUpstreamStore{upstreams: upstreams},
+		clk, zerolog.Nop(), app.RouteServiceConfig{})
+	if err := routeService.Reload(context.Background()); err != nil {
+		t.Fatalf("reload routes: %v", err)
+	}
+	service.SetRouteService(routeService)
+	handler := apihttp.NewProxyHandler(service, zerolog.Nop())
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-Request-Tag", "abc")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("mirror receives a copy of the request", func(t *testing.T) {
+		rec := serve("POST", "/orders/42", `{"qty":3}`)
+		if rec.Code != http.StatusOK || rec.Body.String() != "primary" {
+			t.Fatalf("client got %d %q, want primary response", rec.Code, rec.Body.String())
+		}
+
+		select {
+		case got := <-mirrored:
+			want := mirroredRequest{"POST", "/orders/42", `{"qty":3}`, "abc"}
+			if got != want {
+				t.Errorf("mirror got %+v, want %+v", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("mirror never received the request")
+		}
+	})
+
+	t.Run("failing mirror does not change the response", func(t *testing.T) {
+		// The mirror is still blocked when the client is answered
+		rec := serve("GET", "/slow/1", "")
+		close(release)
+		if rec.Code != http.StatusOK || rec.Body.String() != "primary" {
+			t.Errorf("client got %d %q, want primary response", rec.Code, rec.Body.String())
+		}
+		if got := rec.Header().Get("X-Served-By"); got != "primary" {
+			t.Errorf("X-Served-By = %q, want primary", got)
+		}
+	})
+
+	t.Run("unreachable mirror does not change the response", func(t *testing.T) {
+		rec := serve("GET", "/down/1", "")
+		if rec.Code != http.StatusOK || rec.Body.String() != "primary" {
+			t.Errorf("client got %d %q, want primary response", rec.Code, rec.Body.String())
+		}
+	})
+}
@@ -0,0 +1,200 @@
+package http_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/domain/route"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+)
+
+// testCA issues client certificates for mTLS tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate CA key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             baseTime.Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &k.PublicKey, k)
+	if err != nil {
+		t.Fatalf("create CA certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: k}
+}
+
+// issue returns a client certificate for email, valid from baseTime until
+// after the real current time so it also passes live TLS handshakes.
+func (ca *testCA) issue(t *testing.T, email string) tls.Certificate {
+	t.Helper()
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate client key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:   big.NewInt(time.Now().UnixNano()),
+		Subject:        pkix.Name{CommonName: "Partner Integration"},
+		EmailAddresses: []string{email},
+		NotBefore:      baseTime.Add(-time.Hour),
+		NotAfter:       time.Now().Add(24 * time.Hour),
+		KeyUsage:       x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &k.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("create client certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: k}
+}
+
+func TestProxy_ClientCertificateAuth(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	users := memory.NewUserStore()
+	users.Create(context.Background(), ports.User{ID: "user-1", Email: "partner@example.com", PlanID: "free", Status: "active"})
+
+	upstreams := []route.Upstream{{ID: "backend", Name: "backend", BaseURL: backend.URL, Enabled: true}}
+	routes := []route.Route{
+		{
+			ID: "partner", Name: "partner", PathPattern: "/partner/*", MatchType: route.MatchPrefix,
+			UpstreamID: "backend", Protocol: route.ProtocolHTTP, AuthRequired: true, AuthMethod: route.AuthMethodMTLS, Enabled: true,
+		},
+		{
+			ID: "status", Name: "status", PathPattern: "/status", MatchType: route.MatchExact,
+			UpstreamID: "backend", Protocol: route.ProtocolHTTP, AuthRequired: true, Enabled: true,
+		},
+	}
+
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: backend.URL})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	defer client.Close()
+
+	clk := clock.NewFake(baseTime)
+	recorder := &testUsageRecorder{}
+	service := app.NewProxyService(app.ProxyDeps{
+		Keys:      memory.NewKeyStore(),
+		Users:     users,
+		RateLimit: memory.NewRateLimitStore(),
+		Usage:     recorder,
+		Upstream:  client,
+		Clock:     clk,
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100,
+		RateWindow: 60,
+		Plans:      []plan.Plan{{ID: "free", Name: "Free", RateLimitPerMinute: 600, RequestsPerMonth: -1}},
+	})
+	routeService := app.NewRouteService(&staticRouteStore{routes: routes}, &staticUpstreamStore{upstreams: upstreams},
+		clk, zerolog.Nop(), app.RouteServiceConfig{})
+	if err := routeService.Reload(context.Background()); err != nil {
+		t.Fatalf("reload routes: %v", err)
+	}
+	service.SetRouteService(routeService)
+
+	trusted := newTestCA(t, "Partner CA")
+	untrusted := newTestCA(t, "Rogue CA")
+	pool := x509.NewCertPool()
+	pool.AddCert(trusted.cert)
+	service.SetClientCAs(pool)
+
+	// The gateway asks for a client certificate without requiring one
+	server := httptest.NewUnstartedServer(apihttp.NewProxyHandler(service, zerolog.Nop()))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	get := func(t *testing.T, path string, certs ...tls.Certificate) (int, string) {
+		t.Helper()
+		// A fresh transport per request so connections aren't reused across identities
+		transport := server.Client().Transport.(*http.Transport).Clone()
+		transport.TLSClientConfig.Certificates = certs
+		defer transport.CloseIdleConnections()
+		resp, err := (&http.Client{Transport: transport}).Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+	expectRejected := func(t *testing.T, status int, body, code string) {
+		t.Helper()
+		if status != http.StatusUnauthorized {
+			t.Fatalf("status = %d, want 401: %s", status, body)
+		}
+		if !strings.Contains(body, code) {
+			t.Errorf("body = %s, want %s error", body, code)
+		}
+	}
+
+	t.Run("valid client certificate", func(t *testing.T) {
+		status, body := get(t, "/partner/orders", trusted.issue(t, "partner@example.com"))
+		if status != http.StatusOK {
+			t.Fatalf("status = %d, want 200: %s", status, body)
+		}
+		if len(recorder.events) == 0 || recorder.events[len(recorder.events)-1].UserID != "user-1" {
+			t.Errorf("request was not metered to the certificate's user: %+v", recorder.events)
+		}
+	})
+
+	t.Run("untrusted client certificate", func(t *testing.T) {
+		status, body := get(t, "/partner/orders", untrusted.issue(t, "partner@example.com"))
+		expectRejected(t, status, body, key.ReasonInvalidClientCert)
+	})
+
+	t.Run("no client certificate", func(t *testing.T) {
+		status, body := get(t, "/partner/orders")
+		expectRejected(t, status, body, key.ReasonMissingClientCert)
+	})
+
+	t.Run("certificate for unknown user", func(t *testing.T) {
+		status, body := get(t, "/partner/orders", trusted.issue(t, "stranger@example.com"))
+		expectRejected(t, status, body, key.ReasonUnknownClientCert)
+	})
+
+	t.Run("api key route ignores certificates", func(t *testing.T) {
+		status, body := get(t, "/status", trusted.issue(t, "partner@example.com"))
+		if status != http.StatusUnauthorized || !strings.Contains(body, "missing") {
+			t.Errorf("status = %d, body = %s; want missing API key", status, body)
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/route"
+	"github.com/rs/zerolog"
+)
+
+// flappingServer fails with 502 while failing is set and counts the requests it receives.
+type flappingServer struct {
+	*httptest.Server
+	failing atomic.Bool
+	hits    atomic.Int64
+}
+
+func newFlappingServer() *flappingServer {
+	s := &flappingServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.hits.Add(1)
+		if s.failing.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	return s
+}
+
+func TestProxy_PassiveHealth_EjectsAndReadmits(t *testing.T) {
+	flaky := newFlappingServer()
+	defer flaky.Close()
+	stable := newFlappingServer()
+	defer stable.Close()
+
+	upstreams := []route.Upstream{
+		{ID: "flaky", Name: "flaky", BaseURL: flaky.URL, Timeout: 5 * time.Second, Enabled: true,
+			FailureThreshold: 3, EjectionCooldown: 30 * time.Second},
+		{ID: "stable", Name: "stable", BaseURL: stable.URL, Timeout: 5 * time.Second, Enabled: true,
+			FailureThreshold: 3, EjectionCooldown: 30 * time.Second},
+	}
+	routes := []route.Route{{
+		ID: "pool", Name: "pool", PathPattern: "/api/*", MatchType: route.MatchPrefix,
+		Protocol: route.ProtocolHTTP, Enabled: true,
+		Upstreams: []route.WeightedUpstream{
+			{UpstreamID: "flaky", Weight: 1},
+			{UpstreamID: "stable", Weight: 1},
+		},
+	}}
+
+	clk := clock.NewFake(baseTime)
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: stable.URL})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	defer client.Close()
+
+	service := app.NewProxyService(app.ProxyDeps{
+		Keys:      memory.NewKeyStore(),
+		Users:     memory.NewUserStore(),
+		RateLimit: memory.NewRateLimitStore(),
+		Usage:     &testUsageRecorder{},
+		Upstream:  client,
+		Clock:     clk,
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{KeyPrefix: "ak_"})
+
+	routeService := app.NewRouteService(&staticRouteStore{routes: routes}, &staticUpstreamStore{upstreams: upstreams},
+		clk, zerolog.Nop(), app.RouteServiceConfig{})
+	if err := routeService.Reload(context.Background()); err != nil {
+		t.Fatalf("reload routes: %v", err)
+	}
+	service.SetRouteService(routeService)
+	handler := apihttp.NewProxyHandler(service, zerolog.Nop())
+
+	send := func(n int) {
+		for i := 0; i < n; i++ {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest("GET", "/api/data", nil))
+		}
+	}
+
+	// Healthy: traffic alternates between both upstreams
+	send(4)
+	if flaky.hits.Load() != 2 || stable.hits.Load() != 2 {
+		t.Fatalf("hits flaky=%d stable=%d, want 2/2", flaky.hits.Load(), stable.hits.Load())
+	}
+
+	// Flaky starts failing; the third consecutive 502 ejects it
+	flaky.failing.Store(true)
+	send(6)
+	if got := flaky.hits.Load(); got != 5 {
+		t.Fatalf("flaky hits = %d, want 5 (ejected after 3 failures)", got)
+	}
+	if routeService.IsUpstreamHealthy("flaky") {
+		t.Fatal("flaky should be ejected")
+	}
+
+	// While ejected, all traffic goes to stable
+	send(10)
+	if got := flaky.hits.Load(); got != 5 {
+		t.Errorf("flaky received %d requests while ejected", got-5)
+	}
+
+	// Just before the cooldown ends it is still ejected
+	clk.Advance(29 * time.Second)
+	send(4)
+	if got := flaky.hits.Load(); got != 5 {
+		t.Errorf("flaky re-admitted before cooldown elapsed")
+	}
+
+	// After the cooldown it is re-admitted and receives traffic again
+	flaky.failing.Store(false)
+	clk.Advance(time.Second)
+	if !routeService.IsUpstreamHealthy("flaky") {
+		t.Fatal("flaky should be re-admitted after cooldown")
+	}
+	send(4)
+	if got := flaky.hits.Load(); got != 7 {
+		t.Errorf("flaky hits = %d, want 7 after re-admission", got)
+	}
+}
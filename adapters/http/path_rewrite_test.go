@@ -0,0 +1,94 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/artpar/apigate/adapters/clock"
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/domain/route"
+	"github.com/rs/zerolog"
+)
+
+func TestProxy_PathRewrite(t *testing.T) {
+	var upstreamPath, upstreamQuery string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamPath, upstreamQuery = r.URL.Path, r.URL.RawQuery
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	upstreams := []route.Upstream{{ID: "orders-svc", Name: "Orders", BaseURL: backend.URL, Enabled: true}}
+	routes := []route.Route{
+		{
+			ID: "orders", Name: "orders", PathPattern: "/api/v1/orders*", MatchType: route.MatchPrefix,
+			StripPrefix: "/api/v1", UpstreamID: "orders-svc", Protocol: route.ProtocolHTTP, Enabled: true,
+		},
+		{
+			ID: "user-orders", Name: "user orders", PathPattern: `^/users/([0-9]+)/orders/([0-9]+)$`, MatchType: route.MatchRegex,
+			RewritePath: &route.PathRewriteRule{Regex: `^/users/([0-9]+)/orders/([0-9]+)$`, Replacement: "/v2/orders/$2/customer/$1"},
+			UpstreamID:  "orders-svc", Protocol: route.ProtocolHTTP, Enabled: true,
+		},
+	}
+
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: backend.URL})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	defer client.Close()
+
+	clk := clock.NewFake(baseTime)
+	service := app.NewProxyService(app.ProxyDeps{
+		Keys:      memory.NewKeyStore(),
+		Users:     memory.NewUserStore(),
+		RateLimit: memory.NewRateLimitStore(),
+		Usage:     &testUsageRecorder{},
+		Upstream:  client,
+		Clock:     clk,
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100,
+		RateWindow: 60,
+		Plans:      []plan.Plan{{ID: "free", Name: "Free", RateLimitPerMinute: 600, RequestsPerMonth: -1}},
+	})
+	routeService := app.NewRouteService(&staticRouteStore{routes: routes}, &staticUpstreamStore{upstreams: upstreams},
+		clk, zerolog.Nop(), app.RouteServiceConfig{})
+	if err := routeService.Reload(context.Background()); err != nil {
+		t.Fatalf("reload routes: %v", err)
+	}
+	service.SetRouteService(routeService)
+	handler := apihttp.NewProxyHandler(service, zerolog.Nop())
+
+	tests := []struct {
+		name      string
+		path      string
+		wantPath  string
+		wantQuery string
+	}{
+		{"strip prefix", "/api/v1/orders", "/orders", ""},
+		{"strip prefix keeps the rest", "/api/v1/orders/42?expand=items", "/orders/42", "expand=items"},
+		{"regex rewrite with captures", "/users/7/orders/42", "/v2/orders/42/customer/7", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstreamPath, upstreamQuery = "", ""
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+			}
+			if upstreamPath != tt.wantPath {
+				t.Errorf("upstream path = %q, want %q", upstreamPath, tt.wantPath)
+			}
+			if upstreamQuery != tt.wantQuery {
+				t.Errorf("upstream query = %q, want %q", upstreamQuery, tt.wantQuery)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/artpar/apigate/adapters/clock"
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/domain/route"
+	"github.com/rs/zerolog"
+)
+
+func TestProxy_QueryRouting(t *testing.T) {
+	var backend, upstreamQuery string
+	newBackend := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			backend, upstreamQuery = name, r.URL.RawQuery
+			w.Write([]byte(name))
+		}))
+	}
+	v1 := newBackend("v1")
+	defer v1.Close()
+	v2 := newBackend("v2")
+	defer v2.Close()
+
+	upstreams := []route.Upstream{
+		{ID: "items-v1", Name: "Items v1", BaseURL: v1.URL, Enabled: true},
+		{ID: "items-v2", Name: "Items v2", BaseURL: v2.URL, Enabled: true},
+	}
+	routes := []route.Route{
+		{
+			ID: "items-v2", Name: "items v2", PathPattern: "/items/*", MatchType: route.MatchPrefix,
+			QueryMatch: []route.QueryMatch{{Name: "version", Value: "2", Required: true}},
+			StripQuery: []string{"version"},
+			UpstreamID: "items-v2", Protocol: route.ProtocolHTTP, Enabled: true, Priority: 10,
+		},
+		{
+			ID: "items", Name: "items", PathPattern: "/items/*", MatchType: route.MatchPrefix,
+			UpstreamID: "items-v1", Protocol: route.ProtocolHTTP, Enabled: true,
+		},
+	}
+
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: v1.URL})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	defer client.Close()
+
+	clk := clock.NewFake(baseTime)
+	service := app.NewProxyService(app.ProxyDeps{
+		Keys:      memory.NewKeyStore(),
+		Users:     memory.NewUserStore(),
+		RateLimit: memory.NewRateLimitStore(),
+		Usage:     &testUsageRecorder{},
+		Upstream:  client,
+		Clock:     clk,
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100,
+		RateWindow: 60,
+		Plans:      []plan.Plan{{ID: "free", Name: "Free", RateLimitPerMinute: 600, RequestsPerMonth: -1}},
+	})
+	routeService := app.NewRouteService(&staticRouteStore{routes: routes}, &staticUpstreamStore{upstreams: upstreams},
+		clk, zerolog.Nop(), app.RouteServiceConfig{})
+	if err := routeService.Reload(context.Background()); err != nil {
+		t.Fatalf("reload routes: %v", err)
+	}
+	service.SetRouteService(routeService)
+	handler := apihttp.NewProxyHandler(service, zerolog.Nop())
+
+	tests := []struct {
+		name        string
+		target      string
+		wantBackend string
+		wantQuery   string
+	}{
+		{"version=2 routes to v2 and is stripped", "/items/42?version=2&expand=tags", "v2", "expand=tags"},
+		{"only param is stripped", "/items/42?version=2", "v2", ""},
+		{"other version falls through", "/items/42?version=1", "v1", "version=1"},
+		{"no query falls through", "/items/42", "v1", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend, upstreamQuery = "", ""
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest("GET", tt.target, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+			}
+			if backend != tt.wantBackend {
+				t.Errorf("backend = %q, want %q", backend, tt.wantBackend)
+			}
+			if upstreamQuery != tt.wantQuery {
+				t.Errorf("upstream query = %q, want %q", upstreamQuery, tt.wantQuery)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestProxy_QuotaPeriod(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	const rawKey = "ak_0a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f9"
+	signup := baseTime.Add(-10 * 24 * time.Hour) // Jan 5 2024 12:00 UTC
+
+	tests := []struct {
+		name      string
+		period    plan.QuotaPeriod
+		resetAt   time.Time // last instant of the window containing baseTime
+		nextStart time.Time // first instant of the following window
+	}{
+		{
+			name:      "calendar month",
+			period:    plan.QuotaPeriodCalendarMonth,
+			resetAt:   time.Date(2024, 1, 31, 23, 59, 59, 999999999, time.UTC),
+			nextStart: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			// baseTime is a Monday
+			name:      "calendar week",
+			period:    plan.QuotaPeriodCalendarWeek,
+			resetAt:   time.Date(2024, 1, 21, 23, 59, 59, 999999999, time.UTC),
+			nextStart: time.Date(2024, 1, 22, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "rolling 30 days",
+			period:    plan.QuotaPeriodRolling30d,
+			resetAt:   signup.Add(30*24*time.Hour - time.Nanosecond),
+			nextStart: signup.Add(30 * 24 * time.Hour),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := memory.NewKeyStore()
+			users := memory.NewUserStore()
+			keyHash, _ := bcrypt.GenerateFromPassword([]byte(rawKey), bcrypt.MinCost)
+			keys.Create(context.Background(), key.Key{
+				ID: "key-1", UserID: "user-1", Hash: keyHash, Prefix: rawKey[:12], CreatedAt: signup,
+			})
+			users.Create(context.Background(), ports.User{
+				ID: "user-1", Email: "quota@example.com", PlanID: "small", Status: "active", CreatedAt: signup,
+			})
+
+			quotas := memory.NewQuotaStore(memory.QuotaStoreConfig{})
+			defer quotas.Close()
+
+			client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: backend.URL})
+			if err != nil {
+				t.Fatalf("create upstream client: %v", err)
+			}
+			defer client.Close()
+
+			clk := clock.NewFake(baseTime)
+			service := app.NewProxyService(app.ProxyDeps{
+				Keys:      keys,
+				Users:     users,
+				RateLimit: memory.NewRateLimitStore(),
+				Quota:     quotas,
+				Usage:     &testUsageRecorder{},
+				Upstream:  client,
+				Clock:     clk,
+				IDGen:     &testIDGen{},
+			}, app.ProxyConfig{
+				KeyPrefix:  "ak_",
+				RateBurst:  100,
+				RateWindow: 60,
+				Plans: []plan.Plan{{
+					ID: "small", Name: "Small", RateLimitPerMinute: 600, RequestsPerMonth: 2,
+					QuotaEnforceMode: plan.QuotaEnforceHard, QuotaPeriod: tt.period,
+				}},
+			})
+			handler := apihttp.NewProxyHandler(service, zerolog.Nop())
+
+			send := func() *httptest.ResponseRecorder {
+				req := httptest.NewRequest("GET", "/data", nil)
+				req.Header.Set("X-API-Key", rawKey)
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+				return rec
+			}
+
+			for i := 0; i < 2; i++ {
+				if rec := send(); rec.Code != http.StatusOK {
+					t.Fatalf("request %d status = %d, want 200", i+1, rec.Code)
+				}
+			}
+
+			// The last instant of the window is still over quota
+			clk.Set(tt.resetAt)
+			rec := send()
+			if rec.Code != http.StatusPaymentRequired {
+				t.Fatalf("over quota status = %d, want 402", rec.Code)
+			}
+			if got := rec.Header().Get("X-Quota-Reset"); got != tt.resetAt.Format(time.RFC3339) {
+				t.Errorf("X-Quota-Reset = %q, want %q", got, tt.resetAt.Format(time.RFC3339))
+			}
+
+			// The next window starts with a fresh quota
+			clk.Set(tt.nextStart)
+			if rec := send(); rec.Code != http.StatusOK {
+				t.Errorf("next window status = %d, want 200", rec.Code)
+			}
+		})
+	}
+}
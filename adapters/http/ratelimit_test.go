@@ -0,0 +1,170 @@
+package http_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/adapters/idgen"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newBurstProxy returns a proxy handler for a plan allowing 60 requests per
+// minute with a burst of 5, and creates the given API keys for it.
+func newBurstProxy(t *testing.T, rawKeys ...string) (*apihttp.ProxyHandler, *clock.Fake) {
+	t.Helper()
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	t.Cleanup(backend.Close)
+
+	keys := memory.NewKeyStore()
+	users := memory.NewUserStore()
+	users.Create(context.Background(), ports.User{ID: "user-1", Email: "burst@example.com", PlanID: "bursty", Status: "active"})
+	for i, rawKey := range rawKeys {
+		keyHash, _ := bcrypt.GenerateFromPassword([]byte(rawKey), bcrypt.MinCost)
+		keys.Create(context.Background(), key.Key{
+			ID: fmt.Sprintf("key-%d", i), UserID: "user-1", Hash: keyHash, Prefix: rawKey[:12], CreatedAt: baseTime.Add(-time.Hour),
+		})
+	}
+
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: backend.URL})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	clk := clock.NewFake(baseTime)
+	service := app.NewProxyService(app.ProxyDeps{
+		Keys:      keys,
+		Users:     users,
+		RateLimit: memory.NewRateLimitStore(),
+		Usage:     &syncUsageRecorder{},
+		Upstream:  client,
+		Clock:     clk,
+		IDGen:     idgen.UUID{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100, // Global default, overridden by the plan
+		RateWindow: 60,
+		Plans: []plan.Plan{{
+			ID: "bursty", Name: "Bursty", RateLimitPerMinute: 60, RateLimitBurst: 5, RequestsPerMonth: -1,
+		}},
+	})
+	return apihttp.NewProxyHandler(service, zerolog.Nop()), clk
+}
+
+func TestProxy_RateLimitBurst(t *testing.T) {
+	const rawKey = "ak_5a5a5a5a5a5a5a5a5a5a5a5a5a5a5a5a5a5a5a5a5a5a5a5a5a5a5a5a5a5a5a5a"
+	handler, clk := newBurstProxy(t, rawKey)
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/data", nil)
+		req.Header.Set("X-API-Key", rawKey)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// A burst within the allowance is accepted at once
+	for i := 0; i < 5; i++ {
+		rec := send()
+		if rec.Code != http.StatusOK {
+			t.Fatalf("burst request %d status = %d, want 200", i+1, rec.Code)
+		}
+		if got := rec.Header().Get("X-RateLimit-Burst"); got != "5" {
+			t.Errorf("X-RateLimit-Burst = %q, want 5", got)
+		}
+		if got, want := rec.Header().Get("X-RateLimit-Remaining"), fmt.Sprint(4-i); got != want {
+			t.Errorf("X-RateLimit-Remaining = %q, want %s", got, want)
+		}
+	}
+
+	rec := send()
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("request beyond burst status = %d, want 429", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want 1", got)
+	}
+	if got := rec.Header().Get("X-RateLimit-Burst"); got != "5" {
+		t.Errorf("429 X-RateLimit-Burst = %q, want 5", got)
+	}
+
+	// Sustained traffic at 4 req/s is throttled to the steady rate of 1 req/s
+	allowed, throttled := 0, 0
+	for i := 0; i < 40; i++ {
+		clk.Advance(250 * time.Millisecond)
+		switch code := send().Code; code {
+		case http.StatusOK:
+			allowed++
+		case http.StatusTooManyRequests:
+			throttled++
+		default:
+			t.Fatalf("sustained request status = %d", code)
+		}
+	}
+	if allowed != 10 || throttled != 30 {
+		t.Errorf("sustained traffic: %d allowed, %d throttled; want 10 and 30", allowed, throttled)
+	}
+
+	// After an idle period the full burst is available again
+	clk.Advance(time.Minute)
+	for i := 0; i < 5; i++ {
+		if rec := send(); rec.Code != http.StatusOK {
+			t.Fatalf("burst after idle request %d status = %d, want 200", i+1, rec.Code)
+		}
+	}
+	if rec := send(); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("request beyond refilled burst status = %d, want 429", rec.Code)
+	}
+}
+
+func TestProxy_RateLimitBurst_Concurrent(t *testing.T) {
+	rawKeys := make([]string, 8)
+	for i := range rawKeys {
+		rawKeys[i] = "ak_" + strings.Repeat(fmt.Sprintf("%x", i+1), 64)
+	}
+	handler, _ := newBurstProxy(t, rawKeys...)
+
+	// Many concurrent requests per key: each key gets exactly its burst
+	var allowed [8]atomic.Int64
+	var wg sync.WaitGroup
+	for i, rawKey := range rawKeys {
+		for j := 0; j < 20; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				req := httptest.NewRequest("GET", "/data", nil)
+				req.Header.Set("X-API-Key", rawKey)
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+				if rec.Code == http.StatusOK {
+					allowed[i].Add(1)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	for i := range allowed {
+		if got := allowed[i].Load(); got != 5 {
+			t.Errorf("key %d: %d requests allowed, want burst of 5", i, got)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// defaultRequestTimeout bounds handlers served by the router unless
+// RouterConfig.RequestTimeout overrides it.
+const defaultRequestTimeout = 60 * time.Second
+
+type requestDeadlineKey struct{}
+
+// requestDeadline remembers the context a request had before the router
+// deadline was applied, so long-lived proxy traffic can opt out of it.
+type requestDeadline struct {
+	parent   context.Context
+	detached atomic.Bool
+}
+
+// requestTimeout cancels the request context after d and answers 504 when a
+// handler ran into the deadline. Unlike middleware.Timeout, handlers that
+// detached with withoutRequestTimeout are left alone.
+func requestTimeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rd := &requestDeadline{parent: r.Context()}
+			ctx, cancel := context.WithTimeout(context.WithValue(r.Context(), requestDeadlineKey{}, rd), d)
+			defer func() {
+				cancel()
+				if ctx.Err() == context.DeadlineExceeded && !rd.detached.Load() {
+					w.WriteHeader(http.StatusGatewayTimeout)
+				}
+			}()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// withoutRequestTimeout returns a context that keeps ctx's values but not the
+// router deadline. It is still cancelled when the client goes away. Proxied
+// requests are bounded by the upstream and route timeouts instead, which lets
+// event streams outlive the router deadline.
+func withoutRequestTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	rd, ok := ctx.Value(requestDeadlineKey{}).(*requestDeadline)
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	rd.detached.Store(true)
+	detached, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(rd.parent, cancel)
+	return detached, func() {
+		stop()
+		cancel()
+	}
+}
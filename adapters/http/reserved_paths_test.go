@@ -1,12 +1,17 @@
 package http_test
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/artpar/apigate/adapters/clock"
 	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/adapters/memory"
 	"github.com/artpar/apigate/adapters/metrics"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/route"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog"
 )
@@ -225,6 +230,75 @@ func TestCatchAllRouteCannotOverrideReservedPaths(t *testing.T) {
 	}
 }
 
+// TestBuiltInEndpointsNotShadowedByAPICatchAll verifies that built-in endpoints
+// under /api win over an /api/* proxy route, whatever the route's priority.
+func TestBuiltInEndpointsNotShadowedByAPICatchAll(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("PROXIED"))
+	}))
+	defer backend.Close()
+
+	routes := []route.Route{{
+		ID: "api", Name: "api", PathPattern: "/api/*", MatchType: route.MatchPrefix,
+		UpstreamID: "backend", Protocol: route.ProtocolHTTP, Priority: 1000, Enabled: true,
+	}}
+	upstreams := []route.Upstream{{ID: "backend", Name: "backend", BaseURL: backend.URL, Enabled: true}}
+
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: backend.URL})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	defer client.Close()
+
+	clk := clock.NewFake(baseTime)
+	service := app.NewProxyService(app.ProxyDeps{
+		Keys:      memory.NewKeyStore(),
+		Users:     memory.NewUserStore(),
+		RateLimit: memory.NewRateLimitStore(),
+		Usage:     &testUsageRecorder{},
+		Upstream:  client,
+		Clock:     clk,
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{KeyPrefix: "ak_", RateWindow: 60})
+	routeService := app.NewRouteService(&staticRouteStore{routes: routes}, &staticUpstreamStore{upstreams: upstreams},
+		clk, zerolog.Nop(), app.RouteServiceConfig{})
+	if err := routeService.Reload(context.Background()); err != nil {
+		t.Fatalf("reload routes: %v", err)
+	}
+	service.SetRouteService(routeService)
+
+	cfg := apihttp.RouterConfig{
+		MeterHandler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("METER"))
+		}),
+		MeterEnabled: true,
+		RouteService: routeService,
+	}
+	router := apihttp.NewRouterWithConfig(apihttp.NewProxyHandler(service, zerolog.Nop()), apihttp.NewHealthHandler(nil), zerolog.Nop(), cfg)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/api/v1/meter", "METER"},
+		{"/api/v1/meter/events", "METER"},
+		{"/api/v1/orders", "PROXIED"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if body := rec.Body.String(); body != tt.want {
+				t.Errorf("body = %q, want %q", body, tt.want)
+			}
+		})
+	}
+
+	if reserved := apihttp.ReservedPaths(cfg); !reserved.Contains("/api/v1/meter") || reserved.Contains("/api/v1/orders") {
+		t.Errorf("ReservedPaths() = %v", reserved.Prefixes())
+	}
+}
+
 // contains checks if a string contains a substring.
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && indexOf(s, substr) >= 0
@@ -0,0 +1,256 @@
+package http_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/domain/route"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const cacheTestKey = "ak_1f2e3d4c5b6a79881f2e3d4c5b6a79881f2e3d4c5b6a79881f2e3d4c5b6a7988"
+
+// newCachingProxy returns a proxy handler with a response cache in front of a
+// /catalog/* route to backendURL, authenticated with cacheTestKey.
+func newCachingProxy(t *testing.T, backendURL string, ttl time.Duration, keyHeaders []string) (*apihttp.ProxyHandler, *clock.Fake, *testUsageRecorder) {
+	t.Helper()
+
+	upstreams := []route.Upstream{{ID: "catalog", Name: "catalog", BaseURL: backendURL, Enabled: true}}
+	routes := []route.Route{{
+		ID: "catalog", Name: "catalog", PathPattern: "/catalog/*", MatchType: route.MatchPrefix,
+		UpstreamID: "catalog", Protocol: route.ProtocolHTTP, AuthRequired: true, Enabled: true,
+		CacheTTL: ttl, CacheKeyHeaders: keyHeaders,
+	}}
+
+	keys := memory.NewKeyStore()
+	users := memory.NewUserStore()
+	keyHash, _ := bcrypt.GenerateFromPassword([]byte(cacheTestKey), bcrypt.MinCost)
+	keys.Create(context.Background(), key.Key{
+		ID: "key-1", UserID: "user-1", Hash: keyHash, Prefix: cacheTestKey[:12], CreatedAt: baseTime.Add(-time.Hour),
+	})
+	users.Create(context.Background(), ports.User{ID: "user-1", Email: "cache@example.com", PlanID: "free", Status: "active"})
+
+	clk := clock.NewFake(baseTime)
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: backendURL})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	recorder := &testUsageRecorder{}
+	service := app.NewProxyService(app.ProxyDeps{
+		Keys:      keys,
+		Users:     users,
+		RateLimit: memory.NewRateLimitStore(),
+		Usage:     recorder,
+		Upstream:  client,
+		Clock:     clk,
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100,
+		RateWindow: 60,
+		Plans:      []plan.Plan{{ID: "free", Name: "Free", RateLimitPerMinute: 600, RequestsPerMonth: 1000}},
+	})
+	service.SetResponseCache(memory.NewResponseCache(clk, 0))
+
+	routeService := app.NewRouteService(&staticRouteStore{routes: routes}, &staticUpstreamStore{upstreams: upstreams},
+		clk, zerolog.Nop(), app.RouteServiceConfig{})
+	if err := routeService.Reload(context.Background()); err != nil {
+		t.Fatalf("reload routes: %v", err)
+	}
+	service.SetRouteService(routeService)
+	return apihttp.NewProxyHandler(service, zerolog.Nop()), clk, recorder
+}
+
+func TestProxy_ResponseCache(t *testing.T) {
+	// Upstream returns a new version on every request so cached responses are recognisable
+	var hits atomic.Int64
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := hits.Add(1)
+		if r.URL.Path == "/catalog/private" {
+			w.Header().Set("Cache-Control", "no-store")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"version":%d,"accept":%q}`, n, r.Header.Get("Accept"))
+	}))
+	defer backend.Close()
+
+	handler, clk, recorder := newCachingProxy(t, backend.URL, time.Minute, []string{"Accept"})
+
+	send := func(method, path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-API-Key", cacheTestKey)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s %s status = %d: %s", method, path, rec.Code, rec.Body.String())
+		}
+		return rec
+	}
+	expect := func(rec *httptest.ResponseRecorder, cacheStatus, body string, upstreamHits int64) {
+		t.Helper()
+		if got := rec.Header().Get("X-Cache"); got != cacheStatus {
+			t.Errorf("X-Cache = %q, want %q", got, cacheStatus)
+		}
+		if rec.Body.String() != body {
+			t.Errorf("body = %s, want %s", rec.Body.String(), body)
+		}
+		if got := hits.Load(); got != upstreamHits {
+			t.Errorf("upstream hits = %d, want %d", got, upstreamHits)
+		}
+	}
+
+	acceptJSON := map[string]string{"Accept": "application/json"}
+	v1 := `{"version":1,"accept":"application/json"}`
+
+	// First request misses and fills the cache
+	expect(send("GET", "/catalog/items?page=1", acceptJSON), "MISS", v1, 1)
+
+	// Within the TTL the cached response is served without calling the upstream
+	clk.Advance(59 * time.Second)
+	rec := send("GET", "/catalog/items?page=1", acceptJSON)
+	expect(rec, "HIT", v1, 1)
+	if rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("cached Content-Type = %q", rec.Header().Get("Content-Type"))
+	}
+	if rec.Header().Get("X-RateLimit-Remaining") == "" {
+		t.Error("cache hit missing rate limit headers")
+	}
+
+	// Key headers, query and method are part of the cache key
+	expect(send("GET", "/catalog/items?page=1", map[string]string{"Accept": "text/csv"}), "MISS", `{"version":2,"accept":"text/csv"}`, 2)
+	expect(send("GET", "/catalog/items?page=2", acceptJSON), "MISS", `{"version":3,"accept":"application/json"}`, 3)
+	if rec := send("POST", "/catalog/items?page=1", acceptJSON); rec.Header().Get("X-Cache") != "" || hits.Load() != 4 {
+		t.Errorf("POST X-Cache = %q, upstream hits = %d; want uncached", rec.Header().Get("X-Cache"), hits.Load())
+	}
+
+	// Cache-Control: no-store on the request bypasses the cache
+	rec = send("GET", "/catalog/items?page=1", map[string]string{"Accept": "application/json", "Cache-Control": "no-store"})
+	expect(rec, "", `{"version":5,"accept":"application/json"}`, 5)
+
+	// Responses marked no-store are never cached
+	send("GET", "/catalog/private", acceptJSON)
+	expect(send("GET", "/catalog/private", acceptJSON), "MISS", `{"version":7,"accept":"application/json"}`, 7)
+
+	// Once the TTL expires the upstream is called again
+	clk.Advance(time.Second)
+	expect(send("GET", "/catalog/items?page=1", acceptJSON), "MISS", `{"version":8,"accept":"application/json"}`, 8)
+	expect(send("GET", "/catalog/items?page=1", acceptJSON), "HIT", `{"version":8,"accept":"application/json"}`, 8)
+
+	// Every request is metered, including cache hits
+	if len(recorder.events) != 10 {
+		t.Fatalf("usage events = %d, want 10", len(recorder.events))
+	}
+	if ev := recorder.events[1]; ev.KeyID != "key-1" || ev.Path != "/catalog/items" || ev.StatusCode != http.StatusOK {
+		t.Errorf("cache hit usage event = %+v", ev)
+	}
+}
+
+func TestProxy_ResponseCache_ConditionalRequests(t *testing.T) {
+	var hits atomic.Int64
+	var conditional atomic.Int64 // requests reaching the upstream with conditional headers
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Modified-Since") != "" {
+			conditional.Add(1)
+		}
+		if r.URL.Path == "/catalog/tagged" {
+			w.Header().Set("ETag", `"catalog-v7"`)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"items":["a","b"]}`))
+	}))
+	defer backend.Close()
+
+	handler, clk, recorder := newCachingProxy(t, backend.URL, time.Minute, nil)
+
+	send := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("X-API-Key", cacheTestKey)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	expectNotModified := func(rec *httptest.ResponseRecorder, etag string) {
+		t.Helper()
+		if rec.Code != http.StatusNotModified {
+			t.Fatalf("status = %d, want 304", rec.Code)
+		}
+		if rec.Body.Len() != 0 {
+			t.Errorf("304 body = %q, want empty", rec.Body.String())
+		}
+		if got := rec.Header().Get("ETag"); got != etag {
+			t.Errorf("304 ETag = %q, want %q", got, etag)
+		}
+	}
+
+	// Populate the cache; the gateway generates a weak ETag from the body
+	rec := send("/catalog/items", nil)
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"items":["a","b"]}` {
+		t.Fatalf("populate = %d %s", rec.Code, rec.Body.String())
+	}
+	etag := rec.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("generated ETag = %q, want weak ETag", etag)
+	}
+	lastModified := rec.Header().Get("Last-Modified")
+	if lastModified != baseTime.UTC().Format(http.TimeFormat) {
+		t.Errorf("Last-Modified = %q, want cache time", lastModified)
+	}
+
+	// If-None-Match with the cached ETag is answered from the cache with 304
+	clk.Advance(30 * time.Second)
+	expectNotModified(send("/catalog/items", map[string]string{"If-None-Match": etag}), etag)
+
+	// If-Modified-Since at or after Last-Modified is answered with 304
+	expectNotModified(send("/catalog/items", map[string]string{"If-Modified-Since": lastModified}), etag)
+
+	// A stale validator gets the full cached response
+	rec = send("/catalog/items", map[string]string{"If-None-Match": `W/"stale"`})
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"items":["a","b"]}` || rec.Header().Get("X-Cache") != "HIT" {
+		t.Errorf("stale validator = %d %s (X-Cache %q), want cached 200", rec.Code, rec.Body.String(), rec.Header().Get("X-Cache"))
+	}
+	if got := hits.Load(); got != 1 {
+		t.Errorf("upstream hits = %d, want 1", got)
+	}
+
+	// Upstream ETags are kept, and a conditional first request still fills the
+	// cache with the full response before the gateway answers 304
+	expectNotModified(send("/catalog/tagged", map[string]string{"If-None-Match": `"catalog-v7"`}), `"catalog-v7"`)
+	if conditional.Load() != 0 {
+		t.Error("conditional headers were forwarded to the upstream")
+	}
+	rec = send("/catalog/tagged", nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Cache") != "HIT" || rec.Body.Len() == 0 {
+		t.Errorf("after conditional miss = %d (X-Cache %q), want cached 200 with body", rec.Code, rec.Header().Get("X-Cache"))
+	}
+
+	// Conditional requests are metered with their 304 status
+	if len(recorder.events) != 6 {
+		t.Fatalf("usage events = %d, want 6", len(recorder.events))
+	}
+	if got := recorder.events[1].StatusCode; got != http.StatusNotModified {
+		t.Errorf("metered status = %d, want 304", got)
+	}
+}
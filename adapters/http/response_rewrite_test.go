@@ -0,0 +1,175 @@
+package http_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/artpar/apigate/adapters/clock"
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/domain/route"
+	"github.com/rs/zerolog"
+)
+
+func TestProxy_RewriteResponseURLs(t *testing.T) {
+	var backendURL string
+	var gzipped, png []byte
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch strings.TrimPrefix(r.URL.Path, "/plain") {
+		case "/api/orders":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"self":"` + backendURL + `/orders/1","escaped":"` + strings.ReplaceAll(backendURL, "/", `\/`) + `\/orders\/1"}`))
+		case "/api/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(`<a href="` + backendURL + `/docs">docs</a>`))
+		case "/api/archive":
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(gzipped)
+		case "/api/logo":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(png)
+		case "/api/feed":
+			w.Header().Set("Content-Type", "application/json")
+			flusher := w.(http.Flusher)
+			half := len(backendURL) / 2
+			for _, chunk := range []string{`[{"href":"` + backendURL[:half], backendURL[half:] + `/1"},`, `{"href":"` + backendURL + `/2"}]`} {
+				w.Write([]byte(chunk))
+				flusher.Flush()
+			}
+		}
+	}))
+	defer backend.Close()
+	backendURL = backend.URL
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(`{"self":"` + backendURL + `/archive"}`))
+	zw.Close()
+	gzipped = buf.Bytes()
+	png = append([]byte("\x89PNG\r\n\x1a\n"), backendURL...)
+
+	upstreams := []route.Upstream{{ID: "backend", Name: "backend", BaseURL: backend.URL, Enabled: true}}
+	routes := []route.Route{
+		{
+			ID: "api", Name: "api", PathPattern: "/api/*", MatchType: route.MatchPrefix,
+			UpstreamID: "backend", Protocol: route.ProtocolHTTP, Enabled: true, RewriteResponseURLs: true,
+		},
+		{
+			ID: "feed", Name: "feed", PathPattern: "/api/feed", MatchType: route.MatchExact, Priority: 10,
+			UpstreamID: "backend", Protocol: route.ProtocolHTTPStream, Enabled: true, RewriteResponseURLs: true,
+		},
+		{
+			ID: "plain", Name: "plain", PathPattern: "/plain/*", MatchType: route.MatchPrefix,
+			UpstreamID: "backend", Protocol: route.ProtocolHTTP, Enabled: true,
+		},
+	}
+
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: backend.URL})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	defer client.Close()
+
+	clk := clock.NewFake(baseTime)
+	service := app.NewProxyService(app.ProxyDeps{
+		Keys:      memory.NewKeyStore(),
+		Users:     memory.NewUserStore(),
+		RateLimit: memory.NewRateLimitStore(),
+		Usage:     &testUsageRecorder{},
+		Upstream:  client,
+		Clock:     clk,
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100,
+		RateWindow: 60,
+		Plans:      []plan.Plan{{ID: "free", Name: "Free", RateLimitPerMinute: 600, RequestsPerMonth: -1}},
+		PublicURL:  "https://api.example.com",
+	})
+	routeService := app.NewRouteService(&staticRouteStore{routes: routes}, &staticUpstreamStore{upstreams: upstreams},
+		clk, zerolog.Nop(), app.RouteServiceConfig{})
+	if err := routeService.Reload(context.Background()); err != nil {
+		t.Fatalf("reload routes: %v", err)
+	}
+	service.SetRouteService(routeService)
+	handler := apihttp.NewProxyHandler(service, zerolog.Nop())
+	handler.SetStreamingUpstream(client)
+
+	get := func(t *testing.T, path string, header http.Header) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest("GET", path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d, want 200: %s", path, rec.Code, rec.Body.String())
+		}
+		return rec
+	}
+
+	t.Run("json", func(t *testing.T) {
+		rec := get(t, "/api/orders", nil)
+		want := `{"self":"https://api.example.com/orders/1","escaped":"https:\/\/api.example.com\/orders\/1"}`
+		if rec.Body.String() != want {
+			t.Errorf("body = %s, want %s", rec.Body.String(), want)
+		}
+		if cl := rec.Header().Get("Content-Length"); cl != "" {
+			t.Errorf("Content-Length = %s, want the upstream's length dropped", cl)
+		}
+	})
+
+	t.Run("html", func(t *testing.T) {
+		rec := get(t, "/api/page", nil)
+		if want := `<a href="https://api.example.com/docs">docs</a>`; rec.Body.String() != want {
+			t.Errorf("body = %s, want %s", rec.Body.String(), want)
+		}
+	})
+
+	t.Run("streamed json", func(t *testing.T) {
+		rec := get(t, "/api/feed", nil)
+		want := `[{"href":"https://api.example.com/1"},{"href":"https://api.example.com/2"}]`
+		if rec.Body.String() != want {
+			t.Errorf("body = %s, want %s", rec.Body.String(), want)
+		}
+	})
+
+	t.Run("gzip passed through untouched", func(t *testing.T) {
+		rec := get(t, "/api/archive", http.Header{"Accept-Encoding": {"gzip"}})
+		if !bytes.Equal(rec.Body.Bytes(), gzipped) {
+			t.Fatalf("gzip body was modified")
+		}
+		zr, err := gzip.NewReader(rec.Body)
+		if err != nil {
+			t.Fatalf("gzip reader: %v", err)
+		}
+		body, _ := io.ReadAll(zr)
+		if !strings.Contains(string(body), backendURL) {
+			t.Errorf("decompressed body = %s, want the upstream URL kept", body)
+		}
+	})
+
+	t.Run("binary passed through untouched", func(t *testing.T) {
+		rec := get(t, "/api/logo", nil)
+		if !bytes.Equal(rec.Body.Bytes(), png) {
+			t.Errorf("binary body = %q, want %q", rec.Body.Bytes(), png)
+		}
+	})
+
+	t.Run("route without rewriting", func(t *testing.T) {
+		rec := get(t, "/plain/api/orders", nil)
+		if !strings.Contains(rec.Body.String(), backendURL) {
+			t.Errorf("body = %s, want the upstream URL kept", rec.Body.String())
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package http_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/domain/route"
+	"github.com/rs/zerolog"
+)
+
+func TestProxy_Retry(t *testing.T) {
+	var mu sync.Mutex
+	hits := map[string]int{}
+	bodies := map[string][]string{}
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		hits[r.URL.Path]++
+		n := hits[r.URL.Path]
+		bodies[r.URL.Path] = append(bodies[r.URL.Path], string(body))
+		mu.Unlock()
+
+		// Never reuse connections; net/http transparently retries idempotent
+		// requests that fail on a reused connection
+		w.Header().Set("Connection", "close")
+
+		if n == 1 {
+			if strings.HasSuffix(r.URL.Path, "/unavailable") {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			// Reset the connection without a response
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+		w.Write([]byte(`{"attempt":"ok"}`))
+	}))
+	defer backend.Close()
+
+	policy := &route.RetryPolicy{Attempts: 2, Backoff: 10 * time.Millisecond, Statuses: []int{503}}
+	upstreams := []route.Upstream{{ID: "backend", Name: "backend", BaseURL: backend.URL, Enabled: true}}
+	routes := []route.Route{
+		{
+			ID: "retry", Name: "retry", PathPattern: "/retry/*", MatchType: route.MatchPrefix,
+			UpstreamID: "backend", Protocol: route.ProtocolHTTP, Enabled: true, Retry: policy,
+		},
+		{
+			ID: "writes", Name: "writes", PathPattern: "/writes/*", MatchType: route.MatchPrefix,
+			UpstreamID: "backend", Protocol: route.ProtocolHTTP, Enabled: true,
+			Retry: &route.RetryPolicy{Attempts: 1, Methods: []string{"POST"}},
+		},
+		{
+			ID: "plain", Name: "plain", PathPattern: "/plain/*", MatchType: route.MatchPrefix,
+			UpstreamID: "backend", Protocol: route.ProtocolHTTP, Enabled: true,
+		},
+	}
+
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: backend.URL})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	defer client.Close()
+
+	clk := clock.NewFake(baseTime)
+	service := app.NewProxyService(app.ProxyDeps{
+		Keys:      memory.NewKeyStore(),
+		Users:     memory.NewUserStore(),
+		RateLimit: memory.NewRateLimitStore(),
+		Usage:     &testUsageRecorder{},
+		Upstream:  client,
+		Clock:     clk,
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100,
+		RateWindow: 60,
+		Plans:      []plan.Plan{{ID: "free", Name: "Free", RateLimitPerMinute: 600, RequestsPerMonth: -1}},
+	})
+	routeService := app.NewRouteService(&staticRouteStore{routes: routes}, &staticUpstreamStore{upstreams: upstreams},
+		clk, zerolog.Nop(), app.RouteServiceConfig{})
+	if err := routeService.Reload(context.Background()); err != nil {
+		t.Fatalf("reload routes: %v", err)
+	}
+	service.SetRouteService(routeService)
+	handler := apihttp.NewProxyHandler(service, zerolog.Nop())
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+	hitsFor := func(path string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return hits[path]
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantHits   int
+	}{
+		{"GET after connection reset", "GET", "/retry/reset", "", http.StatusOK, 2},
+		{"GET after listed status", "GET", "/retry/unavailable", "", http.StatusOK, 2},
+		{"POST not retried by default", "POST", "/retry/create", `{"n":1}`, http.StatusBadGateway, 1},
+		{"POST retried when allowed", "POST", "/writes/create", `{"n":1}`, http.StatusOK, 2},
+		{"no policy", "GET", "/plain/reset", "", http.StatusBadGateway, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(tt.method, tt.path, tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK && rec.Body.String() != `{"attempt":"ok"}` {
+				t.Errorf("body = %s, want the successful attempt's body", rec.Body.String())
+			}
+			if got := hitsFor(tt.path); got != tt.wantHits {
+				t.Errorf("upstream hits = %d, want %d", got, tt.wantHits)
+			}
+		})
+	}
+
+	// The retried request carries the same body
+	mu.Lock()
+	defer mu.Unlock()
+	if got := bodies["/writes/create"]; len(got) != 2 || got[0] != `{"n":1}` || got[1] != got[0] {
+		t.Errorf("bodies sent = %q, want the same body twice", got)
+	}
+}
@@ -0,0 +1,105 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestProxy_RotatedKeyGracePeriod(t *testing.T) {
+	const oldRaw = "ak_1f2e3d4c5b6a1f2e3d4c5b6a1f2e3d4c5b6a1f2e3d4c5b6a1f2e3d4c5b6a1f2e"
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	ctx := context.Background()
+	keys := memory.NewKeyStore()
+	users := memory.NewUserStore()
+	users.Create(ctx, ports.User{ID: "user-1", Email: "rotate@example.com", PlanID: "free", Status: "active"})
+
+	oldHash, _ := bcrypt.GenerateFromPassword([]byte(oldRaw), bcrypt.MinCost)
+	old := key.Key{ID: "key-old", UserID: "user-1", Hash: oldHash, Prefix: oldRaw[:12], CreatedAt: baseTime.Add(-time.Hour)}
+	keys.Create(ctx, old)
+
+	// Rotate with a one hour grace period
+	newRaw, replacement, revokeAt := key.Rotate(old, "ak_", baseTime, time.Hour)
+	keys.Create(ctx, replacement)
+	keys.Revoke(ctx, old.ID, revokeAt)
+
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: backend.URL})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	defer client.Close()
+
+	clk := clock.NewFake(baseTime)
+	recorder := &testUsageRecorder{}
+	service := app.NewProxyService(app.ProxyDeps{
+		Keys:      keys,
+		Users:     users,
+		RateLimit: memory.NewRateLimitStore(),
+		Usage:     recorder,
+		Upstream:  client,
+		Clock:     clk,
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100,
+		RateWindow: 60,
+		Plans:      []plan.Plan{{ID: "free", Name: "Free", RateLimitPerMinute: 600, RequestsPerMonth: -1}},
+	})
+	handler := apihttp.NewProxyHandler(service, zerolog.Nop())
+
+	call := func(rawKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/data", nil)
+		req.Header.Set("X-API-Key", rawKey)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// During the grace period both keys work and usage goes to the same user
+	clk.Advance(30 * time.Minute)
+	if rec := call(oldRaw); rec.Code != http.StatusOK {
+		t.Fatalf("old key during grace: status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	if rec := call(newRaw); rec.Code != http.StatusOK {
+		t.Fatalf("new key during grace: status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	if len(recorder.events) != 2 {
+		t.Fatalf("usage events = %d, want 2", len(recorder.events))
+	}
+	for _, e := range recorder.events {
+		if e.UserID != "user-1" {
+			t.Errorf("usage event attributed to %q, want user-1", e.UserID)
+		}
+	}
+
+	// Once the grace period ends the old key is revoked
+	clk.Advance(time.Hour)
+	rec := call(oldRaw)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("old key after grace: status = %d, want 401", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), key.ReasonRevoked) {
+		t.Errorf("body = %s, want %s error", rec.Body.String(), key.ReasonRevoked)
+	}
+	if rec := call(newRaw); rec.Code != http.StatusOK {
+		t.Errorf("new key after grace: status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,130 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/domain/route"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestProxy_RequiredScopes(t *testing.T) {
+	var hits int
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	keyScopes := map[string][]string{
+		"ak_5c0a0e5c0a0e5c0a0e5c0a0e5c0a0e5c0a0e5c0a0e5c0a0e5c0a0e5c0a0e5c0a": {"catalog:read"},
+		"ak_3d1f2a4b5c6e3d1f2a4b5c6e3d1f2a4b5c6e3d1f2a4b5c6e3d1f2a4b5c6e3d1f": {"catalog:*"},
+		"ak_7e8f90a1b2c37e8f90a1b2c37e8f90a1b2c37e8f90a1b2c37e8f90a1b2c37e8f": {"orders:read"},
+		"ak_0b1c2d3e4f500b1c2d3e4f500b1c2d3e4f500b1c2d3e4f500b1c2d3e4f500b1c": nil,
+	}
+	keys := memory.NewKeyStore()
+	users := memory.NewUserStore()
+	users.Create(context.Background(), ports.User{ID: "user-1", Email: "scopes@example.com", PlanID: "free", Status: "active"})
+	for rawKey, scopes := range keyScopes {
+		keyHash, _ := bcrypt.GenerateFromPassword([]byte(rawKey), bcrypt.MinCost)
+		keys.Create(context.Background(), key.Key{
+			ID: rawKey[3:12], UserID: "user-1", Hash: keyHash, Prefix: rawKey[:12], Scopes: scopes, CreatedAt: baseTime.Add(-time.Hour),
+		})
+	}
+
+	upstreams := []route.Upstream{{ID: "backend", Name: "backend", BaseURL: backend.URL, Enabled: true}}
+	routes := []route.Route{
+		{
+			ID: "catalog", Name: "catalog", PathPattern: "/catalog/*", MatchType: route.MatchPrefix,
+			UpstreamID: "backend", Protocol: route.ProtocolHTTP, AuthRequired: true, Enabled: true,
+			RequiredScopes: []string{"catalog:read"},
+		},
+		{
+			ID: "catalog-admin", Name: "catalog-admin", PathPattern: "/admin/catalog/*", MatchType: route.MatchPrefix,
+			UpstreamID: "backend", Protocol: route.ProtocolHTTP, AuthRequired: true, Enabled: true,
+			RequiredScopes: []string{"catalog:read", "catalog:write"},
+		},
+		{
+			ID: "status", Name: "status", PathPattern: "/status", MatchType: route.MatchExact,
+			UpstreamID: "backend", Protocol: route.ProtocolHTTP, AuthRequired: true, Enabled: true,
+		},
+	}
+
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: backend.URL})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	defer client.Close()
+
+	clk := clock.NewFake(baseTime)
+	service := app.NewProxyService(app.ProxyDeps{
+		Keys:      keys,
+		Users:     users,
+		RateLimit: memory.NewRateLimitStore(),
+		Usage:     &testUsageRecorder{},
+		Upstream:  client,
+		Clock:     clk,
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100,
+		RateWindow: 60,
+		Plans:      []plan.Plan{{ID: "free", Name: "Free", RateLimitPerMinute: 600, RequestsPerMonth: -1}},
+	})
+	routeService := app.NewRouteService(&staticRouteStore{routes: routes}, &staticUpstreamStore{upstreams: upstreams},
+		clk, zerolog.Nop(), app.RouteServiceConfig{})
+	if err := routeService.Reload(context.Background()); err != nil {
+		t.Fatalf("reload routes: %v", err)
+	}
+	service.SetRouteService(routeService)
+	handler := apihttp.NewProxyHandler(service, zerolog.Nop())
+
+	tests := []struct {
+		name       string
+		rawKey     string
+		path       string
+		wantStatus int
+	}{
+		{"exact match", "ak_5c0a0e5c0a0e5c0a0e5c0a0e5c0a0e5c0a0e5c0a0e5c0a0e5c0a0e5c0a0e5c0a", "/catalog/items", http.StatusOK},
+		{"wildcard match", "ak_3d1f2a4b5c6e3d1f2a4b5c6e3d1f2a4b5c6e3d1f2a4b5c6e3d1f2a4b5c6e3d1f", "/catalog/items", http.StatusOK},
+		{"wildcard covers all required", "ak_3d1f2a4b5c6e3d1f2a4b5c6e3d1f2a4b5c6e3d1f2a4b5c6e3d1f2a4b5c6e3d1f", "/admin/catalog/items", http.StatusOK},
+		{"missing scope", "ak_7e8f90a1b2c37e8f90a1b2c37e8f90a1b2c37e8f90a1b2c37e8f90a1b2c37e8f", "/catalog/items", http.StatusForbidden},
+		{"one of several required missing", "ak_5c0a0e5c0a0e5c0a0e5c0a0e5c0a0e5c0a0e5c0a0e5c0a0e5c0a0e5c0a0e5c0a", "/admin/catalog/items", http.StatusForbidden},
+		{"unscoped key has full access", "ak_0b1c2d3e4f500b1c2d3e4f500b1c2d3e4f500b1c2d3e4f500b1c2d3e4f500b1c", "/admin/catalog/items", http.StatusOK},
+		{"route without required scopes", "ak_7e8f90a1b2c37e8f90a1b2c37e8f90a1b2c37e8f90a1b2c37e8f90a1b2c37e8f", "/status", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := hits
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Header.Set("X-API-Key", tt.rawKey)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusForbidden {
+				if !strings.Contains(rec.Body.String(), "insufficient_scope") {
+					t.Errorf("body = %s, want insufficient_scope error", rec.Body.String())
+				}
+				if hits != before {
+					t.Error("rejected request reached the upstream")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/domain/route"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestProxy_SignedRequests(t *testing.T) {
+	const (
+		rawKey = "ak_9a8b7c6d5e4f9a8b7c6d5e4f9a8b7c6d5e4f9a8b7c6d5e4f9a8b7c6d5e4f9a8b"
+		secret = "partner-signing-secret"
+	)
+
+	var received []string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.URL.Path)
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	keys := memory.NewKeyStore()
+	users := memory.NewUserStore()
+	users.Create(context.Background(), ports.User{ID: "user-1", Email: "partner@example.com", PlanID: "free", Status: "active"})
+	keyHash, _ := bcrypt.GenerateFromPassword([]byte(rawKey), bcrypt.MinCost)
+	keys.Create(context.Background(), key.Key{
+		ID: "partner", UserID: "user-1", Hash: keyHash, Prefix: rawKey[:12], SigningSecret: secret, CreatedAt: baseTime.Add(-time.Hour),
+	})
+
+	upstreams := []route.Upstream{{ID: "backend", Name: "backend", BaseURL: backend.URL, Enabled: true}}
+	routes := []route.Route{
+		{
+			ID: "partner", Name: "partner", PathPattern: "/partner/*", MatchType: route.MatchPrefix,
+			UpstreamID: "backend", Protocol: route.ProtocolHTTP, AuthRequired: true, AuthMethod: route.AuthMethodHMAC, Enabled: true,
+		},
+		{
+			ID: "status", Name: "status", PathPattern: "/status", MatchType: route.MatchExact,
+			UpstreamID: "backend", Protocol: route.ProtocolHTTP, AuthRequired: true, Enabled: true,
+		},
+	}
+
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: backend.URL})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	defer client.Close()
+
+	clk := clock.NewFake(baseTime)
+	service := app.NewProxyService(app.ProxyDeps{
+		Keys:      keys,
+		Users:     users,
+		RateLimit: memory.NewRateLimitStore(),
+		Usage:     &testUsageRecorder{},
+		Upstream:  client,
+		Clock:     clk,
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:        "ak_",
+		RateBurst:        100,
+		RateWindow:       60,
+		Plans:            []plan.Plan{{ID: "free", Name: "Free", RateLimitPerMinute: 600, RequestsPerMonth: -1}},
+		SignatureMaxSkew: 5 * time.Minute,
+	})
+	routeService := app.NewRouteService(&staticRouteStore{routes: routes}, &staticUpstreamStore{upstreams: upstreams},
+		clk, zerolog.Nop(), app.RouteServiceConfig{})
+	if err := routeService.Reload(context.Background()); err != nil {
+		t.Fatalf("reload routes: %v", err)
+	}
+	service.SetRouteService(routeService)
+	handler := apihttp.NewProxyHandler(service, zerolog.Nop())
+
+	// signedRequest signs body as of the fake clock's current time
+	signedRequest := func(target, body string) *http.Request {
+		req := httptest.NewRequest("POST", target, strings.NewReader(body))
+		ts := strconv.FormatInt(clk.Now().Unix(), 10)
+		req.Header.Set(key.SignatureKeyHeader, rawKey[:12])
+		req.Header.Set(key.SignatureTimestampHeader, ts)
+		req.Header.Set(key.SignatureHeader, key.Sign(secret, key.StringToSign("POST", req.URL.Path, req.URL.RawQuery, ts, []byte(body))))
+		return req
+	}
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	expectRejected := func(t *testing.T, rec *httptest.ResponseRecorder, code string) {
+		t.Helper()
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("status = %d, want 401: %s", rec.Code, rec.Body.String())
+		}
+		if !strings.Contains(rec.Body.String(), code) {
+			t.Errorf("body = %s, want %s error", rec.Body.String(), code)
+		}
+	}
+
+	t.Run("valid signature", func(t *testing.T) {
+		before := len(received)
+		rec := serve(signedRequest("/partner/orders?dry_run=true", `{"item":"book"}`))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+		}
+		if len(received) != before+1 {
+			t.Error("signed request did not reach the upstream")
+		}
+	})
+
+	t.Run("replayed stale timestamp", func(t *testing.T) {
+		req := signedRequest("/partner/orders", `{"item":"book"}`)
+		clk.Advance(6 * time.Minute)
+		defer clk.Advance(-6 * time.Minute)
+		expectRejected(t, serve(req), key.ReasonSignatureExpired)
+	})
+
+	t.Run("tampered body", func(t *testing.T) {
+		req := signedRequest("/partner/orders", `{"item":"book"}`)
+		tampered := httptest.NewRequest("POST", "/partner/orders", strings.NewReader(`{"item":"car"}`))
+		tampered.Header = req.Header
+		expectRejected(t, serve(tampered), key.ReasonInvalidSignature)
+	})
+
+	t.Run("missing signature headers", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/partner/orders", nil)
+		req.Header.Set("X-API-Key", rawKey)
+		expectRejected(t, serve(req), key.ReasonMissingSignature)
+	})
+
+	t.Run("api key route still accepts keys", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/status", nil)
+		req.Header.Set("X-API-Key", rawKey)
+		if rec := serve(req); rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+		}
+	})
+}
@@ -0,0 +1,210 @@
+package http_test
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/domain/route"
+	"github.com/rs/zerolog"
+)
+
+func TestProxy_EventStreamPassthrough(t *testing.T) {
+	var hits atomic.Int32
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		w.Write([]byte("data: first\n\n"))
+		flusher.Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		w.Write([]byte("data: second\n\n"))
+		flusher.Flush()
+	}))
+	defer backend.Close()
+
+	// A plain http route with caching enabled; only the response reveals the stream
+	upstreams := []route.Upstream{{ID: "backend", Name: "backend", BaseURL: backend.URL, Enabled: true}}
+	routes := []route.Route{{
+		ID: "events", Name: "events", PathPattern: "/events", MatchType: route.MatchExact,
+		UpstreamID: "backend", Protocol: route.ProtocolHTTP, Enabled: true,
+		CacheTTL: time.Minute, RewriteResponseURLs: true,
+	}}
+
+	// The stream must outlive the buffered client's timeout
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: backend.URL, Timeout: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	defer client.Close()
+
+	clk := clock.NewFake(baseTime)
+	service := app.NewProxyService(app.ProxyDeps{
+		Keys:      memory.NewKeyStore(),
+		Users:     memory.NewUserStore(),
+		RateLimit: memory.NewRateLimitStore(),
+		Usage:     &testUsageRecorder{},
+		Upstream:  client,
+		Clock:     clk,
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100,
+		RateWindow: 60,
+		Plans:      []plan.Plan{{ID: "free", Name: "Free", RateLimitPerMinute: 600, RequestsPerMonth: -1}},
+	})
+	service.SetResponseCache(memory.NewResponseCache(clk, 0))
+	routeService := app.NewRouteService(&staticRouteStore{routes: routes}, &staticUpstreamStore{upstreams: upstreams},
+		clk, zerolog.Nop(), app.RouteServiceConfig{})
+	if err := routeService.Reload(context.Background()); err != nil {
+		t.Fatalf("reload routes: %v", err)
+	}
+	service.SetRouteService(routeService)
+	handler := apihttp.NewProxyHandler(service, zerolog.Nop())
+	handler.SetStreamingUpstream(client)
+
+	gateway := httptest.NewServer(handler)
+	defer gateway.Close()
+
+	resp, err := http.Get(gateway.URL + "/events")
+	if err != nil {
+		t.Fatalf("GET /events: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+	if xc := resp.Header.Get("X-Cache"); xc != "" {
+		t.Errorf("X-Cache = %q, want event streams to bypass the cache", xc)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	readEvent := func() string {
+		t.Helper()
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read event: %v", err)
+		}
+		if blank, err := reader.ReadString('\n'); err != nil || blank != "\n" {
+			t.Fatalf("read event terminator = %q, %v", blank, err)
+		}
+		return line
+	}
+
+	// The first event arrives while the upstream is still holding the stream open
+	if got := readEvent(); got != "data: first\n" {
+		t.Fatalf("first event = %q", got)
+	}
+	time.Sleep(300 * time.Millisecond)
+	close(release)
+	if got := readEvent(); got != "data: second\n" {
+		t.Fatalf("second event = %q", got)
+	}
+	if rest, err := io.ReadAll(reader); err != nil || len(rest) != 0 {
+		t.Errorf("after events: %q, %v; want clean end of stream", rest, err)
+	}
+
+	// Not cached: a second request reaches the upstream again
+	resp2, err := http.Get(gateway.URL + "/events")
+	if err != nil {
+		t.Fatalf("second GET /events: %v", err)
+	}
+	io.Copy(io.Discard, resp2.Body)
+	resp2.Body.Close()
+	if n := hits.Load(); n != 2 {
+		t.Errorf("upstream hits = %d, want 2", n)
+	}
+}
+
+func TestProxy_EventStreamOutlivesRouterTimeout(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		for i := 0; i < 3; i++ {
+			w.Write([]byte("data: tick\n\n"))
+			flusher.Flush()
+			select {
+			case <-time.After(150 * time.Millisecond):
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}))
+	defer backend.Close()
+
+	upstreams := []route.Upstream{{ID: "backend", Name: "backend", BaseURL: backend.URL, Enabled: true}}
+	routes := []route.Route{{
+		ID: "events", Name: "events", PathPattern: "/events", MatchType: route.MatchExact,
+		UpstreamID: "backend", Protocol: route.ProtocolSSE, Enabled: true,
+	}}
+
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: backend.URL, Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	defer client.Close()
+
+	clk := clock.NewFake(baseTime)
+	service := app.NewProxyService(app.ProxyDeps{
+		Keys:      memory.NewKeyStore(),
+		Users:     memory.NewUserStore(),
+		RateLimit: memory.NewRateLimitStore(),
+		Usage:     &testUsageRecorder{},
+		Upstream:  client,
+		Clock:     clk,
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100,
+		RateWindow: 60,
+		Plans:      []plan.Plan{{ID: "free", Name: "Free", RateLimitPerMinute: 600, RequestsPerMonth: -1}},
+	})
+	routeService := app.NewRouteService(&staticRouteStore{routes: routes}, &staticUpstreamStore{upstreams: upstreams},
+		clk, zerolog.Nop(), app.RouteServiceConfig{})
+	if err := routeService.Reload(context.Background()); err != nil {
+		t.Fatalf("reload routes: %v", err)
+	}
+	service.SetRouteService(routeService)
+	handler := apihttp.NewProxyHandler(service, zerolog.Nop())
+	handler.SetStreamingUpstream(client)
+
+	// The stream runs for ~450ms against a 100ms router deadline
+	router := apihttp.NewRouterWithConfig(handler, apihttp.NewHealthHandler(nil), zerolog.Nop(),
+		apihttp.RouterConfig{RequestTimeout: 100 * time.Millisecond})
+	gateway := httptest.NewServer(router)
+	defer gateway.Close()
+
+	resp, err := http.Get(gateway.URL + "/events")
+	if err != nil {
+		t.Fatalf("GET /events: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read stream: %v", err)
+	}
+	if got, want := string(body), "data: tick\n\ndata: tick\n\ndata: tick\n\n"; got != want {
+		t.Errorf("stream = %q, want all three events", got)
+	}
+}
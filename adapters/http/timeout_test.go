@@ -0,0 +1,130 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/domain/route"
+	"github.com/rs/zerolog"
+)
+
+func TestProxy_RouteTimeouts(t *testing.T) {
+	done := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow-headers":
+			select {
+			case <-time.After(5 * time.Second):
+			case <-done:
+			}
+		case "/slow-body":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"items":[`))
+			w.(http.Flusher).Flush()
+			select {
+			case <-time.After(5 * time.Second):
+			case <-done:
+			}
+			w.Write([]byte(`]}`))
+		default:
+			w.Write([]byte(`{"ok":true}`))
+		}
+	}))
+	defer backend.Close()
+	defer close(done)
+
+	// The upstream's own timeout is generous; the routes tighten it
+	upstreams := []route.Upstream{{ID: "backend", Name: "backend", BaseURL: backend.URL, Timeout: 10 * time.Second, Enabled: true}}
+	routes := []route.Route{
+		{
+			ID: "headers", Name: "headers", PathPattern: "/slow-headers", MatchType: route.MatchExact,
+			UpstreamID: "backend", Protocol: route.ProtocolHTTP, Enabled: true,
+			ResponseHeaderTimeout: 200 * time.Millisecond,
+		},
+		{
+			ID: "body", Name: "body", PathPattern: "/slow-body", MatchType: route.MatchExact,
+			UpstreamID: "backend", Protocol: route.ProtocolHTTP, Enabled: true,
+			ResponseHeaderTimeout: time.Second, RequestTimeout: 300 * time.Millisecond,
+		},
+		{
+			ID: "fast", Name: "fast", PathPattern: "/fast", MatchType: route.MatchExact,
+			UpstreamID: "backend", Protocol: route.ProtocolHTTP, Enabled: true,
+			ResponseHeaderTimeout: 200 * time.Millisecond, RequestTimeout: 300 * time.Millisecond,
+		},
+	}
+
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: backend.URL})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	defer client.Close()
+
+	clk := clock.NewFake(baseTime)
+	service := app.NewProxyService(app.ProxyDeps{
+		Keys:      memory.NewKeyStore(),
+		Users:     memory.NewUserStore(),
+		RateLimit: memory.NewRateLimitStore(),
+		Usage:     &testUsageRecorder{},
+		Upstream:  client,
+		Clock:     clk,
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100,
+		RateWindow: 60,
+		Plans:      []plan.Plan{{ID: "free", Name: "Free", RateLimitPerMinute: 600, RequestsPerMonth: -1}},
+	})
+	routeService := app.NewRouteService(&staticRouteStore{routes: routes}, &staticUpstreamStore{upstreams: upstreams},
+		clk, zerolog.Nop(), app.RouteServiceConfig{})
+	if err := routeService.Reload(context.Background()); err != nil {
+		t.Fatalf("reload routes: %v", err)
+	}
+	service.SetRouteService(routeService)
+	handler := apihttp.NewProxyHandler(service, zerolog.Nop())
+
+	tests := []struct {
+		name     string
+		path     string
+		deadline time.Duration
+		message  string
+	}{
+		{"response header timeout", "/slow-headers", 200 * time.Millisecond, "Upstream sent no response headers within 200ms"},
+		{"request timeout", "/slow-body", 300 * time.Millisecond, "Upstream request did not complete within 300ms"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			start := time.Now()
+			handler.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+			elapsed := time.Since(start)
+
+			if rec.Code != http.StatusGatewayTimeout {
+				t.Fatalf("status = %d, want 504: %s", rec.Code, rec.Body.String())
+			}
+			if elapsed < tt.deadline || elapsed > tt.deadline+time.Second {
+				t.Errorf("504 after %v, want at the %v deadline", elapsed, tt.deadline)
+			}
+			body := rec.Body.String()
+			if !strings.Contains(body, "upstream_timeout") || !strings.Contains(body, tt.message) {
+				t.Errorf("body = %s, want upstream_timeout with %q", body, tt.message)
+			}
+		})
+	}
+
+	t.Run("within deadlines", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/fast", nil))
+		if rec.Code != http.StatusOK || rec.Body.String() != `{"ok":true}` {
+			t.Errorf("got %d %s, want 200", rec.Code, rec.Body.String())
+		}
+	})
+}
@@ -0,0 +1,180 @@
+package http_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestProxy_Tracing(t *testing.T) {
+	var mu sync.Mutex
+	var upstreamTraceparent string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		upstreamTraceparent = r.Header.Get("Traceparent")
+		mu.Unlock()
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	const rawKey = "ak_9e9e9e9e9e9e9e9e9e9e9e9e9e9e9e9e9e9e9e9e9e9e9e9e9e9e9e9e9e9e9e9e"
+	keys := memory.NewKeyStore()
+	users := memory.NewUserStore()
+	keyHash, _ := bcrypt.GenerateFromPassword([]byte(rawKey), bcrypt.MinCost)
+	keys.Create(context.Background(), key.Key{
+		ID: "key-1", UserID: "user-1", Hash: keyHash, Prefix: rawKey[:12], CreatedAt: baseTime.Add(-time.Hour),
+	})
+	users.Create(context.Background(), ports.User{ID: "user-1", Email: "trace@example.com", PlanID: "free", Status: "active"})
+
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: backend.URL})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	defer client.Close()
+	quotas := memory.NewQuotaStore(memory.QuotaStoreConfig{})
+	defer quotas.Close()
+
+	service := app.NewProxyService(app.ProxyDeps{
+		Keys:      keys,
+		Users:     users,
+		RateLimit: memory.NewRateLimitStore(),
+		Quota:     quotas,
+		Usage:     &testUsageRecorder{},
+		Upstream:  client,
+		Clock:     clock.NewFake(baseTime),
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100,
+		RateWindow: 60,
+		Plans:      []plan.Plan{{ID: "free", Name: "Free", RateLimitPerMinute: 600, RequestsPerMonth: 1000}},
+	})
+	exporter := tracetest.NewInMemoryExporter()
+	service.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	handler := apihttp.NewProxyHandler(service, zerolog.Nop())
+
+	send := func(traceparent string) {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/data", nil)
+		req.Header.Set("X-API-Key", rawKey)
+		if traceparent != "" {
+			req.Header.Set("traceparent", traceparent)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", rec.Code)
+		}
+	}
+	spansByName := func() map[string]tracetest.SpanStub {
+		spans := make(map[string]tracetest.SpanStub)
+		for _, span := range exporter.GetSpans() {
+			spans[span.Name] = span
+		}
+		return spans
+	}
+
+	// An incoming trace is continued
+	const traceID, clientSpanID = "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+	send(fmt.Sprintf("00-%s-%s-01", traceID, clientSpanID))
+
+	spans := spansByName()
+	if len(spans) != 5 {
+		t.Fatalf("recorded %d spans, want 5: %v", len(spans), spans)
+	}
+	root, ok := spans["proxy.request"]
+	if !ok {
+		t.Fatal("missing proxy.request span")
+	}
+	if root.SpanKind != trace.SpanKindServer {
+		t.Errorf("root span kind = %v, want server", root.SpanKind)
+	}
+	if got := root.SpanContext.TraceID().String(); got != traceID {
+		t.Errorf("trace ID = %s, want incoming %s", got, traceID)
+	}
+	if !root.Parent.IsRemote() || root.Parent.SpanID().String() != clientSpanID {
+		t.Errorf("root parent = %v, want remote client span %s", root.Parent.SpanID(), clientSpanID)
+	}
+
+	// Pipeline stages are children of the request span, in order
+	var prevEnd time.Time
+	for _, name := range []string{"proxy.auth", "proxy.quota", "proxy.rate_limit", "proxy.upstream"} {
+		span, ok := spans[name]
+		if !ok {
+			t.Errorf("missing %s span", name)
+			continue
+		}
+		if span.Parent.SpanID() != root.SpanContext.SpanID() {
+			t.Errorf("%s parent = %s, want proxy.request", name, span.Parent.SpanID())
+		}
+		if span.StartTime.Before(prevEnd) {
+			t.Errorf("%s started before the previous stage ended", name)
+		}
+		prevEnd = span.EndTime
+	}
+
+	// The upstream receives the gateway's upstream span as its parent
+	upstreamSpan := spans["proxy.upstream"]
+	want := fmt.Sprintf("00-%s-%s-01", traceID, upstreamSpan.SpanContext.SpanID())
+	mu.Lock()
+	got := upstreamTraceparent
+	mu.Unlock()
+	if got != want {
+		t.Errorf("upstream traceparent = %q, want %q", got, want)
+	}
+	if upstreamSpan.SpanKind != trace.SpanKindClient {
+		t.Errorf("upstream span kind = %v, want client", upstreamSpan.SpanKind)
+	}
+
+	// Without an incoming trace a new one is started and still propagated
+	exporter.Reset()
+	send("")
+	root = spansByName()["proxy.request"]
+	if root.Parent.IsValid() {
+		t.Error("request without traceparent has a parent span")
+	}
+	mu.Lock()
+	got = upstreamTraceparent
+	mu.Unlock()
+	if want := fmt.Sprintf("00-%s-%s-01", root.SpanContext.TraceID(), spansByName()["proxy.upstream"].SpanContext.SpanID()); got != want {
+		t.Errorf("upstream traceparent = %q, want %q", got, want)
+	}
+
+	// A rejected request marks the stage that rejected it as failed
+	exporter.Reset()
+	req := httptest.NewRequest("GET", "/data", nil)
+	req.Header.Set("X-API-Key", "ak_0000000000000000000000000000000000000000000000000000000000000000")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("invalid key: status = %d, want 401", rec.Code)
+	}
+	authSpan, ok := spansByName()["proxy.auth"]
+	if !ok {
+		t.Fatal("invalid key: missing proxy.auth span")
+	}
+	if authSpan.Status.Code != codes.Error {
+		t.Errorf("invalid key: auth span status = %v, want error", authSpan.Status.Code)
+	}
+	if len(authSpan.Events) != 1 || authSpan.Events[0].Name != "exception" {
+		t.Errorf("invalid key: auth span events = %v, want the recorded error", authSpan.Events)
+	}
+}
@@ -3,11 +3,15 @@ package http
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/artpar/apigate/domain/proxy"
@@ -19,7 +23,222 @@ import (
 type UpstreamClient struct {
 	client          *http.Client // For buffered requests
 	streamingClient *http.Client // For streaming requests (no timeout)
+	grpcClient      *http.Client // For gRPC requests (HTTP/2 only, no timeout)
 	baseURL         *url.URL
+
+	// Pool settings for clients built for upstreams with custom TLS
+	maxIdleConns    int
+	idleConnTimeout time.Duration
+	timeout         time.Duration
+
+	// Default stage timeouts for requests whose route sets none
+	dialTimeout           time.Duration
+	responseHeaderTimeout time.Duration
+
+	tlsMu      sync.Mutex
+	tlsClients map[string]*upstreamClients // upstream ID -> clients for its TLS settings
+}
+
+// upstreamClients are the clients used to reach one upstream.
+type upstreamClients struct {
+	client    *http.Client
+	streaming *http.Client
+	grpc      *http.Client
+	tlsKey    string // route.Upstream.TLSKey the clients were built for
+}
+
+func (c *upstreamClients) closeIdleConnections() {
+	c.client.CloseIdleConnections()
+	c.streaming.CloseIdleConnections()
+	c.grpc.CloseIdleConnections()
+}
+
+// defaultMaxResponseBody is used when the request does not carry a response limit.
+const defaultMaxResponseBody = 50 << 20 // 50MB
+
+// readResponseBody reads at most limit bytes from body.
+// Returns proxy.ErrResponseBodyTooLarge if the body is larger than limit.
+func readResponseBody(body io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		limit = defaultMaxResponseBody
+	}
+	data, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	if int64(len(data)) > limit {
+		return nil, proxy.ErrResponseBodyTooLarge
+	}
+	return data, nil
+}
+
+// defaultDialTimeout bounds connecting to an upstream when no timeout is configured.
+const defaultDialTimeout = 10 * time.Second
+
+// dialTimeoutKey carries a request's dial timeout to dialUpstream.
+type dialTimeoutKey struct{}
+
+// dialUpstream connects to an upstream within the dial timeout set by send,
+// or defaultDialTimeout for requests that don't carry one.
+func dialUpstream(ctx context.Context, network, addr string) (net.Conn, error) {
+	d := net.Dialer{Timeout: defaultDialTimeout, KeepAlive: 30 * time.Second}
+	if t, ok := ctx.Value(dialTimeoutKey{}).(time.Duration); ok && t > 0 {
+		d.Timeout = t
+	}
+	conn, err := d.DialContext(ctx, network, addr)
+	if err != nil {
+		if ne, ok := err.(net.Error); ok && ne.Timeout() && ctx.Err() == nil {
+			return nil, &proxy.UpstreamTimeoutError{Stage: proxy.StageDial, Timeout: d.Timeout}
+		}
+		return nil, err
+	}
+	return conn, nil
+}
+
+// timeoutsFor resolves the timeouts for req: the route's when set, then the
+// upstream's request timeout, then the client's defaults. Streams have no
+// overall request timeout.
+func (u *UpstreamClient) timeoutsFor(req proxy.Request, upstream *route.Upstream, streaming bool) proxy.Timeouts {
+	t := req.Timeouts
+	if t.Dial <= 0 {
+		t.Dial = u.dialTimeout
+	}
+	if t.ResponseHeader <= 0 {
+		t.ResponseHeader = u.responseHeaderTimeout
+	}
+	if streaming {
+		t.Request = 0
+		return t
+	}
+	if t.Request <= 0 && upstream != nil {
+		t.Request = upstream.Timeout
+	}
+	if t.Request <= 0 {
+		t.Request = u.timeout
+	}
+	return t
+}
+
+// send executes httpReq through client's transport within t. The request
+// timeout bounds the whole exchange, except that once an event stream's
+// headers arrive the stream may stay open for as long as the upstream keeps
+// sending. Timeouts are reported as *proxy.UpstreamTimeoutError.
+func send(client *http.Client, httpReq *http.Request, t proxy.Timeouts) (*http.Response, error) {
+	ctx, cancel := context.WithCancelCause(context.WithValue(httpReq.Context(), dialTimeoutKey{}, t.Dial))
+	expire := func(stage string, timeout time.Duration) *time.Timer {
+		if timeout <= 0 {
+			return nil
+		}
+		return time.AfterFunc(timeout, func() {
+			cancel(&proxy.UpstreamTimeoutError{Stage: stage, Timeout: timeout})
+		})
+	}
+	requestTimer := expire(proxy.StageRequest, t.Request)
+	headerTimer := expire(proxy.StageResponseHeader, t.ResponseHeader)
+	stop := func() {
+		stopTimer(requestTimer)
+		stopTimer(headerTimer)
+		cancel(nil)
+	}
+
+	untimed := &http.Client{Transport: client.Transport}
+	resp, err := untimed.Do(httpReq.WithContext(ctx))
+	if err != nil {
+		stop()
+		if timeout := timeoutCause(ctx); timeout != nil {
+			return nil, timeout
+		}
+		return nil, err
+	}
+
+	// Headers arrived; only the request timeout still applies, and not to event streams
+	stopTimer(headerTimer)
+	if proxy.IsEventStream(resp.Header.Get("Content-Type")) {
+		stopTimer(requestTimer)
+	}
+	resp.Body = &timedBody{ReadCloser: resp.Body, ctx: ctx, stop: stop}
+	return resp, nil
+}
+
+func stopTimer(t *time.Timer) {
+	if t != nil {
+		t.Stop()
+	}
+}
+
+// timeoutCause returns the timeout that cancelled ctx, if any.
+func timeoutCause(ctx context.Context) *proxy.UpstreamTimeoutError {
+	var timeout *proxy.UpstreamTimeoutError
+	if errors.As(context.Cause(ctx), &timeout) {
+		return timeout
+	}
+	return nil
+}
+
+// timedBody reports reads cut short by send's request timeout as that
+// timeout and releases the timers when closed.
+type timedBody struct {
+	io.ReadCloser
+	ctx  context.Context
+	stop func()
+}
+
+func (b *timedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		if timeout := timeoutCause(b.ctx); timeout != nil {
+			err = timeout
+		}
+	}
+	return n, err
+}
+
+func (b *timedBody) Close() error {
+	b.stop()
+	return b.ReadCloser.Close()
+}
+
+// readUpstreamResponse converts resp to a proxy response. Event streams are
+// returned unread in Stream so events reach the client as they arrive; any
+// other body is read in full and resp is closed.
+func readUpstreamResponse(resp *http.Response, maxBody int64, start time.Time, upstreamAddr string) (proxy.Response, error) {
+	result := proxy.Response{
+		Status:       resp.StatusCode,
+		Headers:      upstreamHeaders(resp.Header),
+		UpstreamAddr: upstreamAddr,
+	}
+
+	if proxy.IsEventStream(resp.Header.Get("Content-Type")) {
+		result.Stream = resp.Body
+	} else {
+		defer resp.Body.Close()
+		body, err := readResponseBody(resp.Body, maxBody)
+		if err != nil {
+			return proxy.Response{}, err
+		}
+		result.Body = body
+	}
+
+	result.LatencyMs = time.Since(start).Milliseconds()
+	return result, nil
+}
+
+// upstreamHeaders copies response headers, skipping hop-by-hop headers.
+func upstreamHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string)
+	for k, v := range header {
+		lower := strings.ToLower(k)
+		if lower == "connection" || lower == "keep-alive" ||
+			lower == "proxy-authenticate" || lower == "proxy-authorization" ||
+			lower == "te" || lower == "trailers" || lower == "transfer-encoding" ||
+			lower == "upgrade" {
+			continue
+		}
+		if len(v) > 0 {
+			headers[k] = v[0]
+		}
+	}
+	return headers
 }
 
 // UpstreamConfig contains configuration for the upstream client.
@@ -28,6 +247,10 @@ type UpstreamConfig struct {
 	Timeout        time.Duration
 	MaxIdleConns   int
 	IdleConnTimeout time.Duration
+
+	// Default stage timeouts; routes may override
+	DialTimeout           time.Duration // 0 = 10s
+	ResponseHeaderTimeout time.Duration // 0 = bounded only by Timeout
 }
 
 // NewUpstreamClient creates a new upstream HTTP client.
@@ -52,11 +275,37 @@ func NewUpstreamClient(cfg UpstreamConfig) (*UpstreamClient, error) {
 		idleConnTimeout = 90 * time.Second
 	}
 
+	dialTimeout := cfg.DialTimeout
+	if dialTimeout == 0 {
+		dialTimeout = defaultDialTimeout
+	}
+
+	clients := newUpstreamClients(maxIdleConns, idleConnTimeout, timeout, nil)
+
+	return &UpstreamClient{
+		client:                clients.client,
+		streamingClient:       clients.streaming,
+		grpcClient:            clients.grpc,
+		baseURL:               baseURL,
+		maxIdleConns:          maxIdleConns,
+		idleConnTimeout:       idleConnTimeout,
+		timeout:               timeout,
+		dialTimeout:           dialTimeout,
+		responseHeaderTimeout: cfg.ResponseHeaderTimeout,
+		tlsClients:            make(map[string]*upstreamClients),
+	}, nil
+}
+
+// newUpstreamClients builds the buffered, streaming and gRPC clients sharing
+// the given pool settings and TLS configuration (nil = defaults).
+func newUpstreamClients(maxIdleConns int, idleConnTimeout, timeout time.Duration, tlsConfig *tls.Config) *upstreamClients {
 	transport := &http.Transport{
 		MaxIdleConns:        maxIdleConns,
 		MaxIdleConnsPerHost: maxIdleConns,
 		IdleConnTimeout:     idleConnTimeout,
+		DialContext:         dialUpstream,
 		DisableCompression:  false,
+		TLSClientConfig:     tlsConfig,
 	}
 
 	// Streaming transport with same settings but no compression
@@ -65,25 +314,68 @@ func NewUpstreamClient(cfg UpstreamConfig) (*UpstreamClient, error) {
 		MaxIdleConns:        maxIdleConns,
 		MaxIdleConnsPerHost: maxIdleConns,
 		IdleConnTimeout:     idleConnTimeout,
+		DialContext:         dialUpstream,
 		DisableCompression:  true,
+		TLSClientConfig:     tlsConfig,
 	}
 
-	client := &http.Client{
-		Transport: transport,
-		Timeout:   timeout,
+	// gRPC transport speaks HTTP/2 only: h2c for http:// upstreams, TLS for https://
+	grpcProtocols := new(http.Protocols)
+	grpcProtocols.SetHTTP2(true)
+	grpcProtocols.SetUnencryptedHTTP2(true)
+	grpcTransport := &http.Transport{
+		MaxIdleConns:        maxIdleConns,
+		MaxIdleConnsPerHost: maxIdleConns,
+		IdleConnTimeout:     idleConnTimeout,
+		DialContext:         dialUpstream,
+		DisableCompression:  true,
+		Protocols:           grpcProtocols,
+		TLSClientConfig:     tlsConfig,
+	}
+
+	return &upstreamClients{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+		},
+		// Streaming client has no timeout - streams can run indefinitely
+		streaming: &http.Client{
+			Transport: streamingTransport,
+			Timeout:   0,
+		},
+		// gRPC client has no timeout - deadlines are carried by grpc-timeout
+		grpc: &http.Client{
+			Transport: grpcTransport,
+			Timeout:   0,
+		},
 	}
+}
 
-	// Streaming client has no timeout - streams can run indefinitely
-	streamingClient := &http.Client{
-		Transport: streamingTransport,
-		Timeout:   0, // No timeout for streams
+// clientsFor returns the clients for reaching upstream. Upstreams with custom
+// TLS settings get their own clients, rebuilt when the settings change.
+func (u *UpstreamClient) clientsFor(upstream *route.Upstream) (*upstreamClients, error) {
+	if upstream == nil || !upstream.HasCustomTLS() {
+		return &upstreamClients{client: u.client, streaming: u.streamingClient, grpc: u.grpcClient}, nil
 	}
 
-	return &UpstreamClient{
-		client:          client,
-		streamingClient: streamingClient,
-		baseURL:         baseURL,
-	}, nil
+	tlsKey := upstream.TLSKey()
+	u.tlsMu.Lock()
+	defer u.tlsMu.Unlock()
+	if c, ok := u.tlsClients[upstream.ID]; ok && c.tlsKey == tlsKey {
+		return c, nil
+	}
+
+	tlsConfig, err := upstream.TLSConfig()
+	if err != nil {
+		return nil, fmt.Errorf("upstream %s tls: %w", upstream.ID, err)
+	}
+	c := newUpstreamClients(u.maxIdleConns, u.idleConnTimeout, u.timeout, tlsConfig)
+	c.tlsKey = tlsKey
+	if old, ok := u.tlsClients[upstream.ID]; ok {
+		old.closeIdleConnections()
+	}
+	u.tlsClients[upstream.ID] = c
+	return c, nil
 }
 
 // Forward sends a request to the upstream and returns the response.
@@ -127,41 +419,12 @@ func (u *UpstreamClient) Forward(ctx context.Context, req proxy.Request) (proxy.
 	}
 
 	// Execute request
-	resp, err := u.client.Do(httpReq)
+	resp, err := send(u.client, httpReq, u.timeoutsFor(req, nil, false))
 	if err != nil {
 		return proxy.Response{}, fmt.Errorf("execute request: %w", err)
 	}
-	defer resp.Body.Close()
-
-	// Read response body
-	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 50<<20)) // 50MB limit
-	if err != nil {
-		return proxy.Response{}, fmt.Errorf("read response: %w", err)
-	}
-
-	// Extract response headers
-	headers := make(map[string]string)
-	for k, v := range resp.Header {
-		// Skip hop-by-hop headers
-		lower := strings.ToLower(k)
-		if lower == "connection" || lower == "keep-alive" ||
-			lower == "proxy-authenticate" || lower == "proxy-authorization" ||
-			lower == "te" || lower == "trailers" || lower == "transfer-encoding" ||
-			lower == "upgrade" {
-			continue
-		}
-		if len(v) > 0 {
-			headers[k] = v[0]
-		}
-	}
 
-	return proxy.Response{
-		Status:       resp.StatusCode,
-		Headers:      headers,
-		Body:         respBody,
-		LatencyMs:    time.Since(start).Milliseconds(),
-		UpstreamAddr: u.baseURL.Host,
-	}, nil
+	return readUpstreamResponse(resp, req.MaxResponseBody, start, u.baseURL.Host)
 }
 
 // ForwardTo sends a request to a specific upstream URL (not the default).
@@ -209,51 +472,18 @@ func (u *UpstreamClient) ForwardTo(ctx context.Context, req proxy.Request, upstr
 		httpReq.Header.Set("X-Request-ID", req.TraceID)
 	}
 
-	// Use appropriate client based on upstream timeout
-	client := u.client
-	if upstream.Timeout > 0 {
-		// Create a client with the upstream's timeout
-		client = &http.Client{
-			Transport: u.client.Transport,
-			Timeout:   upstream.Timeout,
-		}
+	clients, err := u.clientsFor(upstream)
+	if err != nil {
+		return proxy.Response{}, err
 	}
 
 	// Execute request
-	resp, err := client.Do(httpReq)
+	resp, err := send(clients.client, httpReq, u.timeoutsFor(req, upstream, false))
 	if err != nil {
 		return proxy.Response{}, fmt.Errorf("execute request: %w", err)
 	}
-	defer resp.Body.Close()
-
-	// Read response body
-	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 50<<20)) // 50MB limit
-	if err != nil {
-		return proxy.Response{}, fmt.Errorf("read response: %w", err)
-	}
 
-	// Extract response headers
-	headers := make(map[string]string)
-	for k, v := range resp.Header {
-		lower := strings.ToLower(k)
-		if lower == "connection" || lower == "keep-alive" ||
-			lower == "proxy-authenticate" || lower == "proxy-authorization" ||
-			lower == "te" || lower == "trailers" || lower == "transfer-encoding" ||
-			lower == "upgrade" {
-			continue
-		}
-		if len(v) > 0 {
-			headers[k] = v[0]
-		}
-	}
-
-	return proxy.Response{
-		Status:       resp.StatusCode,
-		Headers:      headers,
-		Body:         respBody,
-		LatencyMs:    time.Since(start).Milliseconds(),
-		UpstreamAddr: baseURL.Host,
-	}, nil
+	return readUpstreamResponse(resp, req.MaxResponseBody, start, baseURL.Host)
 }
 
 // HealthCheck verifies the upstream is reachable.
@@ -274,10 +504,45 @@ func (u *UpstreamClient) HealthCheck(ctx context.Context) error {
 	return nil
 }
 
+// Probe performs an active health check against upstream.HealthCheckPath.
+// The caller controls the probe timeout through ctx.
+func (u *UpstreamClient) Probe(ctx context.Context, upstream route.Upstream) (int, error) {
+	baseURL, err := url.Parse(upstream.BaseURL)
+	if err != nil {
+		return 0, fmt.Errorf("parse upstream URL: %w", err)
+	}
+	probeURL := baseURL.ResolveReference(&url.URL{Path: upstream.HealthCheckPath})
+
+	req, err := http.NewRequestWithContext(ctx, "GET", probeURL.String(), nil)
+	if err != nil {
+		return 0, fmt.Errorf("create request: %w", err)
+	}
+
+	clients, err := u.clientsFor(&upstream)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := clients.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	return resp.StatusCode, nil
+}
+
 // Close closes the upstream client.
 func (u *UpstreamClient) Close() error {
 	u.client.CloseIdleConnections()
 	u.streamingClient.CloseIdleConnections()
+	u.grpcClient.CloseIdleConnections()
+
+	u.tlsMu.Lock()
+	defer u.tlsMu.Unlock()
+	for _, c := range u.tlsClients {
+		c.closeIdleConnections()
+	}
 	return nil
 }
 
@@ -320,8 +585,8 @@ func (u *UpstreamClient) ForwardStreaming(ctx context.Context, req proxy.Request
 		httpReq.Header.Set("X-Request-ID", req.TraceID)
 	}
 
-	// Execute request with streaming client (no timeout)
-	resp, err := u.streamingClient.Do(httpReq)
+	// Execute request with streaming client (no overall timeout)
+	resp, err := send(u.streamingClient, httpReq, u.timeoutsFor(req, nil, true))
 	if err != nil {
 		return ports.StreamingResponse{}, fmt.Errorf("execute streaming request: %w", err)
 	}
@@ -399,8 +664,13 @@ func (u *UpstreamClient) ForwardStreamingTo(ctx context.Context, req proxy.Reque
 		httpReq.Header.Set("X-Request-ID", req.TraceID)
 	}
 
-	// Execute request with streaming client (no timeout)
-	resp, err := u.streamingClient.Do(httpReq)
+	clients, err := u.clientsFor(upstream)
+	if err != nil {
+		return ports.StreamingResponse{}, err
+	}
+
+	// Execute request with streaming client (no overall timeout)
+	resp, err := send(clients.streaming, httpReq, u.timeoutsFor(req, upstream, true))
 	if err != nil {
 		return ports.StreamingResponse{}, fmt.Errorf("execute streaming request: %w", err)
 	}
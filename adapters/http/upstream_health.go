@@ -0,0 +1,54 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/artpar/apigate/app"
+)
+
+// UpstreamHealthPath is where aggregate upstream health is served.
+const UpstreamHealthPath = "/api/v1/upstream-health"
+
+// UpstreamHealthReporter reports per-upstream health.
+type UpstreamHealthReporter interface {
+	Status() []app.UpstreamStatus
+}
+
+// UpstreamHealthResponse is the body returned by the upstream health endpoint.
+type UpstreamHealthResponse struct {
+	Status    string                  `json:"status"` // "ok" if all upstreams are healthy, otherwise "degraded"
+	Upstreams []UpstreamHealthSummary `json:"upstreams"`
+}
+
+// UpstreamHealthSummary is the public health of one upstream. The endpoint
+// is unauthenticated, so names, probe results and errors, which reveal
+// internal addresses, are only served by GET /admin/upstream-health.
+type UpstreamHealthSummary struct {
+	UpstreamID string `json:"upstream_id"`
+	Healthy    bool   `json:"healthy"`
+	Circuit    string `json:"circuit"` // Circuit breaker state: closed, open, half_open
+}
+
+// NewUpstreamHealthHandler returns a handler that reports the health of every upstream.
+//
+//	@Summary		Upstream health
+//	@Description	Returns whether each upstream is healthy and its circuit breaker state
+//	@Tags			Health
+//	@Produce		json
+//	@Success		200	{object}	UpstreamHealthResponse	"Per-upstream health"
+//	@Router			/api/v1/upstream-health [get]
+func NewUpstreamHealthHandler(reporter UpstreamHealthReporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := UpstreamHealthResponse{Status: "ok", Upstreams: []UpstreamHealthSummary{}}
+		for _, u := range reporter.Status() {
+			if !u.Healthy {
+				resp.Status = "degraded"
+			}
+			resp.Upstreams = append(resp.Upstreams, UpstreamHealthSummary{UpstreamID: u.UpstreamID, Healthy: u.Healthy, Circuit: u.Circuit})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
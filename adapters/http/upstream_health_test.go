@@ -0,0 +1,147 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/route"
+	"github.com/rs/zerolog"
+)
+
+func TestUpstreamClient_Probe(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" || r.Method != http.MethodGet {
+			t.Errorf("unexpected probe %s %s", r.Method, r.URL.Path)
+		}
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: server.URL, Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("new upstream client: %v", err)
+	}
+	u := route.Upstream{ID: "up", BaseURL: server.URL, HealthCheckPath: "/healthz"}
+
+	got, err := client.Probe(context.Background(), u)
+	if err != nil || got != http.StatusOK {
+		t.Fatalf("Probe() = %d, %v; want 200", got, err)
+	}
+
+	status.Store(http.StatusServiceUnavailable)
+	got, err = client.Probe(context.Background(), u)
+	if err != nil || got != http.StatusServiceUnavailable {
+		t.Fatalf("Probe() = %d, %v; want 503", got, err)
+	}
+	if u.ProbeHealthy(got, err) {
+		t.Error("503 should not be a healthy probe")
+	}
+
+	server.Close()
+	if _, err := client.Probe(context.Background(), u); err == nil {
+		t.Error("expected an error probing a closed server")
+	}
+}
+
+// TestUpstreamHealthEndpoint drives a probe from passing to failing and checks
+// the endpoint is served by the built-in handler even when a high-priority
+// catch-all route would otherwise proxy /api/*.
+func TestUpstreamHealthEndpoint(t *testing.T) {
+	var failing atomic.Bool
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" && failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("PROXIED"))
+	}))
+	defer backend.Close()
+
+	upstreams := []route.Upstream{{
+		ID: "backend", Name: "backend", BaseURL: backend.URL, Enabled: true,
+		HealthCheckPath: "/healthz", HealthCheckInterval: 10 * time.Second,
+		HealthCheckTimeout: time.Second, HealthCheckExpectedStatus: http.StatusOK,
+	}}
+	routes := []route.Route{{
+		ID: "catch-all", Name: "catch-all", PathPattern: "/api/*", MatchType: route.MatchPrefix,
+		UpstreamID: "backend", Priority: 100, Enabled: true,
+	}}
+
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	routeService := app.NewRouteService(&staticRouteStore{routes: routes}, &staticUpstreamStore{upstreams: upstreams},
+		clk, zerolog.Nop(), app.RouteServiceConfig{})
+	if err := routeService.Reload(context.Background()); err != nil {
+		t.Fatalf("reload routes: %v", err)
+	}
+
+	prober, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: backend.URL, Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("new upstream client: %v", err)
+	}
+	checker := app.NewHealthChecker(routeService, prober, clk, zerolog.Nop(), app.HealthCheckerConfig{})
+	routeService.SetUpstreamHealth(checker)
+
+	proxyHandler, _ := setupTestHandler()
+	router := apihttp.NewRouterWithConfig(proxyHandler, apihttp.NewHealthHandler(nil), zerolog.Nop(), apihttp.RouterConfig{
+		RouteService:          routeService,
+		UpstreamHealthHandler: apihttp.NewUpstreamHealthHandler(checker),
+	})
+
+	get := func() apihttp.UpstreamHealthResponse {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, apihttp.UpstreamHealthPath, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Fatalf("Content-Type = %q (body %q), want application/json", ct, rec.Body.String())
+		}
+		// The endpoint is public, so it must not reveal upstream details
+		for _, field := range []string{`"name"`, `"last_error"`, `"last_status"`} {
+			if strings.Contains(rec.Body.String(), field) {
+				t.Errorf("public body exposes %s: %s", field, rec.Body.String())
+			}
+		}
+		var resp apihttp.UpstreamHealthResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode %q: %v", rec.Body.String(), err)
+		}
+		return resp
+	}
+
+	checker.CheckDue(context.Background())
+	resp := get()
+	if resp.Status != "ok" || len(resp.Upstreams) != 1 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if u := resp.Upstreams[0]; u.UpstreamID != "backend" || !u.Healthy || u.Circuit != string(route.CircuitClosed) {
+		t.Errorf("unexpected upstream status: %+v", u)
+	}
+
+	failing.Store(true)
+	clk.Advance(10 * time.Second)
+	checker.CheckDue(context.Background())
+
+	resp = get()
+	if resp.Status != "degraded" {
+		t.Errorf("status = %q, want degraded", resp.Status)
+	}
+	if u := resp.Upstreams[0]; u.Healthy {
+		t.Errorf("unexpected upstream status after failing probe: %+v", u)
+	}
+	if got := checker.Status(); len(got) != 1 || got[0].LastStatus != http.StatusInternalServerError {
+		t.Errorf("checker status after failing probe = %+v, want last status 500", got)
+	}
+}
@@ -2,6 +2,7 @@ package http_test
 
 import (
 	"context"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -260,6 +261,38 @@ func TestUpstreamClient_Forward_ContextCancel(t *testing.T) {
 	}
 }
 
+func TestUpstreamClient_Forward_ResponseBodyLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 16)))
+	}))
+	defer server.Close()
+
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	req := proxy.Request{Method: "GET", Path: "/", MaxResponseBody: 16}
+	resp, err := client.Forward(context.Background(), req)
+	if err != nil {
+		t.Fatalf("limit equal to body size: unexpected error: %v", err)
+	}
+	if len(resp.Body) != 16 {
+		t.Errorf("body length = %d, want 16", len(resp.Body))
+	}
+
+	req.MaxResponseBody = 15
+	if _, err := client.Forward(context.Background(), req); !errors.Is(err, proxy.ErrResponseBodyTooLarge) {
+		t.Errorf("err = %v, want ErrResponseBodyTooLarge", err)
+	}
+
+	upstream := &route.Upstream{ID: "u1", BaseURL: server.URL}
+	if _, err := client.ForwardTo(context.Background(), req, upstream); !errors.Is(err, proxy.ErrResponseBodyTooLarge) {
+		t.Errorf("ForwardTo err = %v, want ErrResponseBodyTooLarge", err)
+	}
+}
+
 func TestUpstreamClient_ForwardTo(t *testing.T) {
 	// Create mock upstream server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,152 @@
+package http_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/domain/proxy"
+	"github.com/artpar/apigate/domain/route"
+)
+
+// certPEM encodes a certificate for upstream TLS settings.
+func certPEM(cert *x509.Certificate) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+}
+
+// keyPairPEM encodes a client certificate and its key for upstream TLS settings.
+func keyPairPEM(t *testing.T, cert tls.Certificate) (string, string) {
+	t.Helper()
+	keyDER, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+}
+
+func TestUpstreamClient_ForwardTo_TLS(t *testing.T) {
+	// httptest's certificate is valid for 127.0.0.1 and example.com
+	backend := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+	backendCA := certPEM(backend.Certificate())
+
+	// A second backend that requires a client certificate from clientCA
+	clientCA := newTestCA(t, "Gateway Client CA")
+	clientPool := x509.NewCertPool()
+	clientPool.AddCert(clientCA.cert)
+	mtlsBackend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello " + r.TLS.PeerCertificates[0].EmailAddresses[0]))
+	}))
+	mtlsBackend.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientPool}
+	mtlsBackend.StartTLS()
+	defer mtlsBackend.Close()
+	clientCert, clientKey := keyPairPEM(t, clientCA.issue(t, "gateway@example.com"))
+
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: "http://unused"})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	defer client.Close()
+
+	tests := []struct {
+		name     string
+		upstream route.Upstream
+		wantBody string
+		wantErr  string
+	}{
+		{
+			name:     "custom CA",
+			upstream: route.Upstream{ID: "ca", BaseURL: backend.URL, TLSCACert: backendCA},
+			wantBody: "ok",
+		},
+		{
+			name:     "CA omitted",
+			upstream: route.Upstream{ID: "no-ca", BaseURL: backend.URL},
+			wantErr:  "certificate",
+		},
+		{
+			name:     "insecure skip verify",
+			upstream: route.Upstream{ID: "insecure", BaseURL: backend.URL, TLSInsecureSkipVerify: true},
+			wantBody: "ok",
+		},
+		{
+			name:     "server name override",
+			upstream: route.Upstream{ID: "sni", BaseURL: backend.URL, TLSCACert: backendCA, TLSServerName: "example.com"},
+			wantBody: "ok",
+		},
+		{
+			name:     "server name not in certificate",
+			upstream: route.Upstream{ID: "bad-sni", BaseURL: backend.URL, TLSCACert: backendCA, TLSServerName: "internal.test"},
+			wantErr:  "internal.test",
+		},
+		{
+			name: "client certificate",
+			upstream: route.Upstream{ID: "mtls", BaseURL: mtlsBackend.URL, TLSCACert: certPEM(mtlsBackend.Certificate()),
+				TLSClientCert: clientCert, TLSClientKey: clientKey},
+			wantBody: "hello gateway@example.com",
+		},
+		{
+			name:     "client certificate omitted",
+			upstream: route.Upstream{ID: "mtls-missing", BaseURL: mtlsBackend.URL, TLSCACert: certPEM(mtlsBackend.Certificate())},
+			wantErr:  "certificate",
+		},
+		{
+			name:     "malformed CA bundle",
+			upstream: route.Upstream{ID: "bad-ca", BaseURL: backend.URL, TLSCACert: "not a certificate"},
+			wantErr:  "tls_ca_cert",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.ForwardTo(context.Background(), proxy.Request{Method: "GET", Path: "/"}, &tt.upstream)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ForwardTo() error = %v, want error mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ForwardTo() error = %v", err)
+			}
+			if string(resp.Body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", resp.Body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestUpstreamClient_ForwardTo_TLSSettingsChange(t *testing.T) {
+	backend := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: backend.URL})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	defer client.Close()
+
+	upstream := route.Upstream{ID: "backend", BaseURL: backend.URL, TLSCACert: certPEM(backend.Certificate())}
+	if _, err := client.ForwardTo(context.Background(), proxy.Request{Method: "GET", Path: "/"}, &upstream); err != nil {
+		t.Fatalf("ForwardTo() with CA error = %v", err)
+	}
+
+	// Removing the CA must take effect without restarting
+	upstream.TLSCACert = ""
+	upstream.TLSServerName = "example.com"
+	if _, err := client.ForwardTo(context.Background(), proxy.Request{Method: "GET", Path: "/"}, &upstream); err == nil {
+		t.Error("ForwardTo() succeeded after the CA was removed")
+	}
+}
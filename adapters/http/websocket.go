@@ -0,0 +1,264 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/proxy"
+	"github.com/artpar/apigate/domain/route"
+)
+
+// WebSocketUpstream proxies WebSocket connections to an upstream.
+type WebSocketUpstream interface {
+	// ProxyWebSocket forwards the Upgrade handshake in r to the upstream and,
+	// once switched, copies frames in both directions until either side closes
+	// or r's context is done. req carries the path, query and headers to send upstream. A nil upstream
+	// uses the default upstream. handshake, if not nil, is called with the
+	// upstream's handshake status, or the error sending it the handshake,
+	// before any frames are copied; it is not called if the upstream is
+	// misconfigured. Returns the upstream handshake status.
+	ProxyWebSocket(w http.ResponseWriter, r *http.Request, req proxy.Request, upstream *route.Upstream, handshake func(status int, err error)) (int, error)
+}
+
+// ProxyWebSocket proxies a WebSocket connection to the upstream. It blocks
+// until the connection is closed or r's context is done.
+func (u *UpstreamClient) ProxyWebSocket(w http.ResponseWriter, r *http.Request, req proxy.Request, upstream *route.Upstream, handshake func(status int, err error)) (int, error) {
+	if handshake == nil {
+		handshake = func(int, error) {}
+	}
+
+	clients, err := u.clientsFor(upstream)
+	if err != nil {
+		return 0, err
+	}
+
+	target := u.baseURL
+	if upstream != nil {
+		parsed, err := url.Parse(upstream.BaseURL)
+		if err != nil {
+			return 0, fmt.Errorf("parse upstream URL: %w", err)
+		}
+		target = parsed
+	}
+
+	upstreamURL := target.ResolveReference(&url.URL{
+		Path:     req.Path,
+		RawQuery: req.Query,
+	})
+	// Upstreams may be configured with ws:// or wss:// URLs
+	switch upstreamURL.Scheme {
+	case "ws":
+		upstreamURL.Scheme = "http"
+	case "wss":
+		upstreamURL.Scheme = "https"
+	}
+
+	var status int
+	var proxyErr error
+	answered := false
+	rp := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.Out.URL = upstreamURL
+			pr.Out.Host = upstreamURL.Host
+
+			// Credentials for the gateway are not forwarded
+			pr.Out.Header.Del("Authorization")
+			pr.Out.Header.Del("X-API-Key")
+
+			// Headers after transforms and upstream auth
+			for k, v := range req.Headers {
+				if strings.EqualFold(k, "Host") {
+					continue
+				}
+				pr.Out.Header.Set(k, v)
+			}
+			pr.SetXForwarded()
+			if req.TraceID != "" {
+				pr.Out.Header.Set("X-Request-ID", req.TraceID)
+			}
+		},
+		Transport: clients.streaming.Transport,
+		ModifyResponse: func(resp *http.Response) error {
+			status = resp.StatusCode
+			answered = true
+			handshake(status, nil)
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			proxyErr = err
+			if !answered {
+				answered = true
+				handshake(0, err)
+			}
+			writeError(w, &proxy.ErrUpstreamError)
+		},
+	}
+
+	// The connection outlives the server's read/write timeouts
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	rp.ServeHTTP(w, r)
+
+	if proxyErr != nil {
+		return http.StatusBadGateway, fmt.Errorf("proxy websocket: %w", proxyErr)
+	}
+	return status, nil
+}
+
+// isWebSocketUpgrade returns true if the request asks to switch to the WebSocket protocol.
+func isWebSocketUpgrade(r *http.Request) bool {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return false
+	}
+	for _, v := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// handleWebSocketRequest authenticates the Upgrade handshake, proxies the
+// connection and meters it once it closes, with its duration as latency.
+func (h *ProxyHandler) handleWebSocketRequest(w http.ResponseWriter, r *http.Request, ctx context.Context, req proxy.Request) {
+	start := time.Now()
+
+	if !h.trackWebSocket() {
+		writeError(w, &proxy.ErrShuttingDown)
+		return
+	}
+	defer h.wsConns.Done()
+
+	// Auth and rate limiting apply to the handshake
+	result := h.service.HandleStreaming(ctx, req, nil)
+	if result.Release != nil {
+		defer result.Release()
+	}
+	if result.Error != nil {
+		for k, v := range result.Headers {
+			w.Header().Set(k, v)
+		}
+		writeError(w, result.Error)
+		h.logAccess(req, result.Auth, result.Error.Status, 0, 0, time.Since(start))
+		return
+	}
+
+	wsReq := req
+	if result.ModifiedRequest != nil {
+		wsReq = *result.ModifiedRequest
+	}
+
+	// The connection outlives the request's deadlines; it ends when either
+	// side closes or the gateway shuts down
+	connCtx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+	defer cancel()
+	go func() {
+		select {
+		case <-h.wsClosing:
+			cancel()
+		case <-connCtx.Done():
+		}
+	}()
+
+	// The circuit breaker hears about the handshake, not the whole connection
+	recorded := false
+	status, err := h.wsUpstream.ProxyWebSocket(w, r.WithContext(connCtx), wsReq, result.RouteUpstream, func(status int, err error) {
+		recorded = true
+		h.service.RecordUpstreamResult(result.RouteUpstream, app.UpstreamOutcome(status, err))
+	})
+	if !recorded {
+		h.service.RecordUpstreamResult(result.RouteUpstream, app.UpstreamOutcome(status, err))
+	}
+	if err != nil {
+		h.logger.Error().
+			Err(err).
+			Str("path", wsReq.Path).
+			Msg("websocket upstream error")
+	}
+
+	duration := time.Since(start)
+	h.logAccess(req, result.Auth, status, 0, 0, duration)
+	if status != http.StatusSwitchingProtocols || result.StreamingResponse == nil {
+		return
+	}
+
+	meteringValue := 1.0
+	if matched := result.StreamingResponse.MatchedRoute; matched != nil && matched.MeteringExpr != "" {
+		// The request context may have expired while the connection was open
+		meteringValue = h.service.EvalConnectionMetering(context.WithoutCancel(ctx), matched.MeteringExpr, status, duration, result.Auth)
+	}
+
+	h.service.RecordStreamingUsage(
+		result.StreamingResponse,
+		status,
+		0,
+		0,
+		duration.Milliseconds(),
+		meteringValue,
+		req.RemoteIP,
+		req.UserAgent,
+	)
+
+	h.logger.Info().
+		Str("path", req.Path).
+		Str("type", "websocket").
+		Int64("duration_ms", duration.Milliseconds()).
+		Float64("metering_value", meteringValue).
+		Msg("websocket connection closed")
+}
+
+// trackWebSocket counts a new connection towards WaitWebSockets. It returns
+// false once CloseWebSockets has been called; callers that get true must call
+// h.wsConns.Done when the connection ends.
+func (h *ProxyHandler) trackWebSocket() bool {
+	h.wsMu.Lock()
+	defer h.wsMu.Unlock()
+	if h.wsClosed {
+		return false
+	}
+	h.wsConns.Add(1)
+	return true
+}
+
+// CloseWebSockets closes open WebSocket connections and refuses new ones.
+// http.Server.Shutdown doesn't wait for hijacked connections, so register it
+// with RegisterOnShutdown and wait for the connections with WaitWebSockets.
+func (h *ProxyHandler) CloseWebSockets() {
+	h.wsMu.Lock()
+	defer h.wsMu.Unlock()
+	if !h.wsClosed {
+		h.wsClosed = true
+		close(h.wsClosing)
+	}
+}
+
+// WaitWebSockets closes open WebSocket connections and waits until they have
+// ended and been metered, or ctx is done.
+func (h *ProxyHandler) WaitWebSockets(ctx context.Context) error {
+	h.CloseWebSockets()
+
+	done := make(chan struct{})
+	go func() {
+		h.wsConns.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Ensure interface compliance.
+var _ WebSocketUpstream = (*UpstreamClient)(nil)
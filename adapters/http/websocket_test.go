@@ -0,0 +1,231 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	apihttp "github.com/artpar/apigate/adapters/http"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/domain/route"
+	"github.com/artpar/apigate/ports"
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// wsEchoServer echoes every message and records the handshake headers it sees.
+type wsEchoServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	headers []http.Header
+}
+
+func newWSEchoServer(t *testing.T) *wsEchoServer {
+	s := &wsEchoServer{}
+	upgrader := websocket.Upgrader{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.headers = append(s.headers, r.Header.Clone())
+		s.mu.Unlock()
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upstream upgrade: %v", err)
+			return
+		}
+		defer conn.Close()
+		for {
+			mt, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(mt, msg); err != nil {
+				return
+			}
+		}
+	}))
+	return s
+}
+
+func TestProxy_WebSocket(t *testing.T) {
+	echo := newWSEchoServer(t)
+	defer echo.Close()
+
+	upstreamURL := "ws://" + strings.TrimPrefix(echo.URL, "http://")
+	upstreams := []route.Upstream{{
+		ID: "ws", Name: "ws", BaseURL: upstreamURL, Enabled: true,
+		AuthType: route.AuthHeader, AuthHeader: "X-Upstream-Token", AuthValue: "secret",
+	}}
+	routes := []route.Route{{
+		ID: "ws", Name: "ws", PathPattern: "/ws/*", MatchType: route.MatchPrefix,
+		UpstreamID: "ws", Protocol: route.ProtocolWebSocket, AuthRequired: true, Enabled: true,
+	}}
+
+	keys := memory.NewKeyStore()
+	users := memory.NewUserStore()
+	rawKey := "ak_0a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f9"
+	keyHash, _ := bcrypt.GenerateFromPassword([]byte(rawKey), bcrypt.MinCost)
+	keys.Create(context.Background(), key.Key{
+		ID: "key-1", UserID: "user-1", Hash: keyHash, Prefix: rawKey[:12], CreatedAt: baseTime.Add(-time.Hour),
+	})
+	users.Create(context.Background(), ports.User{ID: "user-1", Email: "ws@example.com", PlanID: "free", Status: "active"})
+
+	clk := clock.NewFake(baseTime)
+	client, err := apihttp.NewUpstreamClient(apihttp.UpstreamConfig{BaseURL: echo.URL})
+	if err != nil {
+		t.Fatalf("create upstream client: %v", err)
+	}
+	defer client.Close()
+
+	recorder := &syncUsageRecorder{}
+	service := app.NewProxyService(app.ProxyDeps{
+		Keys:      keys,
+		Users:     users,
+		RateLimit: memory.NewRateLimitStore(),
+		Usage:     recorder,
+		Upstream:  client,
+		Clock:     clk,
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100,
+		RateWindow: 60,
+		Plans:      []plan.Plan{{ID: "free", Name: "Free", RateLimitPerMinute: 600, RequestsPerMonth: 1000}},
+	})
+
+	routeService := app.NewRouteService(&staticRouteStore{routes: routes}, &staticUpstreamStore{upstreams: upstreams},
+		clk, zerolog.Nop(), app.RouteServiceConfig{})
+	if err := routeService.Reload(context.Background()); err != nil {
+		t.Fatalf("reload routes: %v", err)
+	}
+	service.SetRouteService(routeService)
+
+	handler := apihttp.NewProxyHandler(service, zerolog.Nop())
+	handler.SetWebSocketUpstream(client)
+	router := apihttp.NewRouterWithConfig(handler, apihttp.NewHealthHandler(nil), zerolog.Nop(), apihttp.RouterConfig{})
+
+	gateway := httptest.NewUnstartedServer(router)
+	gateway.Config.RegisterOnShutdown(handler.CloseWebSockets)
+	gateway.Start()
+	defer gateway.Close()
+	wsURL := "ws://" + strings.TrimPrefix(gateway.URL, "http://") + "/ws/echo"
+
+	t.Run("handshake without api key is rejected", func(t *testing.T) {
+		_, resp, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		if err == nil {
+			t.Fatal("expected handshake to fail")
+		}
+		if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("handshake response = %v, want 401", resp)
+		}
+	})
+
+	t.Run("frames are proxied in both directions", func(t *testing.T) {
+		conn, resp, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"X-API-Key": {rawKey}})
+		if err != nil {
+			t.Fatalf("dial: %v (response %v)", err, resp)
+		}
+
+		for _, msg := range []string{"ping", "hello", "bye"} {
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+				t.Fatalf("write %s: %v", msg, err)
+			}
+			_, got, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatalf("read %s: %v", msg, err)
+			}
+			if string(got) != msg {
+				t.Errorf("echo = %q, want %q", got, msg)
+			}
+		}
+
+		// Hold the connection open so its duration is measurable
+		time.Sleep(50 * time.Millisecond)
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		conn.Close()
+
+		// The connection is metered once it closes
+		deadline := time.Now().Add(2 * time.Second)
+		for recorder.count() == 0 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		recorder.mu.Lock()
+		defer recorder.mu.Unlock()
+		if len(recorder.events) != 1 {
+			t.Fatalf("usage events = %d, want 1", len(recorder.events))
+		}
+		ev := recorder.events[0]
+		if ev.StatusCode != http.StatusSwitchingProtocols || ev.KeyID != "key-1" || ev.Path != "/ws/echo" {
+			t.Errorf("usage event = %+v", ev)
+		}
+		if ev.LatencyMs < 50 {
+			t.Errorf("metered duration = %dms, want >= 50ms", ev.LatencyMs)
+		}
+	})
+
+	t.Run("shutdown closes and meters open connections", func(t *testing.T) {
+		conn, resp, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"X-API-Key": {rawKey}})
+		if err != nil {
+			t.Fatalf("dial: %v (response %v)", err, resp)
+		}
+		defer conn.Close()
+		if err := conn.WriteMessage(websocket.TextMessage, []byte("ping")); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		if _, _, err := conn.ReadMessage(); err != nil {
+			t.Fatalf("read: %v", err)
+		}
+
+		// Shutdown returns without waiting for the hijacked connection
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := gateway.Config.Shutdown(ctx); err != nil {
+			t.Fatalf("shutdown: %v", err)
+		}
+		if err := handler.WaitWebSockets(ctx); err != nil {
+			t.Fatalf("WaitWebSockets: %v", err)
+		}
+
+		// Once the wait returns the connection is closed and metered
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		if _, _, err := conn.ReadMessage(); err == nil {
+			t.Error("expected the connection to be closed")
+		}
+		if got := recorder.count(); got != 2 {
+			t.Errorf("usage events = %d, want 2", got)
+		}
+
+		// New handshakes are refused while shutting down
+		req := httptest.NewRequest(http.MethodGet, "/ws/echo", nil)
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("X-API-Key", rawKey)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("handshake during shutdown = %d, want 503", w.Code)
+		}
+	})
+
+	echo.mu.Lock()
+	defer echo.mu.Unlock()
+	if len(echo.headers) != 2 {
+		t.Fatalf("upstream handshakes = %d, want 2", len(echo.headers))
+	}
+	if got := echo.headers[0].Get("X-API-Key"); got != "" {
+		t.Errorf("API key forwarded to upstream: %q", got)
+	}
+	if got := echo.headers[0].Get("X-Upstream-Token"); got != "secret" {
+		t.Errorf("upstream auth header = %q, want secret", got)
+	}
+}
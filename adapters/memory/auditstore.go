@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/artpar/apigate/domain/audit"
+	"github.com/artpar/apigate/ports"
+)
+
+// AuditStore is an in-memory implementation of ports.AuditStore.
+type AuditStore struct {
+	mu      sync.RWMutex
+	entries []audit.Entry // in append order
+}
+
+// NewAuditStore creates a new in-memory audit store.
+func NewAuditStore() *AuditStore {
+	return &AuditStore{}
+}
+
+// Append stores entries.
+func (s *AuditStore) Append(ctx context.Context, entries []audit.Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = append(s.entries, entries...)
+	return nil
+}
+
+// List returns entries matching the filter, newest first.
+func (s *AuditStore) List(ctx context.Context, filter audit.Filter) ([]audit.Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []audit.Entry
+	skipped := 0
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		e := s.entries[i]
+		if filter.ActorID != "" && e.ActorID != filter.ActorID {
+			continue
+		}
+		if filter.ResourceType != "" && e.ResourceType != filter.ResourceType {
+			continue
+		}
+		if filter.ResourceID != "" && e.ResourceID != filter.ResourceID {
+			continue
+		}
+		if skipped < filter.Offset {
+			skipped++
+			continue
+		}
+		result = append(result, e)
+		if filter.Limit > 0 && len(result) >= filter.Limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+// Ensure interface compliance.
+var _ ports.AuditStore = (*AuditStore)(nil)
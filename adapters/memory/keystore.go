@@ -3,6 +3,7 @@ package memory
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
 
@@ -12,14 +13,16 @@ import (
 
 // KeyStore is an in-memory implementation of ports.KeyStore.
 type KeyStore struct {
-	mu   sync.RWMutex
-	keys map[string]key.Key // by ID
+	mu     sync.RWMutex
+	keys   map[string]key.Key   // by ID
+	warned map[string]time.Time // expiry warnings sent, by key ID
 }
 
 // NewKeyStore creates a new in-memory key store.
 func NewKeyStore() *KeyStore {
 	return &KeyStore{
-		keys: make(map[string]key.Key),
+		keys:   make(map[string]key.Key),
+		warned: make(map[string]time.Time),
 	}
 }
 
@@ -46,6 +49,28 @@ func (s *KeyStore) Create(ctx context.Context, k key.Key) error {
 	return nil
 }
 
+// CreateBatch stores all keys, or none if any prefix is already in use.
+func (s *KeyStore) CreateBatch(ctx context.Context, keys []key.Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prefixes := make(map[string]bool, len(s.keys)+len(keys))
+	for _, k := range s.keys {
+		prefixes[k.Prefix] = true
+	}
+	for _, k := range keys {
+		if prefixes[k.Prefix] {
+			return fmt.Errorf("%w: %s", ports.ErrDuplicateKeyPrefix, k.Prefix)
+		}
+		prefixes[k.Prefix] = true
+	}
+
+	for _, k := range keys {
+		s.keys[k.ID] = k
+	}
+	return nil
+}
+
 // Revoke marks a key as revoked.
 func (s *KeyStore) Revoke(ctx context.Context, id string, at time.Time) error {
 	s.mu.Lock()
@@ -72,6 +97,53 @@ func (s *KeyStore) ListByUser(ctx context.Context, userID string) ([]key.Key, er
 	return result, nil
 }
 
+// ListByGroup returns all keys owned by a group.
+func (s *KeyStore) ListByGroup(ctx context.Context, groupID string) ([]key.Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []key.Key
+	for _, k := range s.keys {
+		if k.GroupID == groupID {
+			result = append(result, k)
+		}
+	}
+	return result, nil
+}
+
+// SetMonthlyQuota sets a key's own quota; 0 removes it.
+func (s *KeyStore) SetMonthlyQuota(ctx context.Context, id string, quota int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if k, ok := s.keys[id]; ok {
+		k.MonthlyQuota = quota
+		s.keys[id] = k
+	}
+	return nil
+}
+
+// SetLabels replaces a key's labels; nil removes them all.
+func (s *KeyStore) SetLabels(ctx context.Context, id string, labels map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if k, ok := s.keys[id]; ok {
+		k.Labels = labels
+		s.keys[id] = k
+	}
+	return nil
+}
+
+// ListByUserLabels returns the user's keys matching the label selector.
+func (s *KeyStore) ListByUserLabels(ctx context.Context, userID string, sel key.LabelSelector) ([]key.Key, error) {
+	keys, err := s.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return key.FilterByLabels(keys, sel), nil
+}
+
 // UpdateLastUsed updates the last used timestamp.
 func (s *KeyStore) UpdateLastUsed(ctx context.Context, id string, at time.Time) error {
 	s.mu.Lock()
@@ -84,6 +156,54 @@ func (s *KeyStore) UpdateLastUsed(ctx context.Context, id string, at time.Time)
 	return nil
 }
 
+// RecordUses sets the last-used time and IP of many keys.
+func (s *KeyStore) RecordUses(ctx context.Context, uses []key.Use) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range uses {
+		k, ok := s.keys[u.KeyID]
+		if !ok || (k.LastUsed != nil && k.LastUsed.After(u.At)) {
+			continue
+		}
+		at := u.At
+		k.LastUsed = &at
+		k.LastUsedIP = u.IP
+		s.keys[u.KeyID] = k
+	}
+	return nil
+}
+
+// ListExpiringUnwarned returns unrevoked keys expiring in (now, before] that
+// have not been sent an expiry warning.
+func (s *KeyStore) ListExpiringUnwarned(ctx context.Context, now, before time.Time) ([]key.Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []key.Key
+	for _, k := range s.keys {
+		if k.RevokedAt != nil || k.ExpiresAt == nil {
+			continue
+		}
+		if _, ok := s.warned[k.ID]; ok {
+			continue
+		}
+		if k.ExpiresAt.After(now) && !k.ExpiresAt.After(before) {
+			result = append(result, k)
+		}
+	}
+	return result, nil
+}
+
+// MarkExpiryWarned records that the expiry warning for a key was sent.
+func (s *KeyStore) MarkExpiryWarned(ctx context.Context, id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.warned[id] = at
+	return nil
+}
+
 // GetAll returns all keys (for testing).
 func (s *KeyStore) GetAll() []key.Key {
 	s.mu.RLock()
@@ -101,7 +221,16 @@ func (s *KeyStore) Clear() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.keys = make(map[string]key.Key)
+	s.warned = make(map[string]time.Time)
 }
 
 // Ensure interface compliance.
-var _ ports.KeyStore = (*KeyStore)(nil)
+var (
+	_ ports.KeyStore       = (*KeyStore)(nil)
+	_ ports.KeyExpiryStore = (*KeyStore)(nil)
+	_ ports.KeyBatchStore  = (*KeyStore)(nil)
+	_ ports.GroupKeyStore  = (*KeyStore)(nil)
+	_ ports.KeyQuotaStore  = (*KeyStore)(nil)
+	_ ports.KeyLabelStore  = (*KeyStore)(nil)
+	_ ports.KeyUseStore    = (*KeyStore)(nil)
+)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"hash/fnv"
+	"sort"
 	"sync"
 	"time"
 
@@ -25,6 +26,24 @@ type QuotaStore struct {
 	usageStore ports.UsageStore // For syncing with persistent storage
 	cleanup    *time.Ticker
 	done       chan struct{}
+
+	warningsMu sync.Mutex
+	warnings   map[string]sentWarnings // Keyed like state
+
+	overageMu sync.Mutex
+	overage   map[string]reportedOverage // Keyed like state
+}
+
+// reportedOverage is the overage reported for one user and period.
+type reportedOverage struct {
+	periodStart time.Time
+	units       int64
+}
+
+// sentWarnings are the quota warning thresholds sent for one user and period.
+type sentWarnings struct {
+	periodStart time.Time
+	thresholds  []int
 }
 
 // QuotaStoreConfig configures the quota store.
@@ -48,6 +67,8 @@ func NewQuotaStore(cfg QuotaStoreConfig) *QuotaStore {
 		numShards:  cfg.NumShards,
 		usageStore: cfg.UsageStore,
 		done:       make(chan struct{}),
+		warnings:   make(map[string]sentWarnings),
+		overage:    make(map[string]reportedOverage),
 	}
 
 	for i := range s.shards {
@@ -64,8 +85,10 @@ func NewQuotaStore(cfg QuotaStoreConfig) *QuotaStore {
 }
 
 // key generates the map key for a user and period.
+// Periods are identified by their exact start so weekly and rolling windows
+// within the same month are kept apart.
 func (s *QuotaStore) key(userID string, periodStart time.Time) string {
-	return fmt.Sprintf("%s:%s", userID, periodStart.Format("2006-01"))
+	return fmt.Sprintf("%s:%s", userID, periodStart.UTC().Format(time.RFC3339Nano))
 }
 
 // getShard returns the shard for a given key using consistent hashing.
@@ -172,6 +195,52 @@ func (s *QuotaStore) Sync(ctx context.Context, userID string, periodStart time.T
 	return nil
 }
 
+// ListQuotaWarnings returns the thresholds already sent to the user for the period.
+func (s *QuotaStore) ListQuotaWarnings(ctx context.Context, userID string, periodStart time.Time) ([]int, error) {
+	s.warningsMu.Lock()
+	defer s.warningsMu.Unlock()
+	return append([]int(nil), s.warnings[s.key(userID, periodStart)].thresholds...), nil
+}
+
+// MarkQuotaWarned records that the warning for a threshold was sent.
+func (s *QuotaStore) MarkQuotaWarned(ctx context.Context, userID string, periodStart time.Time, threshold int, at time.Time) error {
+	s.warningsMu.Lock()
+	defer s.warningsMu.Unlock()
+
+	k := s.key(userID, periodStart)
+	w := s.warnings[k]
+	for _, t := range w.thresholds {
+		if t == threshold {
+			return nil
+		}
+	}
+	w.periodStart = periodStart
+	w.thresholds = append(w.thresholds, threshold)
+	sort.Ints(w.thresholds)
+	s.warnings[k] = w
+	return nil
+}
+
+// GetReportedOverage returns the overage units already reported for the period.
+func (s *QuotaStore) GetReportedOverage(ctx context.Context, userID string, periodStart time.Time) (int64, error) {
+	s.overageMu.Lock()
+	defer s.overageMu.Unlock()
+	return s.overage[s.key(userID, periodStart)].units, nil
+}
+
+// AddReportedOverage adds units to the reported overage for the period.
+func (s *QuotaStore) AddReportedOverage(ctx context.Context, userID string, periodStart time.Time, units int64, at time.Time) error {
+	s.overageMu.Lock()
+	defer s.overageMu.Unlock()
+
+	k := s.key(userID, periodStart)
+	r := s.overage[k]
+	r.periodStart = periodStart
+	r.units += units
+	s.overage[k] = r
+	return nil
+}
+
 // cleanupLoop periodically removes old period entries.
 func (s *QuotaStore) cleanupLoop() {
 	for {
@@ -198,6 +267,22 @@ func (s *QuotaStore) doCleanup() {
 		}
 		shard.mu.Unlock()
 	}
+
+	s.warningsMu.Lock()
+	for k, w := range s.warnings {
+		if w.periodStart.Before(cutoff) {
+			delete(s.warnings, k)
+		}
+	}
+	s.warningsMu.Unlock()
+
+	s.overageMu.Lock()
+	for k, r := range s.overage {
+		if r.periodStart.Before(cutoff) {
+			delete(s.overage, k)
+		}
+	}
+	s.overageMu.Unlock()
 }
 
 // Close stops the cleanup goroutine.
@@ -214,6 +299,14 @@ func (s *QuotaStore) Clear() {
 		shard.state = make(map[string]ports.QuotaState)
 		shard.mu.Unlock()
 	}
+
+	s.warningsMu.Lock()
+	s.warnings = make(map[string]sentWarnings)
+	s.warningsMu.Unlock()
+
+	s.overageMu.Lock()
+	s.overage = make(map[string]reportedOverage)
+	s.overageMu.Unlock()
 }
 
 // Len returns the total number of entries across all shards (for testing).
@@ -228,4 +321,8 @@ func (s *QuotaStore) Len() int {
 }
 
 // Ensure interface compliance.
-var _ ports.QuotaStore = (*QuotaStore)(nil)
+var (
+	_ ports.QuotaStore         = (*QuotaStore)(nil)
+	_ ports.QuotaWarningStore  = (*QuotaStore)(nil)
+	_ ports.OverageReportStore = (*QuotaStore)(nil)
+)
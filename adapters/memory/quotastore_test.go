@@ -508,19 +508,24 @@ func TestQuotaStore_PeriodStartFormat(t *testing.T) {
 	defer store.Close()
 	ctx := context.Background()
 
-	// Different times in the same month should hash to same key
-	time1 := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
-	time2 := time.Date(2024, 3, 15, 12, 30, 0, 0, time.UTC)
-	time3 := time.Date(2024, 3, 31, 23, 59, 59, 0, time.UTC)
-
-	store.Increment(ctx, "user1", time1, 10, 0.0, 0)
-	store.Increment(ctx, "user1", time2, 5, 0.0, 0)
-	store.Increment(ctx, "user1", time3, 3, 0.0, 0)
-
-	// Key format is "userID:2006-01", so all three should be same key
-	state, _ := store.Get(ctx, "user1", time1)
-	if state.RequestCount != 18 {
-		t.Errorf("RequestCount = %d, want 18 (all increments combined)", state.RequestCount)
+	// The same period start in different time zones maps to the same key
+	start := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	store.Increment(ctx, "user1", start, 10, 0.0, 0)
+	store.Increment(ctx, "user1", start.In(time.FixedZone("UTC+5", 5*3600)), 5, 0.0, 0)
+
+	state, _ := store.Get(ctx, "user1", start)
+	if state.RequestCount != 15 {
+		t.Errorf("RequestCount = %d, want 15 (all increments combined)", state.RequestCount)
+	}
+
+	// Weekly windows within the same month are kept separate
+	nextWeek := start.AddDate(0, 0, 7)
+	store.Increment(ctx, "user1", nextWeek, 3, 0.0, 0)
+	if state, _ := store.Get(ctx, "user1", nextWeek); state.RequestCount != 3 {
+		t.Errorf("next week RequestCount = %d, want 3", state.RequestCount)
+	}
+	if state, _ := store.Get(ctx, "user1", start); state.RequestCount != 15 {
+		t.Errorf("first week RequestCount = %d, want 15", state.RequestCount)
 	}
 }
 
@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/artpar/apigate/domain/proxy"
+	"github.com/artpar/apigate/ports"
+)
+
+// DefaultResponseCacheEntries is the entry limit used when none is configured.
+const DefaultResponseCacheEntries = 10000
+
+// ResponseCache is an in-memory implementation of ports.ResponseCache.
+// When full, expired entries are evicted first, then the entry closest to expiry.
+type ResponseCache struct {
+	mu         sync.Mutex
+	clock      ports.Clock
+	maxEntries int
+	entries    map[string]cachedResponse
+}
+
+type cachedResponse struct {
+	resp      proxy.Response
+	expiresAt time.Time
+}
+
+// NewResponseCache creates a new in-memory response cache holding at most
+// maxEntries responses (<= 0 uses DefaultResponseCacheEntries).
+func NewResponseCache(clock ports.Clock, maxEntries int) *ResponseCache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultResponseCacheEntries
+	}
+	return &ResponseCache{
+		clock:      clock,
+		maxEntries: maxEntries,
+		entries:    make(map[string]cachedResponse),
+	}
+}
+
+// Get returns the cached response for a key.
+func (c *ResponseCache) Get(ctx context.Context, key string) (proxy.Response, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return proxy.Response{}, false, nil
+	}
+	if !c.clock.Now().Before(entry.expiresAt) {
+		delete(c.entries, key)
+		return proxy.Response{}, false, nil
+	}
+	return copyResponse(entry.resp), true, nil
+}
+
+// Set stores a response for the given TTL.
+func (c *ResponseCache) Set(ctx context.Context, key string, resp proxy.Response, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock.Now()
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= c.maxEntries {
+		c.evict(now)
+	}
+	c.entries[key] = cachedResponse{
+		resp:      copyResponse(resp),
+		expiresAt: now.Add(ttl),
+	}
+	return nil
+}
+
+// Len returns the number of stored entries, including expired ones not yet evicted.
+func (c *ResponseCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// evict frees space for one entry. Must be called with mu held.
+func (c *ResponseCache) evict(now time.Time) {
+	var oldestKey string
+	var oldest time.Time
+	for k, e := range c.entries {
+		if !now.Before(e.expiresAt) {
+			delete(c.entries, k)
+			continue
+		}
+		if oldestKey == "" || e.expiresAt.Before(oldest) {
+			oldestKey, oldest = k, e.expiresAt
+		}
+	}
+	if len(c.entries) >= c.maxEntries && oldestKey != "" {
+		delete(c.entries, oldestKey)
+	}
+}
+
+// copyResponse copies the mutable parts of a response so callers cannot
+// modify cached entries.
+func copyResponse(resp proxy.Response) proxy.Response {
+	resp.Headers = maps.Clone(resp.Headers)
+	if resp.Body != nil {
+		resp.Body = append([]byte(nil), resp.Body...)
+	}
+	return resp
+}
+
+// Ensure interface compliance.
+var _ ports.ResponseCache = (*ResponseCache)(nil)
@@ -0,0 +1,79 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/domain/proxy"
+)
+
+func TestResponseCache_TTL(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	cache := memory.NewResponseCache(clk, 0)
+	ctx := context.Background()
+
+	resp := proxy.Response{Status: 200, Headers: map[string]string{"Content-Type": "application/json"}, Body: []byte(`{"ok":true}`)}
+	if err := cache.Set(ctx, "k", resp, time.Minute); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	clk.Advance(59 * time.Second)
+	got, ok, err := cache.Get(ctx, "k")
+	if err != nil || !ok {
+		t.Fatalf("Get within TTL = %v, %v, want hit", ok, err)
+	}
+	if got.Status != 200 || string(got.Body) != `{"ok":true}` || got.Headers["Content-Type"] != "application/json" {
+		t.Errorf("cached response = %+v", got)
+	}
+
+	clk.Advance(time.Second)
+	if _, ok, _ := cache.Get(ctx, "k"); ok {
+		t.Error("Get after TTL hit, want miss")
+	}
+	if cache.Len() != 0 {
+		t.Errorf("Len after expiry = %d, want 0", cache.Len())
+	}
+}
+
+func TestResponseCache_EntriesAreCopied(t *testing.T) {
+	cache := memory.NewResponseCache(clock.NewFake(time.Now()), 0)
+	ctx := context.Background()
+
+	resp := proxy.Response{Status: 200, Headers: map[string]string{"X-A": "1"}, Body: []byte("body")}
+	cache.Set(ctx, "k", resp, time.Minute)
+	resp.Headers["X-A"] = "changed"
+	resp.Body[0] = 'B'
+
+	got, _, _ := cache.Get(ctx, "k")
+	got.Headers["X-B"] = "added"
+
+	again, _, _ := cache.Get(ctx, "k")
+	if again.Headers["X-A"] != "1" || again.Headers["X-B"] != "" || string(again.Body) != "body" {
+		t.Errorf("cached entry was modified: %+v", again)
+	}
+}
+
+func TestResponseCache_Eviction(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	cache := memory.NewResponseCache(clk, 2)
+	ctx := context.Background()
+
+	cache.Set(ctx, "short", proxy.Response{Status: 200}, time.Minute)
+	cache.Set(ctx, "long", proxy.Response{Status: 200}, time.Hour)
+	cache.Set(ctx, "new", proxy.Response{Status: 200}, time.Hour)
+
+	if cache.Len() != 2 {
+		t.Fatalf("Len = %d, want 2", cache.Len())
+	}
+	if _, ok, _ := cache.Get(ctx, "short"); ok {
+		t.Error("entry closest to expiry was not evicted")
+	}
+	for _, k := range []string{"long", "new"} {
+		if _, ok, _ := cache.Get(ctx, k); !ok {
+			t.Errorf("entry %q was evicted", k)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package memory
 
 import (
 	"context"
+	"sort"
 	"sync"
 	"time"
 
@@ -91,6 +92,43 @@ func (s *UsageStore) GetRecentRequests(ctx context.Context, userID string, limit
 	return matching, nil
 }
 
+// GetTopPaths returns a user's most requested paths in [start, end).
+func (s *UsageStore) GetTopPaths(ctx context.Context, userID string, start, end time.Time, limit int) ([]usage.PathUsage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matching []usage.Event
+	for _, e := range s.events {
+		if e.UserID == userID && !e.Timestamp.Before(start) && e.Timestamp.Before(end) {
+			matching = append(matching, e)
+		}
+	}
+
+	return usage.AggregateByPath(matching, limit), nil
+}
+
+// EachEvent calls fn for each of a user's events in [start, end), oldest first.
+func (s *UsageStore) EachEvent(ctx context.Context, userID string, start, end time.Time, fn func(usage.Event) error) error {
+	s.mu.RLock()
+	var matching []usage.Event
+	for _, e := range s.events {
+		if e.UserID == userID && !e.Timestamp.Before(start) && e.Timestamp.Before(end) {
+			matching = append(matching, e)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.SliceStable(matching, func(i, j int) bool {
+		return matching[i].Timestamp.Before(matching[j].Timestamp)
+	})
+	for _, e := range matching {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // GetAll returns all events (for testing).
 func (s *UsageStore) GetAll() []usage.Event {
 	s.mu.RLock()
@@ -115,4 +153,7 @@ func (s *UsageStore) Clear() {
 }
 
 // Ensure interface compliance.
-var _ ports.UsageStore = (*UsageStore)(nil)
+var (
+	_ ports.UsageStore         = (*UsageStore)(nil)
+	_ ports.UsageEventExporter = (*UsageStore)(nil)
+)
@@ -3,7 +3,10 @@ package memory
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/artpar/apigate/ports"
 )
@@ -33,7 +36,7 @@ func (s *UserStore) Get(ctx context.Context, id string) (ports.User, error) {
 	defer s.mu.RUnlock()
 
 	u, ok := s.users[id]
-	if !ok {
+	if !ok || !userVisible(ctx, u) {
 		return ports.User{}, ErrNotFound
 	}
 	return u, nil
@@ -45,7 +48,7 @@ func (s *UserStore) GetByEmail(ctx context.Context, email string) (ports.User, e
 	defer s.mu.RUnlock()
 
 	id, ok := s.byEmail[email]
-	if !ok {
+	if !ok || !userVisible(ctx, s.users[id]) {
 		return ports.User{}, ErrNotFound
 	}
 	return s.users[id], nil
@@ -57,7 +60,7 @@ func (s *UserStore) GetByStripeID(ctx context.Context, stripeID string) (ports.U
 	defer s.mu.RUnlock()
 
 	for _, u := range s.users {
-		if u.StripeID == stripeID {
+		if u.StripeID == stripeID && userVisible(ctx, u) {
 			return u, nil
 		}
 	}
@@ -69,8 +72,8 @@ func (s *UserStore) Create(ctx context.Context, u ports.User) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Check for duplicate email
-	if _, exists := s.byEmail[u.Email]; exists {
+	// Check for duplicate email; deleted users give theirs up
+	if id, exists := s.byEmail[u.Email]; exists && s.users[id].DeletedAt == nil {
 		return errors.New("email already exists")
 	}
 
@@ -83,7 +86,22 @@ func (s *UserStore) Create(ctx context.Context, u ports.User) error {
 func (s *UserStore) Update(ctx context.Context, u ports.User) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	return s.updateLocked(u)
+}
+
+// UpdateIfUnchanged modifies an existing user only if its UpdatedAt still
+// equals since.
+func (s *UserStore) UpdateIfUnchanged(ctx context.Context, u ports.User, since time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.users[u.ID]; ok && !old.UpdatedAt.Equal(since) {
+		return fmt.Errorf("%w: user %s was modified", ports.ErrConflict, u.ID)
+	}
+	return s.updateLocked(u)
+}
 
+// updateLocked replaces a user. Callers must hold s.mu.
+func (s *UserStore) updateLocked(u ports.User) error {
 	old, ok := s.users[u.ID]
 	if !ok {
 		return ErrNotFound
@@ -104,11 +122,19 @@ func (s *UserStore) List(ctx context.Context, limit, offset int) ([]ports.User,
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	// Collect all users
+	// Collect all users, newest first like the sqlite store, so pages are stable
 	all := make([]ports.User, 0, len(s.users))
 	for _, u := range s.users {
-		all = append(all, u)
+		if userVisible(ctx, u) {
+			all = append(all, u)
+		}
 	}
+	sort.Slice(all, func(i, j int) bool {
+		if !all[i].CreatedAt.Equal(all[j].CreatedAt) {
+			return all[i].CreatedAt.After(all[j].CreatedAt)
+		}
+		return all[i].ID < all[j].ID
+	})
 
 	// Apply offset
 	if offset >= len(all) {
@@ -128,24 +154,57 @@ func (s *UserStore) List(ctx context.Context, limit, offset int) ([]ports.User,
 func (s *UserStore) Count(ctx context.Context) (int, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return len(s.users), nil
+
+	count := 0
+	for _, u := range s.users {
+		if userVisible(ctx, u) {
+			count++
+		}
+	}
+	return count, nil
 }
 
-// Delete removes a user.
+// Delete soft-deletes a user.
 func (s *UserStore) Delete(ctx context.Context, id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	u, ok := s.users[id]
-	if !ok {
+	if !ok || u.DeletedAt != nil {
+		return ErrNotFound
+	}
+
+	now := time.Now().UTC()
+	u.DeletedAt = &now
+	s.users[id] = u
+	return nil
+}
+
+// Restore clears the deletion of a soft-deleted user. It fails with
+// ErrConflict if the email has since been taken by another user.
+func (s *UserStore) Restore(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok || u.DeletedAt == nil {
 		return ErrNotFound
 	}
+	if other, exists := s.byEmail[u.Email]; exists && other != id && s.users[other].DeletedAt == nil {
+		return fmt.Errorf("%w: email of user %s belongs to another user", ports.ErrConflict, id)
+	}
 
-	delete(s.byEmail, u.Email)
-	delete(s.users, id)
+	u.DeletedAt = nil
+	s.users[id] = u
+	s.byEmail[u.Email] = id
 	return nil
 }
 
+// userVisible reports whether u should be returned for reads in ctx.
+func userVisible(ctx context.Context, u ports.User) bool {
+	return u.DeletedAt == nil || ports.IncludeDeletedUsers(ctx)
+}
+
 // GetAll returns all users (for testing).
 func (s *UserStore) GetAll() []ports.User {
 	s.mu.RLock()
@@ -2,6 +2,7 @@ package memory_test
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"testing"
 	"time"
@@ -211,6 +212,27 @@ func TestUserStore_Update_NotFound(t *testing.T) {
 	}
 }
 
+func TestUserStore_UpdateIfUnchanged(t *testing.T) {
+	store := memory.NewUserStore()
+	ctx := context.Background()
+
+	loaded := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.Create(ctx, ports.User{ID: "u1", Email: "u1@example.com", Name: "Old Name", UpdatedAt: loaded})
+
+	if err := store.UpdateIfUnchanged(ctx, ports.User{ID: "u1", Email: "u1@example.com", Name: "First", UpdatedAt: loaded.Add(time.Second)}, loaded); err != nil {
+		t.Fatalf("first update: %v", err)
+	}
+	err := store.UpdateIfUnchanged(ctx, ports.User{ID: "u1", Email: "u1@example.com", Name: "Second"}, loaded)
+	if !errors.Is(err, ports.ErrConflict) {
+		t.Errorf("stale update error = %v, want ErrConflict", err)
+	}
+
+	user, _ := store.Get(ctx, "u1")
+	if user.Name != "First" {
+		t.Errorf("Name = %s, want 'First'", user.Name)
+	}
+}
+
 func TestUserStore_Delete(t *testing.T) {
 	store := memory.NewUserStore()
 	ctx := context.Background()
@@ -248,6 +270,84 @@ func TestUserStore_Delete_NotFound(t *testing.T) {
 	}
 }
 
+func TestUserStore_DeleteAndRestore(t *testing.T) {
+	store := memory.NewUserStore()
+	ctx := context.Background()
+
+	store.Create(ctx, ports.User{ID: "u1", Email: "a@example.com"})
+	store.Create(ctx, ports.User{ID: "u2", Email: "b@example.com"})
+
+	if err := store.Delete(ctx, "u1"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	// Listing hides soft-deleted users unless asked for them
+	users, _ := store.List(ctx, 10, 0)
+	if len(users) != 1 || users[0].ID != "u2" {
+		t.Errorf("List = %v, want only u2", users)
+	}
+	withDeleted := ports.WithDeletedUsers(ctx)
+	users, _ = store.List(withDeleted, 10, 0)
+	if len(users) != 2 {
+		t.Errorf("List with deleted = %d users, want 2", len(users))
+	}
+	deleted, err := store.Get(withDeleted, "u1")
+	if err != nil {
+		t.Fatalf("Get with deleted failed: %v", err)
+	}
+	if deleted.DeletedAt == nil {
+		t.Error("expected DeletedAt to be set")
+	}
+
+	// Deleting twice is not found
+	if err := store.Delete(ctx, "u1"); err != memory.ErrNotFound {
+		t.Errorf("second Delete: expected ErrNotFound, got %v", err)
+	}
+
+	if err := store.Restore(ctx, "u1"); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	restored, err := store.GetByEmail(ctx, "a@example.com")
+	if err != nil {
+		t.Fatalf("GetByEmail after restore failed: %v", err)
+	}
+	if restored.DeletedAt != nil {
+		t.Error("expected DeletedAt to be cleared")
+	}
+	if count, _ := store.Count(ctx); count != 2 {
+		t.Errorf("Count after restore = %d, want 2", count)
+	}
+
+	// Restoring a user that is not deleted is not found
+	if err := store.Restore(ctx, "u2"); err != memory.ErrNotFound {
+		t.Errorf("Restore of active user: expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestUserStore_EmailReusedAfterDelete(t *testing.T) {
+	store := memory.NewUserStore()
+	ctx := context.Background()
+
+	store.Create(ctx, ports.User{ID: "u1", Email: "a@example.com"})
+	if err := store.Create(ctx, ports.User{ID: "u2", Email: "a@example.com"}); err == nil {
+		t.Error("expected an error creating a user with a live user's email")
+	}
+	store.Delete(ctx, "u1")
+
+	// The deleted user's email is free for a new account
+	if err := store.Create(ctx, ports.User{ID: "u2", Email: "a@example.com"}); err != nil {
+		t.Fatalf("Create with deleted user's email failed: %v", err)
+	}
+	if u, err := store.GetByEmail(ctx, "a@example.com"); err != nil || u.ID != "u2" {
+		t.Errorf("GetByEmail = %s, %v; want u2", u.ID, err)
+	}
+
+	// Restoring the old account would duplicate a live email
+	if err := store.Restore(ctx, "u1"); !errors.Is(err, ports.ErrConflict) {
+		t.Errorf("Restore: expected ErrConflict, got %v", err)
+	}
+}
+
 func TestUserStore_List(t *testing.T) {
 	store := memory.NewUserStore()
 	ctx := context.Background()
@@ -13,6 +13,10 @@ type Collector struct {
 	RequestDuration  *prometheus.HistogramVec
 	RequestsInFlight prometheus.Gauge
 
+	// Proxy metrics, labelled by the matched route
+	ProxyRequests *prometheus.CounterVec
+	ProxyDuration *prometheus.HistogramVec
+
 	// Auth metrics
 	AuthFailures *prometheus.CounterVec
 
@@ -20,6 +24,9 @@ type Collector struct {
 	RateLimitHits   *prometheus.CounterVec
 	RateLimitTokens *prometheus.GaugeVec
 
+	// Quota metrics
+	QuotaRejections *prometheus.CounterVec
+
 	// Usage metrics
 	UsageRequests *prometheus.CounterVec
 	UsageBytes    *prometheus.CounterVec
@@ -35,130 +42,9 @@ type Collector struct {
 	ConfigLastReload   prometheus.Gauge
 }
 
-// New creates a new metrics collector with all metrics registered.
+// New creates a new metrics collector registered with the default Prometheus registry.
 func New() *Collector {
-	return &Collector{
-		// Request metrics
-		RequestsTotal: promauto.NewCounterVec(
-			prometheus.CounterOpts{
-				Namespace: "apigate",
-				Name:      "requests_total",
-				Help:      "Total number of requests processed",
-			},
-			[]string{"method", "path", "status", "plan_id"},
-		),
-		RequestDuration: promauto.NewHistogramVec(
-			prometheus.HistogramOpts{
-				Namespace: "apigate",
-				Name:      "request_duration_seconds",
-				Help:      "Request duration in seconds",
-				Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
-			},
-			[]string{"method", "path", "status"},
-		),
-		RequestsInFlight: promauto.NewGauge(
-			prometheus.GaugeOpts{
-				Namespace: "apigate",
-				Name:      "requests_in_flight",
-				Help:      "Number of requests currently being processed",
-			},
-		),
-
-		// Auth metrics
-		AuthFailures: promauto.NewCounterVec(
-			prometheus.CounterOpts{
-				Namespace: "apigate",
-				Name:      "auth_failures_total",
-				Help:      "Total number of authentication failures",
-			},
-			[]string{"reason"},
-		),
-
-		// Rate limit metrics
-		RateLimitHits: promauto.NewCounterVec(
-			prometheus.CounterOpts{
-				Namespace: "apigate",
-				Name:      "rate_limit_hits_total",
-				Help:      "Total number of rate limit hits",
-			},
-			[]string{"plan_id", "user_id"},
-		),
-		RateLimitTokens: promauto.NewGaugeVec(
-			prometheus.GaugeOpts{
-				Namespace: "apigate",
-				Name:      "rate_limit_tokens",
-				Help:      "Current rate limit tokens available",
-			},
-			[]string{"plan_id", "user_id"},
-		),
-
-		// Usage metrics
-		UsageRequests: promauto.NewCounterVec(
-			prometheus.CounterOpts{
-				Namespace: "apigate",
-				Name:      "usage_requests_total",
-				Help:      "Total requests by user/plan",
-			},
-			[]string{"user_id", "plan_id"},
-		),
-		UsageBytes: promauto.NewCounterVec(
-			prometheus.CounterOpts{
-				Namespace: "apigate",
-				Name:      "usage_bytes_total",
-				Help:      "Total bytes transferred by user/plan",
-			},
-			[]string{"user_id", "plan_id", "direction"},
-		),
-
-		// Upstream metrics
-		UpstreamDuration: promauto.NewHistogramVec(
-			prometheus.HistogramOpts{
-				Namespace: "apigate",
-				Name:      "upstream_duration_seconds",
-				Help:      "Upstream request duration in seconds",
-				Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
-			},
-			[]string{"method", "status"},
-		),
-		UpstreamErrors: promauto.NewCounterVec(
-			prometheus.CounterOpts{
-				Namespace: "apigate",
-				Name:      "upstream_errors_total",
-				Help:      "Total number of upstream errors",
-			},
-			[]string{"type"},
-		),
-		UpstreamInFlight: promauto.NewGauge(
-			prometheus.GaugeOpts{
-				Namespace: "apigate",
-				Name:      "upstream_requests_in_flight",
-				Help:      "Number of requests currently being sent to upstream",
-			},
-		),
-
-		// Config metrics
-		ConfigReloads: promauto.NewCounter(
-			prometheus.CounterOpts{
-				Namespace: "apigate",
-				Name:      "config_reloads_total",
-				Help:      "Total number of successful config reloads",
-			},
-		),
-		ConfigReloadErrors: promauto.NewCounter(
-			prometheus.CounterOpts{
-				Namespace: "apigate",
-				Name:      "config_reload_errors_total",
-				Help:      "Total number of config reload errors",
-			},
-		),
-		ConfigLastReload: promauto.NewGauge(
-			prometheus.GaugeOpts{
-				Namespace: "apigate",
-				Name:      "config_last_reload_timestamp",
-				Help:      "Unix timestamp of last successful config reload",
-			},
-		),
-	}
+	return NewWithRegistry(prometheus.DefaultRegisterer)
 }
 
 // NewWithRegistry creates a new metrics collector with a custom registry.
@@ -191,6 +77,23 @@ func NewWithRegistry(reg prometheus.Registerer) *Collector {
 				Help:      "Number of requests currently being processed",
 			},
 		),
+		ProxyRequests: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "apigate",
+				Name:      "proxy_requests_total",
+				Help:      "Total number of proxied requests by route and status code",
+			},
+			[]string{"route", "status"},
+		),
+		ProxyDuration: factory.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: "apigate",
+				Name:      "proxy_duration_seconds",
+				Help:      "Time spent handling proxied requests in seconds",
+				Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+			},
+			[]string{"route"},
+		),
 		AuthFailures: factory.NewCounterVec(
 			prometheus.CounterOpts{
 				Namespace: "apigate",
@@ -215,6 +118,14 @@ func NewWithRegistry(reg prometheus.Registerer) *Collector {
 			},
 			[]string{"plan_id", "user_id"},
 		),
+		QuotaRejections: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "apigate",
+				Name:      "quota_rejections_total",
+				Help:      "Total number of requests rejected for exceeding quota",
+			},
+			[]string{"plan_id"},
+		),
 		UsageRequests: factory.NewCounterVec(
 			prometheus.CounterOpts{
 				Namespace: "apigate",
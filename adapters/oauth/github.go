@@ -15,10 +15,9 @@ import (
 )
 
 const (
-	githubAuthURL     = "https://github.com/login/oauth/authorize"
-	githubTokenURL    = "https://github.com/login/oauth/access_token"
-	githubUserURL     = "https://api.github.com/user"
-	githubUserOrgsURL = "https://api.github.com/user/orgs"
+	githubAuthURL  = "https://github.com/login/oauth/authorize"
+	githubTokenURL = "https://github.com/login/oauth/access_token"
+	githubAPIURL   = "https://api.github.com"
 )
 
 // GitHubProvider implements OAuth for GitHub.
@@ -28,6 +27,9 @@ type GitHubProvider struct {
 	scopes            []string
 	allowedOrgs       []string // Optional: restrict to specific organizations
 	allowPrivateEmail bool
+	authURL           string
+	tokenURL          string
+	apiURL            string
 	httpClient        *http.Client
 }
 
@@ -38,6 +40,11 @@ type GitHubConfig struct {
 	Scopes            []string
 	AllowedOrgs       []string
 	AllowPrivateEmail bool
+
+	// Endpoint overrides for GitHub Enterprise or tests. Empty means github.com's.
+	AuthURL  string
+	TokenURL string
+	APIURL   string // Base of the REST API, e.g. https://github.example.com/api/v3
 }
 
 // NewGitHubProvider creates a new GitHub OAuth provider.
@@ -53,6 +60,9 @@ func NewGitHubProvider(cfg GitHubConfig) *GitHubProvider {
 		scopes:            scopes,
 		allowedOrgs:       cfg.AllowedOrgs,
 		allowPrivateEmail: cfg.AllowPrivateEmail,
+		authURL:           orDefault(cfg.AuthURL, githubAuthURL),
+		tokenURL:          orDefault(cfg.TokenURL, githubTokenURL),
+		apiURL:            strings.TrimSuffix(orDefault(cfg.APIURL, githubAPIURL), "/"),
 		httpClient:        &http.Client{Timeout: 10 * time.Second},
 	}
 }
@@ -74,7 +84,7 @@ func (p *GitHubProvider) GetAuthURL(ctx context.Context, state, codeChallenge, n
 	// GitHub doesn't support PKCE yet, but we include it for future compatibility
 	// and to maintain consistent API across providers
 
-	return githubAuthURL + "?" + params.Encode(), nil
+	return p.authURL + "?" + params.Encode(), nil
 }
 
 // ExchangeCode exchanges an authorization code for tokens.
@@ -86,7 +96,7 @@ func (p *GitHubProvider) ExchangeCode(ctx context.Context, code, codeVerifier, r
 		"redirect_uri":  {redirectURI},
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", githubTokenURL, strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", p.tokenURL, strings.NewReader(data.Encode()))
 	if err != nil {
 		return oauth.TokenResponse{}, fmt.Errorf("create token request: %w", err)
 	}
@@ -134,7 +144,7 @@ func (p *GitHubProvider) ExchangeCode(ctx context.Context, code, codeVerifier, r
 // GetUserProfile fetches the user profile using the access token.
 func (p *GitHubProvider) GetUserProfile(ctx context.Context, accessToken string) (oauth.UserProfile, error) {
 	// Fetch user info
-	req, err := http.NewRequestWithContext(ctx, "GET", githubUserURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", p.apiURL+"/user", nil)
 	if err != nil {
 		return oauth.UserProfile{}, fmt.Errorf("create user request: %w", err)
 	}
@@ -223,7 +233,7 @@ func (p *GitHubProvider) GetUserProfile(ctx context.Context, accessToken string)
 
 // fetchPrimaryEmail fetches the user's primary email from GitHub API.
 func (p *GitHubProvider) fetchPrimaryEmail(ctx context.Context, accessToken string) (string, bool, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", "https://api.github.com/user/emails", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", p.apiURL+"/user/emails", nil)
 	if err != nil {
 		return "", false, err
 	}
@@ -281,7 +291,7 @@ func (p *GitHubProvider) fetchPrimaryEmail(ctx context.Context, accessToken stri
 
 // fetchUserOrgs fetches the user's organizations.
 func (p *GitHubProvider) fetchUserOrgs(ctx context.Context, accessToken string) ([]string, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", githubUserOrgsURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", p.apiURL+"/user/orgs", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -336,7 +346,7 @@ func (p *GitHubProvider) RefreshToken(ctx context.Context, refreshToken string)
 		"refresh_token": {refreshToken},
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", githubTokenURL, strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", p.tokenURL, strings.NewReader(data.Encode()))
 	if err != nil {
 		return oauth.TokenResponse{}, fmt.Errorf("create refresh request: %w", err)
 	}
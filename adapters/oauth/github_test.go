@@ -0,0 +1,76 @@
+package oauth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/artpar/apigate/adapters/oauth"
+)
+
+func newGitHubServer(t *testing.T, userJSON, emailsJSON string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/login/oauth/access_token":
+			r.ParseForm()
+			if r.Form.Get("code") != "good-code" {
+				w.Write([]byte(`{"error":"bad_verification_code","error_description":"The code is incorrect"}`))
+				return
+			}
+			w.Write([]byte(`{"access_token":"gho_123","token_type":"bearer","scope":"read:user,user:email"}`))
+		case "/api/user":
+			w.Write([]byte(userJSON))
+		case "/api/user/emails":
+			w.Write([]byte(emailsJSON))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGitHubProvider_Flow(t *testing.T) {
+	srv := newGitHubServer(t,
+		`{"id":1001,"login":"octocat","name":"","email":""}`,
+		`[{"email":"old@example.com","primary":false,"verified":true},{"email":"octo@example.com","primary":true,"verified":true}]`)
+
+	p := oauth.NewGitHubProvider(oauth.GitHubConfig{
+		ClientID:     "client",
+		ClientSecret: "secret",
+		AuthURL:      srv.URL + "/login/oauth/authorize",
+		TokenURL:     srv.URL + "/login/oauth/access_token",
+		APIURL:       srv.URL + "/api/",
+	})
+	ctx := context.Background()
+
+	authURL, _ := p.GetAuthURL(ctx, "state-1", "", "", "https://app.example.com/cb")
+	if !strings.HasPrefix(authURL, srv.URL+"/login/oauth/authorize?") {
+		t.Errorf("authURL = %s, want the configured endpoint", authURL)
+	}
+
+	tok, err := p.ExchangeCode(ctx, "good-code", "", "https://app.example.com/cb")
+	if err != nil || tok.AccessToken != "gho_123" {
+		t.Fatalf("ExchangeCode = %+v, %v", tok, err)
+	}
+	tok, err = p.ExchangeCode(ctx, "bad-code", "", "https://app.example.com/cb")
+	if err != nil || !strings.Contains(tok.Error, "bad_verification_code") {
+		t.Errorf("bad code = %+v, %v", tok, err)
+	}
+
+	// A private email is looked up from the emails endpoint
+	profile, err := p.GetUserProfile(ctx, tok.AccessToken)
+	if err != nil {
+		t.Fatalf("GetUserProfile: %v", err)
+	}
+	if profile.ProviderUserID != "1001" || profile.Email != "octo@example.com" || !profile.EmailVerified {
+		t.Errorf("profile = %+v, want primary verified email", profile)
+	}
+	if profile.Name != "octocat" {
+		t.Errorf("Name = %q, want login as fallback", profile.Name)
+	}
+}
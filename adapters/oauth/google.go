@@ -27,6 +27,9 @@ type GoogleProvider struct {
 	clientSecret string
 	scopes       []string
 	hostedDomain string // Optional: restrict to specific Google Workspace domain
+	authURL      string
+	tokenURL     string
+	userInfoURL  string
 	httpClient   *http.Client
 }
 
@@ -36,6 +39,11 @@ type GoogleConfig struct {
 	ClientSecret string
 	Scopes       []string
 	HostedDomain string
+
+	// Endpoint overrides, mainly for tests. Empty means Google's.
+	AuthURL     string
+	TokenURL    string
+	UserInfoURL string
 }
 
 // NewGoogleProvider creates a new Google OAuth provider.
@@ -50,6 +58,9 @@ func NewGoogleProvider(cfg GoogleConfig) *GoogleProvider {
 		clientSecret: cfg.ClientSecret,
 		scopes:       scopes,
 		hostedDomain: cfg.HostedDomain,
+		authURL:      orDefault(cfg.AuthURL, googleAuthURL),
+		tokenURL:     orDefault(cfg.TokenURL, googleTokenURL),
+		userInfoURL:  orDefault(cfg.UserInfoURL, googleUserInfoURL),
 		httpClient:   &http.Client{Timeout: 10 * time.Second},
 	}
 }
@@ -81,7 +92,7 @@ func (p *GoogleProvider) GetAuthURL(ctx context.Context, state, codeChallenge, n
 		params.Set("hd", p.hostedDomain)
 	}
 
-	return googleAuthURL + "?" + params.Encode(), nil
+	return p.authURL + "?" + params.Encode(), nil
 }
 
 // ExchangeCode exchanges an authorization code for tokens.
@@ -95,7 +106,7 @@ func (p *GoogleProvider) ExchangeCode(ctx context.Context, code, codeVerifier, r
 		"code_verifier": {codeVerifier},
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", googleTokenURL, strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", p.tokenURL, strings.NewReader(data.Encode()))
 	if err != nil {
 		return oauth.TokenResponse{}, fmt.Errorf("create token request: %w", err)
 	}
@@ -147,7 +158,7 @@ func (p *GoogleProvider) ExchangeCode(ctx context.Context, code, codeVerifier, r
 
 // GetUserProfile fetches the user profile using the access token.
 func (p *GoogleProvider) GetUserProfile(ctx context.Context, accessToken string) (oauth.UserProfile, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", googleUserInfoURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", p.userInfoURL, nil)
 	if err != nil {
 		return oauth.UserProfile{}, fmt.Errorf("create userinfo request: %w", err)
 	}
@@ -206,7 +217,7 @@ func (p *GoogleProvider) RefreshToken(ctx context.Context, refreshToken string)
 		"client_secret": {p.clientSecret},
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", googleTokenURL, strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", p.tokenURL, strings.NewReader(data.Encode()))
 	if err != nil {
 		return oauth.TokenResponse{}, fmt.Errorf("create refresh request: %w", err)
 	}
@@ -253,5 +264,13 @@ func (p *GoogleProvider) RefreshToken(ctx context.Context, refreshToken string)
 	}, nil
 }
 
+// orDefault returns value, or def if value is empty.
+func orDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
 // Ensure interface compliance.
 var _ ports.OAuthProvider = (*GoogleProvider)(nil)
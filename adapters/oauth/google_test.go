@@ -0,0 +1,91 @@
+package oauth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/artpar/apigate/adapters/oauth"
+)
+
+func TestGoogleProvider_Flow(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/token":
+			r.ParseForm()
+			if r.Form.Get("code") != "good-code" || r.Form.Get("code_verifier") != "verifier" || r.Form.Get("client_secret") != "secret" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"invalid_grant","error_description":"Bad code"}`))
+				return
+			}
+			w.Write([]byte(`{"access_token":"at-123","refresh_token":"rt-123","token_type":"Bearer","expires_in":3600}`))
+		case "/userinfo":
+			if r.Header.Get("Authorization") != "Bearer at-123" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"id":"g-42","email":"ada@example.com","verified_email":true,"name":"Ada Lovelace","picture":"https://example.com/ada.png"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	p := oauth.NewGoogleProvider(oauth.GoogleConfig{
+		ClientID:     "client",
+		ClientSecret: "secret",
+		AuthURL:      srv.URL + "/auth",
+		TokenURL:     srv.URL + "/token",
+		UserInfoURL:  srv.URL + "/userinfo",
+	})
+	ctx := context.Background()
+
+	authURL, err := p.GetAuthURL(ctx, "state-1", "challenge", "nonce", "https://app.example.com/cb")
+	if err != nil {
+		t.Fatalf("GetAuthURL: %v", err)
+	}
+	if !strings.HasPrefix(authURL, srv.URL+"/auth?") {
+		t.Errorf("authURL = %s, want the configured endpoint", authURL)
+	}
+	u, _ := url.Parse(authURL)
+	if q := u.Query(); q.Get("state") != "state-1" || q.Get("redirect_uri") != "https://app.example.com/cb" || q.Get("code_challenge") != "challenge" {
+		t.Errorf("auth query = %v", q)
+	}
+
+	tok, err := p.ExchangeCode(ctx, "good-code", "verifier", "https://app.example.com/cb")
+	if err != nil || tok.Error != "" {
+		t.Fatalf("ExchangeCode = %+v, %v", tok, err)
+	}
+	if tok.AccessToken != "at-123" || tok.RefreshToken != "rt-123" || tok.ExpiresIn != 3600 {
+		t.Errorf("token = %+v", tok)
+	}
+
+	tok, err = p.ExchangeCode(ctx, "bad-code", "verifier", "https://app.example.com/cb")
+	if err != nil || !strings.Contains(tok.Error, "invalid_grant") {
+		t.Errorf("bad code = %+v, %v, want invalid_grant", tok, err)
+	}
+
+	profile, err := p.GetUserProfile(ctx, "at-123")
+	if err != nil {
+		t.Fatalf("GetUserProfile: %v", err)
+	}
+	if profile.ProviderUserID != "g-42" || profile.Email != "ada@example.com" || !profile.EmailVerified || profile.Name != "Ada Lovelace" {
+		t.Errorf("profile = %+v", profile)
+	}
+
+	if _, err := p.GetUserProfile(ctx, "wrong"); err == nil {
+		t.Error("expected error for a rejected access token")
+	}
+}
+
+func TestGoogleProvider_DefaultEndpoints(t *testing.T) {
+	p := oauth.NewGoogleProvider(oauth.GoogleConfig{ClientID: "client"})
+	authURL, _ := p.GetAuthURL(context.Background(), "s", "c", "", "https://app.example.com/cb")
+	if !strings.HasPrefix(authURL, "https://accounts.google.com/") {
+		t.Errorf("authURL = %s, want Google's endpoint", authURL)
+	}
+}
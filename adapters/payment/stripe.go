@@ -143,8 +143,12 @@ func (p *StripeProvider) ReportUsage(ctx context.Context, subscriptionItemID str
 }
 
 // ParseWebhook parses and validates a Stripe webhook.
+// The event's API version is not required to match the library's: only the
+// signature and timestamp are verified, and the payload is read as raw JSON.
 func (p *StripeProvider) ParseWebhook(payload []byte, signature string) (string, map[string]any, error) {
-	event, err := webhook.ConstructEvent(payload, signature, p.config.WebhookSecret)
+	event, err := webhook.ConstructEventWithOptions(payload, signature, p.config.WebhookSecret, webhook.ConstructEventOptions{
+		IgnoreAPIVersionMismatch: true,
+	})
 	if err != nil {
 		return "", nil, err
 	}
@@ -0,0 +1,96 @@
+// Package pwned checks passwords against the Have I Been Pwned range API.
+//
+// Only the first five hex characters of the password's SHA-1 hash are sent
+// (k-anonymity); the match against the returned suffixes happens locally.
+package pwned
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/artpar/apigate/ports"
+)
+
+// DefaultBaseURL is the public Pwned Passwords API.
+const DefaultBaseURL = "https://api.pwnedpasswords.com"
+
+// Checker queries the Pwned Passwords range API.
+type Checker struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+// Config configures the checker.
+type Config struct {
+	BaseURL string // Defaults to DefaultBaseURL
+	Timeout time.Duration
+}
+
+// New creates a Pwned Passwords checker.
+func New(cfg Config) *Checker {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	return &Checker{
+		httpClient: &http.Client{Timeout: timeout},
+		baseURL:    strings.TrimRight(baseURL, "/"),
+	}
+}
+
+// IsBreached returns true if password appears in the Pwned Passwords corpus.
+func (c *Checker) IsBreached(ctx context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/range/"+prefix, nil)
+	if err != nil {
+		return false, fmt.Errorf("create request: %w", err)
+	}
+	// Padding hides the real response size from observers.
+	req.Header.Set("Add-Padding", "true")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("pwned passwords: unexpected status %d", resp.StatusCode)
+	}
+
+	// Each line is "SUFFIX:COUNT". Padding entries have a count of 0.
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		candidate, count, ok := strings.Cut(line, ":")
+		if !ok || !strings.EqualFold(candidate, suffix) {
+			continue
+		}
+		n, err := strconv.Atoi(count)
+		if err != nil {
+			return false, fmt.Errorf("parse count: %w", err)
+		}
+		return n > 0, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("read response: %w", err)
+	}
+	return false, nil
+}
+
+// Ensure interface compliance.
+var _ ports.BreachedPasswordChecker = (*Checker)(nil)
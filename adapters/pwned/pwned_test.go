@@ -0,0 +1,104 @@
+package pwned_test
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/artpar/apigate/adapters/pwned"
+)
+
+// hibpServer mocks the range API, reporting breached passwords with a count
+// and adding a zero-count padding entry to every response.
+func hibpServer(t *testing.T, breached map[string]int, gotPrefix *string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefix := strings.TrimPrefix(r.URL.Path, "/range/")
+		if gotPrefix != nil {
+			*gotPrefix = prefix
+		}
+		if r.Header.Get("Add-Padding") != "true" {
+			t.Error("expected Add-Padding header")
+		}
+		fmt.Fprintln(w, "0018A45C4D1DEF81644B54AB7F969B88D65:1")
+		for password, count := range breached {
+			hash := sha1Hex(password)
+			if hash[:5] == prefix {
+				fmt.Fprintf(w, "%s:%d\r\n", hash[5:], count)
+			}
+		}
+		fmt.Fprintf(w, "%s:0\r\n", strings.Repeat("F", 35))
+	}))
+}
+
+func sha1Hex(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func TestChecker_IsBreached(t *testing.T) {
+	var prefix string
+	srv := hibpServer(t, map[string]int{"Password123": 251682}, &prefix)
+	defer srv.Close()
+
+	c := pwned.New(pwned.Config{BaseURL: srv.URL})
+
+	breached, err := c.IsBreached(context.Background(), "Password123")
+	if err != nil {
+		t.Fatalf("IsBreached: %v", err)
+	}
+	if !breached {
+		t.Error("expected Password123 to be breached")
+	}
+	if want := sha1Hex("Password123")[:5]; prefix != want {
+		t.Errorf("sent prefix %q, want %q", prefix, want)
+	}
+}
+
+func TestChecker_NotBreached(t *testing.T) {
+	srv := hibpServer(t, map[string]int{"Password123": 251682}, nil)
+	defer srv.Close()
+
+	c := pwned.New(pwned.Config{BaseURL: srv.URL})
+
+	breached, err := c.IsBreached(context.Background(), "correct-horse-battery-staple-42")
+	if err != nil {
+		t.Fatalf("IsBreached: %v", err)
+	}
+	if breached {
+		t.Error("expected password not to be breached")
+	}
+}
+
+func TestChecker_PaddingEntryIsNotBreached(t *testing.T) {
+	srv := hibpServer(t, map[string]int{"Padded1!": 0}, nil)
+	defer srv.Close()
+
+	c := pwned.New(pwned.Config{BaseURL: srv.URL})
+
+	breached, err := c.IsBreached(context.Background(), "Padded1!")
+	if err != nil {
+		t.Fatalf("IsBreached: %v", err)
+	}
+	if breached {
+		t.Error("expected zero-count padding entry not to count as breached")
+	}
+}
+
+func TestChecker_ServerError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	c := pwned.New(pwned.Config{BaseURL: srv.URL})
+
+	if _, err := c.IsBreached(context.Background(), "Password123"); err == nil {
+		t.Error("expected error for unavailable service")
+	}
+}
@@ -0,0 +1,166 @@
+// Package redis provides Redis-backed implementations of ports for
+// deployments running several APIGate instances.
+package redis
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/artpar/apigate/domain/ratelimit"
+	"github.com/artpar/apigate/ports"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// DefaultKeyPrefix namespaces rate limit keys in Redis.
+const DefaultKeyPrefix = "apigate:ratelimit:"
+
+// bucketScript applies a token bucket check atomically. It mirrors
+// ratelimit.CheckBucket; times are microseconds since the Unix epoch.
+//
+// KEYS[1] = bucket hash
+// ARGV    = capacity, limit, window, now
+// Returns {allowed, tokens, refilled_at}
+var bucketScript = goredis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+local window = tonumber(ARGV[3])
+local now = tonumber(ARGV[4])
+
+-- Timestamps are kept as strings: tostring() would round them
+local tokens = capacity
+local refilled = ARGV[4]
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'last_refill')
+if state[1] and state[2] then
+	tokens = tonumber(state[1])
+	local last = tonumber(state[2])
+	if now > last then
+		tokens = tokens + (now - last) * limit / window
+	else
+		refilled = state[2]
+	end
+	if tokens > capacity then
+		tokens = capacity
+	end
+end
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'last_refill', refilled)
+local ttl = math.ceil((capacity - tokens) * window / limit / 1000) + 1000
+redis.call('PEXPIRE', KEYS[1], ttl)
+return {allowed, tostring(tokens), refilled}
+`)
+
+// RateLimitStore implements ports.RateLimitStore and ports.RateLimitChecker
+// with Redis, so every instance sharing the server enforces a single limit.
+type RateLimitStore struct {
+	client goredis.UniversalClient
+	prefix string
+}
+
+// NewRateLimitStore creates a Redis rate limit store using the given client.
+func NewRateLimitStore(client goredis.UniversalClient) *RateLimitStore {
+	return &RateLimitStore{client: client, prefix: DefaultKeyPrefix}
+}
+
+// NewClient creates a Redis client from a URL such as redis://:password@host:6379/0.
+func NewClient(url string) (*goredis.Client, error) {
+	opts, err := goredis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	return goredis.NewClient(opts), nil
+}
+
+// CheckBucket atomically takes a token from a key's bucket.
+func (s *RateLimitStore) CheckBucket(ctx context.Context, keyID string, cfg ratelimit.Config, now time.Time) (ratelimit.CheckResult, error) {
+	if cfg.Limit <= 0 || cfg.Window <= 0 {
+		result, _ := ratelimit.CheckBucket(ratelimit.WindowState{}, cfg, now)
+		return result, nil
+	}
+
+	res, err := bucketScript.Run(ctx, s.client, []string{s.prefix + keyID},
+		cfg.BucketSize(), cfg.Limit, cfg.Window.Microseconds(), now.UnixMicro()).Slice()
+	if err != nil {
+		return ratelimit.CheckResult{}, err
+	}
+	if len(res) != 3 {
+		return ratelimit.CheckResult{}, errors.New("redis: unexpected rate limit script result")
+	}
+
+	allowed, _ := res[0].(int64)
+	tokens, err := strconv.ParseFloat(toString(res[1]), 64)
+	if err != nil {
+		return ratelimit.CheckResult{}, err
+	}
+	refilled, err := strconv.ParseInt(toString(res[2]), 10, 64)
+	if err != nil {
+		return ratelimit.CheckResult{}, err
+	}
+
+	result, _ := ratelimit.BucketResult(cfg, allowed == 1, tokens, time.UnixMicro(refilled).In(now.Location()))
+	return result, nil
+}
+
+// Get retrieves current rate limit state for a key.
+func (s *RateLimitStore) Get(ctx context.Context, keyID string) (ratelimit.WindowState, error) {
+	values, err := s.client.HMGet(ctx, s.prefix+keyID, "tokens", "last_refill").Result()
+	if err != nil {
+		return ratelimit.WindowState{}, err
+	}
+	if values[0] == nil || values[1] == nil {
+		return ratelimit.WindowState{}, nil
+	}
+
+	tokens, err := strconv.ParseFloat(toString(values[0]), 64)
+	if err != nil {
+		return ratelimit.WindowState{}, err
+	}
+	refilled, err := strconv.ParseInt(toString(values[1]), 10, 64)
+	if err != nil {
+		return ratelimit.WindowState{}, err
+	}
+	return ratelimit.WindowState{Tokens: tokens, LastRefill: time.UnixMicro(refilled)}, nil
+}
+
+// Set updates rate limit state for a key.
+func (s *RateLimitStore) Set(ctx context.Context, keyID string, state ratelimit.WindowState) error {
+	key := s.prefix + keyID
+	if state.LastRefill.IsZero() {
+		return s.client.Del(ctx, key).Err()
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.HSet(ctx, key,
+		"tokens", strconv.FormatFloat(state.Tokens, 'g', -1, 64),
+		"last_refill", strconv.FormatInt(state.LastRefill.UnixMicro(), 10))
+	if ttl := time.Until(state.WindowEnd); ttl > 0 {
+		pipe.PExpire(ctx, key, ttl+time.Second)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// toString converts a Redis reply value to a string.
+func toString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	default:
+		return ""
+	}
+}
+
+// Ensure interface compliance.
+var (
+	_ ports.RateLimitStore   = (*RateLimitStore)(nil)
+	_ ports.RateLimitChecker = (*RateLimitStore)(nil)
+)
@@ -0,0 +1,186 @@
+package redis_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	redisstore "github.com/artpar/apigate/adapters/redis"
+	"github.com/artpar/apigate/domain/ratelimit"
+)
+
+var (
+	baseTime = time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	cfg      = ratelimit.Config{Limit: 60, Window: time.Minute, Burst: 10}
+)
+
+// newStores returns n rate limit stores, each with its own client, sharing one Redis server.
+func newStores(t *testing.T, n int) (*miniredis.Miniredis, []*redisstore.RateLimitStore) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	stores := make([]*redisstore.RateLimitStore, n)
+	for i := range stores {
+		client, err := redisstore.NewClient("redis://" + server.Addr())
+		if err != nil {
+			t.Fatalf("NewClient: %v", err)
+		}
+		t.Cleanup(func() { client.Close() })
+		stores[i] = redisstore.NewRateLimitStore(client)
+	}
+	return server, stores
+}
+
+func TestRateLimitStore_SharedLimit(t *testing.T) {
+	_, stores := newStores(t, 2)
+	ctx := context.Background()
+
+	// Two instances alternate requests for the same key: together they get one burst
+	allowed := 0
+	for i := 0; i < 20; i++ {
+		result, err := stores[i%2].CheckBucket(ctx, "key-1", cfg, baseTime)
+		if err != nil {
+			t.Fatalf("CheckBucket: %v", err)
+		}
+		if result.Allowed {
+			allowed++
+			if want := 9 - i; result.Remaining != want {
+				t.Errorf("request %d remaining = %d, want %d", i+1, result.Remaining, want)
+			}
+		}
+	}
+	if allowed != 10 {
+		t.Errorf("allowed %d requests across instances, want shared burst of 10", allowed)
+	}
+
+	// Denials report when the next token is available
+	result, _ := stores[0].CheckBucket(ctx, "key-1", cfg, baseTime)
+	if result.Allowed || result.Reason != ratelimit.ReasonLimitExceeded {
+		t.Fatalf("result = %+v, want denied", result)
+	}
+	if want := baseTime.Add(time.Second); !result.ResetAt.Equal(want) {
+		t.Errorf("ResetAt = %v, want %v", result.ResetAt, want)
+	}
+
+	// Tokens refill at the steady rate, shared by both instances
+	later := baseTime.Add(3 * time.Second)
+	allowed = 0
+	for i := 0; i < 6; i++ {
+		if result, _ := stores[i%2].CheckBucket(ctx, "key-1", cfg, later); result.Allowed {
+			allowed++
+		}
+	}
+	if allowed != 3 {
+		t.Errorf("allowed %d after 3s, want 3", allowed)
+	}
+
+	// Other keys have their own bucket
+	if result, _ := stores[1].CheckBucket(ctx, "key-2", cfg, baseTime); !result.Allowed {
+		t.Error("separate key was limited")
+	}
+}
+
+func TestRateLimitStore_MatchesDomainBucket(t *testing.T) {
+	_, stores := newStores(t, 1)
+	ctx := context.Background()
+
+	var state ratelimit.WindowState
+	now := baseTime
+	for i := 0; i < 50; i++ {
+		now = now.Add(time.Duration(i%7) * 150 * time.Millisecond)
+		got, err := stores[0].CheckBucket(ctx, "key-1", cfg, now)
+		if err != nil {
+			t.Fatalf("CheckBucket: %v", err)
+		}
+		var want ratelimit.CheckResult
+		want, state = ratelimit.CheckBucket(state, cfg, now)
+		if got.Allowed != want.Allowed || got.Remaining != want.Remaining || !got.ResetAt.Equal(want.ResetAt) {
+			t.Fatalf("request %d: redis %+v, domain %+v", i+1, got, want)
+		}
+	}
+}
+
+func TestRateLimitStore_Concurrent(t *testing.T) {
+	_, stores := newStores(t, 3)
+	ctx := context.Background()
+
+	var allowed atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 60; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := stores[i%3].CheckBucket(ctx, "key-1", cfg, baseTime)
+			if err != nil {
+				t.Errorf("CheckBucket: %v", err)
+				return
+			}
+			if result.Allowed {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := allowed.Load(); got != 10 {
+		t.Errorf("allowed %d concurrent requests across 3 instances, want 10", got)
+	}
+}
+
+func TestRateLimitStore_Expiry(t *testing.T) {
+	server, stores := newStores(t, 1)
+	ctx := context.Background()
+
+	stores[0].CheckBucket(ctx, "key-1", cfg, baseTime)
+	key := redisstore.DefaultKeyPrefix + "key-1"
+	if !server.Exists(key) {
+		t.Fatal("bucket not stored")
+	}
+	// One token used: the bucket is full again after 1s, plus a second of slack
+	if ttl := server.TTL(key); ttl <= 0 || ttl > 2*time.Second {
+		t.Errorf("TTL = %v, want about 2s", ttl)
+	}
+	server.FastForward(3 * time.Second)
+	if server.Exists(key) {
+		t.Error("full bucket was not expired")
+	}
+}
+
+func TestRateLimitStore_GetSet(t *testing.T) {
+	_, stores := newStores(t, 1)
+	ctx := context.Background()
+
+	state, err := stores[0].Get(ctx, "key-1")
+	if err != nil || !state.LastRefill.IsZero() {
+		t.Fatalf("Get on missing key = %+v, %v; want empty state", state, err)
+	}
+
+	want := ratelimit.WindowState{Tokens: 3.5, LastRefill: baseTime, WindowEnd: time.Now().Add(time.Minute)}
+	if err := stores[0].Set(ctx, "key-1", want); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	got, err := stores[0].Get(ctx, "key-1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Tokens != 3.5 || !got.LastRefill.Equal(baseTime) {
+		t.Errorf("Get = %+v, want tokens 3.5 at %v", got, baseTime)
+	}
+
+	// State written with Set is used by CheckBucket
+	result, _ := stores[0].CheckBucket(ctx, "key-1", cfg, baseTime)
+	if !result.Allowed || result.Remaining != 2 {
+		t.Errorf("CheckBucket after Set = %+v, want allowed with 2 remaining", result)
+	}
+}
+
+func TestRateLimitStore_Unavailable(t *testing.T) {
+	server, stores := newStores(t, 1)
+	server.Close()
+
+	if _, err := stores[0].CheckBucket(context.Background(), "key-1", cfg, baseTime); err == nil {
+		t.Error("CheckBucket with Redis down returned no error")
+	}
+}
@@ -2,6 +2,7 @@ package remote
 
 import (
 	"context"
+	"net/url"
 	"time"
 
 	"github.com/artpar/apigate/domain/key"
@@ -27,6 +28,13 @@ import (
 //
 //	GET /keys/user/{user_id}
 //	Response: {"keys": [...]}
+//
+//	GET /keys/user/{user_id}?labels=env=prod,service
+//	Response: {"keys": [...]} (keys with every selected label)
+//
+//	PUT /keys/{id}/labels
+//	Request:  {"labels": {"env": "prod"}}
+//	Response: {}
 type KeyStore struct {
 	client *Client
 }
@@ -51,16 +59,23 @@ type KeyValidateResponse struct {
 
 // RemoteKey represents a key from the remote service.
 type RemoteKey struct {
-	ID        string     `json:"id"`
-	UserID    string     `json:"user_id"`
-	Hash      []byte     `json:"hash,omitempty"` // Only if using server-side comparison
-	Prefix    string     `json:"prefix"`
-	Name      string     `json:"name,omitempty"`
-	Scopes    []string   `json:"scopes,omitempty"`
-	ExpiresAt *time.Time `json:"expires_at,omitempty"`
-	RevokedAt *time.Time `json:"revoked_at,omitempty"`
-	CreatedAt time.Time  `json:"created_at"`
-	LastUsed  *time.Time `json:"last_used,omitempty"`
+	ID            string            `json:"id"`
+	UserID        string            `json:"user_id"`
+	GroupID       string            `json:"group_id,omitempty"`
+	Hash          []byte            `json:"hash,omitempty"` // Only if using server-side comparison
+	Prefix        string            `json:"prefix"`
+	Name          string            `json:"name,omitempty"`
+	Scopes        []string          `json:"scopes,omitempty"`
+	AllowedCIDRs  []string          `json:"allowed_cidrs,omitempty"`
+	SigningSecret string            `json:"signing_secret,omitempty"`
+	RotatedFrom   string            `json:"rotated_from,omitempty"`
+	MonthlyQuota  int64             `json:"monthly_quota,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	ExpiresAt     *time.Time        `json:"expires_at,omitempty"`
+	RevokedAt     *time.Time        `json:"revoked_at,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+	LastUsed      *time.Time        `json:"last_used,omitempty"`
+	LastUsedIP    string            `json:"last_used_ip,omitempty"`
 }
 
 // Get retrieves keys matching a prefix.
@@ -138,6 +153,38 @@ func (s *KeyStore) ListByUser(ctx context.Context, userID string) ([]key.Key, er
 	return keys, nil
 }
 
+// ListByUserLabels returns the user's keys matching the label selector.
+// The selector is sent to the remote service, and the result is filtered
+// again in case the service does not support it.
+func (s *KeyStore) ListByUserLabels(ctx context.Context, userID string, sel key.LabelSelector) ([]key.Key, error) {
+	if len(sel) == 0 {
+		return s.ListByUser(ctx, userID)
+	}
+
+	var resp struct {
+		Keys []RemoteKey `json:"keys"`
+	}
+
+	path := "/keys/user/" + userID + "?labels=" + url.QueryEscape(sel.String())
+	if err := s.client.Request(ctx, "GET", path, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	keys := make([]key.Key, len(resp.Keys))
+	for i, rk := range resp.Keys {
+		keys[i] = toKey(rk)
+	}
+	return key.FilterByLabels(keys, sel), nil
+}
+
+// SetLabels replaces a key's labels; nil removes them all.
+func (s *KeyStore) SetLabels(ctx context.Context, id string, labels map[string]string) error {
+	req := map[string]interface{}{
+		"labels": labels,
+	}
+	return s.client.Request(ctx, "PUT", "/keys/"+id+"/labels", req, nil)
+}
+
 // UpdateLastUsed updates the last used timestamp.
 func (s *KeyStore) UpdateLastUsed(ctx context.Context, id string, at time.Time) error {
 	req := map[string]interface{}{
@@ -148,33 +195,50 @@ func (s *KeyStore) UpdateLastUsed(ctx context.Context, id string, at time.Time)
 
 func toKey(rk RemoteKey) key.Key {
 	return key.Key{
-		ID:        rk.ID,
-		UserID:    rk.UserID,
-		Hash:      rk.Hash,
-		Prefix:    rk.Prefix,
-		Name:      rk.Name,
-		Scopes:    rk.Scopes,
-		ExpiresAt: rk.ExpiresAt,
-		RevokedAt: rk.RevokedAt,
-		CreatedAt: rk.CreatedAt,
-		LastUsed:  rk.LastUsed,
+		ID:            rk.ID,
+		UserID:        rk.UserID,
+		GroupID:       rk.GroupID,
+		Hash:          rk.Hash,
+		Prefix:        rk.Prefix,
+		Name:          rk.Name,
+		Scopes:        rk.Scopes,
+		AllowedCIDRs:  rk.AllowedCIDRs,
+		SigningSecret: rk.SigningSecret,
+		RotatedFrom:   rk.RotatedFrom,
+		MonthlyQuota:  rk.MonthlyQuota,
+		Labels:        rk.Labels,
+		ExpiresAt:     rk.ExpiresAt,
+		RevokedAt:     rk.RevokedAt,
+		CreatedAt:     rk.CreatedAt,
+		LastUsed:      rk.LastUsed,
+		LastUsedIP:    rk.LastUsedIP,
 	}
 }
 
 func fromKey(k key.Key) RemoteKey {
 	return RemoteKey{
-		ID:        k.ID,
-		UserID:    k.UserID,
-		Hash:      k.Hash,
-		Prefix:    k.Prefix,
-		Name:      k.Name,
-		Scopes:    k.Scopes,
-		ExpiresAt: k.ExpiresAt,
-		RevokedAt: k.RevokedAt,
-		CreatedAt: k.CreatedAt,
-		LastUsed:  k.LastUsed,
+		ID:            k.ID,
+		UserID:        k.UserID,
+		GroupID:       k.GroupID,
+		Hash:          k.Hash,
+		Prefix:        k.Prefix,
+		Name:          k.Name,
+		Scopes:        k.Scopes,
+		AllowedCIDRs:  k.AllowedCIDRs,
+		SigningSecret: k.SigningSecret,
+		RotatedFrom:   k.RotatedFrom,
+		MonthlyQuota:  k.MonthlyQuota,
+		Labels:        k.Labels,
+		ExpiresAt:     k.ExpiresAt,
+		RevokedAt:     k.RevokedAt,
+		CreatedAt:     k.CreatedAt,
+		LastUsed:      k.LastUsed,
+		LastUsedIP:    k.LastUsedIP,
 	}
 }
 
 // Ensure interface compliance.
-var _ ports.KeyStore = (*KeyStore)(nil)
+var (
+	_ ports.KeyStore      = (*KeyStore)(nil)
+	_ ports.KeyLabelStore = (*KeyStore)(nil)
+)
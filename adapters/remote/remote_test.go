@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -330,13 +331,14 @@ func TestKeyStore_Get(t *testing.T) {
 		}{
 			Keys: []RemoteKey{
 				{
-					ID:        "key-123",
-					UserID:    "user-456",
-					Prefix:    "ak_abc12345",
-					Name:      "Test Key",
-					Scopes:    []string{"read", "write"},
-					ExpiresAt: &expires,
-					CreatedAt: now,
+					ID:           "key-123",
+					UserID:       "user-456",
+					Prefix:       "ak_abc12345",
+					Name:         "Test Key",
+					Scopes:       []string{"read", "write"},
+					AllowedCIDRs: []string{"10.0.0.0/8"},
+					ExpiresAt:    &expires,
+					CreatedAt:    now,
 				},
 			},
 		}
@@ -372,6 +374,9 @@ func TestKeyStore_Get(t *testing.T) {
 	if len(k.Scopes) != 2 || k.Scopes[0] != "read" || k.Scopes[1] != "write" {
 		t.Errorf("Scopes = %v, want [read write]", k.Scopes)
 	}
+	if len(k.AllowedCIDRs) != 1 || k.AllowedCIDRs[0] != "10.0.0.0/8" {
+		t.Errorf("AllowedCIDRs = %v, want [10.0.0.0/8]", k.AllowedCIDRs)
+	}
 }
 
 func TestKeyStore_Get_NotFound(t *testing.T) {
@@ -633,6 +638,64 @@ func TestKeyStore_ListByUser(t *testing.T) {
 	}
 }
 
+func TestKeyStore_Labels(t *testing.T) {
+	now := time.Now().UTC()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/keys":
+			var req RemoteKey
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatalf("Failed to decode request: %v", err)
+			}
+			if req.Labels["env"] != "prod" {
+				t.Errorf("created labels = %v, want env=prod", req.Labels)
+			}
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPut && r.URL.Path == "/keys/key-1/labels":
+			var req struct {
+				Labels map[string]string `json:"labels"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatalf("Failed to decode request: %v", err)
+			}
+			if len(req.Labels) != 1 || req.Labels["service"] != "billing" {
+				t.Errorf("set labels = %v, want service=billing", req.Labels)
+			}
+		case r.Method == http.MethodGet && r.URL.Path == "/keys/user/user-456":
+			if got := r.URL.Query().Get("labels"); got != "env=prod" {
+				t.Errorf("labels query = %q, want env=prod", got)
+			}
+			// This service ignores the selector, so the client must filter
+			json.NewEncoder(w).Encode(map[string]any{"keys": []RemoteKey{
+				{ID: "key-1", UserID: "user-456", Labels: map[string]string{"env": "prod"}, CreatedAt: now},
+				{ID: "key-2", UserID: "user-456", Labels: map[string]string{"env": "dev"}, CreatedAt: now},
+			}})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	ks := NewKeyStore(NewClient(ClientConfig{BaseURL: server.URL}))
+	ctx := context.Background()
+
+	if err := ks.Create(ctx, key.Key{ID: "key-1", UserID: "user-456", Labels: map[string]string{"env": "prod"}, CreatedAt: now}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := ks.SetLabels(ctx, "key-1", map[string]string{"service": "billing"}); err != nil {
+		t.Fatalf("SetLabels failed: %v", err)
+	}
+
+	keys, err := ks.ListByUserLabels(ctx, "user-456", key.LabelSelector{"env": "prod"})
+	if err != nil {
+		t.Fatalf("ListByUserLabels failed: %v", err)
+	}
+	if len(keys) != 1 || keys[0].ID != "key-1" || keys[0].Labels["env"] != "prod" {
+		t.Errorf("keys = %+v, want only key-1 with env=prod", keys)
+	}
+}
+
 func TestKeyStore_UpdateLastUsed(t *testing.T) {
 	lastUsed := time.Now().UTC()
 
@@ -972,6 +1035,59 @@ func TestUsageRecorder_Close(t *testing.T) {
 	}
 }
 
+func TestUsageRecorder_Close_ReportsDropped(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(ClientConfig{BaseURL: server.URL})
+	recorder := NewUsageRecorder(client, UsageRecorderConfig{
+		BatchSize:     100,
+		FlushInterval: 1 * time.Hour,
+	})
+
+	for i := 0; i < 2; i++ {
+		recorder.Record(usage.Event{KeyID: "key-123", Timestamp: time.Now().UTC()})
+	}
+
+	err := recorder.Close()
+	if err == nil || !strings.Contains(err.Error(), "dropped 2 events") {
+		t.Fatalf("Close error = %v, want it to count 2 dropped events", err)
+	}
+
+	// A second Close is a no-op
+	if err := recorder.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
+
+func TestUsageRecorder_Close_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := NewClient(ClientConfig{BaseURL: server.URL})
+	recorder := NewUsageRecorder(client, UsageRecorderConfig{
+		BatchSize:     100,
+		FlushInterval: 1 * time.Hour,
+	})
+	recorder.closeTimeout = 50 * time.Millisecond
+
+	recorder.Record(usage.Event{KeyID: "key-123", Timestamp: time.Now().UTC()})
+
+	start := time.Now()
+	if err := recorder.Close(); err == nil {
+		t.Error("expected Close to report the dropped event")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Close took %v, want it bounded by the close timeout", elapsed)
+	}
+}
+
 func TestUsageRecorder_FlushLoop(t *testing.T) {
 	var flushCount int
 	var mu sync.Mutex
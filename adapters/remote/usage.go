@@ -2,6 +2,7 @@ package remote
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
 
@@ -22,13 +23,15 @@ import (
 //	Request:  {"event": {...}}
 //	Response: {}
 type UsageRecorder struct {
-	client    *Client
-	buffer    []usage.Event
-	mu        sync.Mutex
-	batchSize int
+	client        *Client
+	buffer        []usage.Event
+	mu            sync.Mutex
+	batchSize     int
 	flushInterval time.Duration
-	stopCh    chan struct{}
-	wg        sync.WaitGroup
+	closeTimeout  time.Duration // Bounds the final flush in Close
+	stopCh        chan struct{}
+	wg            sync.WaitGroup
+	closeOnce     sync.Once
 }
 
 // UsageRecorderConfig configures the usage recorder.
@@ -47,11 +50,12 @@ func NewUsageRecorder(client *Client, cfg UsageRecorderConfig) *UsageRecorder {
 	}
 
 	r := &UsageRecorder{
-		client:    client,
-		buffer:    make([]usage.Event, 0, cfg.BatchSize),
-		batchSize: cfg.BatchSize,
+		client:        client,
+		buffer:        make([]usage.Event, 0, cfg.BatchSize),
+		batchSize:     cfg.BatchSize,
 		flushInterval: cfg.FlushInterval,
-		stopCh:    make(chan struct{}),
+		closeTimeout:  10 * time.Second,
+		stopCh:        make(chan struct{}),
 	}
 
 	r.wg.Add(1)
@@ -149,11 +153,26 @@ func (r *UsageRecorder) flushLoop() {
 	}
 }
 
-// Close stops the recorder and flushes remaining events.
+// Close stops the recorder and flushes remaining events, giving up after the
+// close timeout. Events that could not be sent are dropped and the error says
+// how many.
 func (r *UsageRecorder) Close() error {
-	close(r.stopCh)
-	r.wg.Wait()
-	return r.Flush(context.Background())
+	var err error
+	r.closeOnce.Do(func() {
+		close(r.stopCh)
+		r.wg.Wait()
+
+		ctx, cancel := context.WithTimeout(context.Background(), r.closeTimeout)
+		defer cancel()
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if ferr := r.flushLocked(ctx); ferr != nil {
+			err = fmt.Errorf("usage recorder dropped %d events: %w", len(r.buffer), ferr)
+			r.buffer = r.buffer[:0]
+		}
+	})
+	return err
 }
 
 // Ensure interface compliance.
@@ -0,0 +1,138 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/artpar/apigate/domain/audit"
+	"github.com/artpar/apigate/ports"
+)
+
+// AuditStore implements ports.AuditStore using SQLite.
+type AuditStore struct {
+	db *DB
+}
+
+// NewAuditStore creates a new SQLite audit store.
+func NewAuditStore(db *DB) *AuditStore {
+	return &AuditStore{db: db}
+}
+
+// Append stores entries in a single transaction.
+func (s *AuditStore) Append(ctx context.Context, entries []audit.Entry) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO audit_log (id, actor_id, action, resource_type, resource_id,
+		                       before_json, after_json, changes_json, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, e := range entries {
+		before, err := marshalAuditJSON(e.Before)
+		if err != nil {
+			return fmt.Errorf("marshal audit entry %s: %w", e.ID, err)
+		}
+		after, err := marshalAuditJSON(e.After)
+		if err != nil {
+			return fmt.Errorf("marshal audit entry %s: %w", e.ID, err)
+		}
+		changes, err := marshalAuditJSON(e.Changes)
+		if err != nil {
+			return fmt.Errorf("marshal audit entry %s: %w", e.ID, err)
+		}
+		if _, err := stmt.ExecContext(ctx, e.ID, e.ActorID, e.Action, e.ResourceType, e.ResourceID,
+			before, after, changes, e.CreatedAt); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// List returns entries matching the filter, newest first.
+func (s *AuditStore) List(ctx context.Context, filter audit.Filter) ([]audit.Entry, error) {
+	var where []string
+	var args []any
+	if filter.ActorID != "" {
+		where = append(where, "actor_id = ?")
+		args = append(args, filter.ActorID)
+	}
+	if filter.ResourceType != "" {
+		where = append(where, "resource_type = ?")
+		args = append(args, filter.ResourceType)
+	}
+	if filter.ResourceID != "" {
+		where = append(where, "resource_id = ?")
+		args = append(args, filter.ResourceID)
+	}
+
+	query := `
+		SELECT id, actor_id, action, resource_type, resource_id,
+		       before_json, after_json, changes_json, created_at
+		FROM audit_log`
+	if len(where) > 0 {
+		query += "\n\t\tWHERE " + strings.Join(where, " AND ")
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = -1 // SQLite: no limit
+	}
+	query += "\n\t\tORDER BY created_at DESC, rowid DESC\n\t\tLIMIT ? OFFSET ?"
+	args = append(args, limit, filter.Offset)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []audit.Entry
+	for rows.Next() {
+		var e audit.Entry
+		var before, after, changes sql.NullString
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.Action, &e.ResourceType, &e.ResourceID,
+			&before, &after, &changes, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := unmarshalAuditJSON(before, &e.Before); err != nil {
+			return nil, err
+		}
+		if err := unmarshalAuditJSON(after, &e.After); err != nil {
+			return nil, err
+		}
+		if err := unmarshalAuditJSON(changes, &e.Changes); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// marshalAuditJSON encodes v as JSON. Nil maps are stored as "null" and
+// decode back to nil.
+func marshalAuditJSON(v any) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+func unmarshalAuditJSON(s sql.NullString, v any) error {
+	if !s.Valid {
+		return nil
+	}
+	return json.Unmarshal([]byte(s.String), v)
+}
+
+// Ensure interface compliance.
+var _ ports.AuditStore = (*AuditStore)(nil)
@@ -0,0 +1,85 @@
+package sqlite_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/sqlite"
+	"github.com/artpar/apigate/domain/audit"
+)
+
+func TestAuditStore_AppendAndList(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store := sqlite.NewAuditStore(db)
+	ctx := context.Background()
+	base := time.Now().UTC().Truncate(time.Second)
+
+	update := audit.NewEntry("admin-1", audit.ActionUpdate, audit.ResourcePlan, "pro",
+		map[string]any{"Name": "Pro", "RateLimitPerMinute": float64(600)},
+		map[string]any{"Name": "Pro", "RateLimitPerMinute": float64(1200)}, base)
+	create := audit.NewEntry("admin-2", audit.ActionCreate, audit.ResourceUser, "user-1",
+		nil, map[string]any{"Email": "a@example.com"}, base.Add(time.Second))
+	remove := audit.NewEntry("admin-1", audit.ActionDelete, audit.ResourceUser, "user-1",
+		map[string]any{"Email": "a@example.com"}, nil, base.Add(2*time.Second))
+
+	if err := store.Append(ctx, []audit.Entry{update, create, remove}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	all, err := store.List(ctx, audit.Filter{})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(all) != 3 || all[0].ID != remove.ID || all[2].ID != update.ID {
+		t.Fatalf("List returned %d entries, want 3 newest first", len(all))
+	}
+
+	got := all[2]
+	if got.ActorID != "admin-1" || got.Action != audit.ActionUpdate || got.ResourceID != "pro" {
+		t.Errorf("entry = %+v", got)
+	}
+	if c := got.Changes["RateLimitPerMinute"]; c.Before != float64(600) || c.After != float64(1200) {
+		t.Errorf("changes = %v", got.Changes)
+	}
+	if got.Before["Name"] != "Pro" || !got.CreatedAt.Equal(base) {
+		t.Errorf("before = %v, created_at = %v", got.Before, got.CreatedAt)
+	}
+	if all[0].After != nil || all[1].Before != nil {
+		t.Error("missing snapshots should read back as nil")
+	}
+
+	byActor, _ := store.List(ctx, audit.Filter{ActorID: "admin-1"})
+	if len(byActor) != 2 {
+		t.Errorf("filter by actor: got %d, want 2", len(byActor))
+	}
+	byResource, _ := store.List(ctx, audit.Filter{ResourceType: audit.ResourceUser, ResourceID: "user-1"})
+	if len(byResource) != 2 {
+		t.Errorf("filter by resource: got %d, want 2", len(byResource))
+	}
+	page, _ := store.List(ctx, audit.Filter{Limit: 1, Offset: 1})
+	if len(page) != 1 || page[0].ID != create.ID {
+		t.Errorf("page = %v, want the second newest entry", page)
+	}
+}
+
+func TestAuditStore_AppendOnly(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store := sqlite.NewAuditStore(db)
+	ctx := context.Background()
+	entry := audit.NewEntry("admin", audit.ActionDelete, audit.ResourceKey, "key-1", nil, nil, time.Now().UTC())
+	if err := store.Append(ctx, []audit.Entry{entry}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	if _, err := db.ExecContext(ctx, `UPDATE audit_log SET actor_id = 'someone' WHERE id = ?`, entry.ID); err == nil {
+		t.Error("expected update of an audit entry to fail")
+	}
+	if _, err := db.ExecContext(ctx, `DELETE FROM audit_log WHERE id = ?`, entry.ID); err == nil {
+		t.Error("expected delete of an audit entry to fail")
+	}
+}
@@ -23,6 +23,13 @@ func NewInvoiceStore(db *DB) *InvoiceStore {
 
 // Create stores a new invoice.
 func (s *InvoiceStore) Create(ctx context.Context, inv billing.Invoice) error {
+	return insertInvoice(ctx, s.db, inv)
+}
+
+// insertInvoice inserts inv using db, which may be the database or a transaction.
+func insertInvoice(ctx context.Context, db interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}, inv billing.Invoice) error {
 	now := time.Now().UTC()
 	if inv.CreatedAt.IsZero() {
 		inv.CreatedAt = now
@@ -33,7 +40,7 @@ func (s *InvoiceStore) Create(ctx context.Context, inv billing.Invoice) error {
 		return err
 	}
 
-	_, err = s.db.ExecContext(ctx, `
+	_, err = db.ExecContext(ctx, `
 		INSERT INTO invoices (
 			id, user_id, provider, provider_id,
 			period_start, period_end, items,
@@ -54,6 +61,30 @@ func (s *InvoiceStore) Create(ctx context.Context, inv billing.Invoice) error {
 	return err
 }
 
+// Get retrieves an invoice by ID.
+func (s *InvoiceStore) Get(ctx context.Context, id string) (billing.Invoice, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, user_id, provider, provider_id,
+		       period_start, period_end, items,
+		       subtotal, tax, total, currency,
+		       status, due_date, paid_at, invoice_url, created_at
+		FROM invoices
+		WHERE id = ?
+	`, id)
+	if err != nil {
+		return billing.Invoice{}, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return billing.Invoice{}, err
+		}
+		return billing.Invoice{}, ErrNotFound
+	}
+	return scanInvoiceRow(rows)
+}
+
 // ListByUser returns invoices for a user.
 func (s *InvoiceStore) ListByUser(ctx context.Context, userID string, limit int) ([]billing.Invoice, error) {
 	rows, err := s.db.QueryContext(ctx, `
@@ -146,5 +177,75 @@ func scanInvoiceRow(rows *sql.Rows) (billing.Invoice, error) {
 	return inv, nil
 }
 
+// AddPendingItems records items for the subscription's next invoice.
+func (s *InvoiceStore) AddPendingItems(ctx context.Context, subscriptionID string, items []billing.InvoiceItem, at time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, item := range items {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO pending_invoice_items (
+				subscription_id, description, quantity, unit_price, amount, created_at
+			) VALUES (?, ?, ?, ?, ?, ?)
+		`, subscriptionID, item.Description, item.Quantity, item.UnitPrice, item.Amount, at); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ListPendingItems returns the uninvoiced items recorded before the given
+// time, oldest first.
+func (s *InvoiceStore) ListPendingItems(ctx context.Context, subscriptionID string, before time.Time) ([]billing.InvoiceItem, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT description, quantity, unit_price, amount
+		FROM pending_invoice_items
+		WHERE subscription_id = ? AND invoice_id IS NULL AND created_at < ?
+		ORDER BY created_at, id
+	`, subscriptionID, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []billing.InvoiceItem
+	for rows.Next() {
+		var item billing.InvoiceItem
+		if err := rows.Scan(&item.Description, &item.Quantity, &item.UnitPrice, &item.Amount); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// CreateInvoiceWithItems stores the invoice and marks the uninvoiced items
+// recorded before the given time as added to it, in a single transaction.
+func (s *InvoiceStore) CreateInvoiceWithItems(ctx context.Context, inv billing.Invoice, subscriptionID string, before time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertInvoice(ctx, tx, inv); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE pending_invoice_items
+		SET invoice_id = ?
+		WHERE subscription_id = ? AND invoice_id IS NULL AND created_at < ?
+	`, inv.ID, subscriptionID, before); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 // Ensure interface compliance.
-var _ ports.InvoiceStore = (*InvoiceStore)(nil)
+var (
+	_ ports.InvoiceStore            = (*InvoiceStore)(nil)
+	_ ports.PendingInvoiceItemStore = (*InvoiceStore)(nil)
+)
@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/artpar/apigate/domain/key"
@@ -24,7 +26,7 @@ func NewKeyStore(db *DB) *KeyStore {
 // Get retrieves keys matching a prefix.
 func (s *KeyStore) Get(ctx context.Context, prefix string) ([]key.Key, error) {
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, user_id, hash, prefix, name, scopes, quota_bypass, expires_at, revoked_at, created_at, last_used
+		SELECT id, user_id, hash, prefix, name, scopes, allowed_cidrs, signing_secret, rotated_from, quota_bypass, expires_at, revoked_at, created_at, last_used, group_id, monthly_quota, labels, last_used_ip
 		FROM api_keys
 		WHERE prefix = ?
 	`, prefix)
@@ -46,16 +48,59 @@ func (s *KeyStore) Get(ctx context.Context, prefix string) ([]key.Key, error) {
 
 // Create stores a new key.
 func (s *KeyStore) Create(ctx context.Context, k key.Key) error {
+	return insertKey(ctx, s.db, k)
+}
+
+// CreateBatch stores all keys in a single transaction. The batch is rejected
+// with ports.ErrDuplicateKeyPrefix if any prefix already exists or repeats.
+func (s *KeyStore) CreateBatch(ctx context.Context, keys []key.Key) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, k := range keys {
+		var exists bool
+		err := tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM api_keys WHERE prefix = ?)`, k.Prefix).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("%w: %s", ports.ErrDuplicateKeyPrefix, k.Prefix)
+		}
+		if err := insertKey(ctx, tx, k); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// insertKey inserts k using db, which may be the database or a transaction.
+func insertKey(ctx context.Context, db interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}, k key.Key) error {
 	scopes, err := json.Marshal(k.Scopes)
 	if err != nil {
 		return err
 	}
 
-	_, err = s.db.ExecContext(ctx, `
-		INSERT INTO api_keys (id, user_id, hash, prefix, name, scopes, quota_bypass, expires_at, revoked_at, created_at, last_used)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, k.ID, k.UserID, k.Hash, k.Prefix, k.Name, string(scopes), k.QuotaBypass,
-		nullTime(k.ExpiresAt), nullTime(k.RevokedAt), k.CreatedAt, nullTime(k.LastUsed))
+	allowedCIDRs, err := marshalStringSlice(k.AllowedCIDRs)
+	if err != nil {
+		return err
+	}
+
+	labels, err := marshalLabels(k.Labels)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO api_keys (id, user_id, hash, prefix, name, scopes, allowed_cidrs, signing_secret, rotated_from, quota_bypass, expires_at, revoked_at, created_at, last_used, group_id, monthly_quota, labels, last_used_ip)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, k.ID, k.UserID, k.Hash, k.Prefix, k.Name, string(scopes), allowedCIDRs, nullString(k.SigningSecret), nullString(k.RotatedFrom), k.QuotaBypass,
+		nullTime(k.ExpiresAt), nullTime(k.RevokedAt), k.CreatedAt, nullTime(k.LastUsed), nullString(k.GroupID), k.MonthlyQuota, labels, nullString(k.LastUsedIP))
 	return err
 }
 
@@ -80,7 +125,7 @@ func (s *KeyStore) Revoke(ctx context.Context, id string, at time.Time) error {
 // List returns all keys.
 func (s *KeyStore) List(ctx context.Context) ([]key.Key, error) {
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, user_id, hash, prefix, name, scopes, quota_bypass, expires_at, revoked_at, created_at, last_used
+		SELECT id, user_id, hash, prefix, name, scopes, allowed_cidrs, signing_secret, rotated_from, quota_bypass, expires_at, revoked_at, created_at, last_used, group_id, monthly_quota, labels, last_used_ip
 		FROM api_keys
 		ORDER BY created_at DESC
 	`)
@@ -103,7 +148,7 @@ func (s *KeyStore) List(ctx context.Context) ([]key.Key, error) {
 // ListByUser returns all keys for a user.
 func (s *KeyStore) ListByUser(ctx context.Context, userID string) ([]key.Key, error) {
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, user_id, hash, prefix, name, scopes, quota_bypass, expires_at, revoked_at, created_at, last_used
+		SELECT id, user_id, hash, prefix, name, scopes, allowed_cidrs, signing_secret, rotated_from, quota_bypass, expires_at, revoked_at, created_at, last_used, group_id, monthly_quota, labels, last_used_ip
 		FROM api_keys
 		WHERE user_id = ?
 		ORDER BY created_at DESC
@@ -124,6 +169,30 @@ func (s *KeyStore) ListByUser(ctx context.Context, userID string) ([]key.Key, er
 	return keys, rows.Err()
 }
 
+// ListByGroup returns all keys owned by a group.
+func (s *KeyStore) ListByGroup(ctx context.Context, groupID string) ([]key.Key, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, user_id, hash, prefix, name, scopes, allowed_cidrs, signing_secret, rotated_from, quota_bypass, expires_at, revoked_at, created_at, last_used, group_id, monthly_quota, labels, last_used_ip
+		FROM api_keys
+		WHERE group_id = ?
+		ORDER BY created_at DESC
+	`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []key.Key
+	for rows.Next() {
+		k, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
 // UpdateLastUsed updates the last used timestamp.
 func (s *KeyStore) UpdateLastUsed(ctx context.Context, id string, at time.Time) error {
 	_, err := s.db.ExecContext(ctx, `
@@ -132,6 +201,147 @@ func (s *KeyStore) UpdateLastUsed(ctx context.Context, id string, at time.Time)
 	return err
 }
 
+// RecordUses sets the last-used time and IP of many keys in one transaction.
+func (s *KeyStore) RecordUses(ctx context.Context, uses []key.Use) error {
+	if len(uses) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		UPDATE api_keys SET last_used = ?, last_used_ip = ?
+		WHERE id = ? AND (last_used IS NULL OR last_used <= ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, u := range uses {
+		if _, err := stmt.ExecContext(ctx, u.At, nullString(u.IP), u.KeyID, u.At); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ListExpiringUnwarned returns unrevoked keys expiring in (now, before] that
+// have not been sent an expiry warning.
+func (s *KeyStore) ListExpiringUnwarned(ctx context.Context, now, before time.Time) ([]key.Key, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, user_id, hash, prefix, name, scopes, allowed_cidrs, signing_secret, rotated_from, quota_bypass, expires_at, revoked_at, created_at, last_used, group_id, monthly_quota, labels, last_used_ip
+		FROM api_keys
+		WHERE revoked_at IS NULL AND expiry_warned_at IS NULL
+		  AND expires_at > ? AND expires_at <= ?
+		ORDER BY expires_at ASC
+	`, now.UTC(), before.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []key.Key
+	for rows.Next() {
+		k, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// MarkExpiryWarned records that the expiry warning for a key was sent.
+func (s *KeyStore) MarkExpiryWarned(ctx context.Context, id string, at time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE api_keys SET expiry_warned_at = ? WHERE id = ?
+	`, at, id)
+	return err
+}
+
+// SetMonthlyQuota sets a key's own quota; 0 removes it.
+func (s *KeyStore) SetMonthlyQuota(ctx context.Context, id string, quota int64) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE api_keys SET monthly_quota = ? WHERE id = ?
+	`, quota, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetLabels replaces a key's labels; nil removes them all.
+func (s *KeyStore) SetLabels(ctx context.Context, id string, labels map[string]string) error {
+	encoded, err := marshalLabels(labels)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE api_keys SET labels = ? WHERE id = ?
+	`, encoded, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListByUserLabels returns the user's keys matching the label selector.
+func (s *KeyStore) ListByUserLabels(ctx context.Context, userID string, sel key.LabelSelector) ([]key.Key, error) {
+	where := []string{"user_id = ?"}
+	args := []any{userID}
+	for name, value := range sel {
+		path := `$."` + name + `"`
+		if value == "" {
+			where = append(where, "json_type(labels, ?) IS NOT NULL")
+			args = append(args, path)
+		} else {
+			where = append(where, "json_extract(labels, ?) = ?")
+			args = append(args, path, value)
+		}
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, user_id, hash, prefix, name, scopes, allowed_cidrs, signing_secret, rotated_from, quota_bypass, expires_at, revoked_at, created_at, last_used, group_id, monthly_quota, labels, last_used_ip
+		FROM api_keys
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY created_at DESC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []key.Key
+	for rows.Next() {
+		k, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
 // Update modifies an existing key.
 func (s *KeyStore) Update(ctx context.Context, k key.Key) error {
 	scopes, err := json.Marshal(k.Scopes)
@@ -139,11 +349,21 @@ func (s *KeyStore) Update(ctx context.Context, k key.Key) error {
 		return err
 	}
 
+	allowedCIDRs, err := marshalStringSlice(k.AllowedCIDRs)
+	if err != nil {
+		return err
+	}
+
+	labels, err := marshalLabels(k.Labels)
+	if err != nil {
+		return err
+	}
+
 	result, err := s.db.ExecContext(ctx, `
 		UPDATE api_keys
-		SET name = ?, scopes = ?, quota_bypass = ?, expires_at = ?, revoked_at = ?, last_used = ?
+		SET name = ?, scopes = ?, allowed_cidrs = ?, signing_secret = ?, quota_bypass = ?, monthly_quota = ?, labels = ?, expires_at = ?, revoked_at = ?, last_used = ?, last_used_ip = ?
 		WHERE id = ?
-	`, k.Name, string(scopes), k.QuotaBypass, nullTime(k.ExpiresAt), nullTime(k.RevokedAt), nullTime(k.LastUsed), k.ID)
+	`, k.Name, string(scopes), allowedCIDRs, nullString(k.SigningSecret), k.QuotaBypass, k.MonthlyQuota, labels, nullTime(k.ExpiresAt), nullTime(k.RevokedAt), nullTime(k.LastUsed), nullString(k.LastUsedIP), k.ID)
 	if err != nil {
 		return err
 	}
@@ -160,7 +380,7 @@ func (s *KeyStore) Update(ctx context.Context, k key.Key) error {
 // GetByID retrieves a key by ID.
 func (s *KeyStore) GetByID(ctx context.Context, id string) (key.Key, error) {
 	row := s.db.QueryRowContext(ctx, `
-		SELECT id, user_id, hash, prefix, name, scopes, quota_bypass, expires_at, revoked_at, created_at, last_used
+		SELECT id, user_id, hash, prefix, name, scopes, allowed_cidrs, signing_secret, rotated_from, quota_bypass, expires_at, revoked_at, created_at, last_used, group_id, monthly_quota, labels, last_used_ip
 		FROM api_keys
 		WHERE id = ?
 	`, id)
@@ -185,13 +405,13 @@ func (s *KeyStore) Delete(ctx context.Context, id string) error {
 
 func scanKey(rows *sql.Rows) (key.Key, error) {
 	var k key.Key
-	var scopes sql.NullString
+	var scopes, allowedCIDRs, signingSecret, rotatedFrom, groupID, labels, lastUsedIP sql.NullString
 	var quotaBypass sql.NullBool
 	var expiresAt, revokedAt, lastUsed sql.NullTime
 
 	err := rows.Scan(
-		&k.ID, &k.UserID, &k.Hash, &k.Prefix, &k.Name, &scopes, &quotaBypass,
-		&expiresAt, &revokedAt, &k.CreatedAt, &lastUsed,
+		&k.ID, &k.UserID, &k.Hash, &k.Prefix, &k.Name, &scopes, &allowedCIDRs, &signingSecret, &rotatedFrom, &quotaBypass,
+		&expiresAt, &revokedAt, &k.CreatedAt, &lastUsed, &groupID, &k.MonthlyQuota, &labels, &lastUsedIP,
 	)
 	if err != nil {
 		return key.Key{}, err
@@ -203,6 +423,27 @@ func scanKey(rows *sql.Rows) (key.Key, error) {
 		}
 	}
 
+	if allowedCIDRs.Valid && allowedCIDRs.String != "" {
+		if err := json.Unmarshal([]byte(allowedCIDRs.String), &k.AllowedCIDRs); err != nil {
+			return key.Key{}, err
+		}
+	}
+
+	if labels.Valid && labels.String != "" {
+		if err := json.Unmarshal([]byte(labels.String), &k.Labels); err != nil {
+			return key.Key{}, err
+		}
+	}
+
+	if signingSecret.Valid {
+		k.SigningSecret = signingSecret.String
+	}
+	if rotatedFrom.Valid {
+		k.RotatedFrom = rotatedFrom.String
+	}
+	if groupID.Valid {
+		k.GroupID = groupID.String
+	}
 	if quotaBypass.Valid {
 		k.QuotaBypass = quotaBypass.Bool
 	}
@@ -215,19 +456,22 @@ func scanKey(rows *sql.Rows) (key.Key, error) {
 	if lastUsed.Valid {
 		k.LastUsed = &lastUsed.Time
 	}
+	if lastUsedIP.Valid {
+		k.LastUsedIP = lastUsedIP.String
+	}
 
 	return k, nil
 }
 
 func scanKeyRow(row *sql.Row) (key.Key, error) {
 	var k key.Key
-	var scopes sql.NullString
+	var scopes, allowedCIDRs, signingSecret, rotatedFrom, groupID, labels, lastUsedIP sql.NullString
 	var quotaBypass sql.NullBool
 	var expiresAt, revokedAt, lastUsed sql.NullTime
 
 	err := row.Scan(
-		&k.ID, &k.UserID, &k.Hash, &k.Prefix, &k.Name, &scopes, &quotaBypass,
-		&expiresAt, &revokedAt, &k.CreatedAt, &lastUsed,
+		&k.ID, &k.UserID, &k.Hash, &k.Prefix, &k.Name, &scopes, &allowedCIDRs, &signingSecret, &rotatedFrom, &quotaBypass,
+		&expiresAt, &revokedAt, &k.CreatedAt, &lastUsed, &groupID, &k.MonthlyQuota, &labels, &lastUsedIP,
 	)
 	if errors.Is(err, sql.ErrNoRows) {
 		return key.Key{}, ErrNotFound
@@ -242,6 +486,27 @@ func scanKeyRow(row *sql.Row) (key.Key, error) {
 		}
 	}
 
+	if allowedCIDRs.Valid && allowedCIDRs.String != "" {
+		if err := json.Unmarshal([]byte(allowedCIDRs.String), &k.AllowedCIDRs); err != nil {
+			return key.Key{}, err
+		}
+	}
+
+	if labels.Valid && labels.String != "" {
+		if err := json.Unmarshal([]byte(labels.String), &k.Labels); err != nil {
+			return key.Key{}, err
+		}
+	}
+
+	if signingSecret.Valid {
+		k.SigningSecret = signingSecret.String
+	}
+	if rotatedFrom.Valid {
+		k.RotatedFrom = rotatedFrom.String
+	}
+	if groupID.Valid {
+		k.GroupID = groupID.String
+	}
 	if quotaBypass.Valid {
 		k.QuotaBypass = quotaBypass.Bool
 	}
@@ -254,10 +519,25 @@ func scanKeyRow(row *sql.Row) (key.Key, error) {
 	if lastUsed.Valid {
 		k.LastUsed = &lastUsed.Time
 	}
+	if lastUsedIP.Valid {
+		k.LastUsedIP = lastUsedIP.String
+	}
 
 	return k, nil
 }
 
+// marshalLabels encodes key labels as a JSON object; no labels is NULL.
+func marshalLabels(labels map[string]string) (sql.NullString, error) {
+	if len(labels) == 0 {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(labels)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
 // nullTime converts a *time.Time to sql.NullTime.
 func nullTime(t *time.Time) sql.NullTime {
 	if t == nil {
@@ -267,4 +547,12 @@ func nullTime(t *time.Time) sql.NullTime {
 }
 
 // Ensure interface compliance.
-var _ ports.KeyStore = (*KeyStore)(nil)
+var (
+	_ ports.KeyStore       = (*KeyStore)(nil)
+	_ ports.KeyExpiryStore = (*KeyStore)(nil)
+	_ ports.KeyBatchStore  = (*KeyStore)(nil)
+	_ ports.GroupKeyStore  = (*KeyStore)(nil)
+	_ ports.KeyQuotaStore  = (*KeyStore)(nil)
+	_ ports.KeyLabelStore  = (*KeyStore)(nil)
+	_ ports.KeyUseStore    = (*KeyStore)(nil)
+)
@@ -0,0 +1,5 @@
+-- Migration: Add per-route request/response body size limits
+-- 0 means the route uses the global proxy.max_request_body / proxy.max_response_body settings
+
+ALTER TABLE routes ADD COLUMN max_request_body INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE routes ADD COLUMN max_response_body INTEGER NOT NULL DEFAULT 0;
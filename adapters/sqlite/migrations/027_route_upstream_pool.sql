@@ -0,0 +1,4 @@
+-- Migration: Add weighted upstream pools to routes
+-- JSON array of {"upstream_id": "...", "weight": N}; when set, overrides upstream_id
+
+ALTER TABLE routes ADD COLUMN upstreams TEXT;
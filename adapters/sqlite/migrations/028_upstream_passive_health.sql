@@ -0,0 +1,6 @@
+-- Migration: Add passive health checking thresholds to upstreams
+-- An upstream is ejected from route pools for ejection_cooldown_ms after
+-- failure_threshold consecutive 5xx/connection errors (0 disables ejection)
+
+ALTER TABLE upstreams ADD COLUMN failure_threshold INTEGER NOT NULL DEFAULT 5;
+ALTER TABLE upstreams ADD COLUMN ejection_cooldown_ms INTEGER NOT NULL DEFAULT 30000;
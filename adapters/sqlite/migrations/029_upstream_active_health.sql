@@ -0,0 +1,8 @@
+-- Migration: Add active health check probes to upstreams
+-- Upstreams with a non-empty health_check_path are probed every
+-- health_check_interval_ms and taken out of rotation while probes fail
+
+ALTER TABLE upstreams ADD COLUMN health_check_path TEXT NOT NULL DEFAULT '';
+ALTER TABLE upstreams ADD COLUMN health_check_interval_ms INTEGER NOT NULL DEFAULT 10000;
+ALTER TABLE upstreams ADD COLUMN health_check_timeout_ms INTEGER NOT NULL DEFAULT 2000;
+ALTER TABLE upstreams ADD COLUMN health_check_expected_status INTEGER NOT NULL DEFAULT 200;
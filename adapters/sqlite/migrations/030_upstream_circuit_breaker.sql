@@ -0,0 +1,10 @@
+-- Migration: Add circuit breaker settings to upstreams
+-- A circuit opens when the failure rate within circuit_window_ms reaches
+-- circuit_failure_rate (0 disables), rejects requests for circuit_open_ms,
+-- then closes after circuit_half_open_trials successful trial requests
+
+ALTER TABLE upstreams ADD COLUMN circuit_failure_rate REAL NOT NULL DEFAULT 0;
+ALTER TABLE upstreams ADD COLUMN circuit_window_ms INTEGER NOT NULL DEFAULT 10000;
+ALTER TABLE upstreams ADD COLUMN circuit_min_requests INTEGER NOT NULL DEFAULT 10;
+ALTER TABLE upstreams ADD COLUMN circuit_open_ms INTEGER NOT NULL DEFAULT 30000;
+ALTER TABLE upstreams ADD COLUMN circuit_half_open_trials INTEGER NOT NULL DEFAULT 1;
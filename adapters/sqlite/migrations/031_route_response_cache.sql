@@ -0,0 +1,6 @@
+-- Migration: Add per-route response caching
+-- GET/HEAD responses are cached for cache_ttl_ms (0 disables caching), keyed by
+-- method, path, query and the values of the headers listed in cache_key_headers
+
+ALTER TABLE routes ADD COLUMN cache_ttl_ms INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE routes ADD COLUMN cache_key_headers TEXT;
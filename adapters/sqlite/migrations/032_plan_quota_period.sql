@@ -0,0 +1,4 @@
+-- Add quota_period to plans table
+-- quota_period: "calendar_month" (default), "calendar_week" or "rolling_30d"
+
+ALTER TABLE plans ADD COLUMN quota_period TEXT NOT NULL DEFAULT 'calendar_month';
@@ -0,0 +1,8 @@
+-- Token bucket rate limiting
+-- rate_limit_burst: bucket size per plan (0 = rate limit plus the global burst setting)
+-- tokens/last_refill: token bucket state per key
+
+ALTER TABLE plans ADD COLUMN rate_limit_burst INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE rate_limit_state ADD COLUMN tokens REAL NOT NULL DEFAULT 0;
+ALTER TABLE rate_limit_state ADD COLUMN last_refill DATETIME;
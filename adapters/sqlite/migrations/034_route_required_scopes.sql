@@ -0,0 +1,5 @@
+-- Migration: Add per-route required API key scopes
+-- Requests whose key does not hold every scope in required_scopes (a JSON array)
+-- are rejected with 403 insufficient_scope
+
+ALTER TABLE routes ADD COLUMN required_scopes TEXT;
@@ -0,0 +1,5 @@
+-- Migration: Add per-key source IP allowlist
+-- allowed_cidrs is a JSON array of CIDRs or single addresses; requests from
+-- other client IPs are rejected with 403 ip_not_allowed (NULL = no restriction)
+
+ALTER TABLE api_keys ADD COLUMN allowed_cidrs TEXT;
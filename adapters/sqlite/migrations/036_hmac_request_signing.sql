@@ -0,0 +1,6 @@
+-- Migration: Add HMAC request signing
+-- Routes with auth_method 'hmac' require requests signed with the key's
+-- signing_secret instead of sending the API key itself ('' = api_key)
+
+ALTER TABLE routes ADD COLUMN auth_method TEXT NOT NULL DEFAULT '';
+ALTER TABLE api_keys ADD COLUMN signing_secret TEXT;
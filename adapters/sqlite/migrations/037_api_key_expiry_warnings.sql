@@ -0,0 +1,5 @@
+-- Migration: Track API key expiry warning emails
+-- expiry_warned_at is set once the owner has been warned that the key is about
+-- to expire, so each key gets at most one warning (NULL = not warned)
+
+ALTER TABLE api_keys ADD COLUMN expiry_warned_at DATETIME;
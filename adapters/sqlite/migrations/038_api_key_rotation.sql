@@ -0,0 +1,5 @@
+-- Migration: Link rotated API keys
+-- rotated_from holds the ID of the key a replacement was issued for, so usage
+-- of both keys during the rotation grace period is attributed to the same owner
+
+ALTER TABLE api_keys ADD COLUMN rotated_from TEXT;
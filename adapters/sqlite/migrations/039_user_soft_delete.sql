@@ -0,0 +1,5 @@
+-- Migration: Soft-delete users
+-- deleted_at marks a user as deleted without removing the row, so usage
+-- history keeps its references and the account can be restored (NULL = active)
+
+ALTER TABLE users ADD COLUMN deleted_at DATETIME;
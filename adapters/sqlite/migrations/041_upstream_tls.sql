@@ -0,0 +1,9 @@
+-- Migration: Add TLS settings to upstreams
+-- Upstreams behind a private CA or requiring mutual TLS carry their own CA
+-- bundle, client certificate and key, and SNI override ('' = defaults)
+
+ALTER TABLE upstreams ADD COLUMN tls_ca_cert TEXT NOT NULL DEFAULT '';
+ALTER TABLE upstreams ADD COLUMN tls_client_cert TEXT NOT NULL DEFAULT '';
+ALTER TABLE upstreams ADD COLUMN tls_client_key_encrypted BLOB;
+ALTER TABLE upstreams ADD COLUMN tls_server_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE upstreams ADD COLUMN tls_insecure_skip_verify INTEGER NOT NULL DEFAULT 0;
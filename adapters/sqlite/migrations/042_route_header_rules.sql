@@ -0,0 +1,6 @@
+-- Migration: Add header rules to routes
+-- Ordered set/add/remove/rename rules (JSON) applied to requests before they
+-- are sent upstream and to responses before they are returned
+
+ALTER TABLE routes ADD COLUMN request_headers TEXT;
+ALTER TABLE routes ADD COLUMN response_headers TEXT;
@@ -0,0 +1,5 @@
+-- Migration: Add response URL rewriting to routes
+-- When set, the upstream's base URL is replaced with the gateway's public URL
+-- in JSON and HTML response bodies
+
+ALTER TABLE routes ADD COLUMN rewrite_response_urls INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,7 @@
+-- Migration: Add per-route upstream timeouts
+-- Dial, response header and overall request timeouts in milliseconds;
+-- 0 falls back to the upstream's timeout and the global upstream settings
+
+ALTER TABLE routes ADD COLUMN dial_timeout_ms INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE routes ADD COLUMN response_header_timeout_ms INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE routes ADD COLUMN request_timeout_ms INTEGER NOT NULL DEFAULT 0;
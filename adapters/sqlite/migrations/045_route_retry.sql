@@ -0,0 +1,4 @@
+-- Migration: Add retry policy to routes
+-- JSON route.RetryPolicy; NULL = failed upstream requests are not retried
+
+ALTER TABLE routes ADD COLUMN retry TEXT;
@@ -0,0 +1,4 @@
+-- Migration: Add CORS policy to routes
+-- JSON route.CORSPolicy; NULL = CORS headers are left to the upstream
+
+ALTER TABLE routes ADD COLUMN cors TEXT;
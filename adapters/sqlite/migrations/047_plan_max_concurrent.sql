@@ -0,0 +1,4 @@
+-- Per-plan concurrency limit
+-- max_concurrent: in-flight requests allowed per API key (0 = unlimited)
+
+ALTER TABLE plans ADD COLUMN max_concurrent INTEGER NOT NULL DEFAULT 0;
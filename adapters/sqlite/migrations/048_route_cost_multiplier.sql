@@ -0,0 +1,6 @@
+-- Migration: Add cost multipliers to routes
+-- cost_multiplier weights each request's metered value (0 = 1)
+-- method_cost_multipliers is a JSON object of per-method overrides, e.g. {"POST": 5}
+
+ALTER TABLE routes ADD COLUMN cost_multiplier REAL NOT NULL DEFAULT 0;
+ALTER TABLE routes ADD COLUMN method_cost_multipliers TEXT;
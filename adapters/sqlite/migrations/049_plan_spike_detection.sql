@@ -0,0 +1,8 @@
+-- Per-plan usage spike detection
+-- spike_threshold: alert when a key's recent rate exceeds its baseline by this factor (0 = disabled)
+-- spike_min_requests: requests in the recent window before a spike is considered
+-- spike_throttle_rpm: requests per minute allowed for a spiking key (0 = alert only)
+
+ALTER TABLE plans ADD COLUMN spike_threshold REAL NOT NULL DEFAULT 0;
+ALTER TABLE plans ADD COLUMN spike_min_requests INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE plans ADD COLUMN spike_throttle_rpm INTEGER NOT NULL DEFAULT 0;
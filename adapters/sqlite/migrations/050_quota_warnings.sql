@@ -0,0 +1,10 @@
+-- Quota warning emails sent per user, quota period and threshold
+-- threshold: percent of the quota (e.g. 80, 100)
+
+CREATE TABLE IF NOT EXISTS quota_warnings (
+    user_id TEXT NOT NULL,
+    period_start DATETIME NOT NULL,
+    threshold INTEGER NOT NULL,
+    sent_at DATETIME NOT NULL,
+    PRIMARY KEY (user_id, period_start, threshold)
+);
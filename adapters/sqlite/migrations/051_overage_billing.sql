@@ -0,0 +1,13 @@
+-- Overage billing: plans may keep serving requests past the quota and bill
+-- the excess at overage_price through the payment provider.
+ALTER TABLE plans ADD COLUMN allow_overage INTEGER NOT NULL DEFAULT 0;
+
+-- Overage units already reported to the payment provider per user and quota
+-- period, so each unit is billed once.
+CREATE TABLE IF NOT EXISTS overage_reports (
+    user_id TEXT NOT NULL,
+    period_start DATETIME NOT NULL,
+    reported_units INTEGER NOT NULL DEFAULT 0,
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (user_id, period_start)
+);
@@ -0,0 +1,15 @@
+-- Invoice items waiting for a subscription's next invoice, such as the
+-- proration charge and credit recorded when a plan changes mid-period.
+CREATE TABLE IF NOT EXISTS pending_invoice_items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    subscription_id TEXT NOT NULL,
+    description TEXT NOT NULL,
+    quantity INTEGER NOT NULL DEFAULT 1,
+    unit_price INTEGER NOT NULL DEFAULT 0,
+    amount INTEGER NOT NULL DEFAULT 0,
+    invoice_id TEXT,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_pending_invoice_items_subscription
+    ON pending_invoice_items(subscription_id, invoice_id);
@@ -0,0 +1,4 @@
+-- Dunning: failed payments move a subscription to past_due, send reminders
+-- on a schedule and finally suspend it (status unpaid).
+ALTER TABLE subscriptions ADD COLUMN dunning_started_at DATETIME;
+ALTER TABLE subscriptions ADD COLUMN dunning_step INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,6 @@
+-- Migration: Add prefix stripping and regex path rewriting to routes
+-- strip_prefix: removed from the start of the path before forwarding; NULL = none
+-- rewrite_path: JSON route.PathRewriteRule; NULL = no regex rewrite
+
+ALTER TABLE routes ADD COLUMN strip_prefix TEXT;
+ALTER TABLE routes ADD COLUMN rewrite_path TEXT;
@@ -0,0 +1,6 @@
+-- Migration: Add query-parameter routing conditions to routes
+-- query_match: JSON array of route.QueryMatch; NULL = no query conditions
+-- strip_query: JSON array of query parameter names removed before forwarding; NULL = none
+
+ALTER TABLE routes ADD COLUMN query_match TEXT;
+ALTER TABLE routes ADD COLUMN strip_query TEXT;
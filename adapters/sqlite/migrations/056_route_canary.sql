@@ -0,0 +1,4 @@
+-- Migration: Add hash-based canary routing to routes
+-- canary: JSON route.CanaryPolicy; NULL = no canary
+
+ALTER TABLE routes ADD COLUMN canary TEXT;
@@ -0,0 +1,4 @@
+-- Migration: Add shadow traffic mirroring to routes
+-- mirror_upstream_id: upstream receiving a copy of each request; NULL = no mirroring
+
+ALTER TABLE routes ADD COLUMN mirror_upstream_id TEXT;
@@ -0,0 +1,4 @@
+-- Migration: Add request body schema validation to routes
+-- request_schema: JSON Schema that POST/PUT/PATCH bodies must satisfy; NULL = no validation
+
+ALTER TABLE routes ADD COLUMN request_schema TEXT;
@@ -0,0 +1,8 @@
+-- Migration: TOTP two-factor authentication for portal users
+-- totp_secret: base32 TOTP secret, set at enrollment (NULL = never enrolled)
+-- totp_enabled: 1 once the user has confirmed a code and login requires it
+-- recovery_codes: JSON array of SHA-256 hashes of unused recovery codes
+
+ALTER TABLE users ADD COLUMN totp_secret TEXT;
+ALTER TABLE users ADD COLUMN totp_enabled INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN recovery_codes TEXT;
@@ -0,0 +1,5 @@
+-- Migration: Add per-key quota override
+-- monthly_quota caps a single key's usage per plan quota period, alongside
+-- the plan quota (the lower of the two applies). 0 = plan quota only.
+
+ALTER TABLE api_keys ADD COLUMN monthly_quota INTEGER NOT NULL DEFAULT 0;
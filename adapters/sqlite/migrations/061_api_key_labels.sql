@@ -0,0 +1,5 @@
+-- Migration: Add API key labels
+-- labels is a JSON object of name=value tags (e.g. {"env": "prod"}) used to
+-- organize keys and filter key lists. NULL = no labels.
+
+ALTER TABLE api_keys ADD COLUMN labels TEXT;
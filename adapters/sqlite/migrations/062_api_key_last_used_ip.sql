@@ -0,0 +1,4 @@
+-- Migration: Track the client IP of each API key's last use
+-- last_used_ip is written together with last_used. NULL = unknown.
+
+ALTER TABLE api_keys ADD COLUMN last_used_ip TEXT;
@@ -0,0 +1,5 @@
+-- Migration: reject replayed TOTP codes
+-- totp_last_step: time step of the last accepted TOTP code; codes from that
+-- step or earlier are refused, so a code can only be used once
+
+ALTER TABLE users ADD COLUMN totp_last_step INTEGER NOT NULL DEFAULT 0;
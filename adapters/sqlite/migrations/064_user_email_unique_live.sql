@@ -0,0 +1,35 @@
+-- Migration: let deleted users' emails be reused
+-- email was UNIQUE across all rows, so a soft-deleted user kept their email
+-- and nobody could sign up with it again. Only live users (deleted_at IS NULL)
+-- need unique emails. SQLite can't drop a column constraint, so the table is
+-- recreated without it.
+
+CREATE TABLE users_new (
+    id TEXT PRIMARY KEY,
+    email TEXT NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    stripe_id TEXT,
+    plan_id TEXT NOT NULL DEFAULT 'free',
+    status TEXT NOT NULL DEFAULT 'active',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    password_hash BLOB,
+    deleted_at DATETIME,
+    totp_secret TEXT,
+    totp_enabled INTEGER NOT NULL DEFAULT 0,
+    recovery_codes TEXT,
+    totp_last_step INTEGER NOT NULL DEFAULT 0
+);
+
+INSERT INTO users_new
+    SELECT id, email, name, stripe_id, plan_id, status, created_at, updated_at,
+           password_hash, deleted_at, totp_secret, totp_enabled, recovery_codes, totp_last_step
+    FROM users;
+
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+
+CREATE UNIQUE INDEX idx_users_email_live ON users(email) WHERE deleted_at IS NULL;
+CREATE INDEX idx_users_email ON users(email);
+CREATE INDEX idx_users_stripe_id ON users(stripe_id);
+CREATE INDEX idx_users_status ON users(status);
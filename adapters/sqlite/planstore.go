@@ -20,11 +20,13 @@ func NewPlanStore(db *DB) *PlanStore {
 // List returns all enabled plans.
 func (s *PlanStore) List(ctx context.Context) ([]ports.Plan, error) {
 	rows, err := s.db.DB.QueryContext(ctx, `
-		SELECT id, name, COALESCE(description, ''), rate_limit_per_minute, requests_per_month,
+		SELECT id, name, COALESCE(description, ''), rate_limit_per_minute, COALESCE(rate_limit_burst, 0), COALESCE(max_concurrent, 0), requests_per_month,
+			   COALESCE(spike_threshold, 0), COALESCE(spike_min_requests, 0), COALESCE(spike_throttle_rpm, 0), COALESCE(allow_overage, 0),
 			   price_monthly, overage_price, COALESCE(stripe_price_id, ''),
 			   COALESCE(paddle_price_id, ''), COALESCE(lemon_variant_id, ''),
 			   is_default, enabled,
-			   COALESCE(meter_type, 'requests'), COALESCE(estimated_cost_per_req, 1.0)
+			   COALESCE(meter_type, 'requests'), COALESCE(estimated_cost_per_req, 1.0),
+			   COALESCE(quota_period, 'calendar_month')
 		FROM plans WHERE enabled = 1
 		ORDER BY price_monthly ASC
 	`)
@@ -36,16 +38,18 @@ func (s *PlanStore) List(ctx context.Context) ([]ports.Plan, error) {
 	var plans []ports.Plan
 	for rows.Next() {
 		var p ports.Plan
-		var meterType string
+		var meterType, quotaPeriod string
 		if err := rows.Scan(
-			&p.ID, &p.Name, &p.Description, &p.RateLimitPerMinute, &p.RequestsPerMonth,
+			&p.ID, &p.Name, &p.Description, &p.RateLimitPerMinute, &p.RateLimitBurst, &p.MaxConcurrent, &p.RequestsPerMonth,
+			&p.SpikeThreshold, &p.SpikeMinRequests, &p.SpikeThrottleRPM, &p.AllowOverage,
 			&p.PriceMonthly, &p.OveragePrice, &p.StripePriceID,
 			&p.PaddlePriceID, &p.LemonVariantID, &p.IsDefault, &p.Enabled,
-			&meterType, &p.EstimatedCostPerReq,
+			&meterType, &p.EstimatedCostPerReq, &quotaPeriod,
 		); err != nil {
 			continue
 		}
 		p.MeterType = ports.MeterType(meterType)
+		p.QuotaPeriod = ports.QuotaPeriod(quotaPeriod)
 		plans = append(plans, p)
 	}
 	return plans, nil
@@ -54,24 +58,28 @@ func (s *PlanStore) List(ctx context.Context) ([]ports.Plan, error) {
 // Get retrieves a plan by ID.
 func (s *PlanStore) Get(ctx context.Context, id string) (ports.Plan, error) {
 	var p ports.Plan
-	var meterType string
+	var meterType, quotaPeriod string
 	err := s.db.DB.QueryRowContext(ctx, `
-		SELECT id, name, COALESCE(description, ''), rate_limit_per_minute, requests_per_month,
+		SELECT id, name, COALESCE(description, ''), rate_limit_per_minute, COALESCE(rate_limit_burst, 0), COALESCE(max_concurrent, 0), requests_per_month,
+			   COALESCE(spike_threshold, 0), COALESCE(spike_min_requests, 0), COALESCE(spike_throttle_rpm, 0), COALESCE(allow_overage, 0),
 			   price_monthly, overage_price, COALESCE(stripe_price_id, ''),
 			   COALESCE(paddle_price_id, ''), COALESCE(lemon_variant_id, ''),
 			   is_default, enabled,
-			   COALESCE(meter_type, 'requests'), COALESCE(estimated_cost_per_req, 1.0)
+			   COALESCE(meter_type, 'requests'), COALESCE(estimated_cost_per_req, 1.0),
+			   COALESCE(quota_period, 'calendar_month')
 		FROM plans WHERE id = ?
 	`, id).Scan(
-		&p.ID, &p.Name, &p.Description, &p.RateLimitPerMinute, &p.RequestsPerMonth,
+		&p.ID, &p.Name, &p.Description, &p.RateLimitPerMinute, &p.RateLimitBurst, &p.MaxConcurrent, &p.RequestsPerMonth,
+		&p.SpikeThreshold, &p.SpikeMinRequests, &p.SpikeThrottleRPM, &p.AllowOverage,
 		&p.PriceMonthly, &p.OveragePrice, &p.StripePriceID,
 		&p.PaddlePriceID, &p.LemonVariantID, &p.IsDefault, &p.Enabled,
-		&meterType, &p.EstimatedCostPerReq,
+		&meterType, &p.EstimatedCostPerReq, &quotaPeriod,
 	)
 	if err == sql.ErrNoRows {
 		return p, sql.ErrNoRows
 	}
 	p.MeterType = ports.MeterType(meterType)
+	p.QuotaPeriod = ports.QuotaPeriod(quotaPeriod)
 	return p, err
 }
 
@@ -85,14 +93,21 @@ func (s *PlanStore) Create(ctx context.Context, p ports.Plan) error {
 	if estimatedCost <= 0 {
 		estimatedCost = 1.0
 	}
+	quotaPeriod := string(p.QuotaPeriod)
+	if quotaPeriod == "" {
+		quotaPeriod = "calendar_month"
+	}
 	_, err := s.db.DB.ExecContext(ctx, `
-		INSERT INTO plans (id, name, description, rate_limit_per_minute, requests_per_month,
+		INSERT INTO plans (id, name, description, rate_limit_per_minute, rate_limit_burst, max_concurrent, requests_per_month,
+						   spike_threshold, spike_min_requests, spike_throttle_rpm, allow_overage,
 						   price_monthly, overage_price, stripe_price_id, paddle_price_id,
-						   lemon_variant_id, is_default, enabled, meter_type, estimated_cost_per_req)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, p.ID, p.Name, p.Description, p.RateLimitPerMinute, p.RequestsPerMonth,
+						   lemon_variant_id, is_default, enabled, meter_type, estimated_cost_per_req,
+						   quota_period)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, p.ID, p.Name, p.Description, p.RateLimitPerMinute, p.RateLimitBurst, p.MaxConcurrent, p.RequestsPerMonth,
+		p.SpikeThreshold, p.SpikeMinRequests, p.SpikeThrottleRPM, p.AllowOverage,
 		p.PriceMonthly, p.OveragePrice, p.StripePriceID, p.PaddlePriceID,
-		p.LemonVariantID, p.IsDefault, p.Enabled, meterType, estimatedCost)
+		p.LemonVariantID, p.IsDefault, p.Enabled, meterType, estimatedCost, quotaPeriod)
 	return err
 }
 
@@ -106,16 +121,22 @@ func (s *PlanStore) Update(ctx context.Context, p ports.Plan) error {
 	if estimatedCost <= 0 {
 		estimatedCost = 1.0
 	}
+	quotaPeriod := string(p.QuotaPeriod)
+	if quotaPeriod == "" {
+		quotaPeriod = "calendar_month"
+	}
 	_, err := s.db.DB.ExecContext(ctx, `
-		UPDATE plans SET name = ?, description = ?, rate_limit_per_minute = ?,
+		UPDATE plans SET name = ?, description = ?, rate_limit_per_minute = ?, rate_limit_burst = ?, max_concurrent = ?,
+						 spike_threshold = ?, spike_min_requests = ?, spike_throttle_rpm = ?, allow_overage = ?,
 						 requests_per_month = ?, price_monthly = ?, overage_price = ?,
 						 stripe_price_id = ?, paddle_price_id = ?, lemon_variant_id = ?,
 						 is_default = ?, enabled = ?, meter_type = ?, estimated_cost_per_req = ?,
-						 updated_at = CURRENT_TIMESTAMP
+						 quota_period = ?, updated_at = CURRENT_TIMESTAMP
 		WHERE id = ?
-	`, p.Name, p.Description, p.RateLimitPerMinute, p.RequestsPerMonth,
+	`, p.Name, p.Description, p.RateLimitPerMinute, p.RateLimitBurst, p.MaxConcurrent, p.RequestsPerMonth,
+		p.SpikeThreshold, p.SpikeMinRequests, p.SpikeThrottleRPM, p.AllowOverage,
 		p.PriceMonthly, p.OveragePrice, p.StripePriceID, p.PaddlePriceID,
-		p.LemonVariantID, p.IsDefault, p.Enabled, meterType, estimatedCost, p.ID)
+		p.LemonVariantID, p.IsDefault, p.Enabled, meterType, estimatedCost, quotaPeriod, p.ID)
 	return err
 }
 
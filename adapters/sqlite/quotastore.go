@@ -102,8 +102,80 @@ func (s *QuotaStore) CleanupOldPeriods(ctx context.Context, cutoff time.Time) (i
 	if err != nil {
 		return 0, err
 	}
+	if _, err := s.db.ExecContext(ctx, `
+		DELETE FROM quota_warnings WHERE period_start < ?
+	`, cutoff); err != nil {
+		return 0, err
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		DELETE FROM overage_reports WHERE period_start < ?
+	`, cutoff); err != nil {
+		return 0, err
+	}
 	return result.RowsAffected()
 }
 
+// ListQuotaWarnings returns the thresholds already sent to the user for the period.
+func (s *QuotaStore) ListQuotaWarnings(ctx context.Context, userID string, periodStart time.Time) ([]int, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT threshold FROM quota_warnings
+		WHERE user_id = ? AND period_start = ?
+		ORDER BY threshold
+	`, userID, periodStart)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var thresholds []int
+	for rows.Next() {
+		var threshold int
+		if err := rows.Scan(&threshold); err != nil {
+			return nil, err
+		}
+		thresholds = append(thresholds, threshold)
+	}
+	return thresholds, rows.Err()
+}
+
+// MarkQuotaWarned records that the warning for a threshold was sent.
+func (s *QuotaStore) MarkQuotaWarned(ctx context.Context, userID string, periodStart time.Time, threshold int, at time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO quota_warnings (user_id, period_start, threshold, sent_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id, period_start, threshold) DO NOTHING
+	`, userID, periodStart, threshold, at)
+	return err
+}
+
+// GetReportedOverage returns the overage units already reported for the period.
+func (s *QuotaStore) GetReportedOverage(ctx context.Context, userID string, periodStart time.Time) (int64, error) {
+	var units int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT reported_units FROM overage_reports
+		WHERE user_id = ? AND period_start = ?
+	`, userID, periodStart).Scan(&units)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return units, err
+}
+
+// AddReportedOverage adds units to the reported overage for the period.
+func (s *QuotaStore) AddReportedOverage(ctx context.Context, userID string, periodStart time.Time, units int64, at time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO overage_reports (user_id, period_start, reported_units, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id, period_start) DO UPDATE SET
+			reported_units = reported_units + excluded.reported_units,
+			updated_at = excluded.updated_at
+	`, userID, periodStart, units, at)
+	return err
+}
+
 // Ensure interface compliance.
-var _ ports.QuotaStore = (*QuotaStore)(nil)
+var (
+	_ ports.QuotaStore         = (*QuotaStore)(nil)
+	_ ports.QuotaWarningStore  = (*QuotaStore)(nil)
+	_ ports.OverageReportStore = (*QuotaStore)(nil)
+)
@@ -22,13 +22,14 @@ func NewRateLimitStore(db *DB) *RateLimitStore {
 // Get retrieves current rate limit state for a key.
 func (s *RateLimitStore) Get(ctx context.Context, keyID string) (ratelimit.WindowState, error) {
 	row := s.db.QueryRowContext(ctx, `
-		SELECT count, window_end, burst_used
+		SELECT count, window_end, burst_used, tokens, last_refill
 		FROM rate_limit_state
 		WHERE key_id = ?
 	`, keyID)
 
 	var state ratelimit.WindowState
-	err := row.Scan(&state.Count, &state.WindowEnd, &state.BurstUsed)
+	var lastRefill sql.NullTime
+	err := row.Scan(&state.Count, &state.WindowEnd, &state.BurstUsed, &state.Tokens, &lastRefill)
 	if errors.Is(err, sql.ErrNoRows) {
 		// Return empty state if not found (key has no rate limit history)
 		return ratelimit.WindowState{}, nil
@@ -36,19 +37,22 @@ func (s *RateLimitStore) Get(ctx context.Context, keyID string) (ratelimit.Windo
 	if err != nil {
 		return ratelimit.WindowState{}, err
 	}
+	state.LastRefill = lastRefill.Time
 	return state, nil
 }
 
 // Set updates rate limit state for a key.
 func (s *RateLimitStore) Set(ctx context.Context, keyID string, state ratelimit.WindowState) error {
 	_, err := s.db.ExecContext(ctx, `
-		INSERT INTO rate_limit_state (key_id, count, window_end, burst_used)
-		VALUES (?, ?, ?, ?)
+		INSERT INTO rate_limit_state (key_id, count, window_end, burst_used, tokens, last_refill)
+		VALUES (?, ?, ?, ?, ?, ?)
 		ON CONFLICT(key_id) DO UPDATE SET
 			count = excluded.count,
 			window_end = excluded.window_end,
-			burst_used = excluded.burst_used
-	`, keyID, state.Count, state.WindowEnd, state.BurstUsed)
+			burst_used = excluded.burst_used,
+			tokens = excluded.tokens,
+			last_refill = excluded.last_refill
+	`, keyID, state.Count, state.WindowEnd, state.BurstUsed, state.Tokens, sql.NullTime{Time: state.LastRefill, Valid: !state.LastRefill.IsZero()})
 	return err
 }
 
@@ -26,11 +26,14 @@ func (s *RouteStore) Get(ctx context.Context, id string) (route.Route, error) {
 	row := s.db.QueryRowContext(ctx, `
 		SELECT id, name, description, example_request, example_response,
 		       host_pattern, host_match_type,
-		       path_pattern, match_type, methods, headers,
-		       upstream_id, path_rewrite, method_override,
-		       request_transform, response_transform,
-		       metering_expr, metering_mode, metering_unit, protocol,
-		       auth_required, priority, enabled, created_at, updated_at
+		       path_pattern, match_type, methods, headers, query_match, strip_query,
+		       upstream_id, upstreams, canary, mirror_upstream_id, strip_prefix, rewrite_path, path_rewrite, method_override,
+		       request_transform, response_transform, request_headers, response_headers, rewrite_response_urls,
+		       metering_expr, metering_mode, metering_unit, cost_multiplier, method_cost_multipliers, protocol,
+		       auth_required, auth_method, required_scopes, max_request_body, max_response_body, request_schema,
+		       dial_timeout_ms, response_header_timeout_ms, request_timeout_ms, retry, cors,
+		       cache_ttl_ms, cache_key_headers,
+		       priority, enabled, created_at, updated_at
 		FROM routes
 		WHERE id = ?
 	`, id)
@@ -42,11 +45,14 @@ func (s *RouteStore) List(ctx context.Context) ([]route.Route, error) {
 	rows, err := s.db.QueryContext(ctx, `
 		SELECT id, name, description, example_request, example_response,
 		       host_pattern, host_match_type,
-		       path_pattern, match_type, methods, headers,
-		       upstream_id, path_rewrite, method_override,
-		       request_transform, response_transform,
-		       metering_expr, metering_mode, metering_unit, protocol,
-		       auth_required, priority, enabled, created_at, updated_at
+		       path_pattern, match_type, methods, headers, query_match, strip_query,
+		       upstream_id, upstreams, canary, mirror_upstream_id, strip_prefix, rewrite_path, path_rewrite, method_override,
+		       request_transform, response_transform, request_headers, response_headers, rewrite_response_urls,
+		       metering_expr, metering_mode, metering_unit, cost_multiplier, method_cost_multipliers, protocol,
+		       auth_required, auth_method, required_scopes, max_request_body, max_response_body, request_schema,
+		       dial_timeout_ms, response_header_timeout_ms, request_timeout_ms, retry, cors,
+		       cache_ttl_ms, cache_key_headers,
+		       priority, enabled, created_at, updated_at
 		FROM routes
 		ORDER BY priority DESC, name ASC
 	`)
@@ -71,11 +77,14 @@ func (s *RouteStore) ListEnabled(ctx context.Context) ([]route.Route, error) {
 	rows, err := s.db.QueryContext(ctx, `
 		SELECT id, name, description, example_request, example_response,
 		       host_pattern, host_match_type,
-		       path_pattern, match_type, methods, headers,
-		       upstream_id, path_rewrite, method_override,
-		       request_transform, response_transform,
-		       metering_expr, metering_mode, metering_unit, protocol,
-		       auth_required, priority, enabled, created_at, updated_at
+		       path_pattern, match_type, methods, headers, query_match, strip_query,
+		       upstream_id, upstreams, canary, mirror_upstream_id, strip_prefix, rewrite_path, path_rewrite, method_override,
+		       request_transform, response_transform, request_headers, response_headers, rewrite_response_urls,
+		       metering_expr, metering_mode, metering_unit, cost_multiplier, method_cost_multipliers, protocol,
+		       auth_required, auth_method, required_scopes, max_request_body, max_response_body, request_schema,
+		       dial_timeout_ms, response_header_timeout_ms, request_timeout_ms, retry, cors,
+		       cache_ttl_ms, cache_key_headers,
+		       priority, enabled, created_at, updated_at
 		FROM routes
 		WHERE enabled = 1
 		ORDER BY priority DESC, name ASC
@@ -116,6 +125,26 @@ func (s *RouteStore) Create(ctx context.Context, r route.Route) error {
 		return err
 	}
 
+	queryMatchJSON, err := marshalQueryMatches(r.QueryMatch)
+	if err != nil {
+		return err
+	}
+
+	stripQueryJSON, err := marshalStringSlice(r.StripQuery)
+	if err != nil {
+		return err
+	}
+
+	upstreamsJSON, err := marshalWeightedUpstreams(r.Upstreams)
+	if err != nil {
+		return err
+	}
+
+	canaryJSON, err := marshalCanary(r.Canary)
+	if err != nil {
+		return err
+	}
+
 	reqTransformJSON, err := marshalTransform(r.RequestTransform)
 	if err != nil {
 		return err
@@ -126,25 +155,71 @@ func (s *RouteStore) Create(ctx context.Context, r route.Route) error {
 		return err
 	}
 
+	reqHeadersJSON, err := marshalHeaderRules(r.RequestHeaders)
+	if err != nil {
+		return err
+	}
+
+	respHeadersJSON, err := marshalHeaderRules(r.ResponseHeaders)
+	if err != nil {
+		return err
+	}
+
+	retryJSON, err := marshalRetryPolicy(r.Retry)
+	if err != nil {
+		return err
+	}
+
+	corsJSON, err := marshalCORSPolicy(r.CORS)
+	if err != nil {
+		return err
+	}
+
+	rewriteJSON, err := marshalPathRewrite(r.RewritePath)
+	if err != nil {
+		return err
+	}
+
+	methodCostsJSON, err := marshalMethodCosts(r.MethodCostMultipliers)
+	if err != nil {
+		return err
+	}
+
+	requiredScopesJSON, err := marshalStringSlice(r.RequiredScopes)
+	if err != nil {
+		return err
+	}
+
+	cacheKeyHeadersJSON, err := marshalStringSlice(r.CacheKeyHeaders)
+	if err != nil {
+		return err
+	}
+
 	_, err = s.db.ExecContext(ctx, `
 		INSERT INTO routes (
 			id, name, description, example_request, example_response,
 			host_pattern, host_match_type,
-			path_pattern, match_type, methods, headers,
-			upstream_id, path_rewrite, method_override,
-			request_transform, response_transform,
-			metering_expr, metering_mode, metering_unit, protocol,
-			auth_required, priority, enabled, created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			path_pattern, match_type, methods, headers, query_match, strip_query,
+			upstream_id, upstreams, canary, mirror_upstream_id, strip_prefix, rewrite_path, path_rewrite, method_override,
+			request_transform, response_transform, request_headers, response_headers, rewrite_response_urls,
+			metering_expr, metering_mode, metering_unit, cost_multiplier, method_cost_multipliers, protocol,
+			auth_required, auth_method, required_scopes, max_request_body, max_response_body, request_schema,
+			dial_timeout_ms, response_header_timeout_ms, request_timeout_ms, retry, cors,
+			cache_ttl_ms, cache_key_headers,
+			priority, enabled, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`,
 		r.ID, r.Name, r.Description, r.ExampleRequest, r.ExampleResponse,
 		r.HostPattern, string(r.HostMatchType),
 		r.PathPattern, string(r.MatchType),
-		methodsJSON, headersJSON,
-		r.UpstreamID, nullString(r.PathRewrite), nullString(r.MethodOverride),
-		reqTransformJSON, respTransformJSON,
-		r.MeteringExpr, r.MeteringMode, r.MeteringUnit, string(r.Protocol),
-		boolToInt(r.AuthRequired), r.Priority, boolToInt(r.Enabled), r.CreatedAt, r.UpdatedAt,
+		methodsJSON, headersJSON, queryMatchJSON, stripQueryJSON,
+		r.UpstreamID, upstreamsJSON, canaryJSON, nullString(r.MirrorUpstreamID), nullString(r.StripPrefix), rewriteJSON, nullString(r.PathRewrite), nullString(r.MethodOverride),
+		reqTransformJSON, respTransformJSON, reqHeadersJSON, respHeadersJSON, boolToInt(r.RewriteResponseURLs),
+		r.MeteringExpr, r.MeteringMode, r.MeteringUnit, r.CostMultiplier, methodCostsJSON, string(r.Protocol),
+		boolToInt(r.AuthRequired), string(r.AuthMethod), requiredScopesJSON, r.MaxRequestBody, r.MaxResponseBody, nullString(r.RequestSchema),
+		r.DialTimeout.Milliseconds(), r.ResponseHeaderTimeout.Milliseconds(), r.RequestTimeout.Milliseconds(), retryJSON, corsJSON,
+		r.CacheTTL.Milliseconds(), cacheKeyHeadersJSON,
+		r.Priority, boolToInt(r.Enabled), r.CreatedAt, r.UpdatedAt,
 	)
 
 	if err != nil && isUniqueConstraintError(err) {
@@ -167,6 +242,26 @@ func (s *RouteStore) Update(ctx context.Context, r route.Route) error {
 		return err
 	}
 
+	queryMatchJSON, err := marshalQueryMatches(r.QueryMatch)
+	if err != nil {
+		return err
+	}
+
+	stripQueryJSON, err := marshalStringSlice(r.StripQuery)
+	if err != nil {
+		return err
+	}
+
+	upstreamsJSON, err := marshalWeightedUpstreams(r.Upstreams)
+	if err != nil {
+		return err
+	}
+
+	canaryJSON, err := marshalCanary(r.Canary)
+	if err != nil {
+		return err
+	}
+
 	reqTransformJSON, err := marshalTransform(r.RequestTransform)
 	if err != nil {
 		return err
@@ -177,26 +272,72 @@ func (s *RouteStore) Update(ctx context.Context, r route.Route) error {
 		return err
 	}
 
+	reqHeadersJSON, err := marshalHeaderRules(r.RequestHeaders)
+	if err != nil {
+		return err
+	}
+
+	respHeadersJSON, err := marshalHeaderRules(r.ResponseHeaders)
+	if err != nil {
+		return err
+	}
+
+	retryJSON, err := marshalRetryPolicy(r.Retry)
+	if err != nil {
+		return err
+	}
+
+	corsJSON, err := marshalCORSPolicy(r.CORS)
+	if err != nil {
+		return err
+	}
+
+	rewriteJSON, err := marshalPathRewrite(r.RewritePath)
+	if err != nil {
+		return err
+	}
+
+	methodCostsJSON, err := marshalMethodCosts(r.MethodCostMultipliers)
+	if err != nil {
+		return err
+	}
+
+	requiredScopesJSON, err := marshalStringSlice(r.RequiredScopes)
+	if err != nil {
+		return err
+	}
+
+	cacheKeyHeadersJSON, err := marshalStringSlice(r.CacheKeyHeaders)
+	if err != nil {
+		return err
+	}
+
 	result, err := s.db.ExecContext(ctx, `
 		UPDATE routes
 		SET name = ?, description = ?, example_request = ?, example_response = ?,
 		    host_pattern = ?, host_match_type = ?,
 		    path_pattern = ?, match_type = ?,
-		    methods = ?, headers = ?,
-		    upstream_id = ?, path_rewrite = ?, method_override = ?,
-		    request_transform = ?, response_transform = ?,
-		    metering_expr = ?, metering_mode = ?, metering_unit = ?, protocol = ?,
-		    auth_required = ?, priority = ?, enabled = ?, updated_at = ?
+		    methods = ?, headers = ?, query_match = ?, strip_query = ?,
+		    upstream_id = ?, upstreams = ?, canary = ?, mirror_upstream_id = ?, strip_prefix = ?, rewrite_path = ?, path_rewrite = ?, method_override = ?,
+		    request_transform = ?, response_transform = ?, request_headers = ?, response_headers = ?, rewrite_response_urls = ?,
+		    metering_expr = ?, metering_mode = ?, metering_unit = ?, cost_multiplier = ?, method_cost_multipliers = ?, protocol = ?,
+		    auth_required = ?, auth_method = ?, required_scopes = ?, max_request_body = ?, max_response_body = ?, request_schema = ?,
+		    dial_timeout_ms = ?, response_header_timeout_ms = ?, request_timeout_ms = ?, retry = ?, cors = ?,
+		    cache_ttl_ms = ?, cache_key_headers = ?,
+		    priority = ?, enabled = ?, updated_at = ?
 		WHERE id = ?
 	`,
 		r.Name, r.Description, r.ExampleRequest, r.ExampleResponse,
 		r.HostPattern, string(r.HostMatchType),
 		r.PathPattern, string(r.MatchType),
-		methodsJSON, headersJSON,
-		r.UpstreamID, nullString(r.PathRewrite), nullString(r.MethodOverride),
-		reqTransformJSON, respTransformJSON,
-		r.MeteringExpr, r.MeteringMode, r.MeteringUnit, string(r.Protocol),
-		boolToInt(r.AuthRequired), r.Priority, boolToInt(r.Enabled), r.UpdatedAt, r.ID,
+		methodsJSON, headersJSON, queryMatchJSON, stripQueryJSON,
+		r.UpstreamID, upstreamsJSON, canaryJSON, nullString(r.MirrorUpstreamID), nullString(r.StripPrefix), rewriteJSON, nullString(r.PathRewrite), nullString(r.MethodOverride),
+		reqTransformJSON, respTransformJSON, reqHeadersJSON, respHeadersJSON, boolToInt(r.RewriteResponseURLs),
+		r.MeteringExpr, r.MeteringMode, r.MeteringUnit, r.CostMultiplier, methodCostsJSON, string(r.Protocol),
+		boolToInt(r.AuthRequired), string(r.AuthMethod), requiredScopesJSON, r.MaxRequestBody, r.MaxResponseBody, nullString(r.RequestSchema),
+		r.DialTimeout.Milliseconds(), r.ResponseHeaderTimeout.Milliseconds(), r.RequestTimeout.Milliseconds(), retryJSON, corsJSON,
+		r.CacheTTL.Milliseconds(), cacheKeyHeadersJSON,
+		r.Priority, boolToInt(r.Enabled), r.UpdatedAt, r.ID,
 	)
 	if err != nil {
 		return err
@@ -230,21 +371,27 @@ func (s *RouteStore) Delete(ctx context.Context, id string) error {
 
 func scanRoute(row *sql.Row) (route.Route, error) {
 	var r route.Route
-	var hostMatchType, matchType, protocol string
-	var methodsJSON, headersJSON sql.NullString
-	var pathRewrite, methodOverride sql.NullString
+	var hostMatchType, matchType, protocol, authMethod string
+	var methodsJSON, headersJSON, queryMatchJSON, stripQueryJSON, upstreamsJSON sql.NullString
+	var canaryJSON, mirrorUpstreamID, stripPrefix, rewriteJSON, pathRewrite, methodOverride, requestSchema sql.NullString
 	var reqTransformJSON, respTransformJSON sql.NullString
-	var authRequired, enabled int
+	var reqHeadersJSON, respHeadersJSON, retryJSON, corsJSON, methodCostsJSON sql.NullString
+	var authRequired, enabled, rewriteResponseURLs int
+	var cacheTTLMs, dialTimeoutMs, responseHeaderTimeoutMs, requestTimeoutMs int64
+	var requiredScopesJSON, cacheKeyHeadersJSON sql.NullString
 
 	err := row.Scan(
 		&r.ID, &r.Name, &r.Description, &r.ExampleRequest, &r.ExampleResponse,
 		&r.HostPattern, &hostMatchType,
 		&r.PathPattern, &matchType,
-		&methodsJSON, &headersJSON,
-		&r.UpstreamID, &pathRewrite, &methodOverride,
-		&reqTransformJSON, &respTransformJSON,
-		&r.MeteringExpr, &r.MeteringMode, &r.MeteringUnit, &protocol,
-		&authRequired, &r.Priority, &enabled, &r.CreatedAt, &r.UpdatedAt,
+		&methodsJSON, &headersJSON, &queryMatchJSON, &stripQueryJSON,
+		&r.UpstreamID, &upstreamsJSON, &canaryJSON, &mirrorUpstreamID, &stripPrefix, &rewriteJSON, &pathRewrite, &methodOverride,
+		&reqTransformJSON, &respTransformJSON, &reqHeadersJSON, &respHeadersJSON, &rewriteResponseURLs,
+		&r.MeteringExpr, &r.MeteringMode, &r.MeteringUnit, &r.CostMultiplier, &methodCostsJSON, &protocol,
+		&authRequired, &authMethod, &requiredScopesJSON, &r.MaxRequestBody, &r.MaxResponseBody, &requestSchema,
+		&dialTimeoutMs, &responseHeaderTimeoutMs, &requestTimeoutMs, &retryJSON, &corsJSON,
+		&cacheTTLMs, &cacheKeyHeadersJSON,
+		&r.Priority, &enabled, &r.CreatedAt, &r.UpdatedAt,
 	)
 	if errors.Is(err, sql.ErrNoRows) {
 		return route.Route{}, ErrNotFound
@@ -257,8 +404,37 @@ func scanRoute(row *sql.Row) (route.Route, error) {
 	r.MatchType = route.MatchType(matchType)
 	r.Protocol = route.Protocol(protocol)
 	r.AuthRequired = authRequired == 1
+	r.RewriteResponseURLs = rewriteResponseURLs == 1
+	r.AuthMethod = route.AuthMethod(authMethod)
 	r.Enabled = enabled == 1
-
+	r.CacheTTL = time.Duration(cacheTTLMs) * time.Millisecond
+	r.DialTimeout = time.Duration(dialTimeoutMs) * time.Millisecond
+	r.ResponseHeaderTimeout = time.Duration(responseHeaderTimeoutMs) * time.Millisecond
+	r.RequestTimeout = time.Duration(requestTimeoutMs) * time.Millisecond
+
+	if canaryJSON.Valid && canaryJSON.String != "" {
+		var canary route.CanaryPolicy
+		if err := json.Unmarshal([]byte(canaryJSON.String), &canary); err != nil {
+			return route.Route{}, err
+		}
+		r.Canary = &canary
+	}
+	if mirrorUpstreamID.Valid {
+		r.MirrorUpstreamID = mirrorUpstreamID.String
+	}
+	if requestSchema.Valid {
+		r.RequestSchema = requestSchema.String
+	}
+	if stripPrefix.Valid {
+		r.StripPrefix = stripPrefix.String
+	}
+	if rewriteJSON.Valid && rewriteJSON.String != "" {
+		var rule route.PathRewriteRule
+		if err := json.Unmarshal([]byte(rewriteJSON.String), &rule); err != nil {
+			return route.Route{}, err
+		}
+		r.RewritePath = &rule
+	}
 	if pathRewrite.Valid {
 		r.PathRewrite = pathRewrite.String
 	}
@@ -278,6 +454,36 @@ func scanRoute(row *sql.Row) (route.Route, error) {
 		}
 	}
 
+	if queryMatchJSON.Valid && queryMatchJSON.String != "" {
+		if err := json.Unmarshal([]byte(queryMatchJSON.String), &r.QueryMatch); err != nil {
+			return route.Route{}, err
+		}
+	}
+
+	if stripQueryJSON.Valid && stripQueryJSON.String != "" {
+		if err := json.Unmarshal([]byte(stripQueryJSON.String), &r.StripQuery); err != nil {
+			return route.Route{}, err
+		}
+	}
+
+	if upstreamsJSON.Valid && upstreamsJSON.String != "" {
+		if err := json.Unmarshal([]byte(upstreamsJSON.String), &r.Upstreams); err != nil {
+			return route.Route{}, err
+		}
+	}
+
+	if requiredScopesJSON.Valid && requiredScopesJSON.String != "" {
+		if err := json.Unmarshal([]byte(requiredScopesJSON.String), &r.RequiredScopes); err != nil {
+			return route.Route{}, err
+		}
+	}
+
+	if cacheKeyHeadersJSON.Valid && cacheKeyHeadersJSON.String != "" {
+		if err := json.Unmarshal([]byte(cacheKeyHeadersJSON.String), &r.CacheKeyHeaders); err != nil {
+			return route.Route{}, err
+		}
+	}
+
 	if reqTransformJSON.Valid && reqTransformJSON.String != "" {
 		var t route.Transform
 		if err := json.Unmarshal([]byte(reqTransformJSON.String), &t); err != nil {
@@ -294,26 +500,66 @@ func scanRoute(row *sql.Row) (route.Route, error) {
 		r.ResponseTransform = &t
 	}
 
+	if reqHeadersJSON.Valid && reqHeadersJSON.String != "" {
+		if err := json.Unmarshal([]byte(reqHeadersJSON.String), &r.RequestHeaders); err != nil {
+			return route.Route{}, err
+		}
+	}
+
+	if respHeadersJSON.Valid && respHeadersJSON.String != "" {
+		if err := json.Unmarshal([]byte(respHeadersJSON.String), &r.ResponseHeaders); err != nil {
+			return route.Route{}, err
+		}
+	}
+
+	if retryJSON.Valid && retryJSON.String != "" {
+		var p route.RetryPolicy
+		if err := json.Unmarshal([]byte(retryJSON.String), &p); err != nil {
+			return route.Route{}, err
+		}
+		r.Retry = &p
+	}
+
+	if corsJSON.Valid && corsJSON.String != "" {
+		var p route.CORSPolicy
+		if err := json.Unmarshal([]byte(corsJSON.String), &p); err != nil {
+			return route.Route{}, err
+		}
+		r.CORS = &p
+	}
+
+	if methodCostsJSON.Valid && methodCostsJSON.String != "" {
+		if err := json.Unmarshal([]byte(methodCostsJSON.String), &r.MethodCostMultipliers); err != nil {
+			return route.Route{}, err
+		}
+	}
+
 	return r, nil
 }
 
 func scanRouteRows(rows *sql.Rows) (route.Route, error) {
 	var r route.Route
-	var hostMatchType, matchType, protocol string
-	var methodsJSON, headersJSON sql.NullString
-	var pathRewrite, methodOverride sql.NullString
+	var hostMatchType, matchType, protocol, authMethod string
+	var methodsJSON, headersJSON, queryMatchJSON, stripQueryJSON, upstreamsJSON sql.NullString
+	var canaryJSON, mirrorUpstreamID, stripPrefix, rewriteJSON, pathRewrite, methodOverride, requestSchema sql.NullString
 	var reqTransformJSON, respTransformJSON sql.NullString
-	var authRequired, enabled int
+	var reqHeadersJSON, respHeadersJSON, retryJSON, corsJSON, methodCostsJSON sql.NullString
+	var authRequired, enabled, rewriteResponseURLs int
+	var cacheTTLMs, dialTimeoutMs, responseHeaderTimeoutMs, requestTimeoutMs int64
+	var requiredScopesJSON, cacheKeyHeadersJSON sql.NullString
 
 	err := rows.Scan(
 		&r.ID, &r.Name, &r.Description, &r.ExampleRequest, &r.ExampleResponse,
 		&r.HostPattern, &hostMatchType,
 		&r.PathPattern, &matchType,
-		&methodsJSON, &headersJSON,
-		&r.UpstreamID, &pathRewrite, &methodOverride,
-		&reqTransformJSON, &respTransformJSON,
-		&r.MeteringExpr, &r.MeteringMode, &r.MeteringUnit, &protocol,
-		&authRequired, &r.Priority, &enabled, &r.CreatedAt, &r.UpdatedAt,
+		&methodsJSON, &headersJSON, &queryMatchJSON, &stripQueryJSON,
+		&r.UpstreamID, &upstreamsJSON, &canaryJSON, &mirrorUpstreamID, &stripPrefix, &rewriteJSON, &pathRewrite, &methodOverride,
+		&reqTransformJSON, &respTransformJSON, &reqHeadersJSON, &respHeadersJSON, &rewriteResponseURLs,
+		&r.MeteringExpr, &r.MeteringMode, &r.MeteringUnit, &r.CostMultiplier, &methodCostsJSON, &protocol,
+		&authRequired, &authMethod, &requiredScopesJSON, &r.MaxRequestBody, &r.MaxResponseBody, &requestSchema,
+		&dialTimeoutMs, &responseHeaderTimeoutMs, &requestTimeoutMs, &retryJSON, &corsJSON,
+		&cacheTTLMs, &cacheKeyHeadersJSON,
+		&r.Priority, &enabled, &r.CreatedAt, &r.UpdatedAt,
 	)
 	if err != nil {
 		return route.Route{}, err
@@ -323,8 +569,37 @@ func scanRouteRows(rows *sql.Rows) (route.Route, error) {
 	r.MatchType = route.MatchType(matchType)
 	r.Protocol = route.Protocol(protocol)
 	r.AuthRequired = authRequired == 1
+	r.RewriteResponseURLs = rewriteResponseURLs == 1
+	r.AuthMethod = route.AuthMethod(authMethod)
 	r.Enabled = enabled == 1
-
+	r.CacheTTL = time.Duration(cacheTTLMs) * time.Millisecond
+	r.DialTimeout = time.Duration(dialTimeoutMs) * time.Millisecond
+	r.ResponseHeaderTimeout = time.Duration(responseHeaderTimeoutMs) * time.Millisecond
+	r.RequestTimeout = time.Duration(requestTimeoutMs) * time.Millisecond
+
+	if canaryJSON.Valid && canaryJSON.String != "" {
+		var canary route.CanaryPolicy
+		if err := json.Unmarshal([]byte(canaryJSON.String), &canary); err != nil {
+			return route.Route{}, err
+		}
+		r.Canary = &canary
+	}
+	if mirrorUpstreamID.Valid {
+		r.MirrorUpstreamID = mirrorUpstreamID.String
+	}
+	if requestSchema.Valid {
+		r.RequestSchema = requestSchema.String
+	}
+	if stripPrefix.Valid {
+		r.StripPrefix = stripPrefix.String
+	}
+	if rewriteJSON.Valid && rewriteJSON.String != "" {
+		var rule route.PathRewriteRule
+		if err := json.Unmarshal([]byte(rewriteJSON.String), &rule); err != nil {
+			return route.Route{}, err
+		}
+		r.RewritePath = &rule
+	}
 	if pathRewrite.Valid {
 		r.PathRewrite = pathRewrite.String
 	}
@@ -344,6 +619,36 @@ func scanRouteRows(rows *sql.Rows) (route.Route, error) {
 		}
 	}
 
+	if queryMatchJSON.Valid && queryMatchJSON.String != "" {
+		if err := json.Unmarshal([]byte(queryMatchJSON.String), &r.QueryMatch); err != nil {
+			return route.Route{}, err
+		}
+	}
+
+	if stripQueryJSON.Valid && stripQueryJSON.String != "" {
+		if err := json.Unmarshal([]byte(stripQueryJSON.String), &r.StripQuery); err != nil {
+			return route.Route{}, err
+		}
+	}
+
+	if upstreamsJSON.Valid && upstreamsJSON.String != "" {
+		if err := json.Unmarshal([]byte(upstreamsJSON.String), &r.Upstreams); err != nil {
+			return route.Route{}, err
+		}
+	}
+
+	if requiredScopesJSON.Valid && requiredScopesJSON.String != "" {
+		if err := json.Unmarshal([]byte(requiredScopesJSON.String), &r.RequiredScopes); err != nil {
+			return route.Route{}, err
+		}
+	}
+
+	if cacheKeyHeadersJSON.Valid && cacheKeyHeadersJSON.String != "" {
+		if err := json.Unmarshal([]byte(cacheKeyHeadersJSON.String), &r.CacheKeyHeaders); err != nil {
+			return route.Route{}, err
+		}
+	}
+
 	if reqTransformJSON.Valid && reqTransformJSON.String != "" {
 		var t route.Transform
 		if err := json.Unmarshal([]byte(reqTransformJSON.String), &t); err != nil {
@@ -360,6 +665,40 @@ func scanRouteRows(rows *sql.Rows) (route.Route, error) {
 		r.ResponseTransform = &t
 	}
 
+	if reqHeadersJSON.Valid && reqHeadersJSON.String != "" {
+		if err := json.Unmarshal([]byte(reqHeadersJSON.String), &r.RequestHeaders); err != nil {
+			return route.Route{}, err
+		}
+	}
+
+	if respHeadersJSON.Valid && respHeadersJSON.String != "" {
+		if err := json.Unmarshal([]byte(respHeadersJSON.String), &r.ResponseHeaders); err != nil {
+			return route.Route{}, err
+		}
+	}
+
+	if retryJSON.Valid && retryJSON.String != "" {
+		var p route.RetryPolicy
+		if err := json.Unmarshal([]byte(retryJSON.String), &p); err != nil {
+			return route.Route{}, err
+		}
+		r.Retry = &p
+	}
+
+	if corsJSON.Valid && corsJSON.String != "" {
+		var p route.CORSPolicy
+		if err := json.Unmarshal([]byte(corsJSON.String), &p); err != nil {
+			return route.Route{}, err
+		}
+		r.CORS = &p
+	}
+
+	if methodCostsJSON.Valid && methodCostsJSON.String != "" {
+		if err := json.Unmarshal([]byte(methodCostsJSON.String), &r.MethodCostMultipliers); err != nil {
+			return route.Route{}, err
+		}
+	}
+
 	return r, nil
 }
 
@@ -385,6 +724,39 @@ func marshalHeaderMatches(h []route.HeaderMatch) (sql.NullString, error) {
 	return sql.NullString{String: string(b), Valid: true}, nil
 }
 
+func marshalQueryMatches(q []route.QueryMatch) (sql.NullString, error) {
+	if len(q) == 0 {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(q)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+func marshalWeightedUpstreams(u []route.WeightedUpstream) (sql.NullString, error) {
+	if len(u) == 0 {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(u)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+func marshalHeaderRules(rules []route.HeaderRule) (sql.NullString, error) {
+	if len(rules) == 0 {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(rules)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
 func marshalTransform(t *route.Transform) (sql.NullString, error) {
 	if t == nil {
 		return sql.NullString{}, nil
@@ -396,5 +768,60 @@ func marshalTransform(t *route.Transform) (sql.NullString, error) {
 	return sql.NullString{String: string(b), Valid: true}, nil
 }
 
+func marshalRetryPolicy(p *route.RetryPolicy) (sql.NullString, error) {
+	if p == nil {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+func marshalCORSPolicy(p *route.CORSPolicy) (sql.NullString, error) {
+	if p == nil {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+func marshalCanary(p *route.CanaryPolicy) (sql.NullString, error) {
+	if p == nil {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+func marshalPathRewrite(rule *route.PathRewriteRule) (sql.NullString, error) {
+	if rule == nil {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(rule)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+func marshalMethodCosts(m map[string]float64) (sql.NullString, error) {
+	if len(m) == 0 {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
 // Ensure interface compliance.
 var _ ports.RouteStore = (*RouteStore)(nil)
@@ -42,6 +42,22 @@ func (s *SessionStore) Get(ctx context.Context, id string) (auth.Session, error)
 	return scanSession(row)
 }
 
+// ListByUser returns a user's unexpired sessions, newest first.
+func (s *SessionStore) ListByUser(ctx context.Context, userID string) ([]auth.Session, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, user_id, email, ip_address, user_agent, expires_at, created_at
+		FROM user_sessions
+		WHERE user_id = ? AND expires_at > ?
+		ORDER BY created_at DESC
+	`, userID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanSessions(rows)
+}
+
 // Delete removes a session.
 func (s *SessionStore) Delete(ctx context.Context, id string) error {
 	result, err := s.db.ExecContext(ctx, `
@@ -105,5 +121,27 @@ func scanSession(row *sql.Row) (auth.Session, error) {
 	return sess, nil
 }
 
+func scanSessions(rows *sql.Rows) ([]auth.Session, error) {
+	var sessions []auth.Session
+	for rows.Next() {
+		var sess auth.Session
+		var ipAddress, userAgent sql.NullString
+
+		err := rows.Scan(
+			&sess.ID, &sess.UserID, &sess.Email, &ipAddress, &userAgent,
+			&sess.ExpiresAt, &sess.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		sess.IPAddress = ipAddress.String
+		sess.UserAgent = userAgent.String
+
+		sessions = append(sessions, sess)
+	}
+	return sessions, rows.Err()
+}
+
 // Ensure interface compliance.
 var _ ports.SessionStore = (*SessionStore)(nil)
@@ -123,6 +123,46 @@ func TestSessionStore_DeleteByUser(t *testing.T) {
 	}
 }
 
+func TestSessionStore_ListByUser(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store := sqlite.NewSessionStore(db)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	sessions := []auth.Session{
+		{ID: "sess_old", UserID: "user_list", Email: "list@example.com", IPAddress: "10.0.0.1", ExpiresAt: now.Add(time.Hour), CreatedAt: now.Add(-2 * time.Hour)},
+		{ID: "sess_new", UserID: "user_list", Email: "list@example.com", UserAgent: "Firefox", ExpiresAt: now.Add(time.Hour), CreatedAt: now.Add(-time.Hour)},
+		{ID: "sess_expired", UserID: "user_list", Email: "list@example.com", ExpiresAt: now.Add(-time.Minute), CreatedAt: now.Add(-3 * time.Hour)},
+		{ID: "sess_other", UserID: "user_other", Email: "other@example.com", ExpiresAt: now.Add(time.Hour), CreatedAt: now},
+	}
+	for _, sess := range sessions {
+		if err := store.Create(ctx, sess); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	got, err := store.ListByUser(ctx, "user_list")
+	if err != nil {
+		t.Fatalf("ListByUser failed: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d sessions, want 2 unexpired", len(got))
+	}
+	if got[0].ID != "sess_new" || got[1].ID != "sess_old" {
+		t.Errorf("order = %s, %s, want newest first", got[0].ID, got[1].ID)
+	}
+	if got[0].UserAgent != "Firefox" || got[1].IPAddress != "10.0.0.1" {
+		t.Errorf("sessions = %+v, want IP and user agent", got)
+	}
+
+	got, err = store.ListByUser(ctx, "user_none")
+	if err != nil || len(got) != 0 {
+		t.Errorf("ListByUser(unknown) = %v, %v, want none", got, err)
+	}
+}
+
 func TestSessionStore_DeleteExpired(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -2,7 +2,10 @@ package sqlite_test
 
 import (
 	"context"
+	"errors"
 	"os"
+	"reflect"
+	"strconv"
 	"testing"
 	"time"
 
@@ -153,6 +156,51 @@ func TestUserStore_Update(t *testing.T) {
 	}
 }
 
+func TestUserStore_UpdateIfUnchanged(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store := sqlite.NewUserStore(db)
+	ctx := context.Background()
+
+	if err := store.Create(ctx, ports.User{ID: "user-1", Email: "cas@example.com", Name: "Original", PlanID: "free", Status: "active"}); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	loaded, err := store.Get(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+
+	// Two saves based on the same read: the first wins, the second conflicts
+	first, second := loaded, loaded
+	first.Name = "First"
+	second.Name = "Second"
+	if err := store.UpdateIfUnchanged(ctx, first, loaded.UpdatedAt); err != nil {
+		t.Fatalf("first update: %v", err)
+	}
+	if err := store.UpdateIfUnchanged(ctx, second, loaded.UpdatedAt); !errors.Is(err, ports.ErrConflict) {
+		t.Fatalf("second update error = %v, want ErrConflict", err)
+	}
+
+	got, err := store.Get(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	if got.Name != "First" {
+		t.Errorf("Name = %q, want First", got.Name)
+	}
+
+	// A save based on the current version applies
+	got.Name = "Third"
+	if err := store.UpdateIfUnchanged(ctx, got, got.UpdatedAt); err != nil {
+		t.Errorf("update at current version: %v", err)
+	}
+
+	if err := store.UpdateIfUnchanged(ctx, ports.User{ID: "missing", Email: "missing@example.com"}, time.Time{}); !errors.Is(err, ports.ErrNotFound) {
+		t.Errorf("missing user error = %v, want ErrNotFound", err)
+	}
+}
+
 func TestUserStore_List(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -262,13 +310,15 @@ func TestKeyStore_CreateAndGet(t *testing.T) {
 	}
 
 	k := key.Key{
-		ID:        "key-1",
-		UserID:    "user-1",
-		Hash:      []byte("hash123"),
-		Prefix:    "ak_test12345",
-		Name:      "Test Key",
-		Scopes:    []string{"/api/v1/*"},
-		CreatedAt: time.Now().UTC(),
+		ID:            "key-1",
+		UserID:        "user-1",
+		Hash:          []byte("hash123"),
+		Prefix:        "ak_test12345",
+		Name:          "Test Key",
+		Scopes:        []string{"/api/v1/*"},
+		AllowedCIDRs:  []string{"203.0.113.0/24", "198.51.100.7"},
+		SigningSecret: "s3cr3t",
+		CreatedAt:     time.Now().UTC(),
 	}
 
 	if err := keyStore.Create(ctx, k); err != nil {
@@ -294,6 +344,12 @@ func TestKeyStore_CreateAndGet(t *testing.T) {
 	if len(got.Scopes) != 1 || got.Scopes[0] != "/api/v1/*" {
 		t.Errorf("Scopes = %v, want [/api/v1/*]", got.Scopes)
 	}
+	if len(got.AllowedCIDRs) != 2 || got.AllowedCIDRs[1] != "198.51.100.7" {
+		t.Errorf("AllowedCIDRs = %v, want %v", got.AllowedCIDRs, k.AllowedCIDRs)
+	}
+	if got.SigningSecret != k.SigningSecret {
+		t.Errorf("SigningSecret = %q, want %q", got.SigningSecret, k.SigningSecret)
+	}
 }
 
 func TestKeyStore_Revoke(t *testing.T) {
@@ -336,6 +392,166 @@ func TestKeyStore_Revoke(t *testing.T) {
 	}
 }
 
+func TestKeyStore_SetMonthlyQuota(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userStore := sqlite.NewUserStore(db)
+	keyStore := sqlite.NewKeyStore(db)
+	ctx := context.Background()
+
+	userStore.Create(ctx, ports.User{ID: "user-1", Email: "quota@example.com", PlanID: "free", Status: "active"})
+
+	k := key.Key{
+		ID:           "key-1",
+		UserID:       "user-1",
+		Hash:         []byte("hash"),
+		Prefix:       "ak_quota1234",
+		MonthlyQuota: 500,
+		CreatedAt:    time.Now().UTC(),
+	}
+	if err := keyStore.Create(ctx, k); err != nil {
+		t.Fatalf("create key: %v", err)
+	}
+
+	got, err := keyStore.GetByID(ctx, k.ID)
+	if err != nil {
+		t.Fatalf("get key: %v", err)
+	}
+	if got.MonthlyQuota != 500 {
+		t.Errorf("MonthlyQuota = %d, want 500", got.MonthlyQuota)
+	}
+
+	if err := keyStore.SetMonthlyQuota(ctx, k.ID, 0); err != nil {
+		t.Fatalf("set monthly quota: %v", err)
+	}
+	got, _ = keyStore.GetByID(ctx, k.ID)
+	if got.MonthlyQuota != 0 {
+		t.Errorf("MonthlyQuota = %d, want 0 after removing it", got.MonthlyQuota)
+	}
+
+	if err := keyStore.SetMonthlyQuota(ctx, "missing", 10); err == nil {
+		t.Error("expected error for unknown key")
+	}
+}
+
+func TestKeyStore_Labels(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userStore := sqlite.NewUserStore(db)
+	keyStore := sqlite.NewKeyStore(db)
+	ctx := context.Background()
+
+	userStore.Create(ctx, ports.User{ID: "user-1", Email: "labels@example.com", PlanID: "free", Status: "active"})
+	userStore.Create(ctx, ports.User{ID: "user-2", Email: "other@example.com", PlanID: "free", Status: "active"})
+
+	now := time.Now().UTC()
+	for i, k := range []key.Key{
+		{ID: "key-prod", UserID: "user-1", Labels: map[string]string{"env": "prod", "service": "billing"}},
+		{ID: "key-dev", UserID: "user-1", Labels: map[string]string{"env": "dev", "service": "billing"}},
+		{ID: "key-bare", UserID: "user-1"},
+		{ID: "key-other", UserID: "user-2", Labels: map[string]string{"env": "prod"}},
+	} {
+		k.Hash = []byte("hash")
+		k.Prefix = "ak_label000" + strconv.Itoa(i)
+		k.CreatedAt = now.Add(time.Duration(i) * time.Second)
+		if err := keyStore.Create(ctx, k); err != nil {
+			t.Fatalf("create %s: %v", k.ID, err)
+		}
+	}
+
+	got, err := keyStore.GetByID(ctx, "key-prod")
+	if err != nil {
+		t.Fatalf("get key: %v", err)
+	}
+	if len(got.Labels) != 2 || got.Labels["env"] != "prod" || got.Labels["service"] != "billing" {
+		t.Errorf("Labels = %v, want env=prod service=billing", got.Labels)
+	}
+
+	ids := func(sel key.LabelSelector) []string {
+		t.Helper()
+		keys, err := keyStore.ListByUserLabels(ctx, "user-1", sel)
+		if err != nil {
+			t.Fatalf("list by labels %v: %v", sel, err)
+		}
+		var out []string
+		for _, k := range keys {
+			out = append(out, k.ID)
+		}
+		return out
+	}
+
+	if got := ids(key.LabelSelector{"env": "prod"}); len(got) != 1 || got[0] != "key-prod" {
+		t.Errorf("env=prod = %v, want [key-prod]", got)
+	}
+	if got := ids(key.LabelSelector{"service": ""}); len(got) != 2 {
+		t.Errorf("service = %v, want key-dev and key-prod", got)
+	}
+	if got := ids(key.LabelSelector{"env": "dev", "service": "billing"}); len(got) != 1 || got[0] != "key-dev" {
+		t.Errorf("env=dev,service=billing = %v, want [key-dev]", got)
+	}
+	if got := ids(nil); len(got) != 3 {
+		t.Errorf("empty selector = %v, want all 3 of the user's keys", got)
+	}
+
+	if err := keyStore.SetLabels(ctx, "key-prod", nil); err != nil {
+		t.Fatalf("set labels: %v", err)
+	}
+	got, _ = keyStore.GetByID(ctx, "key-prod")
+	if got.Labels != nil {
+		t.Errorf("Labels = %v, want none after clearing", got.Labels)
+	}
+	if got := ids(key.LabelSelector{"env": "prod"}); len(got) != 0 {
+		t.Errorf("env=prod after clearing = %v, want none", got)
+	}
+
+	if err := keyStore.SetLabels(ctx, "missing", map[string]string{"env": "prod"}); err == nil {
+		t.Error("expected error for unknown key")
+	}
+}
+
+func TestKeyStore_RecordUses(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userStore := sqlite.NewUserStore(db)
+	keyStore := sqlite.NewKeyStore(db)
+	ctx := context.Background()
+
+	userStore.Create(ctx, ports.User{ID: "user-1", Email: "uses@example.com", PlanID: "free", Status: "active"})
+	for i, id := range []string{"key-1", "key-2"} {
+		keyStore.Create(ctx, key.Key{ID: id, UserID: "user-1", Hash: []byte("hash"), Prefix: "ak_uses0000" + strconv.Itoa(i), CreatedAt: time.Now().UTC()})
+	}
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	err := keyStore.RecordUses(ctx, []key.Use{
+		{KeyID: "key-1", At: base, IP: "203.0.113.1"},
+		{KeyID: "key-2", At: base.Add(time.Second), IP: "203.0.113.2"},
+		{KeyID: "key-1", At: base.Add(2 * time.Second), IP: "203.0.113.3"},
+		{KeyID: "missing", At: base},
+	})
+	if err != nil {
+		t.Fatalf("record uses: %v", err)
+	}
+
+	k1, _ := keyStore.GetByID(ctx, "key-1")
+	if k1.LastUsed == nil || !k1.LastUsed.Equal(base.Add(2*time.Second)) || k1.LastUsedIP != "203.0.113.3" {
+		t.Errorf("key-1 last use = %v from %q, want the latest use", k1.LastUsed, k1.LastUsedIP)
+	}
+	k2, _ := keyStore.GetByID(ctx, "key-2")
+	if k2.LastUsedIP != "203.0.113.2" {
+		t.Errorf("key-2 last used IP = %q, want 203.0.113.2", k2.LastUsedIP)
+	}
+
+	// An older use doesn't overwrite a newer one
+	keyStore.RecordUses(ctx, []key.Use{{KeyID: "key-1", At: base, IP: "198.51.100.1"}})
+	k1, _ = keyStore.GetByID(ctx, "key-1")
+	if k1.LastUsedIP != "203.0.113.3" {
+		t.Errorf("key-1 last used IP = %q after an older use, want 203.0.113.3", k1.LastUsedIP)
+	}
+}
+
 func TestKeyStore_ListByUser(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -615,6 +831,163 @@ func TestUsageStore_GetSummary(t *testing.T) {
 	}
 }
 
+func TestUsageStore_GetRequestsAfter(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store := sqlite.NewUsageStore(db)
+	ctx := context.Background()
+
+	record := func(ids ...string) {
+		t.Helper()
+		var events []usage.Event
+		for _, id := range ids {
+			events = append(events, usage.Event{
+				ID:         id,
+				KeyID:      "key-1",
+				UserID:     "user-1",
+				Method:     "GET",
+				Path:       "/api/data",
+				StatusCode: 200,
+				Timestamp:  time.Now().UTC(),
+			})
+		}
+		if err := store.RecordBatch(ctx, events); err != nil {
+			t.Fatalf("record batch: %v", err)
+		}
+	}
+
+	record("evt-1")
+	cursor, err := store.LatestRequestCursor(ctx)
+	if err != nil {
+		t.Fatalf("latest cursor: %v", err)
+	}
+
+	// Only requests recorded after the cursor are returned, in order
+	record("evt-2", "evt-3", "evt-4")
+	events, cursor, err := store.GetRequestsAfter(ctx, cursor, 2)
+	if err != nil {
+		t.Fatalf("get after: %v", err)
+	}
+	if len(events) != 2 || events[0].ID != "evt-2" || events[1].ID != "evt-3" {
+		t.Fatalf("first page = %v", events)
+	}
+
+	events, cursor, err = store.GetRequestsAfter(ctx, cursor, 2)
+	if err != nil {
+		t.Fatalf("get after: %v", err)
+	}
+	if len(events) != 1 || events[0].ID != "evt-4" {
+		t.Fatalf("second page = %v", events)
+	}
+
+	events, _, err = store.GetRequestsAfter(ctx, cursor, 2)
+	if err != nil {
+		t.Fatalf("get after: %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("caught-up page = %v, want none", events)
+	}
+}
+
+func TestUsageStore_GetTopPaths(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store := sqlite.NewUsageStore(db)
+	ctx := context.Background()
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	var events []usage.Event
+	add := func(path string, n, status int, ts time.Time) {
+		for i := 0; i < n; i++ {
+			events = append(events, usage.Event{
+				ID: path + "-" + strconv.Itoa(len(events)), KeyID: "key-1", UserID: "user-1", Method: "GET", Path: path,
+				StatusCode: status, RequestBytes: 10, ResponseBytes: 100, CostMultiplier: 2, Timestamp: ts,
+			})
+		}
+	}
+	add("/v1/items", 4, 200, start.Add(time.Hour))
+	add("/v1/search", 6, 200, start.Add(2*time.Hour))
+	add("/v1/search", 1, 500, start.Add(3*time.Hour))
+	add("/v1/reports", 2, 200, start.Add(4*time.Hour))
+	add("/v1/health", 2, 200, start.Add(5*time.Hour))
+	add("/v1/items", 9, 200, start.AddDate(0, 1, 0)) // outside the range
+	events = append(events, usage.Event{ID: "other", KeyID: "key-2", UserID: "user-2", Method: "GET", Path: "/v1/other", Timestamp: start.Add(time.Hour)})
+	if err := store.RecordBatch(ctx, events); err != nil {
+		t.Fatalf("record batch: %v", err)
+	}
+
+	paths, err := store.GetTopPaths(ctx, "user-1", start, start.AddDate(0, 0, 7), 3)
+	if err != nil {
+		t.Fatalf("get top paths: %v", err)
+	}
+	want := []struct {
+		path     string
+		requests int64
+	}{{"/v1/search", 7}, {"/v1/items", 4}, {"/v1/health", 2}}
+	if len(paths) != len(want) {
+		t.Fatalf("got %d paths, want %d: %+v", len(paths), len(want), paths)
+	}
+	for i, w := range want {
+		if paths[i].Path != w.path || paths[i].RequestCount != w.requests {
+			t.Errorf("paths[%d] = %s (%d), want %s (%d)", i, paths[i].Path, paths[i].RequestCount, w.path, w.requests)
+		}
+	}
+	if p := paths[0]; p.ErrorCount != 1 || p.BytesIn != 70 || p.BytesOut != 700 || p.ComputeUnits != 14 {
+		t.Errorf("search usage = %+v", p)
+	}
+}
+
+func TestUsageStore_EachEvent(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store := sqlite.NewUsageStore(db)
+	ctx := context.Background()
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	// Recorded newest first; exported oldest first
+	var events []usage.Event
+	for i := 4; i >= 0; i-- {
+		events = append(events, usage.Event{
+			ID: "evt-" + strconv.Itoa(i), KeyID: "key-1", UserID: "user-1", Method: "POST", Path: "/api/data",
+			StatusCode: 201, RequestBytes: 5, ResponseBytes: 50, CostMultiplier: 3,
+			Timestamp: start.Add(time.Duration(i) * 24 * time.Hour),
+		})
+	}
+	events = append(events, usage.Event{ID: "other-user", KeyID: "key-2", UserID: "user-2", Method: "GET", Path: "/", Timestamp: start})
+	if err := store.RecordBatch(ctx, events); err != nil {
+		t.Fatalf("record batch: %v", err)
+	}
+
+	var got []usage.Event
+	err := store.EachEvent(ctx, "user-1", start.Add(24*time.Hour), start.Add(4*24*time.Hour), func(e usage.Event) error {
+		got = append(got, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("each event: %v", err)
+	}
+	if len(got) != 3 || got[0].ID != "evt-1" || got[2].ID != "evt-3" {
+		t.Fatalf("events = %v, want evt-1..evt-3", got)
+	}
+	if got[0].CostMultiplier != 3 || got[0].StatusCode != 201 || got[0].ResponseBytes != 50 {
+		t.Errorf("event = %+v", got[0])
+	}
+
+	// Errors from the callback stop the iteration
+	calls := 0
+	stop := errors.New("stop")
+	err = store.EachEvent(ctx, "user-1", start, start.AddDate(0, 1, 0), func(e usage.Event) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("err = %v after %d calls, want stop after 1", err, calls)
+	}
+}
+
 // -----------------------------------------------------------------------------
 // Migration Tests
 // -----------------------------------------------------------------------------
@@ -654,6 +1027,20 @@ func TestRouteStore_CreateAndGet(t *testing.T) {
 	r.Methods = []string{"GET", "POST"}
 	r.Priority = 10
 	r.MeteringExpr = `respBody.usage.tokens ?? 1`
+	r.MaxRequestBody = 1 << 20
+	r.MaxResponseBody = 4 << 20
+	r.CacheTTL = 90 * time.Second
+	r.DialTimeout = 2 * time.Second
+	r.ResponseHeaderTimeout = 5 * time.Second
+	r.RequestTimeout = 45 * time.Second
+	r.Retry = &route.RetryPolicy{Attempts: 2, Backoff: 100 * time.Millisecond, Methods: []string{"GET", "PUT"}, Statuses: []int{502, 503}}
+	r.CORS = &route.CORSPolicy{AllowedOrigins: []string{"https://app.example.com"}, AllowCredentials: true, MaxAge: 10 * time.Minute}
+	r.CostMultiplier = 2.5
+	r.MethodCostMultipliers = map[string]float64{"POST": 5}
+	r.CacheKeyHeaders = []string{"Accept", "Accept-Language"}
+	r.RequiredScopes = []string{"catalog:read", "orders:*"}
+	r.AuthMethod = route.AuthMethodHMAC
+	r.Upstreams = []route.WeightedUpstream{{UpstreamID: "up-1", Weight: 3}, {UpstreamID: "up-2", Weight: 1}}
 
 	if err := store.Create(ctx, r); err != nil {
 		t.Fatalf("create route: %v", err)
@@ -685,6 +1072,34 @@ func TestRouteStore_CreateAndGet(t *testing.T) {
 	if got.MeteringExpr != r.MeteringExpr {
 		t.Errorf("MeteringExpr = %s, want %s", got.MeteringExpr, r.MeteringExpr)
 	}
+	if got.MaxRequestBody != r.MaxRequestBody || got.MaxResponseBody != r.MaxResponseBody {
+		t.Errorf("body limits = %d/%d, want %d/%d", got.MaxRequestBody, got.MaxResponseBody, r.MaxRequestBody, r.MaxResponseBody)
+	}
+	if len(got.Upstreams) != 2 || got.Upstreams[0] != r.Upstreams[0] || got.Upstreams[1] != r.Upstreams[1] {
+		t.Errorf("Upstreams = %v, want %v", got.Upstreams, r.Upstreams)
+	}
+	if got.DialTimeout != r.DialTimeout || got.ResponseHeaderTimeout != r.ResponseHeaderTimeout || got.RequestTimeout != r.RequestTimeout {
+		t.Errorf("timeouts = %v/%v/%v, want %v/%v/%v", got.DialTimeout, got.ResponseHeaderTimeout, got.RequestTimeout,
+			r.DialTimeout, r.ResponseHeaderTimeout, r.RequestTimeout)
+	}
+	if !reflect.DeepEqual(got.Retry, r.Retry) {
+		t.Errorf("Retry = %+v, want %+v", got.Retry, r.Retry)
+	}
+	if !reflect.DeepEqual(got.CORS, r.CORS) {
+		t.Errorf("CORS = %+v, want %+v", got.CORS, r.CORS)
+	}
+	if got.CostMultiplier != 2.5 || !reflect.DeepEqual(got.MethodCostMultipliers, r.MethodCostMultipliers) {
+		t.Errorf("cost multipliers = %v %v, want %v %v", got.CostMultiplier, got.MethodCostMultipliers, r.CostMultiplier, r.MethodCostMultipliers)
+	}
+	if got.CacheTTL != r.CacheTTL || len(got.CacheKeyHeaders) != 2 || got.CacheKeyHeaders[1] != "Accept-Language" {
+		t.Errorf("cache = %v %v, want %v %v", got.CacheTTL, got.CacheKeyHeaders, r.CacheTTL, r.CacheKeyHeaders)
+	}
+	if len(got.RequiredScopes) != 2 || got.RequiredScopes[1] != "orders:*" {
+		t.Errorf("RequiredScopes = %v, want %v", got.RequiredScopes, r.RequiredScopes)
+	}
+	if got.AuthMethod != route.AuthMethodHMAC {
+		t.Errorf("AuthMethod = %q, want %q", got.AuthMethod, route.AuthMethodHMAC)
+	}
 }
 
 func TestRouteStore_CreateWithTransforms(t *testing.T) {
@@ -710,6 +1125,12 @@ func TestRouteStore_CreateWithTransforms(t *testing.T) {
 		SetHeaders:    map[string]string{"X-Response": `"resp-value"`},
 		DeleteHeaders: []string{"X-Internal"},
 	}
+	r.RequestHeaders = []route.HeaderRule{
+		{Op: route.HeaderSet, Name: "X-Internal-Auth", Value: "${INTERNAL_TOKEN}"},
+		{Op: route.HeaderRemove, Name: "Cookie"},
+	}
+	r.ResponseHeaders = []route.HeaderRule{{Op: route.HeaderRename, Name: "X-Upstream-Id", To: "X-Request-Id"}}
+	r.RewriteResponseURLs = true
 
 	if err := store.Create(ctx, r); err != nil {
 		t.Fatalf("create route: %v", err)
@@ -732,6 +1153,15 @@ func TestRouteStore_CreateWithTransforms(t *testing.T) {
 	if got.ResponseTransform == nil {
 		t.Fatal("ResponseTransform should not be nil")
 	}
+	if !reflect.DeepEqual(got.RequestHeaders, r.RequestHeaders) {
+		t.Errorf("RequestHeaders = %+v, want %+v", got.RequestHeaders, r.RequestHeaders)
+	}
+	if !reflect.DeepEqual(got.ResponseHeaders, r.ResponseHeaders) {
+		t.Errorf("ResponseHeaders = %+v, want %+v", got.ResponseHeaders, r.ResponseHeaders)
+	}
+	if !got.RewriteResponseURLs {
+		t.Error("RewriteResponseURLs = false, want true")
+	}
 }
 
 func TestRouteStore_CreateWithHeaders(t *testing.T) {
@@ -775,6 +1205,159 @@ func TestRouteStore_CreateWithHeaders(t *testing.T) {
 	}
 }
 
+func TestRouteStore_PathRewrite(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	upstreamStore := sqlite.NewUpstreamStore(db)
+	ctx := context.Background()
+	upstreamStore.Create(ctx, route.NewUpstream("up-1", "Upstream", "https://api.example.com"))
+
+	store := sqlite.NewRouteStore(db)
+
+	r := route.NewRoute("route-1", "Rewrite Route", "/api/v1/*", "up-1")
+	r.StripPrefix = "/api/v1"
+	r.RewritePath = &route.PathRewriteRule{Regex: `^/users/([0-9]+)$`, Replacement: "/profiles/$1"}
+	if err := store.Create(ctx, r); err != nil {
+		t.Fatalf("create route: %v", err)
+	}
+
+	got, err := store.Get(ctx, r.ID)
+	if err != nil {
+		t.Fatalf("get route: %v", err)
+	}
+	if got.StripPrefix != "/api/v1" {
+		t.Errorf("StripPrefix = %q, want /api/v1", got.StripPrefix)
+	}
+	if got.RewritePath == nil || *got.RewritePath != *r.RewritePath {
+		t.Errorf("RewritePath = %+v, want %+v", got.RewritePath, r.RewritePath)
+	}
+
+	got.StripPrefix = ""
+	got.RewritePath = nil
+	if err := store.Update(ctx, got); err != nil {
+		t.Fatalf("update route: %v", err)
+	}
+	cleared, _ := store.Get(ctx, r.ID)
+	if cleared.StripPrefix != "" || cleared.RewritePath != nil {
+		t.Errorf("rewrite not cleared: %q, %+v", cleared.StripPrefix, cleared.RewritePath)
+	}
+}
+
+func TestRouteStore_QueryMatch(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	upstreamStore := sqlite.NewUpstreamStore(db)
+	ctx := context.Background()
+	upstreamStore.Create(ctx, route.NewUpstream("up-1", "Upstream", "https://api.example.com"))
+
+	store := sqlite.NewRouteStore(db)
+
+	r := route.NewRoute("route-1", "Versioned Route", "/items/*", "up-1")
+	r.QueryMatch = []route.QueryMatch{{Name: "version", Value: "2", Required: true}}
+	r.StripQuery = []string{"version"}
+	if err := store.Create(ctx, r); err != nil {
+		t.Fatalf("create route: %v", err)
+	}
+
+	routes, err := store.List(ctx)
+	if err != nil || len(routes) != 1 {
+		t.Fatalf("list routes: %v, %d routes", err, len(routes))
+	}
+	got := routes[0]
+	if len(got.QueryMatch) != 1 || got.QueryMatch[0] != r.QueryMatch[0] {
+		t.Errorf("QueryMatch = %+v, want %+v", got.QueryMatch, r.QueryMatch)
+	}
+	if len(got.StripQuery) != 1 || got.StripQuery[0] != "version" {
+		t.Errorf("StripQuery = %v, want [version]", got.StripQuery)
+	}
+
+	got.QueryMatch = nil
+	got.StripQuery = nil
+	if err := store.Update(ctx, got); err != nil {
+		t.Fatalf("update route: %v", err)
+	}
+	cleared, _ := store.Get(ctx, r.ID)
+	if cleared.QueryMatch != nil || cleared.StripQuery != nil {
+		t.Errorf("query options not cleared: %+v, %v", cleared.QueryMatch, cleared.StripQuery)
+	}
+}
+
+func TestRouteStore_CanaryAndMirror(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	upstreamStore := sqlite.NewUpstreamStore(db)
+	ctx := context.Background()
+	upstreamStore.Create(ctx, route.NewUpstream("up-1", "Stable", "https://api.example.com"))
+	upstreamStore.Create(ctx, route.NewUpstream("up-2", "Canary", "https://canary.example.com"))
+
+	store := sqlite.NewRouteStore(db)
+
+	r := route.NewRoute("route-1", "Canary Route", "/orders/*", "up-1")
+	r.Canary = &route.CanaryPolicy{UpstreamID: "up-2", Percent: 10, HashBy: route.CanaryHashKey}
+	r.MirrorUpstreamID = "up-2"
+	if err := store.Create(ctx, r); err != nil {
+		t.Fatalf("create route: %v", err)
+	}
+
+	got, err := store.Get(ctx, r.ID)
+	if err != nil {
+		t.Fatalf("get route: %v", err)
+	}
+	if got.Canary == nil || *got.Canary != *r.Canary {
+		t.Errorf("Canary = %+v, want %+v", got.Canary, r.Canary)
+	}
+	if got.MirrorUpstreamID != "up-2" {
+		t.Errorf("MirrorUpstreamID = %q, want up-2", got.MirrorUpstreamID)
+	}
+
+	got.Canary = nil
+	got.MirrorUpstreamID = ""
+	if err := store.Update(ctx, got); err != nil {
+		t.Fatalf("update route: %v", err)
+	}
+	cleared, _ := store.Get(ctx, r.ID)
+	if cleared.Canary != nil || cleared.MirrorUpstreamID != "" {
+		t.Errorf("canary not cleared: %+v, %q", cleared.Canary, cleared.MirrorUpstreamID)
+	}
+}
+
+func TestRouteStore_RequestSchema(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	upstreamStore := sqlite.NewUpstreamStore(db)
+	ctx := context.Background()
+	upstreamStore.Create(ctx, route.NewUpstream("up-1", "Orders", "https://api.example.com"))
+
+	store := sqlite.NewRouteStore(db)
+
+	r := route.NewRoute("route-1", "Orders", "/orders", "up-1")
+	r.RequestSchema = `{"type": "object", "required": ["sku"]}`
+	if err := store.Create(ctx, r); err != nil {
+		t.Fatalf("create route: %v", err)
+	}
+
+	got, err := store.Get(ctx, r.ID)
+	if err != nil {
+		t.Fatalf("get route: %v", err)
+	}
+	if got.RequestSchema != r.RequestSchema {
+		t.Errorf("RequestSchema = %q, want %q", got.RequestSchema, r.RequestSchema)
+	}
+
+	got.RequestSchema = ""
+	if err := store.Update(ctx, got); err != nil {
+		t.Fatalf("update route: %v", err)
+	}
+	cleared, _ := store.Get(ctx, r.ID)
+	if cleared.RequestSchema != "" {
+		t.Errorf("RequestSchema not cleared: %q", cleared.RequestSchema)
+	}
+}
+
 func TestRouteStore_List(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -960,6 +1543,22 @@ func TestUpstreamStore_CreateAndGet(t *testing.T) {
 	u.Timeout = 30 * time.Second
 	u.MaxIdleConns = 50
 	u.IdleConnTimeout = 60 * time.Second
+	u.FailureThreshold = 7
+	u.EjectionCooldown = 45 * time.Second
+	u.HealthCheckPath = "/healthz"
+	u.HealthCheckInterval = 15 * time.Second
+	u.HealthCheckTimeout = 3 * time.Second
+	u.HealthCheckExpectedStatus = 204
+	u.CircuitFailureRate = 0.25
+	u.CircuitWindow = 20 * time.Second
+	u.CircuitMinRequests = 8
+	u.CircuitOpenDuration = time.Minute
+	u.CircuitHalfOpenTrials = 3
+	u.TLSCACert = "ca-pem"
+	u.TLSClientCert = "cert-pem"
+	u.TLSClientKey = "key-pem"
+	u.TLSServerName = "backend.internal"
+	u.TLSInsecureSkipVerify = true
 
 	if err := store.Create(ctx, u); err != nil {
 		t.Fatalf("create upstream: %v", err)
@@ -982,8 +1581,26 @@ func TestUpstreamStore_CreateAndGet(t *testing.T) {
 	if got.Timeout != 30*time.Second {
 		t.Errorf("Timeout = %v, want 30s", got.Timeout)
 	}
-	if got.MaxIdleConns != 50 {
-		t.Errorf("MaxIdleConns = %d, want 50", got.MaxIdleConns)
+	if got.MaxIdleConns != 50 {
+		t.Errorf("MaxIdleConns = %d, want 50", got.MaxIdleConns)
+	}
+	if got.FailureThreshold != 7 || got.EjectionCooldown != 45*time.Second {
+		t.Errorf("passive health = %d/%v, want 7/45s", got.FailureThreshold, got.EjectionCooldown)
+	}
+	if got.HealthCheckPath != "/healthz" || got.HealthCheckInterval != 15*time.Second ||
+		got.HealthCheckTimeout != 3*time.Second || got.HealthCheckExpectedStatus != 204 {
+		t.Errorf("health check = %q/%v/%v/%d, want /healthz/15s/3s/204", got.HealthCheckPath,
+			got.HealthCheckInterval, got.HealthCheckTimeout, got.HealthCheckExpectedStatus)
+	}
+	if got.CircuitFailureRate != 0.25 || got.CircuitWindow != 20*time.Second || got.CircuitMinRequests != 8 ||
+		got.CircuitOpenDuration != time.Minute || got.CircuitHalfOpenTrials != 3 {
+		t.Errorf("circuit breaker = %v/%v/%d/%v/%d, want 0.25/20s/8/1m/3", got.CircuitFailureRate,
+			got.CircuitWindow, got.CircuitMinRequests, got.CircuitOpenDuration, got.CircuitHalfOpenTrials)
+	}
+	if got.TLSCACert != "ca-pem" || got.TLSClientCert != "cert-pem" || got.TLSClientKey != "key-pem" ||
+		got.TLSServerName != "backend.internal" || !got.TLSInsecureSkipVerify {
+		t.Errorf("tls = %q/%q/%q/%q/%v, want ca-pem/cert-pem/key-pem/backend.internal/true", got.TLSCACert,
+			got.TLSClientCert, got.TLSClientKey, got.TLSServerName, got.TLSInsecureSkipVerify)
 	}
 }
 
@@ -1260,6 +1877,112 @@ func TestUserStore_DeleteNotFound(t *testing.T) {
 	}
 }
 
+func TestUserStore_DeleteAndRestore(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store := sqlite.NewUserStore(db)
+	ctx := context.Background()
+
+	for _, u := range []ports.User{
+		{ID: "user-soft-1", Email: "soft1@example.com", PlanID: "free", Status: "active"},
+		{ID: "user-soft-2", Email: "soft2@example.com", PlanID: "free", Status: "active"},
+	} {
+		if err := store.Create(ctx, u); err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+
+	if err := store.Delete(ctx, "user-soft-1"); err != nil {
+		t.Fatalf("delete user: %v", err)
+	}
+
+	// Listing hides soft-deleted users unless asked for them
+	users, err := store.List(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("list users: %v", err)
+	}
+	if len(users) != 1 || users[0].ID != "user-soft-2" {
+		t.Errorf("List = %v, want only user-soft-2", users)
+	}
+	if count, _ := store.Count(ctx); count != 1 {
+		t.Errorf("Count = %d, want 1", count)
+	}
+	if _, err := store.GetByEmail(ctx, "soft1@example.com"); err != sqlite.ErrNotFound {
+		t.Errorf("GetByEmail of deleted user: expected ErrNotFound, got %v", err)
+	}
+
+	withDeleted := ports.WithDeletedUsers(ctx)
+	users, _ = store.List(withDeleted, 10, 0)
+	if len(users) != 2 {
+		t.Errorf("List with deleted = %d users, want 2", len(users))
+	}
+	deleted, err := store.Get(withDeleted, "user-soft-1")
+	if err != nil {
+		t.Fatalf("get deleted user: %v", err)
+	}
+	if deleted.DeletedAt == nil {
+		t.Error("expected DeletedAt to be set")
+	}
+
+	if err := store.Delete(ctx, "user-soft-1"); err != sqlite.ErrNotFound {
+		t.Errorf("second delete: expected ErrNotFound, got %v", err)
+	}
+
+	if err := store.Restore(ctx, "user-soft-1"); err != nil {
+		t.Fatalf("restore user: %v", err)
+	}
+	restored, err := store.Get(ctx, "user-soft-1")
+	if err != nil {
+		t.Fatalf("get restored user: %v", err)
+	}
+	if restored.DeletedAt != nil {
+		t.Error("expected DeletedAt to be cleared")
+	}
+
+	if err := store.Restore(ctx, "user-soft-2"); err != sqlite.ErrNotFound {
+		t.Errorf("restore of active user: expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestUserStore_EmailReusedAfterDelete(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store := sqlite.NewUserStore(db)
+	ctx := context.Background()
+
+	if err := store.Create(ctx, ports.User{ID: "user-old", Email: "reuse@example.com", PlanID: "free", Status: "active"}); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if err := store.Create(ctx, ports.User{ID: "user-dup", Email: "reuse@example.com", PlanID: "free", Status: "active"}); err != sqlite.ErrDuplicate {
+		t.Errorf("create with live user's email: expected ErrDuplicate, got %v", err)
+	}
+	if err := store.Delete(ctx, "user-old"); err != nil {
+		t.Fatalf("delete user: %v", err)
+	}
+
+	// The deleted user's email is free for a new account
+	if err := store.Create(ctx, ports.User{ID: "user-new", Email: "reuse@example.com", PlanID: "free", Status: "active"}); err != nil {
+		t.Fatalf("create user with deleted user's email: %v", err)
+	}
+	got, err := store.GetByEmail(ports.WithDeletedUsers(ctx), "reuse@example.com")
+	if err != nil {
+		t.Fatalf("get by email: %v", err)
+	}
+	if got.ID != "user-new" {
+		t.Errorf("GetByEmail with deleted = %s, want the live user-new", got.ID)
+	}
+
+	// Restoring the old account would duplicate a live email
+	if err := store.Restore(ctx, "user-old"); !errors.Is(err, ports.ErrConflict) {
+		t.Errorf("restore: expected ErrConflict, got %v", err)
+	}
+	if _, err := store.Get(ctx, "user-old"); err != sqlite.ErrNotFound {
+		t.Errorf("get old user after rejected restore: expected ErrNotFound, got %v", err)
+	}
+}
+
 func TestUserStore_UpdateNotFound(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -1280,6 +2003,62 @@ func TestUserStore_UpdateNotFound(t *testing.T) {
 	}
 }
 
+func TestUserStore_TwoFactorFields(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store := sqlite.NewUserStore(db)
+	ctx := context.Background()
+
+	user := ports.User{ID: "user-2fa", Email: "2fa@example.com", PlanID: "free", Status: "active"}
+	if err := store.Create(ctx, user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	got, err := store.Get(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	if got.TOTPSecret != "" || got.TOTPEnabled || got.RecoveryCodes != nil {
+		t.Errorf("new user 2FA = %q/%v/%v, want not enrolled", got.TOTPSecret, got.TOTPEnabled, got.RecoveryCodes)
+	}
+
+	got.TOTPSecret = "JBSWY3DPEHPK3PXP"
+	got.TOTPEnabled = true
+	got.RecoveryCodes = []string{"hash-1", "hash-2"}
+	got.TOTPLastStep = 58012345
+	if err := store.Update(ctx, got); err != nil {
+		t.Fatalf("update user: %v", err)
+	}
+
+	got, err = store.GetByEmail(ctx, user.Email)
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	if got.TOTPSecret != "JBSWY3DPEHPK3PXP" || !got.TOTPEnabled {
+		t.Errorf("2FA = %q/%v, want enrolled", got.TOTPSecret, got.TOTPEnabled)
+	}
+	if len(got.RecoveryCodes) != 2 || got.RecoveryCodes[1] != "hash-2" {
+		t.Errorf("RecoveryCodes = %v", got.RecoveryCodes)
+	}
+	if got.TOTPLastStep != 58012345 {
+		t.Errorf("TOTPLastStep = %d, want 58012345", got.TOTPLastStep)
+	}
+
+	// Disabling clears everything
+	got.TOTPSecret, got.TOTPEnabled, got.RecoveryCodes = "", false, nil
+	if err := store.Update(ctx, got); err != nil {
+		t.Fatalf("update user: %v", err)
+	}
+	users, err := store.List(ctx, 10, 0)
+	if err != nil || len(users) != 1 {
+		t.Fatalf("list users: %v %v", users, err)
+	}
+	if users[0].TOTPSecret != "" || users[0].TOTPEnabled || len(users[0].RecoveryCodes) != 0 {
+		t.Errorf("2FA after disable = %q/%v/%v", users[0].TOTPSecret, users[0].TOTPEnabled, users[0].RecoveryCodes)
+	}
+}
+
 func TestUserStore_CreateWithTimestamps(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -1381,6 +2160,136 @@ func TestUserStore_WithStripeID(t *testing.T) {
 // KeyStore Additional Tests
 // -----------------------------------------------------------------------------
 
+func TestKeyStore_ListExpiringUnwarned(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userStore := sqlite.NewUserStore(db)
+	keyStore := sqlite.NewKeyStore(db)
+	ctx := context.Background()
+
+	userStore.Create(ctx, ports.User{ID: "user-1", Email: "expiring@example.com", PlanID: "free", Status: "active"})
+
+	now := time.Now().UTC()
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+	for _, k := range []key.Key{
+		{ID: "soon", Prefix: "ak_soon00000", ExpiresAt: at(24 * time.Hour)},
+		{ID: "later", Prefix: "ak_later0000", ExpiresAt: at(30 * 24 * time.Hour)},
+		{ID: "expired", Prefix: "ak_expired00", ExpiresAt: at(-time.Hour)},
+		{ID: "never", Prefix: "ak_never0000"},
+		{ID: "revoked", Prefix: "ak_revoked00", ExpiresAt: at(time.Hour), RevokedAt: at(-time.Minute)},
+	} {
+		k.UserID, k.Hash, k.CreatedAt = "user-1", []byte("hash"), now
+		if err := keyStore.Create(ctx, k); err != nil {
+			t.Fatalf("create key %s: %v", k.ID, err)
+		}
+	}
+
+	keys, err := keyStore.ListExpiringUnwarned(ctx, now, now.Add(7*24*time.Hour))
+	if err != nil {
+		t.Fatalf("list expiring: %v", err)
+	}
+	if len(keys) != 1 || keys[0].ID != "soon" {
+		t.Fatalf("expiring keys = %+v, want only soon", keys)
+	}
+
+	if err := keyStore.MarkExpiryWarned(ctx, "soon", now); err != nil {
+		t.Fatalf("mark warned: %v", err)
+	}
+	keys, err = keyStore.ListExpiringUnwarned(ctx, now, now.Add(7*24*time.Hour))
+	if err != nil {
+		t.Fatalf("list expiring: %v", err)
+	}
+	if len(keys) != 0 {
+		t.Errorf("expiring keys after warning = %+v, want none", keys)
+	}
+}
+
+func TestKeyStore_RotatedFrom(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userStore := sqlite.NewUserStore(db)
+	keyStore := sqlite.NewKeyStore(db)
+	ctx := context.Background()
+
+	userStore.Create(ctx, ports.User{ID: "user-1", Email: "rotated@example.com", PlanID: "free", Status: "active"})
+
+	now := time.Now().UTC()
+	old := key.Key{ID: "old", UserID: "user-1", Hash: []byte("hash"), Prefix: "ak_old000000", CreatedAt: now}
+	replacement := key.Key{ID: "new", UserID: "user-1", Hash: []byte("hash"), Prefix: "ak_new000000", RotatedFrom: "old", CreatedAt: now}
+	for _, k := range []key.Key{old, replacement} {
+		if err := keyStore.Create(ctx, k); err != nil {
+			t.Fatalf("create key %s: %v", k.ID, err)
+		}
+	}
+
+	// Scheduled revocation keeps the old key valid until the grace period ends
+	revokeAt := now.Add(time.Hour)
+	if err := keyStore.Revoke(ctx, "old", revokeAt); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+
+	got, err := keyStore.GetByID(ctx, "new")
+	if err != nil {
+		t.Fatalf("get replacement: %v", err)
+	}
+	if got.RotatedFrom != "old" {
+		t.Errorf("RotatedFrom = %q, want old", got.RotatedFrom)
+	}
+	got, err = keyStore.GetByID(ctx, "old")
+	if err != nil {
+		t.Fatalf("get old: %v", err)
+	}
+	if got.RotatedFrom != "" {
+		t.Errorf("old key RotatedFrom = %q, want empty", got.RotatedFrom)
+	}
+	if key.IsRevoked(got, now) || !key.IsRevoked(got, revokeAt) {
+		t.Errorf("old key RevokedAt = %v, want revoked only from %v", got.RevokedAt, revokeAt)
+	}
+}
+
+func TestKeyStore_CreateBatch(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userStore := sqlite.NewUserStore(db)
+	keyStore := sqlite.NewKeyStore(db)
+	ctx := context.Background()
+
+	userStore.Create(ctx, ports.User{ID: "user-1", Email: "batch@example.com", PlanID: "free", Status: "active"})
+	now := time.Now().UTC()
+	newKey := func(id, prefix string) key.Key {
+		return key.Key{ID: id, UserID: "user-1", Hash: []byte("hash"), Prefix: prefix, CreatedAt: now}
+	}
+
+	if err := keyStore.CreateBatch(ctx, []key.Key{newKey("a", "ak_batch0001"), newKey("b", "ak_batch0002")}); err != nil {
+		t.Fatalf("create batch: %v", err)
+	}
+
+	// A conflicting prefix rolls back the whole batch
+	err := keyStore.CreateBatch(ctx, []key.Key{newKey("c", "ak_batch0003"), newKey("d", "ak_batch0001")})
+	if !errors.Is(err, ports.ErrDuplicateKeyPrefix) {
+		t.Fatalf("conflicting batch error = %v, want ErrDuplicateKeyPrefix", err)
+	}
+	keys, err := keyStore.ListByUser(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("list keys: %v", err)
+	}
+	if len(keys) != 2 {
+		t.Errorf("keys after rejected batch = %d, want 2", len(keys))
+	}
+
+	// Prefixes repeated within a batch are rejected too
+	err = keyStore.CreateBatch(ctx, []key.Key{newKey("e", "ak_batch0004"), newKey("f", "ak_batch0004")})
+	if !errors.Is(err, ports.ErrDuplicateKeyPrefix) {
+		t.Errorf("repeated prefix error = %v, want ErrDuplicateKeyPrefix", err)
+	}
+}
+
 func TestKeyStore_List(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -1951,6 +2860,11 @@ func TestPlanStore_CreateAndGet(t *testing.T) {
 		Name:               "Pro Plan",
 		Description:        "Professional tier",
 		RateLimitPerMinute: 1000,
+		MaxConcurrent:      4,
+		SpikeThreshold:     5,
+		SpikeMinRequests:   100,
+		SpikeThrottleRPM:   30,
+		AllowOverage:       true,
 		RequestsPerMonth:   100000,
 		PriceMonthly:       4999, // cents
 		OveragePrice:       1,    // cents
@@ -1979,6 +2893,16 @@ func TestPlanStore_CreateAndGet(t *testing.T) {
 	if got.PriceMonthly != plan.PriceMonthly {
 		t.Errorf("PriceMonthly = %d, want %d", got.PriceMonthly, plan.PriceMonthly)
 	}
+	if got.MaxConcurrent != plan.MaxConcurrent {
+		t.Errorf("MaxConcurrent = %d, want %d", got.MaxConcurrent, plan.MaxConcurrent)
+	}
+	if got.SpikeThreshold != plan.SpikeThreshold || got.SpikeMinRequests != plan.SpikeMinRequests || got.SpikeThrottleRPM != plan.SpikeThrottleRPM {
+		t.Errorf("spike settings = %v/%d/%d, want %v/%d/%d", got.SpikeThreshold, got.SpikeMinRequests, got.SpikeThrottleRPM,
+			plan.SpikeThreshold, plan.SpikeMinRequests, plan.SpikeThrottleRPM)
+	}
+	if !got.AllowOverage {
+		t.Error("AllowOverage = false, want true")
+	}
 }
 
 func TestPlanStore_List(t *testing.T) {
@@ -2847,6 +3771,7 @@ func TestInvoiceStore_CRUD(t *testing.T) {
 		Provider:    "stripe",
 		PeriodStart: now,
 		PeriodEnd:   periodEnd,
+		Items:       []billing.InvoiceItem{{Description: "Pro - Monthly subscription", Quantity: 1, UnitPrice: 9900, Amount: 9900}},
 		Total:       9900,
 		Currency:    "USD",
 		Status:      billing.InvoiceStatusOpen,
@@ -2869,6 +3794,18 @@ func TestInvoiceStore_CRUD(t *testing.T) {
 		t.Errorf("Total = %d, want %d", list[0].Total, inv.Total)
 	}
 
+	// Get
+	got, err := store.Get(ctx, inv.ID)
+	if err != nil {
+		t.Fatalf("get invoice: %v", err)
+	}
+	if got.UserID != inv.UserID || got.Total != inv.Total || len(got.Items) != 1 || got.Items[0].Description != inv.Items[0].Description {
+		t.Errorf("Get = %+v, want %+v", got, inv)
+	}
+	if _, err := store.Get(ctx, "missing"); err != sqlite.ErrNotFound {
+		t.Errorf("Get(missing) error = %v, want ErrNotFound", err)
+	}
+
 	// UpdateStatus
 	paidAt := time.Now()
 	if err := store.UpdateStatus(ctx, inv.ID, billing.InvoiceStatusPaid, &paidAt); err != nil {
@@ -2880,6 +3817,60 @@ func TestInvoiceStore_CRUD(t *testing.T) {
 	}
 }
 
+func TestInvoiceStore_PendingItems(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store := sqlite.NewInvoiceStore(db)
+	ctx := context.Background()
+
+	changedAt := time.Date(2024, 4, 16, 0, 0, 0, 0, time.UTC)
+	periodEnd := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	items := []billing.InvoiceItem{
+		{Description: "Pro - Prorated subscription", Quantity: 1, UnitPrice: 1000, Amount: 1000},
+		{Description: "Business - Credit for time before plan change", Quantity: 1, UnitPrice: -3000, Amount: -3000},
+	}
+	if err := store.AddPendingItems(ctx, "sub-1", items, changedAt); err != nil {
+		t.Fatalf("add pending items: %v", err)
+	}
+	if err := store.AddPendingItems(ctx, "sub-2", items[:1], changedAt); err != nil {
+		t.Fatalf("add pending items: %v", err)
+	}
+
+	if got, _ := store.ListPendingItems(ctx, "sub-1", changedAt); len(got) != 0 {
+		t.Errorf("items before the change = %+v, want none", got)
+	}
+	got, err := store.ListPendingItems(ctx, "sub-1", periodEnd)
+	if err != nil {
+		t.Fatalf("list pending items: %v", err)
+	}
+	if len(got) != 2 || got[0] != items[0] || got[1] != items[1] {
+		t.Errorf("pending items = %+v, want %+v", got, items)
+	}
+
+	inv := billing.Invoice{ID: "inv-1", UserID: "user-1", PeriodStart: changedAt, PeriodEnd: periodEnd, Currency: "usd", Status: billing.InvoiceStatusOpen}
+	if err := store.CreateInvoiceWithItems(ctx, inv, "sub-1", periodEnd); err != nil {
+		t.Fatalf("create invoice with items: %v", err)
+	}
+	if _, err := store.Get(ctx, "inv-1"); err != nil {
+		t.Errorf("get invoice: %v", err)
+	}
+	if got, _ := store.ListPendingItems(ctx, "sub-1", periodEnd); len(got) != 0 {
+		t.Errorf("pending items after invoicing = %+v, want none", got)
+	}
+	if got, _ := store.ListPendingItems(ctx, "sub-2", periodEnd); len(got) != 1 {
+		t.Errorf("other subscription's items = %+v, want 1", got)
+	}
+
+	// A failed invoice leaves the items pending
+	if err := store.CreateInvoiceWithItems(ctx, inv, "sub-2", periodEnd); !errors.Is(err, sqlite.ErrDuplicate) {
+		t.Fatalf("duplicate invoice error = %v, want ErrDuplicate", err)
+	}
+	if got, _ := store.ListPendingItems(ctx, "sub-2", periodEnd); len(got) != 1 {
+		t.Errorf("items after failed invoice = %+v, want 1", got)
+	}
+}
+
 // -----------------------------------------------------------------------------
 // SubscriptionStore Tests
 // -----------------------------------------------------------------------------
@@ -2965,6 +3956,57 @@ func TestSubscriptionStore_CRUD(t *testing.T) {
 	}
 }
 
+func TestSubscriptionStore_Dunning(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store := sqlite.NewSubscriptionStore(db)
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	sub := billing.Subscription{
+		ID:                 "sub-dunning",
+		UserID:             "user-1",
+		PlanID:             "plan-1",
+		Provider:           "stripe",
+		Status:             billing.SubscriptionStatusActive,
+		CurrentPeriodStart: now,
+		CurrentPeriodEnd:   now.AddDate(0, 1, 0),
+	}
+	if err := store.Create(ctx, sub); err != nil {
+		t.Fatalf("create subscription: %v", err)
+	}
+	if got, _ := store.Get(ctx, sub.ID); got.InDunning() || got.DunningStep != 0 {
+		t.Errorf("new subscription in dunning: %+v", got)
+	}
+
+	sub.Status = billing.SubscriptionStatusUnpaid
+	sub.DunningStartedAt = &now
+	sub.DunningStep = 3
+	if err := store.Update(ctx, sub); err != nil {
+		t.Fatalf("update subscription: %v", err)
+	}
+
+	// Suspended subscriptions are still the user's current subscription
+	got, err := store.GetByUser(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("get by user: %v", err)
+	}
+	if got.DunningStartedAt == nil || !got.DunningStartedAt.Equal(now) || got.DunningStep != 3 {
+		t.Errorf("dunning = %v step %d, want %v step 3", got.DunningStartedAt, got.DunningStep, now)
+	}
+
+	sub.Status = billing.SubscriptionStatusActive
+	sub.DunningStartedAt = nil
+	sub.DunningStep = 0
+	if err := store.Update(ctx, sub); err != nil {
+		t.Fatalf("update subscription: %v", err)
+	}
+	if got, _ := store.Get(ctx, sub.ID); got.InDunning() || got.DunningStep != 0 {
+		t.Errorf("dunning not cleared: %+v", got)
+	}
+}
+
 func TestSubscriptionStore_GetNotFound(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -3073,3 +4115,62 @@ func itoa(n int) string {
 	}
 	return itoa(n/10) + string(rune('0'+n%10))
 }
+
+func TestQuotaStore_QuotaWarnings(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store := sqlite.NewQuotaStore(db)
+	ctx := context.Background()
+	march := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	april := march.AddDate(0, 1, 0)
+
+	for _, threshold := range []int{100, 80, 80} {
+		if err := store.MarkQuotaWarned(ctx, "user-1", march, threshold, march.Add(time.Hour)); err != nil {
+			t.Fatalf("mark warned %d: %v", threshold, err)
+		}
+	}
+
+	sent, err := store.ListQuotaWarnings(ctx, "user-1", march)
+	if err != nil {
+		t.Fatalf("list warnings: %v", err)
+	}
+	if len(sent) != 2 || sent[0] != 80 || sent[1] != 100 {
+		t.Errorf("warnings = %v, want [80 100]", sent)
+	}
+	if sent, _ := store.ListQuotaWarnings(ctx, "user-1", april); len(sent) != 0 {
+		t.Errorf("next period warnings = %v, want none", sent)
+	}
+	if sent, _ := store.ListQuotaWarnings(ctx, "user-2", march); len(sent) != 0 {
+		t.Errorf("other user warnings = %v, want none", sent)
+	}
+}
+
+func TestQuotaStore_OverageReports(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store := sqlite.NewQuotaStore(db)
+	ctx := context.Background()
+	march := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	april := march.AddDate(0, 1, 0)
+
+	if units, err := store.GetReportedOverage(ctx, "user-1", march); err != nil || units != 0 {
+		t.Fatalf("unreported overage = %d, %v; want 0, nil", units, err)
+	}
+	for _, units := range []int64{40, 15} {
+		if err := store.AddReportedOverage(ctx, "user-1", march, units, march.Add(time.Hour)); err != nil {
+			t.Fatalf("add reported overage %d: %v", units, err)
+		}
+	}
+
+	if units, _ := store.GetReportedOverage(ctx, "user-1", march); units != 55 {
+		t.Errorf("reported overage = %d, want 55", units)
+	}
+	if units, _ := store.GetReportedOverage(ctx, "user-1", april); units != 0 {
+		t.Errorf("next period overage = %d, want 0", units)
+	}
+	if units, _ := store.GetReportedOverage(ctx, "user-2", march); units != 0 {
+		t.Errorf("other user overage = %d, want 0", units)
+	}
+}
@@ -25,21 +25,24 @@ func (s *SubscriptionStore) Get(ctx context.Context, id string) (billing.Subscri
 	row := s.db.QueryRowContext(ctx, `
 		SELECT id, user_id, plan_id, provider, provider_id, provider_item_id,
 		       status, current_period_start, current_period_end,
-		       cancel_at_period_end, cancelled_at, created_at, updated_at
+		       cancel_at_period_end, cancelled_at, dunning_started_at, dunning_step,
+		       created_at, updated_at
 		FROM subscriptions
 		WHERE id = ?
 	`, id)
 	return scanSubscription(row)
 }
 
-// GetByUser retrieves the active subscription for a user.
+// GetByUser retrieves the current subscription for a user: active, trialing,
+// past due or suspended for non-payment.
 func (s *SubscriptionStore) GetByUser(ctx context.Context, userID string) (billing.Subscription, error) {
 	row := s.db.QueryRowContext(ctx, `
 		SELECT id, user_id, plan_id, provider, provider_id, provider_item_id,
 		       status, current_period_start, current_period_end,
-		       cancel_at_period_end, cancelled_at, created_at, updated_at
+		       cancel_at_period_end, cancelled_at, dunning_started_at, dunning_step,
+		       created_at, updated_at
 		FROM subscriptions
-		WHERE user_id = ? AND status IN ('active', 'trialing', 'past_due')
+		WHERE user_id = ? AND status IN ('active', 'trialing', 'past_due', 'unpaid')
 		ORDER BY created_at DESC
 		LIMIT 1
 	`, userID)
@@ -51,7 +54,8 @@ func (s *SubscriptionStore) GetByProviderID(ctx context.Context, providerID stri
 	row := s.db.QueryRowContext(ctx, `
 		SELECT id, user_id, plan_id, provider, provider_id, provider_item_id,
 		       status, current_period_start, current_period_end,
-		       cancel_at_period_end, cancelled_at, created_at, updated_at
+		       cancel_at_period_end, cancelled_at, dunning_started_at, dunning_step,
+		       created_at, updated_at
 		FROM subscriptions
 		WHERE provider_id = ?
 	`, providerID)
@@ -72,12 +76,14 @@ func (s *SubscriptionStore) Create(ctx context.Context, sub billing.Subscription
 		INSERT INTO subscriptions (
 			id, user_id, plan_id, provider, provider_id, provider_item_id,
 			status, current_period_start, current_period_end,
-			cancel_at_period_end, cancelled_at, created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			cancel_at_period_end, cancelled_at, dunning_started_at, dunning_step,
+			created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`,
 		sub.ID, sub.UserID, sub.PlanID, sub.Provider, sub.ProviderID, sub.ProviderItemID,
 		string(sub.Status), sub.CurrentPeriodStart, sub.CurrentPeriodEnd,
 		boolToInt(sub.CancelAtPeriodEnd), nullTime(sub.CancelledAt),
+		nullTime(sub.DunningStartedAt), sub.DunningStep,
 		sub.CreatedAt, sub.UpdatedAt,
 	)
 
@@ -95,12 +101,14 @@ func (s *SubscriptionStore) Update(ctx context.Context, sub billing.Subscription
 		UPDATE subscriptions
 		SET plan_id = ?, provider = ?, provider_id = ?, provider_item_id = ?,
 		    status = ?, current_period_start = ?, current_period_end = ?,
-		    cancel_at_period_end = ?, cancelled_at = ?, updated_at = ?
+		    cancel_at_period_end = ?, cancelled_at = ?,
+		    dunning_started_at = ?, dunning_step = ?, updated_at = ?
 		WHERE id = ?
 	`,
 		sub.PlanID, sub.Provider, sub.ProviderID, sub.ProviderItemID,
 		string(sub.Status), sub.CurrentPeriodStart, sub.CurrentPeriodEnd,
 		boolToInt(sub.CancelAtPeriodEnd), nullTime(sub.CancelledAt),
+		nullTime(sub.DunningStartedAt), sub.DunningStep,
 		sub.UpdatedAt, sub.ID,
 	)
 	if err != nil {
@@ -121,13 +129,14 @@ func scanSubscription(row *sql.Row) (billing.Subscription, error) {
 	var sub billing.Subscription
 	var status string
 	var providerID, providerItemID sql.NullString
-	var cancelledAt sql.NullTime
+	var cancelledAt, dunningStartedAt sql.NullTime
 	var cancelAtPeriodEnd int
 
 	err := row.Scan(
 		&sub.ID, &sub.UserID, &sub.PlanID, &sub.Provider, &providerID, &providerItemID,
 		&status, &sub.CurrentPeriodStart, &sub.CurrentPeriodEnd,
-		&cancelAtPeriodEnd, &cancelledAt, &sub.CreatedAt, &sub.UpdatedAt,
+		&cancelAtPeriodEnd, &cancelledAt, &dunningStartedAt, &sub.DunningStep,
+		&sub.CreatedAt, &sub.UpdatedAt,
 	)
 	if errors.Is(err, sql.ErrNoRows) {
 		return billing.Subscription{}, ErrNotFound
@@ -147,6 +156,9 @@ func scanSubscription(row *sql.Row) (billing.Subscription, error) {
 	if cancelledAt.Valid {
 		sub.CancelledAt = &cancelledAt.Time
 	}
+	if dunningStartedAt.Valid {
+		sub.DunningStartedAt = &dunningStartedAt.Time
+	}
 
 	return sub, nil
 }
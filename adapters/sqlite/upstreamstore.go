@@ -24,7 +24,12 @@ func NewUpstreamStore(db *DB) *UpstreamStore {
 func (s *UpstreamStore) Get(ctx context.Context, id string) (route.Upstream, error) {
 	row := s.db.QueryRowContext(ctx, `
 		SELECT id, name, description, base_url, timeout_ms, max_idle_conns, idle_conn_timeout_ms,
-		       auth_type, auth_header, auth_value_encrypted, enabled, created_at, updated_at
+		       auth_type, auth_header, auth_value_encrypted,
+		       failure_threshold, ejection_cooldown_ms,
+		       health_check_path, health_check_interval_ms, health_check_timeout_ms, health_check_expected_status,
+		       circuit_failure_rate, circuit_window_ms, circuit_min_requests, circuit_open_ms, circuit_half_open_trials,
+		       tls_ca_cert, tls_client_cert, tls_client_key_encrypted, tls_server_name, tls_insecure_skip_verify,
+		       enabled, created_at, updated_at
 		FROM upstreams
 		WHERE id = ?
 	`, id)
@@ -35,7 +40,12 @@ func (s *UpstreamStore) Get(ctx context.Context, id string) (route.Upstream, err
 func (s *UpstreamStore) List(ctx context.Context) ([]route.Upstream, error) {
 	rows, err := s.db.QueryContext(ctx, `
 		SELECT id, name, description, base_url, timeout_ms, max_idle_conns, idle_conn_timeout_ms,
-		       auth_type, auth_header, auth_value_encrypted, enabled, created_at, updated_at
+		       auth_type, auth_header, auth_value_encrypted,
+		       failure_threshold, ejection_cooldown_ms,
+		       health_check_path, health_check_interval_ms, health_check_timeout_ms, health_check_expected_status,
+		       circuit_failure_rate, circuit_window_ms, circuit_min_requests, circuit_open_ms, circuit_half_open_trials,
+		       tls_ca_cert, tls_client_cert, tls_client_key_encrypted, tls_server_name, tls_insecure_skip_verify,
+		       enabled, created_at, updated_at
 		FROM upstreams
 		ORDER BY name ASC
 	`)
@@ -59,7 +69,12 @@ func (s *UpstreamStore) List(ctx context.Context) ([]route.Upstream, error) {
 func (s *UpstreamStore) ListEnabled(ctx context.Context) ([]route.Upstream, error) {
 	rows, err := s.db.QueryContext(ctx, `
 		SELECT id, name, description, base_url, timeout_ms, max_idle_conns, idle_conn_timeout_ms,
-		       auth_type, auth_header, auth_value_encrypted, enabled, created_at, updated_at
+		       auth_type, auth_header, auth_value_encrypted,
+		       failure_threshold, ejection_cooldown_ms,
+		       health_check_path, health_check_interval_ms, health_check_timeout_ms, health_check_expected_status,
+		       circuit_failure_rate, circuit_window_ms, circuit_min_requests, circuit_open_ms, circuit_half_open_trials,
+		       tls_ca_cert, tls_client_cert, tls_client_key_encrypted, tls_server_name, tls_insecure_skip_verify,
+		       enabled, created_at, updated_at
 		FROM upstreams
 		WHERE enabled = 1
 		ORDER BY name ASC
@@ -93,12 +108,21 @@ func (s *UpstreamStore) Create(ctx context.Context, u route.Upstream) error {
 	_, err := s.db.ExecContext(ctx, `
 		INSERT INTO upstreams (
 			id, name, description, base_url, timeout_ms, max_idle_conns, idle_conn_timeout_ms,
-			auth_type, auth_header, auth_value_encrypted, enabled, created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			auth_type, auth_header, auth_value_encrypted,
+			failure_threshold, ejection_cooldown_ms,
+			health_check_path, health_check_interval_ms, health_check_timeout_ms, health_check_expected_status,
+			circuit_failure_rate, circuit_window_ms, circuit_min_requests, circuit_open_ms, circuit_half_open_trials,
+			tls_ca_cert, tls_client_cert, tls_client_key_encrypted, tls_server_name, tls_insecure_skip_verify,
+			enabled, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`,
 		u.ID, u.Name, u.Description, u.BaseURL,
 		u.Timeout.Milliseconds(), u.MaxIdleConns, u.IdleConnTimeout.Milliseconds(),
 		string(u.AuthType), nullString(u.AuthHeader), nullBytes([]byte(u.AuthValue)),
+		u.FailureThreshold, u.EjectionCooldown.Milliseconds(),
+		u.HealthCheckPath, u.HealthCheckInterval.Milliseconds(), u.HealthCheckTimeout.Milliseconds(), u.HealthCheckExpectedStatus,
+		u.CircuitFailureRate, u.CircuitWindow.Milliseconds(), u.CircuitMinRequests, u.CircuitOpenDuration.Milliseconds(), u.CircuitHalfOpenTrials,
+		u.TLSCACert, u.TLSClientCert, nullBytes([]byte(u.TLSClientKey)), u.TLSServerName, boolToInt(u.TLSInsecureSkipVerify),
 		boolToInt(u.Enabled), u.CreatedAt, u.UpdatedAt,
 	)
 
@@ -117,12 +141,20 @@ func (s *UpstreamStore) Update(ctx context.Context, u route.Upstream) error {
 		SET name = ?, description = ?, base_url = ?, timeout_ms = ?,
 		    max_idle_conns = ?, idle_conn_timeout_ms = ?,
 		    auth_type = ?, auth_header = ?, auth_value_encrypted = ?,
+		    failure_threshold = ?, ejection_cooldown_ms = ?,
+		    health_check_path = ?, health_check_interval_ms = ?, health_check_timeout_ms = ?, health_check_expected_status = ?,
+		    circuit_failure_rate = ?, circuit_window_ms = ?, circuit_min_requests = ?, circuit_open_ms = ?, circuit_half_open_trials = ?,
+		    tls_ca_cert = ?, tls_client_cert = ?, tls_client_key_encrypted = ?, tls_server_name = ?, tls_insecure_skip_verify = ?,
 		    enabled = ?, updated_at = ?
 		WHERE id = ?
 	`,
 		u.Name, u.Description, u.BaseURL,
 		u.Timeout.Milliseconds(), u.MaxIdleConns, u.IdleConnTimeout.Milliseconds(),
 		string(u.AuthType), nullString(u.AuthHeader), nullBytes([]byte(u.AuthValue)),
+		u.FailureThreshold, u.EjectionCooldown.Milliseconds(),
+		u.HealthCheckPath, u.HealthCheckInterval.Milliseconds(), u.HealthCheckTimeout.Milliseconds(), u.HealthCheckExpectedStatus,
+		u.CircuitFailureRate, u.CircuitWindow.Milliseconds(), u.CircuitMinRequests, u.CircuitOpenDuration.Milliseconds(), u.CircuitHalfOpenTrials,
+		u.TLSCACert, u.TLSClientCert, nullBytes([]byte(u.TLSClientKey)), u.TLSServerName, boolToInt(u.TLSInsecureSkipVerify),
 		boolToInt(u.Enabled), u.UpdatedAt, u.ID,
 	)
 	if err != nil {
@@ -157,16 +189,24 @@ func (s *UpstreamStore) Delete(ctx context.Context, id string) error {
 
 func scanUpstream(row *sql.Row) (route.Upstream, error) {
 	var u route.Upstream
-	var timeoutMs, idleConnTimeoutMs int64
+	var timeoutMs, idleConnTimeoutMs, ejectionCooldownMs int64
+	var healthCheckIntervalMs, healthCheckTimeoutMs int64
+	var circuitWindowMs, circuitOpenMs int64
 	var authType string
 	var authHeader sql.NullString
 	var authValue []byte
+	var tlsClientKey []byte
+	var tlsInsecureSkipVerify int
 	var enabled int
 
 	err := row.Scan(
 		&u.ID, &u.Name, &u.Description, &u.BaseURL,
 		&timeoutMs, &u.MaxIdleConns, &idleConnTimeoutMs,
 		&authType, &authHeader, &authValue,
+		&u.FailureThreshold, &ejectionCooldownMs,
+		&u.HealthCheckPath, &healthCheckIntervalMs, &healthCheckTimeoutMs, &u.HealthCheckExpectedStatus,
+		&u.CircuitFailureRate, &circuitWindowMs, &u.CircuitMinRequests, &circuitOpenMs, &u.CircuitHalfOpenTrials,
+		&u.TLSCACert, &u.TLSClientCert, &tlsClientKey, &u.TLSServerName, &tlsInsecureSkipVerify,
 		&enabled, &u.CreatedAt, &u.UpdatedAt,
 	)
 	if errors.Is(err, sql.ErrNoRows) {
@@ -178,11 +218,18 @@ func scanUpstream(row *sql.Row) (route.Upstream, error) {
 
 	u.Timeout = time.Duration(timeoutMs) * time.Millisecond
 	u.IdleConnTimeout = time.Duration(idleConnTimeoutMs) * time.Millisecond
+	u.EjectionCooldown = time.Duration(ejectionCooldownMs) * time.Millisecond
+	u.HealthCheckInterval = time.Duration(healthCheckIntervalMs) * time.Millisecond
+	u.HealthCheckTimeout = time.Duration(healthCheckTimeoutMs) * time.Millisecond
+	u.CircuitWindow = time.Duration(circuitWindowMs) * time.Millisecond
+	u.CircuitOpenDuration = time.Duration(circuitOpenMs) * time.Millisecond
 	u.AuthType = route.AuthType(authType)
 	if authHeader.Valid {
 		u.AuthHeader = authHeader.String
 	}
 	u.AuthValue = string(authValue)
+	u.TLSClientKey = string(tlsClientKey)
+	u.TLSInsecureSkipVerify = tlsInsecureSkipVerify == 1
 	u.Enabled = enabled == 1
 
 	return u, nil
@@ -190,16 +237,24 @@ func scanUpstream(row *sql.Row) (route.Upstream, error) {
 
 func scanUpstreamRows(rows *sql.Rows) (route.Upstream, error) {
 	var u route.Upstream
-	var timeoutMs, idleConnTimeoutMs int64
+	var timeoutMs, idleConnTimeoutMs, ejectionCooldownMs int64
+	var healthCheckIntervalMs, healthCheckTimeoutMs int64
+	var circuitWindowMs, circuitOpenMs int64
 	var authType string
 	var authHeader sql.NullString
 	var authValue []byte
+	var tlsClientKey []byte
+	var tlsInsecureSkipVerify int
 	var enabled int
 
 	err := rows.Scan(
 		&u.ID, &u.Name, &u.Description, &u.BaseURL,
 		&timeoutMs, &u.MaxIdleConns, &idleConnTimeoutMs,
 		&authType, &authHeader, &authValue,
+		&u.FailureThreshold, &ejectionCooldownMs,
+		&u.HealthCheckPath, &healthCheckIntervalMs, &healthCheckTimeoutMs, &u.HealthCheckExpectedStatus,
+		&u.CircuitFailureRate, &circuitWindowMs, &u.CircuitMinRequests, &circuitOpenMs, &u.CircuitHalfOpenTrials,
+		&u.TLSCACert, &u.TLSClientCert, &tlsClientKey, &u.TLSServerName, &tlsInsecureSkipVerify,
 		&enabled, &u.CreatedAt, &u.UpdatedAt,
 	)
 	if err != nil {
@@ -208,11 +263,18 @@ func scanUpstreamRows(rows *sql.Rows) (route.Upstream, error) {
 
 	u.Timeout = time.Duration(timeoutMs) * time.Millisecond
 	u.IdleConnTimeout = time.Duration(idleConnTimeoutMs) * time.Millisecond
+	u.EjectionCooldown = time.Duration(ejectionCooldownMs) * time.Millisecond
+	u.HealthCheckInterval = time.Duration(healthCheckIntervalMs) * time.Millisecond
+	u.HealthCheckTimeout = time.Duration(healthCheckTimeoutMs) * time.Millisecond
+	u.CircuitWindow = time.Duration(circuitWindowMs) * time.Millisecond
+	u.CircuitOpenDuration = time.Duration(circuitOpenMs) * time.Millisecond
 	u.AuthType = route.AuthType(authType)
 	if authHeader.Valid {
 		u.AuthHeader = authHeader.String
 	}
 	u.AuthValue = string(authValue)
+	u.TLSClientKey = string(tlsClientKey)
+	u.TLSInsecureSkipVerify = tlsInsecureSkipVerify == 1
 	u.Enabled = enabled == 1
 
 	return u, nil
@@ -187,6 +187,137 @@ func (s *UsageStore) GetRecentRequests(ctx context.Context, userID string, limit
 	return events, rows.Err()
 }
 
+// GetTopPaths returns a user's most requested paths in [start, end),
+// busiest first.
+func (s *UsageStore) GetTopPaths(ctx context.Context, userID string, start, end time.Time, limit int) ([]usage.PathUsage, error) {
+	startStr := start.UTC().Format("2006-01-02 15:04:05")
+	endStr := end.UTC().Format("2006-01-02 15:04:05")
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			path,
+			COUNT(*) as request_count,
+			COALESCE(SUM(cost_multiplier), 0) as compute_units,
+			COALESCE(SUM(request_bytes), 0) as bytes_in,
+			COALESCE(SUM(response_bytes), 0) as bytes_out,
+			COALESCE(SUM(CASE WHEN status_code >= 400 THEN 1 ELSE 0 END), 0) as error_count
+		FROM usage_events
+		WHERE user_id = ? AND datetime(timestamp) >= datetime(?) AND datetime(timestamp) < datetime(?)
+		GROUP BY path
+		ORDER BY request_count DESC, path
+		LIMIT ?
+	`, userID, startStr, endStr, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var paths []usage.PathUsage
+	for rows.Next() {
+		var p usage.PathUsage
+		if err := rows.Scan(&p.Path, &p.RequestCount, &p.ComputeUnits, &p.BytesIn, &p.BytesOut, &p.ErrorCount); err != nil {
+			return nil, err
+		}
+		paths = append(paths, p)
+	}
+
+	return paths, rows.Err()
+}
+
+// EachEvent calls fn for each of a user's events in [start, end), oldest
+// first. Rows are streamed from the database rather than loaded up front.
+func (s *UsageStore) EachEvent(ctx context.Context, userID string, start, end time.Time, fn func(usage.Event) error) error {
+	startStr := start.UTC().Format("2006-01-02 15:04:05")
+	endStr := end.UTC().Format("2006-01-02 15:04:05")
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, key_id, user_id, method, path, status_code, latency_ms,
+		       request_bytes, response_bytes, cost_multiplier, ip_address, user_agent, timestamp
+		FROM usage_events
+		WHERE user_id = ? AND datetime(timestamp) >= datetime(?) AND datetime(timestamp) < datetime(?)
+		ORDER BY timestamp
+	`, userID, startStr, endStr)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e usage.Event
+		var ipAddress, userAgent sql.NullString
+
+		err := rows.Scan(
+			&e.ID, &e.KeyID, &e.UserID, &e.Method, &e.Path, &e.StatusCode, &e.LatencyMs,
+			&e.RequestBytes, &e.ResponseBytes, &e.CostMultiplier, &ipAddress, &userAgent, &e.Timestamp,
+		)
+		if err != nil {
+			return err
+		}
+
+		if ipAddress.Valid {
+			e.IPAddress = ipAddress.String
+		}
+		if userAgent.Valid {
+			e.UserAgent = userAgent.String
+		}
+
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// LatestRequestCursor returns the cursor of the most recently recorded
+// request, for GetRequestsAfter to follow new requests from.
+func (s *UsageStore) LatestRequestCursor(ctx context.Context) (int64, error) {
+	var cursor int64
+	err := s.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(rowid), 0) FROM usage_events`).Scan(&cursor)
+	return cursor, err
+}
+
+// GetRequestsAfter returns up to limit request logs recorded after cursor, in
+// the order they were recorded, and the cursor to continue from. Requests are
+// recorded in batches, so this follows recording order rather than timestamps.
+func (s *UsageStore) GetRequestsAfter(ctx context.Context, cursor int64, limit int) ([]usage.Event, int64, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT rowid, id, key_id, user_id, method, path, status_code, latency_ms,
+		       request_bytes, response_bytes, cost_multiplier, ip_address, user_agent, timestamp
+		FROM usage_events
+		WHERE rowid > ?
+		ORDER BY rowid
+		LIMIT ?
+	`, cursor, limit)
+	if err != nil {
+		return nil, cursor, err
+	}
+	defer rows.Close()
+
+	var events []usage.Event
+	for rows.Next() {
+		var e usage.Event
+		var ipAddress, userAgent sql.NullString
+
+		err := rows.Scan(
+			&cursor, &e.ID, &e.KeyID, &e.UserID, &e.Method, &e.Path, &e.StatusCode, &e.LatencyMs,
+			&e.RequestBytes, &e.ResponseBytes, &e.CostMultiplier, &ipAddress, &userAgent, &e.Timestamp,
+		)
+		if err != nil {
+			return nil, cursor, err
+		}
+
+		if ipAddress.Valid {
+			e.IPAddress = ipAddress.String
+		}
+		if userAgent.Valid {
+			e.UserAgent = userAgent.String
+		}
+
+		events = append(events, e)
+	}
+
+	return events, cursor, rows.Err()
+}
+
 // SaveSummary persists a pre-aggregated summary.
 func (s *UsageStore) SaveSummary(ctx context.Context, summary usage.Summary) error {
 	_, err := s.db.ExecContext(ctx, `
@@ -219,4 +350,7 @@ func (s *UsageStore) Cleanup(ctx context.Context, olderThan time.Time) (int64, e
 }
 
 // Ensure interface compliance.
-var _ ports.UsageStore = (*UsageStore)(nil)
+var (
+	_ ports.UsageStore         = (*UsageStore)(nil)
+	_ ports.UsageEventExporter = (*UsageStore)(nil)
+)
@@ -3,7 +3,9 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/artpar/apigate/ports"
@@ -29,9 +31,10 @@ func NewUserStore(db *DB) *UserStore {
 // Get retrieves a user by ID.
 func (s *UserStore) Get(ctx context.Context, id string) (ports.User, error) {
 	row := s.db.QueryRowContext(ctx, `
-		SELECT id, email, password_hash, name, stripe_id, plan_id, status, created_at, updated_at
+		SELECT id, email, password_hash, name, stripe_id, plan_id, status, created_at, updated_at, deleted_at,
+		       totp_secret, totp_enabled, recovery_codes, totp_last_step
 		FROM users
-		WHERE id = ?
+		WHERE id = ?`+userDeletedFilter(ctx, " AND ")+`
 	`, id)
 	return scanUser(row)
 }
@@ -39,9 +42,12 @@ func (s *UserStore) Get(ctx context.Context, id string) (ports.User, error) {
 // GetByEmail retrieves a user by email.
 func (s *UserStore) GetByEmail(ctx context.Context, email string) (ports.User, error) {
 	row := s.db.QueryRowContext(ctx, `
-		SELECT id, email, password_hash, name, stripe_id, plan_id, status, created_at, updated_at
+		SELECT id, email, password_hash, name, stripe_id, plan_id, status, created_at, updated_at, deleted_at,
+		       totp_secret, totp_enabled, recovery_codes, totp_last_step
 		FROM users
-		WHERE email = ?
+		WHERE email = ?`+userDeletedFilter(ctx, " AND ")+`
+		ORDER BY deleted_at IS NOT NULL, deleted_at DESC
+		LIMIT 1
 	`, email)
 	return scanUser(row)
 }
@@ -50,9 +56,10 @@ func (s *UserStore) GetByEmail(ctx context.Context, email string) (ports.User, e
 // Used by payment webhooks to find users from Stripe events.
 func (s *UserStore) GetByStripeID(ctx context.Context, stripeID string) (ports.User, error) {
 	row := s.db.QueryRowContext(ctx, `
-		SELECT id, email, password_hash, name, stripe_id, plan_id, status, created_at, updated_at
+		SELECT id, email, password_hash, name, stripe_id, plan_id, status, created_at, updated_at, deleted_at,
+		       totp_secret, totp_enabled, recovery_codes, totp_last_step
 		FROM users
-		WHERE stripe_id = ?
+		WHERE stripe_id = ?`+userDeletedFilter(ctx, " AND ")+`
 	`, stripeID)
 	return scanUser(row)
 }
@@ -67,10 +74,16 @@ func (s *UserStore) Create(ctx context.Context, u ports.User) error {
 		u.UpdatedAt = now
 	}
 
-	_, err := s.db.ExecContext(ctx, `
-		INSERT INTO users (id, email, password_hash, name, stripe_id, plan_id, status, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, u.ID, u.Email, u.PasswordHash, u.Name, nullString(u.StripeID), u.PlanID, u.Status, u.CreatedAt, u.UpdatedAt)
+	recoveryCodes, err := marshalStringSlice(u.RecoveryCodes)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO users (id, email, password_hash, name, stripe_id, plan_id, status, totp_secret, totp_enabled, recovery_codes, totp_last_step, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, u.ID, u.Email, u.PasswordHash, u.Name, nullString(u.StripeID), u.PlanID, u.Status,
+		nullString(u.TOTPSecret), u.TOTPEnabled, recoveryCodes, u.TOTPLastStep, u.CreatedAt, u.UpdatedAt)
 
 	if err != nil && isUniqueConstraintError(err) {
 		return ErrDuplicate
@@ -80,35 +93,64 @@ func (s *UserStore) Create(ctx context.Context, u ports.User) error {
 
 // Update modifies an existing user.
 func (s *UserStore) Update(ctx context.Context, u ports.User) error {
-	u.UpdatedAt = time.Now().UTC()
-
-	result, err := s.db.ExecContext(ctx, `
-		UPDATE users
-		SET email = ?, password_hash = ?, name = ?, stripe_id = ?, plan_id = ?, status = ?, updated_at = ?
-		WHERE id = ?
-	`, u.Email, u.PasswordHash, u.Name, nullString(u.StripeID), u.PlanID, u.Status, u.UpdatedAt, u.ID)
+	rows, err := s.update(ctx, u, "")
 	if err != nil {
-		if isUniqueConstraintError(err) {
-			return ErrDuplicate
-		}
 		return err
 	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
 
-	rows, err := result.RowsAffected()
+// UpdateIfUnchanged modifies an existing user only if its updated_at still
+// equals since, so a save based on a stale read can't overwrite a newer one.
+func (s *UserStore) UpdateIfUnchanged(ctx context.Context, u ports.User, since time.Time) error {
+	rows, err := s.update(ctx, u, " AND updated_at = ?", since)
 	if err != nil {
 		return err
 	}
 	if rows == 0 {
-		return ErrNotFound
+		if _, err := s.Get(ports.WithDeletedUsers(ctx), u.ID); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: user %s was modified", ports.ErrConflict, u.ID)
 	}
 	return nil
 }
 
+// update writes u with a fresh updated_at and returns the number of rows
+// changed. cond and its args further restrict the WHERE clause.
+func (s *UserStore) update(ctx context.Context, u ports.User, cond string, condArgs ...any) (int64, error) {
+	u.UpdatedAt = time.Now().UTC()
+
+	recoveryCodes, err := marshalStringSlice(u.RecoveryCodes)
+	if err != nil {
+		return 0, err
+	}
+
+	args := []any{u.Email, u.PasswordHash, u.Name, nullString(u.StripeID), u.PlanID, u.Status,
+		nullString(u.TOTPSecret), u.TOTPEnabled, recoveryCodes, u.TOTPLastStep, u.UpdatedAt, u.ID}
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE users
+		SET email = ?, password_hash = ?, name = ?, stripe_id = ?, plan_id = ?, status = ?,
+		    totp_secret = ?, totp_enabled = ?, recovery_codes = ?, totp_last_step = ?, updated_at = ?
+		WHERE id = ?`+cond, append(args, condArgs...)...)
+	if err != nil {
+		if isUniqueConstraintError(err) {
+			return 0, ErrDuplicate
+		}
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 // List returns users with pagination.
 func (s *UserStore) List(ctx context.Context, limit, offset int) ([]ports.User, error) {
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, email, password_hash, name, stripe_id, plan_id, status, created_at, updated_at
-		FROM users
+		SELECT id, email, password_hash, name, stripe_id, plan_id, status, created_at, updated_at, deleted_at,
+		       totp_secret, totp_enabled, recovery_codes, totp_last_step
+		FROM users`+userDeletedFilter(ctx, " WHERE ")+`
 		ORDER BY created_at DESC
 		LIMIT ? OFFSET ?
 	`, limit, offset)
@@ -131,13 +173,17 @@ func (s *UserStore) List(ctx context.Context, limit, offset int) ([]ports.User,
 // Count returns total user count.
 func (s *UserStore) Count(ctx context.Context) (int, error) {
 	var count int
-	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`).Scan(&count)
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`+userDeletedFilter(ctx, " WHERE ")).Scan(&count)
 	return count, err
 }
 
-// Delete permanently removes a user.
+// Delete soft-deletes a user. The row is kept so usage history and other
+// references stay intact, and the user can be restored.
 func (s *UserStore) Delete(ctx context.Context, id string) error {
-	result, err := s.db.ExecContext(ctx, `DELETE FROM users WHERE id = ?`, id)
+	now := time.Now().UTC()
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE users SET deleted_at = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL
+	`, now, now, id)
 	if err != nil {
 		return err
 	}
@@ -151,13 +197,47 @@ func (s *UserStore) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+// Restore clears the deletion of a soft-deleted user. It fails with
+// ErrConflict if the email has since been taken by another user.
+func (s *UserStore) Restore(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE users SET deleted_at = NULL, updated_at = ? WHERE id = ? AND deleted_at IS NOT NULL
+	`, time.Now().UTC(), id)
+	if isUniqueConstraintError(err) {
+		return fmt.Errorf("%w: email of user %s belongs to another user", ports.ErrConflict, id)
+	}
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// userDeletedFilter returns the condition hiding soft-deleted users, joined
+// with the given keyword, or "" if ctx includes deleted users.
+func userDeletedFilter(ctx context.Context, join string) string {
+	if ports.IncludeDeletedUsers(ctx) {
+		return ""
+	}
+	return join + "deleted_at IS NULL"
+}
+
 func scanUser(row *sql.Row) (ports.User, error) {
 	var u ports.User
 	var stripeID sql.NullString
 	var passwordHash []byte
+	var deletedAt sql.NullTime
+	var totpSecret, recoveryCodes sql.NullString
 
 	err := row.Scan(
-		&u.ID, &u.Email, &passwordHash, &u.Name, &stripeID, &u.PlanID, &u.Status, &u.CreatedAt, &u.UpdatedAt,
+		&u.ID, &u.Email, &passwordHash, &u.Name, &stripeID, &u.PlanID, &u.Status, &u.CreatedAt, &u.UpdatedAt, &deletedAt,
+		&totpSecret, &u.TOTPEnabled, &recoveryCodes, &u.TOTPLastStep,
 	)
 	if errors.Is(err, sql.ErrNoRows) {
 		return ports.User{}, ErrNotFound
@@ -170,6 +250,15 @@ func scanUser(row *sql.Row) (ports.User, error) {
 	if stripeID.Valid {
 		u.StripeID = stripeID.String
 	}
+	if deletedAt.Valid {
+		u.DeletedAt = &deletedAt.Time
+	}
+	u.TOTPSecret = totpSecret.String
+	if recoveryCodes.Valid && recoveryCodes.String != "" {
+		if err := json.Unmarshal([]byte(recoveryCodes.String), &u.RecoveryCodes); err != nil {
+			return ports.User{}, err
+		}
+	}
 	return u, nil
 }
 
@@ -177,9 +266,12 @@ func scanUserRows(rows *sql.Rows) (ports.User, error) {
 	var u ports.User
 	var stripeID sql.NullString
 	var passwordHash []byte
+	var deletedAt sql.NullTime
+	var totpSecret, recoveryCodes sql.NullString
 
 	err := rows.Scan(
-		&u.ID, &u.Email, &passwordHash, &u.Name, &stripeID, &u.PlanID, &u.Status, &u.CreatedAt, &u.UpdatedAt,
+		&u.ID, &u.Email, &passwordHash, &u.Name, &stripeID, &u.PlanID, &u.Status, &u.CreatedAt, &u.UpdatedAt, &deletedAt,
+		&totpSecret, &u.TOTPEnabled, &recoveryCodes, &u.TOTPLastStep,
 	)
 	if err != nil {
 		return ports.User{}, err
@@ -189,6 +281,15 @@ func scanUserRows(rows *sql.Rows) (ports.User, error) {
 	if stripeID.Valid {
 		u.StripeID = stripeID.String
 	}
+	if deletedAt.Valid {
+		u.DeletedAt = &deletedAt.Time
+	}
+	u.TOTPSecret = totpSecret.String
+	if recoveryCodes.Valid && recoveryCodes.String != "" {
+		if err := json.Unmarshal([]byte(recoveryCodes.String), &u.RecoveryCodes); err != nil {
+			return ports.User{}, err
+		}
+	}
 	return u, nil
 }
 
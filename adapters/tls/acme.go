@@ -97,10 +97,12 @@ type ACMEProvider struct {
 	logger     *slog.Logger
 
 	// ACME configuration
-	client      *acme.Client
-	email       string
-	staging     bool
-	renewalDays int
+	client       *acme.Client
+	directoryURL string
+	email        string
+	staging      bool
+	renewalDays  int
+	http01Only   bool
 
 	// Domain management
 	mu      sync.RWMutex
@@ -114,6 +116,10 @@ type ACMEProvider struct {
 	tlsAlpnCerts map[string]*cryptotls.Certificate // domain -> TLS-ALPN-01 challenge cert
 	http01Tokens map[string]string                 // token -> key authorization
 
+	// Orders in flight, so concurrent handshakes for a domain share one
+	obtainMu  sync.Mutex
+	obtaining map[string]*obtainCall
+
 	// Rate limit tracking (fast-fail)
 	rateLimitMu    sync.RWMutex
 	rateLimitUntil map[string]time.Time
@@ -129,6 +135,17 @@ type ACMEConfig struct {
 	Staging     bool     // Use staging server for testing
 	Domains     []string // Domains to obtain certificates for
 	RenewalDays int      // Days before expiry to renew (default: 30)
+	HTTP01Only  bool     // Only answer HTTP-01 challenges, when nothing serves TLS-ALPN-01 on :443
+
+	// DirectoryURL overrides the Let's Encrypt directory, for other ACME CAs
+	DirectoryURL string
+}
+
+// obtainCall is a certificate order in flight for a domain.
+type obtainCall struct {
+	done chan struct{}
+	cert *cryptotls.Certificate
+	err  error
 }
 
 // NewACMEProvider creates a new direct ACME TLS provider.
@@ -159,6 +176,9 @@ func NewACMEProvider(certStore ports.CertificateStore, cfg ACMEConfig) (*ACMEPro
 	if cfg.Staging {
 		directoryURL = letsEncryptStaging
 	}
+	if cfg.DirectoryURL != "" {
+		directoryURL = cfg.DirectoryURL
+	}
 	logger.Info("[ACME:INIT] Using ACME directory",
 		"url", directoryURL,
 		"is_staging", cfg.Staging)
@@ -222,10 +242,13 @@ func NewACMEProvider(certStore ports.CertificateStore, cfg ACMEConfig) (*ACMEPro
 		email:          cfg.Email,
 		staging:        cfg.Staging,
 		renewalDays:    renewalDays,
+		http01Only:     cfg.HTTP01Only,
 		domains:        cfg.Domains,
 		accountKey:     accountKey,
 		logger:         logger,
 		client:         acmeClient,
+		directoryURL:   cfg.DirectoryURL,
+		obtaining:      make(map[string]*obtainCall),
 		tlsAlpnCerts:   make(map[string]*cryptotls.Certificate),
 		http01Tokens:   make(map[string]string),
 		rateLimitUntil: make(map[string]time.Time),
@@ -309,6 +332,9 @@ func (p *ACMEProvider) SetLogger(logger *slog.Logger) {
 
 // getDirectoryURL returns the ACME directory URL.
 func (p *ACMEProvider) getDirectoryURL() string {
+	if p.directoryURL != "" {
+		return p.directoryURL
+	}
 	if p.staging {
 		return letsEncryptStaging
 	}
@@ -598,7 +624,7 @@ func (p *ACMEProvider) HTTPHandler(fallback http.Handler) http.Handler {
 }
 
 // selectChallenge selects the best challenge type from available challenges.
-// Prefers TLS-ALPN-01 over HTTP-01.
+// Prefers TLS-ALPN-01 over HTTP-01, unless only HTTP-01 is enabled.
 func (p *ACMEProvider) selectChallenge(challenges []*acme.Challenge) *acme.Challenge {
 	var tlsAlpn, http01 *acme.Challenge
 
@@ -612,12 +638,49 @@ func (p *ACMEProvider) selectChallenge(challenges []*acme.Challenge) *acme.Chall
 	}
 
 	// Prefer TLS-ALPN-01
-	if tlsAlpn != nil {
+	if tlsAlpn != nil && !p.http01Only {
 		return tlsAlpn
 	}
 	return http01
 }
 
+// obtainOnce obtains a certificate for a domain, or waits for the order already
+// in flight for it, so that concurrent handshakes place a single order. With
+// reuseCached, a certificate cached by an order that finished in the meantime
+// is returned instead of placing another.
+func (p *ACMEProvider) obtainOnce(ctx context.Context, domain string, reuseCached bool) (*cryptotls.Certificate, error) {
+	p.obtainMu.Lock()
+	if call, ok := p.obtaining[domain]; ok {
+		p.obtainMu.Unlock()
+		p.logger.Info("[ACME:OBTAIN] Waiting for order already in flight",
+			"domain", domain)
+		select {
+		case <-call.done:
+			return call.cert, call.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if reuseCached {
+		if cert := p.getCachedCert(domain); cert != nil {
+			p.obtainMu.Unlock()
+			return cert, nil
+		}
+	}
+	call := &obtainCall{done: make(chan struct{})}
+	p.obtaining[domain] = call
+	p.obtainMu.Unlock()
+
+	call.cert, call.err = p.obtainCertificateDirect(ctx, domain)
+
+	p.obtainMu.Lock()
+	delete(p.obtaining, domain)
+	p.obtainMu.Unlock()
+	close(call.done)
+
+	return call.cert, call.err
+}
+
 // obtainCertificateDirect obtains a certificate using direct ACME flow.
 // This is the main method that implements the 5-step ACME flow with explicit caching.
 func (p *ACMEProvider) obtainCertificateDirect(ctx context.Context, domain string) (*cryptotls.Certificate, error) {
@@ -1048,7 +1111,7 @@ func (p *ACMEProvider) GetCertificateWithLogging(hello *cryptotls.ClientHelloInf
 		"request_id", requestID,
 		"domain", domain)
 
-	cert, err := p.obtainCertificateDirect(ctx, domain)
+	cert, err := p.obtainOnce(ctx, domain, true)
 	totalDuration := time.Since(start)
 
 	if err != nil {
@@ -1101,7 +1164,7 @@ func (p *ACMEProvider) ObtainCertificate(ctx context.Context, domain string) (do
 	}
 
 	// Obtain via direct ACME flow
-	_, err := p.obtainCertificateDirect(ctx, domain)
+	_, err := p.obtainOnce(ctx, domain, false)
 	if err != nil {
 		p.logger.Error("[ACME:OBTAIN] Failed to obtain certificate",
 			"domain", domain,
@@ -0,0 +1,366 @@
+package tls
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	cryptotls "crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/sqlite"
+	"github.com/artpar/apigate/ports"
+)
+
+// fakeACMEServer is a minimal RFC 8555 CA in the spirit of pebble. It skips
+// JWS verification, offers only HTTP-01, and validates a challenge by asking
+// the provider under test for the key authorization.
+type fakeACMEServer struct {
+	t      *testing.T
+	srv    *httptest.Server
+	caKey  *ecdsa.PrivateKey
+	caCert *x509.Certificate
+
+	// validate fetches the HTTP-01 response for a token
+	validate func(token string) (string, error)
+
+	mu     sync.Mutex
+	nonce  int
+	orders []*fakeOrder
+}
+
+type fakeOrder struct {
+	domain  string
+	status  string
+	certPEM []byte
+}
+
+func newFakeACMEServer(t *testing.T) *fakeACMEServer {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate CA key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Fake ACME CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("create CA certificate: %v", err)
+	}
+	caCert, _ := x509.ParseCertificate(der)
+
+	s := &fakeACMEServer{t: t, caKey: caKey, caCert: caCert}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.srv.Close)
+	return s
+}
+
+func (s *fakeACMEServer) directoryURL() string {
+	return s.srv.URL + "/dir"
+}
+
+// orderCount returns how many orders have been placed.
+func (s *fakeACMEServer) orderCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.orders)
+}
+
+func (s *fakeACMEServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.nonce++
+	w.Header().Set("Replay-Nonce", fmt.Sprintf("nonce-%d", s.nonce))
+	s.mu.Unlock()
+
+	base := s.srv.URL
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch parts[0] {
+	case "dir":
+		s.writeJSON(w, http.StatusOK, map[string]any{
+			"newNonce":   base + "/nonce",
+			"newAccount": base + "/account",
+			"newOrder":   base + "/order",
+			"revokeCert": base + "/revoke",
+		})
+	case "nonce":
+		w.WriteHeader(http.StatusOK)
+	case "account":
+		w.Header().Set("Location", base+"/acct/1")
+		s.writeJSON(w, http.StatusCreated, map[string]any{"status": "valid"})
+	case "order":
+		if len(parts) == 1 {
+			var req struct {
+				Identifiers []struct{ Value string } `json:"identifiers"`
+			}
+			s.readPayload(r, &req)
+			s.mu.Lock()
+			s.orders = append(s.orders, &fakeOrder{domain: req.Identifiers[0].Value, status: "pending"})
+			id := len(s.orders) - 1
+			s.mu.Unlock()
+			w.Header().Set("Location", fmt.Sprintf("%s/order/%d", base, id))
+			s.writeJSON(w, http.StatusCreated, s.orderJSON(id))
+			return
+		}
+		s.writeJSON(w, http.StatusOK, s.orderJSON(s.orderID(parts[1])))
+	case "authz":
+		s.writeJSON(w, http.StatusOK, s.authzJSON(s.orderID(parts[1])))
+	case "chal":
+		id := s.orderID(parts[1])
+		status := "valid"
+		keyAuth, err := s.validate(fmt.Sprintf("token-%d", id))
+		if err != nil || !strings.HasPrefix(keyAuth, fmt.Sprintf("token-%d.", id)) {
+			status = "invalid"
+		}
+		s.mu.Lock()
+		s.orders[id].status = status
+		s.mu.Unlock()
+		s.writeJSON(w, http.StatusOK, s.authzJSON(id)["challenges"].([]map[string]any)[0])
+	case "finalize":
+		id := s.orderID(parts[1])
+		var req struct {
+			CSR string `json:"csr"`
+		}
+		s.readPayload(r, &req)
+		csrDER, _ := base64.RawURLEncoding.DecodeString(req.CSR)
+		csr, err := x509.ParseCertificateRequest(csrDER)
+		if err != nil {
+			s.t.Errorf("fake ACME: parse CSR: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		leaf := &x509.Certificate{
+			SerialNumber: big.NewInt(int64(id) + 100),
+			Subject:      pkix.Name{CommonName: csr.DNSNames[0]},
+			DNSNames:     csr.DNSNames,
+			NotBefore:    time.Now().Add(-time.Minute),
+			NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, leaf, s.caCert, csr.PublicKey, s.caKey)
+		if err != nil {
+			s.t.Errorf("fake ACME: issue certificate: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.mu.Lock()
+		s.orders[id].certPEM = append(
+			pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.caCert.Raw})...)
+		s.mu.Unlock()
+		w.Header().Set("Location", fmt.Sprintf("%s/order/%d", base, id))
+		s.writeJSON(w, http.StatusOK, s.orderJSON(id))
+	case "cert":
+		id := s.orderID(parts[1])
+		s.mu.Lock()
+		chain := s.orders[id].certPEM
+		s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/pem-certificate-chain")
+		w.Write(chain)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *fakeACMEServer) orderID(v string) int {
+	var id int
+	fmt.Sscanf(v, "%d", &id)
+	return id
+}
+
+func (s *fakeACMEServer) orderJSON(id int) map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o := s.orders[id]
+	resp := map[string]any{
+		"status":         o.status,
+		"identifiers":    []map[string]string{{"type": "dns", "value": o.domain}},
+		"authorizations": []string{fmt.Sprintf("%s/authz/%d", s.srv.URL, id)},
+		"finalize":       fmt.Sprintf("%s/finalize/%d", s.srv.URL, id),
+	}
+	if o.certPEM != nil {
+		resp["status"] = "valid"
+		resp["certificate"] = fmt.Sprintf("%s/cert/%d", s.srv.URL, id)
+	} else if o.status == "valid" {
+		resp["status"] = "ready"
+	}
+	return resp
+}
+
+func (s *fakeACMEServer) authzJSON(id int) map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o := s.orders[id]
+	return map[string]any{
+		"status":     o.status,
+		"identifier": map[string]string{"type": "dns", "value": o.domain},
+		"challenges": []map[string]any{{
+			"type":   challengeHTTP01,
+			"url":    fmt.Sprintf("%s/chal/%d", s.srv.URL, id),
+			"token":  fmt.Sprintf("token-%d", id),
+			"status": o.status,
+		}},
+	}
+}
+
+func (s *fakeACMEServer) readPayload(r *http.Request, v any) {
+	var jws struct {
+		Payload string `json:"payload"`
+	}
+	body, _ := io.ReadAll(r.Body)
+	if err := json.Unmarshal(body, &jws); err != nil {
+		s.t.Errorf("fake ACME: decode JWS: %v", err)
+		return
+	}
+	payload, _ := base64.RawURLEncoding.DecodeString(jws.Payload)
+	if err := json.Unmarshal(payload, v); err != nil {
+		s.t.Errorf("fake ACME: decode payload: %v", err)
+	}
+}
+
+func (s *fakeACMEServer) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// newOnDemandProvider returns a provider for domains backed by a fake CA that
+// validates challenges against the provider's own HTTP-01 handler.
+func newOnDemandProvider(t *testing.T, ca *fakeACMEServer, certStore ports.CertificateStore, domains ...string) *ACMEProvider {
+	t.Helper()
+
+	provider, err := NewACMEProvider(certStore, ACMEConfig{
+		Email:        "test@example.com",
+		Domains:      domains,
+		DirectoryURL: ca.directoryURL(),
+	})
+	if err != nil {
+		t.Fatalf("NewACMEProvider failed: %v", err)
+	}
+
+	ca.validate = func(token string) (string, error) {
+		// Give concurrent handshakes time to pile up behind the order
+		time.Sleep(50 * time.Millisecond)
+		w := httptest.NewRecorder()
+		provider.HTTPHandler(nil).ServeHTTP(w, httptest.NewRequest("GET", "/.well-known/acme-challenge/"+token, nil))
+		if w.Code != http.StatusOK {
+			return "", fmt.Errorf("challenge status %d", w.Code)
+		}
+		return w.Body.String(), nil
+	}
+	return provider
+}
+
+func TestGetCertificateWithLogging_ObtainsOnDemand(t *testing.T) {
+	db, err := sqlite.Open(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	certStore := sqlite.NewCertificateStore(db)
+
+	ca := newFakeACMEServer(t)
+	provider := newOnDemandProvider(t, ca, certStore, "app.example.test")
+	hello := &cryptotls.ClientHelloInfo{ServerName: "app.example.test"}
+
+	cert, err := provider.GetCertificateWithLogging(hello)
+	if err != nil {
+		t.Fatalf("first handshake: %v", err)
+	}
+	if cert.Leaf == nil || cert.Leaf.DNSNames[0] != "app.example.test" {
+		t.Fatalf("certificate is not for the requested domain: %+v", cert.Leaf)
+	}
+
+	stored, err := certStore.GetByDomain(context.Background(), "app.example.test")
+	if err != nil {
+		t.Fatalf("certificate not stored: %v", err)
+	}
+	if stored.Issuer != "Fake ACME CA" {
+		t.Errorf("stored issuer = %q", stored.Issuer)
+	}
+
+	// Later handshakes reuse it, as does a restarted provider via the store
+	again, err := provider.GetCertificateWithLogging(hello)
+	if err != nil || again != cert {
+		t.Errorf("second handshake = %v, %v; want the cached certificate", again, err)
+	}
+	restarted := newOnDemandProvider(t, ca, certStore, "app.example.test")
+	if _, err := restarted.GetCertificateWithLogging(hello); err != nil {
+		t.Errorf("handshake after restart: %v", err)
+	}
+	if n := ca.orderCount(); n != 1 {
+		t.Errorf("orders placed = %d, want 1", n)
+	}
+
+	// Domains outside the allowed list never reach the CA
+	if _, err := provider.GetCertificateWithLogging(&cryptotls.ClientHelloInfo{ServerName: "other.example.test"}); err == nil {
+		t.Error("handshake for a disallowed domain should fail")
+	}
+	if n := ca.orderCount(); n != 1 {
+		t.Errorf("orders placed = %d after disallowed handshake, want 1", n)
+	}
+}
+
+func TestGetCertificateWithLogging_ConcurrentHandshakesShareOrder(t *testing.T) {
+	db, err := sqlite.Open(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	ca := newFakeACMEServer(t)
+	provider := newOnDemandProvider(t, ca, sqlite.NewCertificateStore(db), "app.example.test")
+
+	const handshakes = 10
+	certs := make([]*cryptotls.Certificate, handshakes)
+	errs := make([]error, handshakes)
+	var wg sync.WaitGroup
+	for i := 0; i < handshakes; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			certs[i], errs[i] = provider.GetCertificateWithLogging(&cryptotls.ClientHelloInfo{ServerName: "app.example.test"})
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range certs {
+		if errs[i] != nil || certs[i] == nil {
+			t.Fatalf("handshake %d: %v", i, errs[i])
+		}
+		if certs[i] != certs[0] {
+			t.Errorf("handshake %d got a different certificate", i)
+		}
+	}
+	if n := ca.orderCount(); n != 1 {
+		t.Errorf("orders placed = %d, want 1", n)
+	}
+}
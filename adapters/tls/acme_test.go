@@ -199,6 +199,7 @@ func TestSelectChallenge(t *testing.T) {
 	tests := []struct {
 		name       string
 		challenges []*acme.Challenge
+		http01Only bool
 		wantType   string
 	}{
 		{
@@ -228,10 +229,20 @@ func TestSelectChallenge(t *testing.T) {
 			challenges: []*acme.Challenge{},
 			wantType:   "",
 		},
+		{
+			name: "HTTP-01 only",
+			challenges: []*acme.Challenge{
+				{Type: challengeTLSALPN01, Token: "tls-token"},
+				{Type: challengeHTTP01, Token: "http-token"},
+			},
+			http01Only: true,
+			wantType:   challengeHTTP01,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			provider.http01Only = tt.http01Only
 			result := provider.selectChallenge(tt.challenges)
 			if tt.wantType == "" {
 				if result != nil {
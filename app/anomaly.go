@@ -0,0 +1,370 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/artpar/apigate/domain/anomaly"
+	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/domain/ratelimit"
+	"github.com/artpar/apigate/domain/webhook"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+)
+
+// SpikeDetectorConfig contains configuration for SpikeDetector.
+type SpikeDetectorConfig struct {
+	Window           time.Duration // Recent period compared against the baseline (default: 5m)
+	Baseline         time.Duration // Trailing period a key's normal rate is measured over (default: 1h)
+	ThrottleDuration time.Duration // How long a spiking key stays throttled; also the alert cooldown (default: 1h)
+	AppName          string        // Application name used in the email (default: APIGate)
+	BaseURL          string        // Portal base URL for the link to the API keys page
+}
+
+// SpikeDetector watches each API key's request rate and alerts the key's
+// owner when the rate over the recent window exceeds the key's trailing
+// baseline by the plan's spike threshold. Plans with a throttle rate also
+// have the key's rate limit lowered until the throttle expires.
+//
+// Counts are kept in memory per minute, so each gateway instance watches the
+// traffic it serves. A key is only checked once it has been seen for a full
+// baseline period.
+type SpikeDetector struct {
+	users  ports.UserStore
+	email  ports.EmailSender     // Optional - nil disables alert emails
+	events ports.EventDispatcher // Optional - nil disables usage.spike events
+	logger zerolog.Logger
+	cfg    SpikeDetectorConfig
+
+	windowMins   int64
+	baselineMins int64
+
+	mu        sync.Mutex
+	keys      map[string]*keyActivity
+	lastPrune time.Time
+
+	pending sync.WaitGroup
+}
+
+// keyActivity is one key's request counts and alert state.
+type keyActivity struct {
+	firstSeen     time.Time
+	lastSeen      time.Time
+	buckets       []int64 // Requests per minute, indexed by minute modulo length
+	newest        int64   // Minute (since the Unix epoch) of the newest bucket
+	cooldownUntil time.Time
+	throttleRPM   int
+	throttleUntil time.Time
+}
+
+// NewSpikeDetector creates a new usage spike detector.
+func NewSpikeDetector(
+	users ports.UserStore,
+	email ports.EmailSender,
+	events ports.EventDispatcher,
+	logger zerolog.Logger,
+	cfg SpikeDetectorConfig,
+) *SpikeDetector {
+	if cfg.Window < time.Minute {
+		cfg.Window = 5 * time.Minute
+	}
+	if cfg.Baseline < time.Minute {
+		cfg.Baseline = time.Hour
+	}
+	if cfg.ThrottleDuration <= 0 {
+		cfg.ThrottleDuration = time.Hour
+	}
+	if cfg.AppName == "" {
+		cfg.AppName = "APIGate"
+	}
+
+	return &SpikeDetector{
+		users:        users,
+		email:        email,
+		events:       events,
+		logger:       logger.With().Str("service", "spike_detector").Logger(),
+		cfg:          cfg,
+		windowMins:   int64(cfg.Window / time.Minute),
+		baselineMins: int64(cfg.Baseline / time.Minute),
+		keys:         make(map[string]*keyActivity),
+	}
+}
+
+// Observe counts a request made with k at now and checks the key for a
+// spike using the plan's thresholds. It returns true when a new alert is
+// raised; alerts for a key are then suppressed for the throttle duration.
+// The email and webhook event are sent in the background.
+func (d *SpikeDetector) Observe(k key.Key, p plan.Plan, now time.Time) bool {
+	cfg := anomaly.Config{
+		Window:      time.Duration(d.windowMins) * time.Minute,
+		Baseline:    time.Duration(d.baselineMins) * time.Minute,
+		Threshold:   p.SpikeThreshold,
+		MinRequests: int64(p.SpikeMinRequests),
+	}
+	if !cfg.Enabled() {
+		return false
+	}
+
+	d.mu.Lock()
+	d.prune(now)
+	a := d.keys[k.ID]
+	if a == nil {
+		a = &keyActivity{firstSeen: now, buckets: make([]int64, d.windowMins+d.baselineMins)}
+		d.keys[k.ID] = a
+	}
+	a.add(now)
+
+	if now.Sub(a.firstSeen) < cfg.Baseline+cfg.Window || now.Before(a.cooldownUntil) {
+		d.mu.Unlock()
+		return false
+	}
+	result := anomaly.Detect(a.sum(0, d.windowMins), a.sum(d.windowMins, d.baselineMins), cfg)
+	if !result.Spike {
+		d.mu.Unlock()
+		return false
+	}
+	a.cooldownUntil = now.Add(d.cfg.ThrottleDuration)
+	if p.SpikeThrottleRPM > 0 {
+		a.throttleRPM = p.SpikeThrottleRPM
+		a.throttleUntil = now.Add(d.cfg.ThrottleDuration)
+	}
+	d.mu.Unlock()
+
+	d.logger.Warn().
+		Str("key_id", k.ID).
+		Str("user_id", k.UserID).
+		Float64("recent_rpm", result.RecentRate).
+		Float64("baseline_rpm", result.BaselineRate).
+		Int("throttle_rpm", p.SpikeThrottleRPM).
+		Msg("usage spike detected")
+
+	d.pending.Add(1)
+	go func() {
+		defer d.pending.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		d.alert(ctx, k, p, result, now)
+	}()
+	return true
+}
+
+// Throttle lowers cfg to the throttle rate while the key is throttled.
+func (d *SpikeDetector) Throttle(keyID string, cfg ratelimit.Config, now time.Time) ratelimit.Config {
+	d.mu.Lock()
+	a := d.keys[keyID]
+	var rpm int
+	if a != nil && now.Before(a.throttleUntil) {
+		rpm = a.throttleRPM
+	}
+	d.mu.Unlock()
+	if rpm <= 0 {
+		return cfg
+	}
+
+	limit := int(float64(rpm) * cfg.Window.Minutes())
+	if limit < 1 {
+		limit = 1
+	}
+	if limit < cfg.Limit {
+		cfg.Limit = limit
+	}
+	if cfg.Burst <= 0 || cfg.Burst > cfg.Limit {
+		cfg.Burst = cfg.Limit
+	}
+	return cfg
+}
+
+// Wait blocks until alerts being sent in the background have finished.
+func (d *SpikeDetector) Wait() {
+	d.pending.Wait()
+}
+
+// prune forgets keys idle for longer than the whole tracked period, at most
+// once per baseline period. Callers must hold d.mu.
+func (d *SpikeDetector) prune(now time.Time) {
+	if now.Sub(d.lastPrune) < d.cfg.Baseline {
+		return
+	}
+	d.lastPrune = now
+	idle := time.Duration(d.windowMins+d.baselineMins) * time.Minute
+	for id, a := range d.keys {
+		if now.Sub(a.lastSeen) > idle && !now.Before(a.throttleUntil) {
+			delete(d.keys, id)
+		}
+	}
+}
+
+// add counts one request in the bucket for now's minute, clearing buckets for
+// the minutes skipped since the newest one.
+func (a *keyActivity) add(now time.Time) {
+	minute := now.Unix() / 60
+	size := int64(len(a.buckets))
+	if a.lastSeen.IsZero() || minute-a.newest >= size {
+		clear(a.buckets)
+		a.newest = minute
+	}
+	for ; a.newest < minute; a.newest++ {
+		a.buckets[(a.newest+1)%size] = 0
+	}
+	// A clock that went backwards counts toward the newest minute
+	a.buckets[a.newest%size]++
+	a.lastSeen = now
+}
+
+// sum totals n minutes of buckets, starting skip minutes before the newest.
+func (a *keyActivity) sum(skip, n int64) int64 {
+	size := int64(len(a.buckets))
+	var total int64
+	for i := skip; i < skip+n && i < size; i++ {
+		total += a.buckets[(a.newest-i)%size]
+	}
+	return total
+}
+
+// alert emails the key's owner and publishes a usage.spike event.
+func (d *SpikeDetector) alert(ctx context.Context, k key.Key, p plan.Plan, result anomaly.Result, now time.Time) {
+	if d.events != nil {
+		data := map[string]interface{}{
+			"key_id":       k.ID,
+			"user_id":      k.UserID,
+			"plan_id":      p.ID,
+			"recent_rpm":   result.RecentRate,
+			"baseline_rpm": result.BaselineRate,
+			"factor":       result.Factor,
+			"throttle_rpm": p.SpikeThrottleRPM,
+			"detected_at":  now.Format(time.RFC3339),
+		}
+		if err := d.events.DispatchEvent(ctx, webhook.EventUsageSpike, k.UserID, data); err != nil {
+			d.logger.Warn().Err(err).Str("key_id", k.ID).Msg("failed to dispatch usage spike event")
+		}
+	}
+
+	if d.email == nil {
+		return
+	}
+	user, err := d.users.Get(ctx, k.UserID)
+	if err != nil {
+		d.logger.Warn().Err(err).Str("key_id", k.ID).Msg("owner of spiking key not found")
+		return
+	}
+	if user.Status != "active" || user.Email == "" {
+		return
+	}
+	msg, err := d.render(user, k, p, result)
+	if err != nil {
+		d.logger.Error().Err(err).Str("key_id", k.ID).Msg("failed to render usage spike email")
+		return
+	}
+	if err := d.email.Send(ctx, msg); err != nil {
+		d.logger.Warn().Err(err).Str("key_id", k.ID).Msg("failed to send usage spike email")
+	}
+}
+
+// usageSpikeEmailData holds data for the usage spike email templates.
+type usageSpikeEmailData struct {
+	Name        string
+	AppName     string
+	KeyName     string
+	KeyPrefix   string
+	RecentRPM   string
+	BaselineRPM string
+	Factor      string
+	ThrottleRPM int
+	Throttle    string
+	Link        string
+}
+
+func (d *SpikeDetector) render(user ports.User, k key.Key, p plan.Plan, result anomaly.Result) (ports.EmailMessage, error) {
+	data := usageSpikeEmailData{
+		Name:        user.Name,
+		AppName:     d.cfg.AppName,
+		KeyName:     k.Name,
+		KeyPrefix:   k.Prefix,
+		RecentRPM:   fmt.Sprintf("%.1f", result.RecentRate),
+		BaselineRPM: fmt.Sprintf("%.1f", result.BaselineRate),
+		Factor:      fmt.Sprintf("%.0f", result.Factor),
+		ThrottleRPM: p.SpikeThrottleRPM,
+		Throttle:    d.cfg.ThrottleDuration.String(),
+		Link:        strings.TrimSuffix(d.cfg.BaseURL, "/") + "/portal/api-keys",
+	}
+	if data.Name == "" {
+		data.Name = user.Email
+	}
+	if data.KeyName == "" {
+		data.KeyName = k.Prefix
+	}
+
+	var htmlBuf, textBuf bytes.Buffer
+	if err := usageSpikeHTMLTmpl.Execute(&htmlBuf, data); err != nil {
+		return ports.EmailMessage{}, fmt.Errorf("execute usage spike html template: %w", err)
+	}
+	if err := usageSpikeTextTmpl.Execute(&textBuf, data); err != nil {
+		return ports.EmailMessage{}, fmt.Errorf("execute usage spike text template: %w", err)
+	}
+
+	return ports.EmailMessage{
+		To:       user.Email,
+		Subject:  fmt.Sprintf("Unusual traffic on your %s API key", d.cfg.AppName),
+		HTMLBody: htmlBuf.String(),
+		TextBody: textBuf.String(),
+	}, nil
+}
+
+var usageSpikeHTMLTmpl = htmltemplate.Must(htmltemplate.New("usageSpike").Parse(strings.TrimSpace(`
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Unusual API Traffic</title>
+    <style>
+        body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { text-align: center; padding: 20px 0; }
+        .content { background: #f9f9f9; padding: 30px; border-radius: 8px; }
+        .button { display: inline-block; background: #007bff; color: white; padding: 12px 30px; text-decoration: none; border-radius: 5px; margin: 20px 0; }
+        .footer { text-align: center; padding: 20px; color: #666; font-size: 14px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>{{.AppName}}</h1>
+        </div>
+        <div class="content">
+            <h2>Unusual traffic on your API key</h2>
+            <p>Hi {{.Name}},</p>
+            <p>Your API key <strong>{{.KeyName}}</strong> (<code>{{.KeyPrefix}}...</code>) made <strong>{{.RecentRPM}} requests per minute</strong> recently, about {{.Factor}} times its usual rate of {{.BaselineRPM}} per minute.</p>
+            {{if gt .ThrottleRPM 0}}<p>To protect your quota, the key is limited to {{.ThrottleRPM}} requests per minute for the next {{.Throttle}}.</p>{{end}}
+            <p>If you don't recognise this traffic, the key may have leaked. Revoke it and create a new one.</p>
+            <p style="text-align: center;">
+                <a href="{{.Link}}" class="button">Manage API Keys</a>
+            </p>
+        </div>
+        <div class="footer">
+            <p>You are receiving this because you own an API key on {{.AppName}}.</p>
+        </div>
+    </div>
+</body>
+</html>
+`)))
+
+var usageSpikeTextTmpl = texttemplate.Must(texttemplate.New("usageSpike").Parse(`Hi {{.Name}},
+
+Your {{.AppName}} API key "{{.KeyName}}" ({{.KeyPrefix}}...) made {{.RecentRPM}} requests per minute recently, about {{.Factor}} times its usual rate of {{.BaselineRPM}} per minute.
+{{if gt .ThrottleRPM 0}}
+To protect your quota, the key is limited to {{.ThrottleRPM}} requests per minute for the next {{.Throttle}}.
+{{end}}
+If you don't recognise this traffic, the key may have leaked. Revoke it and create a new one.
+
+Manage your API keys: {{.Link}}
+
+Thanks,
+The {{.AppName}} Team`))
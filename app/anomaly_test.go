@@ -0,0 +1,235 @@
+package app_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	"github.com/artpar/apigate/adapters/email"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/domain/proxy"
+	"github.com/artpar/apigate/domain/ratelimit"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var spikePlan = plan.Plan{ID: "pro", SpikeThreshold: 5, SpikeMinRequests: 50, SpikeThrottleRPM: 10}
+
+func newTestSpikeDetector(t *testing.T) (*app.SpikeDetector, *email.MockSender, *testEventDispatcher) {
+	t.Helper()
+	users := memory.NewUserStore()
+	users.Create(context.Background(), ports.User{ID: "user-1", Email: "owner@example.com", Name: "Owner", Status: "active"})
+
+	sender := email.NewMockSender("https://portal.example.com", "Acme API")
+	events := &testEventDispatcher{}
+	d := app.NewSpikeDetector(users, sender, events, zerolog.Nop(), app.SpikeDetectorConfig{
+		Window:           5 * time.Minute,
+		Baseline:         time.Hour,
+		ThrottleDuration: 30 * time.Minute,
+		AppName:          "Acme API",
+		BaseURL:          "https://portal.example.com",
+	})
+	return d, sender, events
+}
+
+// stream feeds the detector perMinute(m) requests, evenly spaced, for each
+// minute m of the given duration and returns the number of alerts raised and
+// the time after the last minute.
+func stream(d *app.SpikeDetector, k key.Key, p plan.Plan, start time.Time, minutes int, perMinute func(m int) int) (int, time.Time) {
+	alerts := 0
+	for m := 0; m < minutes; m++ {
+		n := perMinute(m)
+		for i := 0; i < n; i++ {
+			at := start.Add(time.Duration(m)*time.Minute + time.Duration(i)*time.Minute/time.Duration(n))
+			if d.Observe(k, p, at) {
+				alerts++
+			}
+		}
+	}
+	return alerts, start.Add(time.Duration(minutes) * time.Minute)
+}
+
+func steady(rate int) func(int) int {
+	return func(int) int { return rate }
+}
+
+func TestSpikeDetector_BurstTriggersAlert(t *testing.T) {
+	d, sender, events := newTestSpikeDetector(t)
+	k := key.Key{ID: "key-1", UserID: "user-1", Name: "Production", Prefix: "ak_prod0000"}
+
+	alerts, now := stream(d, k, spikePlan, baseTime, 90, steady(10))
+	if alerts != 0 {
+		t.Fatalf("steady traffic raised %d alerts", alerts)
+	}
+
+	// A leaked key suddenly sends 20x its usual traffic
+	alerts, now = stream(d, k, spikePlan, now, 5, steady(200))
+	if alerts != 1 {
+		t.Fatalf("burst raised %d alerts, want 1", alerts)
+	}
+	d.Wait()
+
+	msgs := sender.FindByTo("owner@example.com")
+	if len(msgs) != 1 {
+		t.Fatalf("got %d emails, want 1", len(msgs))
+	}
+	if !strings.Contains(msgs[0].TextBody, "Production") || !strings.Contains(msgs[0].TextBody, "10 requests per minute") {
+		t.Errorf("email body missing key name or throttle rate:\n%s", msgs[0].TextBody)
+	}
+	if n := events.count(); n != 1 {
+		t.Errorf("got %d usage.spike events, want 1", n)
+	}
+
+	// The key is throttled to the plan's spike rate until the throttle expires
+	cfg := ratelimit.Config{Limit: 600, Window: time.Minute, Burst: 700}
+	if got := d.Throttle(k.ID, cfg, now); got.Limit != 10 || got.Burst != 10 {
+		t.Errorf("throttled config = %d/%d, want 10/10", got.Limit, got.Burst)
+	}
+	if got := d.Throttle(k.ID, cfg, now.Add(30*time.Minute)); got != cfg {
+		t.Errorf("config after throttle expiry = %+v, want %+v", got, cfg)
+	}
+	if got := d.Throttle("other-key", cfg, now); got != cfg {
+		t.Errorf("unrelated key was throttled: %+v", got)
+	}
+}
+
+func TestSpikeDetector_NoAlert(t *testing.T) {
+	k := key.Key{ID: "key-1", UserID: "user-1"}
+
+	tests := []struct {
+		name      string
+		p         plan.Plan
+		minutes   int
+		perMinute func(m int) int
+	}{
+		{"steady rate", spikePlan, 180, steady(30)},
+		{"gradual ramp", spikePlan, 180, func(m int) int { return 10 + m/2 }},
+		{"daily-style doubling", spikePlan, 120, func(m int) int {
+			if m < 60 {
+				return 20
+			}
+			return 40
+		}},
+		{"burst below minimum requests", spikePlan, 70, func(m int) int {
+			if m >= 65 {
+				return 8 // 8x the baseline, but only 40 requests in the window
+			}
+			return 1
+		}},
+		{"burst during warm-up", spikePlan, 30, func(m int) int {
+			if m >= 20 {
+				return 500
+			}
+			return 1
+		}},
+		{"detection disabled", plan.Plan{ID: "free"}, 80, func(m int) int {
+			if m >= 70 {
+				return 500
+			}
+			return 5
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, sender, events := newTestSpikeDetector(t)
+			alerts, now := stream(d, k, tt.p, baseTime, tt.minutes, tt.perMinute)
+			d.Wait()
+			if alerts != 0 || len(sender.GetEmails()) != 0 || events.count() != 0 {
+				t.Errorf("got %d alerts, %d emails, %d events; want none", alerts, len(sender.GetEmails()), events.count())
+			}
+			cfg := ratelimit.Config{Limit: 600, Window: time.Minute}
+			if got := d.Throttle(k.ID, cfg, now); got != cfg {
+				t.Errorf("key was throttled: %+v", got)
+			}
+		})
+	}
+}
+
+func TestSpikeDetector_AlertCooldown(t *testing.T) {
+	d, sender, _ := newTestSpikeDetector(t)
+	k := key.Key{ID: "key-1", UserID: "user-1"}
+	alertOnly := spikePlan
+	alertOnly.SpikeThrottleRPM = 0
+
+	_, now := stream(d, k, alertOnly, baseTime, 70, steady(5))
+	// A sustained spike alerts once per cooldown, not on every request
+	alerts, now := stream(d, k, alertOnly, now, 20, steady(300))
+	if alerts != 1 {
+		t.Fatalf("sustained spike raised %d alerts during the cooldown, want 1", alerts)
+	}
+	d.Wait()
+	if n := len(sender.GetEmails()); n != 1 {
+		t.Errorf("got %d emails, want 1", n)
+	}
+
+	// Alert-only plans never throttle
+	cfg := ratelimit.Config{Limit: 600, Window: time.Minute}
+	if got := d.Throttle(k.ID, cfg, now); got != cfg {
+		t.Errorf("alert-only plan throttled the key: %+v", got)
+	}
+}
+
+func TestProxyService_SpikeThrottlesKey(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(baseTime)
+	stores := &testStores{keys: memory.NewKeyStore(), users: memory.NewUserStore(), rateLimit: memory.NewRateLimitStore(), usage: &testUsageRecorder{}}
+	svc := app.NewProxyService(app.ProxyDeps{
+		Keys:      stores.keys,
+		Users:     stores.users,
+		RateLimit: stores.rateLimit,
+		Usage:     stores.usage,
+		Upstream:  &testUpstream{},
+		Clock:     clk,
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateWindow: 60,
+		Plans: []plan.Plan{{
+			ID: "pro", Name: "Pro", RateLimitPerMinute: 1000, RequestsPerMonth: -1,
+			SpikeThreshold: 5, SpikeMinRequests: 20, SpikeThrottleRPM: 2,
+		}},
+	})
+	detector := app.NewSpikeDetector(stores.users, nil, nil, zerolog.Nop(), app.SpikeDetectorConfig{
+		Window:   time.Minute,
+		Baseline: 10 * time.Minute,
+	})
+	svc.SetSpikeDetector(detector)
+
+	rawKey := "ak_0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	keyHash, _ := bcrypt.GenerateFromPassword([]byte(rawKey), bcrypt.MinCost)
+	stores.keys.Create(ctx, key.Key{ID: "key-1", UserID: "user-1", Hash: keyHash, Prefix: rawKey[:12], CreatedAt: baseTime.Add(-time.Hour)})
+	stores.users.Create(ctx, ports.User{ID: "user-1", Email: "test@example.com", PlanID: "pro", Status: "active", CreatedAt: baseTime.Add(-time.Hour)})
+	req := proxy.Request{APIKey: rawKey, Method: "GET", Path: "/api/data"}
+
+	// Twelve quiet minutes establish the baseline
+	for m := 0; m < 12; m++ {
+		if result := svc.Handle(ctx, req); result.Error != nil {
+			t.Fatalf("baseline request: unexpected error %v", result.Error)
+		}
+		clk.Advance(time.Minute)
+	}
+
+	// A burst is allowed until the spike is detected on the 20th request,
+	// then the key is held to the plan's throttle rate
+	var limited int
+	for i := 0; i < 100; i++ {
+		result := svc.Handle(ctx, req)
+		if result.Error != nil {
+			if i < 20 || result.Error.Code != proxy.ErrRateLimited.Code {
+				t.Fatalf("request %d: unexpected error %v", i, result.Error)
+			}
+			limited++
+		}
+		clk.Advance(100 * time.Millisecond)
+	}
+	detector.Wait()
+	if limited < 75 {
+		t.Errorf("only %d of 100 burst requests were throttled", limited)
+	}
+}
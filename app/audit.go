@@ -0,0 +1,133 @@
+package app
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/artpar/apigate/domain/audit"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+)
+
+// AuditLogConfig contains configuration for AuditLog.
+type AuditLogConfig struct {
+	BufferSize    int           // Entries queued before new ones are dropped (default: 1000)
+	BatchSize     int           // Entries written per store call (default: 100)
+	FlushInterval time.Duration // Maximum time an entry waits before being written (default: 1s)
+}
+
+// AuditLog writes audit entries to the store in the background so admin
+// requests never wait on it. The queue is bounded: when it is full, new
+// entries are dropped and counted rather than blocking the request.
+type AuditLog struct {
+	store  ports.AuditStore
+	logger zerolog.Logger
+	cfg    AuditLogConfig
+
+	entries chan audit.Entry
+	dropped atomic.Int64
+	stop    chan struct{}
+	done    sync.WaitGroup
+}
+
+// NewAuditLog creates a new audit log writer. Call Start to begin writing.
+func NewAuditLog(store ports.AuditStore, logger zerolog.Logger, cfg AuditLogConfig) *AuditLog {
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 1000
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+
+	return &AuditLog{
+		store:   store,
+		logger:  logger.With().Str("service", "audit").Logger(),
+		cfg:     cfg,
+		entries: make(chan audit.Entry, cfg.BufferSize),
+		stop:    make(chan struct{}),
+	}
+}
+
+// Record queues an entry without blocking. If the queue is full the entry
+// is dropped.
+func (l *AuditLog) Record(entry audit.Entry) {
+	select {
+	case l.entries <- entry:
+	default:
+		dropped := l.dropped.Add(1)
+		l.logger.Warn().
+			Str("action", entry.Action).
+			Str("resource_type", entry.ResourceType).
+			Str("resource_id", entry.ResourceID).
+			Int64("dropped_total", dropped).
+			Msg("audit queue full, entry dropped")
+	}
+}
+
+// Dropped returns the number of entries dropped because the queue was full.
+func (l *AuditLog) Dropped() int64 {
+	return l.dropped.Load()
+}
+
+// Start writes queued entries in the background.
+func (l *AuditLog) Start() {
+	l.done.Add(1)
+	go l.loop()
+}
+
+// Stop writes any queued entries and stops the background writer.
+func (l *AuditLog) Stop() {
+	close(l.stop)
+	l.done.Wait()
+}
+
+func (l *AuditLog) loop() {
+	defer l.done.Done()
+	ticker := time.NewTicker(l.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]audit.Entry, 0, l.cfg.BatchSize)
+	for {
+		select {
+		case e := <-l.entries:
+			batch = append(batch, e)
+			if len(batch) >= l.cfg.BatchSize {
+				batch = l.write(batch)
+			}
+		case <-ticker.C:
+			batch = l.write(batch)
+		case <-l.stop:
+			for {
+				select {
+				case e := <-l.entries:
+					batch = append(batch, e)
+				default:
+					l.write(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// write stores a batch and returns the emptied batch for reuse. Failed
+// batches are logged and discarded so a broken store cannot back up the queue.
+func (l *AuditLog) write(batch []audit.Entry) []audit.Entry {
+	if len(batch) == 0 {
+		return batch
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := l.store.Append(ctx, batch); err != nil {
+		l.logger.Error().Err(err).Int("count", len(batch)).Msg("failed to write audit entries")
+	}
+	return batch[:0]
+}
+
+// Ensure interface compliance.
+var _ ports.AuditRecorder = (*AuditLog)(nil)
@@ -0,0 +1,71 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/audit"
+	"github.com/rs/zerolog"
+)
+
+// blockingAuditStore holds every Append until released.
+type blockingAuditStore struct {
+	*memory.AuditStore
+	release chan struct{}
+}
+
+func (s *blockingAuditStore) Append(ctx context.Context, entries []audit.Entry) error {
+	<-s.release
+	return s.AuditStore.Append(ctx, entries)
+}
+
+func TestAuditLog_WritesOnStop(t *testing.T) {
+	store := memory.NewAuditStore()
+	log := app.NewAuditLog(store, zerolog.Nop(), app.AuditLogConfig{FlushInterval: time.Hour})
+	log.Start()
+
+	for _, id := range []string{"a", "b", "c"} {
+		log.Record(audit.NewEntry("admin", audit.ActionDelete, audit.ResourceUser, id, nil, nil, time.Now()))
+	}
+	log.Stop()
+
+	entries, _ := store.List(context.Background(), audit.Filter{})
+	if len(entries) != 3 {
+		t.Errorf("got %d entries after Stop, want 3", len(entries))
+	}
+}
+
+func TestAuditLog_RecordDoesNotBlockWhenFull(t *testing.T) {
+	store := &blockingAuditStore{AuditStore: memory.NewAuditStore(), release: make(chan struct{})}
+	log := app.NewAuditLog(store, zerolog.Nop(), app.AuditLogConfig{BufferSize: 2, BatchSize: 1})
+	log.Start()
+
+	// The writer takes one entry and blocks in the store; two more fill the
+	// queue and the rest are dropped without blocking.
+	recorded := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			log.Record(audit.NewEntry("admin", audit.ActionUpdate, audit.ResourcePlan, "pro", nil, nil, time.Now()))
+		}
+		close(recorded)
+	}()
+	select {
+	case <-recorded:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Record blocked on a full queue")
+	}
+
+	close(store.release)
+	log.Stop()
+
+	entries, _ := store.List(context.Background(), audit.Filter{})
+	if got := int64(len(entries)) + log.Dropped(); got != 10 {
+		t.Errorf("written (%d) + dropped (%d) = %d, want 10", len(entries), log.Dropped(), got)
+	}
+	if log.Dropped() == 0 {
+		t.Error("expected entries to be dropped when the queue is full")
+	}
+}
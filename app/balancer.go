@@ -0,0 +1,124 @@
+package app
+
+import (
+	"sync"
+
+	"github.com/artpar/apigate/domain/route"
+	"github.com/artpar/apigate/ports"
+)
+
+// UpstreamHealth reports whether an upstream should receive traffic.
+// Implementations must be safe for concurrent use.
+type UpstreamHealth interface {
+	IsHealthy(upstreamID string) bool
+}
+
+// upstreamBalancer keeps per-route weighted round-robin state.
+// It is safe for concurrent use.
+type upstreamBalancer struct {
+	mu    sync.Mutex
+	state map[string][]int // route ID -> running weights of pool members
+}
+
+func newUpstreamBalancer() *upstreamBalancer {
+	return &upstreamBalancer{state: make(map[string][]int)}
+}
+
+// pick selects the next pool member for the route.
+// Returns the upstream ID and false if no member is eligible.
+func (b *upstreamBalancer) pick(routeID string, pool []route.WeightedUpstream, eligible func(string) bool) (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	current := b.state[routeID]
+	if len(current) != len(pool) {
+		// Pool changed since the last pick - start over
+		current = make([]int, len(pool))
+		b.state[routeID] = current
+	}
+
+	idx := route.SelectWeighted(pool, current, eligible)
+	if idx < 0 {
+		return "", false
+	}
+	return pool[idx].UpstreamID, true
+}
+
+// passiveHealthTracker records live-traffic outcomes per upstream and ejects
+// upstreams that fail repeatedly. It is safe for concurrent use.
+type passiveHealthTracker struct {
+	mu    sync.Mutex
+	clock ports.Clock
+	state map[string]route.PassiveHealth // upstream ID -> health
+}
+
+func newPassiveHealthTracker(clock ports.Clock) *passiveHealthTracker {
+	return &passiveHealthTracker{clock: clock, state: make(map[string]route.PassiveHealth)}
+}
+
+// record updates the upstream's state and returns true if this outcome ejected it.
+func (t *passiveHealthTracker) record(u *route.Upstream, failed bool) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.clock.Now()
+	prev := t.state[u.ID]
+	next := prev.Record(*u, failed, now)
+	t.state[u.ID] = next
+	return !prev.Ejected(now) && next.Ejected(now)
+}
+
+// ejected returns true if the upstream is currently out of rotation.
+func (t *passiveHealthTracker) ejected(upstreamID string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.state[upstreamID].Ejected(t.clock.Now())
+}
+
+// circuitBreakerTracker holds per-upstream circuit breaker state. It is safe
+// for concurrent use.
+type circuitBreakerTracker struct {
+	mu    sync.Mutex
+	clock ports.Clock
+	state map[string]route.CircuitBreaker // upstream ID -> breaker
+}
+
+func newCircuitBreakerTracker(clock ports.Clock) *circuitBreakerTracker {
+	return &circuitBreakerTracker{clock: clock, state: make(map[string]route.CircuitBreaker)}
+}
+
+// allow reports whether a request may be sent to the upstream, reserving a
+// trial slot when the circuit is half-open.
+func (t *circuitBreakerTracker) allow(u *route.Upstream) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	next, ok := t.state[u.ID].Allow(*u, t.clock.Now())
+	t.state[u.ID] = next
+	return ok
+}
+
+// record updates the breaker and returns the previous and new states.
+func (t *circuitBreakerTracker) record(u *route.Upstream, failed bool) (route.CircuitState, route.CircuitState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	prev := t.state[u.ID]
+	next := prev.Record(*u, failed, t.clock.Now())
+	t.state[u.ID] = next
+	return prev.State, next.State
+}
+
+// release frees a half-open trial reserved for a request with a neutral outcome.
+func (t *circuitBreakerTracker) release(u *route.Upstream) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state[u.ID] = t.state[u.ID].Release(*u, t.clock.Now())
+}
+
+// stateOf returns the upstream's current circuit state.
+func (t *circuitBreakerTracker) stateOf(u route.Upstream) route.CircuitState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.state[u.ID].StateAt(u, t.clock.Now())
+}
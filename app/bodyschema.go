@@ -0,0 +1,49 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/artpar/apigate/domain/proxy"
+	"github.com/artpar/apigate/domain/route"
+)
+
+// validateBody checks the body of a POST, PUT or PATCH request against the
+// route's request schema. It returns nil if the route has no schema or the
+// body satisfies it, and otherwise a 400 listing each offending field.
+func (s *ProxyService) validateBody(ctx context.Context, req proxy.Request, matchedRoute *route.Route) *proxy.ErrorResponse {
+	if matchedRoute == nil || matchedRoute.RequestSchema == "" || s.routeService == nil {
+		return nil
+	}
+	switch req.Method {
+	case "POST", "PUT", "PATCH":
+	default:
+		return nil
+	}
+	schema := s.routeService.table(ctx).bodySchema(matchedRoute.ID)
+	if schema == nil {
+		return nil
+	}
+
+	invalid := func(fields ...proxy.FieldError) *proxy.ErrorResponse {
+		resp := proxy.ErrInvalidRequestBody
+		resp.Fields = fields
+		return &resp
+	}
+	if len(req.Body) == 0 {
+		return invalid(proxy.FieldError{Message: "request body is required"})
+	}
+	var body any
+	if err := json.Unmarshal(req.Body, &body); err != nil {
+		return invalid(proxy.FieldError{Message: "request body is not valid JSON"})
+	}
+	errs := schema.Validate(body)
+	if len(errs) == 0 {
+		return nil
+	}
+	fields := make([]proxy.FieldError, len(errs))
+	for i, e := range errs {
+		fields[i] = proxy.FieldError{Pointer: e.Pointer, Message: e.Message}
+	}
+	return invalid(fields...)
+}
@@ -0,0 +1,55 @@
+package app
+
+import (
+	"io"
+	"sync"
+)
+
+// concurrencyLimiter counts in-flight requests per API key. Counts are kept
+// in memory, so each gateway instance enforces its plan limits on its own.
+type concurrencyLimiter struct {
+	mu       sync.Mutex
+	inFlight map[string]int
+}
+
+func newConcurrencyLimiter() *concurrencyLimiter {
+	return &concurrencyLimiter{inFlight: make(map[string]int)}
+}
+
+// acquire takes one of the limit slots for keyID. It returns false when all
+// slots are in use. The returned release frees the slot and may be called
+// more than once. A limit of 0 or less means unlimited.
+func (l *concurrencyLimiter) acquire(keyID string, limit int) (release func(), ok bool) {
+	if limit <= 0 {
+		return func() {}, true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inFlight[keyID] >= limit {
+		return nil, false
+	}
+	l.inFlight[keyID]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			if l.inFlight[keyID]--; l.inFlight[keyID] <= 0 {
+				delete(l.inFlight, keyID)
+			}
+		})
+	}, true
+}
+
+// releasingStream frees a concurrency slot when the relayed stream is closed.
+type releasingStream struct {
+	io.ReadCloser
+	release func()
+}
+
+func (s *releasingStream) Close() error {
+	defer s.release()
+	return s.ReadCloser.Close()
+}
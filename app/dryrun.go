@@ -0,0 +1,238 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/domain/proxy"
+	"github.com/artpar/apigate/domain/quota"
+	"github.com/artpar/apigate/domain/ratelimit"
+	"github.com/artpar/apigate/domain/route"
+)
+
+// DryRunRequest is a synthetic request to evaluate without proxying it.
+type DryRunRequest struct {
+	Method   string            `json:"method"`
+	Path     string            `json:"path"`
+	Query    string            `json:"query,omitempty"` // Raw query string, without the leading "?"
+	Host     string            `json:"host,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	APIKey   string            `json:"api_key,omitempty"`   // API key or bearer token the client would send
+	RemoteIP string            `json:"remote_ip,omitempty"` // Client IP, checked against key IP allowlists
+}
+
+// DryRunResult describes how the proxy would handle a request.
+type DryRunResult struct {
+	Decision string `json:"decision"`         // "allow" or "reject"
+	Status   int    `json:"status,omitempty"` // Status the gateway would reject the request with
+	Error    string `json:"error,omitempty"`  // Error code the gateway would reject the request with
+	Message  string `json:"message,omitempty"`
+
+	Route     *DryRunRoute     `json:"route,omitempty"`    // nil when no route matches
+	Upstream  *DryRunUpstream  `json:"upstream,omitempty"` // nil for the default upstream
+	Auth      DryRunAuth       `json:"auth"`
+	RateLimit *DryRunRateLimit `json:"rate_limit,omitempty"`
+	Quota     *DryRunQuota     `json:"quota,omitempty"`
+}
+
+// DryRunRoute is the route a dry-run request matched.
+type DryRunRoute struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	PathParams map[string]string `json:"path_params,omitempty"`
+}
+
+// DryRunUpstream is the upstream a dry-run request would be sent to.
+type DryRunUpstream struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// DryRunAuth is the authentication decision for a dry-run request.
+type DryRunAuth struct {
+	Required      bool     `json:"required"`
+	Method        string   `json:"method,omitempty"`
+	Authenticated bool     `json:"authenticated"`
+	KeyID         string   `json:"key_id,omitempty"`
+	UserID        string   `json:"user_id,omitempty"`
+	PlanID        string   `json:"plan_id,omitempty"`
+	Scopes        []string `json:"scopes,omitempty"`
+}
+
+// DryRunRateLimit is the rate limit check the request would face.
+// Remaining is what would be left after the request.
+type DryRunRateLimit struct {
+	Limit     int       `json:"limit"`
+	Burst     int       `json:"burst"`
+	Remaining int       `json:"remaining"`
+	ResetAt   time.Time `json:"reset_at"`
+	Allowed   bool      `json:"allowed"`
+}
+
+// DryRunQuota is the quota check the request would face.
+// Used is the usage so far in the current period.
+type DryRunQuota struct {
+	Unlimited bool      `json:"unlimited,omitempty"` // Plan has no quota
+	Bypass    bool      `json:"bypass,omitempty"`    // Key skips quota checks
+	Used      int64     `json:"used"`
+	Limit     int64     `json:"limit"`
+	KeyUsed   int64     `json:"key_used,omitempty"`  // Usage so far counted against the key's own quota
+	KeyLimit  int64     `json:"key_limit,omitempty"` // The key's own quota, if it has one
+	PeriodEnd time.Time `json:"period_end"`
+	Allowed   bool      `json:"allowed"`
+}
+
+// DryRun evaluates a request the way Handle would - route matching, upstream
+// selection, authentication, quota and rate limits - without calling the
+// upstream, consuming rate limit tokens or recording usage.
+func (s *ProxyService) DryRun(ctx context.Context, in DryRunRequest) DryRunResult {
+	now := s.clock.Now()
+	dynCfg := s.getDynamicConfig()
+
+	headers := make(map[string]string, len(in.Headers)+1)
+	for k, v := range in.Headers {
+		headers[k] = v
+	}
+	if in.Host != "" {
+		headers["Host"] = in.Host
+	}
+	req := proxy.Request{
+		APIKey:   in.APIKey,
+		Method:   in.Method,
+		Path:     in.Path,
+		Query:    in.Query,
+		Headers:  headers,
+		RemoteIP: in.RemoteIP,
+	}
+
+	result := DryRunResult{Decision: "allow", Auth: DryRunAuth{Required: true}}
+	reject := func(e *proxy.ErrorResponse) DryRunResult {
+		result.Decision = "reject"
+		result.Status = e.Status
+		result.Error = e.Code
+		result.Message = e.Message
+		return result
+	}
+
+	// Route and upstream, from the same route table
+	var matchedRoute *route.Route
+	if s.routeService != nil {
+		ctx = s.routeService.Pin(ctx)
+		if match := s.routeService.MatchRequest(ctx, req); match != nil {
+			matchedRoute = match.Route
+			result.Route = &DryRunRoute{ID: match.Route.ID, Name: match.Route.Name, PathParams: match.PathParams}
+			if matchedRoute.HasUpstream() {
+				result.Upstream = s.dryRunUpstream(ctx, req, matchedRoute, nil)
+			}
+		}
+	}
+
+	if matchedRoute != nil && !matchedRoute.AuthRequired {
+		result.Auth.Required = false
+		return result
+	}
+
+	// Authentication, IP allowlist and scopes
+	result.Auth.Method = string(route.AuthMethodAPIKey)
+	if matchedRoute != nil && matchedRoute.AuthMethod != "" {
+		result.Auth.Method = string(matchedRoute.AuthMethod)
+	}
+	matchedKey, user, authErr := s.authenticate(ctx, req, matchedRoute, now)
+	if authErr != nil {
+		return reject(authErr)
+	}
+	user, authErr = s.groupPlan(ctx, matchedKey, user)
+	if authErr != nil {
+		return reject(authErr)
+	}
+	result.Auth.Authenticated = true
+	result.Auth.KeyID = matchedKey.ID
+	result.Auth.UserID = user.ID
+	result.Auth.PlanID = user.PlanID
+	result.Auth.Scopes = matchedKey.Scopes
+
+	if !key.AllowsIP(matchedKey, req.RemoteIP) {
+		return reject(&proxy.ErrIPNotAllowed)
+	}
+	if matchedRoute != nil && !hasRouteScopes(matchedKey, matchedRoute) {
+		return reject(&proxy.ErrInsufficientScope)
+	}
+
+	// Canary routing depends on the caller, so pick the upstream again
+	if matchedRoute != nil && matchedRoute.Canary != nil && matchedRoute.HasUpstream() {
+		auth := &proxy.AuthContext{KeyID: matchedKey.ID, KeyPrefix: matchedKey.Prefix, UserID: matchedKey.UserID}
+		result.Upstream = s.dryRunUpstream(ctx, req, matchedRoute, auth)
+	}
+
+	userPlan, _ := plan.FindPlan(dynCfg.Plans, user.PlanID)
+	costWeight := 1.0
+	if matchedRoute != nil {
+		costWeight = matchedRoute.CostMultiplierFor(req.Method)
+	}
+
+	// Quota, read without recording the request
+	periodStart, periodEnd := quota.WindowBounds(quota.Period(userPlan.QuotaPeriod), now, user.CreatedAt)
+	result.Quota = &DryRunQuota{PeriodEnd: periodEnd, Allowed: true}
+	switch {
+	case userPlan.RequestsPerMonth < 0:
+		result.Quota.Unlimited = true
+	case matchedKey.QuotaBypass:
+		result.Quota.Bypass = true
+	case s.quota != nil:
+		quotaCfg, increment := quotaConfig(userPlan, costWeight)
+		quotaState, _ := s.quota.Get(ctx, matchedKey.AccountID(), periodStart)
+		check := quota.Check(quotaState, quotaCfg, increment)
+		result.Quota.Used = check.CurrentUsage - increment
+		result.Quota.Limit = check.Limit
+		result.Quota.Allowed = check.Allowed
+		if !check.Allowed {
+			return reject(&proxy.ErrQuotaExceeded)
+		}
+	}
+	if check, ok := s.checkKeyQuota(ctx, matchedKey, userPlan, costWeight, periodStart); ok {
+		_, increment := quotaConfig(userPlan, costWeight)
+		result.Quota.KeyUsed = check.CurrentUsage - increment
+		result.Quota.KeyLimit = check.Limit
+		if !check.Allowed {
+			result.Quota.Allowed = false
+			return reject(&proxy.ErrQuotaExceeded)
+		}
+	}
+
+	// Rate limit, checked against a copy of the bucket so no token is spent
+	rlConfig := rateLimitConfig(userPlan, dynCfg)
+	if s.spikes != nil {
+		rlConfig = s.spikes.Throttle(matchedKey.ID, rlConfig, now)
+	}
+	rlState, _ := s.rateLimit.Get(ctx, matchedKey.ID)
+	rlResult, _ := ratelimit.CheckBucket(rlState, rlConfig, now)
+	result.RateLimit = &DryRunRateLimit{
+		Limit:     rlConfig.Limit,
+		Burst:     rlConfig.BucketSize(),
+		Remaining: rlResult.Remaining,
+		ResetAt:   rlResult.ResetAt,
+		Allowed:   rlResult.Allowed,
+	}
+	if !rlResult.Allowed {
+		return reject(&proxy.ErrRateLimited)
+	}
+
+	return result
+}
+
+// dryRunUpstream describes the upstream the request would be sent to on the
+// matched route, or nil if the route's upstream is missing.
+func (s *ProxyService) dryRunUpstream(ctx context.Context, req proxy.Request, matchedRoute *route.Route, auth *proxy.AuthContext) *DryRunUpstream {
+	u := s.routeService.SelectUpstreamFor(ctx, matchedRoute, auth)
+	if u == nil {
+		return nil
+	}
+	result := &DryRunUpstream{ID: u.ID, Name: u.Name}
+	if target, err := s.routeService.ResolveUpstreamURL(u, matchedRoute.RewriteUpstreamPath(req.Path), route.StripQueryParams(req.Query, matchedRoute.StripQuery)); err == nil {
+		result.URL = target.String()
+	}
+	return result
+}
@@ -0,0 +1,170 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/domain/proxy"
+	"github.com/artpar/apigate/domain/route"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// countingUpstream counts requests that reach it.
+type countingUpstream struct {
+	testUpstream
+	calls int
+}
+
+func (u *countingUpstream) Forward(ctx context.Context, req proxy.Request) (proxy.Response, error) {
+	u.calls++
+	return u.testUpstream.Forward(ctx, req)
+}
+
+func (u *countingUpstream) ForwardTo(ctx context.Context, req proxy.Request, upstream *route.Upstream) (proxy.Response, error) {
+	u.calls++
+	return u.testUpstream.Forward(ctx, req)
+}
+
+const dryRunKey = "ak_0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func newDryRunProxy(t *testing.T) (*app.ProxyService, *testStores, *countingUpstream) {
+	t.Helper()
+	ctx := context.Background()
+
+	stores := &testStores{keys: memory.NewKeyStore(), users: memory.NewUserStore(), rateLimit: memory.NewRateLimitStore(), usage: &testUsageRecorder{}}
+	upstream := &countingUpstream{}
+	svc := app.NewProxyService(app.ProxyDeps{
+		Keys:      stores.keys,
+		Users:     stores.users,
+		RateLimit: stores.rateLimit,
+		Usage:     stores.usage,
+		Upstream:  upstream,
+		Clock:     clock.NewFake(baseTime),
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  0,
+		RateWindow: 60,
+		Plans:      []plan.Plan{{ID: "free", Name: "Free", RateLimitPerMinute: 10, RequestsPerMonth: 1000}},
+	})
+
+	routes := []route.Route{
+		{ID: "orders", Name: "Orders", HostPattern: "api.example.com", HostMatchType: route.HostMatchExact,
+			PathPattern: "/orders/*", MatchType: route.MatchPrefix, UpstreamID: "orders-svc", AuthRequired: true, Enabled: true},
+		{ID: "status", Name: "Status", PathPattern: "/status", MatchType: route.MatchExact, UpstreamID: "orders-svc", Enabled: true},
+	}
+	upstreams := []route.Upstream{{ID: "orders-svc", Name: "Orders Service", BaseURL: "http://orders.internal:9000", Enabled: true}}
+	routeService := app.NewRouteService(&mockRouteStore{routes: routes}, &mockUpstreamStore{upstreams: upstreams}, clock.NewFake(baseTime), zerolog.Nop(), app.RouteServiceConfig{})
+	if err := routeService.Reload(ctx); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	svc.SetRouteService(routeService)
+
+	keyHash, _ := bcrypt.GenerateFromPassword([]byte(dryRunKey), bcrypt.MinCost)
+	stores.keys.Create(ctx, key.Key{ID: "key-1", UserID: "user-1", Hash: keyHash, Prefix: dryRunKey[:12], CreatedAt: baseTime.Add(-time.Hour)})
+	stores.users.Create(ctx, ports.User{ID: "user-1", Email: "test@example.com", PlanID: "free", Status: "active", CreatedAt: baseTime.Add(-time.Hour)})
+
+	return svc, stores, upstream
+}
+
+func TestProxyService_DryRun_MatchedRoute(t *testing.T) {
+	svc, stores, upstream := newDryRunProxy(t)
+	ctx := context.Background()
+
+	req := app.DryRunRequest{Method: "GET", Path: "/orders/42", Host: "api.example.com", APIKey: dryRunKey}
+	result := svc.DryRun(ctx, req)
+
+	if result.Decision != "allow" {
+		t.Fatalf("decision = %s (%s), want allow", result.Decision, result.Error)
+	}
+	if result.Route == nil || result.Route.ID != "orders" {
+		t.Errorf("route = %+v, want orders", result.Route)
+	}
+	if result.Upstream == nil || result.Upstream.ID != "orders-svc" || result.Upstream.URL != "http://orders.internal:9000/orders/42" {
+		t.Errorf("upstream = %+v", result.Upstream)
+	}
+	if !result.Auth.Required || !result.Auth.Authenticated || result.Auth.KeyID != "key-1" || result.Auth.PlanID != "free" {
+		t.Errorf("auth = %+v", result.Auth)
+	}
+	if result.RateLimit == nil || !result.RateLimit.Allowed || result.RateLimit.Limit != 10 {
+		t.Errorf("rate limit = %+v", result.RateLimit)
+	}
+
+	// Nothing is proxied, recorded or spent
+	again := svc.DryRun(ctx, req)
+	if again.RateLimit.Remaining != result.RateLimit.Remaining {
+		t.Errorf("dry run spent a rate limit token: remaining %d then %d", result.RateLimit.Remaining, again.RateLimit.Remaining)
+	}
+	if upstream.calls != 0 {
+		t.Errorf("upstream called %d times", upstream.calls)
+	}
+	if events := stores.usage.Drain(); len(events) != 0 {
+		t.Errorf("recorded %d usage events", len(events))
+	}
+
+	// The host is part of matching
+	if other := svc.DryRun(ctx, app.DryRunRequest{Method: "GET", Path: "/orders/42", Host: "other.example.com", APIKey: dryRunKey}); other.Route != nil {
+		t.Errorf("route %s matched on another host", other.Route.ID)
+	}
+}
+
+func TestProxyService_DryRun_PublicRoute(t *testing.T) {
+	svc, _, _ := newDryRunProxy(t)
+
+	result := svc.DryRun(context.Background(), app.DryRunRequest{Method: "GET", Path: "/status"})
+	if result.Decision != "allow" || result.Route == nil || result.Route.ID != "status" {
+		t.Fatalf("result = %+v, want status route allowed", result)
+	}
+	if result.Auth.Required {
+		t.Error("public route should not require auth")
+	}
+	if result.RateLimit != nil || result.Quota != nil {
+		t.Error("public route should not report rate limit or quota")
+	}
+}
+
+func TestProxyService_DryRun_UnmatchedPath(t *testing.T) {
+	svc, _, _ := newDryRunProxy(t)
+
+	result := svc.DryRun(context.Background(), app.DryRunRequest{Method: "GET", Path: "/unknown", APIKey: dryRunKey})
+	if result.Route != nil {
+		t.Errorf("route = %+v, want none", result.Route)
+	}
+	if result.Upstream != nil {
+		t.Errorf("upstream = %+v, want default upstream", result.Upstream)
+	}
+	// Unmatched requests go to the default upstream and still need a key
+	if result.Decision != "allow" || !result.Auth.Required || !result.Auth.Authenticated {
+		t.Errorf("result = %+v, want authenticated allow", result)
+	}
+}
+
+func TestProxyService_DryRun_AuthRejected(t *testing.T) {
+	svc, _, _ := newDryRunProxy(t)
+	ctx := context.Background()
+
+	missing := svc.DryRun(ctx, app.DryRunRequest{Method: "GET", Path: "/orders/42", Host: "api.example.com"})
+	if missing.Decision != "reject" || missing.Status != 401 || missing.Error != "missing_api_key" {
+		t.Errorf("missing key: %+v", missing)
+	}
+	if missing.Route == nil || missing.Route.ID != "orders" {
+		t.Errorf("missing key: route should still be reported, got %+v", missing.Route)
+	}
+
+	wrong := "ak_ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"
+	invalid := svc.DryRun(ctx, app.DryRunRequest{Method: "GET", Path: "/orders/42", Host: "api.example.com", APIKey: wrong})
+	if invalid.Decision != "reject" || invalid.Status != 401 || invalid.Auth.Authenticated {
+		t.Errorf("invalid key: %+v", invalid)
+	}
+	if invalid.RateLimit != nil {
+		t.Error("rejected request should not report rate limit state")
+	}
+}
@@ -0,0 +1,370 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/artpar/apigate/domain/billing"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+)
+
+// DunningConfig contains configuration for DunningService.
+type DunningConfig struct {
+	Schedule      []int         // Days after the first failed payment to send reminders; suspended on the last (default: 3, 7, 14)
+	CheckInterval time.Duration // How often the schedule is checked (default: 1h)
+	AppName       string        // Application name used in emails (default: APIGate)
+	BaseURL       string        // Portal base URL for the link to the billing page
+}
+
+// DunningService chases failed subscription payments. A failed payment moves
+// the subscription to past_due and emails the user; reminders follow on the
+// days of the schedule, and on the last day the subscription is suspended
+// (marked unpaid) and the user moved to the default plan. A successful
+// payment ends dunning and restores the plan of a suspended subscription.
+// The dunning state is kept on the subscription.
+type DunningService struct {
+	users         ports.UserStore
+	plans         ports.PlanStore
+	subscriptions ports.SubscriptionStore
+	email         ports.EmailSender
+	clock         ports.Clock
+	logger        zerolog.Logger
+	cfg           DunningConfig
+
+	stop chan struct{}
+}
+
+// dunningPageSize is how many users are read per page while checking.
+const dunningPageSize = 100
+
+// NewDunningService creates a new dunning service.
+func NewDunningService(
+	users ports.UserStore,
+	plans ports.PlanStore,
+	subscriptions ports.SubscriptionStore,
+	email ports.EmailSender,
+	clock ports.Clock,
+	logger zerolog.Logger,
+	cfg DunningConfig,
+) *DunningService {
+	if len(cfg.Schedule) == 0 {
+		cfg.Schedule = billing.DefaultDunningSchedule
+	}
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = time.Hour
+	}
+	if cfg.AppName == "" {
+		cfg.AppName = "APIGate"
+	}
+
+	return &DunningService{
+		users:         users,
+		plans:         plans,
+		subscriptions: subscriptions,
+		email:         email,
+		clock:         clock,
+		logger:        logger.With().Str("service", "dunning").Logger(),
+		cfg:           cfg,
+		stop:          make(chan struct{}),
+	}
+}
+
+// Start checks the schedule in the background, once immediately and then
+// every check interval.
+func (d *DunningService) Start(ctx context.Context) {
+	go func() {
+		d.CheckDue(ctx)
+		d.loop()
+	}()
+}
+
+// Stop stops background checking.
+func (d *DunningService) Stop() {
+	close(d.stop)
+}
+
+func (d *DunningService) loop() {
+	ticker := time.NewTicker(d.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+			d.CheckDue(context.Background())
+		}
+	}
+}
+
+// PaymentFailed records a failed payment for sub. The first failure starts
+// dunning and emails the user; later failures keep the schedule.
+func (d *DunningService) PaymentFailed(ctx context.Context, sub billing.Subscription) error {
+	next, action := billing.DunningFailed(sub, d.clock.Now().UTC())
+	if action == billing.DunningActionNone {
+		return nil
+	}
+	return d.apply(ctx, next, action)
+}
+
+// PaymentSucceeded records a successful payment for sub, ending dunning. A
+// suspended subscription is reactivated and its plan restored.
+func (d *DunningService) PaymentSucceeded(ctx context.Context, sub billing.Subscription) error {
+	if !sub.InDunning() && billing.StatusAfterInvoicePaid(sub.Status) == sub.Status {
+		return nil
+	}
+	next, action := billing.DunningPaid(sub, d.clock.Now().UTC())
+	return d.apply(ctx, next, action)
+}
+
+// CheckDue takes the schedule step due for every subscription in dunning and
+// returns the number of steps taken.
+func (d *DunningService) CheckDue(ctx context.Context) int {
+	now := d.clock.Now().UTC()
+	taken := 0
+	for offset := 0; ; offset += dunningPageSize {
+		users, err := d.users.List(ctx, dunningPageSize, offset)
+		if err != nil {
+			d.logger.Error().Err(err).Msg("failed to list users")
+			break
+		}
+		for _, user := range users {
+			sub, err := d.subscriptions.GetByUser(ctx, user.ID)
+			if err != nil || !sub.InDunning() {
+				continue
+			}
+			next, action := billing.DunningStepDue(sub, d.cfg.Schedule, now)
+			if action == billing.DunningActionNone {
+				continue
+			}
+			if err := d.apply(ctx, next, action); err != nil {
+				d.logger.Error().Err(err).Str("subscription_id", sub.ID).Msg("failed to take dunning step")
+				continue
+			}
+			taken++
+		}
+		if len(users) < dunningPageSize {
+			break
+		}
+	}
+
+	if taken > 0 {
+		d.logger.Info().Int("steps", taken).Msg("dunning steps taken")
+	}
+	return taken
+}
+
+// apply stores the subscription after a dunning transition, changes the
+// user's plan on suspension or restore, and emails the user.
+func (d *DunningService) apply(ctx context.Context, sub billing.Subscription, action billing.DunningAction) error {
+	if err := d.subscriptions.Update(ctx, sub); err != nil {
+		return fmt.Errorf("update subscription: %w", err)
+	}
+	d.logger.Info().
+		Str("subscription_id", sub.ID).
+		Str("user_id", sub.UserID).
+		Str("status", string(sub.Status)).
+		Str("action", string(action)).
+		Msg("dunning state changed")
+	if action == billing.DunningActionNone {
+		return nil
+	}
+
+	user, err := d.users.Get(ctx, sub.UserID)
+	if err != nil {
+		return fmt.Errorf("get user: %w", err)
+	}
+	plan, err := d.plans.Get(ctx, sub.PlanID)
+	if err != nil {
+		return fmt.Errorf("get plan: %w", err)
+	}
+
+	var downgradedTo string
+	switch action {
+	case billing.DunningActionSuspend:
+		if fallback, ok := d.defaultPlan(ctx); ok {
+			if err := d.setPlan(ctx, user, fallback.ID); err != nil {
+				return err
+			}
+			downgradedTo = fallback.Name
+		}
+	case billing.DunningActionRestore:
+		if err := d.setPlan(ctx, user, sub.PlanID); err != nil {
+			return err
+		}
+	}
+
+	if user.Email == "" {
+		return nil
+	}
+	msg, err := d.render(user, plan, sub, action, downgradedTo)
+	if err != nil {
+		return err
+	}
+	if err := d.email.Send(ctx, msg); err != nil {
+		d.logger.Warn().Err(err).Str("user_id", user.ID).Str("action", string(action)).Msg("failed to send dunning email")
+	}
+	return nil
+}
+
+// setPlan moves the user to planID.
+func (d *DunningService) setPlan(ctx context.Context, user ports.User, planID string) error {
+	if user.PlanID == planID {
+		return nil
+	}
+	user.PlanID = planID
+	user.UpdatedAt = d.clock.Now().UTC()
+	if err := d.users.Update(ctx, user); err != nil {
+		return fmt.Errorf("update user plan: %w", err)
+	}
+	return nil
+}
+
+// defaultPlan returns the plan suspended users are moved to.
+func (d *DunningService) defaultPlan(ctx context.Context) (ports.Plan, bool) {
+	plans, err := d.plans.List(ctx)
+	if err != nil {
+		d.logger.Error().Err(err).Msg("failed to list plans")
+		return ports.Plan{}, false
+	}
+	for _, p := range plans {
+		if p.IsDefault && p.Enabled {
+			return p, true
+		}
+	}
+	d.logger.Warn().Msg("no default plan found, suspended user plan unchanged")
+	return ports.Plan{}, false
+}
+
+// dunningEmailData holds data for the dunning email templates.
+type dunningEmailData struct {
+	Name        string
+	AppName     string
+	PlanName    string
+	Action      string
+	Downgraded  string // Plan the user was moved to on suspension
+	SuspendsOn  string
+	DaysOverdue int
+	Link        string
+}
+
+func (d *DunningService) render(user ports.User, plan ports.Plan, sub billing.Subscription, action billing.DunningAction, downgradedTo string) (ports.EmailMessage, error) {
+	data := dunningEmailData{
+		Name:       user.Name,
+		AppName:    d.cfg.AppName,
+		PlanName:   plan.Name,
+		Action:     string(action),
+		Downgraded: downgradedTo,
+		Link:       strings.TrimSuffix(d.cfg.BaseURL, "/") + "/portal/billing",
+	}
+	if data.Name == "" {
+		data.Name = user.Email
+	}
+	if suspendsAt := billing.DunningSuspendsAt(sub, d.cfg.Schedule); !suspendsAt.IsZero() {
+		data.SuspendsOn = suspendsAt.UTC().Format("January 2, 2006")
+		data.DaysOverdue = int(d.clock.Now().Sub(*sub.DunningStartedAt).Hours() / 24)
+	}
+
+	var htmlBuf, textBuf bytes.Buffer
+	if err := dunningHTMLTmpl.Execute(&htmlBuf, data); err != nil {
+		return ports.EmailMessage{}, fmt.Errorf("execute dunning html template: %w", err)
+	}
+	if err := dunningTextTmpl.Execute(&textBuf, data); err != nil {
+		return ports.EmailMessage{}, fmt.Errorf("execute dunning text template: %w", err)
+	}
+
+	var subject string
+	switch action {
+	case billing.DunningActionNotify:
+		subject = fmt.Sprintf("Your %s payment failed", d.cfg.AppName)
+	case billing.DunningActionRemind:
+		subject = fmt.Sprintf("Reminder: your %s payment is past due", d.cfg.AppName)
+	case billing.DunningActionSuspend:
+		subject = fmt.Sprintf("Your %s subscription has been suspended", d.cfg.AppName)
+	default:
+		subject = fmt.Sprintf("Your %s subscription is active again", d.cfg.AppName)
+	}
+	return ports.EmailMessage{
+		To:       user.Email,
+		Subject:  subject,
+		HTMLBody: htmlBuf.String(),
+		TextBody: textBuf.String(),
+	}, nil
+}
+
+var dunningHTMLTmpl = htmltemplate.Must(htmltemplate.New("dunning").Parse(strings.TrimSpace(`
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Payment</title>
+    <style>
+        body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { text-align: center; padding: 20px 0; }
+        .content { background: #f9f9f9; padding: 30px; border-radius: 8px; }
+        .button { display: inline-block; background: #007bff; color: white; padding: 12px 30px; text-decoration: none; border-radius: 5px; margin: 20px 0; }
+        .footer { text-align: center; padding: 20px; color: #666; font-size: 14px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>{{.AppName}}</h1>
+        </div>
+        <div class="content">
+            {{if eq .Action "notify"}}<h2>Your payment failed</h2>
+            <p>Hi {{.Name}},</p>
+            <p>We couldn't collect the payment for your {{.PlanName}} subscription. We'll keep trying, and your access continues for now.</p>
+            <p>Please update your payment method before <strong>{{.SuspendsOn}}</strong> to avoid your subscription being suspended.</p>
+            {{else if eq .Action "remind"}}<h2>Your payment is past due</h2>
+            <p>Hi {{.Name}},</p>
+            <p>The payment for your {{.PlanName}} subscription is now {{.DaysOverdue}} days overdue.</p>
+            <p>Please update your payment method before <strong>{{.SuspendsOn}}</strong> to avoid your subscription being suspended.</p>
+            {{else if eq .Action "suspend"}}<h2>Your subscription has been suspended</h2>
+            <p>Hi {{.Name}},</p>
+            <p>We were unable to collect the payment for your {{.PlanName}} subscription, so it has been suspended{{if .Downgraded}} and your account moved to the {{.Downgraded}} plan{{end}}.</p>
+            <p>Pay the outstanding invoice to restore your {{.PlanName}} plan.</p>
+            {{else}}<h2>Your subscription is active again</h2>
+            <p>Hi {{.Name}},</p>
+            <p>Thanks, we received your payment. Your {{.PlanName}} plan has been restored.</p>
+            {{end}}
+            <p style="text-align: center;">
+                <a href="{{.Link}}" class="button">View Billing</a>
+            </p>
+        </div>
+        <div class="footer">
+            <p>You are receiving this because you have a subscription on {{.AppName}}.</p>
+        </div>
+    </div>
+</body>
+</html>
+`)))
+
+var dunningTextTmpl = texttemplate.Must(texttemplate.New("dunning").Parse(`Hi {{.Name}},
+{{if eq .Action "notify"}}
+We couldn't collect the payment for your {{.PlanName}} subscription. We'll keep trying, and your access continues for now.
+
+Please update your payment method before {{.SuspendsOn}} to avoid your subscription being suspended.
+{{else if eq .Action "remind"}}
+The payment for your {{.PlanName}} subscription is now {{.DaysOverdue}} days overdue.
+
+Please update your payment method before {{.SuspendsOn}} to avoid your subscription being suspended.
+{{else if eq .Action "suspend"}}
+We were unable to collect the payment for your {{.PlanName}} subscription, so it has been suspended{{if .Downgraded}} and your account moved to the {{.Downgraded}} plan{{end}}.
+
+Pay the outstanding invoice to restore your {{.PlanName}} plan.
+{{else}}
+Thanks, we received your payment. Your {{.PlanName}} plan has been restored.
+{{end}}
+View billing: {{.Link}}
+
+Thanks,
+The {{.AppName}} Team`))
@@ -0,0 +1,204 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	"github.com/artpar/apigate/adapters/email"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/domain/billing"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+)
+
+type dunningFixture struct {
+	service *DunningService
+	users   *memory.UserStore
+	subs    *mockSubscriptionStore
+	sender  *email.MockSender
+	clock   *clock.Fake
+}
+
+var dunningStart = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func newDunningFixture(t *testing.T) *dunningFixture {
+	t.Helper()
+	f := &dunningFixture{
+		users: memory.NewUserStore(),
+		subs: &mockSubscriptionStore{subscriptions: []billing.Subscription{
+			{ID: "sub-1", UserID: "alice", PlanID: "pro", ProviderID: "sub_stripe", Provider: "stripe", Status: billing.SubscriptionStatusActive},
+		}},
+		sender: email.NewMockSender("https://portal.example.com", "Acme API"),
+		clock:  clock.NewFake(dunningStart),
+	}
+	f.users.Create(context.Background(), ports.User{ID: "alice", Email: "alice@example.com", Name: "Alice", StripeID: "cus_alice", PlanID: "pro", Status: "active"})
+	plans := &mockPlanStore{plans: []ports.Plan{
+		{ID: "free", Name: "Free", IsDefault: true, Enabled: true},
+		{ID: "pro", Name: "Pro", PriceMonthly: 2900, Enabled: true},
+	}}
+	f.service = NewDunningService(f.users, plans, f.subs, f.sender, f.clock, zerolog.Nop(), DunningConfig{
+		Schedule: []int{3, 7, 14},
+		AppName:  "Acme API",
+		BaseURL:  "https://portal.example.com",
+	})
+	return f
+}
+
+func (f *dunningFixture) sub() billing.Subscription {
+	return f.subs.subscriptions[0]
+}
+
+func (f *dunningFixture) plan(t *testing.T) string {
+	t.Helper()
+	user, err := f.users.Get(context.Background(), "alice")
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	return user.PlanID
+}
+
+func TestDunningService_RetryScheduleToSuspension(t *testing.T) {
+	ctx := context.Background()
+	f := newDunningFixture(t)
+
+	if err := f.service.PaymentFailed(ctx, f.sub()); err != nil {
+		t.Fatalf("PaymentFailed: %v", err)
+	}
+	if f.sub().Status != billing.SubscriptionStatusPastDue || !f.sub().InDunning() {
+		t.Fatalf("subscription after failure = %+v, want past_due in dunning", f.sub())
+	}
+	last, ok := f.sender.GetLastEmail()
+	if !ok || last.To != "alice@example.com" || last.Subject != "Your Acme API payment failed" {
+		t.Fatalf("failure email = %+v", last)
+	}
+	if !strings.Contains(last.TextBody, "June 15, 2024") || !strings.Contains(last.TextBody, "https://portal.example.com/portal/billing") {
+		t.Errorf("failure email does not give the suspension date and billing link:\n%s", last.TextBody)
+	}
+
+	// A retried payment failing again keeps the schedule and sends nothing
+	f.clock.Advance(24 * time.Hour)
+	f.service.PaymentFailed(ctx, f.sub())
+	if f.sender.Count() != 1 || !f.sub().DunningStartedAt.Equal(dunningStart) {
+		t.Errorf("repeated failure sent %d emails, started %v", f.sender.Count(), f.sub().DunningStartedAt)
+	}
+
+	steps := []struct {
+		day     int
+		taken   int
+		subject string
+		status  billing.SubscriptionStatus
+		plan    string
+	}{
+		{2, 0, "", billing.SubscriptionStatusPastDue, "pro"},
+		{3, 1, "Reminder: your Acme API payment is past due", billing.SubscriptionStatusPastDue, "pro"},
+		{4, 0, "", billing.SubscriptionStatusPastDue, "pro"},
+		{7, 1, "Reminder: your Acme API payment is past due", billing.SubscriptionStatusPastDue, "pro"},
+		{14, 1, "Your Acme API subscription has been suspended", billing.SubscriptionStatusUnpaid, "free"},
+		{20, 0, "", billing.SubscriptionStatusUnpaid, "free"},
+	}
+	for _, st := range steps {
+		f.clock.Set(dunningStart.AddDate(0, 0, st.day))
+		before := f.sender.Count()
+		if n := f.service.CheckDue(ctx); n != st.taken {
+			t.Errorf("day %d: %d steps taken, want %d", st.day, n, st.taken)
+		}
+		if st.subject != "" {
+			last, _ := f.sender.GetLastEmail()
+			if f.sender.Count() != before+1 || last.Subject != st.subject {
+				t.Errorf("day %d: email %q, want %q", st.day, last.Subject, st.subject)
+			}
+		} else if f.sender.Count() != before {
+			t.Errorf("day %d: unexpected email", st.day)
+		}
+		if f.sub().Status != st.status || f.plan(t) != st.plan {
+			t.Errorf("day %d: status %s on plan %s, want %s on %s", st.day, f.sub().Status, f.plan(t), st.status, st.plan)
+		}
+	}
+
+	last, _ = f.sender.GetLastEmail()
+	if !strings.Contains(last.TextBody, "moved to the Free plan") {
+		t.Errorf("suspension email does not name the new plan:\n%s", last.TextBody)
+	}
+}
+
+func TestDunningService_PaymentRestoresSuspendedPlan(t *testing.T) {
+	ctx := context.Background()
+	f := newDunningFixture(t)
+
+	f.service.PaymentFailed(ctx, f.sub())
+	f.clock.Set(dunningStart.AddDate(0, 0, 15))
+	f.service.CheckDue(ctx)
+	if f.sub().Status != billing.SubscriptionStatusUnpaid || f.plan(t) != "free" {
+		t.Fatalf("not suspended: %s on %s", f.sub().Status, f.plan(t))
+	}
+
+	if err := f.service.PaymentSucceeded(ctx, f.sub()); err != nil {
+		t.Fatalf("PaymentSucceeded: %v", err)
+	}
+	if f.sub().Status != billing.SubscriptionStatusActive || f.sub().InDunning() || f.plan(t) != "pro" {
+		t.Errorf("after payment: %s on %s, in dunning %v; want active on pro", f.sub().Status, f.plan(t), f.sub().InDunning())
+	}
+	if last, _ := f.sender.GetLastEmail(); last.Subject != "Your Acme API subscription is active again" {
+		t.Errorf("last email = %q", last.Subject)
+	}
+
+	// Nothing further is scheduled
+	f.clock.Set(dunningStart.AddDate(0, 2, 0))
+	if n := f.service.CheckDue(ctx); n != 0 {
+		t.Errorf("%d steps taken after recovery", n)
+	}
+}
+
+func TestDunningService_PaymentBeforeSuspension(t *testing.T) {
+	ctx := context.Background()
+	f := newDunningFixture(t)
+
+	f.service.PaymentFailed(ctx, f.sub())
+	f.clock.Set(dunningStart.AddDate(0, 0, 5))
+	f.service.CheckDue(ctx)
+	sent := f.sender.Count()
+
+	f.service.PaymentSucceeded(ctx, f.sub())
+	if f.sub().Status != billing.SubscriptionStatusActive || f.sub().InDunning() || f.plan(t) != "pro" {
+		t.Errorf("after payment: %+v", f.sub())
+	}
+	if f.sender.Count() != sent {
+		t.Errorf("payment before suspension sent an email")
+	}
+
+	// Payment for a subscription that was never in dunning changes nothing
+	if err := f.service.PaymentSucceeded(ctx, f.sub()); err != nil {
+		t.Errorf("PaymentSucceeded: %v", err)
+	}
+	f.clock.Set(dunningStart.AddDate(0, 1, 0))
+	if n := f.service.CheckDue(ctx); n != 0 || f.sub().Status != billing.SubscriptionStatusActive {
+		t.Errorf("%d steps taken, status %s", n, f.sub().Status)
+	}
+}
+
+func TestPaymentWebhookService_DunningEvents(t *testing.T) {
+	ctx := context.Background()
+	f := newDunningFixture(t)
+	webhooks := NewPaymentWebhookService(f.users, f.subs, &mockPlanStore{}, &mockIDGenerator{}, zerolog.Nop())
+	webhooks.SetDunning(f.service)
+
+	if err := webhooks.HandleInvoiceFailed(ctx, "in_1", "cus_alice"); err != nil {
+		t.Fatalf("HandleInvoiceFailed: %v", err)
+	}
+	if !f.sub().InDunning() || f.sender.Count() != 1 {
+		t.Fatalf("failed invoice did not start dunning: %+v, %d emails", f.sub(), f.sender.Count())
+	}
+
+	f.clock.Set(dunningStart.AddDate(0, 0, 14))
+	f.service.CheckDue(ctx)
+
+	if err := webhooks.HandleInvoicePaid(ctx, "in_1", "cus_alice", 2900); err != nil {
+		t.Fatalf("HandleInvoicePaid: %v", err)
+	}
+	if f.sub().Status != billing.SubscriptionStatusActive || f.plan(t) != "pro" {
+		t.Errorf("paid invoice did not restore the subscription: %s on %s", f.sub().Status, f.plan(t))
+	}
+}
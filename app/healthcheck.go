@@ -0,0 +1,214 @@
+package app
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/artpar/apigate/domain/route"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+)
+
+// UpstreamStatus is the health of a single upstream (value type).
+type UpstreamStatus struct {
+	UpstreamID  string     `json:"upstream_id"`
+	Name        string     `json:"name"`
+	Healthy     bool       `json:"healthy"`      // Eligible for traffic: probe passing, not ejected, circuit not open
+	ActiveCheck bool       `json:"active_check"` // Whether the upstream is actively probed
+	Ejected     bool       `json:"ejected"`      // Ejected by passive health checking
+	Circuit     string     `json:"circuit"`      // Circuit breaker state: closed, open, half_open
+	LastCheck   *time.Time `json:"last_check,omitempty"`
+	LastStatus  int        `json:"last_status,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+}
+
+// HealthCheckerConfig contains configuration for HealthChecker.
+type HealthCheckerConfig struct {
+	TickInterval time.Duration // How often due probes are scheduled (default: 1s)
+}
+
+// HealthChecker actively probes upstreams that have a health check path and
+// reports them unhealthy to the route service while their probes fail.
+type HealthChecker struct {
+	routes *RouteService
+	prober ports.UpstreamProber
+	clock  ports.Clock
+	logger zerolog.Logger
+	tick   time.Duration
+
+	mu      sync.RWMutex
+	results map[string]probeResult // upstream ID -> latest probe
+
+	stop chan struct{}
+}
+
+type probeResult struct {
+	healthy   bool
+	checkedAt time.Time
+	status    int
+	err       string
+}
+
+// NewHealthChecker creates a new active health checker.
+func NewHealthChecker(
+	routes *RouteService,
+	prober ports.UpstreamProber,
+	clock ports.Clock,
+	logger zerolog.Logger,
+	cfg HealthCheckerConfig,
+) *HealthChecker {
+	if cfg.TickInterval == 0 {
+		cfg.TickInterval = time.Second
+	}
+
+	return &HealthChecker{
+		routes:  routes,
+		prober:  prober,
+		clock:   clock,
+		logger:  logger.With().Str("service", "healthcheck").Logger(),
+		tick:    cfg.TickInterval,
+		results: make(map[string]probeResult),
+		stop:    make(chan struct{}),
+	}
+}
+
+// Start probes all checked upstreams once, so failing upstreams are marked
+// down before traffic is routed, then keeps probing in the background.
+func (h *HealthChecker) Start(ctx context.Context) {
+	h.CheckDue(ctx)
+	go h.loop()
+}
+
+// Stop stops background probing.
+func (h *HealthChecker) Stop() {
+	close(h.stop)
+}
+
+func (h *HealthChecker) loop() {
+	ticker := time.NewTicker(h.tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-ticker.C:
+			h.CheckDue(context.Background())
+		}
+	}
+}
+
+// CheckDue probes every actively checked upstream whose interval has elapsed
+// since its last probe. Probes run concurrently.
+func (h *HealthChecker) CheckDue(ctx context.Context) {
+	upstreams := h.routes.GetUpstreams()
+	now := h.clock.Now()
+
+	h.mu.Lock()
+	for id := range h.results {
+		// Forget upstreams that were removed or no longer actively checked
+		if u, ok := upstreams[id]; !ok || !u.HealthCheckEnabled() {
+			delete(h.results, id)
+		}
+	}
+	h.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, u := range upstreams {
+		if !u.HealthCheckEnabled() || !h.due(u, now) {
+			continue
+		}
+		wg.Add(1)
+		go func(u route.Upstream) {
+			defer wg.Done()
+			h.probe(ctx, u)
+		}(u)
+	}
+	wg.Wait()
+}
+
+func (h *HealthChecker) due(u route.Upstream, now time.Time) bool {
+	h.mu.RLock()
+	last, ok := h.results[u.ID]
+	h.mu.RUnlock()
+	return !ok || now.Sub(last.checkedAt) >= u.HealthCheckInterval
+}
+
+func (h *HealthChecker) probe(ctx context.Context, u route.Upstream) {
+	timeout := u.HealthCheckTimeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	probeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	status, err := h.prober.Probe(probeCtx, u)
+	result := probeResult{
+		healthy:   u.ProbeHealthy(status, err),
+		checkedAt: h.clock.Now(),
+		status:    status,
+	}
+	if err != nil {
+		result.err = err.Error()
+	}
+
+	h.mu.Lock()
+	prev, seen := h.results[u.ID]
+	h.results[u.ID] = result
+	h.mu.Unlock()
+
+	if seen && prev.healthy == result.healthy {
+		return
+	}
+	if result.healthy {
+		h.logger.Info().Str("upstream_id", u.ID).Msg("upstream health check passing")
+	} else {
+		h.logger.Warn().
+			Str("upstream_id", u.ID).
+			Int("status", status).
+			Str("error", result.err).
+			Msg("upstream health check failing")
+	}
+}
+
+// IsHealthy implements UpstreamHealth. Upstreams that have not been probed are healthy.
+func (h *HealthChecker) IsHealthy(upstreamID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	r, ok := h.results[upstreamID]
+	return !ok || r.healthy
+}
+
+// Status returns the aggregate health of all enabled upstreams, sorted by name.
+func (h *HealthChecker) Status() []UpstreamStatus {
+	upstreams := h.routes.GetUpstreams()
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	statuses := make([]UpstreamStatus, 0, len(upstreams))
+	for _, u := range upstreams {
+		st := UpstreamStatus{
+			UpstreamID:  u.ID,
+			Name:        u.Name,
+			ActiveCheck: u.HealthCheckEnabled(),
+			Ejected:     h.routes.passive.ejected(u.ID),
+			Circuit:     string(h.routes.CircuitState(u)),
+		}
+		probeOK := true
+		if r, ok := h.results[u.ID]; ok {
+			checkedAt := r.checkedAt
+			st.LastCheck = &checkedAt
+			st.LastStatus = r.status
+			st.LastError = r.err
+			probeOK = r.healthy
+		}
+		st.Healthy = probeOK && !st.Ejected && st.Circuit != string(route.CircuitOpen)
+		statuses = append(statuses, st)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
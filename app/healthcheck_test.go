@@ -0,0 +1,186 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/route"
+	"github.com/rs/zerolog"
+)
+
+// switchProber returns a configurable status per upstream and counts probes.
+type switchProber struct {
+	mu     sync.Mutex
+	status map[string]int
+	err    map[string]error
+	probes map[string]int
+}
+
+func newSwitchProber() *switchProber {
+	return &switchProber{
+		status: map[string]int{},
+		err:    map[string]error{},
+		probes: map[string]int{},
+	}
+}
+
+func (p *switchProber) Probe(ctx context.Context, u route.Upstream) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.probes[u.ID]++
+	if err := p.err[u.ID]; err != nil {
+		return 0, err
+	}
+	if s, ok := p.status[u.ID]; ok {
+		return s, nil
+	}
+	return 200, nil
+}
+
+func (p *switchProber) set(id string, status int, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status[id] = status
+	p.err[id] = err
+}
+
+func (p *switchProber) count(id string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.probes[id]
+}
+
+func newTestHealthChecker(t *testing.T) (*app.HealthChecker, *app.RouteService, *route.Route, *switchProber, *clock.Fake) {
+	t.Helper()
+
+	r := route.Route{
+		ID: "pool", Name: "pool", PathPattern: "/api/*", MatchType: route.MatchPrefix, Enabled: true,
+		UpstreamID: "primary",
+		Upstreams: []route.WeightedUpstream{
+			{UpstreamID: "primary", Weight: 1},
+			{UpstreamID: "backup", Weight: 1},
+		},
+	}
+	upstreams := []route.Upstream{
+		{
+			ID: "primary", Name: "primary", BaseURL: "http://primary", Enabled: true,
+			HealthCheckPath: "/healthz", HealthCheckInterval: 10 * time.Second,
+			HealthCheckTimeout: time.Second, HealthCheckExpectedStatus: 200,
+		},
+		{ID: "backup", Name: "backup", BaseURL: "http://backup", Enabled: true},
+	}
+
+	svc := newTestRouteService([]route.Route{r}, upstreams)
+	if err := svc.Reload(context.Background()); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+
+	prober := newSwitchProber()
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	checker := app.NewHealthChecker(svc, prober, clk, zerolog.Nop(), app.HealthCheckerConfig{})
+	svc.SetUpstreamHealth(checker)
+	return checker, svc, &r, prober, clk
+}
+
+func statusOf(t *testing.T, checker *app.HealthChecker, id string) app.UpstreamStatus {
+	t.Helper()
+	for _, st := range checker.Status() {
+		if st.UpstreamID == id {
+			return st
+		}
+	}
+	t.Fatalf("no status for upstream %s", id)
+	return app.UpstreamStatus{}
+}
+
+func TestHealthChecker_ProbeFailureFlipsStatus(t *testing.T) {
+	checker, svc, r, prober, clk := newTestHealthChecker(t)
+	ctx := context.Background()
+
+	checker.CheckDue(ctx)
+	if !checker.IsHealthy("primary") {
+		t.Fatal("primary should be healthy after a passing probe")
+	}
+	st := statusOf(t, checker, "primary")
+	if !st.Healthy || !st.ActiveCheck || st.LastStatus != 200 || st.LastCheck == nil {
+		t.Errorf("unexpected status after passing probe: %+v", st)
+	}
+
+	// Probe starts failing; nothing changes until the interval elapses.
+	prober.set("primary", 503, nil)
+	clk.Advance(5 * time.Second)
+	checker.CheckDue(ctx)
+	if prober.count("primary") != 1 {
+		t.Errorf("probes = %d, want 1 before interval elapses", prober.count("primary"))
+	}
+	if !checker.IsHealthy("primary") {
+		t.Error("primary should still be healthy before the next probe")
+	}
+
+	clk.Advance(5 * time.Second)
+	checker.CheckDue(ctx)
+	if prober.count("primary") != 2 {
+		t.Errorf("probes = %d, want 2 after interval", prober.count("primary"))
+	}
+	if checker.IsHealthy("primary") {
+		t.Fatal("primary should be unhealthy after a failing probe")
+	}
+	st = statusOf(t, checker, "primary")
+	if st.Healthy || st.LastStatus != 503 {
+		t.Errorf("unexpected status after failing probe: %+v", st)
+	}
+
+	// Traffic avoids the probed-down upstream.
+	for i := 0; i < 10; i++ {
+		if u := svc.SelectUpstream(context.Background(), r); u == nil || u.ID != "backup" {
+			t.Fatalf("selected %v, want backup", u)
+		}
+	}
+
+	// Connection errors are failures too.
+	prober.set("primary", 0, errors.New("connection refused"))
+	clk.Advance(10 * time.Second)
+	checker.CheckDue(ctx)
+	if st = statusOf(t, checker, "primary"); st.Healthy || st.LastError != "connection refused" {
+		t.Errorf("unexpected status after probe error: %+v", st)
+	}
+
+	// Recovery.
+	prober.set("primary", 200, nil)
+	clk.Advance(10 * time.Second)
+	checker.CheckDue(ctx)
+	if !checker.IsHealthy("primary") {
+		t.Error("primary should be healthy again after a passing probe")
+	}
+}
+
+func TestHealthChecker_SkipsUnchecked(t *testing.T) {
+	checker, _, _, prober, _ := newTestHealthChecker(t)
+
+	checker.CheckDue(context.Background())
+
+	if prober.count("backup") != 0 {
+		t.Errorf("backup has no health check path, probes = %d", prober.count("backup"))
+	}
+	st := statusOf(t, checker, "backup")
+	if !st.Healthy || st.ActiveCheck || st.LastCheck != nil {
+		t.Errorf("unexpected status for unchecked upstream: %+v", st)
+	}
+}
+
+func TestHealthChecker_StartProbesImmediately(t *testing.T) {
+	checker, _, _, prober, _ := newTestHealthChecker(t)
+	prober.set("primary", 500, nil)
+
+	checker.Start(context.Background())
+	defer checker.Stop()
+
+	if checker.IsHealthy("primary") {
+		t.Error("primary should be marked down before Start returns")
+	}
+}
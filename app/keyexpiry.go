@@ -0,0 +1,230 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+)
+
+// KeyExpiryNotifierConfig contains configuration for KeyExpiryNotifier.
+type KeyExpiryNotifierConfig struct {
+	Window        time.Duration // Warn about keys expiring within this window (default: 7 days)
+	CheckInterval time.Duration // How often keys are scanned (default: 1h)
+	AppName       string        // Application name used in the email (default: APIGate)
+	BaseURL       string        // Portal base URL for the link to the API keys page
+}
+
+// KeyExpiryNotifier emails users before their API keys expire. Each key gets
+// at most one warning; sent warnings are recorded in the key store.
+type KeyExpiryNotifier struct {
+	keys   ports.KeyExpiryStore
+	users  ports.UserStore
+	email  ports.EmailSender
+	clock  ports.Clock
+	logger zerolog.Logger
+	cfg    KeyExpiryNotifierConfig
+
+	stop chan struct{}
+}
+
+// NewKeyExpiryNotifier creates a new key expiry notifier.
+func NewKeyExpiryNotifier(
+	keys ports.KeyExpiryStore,
+	users ports.UserStore,
+	email ports.EmailSender,
+	clock ports.Clock,
+	logger zerolog.Logger,
+	cfg KeyExpiryNotifierConfig,
+) *KeyExpiryNotifier {
+	if cfg.Window <= 0 {
+		cfg.Window = 7 * 24 * time.Hour
+	}
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = time.Hour
+	}
+	if cfg.AppName == "" {
+		cfg.AppName = "APIGate"
+	}
+
+	return &KeyExpiryNotifier{
+		keys:   keys,
+		users:  users,
+		email:  email,
+		clock:  clock,
+		logger: logger.With().Str("service", "key_expiry").Logger(),
+		cfg:    cfg,
+		stop:   make(chan struct{}),
+	}
+}
+
+// Start scans for expiring keys in the background, once immediately and then
+// every check interval.
+func (n *KeyExpiryNotifier) Start(ctx context.Context) {
+	go func() {
+		n.CheckExpiring(ctx)
+		n.loop()
+	}()
+}
+
+// Stop stops background scanning.
+func (n *KeyExpiryNotifier) Stop() {
+	close(n.stop)
+}
+
+func (n *KeyExpiryNotifier) loop() {
+	ticker := time.NewTicker(n.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-n.stop:
+			return
+		case <-ticker.C:
+			n.CheckExpiring(context.Background())
+		}
+	}
+}
+
+// CheckExpiring sends a warning for every unwarned key expiring within the
+// window and returns the number of emails sent. Keys whose email fails are
+// retried on the next scan.
+func (n *KeyExpiryNotifier) CheckExpiring(ctx context.Context) int {
+	now := n.clock.Now()
+	keys, err := n.keys.ListExpiringUnwarned(ctx, now, now.Add(n.cfg.Window))
+	if err != nil {
+		n.logger.Error().Err(err).Msg("failed to list expiring keys")
+		return 0
+	}
+
+	sent := 0
+	for _, k := range keys {
+		user, err := n.users.Get(ctx, k.UserID)
+		if err != nil {
+			n.logger.Warn().Err(err).Str("key_id", k.ID).Msg("owner of expiring key not found")
+			continue
+		}
+		if user.Status != "active" || user.Email == "" {
+			continue
+		}
+
+		msg, err := n.render(k, user, now)
+		if err != nil {
+			n.logger.Error().Err(err).Str("key_id", k.ID).Msg("failed to render key expiry email")
+			continue
+		}
+		if err := n.email.Send(ctx, msg); err != nil {
+			n.logger.Warn().Err(err).Str("key_id", k.ID).Msg("failed to send key expiry email")
+			continue
+		}
+		if err := n.keys.MarkExpiryWarned(ctx, k.ID, now); err != nil {
+			n.logger.Error().Err(err).Str("key_id", k.ID).Msg("failed to record key expiry warning")
+		}
+		sent++
+	}
+
+	if sent > 0 {
+		n.logger.Info().Int("sent", sent).Msg("key expiry warnings sent")
+	}
+	return sent
+}
+
+// keyExpiryEmailData holds data for the key expiry email templates.
+type keyExpiryEmailData struct {
+	Name      string
+	AppName   string
+	KeyName   string
+	KeyPrefix string
+	ExpiresAt string
+	DaysLeft  int
+	Link      string
+}
+
+func (n *KeyExpiryNotifier) render(k key.Key, user ports.User, now time.Time) (ports.EmailMessage, error) {
+	data := keyExpiryEmailData{
+		Name:      user.Name,
+		AppName:   n.cfg.AppName,
+		KeyName:   k.Name,
+		KeyPrefix: k.Prefix,
+		ExpiresAt: k.ExpiresAt.UTC().Format("January 2, 2006 15:04 MST"),
+		DaysLeft:  int(k.ExpiresAt.Sub(now).Hours() / 24),
+		Link:      strings.TrimSuffix(n.cfg.BaseURL, "/") + "/portal/api-keys",
+	}
+	if data.Name == "" {
+		data.Name = user.Email
+	}
+	if data.KeyName == "" {
+		data.KeyName = k.Prefix
+	}
+
+	var htmlBuf, textBuf bytes.Buffer
+	if err := keyExpiryHTMLTmpl.Execute(&htmlBuf, data); err != nil {
+		return ports.EmailMessage{}, fmt.Errorf("execute key expiry html template: %w", err)
+	}
+	if err := keyExpiryTextTmpl.Execute(&textBuf, data); err != nil {
+		return ports.EmailMessage{}, fmt.Errorf("execute key expiry text template: %w", err)
+	}
+
+	return ports.EmailMessage{
+		To:       user.Email,
+		Subject:  fmt.Sprintf("Your %s API key %q expires soon", n.cfg.AppName, data.KeyName),
+		HTMLBody: htmlBuf.String(),
+		TextBody: textBuf.String(),
+	}, nil
+}
+
+var keyExpiryHTMLTmpl = htmltemplate.Must(htmltemplate.New("keyExpiry").Parse(strings.TrimSpace(`
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>API Key Expiring</title>
+    <style>
+        body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { text-align: center; padding: 20px 0; }
+        .content { background: #f9f9f9; padding: 30px; border-radius: 8px; }
+        .button { display: inline-block; background: #007bff; color: white; padding: 12px 30px; text-decoration: none; border-radius: 5px; margin: 20px 0; }
+        .footer { text-align: center; padding: 20px; color: #666; font-size: 14px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>{{.AppName}}</h1>
+        </div>
+        <div class="content">
+            <h2>Your API key expires soon</h2>
+            <p>Hi {{.Name}},</p>
+            <p>Your API key <strong>{{.KeyName}}</strong> (<code>{{.KeyPrefix}}...</code>) expires on <strong>{{.ExpiresAt}}</strong>{{if gt .DaysLeft 0}}, in {{.DaysLeft}} day{{if ne .DaysLeft 1}}s{{end}}{{end}}.</p>
+            <p>Requests made with it will be rejected after that. Create a replacement key and update your integration before then.</p>
+            <p style="text-align: center;">
+                <a href="{{.Link}}" class="button">Manage API Keys</a>
+            </p>
+        </div>
+        <div class="footer">
+            <p>You are receiving this because you own an API key on {{.AppName}}.</p>
+        </div>
+    </div>
+</body>
+</html>
+`)))
+
+var keyExpiryTextTmpl = texttemplate.Must(texttemplate.New("keyExpiry").Parse(`Hi {{.Name}},
+
+Your {{.AppName}} API key "{{.KeyName}}" ({{.KeyPrefix}}...) expires on {{.ExpiresAt}}{{if gt .DaysLeft 0}}, in {{.DaysLeft}} day{{if ne .DaysLeft 1}}s{{end}}{{end}}.
+
+Requests made with it will be rejected after that. Create a replacement key and update your integration before then.
+
+Manage your API keys: {{.Link}}
+
+Thanks,
+The {{.AppName}} Team`))
@@ -0,0 +1,112 @@
+package app_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	"github.com/artpar/apigate/adapters/email"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+)
+
+func TestKeyExpiryNotifier_OneEmailPerExpiringKey(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(now)
+
+	users := memory.NewUserStore()
+	users.Create(ctx, ports.User{ID: "alice", Email: "alice@example.com", Name: "Alice", Status: "active"})
+	users.Create(ctx, ports.User{ID: "bob", Email: "bob@example.com", Name: "Bob", Status: "active"})
+	users.Create(ctx, ports.User{ID: "carol", Email: "carol@example.com", Name: "Carol", Status: "suspended"})
+
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+	keys := memory.NewKeyStore()
+	for _, k := range []key.Key{
+		{ID: "k-soon", UserID: "alice", Prefix: "ak_soon00000", Name: "Production", ExpiresAt: at(3 * 24 * time.Hour)},
+		{ID: "k-edge", UserID: "bob", Prefix: "ak_edge00000", ExpiresAt: at(7 * 24 * time.Hour)},
+		{ID: "k-later", UserID: "alice", Prefix: "ak_later0000", ExpiresAt: at(10 * 24 * time.Hour)},
+		{ID: "k-never", UserID: "alice", Prefix: "ak_never0000"},
+		{ID: "k-expired", UserID: "alice", Prefix: "ak_expired00", ExpiresAt: at(-time.Hour)},
+		{ID: "k-revoked", UserID: "bob", Prefix: "ak_revoked00", ExpiresAt: at(time.Hour), RevokedAt: at(-time.Hour)},
+		{ID: "k-suspended", UserID: "carol", Prefix: "ak_suspend00", ExpiresAt: at(time.Hour)},
+	} {
+		keys.Create(ctx, k)
+	}
+
+	sender := email.NewMockSender("https://portal.example.com", "Acme API")
+	notifier := app.NewKeyExpiryNotifier(keys, users, sender, clk, zerolog.Nop(), app.KeyExpiryNotifierConfig{
+		Window:  7 * 24 * time.Hour,
+		AppName: "Acme API",
+		BaseURL: "https://portal.example.com",
+	})
+
+	if sent := notifier.CheckExpiring(ctx); sent != 2 {
+		t.Fatalf("first scan sent %d emails, want 2", sent)
+	}
+	alice := sender.FindByTo("alice@example.com")
+	if len(alice) != 1 {
+		t.Fatalf("alice got %d emails, want 1", len(alice))
+	}
+	if !strings.Contains(alice[0].Subject, "Production") {
+		t.Errorf("subject = %q, want key name", alice[0].Subject)
+	}
+	for _, want := range []string{"Hi Alice", "ak_soon00000", "in 3 days", "https://portal.example.com/portal/api-keys"} {
+		if !strings.Contains(alice[0].TextBody, want) || !strings.Contains(alice[0].HTMLBody, want) {
+			t.Errorf("email body missing %q:\n%s", want, alice[0].TextBody)
+		}
+	}
+	if n := len(sender.FindByTo("bob@example.com")); n != 1 {
+		t.Errorf("bob got %d emails, want 1", n)
+	}
+
+	// Rescanning doesn't warn about the same keys again
+	if sent := notifier.CheckExpiring(ctx); sent != 0 {
+		t.Errorf("second scan sent %d emails, want 0", sent)
+	}
+
+	// Once the later key enters the window it gets its own single warning
+	clk.Advance(4 * 24 * time.Hour)
+	if sent := notifier.CheckExpiring(ctx); sent != 1 {
+		t.Errorf("scan after advancing sent %d emails, want 1", sent)
+	}
+	notifier.CheckExpiring(ctx)
+	if n := sender.Count(); n != 3 {
+		t.Errorf("total emails = %d, want 3 (one per expiring key)", n)
+	}
+}
+
+func TestKeyExpiryNotifier_RetriesFailedSends(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	expires := now.Add(24 * time.Hour)
+
+	users := memory.NewUserStore()
+	users.Create(ctx, ports.User{ID: "alice", Email: "alice@example.com", Status: "active"})
+	keys := memory.NewKeyStore()
+	keys.Create(ctx, key.Key{ID: "k1", UserID: "alice", Prefix: "ak_k100000000", ExpiresAt: &expires})
+
+	sender := email.NewMockSender("", "APIGate")
+	sender.SetShouldFail(true, nil)
+	notifier := app.NewKeyExpiryNotifier(keys, users, sender, clock.NewFake(now), zerolog.Nop(), app.KeyExpiryNotifierConfig{})
+
+	if sent := notifier.CheckExpiring(ctx); sent != 0 {
+		t.Fatalf("failed send reported %d sent", sent)
+	}
+
+	sender.SetShouldFail(false, nil)
+	if sent := notifier.CheckExpiring(ctx); sent != 1 {
+		t.Errorf("retry sent %d emails, want 1", sent)
+	}
+	if sent := notifier.CheckExpiring(ctx); sent != 0 {
+		t.Errorf("scan after successful retry sent %d emails, want 0", sent)
+	}
+}
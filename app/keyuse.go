@@ -0,0 +1,122 @@
+package app
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+)
+
+// KeyUseTrackerConfig contains configuration for KeyUseTracker.
+type KeyUseTrackerConfig struct {
+	FlushInterval time.Duration // Maximum time a use waits before being written (default: 10s)
+}
+
+// KeyUseTracker keeps the latest use of each API key in memory and writes
+// them to the key store every flush interval, so the proxy never waits on a
+// last-used write. However many requests a key serves, it gets at most one
+// write per interval. Stores that implement ports.KeyUseStore get one write
+// per flush; other stores get one UpdateLastUsed per key.
+type KeyUseTracker struct {
+	keys   ports.KeyStore
+	logger zerolog.Logger
+	cfg    KeyUseTrackerConfig
+
+	mu      sync.Mutex
+	pending map[string]key.Use // key ID -> latest use since the last flush
+
+	stop chan struct{}
+	done sync.WaitGroup
+}
+
+// NewKeyUseTracker creates a new key use tracker. Call Start to begin writing.
+func NewKeyUseTracker(keys ports.KeyStore, logger zerolog.Logger, cfg KeyUseTrackerConfig) *KeyUseTracker {
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 10 * time.Second
+	}
+
+	return &KeyUseTracker{
+		keys:    keys,
+		logger:  logger.With().Str("service", "key_use").Logger(),
+		cfg:     cfg,
+		pending: make(map[string]key.Use),
+		stop:    make(chan struct{}),
+	}
+}
+
+// Record notes a use of a key, replacing any earlier use of it waiting to
+// be written. It never waits for the store.
+func (t *KeyUseTracker) Record(keyID string, at time.Time, ip string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if prev, ok := t.pending[keyID]; ok && prev.At.After(at) {
+		return
+	}
+	t.pending[keyID] = key.Use{KeyID: keyID, At: at, IP: ip}
+}
+
+// Flush writes the latest use of each key recorded since the last flush.
+func (t *KeyUseTracker) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	if len(t.pending) == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	uses := make([]key.Use, 0, len(t.pending))
+	for _, u := range t.pending {
+		uses = append(uses, u)
+	}
+	t.pending = make(map[string]key.Use, len(uses))
+	t.mu.Unlock()
+
+	if store, ok := t.keys.(ports.KeyUseStore); ok {
+		return store.RecordUses(ctx, uses)
+	}
+	for _, u := range uses {
+		if err := t.keys.UpdateLastUsed(ctx, u.KeyID, u.At); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Start writes buffered uses in the background every flush interval.
+func (t *KeyUseTracker) Start() {
+	t.done.Add(1)
+	go t.loop()
+}
+
+// Stop writes any buffered uses and stops the background writer.
+func (t *KeyUseTracker) Stop() {
+	close(t.stop)
+	t.done.Wait()
+}
+
+func (t *KeyUseTracker) loop() {
+	defer t.done.Done()
+	ticker := time.NewTicker(t.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			t.write()
+		case <-t.stop:
+			t.write()
+			return
+		}
+	}
+}
+
+// write flushes pending uses. Failures are logged and the uses dropped; a
+// key's last use is refreshed by its next request anyway.
+func (t *KeyUseTracker) write() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := t.Flush(ctx); err != nil {
+		t.logger.Error().Err(err).Msg("failed to record key last use")
+	}
+}
@@ -0,0 +1,201 @@
+package app_test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+)
+
+// countingKeyUseStore counts batch writes to the key store.
+type countingKeyUseStore struct {
+	*memory.KeyStore
+	mu      sync.Mutex
+	batches [][]key.Use
+}
+
+func (s *countingKeyUseStore) RecordUses(ctx context.Context, uses []key.Use) error {
+	s.mu.Lock()
+	s.batches = append(s.batches, uses)
+	s.mu.Unlock()
+	return s.KeyStore.RecordUses(ctx, uses)
+}
+
+func (s *countingKeyUseStore) batchCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.batches)
+}
+
+// lastUsedOnlyKeyStore hides RecordUses, like a store without batch writes.
+type lastUsedOnlyKeyStore struct {
+	ports.KeyStore
+}
+
+// keyByID finds a key among user-1's keys.
+func keyByID(t *testing.T, keys ports.KeyStore, id string) key.Key {
+	t.Helper()
+	list, _ := keys.ListByUser(context.Background(), "user-1")
+	for _, k := range list {
+		if k.ID == id {
+			return k
+		}
+	}
+	t.Fatalf("key %s not found", id)
+	return key.Key{}
+}
+
+func newKeyUseStore(t *testing.T, ids ...string) *countingKeyUseStore {
+	t.Helper()
+	store := &countingKeyUseStore{KeyStore: memory.NewKeyStore()}
+	for _, id := range ids {
+		store.Create(context.Background(), key.Key{ID: id, UserID: "user-1", Prefix: "ak_" + id})
+	}
+	return store
+}
+
+func TestKeyUseTracker_WritesOnStop(t *testing.T) {
+	store := newKeyUseStore(t, "k1", "k2")
+	tracker := app.NewKeyUseTracker(store, zerolog.Nop(), app.KeyUseTrackerConfig{FlushInterval: time.Hour})
+	tracker.Start()
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tracker.Record("k1", base, "203.0.113.1")
+	tracker.Record("k2", base.Add(time.Second), "203.0.113.2")
+	tracker.Record("k1", base.Add(2*time.Second), "203.0.113.3")
+
+	// Nothing is written until the flush interval or Stop
+	if n := store.batchCount(); n != 0 {
+		t.Fatalf("batches before flush = %d, want 0", n)
+	}
+	k1 := keyByID(t, store, "k1")
+	if k1.LastUsed != nil {
+		t.Fatal("last use written before flush")
+	}
+
+	tracker.Stop()
+
+	if n := store.batchCount(); n != 1 {
+		t.Errorf("batches after Stop = %d, want 1", n)
+	}
+	k1 = keyByID(t, store, "k1")
+	if k1.LastUsed == nil || !k1.LastUsed.Equal(base.Add(2*time.Second)) || k1.LastUsedIP != "203.0.113.3" {
+		t.Errorf("k1 last use = %v from %q, want the latest use", k1.LastUsed, k1.LastUsedIP)
+	}
+	k2 := keyByID(t, store, "k2")
+	if k2.LastUsedIP != "203.0.113.2" {
+		t.Errorf("k2 last used IP = %q, want 203.0.113.2", k2.LastUsedIP)
+	}
+}
+
+func TestKeyUseTracker_FlushesOnInterval(t *testing.T) {
+	store := newKeyUseStore(t, "k1")
+	tracker := app.NewKeyUseTracker(store, zerolog.Nop(), app.KeyUseTrackerConfig{FlushInterval: 20 * time.Millisecond})
+	tracker.Start()
+	defer tracker.Stop()
+
+	tracker.Record("k1", time.Now(), "198.51.100.7")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for store.batchCount() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("uses not flushed on the interval")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestKeyUseTracker_CoalescesRapidUses(t *testing.T) {
+	store := newKeyUseStore(t, "k1", "k2")
+	tracker := app.NewKeyUseTracker(store, zerolog.Nop(), app.KeyUseTrackerConfig{FlushInterval: 50 * time.Millisecond})
+
+	// Many concurrent requests on two keys, all before the first flush
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var wg sync.WaitGroup
+	for g := 0; g < 10; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				n := g*100 + i
+				id := "k1"
+				if n%2 == 1 {
+					id = "k2"
+				}
+				tracker.Record(id, base.Add(time.Duration(n)*time.Millisecond), "203.0.113."+strconv.Itoa(n%256))
+			}
+		}(g)
+	}
+	wg.Wait()
+	tracker.Start()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for store.batchCount() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("uses not flushed on the interval")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	tracker.Stop()
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if len(store.batches) != 1 {
+		t.Fatalf("flushes = %d, want 1 for a burst within one interval", len(store.batches))
+	}
+	latest := map[string]time.Time{"k1": base.Add(998 * time.Millisecond), "k2": base.Add(999 * time.Millisecond)}
+	batch := store.batches[0]
+	if len(batch) != 2 {
+		t.Fatalf("uses flushed = %d, want one per key", len(batch))
+	}
+	for _, u := range batch {
+		if !u.At.Equal(latest[u.KeyID]) {
+			t.Errorf("%s flushed at %v, want latest use %v", u.KeyID, u.At, latest[u.KeyID])
+		}
+	}
+}
+
+func TestKeyUseTracker_KeepsLatestUseOutOfOrder(t *testing.T) {
+	store := newKeyUseStore(t, "k1")
+	tracker := app.NewKeyUseTracker(store, zerolog.Nop(), app.KeyUseTrackerConfig{FlushInterval: time.Hour})
+
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tracker.Record("k1", at, "203.0.113.2")
+	tracker.Record("k1", at.Add(-time.Second), "203.0.113.1") // finished later, started earlier
+	tracker.Flush(context.Background())
+
+	k1 := keyByID(t, store, "k1")
+	if k1.LastUsed == nil || !k1.LastUsed.Equal(at) || k1.LastUsedIP != "203.0.113.2" {
+		t.Errorf("last use = %v from %q, want %v from 203.0.113.2", k1.LastUsed, k1.LastUsedIP, at)
+	}
+
+	// Nothing left to write after a flush
+	tracker.Flush(context.Background())
+	if n := store.batchCount(); n != 1 {
+		t.Errorf("batches = %d, want 1", n)
+	}
+}
+
+func TestKeyUseTracker_FallsBackToUpdateLastUsed(t *testing.T) {
+	keys := memory.NewKeyStore()
+	keys.Create(context.Background(), key.Key{ID: "k1", UserID: "user-1", Prefix: "ak_k1"})
+	tracker := app.NewKeyUseTracker(lastUsedOnlyKeyStore{keys}, zerolog.Nop(), app.KeyUseTrackerConfig{})
+
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tracker.Record("k1", at, "203.0.113.1")
+	if err := tracker.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	k1 := keyByID(t, keys, "k1")
+	if k1.LastUsed == nil || !k1.LastUsed.Equal(at) {
+		t.Errorf("LastUsed = %v, want %v", k1.LastUsed, at)
+	}
+}
@@ -0,0 +1,437 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/artpar/apigate/domain/billing"
+	"github.com/artpar/apigate/domain/quota"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+)
+
+// LocalBillingProviderName is the provider name recorded on local
+// subscriptions and invoices.
+const LocalBillingProviderName = "local"
+
+// LocalBillingConfig contains configuration for LocalBillingProvider.
+type LocalBillingConfig struct {
+	CheckInterval time.Duration // How often ended periods are invoiced (default: 1h)
+}
+
+// LocalBillingProvider is a billing provider for self-hosted setups without a
+// payment processor. Customers are the local users, subscriptions and
+// invoices are kept in the local stores, and each subscription is invoiced
+// monthly from the usage recorded for it. It makes no external calls;
+// collecting payment for the invoices is left to the operator.
+//
+// When the invoice store also keeps pending items, a plan change mid-period
+// keeps the subscription and its period and is prorated on the next invoice.
+type LocalBillingProvider struct {
+	users         ports.UserStore
+	plans         ports.PlanStore
+	subscriptions ports.SubscriptionStore
+	invoices      ports.InvoiceStore
+	pending       ports.PendingInvoiceItemStore // nil if plan changes are not prorated
+	usage         ports.UsageStore
+	idGen         ports.IDGenerator
+	clock         ports.Clock
+	logger        zerolog.Logger
+	cfg           LocalBillingConfig
+
+	stop chan struct{}
+}
+
+// localBillingPageSize is how many users are read per page while invoicing.
+const localBillingPageSize = 100
+
+// NewLocalBillingProvider creates a new local billing provider.
+func NewLocalBillingProvider(
+	users ports.UserStore,
+	plans ports.PlanStore,
+	subscriptions ports.SubscriptionStore,
+	invoices ports.InvoiceStore,
+	usage ports.UsageStore,
+	idGen ports.IDGenerator,
+	clock ports.Clock,
+	logger zerolog.Logger,
+	cfg LocalBillingConfig,
+) *LocalBillingProvider {
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = time.Hour
+	}
+	pending, _ := invoices.(ports.PendingInvoiceItemStore)
+
+	return &LocalBillingProvider{
+		users:         users,
+		plans:         plans,
+		subscriptions: subscriptions,
+		invoices:      invoices,
+		pending:       pending,
+		usage:         usage,
+		idGen:         idGen,
+		clock:         clock,
+		logger:        logger.With().Str("service", "local_billing").Logger(),
+		cfg:           cfg,
+		stop:          make(chan struct{}),
+	}
+}
+
+// CreateCustomer returns the customer ID for a user. Local customers are the
+// users themselves, so the customer ID is the user ID.
+func (p *LocalBillingProvider) CreateCustomer(ctx context.Context, email, name, userID string) (string, error) {
+	if _, err := p.users.Get(ctx, userID); err != nil {
+		return "", fmt.Errorf("get user: %w", err)
+	}
+	return userID, nil
+}
+
+// CreateSubscription subscribes a customer to a plan. The price ID is the
+// plan ID. The user is moved to the plan. A current subscription to another
+// plan is switched to the new plan for the rest of its period, with the
+// proration added to its next invoice; otherwise it is ended and invoiced up
+// to now.
+func (p *LocalBillingProvider) CreateSubscription(ctx context.Context, customerID, priceID string) (billing.Subscription, error) {
+	user, err := p.users.Get(ctx, customerID)
+	if err != nil {
+		return billing.Subscription{}, fmt.Errorf("get user: %w", err)
+	}
+	plan, err := p.plans.Get(ctx, priceID)
+	if err != nil {
+		return billing.Subscription{}, fmt.Errorf("get plan: %w", err)
+	}
+
+	now := p.clock.Now().UTC()
+	if current, err := p.subscriptions.GetByUser(ctx, user.ID); err == nil && current.Provider == LocalBillingProviderName {
+		if p.pending != nil && current.PlanID != plan.ID && now.Before(current.CurrentPeriodEnd) {
+			return p.changePlan(ctx, user, current, plan, now)
+		}
+		if err := p.endSubscription(ctx, current, now); err != nil {
+			return billing.Subscription{}, err
+		}
+	}
+
+	sub := billing.Subscription{
+		ID:                 p.idGen.New(),
+		UserID:             user.ID,
+		PlanID:             plan.ID,
+		Provider:           LocalBillingProviderName,
+		Status:             billing.SubscriptionStatusActive,
+		CurrentPeriodStart: now,
+		CurrentPeriodEnd:   now.AddDate(0, 1, 0),
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+	if err := p.subscriptions.Create(ctx, sub); err != nil {
+		return billing.Subscription{}, fmt.Errorf("create subscription: %w", err)
+	}
+
+	user.PlanID = plan.ID
+	user.UpdatedAt = now
+	if err := p.users.Update(ctx, user); err != nil {
+		sub.Status = billing.SubscriptionStatusCancelled
+		sub.CancelledAt = &now
+		if rollbackErr := p.subscriptions.Update(ctx, sub); rollbackErr != nil {
+			p.logger.Error().Err(rollbackErr).Str("subscription_id", sub.ID).Msg("failed to roll back subscription")
+		}
+		return billing.Subscription{}, fmt.Errorf("update user plan: %w", err)
+	}
+
+	p.logger.Info().Str("user_id", user.ID).Str("plan_id", plan.ID).Str("subscription_id", sub.ID).Msg("subscription created")
+	return sub, nil
+}
+
+// changePlan moves sub and its user to plan at `at`, keeping the billing
+// period, and records the proration for the period's invoice.
+func (p *LocalBillingProvider) changePlan(ctx context.Context, user ports.User, sub billing.Subscription, plan ports.Plan, at time.Time) (billing.Subscription, error) {
+	oldPlan, err := p.plans.Get(ctx, sub.PlanID)
+	if err != nil {
+		return billing.Subscription{}, fmt.Errorf("get current plan: %w", err)
+	}
+	items := billing.ProrationItems(oldPlan.Name, oldPlan.PriceMonthly, plan.Name, plan.PriceMonthly, sub.CurrentPeriodStart, sub.CurrentPeriodEnd, at)
+
+	previous := sub
+	sub.PlanID = plan.ID
+	sub.CancelAtPeriodEnd = false
+	sub.UpdatedAt = at
+	if err := p.subscriptions.Update(ctx, sub); err != nil {
+		return billing.Subscription{}, fmt.Errorf("update subscription: %w", err)
+	}
+	if len(items) > 0 {
+		if err := p.pending.AddPendingItems(ctx, sub.ID, items, at); err != nil {
+			if rollbackErr := p.subscriptions.Update(ctx, previous); rollbackErr != nil {
+				p.logger.Error().Err(rollbackErr).Str("subscription_id", sub.ID).Msg("failed to roll back plan change")
+			}
+			return billing.Subscription{}, fmt.Errorf("record proration: %w", err)
+		}
+	}
+
+	user.PlanID = plan.ID
+	user.UpdatedAt = at
+	if err := p.users.Update(ctx, user); err != nil {
+		return billing.Subscription{}, fmt.Errorf("update user plan: %w", err)
+	}
+
+	var net int64
+	for _, item := range items {
+		net += item.Amount
+	}
+	p.logger.Info().
+		Str("user_id", user.ID).
+		Str("old_plan", oldPlan.ID).
+		Str("new_plan", plan.ID).
+		Int64("proration", net).
+		Msg("subscription plan changed")
+	return sub, nil
+}
+
+// CancelSubscription ends a subscription now, invoices its current period up
+// to now and moves the user to the default plan.
+func (p *LocalBillingProvider) CancelSubscription(ctx context.Context, subscriptionID string) error {
+	sub, err := p.subscriptions.Get(ctx, subscriptionID)
+	if err != nil {
+		return fmt.Errorf("get subscription: %w", err)
+	}
+	if sub.Status == billing.SubscriptionStatusCancelled {
+		return nil
+	}
+	if err := p.endSubscription(ctx, sub, p.clock.Now().UTC()); err != nil {
+		return err
+	}
+	return p.revertToDefaultPlan(ctx, sub.UserID)
+}
+
+// ReportUsage is a no-op: usage is already recorded locally by the proxy and
+// is read from the usage store when a period is invoiced.
+func (p *LocalBillingProvider) ReportUsage(ctx context.Context, subscriptionItemID string, quantity int64, timestamp time.Time) error {
+	return nil
+}
+
+// CreateInvoice records an open invoice for a customer with the given items.
+func (p *LocalBillingProvider) CreateInvoice(ctx context.Context, customerID string, items []billing.InvoiceItem) (billing.Invoice, error) {
+	now := p.clock.Now().UTC()
+	var subtotal int64
+	for _, item := range items {
+		subtotal += item.Amount
+	}
+	inv := billing.Invoice{
+		ID:          p.idGen.New(),
+		UserID:      customerID,
+		Provider:    LocalBillingProviderName,
+		PeriodStart: now,
+		PeriodEnd:   now,
+		Items:       items,
+		Subtotal:    subtotal,
+		Total:       subtotal,
+		Currency:    "usd",
+		Status:      billing.InvoiceStatusOpen,
+		CreatedAt:   now,
+	}
+	if err := p.invoices.Create(ctx, inv); err != nil {
+		return billing.Invoice{}, fmt.Errorf("create invoice: %w", err)
+	}
+	return inv, nil
+}
+
+// Start invoices ended periods in the background, once immediately and then
+// every check interval.
+func (p *LocalBillingProvider) Start(ctx context.Context) {
+	go func() {
+		p.InvoiceDue(ctx)
+		p.loop()
+	}()
+}
+
+// Stop stops background invoicing.
+func (p *LocalBillingProvider) Stop() {
+	close(p.stop)
+}
+
+func (p *LocalBillingProvider) loop() {
+	ticker := time.NewTicker(p.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.InvoiceDue(context.Background())
+		}
+	}
+}
+
+// InvoiceDue invoices every local subscription period that has ended and
+// returns the number of invoices created. Subscriptions move on to their next
+// period, or end if they were set to cancel at period end. Periods missed
+// while the server was down are caught up one invoice each.
+func (p *LocalBillingProvider) InvoiceDue(ctx context.Context) int {
+	now := p.clock.Now().UTC()
+	created := 0
+	for offset := 0; ; offset += localBillingPageSize {
+		users, err := p.users.List(ctx, localBillingPageSize, offset)
+		if err != nil {
+			p.logger.Error().Err(err).Msg("failed to list users")
+			break
+		}
+		for _, user := range users {
+			sub, err := p.subscriptions.GetByUser(ctx, user.ID)
+			if err != nil || sub.Provider != LocalBillingProviderName {
+				continue
+			}
+			n, err := p.invoicePeriods(ctx, sub, now)
+			created += n
+			if err != nil {
+				p.logger.Error().Err(err).Str("subscription_id", sub.ID).Msg("failed to invoice subscription")
+			}
+		}
+		if len(users) < localBillingPageSize {
+			break
+		}
+	}
+
+	if created > 0 {
+		p.logger.Info().Int("invoices", created).Msg("local invoices created")
+	}
+	return created
+}
+
+// invoicePeriods invoices each ended period of sub and advances it.
+func (p *LocalBillingProvider) invoicePeriods(ctx context.Context, sub billing.Subscription, now time.Time) (int, error) {
+	created := 0
+	for !sub.CurrentPeriodEnd.After(now) {
+		ok, err := p.invoicePeriod(ctx, sub, sub.CurrentPeriodStart, sub.CurrentPeriodEnd)
+		if err != nil {
+			return created, err
+		}
+		if ok {
+			created++
+		}
+
+		if sub.CancelAtPeriodEnd {
+			end := sub.CurrentPeriodEnd
+			sub.Status = billing.SubscriptionStatusCancelled
+			sub.CancelledAt = &end
+			sub.UpdatedAt = now
+			if err := p.subscriptions.Update(ctx, sub); err != nil {
+				return created, fmt.Errorf("cancel subscription: %w", err)
+			}
+			return created, p.revertToDefaultPlan(ctx, sub.UserID)
+		}
+
+		sub.CurrentPeriodStart = sub.CurrentPeriodEnd
+		sub.CurrentPeriodEnd = sub.CurrentPeriodEnd.AddDate(0, 1, 0)
+		sub.UpdatedAt = now
+		if err := p.subscriptions.Update(ctx, sub); err != nil {
+			return created, fmt.Errorf("advance subscription period: %w", err)
+		}
+	}
+	return created, nil
+}
+
+// invoicePeriod creates the invoice for [start, end) of sub from the usage
+// recorded in it, plus any pending items such as proration. The invoice ID is
+// derived from the subscription and period, so a period is never invoiced
+// twice; it reports whether an invoice was created.
+func (p *LocalBillingProvider) invoicePeriod(ctx context.Context, sub billing.Subscription, start, end time.Time) (bool, error) {
+	id := localInvoiceID(sub.ID, start)
+	if _, err := p.invoices.Get(ctx, id); err == nil {
+		return false, nil
+	}
+
+	plan, err := p.plans.Get(ctx, sub.PlanID)
+	if err != nil {
+		return false, fmt.Errorf("get plan: %w", err)
+	}
+	summary, err := p.usage.GetSummary(ctx, sub.UserID, start, end.Add(-time.Nanosecond))
+	if err != nil {
+		return false, fmt.Errorf("get usage: %w", err)
+	}
+	used := summary.RequestCount
+	meterType := billing.MeterTypeRequests
+	if quota.ConfigFromPlan(plan).MeterType == quota.MeterTypeComputeUnits {
+		used = int64(summary.ComputeUnits)
+		meterType = billing.MeterTypeComputeUnits
+	}
+
+	inv := billing.CalculatePeriodInvoice(sub.UserID, start, end, plan.Name, plan.PriceMonthly, used, plan.RequestsPerMonth, plan.OveragePrice, meterType)
+	inv.ID = id
+	inv.Provider = LocalBillingProviderName
+	due := end.AddDate(0, 0, 14)
+	inv.DueDate = &due
+
+	if p.pending != nil {
+		items, err := p.pending.ListPendingItems(ctx, sub.ID, end)
+		if err != nil {
+			return false, fmt.Errorf("list pending items: %w", err)
+		}
+		for _, item := range items {
+			inv.Items = append(inv.Items, item)
+			inv.Subtotal += item.Amount
+			inv.Total += item.Amount
+		}
+	}
+
+	// The pending items are marked together with the invoice, so a failure
+	// leaves neither and the period is invoiced again on the next run
+	if p.pending != nil {
+		err = p.pending.CreateInvoiceWithItems(ctx, inv, sub.ID, end)
+	} else {
+		err = p.invoices.Create(ctx, inv)
+	}
+	if err != nil {
+		return false, fmt.Errorf("create invoice: %w", err)
+	}
+	return true, nil
+}
+
+// endSubscription cancels sub at the given time and invoices its current period up to it.
+func (p *LocalBillingProvider) endSubscription(ctx context.Context, sub billing.Subscription, at time.Time) error {
+	if at.After(sub.CurrentPeriodStart) {
+		if _, err := p.invoicePeriod(ctx, sub, sub.CurrentPeriodStart, at); err != nil {
+			return err
+		}
+	}
+	sub.Status = billing.SubscriptionStatusCancelled
+	sub.CancelledAt = &at
+	sub.CurrentPeriodEnd = at
+	sub.UpdatedAt = at
+	if err := p.subscriptions.Update(ctx, sub); err != nil {
+		return fmt.Errorf("cancel subscription: %w", err)
+	}
+	return nil
+}
+
+// revertToDefaultPlan moves the user to the default plan, if there is one.
+func (p *LocalBillingProvider) revertToDefaultPlan(ctx context.Context, userID string) error {
+	plans, err := p.plans.List(ctx)
+	if err != nil {
+		return fmt.Errorf("list plans: %w", err)
+	}
+	for _, plan := range plans {
+		if !plan.IsDefault {
+			continue
+		}
+		user, err := p.users.Get(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("get user: %w", err)
+		}
+		user.PlanID = plan.ID
+		user.UpdatedAt = p.clock.Now().UTC()
+		if err := p.users.Update(ctx, user); err != nil {
+			return fmt.Errorf("update user plan: %w", err)
+		}
+		return nil
+	}
+	p.logger.Warn().Str("user_id", userID).Msg("no default plan found, user plan unchanged")
+	return nil
+}
+
+// localInvoiceID is the ID of the invoice for a subscription period.
+func localInvoiceID(subscriptionID string, periodStart time.Time) string {
+	return "inv_" + subscriptionID + "_" + periodStart.UTC().Format("20060102T150405")
+}
+
+// Ensure interface compliance.
+var _ ports.BillingProvider = (*LocalBillingProvider)(nil)
@@ -0,0 +1,377 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/domain/billing"
+	"github.com/artpar/apigate/domain/usage"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+)
+
+type mockInvoiceStore struct {
+	invoices  []billing.Invoice
+	pending   []pendingItem
+	createErr error // Returned by CreateInvoiceWithItems when set
+}
+
+type pendingItem struct {
+	subscriptionID string
+	item           billing.InvoiceItem
+	at             time.Time
+	invoiceID      string
+}
+
+func (m *mockInvoiceStore) Create(ctx context.Context, inv billing.Invoice) error {
+	for _, existing := range m.invoices {
+		if existing.ID == inv.ID {
+			return errors.New("duplicate")
+		}
+	}
+	m.invoices = append(m.invoices, inv)
+	return nil
+}
+
+func (m *mockInvoiceStore) Get(ctx context.Context, id string) (billing.Invoice, error) {
+	for _, inv := range m.invoices {
+		if inv.ID == id {
+			return inv, nil
+		}
+	}
+	return billing.Invoice{}, errors.New("not found")
+}
+
+func (m *mockInvoiceStore) ListByUser(ctx context.Context, userID string, limit int) ([]billing.Invoice, error) {
+	var out []billing.Invoice
+	for _, inv := range m.invoices {
+		if inv.UserID == userID {
+			out = append(out, inv)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockInvoiceStore) UpdateStatus(ctx context.Context, id string, status billing.InvoiceStatus, paidAt *time.Time) error {
+	return nil
+}
+
+func (m *mockInvoiceStore) AddPendingItems(ctx context.Context, subscriptionID string, items []billing.InvoiceItem, at time.Time) error {
+	for _, item := range items {
+		m.pending = append(m.pending, pendingItem{subscriptionID: subscriptionID, item: item, at: at})
+	}
+	return nil
+}
+
+func (m *mockInvoiceStore) ListPendingItems(ctx context.Context, subscriptionID string, before time.Time) ([]billing.InvoiceItem, error) {
+	var out []billing.InvoiceItem
+	for _, p := range m.pending {
+		if p.subscriptionID == subscriptionID && p.invoiceID == "" && p.at.Before(before) {
+			out = append(out, p.item)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockInvoiceStore) CreateInvoiceWithItems(ctx context.Context, inv billing.Invoice, subscriptionID string, before time.Time) error {
+	if m.createErr != nil {
+		return m.createErr
+	}
+	if err := m.Create(ctx, inv); err != nil {
+		return err
+	}
+	for i, p := range m.pending {
+		if p.subscriptionID == subscriptionID && p.invoiceID == "" && p.at.Before(before) {
+			m.pending[i].invoiceID = inv.ID
+		}
+	}
+	return nil
+}
+
+type localBillingFixture struct {
+	provider *LocalBillingProvider
+	users    *memory.UserStore
+	subs     *mockSubscriptionStore
+	invoices *mockInvoiceStore
+	usage    *memory.UsageStore
+	clock    *clock.Fake
+	events   int
+}
+
+var localBillingStart = time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)
+
+func newLocalBillingFixture(t *testing.T) *localBillingFixture {
+	t.Helper()
+	f := &localBillingFixture{
+		users:    memory.NewUserStore(),
+		subs:     &mockSubscriptionStore{},
+		invoices: &mockInvoiceStore{},
+		usage:    memory.NewUsageStore(),
+		clock:    clock.NewFake(localBillingStart),
+	}
+	f.users.Create(context.Background(), ports.User{ID: "alice", Email: "alice@example.com", Name: "Alice", PlanID: "free", Status: "active"})
+	plans := &mockPlanStore{plans: []ports.Plan{
+		{ID: "free", Name: "Free", RequestsPerMonth: 100, IsDefault: true},
+		{ID: "pro", Name: "Pro", RequestsPerMonth: 1000, PriceMonthly: 2900, OveragePrice: 10},
+		{ID: "business", Name: "Business", RequestsPerMonth: 10000, PriceMonthly: 5800},
+	}}
+	f.provider = NewLocalBillingProvider(f.users, plans, f.subs, f.invoices, f.usage, &mockIDGenerator{}, f.clock, zerolog.Nop(), LocalBillingConfig{})
+	return f
+}
+
+// record stores count requests made by alice at the current time.
+func (f *localBillingFixture) record(t *testing.T, count int) {
+	t.Helper()
+	var events []usage.Event
+	for i := 0; i < count; i++ {
+		f.events++
+		events = append(events, usage.Event{ID: "evt-" + itoa(f.events), UserID: "alice", Method: "GET", Path: "/v1", StatusCode: 200, Timestamp: f.clock.Now()})
+	}
+	if err := f.usage.RecordBatch(context.Background(), events); err != nil {
+		t.Fatalf("record usage: %v", err)
+	}
+}
+
+func TestLocalBillingProvider_CreateSubscription(t *testing.T) {
+	ctx := context.Background()
+	f := newLocalBillingFixture(t)
+
+	customerID, err := f.provider.CreateCustomer(ctx, "alice@example.com", "Alice", "alice")
+	if err != nil || customerID != "alice" {
+		t.Fatalf("CreateCustomer = %q, %v; want alice", customerID, err)
+	}
+	if _, err := f.provider.CreateCustomer(ctx, "nobody@example.com", "", "nobody"); err == nil {
+		t.Error("CreateCustomer succeeded for an unknown user")
+	}
+
+	sub, err := f.provider.CreateSubscription(ctx, customerID, "pro")
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+	if sub.Provider != LocalBillingProviderName || sub.Status != billing.SubscriptionStatusActive || sub.PlanID != "pro" {
+		t.Errorf("subscription = %+v", sub)
+	}
+	if !sub.CurrentPeriodStart.Equal(localBillingStart) || !sub.CurrentPeriodEnd.Equal(localBillingStart.AddDate(0, 1, 0)) {
+		t.Errorf("period = %v - %v, want one month from %v", sub.CurrentPeriodStart, sub.CurrentPeriodEnd, localBillingStart)
+	}
+	if stored, err := f.subs.GetByUser(ctx, "alice"); err != nil || stored.ID != sub.ID {
+		t.Errorf("stored subscription = %+v, %v", stored, err)
+	}
+	if user, _ := f.users.Get(ctx, "alice"); user.PlanID != "pro" {
+		t.Errorf("user plan = %q, want pro", user.PlanID)
+	}
+	if _, err := f.provider.CreateSubscription(ctx, customerID, "missing"); err == nil {
+		t.Error("CreateSubscription succeeded for an unknown plan")
+	}
+}
+
+func TestLocalBillingProvider_InvoicesPeriodFromUsage(t *testing.T) {
+	ctx := context.Background()
+	f := newLocalBillingFixture(t)
+	f.clock.Set(localBillingStart.Add(-time.Hour))
+	f.record(t, 50) // Before the subscription; not billed
+	f.clock.Set(localBillingStart)
+
+	sub, err := f.provider.CreateSubscription(ctx, "alice", "pro")
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+	f.clock.Advance(time.Hour)
+	f.record(t, 1800)
+	f.clock.Advance(10 * 24 * time.Hour)
+	f.record(t, 700)
+
+	if n := f.provider.InvoiceDue(ctx); n != 0 {
+		t.Fatalf("invoiced %d periods before the period ended", n)
+	}
+
+	f.clock.Set(sub.CurrentPeriodEnd.Add(time.Minute))
+	f.record(t, 30) // In the next period
+	if n := f.provider.InvoiceDue(ctx); n != 1 {
+		t.Fatalf("InvoiceDue created %d invoices, want 1", n)
+	}
+	if n := f.provider.InvoiceDue(ctx); n != 0 {
+		t.Errorf("second run created %d invoices, want 0", n)
+	}
+
+	if len(f.invoices.invoices) != 1 {
+		t.Fatalf("got %d invoices, want 1", len(f.invoices.invoices))
+	}
+	inv := f.invoices.invoices[0]
+	// 2500 requests on a 1000 request plan: 1500 overage at $0.001 = $1.50
+	if inv.Total != 2900+150 || inv.Status != billing.InvoiceStatusOpen || inv.UserID != "alice" {
+		t.Errorf("invoice = %+v, want open $30.50 for alice", inv)
+	}
+	if len(inv.Items) != 2 || !strings.Contains(inv.Items[1].Description, "1,500 requests") {
+		t.Errorf("items = %+v", inv.Items)
+	}
+	if !inv.PeriodStart.Equal(sub.CurrentPeriodStart) || !inv.PeriodEnd.Equal(sub.CurrentPeriodEnd) {
+		t.Errorf("invoice period = %v - %v", inv.PeriodStart, inv.PeriodEnd)
+	}
+
+	next, _ := f.subs.GetByUser(ctx, "alice")
+	if !next.CurrentPeriodStart.Equal(sub.CurrentPeriodEnd) || !next.CurrentPeriodEnd.Equal(sub.CurrentPeriodEnd.AddDate(0, 1, 0)) {
+		t.Errorf("subscription did not advance: %v - %v", next.CurrentPeriodStart, next.CurrentPeriodEnd)
+	}
+}
+
+func TestLocalBillingProvider_CatchesUpMissedPeriods(t *testing.T) {
+	ctx := context.Background()
+	f := newLocalBillingFixture(t)
+	sub, _ := f.provider.CreateSubscription(ctx, "alice", "pro")
+
+	f.clock.Set(sub.CurrentPeriodEnd.AddDate(0, 2, 1))
+	if n := f.provider.InvoiceDue(ctx); n != 3 {
+		t.Errorf("InvoiceDue created %d invoices, want 3", n)
+	}
+	for _, inv := range f.invoices.invoices {
+		if inv.Total != 2900 {
+			t.Errorf("invoice %s total = %d, want the plan price", inv.ID, inv.Total)
+		}
+	}
+}
+
+func TestLocalBillingProvider_Cancel(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("immediately", func(t *testing.T) {
+		f := newLocalBillingFixture(t)
+		sub, _ := f.provider.CreateSubscription(ctx, "alice", "pro")
+		f.clock.Advance(24 * time.Hour)
+		f.record(t, 1200)
+		f.clock.Advance(time.Hour)
+
+		if err := f.provider.CancelSubscription(ctx, sub.ID); err != nil {
+			t.Fatalf("CancelSubscription: %v", err)
+		}
+		if len(f.invoices.invoices) != 1 || f.invoices.invoices[0].Total != 2900+20 {
+			t.Fatalf("final invoices = %+v, want one for $29.20", f.invoices.invoices)
+		}
+		if _, err := f.subs.GetByUser(ctx, "alice"); err == nil {
+			t.Error("subscription is still active")
+		}
+		if user, _ := f.users.Get(ctx, "alice"); user.PlanID != "free" {
+			t.Errorf("user plan = %q, want the default plan", user.PlanID)
+		}
+		if err := f.provider.CancelSubscription(ctx, sub.ID); err != nil || len(f.invoices.invoices) != 1 {
+			t.Errorf("cancelling twice: %v, %d invoices", err, len(f.invoices.invoices))
+		}
+	})
+
+	t.Run("at period end", func(t *testing.T) {
+		f := newLocalBillingFixture(t)
+		sub, _ := f.provider.CreateSubscription(ctx, "alice", "pro")
+		sub.CancelAtPeriodEnd = true
+		f.subs.Update(ctx, sub)
+
+		f.clock.Set(sub.CurrentPeriodEnd.AddDate(0, 3, 0))
+		if n := f.provider.InvoiceDue(ctx); n != 1 {
+			t.Errorf("InvoiceDue created %d invoices, want only the final period", n)
+		}
+		if _, err := f.subs.GetByUser(ctx, "alice"); err == nil {
+			t.Error("subscription is still active")
+		}
+		if user, _ := f.users.Get(ctx, "alice"); user.PlanID != "free" {
+			t.Errorf("user plan = %q, want the default plan", user.PlanID)
+		}
+	})
+}
+
+func TestLocalBillingProvider_ProratesUpgradeMidPeriod(t *testing.T) {
+	ctx := context.Background()
+	f := newLocalBillingFixture(t)
+	sub, _ := f.provider.CreateSubscription(ctx, "alice", "pro")
+
+	// Halfway through the 31 day period
+	f.clock.Advance(sub.CurrentPeriodEnd.Sub(sub.CurrentPeriodStart) / 2)
+	upgraded, err := f.provider.CreateSubscription(ctx, "alice", "business")
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+	if upgraded.ID != sub.ID || upgraded.PlanID != "business" || !upgraded.CurrentPeriodEnd.Equal(sub.CurrentPeriodEnd) {
+		t.Errorf("upgraded subscription = %+v, want %s on business for the same period", upgraded, sub.ID)
+	}
+	if user, _ := f.users.Get(ctx, "alice"); user.PlanID != "business" {
+		t.Errorf("user plan = %q, want business", user.PlanID)
+	}
+	if len(f.invoices.invoices) != 0 {
+		t.Errorf("plan change created invoices: %+v", f.invoices.invoices)
+	}
+
+	f.clock.Set(sub.CurrentPeriodEnd)
+	if n := f.provider.InvoiceDue(ctx); n != 1 {
+		t.Fatalf("InvoiceDue created %d invoices, want 1", n)
+	}
+	inv := f.invoices.invoices[0]
+	// Half a period of Pro ($14.50) and half of Business ($29)
+	if inv.Total != 1450+2900 || inv.Subtotal != inv.Total {
+		t.Errorf("invoice total = %d, want 4350; items %+v", inv.Total, inv.Items)
+	}
+	if len(inv.Items) != 3 || inv.Items[1].Amount != 1450 || inv.Items[2].Amount != -2900 {
+		t.Errorf("items = %+v, want subscription, Pro charge and Business credit", inv.Items)
+	}
+
+	// The proration is billed once
+	f.clock.Set(sub.CurrentPeriodEnd.AddDate(0, 1, 0))
+	f.provider.InvoiceDue(ctx)
+	if len(f.invoices.invoices) != 2 || f.invoices.invoices[1].Total != 5800 {
+		t.Errorf("next invoice = %+v, want $58 without proration", f.invoices.invoices[1:])
+	}
+}
+
+func TestLocalBillingProvider_RetriesFailedInvoice(t *testing.T) {
+	ctx := context.Background()
+	f := newLocalBillingFixture(t)
+	sub, _ := f.provider.CreateSubscription(ctx, "alice", "pro")
+	f.clock.Advance(sub.CurrentPeriodEnd.Sub(sub.CurrentPeriodStart) / 2)
+	f.provider.CreateSubscription(ctx, "alice", "business")
+
+	// Neither the invoice nor the proration is recorded, and the period stays open
+	f.invoices.createErr = errors.New("database is locked")
+	f.clock.Set(sub.CurrentPeriodEnd)
+	if n := f.provider.InvoiceDue(ctx); n != 0 {
+		t.Fatalf("InvoiceDue created %d invoices while the store fails, want 0", n)
+	}
+	if items, _ := f.invoices.ListPendingItems(ctx, sub.ID, sub.CurrentPeriodEnd); len(items) != 2 {
+		t.Errorf("pending items after failure = %+v, want the proration", items)
+	}
+	if current, _ := f.subs.GetByUser(ctx, "alice"); !current.CurrentPeriodEnd.Equal(sub.CurrentPeriodEnd) {
+		t.Errorf("subscription advanced past the failed period to %v", current.CurrentPeriodEnd)
+	}
+
+	// The next run invoices the period with the proration
+	f.invoices.createErr = nil
+	if n := f.provider.InvoiceDue(ctx); n != 1 {
+		t.Fatalf("retry created %d invoices, want 1", n)
+	}
+	if inv := f.invoices.invoices[0]; inv.Total != 1450+2900 || len(inv.Items) != 3 {
+		t.Errorf("retried invoice = %+v, want the prorated total", inv)
+	}
+	if items, _ := f.invoices.ListPendingItems(ctx, sub.ID, sub.CurrentPeriodEnd); len(items) != 0 {
+		t.Errorf("pending items after invoicing = %+v, want none", items)
+	}
+}
+
+func TestLocalBillingProvider_CreateInvoice(t *testing.T) {
+	ctx := context.Background()
+	f := newLocalBillingFixture(t)
+
+	inv, err := f.provider.CreateInvoice(ctx, "alice", []billing.InvoiceItem{
+		{Description: "Setup fee", Quantity: 1, UnitPrice: 5000, Amount: 5000},
+		{Description: "Support hours", Quantity: 3, UnitPrice: 1500, Amount: 4500},
+	})
+	if err != nil {
+		t.Fatalf("CreateInvoice: %v", err)
+	}
+	if inv.Total != 9500 || inv.Subtotal != 9500 || inv.Provider != LocalBillingProviderName {
+		t.Errorf("invoice = %+v, want $95 local invoice", inv)
+	}
+	if stored, err := f.invoices.Get(ctx, inv.ID); err != nil || stored.Total != 9500 {
+		t.Errorf("stored invoice = %+v, %v", stored, err)
+	}
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"context"
+	"maps"
+	"time"
+
+	"github.com/artpar/apigate/domain/proxy"
+	"github.com/artpar/apigate/domain/route"
+)
+
+// maxMirrorsInFlight caps concurrent mirror requests per gateway. Copies
+// beyond it are dropped, so a slow shadow upstream cannot pile up goroutines.
+const maxMirrorsInFlight = 64
+
+// mirrorTimeout bounds a mirror request when the route sets no request timeout.
+const mirrorTimeout = 30 * time.Second
+
+// mirror sends a copy of req to the route's mirror upstream in the
+// background. The mirror's response is discarded and its failures are
+// ignored, so it never affects the client's response. req must be the
+// request as it would be forwarded, before upstream authentication is added.
+func (s *ProxyService) mirror(ctx context.Context, req proxy.Request, matchedRoute *route.Route) {
+	if matchedRoute == nil || matchedRoute.MirrorUpstreamID == "" || s.routeService == nil {
+		return
+	}
+	upstream := s.routeService.table(ctx).upstream(matchedRoute.MirrorUpstreamID)
+	if upstream == nil {
+		return
+	}
+
+	select {
+	case s.mirrorSlots <- struct{}{}:
+	default:
+		return // Too many mirrors in flight - drop this copy
+	}
+
+	// The primary request may change req's headers after this returns
+	req.Headers = s.routeService.ApplyUpstreamAuth(upstream, maps.Clone(req.Headers))
+	req.MaxResponseBody = s.responseBodyLimit(matchedRoute)
+	req.Timeouts = upstreamTimeouts(matchedRoute)
+	timeout := mirrorTimeout
+	if matchedRoute.RequestTimeout > 0 {
+		timeout = matchedRoute.RequestTimeout
+	}
+
+	go func() {
+		defer func() { <-s.mirrorSlots }()
+		// Detach from the client request so the mirror outlives it
+		mctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+		defer cancel()
+		resp, err := s.upstream.ForwardTo(mctx, req, upstream)
+		if err == nil && resp.Stream != nil {
+			resp.Stream.Close()
+		}
+	}()
+}
@@ -0,0 +1,195 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/artpar/apigate/domain/quota"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+)
+
+// OverageReporterConfig contains configuration for OverageReporter.
+type OverageReporterConfig struct {
+	Interval time.Duration // How often overage is reported (default: 1h)
+}
+
+// OverageReporter reports usage past the quota of allow_overage plans to the
+// payment provider as metered usage. Units already reported are recorded per
+// quota period, so each run reports only the new overage.
+type OverageReporter struct {
+	users   ports.UserStore
+	plans   ports.PlanStore
+	subs    ports.SubscriptionStore
+	usage   ports.UsageStore
+	reports ports.OverageReportStore
+	payment ports.PaymentProvider
+	clock   ports.Clock
+	logger  zerolog.Logger
+	cfg     OverageReporterConfig
+
+	stop chan struct{}
+}
+
+// overagePageSize is how many users are read per page while reporting.
+const overagePageSize = 100
+
+// NewOverageReporter creates a new overage reporter.
+func NewOverageReporter(
+	users ports.UserStore,
+	plans ports.PlanStore,
+	subs ports.SubscriptionStore,
+	usage ports.UsageStore,
+	reports ports.OverageReportStore,
+	payment ports.PaymentProvider,
+	clock ports.Clock,
+	logger zerolog.Logger,
+	cfg OverageReporterConfig,
+) *OverageReporter {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Hour
+	}
+
+	return &OverageReporter{
+		users:   users,
+		plans:   plans,
+		subs:    subs,
+		usage:   usage,
+		reports: reports,
+		payment: payment,
+		clock:   clock,
+		logger:  logger.With().Str("service", "overage_reporter").Logger(),
+		cfg:     cfg,
+		stop:    make(chan struct{}),
+	}
+}
+
+// Start reports overage in the background, once immediately and then every
+// interval.
+func (r *OverageReporter) Start(ctx context.Context) {
+	go func() {
+		r.ReportOverage(ctx)
+		r.loop()
+	}()
+}
+
+// Stop stops background reporting.
+func (r *OverageReporter) Stop() {
+	close(r.stop)
+}
+
+func (r *OverageReporter) loop() {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.ReportOverage(context.Background())
+		}
+	}
+}
+
+// ReportOverage reports the unreported overage of every active user on an
+// allow_overage plan and returns the total units reported. Users without an
+// active subscription item are skipped; failed reports are retried on the
+// next run.
+func (r *OverageReporter) ReportOverage(ctx context.Context) int64 {
+	now := r.clock.Now()
+	plans, err := r.plans.List(ctx)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("failed to list plans")
+		return 0
+	}
+	byID := make(map[string]ports.Plan, len(plans))
+	for _, p := range plans {
+		if p.AllowOverage && p.RequestsPerMonth > 0 {
+			byID[p.ID] = p
+		}
+	}
+	if len(byID) == 0 {
+		return 0
+	}
+
+	var total int64
+	for offset := 0; ; offset += overagePageSize {
+		users, err := r.users.List(ctx, overagePageSize, offset)
+		if err != nil {
+			r.logger.Error().Err(err).Msg("failed to list users")
+			break
+		}
+		for _, user := range users {
+			p, ok := byID[user.PlanID]
+			if !ok || user.Status != "active" {
+				continue
+			}
+			total += r.reportUser(ctx, user, p, now)
+		}
+		if len(users) < overagePageSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		r.logger.Info().Int64("units", total).Msg("overage reported")
+	}
+	return total
+}
+
+// reportUser reports the user's new overage and returns the units reported.
+// The previous quota period is checked too, so overage incurred just before
+// a reset is still billed.
+func (r *OverageReporter) reportUser(ctx context.Context, user ports.User, p ports.Plan, now time.Time) int64 {
+	sub, err := r.subs.GetByUser(ctx, user.ID)
+	if err != nil || !sub.IsActive() || sub.ProviderItemID == "" {
+		return 0
+	}
+
+	period := quota.Period(p.QuotaPeriod)
+	currentStart, _ := quota.WindowBounds(period, now, user.CreatedAt)
+	previousStart, _ := quota.WindowBounds(period, currentStart.Add(-time.Nanosecond), user.CreatedAt)
+
+	var reported int64
+	for _, start := range []time.Time{previousStart, currentStart} {
+		_, end := quota.WindowBounds(period, start, user.CreatedAt)
+		reported += r.reportPeriod(ctx, user, p, sub.ProviderItemID, start, end, now)
+	}
+	return reported
+}
+
+func (r *OverageReporter) reportPeriod(ctx context.Context, user ports.User, p ports.Plan, itemID string, start, end, now time.Time) int64 {
+	summary, err := r.usage.GetSummary(ctx, user.ID, start, end)
+	if err != nil {
+		r.logger.Warn().Err(err).Str("user_id", user.ID).Msg("failed to read usage for overage")
+		return 0
+	}
+	used := summary.RequestCount
+	if quota.ConfigFromPlan(p).MeterType == quota.MeterTypeComputeUnits {
+		used = int64(summary.ComputeUnits)
+	}
+	overage := quota.OverageUnits(used, p.RequestsPerMonth)
+	if overage == 0 {
+		return 0
+	}
+
+	alreadyReported, err := r.reports.GetReportedOverage(ctx, user.ID, start)
+	if err != nil {
+		r.logger.Warn().Err(err).Str("user_id", user.ID).Msg("failed to read reported overage")
+		return 0
+	}
+	delta := overage - alreadyReported
+	if delta <= 0 {
+		return 0
+	}
+
+	if err := r.payment.ReportUsage(ctx, itemID, delta, now); err != nil {
+		r.logger.Warn().Err(err).Str("user_id", user.ID).Int64("units", delta).Msg("failed to report overage")
+		return 0
+	}
+	if err := r.reports.AddReportedOverage(ctx, user.ID, start, delta, now); err != nil {
+		r.logger.Error().Err(err).Str("user_id", user.ID).Int64("units", delta).Msg("failed to record reported overage")
+	}
+	return delta
+}
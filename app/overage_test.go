@@ -0,0 +1,163 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/domain/billing"
+	"github.com/artpar/apigate/domain/usage"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+)
+
+// usageReportingProvider records ReportUsage calls.
+type usageReportingProvider struct {
+	ports.PaymentProvider
+
+	mu      sync.Mutex
+	reports []usageReport
+	fail    bool
+}
+
+type usageReport struct {
+	itemID   string
+	quantity int64
+}
+
+func (p *usageReportingProvider) Name() string { return "stripe" }
+
+func (p *usageReportingProvider) ReportUsage(ctx context.Context, subscriptionItemID string, quantity int64, timestamp time.Time) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.fail {
+		return errors.New("provider unavailable")
+	}
+	p.reports = append(p.reports, usageReport{subscriptionItemID, quantity})
+	return nil
+}
+
+func (p *usageReportingProvider) total(itemID string) int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var n int64
+	for _, r := range p.reports {
+		if r.itemID == itemID {
+			n += r.quantity
+		}
+	}
+	return n
+}
+
+func TestOverageReporter_ReportsUsagePastQuota(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start.Add(time.Hour))
+
+	users := memory.NewUserStore()
+	users.Create(ctx, ports.User{ID: "alice", Email: "alice@example.com", PlanID: "payg", Status: "active"})
+	users.Create(ctx, ports.User{ID: "bob", Email: "bob@example.com", PlanID: "hard", Status: "active"})
+	users.Create(ctx, ports.User{ID: "carol", Email: "carol@example.com", PlanID: "payg", Status: "active"})
+	plans := &mockPlanStore{plans: []ports.Plan{
+		{ID: "payg", Name: "Pay as you go", RequestsPerMonth: 10, OveragePrice: 10, AllowOverage: true},
+		{ID: "hard", Name: "Hard", RequestsPerMonth: 10},
+	}}
+	subs := &mockSubscriptionStore{subscriptions: []billing.Subscription{
+		{ID: "sub-alice", UserID: "alice", ProviderItemID: "si_alice", Status: billing.SubscriptionStatusActive},
+		{ID: "sub-bob", UserID: "bob", ProviderItemID: "si_bob", Status: billing.SubscriptionStatusActive},
+		// carol has no subscription to bill
+	}}
+	usageStore := memory.NewUsageStore()
+	quotaStore := memory.NewQuotaStore(memory.QuotaStoreConfig{})
+	defer quotaStore.Close()
+
+	provider := &usageReportingProvider{}
+	reporter := NewOverageReporter(users, plans, subs, usageStore, quotaStore, provider, clk, zerolog.Nop(), OverageReporterConfig{})
+
+	var n int
+	record := func(userID string, count int) {
+		t.Helper()
+		var events []usage.Event
+		for i := 0; i < count; i++ {
+			n++
+			events = append(events, usage.Event{ID: "evt-" + itoa(n), UserID: userID, Method: "GET", Path: "/v1", StatusCode: 200, Timestamp: clk.Now()})
+		}
+		if err := usageStore.RecordBatch(ctx, events); err != nil {
+			t.Fatalf("record usage: %v", err)
+		}
+		clk.Advance(time.Hour)
+	}
+
+	steps := []struct {
+		name         string
+		requests     int
+		wantReported int64
+		wantTotal    int64
+	}{
+		{"within quota", 10, 0, 0},
+		{"past quota", 4, 4, 4},
+		{"no new usage", 0, 0, 4},
+		{"more overage", 6, 6, 10},
+	}
+	for _, step := range steps {
+		for _, id := range []string{"alice", "bob", "carol"} {
+			record(id, step.requests)
+		}
+		if got := reporter.ReportOverage(ctx); got != step.wantReported {
+			t.Fatalf("%s: reported %d units, want %d", step.name, got, step.wantReported)
+		}
+		if got := provider.total("si_alice"); got != step.wantTotal {
+			t.Fatalf("%s: billed %d units to alice, want %d", step.name, got, step.wantTotal)
+		}
+	}
+	if got := provider.total("si_bob"); got != 0 {
+		t.Errorf("hard-quota plan billed %d overage units", got)
+	}
+
+	// Overage from the last hours of a period is still billed after the reset
+	record("alice", 3)
+	clk.Set(start.AddDate(0, 1, 0).Add(time.Hour))
+	record("alice", 5)
+	if got := reporter.ReportOverage(ctx); got != 3 {
+		t.Errorf("after reset: reported %d units, want 3", got)
+	}
+}
+
+func TestOverageReporter_RetriesFailedReport(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(now)
+
+	users := memory.NewUserStore()
+	users.Create(ctx, ports.User{ID: "alice", Email: "alice@example.com", PlanID: "units", Status: "active"})
+	plans := &mockPlanStore{plans: []ports.Plan{
+		{ID: "units", RequestsPerMonth: 1000, MeterType: ports.MeterTypeComputeUnits, AllowOverage: true},
+	}}
+	subs := &mockSubscriptionStore{subscriptions: []billing.Subscription{
+		{ID: "sub-alice", UserID: "alice", ProviderItemID: "si_alice", Status: billing.SubscriptionStatusActive},
+	}}
+	usageStore := memory.NewUsageStore()
+	usageStore.RecordBatch(ctx, []usage.Event{
+		{ID: "big", UserID: "alice", Method: "POST", Path: "/v1/batch", StatusCode: 200, CostMultiplier: 1250, Timestamp: now},
+	})
+	quotaStore := memory.NewQuotaStore(memory.QuotaStoreConfig{})
+	defer quotaStore.Close()
+
+	provider := &usageReportingProvider{fail: true}
+	reporter := NewOverageReporter(users, plans, subs, usageStore, quotaStore, provider, clk, zerolog.Nop(), OverageReporterConfig{})
+
+	if got := reporter.ReportOverage(ctx); got != 0 {
+		t.Fatalf("reported %d units while the provider fails", got)
+	}
+	provider.fail = false
+	if got := reporter.ReportOverage(ctx); got != 250 {
+		t.Fatalf("retry reported %d units, want 250", got)
+	}
+	if got := reporter.ReportOverage(ctx); got != 0 {
+		t.Errorf("third run reported %d units, want 0", got)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/artpar/apigate/domain/billing"
+	"github.com/artpar/apigate/domain/webhook"
 	"github.com/artpar/apigate/ports"
 	"github.com/rs/zerolog"
 )
@@ -19,6 +20,8 @@ type PaymentWebhookService struct {
 	subscriptions ports.SubscriptionStore
 	plans         ports.PlanStore
 	idGen         ports.IDGenerator
+	events        ports.EventDispatcher // Optional: publishes subscription.cancelled
+	dunning       *DunningService       // Optional: chases failed payments
 	logger        zerolog.Logger
 }
 
@@ -39,6 +42,17 @@ func NewPaymentWebhookService(
 	}
 }
 
+// SetEventDispatcher enables subscription.cancelled webhook events.
+func (s *PaymentWebhookService) SetEventDispatcher(events ports.EventDispatcher) {
+	s.events = events
+}
+
+// SetDunning routes payment failures and recoveries through the dunning
+// schedule instead of only updating the subscription status.
+func (s *PaymentWebhookService) SetDunning(dunning *DunningService) {
+	s.dunning = dunning
+}
+
 // HandleCheckoutCompleted handles successful checkout events from payment providers.
 // Creates a subscription record and updates the user's plan.
 func (s *PaymentWebhookService) HandleCheckoutCompleted(
@@ -234,6 +248,15 @@ func (s *PaymentWebhookService) HandleSubscriptionCancelled(
 		Str("user_id", sub.UserID).
 		Msg("subscription cancelled")
 
+	if s.events != nil {
+		_ = s.events.DispatchEvent(ctx, webhook.EventSubscriptionCancelled, sub.UserID, map[string]interface{}{
+			"subscription_id": sub.ID,
+			"user_id":         sub.UserID,
+			"plan_id":         sub.PlanID,
+			"cancelled_at":    now.Format(time.RFC3339),
+		})
+	}
+
 	return nil
 }
 
@@ -265,6 +288,40 @@ func (s *PaymentWebhookService) HandleInvoicePaid(
 		Int64("amount_cents", amountPaid).
 		Msg("invoice payment recorded")
 
+	// A paid invoice brings a past-due subscription back to active
+	sub, err := s.subscriptions.GetByUser(ctx, user.ID)
+	if err != nil {
+		return nil
+	}
+	if s.dunning != nil {
+		if err := s.dunning.PaymentSucceeded(ctx, sub); err != nil {
+			s.logger.Error().Err(err).
+				Str("subscription_id", sub.ID).
+				Msg("failed to end dunning")
+			return err
+		}
+		return nil
+	}
+	status := billing.StatusAfterInvoicePaid(sub.Status)
+	if status == sub.Status {
+		return nil
+	}
+
+	sub.Status = status
+	sub.UpdatedAt = time.Now().UTC()
+	if err := s.subscriptions.Update(ctx, sub); err != nil {
+		s.logger.Error().Err(err).
+			Str("subscription_id", sub.ID).
+			Msg("failed to reactivate subscription")
+		return err
+	}
+
+	s.logger.Info().
+		Str("user_id", user.ID).
+		Str("subscription_id", sub.ID).
+		Str("invoice_id", invoiceID).
+		Msg("subscription reactivated after invoice payment")
+
 	return nil
 }
 
@@ -296,6 +353,16 @@ func (s *PaymentWebhookService) HandleInvoiceFailed(
 		return nil
 	}
 
+	if s.dunning != nil {
+		if err := s.dunning.PaymentFailed(ctx, sub); err != nil {
+			s.logger.Error().Err(err).
+				Str("subscription_id", sub.ID).
+				Msg("failed to start dunning")
+			return err
+		}
+		return nil
+	}
+
 	sub.Status = billing.SubscriptionStatusPastDue
 	sub.UpdatedAt = time.Now().UTC()
 
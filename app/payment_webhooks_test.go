@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/artpar/apigate/domain/billing"
+	"github.com/artpar/apigate/domain/webhook"
 	"github.com/artpar/apigate/ports"
 	"github.com/rs/zerolog"
 )
@@ -49,6 +50,7 @@ func (m *mockUserStore) GetByStripeID(ctx context.Context, stripeID string) (por
 func (m *mockUserStore) Create(ctx context.Context, u ports.User) error { return nil }
 func (m *mockUserStore) Update(ctx context.Context, u ports.User) error { return nil }
 func (m *mockUserStore) Delete(ctx context.Context, id string) error    { return nil }
+func (m *mockUserStore) Restore(ctx context.Context, id string) error   { return nil }
 func (m *mockUserStore) List(ctx context.Context, limit, offset int) ([]ports.User, error) {
 	if m.getErr != nil {
 		return nil, m.getErr
@@ -74,7 +76,8 @@ func (m *mockSubscriptionStore) Get(ctx context.Context, id string) (billing.Sub
 
 func (m *mockSubscriptionStore) GetByUser(ctx context.Context, userID string) (billing.Subscription, error) {
 	for _, s := range m.subscriptions {
-		if s.UserID == userID && s.IsActive() {
+		// Matches the sqlite store: past-due and suspended subscriptions are still current
+		if s.UserID == userID && (s.IsActive() || s.Status == billing.SubscriptionStatusPastDue || s.Status == billing.SubscriptionStatusUnpaid) {
 			return s, nil
 		}
 	}
@@ -293,6 +296,8 @@ func TestPaymentWebhookService_HandleSubscriptionCancelled(t *testing.T) {
 	idGen := &mockIDGenerator{}
 
 	service := NewPaymentWebhookService(users, subscriptions, plans, idGen, logger)
+	dispatcher := &recordingDispatcher{}
+	service.SetEventDispatcher(dispatcher)
 
 	t.Run("cancels subscription and reverts to default plan", func(t *testing.T) {
 		ctx := context.Background()
@@ -310,6 +315,12 @@ func TestPaymentWebhookService_HandleSubscriptionCancelled(t *testing.T) {
 		if sub.CancelledAt == nil {
 			t.Error("CancelledAt should be set")
 		}
+
+		// Verify subscription.cancelled was published
+		events := dispatcher.recorded()
+		if len(events) != 1 || events[0].Type != webhook.EventSubscriptionCancelled || events[0].UserID != "user-1" {
+			t.Errorf("events = %+v, want one subscription.cancelled for user-1", events)
+		}
 	})
 
 	t.Run("returns error for unknown subscription", func(t *testing.T) {
@@ -356,6 +367,20 @@ func TestPaymentWebhookService_HandleInvoicePaid(t *testing.T) {
 			t.Fatalf("HandleInvoicePaid should not fail for unknown customer: %v", err)
 		}
 	})
+
+	t.Run("reactivates past-due subscription", func(t *testing.T) {
+		ctx := context.Background()
+		subscriptions.subscriptions = []billing.Subscription{
+			{ID: "sub-1", UserID: "user-1", ProviderID: "sub_abc123", Status: billing.SubscriptionStatusPastDue},
+		}
+
+		if err := service.HandleInvoicePaid(ctx, "inv_789", "cus_123", 1999); err != nil {
+			t.Fatalf("HandleInvoicePaid failed: %v", err)
+		}
+		if status := subscriptions.subscriptions[0].Status; status != billing.SubscriptionStatusActive {
+			t.Errorf("Status = %s, want active", status)
+		}
+	})
 }
 
 func TestPaymentWebhookService_HandleInvoiceFailed(t *testing.T) {
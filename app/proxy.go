@@ -3,8 +3,13 @@ package app
 
 import (
 	"context"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
+	"hash/fnv"
 	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -17,7 +22,12 @@ import (
 	"github.com/artpar/apigate/domain/ratelimit"
 	"github.com/artpar/apigate/domain/route"
 	"github.com/artpar/apigate/domain/usage"
+	"github.com/artpar/apigate/domain/webhook"
 	"github.com/artpar/apigate/ports"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -25,6 +35,7 @@ import (
 type ProxyService struct {
 	keys             ports.KeyStore
 	users            ports.UserStore
+	groups           ports.GroupStore // Optional: nil meters group keys on their user's plan
 	rateLimit        ports.RateLimitStore
 	quota            ports.QuotaStore
 	usage            ports.UsageRecorder
@@ -41,8 +52,46 @@ type ProxyService struct {
 	// Token service for session authentication (optional - nil disables session auth)
 	tokens *auth.TokenService
 
+	// Sessions backing session tokens (optional - nil skips the revocation check)
+	sessions ports.SessionStore
+
+	// Validator for identity provider JWTs (optional - nil disables JWKS auth)
+	bearer ports.BearerTokenValidator
+
+	// Response cache for routes with a cache TTL (optional - nil disables caching)
+	responseCache ports.ResponseCache
+
+	// Tracer for pipeline spans (no-op unless SetTracerProvider is called)
+	tracer trace.Tracer
+
+	// Publishes quota.exceeded events (optional - nil disables them)
+	events        ports.EventDispatcher
+	quotaNotified sync.Map // userID -> start of the quota period last notified
+
+	// Serialises rate limit read-check-write per key (striped by key hash)
+	rateLimitLocks [rateLimitLockStripes]sync.Mutex
+
+	// In-flight requests per key, capped by the plan's MaxConcurrent
+	concurrency *concurrencyLimiter
+
+	// Alerts on and throttles usage spikes (optional - nil disables detection)
+	spikes *SpikeDetector
+
+	// Batches key last-used writes (optional - nil writes each use directly)
+	keyUses *KeyUseTracker
+
+	// Slots for in-flight mirror requests, capped at maxMirrorsInFlight
+	mirrorSlots chan struct{}
+
 	// Static configuration (requires restart)
-	keyPrefix string
+	keyPrefix        string
+	maxRequestBody   int64         // Global request body limit in bytes (0 = unlimited)
+	maxResponseBody  int64         // Global upstream response body limit in bytes (0 = upstream default)
+	signatureMaxSkew time.Duration // Allowed clock skew for signed requests
+	publicURL        string        // Gateway URL substituted for upstream URLs in rewritten bodies
+
+	// CAs trusted to issue client certificates (nil rejects all mtls routes)
+	clientCAs *x509.CertPool
 
 	// Dynamic configuration (hot-reloadable)
 	dynamicCfg atomic.Pointer[DynamicConfig]
@@ -81,6 +130,10 @@ type ProxyConfig struct {
 	RateWindow       int // seconds
 	Entitlements     []entitlement.Entitlement
 	PlanEntitlements []entitlement.PlanEntitlement
+	MaxRequestBody   int64         // Global request body limit in bytes; routes may override
+	MaxResponseBody  int64         // Global upstream response body limit in bytes; routes may override
+	SignatureMaxSkew time.Duration // Allowed clock skew for signed requests (0 = key.DefaultSignatureMaxSkew)
+	PublicURL        string        // Gateway URL clients use; empty = derived from each request's scheme and Host
 }
 
 // NewProxyService creates a new proxy service.
@@ -96,7 +149,14 @@ func NewProxyService(deps ProxyDeps, cfg ProxyConfig) *ProxyService {
 		idGen:            deps.IDGen,
 		entitlements:     deps.Entitlements,
 		planEntitlements: deps.PlanEntitlements,
+		tracer:           noop.NewTracerProvider().Tracer(tracerName),
+		concurrency:      newConcurrencyLimiter(),
+		mirrorSlots:      make(chan struct{}, maxMirrorsInFlight),
 		keyPrefix:        cfg.KeyPrefix,
+		maxRequestBody:   cfg.MaxRequestBody,
+		maxResponseBody:  cfg.MaxResponseBody,
+		signatureMaxSkew: cfg.SignatureMaxSkew,
+		publicURL:        cfg.PublicURL,
 	}
 
 	// Set initial dynamic config
@@ -123,6 +183,60 @@ func (s *ProxyService) SetTokenService(tokens *auth.TokenService) {
 	s.tokens = tokens
 }
 
+// SetSessionStore sets the store of sessions behind session tokens. Tokens
+// whose session has been revoked or has expired are rejected.
+func (s *ProxyService) SetSessionStore(sessions ports.SessionStore) {
+	s.sessions = sessions
+}
+
+// SetBearerValidator enables authentication with JWTs issued by an external
+// identity provider, e.g. validated against its JWKS. Tokens that aren't API
+// keys or portal session tokens are checked with it.
+func (s *ProxyService) SetBearerValidator(v ports.BearerTokenValidator) {
+	s.bearer = v
+}
+
+// SetClientCAs sets the CAs trusted to issue client certificates for routes
+// with the mtls auth method.
+func (s *ProxyService) SetClientCAs(pool *x509.CertPool) {
+	s.clientCAs = pool
+}
+
+// SetTracerProvider enables OpenTelemetry spans for the request pipeline.
+// Incoming W3C trace context is continued and propagated to the upstream.
+func (s *ProxyService) SetTracerProvider(tp trace.TracerProvider) {
+	s.tracer = tp.Tracer(tracerName)
+}
+
+// SetResponseCache sets the cache used for routes with a cache TTL.
+func (s *ProxyService) SetResponseCache(cache ports.ResponseCache) {
+	s.responseCache = cache
+}
+
+// SetEventDispatcher enables quota.exceeded webhook events. Each user is
+// notified at most once per quota period.
+func (s *ProxyService) SetEventDispatcher(events ports.EventDispatcher) {
+	s.events = events
+}
+
+// SetGroupStore enables group plans for group keys. Their usage and quota
+// are always counted against the group.
+func (s *ProxyService) SetGroupStore(groups ports.GroupStore) {
+	s.groups = groups
+}
+
+// SetSpikeDetector enables usage spike detection for plans with a spike
+// threshold. Spiking keys on plans with a throttle rate are rate limited to it.
+func (s *ProxyService) SetSpikeDetector(d *SpikeDetector) {
+	s.spikes = d
+}
+
+// SetKeyUseTracker batches key last-used writes through t instead of
+// writing on every request.
+func (s *ProxyService) SetKeyUseTracker(t *KeyUseTracker) {
+	s.keyUses = t
+}
+
 // UpdateConfig updates the hot-reloadable configuration.
 // This is thread-safe and can be called while handling requests.
 func (s *ProxyService) UpdateConfig(plans []plan.Plan, endpoints []plan.Endpoint, rateBurst, rateWindow int, ents []entitlement.Entitlement, planEnts []entitlement.PlanEntitlement) {
@@ -152,6 +266,38 @@ type HandleResult struct {
 // Handle processes an incoming proxy request.
 // This method orchestrates pure domain functions with I/O operations.
 func (s *ProxyService) Handle(ctx context.Context, req proxy.Request) HandleResult {
+	ctx = traceContext.Extract(ctx, headerCarrier(req.Headers))
+	ctx, span := s.tracer.Start(ctx, "proxy.request",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("url.path", req.Path),
+		))
+	defer span.End()
+
+	result := s.handle(ctx, req)
+
+	if result.Auth != nil {
+		span.SetAttributes(
+			attribute.String("apigate.user_id", result.Auth.UserID),
+			attribute.String("apigate.plan_id", result.Auth.PlanID),
+		)
+	}
+	if result.Error != nil {
+		span.SetAttributes(
+			attribute.Int("http.response.status_code", result.Error.Status),
+			attribute.String("apigate.error_code", result.Error.Code),
+		)
+		if result.Error.Status >= 500 {
+			span.SetStatus(codes.Error, result.Error.Message)
+		}
+	} else {
+		span.SetAttributes(attribute.Int("http.response.status_code", result.Response.Status))
+	}
+	return result
+}
+
+func (s *ProxyService) handle(ctx context.Context, req proxy.Request) HandleResult {
 	now := s.clock.Now()
 
 	// Get current dynamic config (hot-reloadable)
@@ -163,12 +309,20 @@ func (s *ProxyService) Handle(ctx context.Context, req proxy.Request) HandleResu
 	originalPath := req.Path
 
 	if s.routeService != nil {
-		if match := s.routeService.Match(req.Method, req.Path, req.Headers); match != nil {
+		// Pin the route table so a reload mid-request can't change the upstream
+		ctx = s.routeService.Pin(ctx)
+		if match := s.routeService.MatchRequest(ctx, req); match != nil {
 			matchedRoute = match.Route
 			pathParams = match.PathParams
 		}
 	}
 
+	// Route cost weighting follows the client's method, not a method override
+	costWeight := 1.0
+	if matchedRoute != nil {
+		costWeight = matchedRoute.CostMultiplierFor(req.Method)
+	}
+
 	// 2. Check if this is a public route (no auth required)
 	if matchedRoute != nil && !matchedRoute.AuthRequired {
 		// Public route - skip auth, quota, rate limiting
@@ -177,182 +331,114 @@ func (s *ProxyService) Handle(ctx context.Context, req proxy.Request) HandleResu
 
 	// 3. Authenticate via JWT session token OR API key
 	// Detection: API keys start with configured prefix (e.g., "ak_"), JWTs don't
-	var user ports.User
-	var matchedKey key.Key
-	var err error
-
-	if req.APIKey == "" {
-		return HandleResult{Error: &proxy.ErrMissingKey}
+	authCtx, authSpan := s.tracer.Start(ctx, "proxy.auth")
+	matchedKey, user, authErr := s.authenticate(authCtx, req, matchedRoute, now)
+	if authErr != nil {
+		endRejectedSpan(authSpan, authErr)
+		return HandleResult{Error: authErr}
 	}
+	authSpan.End()
 
-	// Check if token looks like an API key (has the expected prefix)
-	_, isAPIKeyFormat := key.ValidateFormat(req.APIKey, s.keyPrefix)
-
-	if !isAPIKeyFormat && s.tokens != nil {
-		// Token doesn't look like an API key - try JWT validation
-		var claims *auth.Claims
-		claims, err = s.tokens.ValidateToken(req.APIKey)
-		if err == nil {
-			// JWT is valid - get user directly
-			user, err = s.users.Get(ctx, claims.UserID)
-			if err == nil && user.Status == "active" {
-				// Create a synthetic key for tracking (no actual key exists)
-				matchedKey = key.Key{
-					ID:     "session:" + claims.UserID,
-					UserID: claims.UserID,
-				}
-			} else if err != nil {
-				return HandleResult{Error: &proxy.ErrInvalidKey}
-			} else {
-				return HandleResult{Error: &proxy.ErrorResponse{
-					Status:  403,
-					Code:    "user_suspended",
-					Message: "Account is suspended",
-				}}
-			}
-		} else {
-			// JWT validation failed
-			return HandleResult{Error: &proxy.ErrInvalidKey}
-		}
-	} else {
-		// Token looks like an API key - use API key auth flow
-		prefix, valid := key.ValidateFormat(req.APIKey, s.keyPrefix)
-		if !valid {
-			return HandleResult{Error: &proxy.ErrInvalidKey}
-		}
-
-		// Lookup key (I/O)
-		var keys []key.Key
-		keys, err = s.keys.Get(ctx, prefix)
-		if err != nil || len(keys) == 0 {
-			return HandleResult{Error: &proxy.ErrInvalidKey}
-		}
+	// 8.1. Check the client IP is allowed for the key (PURE)
+	if !key.AllowsIP(matchedKey, req.RemoteIP) {
+		return HandleResult{Error: &proxy.ErrIPNotAllowed, Auth: rejectedAuth(matchedKey, user)}
+	}
 
-		// Find matching key by comparing hash (PURE comparison, I/O lookup)
-		found := false
-		for _, k := range keys {
-			if bcrypt.CompareHashAndPassword(k.Hash, []byte(req.APIKey)) == nil {
-				matchedKey = k
-				found = true
-				break
-			}
-		}
-		if !found {
-			return HandleResult{Error: &proxy.ErrInvalidKey}
-		}
+	// 8.2. Check the key holds the route's required scopes (PURE)
+	if matchedRoute != nil && !hasRouteScopes(matchedKey, matchedRoute) {
+		return HandleResult{Error: &proxy.ErrInsufficientScope, Auth: rejectedAuth(matchedKey, user)}
+	}
 
-		// Validate key (PURE)
-		validation := key.Validate(matchedKey, now)
-		if !validation.Valid {
-			return HandleResult{Error: &proxy.ErrorResponse{
-				Status:  401,
-				Code:    validation.Reason,
-				Message: reasonToMessage(validation.Reason),
-			}}
-		}
+	// 8.3. Check the request body against the route's schema (PURE)
+	if errResp := s.validateBody(ctx, req, matchedRoute); errResp != nil {
+		return HandleResult{Error: errResp, Auth: rejectedAuth(matchedKey, user)}
+	}
 
-		// Get user and check status (I/O)
-		user, err = s.users.Get(ctx, matchedKey.UserID)
-		if err != nil {
-			return HandleResult{Error: &proxy.ErrInvalidKey}
-		}
-		if user.Status != "active" {
-			return HandleResult{Error: &proxy.ErrorResponse{
-				Status:  403,
-				Code:    "user_suspended",
-				Message: "Account is suspended",
-			}}
-		}
+	// 8.4. Group keys are metered on the group's plan (I/O)
+	user, errResp := s.groupPlan(ctx, matchedKey, user)
+	if errResp != nil {
+		return HandleResult{Error: errResp, Auth: rejectedAuth(matchedKey, user)}
 	}
 
 	// 9. Get plan and rate limit config (PURE) - uses dynamic config
 	userPlan, _ := plan.FindPlan(dynCfg.Plans, user.PlanID)
-	rlConfig := ratelimit.Config{
-		Limit:       userPlan.RateLimitPerMinute,
-		Window:      time.Duration(dynCfg.RateWindow) * time.Second,
-		BurstTokens: dynCfg.RateBurst,
-	}
-	if rlConfig.Limit == 0 {
-		rlConfig.Limit = 60 // default
+	rlConfig := rateLimitConfig(userPlan, dynCfg)
+	if s.spikes != nil {
+		rlConfig = s.spikes.Throttle(matchedKey.ID, rlConfig, now)
 	}
 
 	// 8.5. Check quota (PURE + I/O for state)
 	// Service accounts (quota_bypass=true) skip quota checks entirely
-	periodStart, periodEnd := quota.PeriodBounds(now)
+	periodStart, periodEnd := quota.WindowBounds(quota.Period(userPlan.QuotaPeriod), now, user.CreatedAt)
 	var quotaResult quota.CheckResult
 	if s.quota != nil && userPlan.RequestsPerMonth >= 0 && !matchedKey.QuotaBypass { // Not unlimited and not service account
-		// Build quota config from plan
-		enforceMode := quota.EnforceHard
-		switch userPlan.QuotaEnforceMode {
-		case plan.QuotaEnforceWarn:
-			enforceMode = quota.EnforceWarn
-		case plan.QuotaEnforceSoft:
-			enforceMode = quota.EnforceSoft
-		}
-		gracePct := userPlan.QuotaGracePct
-		if gracePct == 0 {
-			gracePct = 0.05 // Default 5% grace
-		}
-		// Map plan.MeterType to quota.MeterType
-		meterType := quota.MeterTypeRequests
-		if userPlan.MeterType == plan.MeterTypeComputeUnits {
-			meterType = quota.MeterTypeComputeUnits
-		}
-		estimatedCost := userPlan.EstimatedCostPerReq
-		if estimatedCost <= 0 {
-			estimatedCost = 1.0
-		}
-		quotaCfg := quota.Config{
-			RequestsPerMonth: userPlan.RequestsPerMonth,
-			EnforceMode:      enforceMode,
-			GracePct:         gracePct,
-			MeterType:        meterType,
-			EstimatedCost:    estimatedCost,
-		}
-		quotaState, _ := s.quota.Get(ctx, matchedKey.UserID, periodStart)
-		// For compute_units mode, use estimated cost; for requests, use 1
-		increment := int64(1)
-		if meterType == quota.MeterTypeComputeUnits {
-			increment = int64(estimatedCost)
-		}
+		quotaCtx, quotaSpan := s.tracer.Start(ctx, "proxy.quota")
+		quotaCfg, increment := quotaConfig(userPlan, costWeight)
+		quotaState, _ := s.quota.Get(quotaCtx, matchedKey.AccountID(), periodStart)
 		quotaResult = quota.Check(quotaState, quotaCfg, increment)
+		quotaSpan.SetAttributes(attribute.Bool("apigate.allowed", quotaResult.Allowed))
 
 		if !quotaResult.Allowed {
-			return HandleResult{
-				Error: &proxy.ErrQuotaExceeded,
-				Response: proxy.Response{
-					Headers: map[string]string{
-						"X-Quota-Used":  strconv.FormatInt(quotaResult.CurrentUsage, 10),
-						"X-Quota-Limit": strconv.FormatInt(quotaResult.Limit, 10),
-						"X-Quota-Reset": periodEnd.Format(time.RFC3339),
-						"Retry-After":   strconv.FormatInt(int64(periodEnd.Sub(now).Seconds()), 10),
-					},
-				},
-			}
+			endRejectedSpan(quotaSpan, &proxy.ErrQuotaExceeded)
+			s.notifyQuotaExceeded(matchedKey.AccountID(), userPlan.ID, periodStart, quotaResult)
+			return quotaExceededResult(matchedKey, user, quotaResult, periodEnd, now)
+		}
+		quotaSpan.End()
+	}
+
+	// 8.6. Check the key's own quota; the lower of it and the plan's applies
+	if keyResult, ok := s.checkKeyQuota(ctx, matchedKey, userPlan, costWeight, periodStart); ok {
+		if !keyResult.Allowed {
+			return quotaExceededResult(matchedKey, user, keyResult, periodEnd, now)
+		}
+		// Report whichever quota has less left
+		if quotaResult.Limit <= 0 || keyResult.Limit-keyResult.CurrentUsage < quotaResult.Limit-quotaResult.CurrentUsage {
+			quotaResult = keyResult
 		}
 	}
 
 	// 9. Check rate limit (PURE + I/O for state)
-	rlState, _ := s.rateLimit.Get(ctx, matchedKey.ID)
-	rlResult, newRLState := ratelimit.Check(rlState, rlConfig, now)
-	s.rateLimit.Set(ctx, matchedKey.ID, newRLState)
+	rlCtx, rlSpan := s.tracer.Start(ctx, "proxy.rate_limit")
+	rlResult := s.checkRateLimit(rlCtx, matchedKey.ID, rlConfig, now)
+	rlSpan.SetAttributes(attribute.Bool("apigate.allowed", rlResult.Allowed))
 
 	if !rlResult.Allowed {
+		endRejectedSpan(rlSpan, &proxy.ErrRateLimited)
 		return HandleResult{
 			Error: &proxy.ErrRateLimited,
+			Auth:  rejectedAuth(matchedKey, user),
 			Response: proxy.Response{
 				Headers: map[string]string{
 					"X-RateLimit-Remaining": "0",
 					"X-RateLimit-Reset":     rlResult.ResetAt.Format("2006-01-02T15:04:05Z"),
-					"Retry-After":           itoa(int(rlResult.ResetAt.Sub(now).Seconds())),
+					"X-RateLimit-Burst":     itoa(rlConfig.Burst),
+					"Retry-After":           retryAfter(rlResult.ResetAt.Sub(now)),
 				},
 			},
 		}
 	}
+	rlSpan.End()
+
+	// 9.2. Count the request toward spike detection
+	if s.spikes != nil {
+		s.spikes.Observe(matchedKey, userPlan, now)
+	}
+
+	// 9.5. Take a concurrency slot, held until the response is complete
+	release, ok := s.concurrency.acquire(matchedKey.ID, userPlan.MaxConcurrent)
+	if !ok {
+		return HandleResult{
+			Error:    &proxy.ErrConcurrencyLimit,
+			Auth:     rejectedAuth(matchedKey, user),
+			Response: proxy.Response{Headers: concurrencyHeaders(userPlan)},
+		}
+	}
+	defer func() { release() }()
 
 	// 10. Build auth context (PURE)
 	auth := proxy.AuthContext{
 		KeyID:     matchedKey.ID,
+		KeyPrefix: matchedKey.Prefix,
 		UserID:    matchedKey.UserID,
 		PlanID:    user.PlanID,
 		RateLimit: rlConfig.Limit,
@@ -386,7 +472,11 @@ func (s *ProxyService) Handle(ctx context.Context, req proxy.Request) HandleResu
 		}
 	}
 
-	// 11. Path rewriting (PURE + Expr eval)
+	// 11. Path rewriting: strip prefix, regex rewrite and query params to strip (PURE), then Expr eval
+	if matchedRoute != nil {
+		req.Path = matchedRoute.RewriteUpstreamPath(req.Path)
+		req.Query = route.StripQueryParams(req.Query, matchedRoute.StripQuery)
+	}
 	if matchedRoute != nil && matchedRoute.PathRewrite != "" && s.transformService != nil {
 		// Build context with path params
 		rewriteCtx := map[string]any{
@@ -405,12 +495,18 @@ func (s *ProxyService) Handle(ctx context.Context, req proxy.Request) HandleResu
 		req.Method = matchedRoute.MethodOverride
 	}
 
-	// 13. Forward to upstream (I/O)
+	// 12.5. Apply request header rules (PURE)
+	if matchedRoute != nil && len(matchedRoute.RequestHeaders) > 0 {
+		req.Headers = route.ApplyHeaderRules(req.Headers, matchedRoute.RequestHeaders, headerRefs(&auth))
+	}
+
+	// 13. Forward to upstream (I/O), mirroring a copy first if the route asks for it
+	s.mirror(ctx, req, matchedRoute)
+
 	// If route matched and has an upstream, use that upstream instead of default
-	var resp proxy.Response
 	var routeUpstream *route.Upstream
-	if matchedRoute != nil && matchedRoute.UpstreamID != "" && s.routeService != nil {
-		routeUpstream = s.routeService.GetUpstream(matchedRoute.UpstreamID)
+	if matchedRoute != nil && matchedRoute.HasUpstream() && s.routeService != nil {
+		routeUpstream = s.routeService.SelectUpstreamFor(ctx, matchedRoute, &auth)
 		if routeUpstream != nil {
 			// Apply upstream authentication headers
 			req.Headers = s.routeService.ApplyUpstreamAuth(routeUpstream, req.Headers)
@@ -418,23 +514,35 @@ func (s *ProxyService) Handle(ctx context.Context, req proxy.Request) HandleResu
 	}
 
 	// Forward to route's upstream if available, otherwise use default
-	if routeUpstream != nil {
-		resp, err = s.upstream.ForwardTo(ctx, req, routeUpstream)
-	} else {
-		resp, err = s.upstream.Forward(ctx, req)
+	req.MaxResponseBody = s.responseBodyLimit(matchedRoute)
+	req.Timeouts = upstreamTimeouts(matchedRoute)
+	resp, errResp := s.forward(ctx, req, matchedRoute, routeUpstream)
+	if errResp != nil {
+		return HandleResult{Error: errResp, Auth: &auth}
 	}
-	if err != nil {
-		return HandleResult{Error: &proxy.ErrUpstreamError, Auth: &auth}
+
+	// Event streams hold the slot until the relay closes them
+	if resp.Stream != nil {
+		resp.Stream = &releasingStream{ReadCloser: resp.Stream, release: release}
+		release = func() {}
 	}
 
 	// 14. Apply response transform (PURE + Expr eval)
-	if matchedRoute != nil && matchedRoute.ResponseTransform != nil && s.transformService != nil {
+	// Not Modified responses and event streams have no body to transform
+	if matchedRoute != nil && matchedRoute.ResponseTransform != nil && s.transformService != nil && resp.Status != 304 && resp.Stream == nil {
+		var err error
 		resp, err = s.transformService.TransformResponse(ctx, resp, matchedRoute.ResponseTransform, &auth)
 		if err != nil {
 			// Log error but continue with original response
 		}
 	}
 
+	// 14.5. Apply response header rules (PURE)
+	resp.Headers = s.ApplyResponseHeaders(matchedRoute, &auth, resp.Headers)
+
+	// 14.6. Rewrite upstream URLs in the body (PURE)
+	resp = s.rewriteResponseURLs(matchedRoute, routeUpstream, req, resp)
+
 	// 15. Calculate cost/metering value (PURE + Expr eval)
 	var costMult float64 = 1.0
 
@@ -462,13 +570,14 @@ func (s *ProxyService) Handle(ctx context.Context, req proxy.Request) HandleResu
 		// Fallback to static endpoint cost multiplier
 		costMult = plan.GetCostMultiplier(dynCfg.Endpoints, req.Method, originalPath)
 	}
+	costMult *= costWeight
 
 	// 16. Record usage event (async I/O)
 	bytesTotal := int64(len(req.Body)) + int64(len(resp.Body))
 	event := usage.Event{
 		ID:             s.idGen.New(),
 		KeyID:          matchedKey.ID,
-		UserID:         matchedKey.UserID,
+		UserID:         matchedKey.AccountID(),
 		Method:         req.Method,
 		Path:           originalPath, // Use original path for tracking
 		StatusCode:     resp.Status,
@@ -484,16 +593,14 @@ func (s *ProxyService) Handle(ctx context.Context, req proxy.Request) HandleResu
 
 	// 16.5. Increment quota counter (I/O)
 	if s.quota != nil {
-		s.quota.Increment(ctx, matchedKey.UserID, periodStart, 1, costMult, bytesTotal)
+		s.quota.Increment(ctx, matchedKey.AccountID(), periodStart, 1, costMult, bytesTotal)
+		// Every key is counted, so a key quota set mid-period starts from
+		// the key's real usage
+		s.quota.Increment(ctx, matchedKey.ID, periodStart, 1, costMult, bytesTotal)
 	}
 
 	// 17. Update last used (async I/O)
-	// Use background context since request context may be cancelled
-	go func() {
-		bgCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		s.keys.UpdateLastUsed(bgCtx, matchedKey.ID, now)
-	}()
+	s.recordKeyUse(matchedKey.ID, now, req.RemoteIP)
 
 	// 18. Add rate limit and quota headers to response (PURE)
 	if resp.Headers == nil {
@@ -501,12 +608,16 @@ func (s *ProxyService) Handle(ctx context.Context, req proxy.Request) HandleResu
 	}
 	resp.Headers["X-RateLimit-Remaining"] = itoa(rlResult.Remaining)
 	resp.Headers["X-RateLimit-Reset"] = rlResult.ResetAt.Format("2006-01-02T15:04:05Z")
+	resp.Headers["X-RateLimit-Burst"] = itoa(rlConfig.Burst)
 
 	// Add quota headers if quota is being tracked
 	if quotaResult.Limit > 0 {
 		resp.Headers["X-Quota-Used"] = strconv.FormatInt(quotaResult.CurrentUsage, 10)
 		resp.Headers["X-Quota-Limit"] = strconv.FormatInt(quotaResult.Limit, 10)
 		resp.Headers["X-Quota-Reset"] = periodEnd.Format(time.RFC3339)
+		if quotaResult.OverageAmount > 0 {
+			resp.Headers["X-Quota-Overage"] = strconv.FormatInt(quotaResult.OverageAmount, 10)
+		}
 	}
 
 	return HandleResult{
@@ -523,11 +634,435 @@ func reasonToMessage(reason string) string {
 		return "API key has been revoked"
 	case key.ReasonNotFound:
 		return "API key not found"
+	case key.ReasonMissingSignature:
+		return "Request signature headers are missing"
+	case key.ReasonInvalidSignature:
+		return "Request signature is invalid"
+	case key.ReasonSignatureExpired:
+		return "Request signature timestamp is outside the allowed window"
+	case key.ReasonSigningNotEnabled:
+		return "API key has no signing secret"
+	case key.ReasonMissingClientCert:
+		return "A client certificate is required"
+	case key.ReasonInvalidClientCert:
+		return "Client certificate is not trusted"
+	case key.ReasonUnknownClientCert:
+		return "Client certificate does not match any user"
 	default:
 		return "Invalid API key"
 	}
 }
 
+// authenticate identifies the caller of a request to a route that requires
+// authentication: by client certificate, signature, bearer token or API key,
+// depending on the route. It checks the key and user are active but not IP
+// allowlists, scopes, quota or rate limits.
+func (s *ProxyService) authenticate(ctx context.Context, req proxy.Request, matchedRoute *route.Route, now time.Time) (key.Key, ports.User, *proxy.ErrorResponse) {
+	var user ports.User
+	var matchedKey key.Key
+	var err error
+
+	// Routes may require HMAC-signed requests or a client certificate instead
+	// of the API key itself
+	signed := matchedRoute != nil && matchedRoute.AuthMethod == route.AuthMethodHMAC
+	certAuth := matchedRoute != nil && matchedRoute.AuthMethod == route.AuthMethodMTLS
+	if req.APIKey == "" && !signed && !certAuth {
+		return key.Key{}, ports.User{}, &proxy.ErrMissingKey
+	}
+
+	// Check if token looks like an API key (has the expected prefix)
+	_, isAPIKeyFormat := key.ValidateFormat(req.APIKey, s.keyPrefix)
+
+	if certAuth {
+		return s.authenticateClientCert(ctx, req, now)
+	} else if signed {
+		return s.authenticateSigned(ctx, req, now)
+	} else if !isAPIKeyFormat && (s.tokens != nil || s.bearer != nil) {
+		// Token doesn't look like an API key - try JWT validation
+		var synthetic key.Key
+		synthetic, err = s.validateBearer(ctx, req.APIKey)
+		if err == nil {
+			// JWT is valid - get user directly
+			user, err = s.users.Get(ctx, synthetic.UserID)
+			if err == nil && user.Status == "active" {
+				matchedKey = synthetic
+			} else if err != nil {
+				return key.Key{}, ports.User{}, &proxy.ErrInvalidKey
+			} else {
+				return key.Key{}, ports.User{}, &proxy.ErrorResponse{
+					Status:  403,
+					Code:    "user_suspended",
+					Message: "Account is suspended",
+				}
+			}
+		} else {
+			// JWT validation failed
+			return key.Key{}, ports.User{}, &proxy.ErrInvalidKey
+		}
+	} else {
+		// Token looks like an API key - use API key auth flow
+		prefix, valid := key.ValidateFormat(req.APIKey, s.keyPrefix)
+		if !valid {
+			return key.Key{}, ports.User{}, &proxy.ErrInvalidKey
+		}
+
+		// Lookup key (I/O)
+		var keys []key.Key
+		keys, err = s.keys.Get(ctx, prefix)
+		if err != nil || len(keys) == 0 {
+			return key.Key{}, ports.User{}, &proxy.ErrInvalidKey
+		}
+
+		// Find matching key by comparing hash (PURE comparison, I/O lookup)
+		found := false
+		for _, k := range keys {
+			if bcrypt.CompareHashAndPassword(k.Hash, []byte(req.APIKey)) == nil {
+				matchedKey = k
+				found = true
+				break
+			}
+		}
+		if !found {
+			return key.Key{}, ports.User{}, &proxy.ErrInvalidKey
+		}
+
+		// Validate key (PURE)
+		validation := key.Validate(matchedKey, now)
+		if !validation.Valid {
+			return key.Key{}, ports.User{}, &proxy.ErrorResponse{
+				Status:  401,
+				Code:    validation.Reason,
+				Message: reasonToMessage(validation.Reason),
+			}
+		}
+
+		// Get user and check status (I/O)
+		user, err = s.users.Get(ctx, matchedKey.UserID)
+		if err != nil {
+			return key.Key{}, ports.User{}, &proxy.ErrInvalidKey
+		}
+		if user.Status != "active" {
+			return key.Key{}, ports.User{}, &proxy.ErrorResponse{
+				Status:  403,
+				Code:    "user_suspended",
+				Message: "Account is suspended",
+			}
+		}
+	}
+
+	return matchedKey, user, nil
+}
+
+// ID prefixes of the synthetic keys standing in for callers authenticated
+// without an API key.
+const (
+	sessionKeyPrefix = "session:"
+	jwtKeyPrefix     = "jwt:"
+	certKeyPrefix    = "cert:"
+)
+
+// hasRouteScopes checks the caller holds the route's required scopes.
+// Synthetic keys carry no scopes, which would otherwise mean access to
+// everything, so callers authenticated without an API key are refused on
+// routes that require any scope.
+func hasRouteScopes(k key.Key, r *route.Route) bool {
+	if len(r.RequiredScopes) == 0 {
+		return true
+	}
+	for _, prefix := range []string{sessionKeyPrefix, jwtKeyPrefix, certKeyPrefix} {
+		if strings.HasPrefix(k.ID, prefix) {
+			return false
+		}
+	}
+	return key.HasScopes(k, r.RequiredScopes)
+}
+
+// validateBearer validates a bearer token that isn't an API key: portal
+// session tokens first, then identity provider JWTs. It returns a synthetic
+// key for tracking, since no actual key exists.
+func (s *ProxyService) validateBearer(ctx context.Context, token string) (key.Key, error) {
+	if s.tokens != nil {
+		if claims, err := s.tokens.ValidateToken(token); err == nil {
+			// Impersonation tokens only let an admin view the portal as the
+			// user, not make API calls on their behalf
+			if claims.IsImpersonated() {
+				return key.Key{}, errors.New("impersonation tokens cannot call the API")
+			}
+			// Tokens backed by a session stop working once it is revoked
+			if claims.SessionID != "" && s.sessions != nil {
+				session, err := s.sessions.Get(ctx, claims.SessionID)
+				if err != nil || session.UserID != claims.UserID || session.IsExpired() {
+					return key.Key{}, errors.New("session revoked")
+				}
+			}
+			return key.Key{ID: sessionKeyPrefix + claims.UserID, UserID: claims.UserID}, nil
+		}
+	}
+	if s.bearer == nil {
+		return key.Key{}, errors.New("invalid session token")
+	}
+	userID, err := s.bearer.ValidateBearer(ctx, token)
+	if err != nil {
+		return key.Key{}, err
+	}
+	return key.Key{ID: jwtKeyPrefix + userID, UserID: userID}, nil
+}
+
+// authenticateClientCert authenticates a request by its TLS client
+// certificate. The chain must lead to a trusted client CA, and the user is the
+// one whose email the certificate asserts. Requests are metered against a
+// synthetic per-user key, like session tokens.
+func (s *ProxyService) authenticateClientCert(ctx context.Context, req proxy.Request, now time.Time) (key.Key, ports.User, *proxy.ErrorResponse) {
+	unauthorized := func(reason string) (key.Key, ports.User, *proxy.ErrorResponse) {
+		return key.Key{}, ports.User{}, &proxy.ErrorResponse{Status: 401, Code: reason, Message: reasonToMessage(reason)}
+	}
+
+	if reason := key.VerifyClientCert(req.ClientCerts, s.clientCAs, now); reason != key.ReasonValid {
+		return unauthorized(reason)
+	}
+
+	var user ports.User
+	found := false
+	for _, email := range key.ClientCertEmails(req.ClientCerts[0]) {
+		if u, err := s.users.GetByEmail(ctx, email); err == nil {
+			user, found = u, true
+			break
+		}
+	}
+	if !found {
+		return unauthorized(key.ReasonUnknownClientCert)
+	}
+	if user.Status != "active" {
+		return key.Key{}, ports.User{}, &proxy.ErrorResponse{
+			Status:  403,
+			Code:    "user_suspended",
+			Message: "Account is suspended",
+		}
+	}
+	return key.Key{ID: certKeyPrefix + user.ID, UserID: user.ID}, user, nil
+}
+
+// authenticateSigned authenticates an HMAC-signed request. The key is
+// identified by its prefix and proven by a signature made with its signing
+// secret, so the API key itself is never sent.
+func (s *ProxyService) authenticateSigned(ctx context.Context, req proxy.Request, now time.Time) (key.Key, ports.User, *proxy.ErrorResponse) {
+	unauthorized := func(reason string) (key.Key, ports.User, *proxy.ErrorResponse) {
+		return key.Key{}, ports.User{}, &proxy.ErrorResponse{Status: 401, Code: reason, Message: reasonToMessage(reason)}
+	}
+
+	prefix := proxy.HeaderValue(req.Headers, key.SignatureKeyHeader)
+	signedReq := key.SignedRequest{
+		Method:    req.Method,
+		Path:      req.Path,
+		Query:     req.Query,
+		Timestamp: proxy.HeaderValue(req.Headers, key.SignatureTimestampHeader),
+		Body:      req.Body,
+		Signature: proxy.HeaderValue(req.Headers, key.SignatureHeader),
+	}
+	if prefix == "" {
+		return unauthorized(key.ReasonMissingSignature)
+	}
+
+	keys, err := s.keys.Get(ctx, prefix)
+	if err != nil || len(keys) == 0 {
+		return unauthorized(key.ReasonInvalidSignature)
+	}
+
+	var matchedKey key.Key
+	reason := key.ReasonInvalidSignature
+	for _, k := range keys {
+		if reason = key.VerifySignature(k, signedReq, now, s.signatureMaxSkew); reason == key.ReasonValid {
+			matchedKey = k
+			break
+		}
+	}
+	if reason != key.ReasonValid {
+		return unauthorized(reason)
+	}
+
+	if validation := key.Validate(matchedKey, now); !validation.Valid {
+		return unauthorized(validation.Reason)
+	}
+
+	user, err := s.users.Get(ctx, matchedKey.UserID)
+	if err != nil {
+		return key.Key{}, ports.User{}, &proxy.ErrInvalidKey
+	}
+	if user.Status != "active" {
+		return key.Key{}, ports.User{}, &proxy.ErrorResponse{
+			Status:  403,
+			Code:    "user_suspended",
+			Message: "Account is suspended",
+		}
+	}
+	return matchedKey, user, nil
+}
+
+// rateLimitLockStripes is the number of mutexes rate limit checks are spread over.
+const rateLimitLockStripes = 256
+
+// rateLimitConfig builds the token bucket configuration for a plan.
+// The bucket size defaults to the per-minute rate plus the global burst setting.
+// This is a PURE function.
+func rateLimitConfig(p plan.Plan, dynCfg *DynamicConfig) ratelimit.Config {
+	cfg := ratelimit.Config{
+		Limit:       p.RateLimitPerMinute,
+		Window:      time.Duration(dynCfg.RateWindow) * time.Second,
+		BurstTokens: dynCfg.RateBurst,
+		Burst:       p.RateLimitBurst,
+	}
+	if cfg.Limit == 0 {
+		cfg.Limit = 60 // default
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+	if cfg.Burst <= 0 {
+		cfg.Burst = cfg.Limit + dynCfg.RateBurst
+	}
+	return cfg
+}
+
+// quotaConfig builds the quota check for a plan and the usage one request on
+// a route with the given cost weight adds.
+func quotaConfig(p plan.Plan, costWeight float64) (quota.Config, int64) {
+	enforceMode := quota.EnforceHard
+	switch p.QuotaEnforceMode {
+	case plan.QuotaEnforceWarn:
+		enforceMode = quota.EnforceWarn
+	case plan.QuotaEnforceSoft:
+		enforceMode = quota.EnforceSoft
+	}
+	if p.AllowOverage {
+		enforceMode = quota.EnforceSoft
+	}
+	gracePct := p.QuotaGracePct
+	if gracePct == 0 {
+		gracePct = 0.05 // Default 5% grace
+	}
+	// Map plan.MeterType to quota.MeterType
+	meterType := quota.MeterTypeRequests
+	if p.MeterType == plan.MeterTypeComputeUnits {
+		meterType = quota.MeterTypeComputeUnits
+	}
+	estimatedCost := p.EstimatedCostPerReq
+	if estimatedCost <= 0 {
+		estimatedCost = 1.0
+	}
+	cfg := quota.Config{
+		RequestsPerMonth: p.RequestsPerMonth,
+		EnforceMode:      enforceMode,
+		GracePct:         gracePct,
+		MeterType:        meterType,
+		EstimatedCost:    estimatedCost,
+	}
+	// For compute_units mode, use estimated cost weighted by the route; for requests, use 1
+	increment := int64(1)
+	if meterType == quota.MeterTypeComputeUnits {
+		increment = int64(estimatedCost * costWeight)
+	}
+	return cfg, increment
+}
+
+// checkKeyQuota checks the key's own quota, if it has one and doesn't
+// bypass quotas. It is counted against the key ID in the plan's meter and
+// period, and enforced as a hard cap without grace, since customers set it
+// to stay below their plan quota.
+func (s *ProxyService) checkKeyQuota(ctx context.Context, k key.Key, p plan.Plan, costWeight float64, periodStart time.Time) (quota.CheckResult, bool) {
+	if s.quota == nil || k.MonthlyQuota <= 0 || k.QuotaBypass {
+		return quota.CheckResult{}, false
+	}
+	cfg, increment := quotaConfig(p, costWeight)
+	cfg.RequestsPerMonth = k.MonthlyQuota
+	cfg.EnforceMode = quota.EnforceHard
+	cfg.GracePct = 0
+	state, _ := s.quota.Get(ctx, k.ID, periodStart)
+	return quota.Check(state, cfg, increment), true
+}
+
+// recordKeyUse records a request as the key's last use, through the key use
+// tracker if there is one.
+func (s *ProxyService) recordKeyUse(keyID string, now time.Time, ip string) {
+	if s.keyUses != nil {
+		s.keyUses.Record(keyID, now, ip)
+		return
+	}
+	// Use background context since request context may be cancelled
+	go func() {
+		bgCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if store, ok := s.keys.(ports.KeyUseStore); ok {
+			store.RecordUses(bgCtx, []key.Use{{KeyID: keyID, At: now, IP: ip}})
+			return
+		}
+		s.keys.UpdateLastUsed(bgCtx, keyID, now)
+	}()
+}
+
+// quotaExceededResult rejects a request over a quota, telling the client
+// when the quota resets.
+func quotaExceededResult(k key.Key, user ports.User, result quota.CheckResult, periodEnd, now time.Time) HandleResult {
+	return HandleResult{
+		Error: &proxy.ErrQuotaExceeded,
+		Auth:  rejectedAuth(k, user),
+		Response: proxy.Response{
+			Headers: map[string]string{
+				"X-Quota-Used":  strconv.FormatInt(result.CurrentUsage, 10),
+				"X-Quota-Limit": strconv.FormatInt(result.Limit, 10),
+				"X-Quota-Reset": periodEnd.Format(time.RFC3339),
+				"Retry-After":   strconv.FormatInt(int64(periodEnd.Sub(now).Seconds()), 10),
+			},
+		},
+	}
+}
+
+// concurrencyHeaders returns the headers describing the plan's concurrency limit.
+func concurrencyHeaders(p plan.Plan) map[string]string {
+	if p.MaxConcurrent <= 0 {
+		return nil
+	}
+	return map[string]string{"X-Concurrency-Limit": itoa(p.MaxConcurrent)}
+}
+
+// checkRateLimit takes a token from the key's bucket. Stores that implement
+// ports.RateLimitChecker run the check atomically themselves; otherwise the
+// read-check-write is serialised per key so concurrent requests cannot spend
+// the same token. If a shared store is unavailable the request is allowed.
+func (s *ProxyService) checkRateLimit(ctx context.Context, keyID string, cfg ratelimit.Config, now time.Time) ratelimit.CheckResult {
+	if checker, ok := s.rateLimit.(ports.RateLimitChecker); ok {
+		result, err := checker.CheckBucket(ctx, keyID, cfg, now)
+		if err != nil {
+			return ratelimit.CheckResult{Allowed: true, Remaining: cfg.BucketSize(), ResetAt: now}
+		}
+		return result
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(keyID))
+	mu := &s.rateLimitLocks[h.Sum32()%rateLimitLockStripes]
+	mu.Lock()
+	defer mu.Unlock()
+
+	state, _ := s.rateLimit.Get(ctx, keyID)
+	result, newState := ratelimit.CheckBucket(state, cfg, now)
+	s.rateLimit.Set(ctx, keyID, newState)
+	return result
+}
+
+// rejectedAuth identifies the caller of a request rejected after authentication,
+// so the rejection can be attributed to its user and plan.
+func rejectedAuth(k key.Key, user ports.User) *proxy.AuthContext {
+	return &proxy.AuthContext{KeyID: k.ID, UserID: k.UserID, PlanID: user.PlanID}
+}
+
+// retryAfter formats a Retry-After value, rounding up to whole seconds.
+func retryAfter(d time.Duration) string {
+	if d <= 0 {
+		return "0"
+	}
+	return itoa(int((d + time.Second - 1) / time.Second))
+}
+
 func itoa(n int) string {
 	if n < 0 {
 		return "-" + itoa(-n)
@@ -550,6 +1085,12 @@ func (s *ProxyService) handlePublicRoute(
 	dynCfg *DynamicConfig,
 ) HandleResult {
 	now := s.clock.Now()
+	costWeight := matchedRoute.CostMultiplierFor(req.Method)
+
+	// Check the request body against the route's schema (PURE)
+	if errResp := s.validateBody(ctx, req, matchedRoute); errResp != nil {
+		return HandleResult{Error: errResp}
+	}
 
 	// Apply request transform (PURE + Expr eval)
 	if matchedRoute.RequestTransform != nil && s.transformService != nil {
@@ -564,7 +1105,9 @@ func (s *ProxyService) handlePublicRoute(
 		}
 	}
 
-	// Path rewriting (PURE + Expr eval)
+	// Path rewriting: strip prefix, regex rewrite and query params to strip (PURE), then Expr eval
+	req.Path = matchedRoute.RewriteUpstreamPath(req.Path)
+	req.Query = route.StripQueryParams(req.Query, matchedRoute.StripQuery)
 	if matchedRoute.PathRewrite != "" && s.transformService != nil {
 		rewriteCtx := map[string]any{
 			"path":       req.Path,
@@ -582,13 +1125,17 @@ func (s *ProxyService) handlePublicRoute(
 		req.Method = matchedRoute.MethodOverride
 	}
 
-	// Forward to upstream (I/O)
-	var resp proxy.Response
+	// Apply request header rules (PURE)
+	if len(matchedRoute.RequestHeaders) > 0 {
+		req.Headers = route.ApplyHeaderRules(req.Headers, matchedRoute.RequestHeaders, headerRefs(nil))
+	}
+
+	// Forward to upstream (I/O), mirroring a copy first if the route asks for it
+	s.mirror(ctx, req, matchedRoute)
 	var routeUpstream *route.Upstream
-	var err error
 
-	if matchedRoute.UpstreamID != "" && s.routeService != nil {
-		routeUpstream = s.routeService.GetUpstream(matchedRoute.UpstreamID)
+	if matchedRoute.HasUpstream() && s.routeService != nil {
+		routeUpstream = s.routeService.SelectUpstream(ctx, matchedRoute)
 		if routeUpstream != nil {
 			// Apply upstream authentication headers
 			req.Headers = s.routeService.ApplyUpstreamAuth(routeUpstream, req.Headers)
@@ -596,20 +1143,24 @@ func (s *ProxyService) handlePublicRoute(
 	}
 
 	// Forward to route's upstream if available, otherwise use default
-	if routeUpstream != nil {
-		resp, err = s.upstream.ForwardTo(ctx, req, routeUpstream)
-	} else {
-		resp, err = s.upstream.Forward(ctx, req)
-	}
-	if err != nil {
-		return HandleResult{Error: &proxy.ErrUpstreamError}
+	req.MaxResponseBody = s.responseBodyLimit(matchedRoute)
+	req.Timeouts = upstreamTimeouts(matchedRoute)
+	resp, errResp := s.forward(ctx, req, matchedRoute, routeUpstream)
+	if errResp != nil {
+		return HandleResult{Error: errResp}
 	}
 
 	// Apply response transform (PURE + Expr eval)
-	if matchedRoute.ResponseTransform != nil && s.transformService != nil {
+	if matchedRoute.ResponseTransform != nil && s.transformService != nil && resp.Status != 304 && resp.Stream == nil {
 		resp, _ = s.transformService.TransformResponse(ctx, resp, matchedRoute.ResponseTransform, nil)
 	}
 
+	// Apply response header rules (PURE)
+	resp.Headers = s.ApplyResponseHeaders(matchedRoute, nil, resp.Headers)
+
+	// Rewrite upstream URLs in the body (PURE)
+	resp = s.rewriteResponseURLs(matchedRoute, routeUpstream, req, resp)
+
 	// Calculate cost/metering value for anonymous tracking (PURE + Expr eval)
 	var costMult float64 = 1.0
 	if matchedRoute.MeteringExpr != "" && s.transformService != nil {
@@ -633,6 +1184,7 @@ func (s *ProxyService) handlePublicRoute(
 	} else {
 		costMult = plan.GetCostMultiplier(dynCfg.Endpoints, req.Method, originalPath)
 	}
+	costMult *= costWeight
 
 	// Record anonymous usage event (async I/O)
 	// Use special "anonymous" identifiers for public routes
@@ -676,6 +1228,7 @@ func (s *ProxyService) handlePublicStreamingRoute(
 	dynCfg *DynamicConfig,
 ) StreamingHandleResult {
 	var routeUpstream *route.Upstream
+	costWeight := matchedRoute.CostMultiplierFor(req.Method)
 
 	// Apply request transform
 	if matchedRoute.RequestTransform != nil && s.transformService != nil {
@@ -690,7 +1243,9 @@ func (s *ProxyService) handlePublicStreamingRoute(
 		}
 	}
 
-	// Path rewriting
+	// Path rewriting: strip prefix, regex rewrite and query params to strip, then Expr eval
+	req.Path = matchedRoute.RewriteUpstreamPath(req.Path)
+	req.Query = route.StripQueryParams(req.Query, matchedRoute.StripQuery)
 	if matchedRoute.PathRewrite != "" && s.transformService != nil {
 		rewriteCtx := map[string]any{
 			"path":       req.Path,
@@ -707,23 +1262,36 @@ func (s *ProxyService) handlePublicStreamingRoute(
 		req.Method = matchedRoute.MethodOverride
 	}
 
+	// Apply request header rules
+	if len(matchedRoute.RequestHeaders) > 0 {
+		req.Headers = route.ApplyHeaderRules(req.Headers, matchedRoute.RequestHeaders, headerRefs(nil))
+	}
+
 	// Get and apply upstream auth
-	if matchedRoute.UpstreamID != "" && s.routeService != nil {
-		routeUpstream = s.routeService.GetUpstream(matchedRoute.UpstreamID)
+	if matchedRoute.HasUpstream() && s.routeService != nil {
+		routeUpstream = s.routeService.SelectUpstream(ctx, matchedRoute)
 		if routeUpstream != nil {
 			req.Headers = s.routeService.ApplyUpstreamAuth(routeUpstream, req.Headers)
 		}
 	}
 
+	req.Timeouts = upstreamTimeouts(matchedRoute)
+
+	// The caller reports the outcome with RecordUpstreamResult
+	if routeUpstream != nil && !s.routeService.AllowUpstream(routeUpstream) {
+		return StreamingHandleResult{Error: &proxy.ErrCircuitOpen}
+	}
+
 	// Return streaming context with modified request and upstream for public route
 	// Use anonymous identifiers since no auth context
 	return StreamingHandleResult{
 		StreamingResponse: &StreamingResponseContext{
-			Headers:      make(map[string]string),
-			MatchedRoute: matchedRoute,
-			OriginalPath: originalPath,
-			KeyID:        "anonymous",
-			UserID:       "anonymous",
+			Headers:        make(map[string]string),
+			MatchedRoute:   matchedRoute,
+			OriginalPath:   originalPath,
+			KeyID:          "anonymous",
+			UserID:         "anonymous",
+			CostMultiplier: costWeight,
 		},
 		ModifiedRequest: &req,
 		RouteUpstream:   routeUpstream,
@@ -735,11 +1303,15 @@ func (s *ProxyService) handlePublicStreamingRoute(
 // StreamingHandleResult represents the outcome of handling a streaming request.
 type StreamingHandleResult struct {
 	StreamingResponse *StreamingResponseContext
-	ModifiedRequest   *proxy.Request     // Request after transforms/rewrites
-	RouteUpstream     *route.Upstream    // Route's upstream (if different from default)
+	ModifiedRequest   *proxy.Request  // Request after transforms/rewrites
+	RouteUpstream     *route.Upstream // Route's upstream (if different from default); report the outcome with RecordUpstreamResult
 	Error             *proxy.ErrorResponse
 	Auth              *proxy.AuthContext
 	Headers           map[string]string // Rate limit headers to add
+
+	// Release frees the key's concurrency slot; call it once the stream or
+	// connection has finished. Nil when no slot is held.
+	Release func()
 }
 
 // StreamingResponseContext contains everything needed to stream a response.
@@ -751,17 +1323,305 @@ type StreamingResponseContext struct {
 	UpstreamAddr string
 
 	// For metering after stream completes
-	MatchedRoute *route.Route
-	OriginalPath string
-	KeyID        string
-	UserID       string
+	MatchedRoute   *route.Route
+	OriginalPath   string
+	KeyID          string
+	UserID         string
+	CostMultiplier float64   // Route weight for the client's method; scales the metered value
+	QuotaPeriod    time.Time // Start of the key's quota period; zero for public routes
+}
+
+// ApplyResponseHeaders returns headers with the route's response header rules
+// applied. auth is nil for public routes.
+func (s *ProxyService) ApplyResponseHeaders(matchedRoute *route.Route, auth *proxy.AuthContext, headers map[string]string) map[string]string {
+	if matchedRoute == nil || len(matchedRoute.ResponseHeaders) == 0 {
+		return headers
+	}
+	return route.ApplyHeaderRules(headers, matchedRoute.ResponseHeaders, headerRefs(auth))
+}
+
+// ResponseURLRewrites returns the old/new pairs replacing the upstream's base
+// URL with the gateway's public URL in a response body with these headers.
+// Returns nil when the route doesn't rewrite URLs or the body is compressed
+// or not JSON/HTML.
+func (s *ProxyService) ResponseURLRewrites(matchedRoute *route.Route, upstream *route.Upstream, req proxy.Request, headers map[string]string) []string {
+	if matchedRoute == nil || !matchedRoute.RewriteResponseURLs || upstream == nil || !proxy.IsRewritableBody(headers) {
+		return nil
+	}
+	return proxy.URLRewrites(upstream.BaseURL, proxy.PublicBaseURL(s.publicURL, req))
+}
+
+// rewriteResponseURLs applies ResponseURLRewrites to a buffered response.
+func (s *ProxyService) rewriteResponseURLs(matchedRoute *route.Route, upstream *route.Upstream, req proxy.Request, resp proxy.Response) proxy.Response {
+	pairs := s.ResponseURLRewrites(matchedRoute, upstream, req, resp.Headers)
+	if pairs == nil {
+		return resp
+	}
+	resp.Body = proxy.RewriteBody(resp.Body, pairs)
+	// The length changes with the body; net/http sets it from the new body
+	for k := range resp.Headers {
+		if strings.EqualFold(k, "Content-Length") {
+			delete(resp.Headers, k)
+		}
+	}
+	return resp
+}
+
+// RequestBodyLimit returns the maximum request body size in bytes for req.
+// A positive limit on the matched route overrides the global limit; 0 means unlimited.
+func (s *ProxyService) RequestBodyLimit(req proxy.Request) int64 {
+	if s.routeService != nil {
+		if match := s.routeService.MatchRequest(context.Background(), req); match != nil {
+			return match.Route.RequestBodyLimit(s.maxRequestBody)
+		}
+	}
+	return s.maxRequestBody
+}
+
+// CORSPolicy returns the CORS policy of the route req matches, or nil if the
+// route has none. Preflights are matched by the method they ask about.
+func (s *ProxyService) CORSPolicy(req proxy.Request) *route.CORSPolicy {
+	if s.routeService == nil {
+		return nil
+	}
+	requestMethod := proxy.HeaderValue(req.Headers, "Access-Control-Request-Method")
+	if route.IsPreflight(req.Method, proxy.HeaderValue(req.Headers, "Origin"), requestMethod) {
+		req.Method = requestMethod
+	}
+	if match := s.routeService.MatchRequest(context.Background(), req); match != nil {
+		return match.Route.CORS
+	}
+	return nil
+}
+
+// responseBodyLimit returns the upstream response body limit for the matched route.
+func (s *ProxyService) responseBodyLimit(matchedRoute *route.Route) int64 {
+	if matchedRoute != nil {
+		return matchedRoute.ResponseBodyLimit(s.maxResponseBody)
+	}
+	return s.maxResponseBody
+}
+
+// upstreamTimeouts returns the upstream timeouts set on the matched route.
+func upstreamTimeouts(matchedRoute *route.Route) proxy.Timeouts {
+	if matchedRoute == nil {
+		return proxy.Timeouts{}
+	}
+	return proxy.Timeouts{
+		Dial:           matchedRoute.DialTimeout,
+		ResponseHeader: matchedRoute.ResponseHeaderTimeout,
+		Request:        matchedRoute.RequestTimeout,
+	}
+}
+
+// forward sends req to the route's upstream, or to the default upstream when
+// routeUpstream is nil. Routes with caching enabled are served from the
+// response cache when possible and fill it on a miss; conditional requests
+// against a cacheable response are answered with 304 Not Modified.
+func (s *ProxyService) forward(ctx context.Context, req proxy.Request, matchedRoute *route.Route, routeUpstream *route.Upstream) (proxy.Response, *proxy.ErrorResponse) {
+	var cacheKey string
+	conditions := req
+	if s.responseCache != nil && matchedRoute != nil && matchedRoute.CachingEnabled() && proxy.IsCacheableRequest(req) {
+		cacheKey = proxy.CacheKey(matchedRoute.ID, req, matchedRoute.CacheKeyHeaders)
+		if cached, ok, _ := s.responseCache.Get(ctx, cacheKey); ok {
+			cached.LatencyMs = 0
+			cached.Headers = withHeader(cached.Headers, "X-Cache", proxy.CacheHit)
+			return conditionalResponse(conditions, cached), nil
+		}
+		// Fetch the full representation so it can be cached; the client's
+		// conditions are evaluated by the gateway instead
+		req.Headers = withoutConditionalHeaders(req.Headers)
+	}
+
+	ctx, span := s.tracer.Start(ctx, "proxy.upstream", trace.WithSpanKind(trace.SpanKindClient))
+	req.Headers = withTraceContext(ctx, req.Headers)
+
+	if routeUpstream != nil {
+		span.SetAttributes(attribute.String("apigate.upstream_id", routeUpstream.ID))
+		if !s.routeService.AllowUpstream(routeUpstream) {
+			span.SetStatus(codes.Error, "circuit open")
+			span.End()
+			return proxy.Response{}, &proxy.ErrCircuitOpen
+		}
+	}
+	resp, err := s.sendUpstream(ctx, req, routeUpstream)
+
+	// Retry transient failures; the request body is buffered, so it can be resent
+	if matchedRoute != nil && matchedRoute.Retry != nil && matchedRoute.Retry.AllowsMethod(req.Method) {
+		policy := *matchedRoute.Retry
+		for n := 1; n <= policy.Attempts && isRetriable(policy, resp, err); n++ {
+			if !sleepContext(ctx, policy.BackoffBefore(n)) {
+				break
+			}
+			if routeUpstream != nil && !s.routeService.AllowUpstream(routeUpstream) {
+				break
+			}
+			if resp.Stream != nil {
+				resp.Stream.Close()
+			}
+			span.AddEvent("retry", trace.WithAttributes(attribute.Int("apigate.retry_attempt", n)))
+			resp, err = s.sendUpstream(ctx, req, routeUpstream)
+		}
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "upstream request failed")
+		span.End()
+		return proxy.Response{}, upstreamError(err)
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.Status))
+	span.End()
+
+	// Event streams are relayed as they arrive and never cached
+	if resp.Stream != nil {
+		return resp, nil
+	}
+
+	if cacheKey != "" {
+		if proxy.IsCacheableResponse(resp) {
+			resp = proxy.WithValidators(resp, s.clock.Now())
+			s.responseCache.Set(ctx, cacheKey, resp, matchedRoute.CacheTTL)
+		}
+		resp.Headers = withHeader(resp.Headers, "X-Cache", proxy.CacheMiss)
+		return conditionalResponse(conditions, resp), nil
+	}
+	return resp, nil
+}
+
+// conditionalResponse returns 304 Not Modified in place of resp when the
+// request's If-None-Match or If-Modified-Since shows the client is up to date.
+func conditionalResponse(req proxy.Request, resp proxy.Response) proxy.Response {
+	if proxy.NotModified(req, resp) {
+		return proxy.NotModifiedResponse(resp)
+	}
+	return resp
+}
+
+// withoutConditionalHeaders returns a copy of headers without conditional request headers.
+func withoutConditionalHeaders(headers map[string]string) map[string]string {
+	out := make(map[string]string, len(headers))
+	for k, v := range headers {
+		if strings.EqualFold(k, "If-None-Match") || strings.EqualFold(k, "If-Modified-Since") {
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// withHeader sets a header, allocating the header map if needed.
+func withHeader(headers map[string]string, name, value string) map[string]string {
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	headers[name] = value
+	return headers
+}
+
+// sendUpstream makes one attempt at forwarding req, recording the outcome for
+// the route upstream's health checking and circuit breaker.
+func (s *ProxyService) sendUpstream(ctx context.Context, req proxy.Request, routeUpstream *route.Upstream) (proxy.Response, error) {
+	if routeUpstream == nil {
+		return s.upstream.Forward(ctx, req)
+	}
+	resp, err := s.upstream.ForwardTo(ctx, req, routeUpstream)
+	s.routeService.RecordUpstreamResult(routeUpstream, UpstreamOutcome(resp.Status, err))
+	return resp, err
+}
+
+// isRetriable reports whether a forwarding outcome may be retried under policy.
+// Connection failures are retried; cancellations, oversized responses and
+// timeouts after connecting are not, as retrying them only adds latency.
+func isRetriable(policy route.RetryPolicy, resp proxy.Response, err error) bool {
+	if err != nil {
+		var timeout *proxy.UpstreamTimeoutError
+		if errors.As(err, &timeout) {
+			return timeout.Stage == proxy.StageDial
+		}
+		return UpstreamOutcome(resp.Status, err) == route.OutcomeFailure
+	}
+	return policy.RetriesStatus(resp.Status)
+}
+
+// sleepContext waits for d and reports whether ctx is still live afterwards.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// UpstreamOutcome classifies a forwarding outcome for upstream health from
+// the upstream's status code and the forwarding error.
+// Oversized responses and client cancellations are neutral: they are not
+// upstream faults, but don't show the upstream is working either.
+func UpstreamOutcome(status int, err error) route.UpstreamOutcome {
+	if err != nil {
+		if errors.Is(err, proxy.ErrResponseBodyTooLarge) || errors.Is(err, context.Canceled) {
+			return route.OutcomeNeutral
+		}
+		return route.OutcomeFailure
+	}
+	if status >= 500 {
+		return route.OutcomeFailure
+	}
+	return route.OutcomeSuccess
+}
+
+// upstreamError maps an upstream forwarding error to a client error response.
+func upstreamError(err error) *proxy.ErrorResponse {
+	if errors.Is(err, proxy.ErrResponseBodyTooLarge) {
+		return &proxy.ErrResponseTooLarge
+	}
+	var timeout *proxy.UpstreamTimeoutError
+	if errors.As(err, &timeout) {
+		return proxy.TimeoutResponse(timeout)
+	}
+	return &proxy.ErrUpstreamError
+}
+
+// IsGRPC returns true if the request matches a route with protocol grpc.
+func (s *ProxyService) IsGRPC(req proxy.Request) bool {
+	if s.routeService == nil {
+		return false
+	}
+	match := s.routeService.MatchRequest(context.Background(), req)
+	return match != nil && match.Route.Protocol == route.ProtocolGRPC
+}
+
+// IsWebSocket returns true if the request matches a route with protocol websocket.
+func (s *ProxyService) IsWebSocket(req proxy.Request) bool {
+	if s.routeService == nil {
+		return false
+	}
+	match := s.routeService.MatchRequest(context.Background(), req)
+	return match != nil && match.Route.Protocol == route.ProtocolWebSocket
+}
+
+// RouteName returns the name of the route matching req, or "" if no route matches.
+func (s *ProxyService) RouteName(req proxy.Request) string {
+	if s.routeService == nil {
+		return ""
+	}
+	if match := s.routeService.MatchRequest(context.Background(), req); match != nil {
+		return match.Route.Name
+	}
+	return ""
 }
 
 // ShouldStream determines if a request should use streaming.
 func (s *ProxyService) ShouldStream(req proxy.Request) bool {
 	// Check if route service exists and can determine streaming
 	if s.routeService != nil {
-		if match := s.routeService.Match(req.Method, req.Path, req.Headers); match != nil {
+		if match := s.routeService.MatchRequest(context.Background(), req); match != nil {
 			// Check route protocol
 			switch match.Route.Protocol {
 			case route.ProtocolSSE, route.ProtocolHTTPStream, route.ProtocolWebSocket:
@@ -798,12 +1658,20 @@ func (s *ProxyService) HandleStreaming(ctx context.Context, req proxy.Request, s
 	originalPath := req.Path
 
 	if s.routeService != nil {
-		if match := s.routeService.Match(req.Method, req.Path, req.Headers); match != nil {
+		// Pin the route table so a reload mid-request can't change the upstream
+		ctx = s.routeService.Pin(ctx)
+		if match := s.routeService.MatchRequest(ctx, req); match != nil {
 			matchedRoute = match.Route
 			pathParams = match.PathParams
 		}
 	}
 
+	// Route cost weighting follows the client's method, not a method override
+	costWeight := 1.0
+	if matchedRoute != nil {
+		costWeight = matchedRoute.CostMultiplierFor(req.Method)
+	}
+
 	// 2. Check if this is a public route (no auth required)
 	if matchedRoute != nil && !matchedRoute.AuthRequired {
 		// Public streaming route - skip auth and rate limiting
@@ -816,26 +1684,36 @@ func (s *ProxyService) HandleStreaming(ctx context.Context, req proxy.Request, s
 	var matchedKey key.Key
 	var err error
 
-	if req.APIKey == "" {
+	// Routes may require HMAC-signed requests or a client certificate instead
+	// of the API key itself
+	signed := matchedRoute != nil && matchedRoute.AuthMethod == route.AuthMethodHMAC
+	certAuth := matchedRoute != nil && matchedRoute.AuthMethod == route.AuthMethodMTLS
+	if req.APIKey == "" && !signed && !certAuth {
 		return StreamingHandleResult{Error: &proxy.ErrMissingKey}
 	}
 
 	// Check if token looks like an API key (has the expected prefix)
 	_, isAPIKeyFormat := key.ValidateFormat(req.APIKey, s.keyPrefix)
 
-	if !isAPIKeyFormat && s.tokens != nil {
+	if certAuth {
+		var authErr *proxy.ErrorResponse
+		if matchedKey, user, authErr = s.authenticateClientCert(ctx, req, now); authErr != nil {
+			return StreamingHandleResult{Error: authErr}
+		}
+	} else if signed {
+		var authErr *proxy.ErrorResponse
+		if matchedKey, user, authErr = s.authenticateSigned(ctx, req, now); authErr != nil {
+			return StreamingHandleResult{Error: authErr}
+		}
+	} else if !isAPIKeyFormat && (s.tokens != nil || s.bearer != nil) {
 		// Token doesn't look like an API key - try JWT validation
-		var claims *auth.Claims
-		claims, err = s.tokens.ValidateToken(req.APIKey)
+		var synthetic key.Key
+		synthetic, err = s.validateBearer(ctx, req.APIKey)
 		if err == nil {
 			// JWT is valid - get user directly
-			user, err = s.users.Get(ctx, claims.UserID)
+			user, err = s.users.Get(ctx, synthetic.UserID)
 			if err == nil && user.Status == "active" {
-				// Create a synthetic key for tracking (no actual key exists)
-				matchedKey = key.Key{
-					ID:     "session:" + claims.UserID,
-					UserID: claims.UserID,
-				}
+				matchedKey = synthetic
 			} else if err != nil {
 				return StreamingHandleResult{Error: &proxy.ErrInvalidKey}
 			} else {
@@ -900,23 +1778,43 @@ func (s *ProxyService) HandleStreaming(ctx context.Context, req proxy.Request, s
 		}
 	}
 
+	// 7.4. Check the client IP is allowed for the key
+	if !key.AllowsIP(matchedKey, req.RemoteIP) {
+		return StreamingHandleResult{Error: &proxy.ErrIPNotAllowed}
+	}
+
+	// 7.5. Check the key holds the route's required scopes
+	if matchedRoute != nil && !hasRouteScopes(matchedKey, matchedRoute) {
+		return StreamingHandleResult{Error: &proxy.ErrInsufficientScope}
+	}
+
+	// 7.6. Check the request body against the route's schema
+	if errResp := s.validateBody(ctx, req, matchedRoute); errResp != nil {
+		return StreamingHandleResult{Error: errResp}
+	}
+
+	// 7.7. Group keys are metered on the group's plan
+	user, errResp := s.groupPlan(ctx, matchedKey, user)
+	if errResp != nil {
+		return StreamingHandleResult{Error: errResp}
+	}
+
 	// 8. Get plan and rate limit config
 	userPlan, _ := plan.FindPlan(dynCfg.Plans, user.PlanID)
-	rlConfig := ratelimit.Config{
-		Limit:       userPlan.RateLimitPerMinute,
-		Window:      time.Duration(dynCfg.RateWindow) * time.Second,
-		BurstTokens: dynCfg.RateBurst,
+	rlConfig := rateLimitConfig(userPlan, dynCfg)
+	if s.spikes != nil {
+		rlConfig = s.spikes.Throttle(matchedKey.ID, rlConfig, now)
 	}
-	if rlConfig.Limit == 0 {
-		rlConfig.Limit = 60
+
+	// 8.6. Check the key's own quota
+	periodStart, periodEnd := quota.WindowBounds(quota.Period(userPlan.QuotaPeriod), now, user.CreatedAt)
+	if keyResult, ok := s.checkKeyQuota(ctx, matchedKey, userPlan, costWeight, periodStart); ok && !keyResult.Allowed {
+		rejected := quotaExceededResult(matchedKey, user, keyResult, periodEnd, now)
+		return StreamingHandleResult{Error: rejected.Error, Auth: rejected.Auth, Headers: rejected.Response.Headers}
 	}
 
 	// 9. Check rate limit
-	rlState, _ := s.rateLimit.Get(ctx, matchedKey.ID)
-	rlResult, newRLState := ratelimit.Check(rlState, rlConfig, now)
-	if setErr := s.rateLimit.Set(ctx, matchedKey.ID, newRLState); setErr != nil {
-		// Log but don't fail
-	}
+	rlResult := s.checkRateLimit(ctx, matchedKey.ID, rlConfig, now)
 
 	if !rlResult.Allowed {
 		return StreamingHandleResult{
@@ -924,14 +1822,33 @@ func (s *ProxyService) HandleStreaming(ctx context.Context, req proxy.Request, s
 			Headers: map[string]string{
 				"X-RateLimit-Remaining": "0",
 				"X-RateLimit-Reset":     rlResult.ResetAt.Format("2006-01-02T15:04:05Z"),
-				"Retry-After":           itoa(int(rlResult.ResetAt.Sub(now).Seconds())),
+				"X-RateLimit-Burst":     itoa(rlConfig.Burst),
+				"Retry-After":           retryAfter(rlResult.ResetAt.Sub(now)),
 			},
 		}
 	}
 
+	// 9.2. Count the request toward spike detection
+	if s.spikes != nil {
+		s.spikes.Observe(matchedKey, userPlan, now)
+	}
+
+	// 9.5. Take a concurrency slot, released by the caller once the stream ends
+	release, ok := s.concurrency.acquire(matchedKey.ID, userPlan.MaxConcurrent)
+	if !ok {
+		return StreamingHandleResult{Error: &proxy.ErrConcurrencyLimit, Headers: concurrencyHeaders(userPlan)}
+	}
+	handedOff := false
+	defer func() {
+		if !handedOff {
+			release()
+		}
+	}()
+
 	// 10. Build auth context
 	auth := proxy.AuthContext{
 		KeyID:     matchedKey.ID,
+		KeyPrefix: matchedKey.Prefix,
 		UserID:    matchedKey.UserID,
 		PlanID:    user.PlanID,
 		RateLimit: rlConfig.Limit,
@@ -953,7 +1870,9 @@ func (s *ProxyService) HandleStreaming(ctx context.Context, req proxy.Request, s
 			}
 		}
 
-		// Path rewriting (use pathParams from initial match)
+		// Path rewriting: strip prefix, regex rewrite and query params to strip, then Expr eval (use pathParams from initial match)
+		req.Path = matchedRoute.RewriteUpstreamPath(req.Path)
+		req.Query = route.StripQueryParams(req.Query, matchedRoute.StripQuery)
 		if matchedRoute.PathRewrite != "" && s.transformService != nil {
 			rewriteCtx := map[string]any{
 				"path":       req.Path,
@@ -970,9 +1889,14 @@ func (s *ProxyService) HandleStreaming(ctx context.Context, req proxy.Request, s
 			req.Method = matchedRoute.MethodOverride
 		}
 
+		// Apply request header rules
+		if len(matchedRoute.RequestHeaders) > 0 {
+			req.Headers = route.ApplyHeaderRules(req.Headers, matchedRoute.RequestHeaders, headerRefs(&auth))
+		}
+
 		// Get and apply upstream auth
-		if matchedRoute.UpstreamID != "" {
-			routeUpstream = s.routeService.GetUpstream(matchedRoute.UpstreamID)
+		if matchedRoute.HasUpstream() {
+			routeUpstream = s.routeService.SelectUpstreamFor(ctx, matchedRoute, &auth)
 			if routeUpstream != nil {
 				req.Headers = s.routeService.ApplyUpstreamAuth(routeUpstream, req.Headers)
 			}
@@ -980,21 +1904,27 @@ func (s *ProxyService) HandleStreaming(ctx context.Context, req proxy.Request, s
 	}
 
 	// Update last used
-	// Use background context since request context may be cancelled
-	go func() {
-		bgCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		s.keys.UpdateLastUsed(bgCtx, matchedKey.ID, now)
-	}()
+	s.recordKeyUse(matchedKey.ID, now, req.RemoteIP)
+
+	req.Timeouts = upstreamTimeouts(matchedRoute)
+
+	// The caller reports the outcome with RecordUpstreamResult
+	if routeUpstream != nil && !s.routeService.AllowUpstream(routeUpstream) {
+		return StreamingHandleResult{Error: &proxy.ErrCircuitOpen, Auth: &auth}
+	}
 
 	// Return streaming context with modified request and upstream
+	handedOff = true
 	return StreamingHandleResult{
+		Release: release,
 		StreamingResponse: &StreamingResponseContext{
-			Headers:      make(map[string]string),
-			MatchedRoute: matchedRoute,
-			OriginalPath: originalPath,
-			KeyID:        matchedKey.ID,
-			UserID:       matchedKey.UserID,
+			Headers:        make(map[string]string),
+			MatchedRoute:   matchedRoute,
+			OriginalPath:   originalPath,
+			KeyID:          matchedKey.ID,
+			UserID:         matchedKey.AccountID(),
+			CostMultiplier: costWeight,
+			QuotaPeriod:    periodStart,
 		},
 		ModifiedRequest: &req,
 		RouteUpstream:   routeUpstream,
@@ -1002,10 +1932,22 @@ func (s *ProxyService) HandleStreaming(ctx context.Context, req proxy.Request, s
 		Headers: map[string]string{
 			"X-RateLimit-Remaining": itoa(rlResult.Remaining),
 			"X-RateLimit-Reset":     rlResult.ResetAt.Format("2006-01-02T15:04:05Z"),
+			"X-RateLimit-Burst":     itoa(rlConfig.Burst),
 		},
 	}
 }
 
+// RecordUpstreamResult feeds the outcome of a request that HandleStreaming
+// handed over with a RouteUpstream into the upstream's health checking and
+// circuit breaker. Every such request must be recorded once, as it may hold
+// a half-open trial.
+func (s *ProxyService) RecordUpstreamResult(u *route.Upstream, outcome route.UpstreamOutcome) {
+	if u == nil || s.routeService == nil {
+		return
+	}
+	s.routeService.RecordUpstreamResult(u, outcome)
+}
+
 // RecordStreamingUsage records usage for a completed streaming request.
 func (s *ProxyService) RecordStreamingUsage(
 	streamCtx *StreamingResponseContext,
@@ -1018,6 +1960,9 @@ func (s *ProxyService) RecordStreamingUsage(
 	userAgent string,
 ) {
 	now := s.clock.Now()
+	if streamCtx.CostMultiplier > 0 {
+		meteringValue *= streamCtx.CostMultiplier
+	}
 
 	event := usage.Event{
 		ID:             s.idGen.New(),
@@ -1035,6 +1980,11 @@ func (s *ProxyService) RecordStreamingUsage(
 		Timestamp:      now,
 	}
 	s.usage.Record(event)
+
+	// Count the stream toward the key's quota
+	if s.quota != nil && !streamCtx.QuotaPeriod.IsZero() {
+		s.quota.Increment(context.Background(), streamCtx.KeyID, streamCtx.QuotaPeriod, 1, meteringValue, requestBytes+responseBytes)
+	}
 }
 
 // EvalStreamingMetering evaluates a metering expression for streaming responses.
@@ -1059,21 +2009,49 @@ func (s *ProxyService) EvalStreamingMetering(
 	allData []byte,
 	auth *proxy.AuthContext,
 ) float64 {
-	if s.transformService == nil || meteringExpr == "" {
-		return 1.0
-	}
-
 	// Build metering context with streaming data
-	meteringCtx := map[string]any{
+	return s.evalMetering(ctx, meteringExpr, map[string]any{
 		"status":        status,
 		"responseBytes": responseBytes,
 		"lastChunk":     lastChunk,
 		"allData":       allData,
-		"userID":        "",
-		"planID":        "",
-		"keyID":         "",
+	}, auth)
+}
+
+// EvalConnectionMetering evaluates a metering expression for a proxied
+// connection (WebSocket) once it has closed. The context has:
+// - status: handshake status code
+// - durationMs, durationSeconds: how long the connection was open
+// - userID, planID, keyID: auth context
+//
+// Example expressions:
+//   - "1" (count connections)
+//   - "durationSeconds / 60" (minute-based)
+func (s *ProxyService) EvalConnectionMetering(
+	ctx context.Context,
+	meteringExpr string,
+	status int,
+	duration time.Duration,
+	auth *proxy.AuthContext,
+) float64 {
+	return s.evalMetering(ctx, meteringExpr, map[string]any{
+		"status":          status,
+		"durationMs":      duration.Milliseconds(),
+		"durationSeconds": duration.Seconds(),
+	}, auth)
+}
+
+// evalMetering evaluates a metering expression with the given variables plus
+// the auth context. Returns 1 if the expression is empty or fails, and never
+// a negative value.
+func (s *ProxyService) evalMetering(ctx context.Context, meteringExpr string, meteringCtx map[string]any, auth *proxy.AuthContext) float64 {
+	if s.transformService == nil || meteringExpr == "" {
+		return 1.0
 	}
 
+	meteringCtx["userID"] = ""
+	meteringCtx["planID"] = ""
+	meteringCtx["keyID"] = ""
 	if auth != nil {
 		meteringCtx["userID"] = auth.UserID
 		meteringCtx["planID"] = auth.PlanID
@@ -1129,3 +2107,55 @@ outer:
 	}
 	return false
 }
+
+// groupPlan returns the user a key is metered as, with a group key's group
+// applied: the group's plan if it has one, and the group's creation as the
+// quota window anchor. Keys of suspended or deleted groups are rejected.
+func (s *ProxyService) groupPlan(ctx context.Context, k key.Key, user ports.User) (ports.User, *proxy.ErrorResponse) {
+	if k.GroupID == "" || s.groups == nil {
+		return user, nil
+	}
+
+	g, err := s.groups.Get(ctx, k.GroupID)
+	if err != nil {
+		return user, &proxy.ErrInvalidKey
+	}
+	if !g.IsActive() {
+		return user, &proxy.ErrorResponse{
+			Status:  403,
+			Code:    "group_suspended",
+			Message: "Group is suspended",
+		}
+	}
+
+	if g.PlanID != "" {
+		user.PlanID = g.PlanID
+	}
+	user.CreatedAt = g.CreatedAt
+	return user, nil
+}
+
+// notifyQuotaExceeded publishes a quota.exceeded event the first time a user
+// is rejected in a quota period. Dispatch runs in the background so the
+// rejection is not delayed.
+func (s *ProxyService) notifyQuotaExceeded(userID, planID string, periodStart time.Time, result quota.CheckResult) {
+	if s.events == nil {
+		return
+	}
+	if last, loaded := s.quotaNotified.Swap(userID, periodStart); loaded && last.(time.Time).Equal(periodStart) {
+		return
+	}
+
+	data := map[string]interface{}{
+		"user_id":      userID,
+		"plan_id":      planID,
+		"usage":        result.CurrentUsage,
+		"limit":        result.Limit,
+		"period_start": periodStart.Format(time.RFC3339),
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = s.events.DispatchEvent(ctx, webhook.EventQuotaExceeded, userID, data)
+	}()
+}
@@ -2,17 +2,25 @@ package app_test
 
 import (
 	"context"
+	"errors"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/artpar/apigate/adapters/auth"
 	"github.com/artpar/apigate/adapters/clock"
 	"github.com/artpar/apigate/adapters/memory"
 	"github.com/artpar/apigate/app"
+	domainAuth "github.com/artpar/apigate/domain/auth"
+	"github.com/artpar/apigate/domain/group"
 	"github.com/artpar/apigate/domain/key"
 	"github.com/artpar/apigate/domain/plan"
 	"github.com/artpar/apigate/domain/proxy"
+	"github.com/artpar/apigate/domain/quota"
 	"github.com/artpar/apigate/domain/route"
 	"github.com/artpar/apigate/domain/usage"
+	"github.com/artpar/apigate/domain/webhook"
 	"github.com/artpar/apigate/ports"
 	"github.com/rs/zerolog"
 	"golang.org/x/crypto/bcrypt"
@@ -961,3 +969,654 @@ func TestProxyService_Handle_HashMismatch(t *testing.T) {
 		t.Fatal("expected error for hash mismatch")
 	}
 }
+
+// limitUpstream enforces req.MaxResponseBody like the HTTP upstream client does.
+type limitUpstream struct {
+	body    []byte
+	lastMax int64
+}
+
+func (u *limitUpstream) Forward(ctx context.Context, req proxy.Request) (proxy.Response, error) {
+	u.lastMax = req.MaxResponseBody
+	if req.MaxResponseBody > 0 && int64(len(u.body)) > req.MaxResponseBody {
+		return proxy.Response{}, proxy.ErrResponseBodyTooLarge
+	}
+	return proxy.Response{Status: 200, Body: u.body}, nil
+}
+
+func (u *limitUpstream) HealthCheck(ctx context.Context) error { return nil }
+
+func (u *limitUpstream) ForwardTo(ctx context.Context, req proxy.Request, upstream *route.Upstream) (proxy.Response, error) {
+	return u.Forward(ctx, req)
+}
+
+func TestProxyService_BodyLimits(t *testing.T) {
+	ctx := context.Background()
+	upstream := &limitUpstream{body: []byte(`{"data":"0123456789"}`)}
+
+	svc := app.NewProxyService(app.ProxyDeps{
+		Keys:      memory.NewKeyStore(),
+		Users:     memory.NewUserStore(),
+		RateLimit: memory.NewRateLimitStore(),
+		Usage:     &testUsageRecorder{},
+		Upstream:  upstream,
+		Clock:     clock.NewFake(baseTime),
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:       "ak_",
+		MaxRequestBody:  1024,
+		MaxResponseBody: 1024,
+	})
+
+	routes := []route.Route{
+		{ID: "tiny", Name: "tiny", PathPattern: "/tiny", MatchType: route.MatchExact,
+			MaxRequestBody: 4, MaxResponseBody: 4, Enabled: true},
+		{ID: "public", Name: "public", PathPattern: "/*", MatchType: route.MatchPrefix, Enabled: true},
+	}
+	routeService := app.NewRouteService(&mockRouteStore{routes: routes}, &mockUpstreamStore{},
+		clock.NewFake(baseTime), zerolog.Nop(), app.RouteServiceConfig{})
+	if err := routeService.Reload(ctx); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	svc.SetRouteService(routeService)
+
+	if got := svc.RequestBodyLimit(proxy.Request{Method: "POST", Path: "/tiny"}); got != 4 {
+		t.Errorf("RequestBodyLimit(/tiny) = %d, want 4", got)
+	}
+	if got := svc.RequestBodyLimit(proxy.Request{Method: "POST", Path: "/other"}); got != 1024 {
+		t.Errorf("RequestBodyLimit(/other) = %d, want 1024", got)
+	}
+
+	result := svc.Handle(ctx, proxy.Request{Method: "GET", Path: "/other"})
+	if result.Error != nil {
+		t.Fatalf("unexpected error: %+v", result.Error)
+	}
+	if upstream.lastMax != 1024 {
+		t.Errorf("MaxResponseBody = %d, want global 1024", upstream.lastMax)
+	}
+
+	result = svc.Handle(ctx, proxy.Request{Method: "GET", Path: "/tiny"})
+	if result.Error == nil || result.Error.Code != proxy.ErrResponseTooLarge.Code {
+		t.Fatalf("error = %+v, want %s", result.Error, proxy.ErrResponseTooLarge.Code)
+	}
+	if upstream.lastMax != 4 {
+		t.Errorf("MaxResponseBody = %d, want route limit 4", upstream.lastMax)
+	}
+}
+
+// testEventDispatcher records dispatched events.
+type testEventDispatcher struct {
+	mu     sync.Mutex
+	events []webhook.EventType
+}
+
+func (d *testEventDispatcher) DispatchEvent(ctx context.Context, eventType webhook.EventType, userID string, data map[string]interface{}) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.events = append(d.events, eventType)
+	return nil
+}
+
+func (d *testEventDispatcher) count() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.events)
+}
+
+func TestProxyService_QuotaExceededEventOncePerPeriod(t *testing.T) {
+	ctx := context.Background()
+	quotaStore := memory.NewQuotaStore(memory.QuotaStoreConfig{})
+	defer quotaStore.Close()
+
+	stores := &testStores{keys: memory.NewKeyStore(), users: memory.NewUserStore(), rateLimit: memory.NewRateLimitStore(), usage: &testUsageRecorder{}}
+	svc := app.NewProxyService(app.ProxyDeps{
+		Keys:      stores.keys,
+		Users:     stores.users,
+		RateLimit: stores.rateLimit,
+		Quota:     quotaStore,
+		Usage:     stores.usage,
+		Upstream:  &testUpstream{},
+		Clock:     clock.NewFake(baseTime),
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100,
+		RateWindow: 60,
+		Plans:      []plan.Plan{{ID: "tiny", Name: "Tiny", RateLimitPerMinute: 100, RequestsPerMonth: 1}},
+	})
+	dispatcher := &testEventDispatcher{}
+	svc.SetEventDispatcher(dispatcher)
+
+	rawKey := "ak_0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	keyHash, _ := bcrypt.GenerateFromPassword([]byte(rawKey), bcrypt.MinCost)
+	stores.keys.Create(ctx, key.Key{ID: "key-1", UserID: "user-1", Hash: keyHash, Prefix: rawKey[:12], CreatedAt: baseTime.Add(-time.Hour)})
+	stores.users.Create(ctx, ports.User{ID: "user-1", Email: "test@example.com", PlanID: "tiny", Status: "active", CreatedAt: baseTime.Add(-time.Hour)})
+
+	req := proxy.Request{APIKey: rawKey, Method: "GET", Path: "/api/data"}
+	if result := svc.Handle(ctx, req); result.Error != nil {
+		t.Fatalf("first request: unexpected error %v", result.Error)
+	}
+	for i := 0; i < 3; i++ {
+		if result := svc.Handle(ctx, req); result.Error == nil || result.Error.Code != proxy.ErrQuotaExceeded.Code {
+			t.Fatalf("request %d: expected quota exceeded, got %v", i+2, result.Error)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for dispatcher.count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := dispatcher.count(); n != 1 {
+		t.Errorf("Expected 1 quota.exceeded event, got %d", n)
+	}
+}
+
+func TestProxyService_AllowOverageServesPastQuota(t *testing.T) {
+	ctx := context.Background()
+	quotaStore := memory.NewQuotaStore(memory.QuotaStoreConfig{})
+	defer quotaStore.Close()
+
+	stores := &testStores{keys: memory.NewKeyStore(), users: memory.NewUserStore(), rateLimit: memory.NewRateLimitStore(), usage: &testUsageRecorder{}}
+	svc := app.NewProxyService(app.ProxyDeps{
+		Keys:      stores.keys,
+		Users:     stores.users,
+		RateLimit: stores.rateLimit,
+		Quota:     quotaStore,
+		Usage:     stores.usage,
+		Upstream:  &testUpstream{},
+		Clock:     clock.NewFake(baseTime),
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100,
+		RateWindow: 60,
+		Plans: []plan.Plan{{
+			ID: "payg", Name: "Pay as you go", RateLimitPerMinute: 100, RequestsPerMonth: 2,
+			OveragePrice: 10, AllowOverage: true,
+		}},
+	})
+
+	rawKey := "ak_0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	keyHash, _ := bcrypt.GenerateFromPassword([]byte(rawKey), bcrypt.MinCost)
+	stores.keys.Create(ctx, key.Key{ID: "key-1", UserID: "user-1", Hash: keyHash, Prefix: rawKey[:12], CreatedAt: baseTime.Add(-time.Hour)})
+	stores.users.Create(ctx, ports.User{ID: "user-1", Email: "test@example.com", PlanID: "payg", Status: "active", CreatedAt: baseTime.Add(-time.Hour)})
+
+	req := proxy.Request{APIKey: rawKey, Method: "GET", Path: "/api/data"}
+	for i := 1; i <= 5; i++ {
+		result := svc.Handle(ctx, req)
+		if result.Error != nil {
+			t.Fatalf("request %d: unexpected error %v", i, result.Error)
+		}
+		wantOverage := ""
+		if i > 2 {
+			wantOverage = strconv.Itoa(i - 2)
+		}
+		if got := result.Response.Headers["X-Quota-Overage"]; got != wantOverage {
+			t.Errorf("request %d: X-Quota-Overage = %q, want %q", i, got, wantOverage)
+		}
+	}
+}
+
+func TestProxyService_RouteCostMultiplier(t *testing.T) {
+	ctx := context.Background()
+	quotaStore := memory.NewQuotaStore(memory.QuotaStoreConfig{})
+	defer quotaStore.Close()
+
+	stores := &testStores{keys: memory.NewKeyStore(), users: memory.NewUserStore(), rateLimit: memory.NewRateLimitStore(), usage: &testUsageRecorder{}}
+	svc := app.NewProxyService(app.ProxyDeps{
+		Keys:      stores.keys,
+		Users:     stores.users,
+		RateLimit: stores.rateLimit,
+		Quota:     quotaStore,
+		Usage:     stores.usage,
+		Upstream:  &testUpstream{},
+		Clock:     clock.NewFake(baseTime),
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100,
+		RateWindow: 60,
+		Plans: []plan.Plan{{
+			ID: "units", Name: "Units", RateLimitPerMinute: 100, RequestsPerMonth: 100,
+			MeterType: plan.MeterTypeComputeUnits,
+		}},
+	})
+
+	routes := []route.Route{
+		{ID: "reports", Name: "Reports", PathPattern: "/reports/*", MatchType: route.MatchPrefix, AuthRequired: true, Enabled: true, CostMultiplier: 5},
+		{
+			ID: "search", Name: "Search", PathPattern: "/search", MatchType: route.MatchExact, AuthRequired: true, Enabled: true,
+			CostMultiplier: 2, MethodCostMultipliers: map[string]float64{"POST": 3}, MethodOverride: "GET",
+		},
+	}
+	routeService := app.NewRouteService(&mockRouteStore{routes: routes}, &mockUpstreamStore{}, clock.NewFake(baseTime), zerolog.Nop(), app.RouteServiceConfig{})
+	if err := routeService.Reload(ctx); err != nil {
+		t.Fatalf("reload routes: %v", err)
+	}
+	svc.SetRouteService(routeService)
+
+	rawKey := "ak_0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	keyHash, _ := bcrypt.GenerateFromPassword([]byte(rawKey), bcrypt.MinCost)
+	stores.keys.Create(ctx, key.Key{ID: "key-1", UserID: "user-1", Hash: keyHash, Prefix: rawKey[:12], CreatedAt: baseTime.Add(-time.Hour)})
+	stores.users.Create(ctx, ports.User{ID: "user-1", Email: "test@example.com", PlanID: "units", Status: "active", CreatedAt: baseTime.Add(-time.Hour)})
+	periodStart, _ := quota.WindowBounds(quota.Period(""), baseTime, baseTime.Add(-time.Hour))
+
+	tests := []struct {
+		method, path string
+		wantCost     float64
+	}{
+		{"GET", "/reports/daily", 5},
+		{"GET", "/search", 2},
+		{"POST", "/search", 3}, // per-method override, matched before the method override
+		{"GET", "/other", 1},
+	}
+	var total float64
+	for _, tt := range tests {
+		before, _ := quotaStore.Get(ctx, "user-1", periodStart)
+		result := svc.Handle(ctx, proxy.Request{APIKey: rawKey, Method: tt.method, Path: tt.path})
+		if result.Error != nil {
+			t.Fatalf("%s %s: unexpected error %v", tt.method, tt.path, result.Error)
+		}
+		after, _ := quotaStore.Get(ctx, "user-1", periodStart)
+		if got := after.ComputeUnits - before.ComputeUnits; got != tt.wantCost {
+			t.Errorf("%s %s: quota decremented by %v, want %v", tt.method, tt.path, got, tt.wantCost)
+		}
+		if got := after.RequestCount - before.RequestCount; got != 1 {
+			t.Errorf("%s %s: request count increased by %d, want 1", tt.method, tt.path, got)
+		}
+		events := stores.usage.Drain()
+		if len(events) != 1 || events[0].CostMultiplier != tt.wantCost {
+			t.Errorf("%s %s: usage events %+v, want one with cost %v", tt.method, tt.path, events, tt.wantCost)
+		}
+		total += tt.wantCost
+	}
+	if state, _ := quotaStore.Get(ctx, "user-1", periodStart); state.ComputeUnits != total {
+		t.Errorf("total compute units = %v, want %v", state.ComputeUnits, total)
+	}
+}
+
+// testGroupStore serves groups by ID for group key tests.
+type testGroupStore struct {
+	ports.GroupStore
+	groups map[string]group.Group
+}
+
+func (s *testGroupStore) Get(ctx context.Context, id string) (group.Group, error) {
+	g, ok := s.groups[id]
+	if !ok {
+		return group.Group{}, errors.New("not found")
+	}
+	return g, nil
+}
+
+func TestProxyService_GroupKeysShareGroupQuota(t *testing.T) {
+	ctx := context.Background()
+	quotaStore := memory.NewQuotaStore(memory.QuotaStoreConfig{})
+	defer quotaStore.Close()
+
+	stores := &testStores{keys: memory.NewKeyStore(), users: memory.NewUserStore(), rateLimit: memory.NewRateLimitStore(), usage: &testUsageRecorder{}}
+	svc := app.NewProxyService(app.ProxyDeps{
+		Keys:      stores.keys,
+		Users:     stores.users,
+		RateLimit: stores.rateLimit,
+		Quota:     quotaStore,
+		Usage:     stores.usage,
+		Upstream:  &testUpstream{},
+		Clock:     clock.NewFake(baseTime),
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100,
+		RateWindow: 60,
+		Plans: []plan.Plan{
+			{ID: "free", Name: "Free", RateLimitPerMinute: 100, RequestsPerMonth: 1000},
+			{ID: "team", Name: "Team", RateLimitPerMinute: 100, RequestsPerMonth: 2},
+		},
+	})
+	groups := &testGroupStore{groups: map[string]group.Group{
+		"grp-1": {ID: "grp-1", PlanID: "team", Status: group.StatusActive, CreatedAt: baseTime.Add(-time.Hour)},
+	}}
+	svc.SetGroupStore(groups)
+
+	// Two members each created a key for the group
+	var rawKeys []string
+	for i, userID := range []string{"user-1", "user-2"} {
+		rawKey := "ak_" + strconv.Itoa(i) + "123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+		keyHash, _ := bcrypt.GenerateFromPassword([]byte(rawKey), bcrypt.MinCost)
+		stores.keys.Create(ctx, key.Key{ID: "key-" + userID, UserID: userID, GroupID: "grp-1", Hash: keyHash, Prefix: rawKey[:12], CreatedAt: baseTime.Add(-time.Hour)})
+		stores.users.Create(ctx, ports.User{ID: userID, Email: userID + "@example.com", PlanID: "free", Status: "active", CreatedAt: baseTime.Add(-time.Hour)})
+		rawKeys = append(rawKeys, rawKey)
+	}
+
+	// The group plan allows 2 requests across both keys
+	for i, rawKey := range []string{rawKeys[0], rawKeys[1]} {
+		result := svc.Handle(ctx, proxy.Request{APIKey: rawKey, Method: "GET", Path: "/api/data"})
+		if result.Error != nil {
+			t.Fatalf("request %d: unexpected error %v", i+1, result.Error)
+		}
+		if result.Auth.PlanID != "team" {
+			t.Errorf("request %d: plan = %q, want team", i+1, result.Auth.PlanID)
+		}
+	}
+	result := svc.Handle(ctx, proxy.Request{APIKey: rawKeys[0], Method: "GET", Path: "/api/data"})
+	if result.Error == nil || result.Error.Code != proxy.ErrQuotaExceeded.Code {
+		t.Fatalf("third request: expected quota exceeded, got %v", result.Error)
+	}
+
+	for _, e := range stores.usage.Drain() {
+		if e.UserID != "grp-1" {
+			t.Errorf("usage event for key %s recorded against %q, want grp-1", e.KeyID, e.UserID)
+		}
+	}
+
+	// Keys of a suspended group stop working
+	groups.groups["grp-1"] = groups.groups["grp-1"].WithStatus(group.StatusSuspended)
+	result = svc.Handle(ctx, proxy.Request{APIKey: rawKeys[1], Method: "GET", Path: "/api/data"})
+	if result.Error == nil || result.Error.Code != "group_suspended" {
+		t.Fatalf("suspended group: expected group_suspended, got %v", result.Error)
+	}
+}
+
+func TestProxyService_KeyUseTracker(t *testing.T) {
+	ctx := context.Background()
+	stores := &testStores{keys: memory.NewKeyStore(), users: memory.NewUserStore(), rateLimit: memory.NewRateLimitStore(), usage: &testUsageRecorder{}}
+	svc := app.NewProxyService(app.ProxyDeps{
+		Keys:      stores.keys,
+		Users:     stores.users,
+		RateLimit: stores.rateLimit,
+		Usage:     stores.usage,
+		Upstream:  &testUpstream{},
+		Clock:     clock.NewFake(baseTime),
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100,
+		RateWindow: 60,
+		Plans:      []plan.Plan{{ID: "free", Name: "Free", RateLimitPerMinute: 100}},
+	})
+	tracker := app.NewKeyUseTracker(stores.keys, zerolog.Nop(), app.KeyUseTrackerConfig{FlushInterval: time.Hour})
+	svc.SetKeyUseTracker(tracker)
+
+	stores.users.Create(ctx, ports.User{ID: "user-1", Email: "user-1@example.com", PlanID: "free", Status: "active", CreatedAt: baseTime.Add(-time.Hour)})
+	rawKey := "ak_0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	keyHash, _ := bcrypt.GenerateFromPassword([]byte(rawKey), bcrypt.MinCost)
+	stores.keys.Create(ctx, key.Key{ID: "key-1", UserID: "user-1", Hash: keyHash, Prefix: rawKey[:12], CreatedAt: baseTime.Add(-time.Hour)})
+
+	for i := 0; i < 5; i++ {
+		if result := svc.Handle(ctx, proxy.Request{APIKey: rawKey, Method: "GET", Path: "/api/data", RemoteIP: "203.0.113.5"}); result.Error != nil {
+			t.Fatalf("request %d: unexpected error %v", i, result.Error)
+		}
+	}
+
+	// Requests only note the use; the store is written on flush
+	keys, _ := stores.keys.ListByUser(ctx, "user-1")
+	if keys[0].LastUsed != nil {
+		t.Fatal("last use written on the request path")
+	}
+	if err := tracker.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	keys, _ = stores.keys.ListByUser(ctx, "user-1")
+	if keys[0].LastUsed == nil || !keys[0].LastUsed.Equal(baseTime) || keys[0].LastUsedIP != "203.0.113.5" {
+		t.Errorf("last use = %v from %q, want %v from 203.0.113.5", keys[0].LastUsed, keys[0].LastUsedIP, baseTime)
+	}
+}
+
+// testSessionStore implements ports.SessionStore for testing.
+type testSessionStore struct {
+	sessions map[string]domainAuth.Session
+}
+
+func (s *testSessionStore) Create(ctx context.Context, session domainAuth.Session) error {
+	s.sessions[session.ID] = session
+	return nil
+}
+
+func (s *testSessionStore) Get(ctx context.Context, id string) (domainAuth.Session, error) {
+	if session, ok := s.sessions[id]; ok {
+		return session, nil
+	}
+	return domainAuth.Session{}, errors.New("not found")
+}
+
+func (s *testSessionStore) ListByUser(ctx context.Context, userID string) ([]domainAuth.Session, error) {
+	return nil, nil
+}
+
+func (s *testSessionStore) Delete(ctx context.Context, id string) error {
+	delete(s.sessions, id)
+	return nil
+}
+
+func (s *testSessionStore) DeleteByUser(ctx context.Context, userID string) error {
+	for id, session := range s.sessions {
+		if session.UserID == userID {
+			delete(s.sessions, id)
+		}
+	}
+	return nil
+}
+
+func (s *testSessionStore) DeleteExpired(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+// newSessionTokenProxy returns a proxy that accepts portal session tokens,
+// with an active user "user-1" on the free plan.
+func newSessionTokenProxy(t *testing.T) (*app.ProxyService, *auth.TokenService, *testSessionStore) {
+	t.Helper()
+	svc, stores := newTestProxyService()
+	tokens := auth.NewTokenService("test-secret", time.Hour)
+	sessions := &testSessionStore{sessions: make(map[string]domainAuth.Session)}
+	svc.SetTokenService(tokens)
+	svc.SetSessionStore(sessions)
+	stores.users.Create(context.Background(), ports.User{ID: "user-1", Email: "user-1@example.com", PlanID: "free", Status: "active", CreatedAt: baseTime.Add(-time.Hour)})
+	return svc, tokens, sessions
+}
+
+func TestProxyService_SessionToken_Revoked(t *testing.T) {
+	ctx := context.Background()
+	svc, tokens, sessions := newSessionTokenProxy(t)
+
+	session := domainAuth.GenerateSession("user-1", "user-1@example.com", "203.0.113.5", "test", time.Hour)
+	sessions.Create(ctx, session)
+	token, _, err := tokens.GenerateSessionToken("user-1", "user-1@example.com", "user", session.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateSessionToken: %v", err)
+	}
+
+	req := proxy.Request{APIKey: token, Method: "GET", Path: "/api/data"}
+	if result := svc.Handle(ctx, req); result.Error != nil {
+		t.Fatalf("live session: unexpected error %v", result.Error)
+	}
+
+	// Log out everywhere
+	sessions.DeleteByUser(ctx, "user-1")
+
+	result := svc.Handle(ctx, req)
+	if result.Error == nil || result.Error.Status != 401 {
+		t.Fatalf("revoked session: error = %v, want 401", result.Error)
+	}
+	if streamed := svc.HandleStreaming(ctx, req, nil); streamed.Error == nil || streamed.Error.Status != 401 {
+		t.Errorf("revoked session (streaming): error = %v, want 401", streamed.Error)
+	}
+}
+
+func TestProxyService_ImpersonationToken_Rejected(t *testing.T) {
+	ctx := context.Background()
+	svc, tokens, _ := newSessionTokenProxy(t)
+
+	token, _, err := tokens.GenerateImpersonationToken("user-1", "user-1@example.com", "admin-1", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateImpersonationToken: %v", err)
+	}
+
+	result := svc.Handle(ctx, proxy.Request{APIKey: token, Method: "GET", Path: "/api/data"})
+	if result.Error == nil || result.Error.Status != 401 {
+		t.Errorf("impersonation token: error = %v, want 401", result.Error)
+	}
+}
+
+func TestProxyService_SessionToken_ScopedRoute(t *testing.T) {
+	ctx := context.Background()
+	svc, tokens, sessions := newSessionTokenProxy(t)
+
+	routes := []route.Route{
+		{ID: "scoped", Name: "Orders", PathPattern: "/orders/*", MatchType: route.MatchPrefix, UpstreamID: "upstream-1", RequiredScopes: []string{"orders:read"}, AuthRequired: true, Enabled: true, Priority: 10},
+		{ID: "open", Name: "API", PathPattern: "/api/*", MatchType: route.MatchPrefix, UpstreamID: "upstream-1", AuthRequired: true, Enabled: true},
+	}
+	upstreams := []route.Upstream{{ID: "upstream-1", Name: "Backend", BaseURL: "https://backend.example.com", Enabled: true}}
+	routeService := app.NewRouteService(&mockRouteStore{routes: routes}, &mockUpstreamStore{upstreams: upstreams}, clock.NewFake(baseTime), zerolog.Nop(), app.RouteServiceConfig{})
+	_ = routeService.Start(ctx)
+	defer routeService.Stop()
+	svc.SetRouteService(routeService)
+
+	session := domainAuth.GenerateSession("user-1", "user-1@example.com", "203.0.113.5", "test", time.Hour)
+	sessions.Create(ctx, session)
+	token, _, _ := tokens.GenerateSessionToken("user-1", "user-1@example.com", "user", session.ID, time.Hour)
+
+	// A session token has no scopes, so it can't call a route that requires one
+	result := svc.Handle(ctx, proxy.Request{APIKey: token, Method: "GET", Path: "/orders/1"})
+	if result.Error == nil || result.Error.Code != proxy.ErrInsufficientScope.Code {
+		t.Errorf("scoped route: error = %v, want %s", result.Error, proxy.ErrInsufficientScope.Code)
+	}
+	if result := svc.Handle(ctx, proxy.Request{APIKey: token, Method: "GET", Path: "/api/data"}); result.Error != nil {
+		t.Errorf("unscoped route: unexpected error %v", result.Error)
+	}
+}
+
+func TestProxyService_KeyMonthlyQuota(t *testing.T) {
+	tests := []struct {
+		name         string
+		planQuota    int64
+		keyQuota     int64
+		wantRequests int    // requests allowed before the quota is exceeded
+		wantLimit    string // X-Quota-Limit reported
+	}{
+		{"key quota below plan", 10, 2, 2, "2"},
+		{"key quota above plan", 3, 10, 3, "3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			quotaStore := memory.NewQuotaStore(memory.QuotaStoreConfig{})
+			defer quotaStore.Close()
+
+			stores := &testStores{keys: memory.NewKeyStore(), users: memory.NewUserStore(), rateLimit: memory.NewRateLimitStore(), usage: &testUsageRecorder{}}
+			svc := app.NewProxyService(app.ProxyDeps{
+				Keys:      stores.keys,
+				Users:     stores.users,
+				RateLimit: stores.rateLimit,
+				Quota:     quotaStore,
+				Usage:     stores.usage,
+				Upstream:  &testUpstream{},
+				Clock:     clock.NewFake(baseTime),
+				IDGen:     &testIDGen{},
+			}, app.ProxyConfig{
+				KeyPrefix:  "ak_",
+				RateBurst:  100,
+				RateWindow: 60,
+				Plans:      []plan.Plan{{ID: "free", Name: "Free", RateLimitPerMinute: 100, RequestsPerMonth: tt.planQuota}},
+			})
+
+			stores.users.Create(ctx, ports.User{ID: "user-1", Email: "user-1@example.com", PlanID: "free", Status: "active", CreatedAt: baseTime.Add(-time.Hour)})
+			ciKey := "ak_0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+			otherKey := "ak_1123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+			for i, rawKey := range []string{ciKey, otherKey} {
+				keyHash, _ := bcrypt.GenerateFromPassword([]byte(rawKey), bcrypt.MinCost)
+				k := key.Key{ID: "key-" + strconv.Itoa(i), UserID: "user-1", Hash: keyHash, Prefix: rawKey[:12], CreatedAt: baseTime.Add(-time.Hour)}
+				if rawKey == ciKey {
+					k.MonthlyQuota = tt.keyQuota
+				}
+				stores.keys.Create(ctx, k)
+			}
+
+			for i := 1; i <= tt.wantRequests; i++ {
+				result := svc.Handle(ctx, proxy.Request{APIKey: ciKey, Method: "GET", Path: "/api/data"})
+				if result.Error != nil {
+					t.Fatalf("request %d: unexpected error %v", i, result.Error)
+				}
+				if got := result.Response.Headers["X-Quota-Limit"]; got != tt.wantLimit {
+					t.Errorf("request %d: X-Quota-Limit = %q, want %q", i, got, tt.wantLimit)
+				}
+			}
+
+			result := svc.Handle(ctx, proxy.Request{APIKey: ciKey, Method: "GET", Path: "/api/data"})
+			if result.Error == nil || result.Error.Code != proxy.ErrQuotaExceeded.Code {
+				t.Fatalf("request %d: expected quota exceeded, got %v", tt.wantRequests+1, result.Error)
+			}
+			if got := result.Response.Headers["X-Quota-Limit"]; got != tt.wantLimit {
+				t.Errorf("rejected: X-Quota-Limit = %q, want %q", got, tt.wantLimit)
+			}
+
+			// The key quota caps only its key; other keys share the plan quota
+			result = svc.Handle(ctx, proxy.Request{APIKey: otherKey, Method: "GET", Path: "/api/data"})
+			planLeft := int64(tt.wantRequests) < tt.planQuota
+			if planLeft && result.Error != nil {
+				t.Errorf("other key: unexpected error %v", result.Error)
+			}
+			if !planLeft && (result.Error == nil || result.Error.Code != proxy.ErrQuotaExceeded.Code) {
+				t.Errorf("other key: expected plan quota exceeded, got %v", result.Error)
+			}
+		})
+	}
+}
+
+func TestProxyService_KeyMonthlyQuota_SetMidPeriod(t *testing.T) {
+	ctx := context.Background()
+	quotaStore := memory.NewQuotaStore(memory.QuotaStoreConfig{})
+	defer quotaStore.Close()
+
+	stores := &testStores{keys: memory.NewKeyStore(), users: memory.NewUserStore(), rateLimit: memory.NewRateLimitStore(), usage: &testUsageRecorder{}}
+	svc := app.NewProxyService(app.ProxyDeps{
+		Keys:      stores.keys,
+		Users:     stores.users,
+		RateLimit: stores.rateLimit,
+		Quota:     quotaStore,
+		Usage:     stores.usage,
+		Upstream:  &testUpstream{},
+		Clock:     clock.NewFake(baseTime),
+		IDGen:     &testIDGen{},
+	}, app.ProxyConfig{
+		KeyPrefix:  "ak_",
+		RateBurst:  100,
+		RateWindow: 60,
+		Plans:      []plan.Plan{{ID: "free", Name: "Free", RateLimitPerMinute: 100, RequestsPerMonth: 100}},
+	})
+
+	stores.users.Create(ctx, ports.User{ID: "user-1", Email: "user-1@example.com", PlanID: "free", Status: "active", CreatedAt: baseTime.Add(-time.Hour)})
+	rawKey := "ak_0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	keyHash, _ := bcrypt.GenerateFromPassword([]byte(rawKey), bcrypt.MinCost)
+	stores.keys.Create(ctx, key.Key{ID: "key-0", UserID: "user-1", Hash: keyHash, Prefix: rawKey[:12], CreatedAt: baseTime.Add(-time.Hour)})
+	req := proxy.Request{APIKey: rawKey, Method: "GET", Path: "/api/data"}
+
+	// Use the key before it has a quota of its own, buffered and streamed
+	for i := 1; i <= 2; i++ {
+		if result := svc.Handle(ctx, req); result.Error != nil {
+			t.Fatalf("request %d: unexpected error %v", i, result.Error)
+		}
+	}
+	streamed := svc.HandleStreaming(ctx, req, nil)
+	if streamed.Error != nil {
+		t.Fatalf("stream: unexpected error %v", streamed.Error)
+	}
+	streamed.Release()
+	svc.RecordStreamingUsage(streamed.StreamingResponse, 200, 0, 100, 10, 1, "", "")
+
+	// The quota counts the usage from before it was set
+	stores.keys.SetMonthlyQuota(ctx, "key-0", 3)
+
+	result := svc.Handle(ctx, req)
+	if result.Error == nil || result.Error.Code != proxy.ErrQuotaExceeded.Code {
+		t.Fatalf("after quota: expected quota exceeded, got %v", result.Error)
+	}
+	streamed = svc.HandleStreaming(ctx, req, nil)
+	if streamed.Error == nil || streamed.Error.Code != proxy.ErrQuotaExceeded.Code {
+		t.Fatalf("after quota (streaming): expected quota exceeded, got %v", streamed.Error)
+	}
+	if got := streamed.Headers["X-Quota-Limit"]; got != "3" {
+		t.Errorf("streaming rejection: X-Quota-Limit = %q, want %q", got, "3")
+	}
+}
@@ -0,0 +1,306 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/artpar/apigate/domain/quota"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+)
+
+// QuotaWarningNotifierConfig contains configuration for QuotaWarningNotifier.
+type QuotaWarningNotifierConfig struct {
+	Thresholds    []int         // Usage percentages that trigger a warning (default: 80, 100)
+	CheckInterval time.Duration // How often usage is scanned (default: 10m)
+	AppName       string        // Application name used in the email (default: APIGate)
+	BaseURL       string        // Portal base URL for the link to the usage page
+}
+
+// QuotaWarningNotifier emails users as their usage reaches each warning
+// threshold of their plan's quota. Each threshold is sent at most once per
+// quota period; sent warnings are recorded in the warning store.
+type QuotaWarningNotifier struct {
+	users    ports.UserStore
+	plans    ports.PlanStore
+	usage    ports.UsageStore
+	warnings ports.QuotaWarningStore
+	email    ports.EmailSender
+	clock    ports.Clock
+	logger   zerolog.Logger
+	cfg      QuotaWarningNotifierConfig
+
+	stop chan struct{}
+}
+
+// quotaWarningPageSize is how many users are read per page while scanning.
+const quotaWarningPageSize = 100
+
+// NewQuotaWarningNotifier creates a new quota warning notifier.
+func NewQuotaWarningNotifier(
+	users ports.UserStore,
+	plans ports.PlanStore,
+	usage ports.UsageStore,
+	warnings ports.QuotaWarningStore,
+	email ports.EmailSender,
+	clock ports.Clock,
+	logger zerolog.Logger,
+	cfg QuotaWarningNotifierConfig,
+) *QuotaWarningNotifier {
+	if len(cfg.Thresholds) == 0 {
+		cfg.Thresholds = []int{80, 100}
+	}
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = 10 * time.Minute
+	}
+	if cfg.AppName == "" {
+		cfg.AppName = "APIGate"
+	}
+
+	return &QuotaWarningNotifier{
+		users:    users,
+		plans:    plans,
+		usage:    usage,
+		warnings: warnings,
+		email:    email,
+		clock:    clock,
+		logger:   logger.With().Str("service", "quota_warning").Logger(),
+		cfg:      cfg,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start scans usage in the background, once immediately and then every
+// check interval.
+func (n *QuotaWarningNotifier) Start(ctx context.Context) {
+	go func() {
+		n.CheckUsage(ctx)
+		n.loop()
+	}()
+}
+
+// Stop stops background scanning.
+func (n *QuotaWarningNotifier) Stop() {
+	close(n.stop)
+}
+
+func (n *QuotaWarningNotifier) loop() {
+	ticker := time.NewTicker(n.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-n.stop:
+			return
+		case <-ticker.C:
+			n.CheckUsage(context.Background())
+		}
+	}
+}
+
+// CheckUsage sends a warning to every active user whose usage in the current
+// quota period has reached a threshold not yet warned about, and returns the
+// number of emails sent. A user past several new thresholds gets one email for
+// the highest. Users whose email fails are retried on the next scan.
+func (n *QuotaWarningNotifier) CheckUsage(ctx context.Context) int {
+	now := n.clock.Now()
+	plans, err := n.plans.List(ctx)
+	if err != nil {
+		n.logger.Error().Err(err).Msg("failed to list plans")
+		return 0
+	}
+	byID := make(map[string]ports.Plan, len(plans))
+	for _, p := range plans {
+		byID[p.ID] = p
+	}
+
+	sent := 0
+	for offset := 0; ; offset += quotaWarningPageSize {
+		users, err := n.users.List(ctx, quotaWarningPageSize, offset)
+		if err != nil {
+			n.logger.Error().Err(err).Msg("failed to list users")
+			break
+		}
+		for _, user := range users {
+			p, ok := byID[user.PlanID]
+			if !ok || user.Status != "active" || user.Email == "" {
+				continue
+			}
+			if n.checkUser(ctx, user, p, now) {
+				sent++
+			}
+		}
+		if len(users) < quotaWarningPageSize {
+			break
+		}
+	}
+
+	if sent > 0 {
+		n.logger.Info().Int("sent", sent).Msg("quota warnings sent")
+	}
+	return sent
+}
+
+// checkUser sends the user's due warning, if any, and reports whether an
+// email was sent.
+func (n *QuotaWarningNotifier) checkUser(ctx context.Context, user ports.User, p ports.Plan, now time.Time) bool {
+	if p.RequestsPerMonth <= 0 {
+		return false
+	}
+	periodStart, periodEnd := quota.WindowBounds(quota.Period(p.QuotaPeriod), now, user.CreatedAt)
+
+	summary, err := n.usage.GetSummary(ctx, user.ID, periodStart, periodEnd)
+	if err != nil {
+		n.logger.Warn().Err(err).Str("user_id", user.ID).Msg("failed to read usage for quota warning")
+		return false
+	}
+	used := summary.RequestCount
+	if quota.ConfigFromPlan(p).MeterType == quota.MeterTypeComputeUnits {
+		used = int64(summary.ComputeUnits)
+	}
+
+	sentBefore, err := n.warnings.ListQuotaWarnings(ctx, user.ID, periodStart)
+	if err != nil {
+		n.logger.Warn().Err(err).Str("user_id", user.ID).Msg("failed to read sent quota warnings")
+		return false
+	}
+	due := quota.DueWarnings(used, p.RequestsPerMonth, n.cfg.Thresholds, sentBefore)
+	if len(due) == 0 {
+		return false
+	}
+
+	msg, err := n.render(user, p, due[len(due)-1], used, periodEnd)
+	if err != nil {
+		n.logger.Error().Err(err).Str("user_id", user.ID).Msg("failed to render quota warning email")
+		return false
+	}
+	if err := n.email.Send(ctx, msg); err != nil {
+		n.logger.Warn().Err(err).Str("user_id", user.ID).Msg("failed to send quota warning email")
+		return false
+	}
+	// Lower thresholds passed at the same time are covered by this email
+	for _, threshold := range due {
+		if err := n.warnings.MarkQuotaWarned(ctx, user.ID, periodStart, threshold, now); err != nil {
+			n.logger.Error().Err(err).Str("user_id", user.ID).Int("threshold", threshold).Msg("failed to record quota warning")
+		}
+	}
+	return true
+}
+
+// quotaWarningEmailData holds data for the quota warning email templates.
+type quotaWarningEmailData struct {
+	Name      string
+	AppName   string
+	PlanName  string
+	Threshold int
+	Used      int64
+	Limit     int64
+	Unit      string
+	Exceeded  bool
+	Blocking  bool
+	ResetsAt  string
+	Link      string
+}
+
+func (n *QuotaWarningNotifier) render(user ports.User, p ports.Plan, threshold int, used int64, periodEnd time.Time) (ports.EmailMessage, error) {
+	cfg := quota.ConfigFromPlan(p)
+	data := quotaWarningEmailData{
+		Name:      user.Name,
+		AppName:   n.cfg.AppName,
+		PlanName:  p.Name,
+		Threshold: threshold,
+		Used:      used,
+		Limit:     p.RequestsPerMonth,
+		Unit:      "requests",
+		Exceeded:  threshold >= 100,
+		Blocking:  cfg.EnforceMode == quota.EnforceHard,
+		ResetsAt:  periodEnd.Add(time.Nanosecond).UTC().Format("January 2, 2006 15:04 MST"),
+		Link:      strings.TrimSuffix(n.cfg.BaseURL, "/") + "/portal/usage",
+	}
+	if data.Name == "" {
+		data.Name = user.Email
+	}
+	if cfg.MeterType == quota.MeterTypeComputeUnits {
+		data.Unit = "units"
+	}
+
+	var htmlBuf, textBuf bytes.Buffer
+	if err := quotaWarningHTMLTmpl.Execute(&htmlBuf, data); err != nil {
+		return ports.EmailMessage{}, fmt.Errorf("execute quota warning html template: %w", err)
+	}
+	if err := quotaWarningTextTmpl.Execute(&textBuf, data); err != nil {
+		return ports.EmailMessage{}, fmt.Errorf("execute quota warning text template: %w", err)
+	}
+
+	subject := fmt.Sprintf("You've used %d%% of your %s quota", threshold, n.cfg.AppName)
+	if data.Exceeded {
+		subject = fmt.Sprintf("You've reached your %s quota", n.cfg.AppName)
+	}
+	return ports.EmailMessage{
+		To:       user.Email,
+		Subject:  subject,
+		HTMLBody: htmlBuf.String(),
+		TextBody: textBuf.String(),
+	}, nil
+}
+
+var quotaWarningHTMLTmpl = htmltemplate.Must(htmltemplate.New("quotaWarning").Parse(strings.TrimSpace(`
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Quota Warning</title>
+    <style>
+        body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { text-align: center; padding: 20px 0; }
+        .content { background: #f9f9f9; padding: 30px; border-radius: 8px; }
+        .button { display: inline-block; background: #007bff; color: white; padding: 12px 30px; text-decoration: none; border-radius: 5px; margin: 20px 0; }
+        .footer { text-align: center; padding: 20px; color: #666; font-size: 14px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>{{.AppName}}</h1>
+        </div>
+        <div class="content">
+            <h2>{{if .Exceeded}}You've reached your quota{{else}}You've used {{.Threshold}}% of your quota{{end}}</h2>
+            <p>Hi {{.Name}},</p>
+            <p>You have used <strong>{{.Used}} of {{.Limit}} {{.Unit}}</strong> included in your {{.PlanName}} plan for this period.</p>
+            {{if .Exceeded}}{{if .Blocking}}<p>Further requests will be rejected until your quota resets on <strong>{{.ResetsAt}}</strong>.</p>{{else}}<p>Requests are still being served, but usage above your quota may be billed as overage until it resets on <strong>{{.ResetsAt}}</strong>.</p>{{end}}{{else}}<p>Your quota resets on <strong>{{.ResetsAt}}</strong>.</p>{{end}}
+            <p>Upgrade your plan if you need more capacity.</p>
+            <p style="text-align: center;">
+                <a href="{{.Link}}" class="button">View Usage</a>
+            </p>
+        </div>
+        <div class="footer">
+            <p>You are receiving this because you have an account on {{.AppName}}.</p>
+        </div>
+    </div>
+</body>
+</html>
+`)))
+
+var quotaWarningTextTmpl = texttemplate.Must(texttemplate.New("quotaWarning").Parse(`Hi {{.Name}},
+
+You have used {{.Used}} of {{.Limit}} {{.Unit}} included in your {{.PlanName}} plan for this period{{if not .Exceeded}} ({{.Threshold}}%){{end}}.
+{{if .Exceeded}}{{if .Blocking}}
+Further requests will be rejected until your quota resets on {{.ResetsAt}}.
+{{else}}
+Requests are still being served, but usage above your quota may be billed as overage until it resets on {{.ResetsAt}}.
+{{end}}{{else}}
+Your quota resets on {{.ResetsAt}}.
+{{end}}
+Upgrade your plan if you need more capacity.
+
+View your usage: {{.Link}}
+
+Thanks,
+The {{.AppName}} Team`))
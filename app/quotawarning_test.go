@@ -0,0 +1,165 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	"github.com/artpar/apigate/adapters/email"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/domain/usage"
+	"github.com/artpar/apigate/ports"
+	"github.com/rs/zerolog"
+)
+
+func TestQuotaWarningNotifier_EachThresholdOncePerPeriod(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start.Add(time.Hour))
+
+	users := memory.NewUserStore()
+	users.Create(ctx, ports.User{ID: "alice", Email: "alice@example.com", Name: "Alice", PlanID: "starter", Status: "active"})
+	users.Create(ctx, ports.User{ID: "bob", Email: "bob@example.com", PlanID: "unlimited", Status: "active"})
+	plans := &mockPlanStore{plans: []ports.Plan{
+		{ID: "starter", Name: "Starter", RequestsPerMonth: 100},
+		{ID: "unlimited", Name: "Unlimited", RequestsPerMonth: -1},
+	}}
+	usageStore := memory.NewUsageStore()
+	quotaStore := memory.NewQuotaStore(memory.QuotaStoreConfig{})
+	defer quotaStore.Close()
+
+	sender := email.NewMockSender("https://portal.example.com", "Acme API")
+	notifier := NewQuotaWarningNotifier(users, plans, usageStore, quotaStore, sender, clk, zerolog.Nop(), QuotaWarningNotifierConfig{
+		Thresholds: []int{80, 100},
+		AppName:    "Acme API",
+		BaseURL:    "https://portal.example.com",
+	})
+
+	var n int
+	record := func(userID string, count int) {
+		t.Helper()
+		var events []usage.Event
+		for i := 0; i < count; i++ {
+			n++
+			events = append(events, usage.Event{ID: "evt-" + itoa(n), UserID: userID, Method: "GET", Path: "/v1", StatusCode: 200, Timestamp: clk.Now()})
+		}
+		if err := usageStore.RecordBatch(ctx, events); err != nil {
+			t.Fatalf("record usage: %v", err)
+		}
+		clk.Advance(time.Hour)
+	}
+
+	steps := []struct {
+		name      string
+		requests  int
+		wantSent  int
+		wantTotal int
+	}{
+		{"below 80%", 79, 0, 0},
+		{"reaches 80%", 1, 1, 1},
+		{"still between thresholds", 10, 0, 1},
+		{"reaches 100%", 10, 1, 2},
+		{"over quota", 50, 0, 2},
+	}
+	for _, step := range steps {
+		record("alice", step.requests)
+		record("bob", step.requests*10)
+		if sent := notifier.CheckUsage(ctx); sent != step.wantSent {
+			t.Fatalf("%s: sent %d emails, want %d", step.name, sent, step.wantSent)
+		}
+		if got := len(sender.FindByTo("alice@example.com")); got != step.wantTotal {
+			t.Fatalf("%s: alice has %d emails, want %d", step.name, got, step.wantTotal)
+		}
+	}
+
+	alice := sender.FindByTo("alice@example.com")
+	if !strings.Contains(alice[0].Subject, "80%") || !strings.Contains(alice[0].TextBody, "80 of 100 requests") {
+		t.Errorf("first warning = %q:\n%s", alice[0].Subject, alice[0].TextBody)
+	}
+	if !strings.Contains(alice[1].Subject, "reached") || !strings.Contains(alice[1].TextBody, "rejected") {
+		t.Errorf("second warning = %q:\n%s", alice[1].Subject, alice[1].TextBody)
+	}
+	if got := len(sender.FindByTo("bob@example.com")); got != 0 {
+		t.Errorf("unlimited user got %d emails", got)
+	}
+
+	// A new quota period starts the warnings over
+	clk.Set(start.AddDate(0, 1, 0))
+	record("alice", 85)
+	if sent := notifier.CheckUsage(ctx); sent != 1 {
+		t.Fatalf("new period: sent %d emails, want 1", sent)
+	}
+	if got := len(sender.FindByTo("alice@example.com")); got != 3 {
+		t.Errorf("alice has %d emails after the new period, want 3", got)
+	}
+}
+
+func TestQuotaWarningNotifier_JumpPastSeveralThresholds(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(now)
+
+	users := memory.NewUserStore()
+	users.Create(ctx, ports.User{ID: "alice", Email: "alice@example.com", PlanID: "units", Status: "active"})
+	plans := &mockPlanStore{plans: []ports.Plan{
+		{ID: "units", Name: "Units", RequestsPerMonth: 1000, MeterType: ports.MeterTypeComputeUnits},
+	}}
+	usageStore := memory.NewUsageStore()
+	usageStore.RecordBatch(ctx, []usage.Event{
+		{ID: "big", UserID: "alice", Method: "POST", Path: "/v1/batch", StatusCode: 200, CostMultiplier: 1200, Timestamp: now},
+	})
+	quotaStore := memory.NewQuotaStore(memory.QuotaStoreConfig{})
+	defer quotaStore.Close()
+
+	sender := email.NewMockSender("https://portal.example.com", "Acme API")
+	notifier := NewQuotaWarningNotifier(users, plans, usageStore, quotaStore, sender, clk, zerolog.Nop(), QuotaWarningNotifierConfig{})
+
+	// One email for the highest threshold; the lower one is not sent afterwards
+	for i := 0; i < 3; i++ {
+		notifier.CheckUsage(ctx)
+	}
+	emails := sender.FindByTo("alice@example.com")
+	if len(emails) != 1 {
+		t.Fatalf("got %d emails, want 1", len(emails))
+	}
+	if !strings.Contains(emails[0].Subject, "reached") || !strings.Contains(emails[0].TextBody, "1200 of 1000 units") {
+		t.Errorf("warning = %q:\n%s", emails[0].Subject, emails[0].TextBody)
+	}
+
+	sent, _ := quotaStore.ListQuotaWarnings(ctx, "alice", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+	if len(sent) != 2 || sent[0] != 80 || sent[1] != 100 {
+		t.Errorf("recorded warnings = %v, want [80 100]", sent)
+	}
+}
+
+func TestQuotaWarningNotifier_RetriesFailedEmail(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(now)
+
+	users := memory.NewUserStore()
+	users.Create(ctx, ports.User{ID: "alice", Email: "alice@example.com", PlanID: "starter", Status: "active"})
+	plans := &mockPlanStore{plans: []ports.Plan{{ID: "starter", RequestsPerMonth: 10}}}
+	usageStore := memory.NewUsageStore()
+	var events []usage.Event
+	for i := 0; i < 9; i++ {
+		events = append(events, usage.Event{ID: "evt-" + itoa(i), UserID: "alice", StatusCode: 200, Timestamp: now})
+	}
+	usageStore.RecordBatch(ctx, events)
+	quotaStore := memory.NewQuotaStore(memory.QuotaStoreConfig{})
+	defer quotaStore.Close()
+
+	sender := email.NewMockSender("https://portal.example.com", "Acme API")
+	notifier := NewQuotaWarningNotifier(users, plans, usageStore, quotaStore, sender, clk, zerolog.Nop(), QuotaWarningNotifierConfig{})
+
+	sender.SetShouldFail(true, nil)
+	if sent := notifier.CheckUsage(ctx); sent != 0 {
+		t.Fatalf("sent %d emails while the sender fails", sent)
+	}
+	sender.SetShouldFail(false, nil)
+	if sent := notifier.CheckUsage(ctx); sent != 1 {
+		t.Fatalf("retry sent %d emails, want 1", sent)
+	}
+}
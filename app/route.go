@@ -3,12 +3,16 @@ package app
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
 	"sync/atomic"
 	"time"
 
+	"github.com/artpar/apigate/core/openapi"
+	"github.com/artpar/apigate/domain/proxy"
 	"github.com/artpar/apigate/domain/route"
 	"github.com/artpar/apigate/ports"
 	"github.com/rs/zerolog"
@@ -24,6 +28,12 @@ type RouteService struct {
 	// Cached route data for fast matching
 	cache atomic.Pointer[RouteCache]
 
+	// Weighted upstream pool selection
+	balancer *upstreamBalancer
+	passive  *passiveHealthTracker
+	breakers *circuitBreakerTracker
+	health   UpstreamHealth // Optional - nil treats all upstreams as healthy
+
 	// Refresh interval
 	refreshInterval time.Duration
 	stopRefresh     chan struct{}
@@ -34,6 +44,7 @@ type RouteCache struct {
 	Matcher     *route.Matcher
 	Routes      []route.Route
 	Upstreams   map[string]route.Upstream
+	BodySchemas map[string]*openapi.Schema // Compiled RequestSchema by route ID
 	RefreshedAt time.Time
 }
 
@@ -61,6 +72,9 @@ func NewRouteService(
 		logger:          logger.With().Str("service", "route").Logger(),
 		refreshInterval: cfg.RefreshInterval,
 		stopRefresh:     make(chan struct{}),
+		balancer:        newUpstreamBalancer(),
+		passive:         newPassiveHealthTracker(clock),
+		breakers:        newCircuitBreakerTracker(clock),
 	}
 
 	return s
@@ -103,7 +117,9 @@ func (s *RouteService) refreshLoop() {
 	}
 }
 
-// Reload refreshes routes from storage.
+// Reload refreshes routes from storage and atomically swaps in the new table.
+// A table that fails validation is rejected and the previous one stays live.
+// Requests already pinned to the previous table keep using it until they finish.
 func (s *RouteService) Reload(ctx context.Context) error {
 	// Load enabled routes
 	routes, err := s.routes.ListEnabled(ctx)
@@ -123,17 +139,36 @@ func (s *RouteService) Reload(ctx context.Context) error {
 		upstreamMap[u.ID] = u
 	}
 
-	// Build matcher
+	// Validate and build matcher before touching the live table
+	if err := route.ValidateTable(routes); err != nil {
+		s.logger.Error().Err(err).Msg("route reload rejected, keeping previous routes")
+		return err
+	}
 	matcher, err := route.NewMatcher(routes)
 	if err != nil {
+		s.logger.Error().Err(err).Msg("route reload rejected, keeping previous routes")
 		return err
 	}
+	schemas := make(map[string]*openapi.Schema)
+	for _, r := range routes {
+		if r.RequestSchema == "" {
+			continue
+		}
+		schema, err := openapi.ParseSchema(r.RequestSchema)
+		if err != nil {
+			err = fmt.Errorf("route %s: request schema: %w", r.ID, err)
+			s.logger.Error().Err(err).Msg("route reload rejected, keeping previous routes")
+			return err
+		}
+		schemas[r.ID] = schema
+	}
 
 	// Atomic swap
 	cache := &RouteCache{
 		Matcher:     matcher,
 		Routes:      routes,
 		Upstreams:   upstreamMap,
+		BodySchemas: schemas,
 		RefreshedAt: s.clock.Now(),
 	}
 	s.cache.Store(cache)
@@ -146,28 +181,239 @@ func (s *RouteService) Reload(ctx context.Context) error {
 	return nil
 }
 
+// routeTableKey is the context key for a pinned route table.
+type routeTableKey struct{}
+
+// Pin returns a context carrying the current route table. Matching and
+// upstream selection with the returned context use that table even if routes
+// are reloaded while the request is in flight. A context that is already
+// pinned is returned unchanged.
+func (s *RouteService) Pin(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(routeTableKey{}).(*RouteCache); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, routeTableKey{}, s.cache.Load())
+}
+
+// table returns the route table pinned to ctx, or the current table.
+func (s *RouteService) table(ctx context.Context) *RouteCache {
+	if cache, ok := ctx.Value(routeTableKey{}).(*RouteCache); ok {
+		return cache
+	}
+	return s.cache.Load()
+}
+
 // Match finds the best matching route for a request.
 // Returns nil if no route matches.
 func (s *RouteService) Match(method, path string, headers map[string]string) *route.MatchResult {
-	cache := s.cache.Load()
+	return s.MatchContext(context.Background(), method, path, headers)
+}
+
+// MatchContext is like Match but uses the route table pinned to ctx, if any.
+// The match is recorded in the RouteTrace attached to ctx, if any.
+func (s *RouteService) MatchContext(ctx context.Context, method, path string, headers map[string]string) *route.MatchResult {
+	return s.matchQuery(ctx, method, path, "", headers)
+}
+
+// MatchRequest is like MatchContext but also matches the request's query
+// string against route query conditions.
+func (s *RouteService) MatchRequest(ctx context.Context, req proxy.Request) *route.MatchResult {
+	return s.matchQuery(ctx, req.Method, req.Path, req.Query, req.Headers)
+}
+
+func (s *RouteService) matchQuery(ctx context.Context, method, path, rawQuery string, headers map[string]string) *route.MatchResult {
+	cache := s.table(ctx)
 	if cache == nil || cache.Matcher == nil {
 		return nil
 	}
-	return cache.Matcher.Match(method, path, headers)
+	result := cache.Matcher.MatchQuery(method, path, rawQuery, headers)
+	if trace := routeTraceFrom(ctx); trace != nil && result != nil {
+		trace.RouteID = result.Route.ID
+		trace.RouteName = result.Route.Name
+		trace.MatchReason = result.Route.MatchReason()
+	}
+	return result
+}
+
+// RouteTrace records how a request was routed, for debug response headers.
+// Fields are left empty when no route matched or the default upstream is used.
+type RouteTrace struct {
+	RouteID     string
+	RouteName   string
+	MatchReason string
+	UpstreamID  string
+	Upstream    string // Upstream name
+}
+
+// routeTraceKey is the context key for a RouteTrace.
+type routeTraceKey struct{}
+
+// WithRouteTrace returns a context in which route matching and upstream
+// selection are recorded into t.
+func WithRouteTrace(ctx context.Context, t *RouteTrace) context.Context {
+	return context.WithValue(ctx, routeTraceKey{}, t)
+}
+
+// routeTraceFrom returns the RouteTrace attached to ctx, or nil.
+func routeTraceFrom(ctx context.Context) *RouteTrace {
+	t, _ := ctx.Value(routeTraceKey{}).(*RouteTrace)
+	return t
 }
 
 // GetUpstream returns an upstream by ID.
 func (s *RouteService) GetUpstream(id string) *route.Upstream {
-	cache := s.cache.Load()
-	if cache == nil {
+	return s.cache.Load().upstream(id)
+}
+
+// upstream returns the upstream with the given ID from the table, or nil.
+func (c *RouteCache) bodySchema(routeID string) *openapi.Schema {
+	if c == nil {
 		return nil
 	}
-	if u, ok := cache.Upstreams[id]; ok {
+	return c.BodySchemas[routeID]
+}
+
+func (c *RouteCache) upstream(id string) *route.Upstream {
+	if c == nil {
+		return nil
+	}
+	if u, ok := c.Upstreams[id]; ok {
 		return &u
 	}
 	return nil
 }
 
+// SetUpstreamHealth sets the health source used to skip unhealthy pool members.
+func (s *RouteService) SetUpstreamHealth(h UpstreamHealth) {
+	s.health = h
+}
+
+// IsUpstreamHealthy returns false if the upstream has been ejected by passive
+// health checking or is reported unhealthy by the configured health source.
+func (s *RouteService) IsUpstreamHealthy(upstreamID string) bool {
+	if s.passive.ejected(upstreamID) {
+		return false
+	}
+	return s.health == nil || s.health.IsHealthy(upstreamID)
+}
+
+// RecordUpstreamResult feeds a live request outcome into passive health checking
+// and the upstream's circuit breaker. A neutral outcome only frees the
+// half-open trial the request may have reserved.
+func (s *RouteService) RecordUpstreamResult(u *route.Upstream, outcome route.UpstreamOutcome) {
+	if outcome == route.OutcomeNeutral {
+		s.breakers.release(u)
+		return
+	}
+
+	failed := outcome == route.OutcomeFailure
+	if s.passive.record(u, failed) {
+		s.logger.Warn().
+			Str("upstream_id", u.ID).
+			Int("failure_threshold", u.FailureThreshold).
+			Dur("cooldown", u.EjectionCooldown).
+			Msg("upstream ejected after consecutive failures")
+	}
+
+	if prev, next := s.breakers.record(u, failed); prev != next {
+		s.logger.Warn().
+			Str("upstream_id", u.ID).
+			Str("from", string(prev)).
+			Str("to", string(next)).
+			Msg("upstream circuit state changed")
+	}
+}
+
+// AllowUpstream reports whether a request may be sent to the upstream according
+// to its circuit breaker. A true result for a half-open circuit reserves a trial,
+// so every allowed request must be followed by RecordUpstreamResult.
+func (s *RouteService) AllowUpstream(u *route.Upstream) bool {
+	return s.breakers.allow(u)
+}
+
+// CircuitState returns the current circuit breaker state of the upstream.
+func (s *RouteService) CircuitState(u route.Upstream) route.CircuitState {
+	return s.breakers.stateOf(u)
+}
+
+// SelectUpstream returns the upstream a request on the given route should be sent to.
+// Routes with an upstream pool use weighted round-robin, skipping disabled and
+// unhealthy members and members with an open circuit. If every member is
+// unhealthy, health is ignored so traffic
+// still flows. Routes without a pool use UpstreamID.
+// Upstreams are looked up in the route table pinned to ctx, if any, and the
+// selection is recorded in the RouteTrace attached to ctx, if any.
+func (s *RouteService) SelectUpstream(ctx context.Context, r *route.Route) *route.Upstream {
+	return s.SelectUpstreamFor(ctx, r, nil)
+}
+
+// SelectUpstreamFor is like SelectUpstream but also applies the route's
+// canary policy to the caller described by auth. Callers in the canary cohort
+// go to the canary upstream while it is healthy with a closed circuit;
+// everyone else, and anonymous callers, use the normal selection.
+func (s *RouteService) SelectUpstreamFor(ctx context.Context, r *route.Route, auth *proxy.AuthContext) *route.Upstream {
+	u := s.canaryUpstream(ctx, r, auth)
+	if u == nil {
+		u = s.selectUpstream(ctx, r)
+	}
+	if trace := routeTraceFrom(ctx); trace != nil && u != nil {
+		trace.UpstreamID = u.ID
+		trace.Upstream = u.Name
+	}
+	return u
+}
+
+// canaryUpstream returns the route's canary upstream if the caller belongs to
+// the canary cohort and the upstream can take traffic, otherwise nil.
+func (s *RouteService) canaryUpstream(ctx context.Context, r *route.Route, auth *proxy.AuthContext) *route.Upstream {
+	if r.Canary == nil || auth == nil {
+		return nil
+	}
+	if !r.Canary.Selects(r.Canary.Subject(auth.UserID, auth.KeyPrefix)) {
+		return nil
+	}
+	cache := s.table(ctx)
+	if cache == nil {
+		return nil
+	}
+	u, ok := cache.Upstreams[r.Canary.UpstreamID]
+	if !ok || !s.IsUpstreamHealthy(u.ID) || s.CircuitState(u) == route.CircuitOpen {
+		return nil
+	}
+	return &u
+}
+
+func (s *RouteService) selectUpstream(ctx context.Context, r *route.Route) *route.Upstream {
+	cache := s.table(ctx)
+	if len(r.Upstreams) == 0 {
+		return cache.upstream(r.UpstreamID)
+	}
+
+	if cache == nil {
+		return nil
+	}
+
+	available := func(id string) bool {
+		_, ok := cache.Upstreams[id]
+		return ok
+	}
+	healthy := func(id string) bool {
+		u, ok := cache.Upstreams[id]
+		return ok && s.IsUpstreamHealthy(id) && s.CircuitState(u) != route.CircuitOpen
+	}
+
+	id, ok := s.balancer.pick(r.ID, r.Upstreams, healthy)
+	if !ok {
+		id, ok = s.balancer.pick(r.ID, r.Upstreams, available)
+	}
+	if !ok {
+		return cache.upstream(r.UpstreamID)
+	}
+
+	u := cache.Upstreams[id]
+	return &u
+}
+
 // GetRoutes returns all cached routes.
 func (s *RouteService) GetRoutes() []route.Route {
 	cache := s.cache.Load()
@@ -280,6 +526,31 @@ func expandEnvVars(s string) string {
 	return result
 }
 
+// headerRefs resolves ${...} references in route header rule values:
+// key.id, key.prefix, key.user_id and key.plan_id come from the caller
+// (empty when auth is nil), anything else from the environment.
+func headerRefs(auth *proxy.AuthContext) func(string) string {
+	return func(name string) string {
+		if !strings.HasPrefix(name, "key.") {
+			return os.Getenv(name)
+		}
+		if auth == nil {
+			return ""
+		}
+		switch name {
+		case "key.id":
+			return auth.KeyID
+		case "key.prefix":
+			return auth.KeyPrefix
+		case "key.user_id":
+			return auth.UserID
+		case "key.plan_id":
+			return auth.PlanID
+		}
+		return ""
+	}
+}
+
 // RouteTestRequest contains the input for testing a route.
 type RouteTestRequest struct {
 	Method  string            `json:"method"`
@@ -379,7 +650,7 @@ func (s *RouteService) TestRoute(req RouteTestRequest) RouteTestResult {
 		result.TransformedMethod = matchedRoute.MethodOverride
 	}
 
-	result.TransformedPath = req.Path
+	result.TransformedPath = matchedRoute.RewriteUpstreamPath(req.Path)
 	// Note: Path rewrite would need TransformService evaluation
 	// For now, just show the raw path_rewrite expression if set
 	if matchedRoute.PathRewrite != "" {
@@ -405,6 +676,11 @@ func (s *RouteService) TestRoute(req RouteTestRequest) RouteTestResult {
 		}
 	}
 
+	// Header rules, without caller references since the test has no key
+	if len(matchedRoute.RequestHeaders) > 0 {
+		result.TransformedHeaders = route.ApplyHeaderRules(result.TransformedHeaders, matchedRoute.RequestHeaders, headerRefs(nil))
+	}
+
 	// Body transformation
 	result.TransformedBody = req.Body
 	if matchedRoute.RequestTransform != nil && matchedRoute.RequestTransform.BodyExpr != "" {
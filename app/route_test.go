@@ -2,12 +2,15 @@ package app_test
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/artpar/apigate/adapters/clock"
 	"github.com/artpar/apigate/app"
+	"github.com/artpar/apigate/domain/proxy"
 	"github.com/artpar/apigate/domain/route"
 	"github.com/rs/zerolog"
 )
@@ -659,6 +662,163 @@ func TestRouteService_Reload(t *testing.T) {
 	}
 }
 
+func TestRouteService_Reload_NewRequestsSeeNewRoutes(t *testing.T) {
+	routeStore := &mockRouteStore{routes: []route.Route{
+		{ID: "r1", Name: "v1", PathPattern: "/api/*", MatchType: route.MatchPrefix, UpstreamID: "old", Enabled: true},
+	}}
+	upstreamStore := &mockUpstreamStore{upstreams: []route.Upstream{
+		{ID: "old", Name: "old", BaseURL: "http://old", Enabled: true},
+	}}
+	svc := app.NewRouteService(routeStore, upstreamStore, clock.NewFake(time.Now()), zerolog.Nop(), app.RouteServiceConfig{RefreshInterval: time.Hour})
+	ctx := context.Background()
+	if err := svc.Reload(ctx); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	// A request that started before the change pins the current table
+	inFlight := svc.Pin(ctx)
+	oldMatch := svc.MatchContext(inFlight, "GET", "/api/users", nil)
+	if oldMatch == nil || oldMatch.Route.Name != "v1" {
+		t.Fatalf("expected v1 before reload, got %+v", oldMatch)
+	}
+
+	routeStore.routes = []route.Route{
+		{ID: "r1", Name: "v2", PathPattern: "/api/*", MatchType: route.MatchPrefix, UpstreamID: "new", Enabled: true},
+		{ID: "r2", Name: "orders", PathPattern: "/orders", MatchType: route.MatchExact, UpstreamID: "new", Enabled: true},
+	}
+	upstreamStore.upstreams = []route.Upstream{
+		{ID: "new", Name: "new", BaseURL: "http://new", Enabled: true},
+	}
+	if err := svc.Reload(ctx); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	// New requests hit the new routes
+	if m := svc.Match("GET", "/api/users", nil); m == nil || m.Route.Name != "v2" {
+		t.Errorf("expected v2 after reload, got %+v", m)
+	}
+	if m := svc.Match("GET", "/orders", nil); m == nil || m.Route.Name != "orders" {
+		t.Errorf("expected orders route after reload, got %+v", m)
+	}
+	if u := svc.SelectUpstream(ctx, svc.Match("GET", "/api/users", nil).Route); u == nil || u.ID != "new" {
+		t.Errorf("expected new upstream, got %+v", u)
+	}
+
+	// The in-flight request completes against the table it started with
+	if m := svc.MatchContext(inFlight, "GET", "/orders", nil); m != nil {
+		t.Errorf("in-flight request should not see routes added later, got %s", m.Route.Name)
+	}
+	if u := svc.SelectUpstream(inFlight, oldMatch.Route); u == nil || u.ID != "old" {
+		t.Errorf("in-flight request should keep its upstream, got %+v", u)
+	}
+}
+
+func TestRouteService_Reload_RejectsInvalidRoutes(t *testing.T) {
+	routeStore := &mockRouteStore{routes: []route.Route{
+		{ID: "r1", Name: "v1", PathPattern: "/api/*", MatchType: route.MatchPrefix, Enabled: true},
+	}}
+	svc := app.NewRouteService(routeStore, &mockUpstreamStore{}, clock.NewFake(time.Now()), zerolog.Nop(), app.RouteServiceConfig{RefreshInterval: time.Hour})
+	ctx := context.Background()
+	if err := svc.Reload(ctx); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		routes []route.Route
+	}{
+		{"duplicate id", []route.Route{
+			{ID: "r1", Name: "a", PathPattern: "/a/*", MatchType: route.MatchPrefix, Enabled: true},
+			{ID: "r1", Name: "b", PathPattern: "/b/*", MatchType: route.MatchPrefix, Enabled: true},
+		}},
+		{"bad regex", []route.Route{
+			{ID: "r1", Name: "a", PathPattern: "([", MatchType: route.MatchRegex, Enabled: true},
+		}},
+		{"bad retry policy", []route.Route{
+			{ID: "r1", Name: "a", PathPattern: "/a/*", MatchType: route.MatchPrefix, Enabled: true, Retry: &route.RetryPolicy{Attempts: -1}},
+		}},
+	}
+	for _, tt := range tests {
+		routeStore.routes = tt.routes
+		if err := svc.Reload(ctx); err == nil {
+			t.Errorf("%s: expected reload error", tt.name)
+		}
+		if m := svc.Match("GET", "/api/users", nil); m == nil || m.Route.Name != "v1" {
+			t.Errorf("%s: previous routes should stay live, got %+v", tt.name, m)
+		}
+	}
+}
+
+func TestRouteService_Reload_ConcurrentRequests(t *testing.T) {
+	routeStore := &mockRouteStore{routes: []route.Route{
+		{ID: "r1", Name: "v1", PathPattern: "/api/*", MatchType: route.MatchPrefix, UpstreamID: "u1", Enabled: true},
+	}}
+	upstreamStore := &mockUpstreamStore{upstreams: []route.Upstream{
+		{ID: "u1", Name: "u1", BaseURL: "http://u1", Enabled: true},
+	}}
+	svc := app.NewRouteService(routeStore, upstreamStore, clock.NewFake(time.Now()), zerolog.Nop(), app.RouteServiceConfig{RefreshInterval: time.Hour})
+	ctx := context.Background()
+	if err := svc.Reload(ctx); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	// Reloads alternate between two tables; every request must resolve an
+	// upstream from the same table as its route.
+	tables := [2]struct {
+		routes    []route.Route
+		upstreams []route.Upstream
+	}{
+		{routeStore.routes, upstreamStore.upstreams},
+		{
+			[]route.Route{{ID: "r1", Name: "v2", PathPattern: "/api/*", MatchType: route.MatchPrefix, UpstreamID: "u2", Enabled: true}},
+			[]route.Upstream{{ID: "u2", Name: "u2", BaseURL: "http://u2", Enabled: true}},
+		},
+	}
+	want := map[string]string{"v1": "u1", "v2": "u2"}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	errs := make(chan string, 8)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				reqCtx := svc.Pin(ctx)
+				m := svc.MatchContext(reqCtx, "GET", "/api/x", nil)
+				if m == nil {
+					errs <- "no match during reload"
+					return
+				}
+				if u := svc.SelectUpstream(reqCtx, m.Route); u == nil || u.ID != want[m.Route.Name] {
+					errs <- "route " + m.Route.Name + " resolved the wrong upstream"
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 200; i++ {
+		next := tables[(i+1)%2]
+		routeStore.routes = next.routes
+		upstreamStore.upstreams = next.upstreams
+		if err := svc.Reload(ctx); err != nil {
+			t.Fatalf("Reload failed: %v", err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+	close(errs)
+	for e := range errs {
+		t.Error(e)
+	}
+}
+
 func TestRouteService_OnlyEnabledRoutes(t *testing.T) {
 	routes := []route.Route{
 		{ID: "r1", Name: "Enabled", PathPattern: "/a/*", MatchType: route.MatchPrefix, Enabled: true},
@@ -676,3 +836,166 @@ func TestRouteService_OnlyEnabledRoutes(t *testing.T) {
 		t.Errorf("got %s, want Enabled", cached[0].Name)
 	}
 }
+
+// staticHealth marks the listed upstream IDs as unhealthy.
+type staticHealth map[string]bool
+
+func (h staticHealth) IsHealthy(upstreamID string) bool { return !h[upstreamID] }
+
+func newPoolRouteService(t *testing.T) (*app.RouteService, *route.Route) {
+	t.Helper()
+
+	r := route.Route{
+		ID: "pool", Name: "pool", PathPattern: "/api/*", MatchType: route.MatchPrefix, Enabled: true,
+		UpstreamID: "primary",
+		Upstreams: []route.WeightedUpstream{
+			{UpstreamID: "primary", Weight: 3},
+			{UpstreamID: "canary", Weight: 1},
+		},
+	}
+	upstreams := []route.Upstream{
+		{ID: "primary", Name: "primary", BaseURL: "http://primary", Enabled: true},
+		{ID: "canary", Name: "canary", BaseURL: "http://canary", Enabled: true},
+	}
+
+	svc := newTestRouteService([]route.Route{r}, upstreams)
+	if err := svc.Reload(context.Background()); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	return svc, &r
+}
+
+func TestRouteService_SelectUpstream_WeightedDistribution(t *testing.T) {
+	svc, r := newPoolRouteService(t)
+
+	const total = 1000
+	var mu sync.Mutex
+	counts := map[string]int{}
+
+	var wg sync.WaitGroup
+	for i := 0; i < total; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			u := svc.SelectUpstream(context.Background(), r)
+			if u == nil {
+				t.Error("expected an upstream")
+				return
+			}
+			mu.Lock()
+			counts[u.ID]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	// 3:1 weighting -> 750/250, allow 5% tolerance
+	const tolerance = total * 5 / 100
+	if diff := counts["primary"] - 750; diff < -tolerance || diff > tolerance {
+		t.Errorf("primary = %d, want 750 ± %d", counts["primary"], tolerance)
+	}
+	if diff := counts["canary"] - 250; diff < -tolerance || diff > tolerance {
+		t.Errorf("canary = %d, want 250 ± %d", counts["canary"], tolerance)
+	}
+	if counts["primary"]+counts["canary"] != total {
+		t.Errorf("total = %d, want %d", counts["primary"]+counts["canary"], total)
+	}
+}
+
+func TestRouteService_SelectUpstream_SkipsUnhealthy(t *testing.T) {
+	svc, r := newPoolRouteService(t)
+	svc.SetUpstreamHealth(staticHealth{"primary": true})
+
+	for i := 0; i < 10; i++ {
+		if u := svc.SelectUpstream(context.Background(), r); u == nil || u.ID != "canary" {
+			t.Fatalf("selection %d = %v, want canary", i, u)
+		}
+	}
+}
+
+func TestRouteService_SelectUpstream_AllUnhealthyFailsOpen(t *testing.T) {
+	svc, r := newPoolRouteService(t)
+	svc.SetUpstreamHealth(staticHealth{"primary": true, "canary": true})
+
+	if u := svc.SelectUpstream(context.Background(), r); u == nil {
+		t.Fatal("expected an upstream when all pool members are unhealthy")
+	}
+}
+
+func TestRouteService_SelectUpstream_NoPool(t *testing.T) {
+	svc, r := newPoolRouteService(t)
+	single := *r
+	single.Upstreams = nil
+
+	if u := svc.SelectUpstream(context.Background(), &single); u == nil || u.ID != "primary" {
+		t.Errorf("SelectUpstream = %v, want primary", u)
+	}
+}
+
+func newCanaryRouteService(t *testing.T, percent int) (*app.RouteService, *route.Route) {
+	t.Helper()
+
+	r := route.Route{
+		ID: "orders", Name: "orders", PathPattern: "/orders/*", MatchType: route.MatchPrefix, Enabled: true,
+		UpstreamID: "stable",
+		Canary:     &route.CanaryPolicy{UpstreamID: "canary", Percent: percent},
+	}
+	upstreams := []route.Upstream{
+		{ID: "stable", Name: "stable", BaseURL: "http://stable", Enabled: true},
+		{ID: "canary", Name: "canary", BaseURL: "http://canary", Enabled: true},
+	}
+
+	svc := newTestRouteService([]route.Route{r}, upstreams)
+	if err := svc.Reload(context.Background()); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	return svc, &r
+}
+
+func TestRouteService_SelectUpstreamFor_CanaryShare(t *testing.T) {
+	svc, r := newCanaryRouteService(t, 10)
+	ctx := context.Background()
+
+	const users = 2000
+	canary := 0
+	for i := 0; i < users; i++ {
+		auth := &proxy.AuthContext{UserID: fmt.Sprintf("user-%d", i)}
+		first := svc.SelectUpstreamFor(ctx, r, auth)
+		if first == nil {
+			t.Fatalf("user %d: no upstream", i)
+		}
+		// The same user is always routed the same way
+		for j := 0; j < 3; j++ {
+			if again := svc.SelectUpstreamFor(ctx, r, auth); again.ID != first.ID {
+				t.Fatalf("user %d routed to %s then %s", i, first.ID, again.ID)
+			}
+		}
+		if first.ID == "canary" {
+			canary++
+		}
+	}
+
+	// ~10% of distinct users, allow 2 points either way
+	if canary < users*8/100 || canary > users*12/100 {
+		t.Errorf("canary users = %d of %d, want ~10%%", canary, users)
+	}
+
+	// Anonymous callers stay on the stable upstream
+	if u := svc.SelectUpstream(ctx, r); u == nil || u.ID != "stable" {
+		t.Errorf("SelectUpstream = %v, want stable", u)
+	}
+}
+
+func TestRouteService_SelectUpstreamFor_CanaryUnhealthy(t *testing.T) {
+	svc, r := newCanaryRouteService(t, 100)
+	auth := &proxy.AuthContext{UserID: "user-1"}
+
+	if u := svc.SelectUpstreamFor(context.Background(), r, auth); u == nil || u.ID != "canary" {
+		t.Fatalf("SelectUpstreamFor = %v, want canary at 100%%", u)
+	}
+
+	svc.SetUpstreamHealth(staticHealth{"canary": true})
+	if u := svc.SelectUpstreamFor(context.Background(), r, auth); u == nil || u.ID != "stable" {
+		t.Errorf("SelectUpstreamFor = %v, want stable while canary is unhealthy", u)
+	}
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"strings"
+
+	"github.com/artpar/apigate/domain/proxy"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the instrumentation scope of proxy spans.
+const tracerName = "github.com/artpar/apigate/app"
+
+// traceContext propagates W3C traceparent and tracestate headers.
+var traceContext = propagation.TraceContext{}
+
+// headerCarrier adapts proxy request headers, whose names are not normalised,
+// to a propagation.TextMapCarrier with case-insensitive lookups.
+type headerCarrier map[string]string
+
+func (c headerCarrier) Get(key string) string {
+	for k, v := range c {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
+
+func (c headerCarrier) Set(key, value string) {
+	for k := range c {
+		if strings.EqualFold(k, key) {
+			delete(c, k)
+		}
+	}
+	c[key] = value
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// withTraceContext returns a copy of headers carrying the trace context of
+// ctx, replacing any trace headers sent by the client. Without a valid span
+// context the headers are copied unchanged.
+func withTraceContext(ctx context.Context, headers map[string]string) map[string]string {
+	out := headerCarrier(maps.Clone(headers))
+	if out == nil {
+		out = make(headerCarrier)
+	}
+	traceContext.Inject(ctx, out)
+	return out
+}
+
+// endRejectedSpan ends the span of a pipeline stage that rejected the
+// request, recording the rejection as the span's error.
+func endRejectedSpan(span trace.Span, errResp *proxy.ErrorResponse) {
+	span.SetAttributes(attribute.String("apigate.error_code", errResp.Code))
+	span.RecordError(errors.New(errResp.Message))
+	span.SetStatus(codes.Error, errResp.Message)
+	span.End()
+}
@@ -0,0 +1,42 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/artpar/apigate/domain/webhook"
+	"github.com/artpar/apigate/ports"
+)
+
+// UserEvents wraps a UserStore and publishes a user.created webhook event
+// after each successful Create, so every signup path (admin API, web UI,
+// portal, invites, OAuth) notifies subscribers the same way.
+type UserEvents struct {
+	ports.UserStore
+	events ports.EventDispatcher
+}
+
+// NewUserEvents creates a UserStore that publishes user.created events.
+func NewUserEvents(users ports.UserStore, events ports.EventDispatcher) *UserEvents {
+	return &UserEvents{UserStore: users, events: events}
+}
+
+// Create stores the user and publishes user.created.
+func (s *UserEvents) Create(ctx context.Context, u ports.User) error {
+	if err := s.UserStore.Create(ctx, u); err != nil {
+		return err
+	}
+
+	_ = s.events.DispatchEvent(ctx, webhook.EventUserCreated, u.ID, map[string]interface{}{
+		"user_id":    u.ID,
+		"email":      u.Email,
+		"name":       u.Name,
+		"plan_id":    u.PlanID,
+		"status":     u.Status,
+		"created_at": u.CreatedAt.UTC().Format(time.RFC3339),
+	})
+	return nil
+}
+
+// Ensure interface compliance.
+var _ ports.UserStore = (*UserEvents)(nil)
@@ -0,0 +1,39 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/domain/webhook"
+	"github.com/artpar/apigate/ports"
+)
+
+func TestUserEvents_CreatePublishesUserCreated(t *testing.T) {
+	dispatcher := &recordingDispatcher{}
+	users := NewUserEvents(memory.NewUserStore(), dispatcher)
+	ctx := context.Background()
+
+	if err := users.Create(ctx, ports.User{ID: "usr_1", Email: "new@example.com", PlanID: "free", Status: "active"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := users.Get(ctx, "usr_1"); err != nil {
+		t.Fatalf("user was not stored: %v", err)
+	}
+
+	events := dispatcher.recorded()
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	if events[0].Type != webhook.EventUserCreated || events[0].UserID != "usr_1" || events[0].Data["email"] != "new@example.com" {
+		t.Errorf("event = %+v, want user.created for usr_1", events[0])
+	}
+
+	// A failed create publishes nothing
+	if err := users.Create(ctx, ports.User{ID: "usr_2", Email: "new@example.com"}); err == nil {
+		t.Fatal("Expected duplicate email to fail")
+	}
+	if n := len(dispatcher.recorded()); n != 1 {
+		t.Errorf("Expected no event for a failed create, got %d events", n)
+	}
+}
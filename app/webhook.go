@@ -20,9 +20,11 @@ type WebhookService struct {
 	deliveries  ports.DeliveryStore
 	logger      zerolog.Logger
 	client      *http.Client
+	backoff     time.Duration // Delay before the first retry; grows exponentially
 	mu          sync.Mutex
 	stopCh      chan struct{}
 	running     bool
+	inFlight    map[string]bool    // Delivery IDs currently being sent (guarded by mu)
 	shutdownCtx context.Context    // For graceful shutdown of spawned goroutines
 	shutdownFn  context.CancelFunc // Cancel function for shutdown
 }
@@ -43,17 +45,25 @@ func NewWebhookService(
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		backoff:     webhook.DefaultRetryBackoff,
 		stopCh:      make(chan struct{}),
+		inFlight:    make(map[string]bool),
 		shutdownCtx: shutdownCtx,
 		shutdownFn:  shutdownFn,
 	}
 }
 
+// SetRetryBackoff sets the delay before the first retry of a failed delivery.
+// Each later retry waits exponentially longer.
+func (s *WebhookService) SetRetryBackoff(backoff time.Duration) {
+	s.backoff = backoff
+}
+
 // Dispatch dispatches an event to all subscribed webhooks.
 // This is the main entry point for sending webhook events.
 func (s *WebhookService) Dispatch(ctx context.Context, event webhook.Event) error {
 	// Find all webhooks subscribed to this event
-	webhooks, err := s.webhooks.ListForEvent(ctx, event.Type)
+	subscribed, err := s.webhooks.ListForEvent(ctx, event.Type)
 	if err != nil {
 		s.logger.Error().Err(err).
 			Str("event_type", string(event.Type)).
@@ -61,6 +71,14 @@ func (s *WebhookService) Dispatch(ctx context.Context, event webhook.Event) erro
 		return err
 	}
 
+	// Users' webhooks only receive their own events
+	var webhooks []webhook.Webhook
+	for _, wh := range subscribed {
+		if webhook.DeliversTo(wh, event) {
+			webhooks = append(webhooks, wh)
+		}
+	}
+
 	if len(webhooks) == 0 {
 		s.logger.Debug().
 			Str("event_type", string(event.Type)).
@@ -99,6 +117,7 @@ func (s *WebhookService) Dispatch(ctx context.Context, event webhook.Event) erro
 
 		// Dispatch asynchronously with timeout derived from shutdown context
 		// This prevents goroutine leaks - goroutines will be cancelled on shutdown
+		s.markInFlight(delivery.ID)
 		webhookCtx, cancel := context.WithTimeout(s.shutdownCtx, 30*time.Second)
 		go func(ctx context.Context, cancelFn context.CancelFunc) {
 			defer cancelFn()
@@ -117,6 +136,7 @@ func (s *WebhookService) Dispatch(ctx context.Context, event webhook.Event) erro
 
 // sendWebhook sends a webhook and updates the delivery status.
 func (s *WebhookService) sendWebhook(ctx context.Context, wh webhook.Webhook, delivery webhook.Delivery, payload []byte) {
+	defer s.clearInFlight(delivery.ID)
 	start := time.Now()
 
 	// Apply webhook-specific timeout via context (not new client)
@@ -191,21 +211,30 @@ func (s *WebhookService) markSuccess(ctx context.Context, d webhook.Delivery, st
 
 // markFailed marks a delivery as failed and schedules retry if needed.
 func (s *WebhookService) markFailed(ctx context.Context, d webhook.Delivery, statusCode int, respBody, errMsg string, durationMS int) {
-	updated := webhook.MarkFailed(d, statusCode, respBody, errMsg, durationMS, time.Now())
+	updated := webhook.MarkFailedWithBackoff(d, statusCode, respBody, errMsg, durationMS, s.backoff, time.Now())
 	if err := s.deliveries.Update(ctx, updated); err != nil {
 		s.logger.Error().Err(err).
 			Str("delivery_id", d.ID).
 			Msg("failed to update delivery status")
 	}
 
-	if updated.Status == webhook.DeliveryRetrying {
+	switch updated.Status {
+	case webhook.DeliveryRetrying:
 		s.logger.Info().
 			Str("delivery_id", d.ID).
 			Str("webhook_id", d.WebhookID).
 			Int("attempt", d.Attempt).
 			Time("next_retry", *updated.NextRetry).
 			Msg("webhook delivery scheduled for retry")
-	} else {
+	case webhook.DeliveryDeadLetter:
+		s.logger.Error().
+			Str("delivery_id", d.ID).
+			Str("webhook_id", d.WebhookID).
+			Int("attempts", d.Attempt).
+			Int("status_code", statusCode).
+			Str("error", errMsg).
+			Msg("webhook delivery dead-lettered after max attempts")
+	default:
 		s.logger.Warn().
 			Str("delivery_id", d.ID).
 			Str("webhook_id", d.WebhookID).
@@ -276,6 +305,11 @@ func (s *WebhookService) processRetries(ctx context.Context) {
 	s.logger.Debug().Int("count", len(pending)).Msg("processing pending webhook deliveries")
 
 	for _, d := range pending {
+		// Skip deliveries whose previous attempt is still being sent
+		if s.isInFlight(d.ID) {
+			continue
+		}
+
 		// Get the webhook
 		wh, err := s.webhooks.Get(ctx, d.WebhookID)
 		if err != nil {
@@ -300,6 +334,7 @@ func (s *WebhookService) processRetries(ctx context.Context) {
 		}
 
 		// Re-dispatch with timeout derived from shutdown context
+		s.markInFlight(d.ID)
 		retryCtx, cancel := context.WithTimeout(s.shutdownCtx, 30*time.Second)
 		go func(ctx context.Context, cancelFn context.CancelFunc) {
 			defer cancelFn()
@@ -308,6 +343,24 @@ func (s *WebhookService) processRetries(ctx context.Context) {
 	}
 }
 
+func (s *WebhookService) markInFlight(deliveryID string) {
+	s.mu.Lock()
+	s.inFlight[deliveryID] = true
+	s.mu.Unlock()
+}
+
+func (s *WebhookService) clearInFlight(deliveryID string) {
+	s.mu.Lock()
+	delete(s.inFlight, deliveryID)
+	s.mu.Unlock()
+}
+
+func (s *WebhookService) isInFlight(deliveryID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inFlight[deliveryID]
+}
+
 // DispatchEvent is a convenience method for creating and dispatching events.
 func (s *WebhookService) DispatchEvent(ctx context.Context, eventType webhook.EventType, userID string, data map[string]interface{}) error {
 	event := webhook.Event{
@@ -354,6 +407,7 @@ func (s *WebhookService) TestWebhook(ctx context.Context, webhookID string) erro
 	}
 
 	// Dispatch with timeout derived from shutdown context
+	s.markInFlight(delivery.ID)
 	testCtx, cancel := context.WithTimeout(s.shutdownCtx, 30*time.Second)
 	go func() {
 		defer cancel()
@@ -361,3 +415,6 @@ func (s *WebhookService) TestWebhook(ctx context.Context, webhookID string) erro
 	}()
 	return nil
 }
+
+// Ensure interface compliance.
+var _ ports.EventDispatcher = (*WebhookService)(nil)
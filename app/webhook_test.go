@@ -3,6 +3,7 @@ package app
 import (
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"sync"
@@ -610,3 +611,176 @@ func TestWebhookService_TestWebhook(t *testing.T) {
 		t.Errorf("Expected webhook_id wh_test_webhook, got %s", d.WebhookID)
 	}
 }
+
+// waitForDelivery polls until the only delivery reaches a final status.
+func waitForDelivery(t *testing.T, store *mockDeliveryStore) webhook.Delivery {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if deliveries := store.getDeliveries(); len(deliveries) == 1 {
+			switch deliveries[0].Status {
+			case webhook.DeliverySuccess, webhook.DeliveryFailed, webhook.DeliveryDeadLetter:
+				return deliveries[0]
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Delivery did not reach a final status within timeout")
+	return webhook.Delivery{}
+}
+
+func TestWebhookService_RetriesUntilDelivered(t *testing.T) {
+	var mu sync.Mutex
+	attempts := 0
+	var badSignatures int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if !webhook.VerifySignature(body, r.Header.Get("X-Webhook-Signature"), "whsec_retry") {
+			badSignatures++
+		}
+		// Fail twice, then succeed
+		if attempts <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	webhookStore := newMockWebhookStore()
+	deliveryStore := newMockDeliveryStore()
+	webhookStore.Create(context.Background(), webhook.Webhook{
+		ID:         "wh_flaky",
+		URL:        server.URL,
+		Secret:     "whsec_retry",
+		Events:     []webhook.EventType{webhook.EventUserCreated},
+		RetryCount: 3,
+		Enabled:    true,
+	})
+
+	svc := NewWebhookService(webhookStore, deliveryStore, zerolog.Nop())
+	svc.SetRetryBackoff(10 * time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	svc.StartRetryWorker(ctx, 5*time.Millisecond)
+	defer svc.StopRetryWorker()
+
+	if err := svc.DispatchEvent(ctx, webhook.EventUserCreated, "usr_new", map[string]interface{}{"email": "new@example.com"}); err != nil {
+		t.Fatalf("DispatchEvent failed: %v", err)
+	}
+
+	d := waitForDelivery(t, deliveryStore)
+	if d.Status != webhook.DeliverySuccess {
+		t.Fatalf("Expected status success, got %s", d.Status)
+	}
+	if d.Attempt != 3 {
+		t.Errorf("Expected delivery on attempt 3, got %d", d.Attempt)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != 3 {
+		t.Errorf("Expected 3 requests, got %d", attempts)
+	}
+	if badSignatures != 0 {
+		t.Errorf("%d of %d requests had an invalid signature", badSignatures, attempts)
+	}
+}
+
+func TestWebhookService_DeadLetterAfterMaxAttempts(t *testing.T) {
+	var mu sync.Mutex
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		mu.Unlock()
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	webhookStore := newMockWebhookStore()
+	deliveryStore := newMockDeliveryStore()
+	webhookStore.Create(context.Background(), webhook.Webhook{
+		ID:         "wh_down",
+		URL:        server.URL,
+		Secret:     "whsec_down",
+		Events:     []webhook.EventType{webhook.EventQuotaExceeded},
+		RetryCount: 2,
+		Enabled:    true,
+	})
+
+	svc := NewWebhookService(webhookStore, deliveryStore, zerolog.Nop())
+	svc.SetRetryBackoff(10 * time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	svc.StartRetryWorker(ctx, 5*time.Millisecond)
+	defer svc.StopRetryWorker()
+
+	svc.DispatchEvent(ctx, webhook.EventQuotaExceeded, "usr_123", map[string]interface{}{})
+
+	d := waitForDelivery(t, deliveryStore)
+	if d.Status != webhook.DeliveryDeadLetter {
+		t.Fatalf("Expected status dead_letter, got %s", d.Status)
+	}
+	if d.Attempt != 2 || d.StatusCode != 500 {
+		t.Errorf("Expected attempt 2 with status 500, got attempt %d with %d", d.Attempt, d.StatusCode)
+	}
+
+	// No further attempts once dead-lettered
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != 2 {
+		t.Errorf("Expected 2 requests, got %d", attempts)
+	}
+}
+
+func TestWebhookService_DispatchOnlyToOwner(t *testing.T) {
+	webhookStore := newMockWebhookStore()
+	deliveryStore := newMockDeliveryStore()
+	for _, wh := range []webhook.Webhook{
+		{ID: "wh_owner", UserID: "usr_1"},
+		{ID: "wh_other", UserID: "usr_2"},
+		{ID: "wh_system"},
+	} {
+		wh.URL = "http://127.0.0.1:0"
+		wh.Events = []webhook.EventType{webhook.EventSubscriptionCancelled}
+		wh.RetryCount = 1
+		wh.Enabled = true
+		webhookStore.Create(context.Background(), wh)
+	}
+
+	svc := NewWebhookService(webhookStore, deliveryStore, zerolog.Nop())
+	defer svc.StopRetryWorker()
+	svc.DispatchEvent(context.Background(), webhook.EventSubscriptionCancelled, "usr_1", map[string]interface{}{})
+
+	got := make(map[string]bool)
+	for _, d := range deliveryStore.getDeliveries() {
+		got[d.WebhookID] = true
+	}
+	if !got["wh_owner"] || !got["wh_system"] || got["wh_other"] || len(got) != 2 {
+		t.Errorf("Deliveries created for %v, want wh_owner and wh_system", got)
+	}
+}
+
+// recordingDispatcher records dispatched events for assertions.
+type recordingDispatcher struct {
+	mu     sync.Mutex
+	events []webhook.Event
+}
+
+func (r *recordingDispatcher) DispatchEvent(ctx context.Context, eventType webhook.EventType, userID string, data map[string]interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, webhook.Event{Type: eventType, UserID: userID, Data: data})
+	return nil
+}
+
+func (r *recordingDispatcher) recorded() []webhook.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]webhook.Event(nil), r.events...)
+}
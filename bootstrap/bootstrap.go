@@ -6,15 +6,19 @@ package bootstrap
 import (
 	"context"
 	cryptotls "crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/artpar/apigate/adapters/accesslog"
 	"github.com/artpar/apigate/adapters/auth"
 	"github.com/artpar/apigate/adapters/clock"
 	"github.com/artpar/apigate/adapters/email"
@@ -22,8 +26,12 @@ import (
 	apihttp "github.com/artpar/apigate/adapters/http"
 	"github.com/artpar/apigate/adapters/http/admin"
 	"github.com/artpar/apigate/adapters/idgen"
+	"github.com/artpar/apigate/adapters/memory"
 	"github.com/artpar/apigate/adapters/metrics"
+	"github.com/artpar/apigate/adapters/oauth"
 	"github.com/artpar/apigate/adapters/payment"
+	"github.com/artpar/apigate/adapters/pwned"
+	redisstore "github.com/artpar/apigate/adapters/redis"
 	"github.com/artpar/apigate/adapters/sqlite"
 	adapterstls "github.com/artpar/apigate/adapters/tls"
 	"github.com/artpar/apigate/app"
@@ -32,14 +40,21 @@ import (
 	"github.com/artpar/apigate/core/convention"
 	"github.com/artpar/apigate/core/events"
 	"github.com/artpar/apigate/core/openapi"
+	domainAuth "github.com/artpar/apigate/domain/auth"
+	"github.com/artpar/apigate/domain/billing"
 	"github.com/artpar/apigate/domain/entitlement"
+	"github.com/artpar/apigate/domain/key"
 	"github.com/artpar/apigate/domain/plan"
+	"github.com/artpar/apigate/domain/proxy"
+	"github.com/artpar/apigate/domain/quota"
 	"github.com/artpar/apigate/domain/settings"
 	"github.com/artpar/apigate/domain/webhook"
+	"github.com/artpar/apigate/pkg/clientip"
 	"github.com/artpar/apigate/ports"
 	"github.com/artpar/apigate/web"
 	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 // Environment variable names for bootstrap configuration.
@@ -51,6 +66,11 @@ const (
 	EnvLogLevel    = "APIGATE_LOG_LEVEL"
 	EnvLogFormat   = "APIGATE_LOG_FORMAT"
 
+	// Module storage (override the storage.* settings)
+	EnvStorageDriver                     = "APIGATE_STORAGE_DRIVER"
+	EnvStorageDSN                        = "APIGATE_STORAGE_DSN"
+	EnvStorageAllowDestructiveMigrations = "APIGATE_STORAGE_ALLOW_DESTRUCTIVE_MIGRATIONS"
+
 	// TLS environment variables (synced to database settings)
 	EnvTLSEnabled      = "APIGATE_TLS_ENABLED"
 	EnvTLSMode         = "APIGATE_TLS_MODE"
@@ -61,6 +81,7 @@ const (
 	EnvTLSHTTPRedirect = "APIGATE_TLS_HTTP_REDIRECT"
 	EnvTLSMinVersion   = "APIGATE_TLS_MIN_VERSION"
 	EnvTLSACMEStaging  = "APIGATE_TLS_ACME_STAGING"
+	EnvTLSClientCA     = "APIGATE_TLS_CLIENT_CA"
 
 	// Web UI environment variables (synced to database settings)
 	EnvWebUIEnabled  = "APIGATE_WEBUI_ENABLED"
@@ -80,8 +101,18 @@ type App struct {
 
 	// Services
 	proxyService     *app.ProxyService
+	proxyHandler     *apihttp.ProxyHandler
 	routeService     *app.RouteService
 	transformService *app.TransformService
+	healthChecker    *app.HealthChecker
+	keyExpiry        *app.KeyExpiryNotifier
+	spikeDetector    *app.SpikeDetector
+	keyUses          *app.KeyUseTracker
+	quotaWarnings    *app.QuotaWarningNotifier
+	overageReporter  *app.OverageReporter
+	localBilling     *app.LocalBillingProvider
+	dunning          *app.DunningService
+	auditLog         *app.AuditLog
 
 	// Module runtime (declarative modules)
 	ModuleRuntime *ModuleRuntime
@@ -96,6 +127,9 @@ type App struct {
 	// Adapters (for cleanup)
 	usageRecorder   ports.UsageRecorder
 	upstream        *apihttp.UpstreamClient
+	redisClient     io.Closer
+	accessLog       io.Closer
+	tracerProvider  *sdktrace.TracerProvider
 	paymentProvider ports.PaymentProvider
 	emailSender     ports.EmailSender
 	webhookService  *app.WebhookService
@@ -139,7 +173,7 @@ func NewWithConfig(cfg Config) (*App, error) {
 
 	// Initialize metrics if enabled
 	s := a.Settings.Get()
-	if s.GetBool("metrics.enabled") {
+	if s.GetBool(settings.KeyMetricsEnabled) {
 		a.Metrics = metrics.New()
 		logger.Info().Msg("prometheus metrics enabled")
 	}
@@ -200,7 +234,25 @@ func (a *App) InitModuleRuntime(rootCmd interface{}) error {
 	}
 
 	// Create module runtime
-	mr, err := NewModuleRuntime(a.DB.DB, cobraCmd, a.Logger, ModuleConfig{})
+	// Module storage: environment overrides the storage.* settings
+	s := a.Settings.Get()
+	driver := s.Get(settings.KeyStorageDriver)
+	if v := os.Getenv(EnvStorageDriver); v != "" {
+		driver = v
+	}
+	dsn := s.Get(settings.KeyStorageDSN)
+	if v := os.Getenv(EnvStorageDSN); v != "" {
+		dsn = v
+	}
+	allowDestructive := s.GetBool(settings.KeyStorageAllowDestructiveMigrations)
+	if v := os.Getenv(EnvStorageAllowDestructiveMigrations); v != "" {
+		allowDestructive = v == "true" || v == "1"
+	}
+	mr, err := NewModuleRuntime(a.DB.DB, cobraCmd, a.Logger, ModuleConfig{
+		StorageDriver:              driver,
+		StorageDSN:                 dsn,
+		AllowDestructiveMigrations: allowDestructive,
+	})
 	if err != nil {
 		return fmt.Errorf("create module runtime: %w", err)
 	}
@@ -270,11 +322,33 @@ func (a *App) initHTTPServer() error {
 		RateWindow:       s.GetInt(settings.KeyRateLimitWindowSecs, 60),
 		Entitlements:     ents,
 		PlanEntitlements: planEnts,
+		MaxRequestBody:   int64(s.GetInt(settings.KeyProxyMaxRequestBody, 10<<20)),
+		MaxResponseBody:  int64(s.GetInt(settings.KeyProxyMaxResponseBody, 50<<20)),
+		SignatureMaxSkew: s.GetDuration(settings.KeyAuthHMACMaxSkew, key.DefaultSignatureMaxSkew),
+		PublicURL:        s.Get(settings.KeyProxyPublicURL),
 	}
 
 	// Create proxy service
 	a.proxyService = app.NewProxyService(deps, proxyCfg)
 
+	// OpenTelemetry tracing of the proxy pipeline (no-op without an endpoint)
+	if endpoint := s.Get(settings.KeyTracingOTLPEndpoint); endpoint != "" {
+		tp, err := newTracerProvider(ctx, endpoint, s.GetOrDefault(settings.KeyTracingServiceName, "apigate"))
+		if err != nil {
+			return err
+		}
+		a.tracerProvider = tp
+		a.proxyService.SetTracerProvider(tp)
+		a.Logger.Info().Str("endpoint", endpoint).Msg("opentelemetry tracing enabled")
+	}
+
+	// Group keys are metered on their group's plan
+	groupStore := sqlite.NewGroupStore(a.DB)
+	a.proxyService.SetGroupStore(groupStore)
+
+	// Response cache for routes with a cache TTL (in-memory, per instance)
+	a.proxyService.SetResponseCache(memory.NewResponseCache(deps.Clock, s.GetInt(settings.KeyProxyCacheMaxEntries, memory.DefaultResponseCacheEntries)))
+
 	// Create and wire route service for dynamic routing
 	routeStore := sqlite.NewRouteStore(a.DB)
 	upstreamStore := sqlite.NewUpstreamStore(a.DB)
@@ -294,6 +368,11 @@ func (a *App) initHTTPServer() error {
 		a.Logger.Warn().Err(err).Msg("failed to start route service, continuing with empty routes")
 	}
 
+	// Start active upstream health checks (first round runs before traffic is served)
+	a.healthChecker = app.NewHealthChecker(a.routeService, a.upstream, deps.Clock, a.Logger, app.HealthCheckerConfig{})
+	a.routeService.SetUpstreamHealth(a.healthChecker)
+	a.healthChecker.Start(ctx)
+
 	// Wire router reloader for hook-triggered reloads
 	SetRouterReloader(a.routeService)
 
@@ -308,6 +387,19 @@ func (a *App) initHTTPServer() error {
 	if jwtSecret := s.Get(settings.KeyAuthJWTSecret); jwtSecret != "" {
 		tokenService := auth.NewTokenService(jwtSecret, 7*24*time.Hour)
 		a.proxyService.SetTokenService(tokenService)
+		a.proxyService.SetSessionStore(sqlite.NewSessionStore(a.DB))
+	}
+
+	// Accept JWTs from an external identity provider, validated against its JWKS
+	if jwksURL := s.Get(settings.KeyAuthJWKSURL); jwksURL != "" {
+		a.proxyService.SetBearerValidator(auth.NewJWKSValidator(auth.JWKSConfig{
+			URL:             jwksURL,
+			Issuer:          s.Get(settings.KeyAuthJWKSIssuer),
+			Audience:        s.Get(settings.KeyAuthJWKSAudience),
+			UserClaim:       s.Get(settings.KeyAuthJWKSUserClaim),
+			RefreshInterval: s.GetDuration(settings.KeyAuthJWKSRefresh, auth.DefaultJWKSRefreshInterval),
+		}))
+		a.Logger.Info().Str("jwks_url", jwksURL).Msg("identity provider JWT authentication enabled")
 	}
 
 	a.Logger.Info().Msg("route and transform services initialized")
@@ -320,6 +412,46 @@ func (a *App) initHTTPServer() error {
 		proxyHandler = apihttp.NewProxyHandler(a.proxyService, a.Logger)
 	}
 	proxyHandler.SetStreamingUpstream(a.upstream)
+	proxyHandler.SetGRPCUpstream(a.upstream)
+	proxyHandler.SetWebSocketUpstream(a.upstream)
+
+	// Client IPs are taken from X-Forwarded-For only across trusted proxy hops
+	trustedProxies, err := clientip.ParseTrusted(strings.Split(s.Get(settings.KeyProxyTrustedProxies), ","))
+	if err != nil {
+		return fmt.Errorf("%s: %w", settings.KeyProxyTrustedProxies, err)
+	}
+	proxyHandler.SetTrustedProxies(trustedProxies)
+
+	// Trusted clients can ask for headers explaining how a request was routed
+	debugSources, err := clientip.ParseTrusted(strings.Split(s.Get(settings.KeyProxyDebugSources), ","))
+	if err != nil {
+		return fmt.Errorf("%s: %w", settings.KeyProxyDebugSources, err)
+	}
+	proxyHandler.SetDebugSources(debugSources)
+
+	// Compress eligible buffered responses for clients that accept it
+	proxyHandler.SetCompression(proxy.CompressionConfig{
+		Enabled: s.GetBool(settings.KeyProxyCompressionEnabled),
+		MinSize: s.GetInt(settings.KeyProxyCompressionMinSize, 1024),
+		Types:   strings.Split(s.Get(settings.KeyProxyCompressionTypes), ","),
+	})
+
+	// Maintenance mode follows settings changes (see Reload)
+	proxyHandler.SetMaintenance(maintenanceConfig(s))
+	a.proxyHandler = proxyHandler
+
+	// Access log file, independent of the application log level
+	if path := s.Get(settings.KeyAccessLogPath); path != "" {
+		maxSize := int64(s.GetInt(settings.KeyAccessLogMaxSizeMB, 100)) << 20
+		file, err := accesslog.OpenRotatingFile(path, maxSize, s.GetInt(settings.KeyAccessLogMaxBackups, 5))
+		if err != nil {
+			return err
+		}
+		a.accessLog = file
+		proxyHandler.SetAccessLog(accesslog.New(file))
+		a.Logger.Info().Str("path", path).Msg("access log enabled")
+	}
+
 	healthHandler := apihttp.NewHealthHandler(a.upstream)
 
 	// Create shared stores for admin and web handlers
@@ -329,6 +461,7 @@ func (a *App) initHTTPServer() error {
 	bcryptHasher := hasher.NewBcrypt(0)
 	sessionStore := sqlite.NewSessionStore(a.DB)
 	tokenStore := sqlite.NewTokenStore(a.DB)
+	oauthProviders, oauthRedirectURLs := oauthProviders(s)
 
 	// Create email sender (used by both admin and portal)
 	emailSender, err := email.NewSender(s)
@@ -352,6 +485,10 @@ func (a *App) initHTTPServer() error {
 	deliveryStore := sqlite.NewDeliveryStore(a.DB.DB)
 	a.webhookService = app.NewWebhookService(webhookStore, deliveryStore, a.Logger)
 
+	// Publish internal events (user.created, quota.exceeded) to webhook subscribers
+	deps.Users = app.NewUserEvents(deps.Users, a.webhookService)
+	a.proxyService.SetEventDispatcher(a.webhookService)
+
 	// Create subscription store for payment webhooks
 	subscriptionStore := sqlite.NewSubscriptionStore(a.DB)
 
@@ -362,6 +499,53 @@ func (a *App) initHTTPServer() error {
 	a.webhookService.StartRetryWorker(ctx, time.Minute)
 	a.Logger.Info().Msg("webhook service initialized with retry worker")
 
+	// Alert key owners about usage spikes on plans with a spike threshold
+	a.spikeDetector = app.NewSpikeDetector(deps.Users, emailSender, a.webhookService, a.Logger, app.SpikeDetectorConfig{
+		Window:           s.GetDuration(settings.KeyAnomalyWindow, 5*time.Minute),
+		Baseline:         s.GetDuration(settings.KeyAnomalyBaseline, time.Hour),
+		ThrottleDuration: s.GetDuration(settings.KeyAnomalyThrottleDuration, time.Hour),
+		AppName:          s.GetOrDefault(settings.KeyPortalAppName, "APIGate"),
+		BaseURL:          s.Get(settings.KeyPortalBaseURL),
+	})
+	a.proxyService.SetSpikeDetector(a.spikeDetector)
+
+	// Batch API key last-used writes instead of writing on every request
+	a.keyUses = app.NewKeyUseTracker(deps.Keys, a.Logger, app.KeyUseTrackerConfig{})
+	a.keyUses.Start()
+	a.proxyService.SetKeyUseTracker(a.keyUses)
+
+	// Warn key owners by email before their API keys expire
+	if days := s.GetInt(settings.KeyAuthKeyExpiryWarningDays, 7); days > 0 && s.GetOrDefault(settings.KeyEmailProvider, "none") != "none" {
+		if expiryStore, ok := deps.Keys.(ports.KeyExpiryStore); ok {
+			a.keyExpiry = app.NewKeyExpiryNotifier(expiryStore, deps.Users, emailSender, deps.Clock, a.Logger, app.KeyExpiryNotifierConfig{
+				Window:  time.Duration(days) * 24 * time.Hour,
+				AppName: s.GetOrDefault(settings.KeyPortalAppName, "APIGate"),
+				BaseURL: s.Get(settings.KeyPortalBaseURL),
+			})
+			a.keyExpiry.Start(ctx)
+		}
+	}
+
+	// Warn users by email as their usage approaches and reaches the plan quota
+	if thresholds := quota.ParseWarningThresholds(s.GetOrDefault(settings.KeyQuotaWarningThresholds, "80,100")); len(thresholds) > 0 && s.GetOrDefault(settings.KeyEmailProvider, "none") != "none" {
+		if warningStore, ok := deps.Quota.(ports.QuotaWarningStore); ok {
+			a.quotaWarnings = app.NewQuotaWarningNotifier(deps.Users, planStore, usageStore, warningStore, emailSender, deps.Clock, a.Logger, app.QuotaWarningNotifierConfig{
+				Thresholds: thresholds,
+				AppName:    s.GetOrDefault(settings.KeyPortalAppName, "APIGate"),
+				BaseURL:    s.Get(settings.KeyPortalBaseURL),
+			})
+			a.quotaWarnings.Start(ctx)
+		}
+	}
+
+	// Record admin mutations to the append-only audit log
+	auditStore := sqlite.NewAuditStore(a.DB)
+	a.auditLog = app.NewAuditLog(auditStore, a.Logger, app.AuditLogConfig{})
+	a.auditLog.Start()
+
+	// Replay repeated Idempotency-Key requests to the admin API and portal
+	idempotencyStore := memory.NewResponseCache(deps.Clock, memory.DefaultResponseCacheEntries)
+
 	// Create OpenAPI service for unified documentation (before admin handler for cache invalidation)
 	openAPIService := openapi.NewService(openapi.ServiceConfig{
 		RouteStore:    routeStore,
@@ -402,8 +586,18 @@ func (a *App) initHTTPServer() error {
 			if openAPIService != nil {
 				openAPIService.InvalidateCache()
 			}
+			// Pick up settings edited directly in the database (e.g. maintenance mode)
+			if err := a.Reload(); err != nil {
+				return fmt.Errorf("reload settings: %w", err)
+			}
 			return nil
 		},
+		KeyRotationGrace: s.GetDuration(settings.KeyAuthKeyRotationGrace, key.DefaultRotationGrace),
+		Audit:            a.auditLog,
+		AuditStore:       auditStore,
+		Idempotency:      idempotencyStore,
+		RequestTester:    a.proxyService,
+		UpstreamHealth:   a.healthChecker,
 	})
 
 	// Create web UI handler
@@ -433,6 +627,7 @@ func (a *App) initHTTPServer() error {
 		},
 		Logger:        a.Logger,
 		Hasher:        bcryptHasher,
+		Audit:         a.auditLog,
 		JWTSecret:     s.Get(settings.KeyAuthJWTSecret),
 		ExprValidator: a.transformService,
 		RouteTester:   a.routeService,
@@ -462,6 +657,26 @@ func (a *App) initHTTPServer() error {
 	}
 	a.paymentProvider = paymentProvider
 
+	// Bill usage past the quota of allow_overage plans through the payment provider
+	if paymentProvider.Name() != "none" {
+		if reportStore, ok := deps.Quota.(ports.OverageReportStore); ok {
+			a.overageReporter = app.NewOverageReporter(deps.Users, planStore, subscriptionStore, usageStore, reportStore, paymentProvider, deps.Clock, a.Logger, app.OverageReporterConfig{
+				Interval: s.GetDuration(settings.KeyPaymentOverageInterval, time.Hour),
+			})
+			a.overageReporter.Start(ctx)
+		}
+	}
+
+	// Invoice subscriptions locally when no payment processor is used
+	invoiceStore := sqlite.NewInvoiceStore(a.DB)
+	var billingProvider ports.BillingProvider
+	if s.GetOrDefault(settings.KeyBillingProvider, "none") == app.LocalBillingProviderName {
+		a.localBilling = app.NewLocalBillingProvider(deps.Users, planStore, subscriptionStore, invoiceStore, usageStore, deps.IDGen, deps.Clock, a.Logger, app.LocalBillingConfig{})
+		a.localBilling.Start(ctx)
+		billingProvider = a.localBilling
+		a.Logger.Info().Msg("local billing enabled")
+	}
+
 	// Create user portal handler (if enabled)
 	var portalRouter http.Handler
 	if s.GetBool(settings.KeyPortalEnabled) {
@@ -478,11 +693,24 @@ func (a *App) initHTTPServer() error {
 			PlanEntitlements: deps.PlanEntitlements,
 			Webhooks:         webhookStore,
 			Deliveries:       deliveryStore,
+			Idempotency:      idempotencyStore,
 			Logger:           a.Logger,
 			Hasher:           bcryptHasher,
 			IDGen:            deps.IDGen,
 			Payment:          paymentProvider,
-			OpenAPIService:   openAPIService,
+			Billing:          billingProvider,
+			// Only consulted when auth.password_check_breached is on
+			BreachedPasswords: pwned.New(pwned.Config{}),
+			OAuthProviders:    oauthProviders,
+			OAuthIdentities:   sqlite.NewOAuthIdentityStore(a.DB),
+			OAuthStates:       sqlite.NewOAuthStateStore(a.DB),
+			OAuthRedirectURLs: oauthRedirectURLs,
+			Groups:            groupStore,
+			GroupMembers:      sqlite.NewGroupMemberStore(a.DB),
+			GroupInvites:      sqlite.NewGroupInviteStore(a.DB),
+			Subscriptions:     subscriptionStore,
+			Invoices:          invoiceStore,
+			OpenAPIService:    openAPIService,
 			IsSetup: func() bool {
 				users, err := deps.Users.List(context.Background(), 1, 0)
 				return err == nil && len(users) > 0
@@ -490,6 +718,9 @@ func (a *App) initHTTPServer() error {
 			JWTSecret:        s.Get(settings.KeyAuthJWTSecret),
 			BaseURL:          s.Get(settings.KeyPortalBaseURL),
 			AppName:          s.GetOrDefault(settings.KeyPortalAppName, "APIGate"),
+			KeyRotationGrace: s.GetDuration(settings.KeyAuthKeyRotationGrace, key.DefaultRotationGrace),
+			SessionTTL:       s.GetDuration(settings.KeyAuthSessionTTL, domainAuth.DefaultSessionTTL),
+			RememberMeTTL:    s.GetDuration(settings.KeyAuthRememberMeTTL, domainAuth.DefaultRememberMeTTL),
 		})
 		if err != nil {
 			return fmt.Errorf("create portal handler: %w", err)
@@ -515,6 +746,18 @@ func (a *App) initHTTPServer() error {
 		idgen.UUID{},
 		a.Logger,
 	)
+	paymentWebhookService.SetEventDispatcher(a.webhookService)
+
+	// Chase failed payments: reminders, then suspension to the default plan
+	if schedule := billing.ParseDunningSchedule(s.GetOrDefault(settings.KeyPaymentDunningSchedule, "3,7,14")); len(schedule) > 0 && paymentProvider.Name() != "none" {
+		a.dunning = app.NewDunningService(deps.Users, planStore, subscriptionStore, emailSender, deps.Clock, a.Logger, app.DunningConfig{
+			Schedule: schedule,
+			AppName:  s.GetOrDefault(settings.KeyPortalAppName, "APIGate"),
+			BaseURL:  s.Get(settings.KeyPortalBaseURL),
+		})
+		a.dunning.Start(ctx)
+		paymentWebhookService.SetDunning(a.dunning)
+	}
 
 	// Create payment webhook HTTP handler
 	paymentWebhookHandler := web.NewPaymentWebhookHandler(
@@ -548,6 +791,7 @@ func (a *App) initHTTPServer() error {
 
 	routerCfg := apihttp.RouterConfig{
 		Metrics:               a.Metrics,
+		TrustedProxies:        trustedProxies,
 		EnableOpenAPI:         s.GetBool("openapi.enabled"),
 		AdminHandler:          adminHandler.Router(),
 		AuthHandler:           adminHandler.AuthRouter(),
@@ -558,6 +802,7 @@ func (a *App) initHTTPServer() error {
 		DocsHandler:           docsRouter,
 		PaymentWebhookHandler: paymentWebhookHandler,
 		MeterHandler:          adminHandler.MeterRouter(),
+		UpstreamHealthHandler: apihttp.NewUpstreamHealthHandler(a.healthChecker),
 		RouteService:          a.routeService, // Enable priority-based routing
 
 		// Configurable handler paths (with backward-compatible defaults)
@@ -577,6 +822,11 @@ func (a *App) initHTTPServer() error {
 		MeterEnabled:           s.GetBool(settings.KeyMeterEnabled),
 	}
 
+	if a.Metrics != nil && s.GetBool(settings.KeyMetricsRequireAuth) {
+		routerCfg.MetricsAuth = adminHandler.AuthMiddleware
+		a.Logger.Info().Msg("metrics endpoint requires admin authentication")
+	}
+
 	// Add portal auth handler for SPA frontends (if module runtime is initialized)
 	if a.ModuleRuntime != nil {
 		routerCfg.PortalAuthHandler = a.ModuleRuntime.AuthHandler()
@@ -592,10 +842,12 @@ func (a *App) initHTTPServer() error {
 		routerCfg.ModuleHandler = a.ModuleRuntime.Handler()
 		a.Logger.Info().Str("path", routerCfg.ModuleBasePath).Msg("module handler mounted")
 
-		// Add metrics handler from exporter
-		if metricsHandler := a.ModuleRuntime.MetricsHandler(); metricsHandler != nil {
-			routerCfg.MetricsHandler = metricsHandler
-			a.Logger.Info().Msg("prometheus metrics handler mounted at /metrics")
+		// Without gateway metrics, /metrics serves the module analytics exporter
+		if a.Metrics == nil {
+			if metricsHandler := a.ModuleRuntime.MetricsHandler(); metricsHandler != nil {
+				routerCfg.MetricsHandler = metricsHandler
+				a.Logger.Info().Msg("prometheus metrics handler mounted at /metrics")
+			}
 		}
 
 		// Bridge event bus to webhook service
@@ -603,8 +855,14 @@ func (a *App) initHTTPServer() error {
 		if a.webhookService != nil {
 			a.subscribeWebhooksToEvents()
 		}
+
+		// Settings changed through the module API take effect without a restart
+		a.subscribeSettingsReload()
 	}
 
+	// Proxy routes may not claim paths served by built-in endpoints
+	adminHandler.SetReservedPaths(apihttp.ReservedPaths(routerCfg))
+
 	router := apihttp.NewRouterWithConfig(proxyHandler, healthHandler, a.Logger, routerCfg)
 
 	// Get server config from env (bootstrap) or settings
@@ -621,12 +879,20 @@ func (a *App) initHTTPServer() error {
 	readTimeout := s.GetDuration(settings.KeyServerReadTimeout, 30*time.Second)
 	writeTimeout := s.GetDuration(settings.KeyServerWriteTimeout, 60*time.Second)
 
+	// Accept HTTP/2 without TLS (h2c) as well so plaintext gRPC clients can connect
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+
 	a.HTTPServer = &http.Server{
 		Addr:         addr,
 		Handler:      router,
 		ReadTimeout:  readTimeout,
 		WriteTimeout: writeTimeout,
+		Protocols:    protocols,
 	}
+	a.HTTPServer.RegisterOnShutdown(proxyHandler.CloseWebSockets)
 
 	a.Logger.Info().Str("addr", addr).Msg("http server configured")
 	return nil
@@ -650,6 +916,7 @@ func (a *App) syncTLSEnvToSettings(ctx context.Context) error {
 		EnvTLSHTTPRedirect: settings.KeyTLSHTTPRedirect,
 		EnvTLSMinVersion:   settings.KeyTLSMinVersion,
 		EnvTLSACMEStaging:  settings.KeyTLSACMEStaging,
+		EnvTLSClientCA:     settings.KeyTLSClientCAPath,
 	}
 
 	for envVar, settingKey := range envToSettingMap {
@@ -737,14 +1004,47 @@ func (a *App) initTLS() error {
 
 	switch a.tlsMode {
 	case "acme":
-		return a.initACMETLS(s, minVersion)
+		if err := a.initACMETLS(s, minVersion); err != nil {
+			return err
+		}
 	case "manual":
-		return a.initManualTLS(s, minVersion)
+		if err := a.initManualTLS(s, minVersion); err != nil {
+			return err
+		}
 	default:
 		a.Logger.Warn().Str("mode", a.tlsMode).Msg("unknown TLS mode, disabling TLS")
 		a.tlsEnabled = false
 		return nil
 	}
+
+	return a.initClientCAs(s)
+}
+
+// initClientCAs loads the CAs trusted to issue client certificates. The TLS
+// server asks for a certificate without requiring one, so routes using other
+// auth methods keep working and mtls routes verify it per request.
+func (a *App) initClientCAs(s settings.Settings) error {
+	caPath := s.Get(settings.KeyTLSClientCAPath)
+	if caPath == "" {
+		return nil
+	}
+
+	pemData, err := os.ReadFile(caPath)
+	if err != nil {
+		return fmt.Errorf("read client CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemData) {
+		return fmt.Errorf("client CA bundle %s contains no certificates", caPath)
+	}
+
+	a.tlsConfig.ClientAuth = cryptotls.RequestClientCert
+	if a.proxyService != nil {
+		a.proxyService.SetClientCAs(pool)
+	}
+
+	a.Logger.Info().Str("client_ca_path", caPath).Msg("client certificate authentication enabled")
+	return nil
 }
 
 // initACMETLS initializes ACME (Let's Encrypt) TLS.
@@ -837,9 +1137,24 @@ func (a *App) buildDependencies(s settings.Settings) (app.ProxyDeps, error) {
 	// User store
 	deps.Users = sqlite.NewUserStore(a.DB)
 
-	// Rate limit store (SQLite for persistence across restarts)
-	// This ensures rate limits survive application restarts
-	deps.RateLimit = sqlite.NewRateLimitStore(a.DB)
+	// Rate limit store: Redis when configured so all instances share one limit,
+	// otherwise SQLite for persistence across restarts
+	if redisURL := s.Get(settings.KeyRateLimitRedisURL); redisURL != "" {
+		client, err := redisstore.NewClient(redisURL)
+		if err != nil {
+			return deps, fmt.Errorf("parse %s: %w", settings.KeyRateLimitRedisURL, err)
+		}
+		pingCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := client.Ping(pingCtx).Err(); err != nil {
+			a.Logger.Warn().Err(err).Msg("redis rate limit store unreachable, requests are allowed until it recovers")
+		}
+		cancel()
+		a.redisClient = client
+		deps.RateLimit = redisstore.NewRateLimitStore(client)
+		a.Logger.Info().Str("addr", client.Options().Addr).Msg("using redis rate limit store")
+	} else {
+		deps.RateLimit = sqlite.NewRateLimitStore(a.DB)
+	}
 
 	// Usage recorder
 	usageStore := sqlite.NewUsageStore(a.DB)
@@ -861,6 +1176,8 @@ func (a *App) buildDependencies(s settings.Settings) (app.ProxyDeps, error) {
 		Timeout:         s.GetDuration(settings.KeyUpstreamTimeout, 30*time.Second),
 		MaxIdleConns:    s.GetInt(settings.KeyUpstreamMaxIdleConns, 100),
 		IdleConnTimeout: s.GetDuration(settings.KeyUpstreamIdleConnTimeout, 90*time.Second),
+		DialTimeout:           s.GetDuration(settings.KeyUpstreamDialTimeout, 10*time.Second),
+		ResponseHeaderTimeout: s.GetDuration(settings.KeyUpstreamResponseHeaderTimeout, 0),
 	})
 	if err != nil {
 		return deps, fmt.Errorf("build upstream: %w", err)
@@ -878,11 +1195,18 @@ func (a *App) buildDependencies(s settings.Settings) (app.ProxyDeps, error) {
 func (a *App) loadPlans(ctx context.Context) []plan.Plan {
 	// Load plans from database (with quota fields using COALESCE for backwards compatibility)
 	rows, err := a.DB.DB.QueryContext(ctx, `
-		SELECT id, name, rate_limit_per_minute, requests_per_month, price_monthly, overage_price,
+		SELECT id, name, rate_limit_per_minute, COALESCE(rate_limit_burst, 0) as rate_limit_burst,
+		       COALESCE(max_concurrent, 0) as max_concurrent,
+		       COALESCE(spike_threshold, 0) as spike_threshold,
+		       COALESCE(spike_min_requests, 0) as spike_min_requests,
+		       COALESCE(spike_throttle_rpm, 0) as spike_throttle_rpm,
+		       COALESCE(allow_overage, 0) as allow_overage,
+		       requests_per_month, price_monthly, overage_price,
 		       COALESCE(quota_enforce_mode, 'hard') as quota_enforce_mode,
 		       COALESCE(quota_grace_pct, 0.05) as quota_grace_pct,
 		       COALESCE(meter_type, 'requests') as meter_type,
-		       COALESCE(estimated_cost_per_req, 1.0) as estimated_cost_per_req
+		       COALESCE(estimated_cost_per_req, 1.0) as estimated_cost_per_req,
+		       COALESCE(quota_period, 'calendar_month') as quota_period
 		FROM plans WHERE enabled = 1
 	`)
 	if err != nil {
@@ -896,6 +1220,7 @@ func (a *App) loadPlans(ctx context.Context) []plan.Plan {
 			QuotaGracePct:       0.05,
 			MeterType:           plan.MeterTypeRequests,
 			EstimatedCostPerReq: 1.0,
+			QuotaPeriod:         plan.QuotaPeriodCalendarMonth,
 		}}
 	}
 	defer rows.Close()
@@ -903,8 +1228,8 @@ func (a *App) loadPlans(ctx context.Context) []plan.Plan {
 	var plans []plan.Plan
 	for rows.Next() {
 		var p plan.Plan
-		var enforceMode, meterType string
-		if err := rows.Scan(&p.ID, &p.Name, &p.RateLimitPerMinute, &p.RequestsPerMonth, &p.PriceMonthly, &p.OveragePrice, &enforceMode, &p.QuotaGracePct, &meterType, &p.EstimatedCostPerReq); err != nil {
+		var enforceMode, meterType, quotaPeriod string
+		if err := rows.Scan(&p.ID, &p.Name, &p.RateLimitPerMinute, &p.RateLimitBurst, &p.MaxConcurrent, &p.SpikeThreshold, &p.SpikeMinRequests, &p.SpikeThrottleRPM, &p.AllowOverage, &p.RequestsPerMonth, &p.PriceMonthly, &p.OveragePrice, &enforceMode, &p.QuotaGracePct, &meterType, &p.EstimatedCostPerReq, &quotaPeriod); err != nil {
 			continue
 		}
 		// Convert enforce mode string to type
@@ -923,6 +1248,15 @@ func (a *App) loadPlans(ctx context.Context) []plan.Plan {
 		default:
 			p.MeterType = plan.MeterTypeRequests
 		}
+		// Convert quota period string to type
+		switch quotaPeriod {
+		case "calendar_week":
+			p.QuotaPeriod = plan.QuotaPeriodCalendarWeek
+		case "rolling_30d":
+			p.QuotaPeriod = plan.QuotaPeriodRolling30d
+		default:
+			p.QuotaPeriod = plan.QuotaPeriodCalendarMonth
+		}
 		plans = append(plans, p)
 	}
 
@@ -936,6 +1270,7 @@ func (a *App) loadPlans(ctx context.Context) []plan.Plan {
 			QuotaGracePct:       0.05,
 			MeterType:           plan.MeterTypeRequests,
 			EstimatedCostPerReq: 1.0,
+			QuotaPeriod:         plan.QuotaPeriodCalendarMonth,
 		}}
 	}
 	return plans
@@ -1018,18 +1353,41 @@ func (a *App) Run() error {
 		}()
 	}
 
-	// Wait for interrupt or error
+	// Wait for interrupt or error; SIGHUP reloads routes without a restart
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-
-	select {
-	case err := <-errCh:
-		return fmt.Errorf("server error: %w", err)
-	case sig := <-quit:
-		a.Logger.Info().Str("signal", sig.String()).Msg("shutting down")
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case err := <-errCh:
+			// Still flush buffered usage and stop background services
+			a.Shutdown()
+			return fmt.Errorf("server error: %w", err)
+		case <-hup:
+			a.reloadRoutes()
+		case sig := <-quit:
+			a.Logger.Info().Str("signal", sig.String()).Msg("shutting down")
+			return a.Shutdown()
+		}
 	}
+}
 
-	return a.Shutdown()
+// reloadRoutes reloads the route table from the database. An invalid table is
+// rejected by the route service and the previous routes stay live.
+func (a *App) reloadRoutes() {
+	if a.routeService == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := a.routeService.Reload(ctx); err != nil {
+		a.Logger.Error().Err(err).Msg("route reload failed")
+		return
+	}
+	a.Logger.Info().Int("routes", len(a.routeService.GetRoutes())).Msg("routes reloaded")
 }
 
 // startACMEChallengeServer starts an HTTP server for ACME HTTP-01 challenges.
@@ -1107,8 +1465,14 @@ func (a *App) startHTTPRedirectServer(errCh chan error) error {
 	return nil
 }
 
-// Shutdown gracefully stops the application.
+// Shutdown gracefully stops the application. The HTTP servers stop accepting
+// connections first and in-flight requests, including streams, get up to
+// server.shutdown_timeout to finish before their connections are closed.
+// Background services and the usage recorder are stopped and flushed after
+// that, so work done by the drained requests is not lost.
 func (a *App) Shutdown() error {
+	a.drainHTTP()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
@@ -1119,34 +1483,51 @@ func (a *App) Shutdown() error {
 		}
 	}
 
-	// Stop route service refresh loop
+	// Stop route service refresh loop and health checks
 	if a.routeService != nil {
 		a.routeService.Stop()
 	}
+	if a.healthChecker != nil {
+		a.healthChecker.Stop()
+	}
+	if a.keyExpiry != nil {
+		a.keyExpiry.Stop()
+	}
+	if a.spikeDetector != nil {
+		a.spikeDetector.Wait()
+	}
+	if a.quotaWarnings != nil {
+		a.quotaWarnings.Stop()
+	}
+	if a.overageReporter != nil {
+		a.overageReporter.Stop()
+	}
+	if a.localBilling != nil {
+		a.localBilling.Stop()
+	}
+	if a.dunning != nil {
+		a.dunning.Stop()
+	}
+
+	// Write any queued audit entries before the database closes
+	if a.auditLog != nil {
+		a.auditLog.Stop()
+	}
 
 	// Stop webhook retry worker
 	if a.webhookService != nil {
 		a.webhookService.StopRetryWorker()
 	}
 
-	// Shutdown HTTP challenge server (ACME or redirect)
-	if a.httpChallenge != nil {
-		if err := a.httpChallenge.Shutdown(ctx); err != nil {
-			a.Logger.Error().Err(err).Msg("http challenge server shutdown error")
-		}
-	}
-
-	// Shutdown HTTP/HTTPS server
-	if a.HTTPServer != nil {
-		if err := a.HTTPServer.Shutdown(ctx); err != nil {
-			a.Logger.Error().Err(err).Msg("http server shutdown error")
-		}
+	// Write buffered key last uses before the database closes
+	if a.keyUses != nil {
+		a.keyUses.Stop()
 	}
 
-	// Flush usage recorder
+	// Flush usage recorder (bounded by its close timeout)
 	if a.usageRecorder != nil {
 		if err := a.usageRecorder.Close(); err != nil {
-			a.Logger.Error().Err(err).Msg("usage recorder close error")
+			a.Logger.Error().Err(err).Msg("usage events lost on shutdown")
 		}
 	}
 
@@ -1155,6 +1536,27 @@ func (a *App) Shutdown() error {
 		a.upstream.Close()
 	}
 
+	// Flush buffered spans
+	if a.tracerProvider != nil {
+		if err := a.tracerProvider.Shutdown(ctx); err != nil {
+			a.Logger.Error().Err(err).Msg("tracer provider shutdown error")
+		}
+	}
+
+	// Close access log (after the server has stopped writing to it)
+	if a.accessLog != nil {
+		if err := a.accessLog.Close(); err != nil {
+			a.Logger.Error().Err(err).Msg("access log close error")
+		}
+	}
+
+	// Close shared rate limit store connection
+	if a.redisClient != nil {
+		if err := a.redisClient.Close(); err != nil {
+			a.Logger.Error().Err(err).Msg("redis client close error")
+		}
+	}
+
 	// Close capability container (releases provider resources)
 	if a.Capabilities != nil {
 		if err := a.Capabilities.Close(); err != nil {
@@ -1173,6 +1575,46 @@ func (a *App) Shutdown() error {
 	return nil
 }
 
+// drainHTTP stops the HTTP servers from accepting new connections and waits
+// for in-flight requests to finish. Connections still busy when the shutdown
+// timeout expires are closed. Proxied WebSocket connections are closed right
+// away and waited for within the same timeout, so their usage is recorded
+// before the usage recorder is flushed.
+func (a *App) drainHTTP() {
+	timeout := 30 * time.Second
+	if a.Settings != nil {
+		timeout = a.Settings.Get().GetDuration(settings.KeyServerShutdownTimeout, timeout)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	drain := func(name string, srv *http.Server) {
+		defer wg.Done()
+		if err := srv.Shutdown(ctx); err != nil {
+			a.Logger.Warn().Err(err).Str("server", name).Dur("timeout", timeout).
+				Msg("in-flight requests did not finish before shutdown timeout, closing connections")
+			srv.Close()
+		}
+	}
+	if a.HTTPServer != nil {
+		wg.Add(1)
+		go drain("http", a.HTTPServer)
+	}
+	if a.httpChallenge != nil {
+		wg.Add(1)
+		go drain("http_challenge", a.httpChallenge)
+	}
+	wg.Wait()
+
+	if a.proxyHandler != nil {
+		if err := a.proxyHandler.WaitWebSockets(ctx); err != nil {
+			a.Logger.Warn().Err(err).Dur("timeout", timeout).
+				Msg("websocket connections did not close before shutdown timeout")
+		}
+	}
+}
+
 // Reload reloads settings from the database.
 func (a *App) Reload() error {
 	ctx := context.Background()
@@ -1196,10 +1638,51 @@ func (a *App) Reload() error {
 		)
 	}
 
+	if a.proxyHandler != nil {
+		a.proxyHandler.SetMaintenance(maintenanceConfig(s))
+	}
+
 	a.Logger.Info().Msg("settings reloaded from database")
 	return nil
 }
 
+// maintenanceConfig returns the maintenance mode configured in settings.
+func maintenanceConfig(s settings.Settings) proxy.Maintenance {
+	return proxy.Maintenance{
+		Enabled:    s.GetBool(settings.KeyMaintenanceEnabled),
+		RetryAfter: s.GetDuration(settings.KeyMaintenanceRetryAfter, 5*time.Minute),
+		Message:    s.Get(settings.KeyMaintenanceMessage),
+		JSONBody:   s.Get(settings.KeyMaintenanceJSONBody),
+		HTMLBody:   s.Get(settings.KeyMaintenanceHTMLBody),
+	}
+}
+
+// oauthProviders returns the social login providers enabled in settings,
+// keyed by name, with any configured redirect URLs.
+func oauthProviders(s settings.Settings) (map[string]ports.OAuthProvider, map[string]string) {
+	providers := make(map[string]ports.OAuthProvider)
+	redirectURLs := make(map[string]string)
+	if !s.GetBool(settings.KeyOAuthEnabled) {
+		return providers, redirectURLs
+	}
+
+	if s.GetBool(settings.KeyOAuthGoogleEnabled) && s.Get(settings.KeyOAuthGoogleClientID) != "" {
+		providers["google"] = oauth.NewGoogleProvider(oauth.GoogleConfig{
+			ClientID:     s.Get(settings.KeyOAuthGoogleClientID),
+			ClientSecret: s.Get(settings.KeyOAuthGoogleClientSecret),
+		})
+		redirectURLs["google"] = s.Get(settings.KeyOAuthGoogleRedirectURL)
+	}
+	if s.GetBool(settings.KeyOAuthGitHubEnabled) && s.Get(settings.KeyOAuthGitHubClientID) != "" {
+		providers["github"] = oauth.NewGitHubProvider(oauth.GitHubConfig{
+			ClientID:     s.Get(settings.KeyOAuthGitHubClientID),
+			ClientSecret: s.Get(settings.KeyOAuthGitHubClientSecret),
+		})
+		redirectURLs["github"] = s.Get(settings.KeyOAuthGitHubRedirectURL)
+	}
+	return providers, redirectURLs
+}
+
 // ReloadPlans reloads only the plans from the database into the proxy service.
 // This is called by the reload_plans hook after plan create/update/delete.
 func (a *App) ReloadPlans(ctx context.Context) error {
@@ -1319,6 +1802,26 @@ func (n *noopEmailSender) SendWelcome(ctx context.Context, to, name string) erro
 	return nil
 }
 
+// subscribeSettingsReload reloads settings whenever a setting is created,
+// updated or deleted through the module runtime.
+func (a *App) subscribeSettingsReload() {
+	if a.ModuleRuntime == nil || a.ModuleRuntime.Runtime == nil {
+		return
+	}
+	bus := a.ModuleRuntime.Runtime.Events()
+	if bus == nil {
+		return
+	}
+
+	bus.Subscribe("setting.changed", func(ctx context.Context, event events.Event) error {
+		if err := a.Reload(); err != nil {
+			a.Logger.Error().Err(err).Msg("failed to reload settings after change")
+			return err
+		}
+		return nil
+	})
+}
+
 // subscribeWebhooksToEvents bridges the event bus to the webhook service.
 // Events emitted by YAML hooks (emit:) are forwarded to the webhook dispatcher
 // so customers can receive webhook notifications for module events.
@@ -2,6 +2,7 @@ package bootstrap_test
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -126,6 +127,139 @@ func TestBootstrap_GracefulShutdown(t *testing.T) {
 	}
 }
 
+// serveBlocking points the app's HTTP server at a handler that blocks until
+// release is closed and serves it on a random local port.
+func serveBlocking(t *testing.T, app *bootstrap.App, release <-chan struct{}) (addr string, started <-chan struct{}) {
+	t.Helper()
+	inFlight := make(chan struct{}, 1)
+	app.HTTPServer.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inFlight <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go app.HTTPServer.Serve(ln)
+	return ln.Addr().String(), inFlight
+}
+
+func TestBootstrap_GracefulShutdown_DrainsInFlight(t *testing.T) {
+	dir := t.TempDir()
+	os.Setenv(bootstrap.EnvDatabaseDSN, filepath.Join(dir, "drain-test.db"))
+	defer os.Unsetenv(bootstrap.EnvDatabaseDSN)
+
+	app, err := bootstrap.New()
+	if err != nil {
+		t.Fatalf("create app: %v", err)
+	}
+	if err := app.Settings.Set(context.Background(), "server.shutdown_timeout", "10s"); err != nil {
+		t.Fatalf("set shutdown timeout: %v", err)
+	}
+
+	release := make(chan struct{})
+	addr, started := serveBlocking(t, app, release)
+
+	// Start a request and wait until the handler is running
+	status := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/")
+		if err != nil {
+			status <- 0
+			return
+		}
+		resp.Body.Close()
+		status <- resp.StatusCode
+	}()
+	<-started
+
+	shutdownDone := make(chan error, 1)
+	go func() { shutdownDone <- app.Shutdown() }()
+
+	// New connections are refused once shutdown has begun
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conn, err := net.DialTimeout("tcp", addr, 100*time.Millisecond)
+		if err != nil {
+			break
+		}
+		conn.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("server still accepting connections during shutdown")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	select {
+	case <-shutdownDone:
+		t.Fatal("shutdown returned before the in-flight request finished")
+	default:
+	}
+
+	close(release)
+
+	if got := <-status; got != http.StatusOK {
+		t.Errorf("in-flight request status = %d, want %d", got, http.StatusOK)
+	}
+	select {
+	case err := <-shutdownDone:
+		if err != nil {
+			t.Errorf("shutdown error: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("shutdown did not finish after the in-flight request completed")
+	}
+}
+
+func TestBootstrap_GracefulShutdown_TimeoutClosesConnections(t *testing.T) {
+	dir := t.TempDir()
+	os.Setenv(bootstrap.EnvDatabaseDSN, filepath.Join(dir, "drain-timeout-test.db"))
+	defer os.Unsetenv(bootstrap.EnvDatabaseDSN)
+
+	app, err := bootstrap.New()
+	if err != nil {
+		t.Fatalf("create app: %v", err)
+	}
+	if err := app.Settings.Set(context.Background(), "server.shutdown_timeout", "100ms"); err != nil {
+		t.Fatalf("set shutdown timeout: %v", err)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	addr, started := serveBlocking(t, app, release)
+
+	reqErr := make(chan error, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/")
+		if err == nil {
+			resp.Body.Close()
+		}
+		reqErr <- err
+	}()
+	<-started
+
+	shutdownDone := make(chan error, 1)
+	go func() { shutdownDone <- app.Shutdown() }()
+
+	select {
+	case err := <-shutdownDone:
+		if err != nil {
+			t.Errorf("shutdown error: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("shutdown waited on a stuck request past the timeout")
+	}
+	select {
+	case err := <-reqErr:
+		if err == nil {
+			t.Error("expected the stuck request's connection to be closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stuck request was not closed")
+	}
+}
+
 func TestBootstrap_SettingsLoad(t *testing.T) {
 	dir := t.TempDir()
 	dbPath := filepath.Join(dir, "settings-test.db")
@@ -30,7 +30,7 @@ func boolPtr(b bool) *bool {
 type ModuleRuntime struct {
 	Runtime   *runtime.Runtime
 	Registry  *registry.Registry
-	Storage   *storage.SQLiteStore
+	Storage   storage.Store
 	Analytics *analytics.SQLiteStore
 	HTTP      *httpChannel.Channel
 	CLI       *cliChannel.Channel
@@ -50,6 +50,17 @@ type ModuleConfig struct {
 
 	// EmbeddedModules are modules defined in code (for core modules).
 	EmbeddedModules []schema.Module
+
+	// StorageDriver selects the module store: "sqlite" (default) or "postgres".
+	StorageDriver string
+
+	// StorageDSN is the module store's connection string. When empty with the
+	// sqlite driver, modules share the application database.
+	StorageDSN string
+
+	// AllowDestructiveMigrations lets the module store drop the columns of
+	// fields removed from a module. Otherwise such modules fail to load.
+	AllowDestructiveMigrations bool
 }
 
 // NewModuleRuntime creates a new module runtime using an existing database.
@@ -58,8 +69,19 @@ func NewModuleRuntime(db *sql.DB, rootCmd *cobra.Command, logger zerolog.Logger,
 		Logger: logger,
 	}
 
-	// Create storage adapter from existing DB
-	mr.Storage = storage.NewSQLiteStoreFromDB(db)
+	// Create storage adapter from existing DB, or the configured store
+	if cfg.StorageDSN == "" && (cfg.StorageDriver == "" || cfg.StorageDriver == "sqlite") {
+		mr.Storage = storage.NewSQLiteStoreFromDB(db)
+	} else {
+		store, err := storage.Open(cfg.StorageDriver, cfg.StorageDSN)
+		if err != nil {
+			return nil, fmt.Errorf("open module storage: %w", err)
+		}
+		mr.Storage = store
+	}
+	if m, ok := mr.Storage.(storage.Migrator); ok {
+		m.SetAllowDestructiveMigrations(cfg.AllowDestructiveMigrations)
+	}
 
 	// Create analytics store
 	analyticsStore, err := analytics.NewSQLiteStore(db, analytics.DefaultSQLiteConfig())
@@ -254,9 +276,9 @@ func (mr *ModuleRuntime) GetCLIPaths() []schema.PathClaim {
 	return mr.Registry.GetCLIPaths()
 }
 
-// runtimeStorageAdapter adapts storage.SQLiteStore to runtime.Storage.
+// runtimeStorageAdapter adapts storage.Store to runtime.Storage.
 type runtimeStorageAdapter struct {
-	store *storage.SQLiteStore
+	store storage.Store
 }
 
 func (a *runtimeStorageAdapter) CreateTable(ctx context.Context, mod convention.Derived) error {
@@ -275,7 +297,9 @@ func (a *runtimeStorageAdapter) List(ctx context.Context, module string, opts ru
 	return a.store.List(ctx, module, storage.ListOptions{
 		Limit:     opts.Limit,
 		Offset:    opts.Offset,
+		Cursor:    opts.Cursor,
 		Filters:   opts.Filters,
+		Search:    opts.Search,
 		OrderBy:   opts.OrderBy,
 		OrderDesc: opts.OrderDesc,
 	})
@@ -424,7 +448,7 @@ func coreRouteModule() schema.Module {
 			"response_transform": {Type: schema.FieldTypeJSON, Description: "Rules to transform response headers and body"},
 			"metering_expr":      {Type: schema.FieldTypeString, Default: "1", Description: "Expression to calculate request cost for rate limiting"},
 			"metering_mode":      {Type: schema.FieldTypeEnum, Values: []string{"request", "response_field", "bytes", "custom"}, Default: "request", Description: "How API usage is measured for billing"},
-			"protocol":           {Type: schema.FieldTypeEnum, Values: []string{"http", "http_stream", "sse", "websocket"}, Default: "http", Description: "Protocol handling mode for this route"},
+			"protocol":           {Type: schema.FieldTypeEnum, Values: []string{"http", "http_stream", "sse", "websocket", "grpc"}, Default: "http", Description: "Protocol handling mode for this route"},
 			"priority":           {Type: schema.FieldTypeInt, Default: 0, Description: "Route matching priority (higher values match first)"},
 			"enabled":            {Type: schema.FieldTypeBool, Default: true, Description: "Whether this route is active and processing requests"},
 		},
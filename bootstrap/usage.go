@@ -2,6 +2,7 @@ package bootstrap
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
 
@@ -16,9 +17,15 @@ type LocalUsageRecorder struct {
 	mu            sync.Mutex
 	batchSize     int
 	flushInterval time.Duration
+	closeTimeout  time.Duration // Bounds the final flush in Close
 	stopCh        chan struct{}
 	wg            sync.WaitGroup
 	closeOnce     sync.Once
+
+	writes  sync.WaitGroup // Background batch writes in flight
+	writing int            // Events in background writes not yet finished
+	dropped int            // Events lost to failed writes
+	dropErr error          // Most recent write failure
 }
 
 // NewLocalUsageRecorder creates a new local usage recorder.
@@ -35,6 +42,7 @@ func NewLocalUsageRecorder(store ports.UsageStore, batchSize int, flushInterval
 		buffer:        make([]usage.Event, 0, batchSize),
 		batchSize:     batchSize,
 		flushInterval: flushInterval,
+		closeTimeout:  10 * time.Second,
 		stopCh:        make(chan struct{}),
 	}
 
@@ -73,10 +81,21 @@ func (r *LocalUsageRecorder) flushLocked(ctx context.Context) error {
 	r.buffer = r.buffer[:0]
 
 	// Write in background to not block
+	r.writing += len(events)
+	r.writes.Add(1)
 	go func() {
+		defer r.writes.Done()
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
-		r.store.RecordBatch(ctx, events)
+		err := r.store.RecordBatch(ctx, events)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.writing -= len(events)
+		if err != nil {
+			r.dropped += len(events)
+			r.dropErr = err
+		}
 	}()
 
 	return nil
@@ -97,7 +116,10 @@ func (r *LocalUsageRecorder) flushLoop() {
 	}
 }
 
-// Close stops the recorder and flushes remaining events.
+// Close stops the recorder, writes the remaining events and waits for batches
+// already being written in the background. It gives up after the close
+// timeout. If any events were lost, including to earlier failed writes, the
+// error says how many.
 func (r *LocalUsageRecorder) Close() error {
 	var err error
 	r.closeOnce.Do(func() {
@@ -105,14 +127,42 @@ func (r *LocalUsageRecorder) Close() error {
 		r.wg.Wait()
 
 		// Final flush with timeout
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), r.closeTimeout)
 		defer cancel()
 
 		r.mu.Lock()
-		defer r.mu.Unlock()
+		events := r.buffer
+		r.buffer = nil
+		r.mu.Unlock()
+
+		if len(events) > 0 {
+			if werr := r.store.RecordBatch(ctx, events); werr != nil {
+				r.mu.Lock()
+				r.dropped += len(events)
+				r.dropErr = werr
+				r.mu.Unlock()
+			}
+		}
+
+		written := make(chan struct{})
+		go func() {
+			r.writes.Wait()
+			close(written)
+		}()
+		timedOut := false
+		select {
+		case <-written:
+		case <-ctx.Done():
+			timedOut = true
+		}
 
-		if len(r.buffer) > 0 {
-			err = r.store.RecordBatch(ctx, r.buffer)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		switch {
+		case timedOut:
+			err = fmt.Errorf("usage recorder dropped %d events: %w", r.dropped+r.writing, ctx.Err())
+		case r.dropped > 0:
+			err = fmt.Errorf("usage recorder dropped %d events: %w", r.dropped, r.dropErr)
 		}
 	})
 	return err
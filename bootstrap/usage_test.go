@@ -2,11 +2,14 @@ package bootstrap
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/artpar/apigate/domain/usage"
+	"github.com/rs/zerolog"
 )
 
 // mockUsageStore implements ports.UsageStore for testing.
@@ -14,9 +17,17 @@ type mockUsageStore struct {
 	mu           sync.Mutex
 	batchRecords [][]usage.Event
 	recordErr    error
+	delay        time.Duration // Simulates a slow store
 }
 
 func (m *mockUsageStore) RecordBatch(ctx context.Context, events []usage.Event) error {
+	if m.delay > 0 {
+		select {
+		case <-time.After(m.delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	if m.recordErr != nil {
@@ -41,6 +52,10 @@ func (m *mockUsageStore) GetRecentRequests(ctx context.Context, userID string, l
 	return nil, nil
 }
 
+func (m *mockUsageStore) GetTopPaths(ctx context.Context, userID string, start, end time.Time, limit int) ([]usage.PathUsage, error) {
+	return nil, nil
+}
+
 func (m *mockUsageStore) getTotalRecordedEvents() int {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -290,3 +305,79 @@ func TestLocalUsageRecorder_ConcurrentRecord(t *testing.T) {
 		t.Errorf("expected at least 100 events after concurrent recording, got %d", total)
 	}
 }
+
+func recordTestEvents(r *LocalUsageRecorder, n int) {
+	for i := 0; i < n; i++ {
+		r.Record(usage.Event{
+			UserID:     "user1",
+			KeyID:      "key1",
+			Path:       "/api/test",
+			Method:     "GET",
+			StatusCode: 200,
+			Timestamp:  time.Now(),
+		})
+	}
+}
+
+func TestLocalUsageRecorder_Close_WaitsForBackgroundWrites(t *testing.T) {
+	store := &mockUsageStore{delay: 100 * time.Millisecond}
+	recorder := NewLocalUsageRecorder(store, 5, 10*time.Second)
+
+	// Two full batches go to background writes; two events stay buffered
+	recordTestEvents(recorder, 12)
+
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if got := store.getTotalRecordedEvents(); got != 12 {
+		t.Errorf("recorded %d events after Close, want 12", got)
+	}
+}
+
+func TestLocalUsageRecorder_Close_ReportsDropped(t *testing.T) {
+	store := &mockUsageStore{recordErr: errors.New("database is locked")}
+	recorder := NewLocalUsageRecorder(store, 5, 10*time.Second)
+
+	recordTestEvents(recorder, 7)
+
+	err := recorder.Close()
+	if err == nil {
+		t.Fatal("expected Close to report dropped events")
+	}
+	if !strings.Contains(err.Error(), "dropped 7 events") {
+		t.Errorf("Close error = %q, want it to count 7 dropped events", err)
+	}
+}
+
+func TestLocalUsageRecorder_Close_Timeout(t *testing.T) {
+	store := &mockUsageStore{delay: time.Hour}
+	recorder := NewLocalUsageRecorder(store, 100, 10*time.Second)
+	recorder.closeTimeout = 50 * time.Millisecond
+
+	recordTestEvents(recorder, 3)
+
+	start := time.Now()
+	err := recorder.Close()
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Close took %v, want it bounded by the close timeout", elapsed)
+	}
+	if err == nil || !strings.Contains(err.Error(), "dropped 3 events") {
+		t.Errorf("Close error = %v, want it to count 3 dropped events", err)
+	}
+}
+
+func TestShutdown_FlushesUsage(t *testing.T) {
+	store := &mockUsageStore{delay: 50 * time.Millisecond}
+	recorder := NewLocalUsageRecorder(store, 10, 10*time.Second)
+	app := &App{Logger: zerolog.Nop(), usageRecorder: recorder}
+
+	// Requests served just before SIGTERM: one batch mid-write, the rest buffered
+	recordTestEvents(recorder, 15)
+
+	if err := app.Shutdown(); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if got := store.getTotalRecordedEvents(); got != 15 {
+		t.Errorf("recorded %d events after shutdown, want 15", got)
+	}
+}
@@ -2,13 +2,20 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
 	"os"
 	"text/tabwriter"
 	"time"
 
 	"github.com/artpar/apigate/adapters/sqlite"
+	adapterstls "github.com/artpar/apigate/adapters/tls"
+	"github.com/artpar/apigate/domain/settings"
 	"github.com/artpar/apigate/domain/tls"
+	"github.com/artpar/apigate/ports"
 	"github.com/spf13/cobra"
 )
 
@@ -22,11 +29,14 @@ Certificates are used for HTTPS/TLS. You can:
 - View certificate details
 - Upload manual certificates
 - Check expiring/expired certificates
+- Obtain and renew certificates from Let's Encrypt
 - Revoke or delete certificates
 
 Note: ACME certificates are automatically managed when TLS mode is 'acme'.
 
 Examples:
+  apigate certificates obtain --domain api.example.com
+  apigate certificates renew
   apigate certificates list
   apigate certificates get <id>
   apigate certificates get-domain api.example.com
@@ -72,6 +82,39 @@ Examples:
 	RunE: runCertificatesCreate,
 }
 
+var certificatesObtainCmd = &cobra.Command{
+	Use:   "obtain",
+	Short: "Obtain a certificate from Let's Encrypt (ACME)",
+	Long: `Obtain a certificate via ACME and store it in the database.
+
+The domain must resolve to this machine and port 80 must be reachable from
+the internet: the command answers the HTTP-01 challenge itself on --http-addr,
+so stop anything else listening there first. It returns once the certificate
+is stored, or with the error that stopped it.
+
+The contact email and staging server default to the tls.acme_email and
+tls.acme_staging settings.
+
+Examples:
+  apigate certificates obtain --domain api.example.com
+  apigate certificates obtain --domain api.example.com --staging --email ops@example.com`,
+	RunE: runCertificatesObtain,
+}
+
+var certificatesRenewCmd = &cobra.Command{
+	Use:   "renew",
+	Short: "Renew ACME certificates",
+	Long: `Renew ACME certificates that expire within --days, or the certificate
+for --domain regardless of its expiry. Renewal answers the HTTP-01 challenge
+the same way as "certificates obtain".
+
+Examples:
+  apigate certificates renew
+  apigate certificates renew --days 14
+  apigate certificates renew --domain api.example.com`,
+	RunE: runCertificatesRenew,
+}
+
 var certificatesExpiringCmd = &cobra.Command{
 	Use:   "expiring",
 	Short: "List certificates expiring within N days",
@@ -110,6 +153,14 @@ var (
 // Flags for expiring command
 var certExpiringDays int
 
+// Flags for obtain and renew commands
+var (
+	certACMEEmail   string
+	certACMEStaging bool
+	certHTTPAddr    string
+	certRenewDays   int
+)
+
 // Flags for revoke command
 var certRevokeReason string
 
@@ -120,6 +171,8 @@ func init() {
 	certificatesCmd.AddCommand(certificatesGetCmd)
 	certificatesCmd.AddCommand(certificatesGetDomainCmd)
 	certificatesCmd.AddCommand(certificatesCreateCmd)
+	certificatesCmd.AddCommand(certificatesObtainCmd)
+	certificatesCmd.AddCommand(certificatesRenewCmd)
 	certificatesCmd.AddCommand(certificatesExpiringCmd)
 	certificatesCmd.AddCommand(certificatesExpiredCmd)
 	certificatesCmd.AddCommand(certificatesRevokeCmd)
@@ -135,6 +188,16 @@ func init() {
 	_ = certificatesCreateCmd.MarkFlagRequired("cert-pem")
 	_ = certificatesCreateCmd.MarkFlagRequired("key-pem")
 
+	// Obtain and renew command flags
+	for _, c := range []*cobra.Command{certificatesObtainCmd, certificatesRenewCmd} {
+		c.Flags().StringVar(&certDomain, "domain", "", "domain for the certificate")
+		c.Flags().StringVar(&certACMEEmail, "email", "", "ACME contact email (default: tls.acme_email setting)")
+		c.Flags().BoolVar(&certACMEStaging, "staging", false, "use the Let's Encrypt staging server (default: tls.acme_staging setting)")
+		c.Flags().StringVar(&certHTTPAddr, "http-addr", ":80", "address to answer HTTP-01 challenges on")
+	}
+	_ = certificatesObtainCmd.MarkFlagRequired("domain")
+	certificatesRenewCmd.Flags().IntVar(&certRenewDays, "days", 30, "renew certificates expiring within this many days")
+
 	// Expiring command flags
 	certificatesExpiringCmd.Flags().IntVar(&certExpiringDays, "days", 30, "number of days to check")
 
@@ -291,6 +354,137 @@ func runCertificatesCreate(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// acmeIssuer obtains certificates via ACME and stores them in the certificate
+// database.
+type acmeIssuer interface {
+	ObtainCertificate(ctx context.Context, domain string) (tls.Certificate, error)
+	RenewCertificate(ctx context.Context, domain string) (tls.Certificate, error)
+	HTTPHandler(fallback http.Handler) http.Handler
+}
+
+// newACMEIssuer creates the ACME client used by obtain and renew.
+var newACMEIssuer = func(store ports.CertificateStore, cfg adapterstls.ACMEConfig) (acmeIssuer, error) {
+	return adapterstls.NewACMEProvider(store, cfg)
+}
+
+func runCertificatesObtain(cmd *cobra.Command, args []string) error {
+	db, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	cfg, err := acmeConfig(cmd, db, []string{certDomain})
+	if err != nil {
+		return err
+	}
+	return issueCertificates(cmd.Context(), sqlite.NewCertificateStore(db), cfg, certHTTPAddr, false, cmd.OutOrStdout())
+}
+
+func runCertificatesRenew(cmd *cobra.Command, args []string) error {
+	db, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	certStore := sqlite.NewCertificateStore(db)
+
+	domains := []string{certDomain}
+	if certDomain == "" {
+		certs, err := certStore.List(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to list certificates: %w", err)
+		}
+		domains = nil
+		for _, cert := range certs {
+			// Manual certificates are renewed by uploading a new one
+			if cert.Issuer != "Manual" && cert.Status != tls.StatusRevoked && cert.NeedsRenewal(certRenewDays) {
+				domains = append(domains, cert.Domain)
+			}
+		}
+		if len(domains) == 0 {
+			fmt.Fprintf(cmd.OutOrStdout(), "No ACME certificates expiring within %d days.\n", certRenewDays)
+			return nil
+		}
+	}
+
+	cfg, err := acmeConfig(cmd, db, domains)
+	if err != nil {
+		return err
+	}
+	return issueCertificates(cmd.Context(), certStore, cfg, certHTTPAddr, true, cmd.OutOrStdout())
+}
+
+// acmeConfig builds the ACME configuration for domains from the command's
+// flags, falling back to the TLS settings.
+func acmeConfig(cmd *cobra.Command, db *sqlite.DB, domains []string) (adapterstls.ACMEConfig, error) {
+	s, err := sqlite.NewSettingsStore(db).GetAll(cmd.Context())
+	if err != nil {
+		return adapterstls.ACMEConfig{}, fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	email := s.Get(settings.KeyTLSEmail)
+	if cmd.Flags().Changed("email") {
+		email = certACMEEmail
+	}
+	if email == "" {
+		return adapterstls.ACMEConfig{}, fmt.Errorf("an ACME contact email is required (--email or the %s setting)", settings.KeyTLSEmail)
+	}
+
+	staging := s.GetBool(settings.KeyTLSACMEStaging)
+	if cmd.Flags().Changed("staging") {
+		staging = certACMEStaging
+	}
+
+	return adapterstls.ACMEConfig{
+		Email:      email,
+		Staging:    staging,
+		Domains:    domains,
+		HTTP01Only: true,
+	}, nil
+}
+
+// issueCertificates obtains (or renews) a certificate for each configured
+// domain, answering HTTP-01 challenges on addr until every one is stored or
+// has failed.
+func issueCertificates(ctx context.Context, store ports.CertificateStore, cfg adapterstls.ACMEConfig, addr string, renew bool, w io.Writer) error {
+	// Claim the challenge address before registering with the ACME server
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for HTTP-01 challenges on %s: %w", addr, err)
+	}
+	defer ln.Close()
+
+	issuer, err := newACMEIssuer(store, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to start ACME client: %w", err)
+	}
+
+	srv := &http.Server{Handler: issuer.HTTPHandler(nil), ReadHeaderTimeout: 10 * time.Second}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	var errs []error
+	for _, domain := range cfg.Domains {
+		issue := issuer.ObtainCertificate
+		if renew {
+			issue = issuer.RenewCertificate
+		}
+		cert, err := issue(ctx, domain)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", domain, err))
+			continue
+		}
+		fmt.Fprintf(w, "%s Stored certificate: %s for domain %s\n", checkMark, cert.ID, cert.Domain)
+		fmt.Fprintf(w, "   Expires: %s (%d days)\n", cert.ExpiresAt.Format("2006-01-02"), cert.DaysUntilExpiry())
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to issue certificates:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
 func runCertificatesExpiring(cmd *cobra.Command, args []string) error {
 	db, err := openDatabase()
 	if err != nil {
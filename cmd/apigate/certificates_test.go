@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/sqlite"
+	adapterstls "github.com/artpar/apigate/adapters/tls"
+	"github.com/artpar/apigate/domain/settings"
+	"github.com/artpar/apigate/domain/tls"
+	"github.com/artpar/apigate/ports"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// fakeACMEIssuer stands in for the ACME client: it answers one challenge
+// token and stores a certificate for each domain it is asked for.
+type fakeACMEIssuer struct {
+	store   ports.CertificateStore
+	addr    string
+	err     error
+	issued  []string
+	renewed []string
+
+	// challengeBody is what the challenge server answered during issuance
+	challengeBody string
+}
+
+func (f *fakeACMEIssuer) HTTPHandler(fallback http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/.well-known/acme-challenge/token" {
+			w.Write([]byte("key-authorization"))
+			return
+		}
+		http.NotFound(w, r)
+	})
+}
+
+func (f *fakeACMEIssuer) issue(ctx context.Context, domain string) (tls.Certificate, error) {
+	if f.err != nil {
+		return tls.Certificate{}, f.err
+	}
+
+	// The CA fetches the token over HTTP while the order is pending
+	resp, err := http.Get("http://" + f.addr + "/.well-known/acme-challenge/token")
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	f.challengeBody = string(body)
+
+	now := time.Now().UTC()
+	cert := tls.Certificate{
+		ID:        tls.GenerateCertificateID(),
+		Domain:    domain,
+		CertPEM:   []byte("cert"),
+		KeyPEM:    []byte("key"),
+		IssuedAt:  now,
+		ExpiresAt: now.AddDate(0, 0, 90),
+		Issuer:    "Fake CA",
+		Status:    tls.StatusActive,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if existing, err := f.store.GetByDomain(ctx, domain); err == nil {
+		cert.ID = existing.ID
+		return cert, f.store.Update(ctx, cert)
+	}
+	return cert, f.store.Create(ctx, cert)
+}
+
+func (f *fakeACMEIssuer) ObtainCertificate(ctx context.Context, domain string) (tls.Certificate, error) {
+	f.issued = append(f.issued, domain)
+	return f.issue(ctx, domain)
+}
+
+func (f *fakeACMEIssuer) RenewCertificate(ctx context.Context, domain string) (tls.Certificate, error) {
+	f.renewed = append(f.renewed, domain)
+	return f.issue(ctx, domain)
+}
+
+// setupCertificatesTest points the CLI at a fresh database and a fake ACME
+// client answering challenges on a free local port.
+func setupCertificatesTest(t *testing.T, acmeEmail string) (*sqlite.DB, *fakeACMEIssuer, *adapterstls.ACMEConfig) {
+	t.Helper()
+
+	dbFile := t.TempDir() + "/apigate.db"
+	db, err := sqlite.Open(dbFile)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if acmeEmail != "" {
+		if err := sqlite.NewSettingsStore(db).Set(context.Background(), settings.KeyTLSEmail, acmeEmail, false); err != nil {
+			t.Fatalf("set email: %v", err)
+		}
+	}
+	t.Setenv("APIGATE_DATABASE_PATH", dbFile)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("find free port: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	issuer := &fakeACMEIssuer{addr: addr}
+	var cfg adapterstls.ACMEConfig
+	orig := newACMEIssuer
+	newACMEIssuer = func(store ports.CertificateStore, c adapterstls.ACMEConfig) (acmeIssuer, error) {
+		issuer.store = store
+		cfg = c
+		return issuer, nil
+	}
+	t.Cleanup(func() { newACMEIssuer = orig })
+
+	// Flag values outlive a command run, so start every test from the defaults
+	for _, c := range []*cobra.Command{certificatesObtainCmd, certificatesRenewCmd} {
+		c.Flags().VisitAll(func(f *pflag.Flag) {
+			f.Value.Set(f.DefValue)
+			f.Changed = false
+		})
+	}
+
+	return db, issuer, &cfg
+}
+
+func runCertificatesCommand(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(io.Discard)
+	rootCmd.SetArgs(append([]string{"certificates"}, args...))
+	defer rootCmd.SetOut(nil)
+	_, err := rootCmd.ExecuteC()
+	return out.String(), err
+}
+
+func TestCertificatesObtain(t *testing.T) {
+	db, issuer, cfg := setupCertificatesTest(t, "ops@example.com")
+
+	out, err := runCertificatesCommand(t, "obtain", "--domain", "api.example.com", "--http-addr", issuer.addr, "--staging")
+	if err != nil {
+		t.Fatalf("obtain: %v", err)
+	}
+
+	if cfg.Email != "ops@example.com" || !cfg.Staging || !cfg.HTTP01Only {
+		t.Errorf("ACME config = %+v", *cfg)
+	}
+	if len(issuer.issued) != 1 || issuer.issued[0] != "api.example.com" {
+		t.Errorf("issued = %v", issuer.issued)
+	}
+	if issuer.challengeBody != "key-authorization" {
+		t.Errorf("challenge server answered %q", issuer.challengeBody)
+	}
+
+	cert, err := sqlite.NewCertificateStore(db).GetByDomain(context.Background(), "api.example.com")
+	if err != nil {
+		t.Fatalf("certificate not stored: %v", err)
+	}
+	if !strings.Contains(out, cert.ID) {
+		t.Errorf("output should name the stored certificate:\n%s", out)
+	}
+}
+
+func TestCertificatesObtain_Errors(t *testing.T) {
+	_, issuer, _ := setupCertificatesTest(t, "")
+
+	// Without a contact email nothing is attempted
+	if _, err := runCertificatesCommand(t, "obtain", "--domain", "api.example.com", "--http-addr", issuer.addr); err == nil || !strings.Contains(err.Error(), "email") {
+		t.Errorf("obtain without email error = %v", err)
+	}
+	if len(issuer.issued) != 0 {
+		t.Errorf("issued = %v, want none", issuer.issued)
+	}
+
+	// ACME failures fail the command
+	issuer.err = io.ErrUnexpectedEOF
+	_, err := runCertificatesCommand(t, "obtain", "--domain", "api.example.com", "--http-addr", issuer.addr, "--email", "ops@example.com")
+	if err == nil || !strings.Contains(err.Error(), "api.example.com") {
+		t.Errorf("obtain error = %v", err)
+	}
+}
+
+func TestCertificatesRenew(t *testing.T) {
+	db, issuer, _ := setupCertificatesTest(t, "ops@example.com")
+
+	store := sqlite.NewCertificateStore(db)
+	now := time.Now().UTC()
+	for _, c := range []struct {
+		domain  string
+		issuer  string
+		expires time.Time
+	}{
+		{"due.example.com", "R3", now.AddDate(0, 0, 5)},
+		{"manual.example.com", "Manual", now.AddDate(0, 0, 5)},
+		{"later.example.com", "R3", now.AddDate(0, 0, 60)},
+	} {
+		if err := store.Create(context.Background(), tls.Certificate{
+			ID:        tls.GenerateCertificateID(),
+			Domain:    c.domain,
+			CertPEM:   []byte("cert"),
+			KeyPEM:    []byte("key"),
+			IssuedAt:  now,
+			ExpiresAt: c.expires,
+			Issuer:    c.issuer,
+			Status:    tls.StatusActive,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}); err != nil {
+			t.Fatalf("seed %s: %v", c.domain, err)
+		}
+	}
+
+	// Only ACME certificates that are due are renewed
+	if _, err := runCertificatesCommand(t, "renew", "--http-addr", issuer.addr); err != nil {
+		t.Fatalf("renew: %v", err)
+	}
+	if len(issuer.renewed) != 1 || issuer.renewed[0] != "due.example.com" {
+		t.Errorf("renewed = %v, want [due.example.com]", issuer.renewed)
+	}
+
+	// A named domain is renewed whatever its expiry
+	issuer.renewed = nil
+	if _, err := runCertificatesCommand(t, "renew", "--domain", "later.example.com", "--http-addr", issuer.addr); err != nil {
+		t.Fatalf("renew --domain: %v", err)
+	}
+	if len(issuer.renewed) != 1 || issuer.renewed[0] != "later.example.com" {
+		t.Errorf("renewed = %v, want [later.example.com]", issuer.renewed)
+	}
+}
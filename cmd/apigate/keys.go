@@ -104,8 +104,10 @@ func runKeysList(cmd *cobra.Command, args []string) error {
 
 	for _, k := range keys {
 		status := "active"
-		if k.RevokedAt != nil {
+		if key.IsRevoked(k, time.Now()) {
 			status = "revoked"
+		} else if k.RevokedAt != nil {
+			status = "rotating"
 		}
 		created := k.CreatedAt.Format("2006-01-02")
 		fmt.Fprintf(w, "%s\t%s...\t%s\t%s\t%s\n", k.ID, k.Prefix, k.UserID, status, created)
@@ -166,7 +168,7 @@ func runKeysRevoke(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("key not found: %s", keyID)
 	}
 
-	if k.RevokedAt != nil {
+	if key.IsRevoked(k, time.Now()) {
 		fmt.Printf("Key %s is already revoked.\n", keyID)
 		return nil
 	}
@@ -41,7 +41,7 @@ Available subcommands are generated from loaded modules.`,
 
 func tryInitModules() {
 	// Setup quiet logger
-	logger := zerolog.New(os.Stdout).With().Timestamp().Logger()
+	logger := zerolog.New(os.Stderr).With().Timestamp().Logger()
 	zerolog.SetGlobalLevel(zerolog.ErrorLevel)
 
 	// Get database path
@@ -33,8 +33,11 @@ Management:
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
+	if cmd, err := rootCmd.ExecuteC(); err != nil {
+		// Commands that silence errors have already reported them
+		if !cmd.SilenceErrors {
+			fmt.Fprintln(os.Stderr, err)
+		}
 		os.Exit(1)
 	}
 }
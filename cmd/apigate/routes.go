@@ -101,7 +101,7 @@ func init() {
 	routesCreateCmd.Flags().StringVar(&routeUpstream, "upstream", "", "upstream ID (required)")
 	routesCreateCmd.Flags().StringVar(&routePathRewrite, "rewrite", "", "path rewrite expression")
 	routesCreateCmd.Flags().IntVar(&routePriority, "priority", 0, "route priority (higher = first)")
-	routesCreateCmd.Flags().StringVar(&routeProtocol, "protocol", "http", "protocol: http, http_stream, sse, websocket")
+	routesCreateCmd.Flags().StringVar(&routeProtocol, "protocol", "http", "protocol: http, http_stream, sse, websocket, grpc")
 	routesCreateCmd.MarkFlagRequired("name")
 	routesCreateCmd.MarkFlagRequired("path")
 	routesCreateCmd.MarkFlagRequired("upstream")
@@ -244,6 +244,8 @@ func runRoutesCreate(cmd *cobra.Command, args []string) error {
 		protocol = route.ProtocolSSE
 	case "websocket":
 		protocol = route.ProtocolWebSocket
+	case "grpc":
+		protocol = route.ProtocolGRPC
 	default:
 		return fmt.Errorf("invalid protocol: %s", routeProtocol)
 	}
@@ -2,12 +2,19 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"text/tabwriter"
 	"time"
 
 	"github.com/artpar/apigate/adapters/sqlite"
+	"github.com/artpar/apigate/domain/usage"
 	"github.com/spf13/cobra"
 )
 
@@ -20,7 +27,8 @@ Examples:
   apigate usage summary --user=user_123
   apigate usage summary --email=dev@example.com
   apigate usage history --user=user_123 --periods=6
-  apigate usage recent --user=user_123 --limit=20`,
+  apigate usage recent --user=user_123 --limit=20
+  apigate usage tail --status=5xx --json`,
 }
 
 var usageSummaryCmd = &cobra.Command{
@@ -41,11 +49,25 @@ var usageRecentCmd = &cobra.Command{
 	RunE:  runUsageRecent,
 }
 
+var usageTailCmd = &cobra.Command{
+	Use:   "tail",
+	Short: "Stream requests as they are metered",
+	Long: `Print metered requests as they are recorded, until interrupted.
+
+Requests reach the database in batches, so they appear shortly after the
+proxy serves them.`,
+	RunE: runUsageTail,
+}
+
 var (
 	usageUserID  string
 	usageEmail   string
 	usagePeriods int
 	usageLimit   int
+
+	usageTailStatus   string
+	usageTailJSON     bool
+	usageTailInterval time.Duration
 )
 
 func init() {
@@ -54,6 +76,7 @@ func init() {
 	usageCmd.AddCommand(usageSummaryCmd)
 	usageCmd.AddCommand(usageHistoryCmd)
 	usageCmd.AddCommand(usageRecentCmd)
+	usageCmd.AddCommand(usageTailCmd)
 
 	// Common flags
 	usageSummaryCmd.Flags().StringVar(&usageUserID, "user", "", "user ID")
@@ -66,6 +89,12 @@ func init() {
 	usageRecentCmd.Flags().StringVar(&usageUserID, "user", "", "user ID")
 	usageRecentCmd.Flags().StringVar(&usageEmail, "email", "", "user email")
 	usageRecentCmd.Flags().IntVar(&usageLimit, "limit", 20, "number of requests to show")
+
+	usageTailCmd.Flags().StringVar(&usageUserID, "user", "", "only requests by this user ID")
+	usageTailCmd.Flags().StringVar(&usageEmail, "email", "", "only requests by this user email")
+	usageTailCmd.Flags().StringVar(&usageTailStatus, "status", "", "only this status code or class (e.g. 404, 5xx)")
+	usageTailCmd.Flags().BoolVar(&usageTailJSON, "json", false, "print one JSON object per request")
+	usageTailCmd.Flags().DurationVar(&usageTailInterval, "interval", time.Second, "how often to check for new requests")
 }
 
 func resolveUserID(db *sqlite.DB) (string, error) {
@@ -199,3 +228,155 @@ func runUsageRecent(cmd *cobra.Command, args []string) error {
 	w.Flush()
 	return nil
 }
+
+func runUsageTail(cmd *cobra.Command, args []string) error {
+	filter := usageTailFilter{}
+	if err := filter.setStatus(usageTailStatus); err != nil {
+		return err
+	}
+
+	db, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if usageUserID != "" || usageEmail != "" {
+		if filter.userID, err = resolveUserID(db); err != nil {
+			return err
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return tailUsage(ctx, sqlite.NewUsageStore(db), filter, usageTailJSON, usageTailInterval, os.Stdout)
+}
+
+// usageTailSource provides recorded requests in recording order.
+type usageTailSource interface {
+	LatestRequestCursor(ctx context.Context) (int64, error)
+	GetRequestsAfter(ctx context.Context, cursor int64, limit int) ([]usage.Event, int64, error)
+}
+
+// usageTailFilter selects the requests usage tail prints.
+type usageTailFilter struct {
+	userID string
+
+	// status matches a code exactly; statusClass matches its hundreds (5 for 5xx)
+	status      int
+	statusClass int
+}
+
+// setStatus parses a --status value: a code such as 404, or a class such as 5xx.
+func (f *usageTailFilter) setStatus(s string) error {
+	if s == "" {
+		return nil
+	}
+	if len(s) == 3 && strings.HasSuffix(strings.ToLower(s), "xx") && s[0] >= '1' && s[0] <= '5' {
+		f.statusClass = int(s[0] - '0')
+		return nil
+	}
+	code, err := strconv.Atoi(s)
+	if err != nil || code < 100 || code > 599 {
+		return fmt.Errorf("invalid --status %q: want a code such as 404 or a class such as 5xx", s)
+	}
+	f.status = code
+	return nil
+}
+
+func (f usageTailFilter) match(e usage.Event) bool {
+	if f.userID != "" && e.UserID != f.userID {
+		return false
+	}
+	if f.status != 0 && e.StatusCode != f.status {
+		return false
+	}
+	if f.statusClass != 0 && e.StatusCode/100 != f.statusClass {
+		return false
+	}
+	return true
+}
+
+// usageTailEvent is the JSON form of a request printed by usage tail.
+type usageTailEvent struct {
+	ID            string    `json:"id"`
+	Timestamp     time.Time `json:"timestamp"`
+	UserID        string    `json:"user_id"`
+	KeyID         string    `json:"key_id"`
+	Method        string    `json:"method"`
+	Path          string    `json:"path"`
+	Status        int       `json:"status"`
+	LatencyMs     int64     `json:"latency_ms"`
+	RequestBytes  int64     `json:"request_bytes"`
+	ResponseBytes int64     `json:"response_bytes"`
+}
+
+// tailUsage prints requests recorded from now on that match filter, checking
+// for new ones every interval, until ctx is done.
+func tailUsage(ctx context.Context, src usageTailSource, filter usageTailFilter, asJSON bool, interval time.Duration, w io.Writer) error {
+	const batchSize = 100
+
+	cursor, err := src.LatestRequestCursor(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read usage: %w", err)
+	}
+
+	enc := json.NewEncoder(w)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			var events []usage.Event
+			events, cursor, err = src.GetRequestsAfter(ctx, cursor, batchSize)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("failed to read usage: %w", err)
+			}
+
+			for _, e := range events {
+				if !filter.match(e) {
+					continue
+				}
+				if asJSON {
+					if err := enc.Encode(usageTailEvent{
+						ID:            e.ID,
+						Timestamp:     e.Timestamp,
+						UserID:        e.UserID,
+						KeyID:         e.KeyID,
+						Method:        e.Method,
+						Path:          e.Path,
+						Status:        e.StatusCode,
+						LatencyMs:     e.LatencyMs,
+						RequestBytes:  e.RequestBytes,
+						ResponseBytes: e.ResponseBytes,
+					}); err != nil {
+						return err
+					}
+					continue
+				}
+				fmt.Fprintf(w, "%s  %-6s %-40s %d  %d ms  %s\n",
+					e.Timestamp.Format("2006-01-02 15:04:05"),
+					e.Method,
+					e.Path,
+					e.StatusCode,
+					e.LatencyMs,
+					e.UserID,
+				)
+			}
+
+			if len(events) < batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
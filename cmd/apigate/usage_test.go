@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/domain/usage"
+)
+
+// scriptedUsageSource returns one batch of events per poll, then cancels the
+// tail once the script has run out.
+type scriptedUsageSource struct {
+	batches [][]usage.Event
+	cancel  context.CancelFunc
+	cursors []int64
+}
+
+func (s *scriptedUsageSource) LatestRequestCursor(ctx context.Context) (int64, error) {
+	return 10, nil
+}
+
+func (s *scriptedUsageSource) GetRequestsAfter(ctx context.Context, cursor int64, limit int) ([]usage.Event, int64, error) {
+	s.cursors = append(s.cursors, cursor)
+	if len(s.batches) == 0 {
+		s.cancel()
+		return nil, cursor, nil
+	}
+	batch := s.batches[0]
+	s.batches = s.batches[1:]
+	return batch, cursor + int64(len(batch)), nil
+}
+
+func tailEvent(id, userID string, status int) usage.Event {
+	return usage.Event{
+		ID:         id,
+		UserID:     userID,
+		KeyID:      "key-1",
+		Method:     "GET",
+		Path:       "/api/" + id,
+		StatusCode: status,
+		LatencyMs:  12,
+		Timestamp:  time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+func TestTailUsage_PrintsEventsInOrder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	src := &scriptedUsageSource{
+		cancel: cancel,
+		batches: [][]usage.Event{
+			{tailEvent("a", "user-1", 200), tailEvent("b", "user-1", 404)},
+			{},
+			{tailEvent("c", "user-2", 500)},
+		},
+	}
+
+	var out bytes.Buffer
+	if err := tailUsage(ctx, src, usageTailFilter{}, false, time.Millisecond, &out); err != nil {
+		t.Fatalf("tailUsage: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("printed %d lines, want 3:\n%s", len(lines), out.String())
+	}
+	for i, path := range []string{"/api/a", "/api/b", "/api/c"} {
+		if !strings.Contains(lines[i], path) {
+			t.Errorf("line %d = %q, want %s", i, lines[i], path)
+		}
+	}
+
+	// Polling starts from the latest request and follows the cursor
+	if src.cursors[0] != 10 || src.cursors[1] != 12 || src.cursors[3] != 13 {
+		t.Errorf("cursors = %v", src.cursors)
+	}
+}
+
+func TestTailUsage_FiltersAndJSON(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	src := &scriptedUsageSource{
+		cancel: cancel,
+		batches: [][]usage.Event{
+			{tailEvent("a", "user-1", 200), tailEvent("b", "user-1", 502), tailEvent("c", "user-2", 500)},
+			{tailEvent("d", "user-1", 503)},
+		},
+	}
+
+	filter := usageTailFilter{userID: "user-1"}
+	if err := filter.setStatus("5xx"); err != nil {
+		t.Fatalf("setStatus: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := tailUsage(ctx, src, filter, true, time.Millisecond, &out); err != nil {
+		t.Fatalf("tailUsage: %v", err)
+	}
+
+	dec := json.NewDecoder(&out)
+	var ids []string
+	for dec.More() {
+		var e usageTailEvent
+		if err := dec.Decode(&e); err != nil {
+			t.Fatalf("output is not JSON lines: %v", err)
+		}
+		if e.UserID != "user-1" || e.Status/100 != 5 {
+			t.Errorf("event %s should have been filtered out", e.ID)
+		}
+		ids = append(ids, e.ID)
+	}
+	if strings.Join(ids, ",") != "b,d" {
+		t.Errorf("printed %v, want [b d]", ids)
+	}
+}
+
+func TestUsageTailFilter_SetStatus(t *testing.T) {
+	tests := []struct {
+		in      string
+		match   int
+		noMatch int
+	}{
+		{"404", 404, 400},
+		{"4xx", 429, 500},
+		{"5XX", 503, 200},
+	}
+	for _, tt := range tests {
+		var f usageTailFilter
+		if err := f.setStatus(tt.in); err != nil {
+			t.Errorf("setStatus(%q): %v", tt.in, err)
+			continue
+		}
+		if !f.match(usage.Event{StatusCode: tt.match}) {
+			t.Errorf("%q should match %d", tt.in, tt.match)
+		}
+		if f.match(usage.Event{StatusCode: tt.noMatch}) {
+			t.Errorf("%q should not match %d", tt.in, tt.noMatch)
+		}
+	}
+
+	for _, bad := range []string{"abc", "6xx", "99", "1000"} {
+		var f usageTailFilter
+		if err := f.setStatus(bad); err == nil {
+			t.Errorf("setStatus(%q) should fail", bad)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -19,7 +20,8 @@ var validateCmd = &cobra.Command{
 
 Checks:
   - YAML syntax is valid
-  - Required fields are present
+  - Required fields are present and values are in range
+    (every problem is listed with its line and column)
   - Upstream is reachable (optional)
   - Database is writable (optional)
 
@@ -54,6 +56,15 @@ func runValidate(cmd *cobra.Command, args []string) error {
 	// Load and validate config
 	cfg, err := config.Load(cfgFile)
 	if err != nil {
+		var verr *config.ValidationError
+		if errors.As(err, &verr) {
+			fmt.Printf("  %s Config syntax valid\n", checkMark)
+			fmt.Printf("  %s Config values valid\n", crossMark)
+			for _, fe := range verr.Errors {
+				fmt.Printf("      %s\n", fe.Error())
+			}
+			return fmt.Errorf("config has %d error(s)", len(verr.Errors))
+		}
 		fmt.Printf("  %s Config syntax valid\n", crossMark)
 		return fmt.Errorf("config error: %w", err)
 	}
@@ -93,7 +93,7 @@ type RemoteConfig struct {
 
 // DatabaseConfig configures the database.
 type DatabaseConfig struct {
-	Driver string `yaml:"driver"` // "sqlite" or "postgres" (future)
+	Driver string `yaml:"driver"` // "sqlite"; module records can use postgres (storage.driver)
 	DSN    string `yaml:"dsn"`
 }
 
@@ -180,19 +180,30 @@ func Load(path string) (*Config, error) {
 	}
 
 	// Expand environment variables
-	data = []byte(os.ExpandEnv(string(data)))
+	expanded, err := expandEnv(string(data), os.LookupEnv)
+	if err != nil {
+		return nil, fmt.Errorf("expand config: %w", err)
+	}
+	data = []byte(expanded)
 
 	var cfg Config
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return nil, fmt.Errorf("parse config: %w", err)
 	}
 
+	// Keep the node tree so validation errors can point at the offending line
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+
 	// Apply environment variable overrides
 	applyEnvOverrides(&cfg)
 
 	setDefaults(&cfg)
 
 	if err := validate(&cfg); err != nil {
+		err.locate(&root)
 		return nil, fmt.Errorf("validate config: %w", err)
 	}
 
@@ -540,67 +551,3 @@ func setDefaults(cfg *Config) {
 		cfg.TLS.HTTPRedirect = true
 	}
 }
-
-func validate(cfg *Config) error {
-	if cfg.Upstream.URL == "" {
-		return fmt.Errorf("upstream.url is required")
-	}
-
-	validAuthModes := map[string]bool{"local": true, "remote": true}
-	if !validAuthModes[cfg.Auth.Mode] {
-		return fmt.Errorf("auth.mode must be 'local' or 'remote', got %q", cfg.Auth.Mode)
-	}
-	if cfg.Auth.Mode == "remote" && cfg.Auth.Remote.URL == "" {
-		return fmt.Errorf("auth.remote.url is required when auth.mode is 'remote'")
-	}
-
-	validUsageModes := map[string]bool{"local": true, "remote": true}
-	if !validUsageModes[cfg.Usage.Mode] {
-		return fmt.Errorf("usage.mode must be 'local' or 'remote', got %q", cfg.Usage.Mode)
-	}
-	if cfg.Usage.Mode == "remote" && cfg.Usage.Remote.URL == "" {
-		return fmt.Errorf("usage.remote.url is required when usage.mode is 'remote'")
-	}
-
-	validBillingModes := map[string]bool{
-		"none": true, "stripe": true, "paddle": true, "lemonsqueezy": true, "remote": true,
-	}
-	if !validBillingModes[cfg.Billing.Mode] {
-		return fmt.Errorf("billing.mode must be one of: none, stripe, paddle, lemonsqueezy, remote")
-	}
-	if cfg.Billing.Mode == "remote" && cfg.Billing.Remote.URL == "" {
-		return fmt.Errorf("billing.remote.url is required when billing.mode is 'remote'")
-	}
-
-	for i, plan := range cfg.Plans {
-		if plan.ID == "" {
-			return fmt.Errorf("plans[%d].id is required", i)
-		}
-	}
-
-	// TLS validation
-	validTLSModes := map[string]bool{"none": true, "acme": true, "manual": true}
-	if !validTLSModes[cfg.TLS.Mode] {
-		return fmt.Errorf("tls.mode must be 'none', 'acme', or 'manual', got %q", cfg.TLS.Mode)
-	}
-	if cfg.TLS.Enabled {
-		switch cfg.TLS.Mode {
-		case "acme":
-			if cfg.TLS.Domain == "" {
-				return fmt.Errorf("tls.domain is required when tls.mode is 'acme'")
-			}
-		case "manual":
-			if cfg.TLS.CertPath == "" || cfg.TLS.KeyPath == "" {
-				return fmt.Errorf("tls.cert_path and tls.key_path are required when tls.mode is 'manual'")
-			}
-		case "none":
-			return fmt.Errorf("tls.enabled is true but tls.mode is 'none'; set tls.mode to 'acme' or 'manual'")
-		}
-	}
-	validTLSVersions := map[string]bool{"1.2": true, "1.3": true}
-	if !validTLSVersions[cfg.TLS.MinVersion] {
-		return fmt.Errorf("tls.min_version must be '1.2' or '1.3', got %q", cfg.TLS.MinVersion)
-	}
-
-	return nil
-}
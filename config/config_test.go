@@ -1,8 +1,10 @@
 package config_test
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -108,6 +110,82 @@ upstream:
 	}
 }
 
+func TestLoad_EnvDefaults(t *testing.T) {
+	t.Setenv("TEST_LOG_LEVEL", "warn")
+	t.Setenv("TEST_EMPTY_HOST", "")
+
+	content := `
+upstream:
+  url: "${TEST_UNSET_UPSTREAM:-http://fallback:3000}"
+server:
+  host: "${TEST_EMPTY_HOST:-127.0.0.1}"
+logging:
+  level: "${TEST_LOG_LEVEL:-debug}"
+`
+	cfg := writeAndLoad(t, content)
+
+	if cfg.Upstream.URL != "http://fallback:3000" {
+		t.Errorf("Upstream.URL = %s, want default for unset var", cfg.Upstream.URL)
+	}
+	if cfg.Server.Host != "127.0.0.1" {
+		t.Errorf("Server.Host = %s, want default for empty var", cfg.Server.Host)
+	}
+	if cfg.Logging.Level != "warn" {
+		t.Errorf("Logging.Level = %s, want value of set var", cfg.Logging.Level)
+	}
+}
+
+func TestLoad_EnvRequiredMissing(t *testing.T) {
+	t.Setenv("TEST_STRIPE_KEY", "")
+
+	content := `
+upstream:
+  url: "${TEST_REQUIRED_UPSTREAM:?set the upstream URL}"
+billing:
+  mode: stripe
+  stripe_key: "${TEST_STRIPE_KEY:?}"
+`
+	_, err := writeAndLoadErr(t, content)
+	if err == nil {
+		t.Fatal("expected error for missing required variables")
+	}
+	for _, part := range []string{
+		"TEST_REQUIRED_UPSTREAM: set the upstream URL (line 3)",
+		"TEST_STRIPE_KEY: is required (line 6)",
+	} {
+		if !strings.Contains(err.Error(), part) {
+			t.Errorf("error %q missing %q", err, part)
+		}
+	}
+}
+
+func TestLoad_EnvRequiredSet(t *testing.T) {
+	t.Setenv("TEST_REQUIRED_UPSTREAM", "http://required:3000")
+
+	cfg := writeAndLoad(t, `
+upstream:
+  url: "${TEST_REQUIRED_UPSTREAM:?set the upstream URL}"
+`)
+	if cfg.Upstream.URL != "http://required:3000" {
+		t.Errorf("Upstream.URL = %s, want http://required:3000", cfg.Upstream.URL)
+	}
+}
+
+func TestLoad_EnvDollarEscape(t *testing.T) {
+	t.Setenv("TEST_SECRET", "expanded")
+
+	cfg := writeAndLoad(t, `
+upstream:
+  url: "http://localhost:3000"
+auth:
+  jwt_secret: "pa$$word-$${TEST_SECRET}-$TEST_SECRET-costs $5"
+`)
+	want := "pa$word-${TEST_SECRET}-expanded-costs $5"
+	if cfg.Auth.JWTSecret != want {
+		t.Errorf("JWTSecret = %q, want %q", cfg.Auth.JWTSecret, want)
+	}
+}
+
 func TestLoad_RemoteAuth(t *testing.T) {
 	content := `
 upstream:
@@ -467,6 +545,81 @@ plans:
 	}
 }
 
+func TestLoad_ReportsAllErrorsWithLocations(t *testing.T) {
+	content := `upstream:
+  timeout: 5s
+auth:
+  mode: "oauth"
+plans:
+  - id: "free"
+    rate_limit_per_minute: 60
+  - id: "pro"
+    rate_limit_per_minute: 600
+  - id: "broken"
+    rate_limit_per_minute: -5
+    price_monthly: -100
+endpoints:
+  - method: "POST"
+    path: "/expensive"
+    cost_multiplier: -2
+`
+	_, err := writeAndLoadErr(t, content)
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+
+	var verr *config.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *config.ValidationError, got %T: %v", err, err)
+	}
+
+	want := []config.FieldError{
+		{Path: "upstream.url", Message: "is required", Line: 2, Column: 3},
+		{Path: "auth.mode", Message: `must be 'local' or 'remote', got "oauth"`, Line: 4, Column: 9},
+		{Path: "plans[2].rate_limit_per_minute", Message: "must not be negative", Line: 11, Column: 28},
+		{Path: "plans[2].price_monthly", Message: "must not be negative", Line: 12, Column: 20},
+		{Path: "endpoints[0].cost_multiplier", Message: "must not be negative", Line: 16, Column: 22},
+	}
+	if len(verr.Errors) != len(want) {
+		t.Fatalf("got %d errors, want %d: %v", len(verr.Errors), len(want), err)
+	}
+	for i, w := range want {
+		if verr.Errors[i] != w {
+			t.Errorf("error %d = %+v, want %+v", i, verr.Errors[i], w)
+		}
+	}
+
+	msg := err.Error()
+	for _, part := range []string{
+		"5 errors",
+		"plans[2].rate_limit_per_minute must not be negative at line 11, column 28",
+		"endpoints[0].cost_multiplier must not be negative at line 16, column 22",
+	} {
+		if !strings.Contains(msg, part) {
+			t.Errorf("error message %q missing %q", msg, part)
+		}
+	}
+}
+
+func TestLoadFromEnv_ErrorsHaveNoLocation(t *testing.T) {
+	t.Setenv("APIGATE_UPSTREAM_URL", "")
+	t.Setenv("APIGATE_AUTH_MODE", "oauth")
+
+	_, err := config.LoadFromEnv()
+	var verr *config.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *config.ValidationError, got %v", err)
+	}
+	if len(verr.Errors) != 2 {
+		t.Fatalf("got %d errors, want 2: %v", len(verr.Errors), err)
+	}
+	for _, fe := range verr.Errors {
+		if fe.Line != 0 {
+			t.Errorf("%s: unexpected line %d for env config", fe.Path, fe.Line)
+		}
+	}
+}
+
 func TestLoad_ValidBillingModes(t *testing.T) {
 	modes := []string{"none", "stripe", "paddle", "lemonsqueezy"}
 	for _, mode := range modes {
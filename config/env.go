@@ -0,0 +1,103 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// expandEnv substitutes environment variables in the config file contents.
+//
+// Supported forms:
+//
+//	$VAR, ${VAR}         - value of VAR, or "" if unset
+//	${VAR:-default}      - value of VAR, or default if VAR is unset or empty
+//	${VAR:?message}      - value of VAR; an error with message if VAR is unset or empty
+//	$$                   - a literal "$"
+//
+// Every missing required variable is reported, not just the first.
+func expandEnv(s string, lookup func(string) (string, bool)) (string, error) {
+	var b strings.Builder
+	var missing []string
+	line := 1
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\n' {
+			line++
+		}
+		if c != '$' || i+1 == len(s) {
+			b.WriteByte(c)
+			continue
+		}
+
+		switch next := s[i+1]; {
+		case next == '$':
+			b.WriteByte('$')
+			i++
+
+		case next == '{':
+			end := strings.IndexByte(s[i+2:], '}')
+			if end < 0 {
+				b.WriteByte(c)
+				continue
+			}
+			expr := s[i+2 : i+2+end]
+			i += 2 + end
+
+			value, err := expandVar(expr, lookup)
+			if err != nil {
+				missing = append(missing, fmt.Sprintf("%s (line %d)", err, line))
+			}
+			b.WriteString(value)
+
+		case isNameStart(next):
+			j := i + 1
+			for j < len(s) && isNameChar(s[j]) {
+				j++
+			}
+			value, _ := lookup(s[i+1 : j])
+			b.WriteString(value)
+			i = j - 1
+
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing required environment variables: %s", strings.Join(missing, "; "))
+	}
+	return b.String(), nil
+}
+
+// expandVar resolves the inside of a ${...} expression.
+func expandVar(expr string, lookup func(string) (string, bool)) (string, error) {
+	name, op, arg := expr, "", ""
+	if i := strings.Index(expr, ":"); i >= 0 && i+1 < len(expr) && (expr[i+1] == '-' || expr[i+1] == '?') {
+		name, op, arg = expr[:i], expr[i:i+2], expr[i+2:]
+	}
+
+	value, _ := lookup(name)
+	if value != "" {
+		return value, nil
+	}
+
+	switch op {
+	case ":-":
+		return arg, nil
+	case ":?":
+		if arg == "" {
+			arg = "is required"
+		}
+		return "", fmt.Errorf("%s: %s", name, arg)
+	}
+	return "", nil
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,218 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FieldError is a single configuration problem.
+// Line and Column are 1-based and zero when the location is unknown,
+// e.g. for values that only come from environment variables.
+type FieldError struct {
+	Path    string // YAML path of the field, e.g. "plans[2].rate_limit_per_minute"
+	Message string // What is wrong, e.g. "must not be negative"
+	Line    int
+	Column  int
+}
+
+func (e FieldError) Error() string {
+	msg := e.Path + " " + e.Message
+	if e.Line > 0 {
+		msg += fmt.Sprintf(" at line %d, column %d", e.Line, e.Column)
+	}
+	return msg
+}
+
+// ValidationError collects every problem found in a configuration.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.Error()
+	}
+	return fmt.Sprintf("%d errors: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// add records a problem with the field at path.
+func (e *ValidationError) add(path, format string, args ...any) {
+	e.Errors = append(e.Errors, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// locate fills in the line and column of each error from the parsed document.
+// A field missing from the file is reported at its closest enclosing node.
+func (e *ValidationError) locate(root *yaml.Node) {
+	for i := range e.Errors {
+		if n := findNode(root, e.Errors[i].Path); n != nil {
+			e.Errors[i].Line = n.Line
+			e.Errors[i].Column = n.Column
+		}
+	}
+}
+
+// findNode walks a path like "plans[2].id" through the document and returns
+// the deepest node found, or nil if not even the first segment exists.
+func findNode(root *yaml.Node, path string) *yaml.Node {
+	node := root
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return nil
+		}
+		node = node.Content[0]
+	}
+
+	var found *yaml.Node
+	for _, seg := range strings.Split(path, ".") {
+		key, rest, _ := strings.Cut(seg, "[")
+		next := mappingValue(node, key)
+		if next == nil {
+			return found
+		}
+		node, found = next, next
+
+		for rest != "" {
+			idx, after, _ := strings.Cut(rest, "]")
+			n, err := strconv.Atoi(idx)
+			if err != nil || node.Kind != yaml.SequenceNode || n < 0 || n >= len(node.Content) {
+				return found
+			}
+			node = node.Content[n]
+			found = node
+			rest = strings.TrimPrefix(after, "[")
+		}
+	}
+	return found
+}
+
+// mappingValue returns the value node for key in a mapping node, or nil.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// validate checks the whole configuration and reports every problem at once.
+// It returns nil if the configuration is valid.
+func validate(cfg *Config) *ValidationError {
+	errs := &ValidationError{}
+
+	if cfg.Upstream.URL == "" {
+		errs.add("upstream.url", "is required")
+	}
+
+	if cfg.Server.Port < 1 || cfg.Server.Port > 65535 {
+		errs.add("server.port", "must be between 1 and 65535, got %d", cfg.Server.Port)
+	}
+
+	validAuthModes := map[string]bool{"local": true, "remote": true}
+	if !validAuthModes[cfg.Auth.Mode] {
+		errs.add("auth.mode", "must be 'local' or 'remote', got %q", cfg.Auth.Mode)
+	}
+	if cfg.Auth.Mode == "remote" && cfg.Auth.Remote.URL == "" {
+		errs.add("auth.remote.url", "is required when auth.mode is 'remote'")
+	}
+
+	if cfg.RateLimit.BurstTokens < 0 {
+		errs.add("rate_limit.burst_tokens", "must not be negative")
+	}
+	if cfg.RateLimit.WindowSecs < 0 {
+		errs.add("rate_limit.window_secs", "must not be negative")
+	}
+
+	validUsageModes := map[string]bool{"local": true, "remote": true}
+	if !validUsageModes[cfg.Usage.Mode] {
+		errs.add("usage.mode", "must be 'local' or 'remote', got %q", cfg.Usage.Mode)
+	}
+	if cfg.Usage.Mode == "remote" && cfg.Usage.Remote.URL == "" {
+		errs.add("usage.remote.url", "is required when usage.mode is 'remote'")
+	}
+
+	validBillingModes := map[string]bool{
+		"none": true, "stripe": true, "paddle": true, "lemonsqueezy": true, "remote": true,
+	}
+	if !validBillingModes[cfg.Billing.Mode] {
+		errs.add("billing.mode", "must be one of: none, stripe, paddle, lemonsqueezy, remote")
+	}
+	if cfg.Billing.Mode == "remote" && cfg.Billing.Remote.URL == "" {
+		errs.add("billing.remote.url", "is required when billing.mode is 'remote'")
+	}
+
+	planIDs := make(map[string]bool, len(cfg.Plans))
+	for i, plan := range cfg.Plans {
+		path := fmt.Sprintf("plans[%d]", i)
+		if plan.ID == "" {
+			errs.add(path+".id", "is required")
+		} else if planIDs[plan.ID] {
+			errs.add(path+".id", "duplicates plan %q", plan.ID)
+		}
+		planIDs[plan.ID] = true
+		if plan.RateLimitPerMinute < 0 {
+			errs.add(path+".rate_limit_per_minute", "must not be negative")
+		}
+		if plan.RequestsPerMonth < -1 {
+			errs.add(path+".requests_per_month", "must be -1 (unlimited) or more")
+		}
+		if plan.PriceMonthly < 0 {
+			errs.add(path+".price_monthly", "must not be negative")
+		}
+		if plan.OveragePrice < 0 {
+			errs.add(path+".overage_price", "must not be negative")
+		}
+	}
+
+	for i, ep := range cfg.Endpoints {
+		path := fmt.Sprintf("endpoints[%d]", i)
+		if ep.Path == "" {
+			errs.add(path+".path", "is required")
+		}
+		if ep.CostMultiplier < 0 {
+			errs.add(path+".cost_multiplier", "must not be negative")
+		}
+	}
+
+	// TLS validation
+	validTLSModes := map[string]bool{"none": true, "acme": true, "manual": true}
+	if !validTLSModes[cfg.TLS.Mode] {
+		errs.add("tls.mode", "must be 'none', 'acme', or 'manual', got %q", cfg.TLS.Mode)
+	}
+	if cfg.TLS.Enabled {
+		switch cfg.TLS.Mode {
+		case "acme":
+			if cfg.TLS.Domain == "" {
+				errs.add("tls.domain", "is required when tls.mode is 'acme'")
+			}
+		case "manual":
+			if cfg.TLS.CertPath == "" {
+				errs.add("tls.cert_path", "is required when tls.mode is 'manual'")
+			}
+			if cfg.TLS.KeyPath == "" {
+				errs.add("tls.key_path", "is required when tls.mode is 'manual'")
+			}
+		case "none":
+			errs.add("tls.mode", "must be 'acme' or 'manual' when tls.enabled is true")
+		}
+	}
+	validTLSVersions := map[string]bool{"1.2": true, "1.3": true}
+	if !validTLSVersions[cfg.TLS.MinVersion] {
+		errs.add("tls.min_version", "must be '1.2' or '1.3', got %q", cfg.TLS.MinVersion)
+	}
+
+	if len(errs.Errors) == 0 {
+		return nil
+	}
+	return errs
+}
@@ -5,7 +5,6 @@ package cli
 import (
 	"context"
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/artpar/apigate/core/convention"
@@ -14,6 +13,7 @@ import (
 	"github.com/artpar/apigate/core/schema"
 	"github.com/artpar/apigate/core/validation"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // Channel implements the CLI channel for modules.
@@ -25,15 +25,20 @@ type Channel struct {
 	validator  *validation.Validator
 }
 
-// New creates a new CLI channel.
+// New creates a new CLI channel. The output flags are added to rootCmd as
+// persistent flags, so every module command accepts --output json.
 func New(rootCmd *cobra.Command, rt *runtime.Runtime) *Channel {
-	return &Channel{
+	c := &Channel{
 		rootCmd:    rootCmd,
 		runtime:    rt,
 		modules:    make(map[string]convention.Derived),
 		formatters: formatter.DefaultRegistry,
 		validator:  validation.New(make(map[string]convention.Derived)),
 	}
+	if rootCmd != nil && rootCmd.PersistentFlags().Lookup("output") == nil {
+		c.addOutputFlagSet(rootCmd.PersistentFlags())
+	}
+	return c
 }
 
 // Name returns the channel name.
@@ -68,6 +73,8 @@ func (c *Channel) Register(mod convention.Derived) error {
 	for _, action := range mod.Actions {
 		cmd := c.buildActionCommand(mod, action)
 		if cmd != nil {
+			// Failed actions report their own error; usage is noise in scripts
+			cmd.SilenceUsage = true
 			moduleCmd.AddCommand(cmd)
 		}
 	}
@@ -132,7 +139,6 @@ func (c *Channel) buildListCommand(mod convention.Derived, action convention.Der
 
 	cmd.Flags().IntP("limit", "l", 100, "Maximum number of records")
 	cmd.Flags().IntP("offset", "o", 0, "Number of records to skip")
-	c.addOutputFlags(cmd)
 
 	return cmd
 }
@@ -156,8 +162,6 @@ func (c *Channel) buildGetCommand(mod convention.Derived, action convention.Deri
 		},
 	}
 
-	c.addOutputFlags(cmd)
-
 	return cmd
 }
 
@@ -174,7 +178,7 @@ func (c *Channel) buildCreateCommand(mod convention.Derived, action convention.D
 				if val, err := cmd.Flags().GetString(input.Name); err == nil && val != "" {
 					data[input.Name] = convertInput(val, input.Type)
 				} else if input.Required {
-					return fmt.Errorf("required field %q not provided", input.Name)
+					return c.formatError(cmd, fmt.Errorf("required field %q not provided", input.Name))
 				}
 			}
 
@@ -192,11 +196,10 @@ func (c *Channel) buildCreateCommand(mod convention.Derived, action convention.D
 				return c.formatError(cmd, err)
 			}
 
-			outputFmt, _ := cmd.Flags().GetString("output")
-			if outputFmt == "json" || outputFmt == "yaml" {
+			if c.structuredOutput(cmd) {
 				return c.formatRecord(cmd, mod, result.Data)
 			}
-			fmt.Printf("Created %s: %s\n", mod.Source.Name, result.ID)
+			fmt.Fprintf(cmd.OutOrStdout(), "Created %s: %s\n", mod.Source.Name, result.ID)
 			return nil
 		},
 	}
@@ -213,7 +216,6 @@ func (c *Channel) buildCreateCommand(mod convention.Derived, action convention.D
 			cmd.MarkFlagRequired(input.Name)
 		}
 	}
-	c.addOutputFlags(cmd)
 
 	return cmd
 }
@@ -236,7 +238,7 @@ func (c *Channel) buildUpdateCommand(mod convention.Derived, action convention.D
 			}
 
 			if len(data) == 0 {
-				return fmt.Errorf("no fields to update")
+				return c.formatError(cmd, fmt.Errorf("no fields to update"))
 			}
 
 			// Client-side validation
@@ -254,11 +256,10 @@ func (c *Channel) buildUpdateCommand(mod convention.Derived, action convention.D
 				return c.formatError(cmd, err)
 			}
 
-			outputFmt, _ := cmd.Flags().GetString("output")
-			if outputFmt == "json" || outputFmt == "yaml" {
+			if c.structuredOutput(cmd) {
 				return c.formatRecord(cmd, mod, result.Data)
 			}
-			fmt.Printf("Updated %s: %s\n", mod.Source.Name, args[0])
+			fmt.Fprintf(cmd.OutOrStdout(), "Updated %s: %s\n", mod.Source.Name, args[0])
 			return nil
 		},
 	}
@@ -267,7 +268,6 @@ func (c *Channel) buildUpdateCommand(mod convention.Derived, action convention.D
 	for _, input := range action.Input {
 		cmd.Flags().String(input.Name, "", input.Name)
 	}
-	c.addOutputFlags(cmd)
 
 	return cmd
 }
@@ -281,7 +281,11 @@ func (c *Channel) buildDeleteCommand(mod convention.Derived, action convention.D
 		RunE: func(cmd *cobra.Command, args []string) error {
 			force, _ := cmd.Flags().GetBool("force")
 			if !force && action.Confirm {
-				fmt.Printf("Are you sure you want to delete %s %s? (use --force to confirm)\n", mod.Source.Name, args[0])
+				// A script cannot answer the question, so refuse outright
+				if c.structuredOutput(cmd) {
+					return c.formatError(cmd, fmt.Errorf("delete %s %s requires --force", mod.Source.Name, args[0]))
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Are you sure you want to delete %s %s? (use --force to confirm)\n", mod.Source.Name, args[0])
 				return nil
 			}
 
@@ -290,10 +294,13 @@ func (c *Channel) buildDeleteCommand(mod convention.Derived, action convention.D
 				Channel: "cli",
 			})
 			if err != nil {
-				return err
+				return c.formatError(cmd, err)
 			}
 
-			fmt.Printf("Deleted %s: %s\n", mod.Source.Name, args[0])
+			if c.structuredOutput(cmd) {
+				return c.formatRecord(cmd, mod, map[string]any{"id": args[0], "deleted": true})
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Deleted %s: %s\n", mod.Source.Name, args[0])
 			return nil
 		},
 	}
@@ -326,10 +333,13 @@ func (c *Channel) buildCustomCommand(mod convention.Derived, action convention.D
 				Channel: "cli",
 			})
 			if err != nil {
-				return err
+				return c.formatError(cmd, err)
 			}
 
-			fmt.Printf("%s completed for %s: %s\n", strings.Title(action.Name), mod.Source.Name, result.ID)
+			if c.structuredOutput(cmd) {
+				return c.formatRecord(cmd, mod, result.Data)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s completed for %s: %s\n", strings.Title(action.Name), mod.Source.Name, result.ID)
 			return nil
 		},
 	}
@@ -345,9 +355,21 @@ func (c *Channel) buildCustomCommand(mod convention.Derived, action convention.D
 
 // addOutputFlags adds common output format flags to a command.
 func (c *Channel) addOutputFlags(cmd *cobra.Command) {
-	cmd.Flags().StringP("output", "O", "table", "Output format: "+strings.Join(c.formatters.List(), ", "))
-	cmd.Flags().Bool("no-header", false, "Disable header row (table format)")
-	cmd.Flags().Bool("compact", false, "Compact output (json/yaml)")
+	c.addOutputFlagSet(cmd.Flags())
+}
+
+// addOutputFlagSet adds common output format flags to a flag set.
+func (c *Channel) addOutputFlagSet(flags *pflag.FlagSet) {
+	flags.StringP("output", "O", "table", "Output format: "+strings.Join(c.formatters.List(), ", "))
+	flags.Bool("no-header", false, "Disable header row (table format)")
+	flags.Bool("compact", false, "Compact output (json/yaml)")
+}
+
+// structuredOutput reports whether the command writes machine-readable
+// output, in which case it prints nothing but formatted records and errors.
+func (c *Channel) structuredOutput(cmd *cobra.Command) bool {
+	outputFmt, _ := cmd.Flags().GetString("output")
+	return outputFmt == "json" || outputFmt == "yaml"
 }
 
 // getFormatter returns the formatter for the current command.
@@ -380,20 +402,22 @@ func (c *Channel) getFormatOptions(cmd *cobra.Command) formatter.FormatOptions {
 func (c *Channel) formatList(cmd *cobra.Command, mod convention.Derived, records []map[string]any) error {
 	f := c.getFormatter(cmd)
 	opts := c.getFormatOptions(cmd)
-	return f.FormatList(os.Stdout, mod, records, opts)
+	return f.FormatList(cmd.OutOrStdout(), mod, records, opts)
 }
 
 // formatRecord formats and outputs a single record.
 func (c *Channel) formatRecord(cmd *cobra.Command, mod convention.Derived, record map[string]any) error {
 	f := c.getFormatter(cmd)
 	opts := c.getFormatOptions(cmd)
-	return f.FormatRecord(os.Stdout, mod, record, opts)
+	return f.FormatRecord(cmd.OutOrStdout(), mod, record, opts)
 }
 
-// formatError formats and outputs an error.
+// formatError formats and outputs an error, and returns it so the command
+// exits non-zero. Cobra is told not to print it again.
 func (c *Channel) formatError(cmd *cobra.Command, err error) error {
 	f := c.getFormatter(cmd)
-	f.FormatError(os.Stderr, err)
+	f.FormatError(cmd.ErrOrStderr(), err)
+	cmd.SilenceErrors = true
 	return err
 }
 
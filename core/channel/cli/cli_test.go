@@ -1,12 +1,16 @@
 package cli
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"testing"
 
 	"github.com/artpar/apigate/core/convention"
+	"github.com/artpar/apigate/core/runtime"
 	"github.com/artpar/apigate/core/schema"
+	"github.com/artpar/apigate/core/storage"
 	"github.com/spf13/cobra"
 )
 
@@ -778,3 +782,123 @@ func TestChannel_getFormatter_EmptyOutput(t *testing.T) {
 		t.Error("getFormatter should return default formatter when output not set")
 	}
 }
+
+// sqliteRuntimeStorage adapts storage.SQLiteStore to runtime.Storage.
+type sqliteRuntimeStorage struct {
+	*storage.SQLiteStore
+}
+
+func (s sqliteRuntimeStorage) List(ctx context.Context, module string, opts runtime.ListOptions) ([]map[string]any, int64, error) {
+	return s.SQLiteStore.List(ctx, module, storage.ListOptions{
+		Limit:     opts.Limit,
+		Offset:    opts.Offset,
+		Filters:   opts.Filters,
+		OrderBy:   opts.OrderBy,
+		OrderDesc: opts.OrderDesc,
+	})
+}
+
+func TestChannel_JSONOutput(t *testing.T) {
+	store, err := storage.NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer store.Close()
+
+	rootCmd := &cobra.Command{Use: "apigate"}
+	rt := runtime.New(sqliteRuntimeStorage{store}, runtime.Config{})
+	rt.RegisterChannel(New(rootCmd, rt))
+	if err := rt.LoadModule(schema.Module{
+		Name: "widget",
+		Schema: map[string]schema.Field{
+			"name": {Type: schema.FieldTypeString, Lookup: true},
+		},
+		Channels: schema.Channels{CLI: schema.CLIChannel{Serve: schema.CLIServe{Enabled: true}}},
+	}); err != nil {
+		t.Fatalf("LoadModule failed: %v", err)
+	}
+
+	run := func(args ...string) (string, string, error) {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		rootCmd.SetOut(&stdout)
+		rootCmd.SetErr(&stderr)
+		rootCmd.SetArgs(args)
+		err := rootCmd.Execute()
+		return stdout.String(), stderr.String(), err
+	}
+
+	type record struct {
+		Module string         `json:"module"`
+		Data   map[string]any `json:"data"`
+	}
+
+	// Create emits the new record
+	out, _, err := run("widgets", "create", "--name", "sprocket", "--output", "json")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	var created record
+	if err := json.Unmarshal([]byte(out), &created); err != nil {
+		t.Fatalf("create output is not JSON: %v\n%s", err, out)
+	}
+	id, _ := created.Data["id"].(string)
+	if id == "" || created.Data["name"] != "sprocket" {
+		t.Fatalf("created = %+v", created)
+	}
+
+	// The flag is global, so it may also come before the subcommand
+	out, _, err = run("--output", "json", "widgets", "list")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	var list struct {
+		Count int              `json:"count"`
+		Data  []map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(out), &list); err != nil {
+		t.Fatalf("list output is not JSON: %v\n%s", err, out)
+	}
+	if list.Count != 1 || len(list.Data) != 1 || list.Data[0]["id"] != id {
+		t.Errorf("list = %+v", list)
+	}
+
+	out, _, err = run("widgets", "get", "sprocket", "-O", "json")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	var got record
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("get output is not JSON: %v\n%s", err, out)
+	}
+	if got.Module != "widget" || got.Data["id"] != id {
+		t.Errorf("get = %+v", got)
+	}
+
+	// Errors fail the command, with nothing on stdout and a JSON error on stderr
+	out, errOut, err := run("widgets", "get", "missing", "--output", "json")
+	if err == nil {
+		t.Fatal("get of a missing record should fail")
+	}
+	if out != "" {
+		t.Errorf("stdout on error = %q, want empty", out)
+	}
+	var failure struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(errOut), &failure); err != nil || failure.Error == "" {
+		t.Errorf("stderr is not a JSON error: %v\n%s", err, errOut)
+	}
+
+	out, _, err = run("widgets", "delete", id, "--force", "--output", "json")
+	if err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	var deleted record
+	if err := json.Unmarshal([]byte(out), &deleted); err != nil {
+		t.Fatalf("delete output is not JSON: %v\n%s", err, out)
+	}
+	if deleted.Data["id"] != id || deleted.Data["deleted"] != true {
+		t.Errorf("deleted = %+v", deleted)
+	}
+}
@@ -5,8 +5,10 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 
@@ -14,6 +16,7 @@ import (
 	"github.com/artpar/apigate/core/openapi"
 	"github.com/artpar/apigate/core/runtime"
 	"github.com/artpar/apigate/core/schema"
+	"github.com/artpar/apigate/core/storage"
 	"github.com/artpar/apigate/pkg/jsonapi"
 	"github.com/go-chi/chi/v5"
 )
@@ -392,22 +395,23 @@ func (c *Channel) handleCustomAction(mod convention.Derived, actionName string)
 
 // doList handles list requests.
 func (c *Channel) doList(ctx context.Context, w http.ResponseWriter, r *http.Request, mod convention.Derived) {
-	// Parse query parameters
-	limit := 100
-	offset := 0
-	if l := r.URL.Query().Get("limit"); l != "" {
-		limit, _ = strconv.Atoi(l)
-	}
-	if o := r.URL.Query().Get("offset"); o != "" {
-		offset, _ = strconv.Atoi(o)
+	// Parse query parameters: page[number]/page[size], limit/offset, or page[cursor]
+	q := r.URL.Query()
+	page, limit := jsonapi.ParsePaginationParams(q, 100)
+	offset := (page - 1) * limit
+	if o := q.Get("offset"); o != "" {
+		if n, err := strconv.Atoi(o); err == nil && n >= 0 {
+			offset = n
+			page = offset/limit + 1
+		}
 	}
+	cursor := jsonapi.ParseCursor(q)
 
 	// Build filters from query params
-	filters := make(map[string]any)
-	for _, f := range mod.Fields {
-		if val := r.URL.Query().Get(f.Name); val != "" {
-			filters[f.Name] = val
-		}
+	filters, err := parseListFilters(q, mod)
+	if err != nil {
+		jsonapi.WriteBadRequest(w, err.Error())
+		return
 	}
 
 	input := runtime.ActionInput{
@@ -420,9 +424,19 @@ func (c *Channel) doList(ctx context.Context, w http.ResponseWriter, r *http.Req
 		RemoteIP:     r.RemoteAddr,
 		RequestBytes: r.ContentLength,
 	}
+	if cursor != "" {
+		input.Data["cursor"] = cursor
+	}
+	if search := q.Get("q"); search != "" {
+		input.Data["q"] = search
+	}
 
 	result, err := c.runtime.Execute(ctx, mod.Source.Name, "list", input)
 	if err != nil {
+		if errors.Is(err, storage.ErrInvalidCursor) || errors.Is(err, storage.ErrInvalidFilter) {
+			jsonapi.WriteBadRequest(w, err.Error())
+			return
+		}
 		jsonapi.WriteInternalError(w, err.Error())
 		return
 	}
@@ -443,12 +457,62 @@ func (c *Channel) doList(ctx context.Context, w http.ResponseWriter, r *http.Req
 		resources = append(resources, rb.Build())
 	}
 
-	// Calculate page for pagination
-	page := (offset / limit) + 1
-	pagination := jsonapi.NewPagination(int64(result.Count), page, limit, r.URL.String())
+	var pagination *jsonapi.Pagination
+	if cursor != "" {
+		pagination = jsonapi.NewCursorPagination(result.Count, limit, cursor, result.NextCursor, r.URL.String())
+	} else {
+		pagination = jsonapi.NewPagination(result.Count, page, limit, r.URL.String())
+		pagination.NextCursor = result.NextCursor
+	}
 	jsonapi.WriteCollection(w, http.StatusOK, resources, pagination)
 }
 
+// parseListFilters reads equality filters (field=value) and operator filters
+// (field[op]=value, e.g. price[gte]=100) for the module's fields. Parameters
+// that do not name a field, such as page[size], are left to pagination.
+// Internal fields such as hashes and secrets cannot be filtered on, since
+// comparisons would reveal their values.
+func parseListFilters(q url.Values, mod convention.Derived) (map[string]any, error) {
+	fields := make(map[string]bool, len(mod.Fields))
+	for _, f := range mod.Fields {
+		fields[f.Name] = !f.Internal
+	}
+
+	byField := make(map[string][]storage.Filter)
+	for key, vals := range q {
+		if len(vals) == 0 || vals[0] == "" {
+			continue
+		}
+		name, opName, hasOp := strings.Cut(key, "[")
+		filterable, isField := fields[name]
+		if !isField {
+			continue
+		}
+		if !filterable {
+			return nil, fmt.Errorf("%w: field %q cannot be filtered", storage.ErrInvalidFilter, name)
+		}
+		op := storage.OpEq
+		if hasOp {
+			var ok bool
+			op, ok = storage.ParseFilterOp(strings.TrimSuffix(opName, "]"))
+			if !ok || !strings.HasSuffix(opName, "]") {
+				return nil, fmt.Errorf("%w: unsupported filter %q", storage.ErrInvalidFilter, key)
+			}
+		}
+		byField[name] = append(byField[name], storage.Filter{Op: op, Value: vals[0]})
+	}
+
+	filters := make(map[string]any, len(byField))
+	for name, list := range byField {
+		if len(list) == 1 && list[0].Op == storage.OpEq {
+			filters[name] = list[0].Value
+		} else {
+			filters[name] = list
+		}
+	}
+	return filters, nil
+}
+
 // doGet handles get requests.
 func (c *Channel) doGet(ctx context.Context, w http.ResponseWriter, r *http.Request, mod convention.Derived, id string) {
 	result, err := c.runtime.Execute(ctx, mod.Source.Name, "get", runtime.ActionInput{
@@ -520,6 +584,10 @@ func (c *Channel) doUpdate(ctx context.Context, w http.ResponseWriter, r *http.R
 		RequestBytes: r.ContentLength,
 	})
 	if err != nil {
+		if errors.Is(err, storage.ErrConflict) {
+			jsonapi.WriteConflict(w, err.Error())
+			return
+		}
 		jsonapi.WriteBadRequest(w, err.Error())
 		return
 	}
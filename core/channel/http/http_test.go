@@ -13,6 +13,7 @@ import (
 	"github.com/artpar/apigate/core/convention"
 	"github.com/artpar/apigate/core/runtime"
 	"github.com/artpar/apigate/core/schema"
+	"github.com/artpar/apigate/core/storage"
 	"github.com/artpar/apigate/pkg/jsonapi"
 	"github.com/go-chi/chi/v5"
 )
@@ -1206,3 +1207,218 @@ func TestRegisterExplicitEndpoints_AllMethods(t *testing.T) {
 		t.Errorf("Register should not error: %v", err)
 	}
 }
+
+// sqliteRuntimeStorage adapts storage.SQLiteStore to runtime.Storage.
+type sqliteRuntimeStorage struct {
+	*storage.SQLiteStore
+}
+
+func (s sqliteRuntimeStorage) List(ctx context.Context, module string, opts runtime.ListOptions) ([]map[string]any, int64, error) {
+	return s.SQLiteStore.List(ctx, module, storage.ListOptions{
+		Limit:     opts.Limit,
+		Offset:    opts.Offset,
+		Cursor:    opts.Cursor,
+		Filters:   opts.Filters,
+		Search:    opts.Search,
+		OrderBy:   opts.OrderBy,
+		OrderDesc: opts.OrderDesc,
+	})
+}
+
+func TestChannel_DoList_CursorPagination(t *testing.T) {
+	store, err := storage.NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer store.Close()
+
+	rt := runtime.New(sqliteRuntimeStorage{store}, runtime.Config{})
+	c := New(rt, "")
+	rt.RegisterChannel(c)
+	if err := rt.LoadModule(schema.Module{
+		Name: "item",
+		Schema: map[string]schema.Field{
+			"name": {Type: schema.FieldTypeString},
+		},
+		Channels: schema.Channels{HTTP: schema.HTTPChannel{Serve: schema.HTTPServe{Enabled: true}}},
+	}); err != nil {
+		t.Fatalf("LoadModule failed: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := store.Create(context.Background(), "item", map[string]any{"name": string(rune('a' + i))}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	type listResponse struct {
+		Data  []struct{ ID string } `json:"data"`
+		Meta  map[string]any        `json:"meta"`
+		Links map[string]string     `json:"links"`
+	}
+	get := func(url string) listResponse {
+		t.Helper()
+		w := httptest.NewRecorder()
+		c.Handler().ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d: %s", url, w.Code, w.Body.String())
+		}
+		var resp listResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp
+	}
+
+	// Offset page 1 advertises limit, offset and the cursor for page 2
+	first := get("/items?limit=2")
+	if first.Meta["total"] != float64(5) || first.Meta["limit"] != float64(2) || first.Meta["offset"] != float64(0) {
+		t.Errorf("meta = %v", first.Meta)
+	}
+	cursor, _ := first.Meta["next_cursor"].(string)
+	if cursor == "" {
+		t.Fatal("first page should carry next_cursor")
+	}
+
+	// Follow next links from the cursor onwards
+	var ids []string
+	for _, d := range first.Data {
+		ids = append(ids, d.ID)
+	}
+	next := "/items?page[size]=2&page[cursor]=" + cursor
+	for pages := 0; next != "" && pages < 5; pages++ {
+		resp := get(next)
+		for _, d := range resp.Data {
+			ids = append(ids, d.ID)
+		}
+		next = resp.Links["next"]
+	}
+
+	all := get("/items?limit=10")
+	if len(ids) != len(all.Data) {
+		t.Fatalf("walked %d records, want %d", len(ids), len(all.Data))
+	}
+	for i, d := range all.Data {
+		if ids[i] != d.ID {
+			t.Errorf("record %d = %s, want %s", i, ids[i], d.ID)
+		}
+	}
+
+	// A cursor that cannot be decoded is a client error
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/items?cursor=bogus", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("bogus cursor status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestChannel_DoList_FilterOperators(t *testing.T) {
+	store, err := storage.NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer store.Close()
+
+	rt := runtime.New(sqliteRuntimeStorage{store}, runtime.Config{})
+	c := New(rt, "")
+	rt.RegisterChannel(c)
+	if err := rt.LoadModule(schema.Module{
+		Name: "product",
+		Schema: map[string]schema.Field{
+			"name":  {Type: schema.FieldTypeString},
+			"price": {Type: schema.FieldTypeInt},
+			"hash":  {Type: schema.FieldTypeString, Internal: true},
+		},
+		Channels: schema.Channels{HTTP: schema.HTTPChannel{Serve: schema.HTTPServe{Enabled: true}}},
+	}); err != nil {
+		t.Fatalf("LoadModule failed: %v", err)
+	}
+	for i, name := range []string{"apple", "banana", "cherry"} {
+		if _, err := store.Create(context.Background(), "product", map[string]any{"name": name, "price": (i + 1) * 50, "hash": "$2a$10$" + name}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		code  int
+		total float64
+	}{
+		{"/products?price[gte]=100", http.StatusOK, 2},
+		{"/products?price[gt]=50&price[lt]=150", http.StatusOK, 1},
+		{"/products?name=apple&price[ne]=50", http.StatusOK, 0},
+		{"/products?name[in]=apple,cherry&page[size]=10", http.StatusOK, 2},
+		{"/products?name[like]=%25an%25", http.StatusOK, 1},
+		{"/products?name=x%27%20OR%20%271%27=%271", http.StatusOK, 0},
+		{"/products?price[between]=1", http.StatusBadRequest, 0},
+		{"/products?price[gte=1", http.StatusBadRequest, 0},
+		// Internal fields can't be used to probe their values
+		{"/products?hash[like]=%242a%2410%24a%25", http.StatusBadRequest, 0},
+		{"/products?hash[gt]=%242a", http.StatusBadRequest, 0},
+		{"/products?hash=%242a%2410%24apple", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c.Handler().ServeHTTP(w, httptest.NewRequest("GET", tt.query, nil))
+		if w.Code != tt.code {
+			t.Errorf("GET %s = %d, want %d: %s", tt.query, w.Code, tt.code, w.Body.String())
+			continue
+		}
+		if tt.code != http.StatusOK {
+			continue
+		}
+		var resp struct {
+			Meta map[string]any `json:"meta"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if resp.Meta["total"] != tt.total {
+			t.Errorf("GET %s total = %v, want %v", tt.query, resp.Meta["total"], tt.total)
+		}
+	}
+}
+
+func TestChannel_DoUpdate_StaleVersion(t *testing.T) {
+	store, err := storage.NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer store.Close()
+
+	rt := runtime.New(sqliteRuntimeStorage{store}, runtime.Config{})
+	c := New(rt, "")
+	rt.RegisterChannel(c)
+	if err := rt.LoadModule(schema.Module{
+		Name:     "profile",
+		Schema:   map[string]schema.Field{"name": {Type: schema.FieldTypeString}},
+		Channels: schema.Channels{HTTP: schema.HTTPChannel{Serve: schema.HTTPServe{Enabled: true}}},
+	}); err != nil {
+		t.Fatalf("LoadModule failed: %v", err)
+	}
+	id, err := store.Create(context.Background(), "profile", map[string]any{"name": "original"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	update := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("PUT", "/profiles/"+id, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		c.Handler().ServeHTTP(w, req)
+		return w
+	}
+
+	// Two clients read version 1; the second save is rejected
+	if w := update(`{"name": "first", "version": 1}`); w.Code != http.StatusOK {
+		t.Fatalf("first update = %d: %s", w.Code, w.Body.String())
+	}
+	w := update(`{"name": "second", "version": 1}`)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("stale update = %d, want 409: %s", w.Code, w.Body.String())
+	}
+
+	got, _ := store.Get(context.Background(), "profile", "id", id)
+	if got["name"] != "first" {
+		t.Errorf("name = %v, stale update should not apply", got["name"])
+	}
+}
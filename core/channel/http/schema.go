@@ -126,6 +126,7 @@ func (h *SchemaHandler) buildFields(fields []convention.DerivedField) []schema.F
 			Lookup:      f.Lookup,
 			Filterable:  filterable,
 			Sortable:    sortable,
+			Searchable:  f.Searchable,
 			Values:      f.Values,
 			Ref:         f.Ref,
 			Default:     f.Default,
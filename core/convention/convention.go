@@ -21,7 +21,7 @@ type Derived struct {
 	// Table is the database table name.
 	Table string
 
-	// Fields contains all fields including implicit ones (id, created_at, updated_at).
+	// Fields contains all fields including implicit ones (id, created_at, updated_at, version).
 	Fields []DerivedField
 
 	// Actions contains all actions including implicit CRUD.
@@ -60,6 +60,9 @@ type DerivedField struct {
 	// Internal indicates this field is never exposed.
 	Internal bool
 
+	// Searchable indicates this field is full-text indexed.
+	Searchable bool
+
 	// Default value.
 	Default any
 
@@ -160,7 +163,7 @@ func Derive(mod schema.Module) Derived {
 
 // deriveFields creates the full list of fields including implicit ones.
 func deriveFields(mod schema.Module) []DerivedField {
-	fields := make([]DerivedField, 0, len(mod.Schema)+3)
+	fields := make([]DerivedField, 0, len(mod.Schema)+4)
 
 	// Implicit ID field
 	fields = append(fields, DerivedField{
@@ -184,6 +187,7 @@ func deriveFields(mod schema.Module) []DerivedField {
 			Required:    f.IsRequired(),
 			Lookup:      f.Lookup,
 			Internal:    f.IsInternal(),
+			Searchable:  f.Searchable && f.IsText(),
 			Default:     f.Default,
 			Values:      f.Values,
 			Ref:         f.To,
@@ -211,6 +215,17 @@ func deriveFields(mod schema.Module) []DerivedField {
 		Implicit: true,
 	})
 
+	// Implicit version for optimistic concurrency, bumped by every update
+	if _, declared := mod.Schema["version"]; !declared {
+		fields = append(fields, DerivedField{
+			Name:     "version",
+			Type:     schema.FieldTypeInt,
+			SQLType:  "INTEGER",
+			Default:  1,
+			Implicit: true,
+		})
+	}
+
 	return fields
 }
 
@@ -28,9 +28,9 @@ func TestDerive_EmptyModule(t *testing.T) {
 		t.Errorf("expected Table = 'users', got %q", d.Table)
 	}
 
-	// Should have 3 implicit fields: id, created_at, updated_at
-	if len(d.Fields) != 3 {
-		t.Errorf("expected 3 implicit fields, got %d", len(d.Fields))
+	// Should have 4 implicit fields: id, created_at, updated_at, version
+	if len(d.Fields) != 4 {
+		t.Errorf("expected 4 implicit fields, got %d", len(d.Fields))
 	}
 
 	// Should have 5 implicit CRUD actions
@@ -65,9 +65,9 @@ func TestDerive_WithUserDefinedFields(t *testing.T) {
 
 	d := Derive(mod)
 
-	// 3 user fields + 3 implicit fields (id, created_at, updated_at)
-	if len(d.Fields) != 6 {
-		t.Errorf("expected 6 fields, got %d", len(d.Fields))
+	// 3 user fields + 4 implicit fields (id, created_at, updated_at, version)
+	if len(d.Fields) != 7 {
+		t.Errorf("expected 7 fields, got %d", len(d.Fields))
 	}
 
 	// Check for specific fields
@@ -152,7 +152,9 @@ func (a *storageAdapter) List(ctx context.Context, module string, opts runtime.L
 	return a.store.List(ctx, module, storage.ListOptions{
 		Limit:     opts.Limit,
 		Offset:    opts.Offset,
+		Cursor:    opts.Cursor,
 		Filters:   opts.Filters,
+		Search:    opts.Search,
 		OrderBy:   opts.OrderBy,
 		OrderDesc: opts.OrderDesc,
 	})
@@ -0,0 +1,156 @@
+package openapi
+
+import "strings"
+
+// maxExampleDepth bounds how deep GenerateExample follows nested schemas and
+// references, so self-referencing schemas still produce a finite example.
+const maxExampleDepth = 6
+
+// GenerateExample builds a plausible example value for a schema. Explicit
+// examples and defaults are used where present; otherwise values are made up
+// from types and formats (an email field gets "user@example.com", an enum its
+// first value). References are resolved against components.
+func GenerateExample(s *Schema, components map[string]*Schema) any {
+	return generateExample(s, components, 0)
+}
+
+func generateExample(s *Schema, components map[string]*Schema, depth int) any {
+	if s == nil || depth > maxExampleDepth {
+		return nil
+	}
+	if s.Example != nil {
+		return s.Example
+	}
+	if s.Default != nil {
+		return s.Default
+	}
+	if s.Ref != "" {
+		return generateExample(components[strings.TrimPrefix(s.Ref, "#/components/schemas/")], components, depth+1)
+	}
+	if len(s.Enum) > 0 {
+		return s.Enum[0]
+	}
+	if len(s.AllOf) > 0 {
+		merged := map[string]any{}
+		for _, sub := range s.AllOf {
+			if obj, ok := generateExample(sub, components, depth+1).(map[string]any); ok {
+				for k, v := range obj {
+					merged[k] = v
+				}
+			}
+		}
+		return merged
+	}
+	if len(s.OneOf) > 0 {
+		return generateExample(s.OneOf[0], components, depth+1)
+	}
+
+	switch s.Type {
+	case "object", "":
+		if len(s.Properties) == 0 {
+			if s.Type == "" {
+				return nil
+			}
+			return map[string]any{}
+		}
+		obj := make(map[string]any, len(s.Properties))
+		for name, prop := range s.Properties {
+			if v := generateExample(prop, components, depth+1); v != nil {
+				obj[name] = v
+			}
+		}
+		return obj
+	case "array":
+		if item := generateExample(s.Items, components, depth+1); item != nil {
+			return []any{item}
+		}
+		return []any{}
+	case "integer":
+		if s.Minimum != nil {
+			return int(*s.Minimum)
+		}
+		return 1
+	case "number":
+		if s.Minimum != nil {
+			return *s.Minimum
+		}
+		return 1.5
+	case "boolean":
+		return true
+	case "string":
+		return stringExample(s)
+	}
+	return nil
+}
+
+// stringExample returns an example string for a schema's format.
+func stringExample(s *Schema) string {
+	switch s.Format {
+	case "email":
+		return "user@example.com"
+	case "uuid":
+		return "550e8400-e29b-41d4-a716-446655440000"
+	case "uri", "url":
+		return "https://example.com"
+	case "date-time":
+		return "2024-01-15T10:30:00Z"
+	case "date":
+		return "2024-01-15"
+	case "byte":
+		return "ZXhhbXBsZQ=="
+	case "password":
+		return "********"
+	}
+	example := "string"
+	if s.MinLength != nil && len(example) < *s.MinLength {
+		example += strings.Repeat("x", *s.MinLength-len(example))
+	}
+	if s.MaxLength != nil && len(example) > *s.MaxLength {
+		example = example[:*s.MaxLength]
+	}
+	return example
+}
+
+// addExamples gives every JSON request and response body in the spec an
+// example generated from its schema, unless it already has one.
+func addExamples(spec *Spec) {
+	fill := func(content map[string]MediaType) {
+		media, ok := content["application/json"]
+		if !ok || media.Schema == nil || media.ExampleValue() != nil {
+			return
+		}
+		example := GenerateExample(media.Schema, spec.Components.Schemas)
+		if obj, ok := example.(map[string]any); example == nil || ok && len(obj) == 0 {
+			return // Nothing known about the body
+		}
+		media.Example = example
+		content["application/json"] = media
+	}
+	for _, item := range spec.Paths {
+		for _, op := range []*Operation{item.Get, item.Post, item.Put, item.Patch, item.Delete} {
+			if op == nil {
+				continue
+			}
+			if op.RequestBody != nil {
+				fill(op.RequestBody.Content)
+			}
+			for _, resp := range op.Responses {
+				if resp.Content != nil {
+					fill(resp.Content)
+				}
+			}
+		}
+	}
+}
+
+// ExampleValue returns the media type's example, or its schema's inline
+// example if it has none.
+func (m MediaType) ExampleValue() any {
+	if m.Example != nil {
+		return m.Example
+	}
+	if m.Schema != nil {
+		return m.Schema.Example
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/artpar/apigate/core/convention"
+	"github.com/artpar/apigate/core/schema"
+	"github.com/artpar/apigate/domain/route"
+)
+
+func TestGenerateExample_FromSchema(t *testing.T) {
+	minLen := 8
+	minimum := 5.0
+	components := map[string]*Schema{
+		"Address": {Type: "object", Properties: map[string]*Schema{"city": {Type: "string", Example: "Berlin"}}},
+	}
+
+	tests := []struct {
+		name   string
+		schema *Schema
+		want   any
+	}{
+		{"explicit example wins", &Schema{Type: "string", Format: "email", Example: "a@b.c"}, "a@b.c"},
+		{"default", &Schema{Type: "integer", Default: 7}, 7},
+		{"email", &Schema{Type: "string", Format: "email"}, "user@example.com"},
+		{"enum takes first value", &Schema{Type: "string", Enum: []string{"low", "high"}}, "low"},
+		{"min length", &Schema{Type: "string", MinLength: &minLen}, "stringxx"},
+		{"integer minimum", &Schema{Type: "integer", Minimum: &minimum}, 5},
+		{"boolean", &Schema{Type: "boolean"}, true},
+		{"array", &Schema{Type: "array", Items: &Schema{Type: "string", Format: "uuid"}}, []any{"550e8400-e29b-41d4-a716-446655440000"}},
+		{"reference", &Schema{Ref: "#/components/schemas/Address"}, map[string]any{"city": "Berlin"}},
+		{
+			"object",
+			&Schema{Type: "object", Properties: map[string]*Schema{
+				"when": {Type: "string", Format: "date-time"},
+				"qty":  {Type: "number"},
+			}},
+			map[string]any{"when": "2024-01-15T10:30:00Z", "qty": 1.5},
+		},
+		{"unknown reference", &Schema{Ref: "#/components/schemas/Missing"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GenerateExample(tt.schema, components); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GenerateExample() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateExample_SelfReference(t *testing.T) {
+	components := map[string]*Schema{
+		"Node": {Type: "object", Properties: map[string]*Schema{"next": {Ref: "#/components/schemas/Node"}}},
+	}
+	if got := GenerateExample(&Schema{Ref: "#/components/schemas/Node"}, components); got == nil {
+		t.Error("expected a finite example for a self-referencing schema")
+	}
+}
+
+func TestGenerate_UndocumentedPostGetsExample(t *testing.T) {
+	orderModule := createTestModule("order", map[string]schema.Field{
+		"email":  {Type: schema.FieldTypeEmail, Required: boolPtr(true)},
+		"status": {Type: schema.FieldTypeEnum, Values: []string{"pending", "shipped"}},
+	}, nil)
+	spec := NewGenerator(map[string]convention.Derived{"order": deriveModule(orderModule)}).Generate()
+
+	post := spec.Paths["/mod/orders"].Post
+	if post == nil {
+		t.Fatal("expected POST /mod/orders")
+	}
+	body, ok := post.RequestBody.Content["application/json"].ExampleValue().(map[string]any)
+	if !ok {
+		t.Fatalf("request example = %#v, want an object", post.RequestBody.Content["application/json"].ExampleValue())
+	}
+	if body["email"] != "user@example.com" || body["status"] != "pending" {
+		t.Errorf("request example = %v", body)
+	}
+	if _, ok := body["id"]; ok {
+		t.Error("create example should not include the implicit id")
+	}
+
+	created, ok := post.Responses["201"].Content["application/json"].ExampleValue().(map[string]any)
+	if !ok {
+		t.Fatal("expected a 201 response example")
+	}
+	if data, ok := created["data"].(map[string]any); !ok || data["id"] == nil || data["email"] != "user@example.com" {
+		t.Errorf("response example = %v", created)
+	}
+}
+
+func TestRouteGenerator_ExampleFromRequestSchema(t *testing.T) {
+	routes := []route.Route{
+		{
+			ID: "1", Name: "create-order", PathPattern: "/orders", MatchType: route.MatchExact, Methods: []string{"POST"},
+			RequestSchema: `{"type": "object", "properties": {"sku": {"type": "string"}, "email": {"type": "string", "format": "email"}}}`,
+			Enabled:       true,
+		},
+		{
+			ID: "2", Name: "upload", PathPattern: "/uploads", MatchType: route.MatchExact, Methods: []string{"POST"},
+			Enabled: true,
+		},
+	}
+	spec := NewRouteGenerator(routes, nil).Generate()
+
+	got := spec.Paths["/orders"].Post.RequestBody.Content["application/json"].ExampleValue()
+	want := map[string]any{"sku": "string", "email": "user@example.com"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("example = %#v, want %#v", got, want)
+	}
+
+	// Without a schema there is nothing to base an example on
+	if got := spec.Paths["/uploads"].Post.RequestBody.Content["application/json"].ExampleValue(); got != nil {
+		t.Errorf("schemaless example = %#v, want none", got)
+	}
+}
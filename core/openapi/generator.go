@@ -3,13 +3,16 @@
 package openapi
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"regexp"
 	"sort"
 	"strings"
 
 	"github.com/artpar/apigate/core/convention"
 	"github.com/artpar/apigate/core/schema"
+	"gopkg.in/yaml.v3"
 )
 
 // Spec represents an OpenAPI 3.0 specification.
@@ -95,7 +98,8 @@ type Response struct {
 
 // MediaType represents a media type.
 type MediaType struct {
-	Schema *Schema `json:"schema,omitempty"`
+	Schema  *Schema `json:"schema,omitempty"`
+	Example any     `json:"example,omitempty"`
 }
 
 // Schema represents a JSON Schema.
@@ -104,19 +108,21 @@ type Schema struct {
 	Format      string            `json:"format,omitempty"`
 	Description string            `json:"description,omitempty"`
 	Properties  map[string]*Schema `json:"properties,omitempty"`
-	Required    []string          `json:"required,omitempty"`
-	Items       *Schema           `json:"items,omitempty"`
-	Enum        []string          `json:"enum,omitempty"`
-	Ref         string            `json:"$ref,omitempty"`
-	MinLength   *int              `json:"minLength,omitempty"`
-	MaxLength   *int              `json:"maxLength,omitempty"`
-	Minimum     *float64          `json:"minimum,omitempty"`
-	Maximum     *float64          `json:"maximum,omitempty"`
-	Pattern     string            `json:"pattern,omitempty"`
-	Default     any               `json:"default,omitempty"`
-	Example     any               `json:"example,omitempty"`
-	AllOf       []*Schema         `json:"allOf,omitempty"`
-	OneOf       []*Schema         `json:"oneOf,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	Ref         string             `json:"$ref,omitempty"`
+	MinLength   *int               `json:"minLength,omitempty"`
+	MaxLength   *int               `json:"maxLength,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+	Pattern     string             `json:"pattern,omitempty"`
+	Default     any                `json:"default,omitempty"`
+	Example     any                `json:"example,omitempty"`
+	AllOf       []*Schema          `json:"allOf,omitempty"`
+	OneOf       []*Schema          `json:"oneOf,omitempty"`
+
+	pattern *regexp.Regexp // Compiled Pattern, set by ParseSchema
 }
 
 // Components contains reusable schemas.
@@ -216,6 +222,8 @@ func (g *Generator) Generate() *Spec {
 		g.generateModule(spec, mod)
 	}
 
+	addExamples(spec)
+
 	return spec
 }
 
@@ -330,6 +338,7 @@ func (g *Generator) buildResponseSchema(mod convention.Derived) *Schema {
 	schema := &Schema{
 		Type:       "object",
 		Properties: make(map[string]*Schema),
+		Required:   []string{},
 	}
 
 	for _, field := range mod.Fields {
@@ -339,7 +348,20 @@ func (g *Generator) buildResponseSchema(mod convention.Derived) *Schema {
 		}
 
 		fieldSchema := g.fieldToSchema(field)
+		if field.Ref != "" {
+			// Stored references are record IDs, even when a lookup value was given
+			fieldSchema.Format = "uuid"
+			fieldSchema.Example = "550e8400-e29b-41d4-a716-446655440000"
+			if _, ok := g.modules[field.Ref]; ok && field.Description == "" {
+				fieldSchema.Description = fmt.Sprintf("ID of the referenced %s (see #/components/schemas/%s)", field.Ref, strings.Title(field.Ref))
+			}
+		}
 		schema.Properties[field.Name] = fieldSchema
+
+		// Records always carry their implicit fields and required values
+		if field.Implicit || field.Required {
+			schema.Required = append(schema.Required, field.Name)
+		}
 	}
 
 	return schema
@@ -450,6 +472,29 @@ func (g *Generator) fieldToSchema(field convention.DerivedField) *Schema {
 	return s
 }
 
+// inputToSchema converts an action input to OpenAPI schema. Inputs that map
+// to a module field are described by that field; others by their own type.
+func (g *Generator) inputToSchema(mod convention.Derived, input convention.ActionInput) *Schema {
+	if input.Field != "" {
+		for _, field := range mod.Fields {
+			if field.Name == input.Field {
+				s := g.fieldToSchema(field)
+				if input.Description != "" {
+					s.Description = input.Description
+				}
+				return s
+			}
+		}
+	}
+	return g.fieldToSchema(convention.DerivedField{
+		Name:        input.Name,
+		Type:        input.Type,
+		Ref:         input.To,
+		Default:     input.Default,
+		Description: input.Description,
+	})
+}
+
 // generateExample creates a contextual example based on field name and type.
 func (g *Generator) generateExample(field convention.DerivedField, defaultExample any) any {
 	// Generate contextual examples based on field name
@@ -540,6 +585,9 @@ func (g *Generator) applyConstraint(s *Schema, c schema.Constraint) string {
 		return "Must not be empty"
 	case schema.ConstraintOneOf:
 		if values, ok := c.Value.([]string); ok && len(values) > 0 {
+			if len(s.Enum) == 0 {
+				s.Enum = values
+			}
 			return fmt.Sprintf("Must be one of: %s", strings.Join(values, ", "))
 		}
 	case schema.ConstraintRefExists:
@@ -616,6 +664,22 @@ func (g *Generator) addListPath(spec *Spec, mod convention.Derived, basePath, ti
 		})
 	}
 
+	// Add search parameter when fields are full-text indexed
+	var searchableFields []string
+	for _, field := range mod.Fields {
+		if field.Searchable && !field.Internal {
+			searchableFields = append(searchableFields, field.Name)
+		}
+	}
+	if len(searchableFields) > 0 {
+		params = append(params, Parameter{
+			Name:        "q",
+			In:          "query",
+			Description: fmt.Sprintf("Full-text search. Searchable fields: %s", strings.Join(searchableFields, ", ")),
+			Schema:      &Schema{Type: "string"},
+		})
+	}
+
 	// Add filter parameters for filterable fields
 	for _, field := range mod.Fields {
 		if field.Internal {
@@ -834,9 +898,7 @@ func (g *Generator) addCustomActionPath(spec *Spec, mod convention.Derived, acti
 		}
 
 		for _, input := range action.Input {
-			inputSchema.Properties[input.Name] = &Schema{
-				Type: string(input.Type),
-			}
+			inputSchema.Properties[input.Name] = g.inputToSchema(mod, input)
 			if input.Required {
 				inputSchema.Required = append(inputSchema.Required, input.Name)
 			}
@@ -891,3 +953,41 @@ func (spec *Spec) ToJSON() ([]byte, error) {
 func (spec *Spec) ToJSONCompact() ([]byte, error) {
 	return json.Marshal(spec)
 }
+
+// ToYAML converts the spec to YAML.
+// The JSON encoding is used as the intermediate form so that field names,
+// omitempty rules, and key ordering match ToJSON exactly.
+func (spec *Spec) ToYAML() ([]byte, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	// YAML is a superset of JSON, so the node tree can be decoded directly.
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("decode spec: %w", err)
+	}
+	clearNodeStyle(&doc)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("encode spec: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// clearNodeStyle resets the flow/quoted styles inherited from JSON so the
+// encoder emits block-style YAML. Tags are preserved, so strings that look
+// like numbers or booleans (e.g. response codes "200") stay quoted.
+func clearNodeStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		clearNodeStyle(c)
+	}
+}
@@ -2,10 +2,14 @@ package openapi
 
 import (
 	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/artpar/apigate/core/convention"
 	"github.com/artpar/apigate/core/schema"
+	"gopkg.in/yaml.v3"
 )
 
 // Helper to create a test module
@@ -697,6 +701,88 @@ func TestGenerateWithCustomAction(t *testing.T) {
 	}
 }
 
+// TestGenerateComponentSchemas tests the component schemas derived from a module
+func TestGenerateComponentSchemas(t *testing.T) {
+	orderModule := createTestModule("order", map[string]schema.Field{
+		"email":    {Type: schema.FieldTypeEmail, Required: boolPtr(true)},
+		"quantity": {Type: schema.FieldTypeInt},
+		"status":   {Type: schema.FieldTypeEnum, Values: []string{"pending", "shipped"}},
+		"user":     {Type: schema.FieldTypeRef, To: "user"},
+		"tracking": {Type: schema.FieldTypeUUID},
+		"channel": {Type: schema.FieldTypeString, Constraints: []schema.Constraint{
+			{Type: schema.ConstraintOneOf, Value: []string{"web", "api"}},
+		}},
+	}, map[string]schema.Action{
+		"refund": {
+			Description: "Refund the order",
+			Input: []schema.ActionInput{
+				{Name: "amount", Type: "float", Required: true},
+				{Field: "status"},
+			},
+		},
+	})
+	userModule := createTestModule("user", map[string]schema.Field{
+		"name": {Type: schema.FieldTypeString},
+	}, nil)
+
+	spec := NewGenerator(map[string]convention.Derived{
+		"order": deriveModule(orderModule),
+		"user":  deriveModule(userModule),
+	}).Generate()
+
+	order := spec.Components.Schemas["Order"]
+	if order == nil {
+		t.Fatal("expected Order component schema")
+	}
+	want := map[string][2]string{ // property -> type, format
+		"id":         {"string", "uuid"},
+		"email":      {"string", "email"},
+		"quantity":   {"integer", ""},
+		"status":     {"string", ""},
+		"user":       {"string", "uuid"},
+		"tracking":   {"string", "uuid"},
+		"created_at": {"string", "date-time"},
+	}
+	for name, tf := range want {
+		prop := order.Properties[name]
+		if prop == nil {
+			t.Errorf("Order.%s missing", name)
+			continue
+		}
+		if prop.Type != tf[0] || prop.Format != tf[1] {
+			t.Errorf("Order.%s = %s/%s, want %s/%s", name, prop.Type, prop.Format, tf[0], tf[1])
+		}
+	}
+	if got := order.Properties["status"].Enum; !reflect.DeepEqual(got, []string{"pending", "shipped"}) {
+		t.Errorf("Order.status enum = %v", got)
+	}
+	if got := order.Properties["channel"].Enum; !reflect.DeepEqual(got, []string{"web", "api"}) {
+		t.Errorf("Order.channel enum = %v", got)
+	}
+	if !strings.Contains(order.Properties["user"].Description, "#/components/schemas/User") {
+		t.Errorf("Order.user description = %q, want a pointer to User", order.Properties["user"].Description)
+	}
+	for _, name := range []string{"id", "email", "created_at"} {
+		if !slices.Contains(order.Required, name) {
+			t.Errorf("Order.required = %v, want %s", order.Required, name)
+		}
+	}
+
+	// The create schema accepts a lookup value for references
+	if f := spec.Components.Schemas["OrderCreate"].Properties["user"].Format; f != "" {
+		t.Errorf("OrderCreate.user format = %q, want none", f)
+	}
+
+	// Action inputs use the same types as module fields
+	input := spec.Paths["/mod/orders/{id}/refund"].Post.RequestBody.Content["application/json"].Schema
+	if input.Properties["amount"].Type != "number" {
+		t.Errorf("refund.amount type = %q, want number", input.Properties["amount"].Type)
+	}
+	if got := input.Properties["status"].Enum; !reflect.DeepEqual(got, []string{"pending", "shipped"}) {
+		t.Errorf("refund.status enum = %v", got)
+	}
+}
+
 // TestGenerateWithCustomBasePath tests custom base path
 func TestGenerateWithCustomBasePath(t *testing.T) {
 	userModule := schema.Module{
@@ -927,6 +1013,61 @@ func TestToJSONCompact(t *testing.T) {
 	}
 }
 
+// TestToYAML tests that YAML output round-trips to the same structure as ToJSON
+func TestToYAML(t *testing.T) {
+	userModule := createTestModule("user", map[string]schema.Field{
+		"email":  {Type: schema.FieldTypeEmail},
+		"age":    {Type: schema.FieldTypeInt, Default: 18},
+		"active": {Type: schema.FieldTypeBool},
+		"status": {Type: schema.FieldTypeEnum, Values: []string{"active", "true", "404"}},
+	}, nil)
+
+	derived := deriveModule(userModule)
+	gen := NewGenerator(map[string]convention.Derived{"user": derived})
+	gen.AddServer("https://api.example.com", "Production: primary")
+	spec := gen.Generate()
+
+	yamlData, err := spec.ToYAML()
+	if err != nil {
+		t.Fatalf("ToYAML failed: %v", err)
+	}
+	if strings.HasPrefix(strings.TrimSpace(string(yamlData)), "{") {
+		t.Fatal("expected block-style YAML, got JSON")
+	}
+
+	var fromYAML interface{}
+	if err := yaml.Unmarshal(yamlData, &fromYAML); err != nil {
+		t.Fatalf("invalid YAML output: %v", err)
+	}
+	// Normalize YAML scalars (ints vs floats) by passing through JSON.
+	normalized, err := json.Marshal(fromYAML)
+	if err != nil {
+		t.Fatalf("YAML contains non-JSON-compatible values: %v", err)
+	}
+	var got interface{}
+	if err := json.Unmarshal(normalized, &got); err != nil {
+		t.Fatalf("unmarshal normalized: %v", err)
+	}
+
+	jsonData, err := spec.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	var want interface{}
+	if err := json.Unmarshal(jsonData, &want); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("YAML structure differs from JSON\nyaml: %s\njson: %s", normalized, jsonData)
+	}
+
+	// Response codes must stay strings, not become integers.
+	if !strings.Contains(string(yamlData), `"200":`) {
+		t.Error("expected response codes to be quoted in YAML")
+	}
+}
+
 // TestMultipleModules tests generation with multiple modules
 func TestMultipleModules(t *testing.T) {
 	userModule := createTestModule("user", map[string]schema.Field{
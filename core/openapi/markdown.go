@@ -0,0 +1,168 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ToMarkdown renders the spec as a Markdown API reference: endpoints grouped
+// under a heading per tag, each with its parameters as a table and its
+// example request and response bodies as JSON code blocks.
+func (spec *Spec) ToMarkdown() []byte {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n\n", spec.Info.Title)
+	if spec.Info.Description != "" {
+		fmt.Fprintf(&b, "%s\n\n", spec.Info.Description)
+	}
+	if spec.Info.Version != "" {
+		fmt.Fprintf(&b, "Version: %s\n\n", spec.Info.Version)
+	}
+	if len(spec.Servers) > 0 {
+		fmt.Fprintf(&b, "Base URL: `%s`\n\n", spec.Servers[0].URL)
+	}
+	if scheme, ok := spec.Components.SecuritySchemes["apiKey"]; ok && scheme.In == "header" {
+		fmt.Fprintf(&b, "## Authentication\n\nSend your API key in the `%s` header with every request.\n\n", scheme.Name)
+	}
+
+	type endpoint struct {
+		method, path string
+		op           *Operation
+	}
+	groups := make(map[string][]endpoint)
+	paths := make([]string, 0, len(spec.Paths))
+	for path := range spec.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		item := spec.Paths[path]
+		for _, m := range []struct {
+			method string
+			op     *Operation
+		}{{"GET", item.Get}, {"POST", item.Post}, {"PUT", item.Put}, {"PATCH", item.Patch}, {"DELETE", item.Delete}} {
+			if m.op == nil {
+				continue
+			}
+			tag := "Other"
+			if len(m.op.Tags) > 0 {
+				tag = m.op.Tags[0]
+			}
+			groups[tag] = append(groups[tag], endpoint{m.method, path, m.op})
+		}
+	}
+
+	if len(groups) == 0 {
+		b.WriteString("No endpoints are documented yet.\n")
+		return []byte(b.String())
+	}
+
+	tags := make([]string, 0, len(groups))
+	for tag := range groups {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	descriptions := make(map[string]string, len(spec.Tags))
+	for _, t := range spec.Tags {
+		descriptions[t.Name] = t.Description
+	}
+
+	for _, tag := range tags {
+		fmt.Fprintf(&b, "## %s\n\n", tag)
+		if d := descriptions[tag]; d != "" {
+			fmt.Fprintf(&b, "%s\n\n", d)
+		}
+		for _, ep := range groups[tag] {
+			writeMarkdownEndpoint(&b, ep.method, ep.path, ep.op)
+		}
+	}
+
+	return []byte(b.String())
+}
+
+func writeMarkdownEndpoint(b *strings.Builder, method, path string, op *Operation) {
+	fmt.Fprintf(b, "### %s %s\n\n", method, path)
+	if op.Summary != "" && op.Summary != op.Description {
+		fmt.Fprintf(b, "**%s**\n\n", op.Summary)
+	}
+	if op.Description != "" {
+		fmt.Fprintf(b, "%s\n\n", op.Description)
+	}
+
+	if len(op.Parameters) > 0 {
+		b.WriteString("#### Parameters\n\n")
+		b.WriteString("| Name | In | Type | Required | Description |\n")
+		b.WriteString("|------|----|------|----------|-------------|\n")
+		for _, p := range op.Parameters {
+			typ := ""
+			if p.Schema != nil {
+				typ = p.Schema.Type
+			}
+			required := "No"
+			if p.Required {
+				required = "Yes"
+			}
+			fmt.Fprintf(b, "| `%s` | %s | %s | %s | %s |\n",
+				p.Name, p.In, typ, required, markdownCell(p.Description))
+		}
+		b.WriteString("\n")
+	}
+
+	if op.RequestBody != nil {
+		b.WriteString("#### Request Body\n\n")
+		if op.RequestBody.Description != "" {
+			fmt.Fprintf(b, "%s\n\n", op.RequestBody.Description)
+		}
+		if media, ok := op.RequestBody.Content["application/json"]; ok {
+			writeMarkdownExample(b, media.ExampleValue())
+		}
+	}
+
+	if len(op.Responses) > 0 {
+		statuses := make([]string, 0, len(op.Responses))
+		for status := range op.Responses {
+			statuses = append(statuses, status)
+		}
+		sort.Strings(statuses)
+
+		b.WriteString("#### Responses\n\n")
+		b.WriteString("| Status | Description |\n")
+		b.WriteString("|--------|-------------|\n")
+		for _, status := range statuses {
+			fmt.Fprintf(b, "| %s | %s |\n", status, markdownCell(op.Responses[status].Description))
+		}
+		b.WriteString("\n")
+
+		// Show the example of the first successful response that has one
+		for _, status := range statuses {
+			if !strings.HasPrefix(status, "2") {
+				continue
+			}
+			if media, ok := op.Responses[status].Content["application/json"]; ok && media.ExampleValue() != nil {
+				fmt.Fprintf(b, "Example response (%s):\n\n", status)
+				writeMarkdownExample(b, media.ExampleValue())
+				break
+			}
+		}
+	}
+}
+
+func writeMarkdownExample(b *strings.Builder, example any) {
+	if example == nil {
+		return
+	}
+	data, err := json.MarshalIndent(example, "", "  ")
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(b, "```json\n%s\n```\n\n", data)
+}
+
+// markdownCell makes text safe for a single table cell.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,76 @@
+package openapi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/artpar/apigate/domain/route"
+)
+
+func TestSpec_ToMarkdown(t *testing.T) {
+	routes := []route.Route{
+		{
+			ID: "1", Name: "Get order", Description: "Fetch one order", PathPattern: "/orders/{id}", MatchType: route.MatchExact,
+			Methods: []string{"GET"}, UpstreamID: "orders", Enabled: true,
+			ExampleResponse: `{"id": "o-1", "status": "shipped"}`,
+		},
+		{
+			ID: "2", Name: "Create order", PathPattern: "/orders", MatchType: route.MatchExact,
+			Methods: []string{"POST"}, UpstreamID: "orders", Enabled: true,
+			ExampleRequest: `{"sku": "ABC-1"}`,
+		},
+		{
+			ID: "3", Name: "Ping", PathPattern: "/ping", MatchType: route.MatchExact,
+			Methods: []string{"GET"}, UpstreamID: "status", Enabled: true,
+		},
+	}
+	upstreams := map[string]route.Upstream{
+		"orders": {ID: "orders", Name: "Orders", Enabled: true},
+		"status": {ID: "status", Name: "Status", Enabled: true},
+	}
+	gen := NewRouteGenerator(routes, upstreams)
+	gen.SetInfo(Info{Title: "Shop API", Version: "1.0.0"})
+	gen.AddServer("https://api.example.com", "Production")
+	spec := gen.Generate()
+	spec.Components.SecuritySchemes["apiKey"] = SecurityScheme{Type: "apiKey", In: "header", Name: "X-API-Key"}
+
+	md := string(spec.ToMarkdown())
+
+	for _, want := range []string{
+		"# Shop API\n",
+		"Base URL: `https://api.example.com`",
+		"`X-API-Key` header",
+		"## Orders\n",
+		"## Status\n",
+		"### GET /orders/{id}\n",
+		"### POST /orders\n",
+		"### GET /ping\n",
+		"| `id` | path | string | Yes | Path parameter: id |",
+		"```json\n{\n  \"sku\": \"ABC-1\"\n}\n```",
+		"Example response (200):\n\n```json\n{\n  \"id\": \"o-1\",\n  \"status\": \"shipped\"\n}\n```",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown missing %q", want)
+		}
+	}
+
+	// Endpoints appear under their tag, and tags in order
+	if strings.Index(md, "## Orders") > strings.Index(md, "### POST /orders") ||
+		strings.Index(md, "### POST /orders") > strings.Index(md, "## Status") {
+		t.Errorf("endpoints not grouped by tag:\n%s", md)
+	}
+}
+
+func TestSpec_ToMarkdown_Empty(t *testing.T) {
+	spec := &Spec{Info: Info{Title: "Empty API"}, Paths: map[string]PathItem{}}
+	md := string(spec.ToMarkdown())
+	if !strings.HasPrefix(md, "# Empty API\n") || !strings.Contains(md, "No endpoints") {
+		t.Errorf("markdown = %q", md)
+	}
+}
+
+func TestMarkdownCell(t *testing.T) {
+	if got := markdownCell("a | b\nc"); got != `a \| b c` {
+		t.Errorf("markdownCell = %q", got)
+	}
+}
@@ -0,0 +1,231 @@
+package openapi
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// PostmanSchemaURL identifies the Postman collection format produced by ToPostman.
+const PostmanSchemaURL = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+// PostmanCollection is a Postman v2.1 collection.
+type PostmanCollection struct {
+	Info     PostmanInfo       `json:"info"`
+	Auth     *PostmanAuth      `json:"auth,omitempty"`
+	Variable []PostmanVariable `json:"variable,omitempty"`
+	Item     []PostmanItem     `json:"item"`
+}
+
+// PostmanInfo describes a collection.
+type PostmanInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Schema      string `json:"schema"`
+}
+
+// PostmanAuth configures how requests authenticate.
+type PostmanAuth struct {
+	Type   string            `json:"type"` // "apikey"
+	APIKey []PostmanVariable `json:"apikey,omitempty"`
+}
+
+// PostmanVariable is a key/value pair: a collection variable, header,
+// query parameter or auth setting.
+type PostmanVariable struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+	Disabled    bool   `json:"disabled,omitempty"`
+}
+
+// PostmanItem is either a folder (with Item) or a request.
+type PostmanItem struct {
+	Name    string          `json:"name"`
+	Item    []PostmanItem   `json:"item,omitempty"`
+	Request *PostmanRequest `json:"request,omitempty"`
+}
+
+// PostmanRequest is a single request in a collection.
+type PostmanRequest struct {
+	Method      string            `json:"method"`
+	Header      []PostmanVariable `json:"header"`
+	URL         PostmanURL        `json:"url"`
+	Body        *PostmanBody      `json:"body,omitempty"`
+	Description string            `json:"description,omitempty"`
+}
+
+// PostmanURL is a request URL, both raw and split into parts.
+type PostmanURL struct {
+	Raw      string            `json:"raw"`
+	Host     []string          `json:"host"`
+	Path     []string          `json:"path"`
+	Query    []PostmanVariable `json:"query,omitempty"`
+	Variable []PostmanVariable `json:"variable,omitempty"`
+}
+
+// PostmanBody is a raw request body.
+type PostmanBody struct {
+	Mode    string             `json:"mode"`
+	Raw     string             `json:"raw"`
+	Options *PostmanBodyOption `json:"options,omitempty"`
+}
+
+// PostmanBodyOption tells Postman how to highlight a raw body.
+type PostmanBodyOption struct {
+	Raw struct {
+		Language string `json:"language"`
+	} `json:"raw"`
+}
+
+// ToPostman converts the spec into a Postman v2.1 collection. Requests are
+// grouped into one folder per tag and use the {{baseUrl}} and {{apiKey}}
+// collection variables; the API key is sent in the X-API-Key header.
+func (spec *Spec) ToPostman() *PostmanCollection {
+	baseURL := ""
+	if len(spec.Servers) > 0 {
+		baseURL = strings.TrimSuffix(spec.Servers[0].URL, "/")
+	}
+
+	c := &PostmanCollection{
+		Info: PostmanInfo{
+			Name:        spec.Info.Title,
+			Description: spec.Info.Description,
+			Schema:      PostmanSchemaURL,
+		},
+		Auth: &PostmanAuth{
+			Type: "apikey",
+			APIKey: []PostmanVariable{
+				{Key: "key", Value: "X-API-Key", Type: "string"},
+				{Key: "value", Value: "{{apiKey}}", Type: "string"},
+				{Key: "in", Value: "header", Type: "string"},
+			},
+		},
+		Variable: []PostmanVariable{
+			{Key: "baseUrl", Value: baseURL, Type: "string"},
+			{Key: "apiKey", Value: "", Type: "string", Description: "Your API key"},
+		},
+		Item: []PostmanItem{},
+	}
+
+	paths := make([]string, 0, len(spec.Paths))
+	for path := range spec.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	folders := make(map[string]int) // tag -> index in c.Item
+	for _, path := range paths {
+		item := spec.Paths[path]
+		for _, m := range []struct {
+			method string
+			op     *Operation
+		}{{"GET", item.Get}, {"POST", item.Post}, {"PUT", item.Put}, {"PATCH", item.Patch}, {"DELETE", item.Delete}} {
+			if m.op == nil {
+				continue
+			}
+			req := PostmanItem{Name: postmanName(m.method, path, m.op), Request: postmanRequest(m.method, path, m.op)}
+			if len(m.op.Tags) == 0 {
+				c.Item = append(c.Item, req)
+				continue
+			}
+			tag := m.op.Tags[0]
+			i, ok := folders[tag]
+			if !ok {
+				i = len(c.Item)
+				folders[tag] = i
+				c.Item = append(c.Item, PostmanItem{Name: tag})
+			}
+			c.Item[i].Item = append(c.Item[i].Item, req)
+		}
+	}
+
+	return c
+}
+
+// ToPostmanJSON converts the spec to a Postman v2.1 collection in JSON.
+func (spec *Spec) ToPostmanJSON() ([]byte, error) {
+	return json.MarshalIndent(spec.ToPostman(), "", "  ")
+}
+
+func postmanName(method, path string, op *Operation) string {
+	if op.Summary != "" {
+		return op.Summary
+	}
+	return method + " " + path
+}
+
+func postmanRequest(method, path string, op *Operation) *PostmanRequest {
+	req := &PostmanRequest{
+		Method:      method,
+		Header:      []PostmanVariable{},
+		Description: op.Description,
+	}
+
+	// OpenAPI {param} becomes Postman :param
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			segments[i] = ":" + seg[1:len(seg)-1]
+		}
+	}
+	if len(segments) == 1 && segments[0] == "" {
+		segments = []string{}
+	}
+	req.URL = PostmanURL{
+		Raw:  "{{baseUrl}}/" + strings.Join(segments, "/"),
+		Host: []string{"{{baseUrl}}"},
+		Path: segments,
+	}
+
+	var query []string
+	for _, p := range op.Parameters {
+		value := ""
+		if p.Schema != nil && p.Schema.Default != nil {
+			value = postmanValue(p.Schema.Default)
+		}
+		switch p.In {
+		case "path":
+			req.URL.Variable = append(req.URL.Variable, PostmanVariable{Key: p.Name, Value: value, Description: p.Description})
+		case "query":
+			req.URL.Query = append(req.URL.Query, PostmanVariable{Key: p.Name, Value: value, Description: p.Description, Disabled: !p.Required})
+			if p.Required {
+				query = append(query, p.Name+"="+value)
+			}
+		case "header":
+			req.Header = append(req.Header, PostmanVariable{Key: p.Name, Value: value, Description: p.Description, Disabled: !p.Required})
+		}
+	}
+	if len(query) > 0 {
+		req.URL.Raw += "?" + strings.Join(query, "&")
+	}
+
+	if op.RequestBody != nil {
+		if media, ok := op.RequestBody.Content["application/json"]; ok {
+			req.Header = append(req.Header, PostmanVariable{Key: "Content-Type", Value: "application/json"})
+			raw := "{}"
+			if example := media.ExampleValue(); example != nil {
+				if data, err := json.MarshalIndent(example, "", "  "); err == nil {
+					raw = string(data)
+				}
+			}
+			body := &PostmanBody{Mode: "raw", Raw: raw, Options: &PostmanBodyOption{}}
+			body.Options.Raw.Language = "json"
+			req.Body = body
+		}
+	}
+
+	return req
+}
+
+func postmanValue(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
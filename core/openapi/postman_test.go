@@ -0,0 +1,98 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/artpar/apigate/domain/route"
+)
+
+func TestSpec_ToPostman(t *testing.T) {
+	routes := []route.Route{
+		{
+			ID: "1", Name: "Get order", PathPattern: "/orders/{id}", MatchType: route.MatchExact,
+			Methods: []string{"GET"}, UpstreamID: "orders", Enabled: true,
+		},
+		{
+			ID: "2", Name: "Create order", PathPattern: "/orders", MatchType: route.MatchExact,
+			Methods: []string{"POST"}, UpstreamID: "orders", Enabled: true,
+			ExampleRequest: `{"sku": "ABC-1"}`,
+			Headers:        []route.HeaderMatch{{Name: "X-Tenant", Required: true}},
+		},
+	}
+	upstreams := map[string]route.Upstream{"orders": {ID: "orders", Name: "Orders", Enabled: true}}
+	gen := NewRouteGenerator(routes, upstreams)
+	gen.SetInfo(Info{Title: "Shop API", Version: "1.0.0"})
+	gen.AddServer("https://api.example.com/", "Production")
+
+	data, err := gen.Generate().ToPostmanJSON()
+	if err != nil {
+		t.Fatalf("ToPostmanJSON: %v", err)
+	}
+	var c PostmanCollection
+	if err := json.Unmarshal(data, &c); err != nil {
+		t.Fatalf("collection is not valid JSON: %v", err)
+	}
+
+	if c.Info.Name != "Shop API" || c.Info.Schema != PostmanSchemaURL {
+		t.Errorf("info = %+v", c.Info)
+	}
+	if c.Auth == nil || c.Auth.Type != "apikey" {
+		t.Fatalf("auth = %+v, want apikey", c.Auth)
+	}
+	auth := map[string]string{}
+	for _, v := range c.Auth.APIKey {
+		auth[v.Key] = v.Value
+	}
+	if auth["key"] != "X-API-Key" || auth["value"] != "{{apiKey}}" || auth["in"] != "header" {
+		t.Errorf("apikey auth = %v", auth)
+	}
+	vars := map[string]string{}
+	for _, v := range c.Variable {
+		vars[v.Key] = v.Value
+	}
+	if vars["baseUrl"] != "https://api.example.com" {
+		t.Errorf("baseUrl = %q", vars["baseUrl"])
+	}
+	if _, ok := vars["apiKey"]; !ok {
+		t.Error("expected an apiKey variable")
+	}
+
+	if len(c.Item) != 1 || c.Item[0].Name != "Orders" {
+		t.Fatalf("items = %+v, want one Orders folder", c.Item)
+	}
+	requests := map[string]*PostmanRequest{}
+	for _, item := range c.Item[0].Item {
+		requests[item.Name] = item.Request
+	}
+	if len(requests) != 2 {
+		t.Fatalf("requests = %v, want one per operation", requests)
+	}
+
+	get := requests["Get order"]
+	if get == nil || get.Method != "GET" || get.URL.Raw != "{{baseUrl}}/orders/:id" {
+		t.Fatalf("get = %+v", get)
+	}
+	if len(get.URL.Variable) != 1 || get.URL.Variable[0].Key != "id" {
+		t.Errorf("path variables = %+v", get.URL.Variable)
+	}
+
+	create := requests["Create order"]
+	if create == nil || create.Method != "POST" || create.URL.Raw != "{{baseUrl}}/orders" {
+		t.Fatalf("create = %+v", create)
+	}
+	if create.Body == nil || create.Body.Mode != "raw" || create.Body.Options.Raw.Language != "json" {
+		t.Fatalf("body = %+v", create.Body)
+	}
+	var body map[string]any
+	if err := json.Unmarshal([]byte(create.Body.Raw), &body); err != nil || body["sku"] != "ABC-1" {
+		t.Errorf("body raw = %q", create.Body.Raw)
+	}
+	headers := map[string]bool{}
+	for _, h := range create.Header {
+		headers[h.Key] = !h.Disabled
+	}
+	if !headers["X-Tenant"] || !headers["Content-Type"] {
+		t.Errorf("headers = %+v", create.Header)
+	}
+}
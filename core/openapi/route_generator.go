@@ -85,6 +85,8 @@ func (g *RouteGenerator) Generate() *Spec {
 		g.generateRoutePath(spec, r)
 	}
 
+	addExamples(spec)
+
 	return spec
 }
 
@@ -238,11 +240,18 @@ func (g *RouteGenerator) generateRoutePath(spec *Spec, r route.Route) {
 			mediaType := MediaType{
 				Schema: &Schema{Type: "object"},
 			}
+			// Publish the route's body schema when it has one
+			if r.RequestSchema != "" {
+				if s, err := ParseSchema(r.RequestSchema); err == nil {
+					mediaType.Schema = s
+				}
+			}
 			// Add example request if available
 			if r.ExampleRequest != "" {
 				mediaType.Schema.Example = parseJSONExample(r.ExampleRequest)
 			}
 			op.RequestBody = &RequestBody{
+				Required:    r.RequestSchema != "",
 				Description: "Request body to forward to upstream",
 				Content: map[string]MediaType{
 					"application/json": mediaType,
@@ -529,6 +529,34 @@ func TestRouteGenerator_RequestBody(t *testing.T) {
 	}
 }
 
+func TestRouteGenerator_RequestSchema(t *testing.T) {
+	routes := []route.Route{
+		{
+			ID:            "1",
+			Name:          "create-order",
+			PathPattern:   "/orders",
+			MatchType:     route.MatchExact,
+			Methods:       []string{"POST"},
+			RequestSchema: `{"type": "object", "required": ["sku"], "properties": {"sku": {"type": "string"}}}`,
+			Enabled:       true,
+		},
+	}
+
+	spec := NewRouteGenerator(routes, nil).Generate()
+
+	body := spec.Paths["/orders"].Post.RequestBody
+	if body == nil || !body.Required {
+		t.Fatalf("request body = %+v, want required body", body)
+	}
+	s := body.Content["application/json"].Schema
+	if s.Properties["sku"] == nil || s.Properties["sku"].Type != "string" {
+		t.Errorf("schema properties = %v, want sku string", s.Properties)
+	}
+	if len(s.Required) != 1 || s.Required[0] != "sku" {
+		t.Errorf("schema required = %v, want [sku]", s.Required)
+	}
+}
+
 func TestRouteGenerator_RoutePrioritySorting(t *testing.T) {
 	routes := []route.Route{
 		{ID: "1", Name: "low-priority", PathPattern: "/api/a", Priority: 1, Enabled: true, Methods: []string{"GET"}},
@@ -310,6 +310,7 @@ func (s *Service) computeDataHash(routes []route.Route, upstreams map[string]rou
 		hasher.Write([]byte(r.ID))
 		hasher.Write([]byte(r.PathPattern))
 		hasher.Write([]byte(r.Description))
+		hasher.Write([]byte(r.RequestSchema))
 		if r.Enabled {
 			hasher.Write([]byte("1"))
 		} else {
@@ -0,0 +1,269 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValidationError describes one part of a value that does not satisfy a schema.
+type ValidationError struct {
+	Pointer string // JSON pointer to the offending value, e.g. "/items/0/qty"; "" for the whole value
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	if e.Pointer == "" {
+		return e.Message
+	}
+	return e.Pointer + ": " + e.Message
+}
+
+// ParseSchema parses a JSON Schema document for validation with Validate.
+// Only the keywords modelled by Schema are supported; $ref is not resolved.
+// Patterns are compiled up front, so an invalid pattern is an error here.
+func ParseSchema(raw string) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal([]byte(raw), &s); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	if err := s.compile(""); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// compile checks the schema's keywords and compiles its patterns.
+func (s *Schema) compile(at string) error {
+	switch s.Type {
+	case "", "object", "array", "string", "integer", "number", "boolean", "null":
+	default:
+		return fmt.Errorf("invalid schema%s: unknown type %q", schemaPath(at), s.Type)
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid schema%s: pattern: %w", schemaPath(at), err)
+		}
+		s.pattern = re
+	}
+	for name, prop := range s.Properties {
+		if prop != nil {
+			if err := prop.compile(at + "/properties/" + name); err != nil {
+				return err
+			}
+		}
+	}
+	if s.Items != nil {
+		if err := s.Items.compile(at + "/items"); err != nil {
+			return err
+		}
+	}
+	for i, sub := range s.AllOf {
+		if err := sub.compile(at + "/allOf/" + strconv.Itoa(i)); err != nil {
+			return err
+		}
+	}
+	for i, sub := range s.OneOf {
+		if err := sub.compile(at + "/oneOf/" + strconv.Itoa(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func schemaPath(at string) string {
+	if at == "" {
+		return ""
+	}
+	return " at " + at
+}
+
+// Validate checks a decoded JSON value (as produced by encoding/json into
+// an any) against the schema and returns every violation found, ordered by
+// pointer. It returns nil if the value is valid.
+func (s *Schema) Validate(value any) []ValidationError {
+	var errs []ValidationError
+	s.validate(value, "", &errs)
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Pointer < errs[j].Pointer })
+	return errs
+}
+
+func (s *Schema) validate(value any, ptr string, errs *[]ValidationError) {
+	if s == nil {
+		return
+	}
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, ValidationError{Pointer: ptr, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.Type != "" && !hasType(value, s.Type) {
+		fail("must be %s, got %s", withArticle(s.Type), withArticle(jsonType(value)))
+		return
+	}
+
+	for _, sub := range s.AllOf {
+		sub.validate(value, ptr, errs)
+	}
+	if len(s.OneOf) > 0 {
+		matched := 0
+		for _, sub := range s.OneOf {
+			if len(sub.Validate(value)) == 0 {
+				matched++
+			}
+		}
+		if matched != 1 {
+			fail("must match exactly one allowed schema, matched %d", matched)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*errs = append(*errs, ValidationError{Pointer: ptr + "/" + escapePointer(name), Message: "is required"})
+			}
+		}
+		for name, prop := range s.Properties {
+			if field, ok := v[name]; ok {
+				prop.validate(field, ptr+"/"+escapePointer(name), errs)
+			}
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(item, ptr+"/"+strconv.Itoa(i), errs)
+			}
+		}
+	case string:
+		length := len([]rune(v))
+		if s.MinLength != nil && length < *s.MinLength {
+			fail("must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			fail("must be at most %d characters", *s.MaxLength)
+		}
+		if s.Pattern != "" {
+			re := s.pattern
+			if re == nil {
+				re, _ = regexp.Compile(s.Pattern)
+			}
+			if re != nil && !re.MatchString(v) {
+				fail("must match pattern %s", s.Pattern)
+			}
+		}
+		if len(s.Enum) > 0 && !containsString(s.Enum, v) {
+			fail("must be one of %s", strings.Join(s.Enum, ", "))
+		}
+		if msg := checkFormat(s.Format, v); msg != "" {
+			fail("%s", msg)
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			fail("must be at least %s", formatNumber(*s.Minimum))
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			fail("must be at most %s", formatNumber(*s.Maximum))
+		}
+	}
+}
+
+// hasType reports whether a decoded JSON value has the given schema type.
+func hasType(value any, typ string) bool {
+	switch typ {
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	default:
+		return jsonType(value) == typ
+	}
+}
+
+// jsonType names the JSON type of a decoded value.
+func jsonType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func withArticle(typ string) string {
+	switch typ {
+	case "null":
+		return "null"
+	case "object", "array", "integer":
+		return "an " + typ
+	default:
+		return "a " + typ
+	}
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// checkFormat returns a message if v does not have the given string format.
+// Unknown formats are not checked.
+func checkFormat(format, v string) string {
+	switch format {
+	case "email":
+		if addr, err := mail.ParseAddress(v); err != nil || addr.Address != v {
+			return "must be a valid email address"
+		}
+	case "uuid":
+		if !uuidPattern.MatchString(v) {
+			return "must be a valid UUID"
+		}
+	case "uri":
+		if u, err := url.Parse(v); err != nil || u.Scheme == "" {
+			return "must be a valid URI"
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, v); err != nil {
+			return "must be an RFC 3339 date-time"
+		}
+	case "date":
+		if _, err := time.Parse(time.DateOnly, v); err != nil {
+			return "must be a date (YYYY-MM-DD)"
+		}
+	}
+	return ""
+}
+
+// escapePointer escapes a property name for use in a JSON pointer.
+func escapePointer(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
+
+func containsString(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
@@ -0,0 +1,113 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const orderSchema = `{
+	"type": "object",
+	"required": ["sku", "quantity"],
+	"properties": {
+		"sku": {"type": "string", "pattern": "^[A-Z]{3}-[0-9]+$"},
+		"quantity": {"type": "integer", "minimum": 1, "maximum": 100},
+		"email": {"type": "string", "format": "email"},
+		"priority": {"type": "string", "enum": ["low", "high"]},
+		"tags": {"type": "array", "items": {"type": "string", "maxLength": 5}}
+	}
+}`
+
+func decode(t *testing.T, body string) any {
+	t.Helper()
+	var v any
+	if err := json.Unmarshal([]byte(body), &v); err != nil {
+		t.Fatalf("decode %s: %v", body, err)
+	}
+	return v
+}
+
+func TestSchema_Validate(t *testing.T) {
+	schema, err := ParseSchema(orderSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want []ValidationError
+	}{
+		{
+			name: "valid body",
+			body: `{"sku": "ABC-12", "quantity": 3, "email": "a@example.com", "priority": "high", "tags": ["x"]}`,
+		},
+		{
+			name: "missing required field",
+			body: `{"sku": "ABC-12"}`,
+			want: []ValidationError{{Pointer: "/quantity", Message: "is required"}},
+		},
+		{
+			name: "wrong type",
+			body: `{"sku": "ABC-12", "quantity": "three"}`,
+			want: []ValidationError{{Pointer: "/quantity", Message: "must be an integer, got a string"}},
+		},
+		{
+			name: "fraction is not an integer",
+			body: `{"sku": "ABC-12", "quantity": 1.5}`,
+			want: []ValidationError{{Pointer: "/quantity", Message: "must be an integer, got a number"}},
+		},
+		{
+			name: "body is not an object",
+			body: `[1, 2]`,
+			want: []ValidationError{{Pointer: "", Message: "must be an object, got an array"}},
+		},
+		{
+			name: "constraints",
+			body: `{"sku": "abc", "quantity": 0, "email": "nope", "priority": "urgent", "tags": ["ok", "too-long"]}`,
+			want: []ValidationError{
+				{Pointer: "/email", Message: "must be a valid email address"},
+				{Pointer: "/priority", Message: "must be one of low, high"},
+				{Pointer: "/quantity", Message: "must be at least 1"},
+				{Pointer: "/sku", Message: "must match pattern ^[A-Z]{3}-[0-9]+$"},
+				{Pointer: "/tags/1", Message: "must be at most 5 characters"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := schema.Validate(decode(t, tt.body))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchema_ValidateOneOf(t *testing.T) {
+	schema, err := ParseSchema(`{"oneOf": [{"type": "string"}, {"type": "integer"}]}`)
+	if err != nil {
+		t.Fatalf("ParseSchema: %v", err)
+	}
+	if errs := schema.Validate(decode(t, `"a"`)); errs != nil {
+		t.Errorf("string: %v", errs)
+	}
+	if errs := schema.Validate(decode(t, `true`)); len(errs) != 1 {
+		t.Errorf("boolean: got %v, want one error", errs)
+	}
+}
+
+func TestParseSchema_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"not json":          `{"type":`,
+		"unknown type":      `{"type": "decimal"}`,
+		"bad nested regex":  `{"properties": {"a": {"pattern": "("}}}`,
+		"bad items keyword": `{"items": {"type": 5}}`,
+	}
+	for name, raw := range tests {
+		if _, err := ParseSchema(raw); err == nil || !strings.HasPrefix(err.Error(), "invalid schema") {
+			t.Errorf("%s: err = %v, want invalid schema error", name, err)
+		}
+	}
+}
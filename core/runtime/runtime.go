@@ -17,6 +17,7 @@ import (
 	"github.com/artpar/apigate/core/exporter"
 	"github.com/artpar/apigate/core/registry"
 	"github.com/artpar/apigate/core/schema"
+	"github.com/artpar/apigate/core/storage"
 	"github.com/artpar/apigate/core/validation"
 	"github.com/rs/zerolog"
 	"golang.org/x/crypto/bcrypt"
@@ -109,9 +110,16 @@ type ListOptions struct {
 	// Offset is the number of records to skip.
 	Offset int
 
-	// Filters are field-value pairs to filter by.
+	// Cursor resumes after a previous page's NextCursor. When set, Offset is ignored.
+	Cursor string
+
+	// Filters are field-value pairs to filter by. Values of type storage.Filter
+	// or []storage.Filter compare with an operator (gt, in, like, ...).
 	Filters map[string]any
 
+	// Search is a full-text query over the module's searchable fields.
+	Search string
+
 	// OrderBy is the field to sort by.
 	OrderBy string
 
@@ -422,6 +430,9 @@ type ActionResult struct {
 	// Count is the total count for list actions.
 	Count int64
 
+	// NextCursor resumes a list action after this page; empty on the last page.
+	NextCursor string
+
 	// Meta carries extra data from hooks (e.g., raw API key shown only once).
 	Meta map[string]any
 }
@@ -440,6 +451,12 @@ func (r *Runtime) executeList(ctx context.Context, mod convention.Derived, act *
 	if offset, ok := input.Data["offset"].(int); ok {
 		opts.Offset = offset
 	}
+	if cursor, ok := input.Data["cursor"].(string); ok {
+		opts.Cursor = cursor
+	}
+	if q, ok := input.Data["q"].(string); ok {
+		opts.Search = q
+	}
 	if orderBy, ok := input.Data["order_by"].(string); ok {
 		opts.OrderBy = orderBy
 	}
@@ -454,7 +471,7 @@ func (r *Runtime) executeList(ctx context.Context, mod convention.Derived, act *
 		// Copy only field values, excluding pagination params
 		opts.Filters = make(map[string]any)
 		for k, v := range input.Data {
-			if k != "limit" && k != "offset" && k != "order_by" && k != "order_desc" && k != "filters" {
+			if k != "limit" && k != "offset" && k != "cursor" && k != "q" && k != "order_by" && k != "order_desc" && k != "filters" {
 				opts.Filters[k] = v
 			}
 		}
@@ -465,7 +482,12 @@ func (r *Runtime) executeList(ctx context.Context, mod convention.Derived, act *
 		return ActionResult{}, err
 	}
 
-	return ActionResult{List: list, Count: count}, nil
+	next := storage.NextCursor(list, storage.ListOptions{
+		Limit:     opts.Limit,
+		OrderBy:   opts.OrderBy,
+		OrderDesc: opts.OrderDesc,
+	})
+	return ActionResult{List: list, Count: count, NextCursor: next}, nil
 }
 
 // executeGet handles get actions.
@@ -37,6 +37,10 @@ type Field struct {
 	// Index creates a database index on this field.
 	Index bool `yaml:"index,omitempty"`
 
+	// Searchable includes this field in full-text search (the list "q" param).
+	// Only text types (string, email, url, enum) can be searchable.
+	Searchable bool `yaml:"searchable,omitempty"`
+
 	// Constraints defines validation rules for this field.
 	Constraints []Constraint `yaml:"constraints,omitempty"`
 
@@ -85,6 +89,15 @@ func (f Field) IsInternal() bool {
 	return f.Internal || f.Type == FieldTypeSecret
 }
 
+// IsText returns whether the field holds free text that can be searched.
+func (f Field) IsText() bool {
+	switch f.Type {
+	case FieldTypeString, FieldTypeEmail, FieldTypeURL, FieldTypeEnum:
+		return true
+	}
+	return false
+}
+
 // SQLType returns the SQLite column type for this field.
 func (f Field) SQLType() string {
 	switch f.Type {
@@ -39,6 +39,7 @@ type FieldSchema struct {
 	Lookup      bool              `json:"lookup,omitempty"`
 	Filterable  bool              `json:"filterable,omitempty"`  // can be used in query filters
 	Sortable    bool              `json:"sortable,omitempty"`    // can be used in order_by
+	Searchable  bool              `json:"searchable,omitempty"`  // matched by the q search param
 	Values      []string          `json:"values,omitempty"`      // enum options
 	Ref         string            `json:"ref,omitempty"`         // foreign key target module
 	Default     any               `json:"default,omitempty"`
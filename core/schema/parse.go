@@ -165,6 +165,11 @@ func validateField(name string, field Field) error {
 		return fmt.Errorf("field %q: ref type requires 'to' target", name)
 	}
 
+	// Only text fields can be searched
+	if field.Searchable && !field.IsText() {
+		return fmt.Errorf("field %q: searchable requires a string type, got %q", name, field.Type)
+	}
+
 	// Default must match type (basic validation)
 	if field.Default != nil {
 		if err := validateDefault(name, field); err != nil {
@@ -487,6 +487,43 @@ schema:
 	}
 }
 
+func TestValidateSearchable(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr bool
+	}{
+		{
+			name: "searchable string",
+			yaml: `
+module: test
+schema:
+  title: { type: string, searchable: true }
+  contact: { type: email, searchable: true }
+`,
+			wantErr: false,
+		},
+		{
+			name: "searchable int",
+			yaml: `
+module: test
+schema:
+  count: { type: int, searchable: true }
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.yaml))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestIsValidIdentifier(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidCursor is returned by List when a cursor cannot be decoded, was
+// issued for a different ordering, or points at a record that no longer exists.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursor identifies the last record of a page. Only the record ID is carried;
+// the store reads that record's sort value when resuming, so the comparison
+// always uses the stored representation.
+type cursor struct {
+	OrderBy   string `json:"o"`
+	OrderDesc bool   `json:"d,omitempty"`
+	ID        string `json:"id"`
+}
+
+// NextCursor returns the cursor for the page after records, or "" when records
+// is the last page. opts must be the options the page was listed with.
+func NextCursor(records []map[string]any, opts ListOptions) string {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	if len(records) < limit {
+		return ""
+	}
+	id, ok := records[len(records)-1]["id"]
+	if !ok || id == nil {
+		return ""
+	}
+	b, _ := json.Marshal(cursor{
+		OrderBy:   orderByName(opts.OrderBy),
+		OrderDesc: opts.OrderDesc,
+		ID:        fmt.Sprint(id),
+	})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor parses a cursor and checks it was issued for the same ordering.
+func decodeCursor(s string, opts ListOptions) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == "" {
+		return c, ErrInvalidCursor
+	}
+	if c.OrderBy != orderByName(opts.OrderBy) || c.OrderDesc != opts.OrderDesc {
+		return c, fmt.Errorf("%w: issued for a different ordering", ErrInvalidCursor)
+	}
+	return c, nil
+}
+
+// orderByName returns the requested sort field, defaulting to created_at.
+func orderByName(orderBy string) string {
+	if orderBy == "" {
+		return "created_at"
+	}
+	return orderBy
+}
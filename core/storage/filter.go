@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/artpar/apigate/core/convention"
+)
+
+// ErrInvalidFilter is returned by List when a filter names an unknown field or
+// operator.
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// FilterOp is a comparison operator for list filters.
+type FilterOp string
+
+// Filter operators.
+const (
+	OpEq   FilterOp = "eq"
+	OpNe   FilterOp = "ne"
+	OpGt   FilterOp = "gt"
+	OpGte  FilterOp = "gte"
+	OpLt   FilterOp = "lt"
+	OpLte  FilterOp = "lte"
+	OpLike FilterOp = "like"
+	OpIn   FilterOp = "in"
+)
+
+// filterOpSQL maps each operator to its SQL form.
+var filterOpSQL = map[FilterOp]string{
+	OpEq:   "=",
+	OpNe:   "!=",
+	OpGt:   ">",
+	OpGte:  ">=",
+	OpLt:   "<",
+	OpLte:  "<=",
+	OpLike: "LIKE",
+	OpIn:   "IN",
+}
+
+// Filter compares a field with Value using Op. In ListOptions.Filters, a value
+// of type Filter or []Filter applies the operator(s); any other value is an
+// equality match.
+type Filter struct {
+	Op    FilterOp
+	Value any
+}
+
+// ParseFilterOp returns the operator named s.
+func ParseFilterOp(s string) (FilterOp, bool) {
+	op := FilterOp(strings.ToLower(s))
+	_, ok := filterOpSQL[op]
+	return op, ok
+}
+
+// buildFilterConditions translates filters into parameterized SQL conditions,
+// converting values with toDB. Field names are checked against the module
+// schema and operators against the known set, so only values ever reach the
+// query, and only as bind arguments. Internal fields only support equality:
+// range and pattern comparisons would reveal a secret a character at a time.
+func buildFilterConditions(mod convention.Derived, filters map[string]any, toDB func(any, convention.DerivedField) any) ([]string, []any, error) {
+	var conditions []string
+	var args []any
+
+	for name, v := range filters {
+		var field *convention.DerivedField
+		for i := range mod.Fields {
+			if mod.Fields[i].Name == name {
+				field = &mod.Fields[i]
+				break
+			}
+		}
+		if field == nil {
+			return nil, nil, fmt.Errorf("%w: unknown field %q", ErrInvalidFilter, name)
+		}
+
+		var list []Filter
+		switch f := v.(type) {
+		case Filter:
+			list = []Filter{f}
+		case []Filter:
+			list = f
+		default:
+			list = []Filter{{Op: OpEq, Value: v}}
+		}
+
+		for _, f := range list {
+			op, ok := filterOpSQL[f.Op]
+			if !ok {
+				return nil, nil, fmt.Errorf("%w: unknown operator %q for %s", ErrInvalidFilter, f.Op, name)
+			}
+			if field.Internal && f.Op != OpEq {
+				return nil, nil, fmt.Errorf("%w: operator %q not allowed on %s", ErrInvalidFilter, f.Op, name)
+			}
+			if f.Op == OpLike {
+				// Patterns match the text form, whatever the column type
+				conditions = append(conditions, fmt.Sprintf("CAST(%s AS TEXT) LIKE ?", field.Name))
+				args = append(args, fmt.Sprint(f.Value))
+				continue
+			}
+			if f.Op != OpIn {
+				conditions = append(conditions, fmt.Sprintf("%s %s ?", field.Name, op))
+				args = append(args, toDB(f.Value, *field))
+				continue
+			}
+
+			values := filterValues(f.Value)
+			if len(values) == 0 {
+				// IN () matches nothing
+				conditions = append(conditions, "1 = 0")
+				continue
+			}
+			placeholders := make([]string, len(values))
+			for i, val := range values {
+				placeholders[i] = "?"
+				args = append(args, toDB(val, *field))
+			}
+			conditions = append(conditions, fmt.Sprintf("%s IN (%s)", field.Name, strings.Join(placeholders, ", ")))
+		}
+	}
+
+	return conditions, args, nil
+}
+
+// filterValues returns the members of an "in" filter: a slice, or a
+// comma-separated string.
+func filterValues(v any) []any {
+	switch vals := v.(type) {
+	case []any:
+		return vals
+	case []string:
+		out := make([]any, len(vals))
+		for i, s := range vals {
+			out[i] = s
+		}
+		return out
+	case string:
+		var out []any
+		for _, s := range strings.Split(vals, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	case nil:
+		return nil
+	default:
+		return []any{v}
+	}
+}
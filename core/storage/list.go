@@ -0,0 +1,215 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/artpar/apigate/core/convention"
+)
+
+// dialect captures where a backend's SQL differs from SQLite's for the
+// queries shared between stores. Shared queries are written with ?
+// placeholders.
+type dialect struct {
+	// numbered rewrites ? placeholders as $1, $2, ... (Postgres).
+	numbered bool
+
+	// nullsFirst spells out NULL ordering, which SQLite does implicitly
+	// (NULLs first ascending, last descending) and Postgres does the other
+	// way round.
+	nullsFirst bool
+
+	// toDB and fromDB convert field values to and from the database.
+	toDB   func(any, convention.DerivedField) any
+	fromDB func(any, convention.DerivedField) any
+
+	// search builds the condition for a full-text query.
+	search func(mod convention.Derived, query string) (string, []any, error)
+}
+
+// bind rewrites a query's placeholders for the backend.
+func (d dialect) bind(query string) string {
+	if !d.numbered {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// orderClause returns the ORDER BY term for a column.
+func (d dialect) orderClause(column, dir string) string {
+	if !d.nullsFirst {
+		return column + " " + dir
+	}
+	if dir == "DESC" {
+		return column + " DESC NULLS LAST"
+	}
+	return column + " ASC NULLS FIRST"
+}
+
+// listRecords runs a List query for a module.
+func listRecords(ctx context.Context, db *sql.DB, mod convention.Derived, opts ListOptions, d dialect) ([]map[string]any, int64, error) {
+	// Build column list
+	var columns []string
+	for _, f := range mod.Fields {
+		columns = append(columns, f.Name)
+	}
+
+	// Build query
+	var whereClause string
+	var args []any
+
+	if len(opts.Filters) > 0 {
+		conditions, filterArgs, err := buildFilterConditions(mod, opts.Filters, d.toDB)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(conditions) > 0 {
+			whereClause = " WHERE " + strings.Join(conditions, " AND ")
+			args = append(args, filterArgs...)
+		}
+	}
+
+	if opts.Search != "" {
+		condition, searchArgs, err := d.search(mod, opts.Search)
+		if err != nil {
+			return nil, 0, err
+		}
+		if condition != "" {
+			if whereClause == "" {
+				whereClause = " WHERE " + condition
+			} else {
+				whereClause += " AND " + condition
+			}
+			args = append(args, searchArgs...)
+		}
+	}
+
+	// Get count
+	countSQL := fmt.Sprintf("SELECT COUNT(*) FROM %s%s", mod.Table, whereClause)
+	var count int64
+	if err := db.QueryRowContext(ctx, d.bind(countSQL), args...).Scan(&count); err != nil {
+		return nil, 0, err
+	}
+
+	// Add ordering - validate orderBy against actual field names to prevent SQL injection
+	orderBy := opts.OrderBy
+	if orderBy == "" {
+		orderBy = "created_at"
+	} else {
+		// Validate that orderBy is an actual field name
+		validField := false
+		for _, f := range mod.Fields {
+			if f.Name == orderBy {
+				validField = true
+				break
+			}
+		}
+		if !validField {
+			orderBy = "created_at" // Fall back to safe default
+		}
+	}
+	dir := "ASC"
+	if opts.OrderDesc {
+		dir = "DESC"
+	}
+
+	// Resume after the cursor record: rows that sort after its (orderBy, id)
+	if opts.Cursor != "" {
+		c, err := decodeCursor(opts.Cursor, opts)
+		if err != nil {
+			return nil, 0, err
+		}
+		anchorSQL := fmt.Sprintf("SELECT %s FROM %s WHERE id = ?", orderBy, mod.Table)
+		var anchor any
+		if err := db.QueryRowContext(ctx, d.bind(anchorSQL), c.ID).Scan(&anchor); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, 0, fmt.Errorf("%w: record %s no longer exists", ErrInvalidCursor, c.ID)
+			}
+			return nil, 0, err
+		}
+
+		// The anchor's sort value is compared in SQL rather than bound from Go,
+		// so it keeps its stored representation (the driver reformats
+		// timestamps). NULLs sort first ascending and last descending on
+		// every backend (see orderClause).
+		anchorExpr := "(" + anchorSQL + ")"
+		var condition string
+		var condArgs []any
+		switch {
+		case anchor == nil && !opts.OrderDesc:
+			condition = fmt.Sprintf("(%s IS NOT NULL OR id > ?)", orderBy)
+			condArgs = []any{c.ID}
+		case anchor == nil:
+			condition = fmt.Sprintf("(%s IS NULL AND id < ?)", orderBy)
+			condArgs = []any{c.ID}
+		case !opts.OrderDesc:
+			condition = fmt.Sprintf("(%s > %s OR (%s = %s AND id > ?))", orderBy, anchorExpr, orderBy, anchorExpr)
+			condArgs = []any{c.ID, c.ID, c.ID}
+		default:
+			condition = fmt.Sprintf("(%s < %s OR %s IS NULL OR (%s = %s AND id < ?))", orderBy, anchorExpr, orderBy, orderBy, anchorExpr)
+			condArgs = []any{c.ID, c.ID, c.ID}
+		}
+		if whereClause == "" {
+			whereClause = " WHERE " + condition
+		} else {
+			whereClause += " AND " + condition
+		}
+		args = append(args, condArgs...)
+	}
+
+	// Build main query; id breaks ties so pages are stable
+	querySQL := fmt.Sprintf("SELECT %s FROM %s%s", strings.Join(columns, ", "), mod.Table, whereClause)
+	querySQL += " ORDER BY " + d.orderClause(orderBy, dir) + ", id " + dir
+
+	// Add pagination
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	if opts.Cursor != "" {
+		querySQL += fmt.Sprintf(" LIMIT %d", limit)
+	} else {
+		querySQL += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, opts.Offset)
+	}
+
+	rows, err := db.QueryContext(ctx, d.bind(querySQL), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var results []map[string]any
+	for rows.Next() {
+		values := make([]any, len(columns))
+		scanDest := make([]any, len(columns))
+		for i := range values {
+			scanDest[i] = &values[i]
+		}
+
+		if err := rows.Scan(scanDest...); err != nil {
+			continue
+		}
+
+		record := make(map[string]any)
+		for i, col := range columns {
+			record[col] = d.fromDB(values[i], mod.Fields[i])
+		}
+		results = append(results, record)
+	}
+
+	return results, count, nil
+}
@@ -0,0 +1,205 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/artpar/apigate/core/convention"
+)
+
+// ErrDestructiveMigration is returned by CreateTable when a module's schema
+// drops fields that still have columns, and destructive migrations are not
+// allowed.
+var ErrDestructiveMigration = errors.New("destructive migration not allowed")
+
+// migrationsTable records the schema changes applied to module tables.
+const migrationsTable = "module_schema_migrations"
+
+// Migration is a schema change applied to a module's table.
+type Migration struct {
+	Module  string
+	Version int
+
+	// Fields are the fields the module declared after the change
+	Fields []string
+
+	// Statements are the SQL statements applied, if any
+	Statements []string
+
+	AppliedAt time.Time
+}
+
+// Migrator is implemented by stores that migrate existing tables when a
+// module's schema changes.
+type Migrator interface {
+	// SetAllowDestructiveMigrations lets CreateTable drop the columns of
+	// fields removed from a schema. Off by default.
+	SetAllowDestructiveMigrations(allow bool)
+
+	// Migrations returns the changes applied to a module's table, oldest first.
+	Migrations(ctx context.Context, module string) ([]Migration, error)
+}
+
+// tableSpec describes how a backend creates and alters module tables.
+type tableSpec struct {
+	createSQL string
+	columnDef func(convention.DerivedField) string
+
+	// dropColumn returns the statements that drop a column, including
+	// anything the backend will not drop along with it.
+	dropColumn func(table, column string) []string
+}
+
+// ensureTable creates a module's table, or migrates an existing one to the
+// module's fields: new fields are added as columns with their defaults, and
+// columns of fields the module declared before but no longer does are dropped
+// only when allowDestructive is set. Columns the module never declared, such as
+// those of tables it shares with the application, are left alone. Each change
+// is recorded, with the declared fields, as the module's next version.
+func ensureTable(ctx context.Context, db *sql.DB, d dialect, mod convention.Derived, existing []string, spec tableSpec, allowDestructive bool) error {
+	if _, err := db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+  module TEXT NOT NULL,
+  version INTEGER NOT NULL,
+  fields TEXT NOT NULL,
+  statements TEXT NOT NULL,
+  applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (module, version)
+)`, migrationsTable)); err != nil {
+		return fmt.Errorf("create migrations table: %w", err)
+	}
+
+	var version int
+	var recorded string
+	lastSQL := fmt.Sprintf("SELECT version, fields FROM %s WHERE module = ? ORDER BY version DESC LIMIT 1", migrationsTable)
+	err := db.QueryRowContext(ctx, d.bind(lastSQL), mod.Source.Name).Scan(&version, &recorded)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("read migrations: %w", err)
+	}
+
+	fields := make([]string, len(mod.Fields))
+	for i, f := range mod.Fields {
+		fields[i] = f.Name
+	}
+	sort.Strings(fields)
+	declared := strings.Join(fields, ",")
+
+	var stmts []string
+	if len(existing) == 0 {
+		stmts = []string{spec.createSQL}
+	} else {
+		var previous []string
+		if recorded != "" {
+			previous = strings.Split(recorded, ",")
+		}
+		stmts, err = planMigration(mod, existing, previous, spec, allowDestructive)
+		if err != nil {
+			return err
+		}
+	}
+	if len(stmts) == 0 && version > 0 && recorded == declared {
+		return nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin migration: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("migrate %s: %s: %w", mod.Table, stmt, err)
+		}
+	}
+
+	recordSQL := fmt.Sprintf("INSERT INTO %s (module, version, fields, statements) VALUES (?, ?, ?, ?)", migrationsTable)
+	if _, err := tx.ExecContext(ctx, d.bind(recordSQL), mod.Source.Name, version+1, declared, strings.Join(stmts, ";\n")); err != nil {
+		return fmt.Errorf("record migration: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// planMigration returns the statements that bring a table with the existing
+// columns in line with the module's fields, given the fields it declared at
+// its last recorded version.
+func planMigration(mod convention.Derived, existing, previous []string, spec tableSpec, allowDestructive bool) ([]string, error) {
+	have := make(map[string]bool, len(existing))
+	for _, c := range existing {
+		have[c] = true
+	}
+	declared := make(map[string]bool, len(mod.Fields))
+
+	var stmts []string
+	for _, f := range mod.Fields {
+		declared[f.Name] = true
+		if have[f.Name] {
+			continue
+		}
+		if f.Required && f.Default == nil {
+			return nil, fmt.Errorf("add field %s.%s: a required field needs a default to fill existing rows", mod.Source.Name, f.Name)
+		}
+
+		// Constraints that CREATE TABLE declares per table go on the column
+		parts := []string{spec.columnDef(f)}
+		if f.Ref != "" && f.Default == nil {
+			parts = append(parts, fmt.Sprintf("REFERENCES %s(id)", convention.Pluralize(f.Ref)))
+		}
+		parts = append(parts, buildCheckConstraints(f)...)
+		if check := buildEnumCheck(f); check != "" {
+			parts = append(parts, check)
+		}
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", mod.Table, strings.Join(parts, " ")))
+		if f.Unique {
+			stmts = append(stmts, fmt.Sprintf("CREATE UNIQUE INDEX IF NOT EXISTS uq_%s_%s ON %s(%s)", mod.Table, f.Name, mod.Table, f.Name))
+		}
+	}
+
+	var removed []string
+	for _, name := range previous {
+		if !declared[name] && have[name] {
+			removed = append(removed, name)
+		}
+	}
+	if len(removed) > 0 {
+		if !allowDestructive {
+			return nil, fmt.Errorf("%w: %s no longer declares %s (allow destructive migrations to drop them)",
+				ErrDestructiveMigration, mod.Source.Name, strings.Join(removed, ", "))
+		}
+		for _, name := range removed {
+			stmts = append(stmts, spec.dropColumn(mod.Table, name)...)
+		}
+	}
+
+	return stmts, nil
+}
+
+// listMigrations returns the changes recorded for a module, oldest first.
+func listMigrations(ctx context.Context, db *sql.DB, d dialect, module string) ([]Migration, error) {
+	query := fmt.Sprintf("SELECT version, fields, statements, applied_at FROM %s WHERE module = ? ORDER BY version", migrationsTable)
+	rows, err := db.QueryContext(ctx, d.bind(query), module)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var migrations []Migration
+	for rows.Next() {
+		m := Migration{Module: module}
+		var fields, stmts string
+		if err := rows.Scan(&m.Version, &fields, &stmts, &m.AppliedAt); err != nil {
+			return nil, err
+		}
+		m.Fields = strings.Split(fields, ",")
+		if stmts != "" {
+			m.Statements = strings.Split(stmts, ";\n")
+		}
+		migrations = append(migrations, m)
+	}
+	return migrations, rows.Err()
+}
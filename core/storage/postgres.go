@@ -0,0 +1,645 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/artpar/apigate/core/convention"
+	"github.com/artpar/apigate/core/schema"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+// PostgresStore implements Store with PostgreSQL, so several nodes can share
+// module data.
+type PostgresStore struct {
+	db *sql.DB
+	mu sync.RWMutex
+
+	// modules maps module names to their derived definitions
+	modules map[string]convention.Derived
+
+	// allowDestructive lets CreateTable drop columns of removed fields
+	allowDestructive bool
+}
+
+// NewPostgresStore connects to PostgreSQL.
+func NewPostgresStore(dsn string) (*PostgresStore, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("connect: %w", err)
+	}
+
+	return NewPostgresStoreFromDB(db), nil
+}
+
+// NewPostgresStoreFromDB creates a PostgreSQL storage from an existing connection.
+func NewPostgresStoreFromDB(db *sql.DB) *PostgresStore {
+	return &PostgresStore{
+		db:      db,
+		modules: make(map[string]convention.Derived),
+	}
+}
+
+// pgDialect is the dialect for queries shared with SQLite.
+var pgDialect = dialect{
+	numbered:   true,
+	nullsFirst: true,
+	toDB:       convertValuePostgres,
+	fromDB:     convertFromPostgres,
+	search:     buildPostgresSearchCondition,
+}
+
+// CreateTable creates a table for a module.
+func (s *PostgresStore) CreateTable(ctx context.Context, mod convention.Derived) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Store module definition
+	s.modules[mod.Source.Name] = mod
+
+	// Create the table, or migrate it to the current fields
+	existing, err := s.tableColumns(ctx, mod.Table)
+	if err != nil {
+		return fmt.Errorf("create table %s: %w", mod.Table, err)
+	}
+	spec := tableSpec{
+		createSQL: BuildPostgresCreateTableSQL(mod),
+		columnDef: buildPostgresColumnDef,
+		dropColumn: func(table, column string) []string {
+			// Indexes and constraints on the column go with it
+			return []string{fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, column)}
+		},
+	}
+	if err := ensureTable(ctx, s.db, pgDialect, mod, existing, spec, s.allowDestructive); err != nil {
+		return fmt.Errorf("create table %s: %w", mod.Table, err)
+	}
+
+	// Create indexes
+	for _, indexSQL := range BuildIndexSQL(mod) {
+		if _, err := s.db.ExecContext(ctx, indexSQL); err != nil {
+			return fmt.Errorf("create index: %w", err)
+		}
+	}
+
+	// Create full-text index for searchable fields
+	if fields := SearchFields(mod); len(fields) > 0 {
+		h := fnv.New32a()
+		h.Write([]byte(strings.Join(fields, ",")))
+		indexSQL := fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_search_%08x ON %s USING GIN (%s)",
+			mod.Table, h.Sum32(), mod.Table, postgresSearchDocument(fields))
+		if _, err := s.db.ExecContext(ctx, indexSQL); err != nil {
+			return fmt.Errorf("create search index: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// SetAllowDestructiveMigrations lets CreateTable drop the columns of fields
+// removed from a module's schema.
+func (s *PostgresStore) SetAllowDestructiveMigrations(allow bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.allowDestructive = allow
+}
+
+// Migrations returns the changes applied to a module's table, oldest first.
+func (s *PostgresStore) Migrations(ctx context.Context, module string) ([]Migration, error) {
+	return listMigrations(ctx, s.db, pgDialect, module)
+}
+
+// tableColumns returns the column names of a table in the current schema, or
+// nil if it does not exist.
+func (s *PostgresStore) tableColumns(ctx context.Context, table string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT column_name FROM information_schema.columns
+WHERE table_schema = current_schema() AND table_name = $1 ORDER BY ordinal_position`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cols []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		cols = append(cols, name)
+	}
+	return cols, rows.Err()
+}
+
+// postgresRecords performs record operations for a PostgresStore, on its
+// connection pool or in a transaction.
+type postgresRecords struct {
+	*PostgresStore
+	q queryer
+}
+
+// records returns the store's record operations outside a transaction.
+func (s *PostgresStore) records() postgresRecords {
+	return postgresRecords{PostgresStore: s, q: s.db}
+}
+
+// Create inserts a new record.
+func (s *PostgresStore) Create(ctx context.Context, module string, data map[string]any) (string, error) {
+	return s.records().Create(ctx, module, data)
+}
+
+// Get retrieves a record by lookup field.
+func (s *PostgresStore) Get(ctx context.Context, module string, lookup string, value string) (map[string]any, error) {
+	return s.records().Get(ctx, module, lookup, value)
+}
+
+// Update modifies an existing record.
+func (s *PostgresStore) Update(ctx context.Context, module string, id string, data map[string]any) error {
+	return s.records().Update(ctx, module, id, data)
+}
+
+// Delete removes a record.
+func (s *PostgresStore) Delete(ctx context.Context, module string, id string) error {
+	return s.records().Delete(ctx, module, id)
+}
+
+// WithTransaction runs fn in a transaction, committing if it returns nil and
+// rolling back otherwise.
+func (s *PostgresStore) WithTransaction(ctx context.Context, fn func(tx Tx) error) error {
+	return runTx(ctx, s.db, func(tx *sql.Tx) error {
+		return fn(postgresRecords{PostgresStore: s, q: tx})
+	})
+}
+
+// Create inserts a new record.
+func (s postgresRecords) Create(ctx context.Context, module string, data map[string]any) (string, error) {
+	s.mu.RLock()
+	mod, ok := s.modules[module]
+	s.mu.RUnlock()
+
+	if !ok {
+		return "", fmt.Errorf("module %q not registered", module)
+	}
+
+	// Validate references before insert
+	if err := s.validateReferences(ctx, mod, data); err != nil {
+		return "", err
+	}
+
+	// Generate ID if not provided
+	id, ok := data["id"].(string)
+	if !ok || id == "" {
+		id = uuid.New().String()
+		data["id"] = id
+	}
+
+	// Build INSERT statement
+	var columns []string
+	var placeholders []string
+	var values []any
+
+	for _, f := range mod.Fields {
+		if f.Name == "created_at" || f.Name == "updated_at" || (f.Name == VersionField && f.Implicit) {
+			continue // Let DB handle these
+		}
+
+		val, exists := data[f.Name]
+		if !exists {
+			if f.Default != nil {
+				val = f.Default
+			} else if f.Required {
+				return "", fmt.Errorf("required field %q not provided", f.Name)
+			} else {
+				continue
+			}
+		}
+
+		columns = append(columns, f.Name)
+		placeholders = append(placeholders, "?")
+		values = append(values, convertValuePostgres(val, f))
+	}
+
+	insertSQL := fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES (%s)",
+		mod.Table,
+		strings.Join(columns, ", "),
+		strings.Join(placeholders, ", "),
+	)
+
+	if _, err := s.q.ExecContext(ctx, pgDialect.bind(insertSQL), values...); err != nil {
+		return "", fmt.Errorf("insert: %w", err)
+	}
+
+	return id, nil
+}
+
+// Get retrieves a record by lookup field.
+func (s postgresRecords) Get(ctx context.Context, module string, lookup string, value string) (map[string]any, error) {
+	s.mu.RLock()
+	mod, ok := s.modules[module]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("module %q not registered", module)
+	}
+
+	// Build column list, checking the lookup is a real field
+	var columns []string
+	validLookup := false
+	for _, f := range mod.Fields {
+		columns = append(columns, f.Name)
+		if f.Name == lookup {
+			validLookup = true
+		}
+	}
+	if !validLookup {
+		return nil, fmt.Errorf("unknown lookup field %q", lookup)
+	}
+
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s WHERE %s = $1",
+		strings.Join(columns, ", "),
+		mod.Table,
+		lookup,
+	)
+
+	row := s.q.QueryRowContext(ctx, query, value)
+
+	// Scan into interface values
+	values := make([]any, len(columns))
+	scanDest := make([]any, len(columns))
+	for i := range values {
+		scanDest[i] = &values[i]
+	}
+
+	if err := row.Scan(scanDest...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// Build result map
+	result := make(map[string]any)
+	for i, col := range columns {
+		result[col] = convertFromPostgres(values[i], mod.Fields[i])
+	}
+
+	return result, nil
+}
+
+// List retrieves multiple records.
+func (s *PostgresStore) List(ctx context.Context, module string, opts ListOptions) ([]map[string]any, int64, error) {
+	s.mu.RLock()
+	mod, ok := s.modules[module]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, 0, fmt.Errorf("module %q not registered", module)
+	}
+
+	return listRecords(ctx, s.db, mod, opts, pgDialect)
+}
+
+// Update modifies an existing record.
+func (s postgresRecords) Update(ctx context.Context, module string, id string, data map[string]any) error {
+	s.mu.RLock()
+	mod, ok := s.modules[module]
+	s.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("module %q not registered", module)
+	}
+
+	// Validate references before update
+	if err := s.validateReferences(ctx, mod, data); err != nil {
+		return err
+	}
+
+	// An update carrying the version applies only to that version
+	expected, conditional, err := expectedVersion(mod, data)
+	if err != nil {
+		return err
+	}
+
+	// Build UPDATE statement
+	var sets []string
+	var values []any
+
+	for k, v := range data {
+		if k == "id" || k == "created_at" || k == VersionField {
+			continue
+		}
+
+		// Find field definition
+		var field *convention.DerivedField
+		for i := range mod.Fields {
+			if mod.Fields[i].Name == k {
+				field = &mod.Fields[i]
+				break
+			}
+		}
+
+		if field == nil {
+			continue // Skip unknown fields
+		}
+
+		sets = append(sets, k+" = ?")
+		values = append(values, convertValuePostgres(v, *field))
+	}
+
+	if len(sets) == 0 {
+		return nil // Nothing to update
+	}
+
+	// Always update updated_at, and version when the module has one
+	sets = append(sets, "updated_at = CURRENT_TIMESTAMP")
+	if hasVersion(mod) {
+		sets = append(sets, VersionField+" = "+VersionField+" + 1")
+	}
+	values = append(values, id)
+
+	where := "id = ?"
+	if conditional {
+		where += " AND " + VersionField + " = ?"
+		values = append(values, expected)
+	}
+
+	updateSQL := fmt.Sprintf(
+		"UPDATE %s SET %s WHERE %s",
+		mod.Table,
+		strings.Join(sets, ", "),
+		where,
+	)
+
+	result, err := s.q.ExecContext(ctx, pgDialect.bind(updateSQL), values...)
+	if err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	affected, _ := result.RowsAffected()
+	if affected == 0 {
+		if conditional {
+			// Tell a stale version apart from a missing record
+			var count int
+			existsSQL := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE id = ?", mod.Table)
+			if err := s.q.QueryRowContext(ctx, pgDialect.bind(existsSQL), id).Scan(&count); err == nil && count > 0 {
+				return fmt.Errorf("%w: %s %s is no longer at version %d", ErrConflict, module, id, expected)
+			}
+		}
+		return fmt.Errorf("record not found: %s", id)
+	}
+
+	return nil
+}
+
+// Delete removes a record.
+func (s postgresRecords) Delete(ctx context.Context, module string, id string) error {
+	s.mu.RLock()
+	mod, ok := s.modules[module]
+	s.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("module %q not registered", module)
+	}
+
+	deleteSQL := fmt.Sprintf("DELETE FROM %s WHERE id = $1", mod.Table)
+
+	result, err := s.q.ExecContext(ctx, deleteSQL, id)
+	if err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	affected, _ := result.RowsAffected()
+	if affected == 0 {
+		return fmt.Errorf("record not found: %s", id)
+	}
+
+	return nil
+}
+
+// Close closes the database connection.
+func (s *PostgresStore) Close() error {
+	return s.db.Close()
+}
+
+// DB returns the underlying database connection.
+func (s *PostgresStore) DB() *sql.DB {
+	return s.db
+}
+
+// validateReferences checks that all referenced records exist.
+func (s postgresRecords) validateReferences(ctx context.Context, mod convention.Derived, data map[string]any) error {
+	for _, field := range mod.Fields {
+		if field.Ref == "" {
+			continue
+		}
+
+		refID, ok := data[field.Name].(string)
+		if !ok || refID == "" {
+			continue // No value provided, skip validation
+		}
+
+		s.mu.RLock()
+		refMod, ok := s.modules[field.Ref]
+		s.mu.RUnlock()
+		if !ok {
+			return fmt.Errorf("referenced module %q not registered for field %q", field.Ref, field.Name)
+		}
+
+		var count int
+		query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE id = $1", refMod.Table)
+		if err := s.q.QueryRowContext(ctx, query, refID).Scan(&count); err != nil {
+			return fmt.Errorf("check reference for field %q: %w", field.Name, err)
+		}
+
+		if count == 0 {
+			return fmt.Errorf("referenced %s with id %q does not exist (field: %s)", field.Ref, refID, field.Name)
+		}
+	}
+
+	return nil
+}
+
+// BuildPostgresCreateTableSQL generates PostgreSQL CREATE TABLE SQL from a
+// derived module.
+func BuildPostgresCreateTableSQL(mod convention.Derived) string {
+	return buildCreateTableSQL(mod, buildPostgresColumnDef)
+}
+
+// PostgresType returns the PostgreSQL column type for a field.
+func PostgresType(f convention.DerivedField) string {
+	switch f.Type {
+	case schema.FieldTypeInt:
+		return "BIGINT"
+	case schema.FieldTypeFloat:
+		return "DOUBLE PRECISION"
+	case schema.FieldTypeBool:
+		return "BOOLEAN"
+	case schema.FieldTypeTimestamp:
+		return "TIMESTAMPTZ"
+	case schema.FieldTypeBytes, schema.FieldTypeSecret:
+		return "BYTEA"
+	case schema.FieldTypeJSON, schema.FieldTypeStrings, schema.FieldTypeInts:
+		return "JSONB"
+	default:
+		return "TEXT"
+	}
+}
+
+// buildPostgresColumnDef builds a PostgreSQL column definition.
+func buildPostgresColumnDef(f convention.DerivedField) string {
+	parts := []string{f.Name, PostgresType(f)}
+
+	if f.Name == "id" {
+		parts = append(parts, "PRIMARY KEY")
+	}
+
+	if f.Required {
+		parts = append(parts, "NOT NULL")
+	}
+
+	if f.Default != nil {
+		defaultVal := formatDefault(f.Default, f.Type)
+		if b, ok := f.Default.(bool); ok {
+			defaultVal = "FALSE"
+			if b {
+				defaultVal = "TRUE"
+			}
+		}
+		if defaultVal != "" {
+			parts = append(parts, "DEFAULT "+defaultVal)
+		}
+	}
+
+	// Special defaults for timestamps
+	if f.Name == "created_at" || f.Name == "updated_at" {
+		parts = append(parts, "DEFAULT CURRENT_TIMESTAMP")
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// convertValuePostgres converts a Go value to a PostgreSQL value.
+func convertValuePostgres(val any, f convention.DerivedField) any {
+	if val == nil {
+		return nil
+	}
+
+	switch f.Type {
+	case schema.FieldTypeBool:
+		switch v := val.(type) {
+		case bool:
+			return v
+		case string:
+			return v == "true" || v == "1"
+		case int:
+			return v != 0
+		case int64:
+			return v != 0
+		default:
+			return false
+		}
+	case schema.FieldTypeSecret, schema.FieldTypeBytes:
+		if s, ok := val.(string); ok {
+			return []byte(s)
+		}
+		return val
+	case schema.FieldTypeJSON, schema.FieldTypeStrings, schema.FieldTypeInts:
+		// JSONB takes the encoded document
+		switch v := val.(type) {
+		case string:
+			return v
+		case []byte:
+			return string(v)
+		default:
+			b, err := json.Marshal(v)
+			if err != nil {
+				return val
+			}
+			return string(b)
+		}
+	case schema.FieldTypeTimestamp:
+		if s, ok := val.(string); ok && s == "" {
+			return nil
+		}
+		return val
+	default:
+		return val
+	}
+}
+
+// convertFromPostgres converts a PostgreSQL value to the Go value SQLiteStore
+// would return, so callers see the same shapes on both backends.
+func convertFromPostgres(val any, f convention.DerivedField) any {
+	if val == nil {
+		return nil
+	}
+
+	switch f.Type {
+	case schema.FieldTypeSecret, schema.FieldTypeBytes:
+		return val
+	case schema.FieldTypeTimestamp:
+		// Match SQLite's CURRENT_TIMESTAMP text
+		if t, ok := val.(time.Time); ok {
+			return t.UTC().Format("2006-01-02 15:04:05")
+		}
+		return val
+	default:
+		if b, ok := val.([]byte); ok {
+			return string(b)
+		}
+		return val
+	}
+}
+
+// postgresSearchDocument returns the tsvector expression over searchable
+// fields. The search index is built on this exact expression.
+func postgresSearchDocument(fields []string) string {
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		parts[i] = "coalesce(" + f + ", '')"
+	}
+	return "to_tsvector('simple', " + strings.Join(parts, " || ' ' || ") + ")"
+}
+
+// buildPostgresSearchCondition matches every word and "quoted phrase" against
+// the searchable fields. phraseto_tsquery treats its input as plain text, so
+// tsquery operators in user input have no effect.
+func buildPostgresSearchCondition(mod convention.Derived, query string) (string, []any, error) {
+	fields := SearchFields(mod)
+	if len(fields) == 0 {
+		return "", nil, fmt.Errorf("%w: %s has no searchable fields", ErrInvalidFilter, mod.Source.Name)
+	}
+	terms := parseSearchTerms(query)
+	if len(terms) == 0 {
+		return "", nil, nil
+	}
+
+	queries := make([]string, len(terms))
+	args := make([]any, len(terms))
+	for i, t := range terms {
+		queries[i] = "phraseto_tsquery('simple', ?)"
+		args[i] = t
+	}
+	condition := fmt.Sprintf("%s @@ (%s)", postgresSearchDocument(fields), strings.Join(queries, " && "))
+	return condition, args, nil
+}
+
+// Ensure interface compliance.
+var (
+	_ Store    = (*PostgresStore)(nil)
+	_ Migrator = (*PostgresStore)(nil)
+)
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/artpar/apigate/core/convention"
+)
+
+// SearchFields returns the names of a module's searchable fields.
+func SearchFields(mod convention.Derived) []string {
+	var fields []string
+	for _, f := range mod.Fields {
+		if f.Searchable {
+			fields = append(fields, f.Name)
+		}
+	}
+	return fields
+}
+
+// ftsTable returns the name of a module's FTS5 index table.
+func ftsTable(mod convention.Derived) string {
+	return mod.Table + "_fts"
+}
+
+// createSearchIndex creates (or rebuilds when its columns changed) an
+// external-content FTS5 table over the module's searchable fields, kept in sync
+// by triggers. It returns false when SQLite was built without FTS5, in which
+// case searches fall back to LIKE.
+func (s *SQLiteStore) createSearchIndex(ctx context.Context, mod convention.Derived) (bool, error) {
+	fields := SearchFields(mod)
+	if len(fields) == 0 {
+		return false, nil
+	}
+	fts := ftsTable(mod)
+
+	existing, err := s.tableColumns(ctx, fts)
+	if err != nil {
+		return false, err
+	}
+	if strings.Join(existing, ",") == strings.Join(fields, ",") {
+		return true, nil
+	}
+
+	stmts := []string{
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s_ai", fts),
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s_ad", fts),
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s_au", fts),
+		fmt.Sprintf("DROP TABLE IF EXISTS %s", fts),
+	}
+	for _, stmt := range stmts {
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+			return false, fmt.Errorf("drop search index: %w", err)
+		}
+	}
+
+	createSQL := fmt.Sprintf("CREATE VIRTUAL TABLE %s USING fts5(%s, content='%s', content_rowid='rowid')",
+		fts, strings.Join(fields, ", "), mod.Table)
+	if _, err := s.db.ExecContext(ctx, createSQL); err != nil {
+		if strings.Contains(err.Error(), "no such module: fts5") {
+			return false, nil
+		}
+		return false, fmt.Errorf("create search index: %w", err)
+	}
+
+	cols := strings.Join(fields, ", ")
+	newVals := "new." + strings.Join(fields, ", new.")
+	oldVals := "old." + strings.Join(fields, ", old.")
+	stmts = []string{
+		fmt.Sprintf("CREATE TRIGGER %s_ai AFTER INSERT ON %s BEGIN INSERT INTO %s(rowid, %s) VALUES (new.rowid, %s); END",
+			fts, mod.Table, fts, cols, newVals),
+		fmt.Sprintf("CREATE TRIGGER %s_ad AFTER DELETE ON %s BEGIN INSERT INTO %s(%s, rowid, %s) VALUES ('delete', old.rowid, %s); END",
+			fts, mod.Table, fts, fts, cols, oldVals),
+		fmt.Sprintf("CREATE TRIGGER %s_au AFTER UPDATE ON %s BEGIN INSERT INTO %s(%s, rowid, %s) VALUES ('delete', old.rowid, %s); INSERT INTO %s(rowid, %s) VALUES (new.rowid, %s); END",
+			fts, mod.Table, fts, fts, cols, oldVals, fts, cols, newVals),
+		// Index rows that existed before the index did
+		fmt.Sprintf("INSERT INTO %s(%s) VALUES ('rebuild')", fts, fts),
+	}
+	for _, stmt := range stmts {
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+			return false, fmt.Errorf("create search index: %w", err)
+		}
+	}
+	return true, nil
+}
+
+// tableColumns returns the column names of a table, or nil if it does not exist.
+func (s *SQLiteStore) tableColumns(ctx context.Context, table string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cols []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		cols = append(cols, name)
+	}
+	return cols, rows.Err()
+}
+
+// buildSearchCondition returns the condition matching records for a search
+// query. Every term (a word, or a "quoted phrase") must match. With FTS5 terms
+// match whole words; without it they match substrings.
+func buildSearchCondition(mod convention.Derived, query string, fts bool) (string, []any, error) {
+	fields := SearchFields(mod)
+	if len(fields) == 0 {
+		return "", nil, fmt.Errorf("%w: %s has no searchable fields", ErrInvalidFilter, mod.Source.Name)
+	}
+	terms := parseSearchTerms(query)
+	if len(terms) == 0 {
+		return "", nil, nil
+	}
+
+	if fts {
+		// Quoting each term makes FTS5 treat it as a literal phrase, so
+		// operators and column filters in user input have no effect.
+		quoted := make([]string, len(terms))
+		for i, t := range terms {
+			quoted[i] = `"` + strings.ReplaceAll(t, `"`, `""`) + `"`
+		}
+		fts := ftsTable(mod)
+		condition := fmt.Sprintf("rowid IN (SELECT rowid FROM %s WHERE %s MATCH ?)", fts, fts)
+		return condition, []any{strings.Join(quoted, " ")}, nil
+	}
+
+	var conditions []string
+	var args []any
+	for _, t := range terms {
+		pattern := "%" + escapeLike(t) + "%"
+		ors := make([]string, len(fields))
+		for i, f := range fields {
+			ors[i] = f + ` LIKE ? ESCAPE '\'`
+			args = append(args, pattern)
+		}
+		conditions = append(conditions, "("+strings.Join(ors, " OR ")+")")
+	}
+	return strings.Join(conditions, " AND "), args, nil
+}
+
+// parseSearchTerms splits a query into words and "quoted phrases".
+func parseSearchTerms(query string) []string {
+	var terms []string
+	for i, part := range strings.Split(query, `"`) {
+		if i%2 == 1 {
+			// Inside quotes: one phrase
+			if phrase := strings.Join(strings.Fields(part), " "); phrase != "" {
+				terms = append(terms, phrase)
+			}
+			continue
+		}
+		terms = append(terms, strings.Fields(part)...)
+	}
+	return terms
+}
+
+// escapeLike escapes LIKE wildcards so they match literally.
+func escapeLike(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/artpar/apigate/core/convention"
 	"github.com/google/uuid"
@@ -14,45 +15,95 @@ import (
 
 // SQLiteStore implements Store with SQLite.
 type SQLiteStore struct {
-	db *sql.DB
-	mu sync.RWMutex
+	// db serves writes; readDB serves reads and may be the same pool
+	db     *sql.DB
+	readDB *sql.DB
+	mu     sync.RWMutex
 
 	// modules maps module names to their derived definitions
 	modules map[string]convention.Derived
+
+	// fts records modules whose search uses an FTS5 index
+	fts map[string]bool
+
+	// allowDestructive lets CreateTable drop columns of removed fields
+	allowDestructive bool
+}
+
+// SQLiteConfig configures the connections of a SQLiteStore.
+type SQLiteConfig struct {
+	// BusyTimeout is how long a statement waits for a lock held by another
+	// connection or process before failing with "database is locked".
+	BusyTimeout time.Duration
+
+	// MaxReadConns bounds the connections serving reads. Writes share a
+	// single connection, so they queue in the pool rather than contend for
+	// the database's write lock, while reads run concurrently alongside.
+	MaxReadConns int
+}
+
+// DefaultSQLiteConfig returns sensible defaults.
+func DefaultSQLiteConfig() SQLiteConfig {
+	return SQLiteConfig{
+		BusyTimeout:  5 * time.Second,
+		MaxReadConns: 4,
+	}
 }
 
-// NewSQLiteStore creates a new SQLite storage.
+// NewSQLiteStore creates a new SQLite storage with the default configuration.
 func NewSQLiteStore(path string) (*SQLiteStore, error) {
-	db, err := sql.Open("sqlite3", path+"?_journal_mode=WAL&_busy_timeout=5000")
+	return NewSQLiteStoreWithConfig(path, DefaultSQLiteConfig())
+}
+
+// NewSQLiteStoreWithConfig creates a new SQLite storage in WAL mode, with one
+// connection for writes and a pool of up to cfg.MaxReadConns for reads. An
+// in-memory database lives in a single connection, which serves both.
+func NewSQLiteStoreWithConfig(path string, cfg SQLiteConfig) (*SQLiteStore, error) {
+	// Connection pragmas go in the DSN so every pooled connection gets them
+	params := fmt.Sprintf("_journal_mode=WAL&_busy_timeout=%d&_synchronous=NORMAL&_cache_size=-64000&_foreign_keys=1",
+		cfg.BusyTimeout.Milliseconds())
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+
+	// Immediate transactions take the write lock up front, so they wait on
+	// busy_timeout instead of failing when they first write
+	db, err := sql.Open("sqlite3", path+sep+params+"&_txlock=immediate")
 	if err != nil {
 		return nil, fmt.Errorf("open database: %w", err)
 	}
+	db.SetMaxOpenConns(1)
 
-	// Set pragmas for performance
-	pragmas := []string{
-		"PRAGMA synchronous = NORMAL",
-		"PRAGMA cache_size = -64000",
-		"PRAGMA temp_store = MEMORY",
-		"PRAGMA foreign_keys = ON",
+	if _, err := db.Exec("PRAGMA temp_store = MEMORY"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("set pragma: %w", err)
 	}
-	for _, pragma := range pragmas {
-		if _, err := db.Exec(pragma); err != nil {
-			db.Close()
-			return nil, fmt.Errorf("set pragma: %w", err)
-		}
+
+	store := NewSQLiteStoreFromDB(db)
+	if path == ":memory:" || strings.Contains(path, "mode=memory") {
+		return store, nil
 	}
 
-	return &SQLiteStore{
-		db:      db,
-		modules: make(map[string]convention.Derived),
-	}, nil
+	readDB, err := sql.Open("sqlite3", path+sep+params)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	readDB.SetMaxOpenConns(max(cfg.MaxReadConns, 1))
+	store.readDB = readDB
+
+	return store, nil
 }
 
-// NewSQLiteStoreFromDB creates a SQLite storage from an existing connection.
+// NewSQLiteStoreFromDB creates a SQLite storage from an existing connection,
+// which serves both reads and writes.
 func NewSQLiteStoreFromDB(db *sql.DB) *SQLiteStore {
 	return &SQLiteStore{
 		db:      db,
+		readDB:  db,
 		modules: make(map[string]convention.Derived),
+		fts:     make(map[string]bool),
 	}
 }
 
@@ -64,9 +115,17 @@ func (s *SQLiteStore) CreateTable(ctx context.Context, mod convention.Derived) e
 	// Store module definition
 	s.modules[mod.Source.Name] = mod
 
-	// Create table
-	createSQL := BuildCreateTableSQL(mod)
-	if _, err := s.db.ExecContext(ctx, createSQL); err != nil {
+	// Create the table, or migrate it to the current fields
+	existing, err := s.tableColumns(ctx, mod.Table)
+	if err != nil {
+		return fmt.Errorf("create table %s: %w", mod.Table, err)
+	}
+	spec := tableSpec{
+		createSQL:  BuildCreateTableSQL(mod),
+		columnDef:  buildColumnDef,
+		dropColumn: sqliteDropColumn,
+	}
+	if err := ensureTable(ctx, s.db, dialect{}, mod, existing, spec, s.allowDestructive); err != nil {
 		return fmt.Errorf("create table %s: %w", mod.Table, err)
 	}
 
@@ -77,11 +136,87 @@ func (s *SQLiteStore) CreateTable(ctx context.Context, mod convention.Derived) e
 		}
 	}
 
+	// Create full-text index for searchable fields
+	fts, err := s.createSearchIndex(ctx, mod)
+	if err != nil {
+		return fmt.Errorf("create table %s: %w", mod.Table, err)
+	}
+	s.fts[mod.Source.Name] = fts
+
 	return nil
 }
 
+// SetAllowDestructiveMigrations lets CreateTable drop the columns of fields
+// removed from a module's schema.
+func (s *SQLiteStore) SetAllowDestructiveMigrations(allow bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.allowDestructive = allow
+}
+
+// Migrations returns the changes applied to a module's table, oldest first.
+func (s *SQLiteStore) Migrations(ctx context.Context, module string) ([]Migration, error) {
+	return listMigrations(ctx, s.readDB, dialect{}, module)
+}
+
+// sqliteDropColumn drops a column along with the indexes and search triggers
+// that would otherwise block it. The search index is rebuilt afterwards.
+func sqliteDropColumn(table, column string) []string {
+	fts := table + "_fts"
+	return []string{
+		fmt.Sprintf("DROP INDEX IF EXISTS idx_%s_%s", table, column),
+		fmt.Sprintf("DROP INDEX IF EXISTS uq_%s_%s", table, column),
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s_ai", fts),
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s_ad", fts),
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s_au", fts),
+		fmt.Sprintf("DROP TABLE IF EXISTS %s", fts),
+		fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, column),
+	}
+}
+
+// sqliteRecords performs record operations for a SQLiteStore, reading and
+// writing through its connection pools or through a transaction.
+type sqliteRecords struct {
+	*SQLiteStore
+	read, write queryer
+}
+
+// records returns the store's record operations outside a transaction.
+func (s *SQLiteStore) records() sqliteRecords {
+	return sqliteRecords{SQLiteStore: s, read: s.readDB, write: s.db}
+}
+
 // Create inserts a new record.
 func (s *SQLiteStore) Create(ctx context.Context, module string, data map[string]any) (string, error) {
+	return s.records().Create(ctx, module, data)
+}
+
+// Get retrieves a record by lookup field.
+func (s *SQLiteStore) Get(ctx context.Context, module string, lookup string, value string) (map[string]any, error) {
+	return s.records().Get(ctx, module, lookup, value)
+}
+
+// Update modifies an existing record.
+func (s *SQLiteStore) Update(ctx context.Context, module string, id string, data map[string]any) error {
+	return s.records().Update(ctx, module, id, data)
+}
+
+// Delete removes a record.
+func (s *SQLiteStore) Delete(ctx context.Context, module string, id string) error {
+	return s.records().Delete(ctx, module, id)
+}
+
+// WithTransaction runs fn in a transaction, committing if it returns nil and
+// rolling back otherwise. Writes are serialized, so fn must use tx rather than
+// the store, or it will wait on its own transaction.
+func (s *SQLiteStore) WithTransaction(ctx context.Context, fn func(tx Tx) error) error {
+	return runTx(ctx, s.db, func(tx *sql.Tx) error {
+		return fn(sqliteRecords{SQLiteStore: s, read: tx, write: tx})
+	})
+}
+
+// Create inserts a new record.
+func (s sqliteRecords) Create(ctx context.Context, module string, data map[string]any) (string, error) {
 	s.mu.RLock()
 	mod, ok := s.modules[module]
 	s.mu.RUnlock()
@@ -108,7 +243,7 @@ func (s *SQLiteStore) Create(ctx context.Context, module string, data map[string
 	var values []any
 
 	for _, f := range mod.Fields {
-		if f.Name == "created_at" || f.Name == "updated_at" {
+		if f.Name == "created_at" || f.Name == "updated_at" || (f.Name == VersionField && f.Implicit) {
 			continue // Let DB handle these
 		}
 
@@ -135,7 +270,7 @@ func (s *SQLiteStore) Create(ctx context.Context, module string, data map[string
 		strings.Join(placeholders, ", "),
 	)
 
-	if _, err := s.db.ExecContext(ctx, insertSQL, values...); err != nil {
+	if _, err := s.write.ExecContext(ctx, insertSQL, values...); err != nil {
 		return "", fmt.Errorf("insert: %w", err)
 	}
 
@@ -143,7 +278,7 @@ func (s *SQLiteStore) Create(ctx context.Context, module string, data map[string
 }
 
 // Get retrieves a record by lookup field.
-func (s *SQLiteStore) Get(ctx context.Context, module string, lookup string, value string) (map[string]any, error) {
+func (s sqliteRecords) Get(ctx context.Context, module string, lookup string, value string) (map[string]any, error) {
 	s.mu.RLock()
 	mod, ok := s.modules[module]
 	s.mu.RUnlock()
@@ -165,7 +300,7 @@ func (s *SQLiteStore) Get(ctx context.Context, module string, lookup string, val
 		lookup,
 	)
 
-	row := s.db.QueryRowContext(ctx, query, value)
+	row := s.read.QueryRowContext(ctx, query, value)
 
 	// Scan into interface values
 	values := make([]any, len(columns))
@@ -194,101 +329,24 @@ func (s *SQLiteStore) Get(ctx context.Context, module string, lookup string, val
 func (s *SQLiteStore) List(ctx context.Context, module string, opts ListOptions) ([]map[string]any, int64, error) {
 	s.mu.RLock()
 	mod, ok := s.modules[module]
+	fts := s.fts[module]
 	s.mu.RUnlock()
 
 	if !ok {
 		return nil, 0, fmt.Errorf("module %q not registered", module)
 	}
 
-	// Build column list
-	var columns []string
-	for _, f := range mod.Fields {
-		columns = append(columns, f.Name)
-	}
-
-	// Build query
-	var whereClause string
-	var args []any
-
-	if len(opts.Filters) > 0 {
-		var conditions []string
-		for k, v := range opts.Filters {
-			conditions = append(conditions, k+" = ?")
-			args = append(args, v)
-		}
-		whereClause = " WHERE " + strings.Join(conditions, " AND ")
-	}
-
-	// Get count
-	countSQL := fmt.Sprintf("SELECT COUNT(*) FROM %s%s", mod.Table, whereClause)
-	var count int64
-	if err := s.db.QueryRowContext(ctx, countSQL, args...).Scan(&count); err != nil {
-		return nil, 0, err
-	}
-
-	// Build main query
-	querySQL := fmt.Sprintf("SELECT %s FROM %s%s", strings.Join(columns, ", "), mod.Table, whereClause)
-
-	// Add ordering - validate orderBy against actual field names to prevent SQL injection
-	orderBy := opts.OrderBy
-	if orderBy == "" {
-		orderBy = "created_at"
-	} else {
-		// Validate that orderBy is an actual field name
-		validField := false
-		for _, f := range mod.Fields {
-			if f.Name == orderBy {
-				validField = true
-				break
-			}
-		}
-		if !validField {
-			orderBy = "created_at" // Fall back to safe default
-		}
-	}
-	if opts.OrderDesc {
-		querySQL += fmt.Sprintf(" ORDER BY %s DESC", orderBy)
-	} else {
-		querySQL += fmt.Sprintf(" ORDER BY %s ASC", orderBy)
-	}
-
-	// Add pagination
-	limit := opts.Limit
-	if limit <= 0 {
-		limit = 100
-	}
-	querySQL += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, opts.Offset)
-
-	rows, err := s.db.QueryContext(ctx, querySQL, args...)
-	if err != nil {
-		return nil, 0, err
-	}
-	defer rows.Close()
-
-	var results []map[string]any
-	for rows.Next() {
-		values := make([]any, len(columns))
-		scanDest := make([]any, len(columns))
-		for i := range values {
-			scanDest[i] = &values[i]
-		}
-
-		if err := rows.Scan(scanDest...); err != nil {
-			continue
-		}
-
-		record := make(map[string]any)
-		for i, col := range columns {
-			record[col] = convertFromDB(values[i], mod.Fields[i])
-		}
-		results = append(results, record)
-	}
-
-	return results, count, nil
+	return listRecords(ctx, s.readDB, mod, opts, dialect{
+		toDB:   convertValue,
+		fromDB: convertFromDB,
+		search: func(mod convention.Derived, query string) (string, []any, error) {
+			return buildSearchCondition(mod, query, fts)
+		},
+	})
 }
 
 // Update modifies an existing record.
-func (s *SQLiteStore) Update(ctx context.Context, module string, id string, data map[string]any) error {
+func (s sqliteRecords) Update(ctx context.Context, module string, id string, data map[string]any) error {
 	s.mu.RLock()
 	mod, ok := s.modules[module]
 	s.mu.RUnlock()
@@ -302,12 +360,18 @@ func (s *SQLiteStore) Update(ctx context.Context, module string, id string, data
 		return err
 	}
 
+	// An update carrying the version applies only to that version
+	expected, conditional, err := expectedVersion(mod, data)
+	if err != nil {
+		return err
+	}
+
 	// Build UPDATE statement
 	var sets []string
 	var values []any
 
 	for k, v := range data {
-		if k == "id" || k == "created_at" {
+		if k == "id" || k == "created_at" || k == VersionField {
 			continue
 		}
 
@@ -332,23 +396,41 @@ func (s *SQLiteStore) Update(ctx context.Context, module string, id string, data
 		return nil // Nothing to update
 	}
 
-	// Always update updated_at
+	// Always update updated_at, and version when the module has one
 	sets = append(sets, "updated_at = CURRENT_TIMESTAMP")
+	if hasVersion(mod) {
+		sets = append(sets, VersionField+" = "+VersionField+" + 1")
+	}
 	values = append(values, id)
 
+	where := "id = ?"
+	if conditional {
+		where += " AND " + VersionField + " = ?"
+		values = append(values, expected)
+	}
+
 	updateSQL := fmt.Sprintf(
-		"UPDATE %s SET %s WHERE id = ?",
+		"UPDATE %s SET %s WHERE %s",
 		mod.Table,
 		strings.Join(sets, ", "),
+		where,
 	)
 
-	result, err := s.db.ExecContext(ctx, updateSQL, values...)
+	result, err := s.write.ExecContext(ctx, updateSQL, values...)
 	if err != nil {
 		return fmt.Errorf("update: %w", err)
 	}
 
 	affected, _ := result.RowsAffected()
 	if affected == 0 {
+		if conditional {
+			// Tell a stale version apart from a missing record
+			var count int
+			existsSQL := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE id = ?", mod.Table)
+			if err := s.write.QueryRowContext(ctx, existsSQL, id).Scan(&count); err == nil && count > 0 {
+				return fmt.Errorf("%w: %s %s is no longer at version %d", ErrConflict, module, id, expected)
+			}
+		}
 		return fmt.Errorf("record not found: %s", id)
 	}
 
@@ -356,7 +438,7 @@ func (s *SQLiteStore) Update(ctx context.Context, module string, id string, data
 }
 
 // Delete removes a record.
-func (s *SQLiteStore) Delete(ctx context.Context, module string, id string) error {
+func (s sqliteRecords) Delete(ctx context.Context, module string, id string) error {
 	s.mu.RLock()
 	mod, ok := s.modules[module]
 	s.mu.RUnlock()
@@ -367,7 +449,7 @@ func (s *SQLiteStore) Delete(ctx context.Context, module string, id string) erro
 
 	deleteSQL := fmt.Sprintf("DELETE FROM %s WHERE id = ?", mod.Table)
 
-	result, err := s.db.ExecContext(ctx, deleteSQL, id)
+	result, err := s.write.ExecContext(ctx, deleteSQL, id)
 	if err != nil {
 		return fmt.Errorf("delete: %w", err)
 	}
@@ -380,12 +462,15 @@ func (s *SQLiteStore) Delete(ctx context.Context, module string, id string) erro
 	return nil
 }
 
-// Close closes the database connection.
+// Close closes the database connections.
 func (s *SQLiteStore) Close() error {
+	if s.readDB != s.db {
+		s.readDB.Close()
+	}
 	return s.db.Close()
 }
 
-// DB returns the underlying database connection.
+// DB returns the database connection used for writes.
 func (s *SQLiteStore) DB() *sql.DB {
 	return s.db
 }
@@ -461,7 +546,7 @@ func convertFromDB(val any, f convention.DerivedField) any {
 }
 
 // validateReferences checks that all referenced records exist.
-func (s *SQLiteStore) validateReferences(ctx context.Context, mod convention.Derived, data map[string]any) error {
+func (s sqliteRecords) validateReferences(ctx context.Context, mod convention.Derived, data map[string]any) error {
 	for _, field := range mod.Fields {
 		// Skip fields without references
 		if field.Ref == "" {
@@ -488,7 +573,7 @@ func (s *SQLiteStore) validateReferences(ctx context.Context, mod convention.Der
 		// Check if the referenced record exists
 		var count int
 		query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE id = ?", refMod.Table)
-		if err := s.db.QueryRowContext(ctx, query, refID).Scan(&count); err != nil {
+		if err := s.read.QueryRowContext(ctx, query, refID).Scan(&count); err != nil {
 			return fmt.Errorf("check reference for field %q: %w", field.Name, err)
 		}
 
@@ -499,3 +584,9 @@ func (s *SQLiteStore) validateReferences(ctx context.Context, mod convention.Der
 
 	return nil
 }
+
+// Ensure interface compliance.
+var (
+	_ Store    = (*SQLiteStore)(nil)
+	_ Migrator = (*SQLiteStore)(nil)
+)
@@ -2,7 +2,13 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/artpar/apigate/core/convention"
 	"github.com/artpar/apigate/core/schema"
@@ -263,6 +269,57 @@ func TestNewSQLiteStoreFromDB(t *testing.T) {
 	}
 }
 
+// TestSQLiteStoreConcurrentWrites runs parallel creates and updates with a
+// busy timeout too short to wait out a competing writer, so they only succeed
+// because writes are serialized.
+func TestSQLiteStoreConcurrentWrites(t *testing.T) {
+	for _, path := range []string{filepath.Join(t.TempDir(), "store.db"), ":memory:"} {
+		t.Run(path, func(t *testing.T) {
+			store, err := NewSQLiteStoreWithConfig(path, SQLiteConfig{BusyTimeout: time.Millisecond, MaxReadConns: 4})
+			if err != nil {
+				t.Fatalf("NewSQLiteStoreWithConfig failed: %v", err)
+			}
+			defer store.Close()
+
+			ctx := context.Background()
+			if err := store.CreateTable(ctx, migrationModule(nil)); err != nil {
+				t.Fatalf("CreateTable failed: %v", err)
+			}
+
+			const workers, writes = 32, 25
+			var wg sync.WaitGroup
+			errs := make(chan error, workers*writes)
+			for w := 0; w < workers; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					for i := 0; i < writes; i++ {
+						id, err := store.Create(ctx, "gadget", map[string]any{"name": fmt.Sprintf("w%d-%d", w, i)})
+						if err == nil {
+							err = store.Update(ctx, "gadget", id, map[string]any{"name": fmt.Sprintf("w%d-%d-updated", w, i)})
+						}
+						if err == nil {
+							_, _, err = store.List(ctx, "gadget", ListOptions{Limit: 10})
+						}
+						if err != nil {
+							errs <- err
+						}
+					}
+				}(w)
+			}
+			wg.Wait()
+			close(errs)
+
+			for err := range errs {
+				t.Errorf("concurrent write failed: %v", err)
+			}
+			if _, total, err := store.List(ctx, "gadget", ListOptions{}); err != nil || total != workers*writes {
+				t.Errorf("total = %d, %v; want %d", total, err, workers*writes)
+			}
+		})
+	}
+}
+
 // TestDBMethod tests the DB() method
 func TestDBMethod(t *testing.T) {
 	store, err := NewSQLiteStore(":memory:")
@@ -706,6 +763,160 @@ func TestListWithPagination(t *testing.T) {
 	}
 }
 
+// TestListWithCursor tests cursor pagination walks every record exactly once,
+// in a stable order, including across ties in the sort field.
+func TestListWithCursor(t *testing.T) {
+	store, err := NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer store.Close()
+
+	mod := schema.Module{
+		Name: "product",
+		Schema: map[string]schema.Field{
+			"name":  {Type: schema.FieldTypeString},
+			"price": {Type: schema.FieldTypeInt},
+		},
+	}
+	ctx := context.Background()
+	if err := store.CreateTable(ctx, convention.Derive(mod)); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	// Seven records, several sharing a price and all sharing created_at second
+	prices := []int{30, 10, 20, 10, 30, 20, 10}
+	for i, p := range prices {
+		if _, err := store.Create(ctx, "product", map[string]any{"name": string(rune('A' + i)), "price": p}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	walk := func(t *testing.T, opts ListOptions) []string {
+		t.Helper()
+		var ids []string
+		for page := 0; page < 10; page++ {
+			list, count, err := store.List(ctx, "product", opts)
+			if err != nil {
+				t.Fatalf("List page %d failed: %v", page, err)
+			}
+			if count != int64(len(prices)) {
+				t.Errorf("count = %d, want %d", count, len(prices))
+			}
+			for _, rec := range list {
+				ids = append(ids, rec["id"].(string))
+			}
+			next := NextCursor(list, opts)
+			if next == "" {
+				return ids
+			}
+			opts.Cursor = next
+		}
+		t.Fatal("cursor pagination did not terminate")
+		return nil
+	}
+
+	for _, tt := range []struct {
+		name string
+		opts ListOptions
+	}{
+		{"default order", ListOptions{Limit: 3}},
+		{"price ascending", ListOptions{Limit: 3, OrderBy: "price"}},
+		{"price descending", ListOptions{Limit: 2, OrderBy: "price", OrderDesc: true}},
+		{"exact multiple of limit", ListOptions{Limit: 7, OrderBy: "price"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := walk(t, tt.opts)
+
+			// The same ordering read in one page is the reference
+			all := tt.opts
+			all.Limit = 100
+			list, _, err := store.List(ctx, "product", all)
+			if err != nil {
+				t.Fatalf("List failed: %v", err)
+			}
+			if len(got) != len(list) {
+				t.Fatalf("cursor walk returned %d records, want %d", len(got), len(list))
+			}
+			for i, rec := range list {
+				if got[i] != rec["id"] {
+					t.Errorf("record %d = %v, want %v", i, got[i], rec["id"])
+				}
+			}
+
+			// Offset pages agree with the single page, so ordering is stable
+			offsetOpts := tt.opts
+			for offset := 0; offset < len(list); offset += tt.opts.Limit {
+				offsetOpts.Offset = offset
+				page, _, err := store.List(ctx, "product", offsetOpts)
+				if err != nil {
+					t.Fatalf("List offset %d failed: %v", offset, err)
+				}
+				for i, rec := range page {
+					if rec["id"] != list[offset+i]["id"] {
+						t.Errorf("offset %d record %d = %v, want %v", offset, i, rec["id"], list[offset+i]["id"])
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestListWithInvalidCursor tests that unusable cursors are rejected.
+func TestListWithInvalidCursor(t *testing.T) {
+	store, err := NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer store.Close()
+
+	mod := schema.Module{
+		Name: "product",
+		Schema: map[string]schema.Field{
+			"name": {Type: schema.FieldTypeString},
+		},
+	}
+	ctx := context.Background()
+	if err := store.CreateTable(ctx, convention.Derive(mod)); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		store.Create(ctx, "product", map[string]any{"name": string(rune('A' + i))})
+	}
+
+	opts := ListOptions{Limit: 1}
+	list, _, err := store.List(ctx, "product", opts)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	next := NextCursor(list, opts)
+
+	tests := []struct {
+		name string
+		opts ListOptions
+	}{
+		{"garbage", ListOptions{Limit: 1, Cursor: "not a cursor!"}},
+		{"different order field", ListOptions{Limit: 1, Cursor: next, OrderBy: "name"}},
+		{"different direction", ListOptions{Limit: 1, Cursor: next, OrderDesc: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := store.List(ctx, "product", tt.opts); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("List error = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+
+	t.Run("deleted record", func(t *testing.T) {
+		if err := store.Delete(ctx, "product", list[0]["id"].(string)); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if _, _, err := store.List(ctx, "product", ListOptions{Limit: 1, Cursor: next}); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("List error = %v, want ErrInvalidCursor", err)
+		}
+	})
+}
+
 // TestConvertValueBool tests convertValue for bool type
 func TestConvertValueBool(t *testing.T) {
 	field := convention.DerivedField{Type: "bool"}
@@ -1004,3 +1215,280 @@ func TestUpdateWithReferences(t *testing.T) {
 		t.Error("Expected error for invalid reference")
 	}
 }
+
+// setupFilterStore creates products named by price for filter tests.
+func setupFilterStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+	store, err := NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	mod := schema.Module{
+		Name: "product",
+		Schema: map[string]schema.Field{
+			"name":  {Type: schema.FieldTypeString},
+			"price": {Type: schema.FieldTypeInt},
+			"token": {Type: schema.FieldTypeString, Internal: true},
+		},
+	}
+	ctx := context.Background()
+	if err := store.CreateTable(ctx, convention.Derive(mod)); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	for _, p := range []struct {
+		name  string
+		price int
+	}{{"apple", 50}, {"banana", 100}, {"cherry", 150}, {"date", 200}} {
+		if _, err := store.Create(ctx, "product", map[string]any{"name": p.name, "price": p.price}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	return store
+}
+
+// TestListWithFilterOperators tests each filter operator
+func TestListWithFilterOperators(t *testing.T) {
+	store := setupFilterStore(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		filters map[string]any
+		want    []string
+	}{
+		{"equality", map[string]any{"name": "banana"}, []string{"banana"}},
+		{"eq", map[string]any{"price": Filter{Op: OpEq, Value: "100"}}, []string{"banana"}},
+		{"ne", map[string]any{"price": Filter{Op: OpNe, Value: 100}}, []string{"apple", "cherry", "date"}},
+		{"gt", map[string]any{"price": Filter{Op: OpGt, Value: "100"}}, []string{"cherry", "date"}},
+		{"gte", map[string]any{"price": Filter{Op: OpGte, Value: "100"}}, []string{"banana", "cherry", "date"}},
+		{"lt", map[string]any{"price": Filter{Op: OpLt, Value: 100}}, []string{"apple"}},
+		{"lte", map[string]any{"price": Filter{Op: OpLte, Value: 100}}, []string{"apple", "banana"}},
+		{"like", map[string]any{"name": Filter{Op: OpLike, Value: "%an%"}}, []string{"banana"}},
+		{"in string", map[string]any{"name": Filter{Op: OpIn, Value: "apple, date"}}, []string{"apple", "date"}},
+		{"in slice", map[string]any{"price": Filter{Op: OpIn, Value: []any{50, 150}}}, []string{"apple", "cherry"}},
+		{"in empty", map[string]any{"name": Filter{Op: OpIn, Value: ""}}, nil},
+		{"range", map[string]any{"price": []Filter{{Op: OpGt, Value: 50}, {Op: OpLt, Value: 200}}}, []string{"banana", "cherry"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, count, err := store.List(ctx, "product", ListOptions{Filters: tt.filters, OrderBy: "price"})
+			if err != nil {
+				t.Fatalf("List failed: %v", err)
+			}
+			var got []string
+			for _, r := range list {
+				got = append(got, r["name"].(string))
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if count != int64(len(tt.want)) {
+				t.Errorf("count = %d, want %d", count, len(tt.want))
+			}
+		})
+	}
+}
+
+// TestListFilterInjection tests that filter input cannot alter the query
+func TestListFilterInjection(t *testing.T) {
+	store := setupFilterStore(t)
+	ctx := context.Background()
+
+	// Values are bound, never interpolated
+	for _, v := range []any{
+		"x' OR '1'='1",
+		Filter{Op: OpLike, Value: "%' OR 1=1 --"},
+		Filter{Op: OpIn, Value: "apple') OR ('1'='1"},
+	} {
+		list, _, err := store.List(ctx, "product", ListOptions{Filters: map[string]any{"name": v}})
+		if err != nil {
+			t.Fatalf("List(%v) failed: %v", v, err)
+		}
+		if len(list) != 0 {
+			t.Errorf("List(%v) returned %d records, want 0", v, len(list))
+		}
+	}
+
+	// Field names and operators must be known
+	for _, filters := range []map[string]any{
+		{"name = name OR 1=1 --": "x"},
+		{"price": Filter{Op: "= 0 OR 1=1 --", Value: 1}},
+		// Internal fields can't be probed with range or pattern comparisons
+		{"token": Filter{Op: OpLike, Value: "a%"}},
+		{"token": Filter{Op: OpGt, Value: "a"}},
+	} {
+		_, _, err := store.List(ctx, "product", ListOptions{Filters: filters})
+		if !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("List(%v) error = %v, want ErrInvalidFilter", filters, err)
+		}
+	}
+
+	// The table survives
+	if _, count, err := store.List(ctx, "product", ListOptions{}); err != nil || count != 4 {
+		t.Errorf("List after injection attempts = %d, %v; want 4 records", count, err)
+	}
+}
+
+func TestParseFilterOp(t *testing.T) {
+	for _, s := range []string{"eq", "ne", "gt", "gte", "lt", "lte", "like", "in", "GTE"} {
+		if _, ok := ParseFilterOp(s); !ok {
+			t.Errorf("ParseFilterOp(%q) not recognized", s)
+		}
+	}
+	for _, s := range []string{"", "between", "=", "gte;"} {
+		if _, ok := ParseFilterOp(s); ok {
+			t.Errorf("ParseFilterOp(%q) should be rejected", s)
+		}
+	}
+}
+
+// setupSearchStore creates a module with searchable title and body fields.
+func setupSearchStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+	store, err := NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	mod := schema.Module{
+		Name: "article",
+		Schema: map[string]schema.Field{
+			"title": {Type: schema.FieldTypeString, Searchable: true},
+			"body":  {Type: schema.FieldTypeString, Searchable: true},
+			"sku":   {Type: schema.FieldTypeString},
+		},
+	}
+	ctx := context.Background()
+	if err := store.CreateTable(ctx, convention.Derive(mod)); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	for _, a := range []map[string]any{
+		{"id": "1", "title": "Red apple", "body": "A crisp red apple from the orchard", "sku": "fruit"},
+		{"id": "2", "title": "Green apple", "body": "Sour and green", "sku": "fruit"},
+		{"id": "3", "title": "Apple pie", "body": "Baked apples with red cinnamon", "sku": "dessert"},
+		{"id": "4", "title": "Banana bread", "body": "Moist bread", "sku": "dessert"},
+	} {
+		if _, err := store.Create(ctx, "article", a); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	return store
+}
+
+func searchIDs(t *testing.T, store *SQLiteStore, opts ListOptions) []string {
+	t.Helper()
+	opts.OrderBy = "id"
+	list, _, err := store.List(context.Background(), "article", opts)
+	if err != nil {
+		t.Fatalf("List(%q) failed: %v", opts.Search, err)
+	}
+	var ids []string
+	for _, r := range list {
+		ids = append(ids, r["id"].(string))
+	}
+	return ids
+}
+
+// TestListWithSearch tests term and phrase search with the store's search
+// backend (FTS5 when compiled in, LIKE otherwise) and with the LIKE fallback.
+func TestListWithSearch(t *testing.T) {
+	for _, backend := range []string{"default", "like"} {
+		t.Run(backend, func(t *testing.T) {
+			store := setupSearchStore(t)
+			if backend == "like" {
+				store.fts["article"] = false
+			}
+
+			tests := []struct {
+				query string
+				want  string
+			}{
+				{"banana", "4"},
+				{"APPLE", "1,2,3"},
+				{"red apple", "1,3"},
+				{`"red apple"`, "1"},
+				{`"crisp red" orchard`, "1"},
+				{"cinnamon", "3"},
+				{"fruit", ""}, // sku is not searchable
+				{"kiwi", ""},
+				{`" OR title:* "`, ""},
+				{"   ", "1,2,3,4"},
+			}
+			for _, tt := range tests {
+				got := strings.Join(searchIDs(t, store, ListOptions{Search: tt.query}), ",")
+				if got != tt.want {
+					t.Errorf("search %q = [%s], want [%s]", tt.query, got, tt.want)
+				}
+			}
+
+			// Search combines with filters
+			got := strings.Join(searchIDs(t, store, ListOptions{Search: "apple", Filters: map[string]any{"sku": "dessert"}}), ",")
+			if got != "3" {
+				t.Errorf("search with filter = [%s], want [3]", got)
+			}
+		})
+	}
+}
+
+// TestSearchIndexTracksChanges tests that updates and deletes are reflected
+func TestSearchIndexTracksChanges(t *testing.T) {
+	store := setupSearchStore(t)
+	ctx := context.Background()
+
+	if err := store.Update(ctx, "article", "4", map[string]any{"title": "Kiwi tart"}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if err := store.Delete(ctx, "article", "2"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	if got := strings.Join(searchIDs(t, store, ListOptions{Search: "kiwi"}), ","); got != "4" {
+		t.Errorf("search kiwi = [%s], want [4]", got)
+	}
+	if got := strings.Join(searchIDs(t, store, ListOptions{Search: "banana"}), ","); got != "" {
+		t.Errorf("search banana = [%s], want []", got)
+	}
+	if got := strings.Join(searchIDs(t, store, ListOptions{Search: "green"}), ","); got != "" {
+		t.Errorf("search green = [%s], want []", got)
+	}
+
+	// Re-registering the module keeps the index usable
+	if err := store.CreateTable(ctx, store.modules["article"]); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	if got := strings.Join(searchIDs(t, store, ListOptions{Search: "apple"}), ","); got != "1,3" {
+		t.Errorf("search apple after re-register = [%s], want [1,3]", got)
+	}
+}
+
+func TestListSearchWithoutSearchableFields(t *testing.T) {
+	store := setupFilterStore(t)
+
+	_, _, err := store.List(context.Background(), "product", ListOptions{Search: "apple"})
+	if !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("List error = %v, want ErrInvalidFilter", err)
+	}
+}
+
+func TestParseSearchTerms(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"red apple", []string{"red", "apple"}},
+		{`"red  apple" pie`, []string{"red apple", "pie"}},
+		{`pie "red apple`, []string{"pie", "red apple"}},
+		{`""`, nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got := parseSearchTerms(tt.query)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("parseSearchTerms(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
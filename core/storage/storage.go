@@ -4,7 +4,10 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
 
 	"github.com/artpar/apigate/core/convention"
@@ -31,10 +34,72 @@ type Store interface {
 	// Delete removes a record.
 	Delete(ctx context.Context, module string, id string) error
 
+	// WithTransaction runs fn in a transaction, committing if fn returns nil
+	// and rolling back otherwise. Within fn, use tx rather than the store.
+	WithTransaction(ctx context.Context, fn func(tx Tx) error) error
+
 	// Close closes the storage connection.
 	Close() error
 }
 
+// ErrConflict is returned by Update when the record is no longer at the
+// version the update expected.
+var ErrConflict = errors.New("version conflict")
+
+// VersionField is the implicit field counting a record's updates. An update
+// that includes it applies only if the record is still at that version, so
+// concurrent writers cannot silently overwrite each other.
+const VersionField = "version"
+
+// hasVersion reports whether a module has the implicit version field.
+func hasVersion(mod convention.Derived) bool {
+	for _, f := range mod.Fields {
+		if f.Name == VersionField {
+			return f.Implicit
+		}
+	}
+	return false
+}
+
+// expectedVersion returns the version an update is conditional on, if any.
+func expectedVersion(mod convention.Derived, data map[string]any) (int64, bool, error) {
+	v, ok := data[VersionField]
+	if !ok || v == nil || !hasVersion(mod) {
+		return 0, false, nil
+	}
+	switch n := v.(type) {
+	case int:
+		return int64(n), true, nil
+	case int64:
+		return n, true, nil
+	case float64:
+		if n == math.Trunc(n) {
+			return int64(n), true, nil
+		}
+	case string:
+		if i, err := strconv.ParseInt(n, 10, 64); err == nil {
+			return i, true, nil
+		}
+	}
+	return 0, false, fmt.Errorf("invalid %s: %v", VersionField, v)
+}
+
+// Open creates a store for a driver: "sqlite" (the default) takes a file
+// path, "postgres" a connection URL or DSN.
+func Open(driver, dsn string) (Store, error) {
+	switch driver {
+	case "", "sqlite", "sqlite3":
+		return NewSQLiteStore(dsn)
+	case "postgres", "postgresql":
+		return NewPostgresStore(dsn)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q (want sqlite or postgres)", driver)
+	}
+}
+
+// DefaultListLimit is the page size used when ListOptions.Limit is not set.
+const DefaultListLimit = 100
+
 // ListOptions configures list queries.
 type ListOptions struct {
 	// Limit is the maximum number of records to return.
@@ -43,9 +108,18 @@ type ListOptions struct {
 	// Offset is the number of records to skip.
 	Offset int
 
-	// Filters are field-value pairs to filter by.
+	// Cursor resumes after the last record of a previous page (see NextCursor).
+	// When set, Offset is ignored.
+	Cursor string
+
+	// Filters are field-value pairs to filter by. A value of type Filter or
+	// []Filter compares with an operator; any other value must be equal.
 	Filters map[string]any
 
+	// Search is a full-text query over the module's searchable fields. Words
+	// and "quoted phrases" must all match.
+	Search string
+
 	// OrderBy is the field to sort by.
 	OrderBy string
 
@@ -66,11 +140,17 @@ type ColumnDef struct {
 
 // BuildCreateTableSQL generates CREATE TABLE SQL from a derived module.
 func BuildCreateTableSQL(mod convention.Derived) string {
+	return buildCreateTableSQL(mod, buildColumnDef)
+}
+
+// buildCreateTableSQL generates CREATE TABLE SQL using a backend's column
+// definitions. Constraints are portable SQL.
+func buildCreateTableSQL(mod convention.Derived, columnDef func(convention.DerivedField) string) string {
 	var columns []string
 	var constraints []string
 
 	for _, f := range mod.Fields {
-		col := buildColumnDef(f)
+		col := columnDef(f)
 		columns = append(columns, col)
 
 		if f.Unique && f.Name != "id" {
@@ -89,15 +169,8 @@ func BuildCreateTableSQL(mod convention.Derived) string {
 		constraints = append(constraints, checkConstraints...)
 
 		// Add enum CHECK constraint
-		if f.Type == schema.FieldTypeEnum && len(f.Values) > 0 {
-			values := make([]string, len(f.Values))
-			for i, v := range f.Values {
-				values[i] = fmt.Sprintf("'%s'", strings.ReplaceAll(v, "'", "''"))
-			}
-			constraints = append(constraints, fmt.Sprintf(
-				"CHECK(%s IN (%s))",
-				f.Name, strings.Join(values, ", "),
-			))
+		if check := buildEnumCheck(f); check != "" {
+			constraints = append(constraints, check)
 		}
 	}
 
@@ -148,14 +221,27 @@ func buildCheckConstraints(f convention.DerivedField) []string {
 				}
 				checks = append(checks, fmt.Sprintf("CHECK(%s IN (%s))", f.Name, strings.Join(quotedValues, ", ")))
 			}
-		// Note: pattern constraints require regex support which SQLite doesn't have natively
-		// We rely on application-level validation for patterns
+			// Note: pattern constraints require regex support which SQLite doesn't have natively
+			// We rely on application-level validation for patterns
 		}
 	}
 
 	return checks
 }
 
+// buildEnumCheck generates the CHECK constraint limiting an enum field to its
+// values, or "" for other fields.
+func buildEnumCheck(f convention.DerivedField) string {
+	if f.Type != schema.FieldTypeEnum || len(f.Values) == 0 {
+		return ""
+	}
+	values := make([]string, len(f.Values))
+	for i, v := range f.Values {
+		values[i] = fmt.Sprintf("'%s'", strings.ReplaceAll(v, "'", "''"))
+	}
+	return fmt.Sprintf("CHECK(%s IN (%s))", f.Name, strings.Join(values, ", "))
+}
+
 // getNumericValue extracts a numeric value from an interface.
 func getNumericValue(v any) (float64, bool) {
 	switch val := v.(type) {
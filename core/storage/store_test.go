@@ -0,0 +1,548 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/core/convention"
+	"github.com/artpar/apigate/core/schema"
+)
+
+// postgresDSNEnv names the database the Postgres backend tests run against.
+// Each test works in its own schema, dropped afterwards.
+const postgresDSNEnv = "APIGATE_TEST_POSTGRES_DSN"
+
+// forEachBackend runs a test against every Store implementation. Postgres is
+// skipped unless APIGATE_TEST_POSTGRES_DSN is set.
+func forEachBackend(t *testing.T, test func(t *testing.T, store Store)) {
+	t.Run("sqlite", func(t *testing.T) {
+		store, err := NewSQLiteStore(":memory:")
+		if err != nil {
+			t.Fatalf("NewSQLiteStore failed: %v", err)
+		}
+		defer store.Close()
+		test(t, store)
+	})
+
+	t.Run("postgres", func(t *testing.T) {
+		dsn := os.Getenv(postgresDSNEnv)
+		if dsn == "" {
+			t.Skipf("%s not set", postgresDSNEnv)
+		}
+		store, err := NewPostgresStore(dsn)
+		if err != nil {
+			t.Fatalf("NewPostgresStore failed: %v", err)
+		}
+		defer store.Close()
+
+		// One connection so search_path applies to every query
+		db := store.DB()
+		db.SetMaxOpenConns(1)
+		schemaName := fmt.Sprintf("apigate_test_%d", time.Now().UnixNano())
+		if _, err := db.Exec("CREATE SCHEMA " + schemaName); err != nil {
+			t.Fatalf("create schema: %v", err)
+		}
+		defer db.Exec("DROP SCHEMA " + schemaName + " CASCADE")
+		if _, err := db.Exec("SET search_path TO " + schemaName); err != nil {
+			t.Fatalf("set search_path: %v", err)
+		}
+		test(t, store)
+	})
+}
+
+// backendModule covers every stored field type.
+func backendModule() convention.Derived {
+	return convention.Derive(schema.Module{
+		Name: "widget",
+		Schema: map[string]schema.Field{
+			"name":    {Type: schema.FieldTypeString, Lookup: true, Searchable: true},
+			"price":   {Type: schema.FieldTypeInt},
+			"weight":  {Type: schema.FieldTypeFloat},
+			"active":  {Type: schema.FieldTypeBool, Default: true},
+			"status":  {Type: schema.FieldTypeEnum, Values: []string{"draft", "live"}, Default: "draft"},
+			"tags":    {Type: schema.FieldTypeStrings},
+			"config":  {Type: schema.FieldTypeJSON},
+			"secret":  {Type: schema.FieldTypeSecret},
+			"release": {Type: schema.FieldTypeTimestamp},
+		},
+	})
+}
+
+func TestStoreBackends_CRUD(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		if err := store.CreateTable(ctx, backendModule()); err != nil {
+			t.Fatalf("CreateTable failed: %v", err)
+		}
+		// CreateTable is idempotent
+		if err := store.CreateTable(ctx, backendModule()); err != nil {
+			t.Fatalf("second CreateTable failed: %v", err)
+		}
+
+		id, err := store.Create(ctx, "widget", map[string]any{
+			"name":    "gear",
+			"price":   250,
+			"weight":  1.5,
+			"tags":    `["a","b"]`,
+			"config":  `{"size":3}`,
+			"secret":  "hash",
+			"release": "2025-03-01 10:00:00",
+		})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+
+		got, err := store.Get(ctx, "widget", "name", "gear")
+		if err != nil || got == nil {
+			t.Fatalf("Get = %v, %v", got, err)
+		}
+		if got["id"] != id {
+			t.Errorf("id = %v, want %s", got["id"], id)
+		}
+		if got["price"] != int64(250) {
+			t.Errorf("price = %#v, want int64(250)", got["price"])
+		}
+		if got["weight"] != 1.5 {
+			t.Errorf("weight = %#v, want 1.5", got["weight"])
+		}
+		if got["active"] != true {
+			t.Errorf("active = %#v, want default true", got["active"])
+		}
+		if got["status"] != "draft" {
+			t.Errorf("status = %#v, want default draft", got["status"])
+		}
+		if s, _ := got["config"].(string); strings.ReplaceAll(s, " ", "") != `{"size":3}` {
+			t.Errorf("config = %#v", got["config"])
+		}
+		if b, _ := got["secret"].([]byte); string(b) != "hash" {
+			t.Errorf("secret = %#v, want []byte(hash)", got["secret"])
+		}
+		if s, _ := got["release"].(string); !strings.HasPrefix(s, "2025-03-01") {
+			t.Errorf("release = %#v", got["release"])
+		}
+		if got["created_at"] == nil {
+			t.Error("created_at should be set by the database")
+		}
+
+		if err := store.Update(ctx, "widget", id, map[string]any{"active": false, "price": 300}); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+		got, _ = store.Get(ctx, "widget", "id", id)
+		if got["active"] != false || got["price"] != int64(300) {
+			t.Errorf("after update active = %#v, price = %#v", got["active"], got["price"])
+		}
+
+		if err := store.Update(ctx, "widget", "missing", map[string]any{"price": 1}); err == nil {
+			t.Error("Update of a missing record should fail")
+		}
+		if err := store.Delete(ctx, "widget", id); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if got, _ := store.Get(ctx, "widget", "id", id); got != nil {
+			t.Errorf("Get after delete = %v, want nil", got)
+		}
+		if err := store.Delete(ctx, "widget", id); err == nil {
+			t.Error("second Delete should fail")
+		}
+	})
+}
+
+func TestStoreBackends_List(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		if err := store.CreateTable(ctx, backendModule()); err != nil {
+			t.Fatalf("CreateTable failed: %v", err)
+		}
+		for i, name := range []string{"alpha gear", "beta gear", "gamma spring", "delta spring", "epsilon"} {
+			if _, err := store.Create(ctx, "widget", map[string]any{
+				"id":     fmt.Sprintf("w%d", i),
+				"name":   name,
+				"price":  (i + 1) * 100,
+				"active": i%2 == 0,
+			}); err != nil {
+				t.Fatalf("Create failed: %v", err)
+			}
+		}
+
+		names := func(opts ListOptions) string {
+			t.Helper()
+			list, _, err := store.List(ctx, "widget", opts)
+			if err != nil {
+				t.Fatalf("List(%+v) failed: %v", opts, err)
+			}
+			var out []string
+			for _, r := range list {
+				out = append(out, strings.Fields(r["name"].(string))[0])
+			}
+			return strings.Join(out, ",")
+		}
+
+		tests := []struct {
+			name string
+			opts ListOptions
+			want string
+		}{
+			{"order desc", ListOptions{OrderBy: "price", OrderDesc: true, Limit: 2}, "epsilon,delta"},
+			{"offset", ListOptions{OrderBy: "price", Limit: 2, Offset: 3}, "delta,epsilon"},
+			{"bool filter", ListOptions{OrderBy: "price", Filters: map[string]any{"active": "true"}}, "alpha,gamma,epsilon"},
+			{"range", ListOptions{OrderBy: "price", Filters: map[string]any{"price": []Filter{{Op: OpGt, Value: "100"}, {Op: OpLte, Value: 400}}}}, "beta,gamma,delta"},
+			{"in", ListOptions{OrderBy: "price", Filters: map[string]any{"price": Filter{Op: OpIn, Value: "100,500"}}}, "alpha,epsilon"},
+			{"like", ListOptions{OrderBy: "price", Filters: map[string]any{"name": Filter{Op: OpLike, Value: "%spring"}}}, "gamma,delta"},
+			{"search", ListOptions{OrderBy: "price", Search: "gear"}, "alpha,beta"},
+			{"phrase", ListOptions{OrderBy: "price", Search: `"delta spring"`}, "delta"},
+			{"injection", ListOptions{Filters: map[string]any{"name": "x' OR '1'='1"}}, ""},
+		}
+		for _, tt := range tests {
+			if got := names(tt.opts); got != tt.want {
+				t.Errorf("%s: got [%s], want [%s]", tt.name, got, tt.want)
+			}
+		}
+
+		// Walk the table with cursors
+		var walked []string
+		opts := ListOptions{OrderBy: "price", OrderDesc: true, Limit: 2}
+		for pages := 0; pages < 5; pages++ {
+			list, total, err := store.List(ctx, "widget", opts)
+			if err != nil {
+				t.Fatalf("List failed: %v", err)
+			}
+			if total != 5 {
+				t.Errorf("total = %d, want 5", total)
+			}
+			for _, r := range list {
+				walked = append(walked, r["id"].(string))
+			}
+			if opts.Cursor = NextCursor(list, opts); opts.Cursor == "" {
+				break
+			}
+		}
+		if got := strings.Join(walked, ","); got != "w4,w3,w2,w1,w0" {
+			t.Errorf("cursor walk = %s", got)
+		}
+	})
+}
+
+// migrationModule returns a version of the gadget module with the given fields
+// besides name.
+func migrationModule(fields map[string]schema.Field) convention.Derived {
+	s := map[string]schema.Field{"name": {Type: schema.FieldTypeString, Lookup: true, Searchable: true}}
+	for name, f := range fields {
+		s[name] = f
+	}
+	return convention.Derive(schema.Module{Name: "gadget", Schema: s})
+}
+
+func TestStoreBackends_Migrations(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		migrator := store.(Migrator)
+		required := true
+
+		if err := store.CreateTable(ctx, migrationModule(nil)); err != nil {
+			t.Fatalf("CreateTable failed: %v", err)
+		}
+		id, err := store.Create(ctx, "gadget", map[string]any{"name": "old"})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+
+		// Adding fields adds columns, filling existing rows with defaults
+		v2 := migrationModule(map[string]schema.Field{
+			"stock": {Type: schema.FieldTypeInt, Default: 5, Required: &required},
+			"label": {Type: schema.FieldTypeString},
+			"state": {Type: schema.FieldTypeEnum, Values: []string{"on", "off"}, Default: "on"},
+		})
+		if err := store.CreateTable(ctx, v2); err != nil {
+			t.Fatalf("CreateTable with new fields failed: %v", err)
+		}
+		got, err := store.Get(ctx, "gadget", "id", id)
+		if err != nil || got == nil {
+			t.Fatalf("Get = %v, %v", got, err)
+		}
+		if got["stock"] != int64(5) {
+			t.Errorf("stock = %#v, want default 5", got["stock"])
+		}
+		if got["state"] != "on" {
+			t.Errorf("state = %#v, want default on", got["state"])
+		}
+		if got["label"] != nil {
+			t.Errorf("label = %#v, want nil", got["label"])
+		}
+		if _, err := store.Create(ctx, "gadget", map[string]any{"name": "bad", "state": "broken"}); err == nil {
+			t.Error("added enum column should keep its CHECK constraint")
+		}
+
+		// Re-registering an unchanged module records nothing
+		if err := store.CreateTable(ctx, v2); err != nil {
+			t.Fatalf("CreateTable unchanged failed: %v", err)
+		}
+		migrations, err := migrator.Migrations(ctx, "gadget")
+		if err != nil {
+			t.Fatalf("Migrations failed: %v", err)
+		}
+		if len(migrations) != 2 {
+			t.Fatalf("got %d migrations, want 2: %+v", len(migrations), migrations)
+		}
+		if m := migrations[1]; m.Version != 2 || len(m.Statements) != 3 ||
+			!strings.HasPrefix(m.Statements[0], "ALTER TABLE gadgets ADD COLUMN") {
+			t.Errorf("migration 2 = %+v", m)
+		}
+
+		// A required field needs a default for existing rows
+		err = store.CreateTable(ctx, migrationModule(map[string]schema.Field{
+			"stock": {Type: schema.FieldTypeInt, Default: 5, Required: &required},
+			"label": {Type: schema.FieldTypeString},
+			"state": {Type: schema.FieldTypeEnum, Values: []string{"on", "off"}, Default: "on"},
+			"sku":   {Type: schema.FieldTypeString, Required: &required},
+		}))
+		if err == nil {
+			t.Error("adding a required field without a default should fail")
+		}
+
+		// Dropping fields needs the explicit flag
+		v3 := migrationModule(map[string]schema.Field{"stock": {Type: schema.FieldTypeInt, Default: 5}})
+		if err := store.CreateTable(ctx, v3); !errors.Is(err, ErrDestructiveMigration) {
+			t.Fatalf("CreateTable dropping fields = %v, want ErrDestructiveMigration", err)
+		}
+		if got, _ := store.Get(ctx, "gadget", "id", id); got == nil {
+			t.Fatal("rejected migration should leave the record")
+		}
+
+		migrator.SetAllowDestructiveMigrations(true)
+		if err := store.CreateTable(ctx, v3); err != nil {
+			t.Fatalf("CreateTable dropping fields with flag failed: %v", err)
+		}
+		got, _ = store.Get(ctx, "gadget", "id", id)
+		if _, ok := got["label"]; ok {
+			t.Errorf("label should be dropped: %v", got)
+		}
+		if got["name"] != "old" || got["stock"] != int64(5) {
+			t.Errorf("after drop = %v", got)
+		}
+		if list, _, err := store.List(ctx, "gadget", ListOptions{Search: "old"}); err != nil || len(list) != 1 {
+			t.Errorf("search after drop = %v, %v", list, err)
+		}
+		migrations, _ = migrator.Migrations(ctx, "gadget")
+		if len(migrations) != 3 || strings.Join(migrations[2].Fields, ",") != "created_at,id,name,stock,updated_at,version" {
+			t.Errorf("migrations after drop = %+v", migrations)
+		}
+	})
+}
+
+func TestStoreBackends_MigrationKeepsUndeclaredColumns(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+
+		// A table the module shares with other code, created outside it
+		db := store.(interface{ DB() *sql.DB }).DB()
+		if _, err := db.Exec("CREATE TABLE gadgets (id TEXT PRIMARY KEY, name TEXT, internal TEXT, created_at TIMESTAMP, updated_at TIMESTAMP, version INTEGER)"); err != nil {
+			t.Fatalf("create table: %v", err)
+		}
+		if err := store.CreateTable(ctx, migrationModule(nil)); err != nil {
+			t.Fatalf("CreateTable over existing table failed: %v", err)
+		}
+
+		migrations, err := store.(Migrator).Migrations(ctx, "gadget")
+		if err != nil {
+			t.Fatalf("Migrations failed: %v", err)
+		}
+		if len(migrations) != 1 || len(migrations[0].Statements) != 0 {
+			t.Errorf("want one baseline migration, got %+v", migrations)
+		}
+	})
+}
+
+func TestStoreBackends_Transactions(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		required := true
+		for _, mod := range []schema.Module{
+			{Name: "category", Schema: map[string]schema.Field{
+				"name": {Type: schema.FieldTypeString, Lookup: true},
+			}},
+			{Name: "product", Schema: map[string]schema.Field{
+				"name":     {Type: schema.FieldTypeString, Lookup: true},
+				"price":    {Type: schema.FieldTypeInt, Required: &required},
+				"category": {Type: schema.FieldTypeRef, To: "category"},
+			}},
+		} {
+			if err := store.CreateTable(ctx, convention.Derive(mod)); err != nil {
+				t.Fatalf("CreateTable %s failed: %v", mod.Name, err)
+			}
+		}
+		existing, err := store.Create(ctx, "product", map[string]any{"name": "existing", "price": 1})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+
+		// Committed: the product may reference the category created with it
+		err = store.WithTransaction(ctx, func(tx Tx) error {
+			catID, err := tx.Create(ctx, "category", map[string]any{"name": "tools"})
+			if err != nil {
+				return err
+			}
+			if got, err := tx.Get(ctx, "category", "id", catID); err != nil || got == nil {
+				return fmt.Errorf("read own write: %v, %v", got, err)
+			}
+			_, err = tx.Create(ctx, "product", map[string]any{"name": "hammer", "price": 10, "category": catID})
+			return err
+		})
+		if err != nil {
+			t.Fatalf("WithTransaction failed: %v", err)
+		}
+		if got, _ := store.Get(ctx, "product", "name", "hammer"); got == nil {
+			t.Error("committed product missing")
+		}
+
+		// A failing statement mid-transaction rolls back earlier writes
+		err = store.WithTransaction(ctx, func(tx Tx) error {
+			if _, err := tx.Create(ctx, "category", map[string]any{"name": "garden"}); err != nil {
+				return err
+			}
+			if err := tx.Update(ctx, "product", existing, map[string]any{"price": 99}); err != nil {
+				return err
+			}
+			_, err := tx.Create(ctx, "product", map[string]any{"name": "rake"}) // missing price
+			return err
+		})
+		if err == nil {
+			t.Fatal("WithTransaction should return the failing statement's error")
+		}
+		if got, _ := store.Get(ctx, "category", "name", "garden"); got != nil {
+			t.Errorf("category from failed transaction persisted: %v", got)
+		}
+		if got, _ := store.Get(ctx, "product", "id", existing); got["price"] != int64(1) {
+			t.Errorf("update from failed transaction persisted: price = %#v", got["price"])
+		}
+
+		// Returning an error rolls back too, and the error is passed through
+		errAbort := errors.New("abort")
+		err = store.WithTransaction(ctx, func(tx Tx) error {
+			if _, err := tx.Create(ctx, "category", map[string]any{"name": "kitchen"}); err != nil {
+				return err
+			}
+			if err := tx.Delete(ctx, "product", existing); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		if !errors.Is(err, errAbort) {
+			t.Fatalf("WithTransaction = %v, want errAbort", err)
+		}
+		if got, _ := store.Get(ctx, "category", "name", "kitchen"); got != nil {
+			t.Errorf("category from aborted transaction persisted: %v", got)
+		}
+		if got, _ := store.Get(ctx, "product", "id", existing); got == nil {
+			t.Error("delete from aborted transaction persisted")
+		}
+
+		// The store stays usable after a rollback
+		if _, err := store.Create(ctx, "category", map[string]any{"name": "after"}); err != nil {
+			t.Errorf("Create after rollback failed: %v", err)
+		}
+	})
+}
+
+func TestStoreBackends_OptimisticConcurrency(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		if err := store.CreateTable(ctx, migrationModule(nil)); err != nil {
+			t.Fatalf("CreateTable failed: %v", err)
+		}
+		id, err := store.Create(ctx, "gadget", map[string]any{"name": "first", "version": 7})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		got, _ := store.Get(ctx, "gadget", "id", id)
+		if got["version"] != int64(1) {
+			t.Fatalf("version after create = %#v, want 1", got["version"])
+		}
+
+		// Both tabs loaded version 1; the first save wins
+		if err := store.Update(ctx, "gadget", id, map[string]any{"name": "tab one", "version": 1}); err != nil {
+			t.Fatalf("Update at current version failed: %v", err)
+		}
+		err = store.Update(ctx, "gadget", id, map[string]any{"name": "tab two", "version": float64(1)})
+		if !errors.Is(err, ErrConflict) {
+			t.Fatalf("stale Update = %v, want ErrConflict", err)
+		}
+		got, _ = store.Get(ctx, "gadget", "id", id)
+		if got["name"] != "tab one" || got["version"] != int64(2) {
+			t.Errorf("after stale update name = %#v, version = %#v", got["name"], got["version"])
+		}
+
+		// Unconditional updates still apply, and bump the version
+		if err := store.Update(ctx, "gadget", id, map[string]any{"name": "forced"}); err != nil {
+			t.Fatalf("unconditional Update failed: %v", err)
+		}
+		if err := store.Update(ctx, "gadget", id, map[string]any{"name": "retry", "version": "3"}); err != nil {
+			t.Errorf("Update at refreshed version failed: %v", err)
+		}
+
+		if err := store.Update(ctx, "gadget", "missing", map[string]any{"name": "x", "version": 1}); err == nil || errors.Is(err, ErrConflict) {
+			t.Errorf("Update of missing record = %v, want not found", err)
+		}
+		if err := store.Update(ctx, "gadget", id, map[string]any{"name": "x", "version": "abc"}); err == nil {
+			t.Error("Update with an invalid version should fail")
+		}
+	})
+}
+
+func TestBuildPostgresCreateTableSQL(t *testing.T) {
+	sql := BuildPostgresCreateTableSQL(backendModule())
+
+	for _, want := range []string{
+		"CREATE TABLE IF NOT EXISTS widgets",
+		"id TEXT PRIMARY KEY",
+		"price BIGINT",
+		"weight DOUBLE PRECISION",
+		"active BOOLEAN DEFAULT TRUE",
+		"status TEXT DEFAULT 'draft'",
+		"tags JSONB",
+		"config JSONB",
+		"secret BYTEA",
+		"release TIMESTAMPTZ",
+		"created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP",
+		"CHECK(status IN ('draft', 'live'))",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("SQL missing %q:\n%s", want, sql)
+		}
+	}
+}
+
+func TestDialectBind(t *testing.T) {
+	query := "SELECT a FROM t WHERE a = ? AND b IN (?, ?) AND c LIKE ? ESCAPE '\\'"
+	if got := (dialect{}).bind(query); got != query {
+		t.Errorf("sqlite bind = %q", got)
+	}
+	want := "SELECT a FROM t WHERE a = $1 AND b IN ($2, $3) AND c LIKE $4 ESCAPE '\\'"
+	if got := pgDialect.bind(query); got != want {
+		t.Errorf("postgres bind = %q, want %q", got, want)
+	}
+
+	if got := pgDialect.orderClause("price", "ASC"); got != "price ASC NULLS FIRST" {
+		t.Errorf("orderClause ASC = %q", got)
+	}
+	if got := pgDialect.orderClause("price", "DESC"); got != "price DESC NULLS LAST" {
+		t.Errorf("orderClause DESC = %q", got)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	store, err := Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Open(sqlite) failed: %v", err)
+	}
+	store.Close()
+
+	if _, err := Open("mysql", "dsn"); err == nil {
+		t.Error("Open(mysql) should fail")
+	}
+}
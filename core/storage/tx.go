@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Tx performs record operations within a transaction started by
+// Store.WithTransaction. Its changes are visible to its own reads, and to
+// everyone else once the transaction commits.
+type Tx interface {
+	// Create inserts a new record.
+	Create(ctx context.Context, module string, data map[string]any) (string, error)
+
+	// Get retrieves a record by lookup field.
+	Get(ctx context.Context, module string, lookup string, value string) (map[string]any, error)
+
+	// Update modifies an existing record.
+	Update(ctx context.Context, module string, id string, data map[string]any) error
+
+	// Delete removes a record.
+	Delete(ctx context.Context, module string, id string) error
+}
+
+// queryer runs statements on a connection pool or in a transaction.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// runTx runs fn in a transaction on db, committing if it returns nil and
+// rolling back if it returns an error or panics.
+func runTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+// Package anomaly provides pure functions for detecting usage spikes.
+// All functions are deterministic with no side effects.
+package anomaly
+
+import "time"
+
+// Config controls spike detection for a key (value type).
+type Config struct {
+	Window      time.Duration // Recent period whose rate is checked
+	Baseline    time.Duration // Trailing period before the window that sets the key's normal rate
+	Threshold   float64       // Recent rate must exceed the baseline rate by this factor (0 = disabled)
+	MinRequests int64         // Requests in the window before a spike is considered
+}
+
+// Enabled reports whether the config detects spikes at all.
+// This is a PURE function.
+func (c Config) Enabled() bool {
+	return c.Threshold > 0 && c.Window > 0 && c.Baseline > 0
+}
+
+// Result describes a key's recent rate compared with its baseline (value type).
+type Result struct {
+	Spike        bool
+	RecentRate   float64 // Requests per minute in the window
+	BaselineRate float64 // Requests per minute in the baseline period
+	Factor       float64 // RecentRate / BaselineRate
+}
+
+// Detect compares the requests counted in the recent window with those counted
+// in the baseline period before it. A baseline with no requests is treated as
+// one request over the period, so a dormant key that suddenly bursts is
+// reported once it reaches MinRequests.
+// This is a PURE function.
+func Detect(recent, baseline int64, cfg Config) Result {
+	if !cfg.Enabled() {
+		return Result{}
+	}
+	if baseline < 1 {
+		baseline = 1
+	}
+
+	r := Result{
+		RecentRate:   float64(recent) / cfg.Window.Minutes(),
+		BaselineRate: float64(baseline) / cfg.Baseline.Minutes(),
+	}
+	r.Factor = r.RecentRate / r.BaselineRate
+	r.Spike = recent >= cfg.MinRequests && r.Factor > cfg.Threshold
+	return r
+}
@@ -0,0 +1,48 @@
+package anomaly
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDetect(t *testing.T) {
+	cfg := Config{Window: 5 * time.Minute, Baseline: time.Hour, Threshold: 5, MinRequests: 50}
+
+	tests := []struct {
+		name     string
+		recent   int64
+		baseline int64
+		want     bool
+	}{
+		{"steady rate", 50, 600, false},        // 10/min vs 10/min
+		{"below threshold", 200, 600, false},   // 40/min vs 10/min = 4x
+		{"spike", 300, 600, true},              // 60/min vs 10/min = 6x
+		{"exactly threshold", 250, 600, false}, // 5x is not more than 5x
+		{"too few requests", 40, 6, false},     // 8/min vs 0.1/min, but under MinRequests
+		{"dormant key bursts", 100, 0, true},   // idle baseline counts as one request
+		{"dormant key quiet", 10, 0, false},    // under MinRequests
+		{"high volume steady", 5000, 60000, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Detect(tt.recent, tt.baseline, cfg)
+			if r.Spike != tt.want {
+				t.Errorf("Spike = %v (factor %.2f), want %v", r.Spike, r.Factor, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetect_Rates(t *testing.T) {
+	r := Detect(300, 600, Config{Window: 5 * time.Minute, Baseline: time.Hour, Threshold: 5})
+	if r.RecentRate != 60 || r.BaselineRate != 10 || r.Factor != 6 {
+		t.Errorf("rates = %v/%v factor %v, want 60/10 factor 6", r.RecentRate, r.BaselineRate, r.Factor)
+	}
+}
+
+func TestDetect_Disabled(t *testing.T) {
+	r := Detect(1000, 0, Config{Window: 5 * time.Minute, Baseline: time.Hour})
+	if r.Spike || r.Factor != 0 {
+		t.Errorf("disabled config returned %+v", r)
+	}
+}
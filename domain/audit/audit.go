@@ -0,0 +1,120 @@
+// Package audit provides the append-only log of admin actions.
+// This is a pure package with no I/O dependencies.
+package audit
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+// Actions recorded in the audit log.
+const (
+	ActionCreate      = "create"
+	ActionUpdate      = "update"
+	ActionDelete      = "delete"
+	ActionRestore     = "restore"
+	ActionRevoke      = "revoke"
+	ActionRotate      = "rotate"
+	ActionImport      = "import"
+	ActionImpersonate = "impersonate"
+)
+
+// Resource types recorded in the audit log.
+const (
+	ResourceUser = "user"
+	ResourceKey  = "key"
+	ResourcePlan = "plan"
+)
+
+// redactedFields are never stored in snapshots.
+var redactedFields = []string{"PasswordHash", "Hash", "SigningSecret", "TOTPSecret", "RecoveryCodes"}
+
+// Entry is a single admin action.
+type Entry struct {
+	ID           string
+	ActorID      string // Admin who performed the action
+	Action       string
+	ResourceType string
+	ResourceID   string
+	Before       map[string]any    // State before the action; nil for creates
+	After        map[string]any    // State after the action; nil for deletes
+	Changes      map[string]Change // Fields that differ between Before and After
+	CreatedAt    time.Time
+}
+
+// Change is the before and after value of a single field.
+type Change struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// Filter selects audit entries. Empty fields match everything.
+type Filter struct {
+	ActorID      string
+	ResourceType string
+	ResourceID   string
+	Limit        int
+	Offset       int
+}
+
+// NewEntry creates an audit entry for an action, computing the changes
+// between before and after.
+func NewEntry(actorID, action, resourceType, resourceID string, before, after map[string]any, now time.Time) Entry {
+	return Entry{
+		ID:           NewID(),
+		ActorID:      actorID,
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Before:       before,
+		After:        after,
+		Changes:      Diff(before, after),
+		CreatedAt:    now,
+	}
+}
+
+// NewID generates a unique audit entry ID.
+func NewID() string {
+	idBytes := make([]byte, 8)
+	rand.Read(idBytes)
+	return "aud_" + hex.EncodeToString(idBytes)
+}
+
+// Snapshot converts a value to a field map for the audit log. Secrets such as
+// password and key hashes are removed. Values are normalized through JSON so
+// snapshots compare the same before and after storage.
+func Snapshot(v any) map[string]any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil
+	}
+	for _, field := range redactedFields {
+		delete(m, field)
+	}
+	return m
+}
+
+// Diff returns the fields whose values differ between before and after.
+// Fields missing on one side are reported with a nil value.
+func Diff(before, after map[string]any) map[string]Change {
+	changes := make(map[string]Change)
+	for field, b := range before {
+		a, ok := after[field]
+		if !ok || !reflect.DeepEqual(a, b) {
+			changes[field] = Change{Before: b, After: a}
+		}
+	}
+	for field, a := range after {
+		if _, ok := before[field]; !ok {
+			changes[field] = Change{After: a}
+		}
+	}
+	return changes
+}
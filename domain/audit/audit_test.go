@@ -0,0 +1,86 @@
+package audit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/domain/audit"
+	"github.com/artpar/apigate/ports"
+)
+
+func TestSnapshot_RedactsSecrets(t *testing.T) {
+	type account struct {
+		Email        string
+		PasswordHash []byte
+		Limit        int
+	}
+
+	snap := audit.Snapshot(account{Email: "a@example.com", PasswordHash: []byte("secret"), Limit: 10})
+
+	if _, ok := snap["PasswordHash"]; ok {
+		t.Error("snapshot should not contain PasswordHash")
+	}
+	if snap["Email"] != "a@example.com" {
+		t.Errorf("Email = %v", snap["Email"])
+	}
+	// Numbers are normalized through JSON
+	if snap["Limit"] != float64(10) {
+		t.Errorf("Limit = %#v, want float64(10)", snap["Limit"])
+	}
+}
+
+func TestSnapshot_RedactsTwoFactorSecrets(t *testing.T) {
+	user := ports.User{
+		ID:            "u1",
+		Email:         "a@example.com",
+		TOTPSecret:    "JBSWY3DPEHPK3PXP",
+		TOTPEnabled:   true,
+		RecoveryCodes: []string{"5e884898da28047151d0e56f8dc6292773603d0d6aabbdd62a11ef721d1542d8"},
+	}
+
+	snap := audit.Snapshot(user)
+
+	for _, field := range []string{"TOTPSecret", "RecoveryCodes", "PasswordHash"} {
+		if _, ok := snap[field]; ok {
+			t.Errorf("snapshot should not contain %s", field)
+		}
+	}
+	if snap["TOTPEnabled"] != true {
+		t.Errorf("TOTPEnabled = %v, want true", snap["TOTPEnabled"])
+	}
+}
+
+func TestDiff(t *testing.T) {
+	before := map[string]any{"name": "Pro", "limit": float64(60), "removed": true}
+	after := map[string]any{"name": "Pro", "limit": float64(600), "added": "x"}
+
+	changes := audit.Diff(before, after)
+
+	if len(changes) != 3 {
+		t.Fatalf("changes = %v, want 3 fields", changes)
+	}
+	if c := changes["limit"]; c.Before != float64(60) || c.After != float64(600) {
+		t.Errorf("limit change = %+v", c)
+	}
+	if c := changes["removed"]; c.Before != true || c.After != nil {
+		t.Errorf("removed change = %+v", c)
+	}
+	if c := changes["added"]; c.Before != nil || c.After != "x" {
+		t.Errorf("added change = %+v", c)
+	}
+	if _, ok := changes["name"]; ok {
+		t.Error("unchanged field should not be reported")
+	}
+}
+
+func TestNewEntry(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	e := audit.NewEntry("admin1", audit.ActionCreate, audit.ResourcePlan, "pro", nil, map[string]any{"name": "Pro"}, now)
+
+	if e.ID == "" || e.CreatedAt != now {
+		t.Errorf("entry = %+v", e)
+	}
+	if e.Changes["name"].After != "Pro" {
+		t.Errorf("create should report all fields as changes, got %v", e.Changes)
+	}
+}
@@ -18,6 +18,7 @@ type TokenType string
 const (
 	TokenTypeEmailVerification TokenType = "email_verification"
 	TokenTypePasswordReset     TokenType = "password_reset"
+	TokenTypeTwoFactor         TokenType = "two_factor" // Password checked; waiting for the 2FA code
 )
 
 // Token represents a verification or password reset token (immutable value type).
@@ -97,6 +98,12 @@ func (t Token) MarkUsed(at time.Time) Token {
 	return t
 }
 
+// Portal session lifetimes used when none are configured.
+const (
+	DefaultSessionTTL    = 24 * time.Hour      // Without "remember me"
+	DefaultRememberMeTTL = 30 * 24 * time.Hour // With "remember me"
+)
+
 // Session represents a user portal session (immutable value type).
 type Session struct {
 	ID        string
@@ -144,8 +151,8 @@ type SignupResult struct {
 	Errors map[string]string // field -> error message
 }
 
-// ValidateSignup validates a signup request (pure function).
-func ValidateSignup(req SignupRequest) SignupResult {
+// ValidateSignup validates a signup request against policy (pure function).
+func ValidateSignup(req SignupRequest, policy PasswordPolicy) SignupResult {
 	errors := make(map[string]string)
 
 	// Validate email
@@ -158,10 +165,8 @@ func ValidateSignup(req SignupRequest) SignupResult {
 	// Validate password
 	if req.Password == "" {
 		errors["password"] = "Password is required"
-	} else if len(req.Password) < 8 {
-		errors["password"] = "Password must be at least 8 characters"
-	} else if !isStrongPassword(req.Password) {
-		errors["password"] = "Password must contain uppercase, lowercase, and number"
+	} else if msg := policy.Check(req.Password); msg != "" {
+		errors["password"] = msg
 	}
 
 	// Validate name
@@ -231,8 +236,9 @@ type PasswordResetConfirm struct {
 	NewPassword string
 }
 
-// ValidatePasswordResetConfirm validates password reset confirmation (pure function).
-func ValidatePasswordResetConfirm(req PasswordResetConfirm) SignupResult {
+// ValidatePasswordResetConfirm validates password reset confirmation against
+// policy (pure function).
+func ValidatePasswordResetConfirm(req PasswordResetConfirm, policy PasswordPolicy) SignupResult {
 	errors := make(map[string]string)
 
 	if req.Token == "" {
@@ -241,10 +247,8 @@ func ValidatePasswordResetConfirm(req PasswordResetConfirm) SignupResult {
 
 	if req.NewPassword == "" {
 		errors["password"] = "New password is required"
-	} else if len(req.NewPassword) < 8 {
-		errors["password"] = "Password must be at least 8 characters"
-	} else if !isStrongPassword(req.NewPassword) {
-		errors["password"] = "Password must contain uppercase, lowercase, and number"
+	} else if msg := policy.Check(req.NewPassword); msg != "" {
+		errors["password"] = msg
 	}
 
 	return SignupResult{
@@ -259,8 +263,9 @@ type ChangePasswordRequest struct {
 	NewPassword     string
 }
 
-// ValidateChangePassword validates a password change request (pure function).
-func ValidateChangePassword(req ChangePasswordRequest) SignupResult {
+// ValidateChangePassword validates a password change request against policy
+// (pure function).
+func ValidateChangePassword(req ChangePasswordRequest, policy PasswordPolicy) SignupResult {
 	errors := make(map[string]string)
 
 	if req.CurrentPassword == "" {
@@ -269,10 +274,8 @@ func ValidateChangePassword(req ChangePasswordRequest) SignupResult {
 
 	if req.NewPassword == "" {
 		errors["new_password"] = "New password is required"
-	} else if len(req.NewPassword) < 8 {
-		errors["new_password"] = "Password must be at least 8 characters"
-	} else if !isStrongPassword(req.NewPassword) {
-		errors["new_password"] = "Password must contain uppercase, lowercase, and number"
+	} else if msg := policy.Check(req.NewPassword); msg != "" {
+		errors["new_password"] = msg
 	} else if req.NewPassword == req.CurrentPassword {
 		errors["new_password"] = "New password must be different from current password"
 	}
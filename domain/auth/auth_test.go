@@ -345,7 +345,7 @@ func TestValidateSignup(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := ValidateSignup(tt.req)
+			result := ValidateSignup(tt.req, DefaultPasswordPolicy())
 
 			if result.Valid != tt.wantValid {
 				t.Errorf("Valid = %v, want %v", result.Valid, tt.wantValid)
@@ -499,7 +499,7 @@ func TestValidatePasswordResetConfirm(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := ValidatePasswordResetConfirm(tt.req)
+			result := ValidatePasswordResetConfirm(tt.req, DefaultPasswordPolicy())
 
 			if result.Valid != tt.wantValid {
 				t.Errorf("Valid = %v, want %v", result.Valid, tt.wantValid)
@@ -561,7 +561,7 @@ func TestValidateChangePassword(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := ValidateChangePassword(tt.req)
+			result := ValidateChangePassword(tt.req, DefaultPasswordPolicy())
 
 			if result.Valid != tt.wantValid {
 				t.Errorf("Valid = %v, want %v", result.Valid, tt.wantValid)
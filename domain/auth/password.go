@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PasswordPolicy describes the rules a new password must meet (value type).
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireNumber bool
+	RequireSymbol bool
+
+	// CheckBreached rejects passwords found in known data breaches. The
+	// lookup needs I/O, so Check ignores it; callers consult a
+	// ports.BreachedPasswordChecker when it is set.
+	CheckBreached bool
+}
+
+// DefaultPasswordPolicy returns the policy used when none is configured:
+// at least 8 characters with uppercase, lowercase, and number.
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:     8,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireNumber: true,
+	}
+}
+
+// BreachedPasswordMessage is the error shown for a password found in a breach.
+const BreachedPasswordMessage = "This password has appeared in a data breach. Please choose a different one"
+
+// Check returns why password does not meet the policy, or "" if it does.
+// This is a PURE function.
+func (p PasswordPolicy) Check(password string) string {
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return fmt.Sprintf("Password must be at least %d characters", p.MinLength)
+	}
+
+	var hasUpper, hasLower, hasNumber, hasSymbol bool
+	for _, c := range password {
+		switch {
+		case unicode.IsUpper(c):
+			hasUpper = true
+		case unicode.IsLower(c):
+			hasLower = true
+		case unicode.IsDigit(c):
+			hasNumber = true
+		case !unicode.IsLetter(c) && !unicode.IsSpace(c):
+			hasSymbol = true
+		}
+	}
+	if (p.RequireUpper && !hasUpper) || (p.RequireLower && !hasLower) ||
+		(p.RequireNumber && !hasNumber) || (p.RequireSymbol && !hasSymbol) {
+		return "Password must contain " + joinClasses(p.classes())
+	}
+	return ""
+}
+
+// Describe returns a one-line summary of the policy for form hints,
+// e.g. "At least 8 characters with uppercase, lowercase, and number".
+func (p PasswordPolicy) Describe() string {
+	desc := fmt.Sprintf("At least %d characters", p.MinLength)
+	if classes := p.classes(); len(classes) > 0 {
+		desc += " with " + joinClasses(classes)
+	}
+	return desc
+}
+
+// classes returns the names of the required character classes.
+func (p PasswordPolicy) classes() []string {
+	var classes []string
+	if p.RequireUpper {
+		classes = append(classes, "uppercase")
+	}
+	if p.RequireLower {
+		classes = append(classes, "lowercase")
+	}
+	if p.RequireNumber {
+		classes = append(classes, "number")
+	}
+	if p.RequireSymbol {
+		classes = append(classes, "symbol")
+	}
+	return classes
+}
+
+// joinClasses joins names as "a", "a and b" or "a, b, and c".
+func joinClasses(names []string) string {
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return names[0]
+	case 2:
+		return names[0] + " and " + names[1]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + ", and " + names[len(names)-1]
+}
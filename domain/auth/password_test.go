@@ -0,0 +1,77 @@
+package auth
+
+import "testing"
+
+func TestPasswordPolicy_Check(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   PasswordPolicy
+		password string
+		want     string
+	}{
+		{"default accepts strong", DefaultPasswordPolicy(), "SecurePass123", ""},
+		{"default rejects short", DefaultPasswordPolicy(), "Ab1", "Password must be at least 8 characters"},
+		{"default rejects missing classes", DefaultPasswordPolicy(), "alllowercase123", "Password must contain uppercase, lowercase, and number"},
+		{"min length", PasswordPolicy{MinLength: 12}, "short-pass1", "Password must be at least 12 characters"},
+		{"min length counts runes", PasswordPolicy{MinLength: 4}, "日本語!", ""},
+		{"min length met", PasswordPolicy{MinLength: 12}, "a long passphrase", ""},
+		{"require upper", PasswordPolicy{RequireUpper: true}, "lower", "Password must contain uppercase"},
+		{"require upper met", PasswordPolicy{RequireUpper: true}, "Upper", ""},
+		{"require lower", PasswordPolicy{RequireLower: true}, "UPPER", "Password must contain lowercase"},
+		{"require lower met", PasswordPolicy{RequireLower: true}, "UPPEr", ""},
+		{"require number", PasswordPolicy{RequireNumber: true}, "nonumber", "Password must contain number"},
+		{"require number met", PasswordPolicy{RequireNumber: true}, "number1", ""},
+		{"require symbol", PasswordPolicy{RequireSymbol: true}, "NoSymbol123", "Password must contain symbol"},
+		{"require symbol met", PasswordPolicy{RequireSymbol: true}, "Symbol#123", ""},
+		{"space is not a symbol", PasswordPolicy{RequireSymbol: true}, "two words", "Password must contain symbol"},
+		{"two classes", PasswordPolicy{RequireUpper: true, RequireSymbol: true}, "Upper", "Password must contain uppercase and symbol"},
+		{"no requirements", PasswordPolicy{}, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Check(tt.password); got != tt.want {
+				t.Errorf("Check(%q) = %q, want %q", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicy_Describe(t *testing.T) {
+	tests := []struct {
+		policy PasswordPolicy
+		want   string
+	}{
+		{DefaultPasswordPolicy(), "At least 8 characters with uppercase, lowercase, and number"},
+		{PasswordPolicy{MinLength: 10}, "At least 10 characters"},
+		{PasswordPolicy{MinLength: 12, RequireNumber: true, RequireSymbol: true}, "At least 12 characters with number and symbol"},
+		{PasswordPolicy{MinLength: 8, RequireUpper: true, RequireLower: true, RequireNumber: true, RequireSymbol: true}, "At least 8 characters with uppercase, lowercase, number, and symbol"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.policy.Describe(); got != tt.want {
+				t.Errorf("Describe() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidators_UsePolicy(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 10, RequireSymbol: true}
+
+	signup := ValidateSignup(SignupRequest{Email: "user@example.com", Password: "SecurePass123", Name: "User"}, policy)
+	if signup.Errors["password"] != "Password must contain symbol" {
+		t.Errorf("signup password error = %q", signup.Errors["password"])
+	}
+
+	reset := ValidatePasswordResetConfirm(PasswordResetConfirm{Token: "tok", NewPassword: "short#"}, policy)
+	if reset.Errors["password"] != "Password must be at least 10 characters" {
+		t.Errorf("reset password error = %q", reset.Errors["password"])
+	}
+
+	change := ValidateChangePassword(ChangePasswordRequest{CurrentPassword: "old", NewPassword: "lowercase only!"}, policy)
+	if !change.Valid {
+		t.Errorf("change password errors = %v, want valid", change.Errors)
+	}
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters (RFC 6238). These are the defaults every authenticator app
+// supports, so they are not configurable.
+const (
+	TOTPPeriod = 30 * time.Second
+	TOTPDigits = 6
+	totpModulo = 1000000 // 10^TOTPDigits
+
+	// totpSkew is how many periods either side of now a code stays valid,
+	// to allow for clock drift between the server and the user's device.
+	totpSkew = 1
+)
+
+// RecoveryCodeCount is the number of recovery codes issued at enrollment.
+const RecoveryCodeCount = 10
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret returns a new random base32 TOTP secret (160 bits).
+func GenerateTOTPSecret() string {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		panic("crypto/rand failed")
+	}
+	return totpEncoding.EncodeToString(secret)
+}
+
+// TOTPProvisioningURI returns the otpauth:// URI that authenticator apps
+// import, usually by scanning it as a QR code (pure function).
+func TOTPProvisioningURI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(TOTPDigits))
+	q.Set("period", fmt.Sprint(int(TOTPPeriod/time.Second)))
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
+
+// TOTPCode returns the code for secret at the given time (pure function).
+func TOTPCode(secret string, at time.Time) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+	return hotp(key, uint64(at.Unix()/int64(TOTPPeriod/time.Second))), nil
+}
+
+// VerifyTOTP reports whether code is valid for secret at the given time,
+// allowing one period of clock drift either way (pure function).
+func VerifyTOTP(secret, code string, at time.Time) bool {
+	_, ok := MatchTOTP(secret, code, at)
+	return ok
+}
+
+// MatchTOTP is like VerifyTOTP but also returns the time step the code
+// belongs to. Callers record the step of each accepted code and reject codes
+// from that step or earlier, so a code can't be replayed (pure function).
+func MatchTOTP(secret, code string, at time.Time) (int64, bool) {
+	code = normalizeCode(code)
+	if len(code) != TOTPDigits {
+		return 0, false
+	}
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil || len(key) == 0 {
+		return 0, false
+	}
+	step := at.Unix() / int64(TOTPPeriod/time.Second)
+	for i := int64(-totpSkew); i <= totpSkew; i++ {
+		if subtle.ConstantTimeCompare([]byte(hotp(key, uint64(step+i))), []byte(code)) == 1 {
+			return step + i, true
+		}
+	}
+	return 0, false
+}
+
+// hotp computes an RFC 4226 HOTP value.
+func hotp(key []byte, counter uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", TOTPDigits, value%totpModulo)
+}
+
+// GenerateRecoveryCodes returns n random single-use recovery codes in the
+// form "xxxxx-xxxxx". Only their hashes (see HashRecoveryCode) are stored.
+func GenerateRecoveryCodes(n int) []string {
+	codes := make([]string, n)
+	for i := range codes {
+		raw := make([]byte, 7)
+		if _, err := rand.Read(raw); err != nil {
+			panic("crypto/rand failed")
+		}
+		enc := strings.ToLower(totpEncoding.EncodeToString(raw))[:10]
+		codes[i] = enc[:5] + "-" + enc[5:]
+	}
+	return codes
+}
+
+// HashRecoveryCode returns the stored form of a recovery code. Case, spaces
+// and dashes are ignored so codes can be typed loosely (pure function).
+func HashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(normalizeCode(code))))
+	return hex.EncodeToString(sum[:])
+}
+
+// UseRecoveryCode checks code against the stored hashes. If it matches, it
+// returns the hashes with that code removed so it can't be used again
+// (pure function).
+func UseRecoveryCode(hashes []string, code string) ([]string, bool) {
+	hash := HashRecoveryCode(code)
+	for i, h := range hashes {
+		if subtle.ConstantTimeCompare([]byte(h), []byte(hash)) == 1 {
+			remaining := make([]string, 0, len(hashes)-1)
+			remaining = append(remaining, hashes[:i]...)
+			return append(remaining, hashes[i+1:]...), true
+		}
+	}
+	return hashes, false
+}
+
+// normalizeCode strips the spaces and dashes users type between digits.
+func normalizeCode(code string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(code))
+}
@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the RFC 6238 SHA-1 test key "12345678901234567890".
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode_RFC6238Vectors(t *testing.T) {
+	// RFC 6238 appendix B, truncated to 6 digits.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		got, err := TOTPCode(rfc6238Secret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("TOTPCode: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("TOTPCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestTOTPCode_InvalidSecret(t *testing.T) {
+	if _, err := TOTPCode("not base32!", time.Now()); err == nil {
+		t.Error("expected error for invalid secret")
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+
+	tests := []struct {
+		name string
+		code string
+		at   time.Time
+		want bool
+	}{
+		{"current code", "081804", now, true},
+		{"with spaces", "081 804", now, true},
+		{"previous period", "081804", now.Add(TOTPPeriod), true},
+		{"next period", "081804", now.Add(-TOTPPeriod), true},
+		{"too old", "081804", now.Add(3 * TOTPPeriod), false},
+		{"wrong code", "123456", now, false},
+		{"too short", "08180", now, false},
+		{"empty", "", now, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyTOTP(rfc6238Secret, tt.code, tt.at); got != tt.want {
+				t.Errorf("VerifyTOTP(%q) = %v, want %v", tt.code, got, tt.want)
+			}
+		})
+	}
+
+	if VerifyTOTP("", "081804", now) {
+		t.Error("empty secret should never verify")
+	}
+}
+
+func TestMatchTOTP_ReturnsStep(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	want := now.Unix() / int64(TOTPPeriod/time.Second)
+
+	// The step is the code's own, whichever period it is checked in
+	for _, at := range []time.Time{now, now.Add(TOTPPeriod), now.Add(-TOTPPeriod)} {
+		step, ok := MatchTOTP(rfc6238Secret, "081804", at)
+		if !ok || step != want {
+			t.Errorf("MatchTOTP at %v = %d, %v; want %d, true", at.Unix(), step, ok, want)
+		}
+	}
+	if _, ok := MatchTOTP(rfc6238Secret, "123456", now); ok {
+		t.Error("wrong code should not match")
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret := GenerateTOTPSecret()
+	if len(secret) != 32 {
+		t.Errorf("secret length = %d, want 32", len(secret))
+	}
+	if secret == GenerateTOTPSecret() {
+		t.Error("secrets should be random")
+	}
+
+	code, err := TOTPCode(secret, time.Now())
+	if err != nil {
+		t.Fatalf("TOTPCode: %v", err)
+	}
+	if !VerifyTOTP(secret, code, time.Now()) {
+		t.Error("generated secret should verify its own code")
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("Acme API", "user@example.com", rfc6238Secret)
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" {
+		t.Errorf("uri = %s, want otpauth://totp/...", uri)
+	}
+	if u.Path != "/Acme API:user@example.com" {
+		t.Errorf("label = %q", u.Path)
+	}
+	q := u.Query()
+	if q.Get("secret") != rfc6238Secret || q.Get("issuer") != "Acme API" {
+		t.Errorf("query = %v", q)
+	}
+	if q.Get("digits") != "6" || q.Get("period") != "30" || q.Get("algorithm") != "SHA1" {
+		t.Errorf("query = %v, want default TOTP parameters", q)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes := GenerateRecoveryCodes(RecoveryCodeCount)
+	if len(codes) != RecoveryCodeCount {
+		t.Fatalf("got %d codes, want %d", len(codes), RecoveryCodeCount)
+	}
+	seen := map[string]bool{}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("code %q, want xxxxx-xxxxx", code)
+		}
+		if seen[code] {
+			t.Errorf("duplicate code %q", code)
+		}
+		seen[code] = true
+		hashes[i] = HashRecoveryCode(code)
+		if hashes[i] == code {
+			t.Error("hash should not equal the code")
+		}
+	}
+
+	// Codes match loosely and can be used once
+	loose := strings.ToUpper(strings.Replace(codes[3], "-", " ", 1))
+	remaining, ok := UseRecoveryCode(hashes, loose)
+	if !ok {
+		t.Fatal("expected recovery code to be accepted")
+	}
+	if len(remaining) != RecoveryCodeCount-1 {
+		t.Errorf("remaining = %d, want %d", len(remaining), RecoveryCodeCount-1)
+	}
+	if _, ok := UseRecoveryCode(remaining, codes[3]); ok {
+		t.Error("used recovery code should be rejected")
+	}
+	if len(hashes) != RecoveryCodeCount || hashes[3] != HashRecoveryCode(codes[3]) {
+		t.Error("UseRecoveryCode should not modify its input")
+	}
+
+	if _, ok := UseRecoveryCode(hashes, "aaaaa-bbbbb"); ok {
+		t.Error("unknown recovery code should be rejected")
+	}
+}
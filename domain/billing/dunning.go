@@ -0,0 +1,118 @@
+package billing
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DunningAction is what the dunning schedule calls for next.
+type DunningAction string
+
+const (
+	DunningActionNone    DunningAction = ""
+	DunningActionNotify  DunningAction = "notify"  // Payment failed; dunning started
+	DunningActionRemind  DunningAction = "remind"  // Payment still failing; access continues
+	DunningActionSuspend DunningAction = "suspend" // Final failure; downgrade access
+	DunningActionRestore DunningAction = "restore" // Paid after suspension; restore access
+)
+
+// DefaultDunningSchedule is the number of days after the first failed payment
+// on which reminders are sent; the subscription is suspended on the last day.
+var DefaultDunningSchedule = []int{3, 7, 14}
+
+// ParseDunningSchedule parses a comma-separated list of days, such as
+// "3,7,14". Invalid and non-positive entries are ignored; the result is
+// sorted and without duplicates.
+// This is a PURE function.
+func ParseDunningSchedule(s string) []int {
+	var days []int
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(s, ",") {
+		d, err := strconv.Atoi(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(part), "d")))
+		if err != nil || d <= 0 || seen[d] {
+			continue
+		}
+		seen[d] = true
+		days = append(days, d)
+	}
+	sort.Ints(days)
+	return days
+}
+
+// InDunning returns true if a failed payment is being chased.
+func (s Subscription) InDunning() bool {
+	return s.DunningStartedAt != nil
+}
+
+// DunningFailed returns sub after a payment failure at `at`. The first failure
+// moves an active subscription to past_due and starts dunning, and the action
+// is notify. Further failures while in dunning keep the schedule and need no
+// action.
+// This is a PURE function.
+func DunningFailed(sub Subscription, at time.Time) (Subscription, DunningAction) {
+	if sub.InDunning() || sub.Status == SubscriptionStatusCancelled {
+		return sub, DunningActionNone
+	}
+	sub.Status = SubscriptionStatusPastDue
+	sub.DunningStartedAt = &at
+	sub.DunningStep = 0
+	sub.UpdatedAt = at
+	return sub, DunningActionNotify
+}
+
+// DunningStepDue returns sub after the schedule step due at now, if any.
+// schedule is in days after the first failure. Steps before the last remind;
+// the last step suspends the subscription by marking it unpaid. When several
+// steps are overdue only the latest is taken.
+// This is a PURE function.
+func DunningStepDue(sub Subscription, schedule []int, now time.Time) (Subscription, DunningAction) {
+	if !sub.InDunning() || sub.Status != SubscriptionStatusPastDue || sub.DunningStep >= len(schedule) {
+		return sub, DunningActionNone
+	}
+	step := sub.DunningStep
+	for step < len(schedule) && !now.Before(sub.DunningStartedAt.AddDate(0, 0, schedule[step])) {
+		step++
+	}
+	if step == sub.DunningStep {
+		return sub, DunningActionNone
+	}
+
+	sub.DunningStep = step
+	sub.UpdatedAt = now
+	if step == len(schedule) {
+		sub.Status = SubscriptionStatusUnpaid
+		return sub, DunningActionSuspend
+	}
+	return sub, DunningActionRemind
+}
+
+// DunningPaid returns sub after a successful payment at `at`, ending any
+// dunning. A suspended subscription is reactivated and the action is restore.
+// This is a PURE function.
+func DunningPaid(sub Subscription, at time.Time) (Subscription, DunningAction) {
+	action := DunningActionNone
+	if sub.Status == SubscriptionStatusUnpaid && sub.InDunning() {
+		action = DunningActionRestore
+	}
+	status := StatusAfterInvoicePaid(sub.Status)
+	if status == sub.Status && !sub.InDunning() {
+		return sub, DunningActionNone
+	}
+	sub.Status = status
+	sub.DunningStartedAt = nil
+	sub.DunningStep = 0
+	sub.UpdatedAt = at
+	return sub, action
+}
+
+// DunningSuspendsAt returns when a subscription in dunning will be suspended
+// under the schedule, or the zero time if it is not in dunning.
+// This is a PURE function.
+func DunningSuspendsAt(sub Subscription, schedule []int) time.Time {
+	if !sub.InDunning() || len(schedule) == 0 {
+		return time.Time{}
+	}
+	return sub.DunningStartedAt.AddDate(0, 0, schedule[len(schedule)-1])
+}
@@ -0,0 +1,100 @@
+package billing_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/domain/billing"
+)
+
+func TestParseDunningSchedule(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []int
+	}{
+		{"3,7,14", []int{3, 7, 14}},
+		{"14, 3d, 7", []int{3, 7, 14}},
+		{"7,7,x,-1", []int{7}},
+		{"0", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := billing.ParseDunningSchedule(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseDunningSchedule(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDunning_StateMachine(t *testing.T) {
+	schedule := []int{3, 7, 14}
+	failedAt := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	sub := billing.Subscription{ID: "sub-1", Status: billing.SubscriptionStatusActive}
+
+	sub, action := billing.DunningFailed(sub, failedAt)
+	if action != billing.DunningActionNotify || sub.Status != billing.SubscriptionStatusPastDue || !sub.InDunning() {
+		t.Fatalf("after failure: %s, %+v", action, sub)
+	}
+	if again, action := billing.DunningFailed(sub, failedAt.AddDate(0, 0, 1)); action != billing.DunningActionNone || !again.DunningStartedAt.Equal(failedAt) {
+		t.Errorf("repeated failure: %s, started %v; want the schedule kept", action, again.DunningStartedAt)
+	}
+	if got := billing.DunningSuspendsAt(sub, schedule); !got.Equal(failedAt.AddDate(0, 0, 14)) {
+		t.Errorf("DunningSuspendsAt = %v", got)
+	}
+
+	steps := []struct {
+		days   int
+		action billing.DunningAction
+		status billing.SubscriptionStatus
+		step   int
+	}{
+		{2, billing.DunningActionNone, billing.SubscriptionStatusPastDue, 0},
+		{3, billing.DunningActionRemind, billing.SubscriptionStatusPastDue, 1},
+		{5, billing.DunningActionNone, billing.SubscriptionStatusPastDue, 1},
+		{7, billing.DunningActionRemind, billing.SubscriptionStatusPastDue, 2},
+		{14, billing.DunningActionSuspend, billing.SubscriptionStatusUnpaid, 3},
+		{30, billing.DunningActionNone, billing.SubscriptionStatusUnpaid, 3},
+	}
+	for _, st := range steps {
+		sub, action = billing.DunningStepDue(sub, schedule, failedAt.AddDate(0, 0, st.days))
+		if action != st.action || sub.Status != st.status || sub.DunningStep != st.step {
+			t.Errorf("day %d: %q, %s step %d; want %q, %s step %d", st.days, action, sub.Status, sub.DunningStep, st.action, st.status, st.step)
+		}
+	}
+
+	sub, action = billing.DunningPaid(sub, failedAt.AddDate(0, 0, 31))
+	if action != billing.DunningActionRestore || sub.Status != billing.SubscriptionStatusActive || sub.InDunning() || sub.DunningStep != 0 {
+		t.Errorf("after payment: %s, %+v", action, sub)
+	}
+}
+
+func TestDunningStepDue_SkipsToLatestOverdueStep(t *testing.T) {
+	failedAt := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	sub, _ := billing.DunningFailed(billing.Subscription{Status: billing.SubscriptionStatusActive}, failedAt)
+
+	sub, action := billing.DunningStepDue(sub, []int{3, 7, 14}, failedAt.AddDate(0, 0, 8))
+	if action != billing.DunningActionRemind || sub.DunningStep != 2 {
+		t.Errorf("got %q step %d, want one reminder for step 2", action, sub.DunningStep)
+	}
+
+	sub, action = billing.DunningStepDue(sub, []int{3, 7, 14}, failedAt.AddDate(0, 1, 0))
+	if action != billing.DunningActionSuspend {
+		t.Errorf("got %q, want suspend", action)
+	}
+}
+
+func TestDunningPaid_BeforeSuspension(t *testing.T) {
+	failedAt := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	sub, _ := billing.DunningFailed(billing.Subscription{Status: billing.SubscriptionStatusActive}, failedAt)
+
+	sub, action := billing.DunningPaid(sub, failedAt.AddDate(0, 0, 2))
+	if action != billing.DunningActionNone || sub.Status != billing.SubscriptionStatusActive || sub.InDunning() {
+		t.Errorf("got %q, %+v; want active and out of dunning", action, sub)
+	}
+
+	// A cancelled subscription does not enter dunning
+	cancelled := billing.Subscription{Status: billing.SubscriptionStatusCancelled}
+	if _, action := billing.DunningFailed(cancelled, failedAt); action != billing.DunningActionNone {
+		t.Errorf("cancelled subscription: %q, want none", action)
+	}
+}
@@ -28,6 +28,8 @@ type Subscription struct {
 	CurrentPeriodEnd   time.Time
 	CancelAtPeriodEnd  bool
 	CancelledAt        *time.Time
+	DunningStartedAt   *time.Time // First failed payment of the current dunning run; nil when not in dunning
+	DunningStep        int        // Dunning schedule steps already taken
 	CreatedAt          time.Time
 	UpdatedAt          time.Time
 }
@@ -42,6 +44,18 @@ func (s Subscription) IsCancelling() bool {
 	return s.CancelAtPeriodEnd
 }
 
+// StatusAfterInvoicePaid returns the subscription status once an invoice has
+// been paid. Past-due and unpaid subscriptions become active again; other
+// states are unchanged.
+func StatusAfterInvoicePaid(status SubscriptionStatus) SubscriptionStatus {
+	switch status {
+	case SubscriptionStatusPastDue, SubscriptionStatusUnpaid:
+		return SubscriptionStatusActive
+	default:
+		return status
+	}
+}
+
 // InvoiceStatus represents the state of an invoice.
 type InvoiceStatus string
 
@@ -182,9 +196,137 @@ func CalculateInvoiceWithMeterType(
 	}
 }
 
+// CalculatePeriodInvoice creates the invoice for one billing period of a plan
+// from the units used in it. Unlike CalculateInvoice, overagePrice is in
+// hundredths of cents per unit, as stored on plans, and the overage amount is
+// rounded to the nearest cent. A negative unitsIncluded means unlimited.
+// This is a PURE function.
+func CalculatePeriodInvoice(
+	userID string,
+	periodStart, periodEnd time.Time,
+	planName string,
+	planPrice int64,
+	unitsUsed, unitsIncluded int64,
+	overagePrice int64,
+	meterType MeterType,
+) Invoice {
+	items := []InvoiceItem{
+		{
+			Description: planName + " - Monthly subscription",
+			Quantity:    1,
+			UnitPrice:   planPrice,
+			Amount:      planPrice,
+		},
+	}
+	subtotal := planPrice
+
+	unitLabel := "requests"
+	if meterType == MeterTypeComputeUnits {
+		unitLabel = "compute units"
+	}
+
+	if unitsIncluded >= 0 && unitsUsed > unitsIncluded && overagePrice > 0 {
+		overage := unitsUsed - unitsIncluded
+		amount := (overage*overagePrice + 50) / 100
+		items = append(items, InvoiceItem{
+			Description: "API overage (" + formatNumber(overage) + " " + unitLabel + " at " + formatRate(overagePrice) + " each)",
+			Quantity:    1,
+			UnitPrice:   amount,
+			Amount:      amount,
+		})
+		subtotal += amount
+	}
+
+	return Invoice{
+		UserID:      userID,
+		PeriodStart: periodStart,
+		PeriodEnd:   periodEnd,
+		Items:       items,
+		Subtotal:    subtotal,
+		Total:       subtotal,
+		Currency:    "usd",
+		Status:      InvoiceStatusOpen,
+		CreatedAt:   periodEnd,
+	}
+}
+
+// ProrationItems returns the invoice items that adjust a period invoice for a
+// plan change at `at`, part way through [periodStart, periodEnd). Periods are
+// invoiced in arrears at the price of the plan in effect at the end, so the
+// adjustment charges the old plan and credits the new plan for the time
+// before the change. Amounts are rounded to the nearest cent; no items are
+// returned when the change is outside the period or the prices are equal.
+// This is a PURE function.
+func ProrationItems(
+	oldPlanName string, oldPrice int64,
+	newPlanName string, newPrice int64,
+	periodStart, periodEnd, at time.Time,
+) []InvoiceItem {
+	if oldPrice == newPrice || !at.After(periodStart) || !at.Before(periodEnd) {
+		return nil
+	}
+
+	used := int64(at.Sub(periodStart) / time.Second)
+	total := int64(periodEnd.Sub(periodStart) / time.Second)
+	span := periodStart.UTC().Format("Jan 2") + " - " + at.UTC().Format("Jan 2")
+
+	charge := ProratedAmount(oldPrice, used, total)
+	credit := ProratedAmount(newPrice, used, total)
+	var items []InvoiceItem
+	if charge != 0 {
+		items = append(items, InvoiceItem{
+			Description: oldPlanName + " - Prorated subscription (" + span + ")",
+			Quantity:    1,
+			UnitPrice:   charge,
+			Amount:      charge,
+		})
+	}
+	if credit != 0 {
+		items = append(items, InvoiceItem{
+			Description: newPlanName + " - Credit for time before plan change (" + span + ")",
+			Quantity:    1,
+			UnitPrice:   -credit,
+			Amount:      -credit,
+		})
+	}
+	return items
+}
+
+// ProratedAmount returns price scaled by used/total, rounded to the nearest
+// cent. A non-positive total yields 0.
+// This is a PURE function.
+func ProratedAmount(price, used, total int64) int64 {
+	if total <= 0 {
+		return 0
+	}
+	return (price*used*2 + total) / (total * 2)
+}
+
+// formatRate formats a price in hundredths of cents as dollars, keeping only
+// the significant decimals (e.g. 10 -> "$0.001").
+func formatRate(hundredthsOfCents int64) string {
+	dollars := hundredthsOfCents / 10000
+	frac := padFour(hundredthsOfCents % 10000)
+	for len(frac) > 2 && frac[len(frac)-1] == '0' {
+		frac = frac[:len(frac)-1]
+	}
+	return "$" + formatNumber(dollars) + "." + frac
+}
+
+func padFour(n int64) string {
+	s := itoa(n)
+	for len(s) < 4 {
+		s = "0" + s
+	}
+	return s
+}
+
 // FormatAmount formats cents as dollars string.
 // This is a PURE function.
 func FormatAmount(cents int64) string {
+	if cents < 0 {
+		return "-" + FormatAmount(-cents)
+	}
 	dollars := cents / 100
 	remainder := cents % 100
 	if remainder == 0 {
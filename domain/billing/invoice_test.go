@@ -42,6 +42,25 @@ func TestSubscription_IsCancelling(t *testing.T) {
 	}
 }
 
+func TestStatusAfterInvoicePaid(t *testing.T) {
+	tests := []struct {
+		status billing.SubscriptionStatus
+		want   billing.SubscriptionStatus
+	}{
+		{billing.SubscriptionStatusPastDue, billing.SubscriptionStatusActive},
+		{billing.SubscriptionStatusUnpaid, billing.SubscriptionStatusActive},
+		{billing.SubscriptionStatusActive, billing.SubscriptionStatusActive},
+		{billing.SubscriptionStatusCancelled, billing.SubscriptionStatusCancelled},
+		{billing.SubscriptionStatusPaused, billing.SubscriptionStatusPaused},
+	}
+
+	for _, tt := range tests {
+		if got := billing.StatusAfterInvoicePaid(tt.status); got != tt.want {
+			t.Errorf("StatusAfterInvoicePaid(%s) = %s, want %s", tt.status, got, tt.want)
+		}
+	}
+}
+
 func TestSubscription_Fields(t *testing.T) {
 	now := time.Now()
 	cancelled := now.Add(-time.Hour)
@@ -256,6 +275,121 @@ func TestCalculateInvoice_UnlimitedPlan(t *testing.T) {
 	}
 }
 
+func TestCalculatePeriodInvoice(t *testing.T) {
+	start := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	tests := []struct {
+		name         string
+		used         int64
+		included     int64
+		overagePrice int64
+		meterType    billing.MeterType
+		wantTotal    int64
+		wantOverage  string
+	}{
+		{"within quota", 900, 1000, 10, billing.MeterTypeRequests, 2900, ""},
+		{"overage", 3500, 1000, 10, billing.MeterTypeRequests, 2900 + 250, "API overage (2,500 requests at $0.001 each)"},
+		{"overage rounds to the cent", 1003, 1000, 15, billing.MeterTypeRequests, 2900, "API overage (3 requests at $0.0015 each)"},
+		{"compute units", 1200, 1000, 250, billing.MeterTypeComputeUnits, 2900 + 500, "API overage (200 compute units at $0.025 each)"},
+		{"no overage price", 5000, 1000, 0, billing.MeterTypeRequests, 2900, ""},
+		{"unlimited", 5000, -1, 10, billing.MeterTypeRequests, 2900, ""},
+	}
+	for _, tt := range tests {
+		inv := billing.CalculatePeriodInvoice("user-1", start, end, "Pro", 2900, tt.used, tt.included, tt.overagePrice, tt.meterType)
+		if inv.Total != tt.wantTotal || inv.Subtotal != tt.wantTotal {
+			t.Errorf("%s: total = %d, want %d", tt.name, inv.Total, tt.wantTotal)
+		}
+		var sum int64
+		for _, item := range inv.Items {
+			sum += item.Amount
+		}
+		if sum != inv.Total {
+			t.Errorf("%s: items sum to %d, total is %d", tt.name, sum, inv.Total)
+		}
+		if tt.wantOverage == "" {
+			if len(inv.Items) != 1 {
+				t.Errorf("%s: got %d items, want only the subscription", tt.name, len(inv.Items))
+			}
+			continue
+		}
+		if len(inv.Items) != 2 || inv.Items[1].Description != tt.wantOverage {
+			t.Errorf("%s: items = %+v, want overage %q", tt.name, inv.Items, tt.wantOverage)
+		}
+	}
+}
+
+func TestProrationItems(t *testing.T) {
+	start := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 30)
+	halfway := start.AddDate(0, 0, 15)
+
+	t.Run("upgrade halfway", func(t *testing.T) {
+		items := billing.ProrationItems("Pro", 2000, "Business", 6000, start, end, halfway)
+		if len(items) != 2 {
+			t.Fatalf("got %d items, want a charge and a credit: %+v", len(items), items)
+		}
+		if items[0].Amount != 1000 || items[0].Description != "Pro - Prorated subscription (Apr 1 - Apr 16)" {
+			t.Errorf("charge = %+v, want $10 for half a period of Pro", items[0])
+		}
+		if items[1].Amount != -3000 || items[1].Description != "Business - Credit for time before plan change (Apr 1 - Apr 16)" {
+			t.Errorf("credit = %+v, want -$30 for half a period of Business", items[1])
+		}
+
+		// The period invoice bills Business in full; with the adjustment the
+		// customer pays half of each plan.
+		inv := billing.CalculatePeriodInvoice("user-1", start, end, "Business", 6000, 0, 1000, 0, billing.MeterTypeRequests)
+		total := inv.Total
+		for _, item := range items {
+			total += item.Amount
+		}
+		if total != 1000+3000 {
+			t.Errorf("prorated period total = %d, want 4000", total)
+		}
+	})
+
+	t.Run("downgrade halfway", func(t *testing.T) {
+		items := billing.ProrationItems("Business", 6000, "Pro", 2000, start, end, halfway)
+		if len(items) != 2 || items[0].Amount != 3000 || items[1].Amount != -1000 {
+			t.Errorf("items = %+v, want +$30 Business and -$10 Pro", items)
+		}
+	})
+
+	t.Run("from a free plan", func(t *testing.T) {
+		items := billing.ProrationItems("Free", 0, "Pro", 2000, start, end, start.AddDate(0, 0, 10))
+		if len(items) != 1 || items[0].Amount != -667 {
+			t.Errorf("items = %+v, want only a -$6.67 credit", items)
+		}
+	})
+
+	for name, at := range map[string]time.Time{"at period start": start, "at period end": end, "after period": end.AddDate(0, 0, 1)} {
+		if items := billing.ProrationItems("Pro", 2000, "Business", 6000, start, end, at); items != nil {
+			t.Errorf("%s: items = %+v, want none", name, items)
+		}
+	}
+	if items := billing.ProrationItems("Pro", 2000, "Pro Annual", 2000, start, end, halfway); items != nil {
+		t.Errorf("same price: items = %+v, want none", items)
+	}
+}
+
+func TestProratedAmount(t *testing.T) {
+	tests := []struct {
+		price, used, total, want int64
+	}{
+		{2000, 15, 30, 1000},
+		{1000, 1, 3, 333},
+		{1000, 2, 3, 667},
+		{2900, 0, 30, 0},
+		{2900, 30, 30, 2900},
+		{2900, 10, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := billing.ProratedAmount(tt.price, tt.used, tt.total); got != tt.want {
+			t.Errorf("ProratedAmount(%d, %d, %d) = %d, want %d", tt.price, tt.used, tt.total, got, tt.want)
+		}
+	}
+}
+
 func TestFormatAmount(t *testing.T) {
 	tests := []struct {
 		cents int64
@@ -271,6 +405,8 @@ func TestFormatAmount(t *testing.T) {
 		{100000000, "$1,000,000"},
 		{50, "$0.50"},
 		{5, "$0.05"},
+		{-3000, "-$30"},
+		{-1050, "-$10.50"},
 	}
 
 	for _, tt := range tests {
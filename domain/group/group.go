@@ -3,7 +3,9 @@
 package group
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"regexp"
 	"strings"
@@ -178,6 +180,28 @@ func GenerateInviteToken() string {
 	return hex.EncodeToString(tokenBytes)
 }
 
+// SignInviteToken returns the token for an invite link: the invite token
+// and its HMAC-SHA256 signature with secret, so links this server didn't
+// issue are rejected before any lookup.
+func SignInviteToken(token, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(token))
+	return token + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyInviteToken returns the invite token from a signed invite link token,
+// and false if the signature doesn't match.
+func VerifyInviteToken(signed, secret string) (string, bool) {
+	token, _, ok := strings.Cut(signed, ".")
+	if !ok || token == "" {
+		return "", false
+	}
+	if !hmac.Equal([]byte(signed), []byte(SignInviteToken(token, secret))) {
+		return "", false
+	}
+	return token, true
+}
+
 // IsExpired returns true if the invite has expired.
 func (i Invite) IsExpired() bool {
 	return time.Now().UTC().After(i.ExpiresAt)
@@ -367,6 +367,36 @@ func TestInvite_IsValid(t *testing.T) {
 	}
 }
 
+func TestVerifyInviteToken(t *testing.T) {
+	token := GenerateInviteToken()
+	signed := SignInviteToken(token, "secret")
+
+	tests := []struct {
+		name   string
+		signed string
+		secret string
+		want   bool
+	}{
+		{"valid", signed, "secret", true},
+		{"wrong secret", signed, "other", false},
+		{"tampered token", GenerateInviteToken() + signed[len(token):], "secret", false},
+		{"unsigned", token, "secret", false},
+		{"empty", "", "secret", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := VerifyInviteToken(tt.signed, tt.secret)
+			if ok != tt.want {
+				t.Fatalf("VerifyInviteToken() ok = %v, want %v", ok, tt.want)
+			}
+			if ok && got != token {
+				t.Errorf("VerifyInviteToken() = %v, want %v", got, token)
+			}
+		})
+	}
+}
+
 func TestValidateCreateGroup(t *testing.T) {
 	tests := []struct {
 		name       string
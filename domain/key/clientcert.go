@@ -0,0 +1,59 @@
+package key
+
+import (
+	"crypto/x509"
+	"strings"
+	"time"
+)
+
+// Reasons for client certificate verification failure.
+const (
+	ReasonMissingClientCert = "missing_client_cert"
+	ReasonInvalidClientCert = "invalid_client_cert"
+	ReasonUnknownClientCert = "unknown_client_cert"
+)
+
+// VerifyClientCert checks a TLS client certificate chain (leaf first) against
+// the trusted CA pool. The leaf must be valid at now and usable for client
+// authentication. Returns ReasonValid on success.
+// This is a PURE function.
+func VerifyClientCert(chain []*x509.Certificate, roots *x509.CertPool, now time.Time) string {
+	if len(chain) == 0 {
+		return ReasonMissingClientCert
+	}
+	if roots == nil {
+		return ReasonInvalidClientCert
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range chain[1:] {
+		intermediates.AddCert(c)
+	}
+	_, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return ReasonInvalidClientCert
+	}
+	return ReasonValid
+}
+
+// ClientCertEmails returns the email identities a client certificate asserts,
+// in the order they should be tried: email SANs, then the subject common name
+// if it looks like an email address.
+// This is a PURE function.
+func ClientCertEmails(cert *x509.Certificate) []string {
+	var emails []string
+	for _, e := range cert.EmailAddresses {
+		if e = strings.TrimSpace(e); e != "" {
+			emails = append(emails, e)
+		}
+	}
+	if cn := strings.TrimSpace(cert.Subject.CommonName); strings.Contains(cn, "@") {
+		emails = append(emails, cn)
+	}
+	return emails
+}
@@ -0,0 +1,112 @@
+package key_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/domain/key"
+)
+
+func issueCert(t *testing.T, tmpl *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, k
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &k.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return cert, k
+}
+
+func TestVerifyClientCert(t *testing.T) {
+	caTmpl := func(serial int64, name string) *x509.Certificate {
+		return &x509.Certificate{
+			SerialNumber: big.NewInt(serial), Subject: pkix.Name{CommonName: name},
+			NotBefore: baseTime.Add(-time.Hour), NotAfter: baseTime.Add(24 * time.Hour),
+			IsCA: true, BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCertSign,
+		}
+	}
+	leafTmpl := func(serial int64, usage x509.ExtKeyUsage) *x509.Certificate {
+		return &x509.Certificate{
+			SerialNumber: big.NewInt(serial), Subject: pkix.Name{CommonName: "client"},
+			NotBefore: baseTime.Add(-time.Hour), NotAfter: baseTime.Add(time.Hour),
+			KeyUsage: x509.KeyUsageDigitalSignature, ExtKeyUsage: []x509.ExtKeyUsage{usage},
+		}
+	}
+
+	root, rootKey := issueCert(t, caTmpl(1, "root"), nil, nil)
+	intermediate, intermediateKey := issueCert(t, caTmpl(2, "intermediate"), root, rootKey)
+	other, otherKey := issueCert(t, caTmpl(3, "other"), nil, nil)
+
+	direct, _ := issueCert(t, leafTmpl(10, x509.ExtKeyUsageClientAuth), root, rootKey)
+	chained, _ := issueCert(t, leafTmpl(11, x509.ExtKeyUsageClientAuth), intermediate, intermediateKey)
+	serverOnly, _ := issueCert(t, leafTmpl(12, x509.ExtKeyUsageServerAuth), root, rootKey)
+	foreign, _ := issueCert(t, leafTmpl(13, x509.ExtKeyUsageClientAuth), other, otherKey)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+
+	tests := []struct {
+		name  string
+		chain []*x509.Certificate
+		roots *x509.CertPool
+		now   time.Time
+		want  string
+	}{
+		{"issued by root", []*x509.Certificate{direct}, roots, baseTime, key.ReasonValid},
+		{"via intermediate", []*x509.Certificate{chained, intermediate}, roots, baseTime, key.ReasonValid},
+		{"missing intermediate", []*x509.Certificate{chained}, roots, baseTime, key.ReasonInvalidClientCert},
+		{"untrusted issuer", []*x509.Certificate{foreign}, roots, baseTime, key.ReasonInvalidClientCert},
+		{"server auth only", []*x509.Certificate{serverOnly}, roots, baseTime, key.ReasonInvalidClientCert},
+		{"expired", []*x509.Certificate{direct}, roots, baseTime.Add(2 * time.Hour), key.ReasonInvalidClientCert},
+		{"no CAs configured", []*x509.Certificate{direct}, nil, baseTime, key.ReasonInvalidClientCert},
+		{"no certificate", nil, roots, baseTime, key.ReasonMissingClientCert},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := key.VerifyClientCert(tt.chain, tt.roots, tt.now); got != tt.want {
+				t.Errorf("VerifyClientCert() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientCertEmails(t *testing.T) {
+	tests := []struct {
+		name string
+		cert *x509.Certificate
+		want []string
+	}{
+		{"email SANs then CN", &x509.Certificate{
+			EmailAddresses: []string{"ops@example.com", "billing@example.com"},
+			Subject:        pkix.Name{CommonName: "partner@example.com"},
+		}, []string{"ops@example.com", "billing@example.com", "partner@example.com"}},
+		{"CN that is not an email", &x509.Certificate{
+			EmailAddresses: []string{"ops@example.com"},
+			Subject:        pkix.Name{CommonName: "Partner Integration"},
+		}, []string{"ops@example.com"}},
+		{"no identity", &x509.Certificate{Subject: pkix.Name{CommonName: "device-42"}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := key.ClientCertEmails(tt.cert); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ClientCertEmails() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
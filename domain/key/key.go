@@ -13,17 +13,31 @@ import (
 
 // Key represents an API key (immutable value type).
 type Key struct {
-	ID          string
-	UserID      string
-	Hash        []byte     // bcrypt hash of the full key
-	Prefix      string     // First 12 chars for lookup
-	Name        string
-	Scopes      []string   // Optional: restrict to specific endpoints
-	QuotaBypass bool       // Service account: bypass quota limits
-	ExpiresAt   *time.Time // nil = never expires
-	RevokedAt   *time.Time // nil = not revoked
-	CreatedAt   time.Time
-	LastUsed    *time.Time
+	ID            string
+	UserID        string
+	GroupID       string // Group that owns the key; empty = owned by the user alone
+	Hash          []byte // bcrypt hash of the full key
+	Prefix        string // First 12 chars for lookup
+	Name          string
+	Scopes        []string          // Optional: restrict to specific endpoints
+	AllowedCIDRs  []string          // Optional: source IPs/CIDRs the key may be used from; empty = any
+	SigningSecret string            // Optional: HMAC secret for signed requests; empty = signing disabled
+	QuotaBypass   bool              // Service account: bypass quota limits
+	MonthlyQuota  int64             // Optional: quota for this key alone per plan quota period; 0 = plan quota only
+	Labels        map[string]string // Optional: name=value tags such as env or service
+	ExpiresAt     *time.Time        // nil = never expires
+	RevokedAt     *time.Time        // nil = not revoked; a future time schedules revocation
+	RotatedFrom   string            // ID of the key this one replaced in a rotation
+	CreatedAt     time.Time
+	LastUsed      *time.Time
+	LastUsedIP    string // Client IP of the most recent request; empty = unknown
+}
+
+// Use is a request made with a key, recorded as the key's last use.
+type Use struct {
+	KeyID string
+	At    time.Time
+	IP    string
 }
 
 // ValidationResult represents the outcome of key validation (value type).
@@ -79,13 +93,8 @@ func Generate(prefix string) (rawKey string, k Key) {
 		panic(fmt.Sprintf("bcrypt failed: %v", err))
 	}
 
-	// Generate key ID
-	idBytes := make([]byte, 8)
-	rand.Read(idBytes)
-	keyID := "key_" + hex.EncodeToString(idBytes)
-
 	k = Key{
-		ID:        keyID,
+		ID:        NewID(),
 		Hash:      hash,
 		Prefix:    rawKey[:12], // First 12 chars for lookup
 		CreatedAt: time.Now().UTC(),
@@ -94,12 +103,34 @@ func Generate(prefix string) (rawKey string, k Key) {
 	return rawKey, k
 }
 
+// NewID returns a new random key ID.
+func NewID() string {
+	idBytes := make([]byte, 8)
+	rand.Read(idBytes)
+	return "key_" + hex.EncodeToString(idBytes)
+}
+
 // WithUserID returns a copy of the key with the UserID set.
 func (k Key) WithUserID(userID string) Key {
 	k.UserID = userID
 	return k
 }
 
+// WithGroupID returns a copy of the key with the GroupID set.
+func (k Key) WithGroupID(groupID string) Key {
+	k.GroupID = groupID
+	return k
+}
+
+// AccountID returns the ID the key's usage and quota are counted against:
+// the owning group for group keys, otherwise the key's user.
+func (k Key) AccountID() string {
+	if k.GroupID != "" {
+		return k.GroupID
+	}
+	return k.UserID
+}
+
 // WithName returns a copy of the key with the Name set.
 func (k Key) WithName(name string) Key {
 	k.Name = name
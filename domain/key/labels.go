@@ -0,0 +1,174 @@
+package key
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Label limits.
+const (
+	MaxLabels      = 20
+	MaxLabelLength = 63
+)
+
+// ParseLabels parses labels written as comma or newline separated
+// "name=value" pairs, e.g. "env=prod, service=billing". An empty string
+// parses to nil.
+func ParseLabels(s string) (map[string]string, error) {
+	var labels map[string]string
+	for _, part := range splitLabelList(s) {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("label %q must be written as name=value", part)
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+		if _, dup := labels[name]; dup {
+			return nil, fmt.Errorf("label %q is set more than once", name)
+		}
+		labels[name] = value
+	}
+	if err := ValidateLabels(labels); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// ValidateLabels checks label names and values. Names are 1-63 letters,
+// digits, '-', '_', '.' or '/'; values are up to 63 of the same characters.
+func ValidateLabels(labels map[string]string) error {
+	if len(labels) > MaxLabels {
+		return fmt.Errorf("a key can have at most %d labels", MaxLabels)
+	}
+	for name, value := range labels {
+		if name == "" {
+			return fmt.Errorf("label names cannot be empty")
+		}
+		if !validLabelText(name) {
+			return fmt.Errorf("invalid label name %q", name)
+		}
+		if !validLabelText(value) {
+			return fmt.Errorf("invalid value %q for label %q", value, name)
+		}
+	}
+	return nil
+}
+
+// FormatLabels renders labels as "name=value" pairs sorted by name, the
+// inverse of ParseLabels.
+func FormatLabels(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + "=" + labels[name]
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// LabelSelector selects keys by label. A key matches when it has every
+// label in the selector; an empty value matches any value of that label.
+type LabelSelector map[string]string
+
+// ParseLabelSelector parses a comma separated selector such as
+// "env=prod,service" (keys labelled env=prod that also have a service
+// label). An empty string selects every key.
+func ParseLabelSelector(s string) (LabelSelector, error) {
+	var sel LabelSelector
+	for _, part := range splitLabelList(s) {
+		name, value, _ := strings.Cut(part, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if name == "" || !validLabelText(name) {
+			return nil, fmt.Errorf("invalid label selector %q", part)
+		}
+		if !validLabelText(value) {
+			return nil, fmt.Errorf("invalid value in label selector %q", part)
+		}
+		if sel == nil {
+			sel = make(LabelSelector)
+		}
+		sel[name] = value
+	}
+	return sel, nil
+}
+
+// Matches reports whether k has every label in the selector.
+func (sel LabelSelector) Matches(k Key) bool {
+	for name, want := range sel {
+		got, ok := k.Labels[name]
+		if !ok || (want != "" && got != want) {
+			return false
+		}
+	}
+	return true
+}
+
+// String renders the selector in the form ParseLabelSelector accepts.
+func (sel LabelSelector) String() string {
+	names := make([]string, 0, len(sel))
+	for name := range sel {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name
+		if sel[name] != "" {
+			parts[i] += "=" + sel[name]
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// FilterByLabels returns the keys matching the selector, in order.
+func FilterByLabels(keys []Key, sel LabelSelector) []Key {
+	if len(sel) == 0 {
+		return keys
+	}
+	var matched []Key
+	for _, k := range keys {
+		if sel.Matches(k) {
+			matched = append(matched, k)
+		}
+	}
+	return matched
+}
+
+// WithLabels returns a copy of the key with the Labels set.
+func (k Key) WithLabels(labels map[string]string) Key {
+	k.Labels = labels
+	return k
+}
+
+func splitLabelList(s string) []string {
+	var parts []string
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+func validLabelText(s string) bool {
+	if len(s) > MaxLabelLength {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == '/':
+		default:
+			return false
+		}
+	}
+	return true
+}
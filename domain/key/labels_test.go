@@ -0,0 +1,77 @@
+package key_test
+
+import (
+	"testing"
+
+	"github.com/artpar/apigate/domain/key"
+)
+
+func TestParseLabels(t *testing.T) {
+	labels, err := key.ParseLabels("env=prod, service=billing\nteam=")
+	if err != nil {
+		t.Fatalf("ParseLabels: %v", err)
+	}
+	if len(labels) != 3 || labels["env"] != "prod" || labels["service"] != "billing" || labels["team"] != "" {
+		t.Errorf("labels = %v", labels)
+	}
+	if got := key.FormatLabels(labels); got != "env=prod, service=billing, team=" {
+		t.Errorf("FormatLabels = %q", got)
+	}
+
+	if labels, err := key.ParseLabels("  "); err != nil || labels != nil {
+		t.Errorf("empty labels = %v, %v; want nil, nil", labels, err)
+	}
+
+	for _, bad := range []string{"env", "=prod", "env=prod,env=dev", "env=pro d", "e nv=prod"} {
+		if _, err := key.ParseLabels(bad); err == nil {
+			t.Errorf("ParseLabels(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestLabelSelector(t *testing.T) {
+	keys := []key.Key{
+		{ID: "k1", Labels: map[string]string{"env": "prod", "service": "billing"}},
+		{ID: "k2", Labels: map[string]string{"env": "staging", "service": "billing"}},
+		{ID: "k3", Labels: map[string]string{"env": "prod"}},
+		{ID: "k4"},
+	}
+
+	tests := []struct {
+		selector string
+		want     []string
+	}{
+		{"", []string{"k1", "k2", "k3", "k4"}},
+		{"env=prod", []string{"k1", "k3"}},
+		{"env=prod,service=billing", []string{"k1"}},
+		{"service", []string{"k1", "k2"}},
+		{"env=dev", nil},
+	}
+	for _, tt := range tests {
+		sel, err := key.ParseLabelSelector(tt.selector)
+		if err != nil {
+			t.Fatalf("ParseLabelSelector(%q): %v", tt.selector, err)
+		}
+		var got []string
+		for _, k := range key.FilterByLabels(keys, sel) {
+			got = append(got, k.ID)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("selector %q matched %v, want %v", tt.selector, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("selector %q matched %v, want %v", tt.selector, got, tt.want)
+				break
+			}
+		}
+		if tt.selector != "" && sel.String() != tt.selector {
+			t.Errorf("String() = %q, want %q", sel.String(), tt.selector)
+		}
+	}
+
+	if _, err := key.ParseLabelSelector("env=prod,=x"); err == nil {
+		t.Error("selector with empty label name accepted")
+	}
+}
@@ -0,0 +1,35 @@
+package key
+
+import "time"
+
+// DefaultRotationGrace is how long a rotated-out key keeps working when no
+// grace period is given.
+const DefaultRotationGrace = 24 * time.Hour
+
+// Rotate issues a replacement for old that carries over its owner, group, name,
+// scopes, IP allowlist, quota bypass, key quota, labels and expiry. A key with a signing secret
+// gets a fresh secret. The old key should be revoked at the returned revokeAt,
+// so clients can switch keys during the grace period without downtime.
+func Rotate(old Key, prefix string, now time.Time, grace time.Duration) (rawKey string, replacement Key, revokeAt time.Time) {
+	rawKey, replacement = Generate(prefix)
+	replacement.UserID = old.UserID
+	replacement.GroupID = old.GroupID
+	replacement.Name = old.Name
+	replacement.Scopes = old.Scopes
+	replacement.AllowedCIDRs = old.AllowedCIDRs
+	replacement.QuotaBypass = old.QuotaBypass
+	replacement.MonthlyQuota = old.MonthlyQuota
+	replacement.Labels = old.Labels
+	replacement.ExpiresAt = old.ExpiresAt
+	replacement.RotatedFrom = old.ID
+	replacement.CreatedAt = now
+	if old.SigningSecret != "" {
+		replacement.SigningSecret = GenerateSigningSecret()
+	}
+
+	if grace < 0 {
+		grace = 0
+	}
+	revokeAt = now.Add(grace)
+	return rawKey, replacement, revokeAt
+}
@@ -0,0 +1,93 @@
+package key_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/domain/key"
+)
+
+func TestRotate(t *testing.T) {
+	expires := baseTime.Add(30 * 24 * time.Hour)
+	old := key.Key{
+		ID:            "key-old",
+		UserID:        "user-1",
+		Prefix:        "ak_oldoldold",
+		Name:          "Production",
+		Scopes:        []string{"read"},
+		AllowedCIDRs:  []string{"203.0.113.0/24"},
+		SigningSecret: "old-secret",
+		QuotaBypass:   true,
+		MonthlyQuota:  1000,
+		Labels:        map[string]string{"env": "prod"},
+		ExpiresAt:     &expires,
+		CreatedAt:     pastTime,
+	}
+
+	rawKey, replacement, revokeAt := key.Rotate(old, "ak_", baseTime, time.Hour)
+
+	if prefix, ok := key.ValidateFormat(rawKey, "ak_"); !ok || prefix != replacement.Prefix {
+		t.Fatalf("replacement raw key %q invalid or prefix mismatch", rawKey)
+	}
+	if replacement.ID == old.ID || replacement.Prefix == old.Prefix {
+		t.Errorf("replacement reuses old ID or prefix: %+v", replacement)
+	}
+	if replacement.UserID != "user-1" || replacement.Name != "Production" || !replacement.QuotaBypass || replacement.MonthlyQuota != 1000 {
+		t.Errorf("replacement lost owner, name, quota bypass or key quota: %+v", replacement)
+	}
+	if len(replacement.Scopes) != 1 || len(replacement.AllowedCIDRs) != 1 || replacement.Labels["env"] != "prod" {
+		t.Errorf("replacement lost scopes, allowlist or labels: %+v", replacement)
+	}
+	if replacement.ExpiresAt == nil || !replacement.ExpiresAt.Equal(expires) {
+		t.Errorf("replacement ExpiresAt = %v, want %v", replacement.ExpiresAt, expires)
+	}
+	if replacement.SigningSecret == "" || replacement.SigningSecret == old.SigningSecret {
+		t.Errorf("replacement should get a fresh signing secret, got %q", replacement.SigningSecret)
+	}
+	if replacement.RotatedFrom != "key-old" {
+		t.Errorf("RotatedFrom = %q, want key-old", replacement.RotatedFrom)
+	}
+	if replacement.RevokedAt != nil {
+		t.Errorf("replacement should not be revoked")
+	}
+	if !revokeAt.Equal(baseTime.Add(time.Hour)) {
+		t.Errorf("revokeAt = %v, want %v", revokeAt, baseTime.Add(time.Hour))
+	}
+}
+
+func TestRotate_NoSigningSecret(t *testing.T) {
+	_, replacement, _ := key.Rotate(key.Key{ID: "key-old", UserID: "user-1"}, "ak_", baseTime, 0)
+	if replacement.SigningSecret != "" {
+		t.Errorf("replacement of an unsigned key got a signing secret")
+	}
+}
+
+func TestValidate_ScheduledRevocation(t *testing.T) {
+	revokeAt := baseTime.Add(time.Hour)
+	k := key.Key{ID: "key-1", UserID: "user-1", CreatedAt: pastTime, RevokedAt: &revokeAt}
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		wantValid bool
+	}{
+		{"during grace period", baseTime, true},
+		{"just before revocation", revokeAt.Add(-time.Second), true},
+		{"at revocation", revokeAt, false},
+		{"after revocation", revokeAt.Add(time.Minute), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := key.Validate(k, tt.now)
+			if result.Valid != tt.wantValid {
+				t.Errorf("Validate().Valid = %v, want %v", result.Valid, tt.wantValid)
+			}
+			if !tt.wantValid && result.Reason != key.ReasonRevoked {
+				t.Errorf("Reason = %q, want key_revoked", result.Reason)
+			}
+			if key.IsRevoked(k, tt.now) == tt.wantValid {
+				t.Errorf("IsRevoked() = %v, want %v", tt.wantValid, !tt.wantValid)
+			}
+		})
+	}
+}
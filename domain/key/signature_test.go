@@ -0,0 +1,81 @@
+package key_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/domain/key"
+)
+
+func TestVerifySignature(t *testing.T) {
+	k := key.Key{ID: "key1", SigningSecret: "s3cr3t"}
+	ts := strconv.FormatInt(baseTime.Unix(), 10)
+	body := []byte(`{"item":"book"}`)
+	sig := key.Sign(k.SigningSecret, key.StringToSign("POST", "/v1/orders", "dry_run=true", ts, body))
+
+	signed := func(mutate func(*key.SignedRequest)) key.SignedRequest {
+		req := key.SignedRequest{
+			Method: "POST", Path: "/v1/orders", Query: "dry_run=true", Timestamp: ts, Body: body, Signature: sig,
+		}
+		if mutate != nil {
+			mutate(&req)
+		}
+		return req
+	}
+
+	tests := []struct {
+		name string
+		key  key.Key
+		req  key.SignedRequest
+		now  time.Time
+		want string
+	}{
+		{"valid", k, signed(nil), baseTime, key.ReasonValid},
+		{"valid within skew", k, signed(nil), baseTime.Add(4 * time.Minute), key.ReasonValid},
+		{"stale timestamp", k, signed(nil), baseTime.Add(6 * time.Minute), key.ReasonSignatureExpired},
+		{"future timestamp", k, signed(nil), baseTime.Add(-6 * time.Minute), key.ReasonSignatureExpired},
+		{"tampered body", k, signed(func(r *key.SignedRequest) { r.Body = []byte(`{"item":"car"}`) }), baseTime, key.ReasonInvalidSignature},
+		{"tampered path", k, signed(func(r *key.SignedRequest) { r.Path = "/v1/refunds" }), baseTime, key.ReasonInvalidSignature},
+		{"tampered query", k, signed(func(r *key.SignedRequest) { r.Query = "" }), baseTime, key.ReasonInvalidSignature},
+		{"wrong method", k, signed(func(r *key.SignedRequest) { r.Method = "PUT" }), baseTime, key.ReasonInvalidSignature},
+		{"wrong secret", key.Key{SigningSecret: "other"}, signed(nil), baseTime, key.ReasonInvalidSignature},
+		{"non-hex signature", k, signed(func(r *key.SignedRequest) { r.Signature = "zz" }), baseTime, key.ReasonInvalidSignature},
+		{"malformed timestamp", k, signed(func(r *key.SignedRequest) { r.Timestamp = "yesterday" }), baseTime, key.ReasonInvalidSignature},
+		{"missing signature", k, signed(func(r *key.SignedRequest) { r.Signature = "" }), baseTime, key.ReasonMissingSignature},
+		{"missing timestamp", k, signed(func(r *key.SignedRequest) { r.Timestamp = "" }), baseTime, key.ReasonMissingSignature},
+		{"signing not enabled", key.Key{ID: "key1"}, signed(nil), baseTime, key.ReasonSigningNotEnabled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := key.VerifySignature(tt.key, tt.req, tt.now, 5*time.Minute); got != tt.want {
+				t.Errorf("VerifySignature() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifySignature_DefaultSkew(t *testing.T) {
+	k := key.Key{SigningSecret: "s3cr3t"}
+	ts := strconv.FormatInt(baseTime.Unix(), 10)
+	req := key.SignedRequest{Method: "GET", Path: "/data", Timestamp: ts}
+	req.Signature = key.Sign(k.SigningSecret, key.StringToSign(req.Method, req.Path, "", ts, nil))
+
+	if got := key.VerifySignature(k, req, baseTime.Add(key.DefaultSignatureMaxSkew-time.Second), 0); got != key.ReasonValid {
+		t.Errorf("within default skew = %q, want valid", got)
+	}
+	if got := key.VerifySignature(k, req, baseTime.Add(key.DefaultSignatureMaxSkew+time.Second), 0); got != key.ReasonSignatureExpired {
+		t.Errorf("past default skew = %q, want %q", got, key.ReasonSignatureExpired)
+	}
+}
+
+func TestGenerateSigningSecret(t *testing.T) {
+	a, b := key.GenerateSigningSecret(), key.GenerateSigningSecret()
+	if len(a) != 64 {
+		t.Errorf("secret length = %d, want 64", len(a))
+	}
+	if a == b {
+		t.Error("secrets should be unique")
+	}
+}
@@ -1,6 +1,7 @@
 package key
 
 import (
+	"net/netip"
 	"strings"
 	"time"
 )
@@ -9,7 +10,7 @@ import (
 // This is a PURE function - no side effects, deterministic.
 func Validate(k Key, now time.Time) ValidationResult {
 	// Check if revoked
-	if k.RevokedAt != nil {
+	if IsRevoked(k, now) {
 		return ValidationResult{
 			Valid:  false,
 			Reason: ReasonRevoked,
@@ -30,6 +31,13 @@ func Validate(k Key, now time.Time) ValidationResult {
 	}
 }
 
+// IsRevoked reports whether the key is revoked at the given time. A key with a
+// future RevokedAt (e.g. one being rotated out) stays valid until then.
+// This is a PURE function.
+func IsRevoked(k Key, now time.Time) bool {
+	return k.RevokedAt != nil && !now.Before(*k.RevokedAt)
+}
+
 // ValidateFormat checks if a raw API key has valid format.
 // Returns (prefix, valid). Prefix is used for database lookup.
 // This is a PURE function.
@@ -64,9 +72,63 @@ func HasScope(k Key, requiredScope string) bool {
 		return true
 	}
 
-	// Check if required scope is in allowed scopes
+	// Check if required scope is granted by any allowed scope
 	for _, s := range k.Scopes {
-		if s == requiredScope || s == "*" {
+		if MatchScope(s, requiredScope) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasScopes checks if the key has access to every one of the given scopes.
+// Empty scopes on the key means access to everything.
+// This is a PURE function.
+func HasScopes(k Key, requiredScopes []string) bool {
+	for _, s := range requiredScopes {
+		if !HasScope(k, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchScope checks if a granted scope covers a required scope.
+// Supports simple wildcards: "*" matches any scope, and "catalog:*" matches
+// "catalog:read" and "catalog:items:write".
+// This is a PURE function.
+func MatchScope(granted, required string) bool {
+	if granted == required || granted == "*" {
+		return true
+	}
+
+	if strings.HasSuffix(granted, ":*") {
+		prefix := strings.TrimSuffix(granted, "*")
+		return strings.HasPrefix(required, prefix) && len(required) > len(prefix)
+	}
+
+	return false
+}
+
+// AllowsIP checks if the key may be used from the given client IP.
+// Empty AllowedCIDRs means any IP is allowed. Entries are CIDRs ("10.0.0.0/8")
+// or single addresses ("203.0.113.7"); invalid entries never match.
+// This is a PURE function.
+func AllowsIP(k Key, ip string) bool {
+	if len(k.AllowedCIDRs) == 0 {
+		return true
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, entry := range k.AllowedCIDRs {
+		prefix, err := ParseCIDR(entry)
+		if err == nil && prefix.Contains(addr) {
 			return true
 		}
 	}
@@ -74,6 +136,27 @@ func HasScope(k Key, requiredScope string) bool {
 	return false
 }
 
+// ParseCIDR parses an allowed CIDR entry. A single address is treated as a
+// host prefix (/32 or /128). IPv4 client addresses are compared unmapped, so
+// IPv4 entries should be written in dotted form.
+// This is a PURE function.
+func ParseCIDR(entry string) (netip.Prefix, error) {
+	entry = strings.TrimSpace(entry)
+	if strings.Contains(entry, "/") {
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(entry)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
 // MatchPath checks if a path matches a scope pattern.
 // Supports simple wildcards: "/api/*" matches "/api/users", "/api/items/123"
 // This is a PURE function.
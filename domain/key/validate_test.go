@@ -196,6 +196,62 @@ func TestHasScope(t *testing.T) {
 	}
 }
 
+func TestHasScopes(t *testing.T) {
+	tests := []struct {
+		name     string
+		scopes   []string
+		required []string
+		wantHas  bool
+	}{
+		{"no required scopes", []string{"catalog:read"}, nil, true},
+		{"unscoped key", nil, []string{"catalog:read"}, true},
+		{"exact match", []string{"catalog:read", "orders:read"}, []string{"catalog:read"}, true},
+		{"all required held", []string{"catalog:read", "orders:read"}, []string{"catalog:read", "orders:read"}, true},
+		{"one required missing", []string{"catalog:read"}, []string{"catalog:read", "orders:read"}, false},
+		{"wildcard match", []string{"catalog:*"}, []string{"catalog:read", "catalog:items:write"}, true},
+		{"wildcard other resource", []string{"catalog:*"}, []string{"orders:read"}, false},
+		{"wildcard needs action", []string{"catalog:*"}, []string{"catalog"}, false},
+		{"global wildcard", []string{"*"}, []string{"orders:write"}, true},
+		{"missing scope", []string{"orders:read"}, []string{"catalog:read"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := key.HasScopes(key.Key{Scopes: tt.scopes}, tt.required)
+			if got != tt.wantHas {
+				t.Errorf("HasScopes() = %v, want %v", got, tt.wantHas)
+			}
+		})
+	}
+}
+
+func TestAllowsIP(t *testing.T) {
+	tests := []struct {
+		name  string
+		cidrs []string
+		ip    string
+		want  bool
+	}{
+		{"no restriction", nil, "198.51.100.1", true},
+		{"in range", []string{"10.0.0.0/8", "203.0.113.0/24"}, "203.0.113.42", true},
+		{"out of range", []string{"203.0.113.0/24"}, "198.51.100.1", false},
+		{"single address", []string{"198.51.100.7"}, "198.51.100.7", true},
+		{"single address other host", []string{"198.51.100.7"}, "198.51.100.8", false},
+		{"ipv6 range", []string{"2001:db8::/32"}, "2001:db8:1::5", true},
+		{"ipv4-mapped client", []string{"203.0.113.0/24"}, "::ffff:203.0.113.9", true},
+		{"invalid client ip", []string{"203.0.113.0/24"}, "not-an-ip", false},
+		{"invalid entry ignored", []string{"bogus", "203.0.113.0/24"}, "203.0.113.9", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := key.AllowsIP(key.Key{AllowedCIDRs: tt.cidrs}, tt.ip); got != tt.want {
+				t.Errorf("AllowsIP(%q) = %v, want %v", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestMatchPath(t *testing.T) {
 	tests := []struct {
 		name    string
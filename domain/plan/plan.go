@@ -18,12 +18,22 @@ const (
 	MeterTypeComputeUnits MeterType = "compute_units" // Count weighted units (tokens, etc.)
 )
 
+// QuotaPeriod determines when a plan's quota window resets.
+type QuotaPeriod string
+
+const (
+	QuotaPeriodCalendarMonth QuotaPeriod = "calendar_month" // Reset on the 1st of each month (default)
+	QuotaPeriodCalendarWeek  QuotaPeriod = "calendar_week"  // Reset every Monday at 00:00
+	QuotaPeriodRolling30d    QuotaPeriod = "rolling_30d"    // Reset every 30 days from the user's sign-up
+)
+
 // Plan represents a pricing tier (immutable value type).
 type Plan struct {
 	ID                  string
 	Name                string
 	RequestsPerMonth    int64 // -1 = unlimited
 	RateLimitPerMinute  int
+	RateLimitBurst      int   // Token bucket size: max requests in a burst (0 = RateLimitPerMinute + global burst)
 	PriceMonthly        int64 // cents
 	OveragePrice        int64 // hundredths of cents per request (10000 = $1)
 	StripePriceID       string
@@ -31,6 +41,12 @@ type Plan struct {
 	QuotaGracePct       float64          // Grace percentage before hard block (e.g., 0.05 = 5%)
 	MeterType           MeterType        // Which metric to enforce: "requests" or "compute_units"
 	EstimatedCostPerReq float64          // Estimated cost per request for pre-check (default 1.0)
+	QuotaPeriod         QuotaPeriod      // When the quota resets - defaults to "calendar_month"
+	MaxConcurrent       int              // In-flight requests allowed per API key (0 = unlimited)
+	SpikeThreshold      float64          // Alert when a key's recent request rate exceeds its baseline by this factor (0 = disabled)
+	SpikeMinRequests    int              // Requests in the recent window before a spike is considered
+	SpikeThrottleRPM    int              // Requests per minute allowed for a spiking key (0 = alert only)
+	AllowOverage        bool             // Serve requests past the quota and bill them at OveragePrice
 }
 
 // Endpoint represents endpoint-specific pricing (value type).
@@ -0,0 +1,182 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"maps"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HTTPTimeFormat is the date format used in Last-Modified and If-Modified-Since headers.
+const HTTPTimeFormat = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+// Cache status values reported in the X-Cache response header.
+const (
+	CacheHit  = "HIT"
+	CacheMiss = "MISS"
+)
+
+// cacheableStatuses are the response codes that may be cached without
+// explicit freshness information (RFC 9110 section 15.1).
+var cacheableStatuses = map[int]bool{
+	200: true, 203: true, 204: true, 300: true, 301: true,
+	404: true, 405: true, 410: true, 414: true, 501: true,
+}
+
+// IsCacheableRequest returns true if a response to req may be served from or stored in a cache.
+// Only GET and HEAD requests are cached, and Cache-Control: no-store bypasses the cache.
+func IsCacheableRequest(req Request) bool {
+	if req.Method != "GET" && req.Method != "HEAD" {
+		return false
+	}
+	return !HasCacheDirective(HeaderValue(req.Headers, "Cache-Control"), "no-store")
+}
+
+// IsCacheableResponse returns true if resp may be stored in a shared cache.
+// Responses marked no-store or private, and responses setting cookies, are not cached.
+func IsCacheableResponse(resp Response) bool {
+	if !cacheableStatuses[resp.Status] {
+		return false
+	}
+	cc := HeaderValue(resp.Headers, "Cache-Control")
+	if HasCacheDirective(cc, "no-store") || HasCacheDirective(cc, "private") {
+		return false
+	}
+	return HeaderValue(resp.Headers, "Set-Cookie") == ""
+}
+
+// CacheKey builds the cache key for a request to a route.
+// The key covers the method, path, query and the values of the given headers.
+func CacheKey(routeID string, req Request, headers []string) string {
+	names := make([]string, len(headers))
+	for i, h := range headers {
+		names[i] = strings.ToLower(h)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(routeID)
+	b.WriteByte('\n')
+	b.WriteString(req.Method)
+	b.WriteByte('\n')
+	b.WriteString(req.Path)
+	b.WriteByte('?')
+	b.WriteString(req.Query)
+	for _, name := range names {
+		b.WriteByte('\n')
+		b.WriteString(name)
+		b.WriteByte(':')
+		b.WriteString(HeaderValue(req.Headers, name))
+	}
+
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// WithValidators returns resp with ETag and Last-Modified headers added when
+// the upstream did not provide them. The generated ETag is a weak validator
+// derived from the body; Last-Modified is the time the response was cached.
+func WithValidators(resp Response, cachedAt time.Time) Response {
+	resp.Headers = maps.Clone(resp.Headers)
+	if resp.Headers == nil {
+		resp.Headers = make(map[string]string)
+	}
+	if HeaderValue(resp.Headers, "ETag") == "" {
+		resp.Headers["ETag"] = WeakETag(resp.Body)
+	}
+	if HeaderValue(resp.Headers, "Last-Modified") == "" {
+		resp.Headers["Last-Modified"] = cachedAt.UTC().Format(HTTPTimeFormat)
+	}
+	return resp
+}
+
+// WeakETag returns a weak entity tag for a body.
+func WeakETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// NotModified returns true if the conditional headers in req show that the
+// client already holds the representation in resp. If-None-Match takes
+// precedence over If-Modified-Since (RFC 9110 section 13.2.2).
+func NotModified(req Request, resp Response) bool {
+	if resp.Status < 200 || resp.Status >= 300 {
+		return false
+	}
+	if ifNoneMatch := HeaderValue(req.Headers, "If-None-Match"); ifNoneMatch != "" {
+		etag := HeaderValue(resp.Headers, "ETag")
+		return etag != "" && etagMatches(ifNoneMatch, etag)
+	}
+
+	ifModifiedSince := HeaderValue(req.Headers, "If-Modified-Since")
+	lastModified := HeaderValue(resp.Headers, "Last-Modified")
+	if ifModifiedSince == "" || lastModified == "" {
+		return false
+	}
+	since, err := time.Parse(HTTPTimeFormat, ifModifiedSince)
+	if err != nil {
+		return false
+	}
+	modified, err := time.Parse(HTTPTimeFormat, lastModified)
+	if err != nil {
+		return false
+	}
+	return !modified.After(since)
+}
+
+// notModifiedHeaders are the response headers kept on a 304 (RFC 9110 section 15.4.5).
+var notModifiedHeaders = []string{"Cache-Control", "Content-Location", "Date", "ETag", "Expires", "Last-Modified", "Vary", "X-Cache"}
+
+// NotModifiedResponse returns the 304 response sent instead of resp.
+func NotModifiedResponse(resp Response) Response {
+	headers := make(map[string]string)
+	for _, name := range notModifiedHeaders {
+		if v := HeaderValue(resp.Headers, name); v != "" {
+			headers[name] = v
+		}
+	}
+	return Response{
+		Status:       304,
+		Headers:      headers,
+		LatencyMs:    resp.LatencyMs,
+		UpstreamAddr: resp.UpstreamAddr,
+	}
+}
+
+// etagMatches reports whether an If-None-Match list matches etag using weak comparison.
+func etagMatches(list, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// HasCacheDirective returns true if a Cache-Control header value contains the directive.
+func HasCacheDirective(cacheControl, directive string) bool {
+	for _, part := range strings.Split(cacheControl, ",") {
+		name, _, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if strings.EqualFold(name, directive) {
+			return true
+		}
+	}
+	return false
+}
+
+// HeaderValue returns the value of a header using a case-insensitive name lookup.
+func HeaderValue(headers map[string]string, name string) string {
+	if v, ok := headers[name]; ok {
+		return v
+	}
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
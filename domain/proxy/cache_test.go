@@ -0,0 +1,157 @@
+package proxy
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsCacheableRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		req  Request
+		want bool
+	}{
+		{"get", Request{Method: "GET"}, true},
+		{"head", Request{Method: "HEAD"}, true},
+		{"post", Request{Method: "POST"}, false},
+		{"no-store", Request{Method: "GET", Headers: map[string]string{"Cache-Control": "no-store"}}, false},
+		{"no-store among directives", Request{Method: "GET", Headers: map[string]string{"cache-control": "max-age=0, No-Store"}}, false},
+		{"no-cache", Request{Method: "GET", Headers: map[string]string{"Cache-Control": "no-cache"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsCacheableRequest(tt.req); got != tt.want {
+				t.Errorf("IsCacheableRequest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsCacheableResponse(t *testing.T) {
+	tests := []struct {
+		name string
+		resp Response
+		want bool
+	}{
+		{"ok", Response{Status: 200}, true},
+		{"not found", Response{Status: 404}, true},
+		{"server error", Response{Status: 500}, false},
+		{"no-store", Response{Status: 200, Headers: map[string]string{"Cache-Control": "no-store"}}, false},
+		{"private", Response{Status: 200, Headers: map[string]string{"Cache-Control": "private, max-age=60"}}, false},
+		{"public", Response{Status: 200, Headers: map[string]string{"Cache-Control": "public, max-age=60"}}, true},
+		{"set-cookie", Response{Status: 200, Headers: map[string]string{"Set-Cookie": "session=1"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsCacheableResponse(tt.resp); got != tt.want {
+				t.Errorf("IsCacheableResponse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	base := Request{Method: "GET", Path: "/catalog", Query: "page=1", Headers: map[string]string{"Accept": "application/json", "X-Trace": "a"}}
+	key := CacheKey("r1", base, []string{"Accept"})
+
+	// Headers outside the selected set do not affect the key
+	other := base
+	other.Headers = map[string]string{"Accept": "application/json", "X-Trace": "b"}
+	if got := CacheKey("r1", other, []string{"accept"}); got != key {
+		t.Error("unselected header changed the cache key")
+	}
+
+	variants := map[string]Request{
+		"method": {Method: "HEAD", Path: "/catalog", Query: "page=1", Headers: base.Headers},
+		"path":   {Method: "GET", Path: "/catalog/2", Query: "page=1", Headers: base.Headers},
+		"query":  {Method: "GET", Path: "/catalog", Query: "page=2", Headers: base.Headers},
+		"header": {Method: "GET", Path: "/catalog", Query: "page=1", Headers: map[string]string{"Accept": "text/csv"}},
+	}
+	for name, req := range variants {
+		if CacheKey("r1", req, []string{"Accept"}) == key {
+			t.Errorf("%s did not change the cache key", name)
+		}
+	}
+	if CacheKey("r2", base, []string{"Accept"}) == key {
+		t.Error("route did not change the cache key")
+	}
+}
+
+func TestWithValidators(t *testing.T) {
+	cachedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	resp := WithValidators(Response{Status: 200, Body: []byte("catalog")}, cachedAt)
+	if got := resp.Headers["ETag"]; got != WeakETag([]byte("catalog")) || !strings.HasPrefix(got, `W/"`) {
+		t.Errorf("ETag = %q, want weak ETag of body", got)
+	}
+	if got := resp.Headers["Last-Modified"]; got != "Fri, 01 Mar 2024 12:00:00 GMT" {
+		t.Errorf("Last-Modified = %q", got)
+	}
+	if WeakETag([]byte("catalog")) == WeakETag([]byte("catalog2")) {
+		t.Error("different bodies produced the same ETag")
+	}
+
+	// Upstream validators are kept
+	upstream := Response{Status: 200, Headers: map[string]string{"Etag": `"v1"`, "Last-Modified": "Mon, 01 Jan 2024 00:00:00 GMT"}}
+	resp = WithValidators(upstream, cachedAt)
+	if resp.Headers["Etag"] != `"v1"` || resp.Headers["ETag"] != "" || resp.Headers["Last-Modified"] != "Mon, 01 Jan 2024 00:00:00 GMT" {
+		t.Errorf("upstream validators changed: %v", resp.Headers)
+	}
+	if _, ok := upstream.Headers["ETag"]; ok {
+		t.Error("WithValidators modified the input headers")
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	resp := Response{Status: 200, Headers: map[string]string{
+		"ETag":          `W/"abc"`,
+		"Last-Modified": "Fri, 01 Mar 2024 12:00:00 GMT",
+	}}
+	cond := func(headers map[string]string) Request {
+		return Request{Method: "GET", Headers: headers}
+	}
+
+	tests := []struct {
+		name string
+		req  Request
+		resp Response
+		want bool
+	}{
+		{"no conditions", cond(nil), resp, false},
+		{"matching etag", cond(map[string]string{"If-None-Match": `W/"abc"`}), resp, true},
+		{"strong form matches weakly", cond(map[string]string{"If-None-Match": `"abc"`}), resp, true},
+		{"etag in list", cond(map[string]string{"If-None-Match": `"x", W/"abc"`}), resp, true},
+		{"wildcard", cond(map[string]string{"If-None-Match": "*"}), resp, true},
+		{"different etag", cond(map[string]string{"If-None-Match": `"xyz"`}), resp, false},
+		{"etag takes precedence", cond(map[string]string{"If-None-Match": `"xyz"`, "If-Modified-Since": "Sat, 02 Mar 2024 00:00:00 GMT"}), resp, false},
+		{"not modified since", cond(map[string]string{"If-Modified-Since": "Fri, 01 Mar 2024 12:00:00 GMT"}), resp, true},
+		{"modified since", cond(map[string]string{"If-Modified-Since": "Fri, 01 Mar 2024 11:59:59 GMT"}), resp, false},
+		{"invalid date", cond(map[string]string{"If-Modified-Since": "yesterday"}), resp, false},
+		{"error response", cond(map[string]string{"If-None-Match": "*"}), Response{Status: 404, Headers: resp.Headers}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NotModified(tt.req, tt.resp); got != tt.want {
+				t.Errorf("NotModified() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotModifiedResponse(t *testing.T) {
+	resp := NotModifiedResponse(Response{
+		Status:  200,
+		Headers: map[string]string{"ETag": `"v1"`, "Cache-Control": "max-age=60", "Content-Type": "application/json", "Content-Length": "7"},
+		Body:    []byte("catalog"),
+	})
+	if resp.Status != 304 || len(resp.Body) != 0 {
+		t.Errorf("response = %d with %d byte body, want 304 without body", resp.Status, len(resp.Body))
+	}
+	if resp.Headers["ETag"] != `"v1"` || resp.Headers["Cache-Control"] != "max-age=60" {
+		t.Errorf("validator headers = %v", resp.Headers)
+	}
+	if _, ok := resp.Headers["Content-Type"]; ok {
+		t.Error("304 kept Content-Type")
+	}
+}
@@ -0,0 +1,78 @@
+package proxy
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// Response encodings the gateway can apply, in order of preference.
+const (
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+)
+
+// CompressionConfig controls compression of proxy responses.
+type CompressionConfig struct {
+	Enabled bool
+	MinSize int      // Bodies smaller than this are sent uncompressed
+	Types   []string // Media types that may be compressed, e.g. "application/json"
+}
+
+// NegotiateEncoding picks the response encoding for an Accept-Encoding
+// header: gzip if acceptable, otherwise deflate. Returns "" when the client
+// accepts neither.
+func NegotiateEncoding(acceptEncoding string) string {
+	q := map[string]float64{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		weight := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				weight = f
+			}
+		}
+		q[name] = weight
+	}
+
+	best, bestQ := "", 0.0
+	for _, enc := range []string{EncodingGzip, EncodingDeflate} {
+		w, ok := q[enc]
+		if !ok {
+			w, ok = q["*"]
+		}
+		if ok && w > bestQ {
+			best, bestQ = enc, w
+		}
+	}
+	return best
+}
+
+// IsCompressible returns true if a response with these headers and body may
+// be compressed: its media type is allowed, it is at least MinSize bytes,
+// it isn't already encoded and it doesn't forbid transformation.
+func IsCompressible(cfg CompressionConfig, headers map[string]string, body []byte) bool {
+	if !cfg.Enabled || len(body) == 0 || len(body) < cfg.MinSize {
+		return false
+	}
+	if enc := HeaderValue(headers, "Content-Encoding"); enc != "" && !strings.EqualFold(enc, "identity") {
+		return false
+	}
+	if HasCacheDirective(HeaderValue(headers, "Cache-Control"), "no-transform") {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(HeaderValue(headers, "Content-Type"))
+	if err != nil {
+		return false
+	}
+	for _, t := range cfg.Types {
+		if strings.EqualFold(strings.TrimSpace(t), mediaType) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"gzip, deflate, br", EncodingGzip},
+		{"deflate", EncodingDeflate},
+		{"GZIP", EncodingGzip},
+		{"gzip;q=0.5, deflate;q=0.8", EncodingDeflate},
+		{"gzip;q=0, deflate", EncodingDeflate},
+		{"*", EncodingGzip},
+		{"*;q=0.1, gzip;q=0", EncodingDeflate},
+		{"br", ""},
+		{"identity", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			if got := NegotiateEncoding(tt.accept); got != tt.want {
+				t.Errorf("NegotiateEncoding(%q) = %q, want %q", tt.accept, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsCompressible(t *testing.T) {
+	cfg := CompressionConfig{Enabled: true, MinSize: 100, Types: []string{"application/json", " text/html"}}
+	large := []byte(strings.Repeat("a", 100))
+	small := []byte(strings.Repeat("a", 99))
+	json := map[string]string{"Content-Type": "application/json; charset=utf-8"}
+
+	tests := []struct {
+		name    string
+		cfg     CompressionConfig
+		headers map[string]string
+		body    []byte
+		want    bool
+	}{
+		{"large json", cfg, json, large, true},
+		{"allowed type with spaces in list", cfg, map[string]string{"Content-Type": "text/html"}, large, true},
+		{"below min size", cfg, json, small, false},
+		{"disabled", CompressionConfig{MinSize: 100, Types: cfg.Types}, json, large, false},
+		{"type not allowed", cfg, map[string]string{"Content-Type": "image/png"}, large, false},
+		{"already encoded", cfg, map[string]string{"Content-Type": "application/json", "Content-Encoding": "gzip"}, large, false},
+		{"identity encoding", cfg, map[string]string{"Content-Type": "application/json", "content-encoding": "identity"}, large, true},
+		{"no-transform", cfg, map[string]string{"Content-Type": "application/json", "Cache-Control": "public, no-transform"}, large, false},
+		{"no content type", cfg, map[string]string{}, large, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsCompressible(tt.cfg, tt.headers, tt.body); got != tt.want {
+				t.Errorf("IsCompressible() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
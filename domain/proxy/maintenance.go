@@ -0,0 +1,76 @@
+package proxy
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Maintenance configures maintenance mode, in which proxy routes answer 503
+// without reaching upstreams.
+type Maintenance struct {
+	Enabled    bool
+	RetryAfter time.Duration // Sent as Retry-After; 0 = header omitted
+	Message    string        // Detail of the default JSON error; empty = ErrMaintenance's
+	JSONBody   string        // Replaces the default JSON error when set
+	HTMLBody   string        // Sent to clients that prefer HTML when set
+}
+
+// Error returns the error sent when no custom JSON body is configured.
+func (m Maintenance) Error() *ErrorResponse {
+	err := ErrMaintenance
+	if m.Message != "" {
+		err.Message = m.Message
+	}
+	return &err
+}
+
+// RetryAfterValue returns the Retry-After header value in whole seconds,
+// rounded up, or "" when no delay is configured.
+func (m Maintenance) RetryAfterValue() string {
+	if m.RetryAfter <= 0 {
+		return ""
+	}
+	secs := int64((m.RetryAfter + time.Second - 1) / time.Second)
+	return strconv.FormatInt(secs, 10)
+}
+
+// PrefersHTML returns true if an Accept header ranks text/html above
+// application/json, as browsers' navigation requests do.
+func PrefersHTML(accept string) bool {
+	return acceptQuality(accept, "text/html") > acceptQuality(accept, "application/json")
+}
+
+// acceptQuality returns the weight an Accept header gives mediaType; the most
+// specific matching range wins.
+func acceptQuality(accept, mediaType string) float64 {
+	typ, _, _ := strings.Cut(mediaType, "/")
+	best, specificity := 0.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		r, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		s := -1
+		switch {
+		case r == mediaType:
+			s = 2
+		case r == typ+"/*":
+			s = 1
+		case r == "*/*":
+			s = 0
+		}
+		if s <= specificity {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		best, specificity = q, s
+	}
+	return best
+}
@@ -0,0 +1,44 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPrefersHTML(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", true},
+		{"application/json", false},
+		{"*/*", false},
+		{"", false},
+		{"text/html;q=0.5, application/json", false},
+		{"text/*", true},
+	}
+	for _, tt := range tests {
+		if got := PrefersHTML(tt.accept); got != tt.want {
+			t.Errorf("PrefersHTML(%q) = %v, want %v", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestMaintenance_RetryAfterValue(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		0:                       "",
+		5 * time.Minute:         "300",
+		1500 * time.Millisecond: "2",
+	} {
+		if got := (Maintenance{RetryAfter: d}).RetryAfterValue(); got != want {
+			t.Errorf("RetryAfterValue(%v) = %q, want %q", d, got, want)
+		}
+	}
+
+	if got := (Maintenance{}).Error().Message; got != ErrMaintenance.Message {
+		t.Errorf("default message = %q", got)
+	}
+	if got := (Maintenance{Message: "Deploying v2"}).Error().Message; got != "Deploying v2" {
+		t.Errorf("custom message = %q", got)
+	}
+}
@@ -1,7 +1,12 @@
 // Package proxy provides request/response value types for the proxy layer.
 package proxy
 
-import "time"
+import (
+	"crypto/x509"
+	"errors"
+	"io"
+	"time"
+)
 
 // Request represents an incoming proxy request (value type).
 // This is extracted from HTTP and passed to pure functions.
@@ -21,6 +26,16 @@ type Request struct {
 	RemoteIP  string
 	UserAgent string
 	TraceID   string
+	Scheme    string // "https" when the client connected over TLS, otherwise "http"
+
+	// TLS client certificate chain, leaf first (nil when none was presented)
+	ClientCerts []*x509.Certificate
+
+	// Maximum upstream response body size in bytes (0 = upstream client default)
+	MaxResponseBody int64
+
+	// Upstream timeouts from the matched route (zero fields = upstream client defaults)
+	Timeouts Timeouts
 }
 
 // Response represents a proxy response (value type).
@@ -30,6 +45,10 @@ type Response struct {
 	Headers map[string]string
 	Body    []byte
 
+	// Stream is set instead of Body when the upstream answered with an event
+	// stream, which is relayed as it arrives. The receiver must close it.
+	Stream io.ReadCloser
+
 	// Metadata (for logging)
 	LatencyMs    int64
 	UpstreamAddr string
@@ -38,6 +57,7 @@ type Response struct {
 // AuthContext contains authenticated user information (value type).
 type AuthContext struct {
 	KeyID     string
+	KeyPrefix string // Lookup prefix of the API key; empty for sessions, JWTs and certificates
 	UserID    string
 	PlanID    string
 	RateLimit int
@@ -56,8 +76,19 @@ type ErrorResponse struct {
 	Status  int
 	Code    string
 	Message string
+	Fields  []FieldError // Per-field details, e.g. from request body validation
 }
 
+// FieldError describes a problem with one field of the request (value type).
+type FieldError struct {
+	Pointer string // JSON pointer into the request body, e.g. "/items/0/qty"
+	Message string
+}
+
+// ErrResponseBodyTooLarge is returned by upstream clients when the response
+// body exceeds Request.MaxResponseBody.
+var ErrResponseBodyTooLarge = errors.New("upstream response body too large")
+
 // Common error responses
 var (
 	ErrMissingKey = ErrorResponse{
@@ -75,6 +106,11 @@ var (
 		Code:    "rate_limit_exceeded",
 		Message: "Rate limit exceeded",
 	}
+	ErrConcurrencyLimit = ErrorResponse{
+		Status:  429,
+		Code:    "concurrency_limit",
+		Message: "Too many concurrent requests for this API key",
+	}
 	ErrQuotaExceeded = ErrorResponse{
 		Status:  402,
 		Code:    "quota_exceeded",
@@ -85,9 +121,54 @@ var (
 		Code:    "upstream_error",
 		Message: "Upstream service unavailable",
 	}
+	ErrRequestTooLarge = ErrorResponse{
+		Status:  413,
+		Code:    "request_too_large",
+		Message: "Request body too large",
+	}
+	ErrInvalidRequestBody = ErrorResponse{
+		Status:  400,
+		Code:    "invalid_request_body",
+		Message: "Request body does not match the route's schema",
+	}
+	ErrIPNotAllowed = ErrorResponse{
+		Status:  403,
+		Code:    "ip_not_allowed",
+		Message: "API key is not allowed from this IP address",
+	}
+	ErrInsufficientScope = ErrorResponse{
+		Status:  403,
+		Code:    "insufficient_scope",
+		Message: "API key lacks the scopes required for this route",
+	}
+	ErrCORSNotAllowed = ErrorResponse{
+		Status:  403,
+		Code:    "cors_not_allowed",
+		Message: "Cross-origin request is not allowed by this route's CORS policy",
+	}
+	ErrResponseTooLarge = ErrorResponse{
+		Status:  502,
+		Code:    "response_too_large",
+		Message: "Upstream response body too large",
+	}
+	ErrCircuitOpen = ErrorResponse{
+		Status:  503,
+		Code:    "circuit_open",
+		Message: "Upstream temporarily unavailable",
+	}
+	ErrMaintenance = ErrorResponse{
+		Status:  503,
+		Code:    "maintenance",
+		Message: "The API is temporarily down for maintenance",
+	}
 	ErrTimeout = ErrorResponse{
 		Status:  504,
 		Code:    "upstream_timeout",
 		Message: "Upstream service timeout",
 	}
+	ErrShuttingDown = ErrorResponse{
+		Status:  503,
+		Code:    "shutting_down",
+		Message: "The gateway is shutting down",
+	}
 )
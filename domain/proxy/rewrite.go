@@ -0,0 +1,59 @@
+package proxy
+
+import (
+	"mime"
+	"strings"
+)
+
+// IsRewritableBody returns true if a response body with these headers is
+// uncompressed JSON or HTML, so upstream URLs in it can be rewritten.
+func IsRewritableBody(headers map[string]string) bool {
+	if enc := HeaderValue(headers, "Content-Encoding"); enc != "" && !strings.EqualFold(enc, "identity") {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(HeaderValue(headers, "Content-Type"))
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") || mediaType == "text/html"
+}
+
+// PublicBaseURL returns the gateway's URL as seen by clients: configured when
+// set, otherwise built from the scheme and Host the request arrived with.
+func PublicBaseURL(configured string, req Request) string {
+	if configured != "" {
+		return strings.TrimRight(configured, "/")
+	}
+	host := HeaderValue(req.Headers, "Host")
+	if host == "" {
+		return ""
+	}
+	scheme := req.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	return scheme + "://" + host
+}
+
+// URLRewrites returns old/new replacement pairs that swap upstreamURL for
+// publicURL, in plain form and with JSON-escaped slashes.
+// Returns nil if either URL is empty or they are the same.
+func URLRewrites(upstreamURL, publicURL string) []string {
+	from := strings.TrimRight(upstreamURL, "/")
+	to := strings.TrimRight(publicURL, "/")
+	if from == "" || to == "" || from == to {
+		return nil
+	}
+	return []string{
+		from, to,
+		strings.ReplaceAll(from, "/", `\/`), strings.ReplaceAll(to, "/", `\/`),
+	}
+}
+
+// RewriteBody applies old/new replacement pairs from URLRewrites to body.
+func RewriteBody(body []byte, pairs []string) []byte {
+	if len(pairs) == 0 || len(body) == 0 {
+		return body
+	}
+	return []byte(strings.NewReplacer(pairs...).Replace(string(body)))
+}
@@ -0,0 +1,61 @@
+package proxy
+
+import "testing"
+
+func TestIsRewritableBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    bool
+	}{
+		{"json", map[string]string{"Content-Type": "application/json; charset=utf-8"}, true},
+		{"json suffix", map[string]string{"content-type": "application/problem+json"}, true},
+		{"html", map[string]string{"Content-Type": "text/html"}, true},
+		{"identity encoding", map[string]string{"Content-Type": "application/json", "Content-Encoding": "identity"}, true},
+		{"gzip", map[string]string{"Content-Type": "application/json", "Content-Encoding": "gzip"}, false},
+		{"binary", map[string]string{"Content-Type": "image/png"}, false},
+		{"plain text", map[string]string{"Content-Type": "text/plain"}, false},
+		{"no content type", map[string]string{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRewritableBody(tt.headers); got != tt.want {
+				t.Errorf("IsRewritableBody() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPublicBaseURL(t *testing.T) {
+	req := Request{Scheme: "https", Headers: map[string]string{"Host": "api.example.com"}}
+
+	if got := PublicBaseURL("https://gateway.example.com/", req); got != "https://gateway.example.com" {
+		t.Errorf("configured = %q", got)
+	}
+	if got := PublicBaseURL("", req); got != "https://api.example.com" {
+		t.Errorf("from request = %q", got)
+	}
+	if got := PublicBaseURL("", Request{Headers: map[string]string{"Host": "localhost:8080"}}); got != "http://localhost:8080" {
+		t.Errorf("without scheme = %q", got)
+	}
+	if got := PublicBaseURL("", Request{}); got != "" {
+		t.Errorf("without host = %q, want empty", got)
+	}
+}
+
+func TestRewriteBody(t *testing.T) {
+	pairs := URLRewrites("http://orders.internal:9000/", "https://api.example.com")
+	body := []byte(`{"self":"http://orders.internal:9000/orders/1","escaped":"http:\/\/orders.internal:9000\/orders\/1"}`)
+	want := `{"self":"https://api.example.com/orders/1","escaped":"https:\/\/api.example.com\/orders\/1"}`
+
+	if got := RewriteBody(body, pairs); string(got) != want {
+		t.Errorf("RewriteBody() = %s, want %s", got, want)
+	}
+	if URLRewrites("https://api.example.com", "https://api.example.com/") != nil {
+		t.Error("URLRewrites() for identical URLs should be nil")
+	}
+	if URLRewrites("http://orders.internal:9000", "") != nil {
+		t.Error("URLRewrites() without a public URL should be nil")
+	}
+}
@@ -0,0 +1,10 @@
+package proxy
+
+import "mime"
+
+// IsEventStream returns true if contentType is a Server-Sent Events stream,
+// which must be relayed to the client without buffering.
+func IsEventStream(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "text/event-stream"
+}
@@ -0,0 +1,22 @@
+package proxy
+
+import "testing"
+
+func TestIsEventStream(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{"text/event-stream", true},
+		{"text/event-stream; charset=utf-8", true},
+		{"Text/Event-Stream", true},
+		{"application/json", false},
+		{"text/plain", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsEventStream(tt.contentType); got != tt.want {
+			t.Errorf("IsEventStream(%q) = %v, want %v", tt.contentType, got, tt.want)
+		}
+	}
+}
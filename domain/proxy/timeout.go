@@ -0,0 +1,46 @@
+package proxy
+
+import (
+	"fmt"
+	"time"
+)
+
+// Timeouts bounds the stages of one upstream exchange (0 = upstream client default).
+type Timeouts struct {
+	Dial           time.Duration // Establishing the connection
+	ResponseHeader time.Duration // Waiting for the response headers once the request is sent
+	Request        time.Duration // The whole exchange, including reading the body
+}
+
+// Stages of an upstream exchange that can time out.
+const (
+	StageDial           = "dial"
+	StageResponseHeader = "response_header"
+	StageRequest        = "request"
+)
+
+// UpstreamTimeoutError is returned by upstream clients when a stage of the
+// exchange exceeds its timeout.
+type UpstreamTimeoutError struct {
+	Stage   string
+	Timeout time.Duration
+}
+
+func (e *UpstreamTimeoutError) Error() string {
+	return fmt.Sprintf("upstream %s timeout after %s", e.Stage, e.Timeout)
+}
+
+// TimeoutResponse returns the 504 sent to the client for an upstream timeout.
+// This is a PURE function.
+func TimeoutResponse(err *UpstreamTimeoutError) *ErrorResponse {
+	resp := ErrTimeout
+	switch err.Stage {
+	case StageDial:
+		resp.Message = fmt.Sprintf("Upstream connection was not established within %s", err.Timeout)
+	case StageResponseHeader:
+		resp.Message = fmt.Sprintf("Upstream sent no response headers within %s", err.Timeout)
+	case StageRequest:
+		resp.Message = fmt.Sprintf("Upstream request did not complete within %s", err.Timeout)
+	}
+	return &resp
+}
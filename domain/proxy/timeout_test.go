@@ -0,0 +1,32 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeoutResponse(t *testing.T) {
+	tests := []struct {
+		stage   string
+		timeout time.Duration
+		want    string
+	}{
+		{StageDial, 2 * time.Second, "Upstream connection was not established within 2s"},
+		{StageResponseHeader, 500 * time.Millisecond, "Upstream sent no response headers within 500ms"},
+		{StageRequest, time.Minute, "Upstream request did not complete within 1m0s"},
+	}
+	for _, tt := range tests {
+		resp := TimeoutResponse(&UpstreamTimeoutError{Stage: tt.stage, Timeout: tt.timeout})
+		if resp.Status != 504 || resp.Code != "upstream_timeout" {
+			t.Errorf("%s: got %d %s, want 504 upstream_timeout", tt.stage, resp.Status, resp.Code)
+		}
+		if resp.Message != tt.want {
+			t.Errorf("%s: message = %q, want %q", tt.stage, resp.Message, tt.want)
+		}
+	}
+
+	// The shared error response is not modified
+	if ErrTimeout.Message != "Upstream service timeout" {
+		t.Errorf("ErrTimeout.Message = %q", ErrTimeout.Message)
+	}
+}
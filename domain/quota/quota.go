@@ -3,6 +3,9 @@
 package quota
 
 import (
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/artpar/apigate/ports"
@@ -131,6 +134,16 @@ func Check(state ports.QuotaState, cfg Config, increment int64) CheckResult {
 	return result
 }
 
+// OverageUnits returns how far usage has gone past the quota limit.
+// Unlimited (negative) and zero limits have no overage.
+// This is a PURE function.
+func OverageUnits(used, limit int64) int64 {
+	if limit <= 0 || used <= limit {
+		return 0
+	}
+	return used - limit
+}
+
 // PeriodBounds returns the start and end of a billing period for a given time.
 // This is a PURE function.
 func PeriodBounds(t time.Time) (start, end time.Time) {
@@ -139,6 +152,46 @@ func PeriodBounds(t time.Time) (start, end time.Time) {
 	return
 }
 
+// Period determines when quota windows reset.
+type Period string
+
+const (
+	PeriodCalendarMonth Period = "calendar_month" // Resets on the 1st of each month (default)
+	PeriodCalendarWeek  Period = "calendar_week"  // Resets every Monday at 00:00
+	PeriodRolling30d    Period = "rolling_30d"    // Consecutive 30-day windows from an anchor time
+)
+
+// RollingWindow is the length of a rolling_30d quota window.
+const RollingWindow = 30 * 24 * time.Hour
+
+// WindowBounds returns the start and end of the quota window containing t.
+// Rolling windows are counted from anchor (typically the user's sign-up time);
+// a zero anchor counts from the Unix epoch. Unknown periods use calendar months.
+// This is a PURE function.
+func WindowBounds(period Period, t, anchor time.Time) (start, end time.Time) {
+	switch period {
+	case PeriodCalendarWeek:
+		// Weeks start on Monday
+		daysSinceMonday := (int(t.Weekday()) + 6) % 7
+		start = time.Date(t.Year(), t.Month(), t.Day()-daysSinceMonday, 0, 0, 0, 0, t.Location())
+		end = start.AddDate(0, 0, 7).Add(-time.Nanosecond)
+	case PeriodRolling30d:
+		if anchor.IsZero() {
+			anchor = time.Unix(0, 0).In(t.Location())
+		}
+		elapsed := t.Sub(anchor)
+		windows := elapsed / RollingWindow
+		if elapsed < 0 && elapsed%RollingWindow != 0 {
+			windows--
+		}
+		start = anchor.Add(windows * RollingWindow)
+		end = start.Add(RollingWindow - time.Nanosecond)
+	default:
+		start, end = PeriodBounds(t)
+	}
+	return
+}
+
 // ConfigFromPlan creates a quota Config from plan settings.
 // This is a PURE function.
 func ConfigFromPlan(p ports.Plan) Config {
@@ -151,6 +204,9 @@ func ConfigFromPlan(p ports.Plan) Config {
 	case ports.QuotaEnforceHard:
 		mode = EnforceHard
 	}
+	if p.AllowOverage {
+		mode = EnforceSoft
+	}
 
 	gracePct := p.QuotaGracePct
 	if gracePct == 0 {
@@ -194,3 +250,49 @@ func (w WarningLevel) String() string {
 		return "unknown"
 	}
 }
+
+// ParseWarningThresholds parses a comma-separated list of usage percentages,
+// such as "80,100", at which quota warnings are sent. Invalid and non-positive
+// entries are ignored; the result is sorted and free of duplicates.
+// This is a PURE function.
+func ParseWarningThresholds(s string) []int {
+	var thresholds []int
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(s, ",") {
+		pct, err := strconv.Atoi(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(part), "%")))
+		if err != nil || pct <= 0 || seen[pct] {
+			continue
+		}
+		seen[pct] = true
+		thresholds = append(thresholds, pct)
+	}
+	sort.Ints(thresholds)
+	return thresholds
+}
+
+// DueWarnings returns the thresholds, in percent of limit, that used has
+// reached and that are not in sent, in ascending order. Unlimited and zero
+// limits have no warnings.
+// This is a PURE function.
+func DueWarnings(used, limit int64, thresholds, sent []int) []int {
+	if limit <= 0 {
+		return nil
+	}
+	var due []int
+	for _, pct := range thresholds {
+		if used*100 < int64(pct)*limit {
+			continue
+		}
+		alreadySent := false
+		for _, s := range sent {
+			if s == pct {
+				alreadySent = true
+				break
+			}
+		}
+		if !alreadySent {
+			due = append(due, pct)
+		}
+	}
+	return due
+}
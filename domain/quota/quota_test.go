@@ -492,6 +492,75 @@ func TestPeriodBounds_NonLeapYear(t *testing.T) {
 	}
 }
 
+// -----------------------------------------------------------------------------
+// WindowBounds function tests
+// -----------------------------------------------------------------------------
+
+func TestWindowBounds(t *testing.T) {
+	utc := func(y int, m time.Month, d, h, min, sec, ns int) time.Time {
+		return time.Date(y, m, d, h, min, sec, ns, time.UTC)
+	}
+	signup := utc(2024, time.January, 10, 15, 30, 0, 0)
+
+	tests := []struct {
+		name      string
+		period    Period
+		t         time.Time
+		anchor    time.Time
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		// Calendar month (and the default for unknown periods)
+		{"month last instant", PeriodCalendarMonth, utc(2024, time.January, 31, 23, 59, 59, 999999999), time.Time{},
+			utc(2024, time.January, 1, 0, 0, 0, 0), utc(2024, time.January, 31, 23, 59, 59, 999999999)},
+		{"month rollover", PeriodCalendarMonth, utc(2024, time.February, 1, 0, 0, 0, 0), time.Time{},
+			utc(2024, time.February, 1, 0, 0, 0, 0), utc(2024, time.February, 29, 23, 59, 59, 999999999)},
+		{"year rollover", PeriodCalendarMonth, utc(2025, time.January, 1, 0, 0, 0, 0), time.Time{},
+			utc(2025, time.January, 1, 0, 0, 0, 0), utc(2025, time.January, 31, 23, 59, 59, 999999999)},
+		{"empty period is calendar month", "", utc(2024, time.March, 15, 12, 0, 0, 0), time.Time{},
+			utc(2024, time.March, 1, 0, 0, 0, 0), utc(2024, time.March, 31, 23, 59, 59, 999999999)},
+
+		// Calendar week (Monday to Sunday)
+		{"week monday start", PeriodCalendarWeek, utc(2024, time.March, 4, 0, 0, 0, 0), time.Time{},
+			utc(2024, time.March, 4, 0, 0, 0, 0), utc(2024, time.March, 10, 23, 59, 59, 999999999)},
+		{"week sunday last instant", PeriodCalendarWeek, utc(2024, time.March, 10, 23, 59, 59, 999999999), time.Time{},
+			utc(2024, time.March, 4, 0, 0, 0, 0), utc(2024, time.March, 10, 23, 59, 59, 999999999)},
+		{"week spanning months", PeriodCalendarWeek, utc(2024, time.March, 1, 9, 0, 0, 0), time.Time{},
+			utc(2024, time.February, 26, 0, 0, 0, 0), utc(2024, time.March, 3, 23, 59, 59, 999999999)},
+		{"week spanning years", PeriodCalendarWeek, utc(2025, time.January, 1, 0, 0, 0, 0), time.Time{},
+			utc(2024, time.December, 30, 0, 0, 0, 0), utc(2025, time.January, 5, 23, 59, 59, 999999999)},
+
+		// Rolling 30-day windows from the anchor
+		{"rolling first window", PeriodRolling30d, signup.Add(time.Hour), signup,
+			signup, signup.Add(RollingWindow - time.Nanosecond)},
+		{"rolling last instant of window", PeriodRolling30d, signup.Add(RollingWindow - time.Nanosecond), signup,
+			signup, signup.Add(RollingWindow - time.Nanosecond)},
+		{"rolling next window", PeriodRolling30d, signup.Add(RollingWindow), signup,
+			signup.Add(RollingWindow), signup.Add(2*RollingWindow - time.Nanosecond)},
+		{"rolling many windows later", PeriodRolling30d, signup.Add(12*RollingWindow + 5*time.Hour), signup,
+			signup.Add(12 * RollingWindow), signup.Add(13*RollingWindow - time.Nanosecond)},
+		{"rolling before anchor", PeriodRolling30d, signup.Add(-time.Hour), signup,
+			signup.Add(-RollingWindow), signup.Add(-time.Nanosecond)},
+		{"rolling zero anchor uses epoch", PeriodRolling30d, time.Unix(0, 0).UTC().Add(RollingWindow + time.Hour), time.Time{},
+			time.Unix(0, 0).UTC().Add(RollingWindow), time.Unix(0, 0).UTC().Add(2*RollingWindow - time.Nanosecond)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := WindowBounds(tt.period, tt.t, tt.anchor)
+			if !start.Equal(tt.wantStart) {
+				t.Errorf("start = %v, want %v", start, tt.wantStart)
+			}
+			if !end.Equal(tt.wantEnd) {
+				t.Errorf("end = %v, want %v", end, tt.wantEnd)
+			}
+			if tt.t.Before(start) || tt.t.After(end) {
+				t.Errorf("%v is outside window [%v, %v]", tt.t, start, end)
+			}
+		})
+	}
+}
+
 // -----------------------------------------------------------------------------
 // ConfigFromPlan function tests
 // -----------------------------------------------------------------------------
@@ -1186,3 +1255,96 @@ func TestMeterTypeConstants(t *testing.T) {
 		t.Errorf("expected MeterTypeComputeUnits='compute_units', got %q", MeterTypeComputeUnits)
 	}
 }
+
+// -----------------------------------------------------------------------------
+// Quota warning tests
+// -----------------------------------------------------------------------------
+
+func TestParseWarningThresholds(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []int
+	}{
+		{"80,100", []int{80, 100}},
+		{" 100, 80%, 50 ", []int{50, 80, 100}},
+		{"80,80,abc,-5,0", []int{80}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got := ParseWarningThresholds(tt.in)
+		if len(got) != len(tt.want) {
+			t.Errorf("ParseWarningThresholds(%q) = %v, want %v", tt.in, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("ParseWarningThresholds(%q) = %v, want %v", tt.in, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestDueWarnings(t *testing.T) {
+	thresholds := []int{80, 100}
+	tests := []struct {
+		name  string
+		used  int64
+		limit int64
+		sent  []int
+		want  []int
+	}{
+		{"below all", 799, 1000, nil, nil},
+		{"exactly 80%", 800, 1000, nil, []int{80}},
+		{"80% already sent", 900, 1000, []int{80}, nil},
+		{"reaches 100%", 1000, 1000, []int{80}, []int{100}},
+		{"jumps past both", 1500, 1000, nil, []int{80, 100}},
+		{"all sent", 1500, 1000, []int{80, 100}, nil},
+		{"unlimited", 5000, -1, nil, nil},
+		{"zero limit", 5000, 0, nil, nil},
+	}
+	for _, tt := range tests {
+		got := DueWarnings(tt.used, tt.limit, thresholds, tt.sent)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: DueWarnings = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: DueWarnings = %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestOverageUnits(t *testing.T) {
+	tests := []struct {
+		name  string
+		used  int64
+		limit int64
+		want  int64
+	}{
+		{"within quota", 900, 1000, 0},
+		{"at quota", 1000, 1000, 0},
+		{"past quota", 1250, 1000, 250},
+		{"unlimited", 5000, -1, 0},
+		{"zero limit", 5000, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := OverageUnits(tt.used, tt.limit); got != tt.want {
+			t.Errorf("%s: OverageUnits(%d, %d) = %d, want %d", tt.name, tt.used, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestConfigFromPlan_AllowOverage(t *testing.T) {
+	cfg := ConfigFromPlan(ports.Plan{RequestsPerMonth: 10, QuotaEnforceMode: ports.QuotaEnforceHard, AllowOverage: true})
+	if cfg.EnforceMode != EnforceSoft {
+		t.Fatalf("EnforceMode = %s, want %s", cfg.EnforceMode, EnforceSoft)
+	}
+	result := Check(ports.QuotaState{RequestCount: 10}, cfg, 1)
+	if !result.Allowed || result.OverageAmount != 1 {
+		t.Errorf("Check past quota = allowed %v, overage %d; want allowed with 1 unit of overage", result.Allowed, result.OverageAmount)
+	}
+}
@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// CheckBucket performs a token bucket rate limit check.
+// The bucket holds up to cfg.Burst tokens and refills at cfg.Limit tokens
+// per cfg.Window, so clients can send short bursts above the steady rate
+// while sustained traffic is held to it. Each request takes one token.
+// This is a PURE function - no side effects, deterministic.
+func CheckBucket(state WindowState, cfg Config, now time.Time) (CheckResult, WindowState) {
+	capacity := float64(cfg.BucketSize())
+
+	tokens := capacity
+	refilledAt := now
+	if !state.LastRefill.IsZero() {
+		tokens = state.Tokens
+		if elapsed := now.Sub(state.LastRefill); elapsed > 0 {
+			tokens += float64(elapsed) * float64(cfg.Limit) / float64(cfg.Window)
+		} else {
+			refilledAt = state.LastRefill // Clock went backwards; don't refill twice
+		}
+		tokens = math.Min(tokens, capacity)
+	}
+
+	allowed := tokens >= 1
+	if allowed {
+		tokens--
+	}
+	return BucketResult(cfg, allowed, tokens, refilledAt)
+}
+
+// BucketResult builds the check result and new state for a bucket left with
+// tokens after a check at refilledAt. Stores that run the bucket arithmetic
+// themselves (such as a Redis script) use it to report the same results as
+// CheckBucket.
+// This is a PURE function.
+func BucketResult(cfg Config, allowed bool, tokens float64, refilledAt time.Time) (CheckResult, WindowState) {
+	capacity := float64(cfg.BucketSize())
+	state := WindowState{
+		Tokens:     tokens,
+		LastRefill: refilledAt,
+		WindowEnd:  refilledAt.Add(refillTime(capacity-tokens, cfg)),
+	}
+
+	if !allowed {
+		return CheckResult{
+			Allowed:   false,
+			Remaining: 0,
+			ResetAt:   refilledAt.Add(refillTime(1-tokens, cfg)), // Next token
+			Reason:    ReasonLimitExceeded,
+		}, state
+	}
+	return CheckResult{
+		Allowed:   true,
+		Remaining: int(tokens),
+		ResetAt:   state.WindowEnd,
+	}, state
+}
+
+// BucketSize returns the token bucket capacity, defaulting to Limit.
+func (c Config) BucketSize() int {
+	if c.Burst > 0 {
+		return c.Burst
+	}
+	return c.Limit
+}
+
+// refillTime returns how long it takes to refill the given number of tokens.
+func refillTime(tokens float64, cfg Config) time.Duration {
+	if tokens <= 0 || cfg.Limit <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(tokens * float64(cfg.Window) / float64(cfg.Limit)))
+}
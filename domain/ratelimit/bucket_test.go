@@ -0,0 +1,113 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/domain/ratelimit"
+)
+
+var bucketCfg = ratelimit.Config{
+	Limit:  60, // one token per second
+	Window: time.Minute,
+	Burst:  10,
+}
+
+func TestCheckBucket_AllowsBurst(t *testing.T) {
+	var state ratelimit.WindowState
+	var result ratelimit.CheckResult
+
+	for i := 0; i < 10; i++ {
+		result, state = ratelimit.CheckBucket(state, bucketCfg, baseTime)
+		if !result.Allowed {
+			t.Fatalf("request %d denied within burst", i+1)
+		}
+		if result.Remaining != 9-i {
+			t.Errorf("request %d remaining = %d, want %d", i+1, result.Remaining, 9-i)
+		}
+	}
+
+	result, state = ratelimit.CheckBucket(state, bucketCfg, baseTime)
+	if result.Allowed {
+		t.Fatal("request beyond burst allowed")
+	}
+	if result.Reason != ratelimit.ReasonLimitExceeded {
+		t.Errorf("reason = %q, want %q", result.Reason, ratelimit.ReasonLimitExceeded)
+	}
+	if want := baseTime.Add(time.Second); !result.ResetAt.Equal(want) {
+		t.Errorf("ResetAt = %v, want next token at %v", result.ResetAt, want)
+	}
+	if want := baseTime.Add(10 * time.Second); !state.WindowEnd.Equal(want) {
+		t.Errorf("WindowEnd = %v, want bucket full at %v", state.WindowEnd, want)
+	}
+}
+
+func TestCheckBucket_SustainedRate(t *testing.T) {
+	var state ratelimit.WindowState
+	now := baseTime
+	for i := 0; i < 10; i++ {
+		_, state = ratelimit.CheckBucket(state, bucketCfg, now)
+	}
+
+	// With the burst spent, requests are allowed at the steady rate only
+	allowed := 0
+	for i := 0; i < 40; i++ {
+		now = now.Add(250 * time.Millisecond) // 4 req/s against a 1 req/s rate
+		var result ratelimit.CheckResult
+		result, state = ratelimit.CheckBucket(state, bucketCfg, now)
+		if result.Allowed {
+			allowed++
+		}
+	}
+	if allowed != 10 {
+		t.Errorf("allowed %d of 40 requests over 10s, want 10", allowed)
+	}
+}
+
+func TestCheckBucket_RefillsToCapacity(t *testing.T) {
+	var state ratelimit.WindowState
+	for i := 0; i < 10; i++ {
+		_, state = ratelimit.CheckBucket(state, bucketCfg, baseTime)
+	}
+
+	// A long idle period refills the bucket, but never beyond the burst size
+	now := baseTime.Add(time.Hour)
+	allowed := 0
+	for i := 0; i < 20; i++ {
+		result, next := ratelimit.CheckBucket(state, bucketCfg, now)
+		state = next
+		if result.Allowed {
+			allowed++
+		}
+	}
+	if allowed != 10 {
+		t.Errorf("allowed %d after idle, want burst of 10", allowed)
+	}
+}
+
+func TestCheckBucket_DefaultsToLimit(t *testing.T) {
+	cfg := ratelimit.Config{Limit: 5, Window: time.Minute}
+	if cfg.BucketSize() != 5 {
+		t.Errorf("BucketSize() = %d, want 5", cfg.BucketSize())
+	}
+
+	var state ratelimit.WindowState
+	for i := 0; i < 5; i++ {
+		var result ratelimit.CheckResult
+		if result, state = ratelimit.CheckBucket(state, cfg, baseTime); !result.Allowed {
+			t.Fatalf("request %d denied", i+1)
+		}
+	}
+	if result, _ := ratelimit.CheckBucket(state, cfg, baseTime); result.Allowed {
+		t.Error("request beyond limit allowed")
+	}
+}
+
+func TestCheckBucket_ClockSkew(t *testing.T) {
+	// A timestamp earlier than the last refill must not add or remove tokens
+	_, state := ratelimit.CheckBucket(ratelimit.WindowState{}, bucketCfg, baseTime)
+	result, next := ratelimit.CheckBucket(state, bucketCfg, baseTime.Add(-time.Minute))
+	if !result.Allowed || next.Tokens != state.Tokens-1 {
+		t.Errorf("after skew: allowed=%v tokens=%v, want allowed with %v", result.Allowed, next.Tokens, state.Tokens-1)
+	}
+}
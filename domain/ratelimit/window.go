@@ -5,10 +5,13 @@ package ratelimit
 import "time"
 
 // WindowState represents the current state of a rate limit window (value type).
+// Tokens and LastRefill hold token bucket state for CheckBucket.
 type WindowState struct {
-	Count     int       // Requests in current window
-	WindowEnd time.Time // When current window ends
-	BurstUsed int       // Burst tokens used
+	Count      int       // Requests in current window
+	WindowEnd  time.Time // When current window ends (CheckBucket: when the bucket is full again)
+	BurstUsed  int       // Burst tokens used
+	Tokens     float64   // Tokens left in the bucket at LastRefill
+	LastRefill time.Time // When Tokens was last updated (zero = full bucket)
 }
 
 // CheckResult represents the outcome of a rate limit check (value type).
@@ -24,6 +27,7 @@ type Config struct {
 	Limit       int           // Requests per window
 	Window      time.Duration // Window duration
 	BurstTokens int           // Extra tokens for bursts
+	Burst       int           // Token bucket capacity for CheckBucket (0 = Limit)
 }
 
 // Reasons for denial
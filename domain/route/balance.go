@@ -0,0 +1,25 @@
+package route
+
+// SelectWeighted picks the next upstream from pool using smooth weighted round-robin.
+// current holds the running weight of each pool member and is updated in place;
+// it must have the same length as pool. Members with a non-positive weight or
+// for which eligible returns false are skipped.
+// Returns the index of the selected member, or -1 if no member is eligible.
+func SelectWeighted(pool []WeightedUpstream, current []int, eligible func(upstreamID string) bool) int {
+	best := -1
+	total := 0
+	for i, u := range pool {
+		if u.Weight <= 0 || (eligible != nil && !eligible(u.UpstreamID)) {
+			continue
+		}
+		current[i] += u.Weight
+		total += u.Weight
+		if best == -1 || current[i] > current[best] {
+			best = i
+		}
+	}
+	if best >= 0 {
+		current[best] -= total
+	}
+	return best
+}
@@ -0,0 +1,166 @@
+package route
+
+import "time"
+
+// CircuitState is the state of an upstream circuit breaker.
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"    // Traffic flows; failures are counted
+	CircuitOpen     CircuitState = "open"      // Traffic is rejected until the open duration elapses
+	CircuitHalfOpen CircuitState = "half_open" // A limited number of trial requests are allowed
+)
+
+// UpstreamOutcome classifies the outcome of a request for upstream health
+// checking and circuit breaking.
+type UpstreamOutcome int
+
+const (
+	OutcomeSuccess UpstreamOutcome = iota // The upstream answered
+	OutcomeFailure                        // Connection error or 5xx response
+	OutcomeNeutral                        // Says nothing about the upstream, such as a client cancellation
+)
+
+// CircuitBreaker is the circuit breaker state for a single upstream (value type).
+//
+// Closed: requests and failures are counted in a fixed window of CircuitWindow.
+// Once at least CircuitMinRequests requests have completed and the failure rate
+// reaches CircuitFailureRate, the circuit opens.
+//
+// Open: requests are rejected until CircuitOpenDuration has elapsed, then the
+// circuit moves to half-open.
+//
+// HalfOpen: up to CircuitHalfOpenTrials requests are let through. A failed trial
+// re-opens the circuit; once that many trials succeed the circuit closes.
+type CircuitBreaker struct {
+	State CircuitState
+
+	WindowStart time.Time
+	Requests    int
+	Failures    int
+
+	OpenedAt time.Time
+
+	TrialsInFlight int
+	TrialSuccesses int
+}
+
+// CircuitBreakerEnabled returns true if the upstream has circuit breaking configured.
+func (u Upstream) CircuitBreakerEnabled() bool {
+	return u.CircuitFailureRate > 0
+}
+
+// Allow decides whether a request may be sent to the upstream at now and
+// returns the updated state. Half-open trials are reserved by Allow and must
+// be followed by a Record.
+func (b CircuitBreaker) Allow(u Upstream, now time.Time) (CircuitBreaker, bool) {
+	if !u.CircuitBreakerEnabled() {
+		return b, true
+	}
+
+	b = b.advance(u, now)
+	switch b.State {
+	case CircuitOpen:
+		return b, false
+	case CircuitHalfOpen:
+		if b.TrialsInFlight+b.TrialSuccesses >= halfOpenTrials(u) {
+			return b, false
+		}
+		b.TrialsInFlight++
+		return b, true
+	default:
+		return b, true
+	}
+}
+
+// Record returns the state after a request outcome.
+func (b CircuitBreaker) Record(u Upstream, failed bool, now time.Time) CircuitBreaker {
+	if !u.CircuitBreakerEnabled() {
+		return b
+	}
+
+	b = b.advance(u, now)
+	switch b.State {
+	case CircuitOpen:
+		// Outcome of a request admitted before the circuit opened
+		return b
+
+	case CircuitHalfOpen:
+		if b.TrialsInFlight > 0 {
+			b.TrialsInFlight--
+		}
+		if failed {
+			return b.open(now)
+		}
+		b.TrialSuccesses++
+		if b.TrialSuccesses >= halfOpenTrials(u) {
+			return CircuitBreaker{State: CircuitClosed, WindowStart: now}
+		}
+		return b
+
+	default:
+		b.Requests++
+		if failed {
+			b.Failures++
+		}
+		if b.Requests >= u.CircuitMinRequests &&
+			float64(b.Failures)/float64(b.Requests) >= u.CircuitFailureRate {
+			return b.open(now)
+		}
+		return b
+	}
+}
+
+// Release returns the state after a request whose outcome says nothing about
+// the upstream, such as one cancelled by the client. It frees the half-open
+// trial the request reserved without counting it as a success or failure.
+func (b CircuitBreaker) Release(u Upstream, now time.Time) CircuitBreaker {
+	if !u.CircuitBreakerEnabled() {
+		return b
+	}
+
+	b = b.advance(u, now)
+	if b.State == CircuitHalfOpen && b.TrialsInFlight > 0 {
+		b.TrialsInFlight--
+	}
+	return b
+}
+
+// StateAt returns the circuit state at now, applying time-based transitions.
+func (b CircuitBreaker) StateAt(u Upstream, now time.Time) CircuitState {
+	if !u.CircuitBreakerEnabled() {
+		return CircuitClosed
+	}
+	return b.advance(u, now).State
+}
+
+// advance applies time-based transitions: rolling the closed window over and
+// moving an open circuit to half-open once its open duration has elapsed.
+func (b CircuitBreaker) advance(u Upstream, now time.Time) CircuitBreaker {
+	switch b.State {
+	case CircuitOpen:
+		if !now.Before(b.OpenedAt.Add(u.CircuitOpenDuration)) {
+			return CircuitBreaker{State: CircuitHalfOpen, OpenedAt: b.OpenedAt}
+		}
+	case CircuitHalfOpen:
+	default:
+		b.State = CircuitClosed
+		if b.WindowStart.IsZero() || now.Sub(b.WindowStart) >= u.CircuitWindow {
+			b.WindowStart = now
+			b.Requests = 0
+			b.Failures = 0
+		}
+	}
+	return b
+}
+
+func (b CircuitBreaker) open(now time.Time) CircuitBreaker {
+	return CircuitBreaker{State: CircuitOpen, OpenedAt: now}
+}
+
+func halfOpenTrials(u Upstream) int {
+	if u.CircuitHalfOpenTrials < 1 {
+		return 1
+	}
+	return u.CircuitHalfOpenTrials
+}
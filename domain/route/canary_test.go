@@ -0,0 +1,88 @@
+package route_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/artpar/apigate/domain/route"
+)
+
+func TestCanaryBucket_Stable(t *testing.T) {
+	for _, subject := range []string{"user-1", "user-2", "ak_1234abcd"} {
+		first := route.CanaryBucket("orders-canary", subject)
+		if first < 0 || first >= route.CanaryBuckets {
+			t.Fatalf("CanaryBucket(%q) = %d, out of range", subject, first)
+		}
+		for i := 0; i < 10; i++ {
+			if got := route.CanaryBucket("orders-canary", subject); got != first {
+				t.Fatalf("CanaryBucket(%q) = %d then %d", subject, first, got)
+			}
+		}
+	}
+}
+
+func TestCanaryPolicy_Selects(t *testing.T) {
+	policy := route.CanaryPolicy{UpstreamID: "orders-canary", Percent: 10}
+
+	const users = 10000
+	selected := 0
+	for i := 0; i < users; i++ {
+		subject := fmt.Sprintf("user-%d", i)
+		if policy.Selects(subject) {
+			selected++
+			// Raising the percentage keeps existing canary users in the canary
+			wider := policy
+			wider.Percent = 25
+			if !wider.Selects(subject) {
+				t.Fatalf("%s left the canary when percent went from 10 to 25", subject)
+			}
+		}
+	}
+	if selected < users*9/100 || selected > users*11/100 {
+		t.Errorf("selected %d of %d users, want ~10%%", selected, users)
+	}
+
+	if policy.Selects("") {
+		t.Error("callers without a subject must not be selected")
+	}
+	if (route.CanaryPolicy{UpstreamID: "c", Percent: 0}).Selects("user-1") {
+		t.Error("0% must select nobody")
+	}
+	if !(route.CanaryPolicy{UpstreamID: "c", Percent: 100}).Selects("user-1") {
+		t.Error("100% must select everybody")
+	}
+}
+
+func TestCanaryPolicy_Subject(t *testing.T) {
+	byUser := route.CanaryPolicy{UpstreamID: "c"}
+	if got := byUser.Subject("user-1", "ak_1234"); got != "user-1" {
+		t.Errorf("Subject() = %q, want user ID by default", got)
+	}
+	byKey := route.CanaryPolicy{UpstreamID: "c", HashBy: route.CanaryHashKey}
+	if got := byKey.Subject("user-1", "ak_1234"); got != "ak_1234" {
+		t.Errorf("Subject() = %q, want key prefix", got)
+	}
+}
+
+func TestValidateCanaryPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  route.CanaryPolicy
+		wantErr bool
+	}{
+		{"valid", route.CanaryPolicy{UpstreamID: "c", Percent: 10}, false},
+		{"hash by key", route.CanaryPolicy{UpstreamID: "c", Percent: 10, HashBy: route.CanaryHashKey}, false},
+		{"missing upstream", route.CanaryPolicy{Percent: 10}, true},
+		{"percent too high", route.CanaryPolicy{UpstreamID: "c", Percent: 101}, true},
+		{"negative percent", route.CanaryPolicy{UpstreamID: "c", Percent: -1}, true},
+		{"unknown hash", route.CanaryPolicy{UpstreamID: "c", Percent: 10, HashBy: "ip"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := route.ValidateCanaryPolicy(tt.policy)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCanaryPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package route
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCORSMethods are allowed when a CORS policy lists no methods.
+var DefaultCORSMethods = []string{"GET", "HEAD", "POST"}
+
+// DefaultCORSHeaders are allowed in preflights when a CORS policy lists no
+// headers: the ones clients need to authenticate and send JSON.
+var DefaultCORSHeaders = []string{"Authorization", "Content-Type", "X-API-Key"}
+
+// CORSPolicy controls which browser origins may call a route.
+type CORSPolicy struct {
+	AllowedOrigins   []string      `json:"allowed_origins"`             // Exact origins, "*", or wildcard subdomains like "https://*.example.com"
+	AllowedMethods   []string      `json:"allowed_methods,omitempty"`   // Empty = DefaultCORSMethods
+	AllowedHeaders   []string      `json:"allowed_headers,omitempty"`   // Request headers allowed in preflights; "*" = any; empty = DefaultCORSHeaders
+	ExposedHeaders   []string      `json:"exposed_headers,omitempty"`   // Response headers scripts may read
+	AllowCredentials bool          `json:"allow_credentials,omitempty"` // Allow cookies and HTTP auth
+	MaxAge           time.Duration `json:"max_age,omitempty"`           // How long browsers may cache a preflight; 0 = browser default
+}
+
+// ValidateCORSPolicy checks that the policy is usable.
+// This is a PURE function.
+func ValidateCORSPolicy(p CORSPolicy) error {
+	if len(p.AllowedOrigins) == 0 {
+		return fmt.Errorf("allowed_origins is required")
+	}
+	for _, o := range p.AllowedOrigins {
+		o = strings.TrimSpace(o)
+		if o == "" {
+			return fmt.Errorf("allowed_origins must not contain empty values")
+		}
+		if o == "*" && p.AllowCredentials {
+			return fmt.Errorf("allow_credentials cannot be combined with origin \"*\"")
+		}
+	}
+	if p.MaxAge < 0 {
+		return fmt.Errorf("max_age must not be negative")
+	}
+	return nil
+}
+
+// IsPreflight returns true for a CORS preflight request: an OPTIONS request
+// carrying Origin and Access-Control-Request-Method.
+// This is a PURE function.
+func IsPreflight(method, origin, requestMethod string) bool {
+	return method == "OPTIONS" && origin != "" && requestMethod != ""
+}
+
+// AllowsOrigin returns true if origin may call the route.
+// This is a PURE function.
+func (p CORSPolicy) AllowsOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	for _, allowed := range p.AllowedOrigins {
+		allowed = strings.TrimSpace(allowed)
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+		// "https://*.example.com" matches any subdomain of example.com
+		if scheme, host, ok := strings.Cut(allowed, "://*."); ok {
+			prefix := scheme + "://"
+			if len(origin) > len(prefix) && strings.EqualFold(origin[:len(prefix)], prefix) &&
+				strings.HasSuffix(strings.ToLower(origin), "."+strings.ToLower(host)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ResponseHeaders returns the CORS headers for a response to origin, or nil
+// if the origin is not allowed.
+// This is a PURE function.
+func (p CORSPolicy) ResponseHeaders(origin string) map[string]string {
+	if !p.AllowsOrigin(origin) {
+		return nil
+	}
+	headers := map[string]string{"Access-Control-Allow-Origin": p.allowOrigin(origin)}
+	if p.AllowCredentials {
+		headers["Access-Control-Allow-Credentials"] = "true"
+	}
+	if len(p.ExposedHeaders) > 0 {
+		headers["Access-Control-Expose-Headers"] = strings.Join(p.ExposedHeaders, ", ")
+	}
+	return headers
+}
+
+// PreflightHeaders returns the headers answering a preflight from origin
+// asking for requestMethod and the comma-separated requestHeaders.
+// Returns false if the origin, method or any header is not allowed.
+// This is a PURE function.
+func (p CORSPolicy) PreflightHeaders(origin, requestMethod, requestHeaders string) (map[string]string, bool) {
+	if !p.AllowsOrigin(origin) {
+		return nil, false
+	}
+
+	methods := p.AllowedMethods
+	if len(methods) == 0 {
+		methods = DefaultCORSMethods
+	}
+	if !containsFold(methods, requestMethod) {
+		return nil, false
+	}
+
+	allowedHeaders := p.AllowedHeaders
+	if len(allowedHeaders) == 0 {
+		allowedHeaders = DefaultCORSHeaders
+	}
+	anyHeader := containsFold(allowedHeaders, "*")
+	var requested []string
+	for _, h := range strings.Split(requestHeaders, ",") {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			continue
+		}
+		if !anyHeader && !containsFold(allowedHeaders, h) {
+			return nil, false
+		}
+		requested = append(requested, h)
+	}
+
+	headers := map[string]string{
+		"Access-Control-Allow-Origin":  p.allowOrigin(origin),
+		"Access-Control-Allow-Methods": strings.Join(methods, ", "),
+	}
+	if len(requested) > 0 {
+		headers["Access-Control-Allow-Headers"] = strings.Join(requested, ", ")
+	}
+	if p.AllowCredentials {
+		headers["Access-Control-Allow-Credentials"] = "true"
+	}
+	if p.MaxAge > 0 {
+		headers["Access-Control-Max-Age"] = strconv.Itoa(int(p.MaxAge.Seconds()))
+	}
+	return headers, true
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for an allowed origin.
+func (p CORSPolicy) allowOrigin(origin string) string {
+	if !p.AllowCredentials && containsFold(p.AllowedOrigins, "*") {
+		return "*"
+	}
+	return origin
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package route
+
+import (
+	"fmt"
+	"net/textproto"
+	"strings"
+)
+
+// HeaderOp defines a header transformation operation.
+type HeaderOp string
+
+const (
+	HeaderSet    HeaderOp = "set"    // Replace the header with Value
+	HeaderAdd    HeaderOp = "add"    // Append Value to the header, comma-separated
+	HeaderRemove HeaderOp = "remove" // Strip the header
+	HeaderRename HeaderOp = "rename" // Move the header's value to To
+)
+
+// HeaderRule is one step of a route's request or response header rules.
+// Rules run in order and match header names case-insensitively.
+type HeaderRule struct {
+	Op    HeaderOp `json:"op"`
+	Name  string   `json:"name"`
+	Value string   `json:"value,omitempty"` // For set/add; supports ${ENV_VAR} and ${key.id}-style references
+	To    string   `json:"to,omitempty"`    // New header name for rename
+}
+
+// ValidateHeaderRules checks that every rule has a known operation and the
+// fields it needs.
+// This is a PURE function.
+func ValidateHeaderRules(rules []HeaderRule) error {
+	for i, r := range rules {
+		if strings.TrimSpace(r.Name) == "" {
+			return fmt.Errorf("rule %d: name is required", i)
+		}
+		switch r.Op {
+		case HeaderSet, HeaderAdd, HeaderRemove:
+		case HeaderRename:
+			if strings.TrimSpace(r.To) == "" {
+				return fmt.Errorf("rule %d: rename requires to", i)
+			}
+		case "":
+			return fmt.Errorf("rule %d: op is required", i)
+		default:
+			return fmt.Errorf("rule %d: unknown op %q (want set, add, remove or rename)", i, r.Op)
+		}
+	}
+	return nil
+}
+
+// ApplyHeaderRules returns a copy of headers with rules applied in order.
+// Values are expanded with resolve, which maps a ${name} reference to its
+// value; unresolved references expand to an empty string.
+// This is a PURE function.
+func ApplyHeaderRules(headers map[string]string, rules []HeaderRule, resolve func(name string) string) map[string]string {
+	result := make(map[string]string, len(headers)+len(rules))
+	for k, v := range headers {
+		result[k] = v
+	}
+
+	for _, r := range rules {
+		name := textproto.CanonicalMIMEHeaderKey(r.Name)
+		switch r.Op {
+		case HeaderSet:
+			deleteHeader(result, r.Name)
+			result[name] = ExpandHeaderValue(r.Value, resolve)
+
+		case HeaderAdd:
+			value := ExpandHeaderValue(r.Value, resolve)
+			if k, ok := findHeader(result, r.Name); ok && result[k] != "" {
+				result[k] = result[k] + ", " + value
+			} else {
+				deleteHeader(result, r.Name)
+				result[name] = value
+			}
+
+		case HeaderRemove:
+			deleteHeader(result, r.Name)
+
+		case HeaderRename:
+			k, ok := findHeader(result, r.Name)
+			if !ok {
+				continue
+			}
+			value := result[k]
+			deleteHeader(result, r.Name)
+			deleteHeader(result, r.To)
+			result[textproto.CanonicalMIMEHeaderKey(r.To)] = value
+		}
+	}
+
+	return result
+}
+
+// ExpandHeaderValue replaces ${name} references in value using resolve.
+// This is a PURE function.
+func ExpandHeaderValue(value string, resolve func(name string) string) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(value, "${")
+		if start == -1 {
+			break
+		}
+		end := strings.IndexByte(value[start+2:], '}')
+		if end == -1 {
+			break
+		}
+		b.WriteString(value[:start])
+		if resolve != nil {
+			b.WriteString(resolve(value[start+2 : start+2+end]))
+		}
+		value = value[start+2+end+1:]
+	}
+	b.WriteString(value)
+	return b.String()
+}
+
+// findHeader returns the key in headers matching name case-insensitively.
+func findHeader(headers map[string]string, name string) (string, bool) {
+	if _, ok := headers[name]; ok {
+		return name, true
+	}
+	for k := range headers {
+		if strings.EqualFold(k, name) {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// deleteHeader removes every key in headers matching name case-insensitively.
+func deleteHeader(headers map[string]string, name string) {
+	for k := range headers {
+		if strings.EqualFold(k, name) {
+			delete(headers, k)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package route
+
+import "time"
+
+// PassiveHealth tracks consecutive failures observed on live traffic to an upstream (value type).
+type PassiveHealth struct {
+	ConsecutiveFailures int
+	EjectedUntil        time.Time
+}
+
+// Record returns the state after a request outcome.
+// A success resets the failure count. Reaching the upstream's FailureThreshold
+// ejects it until now + EjectionCooldown; a threshold <= 0 disables ejection.
+func (h PassiveHealth) Record(u Upstream, failed bool, now time.Time) PassiveHealth {
+	if !failed {
+		h.ConsecutiveFailures = 0
+		return h
+	}
+
+	h.ConsecutiveFailures++
+	if u.FailureThreshold > 0 && h.ConsecutiveFailures >= u.FailureThreshold {
+		h.EjectedUntil = now.Add(u.EjectionCooldown)
+		h.ConsecutiveFailures = 0
+	}
+	return h
+}
+
+// Ejected returns true if the upstream is out of rotation at now.
+func (h PassiveHealth) Ejected(now time.Time) bool {
+	return now.Before(h.EjectedUntil)
+}
+
+// HealthCheckEnabled returns true if the upstream is actively probed.
+func (u Upstream) HealthCheckEnabled() bool {
+	return u.HealthCheckPath != ""
+}
+
+// ProbeHealthy evaluates an active health probe result for the upstream.
+// A probe passes if it completed without error and returned the expected
+// status (200 when unset).
+func (u Upstream) ProbeHealthy(status int, err error) bool {
+	if err != nil {
+		return false
+	}
+	expected := u.HealthCheckExpectedStatus
+	if expected == 0 {
+		expected = 200
+	}
+	return status == expected
+}
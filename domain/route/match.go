@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -208,9 +209,16 @@ func compileHostPattern(cp *compiledPattern, hostPattern string, hostMatchType H
 }
 
 // Match finds the first matching route for the given request.
-// Returns nil if no route matches.
-// Matching order: host -> method -> path -> headers
+// Returns nil if no route matches. Routes with query conditions are matched
+// against an empty query; use MatchQuery when the query string is known.
 func (m *Matcher) Match(method, path string, headers map[string]string) *MatchResult {
+	return m.MatchQuery(method, path, "", headers)
+}
+
+// MatchQuery finds the first matching route for a request with the given
+// raw query string. Returns nil if no route matches.
+// Matching order: host -> method -> path -> headers -> query
+func (m *Matcher) MatchQuery(method, path, rawQuery string, headers map[string]string) *MatchResult {
 	// Extract and normalize host from headers
 	host := normalizeHost(headers["Host"])
 
@@ -238,6 +246,11 @@ func (m *Matcher) Match(method, path string, headers map[string]string) *MatchRe
 			continue
 		}
 
+		// 5. Check query conditions
+		if !matchQuery(route.QueryMatch, rawQuery) {
+			continue
+		}
+
 		return &MatchResult{
 			Route:      route,
 			PathParams: pathParams,
@@ -247,6 +260,45 @@ func (m *Matcher) Match(method, path string, headers map[string]string) *MatchRe
 	return nil
 }
 
+// MatchReason describes the conditions a request met to match the route,
+// e.g. "host exact api.example.com; path prefix /orders/*; methods GET,POST".
+// This is a PURE function.
+func (r Route) MatchReason() string {
+	var parts []string
+	if r.HostPattern != "" {
+		hostType := r.HostMatchType
+		if hostType == HostMatchNone {
+			hostType = HostMatchExact
+			if strings.HasPrefix(r.HostPattern, "*.") {
+				hostType = HostMatchWildcard
+			}
+		}
+		parts = append(parts, "host "+string(hostType)+" "+r.HostPattern)
+	}
+	parts = append(parts, "path "+string(r.MatchType)+" "+r.PathPattern)
+	if len(r.Methods) > 0 {
+		parts = append(parts, "methods "+strings.Join(r.Methods, ","))
+	}
+	if len(r.Headers) > 0 {
+		names := make([]string, len(r.Headers))
+		for i, h := range r.Headers {
+			names[i] = h.Name
+		}
+		parts = append(parts, "headers "+strings.Join(names, ","))
+	}
+	if len(r.QueryMatch) > 0 {
+		names := make([]string, len(r.QueryMatch))
+		for i, q := range r.QueryMatch {
+			names[i] = q.Name
+		}
+		parts = append(parts, "query "+strings.Join(names, ","))
+	}
+	if r.Priority != 0 {
+		parts = append(parts, "priority "+strconv.Itoa(r.Priority))
+	}
+	return strings.Join(parts, "; ")
+}
+
 // normalizeHost normalizes the host header value.
 // Removes port, trailing dots, and converts to lowercase.
 func normalizeHost(host string) string {
@@ -1768,3 +1768,77 @@ func TestRoute_WithHost(t *testing.T) {
 		t.Errorf("HostMatchType = %s, want regex", r4.HostMatchType)
 	}
 }
+
+func TestRoute_MatchReason(t *testing.T) {
+	tests := []struct {
+		name  string
+		route route.Route
+		want  string
+	}{
+		{
+			name:  "path only",
+			route: route.Route{PathPattern: "/status", MatchType: route.MatchExact},
+			want:  "path exact /status",
+		},
+		{
+			name: "all conditions",
+			route: route.Route{
+				HostPattern: "api.example.com", HostMatchType: route.HostMatchExact,
+				PathPattern: "/orders/*", MatchType: route.MatchPrefix,
+				Methods:  []string{"GET", "POST"},
+				Headers:  []route.HeaderMatch{{Name: "X-Version", Value: "2"}},
+				Priority: 10,
+			},
+			want: "host exact api.example.com; path prefix /orders/*; methods GET,POST; headers X-Version; priority 10",
+		},
+		{
+			name:  "inferred host match type",
+			route: route.Route{HostPattern: "*.example.com", PathPattern: "/", MatchType: route.MatchPrefix},
+			want:  "host wildcard *.example.com; path prefix /",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.route.MatchReason(); got != tt.want {
+				t.Errorf("MatchReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatcher_MultiTenantSubdomains(t *testing.T) {
+	routes := []route.Route{
+		{ID: "api", PathPattern: "/*", MatchType: route.MatchPrefix, HostPattern: "api.example.com", HostMatchType: route.HostMatchExact, UpstreamID: "api-svc", Enabled: true},
+		{ID: "tenants", PathPattern: "/*", MatchType: route.MatchPrefix, HostPattern: "*.apps.example.com", HostMatchType: route.HostMatchWildcard, UpstreamID: "tenant-svc", Enabled: true},
+	}
+	matcher, err := route.NewMatcher(routes)
+	if err != nil {
+		t.Fatalf("NewMatcher: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		host string
+		want string // "" = no match
+	}{
+		{"exact host", "api.example.com", "api"},
+		{"exact host with port", "API.example.com:8443", "api"},
+		{"single-level wildcard", "acme.apps.example.com", "tenants"},
+		{"wildcard is one level only", "eu.acme.apps.example.com", ""},
+		{"wildcard needs a subdomain", "apps.example.com", ""},
+		{"non-matching host", "www.example.com", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := matcher.Match("GET", "/orders", map[string]string{"Host": tt.host})
+			got := ""
+			if result != nil {
+				got = result.Route.ID
+			}
+			if got != tt.want {
+				t.Errorf("host %s matched %q, want %q", tt.host, got, tt.want)
+			}
+		})
+	}
+}
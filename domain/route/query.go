@@ -0,0 +1,97 @@
+package route
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// QueryMatch defines query-parameter-based routing criteria, e.g. only match
+// requests carrying ?version=2. A parameter with several values matches if
+// any of them does.
+type QueryMatch struct {
+	Name     string `json:"name"`               // Query parameter name
+	Value    string `json:"value,omitempty"`    // Expected value (exact or regex if IsRegex); empty = any value
+	IsRegex  bool   `json:"is_regex,omitempty"` // If true, Value is treated as regex
+	Required bool   `json:"required,omitempty"` // If true, the parameter must be present
+}
+
+// ValidateQueryMatches checks a route's query conditions and stripped
+// parameter names.
+// This is a PURE function.
+func ValidateQueryMatches(conditions []QueryMatch, strip []string) error {
+	for i, cond := range conditions {
+		if cond.Name == "" {
+			return fmt.Errorf("query match %d: name is required", i)
+		}
+		if cond.IsRegex {
+			if _, err := regexp.Compile(cond.Value); err != nil {
+				return fmt.Errorf("query match %s: invalid regex: %w", cond.Name, err)
+			}
+		}
+	}
+	for _, name := range strip {
+		if name == "" {
+			return fmt.Errorf("strip query: parameter name is required")
+		}
+	}
+	return nil
+}
+
+// matchQuery checks if all query conditions are satisfied by rawQuery.
+func matchQuery(conditions []QueryMatch, rawQuery string) bool {
+	if len(conditions) == 0 {
+		return true
+	}
+	values, _ := url.ParseQuery(rawQuery)
+	for _, cond := range conditions {
+		got, exists := values[cond.Name]
+
+		if cond.Required && !exists {
+			return false
+		}
+		if !exists || cond.Value == "" {
+			continue
+		}
+
+		var regex *regexp.Regexp
+		if cond.IsRegex {
+			var err error
+			if regex, err = regexp.Compile(cond.Value); err != nil {
+				return false
+			}
+		}
+		if !slices.ContainsFunc(got, func(v string) bool {
+			if regex != nil {
+				return regex.MatchString(v)
+			}
+			return v == cond.Value
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// StripQueryParams removes the named parameters from rawQuery, keeping the
+// order and encoding of the rest.
+// This is a PURE function.
+func StripQueryParams(rawQuery string, names []string) string {
+	if rawQuery == "" || len(names) == 0 {
+		return rawQuery
+	}
+	pairs := strings.Split(rawQuery, "&")
+	kept := pairs[:0]
+	for _, pair := range pairs {
+		name, _, _ := strings.Cut(pair, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if !slices.Contains(names, name) {
+			kept = append(kept, pair)
+		}
+	}
+	return strings.Join(kept, "&")
+}
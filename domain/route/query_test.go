@@ -0,0 +1,144 @@
+package route_test
+
+import (
+	"testing"
+
+	"github.com/artpar/apigate/domain/route"
+)
+
+func TestMatcher_QueryMatching(t *testing.T) {
+	routes := []route.Route{
+		{
+			ID:          "v2",
+			Name:        "items-v2",
+			PathPattern: "/items/*",
+			MatchType:   route.MatchPrefix,
+			QueryMatch:  []route.QueryMatch{{Name: "version", Value: "2", Required: true}},
+			UpstreamID:  "up-v2",
+			Enabled:     true,
+			Priority:    10,
+		},
+		{
+			ID:          "beta",
+			Name:        "items-beta",
+			PathPattern: "/items/*",
+			MatchType:   route.MatchPrefix,
+			QueryMatch:  []route.QueryMatch{{Name: "channel", Value: "^beta-[0-9]+$", IsRegex: true, Required: true}},
+			UpstreamID:  "up-beta",
+			Enabled:     true,
+			Priority:    5,
+		},
+		{
+			ID:          "v1",
+			Name:        "items",
+			PathPattern: "/items/*",
+			MatchType:   route.MatchPrefix,
+			UpstreamID:  "up-v1",
+			Enabled:     true,
+		},
+	}
+
+	matcher, err := route.NewMatcher(routes)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"version=2", "v2"},
+		{"limit=10&version=2", "v2"},
+		{"version=1&version=2", "v2"},
+		{"version=3", "v1"},
+		{"", "v1"},
+		{"channel=beta-7", "beta"},
+		{"channel=beta", "v1"},
+		{"version=%32", "v2"},
+	}
+	for _, tt := range tests {
+		result := matcher.MatchQuery("GET", "/items/42", tt.query, nil)
+		if result == nil {
+			t.Errorf("MatchQuery(%q) = nil, want %s", tt.query, tt.want)
+			continue
+		}
+		if result.Route.ID != tt.want {
+			t.Errorf("MatchQuery(%q) = %s, want %s", tt.query, result.Route.ID, tt.want)
+		}
+	}
+
+	// Match has no query, so query-conditioned routes are skipped
+	if result := matcher.Match("GET", "/items/42", nil); result == nil || result.Route.ID != "v1" {
+		t.Errorf("Match() = %+v, want v1", result)
+	}
+}
+
+func TestMatcher_QueryMatchNotRequired(t *testing.T) {
+	routes := []route.Route{
+		{
+			ID:          "r1",
+			PathPattern: "/search",
+			MatchType:   route.MatchExact,
+			QueryMatch:  []route.QueryMatch{{Name: "format", Value: "json"}},
+			Enabled:     true,
+		},
+	}
+	matcher, err := route.NewMatcher(routes)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+
+	if matcher.MatchQuery("GET", "/search", "", nil) == nil {
+		t.Error("absent optional param should match")
+	}
+	if matcher.MatchQuery("GET", "/search", "format=json", nil) == nil {
+		t.Error("matching optional param should match")
+	}
+	if matcher.MatchQuery("GET", "/search", "format=xml", nil) != nil {
+		t.Error("mismatching optional param should not match")
+	}
+}
+
+func TestStripQueryParams(t *testing.T) {
+	tests := []struct {
+		query string
+		names []string
+		want  string
+	}{
+		{"version=2&limit=10", []string{"version"}, "limit=10"},
+		{"limit=10&version=2&sort=name", []string{"version"}, "limit=10&sort=name"},
+		{"version=1&version=2", []string{"version"}, ""},
+		{"api%5Fkey=x&q=a%20b", []string{"api_key"}, "q=a%20b"},
+		{"flag&version=2", []string{"flag"}, "version=2"},
+		{"limit=10", nil, "limit=10"},
+		{"", []string{"version"}, ""},
+	}
+	for _, tt := range tests {
+		if got := route.StripQueryParams(tt.query, tt.names); got != tt.want {
+			t.Errorf("StripQueryParams(%q, %v) = %q, want %q", tt.query, tt.names, got, tt.want)
+		}
+	}
+}
+
+func TestValidateQueryMatches(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions []route.QueryMatch
+		strip      []string
+		wantErr    bool
+	}{
+		{"empty", nil, nil, false},
+		{"valid", []route.QueryMatch{{Name: "version", Value: "2"}}, []string{"version"}, false},
+		{"missing name", []route.QueryMatch{{Value: "2"}}, nil, true},
+		{"bad regex", []route.QueryMatch{{Name: "v", Value: "(", IsRegex: true}}, nil, true},
+		{"empty strip name", nil, []string{""}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := route.ValidateQueryMatches(tt.conditions, tt.strip)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQueryMatches() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
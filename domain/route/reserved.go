@@ -0,0 +1,85 @@
+package route
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ReservedPaths is a registry of path prefixes owned by built-in endpoints
+// (admin API, health checks, metering API, ...). Requests under a reserved
+// prefix are always served by the built-in handler, whatever the priority of
+// a matching proxy route, and proxy routes may not claim them.
+type ReservedPaths struct {
+	prefixes []string
+}
+
+// NewReservedPaths creates a registry of the given prefixes.
+// This is a PURE function.
+func NewReservedPaths(prefixes ...string) ReservedPaths {
+	var r ReservedPaths
+	return r.With(prefixes...)
+}
+
+// With returns a copy of the registry with more prefixes added.
+// Trailing slashes are ignored, and empty prefixes and "/" are skipped.
+// This is a PURE function.
+func (r ReservedPaths) With(prefixes ...string) ReservedPaths {
+	out := ReservedPaths{prefixes: append([]string(nil), r.prefixes...)}
+	for _, p := range prefixes {
+		p = strings.TrimSuffix(p, "/")
+		if p == "" {
+			continue
+		}
+		if !strings.HasPrefix(p, "/") {
+			p = "/" + p
+		}
+		out.prefixes = append(out.prefixes, p)
+	}
+	return out
+}
+
+// Prefixes returns the reserved prefixes in registration order.
+func (r ReservedPaths) Prefixes() []string {
+	return append([]string(nil), r.prefixes...)
+}
+
+// Owner returns the reserved prefix that path falls under: the prefix itself
+// or anything below it at a segment boundary.
+// This is a PURE function.
+func (r ReservedPaths) Owner(path string) (string, bool) {
+	for _, p := range r.prefixes {
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// Contains reports whether path is reserved.
+// This is a PURE function.
+func (r ReservedPaths) Contains(path string) bool {
+	_, ok := r.Owner(path)
+	return ok
+}
+
+// ValidateRoute returns an error if the route's path pattern lies inside a
+// reserved prefix. Broader routes such as "/api/*" are allowed; they just
+// never receive requests for reserved paths. Regex patterns are not checked.
+// This is a PURE function.
+func (r ReservedPaths) ValidateRoute(rt Route) error {
+	if rt.MatchType == MatchRegex {
+		return nil
+	}
+	base := rt.PathPattern
+	if i := strings.IndexAny(base, "*{"); i >= 0 {
+		base = base[:i]
+	}
+	base = strings.TrimSuffix(base, "/")
+	if base == "" {
+		return nil
+	}
+	if owner, ok := r.Owner(base); ok {
+		return fmt.Errorf("path %q is reserved for the built-in endpoint at %s", rt.PathPattern, owner)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package route_test
+
+import (
+	"testing"
+
+	"github.com/artpar/apigate/domain/route"
+)
+
+func TestReservedPaths_Contains(t *testing.T) {
+	reserved := route.NewReservedPaths("/admin/", "/api/v1/meter", "", "/")
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/admin", true},
+		{"/admin/users", true},
+		{"/administrator", false},
+		{"/api/v1/meter", true},
+		{"/api/v1/meter/events", true},
+		{"/api/v1/meters", false},
+		{"/api/v1/orders", false},
+		{"/", false},
+	}
+	for _, tt := range tests {
+		if got := reserved.Contains(tt.path); got != tt.want {
+			t.Errorf("Contains(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	if got := reserved.Prefixes(); len(got) != 2 {
+		t.Errorf("Prefixes() = %v, want 2 entries", got)
+	}
+}
+
+func TestReservedPaths_ValidateRoute(t *testing.T) {
+	reserved := route.NewReservedPaths("/admin", "/api/v1/meter")
+
+	tests := []struct {
+		name    string
+		rt      route.Route
+		wantErr bool
+	}{
+		{"exact reserved path", route.Route{PathPattern: "/api/v1/meter", MatchType: route.MatchExact}, true},
+		{"prefix under reserved path", route.Route{PathPattern: "/api/v1/meter/*", MatchType: route.MatchPrefix}, true},
+		{"param under reserved path", route.Route{PathPattern: "/admin/{section}", MatchType: route.MatchExact}, true},
+		{"catch-all over reserved path", route.Route{PathPattern: "/api/*", MatchType: route.MatchPrefix}, false},
+		{"root catch-all", route.Route{PathPattern: "/*", MatchType: route.MatchPrefix}, false},
+		{"similar name", route.Route{PathPattern: "/api/v1/meters", MatchType: route.MatchExact}, false},
+		{"regex is not checked", route.Route{PathPattern: "^/admin/.*$", MatchType: route.MatchRegex}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := reserved.ValidateRoute(tt.rt)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRoute(%q) error = %v, wantErr %v", tt.rt.PathPattern, err, tt.wantErr)
+			}
+		})
+	}
+}
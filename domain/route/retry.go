@@ -0,0 +1,88 @@
+package route
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaxRetryAttempts caps RetryPolicy.Attempts.
+const MaxRetryAttempts = 5
+
+// maxRetryBackoff caps the delay between two attempts.
+const maxRetryBackoff = 30 * time.Second
+
+// DefaultRetryMethods are retried when a policy lists no methods. Only safe
+// methods are retried by default; other methods must be listed explicitly.
+var DefaultRetryMethods = []string{"GET", "HEAD", "OPTIONS"}
+
+// RetryPolicy controls how a route retries failed upstream requests.
+// Connection failures are always retriable; responses are retried only when
+// their status is listed in Statuses.
+type RetryPolicy struct {
+	Attempts int           `json:"attempts"`           // Retries after the first try; 0 = disabled
+	Backoff  time.Duration `json:"backoff"`            // Delay before the first retry, doubled for each further retry
+	Methods  []string      `json:"methods,omitempty"`  // Methods that may be retried; empty = DefaultRetryMethods
+	Statuses []int         `json:"statuses,omitempty"` // Response statuses that are retried, e.g. 502, 503
+}
+
+// ValidateRetryPolicy checks that the policy's values are in range.
+// This is a PURE function.
+func ValidateRetryPolicy(p RetryPolicy) error {
+	if p.Attempts < 0 || p.Attempts > MaxRetryAttempts {
+		return fmt.Errorf("attempts must be between 0 and %d", MaxRetryAttempts)
+	}
+	if p.Backoff < 0 {
+		return fmt.Errorf("backoff must not be negative")
+	}
+	for _, m := range p.Methods {
+		if strings.TrimSpace(m) == "" {
+			return fmt.Errorf("methods must not contain empty values")
+		}
+	}
+	for _, s := range p.Statuses {
+		if s < 100 || s > 599 {
+			return fmt.Errorf("status %d is not a valid HTTP status", s)
+		}
+	}
+	return nil
+}
+
+// AllowsMethod returns true if requests with method may be retried.
+// This is a PURE function.
+func (p RetryPolicy) AllowsMethod(method string) bool {
+	methods := p.Methods
+	if len(methods) == 0 {
+		methods = DefaultRetryMethods
+	}
+	for _, m := range methods {
+		if strings.EqualFold(strings.TrimSpace(m), method) {
+			return true
+		}
+	}
+	return false
+}
+
+// RetriesStatus returns true if a response with status should be retried.
+// This is a PURE function.
+func (p RetryPolicy) RetriesStatus(status int) bool {
+	for _, s := range p.Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// BackoffBefore returns the delay before retry n (1 = first retry).
+// This is a PURE function.
+func (p RetryPolicy) BackoffBefore(n int) time.Duration {
+	if p.Backoff <= 0 || n < 1 {
+		return 0
+	}
+	d := p.Backoff
+	for i := 1; i < n && d < maxRetryBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxRetryBackoff)
+}
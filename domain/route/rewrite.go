@@ -0,0 +1,72 @@
+package route
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// PathRewriteRule rewrites the upstream path with a regular expression.
+// The part of the path matched by Regex is replaced with Replacement, which
+// may refer to captures as $1, ${1} or ${name}. Paths that do not match are
+// left unchanged.
+type PathRewriteRule struct {
+	Regex       string `json:"regex"`       // e.g. "^/users/([0-9]+)/profile$"
+	Replacement string `json:"replacement"` // e.g. "/v2/profiles/$1"
+}
+
+// ValidatePathRewrite checks a route's strip prefix and regex rewrite.
+// This is a PURE function.
+func ValidatePathRewrite(stripPrefix string, rule *PathRewriteRule) error {
+	if stripPrefix != "" && !strings.HasPrefix(stripPrefix, "/") {
+		return fmt.Errorf("strip prefix %q must start with /", stripPrefix)
+	}
+	if rule == nil {
+		return nil
+	}
+	if rule.Regex == "" {
+		return fmt.Errorf("rewrite regex is required")
+	}
+	if _, err := regexp.Compile(rule.Regex); err != nil {
+		return fmt.Errorf("invalid rewrite regex: %w", err)
+	}
+	return nil
+}
+
+// RewriteUpstreamPath applies the route's StripPrefix and then its
+// RewritePath rule to a request path, giving the path sent upstream.
+// An invalid rewrite regex leaves the path unchanged.
+// This is a PURE function.
+func (r Route) RewriteUpstreamPath(path string) string {
+	path = StripPathPrefix(path, r.StripPrefix)
+	if r.RewritePath == nil {
+		return path
+	}
+	re, err := regexp.Compile(r.RewritePath.Regex)
+	if err != nil || !re.MatchString(path) {
+		return path
+	}
+	path = re.ReplaceAllString(path, r.RewritePath.Replacement)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path
+}
+
+// StripPathPrefix removes prefix from path at a segment boundary, so
+// "/api/v1" turns "/api/v1/orders" into "/orders" and "/api/v1" into "/",
+// but leaves "/api/v10" alone.
+// This is a PURE function.
+func StripPathPrefix(path, prefix string) string {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		return path
+	}
+	if path == prefix {
+		return "/"
+	}
+	if rest, ok := strings.CutPrefix(path, prefix); ok && strings.HasPrefix(rest, "/") {
+		return rest
+	}
+	return path
+}
@@ -0,0 +1,78 @@
+package route_test
+
+import (
+	"testing"
+
+	"github.com/artpar/apigate/domain/route"
+)
+
+func TestRoute_RewriteUpstreamPath(t *testing.T) {
+	tests := []struct {
+		name  string
+		route route.Route
+		path  string
+		want  string
+	}{
+		{"no rewrite", route.Route{}, "/api/v1/orders", "/api/v1/orders"},
+		{"strip prefix", route.Route{StripPrefix: "/api/v1"}, "/api/v1/orders", "/orders"},
+		{"strip prefix with trailing slash", route.Route{StripPrefix: "/api/v1/"}, "/api/v1/orders/7", "/orders/7"},
+		{"strip whole path", route.Route{StripPrefix: "/api/v1"}, "/api/v1", "/"},
+		{"strip at segment boundary only", route.Route{StripPrefix: "/api/v1"}, "/api/v10/orders", "/api/v10/orders"},
+		{
+			"regex captures",
+			route.Route{RewritePath: &route.PathRewriteRule{Regex: `^/users/([0-9]+)/orders/([0-9]+)$`, Replacement: "/v2/orders/$2/user/$1"}},
+			"/users/12/orders/34", "/v2/orders/34/user/12",
+		},
+		{
+			"named captures",
+			route.Route{RewritePath: &route.PathRewriteRule{Regex: `^/(?P<tenant>[a-z]+)/items`, Replacement: "/items/${tenant}"}},
+			"/acme/items/5", "/items/acme/5",
+		},
+		{
+			"strip then rewrite",
+			route.Route{StripPrefix: "/api/v1", RewritePath: &route.PathRewriteRule{Regex: `^/orders/(.+)$`, Replacement: "/order-service/$1"}},
+			"/api/v1/orders/42", "/order-service/42",
+		},
+		{
+			"non-matching regex leaves path",
+			route.Route{RewritePath: &route.PathRewriteRule{Regex: `^/users/([0-9]+)$`, Replacement: "/u/$1"}},
+			"/orders/1", "/orders/1",
+		},
+		{
+			"leading slash added",
+			route.Route{RewritePath: &route.PathRewriteRule{Regex: `^/legacy/`, Replacement: ""}},
+			"/legacy/reports", "/reports",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.route.RewriteUpstreamPath(tt.path); got != tt.want {
+				t.Errorf("RewriteUpstreamPath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePathRewrite(t *testing.T) {
+	tests := []struct {
+		name    string
+		strip   string
+		rule    *route.PathRewriteRule
+		wantErr bool
+	}{
+		{"empty", "", nil, false},
+		{"valid strip", "/api/v1", nil, false},
+		{"strip without slash", "api/v1", nil, true},
+		{"valid rule", "", &route.PathRewriteRule{Regex: `^/a/(.*)$`, Replacement: "/b/$1"}, false},
+		{"missing regex", "", &route.PathRewriteRule{Replacement: "/b"}, true},
+		{"bad regex", "", &route.PathRewriteRule{Regex: `^/a/(`, Replacement: "/b"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := route.ValidatePathRewrite(tt.strip, tt.rule)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePathRewrite() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
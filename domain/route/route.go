@@ -3,6 +3,9 @@
 package route
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -32,7 +35,17 @@ const (
 	ProtocolHTTP       Protocol = "http"        // Buffered HTTP (default)
 	ProtocolHTTPStream Protocol = "http_stream" // Chunked transfer, real-time forwarding
 	ProtocolSSE        Protocol = "sse"         // Server-Sent Events passthrough
-	ProtocolWebSocket  Protocol = "websocket"   // Bidirectional WebSocket (future)
+	ProtocolWebSocket  Protocol = "websocket"   // Bidirectional WebSocket
+	ProtocolGRPC       Protocol = "grpc"        // gRPC over HTTP/2 with trailers and bidirectional streaming
+)
+
+// AuthMethod defines how clients authenticate on a route.
+type AuthMethod string
+
+const (
+	AuthMethodAPIKey AuthMethod = "api_key" // API key or session token (default)
+	AuthMethodHMAC   AuthMethod = "hmac"    // HMAC-SHA256 signed requests using the key's signing secret
+	AuthMethodMTLS   AuthMethod = "mtls"    // TLS client certificate issued by a trusted client CA
 )
 
 // AuthType defines how to authenticate with an upstream.
@@ -65,26 +78,68 @@ type Route struct {
 	MatchType   MatchType // How to interpret pattern
 	Methods     []string  // HTTP methods to match; empty = all methods
 	Headers     []HeaderMatch // Optional header-based matching conditions
+	QueryMatch  []QueryMatch  // Optional query-parameter-based matching conditions
+	StripQuery  []string      // Query parameters removed before forwarding
 
 	// Target configuration
-	UpstreamID     string // Reference to Upstream entity
-	PathRewrite    string // Expr expression for path rewriting
-	MethodOverride string // Override request method (e.g., GET -> POST)
+	UpstreamID       string             // Reference to Upstream entity
+	Upstreams        []WeightedUpstream // Optional weighted pool; overrides UpstreamID when non-empty
+	Canary           *CanaryPolicy      // Optional share of callers sent to a canary upstream
+	MirrorUpstreamID string             // Optional upstream receiving a copy of each request; its responses are discarded
+	StripPrefix      string             // Removed from the start of the path before forwarding, e.g. "/api/v1"
+	RewritePath      *PathRewriteRule   // Regex rewrite of the path, applied after StripPrefix
+	PathRewrite      string             // Expr expression for path rewriting, applied last
+	MethodOverride   string             // Override request method (e.g., GET -> POST)
 
 	// Transformations (stored as JSON, parsed into Transform structs)
 	RequestTransform  *Transform // Applied before forwarding
 	ResponseTransform *Transform // Applied after receiving response
 
+	// Header rules (stored as JSON), applied in order after the transforms
+	RequestHeaders  []HeaderRule // Applied to the request before it is sent upstream
+	ResponseHeaders []HeaderRule // Applied to the upstream response before it is returned
+
+	// Replace the upstream's base URL with the gateway's public URL in JSON and HTML response bodies
+	RewriteResponseURLs bool
+
 	// Metering configuration
 	MeteringExpr string // Expr to extract usage value from response
 	MeteringMode string // "request", "response_field", "bytes", "custom"
 	MeteringUnit string // Display unit: "requests", "tokens", "data_points", "bytes" (for UI labels)
 
+	// Cost weighting; the metered value of each request is multiplied by it (0 = 1)
+	CostMultiplier        float64
+	MethodCostMultipliers map[string]float64 // Per-method overrides of CostMultiplier, e.g. {"POST": 5}
+
 	// Protocol behavior
-	Protocol Protocol // http, http_stream, sse, websocket
+	Protocol Protocol // http, http_stream, sse, websocket, grpc
 
 	// Authentication
-	AuthRequired bool // If false, requests to this route skip API key validation (public route)
+	AuthRequired   bool       // If false, requests to this route skip API key validation (public route)
+	AuthMethod     AuthMethod // How clients authenticate; empty = api_key
+	RequiredScopes []string   // Key scopes needed to call the route, e.g. "catalog:read"; all must be held
+
+	// Body size limits (bytes); 0 = use the global default
+	MaxRequestBody  int64 // Requests with larger bodies are rejected with 413
+	MaxResponseBody int64 // Upstream responses with larger bodies are rejected with 502
+
+	// JSON Schema that POST, PUT and PATCH bodies must satisfy; invalid bodies are rejected with 400
+	RequestSchema string
+
+	// Upstream timeouts; exceeding one returns 504 (0 = upstream/global default)
+	DialTimeout           time.Duration // Connecting to the upstream
+	ResponseHeaderTimeout time.Duration // Waiting for response headers after sending the request
+	RequestTimeout        time.Duration // The whole exchange; overrides the upstream's timeout
+
+	// Retry of failed upstream requests (nil = no retries)
+	Retry *RetryPolicy
+
+	// Cross-origin access from browsers (nil = CORS headers are left to the upstream)
+	CORS *CORSPolicy
+
+	// Response caching; GET and HEAD responses are cached for CacheTTL (0 = disabled)
+	CacheTTL        time.Duration
+	CacheKeyHeaders []string // Request headers whose values are part of the cache key
 
 	// Metadata
 	Priority  int  // Higher = evaluated first (for overlapping patterns)
@@ -101,6 +156,13 @@ type HeaderMatch struct {
 	Required bool   // If true, header must be present
 }
 
+// WeightedUpstream is a member of a route's upstream pool.
+// Traffic is spread across pool members in proportion to their weights.
+type WeightedUpstream struct {
+	UpstreamID string `json:"upstream_id"`
+	Weight     int    `json:"weight"` // Relative share of traffic; <= 0 receives no traffic
+}
+
 // Transform defines request or response transformation operations.
 // All string values can be Expr expressions.
 type Transform struct {
@@ -135,6 +197,30 @@ type Upstream struct {
 	AuthHeader string   // Header name for AuthType=header
 	AuthValue  string   // Value (encrypted at rest), supports ${ENV_VAR}
 
+	// TLS to https upstreams; zero values use the system roots and the BaseURL host
+	TLSCACert             string // PEM bundle of CAs trusted for the upstream's certificate
+	TLSClientCert         string // PEM client certificate presented for mutual TLS
+	TLSClientKey          string // PEM private key for TLSClientCert (encrypted at rest)
+	TLSServerName         string // SNI and certificate verification name override
+	TLSInsecureSkipVerify bool   // Skip upstream certificate verification (testing only)
+
+	// Passive health checking (ejection from upstream pools)
+	FailureThreshold int           // Consecutive 5xx/connection errors before ejection; 0 = disabled
+	EjectionCooldown time.Duration // How long an ejected upstream stays out of rotation
+
+	// Active health checking (periodic probes)
+	HealthCheckPath           string        // Path to probe, e.g. "/healthz"; empty = disabled
+	HealthCheckInterval       time.Duration // Time between probes
+	HealthCheckTimeout        time.Duration // Per-probe timeout
+	HealthCheckExpectedStatus int           // Status code a healthy upstream returns
+
+	// Circuit breaking (fast 503s while the upstream is failing)
+	CircuitFailureRate    float64       // Failure rate (0-1) within the window that opens the circuit; 0 = disabled
+	CircuitWindow         time.Duration // Window over which the failure rate is measured
+	CircuitMinRequests    int           // Requests required in the window before the circuit can open
+	CircuitOpenDuration   time.Duration // How long the circuit stays open before allowing trials
+	CircuitHalfOpenTrials int           // Successful trial requests required to close the circuit
+
 	// Metadata
 	Enabled   bool
 	CreatedAt time.Time
@@ -166,16 +252,25 @@ func NewRoute(id, name, pathPattern string, upstreamID string) Route {
 // NewUpstream creates a new Upstream with sensible defaults.
 func NewUpstream(id, name, baseURL string) Upstream {
 	return Upstream{
-		ID:              id,
-		Name:            name,
-		BaseURL:         baseURL,
-		Timeout:         30 * time.Second,
-		MaxIdleConns:    100,
-		IdleConnTimeout: 90 * time.Second,
-		AuthType:        AuthNone,
-		Enabled:         true,
-		CreatedAt:       time.Now(),
-		UpdatedAt:       time.Now(),
+		ID:                        id,
+		Name:                      name,
+		BaseURL:                   baseURL,
+		Timeout:                   30 * time.Second,
+		MaxIdleConns:              100,
+		IdleConnTimeout:           90 * time.Second,
+		AuthType:                  AuthNone,
+		FailureThreshold:          5,
+		EjectionCooldown:          30 * time.Second,
+		HealthCheckInterval:       10 * time.Second,
+		HealthCheckTimeout:        2 * time.Second,
+		HealthCheckExpectedStatus: 200,
+		CircuitWindow:             10 * time.Second,
+		CircuitMinRequests:        10,
+		CircuitOpenDuration:       30 * time.Second,
+		CircuitHalfOpenTrials:     1,
+		Enabled:                   true,
+		CreatedAt:                 time.Now(),
+		UpdatedAt:                 time.Now(),
 	}
 }
 
@@ -225,6 +320,152 @@ func (u Upstream) WithAuth(authType AuthType, header, value string) Upstream {
 	return u
 }
 
+// RequestBodyLimit returns the effective request body limit for this route.
+// A positive route limit overrides the global default.
+func (r Route) RequestBodyLimit(global int64) int64 {
+	return bodyLimit(r.MaxRequestBody, global)
+}
+
+// ResponseBodyLimit returns the effective upstream response body limit for this route.
+// A positive route limit overrides the global default.
+func (r Route) ResponseBodyLimit(global int64) int64 {
+	return bodyLimit(r.MaxResponseBody, global)
+}
+
+func bodyLimit(routeLimit, global int64) int64 {
+	if routeLimit > 0 {
+		return routeLimit
+	}
+	return global
+}
+
+// CostMultiplierFor returns the weight applied to the metered value of a request
+// with the given method. A positive per-method override wins over the route's
+// multiplier; unset multipliers count as 1.
+// This is a PURE function.
+func (r Route) CostMultiplierFor(method string) float64 {
+	for m, mult := range r.MethodCostMultipliers {
+		if mult > 0 && strings.EqualFold(m, method) {
+			return mult
+		}
+	}
+	if r.CostMultiplier > 0 {
+		return r.CostMultiplier
+	}
+	return 1.0
+}
+
+// ValidateCostMultipliers checks that a route's cost multipliers are usable.
+// This is a PURE function.
+func ValidateCostMultipliers(mult float64, perMethod map[string]float64) error {
+	if mult < 0 {
+		return fmt.Errorf("cost multiplier must not be negative")
+	}
+	for m, v := range perMethod {
+		if strings.TrimSpace(m) == "" {
+			return fmt.Errorf("methods must not contain empty values")
+		}
+		if v < 0 {
+			return fmt.Errorf("cost multiplier for %s must not be negative", m)
+		}
+	}
+	return nil
+}
+
+// ValidateHostPattern checks a route's host pattern against its match type.
+// Wildcard patterns are a leading "*." followed by a domain, e.g.
+// "*.apps.example.com", and match exactly one extra label. An empty match
+// type is inferred from the pattern, as the matcher does.
+// This is a PURE function.
+func ValidateHostPattern(pattern string, matchType HostMatchType) error {
+	if pattern == "" {
+		return nil
+	}
+	if matchType == HostMatchNone {
+		matchType = HostMatchExact
+		if strings.HasPrefix(pattern, "*.") {
+			matchType = HostMatchWildcard
+		}
+	}
+
+	switch matchType {
+	case HostMatchExact:
+		if strings.Contains(pattern, "*") {
+			return fmt.Errorf("exact host pattern %q must not contain *; use a wildcard pattern like *.example.com", pattern)
+		}
+	case HostMatchWildcard:
+		if !strings.HasPrefix(pattern, "*.") || len(pattern) == 2 || strings.Contains(pattern[2:], "*") {
+			return fmt.Errorf("invalid wildcard host pattern %q: must be *. followed by a domain, e.g. *.example.com", pattern)
+		}
+	case HostMatchRegex:
+	default:
+		return fmt.Errorf("unknown host match type %q: must be exact, wildcard or regex", matchType)
+	}
+	return compileHostPattern(&compiledPattern{}, pattern, matchType)
+}
+
+// ValidateTable checks that a set of routes can be served together.
+// It rejects duplicate IDs, missing path patterns and invalid policies so a
+// bad reload never replaces a working route table.
+// This is a PURE function.
+func ValidateTable(routes []Route) error {
+	seen := make(map[string]bool, len(routes))
+	for _, r := range routes {
+		if seen[r.ID] {
+			return fmt.Errorf("route %s: duplicate route id", r.ID)
+		}
+		seen[r.ID] = true
+
+		if r.PathPattern == "" {
+			return fmt.Errorf("route %s: path pattern is required", r.ID)
+		}
+		if r.Retry != nil {
+			if err := ValidateRetryPolicy(*r.Retry); err != nil {
+				return fmt.Errorf("route %s: %w", r.ID, err)
+			}
+		}
+		if r.CORS != nil {
+			if err := ValidateCORSPolicy(*r.CORS); err != nil {
+				return fmt.Errorf("route %s: %w", r.ID, err)
+			}
+		}
+		if r.Canary != nil {
+			if err := ValidateCanaryPolicy(*r.Canary); err != nil {
+				return fmt.Errorf("route %s: %w", r.ID, err)
+			}
+		}
+		if err := ValidateHeaderRules(r.RequestHeaders); err != nil {
+			return fmt.Errorf("route %s: request headers: %w", r.ID, err)
+		}
+		if err := ValidateHeaderRules(r.ResponseHeaders); err != nil {
+			return fmt.Errorf("route %s: response headers: %w", r.ID, err)
+		}
+		if err := ValidateCostMultipliers(r.CostMultiplier, r.MethodCostMultipliers); err != nil {
+			return fmt.Errorf("route %s: %w", r.ID, err)
+		}
+		if err := ValidatePathRewrite(r.StripPrefix, r.RewritePath); err != nil {
+			return fmt.Errorf("route %s: %w", r.ID, err)
+		}
+		if err := ValidateQueryMatches(r.QueryMatch, r.StripQuery); err != nil {
+			return fmt.Errorf("route %s: %w", r.ID, err)
+		}
+		if r.RequestSchema != "" && !json.Valid([]byte(r.RequestSchema)) {
+			return fmt.Errorf("route %s: request schema is not valid JSON", r.ID)
+		}
+	}
+	return nil
+}
+
+// CachingEnabled returns true if responses for this route may be cached.
+func (r Route) CachingEnabled() bool {
+	return r.CacheTTL > 0
+}
+
+// HasUpstream returns true if the route targets an upstream or an upstream pool.
+func (r Route) HasUpstream() bool {
+	return r.UpstreamID != "" || len(r.Upstreams) > 0
+}
+
 // IsValid returns true if the route has minimum required fields.
 func (r Route) IsValid() bool {
 	return r.ID != "" && r.Name != "" && r.PathPattern != "" && r.UpstreamID != ""
@@ -0,0 +1,635 @@
+package route_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/domain/route"
+)
+
+func TestRoute_BodyLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		route    route.Route
+		global   int64
+		wantReq  int64
+		wantResp int64
+	}{
+		{"global default", route.Route{}, 1024, 1024, 1024},
+		{"route overrides global", route.Route{MaxRequestBody: 10, MaxResponseBody: 20}, 1024, 10, 20},
+		{"route overrides unlimited global", route.Route{MaxRequestBody: 10}, 0, 10, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.route.RequestBodyLimit(tt.global); got != tt.wantReq {
+				t.Errorf("RequestBodyLimit() = %d, want %d", got, tt.wantReq)
+			}
+			if got := tt.route.ResponseBodyLimit(tt.global); got != tt.wantResp {
+				t.Errorf("ResponseBodyLimit() = %d, want %d", got, tt.wantResp)
+			}
+		})
+	}
+}
+
+func TestSelectWeighted(t *testing.T) {
+	pool := []route.WeightedUpstream{
+		{UpstreamID: "a", Weight: 3},
+		{UpstreamID: "b", Weight: 1},
+		{UpstreamID: "c", Weight: 0},
+	}
+	current := make([]int, len(pool))
+
+	counts := map[string]int{}
+	var sequence []string
+	for i := 0; i < 8; i++ {
+		idx := route.SelectWeighted(pool, current, nil)
+		if idx < 0 {
+			t.Fatal("expected a selection")
+		}
+		counts[pool[idx].UpstreamID]++
+		if i < 4 {
+			sequence = append(sequence, pool[idx].UpstreamID)
+		}
+	}
+
+	if counts["a"] != 6 || counts["b"] != 2 || counts["c"] != 0 {
+		t.Errorf("counts = %v, want a:6 b:2 c:0", counts)
+	}
+	// Smooth WRR interleaves rather than bursting: a a b a, not a a a b
+	if got := strings.Join(sequence, ""); got != "aaba" {
+		t.Errorf("sequence = %s, want aaba", got)
+	}
+}
+
+func TestSelectWeighted_SkipsIneligible(t *testing.T) {
+	pool := []route.WeightedUpstream{
+		{UpstreamID: "a", Weight: 3},
+		{UpstreamID: "b", Weight: 1},
+	}
+	current := make([]int, len(pool))
+	onlyB := func(id string) bool { return id == "b" }
+
+	for i := 0; i < 5; i++ {
+		if idx := route.SelectWeighted(pool, current, onlyB); idx != 1 {
+			t.Fatalf("selection %d = %d, want 1", i, idx)
+		}
+	}
+
+	none := func(string) bool { return false }
+	if idx := route.SelectWeighted(pool, current, none); idx != -1 {
+		t.Errorf("selection with no eligible members = %d, want -1", idx)
+	}
+}
+
+func TestPassiveHealth_Record(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	u := route.Upstream{ID: "u1", FailureThreshold: 3, EjectionCooldown: 30 * time.Second}
+
+	var h route.PassiveHealth
+	h = h.Record(u, true, now)
+	h = h.Record(u, true, now)
+	if h.Ejected(now) {
+		t.Fatal("ejected before reaching threshold")
+	}
+
+	// A success resets the streak
+	h = h.Record(u, false, now)
+	h = h.Record(u, true, now)
+	h = h.Record(u, true, now)
+	if h.Ejected(now) {
+		t.Fatal("ejected although streak was reset")
+	}
+
+	h = h.Record(u, true, now)
+	if !h.Ejected(now) {
+		t.Fatal("expected ejection after 3 consecutive failures")
+	}
+	if !h.Ejected(now.Add(29 * time.Second)) {
+		t.Error("expected ejection to last for the cooldown")
+	}
+	if h.Ejected(now.Add(30 * time.Second)) {
+		t.Error("expected re-admission after the cooldown")
+	}
+}
+
+func TestPassiveHealth_Disabled(t *testing.T) {
+	now := time.Now()
+	u := route.Upstream{ID: "u1", FailureThreshold: 0, EjectionCooldown: time.Minute}
+
+	var h route.PassiveHealth
+	for i := 0; i < 100; i++ {
+		h = h.Record(u, true, now)
+	}
+	if h.Ejected(now) {
+		t.Error("threshold 0 should never eject")
+	}
+}
+
+func TestCircuitBreaker_StateMachine(t *testing.T) {
+	start := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	u := route.Upstream{
+		ID:                    "u1",
+		CircuitFailureRate:    0.5,
+		CircuitWindow:         10 * time.Second,
+		CircuitMinRequests:    4,
+		CircuitOpenDuration:   30 * time.Second,
+		CircuitHalfOpenTrials: 2,
+	}
+
+	var b route.CircuitBreaker
+	send := func(now time.Time, failed bool) bool {
+		t.Helper()
+		var ok bool
+		b, ok = b.Allow(u, now)
+		if ok {
+			b = b.Record(u, failed, now)
+		}
+		return ok
+	}
+
+	// Closed: 3 failures is 100% but below the minimum request count
+	for i := 0; i < 3; i++ {
+		send(start, true)
+	}
+	if got := b.StateAt(u, start); got != route.CircuitClosed {
+		t.Fatalf("state below min requests = %s, want closed", got)
+	}
+
+	// A new window resets the counts: 1 failure in 4 requests stays closed
+	now := start.Add(10 * time.Second)
+	send(now, true)
+	for i := 0; i < 3; i++ {
+		send(now, false)
+	}
+	if got := b.StateAt(u, now); got != route.CircuitClosed {
+		t.Fatalf("state at 25%% failure rate = %s, want closed", got)
+	}
+
+	// 2 more failures: 3/6 = 50% opens the circuit
+	send(now, true)
+	send(now, true)
+	if got := b.StateAt(u, now); got != route.CircuitOpen {
+		t.Fatalf("state at 50%% failure rate = %s, want open", got)
+	}
+	openedAt := now
+
+	// Open: requests are rejected until the open duration elapses
+	if send(openedAt.Add(29*time.Second), false) {
+		t.Fatal("request allowed 29s after opening")
+	}
+
+	// Half-open: exactly 2 trials are admitted concurrently
+	now = openedAt.Add(30 * time.Second)
+	if got := b.StateAt(u, now); got != route.CircuitHalfOpen {
+		t.Fatalf("state after open duration = %s, want half_open", got)
+	}
+	var ok1, ok2, ok3 bool
+	b, ok1 = b.Allow(u, now)
+	b, ok2 = b.Allow(u, now)
+	b, ok3 = b.Allow(u, now)
+	if !ok1 || !ok2 || ok3 {
+		t.Fatalf("half-open admitted %v %v %v, want true true false", ok1, ok2, ok3)
+	}
+
+	// A failed trial re-opens the circuit for a full open duration
+	b = b.Record(u, true, now)
+	if got := b.StateAt(u, now); got != route.CircuitOpen {
+		t.Fatalf("state after failed trial = %s, want open", got)
+	}
+	// The other trial completing late does not change the open circuit
+	b = b.Record(u, false, now)
+	if got := b.StateAt(u, now.Add(29*time.Second)); got != route.CircuitOpen {
+		t.Fatalf("state 29s after re-opening = %s, want open", got)
+	}
+
+	// Two successful trials close the circuit
+	now = now.Add(30 * time.Second)
+	if !send(now, false) {
+		t.Fatal("first trial rejected")
+	}
+	if got := b.StateAt(u, now); got != route.CircuitHalfOpen {
+		t.Fatalf("state after one successful trial = %s, want half_open", got)
+	}
+	if !send(now, false) {
+		t.Fatal("second trial rejected")
+	}
+	if got := b.StateAt(u, now); got != route.CircuitClosed {
+		t.Fatalf("state after successful trials = %s, want closed", got)
+	}
+	if !send(now, false) {
+		t.Fatal("request rejected after closing")
+	}
+}
+
+func TestCircuitBreaker_Release(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	u := route.Upstream{
+		ID:                    "u1",
+		CircuitFailureRate:    0.5,
+		CircuitWindow:         10 * time.Second,
+		CircuitMinRequests:    1,
+		CircuitOpenDuration:   30 * time.Second,
+		CircuitHalfOpenTrials: 1,
+	}
+
+	var b route.CircuitBreaker
+	b, _ = b.Allow(u, now)
+	b = b.Record(u, true, now)
+	now = now.Add(30 * time.Second)
+
+	// A released trial is neither a success nor a failure
+	var ok bool
+	if b, ok = b.Allow(u, now); !ok {
+		t.Fatal("trial rejected")
+	}
+	if _, ok := b.Allow(u, now); ok {
+		t.Fatal("second trial admitted while the first is in flight")
+	}
+	b = b.Release(u, now)
+	if got := b.StateAt(u, now); got != route.CircuitHalfOpen {
+		t.Fatalf("state after release = %s, want half_open", got)
+	}
+	if b.TrialSuccesses != 0 || b.TrialsInFlight != 0 {
+		t.Errorf("after release: %d successes, %d in flight; want 0, 0", b.TrialSuccesses, b.TrialsInFlight)
+	}
+	if _, ok := b.Allow(u, now); !ok {
+		t.Error("trial rejected after the previous one was released")
+	}
+}
+
+func TestCircuitBreaker_Disabled(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	u := route.Upstream{ID: "u1", CircuitMinRequests: 1, CircuitOpenDuration: time.Minute}
+
+	var b route.CircuitBreaker
+	for i := 0; i < 20; i++ {
+		var ok bool
+		if b, ok = b.Allow(u, now); !ok {
+			t.Fatal("request rejected with circuit breaking disabled")
+		}
+		b = b.Record(u, true, now)
+	}
+	if got := b.StateAt(u, now); got != route.CircuitClosed {
+		t.Errorf("state = %s, want closed", got)
+	}
+}
+
+func TestUpstream_TLSConfig(t *testing.T) {
+	cfg, err := route.Upstream{BaseURL: "https://api.example.com"}.TLSConfig()
+	if cfg != nil || err != nil {
+		t.Errorf("default upstream TLSConfig() = %v, %v; want nil, nil", cfg, err)
+	}
+
+	cfg, err = route.Upstream{TLSServerName: "backend.internal", TLSInsecureSkipVerify: true}.TLSConfig()
+	if err != nil || cfg.ServerName != "backend.internal" || !cfg.InsecureSkipVerify {
+		t.Errorf("TLSConfig() = %+v, %v; want server name and skip verify", cfg, err)
+	}
+
+	tests := []struct {
+		name     string
+		upstream route.Upstream
+		wantErr  string
+	}{
+		{"malformed CA", route.Upstream{TLSCACert: "garbage"}, "tls_ca_cert"},
+		{"cert without key", route.Upstream{TLSClientCert: "cert"}, "set together"},
+		{"key without cert", route.Upstream{TLSClientKey: "key"}, "set together"},
+		{"malformed key pair", route.Upstream{TLSClientCert: "cert", TLSClientKey: "key"}, "client certificate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.upstream.TLSConfig(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("TLSConfig() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApplyHeaderRules(t *testing.T) {
+	refs := map[string]string{"TOKEN": "s3cret", "key.id": "key-1"}
+	resolve := func(name string) string { return refs[name] }
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		rules   []route.HeaderRule
+		want    map[string]string
+	}{
+		{"set replaces any casing",
+			map[string]string{"x-internal-auth": "forged"},
+			[]route.HeaderRule{{Op: route.HeaderSet, Name: "X-Internal-Auth", Value: "Token ${TOKEN}"}},
+			map[string]string{"X-Internal-Auth": "Token s3cret"}},
+		{"add appends to existing",
+			map[string]string{"Vary": "Accept-Encoding"},
+			[]route.HeaderRule{{Op: route.HeaderAdd, Name: "vary", Value: "Accept"}},
+			map[string]string{"Vary": "Accept-Encoding, Accept"}},
+		{"add sets when absent",
+			map[string]string{},
+			[]route.HeaderRule{{Op: route.HeaderAdd, Name: "x-key", Value: "${key.id}"}},
+			map[string]string{"X-Key": "key-1"}},
+		{"remove is case-insensitive",
+			map[string]string{"Cookie": "a=1", "Accept": "*/*"},
+			[]route.HeaderRule{{Op: route.HeaderRemove, Name: "COOKIE"}},
+			map[string]string{"Accept": "*/*"}},
+		{"rename replaces target",
+			map[string]string{"X-Client-Trace": "t-1", "X-Trace-Id": "old"},
+			[]route.HeaderRule{{Op: route.HeaderRename, Name: "x-client-trace", To: "x-trace-id"}},
+			map[string]string{"X-Trace-Id": "t-1"}},
+		{"rename of missing header is a no-op",
+			map[string]string{"X-Trace-Id": "old"},
+			[]route.HeaderRule{{Op: route.HeaderRename, Name: "X-Client-Trace", To: "X-Trace-Id"}},
+			map[string]string{"X-Trace-Id": "old"}},
+		{"rules run in order",
+			map[string]string{"Authorization": "Bearer user"},
+			[]route.HeaderRule{
+				{Op: route.HeaderRename, Name: "Authorization", To: "X-Original-Auth"},
+				{Op: route.HeaderRemove, Name: "X-Original-Auth"},
+			},
+			map[string]string{}},
+		{"unknown references expand empty",
+			map[string]string{},
+			[]route.HeaderRule{{Op: route.HeaderSet, Name: "X-Plan", Value: "plan=${key.plan_id};${MISSING"}},
+			map[string]string{"X-Plan": "plan=;${MISSING"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := route.ApplyHeaderRules(tt.headers, tt.rules, resolve)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ApplyHeaderRules() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("input is not modified", func(t *testing.T) {
+		headers := map[string]string{"Cookie": "a=1"}
+		route.ApplyHeaderRules(headers, []route.HeaderRule{{Op: route.HeaderRemove, Name: "Cookie"}}, nil)
+		if headers["Cookie"] != "a=1" {
+			t.Errorf("input headers modified: %v", headers)
+		}
+	})
+}
+
+func TestValidateHeaderRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []route.HeaderRule
+		wantErr string
+	}{
+		{"valid", []route.HeaderRule{
+			{Op: route.HeaderSet, Name: "X-A", Value: "1"},
+			{Op: route.HeaderAdd, Name: "X-B", Value: "2"},
+			{Op: route.HeaderRemove, Name: "Cookie"},
+			{Op: route.HeaderRename, Name: "X-C", To: "X-D"},
+		}, ""},
+		{"missing name", []route.HeaderRule{{Op: route.HeaderRemove}}, "name is required"},
+		{"missing op", []route.HeaderRule{{Name: "Cookie"}}, "op is required"},
+		{"unknown op", []route.HeaderRule{{Op: "drop", Name: "Cookie"}}, "unknown op"},
+		{"rename without target", []route.HeaderRule{{Op: route.HeaderRename, Name: "X-C"}}, "rename requires to"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := route.ValidateHeaderRules(tt.rules)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateHeaderRules() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateHeaderRules() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	p := route.RetryPolicy{Attempts: 3, Backoff: 100 * time.Millisecond, Statuses: []int{502, 503}}
+
+	for method, want := range map[string]bool{"GET": true, "head": true, "OPTIONS": true, "POST": false, "PUT": false} {
+		if got := p.AllowsMethod(method); got != want {
+			t.Errorf("default AllowsMethod(%s) = %v, want %v", method, got, want)
+		}
+	}
+	explicit := route.RetryPolicy{Attempts: 1, Methods: []string{"POST"}}
+	if !explicit.AllowsMethod("POST") || explicit.AllowsMethod("GET") {
+		t.Error("explicit methods should replace the defaults")
+	}
+
+	if !p.RetriesStatus(503) || p.RetriesStatus(500) {
+		t.Error("RetriesStatus should match only the listed statuses")
+	}
+
+	for n, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 400 * time.Millisecond, 20: 30 * time.Second} {
+		if got := p.BackoffBefore(n); got != want {
+			t.Errorf("BackoffBefore(%d) = %v, want %v", n, got, want)
+		}
+	}
+}
+
+func TestValidateRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  route.RetryPolicy
+		wantErr string
+	}{
+		{"valid", route.RetryPolicy{Attempts: 2, Backoff: time.Second, Methods: []string{"GET"}, Statuses: []int{503}}, ""},
+		{"too many attempts", route.RetryPolicy{Attempts: 6}, "attempts must be between 0 and 5"},
+		{"negative backoff", route.RetryPolicy{Attempts: 1, Backoff: -time.Second}, "backoff must not be negative"},
+		{"empty method", route.RetryPolicy{Attempts: 1, Methods: []string{" "}}, "methods must not contain empty values"},
+		{"bad status", route.RetryPolicy{Attempts: 1, Statuses: []int{99}}, "status 99 is not a valid HTTP status"},
+	}
+	for _, tt := range tests {
+		err := route.ValidateRetryPolicy(tt.policy)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != tt.wantErr {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCORSPolicy(t *testing.T) {
+	p := route.CORSPolicy{
+		AllowedOrigins: []string{"https://app.example.com", "https://*.example.org"},
+		AllowedMethods: []string{"GET", "PUT"},
+		ExposedHeaders: []string{"X-Request-ID"},
+		MaxAge:         10 * time.Minute,
+	}
+
+	for origin, want := range map[string]bool{
+		"https://app.example.com":  true,
+		"https://APP.example.com":  true,
+		"https://eu.example.org":   true,
+		"https://example.org":      false,
+		"http://eu.example.org":    false,
+		"https://evil.example.com": false,
+		"":                         false,
+	} {
+		if got := p.AllowsOrigin(origin); got != want {
+			t.Errorf("AllowsOrigin(%q) = %v, want %v", origin, got, want)
+		}
+	}
+
+	got := p.ResponseHeaders("https://app.example.com")
+	want := map[string]string{
+		"Access-Control-Allow-Origin":   "https://app.example.com",
+		"Access-Control-Expose-Headers": "X-Request-ID",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ResponseHeaders = %v, want %v", got, want)
+	}
+	if got := p.ResponseHeaders("https://evil.example.com"); got != nil {
+		t.Errorf("ResponseHeaders for disallowed origin = %v, want nil", got)
+	}
+
+	got, ok := p.PreflightHeaders("https://eu.example.org", "PUT", "content-type, x-api-key")
+	want = map[string]string{
+		"Access-Control-Allow-Origin":  "https://eu.example.org",
+		"Access-Control-Allow-Methods": "GET, PUT",
+		"Access-Control-Allow-Headers": "content-type, x-api-key",
+		"Access-Control-Max-Age":       "600",
+	}
+	if !ok || !reflect.DeepEqual(got, want) {
+		t.Errorf("PreflightHeaders = %v, %v; want %v", got, ok, want)
+	}
+	if _, ok := p.PreflightHeaders("https://eu.example.org", "DELETE", ""); ok {
+		t.Error("preflight for a method not allowed succeeded")
+	}
+	if _, ok := p.PreflightHeaders("https://eu.example.org", "GET", "X-Custom"); ok {
+		t.Error("preflight for a header not allowed succeeded")
+	}
+
+	// A wildcard origin is sent as "*" unless credentials are allowed
+	wildcard := route.CORSPolicy{AllowedOrigins: []string{"*"}}
+	if got := wildcard.ResponseHeaders("https://a.test")["Access-Control-Allow-Origin"]; got != "*" {
+		t.Errorf("wildcard Allow-Origin = %q, want *", got)
+	}
+	creds := route.CORSPolicy{AllowedOrigins: []string{"https://a.test"}, AllowCredentials: true}
+	if got := creds.ResponseHeaders("https://a.test"); got["Access-Control-Allow-Credentials"] != "true" {
+		t.Errorf("credentials headers = %v", got)
+	}
+}
+
+func TestValidateCORSPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  route.CORSPolicy
+		wantErr string
+	}{
+		{"valid", route.CORSPolicy{AllowedOrigins: []string{"https://app.example.com"}, AllowCredentials: true}, ""},
+		{"no origins", route.CORSPolicy{}, "allowed_origins is required"},
+		{"empty origin", route.CORSPolicy{AllowedOrigins: []string{" "}}, "allowed_origins must not contain empty values"},
+		{"credentials with wildcard", route.CORSPolicy{AllowedOrigins: []string{"*"}, AllowCredentials: true}, `allow_credentials cannot be combined with origin "*"`},
+		{"negative max age", route.CORSPolicy{AllowedOrigins: []string{"*"}, MaxAge: -time.Second}, "max_age must not be negative"},
+	}
+	for _, tt := range tests {
+		err := route.ValidateCORSPolicy(tt.policy)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != tt.wantErr {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCostMultiplierFor(t *testing.T) {
+	tests := []struct {
+		name   string
+		route  route.Route
+		method string
+		want   float64
+	}{
+		{"unset", route.Route{}, "GET", 1},
+		{"route multiplier", route.Route{CostMultiplier: 5}, "GET", 5},
+		{"method override", route.Route{CostMultiplier: 5, MethodCostMultipliers: map[string]float64{"post": 10}}, "POST", 10},
+		{"other method uses route multiplier", route.Route{CostMultiplier: 5, MethodCostMultipliers: map[string]float64{"POST": 10}}, "GET", 5},
+		{"method override without route multiplier", route.Route{MethodCostMultipliers: map[string]float64{"DELETE": 0.5}}, "DELETE", 0.5},
+		{"zero override ignored", route.Route{CostMultiplier: 3, MethodCostMultipliers: map[string]float64{"GET": 0}}, "GET", 3},
+	}
+	for _, tt := range tests {
+		if got := tt.route.CostMultiplierFor(tt.method); got != tt.want {
+			t.Errorf("%s: CostMultiplierFor(%s) = %v, want %v", tt.name, tt.method, got, tt.want)
+		}
+	}
+}
+
+func TestValidateCostMultipliers(t *testing.T) {
+	if err := route.ValidateCostMultipliers(2, map[string]float64{"POST": 5}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := route.ValidateCostMultipliers(-1, nil); err == nil {
+		t.Error("expected error for negative multiplier")
+	}
+	if err := route.ValidateCostMultipliers(1, map[string]float64{"POST": -2}); err == nil {
+		t.Error("expected error for negative method multiplier")
+	}
+	if err := route.ValidateCostMultipliers(1, map[string]float64{" ": 2}); err == nil {
+		t.Error("expected error for empty method")
+	}
+}
+
+func TestValidateTable(t *testing.T) {
+	valid := []route.Route{
+		{ID: "a", PathPattern: "/a", Retry: &route.RetryPolicy{Attempts: 2}},
+		{ID: "b", PathPattern: "/b", CORS: &route.CORSPolicy{AllowedOrigins: []string{"*"}}},
+	}
+	if err := route.ValidateTable(valid); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		routes []route.Route
+	}{
+		{"duplicate id", []route.Route{{ID: "a", PathPattern: "/a"}, {ID: "a", PathPattern: "/b"}}},
+		{"missing pattern", []route.Route{{ID: "a"}}},
+		{"bad retry", []route.Route{{ID: "a", PathPattern: "/a", Retry: &route.RetryPolicy{Attempts: -1}}}},
+		{"bad cors", []route.Route{{ID: "a", PathPattern: "/a", CORS: &route.CORSPolicy{}}}},
+		{"bad header rule", []route.Route{{ID: "a", PathPattern: "/a", RequestHeaders: []route.HeaderRule{{}}}}},
+		{"bad cost", []route.Route{{ID: "a", PathPattern: "/a", CostMultiplier: -1}}},
+	}
+	for _, tt := range tests {
+		if err := route.ValidateTable(tt.routes); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
+
+func TestValidateHostPattern(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		matchType route.HostMatchType
+		wantErr   bool
+	}{
+		{"", route.HostMatchNone, false},
+		{"api.example.com", route.HostMatchNone, false},
+		{"api.example.com", route.HostMatchExact, false},
+		{"*.apps.example.com", route.HostMatchNone, false},
+		{"*.apps.example.com", route.HostMatchWildcard, false},
+		{`^[a-z]+\.example\.com$`, route.HostMatchRegex, false},
+		{"api.*.example.com", route.HostMatchWildcard, true},
+		{"*.*.example.com", route.HostMatchWildcard, true},
+		{"*.", route.HostMatchWildcard, true},
+		{"api.*.example.com", route.HostMatchExact, true},
+		{"[invalid", route.HostMatchRegex, true},
+		{"api.example.com", "glob", true},
+	}
+	for _, tt := range tests {
+		err := route.ValidateHostPattern(tt.pattern, tt.matchType)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateHostPattern(%q, %q) error = %v, wantErr %v", tt.pattern, tt.matchType, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package route
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+)
+
+// HasCustomTLS returns true if the upstream overrides the default TLS settings.
+func (u Upstream) HasCustomTLS() bool {
+	return u.TLSCACert != "" || u.TLSClientCert != "" || u.TLSClientKey != "" ||
+		u.TLSServerName != "" || u.TLSInsecureSkipVerify
+}
+
+// TLSKey identifies the upstream's TLS settings, so clients built for them
+// can be reused until they change.
+func (u Upstream) TLSKey() string {
+	return fmt.Sprintf("%t\x00%s\x00%s\x00%s\x00%s",
+		u.TLSInsecureSkipVerify, u.TLSServerName, u.TLSCACert, u.TLSClientCert, u.TLSClientKey)
+}
+
+// TLSConfig builds the client TLS configuration for connecting to the upstream.
+// Returns nil when the upstream uses the defaults, and an error when the CA
+// bundle or client certificate can't be parsed.
+// This is a PURE function.
+func (u Upstream) TLSConfig() (*tls.Config, error) {
+	if !u.HasCustomTLS() {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{
+		ServerName:         u.TLSServerName,
+		InsecureSkipVerify: u.TLSInsecureSkipVerify,
+	}
+
+	if u.TLSCACert != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(u.TLSCACert)) {
+			return nil, errors.New("tls_ca_cert contains no PEM certificates")
+		}
+		cfg.RootCAs = pool
+	}
+
+	if (u.TLSClientCert == "") != (u.TLSClientKey == "") {
+		return nil, errors.New("tls_client_cert and tls_client_key must be set together")
+	}
+	if u.TLSClientCert != "" {
+		cert, err := tls.X509KeyPair([]byte(u.TLSClientCert), []byte(u.TLSClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("tls client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
@@ -66,10 +66,11 @@ func (s Settings) GetDuration(key string, defaultValue time.Duration) time.Durat
 // Known setting keys (namespaced by category).
 const (
 	// Server settings
-	KeyServerHost         = "server.host"
-	KeyServerPort         = "server.port"
-	KeyServerReadTimeout  = "server.read_timeout"
-	KeyServerWriteTimeout = "server.write_timeout"
+	KeyServerHost            = "server.host"
+	KeyServerPort            = "server.port"
+	KeyServerReadTimeout     = "server.read_timeout"
+	KeyServerWriteTimeout    = "server.write_timeout"
+	KeyServerShutdownTimeout = "server.shutdown_timeout" // How long in-flight requests get to finish on shutdown
 
 	// Portal settings
 	KeyPortalEnabled = "portal.enabled"
@@ -135,25 +136,108 @@ const (
 	KeyPaymentLemonAPIKey        = "payment.lemonsqueezy.api_key"
 	KeyPaymentLemonStoreID       = "payment.lemonsqueezy.store_id"
 	KeyPaymentLemonWebhookSecret = "payment.lemonsqueezy.webhook_secret"
+	KeyPaymentOverageInterval    = "payment.overage_report_interval" // How often allow_overage usage is reported
+	KeyPaymentDunningSchedule    = "payment.dunning_schedule"        // Days after a failed payment to send reminders; suspended on the last, e.g. "3,7,14" ("0" = disabled)
+
+	// Billing settings
+	KeyBillingProvider = "billing.provider" // local, none
 
 	// Auth settings
 	KeyAuthMode                     = "auth.mode"
 	KeyAuthHeader                   = "auth.header"
 	KeyAuthJWTSecret                = "auth.jwt_secret"
 	KeyAuthKeyPrefix                = "auth.key_prefix"
-	KeyAuthSessionTTL               = "auth.session_ttl"
+	KeyAuthSessionTTL               = "auth.session_ttl"     // Portal session lifetime without "remember me"
+	KeyAuthRememberMeTTL            = "auth.remember_me_ttl" // Portal session lifetime with "remember me"
 	KeyAuthRequireEmailVerification = "auth.require_email_verification"
+	KeyAuthHMACMaxSkew              = "auth.hmac_max_skew"           // Allowed clock skew for HMAC-signed requests
+	KeyAuthKeyExpiryWarningDays     = "auth.key_expiry_warning_days" // Email key owners this many days before expiry (0 = disabled)
+	KeyAuthKeyRotationGrace         = "auth.key_rotation_grace"      // How long a rotated-out key keeps working
+
+	// External identity provider JWTs validated against a JWKS (empty URL = disabled)
+	KeyAuthJWKSURL       = "auth.jwks_url"
+	KeyAuthJWKSIssuer    = "auth.jwks_issuer"     // Required "iss" claim (empty = not checked)
+	KeyAuthJWKSAudience  = "auth.jwks_audience"   // Required "aud" claim (empty = not checked)
+	KeyAuthJWKSUserClaim = "auth.jwks_user_claim" // Claim holding the APIGate user ID
+	KeyAuthJWKSRefresh   = "auth.jwks_refresh"    // How long fetched signing keys are cached
+
+	// Password policy for signup, reset, and change password
+	KeyAuthPasswordMinLength     = "auth.password_min_length"
+	KeyAuthPasswordRequireUpper  = "auth.password_require_uppercase"
+	KeyAuthPasswordRequireLower  = "auth.password_require_lowercase"
+	KeyAuthPasswordRequireNumber = "auth.password_require_number"
+	KeyAuthPasswordRequireSymbol = "auth.password_require_symbol"
+	KeyAuthPasswordCheckBreached = "auth.password_check_breached" // Reject passwords found by the Pwned Passwords range API
 
 	// Rate limit settings
 	KeyRateLimitEnabled     = "ratelimit.enabled"
 	KeyRateLimitBurstTokens = "ratelimit.burst_tokens"
 	KeyRateLimitWindowSecs  = "ratelimit.window_secs"
+	KeyRateLimitRedisURL    = "ratelimit.redis_url" // Shared limits across instances (empty = local)
+
+	// Usage spike detection; thresholds are set per plan
+	KeyAnomalyWindow           = "anomaly.window"            // Recent period compared against the baseline
+	KeyAnomalyBaseline         = "anomaly.baseline"          // Trailing period a key's normal rate is measured over
+	KeyAnomalyThrottleDuration = "anomaly.throttle_duration" // How long a spiking key stays throttled; also the alert cooldown
+
+	// Quota warning emails
+	KeyQuotaWarningThresholds = "quota.warning_thresholds" // Comma-separated usage percentages, e.g. "80,100" ("0" = disabled)
 
 	// Upstream settings (default upstream when no route matches)
 	KeyUpstreamURL            = "upstream.url"
 	KeyUpstreamTimeout        = "upstream.timeout"
 	KeyUpstreamMaxIdleConns   = "upstream.max_idle_conns"
 	KeyUpstreamIdleConnTimeout = "upstream.idle_conn_timeout"
+	KeyUpstreamDialTimeout           = "upstream.dial_timeout"            // Routes may override
+	KeyUpstreamResponseHeaderTimeout = "upstream.response_header_timeout" // 0 = bounded only by upstream.timeout; routes may override
+
+	// Proxy body size limits in bytes (0 = unlimited); routes may override
+	KeyProxyMaxRequestBody  = "proxy.max_request_body"
+	KeyProxyMaxResponseBody = "proxy.max_response_body"
+
+	// Proxy response cache size (entries); routes opt in with cache_ttl_ms
+	KeyProxyCacheMaxEntries = "proxy.cache_max_entries"
+
+	// Load balancers/proxies whose X-Forwarded-For is trusted (comma-separated CIDRs)
+	KeyProxyTrustedProxies = "proxy.trusted_proxies"
+
+	// Client IPs allowed to request route debug headers with X-APIGate-Debug: 1
+	// (comma-separated CIDRs; empty disables debug headers)
+	KeyProxyDebugSources = "proxy.debug_sources"
+
+	// Gateway URL clients use, substituted for upstream URLs by routes with
+	// rewrite_response_urls (empty = each request's scheme and Host)
+	KeyProxyPublicURL = "proxy.public_url"
+
+	// Compression of buffered responses for clients sending Accept-Encoding
+	KeyProxyCompressionEnabled = "proxy.compression_enabled"
+	KeyProxyCompressionMinSize = "proxy.compression_min_size" // Bytes; smaller bodies are sent as-is
+	KeyProxyCompressionTypes   = "proxy.compression_types"    // Comma-separated media types
+
+	// Maintenance mode: proxy routes answer 503 while portal and admin stay up
+	KeyMaintenanceEnabled    = "maintenance.enabled"
+	KeyMaintenanceRetryAfter = "maintenance.retry_after" // Sent as Retry-After (0 = omitted)
+	KeyMaintenanceMessage    = "maintenance.message"     // Detail of the default JSON error
+	KeyMaintenanceJSONBody   = "maintenance.json_body"   // Replaces the default JSON error
+	KeyMaintenanceHTMLBody   = "maintenance.html_body"   // Sent to clients that prefer HTML
+
+	// Prometheus metrics served at /metrics
+	KeyMetricsEnabled     = "metrics.enabled"
+	KeyMetricsRequireAuth = "metrics.require_auth" // Require admin authentication to scrape
+
+	// JSON access log, one line per proxied request (empty path = disabled)
+	KeyAccessLogPath       = "accesslog.path"
+	KeyAccessLogMaxSizeMB  = "accesslog.max_size_mb" // Rotate once the file reaches this size
+	KeyAccessLogMaxBackups = "accesslog.max_backups" // Rotated files to keep
+
+	// OpenTelemetry tracing (empty endpoint = disabled)
+	KeyTracingOTLPEndpoint = "tracing.otlp_endpoint" // OTLP/HTTP collector URL
+	KeyTracingServiceName  = "tracing.service_name"
+
+	// Declarative module storage (empty DSN with sqlite = the app database)
+	KeyStorageDriver                     = "storage.driver" // sqlite, postgres
+	KeyStorageDSN                        = "storage.dsn"
+	KeyStorageAllowDestructiveMigrations = "storage.allow_destructive_migrations" // Drop columns of removed module fields
 
 	// Terminology settings (customize UI labels for different metering modes)
 	KeyMeteringUnit = "metering.unit" // requests, tokens, data_points, bytes
@@ -173,8 +257,9 @@ const (
 	KeyTLSCertPath     = "tls.cert_path"    // Manual mode: cert file
 	KeyTLSKeyPath      = "tls.key_path"     // Manual mode: key file
 	KeyTLSHTTPRedirect = "tls.http_redirect"
-	KeyTLSMinVersion   = "tls.min_version"  // TLS 1.2 or 1.3
-	KeyTLSACMEStaging  = "tls.acme_staging" // Use staging for testing
+	KeyTLSMinVersion   = "tls.min_version"    // TLS 1.2 or 1.3
+	KeyTLSACMEStaging  = "tls.acme_staging"   // Use staging for testing
+	KeyTLSClientCAPath = "tls.client_ca_path" // PEM bundle of CAs trusted for client certificates (mtls routes)
 
 	// OAuth settings
 	KeyOAuthEnabled           = "oauth.enabled"
@@ -185,11 +270,13 @@ const (
 	KeyOAuthGoogleEnabled      = "oauth.google.enabled"
 	KeyOAuthGoogleClientID     = "oauth.google.client_id"
 	KeyOAuthGoogleClientSecret = "oauth.google.client_secret"
+	KeyOAuthGoogleRedirectURL  = "oauth.google.redirect_url" // Portal callback URL registered with Google
 
 	// GitHub OAuth
 	KeyOAuthGitHubEnabled      = "oauth.github.enabled"
 	KeyOAuthGitHubClientID     = "oauth.github.client_id"
 	KeyOAuthGitHubClientSecret = "oauth.github.client_secret"
+	KeyOAuthGitHubRedirectURL  = "oauth.github.redirect_url" // Portal callback URL registered with GitHub
 
 	// Generic OIDC
 	KeyOAuthOIDCEnabled      = "oauth.oidc.enabled"
@@ -204,6 +291,8 @@ const (
 func SensitiveKeys() []string {
 	return []string{
 		KeyAuthJWTSecret,
+		KeyRateLimitRedisURL, // May contain a password
+		KeyStorageDSN,        // May contain a password
 		KeyEmailSMTPPassword,
 		KeyEmailSendGridKey,
 		KeyEmailSESSecretKey,
@@ -236,6 +325,7 @@ func Defaults() Settings {
 		KeyServerPort:                   "8080",
 		KeyServerReadTimeout:            "30s",
 		KeyServerWriteTimeout:           "60s",
+		KeyServerShutdownTimeout:        "30s",
 		KeyPortalEnabled:                "true",
 		KeyPortalAppName:                "APIGate",
 		KeyWebUIEnabled:                 "true", // Web UI enabled by default (backward compatible)
@@ -255,19 +345,68 @@ func Defaults() Settings {
 		KeyPaymentWebhookEnabled:  "true",
 		KeyMeterEnabled:           "true",
 		KeyAuthRequireEmailVerification: "false",
-		KeyEmailProvider:       "none",
-		KeyPaymentProvider:     "none",
-		KeyAuthMode:            "local",
-		KeyAuthHeader:          "X-API-Key",
-		KeyAuthKeyPrefix:       "ak_",
-		KeyAuthSessionTTL:      "168h", // 7 days
-		KeyRateLimitEnabled:    "true",
-		KeyRateLimitBurstTokens: "5",
-		KeyRateLimitWindowSecs:  "60",
-		KeyUpstreamTimeout:      "30s",
-		KeyUpstreamMaxIdleConns: "100",
-		KeyUpstreamIdleConnTimeout: "90s",
-		KeyMeteringUnit:         "requests",
+		KeyAuthHMACMaxSkew:              "5m",
+		KeyAuthKeyExpiryWarningDays:     "7",
+		KeyAuthKeyRotationGrace:         "24h",
+		KeyAuthJWKSURL:                  "",
+		KeyAuthJWKSIssuer:               "",
+		KeyAuthJWKSAudience:             "",
+		KeyAuthJWKSUserClaim:            "sub",
+		KeyAuthJWKSRefresh:              "1h",
+		KeyAuthPasswordMinLength:        "8",
+		KeyAuthPasswordRequireUpper:     "true",
+		KeyAuthPasswordRequireLower:     "true",
+		KeyAuthPasswordRequireNumber:    "true",
+		KeyAuthPasswordRequireSymbol:    "false",
+		KeyAuthPasswordCheckBreached:    "false",
+		KeyEmailProvider:                "none",
+		KeyPaymentProvider:              "none",
+		KeyPaymentOverageInterval:       "1h",
+		KeyPaymentDunningSchedule:       "3,7,14",
+		KeyBillingProvider:              "none",
+		KeyAuthMode:                     "local",
+		KeyAuthHeader:                   "X-API-Key",
+		KeyAuthKeyPrefix:                "ak_",
+		KeyAuthSessionTTL:               "24h",
+		KeyAuthRememberMeTTL:            "720h", // 30 days
+		KeyRateLimitEnabled:             "true",
+		KeyRateLimitBurstTokens:         "5",
+		KeyRateLimitWindowSecs:          "60",
+		KeyRateLimitRedisURL:            "",
+		KeyAnomalyWindow:                "5m",
+		KeyAnomalyBaseline:              "1h",
+		KeyAnomalyThrottleDuration:      "1h",
+		KeyQuotaWarningThresholds:       "80,100",
+		KeyUpstreamTimeout:              "30s",
+		KeyUpstreamMaxIdleConns:         "100",
+		KeyUpstreamIdleConnTimeout:      "90s",
+		KeyUpstreamDialTimeout:          "10s",
+		KeyUpstreamResponseHeaderTimeout: "0s",
+		KeyProxyMaxRequestBody:          "10485760", // 10MB
+		KeyProxyMaxResponseBody:         "52428800", // 50MB
+		KeyProxyCacheMaxEntries:         "10000",
+		KeyProxyTrustedProxies:          "",
+		KeyProxyDebugSources:            "",
+		KeyProxyPublicURL:               "",
+		KeyProxyCompressionEnabled:      "true",
+		KeyProxyCompressionMinSize:      "1024",
+		KeyProxyCompressionTypes:        "application/json,application/problem+json,application/xml,application/javascript,text/html,text/plain,text/css,text/xml,text/csv",
+		KeyMaintenanceEnabled:           "false",
+		KeyMaintenanceRetryAfter:        "5m",
+		KeyMaintenanceMessage:           "",
+		KeyMaintenanceJSONBody:          "",
+		KeyMaintenanceHTMLBody:          "",
+		KeyMetricsEnabled:               "false",
+		KeyMetricsRequireAuth:           "false",
+		KeyAccessLogPath:                "",
+		KeyAccessLogMaxSizeMB:           "100",
+		KeyAccessLogMaxBackups:          "5",
+		KeyTracingOTLPEndpoint:          "",
+		KeyTracingServiceName:           "apigate",
+		KeyStorageDriver:                "sqlite",
+		KeyStorageDSN:                   "",
+		KeyStorageAllowDestructiveMigrations: "false",
+		KeyMeteringUnit:                 "requests",
 		// Groups defaults
 		KeyGroupsEnabled:         "true",
 		KeyGroupsMaxPerUser:      "10",
@@ -0,0 +1,97 @@
+package streaming
+
+import (
+	"bytes"
+	"io"
+)
+
+// ReplaceReader rewrites a stream, replacing old strings with new ones as
+// data passes through. Only the bytes that could begin a match split across
+// reads are held back, so unrelated data (such as the end of an SSE event)
+// is never delayed.
+type ReplaceReader struct {
+	reader  io.ReadCloser
+	olds    [][]byte
+	news    [][]byte
+	pending []byte
+	out     bytes.Buffer
+	buf     []byte
+	err     error
+}
+
+// NewReplaceReader creates a reader replacing each old string with its new
+// one, given as old/new pairs like strings.NewReplacer. At each position the
+// first listed old string that matches wins.
+func NewReplaceReader(r io.ReadCloser, oldnew ...string) *ReplaceReader {
+	rr := &ReplaceReader{reader: r, buf: make([]byte, 32*1024)}
+	for i := 0; i+1 < len(oldnew); i += 2 {
+		if oldnew[i] == "" {
+			continue
+		}
+		rr.olds = append(rr.olds, []byte(oldnew[i]))
+		rr.news = append(rr.news, []byte(oldnew[i+1]))
+	}
+	return rr
+}
+
+// Read implements io.Reader.
+func (r *ReplaceReader) Read(p []byte) (int, error) {
+	for r.out.Len() == 0 && r.err == nil {
+		n, err := r.reader.Read(r.buf)
+		r.pending = append(r.pending, r.buf[:n]...)
+		if err != nil {
+			r.err = err
+		}
+		r.process(err != nil)
+	}
+	if r.out.Len() > 0 {
+		return r.out.Read(p)
+	}
+	return 0, r.err
+}
+
+// Close closes the underlying reader.
+func (r *ReplaceReader) Close() error {
+	return r.reader.Close()
+}
+
+// process moves pending data to the output, replacing complete matches and
+// keeping back a trailing partial match unless the stream has ended.
+func (r *ReplaceReader) process(final bool) {
+	for {
+		at, which := -1, -1
+		for k, old := range r.olds {
+			if i := bytes.Index(r.pending, old); i >= 0 && (at == -1 || i < at) {
+				at, which = i, k
+			}
+		}
+		if at == -1 {
+			break
+		}
+		r.out.Write(r.pending[:at])
+		r.out.Write(r.news[which])
+		r.pending = r.pending[at+len(r.olds[which]):]
+	}
+
+	keep := 0
+	if !final {
+		keep = r.partialMatch()
+	}
+	r.out.Write(r.pending[:len(r.pending)-keep])
+	r.pending = append(r.pending[:0:0], r.pending[len(r.pending)-keep:]...)
+}
+
+// partialMatch returns the length of the longest suffix of pending that is
+// the start of an old string.
+func (r *ReplaceReader) partialMatch() int {
+	longest := 0
+	for _, old := range r.olds {
+		for l := min(len(old)-1, len(r.pending)); l > longest; l-- {
+			if bytes.HasSuffix(r.pending, old[:l]) {
+				longest = l
+				break
+			}
+		}
+	}
+	return longest
+}
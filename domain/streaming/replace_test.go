@@ -0,0 +1,86 @@
+package streaming_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/artpar/apigate/domain/streaming"
+)
+
+// chunkReader returns one chunk per Read, like a network stream.
+type chunkReader struct {
+	chunks []string
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	if len(c.chunks) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, c.chunks[0])
+	c.chunks[0] = c.chunks[0][n:]
+	if c.chunks[0] == "" {
+		c.chunks = c.chunks[1:]
+	}
+	return n, nil
+}
+
+func TestReplaceReader(t *testing.T) {
+	pairs := []string{"http://internal:8080", "https://api.example.com", `http:\/\/internal:8080`, `https:\/\/api.example.com`}
+
+	tests := []struct {
+		name   string
+		chunks []string
+		want   string
+	}{
+		{"single chunk", []string{`{"next":"http://internal:8080/items?page=2"}`},
+			`{"next":"https://api.example.com/items?page=2"}`},
+		{"match split across chunks", []string{`{"self":"http://inter`, `nal:8080/a","b":"http://internal:`, `8080/b"}`},
+			`{"self":"https://api.example.com/a","b":"https://api.example.com/b"}`},
+		{"escaped slashes", []string{`{"u":"http:\/\/internal:8080\/x"}`},
+			`{"u":"https:\/\/api.example.com\/x"}`},
+		{"partial match at end of stream", []string{"see http://internal:80"},
+			"see http://internal:80"},
+		{"no match", []string{"data: one\n\n", "data: two\n\n"},
+			"data: one\n\ndata: two\n\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := streaming.NewReplaceReader(nopCloser{&chunkReader{chunks: tt.chunks}}, pairs...)
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("one byte at a time", func(t *testing.T) {
+		body := `[{"href":"http://internal:8080/1"},{"href":"http://internal:8080/2"}]`
+		r := streaming.NewReplaceReader(nopCloser{iotest.OneByteReader(strings.NewReader(body))}, pairs...)
+		got, _ := io.ReadAll(r)
+		if want := strings.ReplaceAll(body, "http://internal:8080", "https://api.example.com"); string(got) != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+}
+
+func TestReplaceReader_DoesNotHoldUnrelatedData(t *testing.T) {
+	// An SSE event must be delivered as soon as it arrives, not held back
+	// waiting for more data to rule out a match
+	r := streaming.NewReplaceReader(nopCloser{&chunkReader{chunks: []string{"data: done\n\n", "data: http://internal:8080\n\n"}}},
+		"http://internal:8080", "https://api.example.com")
+
+	buf := make([]byte, 1024)
+	n, err := r.Read(buf)
+	if err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+	if got := string(buf[:n]); got != "data: done\n\n" {
+		t.Errorf("first read = %q, want the whole first event", got)
+	}
+}
@@ -1,6 +1,9 @@
 package usage
 
-import "time"
+import (
+	"sort"
+	"time"
+)
 
 // Aggregate combines multiple events into a summary.
 // This is a PURE function.
@@ -56,6 +59,45 @@ func Aggregate(events []Event, periodStart, periodEnd time.Time) Summary {
 	}
 }
 
+// AggregateByPath groups events by request path and returns the n busiest
+// paths, by request count and then path. n <= 0 returns every path.
+// This is a PURE function.
+func AggregateByPath(events []Event, n int) []PathUsage {
+	byPath := make(map[string]*PathUsage)
+	var paths []*PathUsage
+	for _, e := range events {
+		p, ok := byPath[e.Path]
+		if !ok {
+			p = &PathUsage{Path: e.Path}
+			byPath[e.Path] = p
+			paths = append(paths, p)
+		}
+		p.RequestCount++
+		p.ComputeUnits += e.CostMultiplier
+		p.BytesIn += e.RequestBytes
+		p.BytesOut += e.ResponseBytes
+		if e.StatusCode >= 400 {
+			p.ErrorCount++
+		}
+	}
+
+	sort.Slice(paths, func(i, j int) bool {
+		if paths[i].RequestCount != paths[j].RequestCount {
+			return paths[i].RequestCount > paths[j].RequestCount
+		}
+		return paths[i].Path < paths[j].Path
+	})
+	if n > 0 && len(paths) > n {
+		paths = paths[:n]
+	}
+
+	result := make([]PathUsage, len(paths))
+	for i, p := range paths {
+		result[i] = *p
+	}
+	return result
+}
+
 // MergeSummaries combines multiple summaries.
 // This is a PURE function.
 func MergeSummaries(summaries ...Summary) Summary {
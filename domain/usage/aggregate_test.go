@@ -284,6 +284,41 @@ func TestAggregate_Empty(t *testing.T) {
 }
 
 // TestMergeSummaries tests the MergeSummaries function
+func TestAggregateByPath(t *testing.T) {
+	events := []usage.Event{
+		{Path: "/v1/search", StatusCode: 200, RequestBytes: 10, ResponseBytes: 100, CostMultiplier: 1},
+		{Path: "/v1/items", StatusCode: 200, RequestBytes: 20, ResponseBytes: 200, CostMultiplier: 1},
+		{Path: "/v1/search", StatusCode: 500, RequestBytes: 10, ResponseBytes: 50, CostMultiplier: 1},
+		{Path: "/v1/reports", StatusCode: 200, CostMultiplier: 5},
+		{Path: "/v1/search", StatusCode: 200, RequestBytes: 10, ResponseBytes: 100, CostMultiplier: 1},
+		{Path: "/v1/items", StatusCode: 404, RequestBytes: 20, CostMultiplier: 1},
+		{Path: "/v1/health", StatusCode: 200, CostMultiplier: 1},
+	}
+
+	paths := usage.AggregateByPath(events, 3)
+	if len(paths) != 3 {
+		t.Fatalf("got %d paths, want 3", len(paths))
+	}
+	// Busiest first; ties are ordered by path
+	want := []string{"/v1/search", "/v1/items", "/v1/health"}
+	for i, p := range paths {
+		if p.Path != want[i] {
+			t.Errorf("paths[%d] = %s, want %s", i, p.Path, want[i])
+		}
+	}
+	search := paths[0]
+	if search.RequestCount != 3 || search.ErrorCount != 1 || search.BytesIn != 30 || search.BytesOut != 250 || search.ComputeUnits != 3 {
+		t.Errorf("search usage = %+v", search)
+	}
+
+	if all := usage.AggregateByPath(events, 0); len(all) != 4 {
+		t.Errorf("n = 0: got %d paths, want all 4", len(all))
+	}
+	if empty := usage.AggregateByPath(nil, 5); len(empty) != 0 {
+		t.Errorf("no events: got %v", empty)
+	}
+}
+
 func TestMergeSummaries(t *testing.T) {
 	t.Run("empty summaries", func(t *testing.T) {
 		result := usage.MergeSummaries()
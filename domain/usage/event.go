@@ -132,15 +132,26 @@ func IsValidEventType(eventType string) bool {
 
 // Summary represents aggregated usage for a period (value type).
 type Summary struct {
-	UserID        string
-	PeriodStart   time.Time
-	PeriodEnd     time.Time
-	RequestCount  int64
-	ComputeUnits  float64 // Weighted by cost multipliers
-	BytesIn       int64
-	BytesOut      int64
-	ErrorCount    int64 // 4xx + 5xx responses
-	AvgLatencyMs  int64
+	UserID       string
+	PeriodStart  time.Time
+	PeriodEnd    time.Time
+	RequestCount int64
+	ComputeUnits float64 // Weighted by cost multipliers
+	BytesIn      int64
+	BytesOut     int64
+	ErrorCount   int64 // 4xx + 5xx responses
+	AvgLatencyMs int64
+	TopPaths     []PathUsage // Most requested paths, busiest first (when requested)
+}
+
+// PathUsage is the usage of a single request path within a period (value type).
+type PathUsage struct {
+	Path         string
+	RequestCount int64
+	ComputeUnits float64
+	BytesIn      int64
+	BytesOut     int64
+	ErrorCount   int64
 }
 
 // Quota represents usage limits for a plan (value type).
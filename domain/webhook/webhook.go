@@ -17,18 +17,22 @@ type EventType string
 
 // Supported event types
 const (
-	EventUsageThreshold    EventType = "usage.threshold"      // User reached usage threshold
-	EventUsageLimit        EventType = "usage.limit"          // User reached usage limit
-	EventKeyCreated        EventType = "key.created"          // API key was created
-	EventKeyRevoked        EventType = "key.revoked"          // API key was revoked
-	EventSubscriptionStart EventType = "subscription.start"   // Subscription started
-	EventSubscriptionEnd   EventType = "subscription.end"     // Subscription ended
-	EventSubscriptionRenew EventType = "subscription.renew"   // Subscription renewed
-	EventPlanChanged       EventType = "plan.changed"         // User changed plans
-	EventPaymentSuccess    EventType = "payment.success"      // Payment succeeded
-	EventPaymentFailed     EventType = "payment.failed"       // Payment failed
-	EventInvoiceCreated    EventType = "invoice.created"      // Invoice was created
-	EventTest              EventType = "test"                 // Test event
+	EventUsageThreshold        EventType = "usage.threshold"        // User reached usage threshold
+	EventUsageLimit            EventType = "usage.limit"            // User reached usage limit
+	EventKeyCreated            EventType = "key.created"            // API key was created
+	EventKeyRevoked            EventType = "key.revoked"            // API key was revoked
+	EventSubscriptionStart     EventType = "subscription.start"     // Subscription started
+	EventSubscriptionEnd       EventType = "subscription.end"       // Subscription ended
+	EventSubscriptionRenew     EventType = "subscription.renew"     // Subscription renewed
+	EventPlanChanged           EventType = "plan.changed"           // User changed plans
+	EventPaymentSuccess        EventType = "payment.success"        // Payment succeeded
+	EventPaymentFailed         EventType = "payment.failed"         // Payment failed
+	EventInvoiceCreated        EventType = "invoice.created"        // Invoice was created
+	EventUserCreated           EventType = "user.created"           // User account was created
+	EventSubscriptionCancelled EventType = "subscription.cancelled" // Subscription was cancelled
+	EventQuotaExceeded         EventType = "quota.exceeded"         // User exceeded their plan quota
+	EventUsageSpike            EventType = "usage.spike"            // API key request rate spiked above its baseline
+	EventTest                  EventType = "test"                   // Test event
 )
 
 // AllEventTypes returns all supported event types.
@@ -45,6 +49,10 @@ func AllEventTypes() []EventType {
 		EventPaymentSuccess,
 		EventPaymentFailed,
 		EventInvoiceCreated,
+		EventUserCreated,
+		EventSubscriptionCancelled,
+		EventQuotaExceeded,
+		EventUsageSpike,
 		EventTest,
 	}
 }
@@ -53,10 +61,19 @@ func AllEventTypes() []EventType {
 type DeliveryStatus string
 
 const (
-	DeliveryPending  DeliveryStatus = "pending"
-	DeliverySuccess  DeliveryStatus = "success"
-	DeliveryFailed   DeliveryStatus = "failed"
-	DeliveryRetrying DeliveryStatus = "retrying"
+	DeliveryPending    DeliveryStatus = "pending"
+	DeliverySuccess    DeliveryStatus = "success"
+	DeliveryFailed     DeliveryStatus = "failed"
+	DeliveryRetrying   DeliveryStatus = "retrying"
+	DeliveryDeadLetter DeliveryStatus = "dead_letter" // Retries exhausted; kept for inspection
+)
+
+// Retry backoff: the first retry waits DefaultRetryBackoff and each later
+// retry waits RetryBackoffFactor times longer, up to MaxRetryDelay.
+const (
+	DefaultRetryBackoff = time.Minute
+	RetryBackoffFactor  = 4
+	MaxRetryDelay       = 6 * time.Hour
 )
 
 // Webhook represents a webhook configuration (value type).
@@ -173,6 +190,13 @@ func SubscribesToEvent(webhook Webhook, eventType EventType) bool {
 	return false
 }
 
+// DeliversTo checks if a webhook should receive an event. Webhooks owned by a
+// user only receive that user's events; webhooks without an owner receive all.
+// This is a PURE function.
+func DeliversTo(webhook Webhook, event Event) bool {
+	return webhook.UserID == "" || webhook.UserID == event.UserID
+}
+
 // FilterWebhooksForEvent returns webhooks that subscribe to a given event.
 // This is a PURE function.
 func FilterWebhooksForEvent(webhooks []Webhook, eventType EventType) []Webhook {
@@ -188,6 +212,10 @@ func FilterWebhooksForEvent(webhooks []Webhook, eventType EventType) []Webhook {
 // ShouldRetry determines if a delivery should be retried based on status code.
 // This is a PURE function.
 func ShouldRetry(statusCode int) bool {
+	// No status means the request never got a response (connection error, timeout)
+	if statusCode == 0 {
+		return true
+	}
 	// Retry on server errors and some client errors
 	if statusCode >= 500 {
 		return true // Server errors
@@ -201,20 +229,24 @@ func ShouldRetry(statusCode int) bool {
 	return false
 }
 
+// RetryDelay returns how long to wait after a failed attempt, growing
+// exponentially from base.
+// This is a PURE function.
+func RetryDelay(attempt int, base time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempt; i++ {
+		delay *= RetryBackoffFactor
+		if delay >= MaxRetryDelay {
+			return MaxRetryDelay
+		}
+	}
+	return delay
+}
+
 // CalculateNextRetry calculates the next retry time using exponential backoff.
 // This is a PURE function.
 func CalculateNextRetry(attempt int, now time.Time) time.Time {
-	// Exponential backoff: 1min, 5min, 30min
-	delays := []time.Duration{
-		1 * time.Minute,
-		5 * time.Minute,
-		30 * time.Minute,
-	}
-	idx := attempt - 1
-	if idx >= len(delays) {
-		idx = len(delays) - 1
-	}
-	return now.Add(delays[idx])
+	return now.Add(RetryDelay(attempt, DefaultRetryBackoff))
 }
 
 // NewDelivery creates a new delivery for a webhook and event.
@@ -245,9 +277,17 @@ func MarkSuccess(d Delivery, statusCode int, responseBody string, durationMS int
 	return d
 }
 
-// MarkFailed updates a delivery as failed.
+// MarkFailed updates a delivery as failed using the default retry backoff.
 // This is a PURE function - returns a new Delivery.
 func MarkFailed(d Delivery, statusCode int, responseBody, errMsg string, durationMS int, now time.Time) Delivery {
+	return MarkFailedWithBackoff(d, statusCode, responseBody, errMsg, durationMS, DefaultRetryBackoff, now)
+}
+
+// MarkFailedWithBackoff updates a delivery as failed. Retryable failures are
+// rescheduled until MaxAttempts is reached, after which the delivery is
+// dead-lettered. Non-retryable failures are marked failed immediately.
+// This is a PURE function - returns a new Delivery.
+func MarkFailedWithBackoff(d Delivery, statusCode int, responseBody, errMsg string, durationMS int, backoff time.Duration, now time.Time) Delivery {
 	d.StatusCode = statusCode
 	d.ResponseBody = truncate(responseBody, 1000)
 	d.Error = errMsg
@@ -255,12 +295,16 @@ func MarkFailed(d Delivery, statusCode int, responseBody, errMsg string, duratio
 	d.UpdatedAt = now
 
 	// Check if we should retry
-	if d.Attempt < d.MaxAttempts && ShouldRetry(statusCode) {
+	switch {
+	case !ShouldRetry(statusCode):
+		d.Status = DeliveryFailed
+	case d.Attempt < d.MaxAttempts:
 		d.Status = DeliveryRetrying
-		nextRetry := CalculateNextRetry(d.Attempt, now)
+		nextRetry := now.Add(RetryDelay(d.Attempt, backoff))
 		d.NextRetry = &nextRetry
-	} else {
-		d.Status = DeliveryFailed
+	default:
+		d.Status = DeliveryDeadLetter
+		d.NextRetry = nil
 	}
 	return d
 }
@@ -91,6 +91,7 @@ func TestShouldRetry(t *testing.T) {
 		statusCode int
 		shouldRetry bool
 	}{
+		{0, true}, // No response (connection error)
 		{200, false},
 		{201, false},
 		{400, false},
@@ -122,25 +123,34 @@ func TestCalculateNextRetry(t *testing.T) {
 		t.Errorf("Attempt 1: expected %v, got %v", expected, next)
 	}
 
-	// Second attempt: 5 minutes
+	// Second attempt: 4 minutes
 	next = CalculateNextRetry(2, now)
-	expected = now.Add(5 * time.Minute)
+	expected = now.Add(4 * time.Minute)
 	if !next.Equal(expected) {
 		t.Errorf("Attempt 2: expected %v, got %v", expected, next)
 	}
 
-	// Third attempt: 30 minutes
+	// Third attempt: 16 minutes
 	next = CalculateNextRetry(3, now)
-	expected = now.Add(30 * time.Minute)
+	expected = now.Add(16 * time.Minute)
 	if !next.Equal(expected) {
 		t.Errorf("Attempt 3: expected %v, got %v", expected, next)
 	}
 
-	// Fourth+ attempt: still 30 minutes (max)
-	next = CalculateNextRetry(5, now)
-	expected = now.Add(30 * time.Minute)
+	// Later attempts are capped
+	next = CalculateNextRetry(10, now)
+	expected = now.Add(MaxRetryDelay)
 	if !next.Equal(expected) {
-		t.Errorf("Attempt 5: expected %v, got %v", expected, next)
+		t.Errorf("Attempt 10: expected %v, got %v", expected, next)
+	}
+}
+
+func TestRetryDelay_CustomBase(t *testing.T) {
+	if d := RetryDelay(1, 10*time.Millisecond); d != 10*time.Millisecond {
+		t.Errorf("Attempt 1: expected 10ms, got %v", d)
+	}
+	if d := RetryDelay(3, 10*time.Millisecond); d != 160*time.Millisecond {
+		t.Errorf("Attempt 3: expected 160ms, got %v", d)
 	}
 }
 
@@ -248,8 +258,25 @@ func TestMarkFailed_MaxAttemptsReached(t *testing.T) {
 	// Even with 500, should not retry if max attempts reached
 	updated := MarkFailed(delivery, 500, "Internal Server Error", "server error", 100, now)
 
-	if updated.Status != DeliveryFailed {
-		t.Errorf("Expected status 'failed', got '%s'", updated.Status)
+	if updated.Status != DeliveryDeadLetter {
+		t.Errorf("Expected status 'dead_letter', got '%s'", updated.Status)
+	}
+	if updated.NextRetry != nil {
+		t.Error("Expected no further retry for a dead-lettered delivery")
+	}
+}
+
+func TestDeliversTo(t *testing.T) {
+	event := Event{Type: EventUserCreated, UserID: "usr_1"}
+
+	if !DeliversTo(Webhook{}, event) {
+		t.Error("Webhook without an owner should receive all events")
+	}
+	if !DeliversTo(Webhook{UserID: "usr_1"}, event) {
+		t.Error("Webhook should receive its owner's events")
+	}
+	if DeliversTo(Webhook{UserID: "usr_2"}, event) {
+		t.Error("Webhook should not receive another user's events")
 	}
 }
 
@@ -0,0 +1,126 @@
+// Package clientip resolves the real client IP address of an HTTP request
+// behind load balancers and reverse proxies.
+//
+// Forwarding headers are attacker-controlled unless they were set by a proxy
+// the gateway trusts, so X-Forwarded-For is only walked across trusted hops.
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ParseTrusted parses a list of trusted proxy CIDRs. Single addresses are
+// treated as host prefixes (/32 or /128). Empty entries are skipped.
+func ParseTrusted(entries []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// FromRequest returns the client IP of r. See Resolve.
+func FromRequest(r *http.Request, trusted []netip.Prefix) string {
+	return Resolve(r.RemoteAddr, r.Header.Values("X-Forwarded-For"), r.Header.Get("X-Real-IP"), trusted)
+}
+
+// Resolve returns the client IP for a connection from remoteAddr that carried
+// the given X-Forwarded-For values and X-Real-IP header.
+//
+// When the peer is not a trusted proxy its address is the client IP and the
+// headers are ignored. Otherwise X-Forwarded-For is walked from the right,
+// skipping trusted hops, and the first untrusted address is the client. If
+// every hop is trusted the leftmost address is used. X-Real-IP is only
+// consulted when a trusted peer sent no X-Forwarded-For.
+func Resolve(remoteAddr string, xff []string, xRealIP string, trusted []netip.Prefix) string {
+	peer := remoteAddr
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		peer = host
+	}
+	if !IsTrusted(peer, trusted) {
+		return peer
+	}
+
+	var hops []string
+	for _, value := range xff {
+		for _, hop := range strings.Split(value, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	if len(hops) == 0 {
+		if ip := strings.TrimSpace(xRealIP); ip != "" {
+			if _, err := netip.ParseAddr(ip); err == nil {
+				return ip
+			}
+		}
+		return peer
+	}
+
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		if _, err := netip.ParseAddr(hops[i]); err != nil {
+			// A malformed hop can't be attributed; stop at the last good address
+			break
+		}
+		client = hops[i]
+		if !IsTrusted(client, trusted) {
+			break
+		}
+	}
+	return client
+}
+
+// IsTrusted reports whether ip belongs to one of the trusted prefixes.
+func IsTrusted(ip string, trusted []netip.Prefix) bool {
+	if len(trusted) == 0 {
+		return false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware replaces r.RemoteAddr with the resolved client IP so handlers
+// further down the chain see the real client address. It is a
+// trusted-proxy-aware replacement for chi's RealIP middleware.
+func Middleware(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip := FromRequest(r, trusted); ip != "" {
+				r.RemoteAddr = ip
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
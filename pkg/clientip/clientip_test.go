@@ -0,0 +1,103 @@
+package clientip
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	trusted, err := ParseTrusted([]string{"10.0.0.0/8", "192.168.1.10", "fd00::/8"})
+	if err != nil {
+		t.Fatalf("ParseTrusted: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        []string
+		xRealIP    string
+		want       string
+	}{
+		{"no proxy", "203.0.113.7:52000", nil, "", "203.0.113.7"},
+		{"no port", "203.0.113.7", nil, "", "203.0.113.7"},
+		{"ipv6 peer", "[2001:db8::1]:52000", nil, "", "2001:db8::1"},
+		{"single proxy", "10.0.0.5:52000", []string{"203.0.113.7"}, "", "203.0.113.7"},
+		{"chained proxies", "10.0.0.5:52000", []string{"203.0.113.7, 192.168.1.10, 10.2.3.4"}, "", "203.0.113.7"},
+		{"chained across headers", "10.0.0.5:52000", []string{"203.0.113.7", "10.2.3.4"}, "", "203.0.113.7"},
+		{"ipv6 chain", "[fd00::1]:52000", []string{"2001:db8::9, fd00::2"}, "", "2001:db8::9"},
+		{"spoofed entry left of client", "10.0.0.5:52000", []string{"198.51.100.1, 203.0.113.7"}, "", "203.0.113.7"},
+		{"untrusted xff", "203.0.113.7:52000", []string{"198.51.100.1"}, "", "203.0.113.7"},
+		{"untrusted x-real-ip", "203.0.113.7:52000", nil, "198.51.100.1", "203.0.113.7"},
+		{"x-real-ip from trusted proxy", "10.0.0.5:52000", nil, "203.0.113.7", "203.0.113.7"},
+		{"xff preferred over x-real-ip", "10.0.0.5:52000", []string{"203.0.113.7"}, "198.51.100.1", "203.0.113.7"},
+		{"all hops trusted", "10.0.0.5:52000", []string{"10.9.9.9, 10.2.3.4"}, "", "10.9.9.9"},
+		{"malformed hop", "10.0.0.5:52000", []string{"garbage, 10.2.3.4"}, "", "10.2.3.4"},
+		{"trusted proxy without headers", "10.0.0.5:52000", nil, "", "10.0.0.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Resolve(tt.remoteAddr, tt.xff, tt.xRealIP, trusted); got != tt.want {
+				t.Errorf("Resolve() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolve_NoTrustedProxies(t *testing.T) {
+	if got := Resolve("10.0.0.5:52000", []string{"203.0.113.7"}, "198.51.100.1", nil); got != "10.0.0.5" {
+		t.Errorf("Resolve() = %q, want peer address", got)
+	}
+}
+
+func TestParseTrusted(t *testing.T) {
+	prefixes, err := ParseTrusted([]string{" 10.1.2.3/8 ", "", "192.168.1.10", "::ffff:172.16.0.1", "2001:db8::/32"})
+	if err != nil {
+		t.Fatalf("ParseTrusted: %v", err)
+	}
+	want := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.168.1.10/32"),
+		netip.MustParsePrefix("172.16.0.1/32"),
+		netip.MustParsePrefix("2001:db8::/32"),
+	}
+	if len(prefixes) != len(want) {
+		t.Fatalf("prefixes = %v, want %v", prefixes, want)
+	}
+	for i := range want {
+		if prefixes[i] != want[i] {
+			t.Errorf("prefix %d = %v, want %v", i, prefixes[i], want[i])
+		}
+	}
+
+	for _, bad := range []string{"10.0.0.0/33", "not-an-ip"} {
+		if _, err := ParseTrusted([]string{bad}); err == nil {
+			t.Errorf("ParseTrusted(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var seen string
+	handler := Middleware([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.RemoteAddr
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.5:52000"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1, 203.0.113.7")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if seen != "203.0.113.7" {
+		t.Errorf("RemoteAddr behind trusted proxy = %q, want 203.0.113.7", seen)
+	}
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "198.51.100.1:52000"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if seen != "198.51.100.1" {
+		t.Errorf("RemoteAddr from untrusted peer = %q, want 198.51.100.1", seen)
+	}
+}
@@ -0,0 +1,271 @@
+// Package idempotency replays the stored response of a mutating request when
+// the client repeats it with the same Idempotency-Key, so double submits and
+// client retries have a single side effect.
+//
+// Keys are scoped per caller and endpoint: the same key sent by another user,
+// or to another path, is an unrelated request. Reusing a key with a different
+// request body is rejected with 422.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/artpar/apigate/domain/proxy"
+	"github.com/artpar/apigate/ports"
+)
+
+const (
+	// Header carries the client-chosen idempotency key.
+	Header = "Idempotency-Key"
+
+	// FormField carries the key for HTML form posts, which cannot set headers.
+	FormField = "idempotency_key"
+
+	// ReplayedHeader is set to "true" on responses served from the store.
+	ReplayedHeader = "Idempotent-Replayed"
+
+	// DefaultTTL is how long a stored response is replayed.
+	DefaultTTL = 24 * time.Hour
+
+	// MaxKeyLength is the longest key accepted.
+	MaxKeyLength = 255
+)
+
+// Config configures the middleware.
+type Config struct {
+	// Store holds the first response for each key.
+	Store ports.ResponseCache
+
+	// TTL is how long responses are replayed (<= 0 uses DefaultTTL).
+	TTL time.Duration
+
+	// Scope returns the caller a key belongs to, usually the user ID.
+	// Requests with an empty scope are not deduplicated.
+	Scope func(*http.Request) string
+}
+
+// Middleware deduplicates POST, PUT, PATCH and DELETE requests that carry an
+// idempotency key. The first response for a key is stored and replayed for
+// later requests with the same key and body; concurrent duplicates wait for
+// the first to finish. Server errors (5xx) are not stored so the request can
+// be retried.
+func Middleware(cfg Config) func(http.Handler) http.Handler {
+	ttl := cfg.TTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	locks := &keyLocks{held: make(map[string]*keyLock)}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !isMutating(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			key := requestKey(r)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > MaxKeyLength {
+				http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+				return
+			}
+			scope := ""
+			if cfg.Scope != nil {
+				scope = cfg.Scope(r)
+			}
+			if scope == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			hash, err := requestHash(r)
+			if err != nil {
+				http.Error(w, "Failed to read request body", http.StatusBadRequest)
+				return
+			}
+
+			storeKey := StoreKey(scope, r.Method, r.URL.Path, key)
+			unlock := locks.lock(storeKey)
+			defer unlock()
+
+			ctx := r.Context()
+			if resp, ok, err := cfg.Store.Get(ctx, storeKey); err == nil && ok {
+				var stored entry
+				if json.Unmarshal(resp.Body, &stored) == nil {
+					if stored.RequestHash != hash {
+						http.Error(w, "Idempotency-Key was already used with a different request", http.StatusUnprocessableEntity)
+						return
+					}
+					replay(w, stored)
+					return
+				}
+			}
+
+			rec := &recorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+
+			status := rec.statusCode()
+			if status >= http.StatusInternalServerError {
+				return
+			}
+			body, err := json.Marshal(entry{
+				RequestHash: hash,
+				Status:      status,
+				Header:      w.Header().Clone(),
+				Body:        rec.body.Bytes(),
+			})
+			if err != nil {
+				return
+			}
+			_ = cfg.Store.Set(context.WithoutCancel(ctx), storeKey, proxy.Response{Status: status, Body: body}, ttl)
+		})
+	}
+}
+
+// StoreKey returns the store key for a caller's request to an endpoint.
+func StoreKey(scope, method, path, key string) string {
+	sum := sha256.Sum256([]byte(scope + "\x00" + method + "\x00" + path + "\x00" + key))
+	return "idempotency:" + hex.EncodeToString(sum[:])
+}
+
+// NewKey returns a random key for embedding in HTML forms.
+func NewKey() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// requestKey returns the key from the header, falling back to the form field
+// for URL-encoded form posts.
+func requestKey(r *http.Request) string {
+	if key := strings.TrimSpace(r.Header.Get(Header)); key != "" {
+		return key
+	}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		if err := r.ParseForm(); err == nil {
+			return strings.TrimSpace(r.PostForm.Get(FormField))
+		}
+	}
+	return ""
+}
+
+// requestHash returns a hash of the request body, leaving the body in place
+// for the handler. Form posts were already parsed by requestKey, so their
+// fields are hashed instead.
+func requestHash(r *http.Request) (string, error) {
+	var body []byte
+	if r.PostForm != nil {
+		body = []byte(r.PostForm.Encode())
+	} else if r.Body != nil {
+		b, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return "", err
+		}
+		r.Body = io.NopCloser(bytes.NewReader(b))
+		body = b
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// entry is what the store holds for a key, JSON-encoded as the stored
+// response body: the first response with all its header values, and a hash
+// of the request that produced it.
+type entry struct {
+	RequestHash string      `json:"request_hash"`
+	Status      int         `json:"status"`
+	Header      http.Header `json:"header"`
+	Body        []byte      `json:"body"`
+}
+
+func replay(w http.ResponseWriter, stored entry) {
+	for k, v := range stored.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(stored.Status)
+	w.Write(stored.Body)
+}
+
+// recorder passes the response through while keeping a copy of it.
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *recorder) statusCode() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+// keyLocks serializes requests that share a store key.
+type keyLocks struct {
+	mu   sync.Mutex
+	held map[string]*keyLock
+}
+
+type keyLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+func (l *keyLocks) lock(key string) func() {
+	l.mu.Lock()
+	kl, ok := l.held[key]
+	if !ok {
+		kl = &keyLock{}
+		l.held[key] = kl
+	}
+	kl.refs++
+	l.mu.Unlock()
+
+	kl.mu.Lock()
+	return func() {
+		kl.mu.Unlock()
+		l.mu.Lock()
+		kl.refs--
+		if kl.refs == 0 {
+			delete(l.held, key)
+		}
+		l.mu.Unlock()
+	}
+}
@@ -0,0 +1,242 @@
+package idempotency
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/clock"
+	"github.com/artpar/apigate/adapters/memory"
+)
+
+// newTestHandler returns a middleware-wrapped handler that counts its side
+// effects and answers with a body unique to each execution.
+func newTestHandler(t *testing.T, clk *clock.Fake, status int) (http.Handler, *int64) {
+	t.Helper()
+	var calls int64
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `{"id":"key_%d"}`, n)
+	})
+	mw := Middleware(Config{
+		Store: memory.NewResponseCache(clk, 0),
+		TTL:   time.Hour,
+		Scope: func(r *http.Request) string { return r.Header.Get("X-User") },
+	})
+	return mw(next), &calls
+}
+
+func send(h http.Handler, method, path, user, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("X-User", user)
+	if key != "" {
+		req.Header.Set(Header, key)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestMiddleware_ReplaysDuplicate(t *testing.T) {
+	h, calls := newTestHandler(t, clock.NewFake(time.Now()), http.StatusCreated)
+
+	first := send(h, "POST", "/api-keys", "user1", "abc")
+	second := send(h, "POST", "/api-keys", "user1", "abc")
+
+	if *calls != 1 {
+		t.Fatalf("handler calls = %d, want 1", *calls)
+	}
+	if second.Code != first.Code || second.Code != http.StatusCreated {
+		t.Errorf("status = %d/%d, want %d", first.Code, second.Code, http.StatusCreated)
+	}
+	if second.Body.String() != first.Body.String() {
+		t.Errorf("replayed body = %q, want %q", second.Body.String(), first.Body.String())
+	}
+	if got := second.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("replayed Content-Type = %q", got)
+	}
+	if first.Header().Get(ReplayedHeader) != "" {
+		t.Error("first response should not be marked as replayed")
+	}
+	if second.Header().Get(ReplayedHeader) != "true" {
+		t.Error("second response should be marked as replayed")
+	}
+}
+
+func TestMiddleware_ConcurrentDuplicates(t *testing.T) {
+	h, calls := newTestHandler(t, clock.NewFake(time.Now()), http.StatusCreated)
+
+	const n = 10
+	bodies := make([]string, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			bodies[i] = send(h, "POST", "/api-keys", "user1", "abc").Body.String()
+		}(i)
+	}
+	wg.Wait()
+
+	if *calls != 1 {
+		t.Fatalf("handler calls = %d, want 1", *calls)
+	}
+	for i := 1; i < n; i++ {
+		if bodies[i] != bodies[0] {
+			t.Errorf("body[%d] = %q, want %q", i, bodies[i], bodies[0])
+		}
+	}
+}
+
+func TestMiddleware_DifferentBody(t *testing.T) {
+	h, calls := newTestHandler(t, clock.NewFake(time.Now()), http.StatusCreated)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api-keys", strings.NewReader(body))
+		req.Header.Set("X-User", "user1")
+		req.Header.Set(Header, "abc")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	first := post(`{"name":"prod"}`)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first status = %d, want %d", first.Code, http.StatusCreated)
+	}
+	if w := post(`{"name":"staging"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("different body: status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+	if w := post(`{"name":"prod"}`); w.Code != http.StatusCreated || w.Header().Get(ReplayedHeader) != "true" {
+		t.Errorf("same body: status = %d, replayed = %q, want a replayed %d", w.Code, w.Header().Get(ReplayedHeader), http.StatusCreated)
+	}
+	if *calls != 1 {
+		t.Errorf("handler calls = %d, want 1", *calls)
+	}
+}
+
+func TestMiddleware_ReplaysAllHeaderValues(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Set-Cookie", "a=1")
+		w.Header().Add("Set-Cookie", "b=2")
+		w.Write([]byte("ok"))
+	})
+	h := Middleware(Config{
+		Store: memory.NewResponseCache(clock.NewFake(time.Now()), 0),
+		Scope: func(r *http.Request) string { return "user1" },
+	})(next)
+
+	send(h, "POST", "/login", "user1", "abc")
+	second := send(h, "POST", "/login", "user1", "abc")
+
+	if got := second.Header().Values("Set-Cookie"); len(got) != 2 || got[0] != "a=1" || got[1] != "b=2" {
+		t.Errorf("replayed Set-Cookie = %v, want [a=1 b=2]", got)
+	}
+}
+
+func TestMiddleware_Scoping(t *testing.T) {
+	h, calls := newTestHandler(t, clock.NewFake(time.Now()), http.StatusOK)
+
+	send(h, "POST", "/api-keys", "user1", "abc")
+	send(h, "POST", "/api-keys", "user2", "abc")     // other user
+	send(h, "POST", "/plans/change", "user1", "abc") // other endpoint
+	send(h, "POST", "/api-keys", "user1", "def")     // other key
+
+	if *calls != 4 {
+		t.Errorf("handler calls = %d, want 4", *calls)
+	}
+}
+
+func TestMiddleware_PassesThrough(t *testing.T) {
+	h, calls := newTestHandler(t, clock.NewFake(time.Now()), http.StatusOK)
+
+	send(h, "POST", "/api-keys", "user1", "")
+	send(h, "POST", "/api-keys", "user1", "")
+	send(h, "GET", "/api-keys", "user1", "abc")
+	send(h, "GET", "/api-keys", "user1", "abc")
+	send(h, "POST", "/api-keys", "", "abc") // no scope
+	send(h, "POST", "/api-keys", "", "abc")
+
+	if *calls != 6 {
+		t.Errorf("handler calls = %d, want 6", *calls)
+	}
+}
+
+func TestMiddleware_ServerErrorsNotStored(t *testing.T) {
+	h, calls := newTestHandler(t, clock.NewFake(time.Now()), http.StatusInternalServerError)
+
+	send(h, "POST", "/api-keys", "user1", "abc")
+	second := send(h, "POST", "/api-keys", "user1", "abc")
+
+	if *calls != 2 {
+		t.Errorf("handler calls = %d, want 2", *calls)
+	}
+	if second.Header().Get(ReplayedHeader) != "" {
+		t.Error("server error should not be replayed")
+	}
+}
+
+func TestMiddleware_ExpiresAfterTTL(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	h, calls := newTestHandler(t, clk, http.StatusOK)
+
+	send(h, "POST", "/api-keys", "user1", "abc")
+	clk.Advance(time.Hour)
+	send(h, "POST", "/api-keys", "user1", "abc")
+
+	if *calls != 2 {
+		t.Errorf("handler calls = %d, want 2", *calls)
+	}
+}
+
+func TestMiddleware_KeyTooLong(t *testing.T) {
+	h, calls := newTestHandler(t, clock.NewFake(time.Now()), http.StatusOK)
+
+	w := send(h, "POST", "/api-keys", "user1", strings.Repeat("k", MaxKeyLength+1))
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if *calls != 0 {
+		t.Errorf("handler calls = %d, want 0", *calls)
+	}
+}
+
+func TestMiddleware_FormField(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	var names []string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		names = append(names, r.FormValue("name"))
+		w.Write([]byte("created"))
+	})
+	h := Middleware(Config{
+		Store: memory.NewResponseCache(clk, 0),
+		Scope: func(r *http.Request) string { return "user1" },
+	})(next)
+
+	form := url.Values{"name": {"prod"}, FormField: {"form-key"}}
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("POST", "/api-keys", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if len(names) != 1 || names[0] != "prod" {
+		t.Errorf("handler saw %v, want one request with name prod", names)
+	}
+}
+
+func TestNewKey(t *testing.T) {
+	a, b := NewKey(), NewKey()
+	if len(a) != 32 || a == b {
+		t.Errorf("NewKey() = %q, %q", a, b)
+	}
+}
@@ -0,0 +1,230 @@
+// Package invoicedoc renders a billing invoice for customers, either as a
+// printable HTML page or as a PDF download.
+//
+// The PDF is written directly with the standard Helvetica fonts, so no
+// external renderer is needed. Text outside Latin-1 is replaced with "?".
+package invoicedoc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/artpar/apigate/domain/billing"
+)
+
+// Document formats.
+const (
+	FormatHTML = "html"
+	FormatPDF  = "pdf"
+)
+
+// ErrUnknownFormat is returned for formats other than html and pdf.
+var ErrUnknownFormat = errors.New("format must be html or pdf")
+
+// Customer is the billed party shown on the invoice.
+type Customer struct {
+	Name  string
+	Email string
+}
+
+// Document is an invoice with the details needed to render it.
+type Document struct {
+	Invoice  billing.Invoice
+	Seller   string // Name of the issuing business, e.g. the app name
+	Customer Customer
+}
+
+// Filename returns the download filename for an invoice document.
+func Filename(inv billing.Invoice, format string) string {
+	id := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, inv.ID)
+	return fmt.Sprintf("invoice_%s.%s", id, format)
+}
+
+// ContentType returns the MIME type of a document format.
+func ContentType(format string) string {
+	if format == FormatPDF {
+		return "application/pdf"
+	}
+	return "text/html; charset=utf-8"
+}
+
+// Render writes the document in the given format. An empty format means HTML.
+func Render(w io.Writer, d Document, format string) error {
+	switch format {
+	case "", FormatHTML:
+		return HTML(w, d)
+	case FormatPDF:
+		return PDF(w, d)
+	default:
+		return ErrUnknownFormat
+	}
+}
+
+// view holds the formatted invoice shared by the HTML and PDF renderers.
+type view struct {
+	Number   string
+	Seller   string
+	Customer Customer
+	Status   string
+	Issued   string
+	Period   string
+	Due      string
+	Paid     string
+	Currency string
+	Items    []viewItem
+	Subtotal string
+	Tax      string // Empty when there is no tax
+	Total    string
+}
+
+type viewItem struct {
+	Description string
+	Quantity    string
+	UnitPrice   string
+	Amount      string
+}
+
+const dateLayout = "Jan 2, 2006"
+
+func newView(d Document) view {
+	inv := d.Invoice
+	v := view{
+		Number:   inv.ID,
+		Seller:   d.Seller,
+		Customer: d.Customer,
+		Status:   strings.ToUpper(string(inv.Status)),
+		Issued:   formatDate(inv.CreatedAt),
+		Currency: strings.ToUpper(inv.Currency),
+		Subtotal: billing.FormatAmount(inv.Subtotal),
+		Total:    billing.FormatAmount(inv.Total),
+	}
+	if v.Seller == "" {
+		v.Seller = "APIGate"
+	}
+	if v.Currency == "" {
+		v.Currency = "USD"
+	}
+	if !inv.PeriodStart.IsZero() && !inv.PeriodEnd.IsZero() {
+		v.Period = formatDate(inv.PeriodStart) + " - " + formatDate(inv.PeriodEnd)
+	}
+	if inv.DueDate != nil {
+		v.Due = formatDate(*inv.DueDate)
+	}
+	if inv.PaidAt != nil {
+		v.Paid = formatDate(*inv.PaidAt)
+	}
+	if inv.Tax != 0 {
+		v.Tax = billing.FormatAmount(inv.Tax)
+	}
+	for _, item := range inv.Items {
+		v.Items = append(v.Items, viewItem{
+			Description: item.Description,
+			Quantity:    fmt.Sprintf("%d", item.Quantity),
+			UnitPrice:   billing.FormatAmount(item.UnitPrice),
+			Amount:      billing.FormatAmount(item.Amount),
+		})
+	}
+	return v
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(dateLayout)
+}
+
+// HTML writes the invoice as a self-contained page styled for printing.
+func HTML(w io.Writer, d Document) error {
+	var buf bytes.Buffer
+	if err := htmlTmpl.Execute(&buf, newView(d)); err != nil {
+		return fmt.Errorf("execute invoice template: %w", err)
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+var htmlTmpl = htmltemplate.Must(htmltemplate.New("invoice").Parse(strings.TrimSpace(`
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <title>Invoice {{.Number}} - {{.Seller}}</title>
+    <style>
+        body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; color: #111827; margin: 0; padding: 40px; }
+        .invoice { max-width: 800px; margin: 0 auto; }
+        .header { display: flex; justify-content: space-between; align-items: flex-start; margin-bottom: 32px; }
+        .header h1 { margin: 0; font-size: 28px; }
+        .meta { text-align: right; color: #4b5563; font-size: 14px; line-height: 1.6; }
+        .status { display: inline-block; padding: 2px 8px; border-radius: 4px; background: #f3f4f6; font-size: 12px; font-weight: 600; }
+        .parties { display: flex; justify-content: space-between; margin-bottom: 32px; font-size: 14px; line-height: 1.6; }
+        .label { color: #6b7280; font-size: 12px; text-transform: uppercase; letter-spacing: 0.05em; }
+        table { width: 100%; border-collapse: collapse; font-size: 14px; }
+        th { text-align: left; color: #6b7280; font-weight: 500; border-bottom: 2px solid #e5e7eb; padding: 8px; }
+        td { border-bottom: 1px solid #e5e7eb; padding: 8px; }
+        .num { text-align: right; white-space: nowrap; }
+        .totals td { border: none; padding: 4px 8px; }
+        .totals .grand td { font-weight: 700; font-size: 16px; border-top: 2px solid #111827; }
+        .actions { margin-top: 32px; text-align: right; }
+        @media print { body { padding: 0; } .actions { display: none; } }
+    </style>
+</head>
+<body>
+    <div class="invoice">
+        <div class="header">
+            <div>
+                <h1>Invoice</h1>
+                <div style="font-size: 18px; margin-top: 4px;">{{.Seller}}</div>
+            </div>
+            <div class="meta">
+                <div>Invoice <strong>{{.Number}}</strong></div>
+                {{if .Issued}}<div>Issued {{.Issued}}</div>{{end}}
+                {{if .Period}}<div>Period {{.Period}}</div>{{end}}
+                {{if .Due}}<div>Due {{.Due}}</div>{{end}}
+                {{if .Paid}}<div>Paid {{.Paid}}</div>{{end}}
+                <div><span class="status">{{.Status}}</span></div>
+            </div>
+        </div>
+        <div class="parties">
+            <div>
+                <div class="label">Bill to</div>
+                {{if .Customer.Name}}<div>{{.Customer.Name}}</div>{{end}}
+                {{if .Customer.Email}}<div>{{.Customer.Email}}</div>{{end}}
+            </div>
+            <div style="text-align: right;">
+                <div class="label">Currency</div>
+                <div>{{.Currency}}</div>
+            </div>
+        </div>
+        <table>
+            <thead>
+                <tr><th>Description</th><th class="num">Qty</th><th class="num">Unit price</th><th class="num">Amount</th></tr>
+            </thead>
+            <tbody>
+                {{range .Items}}<tr><td>{{.Description}}</td><td class="num">{{.Quantity}}</td><td class="num">{{.UnitPrice}}</td><td class="num">{{.Amount}}</td></tr>
+                {{else}}<tr><td colspan="4" style="color: #6b7280;">No line items</td></tr>
+                {{end}}
+            </tbody>
+        </table>
+        <table class="totals" style="margin-top: 16px;">
+            <tr><td></td><td class="num" style="width: 160px;">Subtotal</td><td class="num" style="width: 120px;">{{.Subtotal}}</td></tr>
+            {{if .Tax}}<tr><td></td><td class="num">Tax</td><td class="num">{{.Tax}}</td></tr>{{end}}
+            <tr class="grand"><td></td><td class="num">Total</td><td class="num">{{.Total}}</td></tr>
+        </table>
+        <div class="actions">
+            <button onclick="window.print()">Print</button>
+        </div>
+    </div>
+</body>
+</html>
+`)))
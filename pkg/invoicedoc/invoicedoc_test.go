@@ -0,0 +1,180 @@
+package invoicedoc
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/domain/billing"
+)
+
+var baseTime = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+func testDocument() Document {
+	inv := billing.CalculateInvoice("user-1", baseTime, baseTime.AddDate(0, 1, 0).Add(-time.Second), "Pro", 4900, 12500, 10000, 2)
+	inv.ID = "inv_2024_03"
+	inv.Currency = "usd"
+	inv.Status = billing.InvoiceStatusOpen
+	return Document{
+		Invoice:  inv,
+		Seller:   "Acme API",
+		Customer: Customer{Name: "Ada (Ops) Lovelace", Email: "ada@example.com"},
+	}
+}
+
+func TestHTML(t *testing.T) {
+	d := testDocument()
+	var buf bytes.Buffer
+	if err := HTML(&buf, d); err != nil {
+		t.Fatalf("HTML: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"Pro - Monthly subscription",
+		"API overage (2,500 requests)",
+		"$49", "$50", "$0.02",
+		"Total</td><td class=\"num\">$99</td>",
+		"Ada (Ops) Lovelace", "ada@example.com", "Acme API", "inv_2024_03", "USD", "OPEN",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("HTML is missing %q", want)
+		}
+	}
+
+	d.Invoice.Items = []billing.InvoiceItem{{Description: "<script>alert(1)</script>", Quantity: 1}}
+	buf.Reset()
+	HTML(&buf, d)
+	if strings.Contains(buf.String(), "<script>alert") {
+		t.Error("line item description was not escaped")
+	}
+}
+
+// pdfStrings returns the text drawn by Tj operators, unescaped.
+func pdfStrings(pdf []byte) []string {
+	var out []string
+	for _, m := range regexp.MustCompile(`\(((?:\\.|[^\\)])*)\) Tj`).FindAllSubmatch(pdf, -1) {
+		out = append(out, regexp.MustCompile(`\\([\\()])`).ReplaceAllString(string(m[1]), "$1"))
+	}
+	return out
+}
+
+func TestPDF(t *testing.T) {
+	d := testDocument()
+	var buf bytes.Buffer
+	if err := PDF(&buf, d); err != nil {
+		t.Fatalf("PDF: %v", err)
+	}
+	pdf := buf.Bytes()
+
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatal("output is not framed as a PDF")
+	}
+	text := strings.Join(pdfStrings(pdf), "\n")
+	for _, item := range d.Invoice.Items {
+		if !strings.Contains(text, item.Description) {
+			t.Errorf("PDF is missing line item %q", item.Description)
+		}
+	}
+	if !strings.Contains(text, "Total\n$99") {
+		t.Errorf("PDF total is not $99:\n%s", text)
+	}
+	for _, want := range []string{"Ada (Ops) Lovelace", "ada@example.com", "Acme API", "Invoice inv_2024_03"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("PDF is missing %q", want)
+		}
+	}
+	checkXref(t, pdf)
+}
+
+func TestPDF_ManyItemsSpanPages(t *testing.T) {
+	d := testDocument()
+	d.Invoice.Items = nil
+	var total int64
+	for i := 1; i <= 120; i++ {
+		d.Invoice.Items = append(d.Invoice.Items, billing.InvoiceItem{
+			Description: fmt.Sprintf("Line item number %d", i), Quantity: 1, UnitPrice: 100, Amount: 100,
+		})
+		total += 100
+	}
+	d.Invoice.Subtotal, d.Invoice.Total = total, total
+
+	var buf bytes.Buffer
+	if err := PDF(&buf, d); err != nil {
+		t.Fatalf("PDF: %v", err)
+	}
+	pdf := buf.Bytes()
+	if !regexp.MustCompile(`/Count [2-9]`).Match(pdf) {
+		t.Error("120 line items fit on one page")
+	}
+	text := strings.Join(pdfStrings(pdf), "\n")
+	for i := 1; i <= 120; i++ {
+		if !strings.Contains(text, fmt.Sprintf("Line item number %d\n", i)) {
+			t.Errorf("PDF is missing line item %d", i)
+		}
+	}
+	if !strings.Contains(text, "Total\n$120") {
+		t.Error("PDF total is not $120")
+	}
+	checkXref(t, pdf)
+}
+
+// checkXref verifies that every cross-reference entry points at its object.
+func checkXref(t *testing.T, pdf []byte) {
+	t.Helper()
+	m := regexp.MustCompile(`startxref\n(\d+)`).FindSubmatch(pdf)
+	if m == nil {
+		t.Fatal("missing startxref")
+	}
+	start, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(pdf[start:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the xref table", start)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(pdf[start:], -1)
+	for i, e := range entries {
+		off, _ := strconv.Atoi(string(e[1]))
+		if want := fmt.Sprintf("%d 0 obj", i+1); !bytes.HasPrefix(pdf[off:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q", i+1, pdf[off:off+10])
+		}
+	}
+}
+
+func TestEscape(t *testing.T) {
+	tests := map[string]string{
+		"plain":        "plain",
+		`a (b) \c`:     `a \(b\) \\c`,
+		"café":         `caf\351`,
+		"日本":           "??",
+		"price: €5.00": "price: ?5.00",
+	}
+	for in, want := range tests {
+		if got := escape(in); got != want {
+			t.Errorf("escape(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	got := wrap("API overage for the reporting endpoints in March", 20)
+	want := []string{"API overage for the", "reporting endpoints", "in March"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("wrap = %q, want %q", got, want)
+	}
+	if got := wrap(strings.Repeat("x", 25), 10); len(got) != 3 || got[2] != "xxxxx" {
+		t.Errorf("wrap of a long word = %q", got)
+	}
+}
+
+func TestRender(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Render(&buf, testDocument(), "docx"); err != ErrUnknownFormat {
+		t.Errorf("Render(docx) error = %v, want ErrUnknownFormat", err)
+	}
+	if got := Filename(billing.Invoice{ID: "in/1 2"}, FormatPDF); got != "invoice_in_1_2.pdf" {
+		t.Errorf("Filename = %q", got)
+	}
+}
@@ -0,0 +1,283 @@
+package invoicedoc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A4 page geometry in points.
+const (
+	pageWidth    = 595.0
+	pageHeight   = 842.0
+	marginLeft   = 50.0
+	marginRight  = 545.0
+	marginTop    = 790.0
+	marginBottom = 70.0
+)
+
+// Right edges of the numeric item columns.
+const (
+	colQuantity  = 360.0
+	colUnitPrice = 450.0
+	colAmount    = marginRight
+)
+
+// maxDescriptionChars keeps descriptions clear of the quantity column;
+// longer descriptions wrap onto further lines.
+const maxDescriptionChars = 48
+
+// PDF writes the invoice as a PDF document. Long invoices continue on
+// further pages with the item header repeated.
+func PDF(w io.Writer, d Document) error {
+	v := newView(d)
+	l := &pdfLayout{}
+	l.newPage()
+
+	l.text(marginLeft, l.y, 24, true, "Invoice")
+	l.textRight(marginRight, l.y, 10, false, "Invoice "+v.Number)
+	l.y -= 22
+	l.text(marginLeft, l.y, 14, false, v.Seller)
+	meta := []string{}
+	if v.Issued != "" {
+		meta = append(meta, "Issued "+v.Issued)
+	}
+	if v.Period != "" {
+		meta = append(meta, "Period "+v.Period)
+	}
+	if v.Due != "" {
+		meta = append(meta, "Due "+v.Due)
+	}
+	if v.Paid != "" {
+		meta = append(meta, "Paid "+v.Paid)
+	}
+	meta = append(meta, "Status "+v.Status)
+	y := l.y + 8
+	for _, line := range meta {
+		y -= 14
+		l.textRight(marginRight, y, 10, false, line)
+	}
+	l.y = min(l.y, y) - 30
+
+	l.text(marginLeft, l.y, 9, true, "BILL TO")
+	l.textRight(marginRight, l.y, 9, true, "CURRENCY")
+	l.y -= 14
+	l.textRight(marginRight, l.y, 10, false, v.Currency)
+	for _, line := range []string{v.Customer.Name, v.Customer.Email} {
+		if line != "" {
+			l.text(marginLeft, l.y, 10, false, line)
+			l.y -= 14
+		}
+	}
+	l.y -= 24
+
+	l.itemHeader()
+	if len(v.Items) == 0 {
+		l.text(marginLeft, l.y, 10, false, "No line items")
+		l.y -= 16
+	}
+	for _, item := range v.Items {
+		lines := wrap(item.Description, maxDescriptionChars)
+		if l.y-float64(len(lines)-1)*13 < marginBottom {
+			l.newPage()
+			l.itemHeader()
+		}
+		l.textRight(colQuantity, l.y, 10, false, item.Quantity)
+		l.textRight(colUnitPrice, l.y, 10, false, item.UnitPrice)
+		l.textRight(colAmount, l.y, 10, false, item.Amount)
+		for _, line := range lines {
+			l.text(marginLeft, l.y, 10, false, line)
+			l.y -= 13
+		}
+		l.y -= 5
+		l.line(marginLeft, l.y+9, marginRight, l.y+9, 0.5)
+	}
+
+	if l.y-60 < marginBottom {
+		l.newPage()
+	}
+	l.y -= 10
+	l.textRight(colUnitPrice, l.y, 10, false, "Subtotal")
+	l.textRight(colAmount, l.y, 10, false, v.Subtotal)
+	if v.Tax != "" {
+		l.y -= 16
+		l.textRight(colUnitPrice, l.y, 10, false, "Tax")
+		l.textRight(colAmount, l.y, 10, false, v.Tax)
+	}
+	l.y -= 8
+	l.line(colQuantity, l.y, marginRight, l.y, 1.5)
+	l.y -= 16
+	l.textRight(colUnitPrice, l.y, 12, true, "Total")
+	l.textRight(colAmount, l.y, 12, true, v.Total)
+
+	return l.writeTo(w, "Invoice "+v.Number)
+}
+
+// pdfLayout accumulates page content streams.
+type pdfLayout struct {
+	pages []*bytes.Buffer
+	y     float64
+}
+
+func (l *pdfLayout) newPage() {
+	l.pages = append(l.pages, &bytes.Buffer{})
+	l.y = marginTop
+}
+
+func (l *pdfLayout) page() *bytes.Buffer {
+	return l.pages[len(l.pages)-1]
+}
+
+func (l *pdfLayout) itemHeader() {
+	l.text(marginLeft, l.y, 9, true, "DESCRIPTION")
+	l.textRight(colQuantity, l.y, 9, true, "QTY")
+	l.textRight(colUnitPrice, l.y, 9, true, "UNIT PRICE")
+	l.textRight(colAmount, l.y, 9, true, "AMOUNT")
+	l.line(marginLeft, l.y-6, marginRight, l.y-6, 1)
+	l.y -= 22
+}
+
+func (l *pdfLayout) text(x, y, size float64, bold bool, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(l.page(), "BT /%s %s Tf %s %s Td (%s) Tj ET\n", font, num(size), num(x), num(y), escape(s))
+}
+
+func (l *pdfLayout) textRight(right, y, size float64, bold bool, s string) {
+	l.text(right-textWidth(s, size, bold), y, size, bold, s)
+}
+
+func (l *pdfLayout) line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(l.page(), "%s w %s %s m %s %s l S\n", num(width), num(x1), num(y1), num(x2), num(y2))
+}
+
+// writeTo writes the PDF file structure around the page content streams.
+// Objects: 1 catalog, 2 page tree, 3-4 fonts, 5 info, then a page and its
+// content stream for each page.
+func (l *pdfLayout) writeTo(w io.Writer, title string) error {
+	var buf bytes.Buffer
+	var offsets []int
+	obj := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	kids := make([]string, len(l.pages))
+	for i := range l.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 6+2*i)
+	}
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(l.pages)))
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	obj(fmt.Sprintf("<< /Title (%s) >>", escape(title)))
+	for i, content := range l.pages {
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			num(pageWidth), num(pageHeight), 7+2*i))
+		obj(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// num formats a coordinate without trailing zeros.
+func num(f float64) string {
+	s := fmt.Sprintf("%.2f", f)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// escape encodes s as the body of a PDF literal string in WinAnsi. Latin-1
+// characters are written as octal escapes; anything else becomes "?".
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// wrap splits s into lines of at most width characters, breaking at spaces
+// where possible.
+func wrap(s string, width int) []string {
+	words := strings.Fields(s)
+	if len(words) == 0 {
+		return []string{""}
+	}
+	var lines []string
+	line := ""
+	for _, word := range words {
+		for len(word) > width {
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			lines = append(lines, word[:width])
+			word = word[width:]
+		}
+		switch {
+		case line == "":
+			line = word
+		case len(line)+1+len(word) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// textWidth approximates the width of s in points using Helvetica metrics
+// for the characters that appear in amounts and headings.
+func textWidth(s string, size float64, bold bool) float64 {
+	units := 0
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9', r == '$':
+			units += 556
+		case r == ',' || r == '.' || r == ' ':
+			units += 278
+		case r == '-':
+			units += 333
+		case r >= 'A' && r <= 'Z':
+			units += 700
+		case r == 'i' || r == 'l' || r == 'j' || r == 't' || r == 'f':
+			units += 278
+		case r == 'm' || r == 'w':
+			units += 833
+		default:
+			units += 556
+		}
+	}
+	if bold {
+		units = units * 105 / 100
+	}
+	return float64(units) * size / 1000
+}
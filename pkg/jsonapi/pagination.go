@@ -6,11 +6,16 @@ import (
 )
 
 // Pagination holds pagination information for generating links and metadata.
+//
+// Pages are addressed by number unless Cursor is set, in which case links use
+// page[cursor] and only move forward.
 type Pagination struct {
-	Total   int64  // Total number of items
-	Page    int    // Current page number (1-based)
-	PerPage int    // Items per page
-	BaseURL string // Base URL for generating links
+	Total      int64  // Total number of items
+	Page       int    // Current page number (1-based)
+	PerPage    int    // Items per page
+	BaseURL    string // Base URL for generating links
+	Cursor     string // Cursor the page was read with (cursor pagination)
+	NextCursor string // Cursor for the following page, empty on the last page
 }
 
 // NewPagination creates a new Pagination instance.
@@ -29,6 +34,15 @@ func NewPagination(total int64, page, perPage int, baseURL string) *Pagination {
 	}
 }
 
+// NewCursorPagination creates a Pagination for a page read with a cursor.
+// An empty cursor denotes the first page.
+func NewCursorPagination(total int64, perPage int, cursor, nextCursor, baseURL string) *Pagination {
+	p := NewPagination(total, 1, perPage, baseURL)
+	p.Cursor = cursor
+	p.NextCursor = nextCursor
+	return p
+}
+
 // TotalPages returns the total number of pages.
 func (p *Pagination) TotalPages() int {
 	if p.Total == 0 {
@@ -48,6 +62,9 @@ func (p *Pagination) HasPrev() bool {
 
 // HasNext returns true if there is a next page.
 func (p *Pagination) HasNext() bool {
+	if p.Cursor != "" {
+		return p.NextCursor != ""
+	}
 	return p.Page < p.TotalPages()
 }
 
@@ -63,6 +80,17 @@ func (p *Pagination) Limit() int {
 
 // Links generates pagination links.
 func (p *Pagination) Links() *Links {
+	if p.Cursor != "" {
+		links := &Links{
+			Self:  p.buildCursorURL(p.Cursor),
+			First: p.buildCursorURL(""),
+		}
+		if p.NextCursor != "" {
+			links.Next = p.buildCursorURL(p.NextCursor)
+		}
+		return links
+	}
+
 	totalPages := p.TotalPages()
 
 	links := &Links{
@@ -92,7 +120,7 @@ func (p *Pagination) buildURL(page int) string {
 		return p.BaseURL
 	}
 
-	q := u.Query()
+	q := withoutPaginationParams(u.Query())
 	q.Set("page[number]", strconv.Itoa(page))
 	q.Set("page[size]", strconv.Itoa(p.PerPage))
 	u.RawQuery = q.Encode()
@@ -100,14 +128,62 @@ func (p *Pagination) buildURL(page int) string {
 	return u.String()
 }
 
-// Meta returns pagination metadata.
+// buildCursorURL builds a URL for the page after cursor ("" for the first page).
+func (p *Pagination) buildCursorURL(cursor string) string {
+	if p.BaseURL == "" {
+		return ""
+	}
+
+	u, err := url.Parse(p.BaseURL)
+	if err != nil {
+		return p.BaseURL
+	}
+
+	q := withoutPaginationParams(u.Query())
+	if cursor != "" {
+		q.Set("page[cursor]", cursor)
+	}
+	q.Set("page[size]", strconv.Itoa(p.PerPage))
+	u.RawQuery = q.Encode()
+
+	return u.String()
+}
+
+// withoutPaginationParams removes every pagination parameter style so links
+// carry exactly one.
+func withoutPaginationParams(q url.Values) url.Values {
+	for _, k := range []string{"page[number]", "page[size]", "page[cursor]", "page", "per_page", "limit", "offset", "cursor"} {
+		q.Del(k)
+	}
+	return q
+}
+
+// Meta returns pagination metadata. limit and offset mirror per_page and the
+// page position; next_cursor is present when a following page exists and the
+// store supports cursors.
 func (p *Pagination) Meta() Meta {
-	return Meta{
+	m := Meta{
 		"total":    p.Total,
-		"page":     p.Page,
+		"limit":    p.PerPage,
 		"per_page": p.PerPage,
-		"pages":    p.TotalPages(),
 	}
+	if p.Cursor == "" {
+		m["page"] = p.Page
+		m["pages"] = p.TotalPages()
+		m["offset"] = p.Offset()
+	}
+	if p.NextCursor != "" {
+		m["next_cursor"] = p.NextCursor
+	}
+	return m
+}
+
+// ParseCursor returns the page cursor from page[cursor] or cursor.
+func ParseCursor(query url.Values) string {
+	if v := query.Get("page[cursor]"); v != "" {
+		return v
+	}
+	return query.Get("cursor")
 }
 
 // ParsePaginationParams extracts pagination parameters from URL query.
@@ -286,6 +286,80 @@ func TestPaginationMeta(t *testing.T) {
 	if meta["pages"] != 10 {
 		t.Errorf("meta[pages] = %v, want 10", meta["pages"])
 	}
+	if meta["limit"] != 10 {
+		t.Errorf("meta[limit] = %v, want 10", meta["limit"])
+	}
+	if meta["offset"] != 10 {
+		t.Errorf("meta[offset] = %v, want 10", meta["offset"])
+	}
+	if _, ok := meta["next_cursor"]; ok {
+		t.Error("meta[next_cursor] should be omitted without a cursor")
+	}
+}
+
+func TestCursorPagination(t *testing.T) {
+	t.Run("links follow the cursor", func(t *testing.T) {
+		p := NewCursorPagination(100, 10, "abc", "def", "/users?status=active&page[cursor]=abc&offset=5")
+		links := p.Links()
+
+		if links.Self != "/users?page%5Bcursor%5D=abc&page%5Bsize%5D=10&status=active" {
+			t.Errorf("Self = %v", links.Self)
+		}
+		if links.First != "/users?page%5Bsize%5D=10&status=active" {
+			t.Errorf("First = %v", links.First)
+		}
+		if links.Next != "/users?page%5Bcursor%5D=def&page%5Bsize%5D=10&status=active" {
+			t.Errorf("Next = %v", links.Next)
+		}
+		if links.Prev != "" || links.Last != "" {
+			t.Errorf("Prev/Last should be empty for cursor pages, got %v / %v", links.Prev, links.Last)
+		}
+	})
+
+	t.Run("last page has no next", func(t *testing.T) {
+		p := NewCursorPagination(100, 10, "abc", "", "/users")
+		if p.HasNext() {
+			t.Error("HasNext should be false without a next cursor")
+		}
+		if p.Links().Next != "" {
+			t.Errorf("Next should be empty, got %v", p.Links().Next)
+		}
+	})
+
+	t.Run("meta", func(t *testing.T) {
+		meta := NewCursorPagination(100, 10, "abc", "def", "/users").Meta()
+
+		if meta["total"] != int64(100) || meta["limit"] != 10 || meta["next_cursor"] != "def" {
+			t.Errorf("meta = %v", meta)
+		}
+		if _, ok := meta["page"]; ok {
+			t.Error("meta[page] should be omitted for cursor pages")
+		}
+	})
+
+	t.Run("page links carry next cursor in meta", func(t *testing.T) {
+		p := NewPagination(100, 1, 10, "/users?limit=10&offset=0")
+		p.NextCursor = "def"
+
+		if p.Meta()["next_cursor"] != "def" {
+			t.Errorf("meta[next_cursor] = %v, want def", p.Meta()["next_cursor"])
+		}
+		if next := p.Links().Next; next != "/users?page%5Bnumber%5D=2&page%5Bsize%5D=10" {
+			t.Errorf("Next = %v", next)
+		}
+	})
+}
+
+func TestParseCursor(t *testing.T) {
+	if got := ParseCursor(url.Values{"page[cursor]": {"a"}, "cursor": {"b"}}); got != "a" {
+		t.Errorf("ParseCursor = %q, want a", got)
+	}
+	if got := ParseCursor(url.Values{"cursor": {"b"}}); got != "b" {
+		t.Errorf("ParseCursor = %q, want b", got)
+	}
+	if got := ParseCursor(url.Values{}); got != "" {
+		t.Errorf("ParseCursor = %q, want empty", got)
+	}
 }
 
 func TestParsePaginationParams(t *testing.T) {
@@ -0,0 +1,240 @@
+// Package usageexport writes a user's raw usage events for a date range as a
+// CSV or JSON download, so customers can reconcile their bills.
+//
+// Events are streamed as they are read from stores implementing
+// ports.UsageEventExporter; other stores fall back to their most recent
+// requests.
+package usageexport
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/artpar/apigate/domain/usage"
+	"github.com/artpar/apigate/ports"
+)
+
+// Export formats.
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// FallbackLimit caps the events read from stores that cannot stream a range.
+const FallbackLimit = 10000
+
+// flushEvery is how many events are written between flushes to the client.
+const flushEvery = 500
+
+// Columns is the CSV header row.
+var Columns = []string{
+	"timestamp", "method", "path", "status", "request_bytes", "response_bytes", "cost", "latency_ms", "key_id", "id",
+}
+
+// ErrUnknownFormat is returned for formats other than csv and json.
+var ErrUnknownFormat = errors.New("format must be csv or json")
+
+// Record is an exported usage event.
+type Record struct {
+	Timestamp     time.Time `json:"timestamp"`
+	Method        string    `json:"method"`
+	Path          string    `json:"path"`
+	Status        int       `json:"status"`
+	RequestBytes  int64     `json:"request_bytes"`
+	ResponseBytes int64     `json:"response_bytes"`
+	Cost          float64   `json:"cost"`
+	LatencyMs     int64     `json:"latency_ms"`
+	KeyID         string    `json:"key_id"`
+	ID            string    `json:"id"`
+}
+
+// NewRecord converts a usage event to its exported form.
+func NewRecord(e usage.Event) Record {
+	return Record{
+		Timestamp:     e.Timestamp.UTC(),
+		Method:        e.Method,
+		Path:          e.Path,
+		Status:        e.StatusCode,
+		RequestBytes:  e.RequestBytes,
+		ResponseBytes: e.ResponseBytes,
+		Cost:          e.EffectiveCost(),
+		LatencyMs:     e.LatencyMs,
+		KeyID:         e.KeyID,
+		ID:            e.ID,
+	}
+}
+
+func (r Record) csvRow() []string {
+	return []string{
+		r.Timestamp.Format(time.RFC3339),
+		r.Method,
+		r.Path,
+		strconv.Itoa(r.Status),
+		strconv.FormatInt(r.RequestBytes, 10),
+		strconv.FormatInt(r.ResponseBytes, 10),
+		strconv.FormatFloat(r.Cost, 'f', -1, 64),
+		strconv.FormatInt(r.LatencyMs, 10),
+		r.KeyID,
+		r.ID,
+	}
+}
+
+// ParseRange reads the start and end query parameters, as RFC 3339
+// timestamps or YYYY-MM-DD dates. A date-only end includes that whole day.
+// Missing parameters default to defStart and defEnd.
+func ParseRange(q url.Values, defStart, defEnd time.Time) (start, end time.Time, err error) {
+	start, end = defStart, defEnd
+	if s := q.Get("start"); s != "" {
+		if start, err = parseTime(s, false); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("start: %w", err)
+		}
+	}
+	if s := q.Get("end"); s != "" {
+		if end, err = parseTime(s, true); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("end: %w", err)
+		}
+	}
+	if !end.After(start) {
+		return time.Time{}, time.Time{}, errors.New("end must be after start")
+	}
+	return start, end, nil
+}
+
+func parseTime(s string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, errors.New("expected an RFC 3339 timestamp or a YYYY-MM-DD date")
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// Each calls fn for each of the user's events in [start, end), oldest first.
+func Each(ctx context.Context, store ports.UsageStore, userID string, start, end time.Time, fn func(usage.Event) error) error {
+	if exporter, ok := store.(ports.UsageEventExporter); ok {
+		return exporter.EachEvent(ctx, userID, start, end, fn)
+	}
+
+	events, err := store.GetRecentRequests(ctx, userID, FallbackLimit)
+	if err != nil {
+		return err
+	}
+	// Recent requests are newest first
+	for i := len(events) - 1; i >= 0; i-- {
+		e := events[i]
+		if e.Timestamp.Before(start) || !e.Timestamp.Before(end) {
+			continue
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Filename returns the download filename for an export.
+func Filename(start, end time.Time, format string) string {
+	return fmt.Sprintf("usage_%s_%s.%s", start.UTC().Format("20060102"), end.UTC().Format("20060102"), format)
+}
+
+// Write streams the user's events in [start, end) to w as a download in the
+// given format. An empty format means CSV. ErrUnknownFormat is returned before
+// anything is written; once the response has started, read errors end it early.
+func Write(ctx context.Context, w http.ResponseWriter, store ports.UsageStore, userID string, start, end time.Time, format string) error {
+	if format == "" {
+		format = FormatCSV
+	}
+	if format != FormatCSV && format != FormatJSON {
+		return ErrUnknownFormat
+	}
+
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", Filename(start, end, format)))
+	w.Header().Set("Cache-Control", "no-store")
+
+	if format == FormatJSON {
+		w.Header().Set("Content-Type", "application/json")
+		return writeJSON(ctx, w, flusher, store, userID, start, end)
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	return writeCSV(ctx, w, flusher, store, userID, start, end)
+}
+
+func writeCSV(ctx context.Context, w io.Writer, flusher http.Flusher, store ports.UsageStore, userID string, start, end time.Time) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(Columns); err != nil {
+		return err
+	}
+	n := 0
+	err := Each(ctx, store, userID, start, end, func(e usage.Event) error {
+		if err := cw.Write(NewRecord(e).csvRow()); err != nil {
+			return err
+		}
+		if n++; n%flushEvery == 0 {
+			cw.Flush()
+			flush(flusher)
+		}
+		return cw.Error()
+	})
+	cw.Flush()
+	if err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+func writeJSON(ctx context.Context, w io.Writer, flusher http.Flusher, store ports.UsageStore, userID string, start, end time.Time) error {
+	header, err := json.Marshal(struct {
+		UserID string    `json:"user_id"`
+		Start  time.Time `json:"start"`
+		End    time.Time `json:"end"`
+	}{userID, start.UTC(), end.UTC()})
+	if err != nil {
+		return err
+	}
+	// Open the object and append the events array to the header fields
+	if _, err := fmt.Fprintf(w, `%s,"events":[`, header[:len(header)-1]); err != nil {
+		return err
+	}
+	n := 0
+	err = Each(ctx, store, userID, start, end, func(e usage.Event) error {
+		b, err := json.Marshal(NewRecord(e))
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			b = append([]byte{','}, b...)
+		}
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+		if n++; n%flushEvery == 0 {
+			flush(flusher)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "]}\n")
+	return err
+}
+
+func flush(f http.Flusher) {
+	if f != nil {
+		f.Flush()
+	}
+}
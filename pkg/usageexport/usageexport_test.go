@@ -0,0 +1,185 @@
+package usageexport
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/domain/usage"
+	"github.com/artpar/apigate/ports"
+)
+
+var baseTime = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// populatedStore holds ten events for user-1, one every 12 hours from
+// baseTime, and one event for another user.
+func populatedStore(t *testing.T) *memory.UsageStore {
+	t.Helper()
+	store := memory.NewUsageStore()
+	var events []usage.Event
+	for i := 0; i < 10; i++ {
+		events = append(events, usage.Event{
+			ID: "evt-" + string(rune('a'+i)), KeyID: "key-1", UserID: "user-1",
+			Method: "GET", Path: "/v1/items", StatusCode: 200,
+			RequestBytes: 10, ResponseBytes: 200, CostMultiplier: 2.5,
+			Timestamp: baseTime.Add(time.Duration(i) * 12 * time.Hour),
+		})
+	}
+	events = append(events, usage.Event{ID: "other", UserID: "user-2", Method: "GET", Path: "/v1/items", Timestamp: baseTime})
+	if err := store.RecordBatch(context.Background(), events); err != nil {
+		t.Fatalf("record events: %v", err)
+	}
+	return store
+}
+
+func TestWrite_CSV(t *testing.T) {
+	store := populatedStore(t)
+	rec := httptest.NewRecorder()
+
+	// First three days: events at 0h, 12h, 24h, 36h, 48h and 60h
+	err := Write(context.Background(), rec, store, "user-1", baseTime, baseTime.AddDate(0, 0, 3), "")
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/csv" {
+		t.Errorf("Content-Type = %q, want text/csv", ct)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); !strings.Contains(cd, `filename="usage_20240301_20240304.csv"`) {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("parse CSV: %v", err)
+	}
+	if got := strings.Join(rows[0], ","); got != "timestamp,method,path,status,request_bytes,response_bytes,cost,latency_ms,key_id,id" {
+		t.Errorf("header = %s", got)
+	}
+	if len(rows)-1 != 6 {
+		t.Fatalf("got %d rows, want 6", len(rows)-1)
+	}
+	first := rows[1]
+	if first[0] != "2024-03-01T00:00:00Z" || first[1] != "GET" || first[2] != "/v1/items" || first[3] != "200" ||
+		first[4] != "10" || first[5] != "200" || first[6] != "2.5" || first[9] != "evt-a" {
+		t.Errorf("first row = %v", first)
+	}
+	if last := rows[6]; last[0] != "2024-03-03T12:00:00Z" {
+		t.Errorf("last row timestamp = %s, want 2024-03-03T12:00:00Z", last[0])
+	}
+}
+
+func TestWrite_JSON(t *testing.T) {
+	store := populatedStore(t)
+	rec := httptest.NewRecorder()
+
+	err := Write(context.Background(), rec, store, "user-1", baseTime.Add(24*time.Hour), baseTime.AddDate(0, 0, 10), FormatJSON)
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+
+	var body struct {
+		UserID string   `json:"user_id"`
+		Events []Record `json:"events"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("parse JSON: %v\n%s", err, rec.Body.String())
+	}
+	if body.UserID != "user-1" || len(body.Events) != 8 {
+		t.Fatalf("got user %q with %d events, want user-1 with 8", body.UserID, len(body.Events))
+	}
+	if e := body.Events[0]; e.Cost != 2.5 || e.Status != 200 || !e.Timestamp.Equal(baseTime.Add(24*time.Hour)) {
+		t.Errorf("first event = %+v", e)
+	}
+}
+
+func TestWrite_EmptyRange(t *testing.T) {
+	store := populatedStore(t)
+	rec := httptest.NewRecorder()
+
+	if err := Write(context.Background(), rec, store, "user-1", baseTime.AddDate(1, 0, 0), baseTime.AddDate(1, 1, 0), FormatJSON); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	var body struct {
+		Events []Record `json:"events"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || len(body.Events) != 0 {
+		t.Errorf("got %d events (err %v), want an empty list: %s", len(body.Events), err, rec.Body.String())
+	}
+}
+
+func TestWrite_UnknownFormat(t *testing.T) {
+	rec := httptest.NewRecorder()
+	err := Write(context.Background(), rec, memory.NewUsageStore(), "user-1", baseTime, baseTime.AddDate(0, 1, 0), "xml")
+	if err != ErrUnknownFormat {
+		t.Fatalf("err = %v, want ErrUnknownFormat", err)
+	}
+	if rec.Body.Len() != 0 || rec.Header().Get("Content-Disposition") != "" {
+		t.Error("nothing should be written for an unknown format")
+	}
+}
+
+// recentOnlyStore hides the memory store's EachEvent, like stores that can
+// only list recent requests.
+type recentOnlyStore struct {
+	ports.UsageStore
+}
+
+func TestEach_FallsBackToRecentRequests(t *testing.T) {
+	store := recentOnlyStore{populatedStore(t)}
+
+	var ids []string
+	err := Each(context.Background(), store, "user-1", baseTime.Add(12*time.Hour), baseTime.Add(48*time.Hour), func(e usage.Event) error {
+		ids = append(ids, e.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Each: %v", err)
+	}
+	if got := strings.Join(ids, ","); got != "evt-b,evt-c,evt-d" {
+		t.Errorf("events = %s, want evt-b,evt-c,evt-d oldest first", got)
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	defStart, defEnd := baseTime, baseTime.AddDate(0, 1, 0)
+	tests := []struct {
+		query     string
+		wantStart time.Time
+		wantEnd   time.Time
+		wantErr   bool
+	}{
+		{"", defStart, defEnd, false},
+		{"start=2024-01-01&end=2024-01-31", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), false},
+		{"start=2024-03-05T10:00:00Z", time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC), defEnd, false},
+		{"end=2024-03-10T00:00:00Z", defStart, time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC), false},
+		{"start=yesterday", time.Time{}, time.Time{}, true},
+		{"start=2024-03-10&end=2024-03-01", time.Time{}, time.Time{}, true},
+	}
+	for _, tt := range tests {
+		q, _ := url.ParseQuery(tt.query)
+		start, end, err := ParseRange(q, defStart, defEnd)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected error", tt.query)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tt.query, err)
+			continue
+		}
+		if !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+			t.Errorf("%q: range = %v - %v, want %v - %v", tt.query, start, end, tt.wantStart, tt.wantEnd)
+		}
+	}
+}
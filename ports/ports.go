@@ -9,6 +9,7 @@ import (
 	"io"
 	"time"
 
+	"github.com/artpar/apigate/domain/audit"
 	"github.com/artpar/apigate/domain/auth"
 	"github.com/artpar/apigate/domain/billing"
 	"github.com/artpar/apigate/domain/entitlement"
@@ -31,6 +32,12 @@ import (
 // ErrNotFound is returned when an entity is not found.
 var ErrNotFound = errors.New("not found")
 
+// ErrDuplicateKeyPrefix is returned when a key's prefix is already in use.
+var ErrDuplicateKeyPrefix = errors.New("duplicate key prefix")
+
+// ErrConflict is returned when a record changed after it was read.
+var ErrConflict = errors.New("conflict")
+
 // -----------------------------------------------------------------------------
 // Infrastructure Ports
 // -----------------------------------------------------------------------------
@@ -75,6 +82,58 @@ type KeyStore interface {
 	UpdateLastUsed(ctx context.Context, id string, at time.Time) error
 }
 
+// KeyExpiryStore tracks expiry warnings sent for API keys.
+// Implementations: sqlite, memory
+type KeyExpiryStore interface {
+	// ListExpiringUnwarned returns unrevoked keys expiring after now and no
+	// later than before that have not been sent an expiry warning.
+	ListExpiringUnwarned(ctx context.Context, now, before time.Time) ([]key.Key, error)
+
+	// MarkExpiryWarned records that the expiry warning for a key was sent.
+	MarkExpiryWarned(ctx context.Context, id string, at time.Time) error
+}
+
+// KeyBatchStore creates API keys in bulk.
+// Implementations: sqlite, memory
+type KeyBatchStore interface {
+	// CreateBatch stores all keys or none. It fails with ErrDuplicateKeyPrefix
+	// if any prefix is already in use or repeated within the batch.
+	CreateBatch(ctx context.Context, keys []key.Key) error
+}
+
+// GroupKeyStore lists the API keys owned by a group.
+// Implementations: sqlite, memory
+type GroupKeyStore interface {
+	// ListByGroup returns all keys owned by a group.
+	ListByGroup(ctx context.Context, groupID string) ([]key.Key, error)
+}
+
+// KeyQuotaStore sets the quota of individual API keys.
+// Implementations: sqlite, memory
+type KeyQuotaStore interface {
+	// SetMonthlyQuota sets a key's own quota; 0 removes it.
+	SetMonthlyQuota(ctx context.Context, id string, quota int64) error
+}
+
+// KeyUseStore records the last use of many API keys at once.
+// Implementations: sqlite, memory
+type KeyUseStore interface {
+	// RecordUses sets each key's last-used time and client IP. When a key
+	// appears more than once, its latest use wins.
+	RecordUses(ctx context.Context, uses []key.Use) error
+}
+
+// KeyLabelStore sets API key labels and lists keys by label.
+// Implementations: sqlite, memory, remote
+type KeyLabelStore interface {
+	// SetLabels replaces a key's labels; nil removes them all.
+	SetLabels(ctx context.Context, id string, labels map[string]string) error
+
+	// ListByUserLabels returns the user's keys matching the label selector.
+	// An empty selector returns all of the user's keys, like ListByUser.
+	ListByUserLabels(ctx context.Context, userID string, sel key.LabelSelector) ([]key.Key, error)
+}
+
 // User represents a user account.
 // Note: Provider-specific customer IDs are stored in provider_mapping module,
 // not in the User struct. Use ProviderMappingStore to lookup external IDs.
@@ -88,9 +147,31 @@ type User struct {
 	StripeID     string // Stripe customer ID for payment integration
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
+	DeletedAt    *time.Time // Set when soft-deleted; nil = not deleted
+
+	// Two-factor authentication (TOTP)
+	TOTPSecret    string   // Base32 secret, set at enrollment; "" = not enrolled
+	TOTPEnabled   bool     // Login requires a code once the user has confirmed one
+	RecoveryCodes []string // SHA-256 hashes of unused recovery codes
+	TOTPLastStep  int64    // Time step of the last accepted code; codes from it or earlier are replays
+}
+
+type includeDeletedUsersKey struct{}
+
+// WithDeletedUsers returns a context in which UserStore reads also return
+// soft-deleted users. By default they are hidden.
+func WithDeletedUsers(ctx context.Context) context.Context {
+	return context.WithValue(ctx, includeDeletedUsersKey{}, true)
+}
+
+// IncludeDeletedUsers reports whether ctx opts in to soft-deleted users.
+func IncludeDeletedUsers(ctx context.Context) bool {
+	include, _ := ctx.Value(includeDeletedUsersKey{}).(bool)
+	return include
 }
 
 // UserStore persists user accounts.
+// Reads exclude soft-deleted users unless the context is wrapped with WithDeletedUsers.
 type UserStore interface {
 	// Get retrieves a user by ID.
 	Get(ctx context.Context, id string) (User, error)
@@ -108,9 +189,14 @@ type UserStore interface {
 	// Update modifies an existing user.
 	Update(ctx context.Context, u User) error
 
-	// Delete removes a user.
+	// Delete soft-deletes a user by setting DeletedAt. The user's data is
+	// kept and the account can be brought back with Restore.
 	Delete(ctx context.Context, id string) error
 
+	// Restore clears DeletedAt on a soft-deleted user. It fails with
+	// ErrConflict if a live user has taken the email in the meantime.
+	Restore(ctx context.Context, id string) error
+
 	// List returns users with pagination.
 	List(ctx context.Context, limit, offset int) ([]User, error)
 
@@ -118,6 +204,14 @@ type UserStore interface {
 	Count(ctx context.Context) (int, error)
 }
 
+// UserVersionStore updates users only if nobody else changed them first.
+// Implementations: sqlite, memory
+type UserVersionStore interface {
+	// UpdateIfUnchanged modifies an existing user only if its stored UpdatedAt
+	// still equals since. It fails with ErrConflict otherwise.
+	UpdateIfUnchanged(ctx context.Context, u User, since time.Time) error
+}
+
 // PlanStore persists pricing plans.
 type PlanStore interface {
 	// List returns all enabled plans.
@@ -253,24 +347,40 @@ const (
 	MeterTypeComputeUnits MeterType = "compute_units" // Count weighted units (tokens, etc.)
 )
 
+// QuotaPeriod determines when a plan's quota window resets.
+type QuotaPeriod string
+
+const (
+	QuotaPeriodCalendarMonth QuotaPeriod = "calendar_month" // Reset on the 1st of each month (default)
+	QuotaPeriodCalendarWeek  QuotaPeriod = "calendar_week"  // Reset every Monday at 00:00
+	QuotaPeriodRolling30d    QuotaPeriod = "rolling_30d"    // Reset every 30 days from the user's sign-up
+)
+
 // Plan represents a pricing tier.
 type Plan struct {
-	ID                 string
-	Name               string
-	Description        string
-	RateLimitPerMinute int
-	RequestsPerMonth   int64
-	PriceMonthly       int64 // cents
-	OveragePrice       int64 // hundredths of cents per request (10000 = $1)
-	IsDefault          bool
-	Enabled            bool
-	QuotaEnforceMode   QuotaEnforceMode // "hard", "warn", "soft" - defaults to "hard"
-	QuotaGracePct      float64          // Grace percentage before hard block (e.g., 0.05 = 5%)
-	TrialDays          int              // Number of trial days (0 = no trial)
-	MeterType          MeterType        // Which metric to enforce: "requests" or "compute_units"
-	EstimatedCostPerReq float64         // Estimated cost per request for pre-check (default 1.0)
-	CreatedAt          time.Time
-	UpdatedAt          time.Time
+	ID                  string
+	Name                string
+	Description         string
+	RateLimitPerMinute  int
+	RateLimitBurst      int // Token bucket size: max requests in a burst (0 = RateLimitPerMinute + global burst)
+	RequestsPerMonth    int64
+	PriceMonthly        int64 // cents
+	OveragePrice        int64 // hundredths of cents per request (10000 = $1)
+	IsDefault           bool
+	Enabled             bool
+	QuotaEnforceMode    QuotaEnforceMode // "hard", "warn", "soft" - defaults to "hard"
+	QuotaGracePct       float64          // Grace percentage before hard block (e.g., 0.05 = 5%)
+	TrialDays           int              // Number of trial days (0 = no trial)
+	MeterType           MeterType        // Which metric to enforce: "requests" or "compute_units"
+	EstimatedCostPerReq float64          // Estimated cost per request for pre-check (default 1.0)
+	QuotaPeriod         QuotaPeriod      // When the quota resets - defaults to "calendar_month"
+	MaxConcurrent       int              // In-flight requests allowed per API key (0 = unlimited)
+	SpikeThreshold      float64          // Alert when a key's recent request rate exceeds its baseline by this factor (0 = disabled)
+	SpikeMinRequests    int              // Requests in the recent window before a spike is considered
+	SpikeThrottleRPM    int              // Requests per minute allowed for a spiking key (0 = alert only)
+	AllowOverage        bool             // Serve requests past the quota and bill them at OveragePrice
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
 
 	// Provider-specific price IDs for payment integration
 	StripePriceID  string // Stripe price ID (e.g., price_xxx)
@@ -291,6 +401,19 @@ type UsageStore interface {
 
 	// GetRecentRequests returns recent request logs.
 	GetRecentRequests(ctx context.Context, userID string, limit int) ([]usage.Event, error)
+
+	// GetTopPaths returns a user's most requested paths in [start, end),
+	// busiest first, up to limit.
+	GetTopPaths(ctx context.Context, userID string, start, end time.Time, limit int) ([]usage.PathUsage, error)
+}
+
+// UsageEventExporter is implemented by usage stores that can stream a user's
+// raw usage events for a date range without loading them all into memory.
+// Implementations: sqlite, memory
+type UsageEventExporter interface {
+	// EachEvent calls fn for each of a user's events with a timestamp in
+	// [start, end), oldest first. It stops at the first error fn returns.
+	EachEvent(ctx context.Context, userID string, start, end time.Time, fn func(usage.Event) error) error
 }
 
 // RateLimitStore persists rate limit state.
@@ -302,6 +425,25 @@ type RateLimitStore interface {
 	Set(ctx context.Context, keyID string, state ratelimit.WindowState) error
 }
 
+// RateLimitChecker is implemented by rate limit stores that apply a token
+// bucket check atomically in the store, so the limit holds across every
+// instance sharing it. Stores without it are checked with Get and Set.
+// Implementations: redis
+type RateLimitChecker interface {
+	// CheckBucket takes a token from a key's bucket (see ratelimit.CheckBucket).
+	CheckBucket(ctx context.Context, keyID string, cfg ratelimit.Config, now time.Time) (ratelimit.CheckResult, error)
+}
+
+// ResponseCache stores upstream responses for routes with caching enabled.
+// Implementations: memory
+type ResponseCache interface {
+	// Get returns the cached response for a key. Returns false if missing or expired.
+	Get(ctx context.Context, key string) (proxy.Response, bool, error)
+
+	// Set stores a response for the given TTL.
+	Set(ctx context.Context, key string, resp proxy.Response, ttl time.Duration) error
+}
+
 // QuotaState represents current period usage for fast quota checks.
 type QuotaState struct {
 	UserID       string
@@ -325,6 +467,30 @@ type QuotaStore interface {
 	Sync(ctx context.Context, userID string, periodStart time.Time, summary usage.Summary) error
 }
 
+// QuotaWarningStore records the quota warning emails sent to users, so each
+// threshold is sent at most once per quota period.
+// Implementations: sqlite, memory
+type QuotaWarningStore interface {
+	// ListQuotaWarnings returns the thresholds (percent of the quota) already
+	// sent to the user for the period starting at periodStart.
+	ListQuotaWarnings(ctx context.Context, userID string, periodStart time.Time) ([]int, error)
+
+	// MarkQuotaWarned records that the warning for a threshold was sent.
+	MarkQuotaWarned(ctx context.Context, userID string, periodStart time.Time, threshold int, at time.Time) error
+}
+
+// OverageReportStore records how many overage units have been reported to the
+// payment provider per user and quota period, so each unit is billed once.
+// Implementations: sqlite, memory
+type OverageReportStore interface {
+	// GetReportedOverage returns the overage units already reported for the
+	// period starting at periodStart.
+	GetReportedOverage(ctx context.Context, userID string, periodStart time.Time) (int64, error)
+
+	// AddReportedOverage adds units to the reported overage for the period.
+	AddReportedOverage(ctx context.Context, userID string, periodStart time.Time, units int64, at time.Time) error
+}
+
 // SubscriptionStore persists billing subscriptions.
 type SubscriptionStore interface {
 	// Get retrieves a subscription by ID.
@@ -349,6 +515,9 @@ type InvoiceStore interface {
 	// Create stores a new invoice.
 	Create(ctx context.Context, inv billing.Invoice) error
 
+	// Get retrieves an invoice by ID.
+	Get(ctx context.Context, id string) (billing.Invoice, error)
+
 	// ListByUser returns invoices for a user.
 	ListByUser(ctx context.Context, userID string, limit int) ([]billing.Invoice, error)
 
@@ -356,6 +525,23 @@ type InvoiceStore interface {
 	UpdateStatus(ctx context.Context, id string, status billing.InvoiceStatus, paidAt *time.Time) error
 }
 
+// PendingInvoiceItemStore holds invoice items, such as the proration recorded
+// when a plan changes mid-period, until they are added to a subscription's
+// next invoice.
+// Implementations: sqlite
+type PendingInvoiceItemStore interface {
+	// AddPendingItems records items for the subscription's next invoice.
+	AddPendingItems(ctx context.Context, subscriptionID string, items []billing.InvoiceItem, at time.Time) error
+
+	// ListPendingItems returns the uninvoiced items recorded before the given
+	// time, oldest first.
+	ListPendingItems(ctx context.Context, subscriptionID string, before time.Time) ([]billing.InvoiceItem, error)
+
+	// CreateInvoiceWithItems stores the invoice and marks the uninvoiced items
+	// recorded before the given time as added to it, all or nothing.
+	CreateInvoiceWithItems(ctx context.Context, inv billing.Invoice, subscriptionID string, before time.Time) error
+}
+
 // -----------------------------------------------------------------------------
 // External Service Ports
 // -----------------------------------------------------------------------------
@@ -373,6 +559,12 @@ type Upstream interface {
 	HealthCheck(ctx context.Context) error
 }
 
+// UpstreamProber performs active health probes against upstreams.
+type UpstreamProber interface {
+	// Probe requests the upstream's health check path and returns the response status.
+	Probe(ctx context.Context, upstream route.Upstream) (int, error)
+}
+
 // BillingProvider interfaces with payment processor (Stripe).
 type BillingProvider interface {
 	// CreateCustomer creates a customer in the billing system.
@@ -408,6 +600,19 @@ type UsageRecorder interface {
 	Close() error
 }
 
+// AuditRecorder accepts audit entries for async persistence.
+type AuditRecorder interface {
+	// Record queues an audit entry. This must not block the request path.
+	Record(entry audit.Entry)
+}
+
+// EventDispatcher publishes internal events to webhook subscribers.
+type EventDispatcher interface {
+	// DispatchEvent queues an event for delivery to subscribed webhooks.
+	// Delivery and retries happen in the background.
+	DispatchEvent(ctx context.Context, eventType webhook.EventType, userID string, data map[string]interface{}) error
+}
+
 // WebhookSender sends webhook notifications.
 type WebhookSender interface {
 	// Send delivers a webhook to the configured URL.
@@ -427,6 +632,17 @@ type Hasher interface {
 	Compare(hash []byte, plaintext string) bool
 }
 
+// -----------------------------------------------------------------------------
+// Breached Password Port
+// -----------------------------------------------------------------------------
+
+// BreachedPasswordChecker reports whether a password appears in known data
+// breaches.
+type BreachedPasswordChecker interface {
+	// IsBreached returns true if password has been seen in a breach.
+	IsBreached(ctx context.Context, password string) (bool, error)
+}
+
 // -----------------------------------------------------------------------------
 // Route Ports
 // -----------------------------------------------------------------------------
@@ -570,6 +786,9 @@ type SessionStore interface {
 	// Get retrieves a session by ID.
 	Get(ctx context.Context, id string) (auth.Session, error)
 
+	// ListByUser returns a user's unexpired sessions, newest first.
+	ListByUser(ctx context.Context, userID string) ([]auth.Session, error)
+
 	// Delete removes a session (logout).
 	Delete(ctx context.Context, id string) error
 
@@ -771,6 +990,14 @@ type AuthProvider interface {
 	RevokeToken(ctx context.Context, token string) error
 }
 
+// BearerTokenValidator validates bearer tokens issued by an external identity
+// provider and maps them to APIGate user IDs.
+// Implementations: auth.JWKSValidator
+type BearerTokenValidator interface {
+	// ValidateBearer verifies the token and returns the user ID it belongs to.
+	ValidateBearer(ctx context.Context, token string) (userID string, err error)
+}
+
 // -----------------------------------------------------------------------------
 // Capability Registry
 // -----------------------------------------------------------------------------
@@ -839,6 +1066,20 @@ type InviteStore interface {
 	Count(ctx context.Context) (int, error)
 }
 
+// -----------------------------------------------------------------------------
+// Audit Ports
+// -----------------------------------------------------------------------------
+
+// AuditStore persists the audit log. It is append-only: entries are never
+// updated or deleted.
+type AuditStore interface {
+	// Append stores entries.
+	Append(ctx context.Context, entries []audit.Entry) error
+
+	// List returns entries matching the filter, newest first.
+	List(ctx context.Context, filter audit.Filter) ([]audit.Entry, error)
+}
+
 // -----------------------------------------------------------------------------
 // Group Ports
 // -----------------------------------------------------------------------------
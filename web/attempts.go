@@ -0,0 +1,75 @@
+package web
+
+import (
+	"sync"
+	"time"
+)
+
+// Limits on wrong two-factor codes. A pending login is discarded after
+// maxPendingLoginFailures wrong codes, and a user who gets
+// maxTwoFactorFailures wrong within twoFactorFailureWindow can't try again
+// until the window has passed, however often they repeat the password step.
+const (
+	maxPendingLoginFailures = 5
+	maxTwoFactorFailures    = 10
+	twoFactorFailureWindow  = 15 * time.Minute
+)
+
+// failureCounter counts failures per key within a fixed window, starting at
+// the first failure. Counts live in memory and reset on restart.
+type failureCounter struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	entries map[string]failureEntry
+}
+
+type failureEntry struct {
+	count int
+	start time.Time
+}
+
+func newFailureCounter(window time.Duration) *failureCounter {
+	return &failureCounter{window: window, entries: make(map[string]failureEntry)}
+}
+
+// Count returns the failures recorded for key in the current window.
+func (c *failureCounter) Count(key string, now time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || now.Sub(e.start) >= c.window {
+		return 0
+	}
+	return e.count
+}
+
+// Fail records a failure for key and returns the count in the current window.
+func (c *failureCounter) Fail(key string, now time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.prune(now)
+	e, ok := c.entries[key]
+	if !ok || now.Sub(e.start) >= c.window {
+		e = failureEntry{start: now}
+	}
+	e.count++
+	c.entries[key] = e
+	return e.count
+}
+
+// Reset forgets the failures recorded for key.
+func (c *failureCounter) Reset(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// prune drops expired entries so the map doesn't grow without bound.
+func (c *failureCounter) prune(now time.Time) {
+	for key, e := range c.entries {
+		if now.Sub(e.start) >= c.window {
+			delete(c.entries, key)
+		}
+	}
+}
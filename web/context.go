@@ -2,9 +2,12 @@ package web
 
 import (
 	"context"
+	"net/http"
+	"time"
 
 	"github.com/artpar/apigate/adapters/auth"
 	"github.com/artpar/apigate/core/terminology"
+	"github.com/artpar/apigate/domain/audit"
 )
 
 type ctxKey string
@@ -25,6 +28,19 @@ func getClaims(ctx context.Context) *auth.Claims {
 	return claims
 }
 
+// recordAudit queues an audit entry for an admin mutation made in the web UI.
+// It is a no-op when no audit recorder is configured.
+func (h *Handler) recordAudit(r *http.Request, action, resourceType, resourceID string, before, after map[string]any) {
+	if h.audit == nil {
+		return
+	}
+	var actorID string
+	if claims := getClaims(r.Context()); claims != nil {
+		actorID = claims.UserID
+	}
+	h.audit.Record(audit.NewEntry(actorID, action, resourceType, resourceID, before, after, time.Now().UTC()))
+}
+
 // PageData holds common data for all pages.
 type PageData struct {
 	Title       string
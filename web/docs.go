@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"html"
 	"net/http"
 	"sort"
 	"strings"
@@ -62,14 +63,17 @@ func (h *DocsHandler) Router() chi.Router {
 	// API endpoints for docs
 	r.Get("/openapi.json", h.OpenAPISpec)
 	r.Get("/openapi.yaml", h.OpenAPISpecYAML)
+	r.Get("/postman.json", h.PostmanCollection)
+	r.Get("/api-reference.md", h.APIReferenceMarkdown)
 
 	return r
 }
 
 // DocsHome renders the documentation homepage.
 func (h *DocsHandler) DocsHome(w http.ResponseWriter, r *http.Request) {
+	spec := h.generateOpenAPISpec(r)
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.Write([]byte(h.renderDocsHome()))
+	w.Write([]byte(h.renderDocsHome(spec)))
 }
 
 // QuickstartPage renders the quickstart guide.
@@ -83,8 +87,9 @@ func (h *DocsHandler) QuickstartPage(w http.ResponseWriter, r *http.Request) {
 // AuthenticationPage renders the authentication documentation.
 func (h *DocsHandler) AuthenticationPage(w http.ResponseWriter, r *http.Request) {
 	baseURL := h.getBaseURL(r)
+	spec := h.generateOpenAPISpec(r)
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.Write([]byte(h.renderAuthentication(baseURL)))
+	w.Write([]byte(h.renderAuthentication(baseURL, spec)))
 }
 
 // APIReferencePage renders the API reference from OpenAPI spec.
@@ -123,12 +128,40 @@ func (h *DocsHandler) OpenAPISpec(w http.ResponseWriter, r *http.Request) {
 func (h *DocsHandler) OpenAPISpecYAML(w http.ResponseWriter, r *http.Request) {
 	spec := h.generateOpenAPISpec(r)
 
+	data, err := spec.ToYAML()
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to encode OpenAPI spec as YAML")
+		http.Error(w, "failed to generate YAML specification", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/x-yaml")
-	// Convert to YAML (simplified - just return JSON for now)
-	data, _ := spec.ToJSON()
 	w.Write(data)
 }
 
+// PostmanCollection returns the customer API as a Postman v2.1 collection.
+func (h *DocsHandler) PostmanCollection(w http.ResponseWriter, r *http.Request) {
+	spec := h.generateOpenAPISpec(r)
+
+	data, err := spec.ToPostmanJSON()
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to encode Postman collection")
+		http.Error(w, "failed to generate Postman collection", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// APIReferenceMarkdown returns the API reference as Markdown.
+func (h *DocsHandler) APIReferenceMarkdown(w http.ResponseWriter, r *http.Request) {
+	spec := h.generateOpenAPISpec(r)
+
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.Write(spec.ToMarkdown())
+}
+
 func (h *DocsHandler) getBaseURL(r *http.Request) string {
 	scheme := "http"
 	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
@@ -204,13 +237,13 @@ func (h *DocsHandler) getLogoURL() string {
 // Template Rendering
 // =============================================================================
 
-func (h *DocsHandler) renderDocsHome() string {
+func (h *DocsHandler) renderDocsHome(spec *openapi.Spec) string {
 	// Check for full custom HTML override
 	customHTML := h.getCustomSetting(settings.KeyCustomDocsHomeHTML)
 	if customHTML != "" {
 		// Replace template variables in custom HTML
 		customHTML = strings.ReplaceAll(customHTML, "{{APP_NAME}}", h.appName)
-		customHTML = strings.ReplaceAll(customHTML, "{{NAV}}", h.renderDocsNav("home"))
+		customHTML = strings.ReplaceAll(customHTML, "{{NAV}}", h.renderDocsNav("home", spec))
 		customHTML = strings.ReplaceAll(customHTML, "{{CUSTOM_CSS}}", h.getCustomCSS())
 		customHTML = strings.ReplaceAll(customHTML, "{{FOOTER}}", h.getCustomFooter())
 		customHTML = strings.ReplaceAll(customHTML, "{{PRIMARY_COLOR}}", h.getPrimaryColor())
@@ -241,11 +274,7 @@ func (h *DocsHandler) renderDocsHome() string {
 		colorCSS = fmt.Sprintf(`
 <style>
 :root { --primary-color: %s; }
-.docs-header { background: %s; }
-.btn { background: %s; }
-.btn:hover { background: %s; filter: brightness(1.2); }
-.docs-nav a.active { color: white; }
-</style>`, primaryColor, primaryColor, primaryColor, primaryColor)
+</style>`, primaryColor)
 	}
 
 	// Get logo
@@ -273,43 +302,14 @@ func (h *DocsHandler) renderDocsHome() string {
             <p>%s</p>
         </div>
 
-        <div class="docs-cards">
-            <a href="/docs/quickstart" class="docs-card">
-                <h3>Quickstart</h3>
-                <p>Get started in a few minutes.</p>
-            </a>
-
-            <a href="/docs/authentication" class="docs-card">
-                <h3>Authentication</h3>
-                <p>How to authenticate your requests.</p>
-            </a>
-
-            <a href="/docs/api-reference" class="docs-card">
-                <h3>API Reference</h3>
-                <p>Available endpoints and response codes.</p>
-            </a>
-
-            <a href="/docs/examples" class="docs-card">
-                <h3>Code Examples</h3>
-                <p>cURL, JavaScript, Python, and Go.</p>
-            </a>
-
-            <a href="/docs/try-it" class="docs-card">
-                <h3>Try It</h3>
-                <p>Test the API in your browser.</p>
-            </a>
-
-            <a href="/docs/openapi.json" class="docs-card" target="_blank">
-                <h3>OpenAPI Spec</h3>
-                <p>Download the OpenAPI 3.0 spec.</p>
-            </a>
+        <div class="docs-cards">%s
         </div>
     </main>
     %s
 </body>
 </html>`, h.appName, docsCSS, colorCSS, h.getCustomCSS(),
-		h.renderDocsNavWithLogo("home", logoHTML),
-		heroTitle, heroSubtitle,
+		h.renderDocsNavWithLogo("home", logoHTML, spec),
+		heroTitle, heroSubtitle, h.renderDocsCards(spec),
 		footer)
 }
 
@@ -463,14 +463,14 @@ print(response.json())</code></pre>
     </main>
     <script>%s</script>
 </body>
-</html>`, h.appName, docsCSS, h.renderDocsNav("quickstart"), h.appName, endpointInfo,
+</html>`, h.appName, docsCSS, h.renderDocsNav("quickstart", spec), h.appName, endpointInfo,
 		exampleMethod, baseURL, exampleEndpoint,
 		baseURL, exampleEndpoint, exampleMethod,
 		strings.ToLower(exampleMethod), baseURL, exampleEndpoint,
 		docsJS)
 }
 
-func (h *DocsHandler) renderAuthentication(baseURL string) string {
+func (h *DocsHandler) renderAuthentication(baseURL string, spec *openapi.Spec) string {
 	return fmt.Sprintf(`<!DOCTYPE html>
 <html lang="en">
 <head>
@@ -587,7 +587,7 @@ X-RateLimit-Reset: 1704067200</code></pre>
         </div>
     </main>
 </body>
-</html>`, h.appName, docsCSS, h.renderDocsNav("authentication"), baseURL)
+</html>`, h.appName, docsCSS, h.renderDocsNav("authentication", spec), baseURL)
 }
 
 func (h *DocsHandler) renderAPIReference(spec *openapi.Spec) string {
@@ -682,9 +682,22 @@ func (h *DocsHandler) renderAPIReference(spec *openapi.Spec) string {
 			<p>If you're the admin, go to <strong>Routes</strong> in the admin panel and add endpoint descriptions, example requests, and example responses to your routes.</p>
 		</div>`
 	} else {
+		endpointsHTML = `
+			<input type="search" id="endpoint-search" class="form-input endpoint-search" placeholder="Filter by path, method or description" aria-label="Search endpoints" autocomplete="off">
+			<p id="endpoint-search-empty" class="endpoint-desc" hidden>No endpoints match your search.</p>`
+		anchors := make(map[string]bool, len(endpoints))
 		for _, ep := range endpoints {
 			methodBadge := fmt.Sprintf(`<span class="method-badge method-%s">%s</span>`, strings.ToLower(ep.methods[0]), ep.methods[0])
 
+			// Unique, linkable ID for the card
+			base := endpointAnchor(ep.methods[0], ep.path)
+			anchor := base
+			for i := 2; anchors[anchor]; i++ {
+				anchor = fmt.Sprintf("%s-%d", base, i)
+			}
+			anchors[anchor] = true
+			searchText := strings.ToLower(strings.Join([]string{ep.methods[0], ep.path, ep.summary, ep.description}, " "))
+
 			descText := ""
 			if ep.description != "" {
 				descText = fmt.Sprintf(`<p class="endpoint-desc">%s</p>`, ep.description)
@@ -695,8 +708,8 @@ func (h *DocsHandler) renderAPIReference(spec *openapi.Spec) string {
 			if ep.operation != nil {
 				// Request example
 				if ep.operation.RequestBody != nil && ep.operation.RequestBody.Content != nil {
-					if jsonMedia, ok := ep.operation.RequestBody.Content["application/json"]; ok && jsonMedia.Schema != nil && jsonMedia.Schema.Example != nil {
-						exampleJSON := h.formatExample(jsonMedia.Schema.Example)
+					if jsonMedia, ok := ep.operation.RequestBody.Content["application/json"]; ok && jsonMedia.ExampleValue() != nil {
+						exampleJSON := h.formatExample(jsonMedia.ExampleValue())
 						exampleHTML += fmt.Sprintf(`
 						<div class="example-section">
 							<h5>Example Request</h5>
@@ -707,8 +720,8 @@ func (h *DocsHandler) renderAPIReference(spec *openapi.Spec) string {
 
 				// Response example
 				if resp, ok := ep.operation.Responses["200"]; ok && resp.Content != nil {
-					if jsonMedia, ok := resp.Content["application/json"]; ok && jsonMedia.Schema != nil && jsonMedia.Schema.Example != nil {
-						exampleJSON := h.formatExample(jsonMedia.Schema.Example)
+					if jsonMedia, ok := resp.Content["application/json"]; ok && jsonMedia.ExampleValue() != nil {
+						exampleJSON := h.formatExample(jsonMedia.ExampleValue())
 						exampleHTML += fmt.Sprintf(`
 						<div class="example-section">
 							<h5>Example Response</h5>
@@ -719,14 +732,15 @@ func (h *DocsHandler) renderAPIReference(spec *openapi.Spec) string {
 			}
 
 			endpointsHTML += fmt.Sprintf(`
-				<div class="endpoint-card">
+				<div class="endpoint-card" id="%s" data-search="%s">
 					<div class="endpoint-header">
 						%s
 						<code class="endpoint-path">%s</code>
+						<a href="#%s" class="endpoint-anchor" aria-label="Link to this endpoint">#</a>
 					</div>
 					%s
 					%s
-				</div>`, methodBadge, ep.path, descText, exampleHTML)
+				</div>`, anchor, html.EscapeString(searchText), methodBadge, ep.path, anchor, descText, exampleHTML)
 		}
 	}
 
@@ -798,10 +812,53 @@ func (h *DocsHandler) renderAPIReference(spec *openapi.Spec) string {
             </table>
         </div>
     </main>
+    <script>%s</script>
 </body>
-</html>`, h.appName, docsCSS, h.renderDocsNav("api-reference"), wildcardBanner, baseURL, baseURL, endpointsHTML)
+</html>`, h.appName, docsCSS, h.renderDocsNav("api-reference", spec), wildcardBanner, baseURL, baseURL, endpointsHTML, apiReferenceJS)
 }
 
+// endpointAnchor returns the fragment ID for an endpoint on the API
+// reference page, e.g. "get-users-id" for GET /users/{id}.
+func endpointAnchor(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	dash := true
+	for _, r := range strings.ToLower(path) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash {
+				b.WriteByte('-')
+				dash = false
+			}
+			b.WriteRune(r)
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// apiReferenceJS filters the endpoint cards as the user types in the search
+// box. Every word typed must appear in the card's method, path or description.
+const apiReferenceJS = `
+(function() {
+    const input = document.getElementById('endpoint-search');
+    if (!input) return;
+    const cards = document.querySelectorAll('.endpoint-card[data-search]');
+    const empty = document.getElementById('endpoint-search-empty');
+
+    input.addEventListener('input', () => {
+        const terms = input.value.toLowerCase().split(/\s+/).filter(Boolean);
+        let shown = 0;
+        cards.forEach(card => {
+            const match = terms.every(term => card.dataset.search.includes(term));
+            card.hidden = !match;
+            if (match) shown++;
+        });
+        empty.hidden = shown > 0;
+    });
+})();
+`
+
 // concreteEndpoint represents a specific endpoint (not a wildcard)
 type concreteEndpoint struct {
 	path        string
@@ -888,68 +945,33 @@ func (h *DocsHandler) formatExample(v any) string {
 }
 
 func (h *DocsHandler) renderExamples(baseURL string, spec *openapi.Spec) string {
-	// Find actual GET and POST endpoints from the spec
-	getEndpoint := "/your-endpoint"
-	getDescription := ""
-	postEndpoint := "/your-endpoint"
-	postDescription := ""
-	postExampleBody := `{
-    "name": "Example",
-    "value": 123
-  }`
-
-	// Collect concrete endpoints (skip wildcards)
-	for path, pathItem := range spec.Paths {
+	// Collect concrete endpoints (skip wildcards), ordered by path then method
+	paths := make([]string, 0, len(spec.Paths))
+	for path := range spec.Paths {
 		if strings.Contains(path, "{path}") || strings.Contains(path, "*") {
 			continue
 		}
-
-		// Find first GET endpoint
-		if getEndpoint == "/your-endpoint" && pathItem.Get != nil {
-			getEndpoint = path
-			if pathItem.Get.Description != "" {
-				getDescription = pathItem.Get.Description
-			} else if pathItem.Get.Summary != "" {
-				getDescription = pathItem.Get.Summary
-			}
-		}
-
-		// Find first POST endpoint with example body
-		if postEndpoint == "/your-endpoint" && pathItem.Post != nil {
-			postEndpoint = path
-			if pathItem.Post.Description != "" {
-				postDescription = pathItem.Post.Description
-			} else if pathItem.Post.Summary != "" {
-				postDescription = pathItem.Post.Summary
-			}
-			// Try to get example request body
-			if pathItem.Post.RequestBody != nil && pathItem.Post.RequestBody.Content != nil {
-				if jsonMedia, ok := pathItem.Post.RequestBody.Content["application/json"]; ok && jsonMedia.Schema != nil && jsonMedia.Schema.Example != nil {
-					postExampleBody = h.formatExample(jsonMedia.Schema.Example)
-				}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var sections strings.Builder
+	for _, path := range paths {
+		pathItem := spec.Paths[path]
+		for _, m := range []struct {
+			method string
+			op     *openapi.Operation
+		}{{"GET", pathItem.Get}, {"POST", pathItem.Post}, {"PUT", pathItem.Put}, {"PATCH", pathItem.Patch}, {"DELETE", pathItem.Delete}} {
+			if m.op != nil {
+				sections.WriteString(h.renderExampleSection(baseURL, m.method, path, m.op))
 			}
 		}
-
-		// Stop if we found both
-		if getEndpoint != "/your-endpoint" && postEndpoint != "/your-endpoint" {
-			break
-		}
-	}
-
-	// Build endpoint info callouts
-	getCallout := ""
-	if getEndpoint != "/your-endpoint" && getDescription != "" {
-		getCallout = fmt.Sprintf(`<div class="docs-callout info" style="margin-bottom: 16px;"><strong>%s</strong><p style="margin: 4px 0 0 0;">%s</p></div>`, getEndpoint, getDescription)
 	}
 
-	postCallout := ""
-	if postEndpoint != "/your-endpoint" && postDescription != "" {
-		postCallout = fmt.Sprintf(`<div class="docs-callout info" style="margin-bottom: 16px;"><strong>%s</strong><p style="margin: 4px 0 0 0;">%s</p></div>`, postEndpoint, postDescription)
-	}
-
-	// If no endpoints found, show a warning
+	// If no endpoints found, show a warning and a placeholder example
 	noEndpointsWarning := ""
-	if getEndpoint == "/your-endpoint" && postEndpoint == "/your-endpoint" {
+	if sections.Len() == 0 {
+		sections.WriteString(h.renderExampleSection(baseURL, "GET", "/your-endpoint", &openapi.Operation{}))
 		noEndpointsWarning = `
         <div class="docs-callout warning" style="margin-bottom: 24px;">
             <strong>No documented endpoints yet</strong>
@@ -975,163 +997,15 @@ func (h *DocsHandler) renderExamples(baseURL string, spec *openapi.Spec) string
         <h1>Code Examples</h1>
         <p class="docs-lead">Ready-to-use code snippets for your API endpoints.</p>
 
+        %s
         %s
 
         <div class="docs-section">
-            <h2>Making a GET Request</h2>
-            %s
-            <div class="code-tabs">
-                <button class="code-tab active" data-lang="curl">cURL</button>
-                <button class="code-tab" data-lang="javascript">JavaScript</button>
-                <button class="code-tab" data-lang="python">Python</button>
-                <button class="code-tab" data-lang="go">Go</button>
-            </div>
-
-            <pre class="code-block" data-lang="curl"><code>curl -X GET "%s%s" \
-  -H "X-API-Key: your_api_key" \
-  -H "Content-Type: application/json"</code></pre>
-
-            <pre class="code-block hidden" data-lang="javascript"><code>const API_KEY = 'your_api_key';
+            <h2>Error Handling</h2>
+            <pre class="code-block" data-lang="javascript"><code>const API_KEY = 'your_api_key';
 const BASE_URL = '%s';
 
-async function getData() {
-  const response = await fetch(BASE_URL + '%s', {
-    method: 'GET',
-    headers: {
-      'X-API-Key': API_KEY,
-      'Content-Type': 'application/json'
-    }
-  });
-
-  if (!response.ok) {
-    throw new Error('HTTP error! status: ' + response.status);
-  }
-
-  return response.json();
-}
-
-// Usage
-getData()
-  .then(data => console.log(data))
-  .catch(err => console.error(err));</code></pre>
-
-            <pre class="code-block hidden" data-lang="python"><code>import requests
-
-API_KEY = 'your_api_key'
-BASE_URL = '%s'
-
-def get_data():
-    response = requests.get(
-        f'{BASE_URL}%s',
-        headers={
-            'X-API-Key': API_KEY,
-            'Content-Type': 'application/json'
-        }
-    )
-    response.raise_for_status()
-    return response.json()
-
-# Usage
-data = get_data()
-print(data)</code></pre>
-
-            <pre class="code-block hidden" data-lang="go"><code>package main
-
-import (
-    "encoding/json"
-    "fmt"
-    "io"
-    "net/http"
-)
-
-const (
-    apiKey  = "your_api_key"
-    baseURL = "%s"
-)
-
-func getData() (map[string]interface{}, error) {
-    req, err := http.NewRequest("GET", baseURL+"%s", nil)
-    if err != nil {
-        return nil, err
-    }
-
-    req.Header.Set("X-API-Key", apiKey)
-    req.Header.Set("Content-Type", "application/json")
-
-    client := &http.Client{}
-    resp, err := client.Do(req)
-    if err != nil {
-        return nil, err
-    }
-    defer resp.Body.Close()
-
-    body, _ := io.ReadAll(resp.Body)
-
-    var result map[string]interface{}
-    json.Unmarshal(body, &result)
-    return result, nil
-}
-
-func main() {
-    data, err := getData()
-    if err != nil {
-        fmt.Println("Error:", err)
-        return
-    }
-    fmt.Println(data)
-}</code></pre>
-        </div>
-
-        <div class="docs-section">
-            <h2>Making a POST Request</h2>
-            %s
-            <div class="code-tabs">
-                <button class="code-tab active" data-lang="curl2">cURL</button>
-                <button class="code-tab" data-lang="javascript2">JavaScript</button>
-                <button class="code-tab" data-lang="python2">Python</button>
-            </div>
-
-            <pre class="code-block" data-lang="curl2"><code>curl -X POST "%s%s" \
-  -H "X-API-Key: your_api_key" \
-  -H "Content-Type: application/json" \
-  -d '%s'</code></pre>
-
-            <pre class="code-block hidden" data-lang="javascript2"><code>async function createData(data) {
-  const response = await fetch(BASE_URL + '%s', {
-    method: 'POST',
-    headers: {
-      'X-API-Key': API_KEY,
-      'Content-Type': 'application/json'
-    },
-    body: JSON.stringify(data)
-  });
-
-  return response.json();
-}
-
-// Usage
-createData(%s)
-  .then(data => console.log(data));</code></pre>
-
-            <pre class="code-block hidden" data-lang="python2"><code>def create_data(data):
-    response = requests.post(
-        f'{BASE_URL}%s',
-        headers={
-            'X-API-Key': API_KEY,
-            'Content-Type': 'application/json'
-        },
-        json=data
-    )
-    return response.json()
-
-# Usage
-result = create_data(%s)
-print(result)</code></pre>
-        </div>
-
-        <div class="docs-section">
-            <h2>Error Handling</h2>
-            <pre class="code-block" data-lang="javascript"><code>async function safeApiCall(endpoint) {
+async function safeApiCall(endpoint) {
   try {
     const response = await fetch(BASE_URL + endpoint, {
       headers: { 'X-API-Key': API_KEY }
@@ -1156,15 +1030,52 @@ print(result)</code></pre>
     </main>
     <script>%s</script>
 </body>
-</html>`, h.appName, docsCSS, h.renderDocsNav("examples"), noEndpointsWarning,
-		getCallout, baseURL, getEndpoint,
-		baseURL, getEndpoint,
-		baseURL, getEndpoint,
-		baseURL, getEndpoint,
-		postCallout, baseURL, postEndpoint, postExampleBody,
-		postEndpoint, postExampleBody,
-		postEndpoint, postExampleBody,
-		docsJS)
+</html>`, h.appName, docsCSS, h.renderDocsNav("examples", spec), noEndpointsWarning,
+		sections.String(), baseURL, docsJS)
+}
+
+// renderExampleSection renders one operation's request as tabbed snippets in
+// every supported language, using the operation's example body if it has one.
+func (h *DocsHandler) renderExampleSection(baseURL, method, path string, op *openapi.Operation) string {
+	description := op.Description
+	if description == "" {
+		description = op.Summary
+	}
+	callout := ""
+	if description != "" {
+		callout = fmt.Sprintf(`<div class="docs-callout info" style="margin-bottom: 16px;"><p style="margin: 0;">%s</p></div>`, description)
+	}
+
+	body := ""
+	if op.RequestBody != nil {
+		if jsonMedia, ok := op.RequestBody.Content["application/json"]; ok {
+			body = h.formatExample(jsonMedia.ExampleValue())
+			if body == "" {
+				body = "{}"
+			}
+		}
+	}
+
+	var tabs, blocks strings.Builder
+	for i, s := range requestSnippets(baseURL, method, path, body) {
+		active, hidden := "", " hidden"
+		if i == 0 {
+			active, hidden = " active", ""
+		}
+		fmt.Fprintf(&tabs, `
+                <button class="code-tab%s" data-lang="%s">%s</button>`, active, s.Lang, s.Label)
+		fmt.Fprintf(&blocks, `
+            <pre class="code-block%s" data-lang="%s"><code>%s</code></pre>`, hidden, s.Lang, html.EscapeString(s.Code))
+	}
+
+	return fmt.Sprintf(`
+        <div class="docs-section">
+            <h2>%s %s</h2>
+            %s
+            <div class="code-tabs">%s
+            </div>
+%s
+        </div>`, method, html.EscapeString(path), callout, tabs.String(), blocks.String())
 }
 
 func (h *DocsHandler) renderTryIt(baseURL string, spec *openapi.Spec) string {
@@ -1199,8 +1110,8 @@ func (h *DocsHandler) renderTryIt(baseURL string, spec *openapi.Spec) string {
 			}
 			exBody := ""
 			if pathItem.Post.RequestBody != nil && pathItem.Post.RequestBody.Content != nil {
-				if jsonMedia, ok := pathItem.Post.RequestBody.Content["application/json"]; ok && jsonMedia.Schema != nil && jsonMedia.Schema.Example != nil {
-					exBody = h.formatExample(jsonMedia.Schema.Example)
+				if jsonMedia, ok := pathItem.Post.RequestBody.Content["application/json"]; ok && jsonMedia.ExampleValue() != nil {
+					exBody = h.formatExample(jsonMedia.ExampleValue())
 				}
 			}
 			endpoints = append(endpoints, endpointInfo{method: "POST", path: path, description: desc, exampleBody: exBody})
@@ -1213,8 +1124,8 @@ func (h *DocsHandler) renderTryIt(baseURL string, spec *openapi.Spec) string {
 			}
 			exBody := ""
 			if pathItem.Put.RequestBody != nil && pathItem.Put.RequestBody.Content != nil {
-				if jsonMedia, ok := pathItem.Put.RequestBody.Content["application/json"]; ok && jsonMedia.Schema != nil && jsonMedia.Schema.Example != nil {
-					exBody = h.formatExample(jsonMedia.Schema.Example)
+				if jsonMedia, ok := pathItem.Put.RequestBody.Content["application/json"]; ok && jsonMedia.ExampleValue() != nil {
+					exBody = h.formatExample(jsonMedia.ExampleValue())
 				}
 			}
 			endpoints = append(endpoints, endpointInfo{method: "PUT", path: path, description: desc, exampleBody: exBody})
@@ -1227,8 +1138,8 @@ func (h *DocsHandler) renderTryIt(baseURL string, spec *openapi.Spec) string {
 			}
 			exBody := ""
 			if pathItem.Patch.RequestBody != nil && pathItem.Patch.RequestBody.Content != nil {
-				if jsonMedia, ok := pathItem.Patch.RequestBody.Content["application/json"]; ok && jsonMedia.Schema != nil && jsonMedia.Schema.Example != nil {
-					exBody = h.formatExample(jsonMedia.Schema.Example)
+				if jsonMedia, ok := pathItem.Patch.RequestBody.Content["application/json"]; ok && jsonMedia.ExampleValue() != nil {
+					exBody = h.formatExample(jsonMedia.ExampleValue())
 				}
 			}
 			endpoints = append(endpoints, endpointInfo{method: "PATCH", path: path, description: desc, exampleBody: exBody})
@@ -1314,6 +1225,28 @@ func (h *DocsHandler) renderTryIt(baseURL string, spec *openapi.Spec) string {
 	// Escape default example body for textarea
 	escapedExampleBody := defaultExampleBody
 
+	// Render the default request as code; the page script keeps these in
+	// sync with the form
+	previewBody := ""
+	if defaultMethod == "POST" || defaultMethod == "PUT" || defaultMethod == "PATCH" {
+		previewBody = defaultExampleBody
+	}
+	var langOptions, previews, copyButtons strings.Builder
+	for i, snippet := range requestSnippets(baseURL, defaultMethod, defaultEndpoint, previewBody) {
+		hidden := ""
+		if i > 0 {
+			hidden = " hidden"
+		}
+		fmt.Fprintf(&langOptions, `<option value="%s">%s</option>`, snippet.Lang, snippet.Label)
+		fmt.Fprintf(&previews, `
+                <pre class="curl-preview" id="preview-%s"%s>%s</pre>`, snippet.Lang, hidden, html.EscapeString(snippet.Code))
+		fmt.Fprintf(&copyButtons, `
+                    <button type="button" class="btn btn-secondary btn-icon copy-code" data-lang="%s" title="Copy as %s">
+                        <svg width="14" height="14" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2"><rect x="9" y="9" width="13" height="13" rx="2"/><path d="M5 15H4a2 2 0 01-2-2V4a2 2 0 012-2h9a2 2 0 012 2v1"/></svg>
+                        Copy %s
+                    </button>`, snippet.Lang, snippet.Label, snippet.Label)
+	}
+
 	return fmt.Sprintf(`<!DOCTYPE html>
 <html lang="en">
 <head>
@@ -1322,13 +1255,13 @@ func (h *DocsHandler) renderTryIt(baseURL string, spec *openapi.Spec) string {
     <title>Try It - %s API</title>
     <style>%s
 .endpoint-buttons { display: flex; flex-wrap: wrap; gap: 8px; margin-bottom: 8px; }
-.endpoint-btn { display: inline-flex; align-items: center; gap: 6px; padding: 8px 12px; border: 1px solid #e5e5e5; border-radius: 6px; background: #fff; cursor: pointer; font-size: 13px; font-family: ui-monospace, monospace; transition: all 0.15s ease; }
-.endpoint-btn:hover { border-color: #111; background: #f9f9f9; transform: translateY(-1px); }
-.endpoint-btn.active { border-color: #111; background: #111; color: #fff; }
+.endpoint-btn { display: inline-flex; align-items: center; gap: 6px; padding: 8px 12px; border: 1px solid var(--docs-border); border-radius: 6px; background: var(--docs-bg); color: var(--docs-text); cursor: pointer; font-size: 13px; font-family: ui-monospace, monospace; transition: all 0.15s ease; }
+.endpoint-btn:hover { border-color: var(--docs-text); background: var(--docs-subtle); transform: translateY(-1px); }
+.endpoint-btn.active { border-color: var(--docs-code-bg); background: var(--docs-code-bg); color: #fff; }
 .endpoint-btn.active .method-badge { background: rgba(255,255,255,0.2); color: #fff; }
 .endpoint-btn.has-example::after { content: ''; width: 6px; height: 6px; background: #10b981; border-radius: 50%%; margin-left: 4px; }
 .label-hint { font-weight: 400; color: #888; font-size: 12px; }
-.endpoint-desc { font-size: 13px; color: #666; padding: 8px 12px; background: #f9f9f9; border-radius: 4px; margin-bottom: 16px; min-height: 20px; }
+.endpoint-desc { font-size: 13px; color: var(--docs-muted); padding: 8px 12px; background: var(--docs-subtle); border-radius: 4px; margin-bottom: 16px; min-height: 20px; }
 .endpoint-desc:empty::before { content: 'Select an endpoint above to see its description'; color: #999; font-style: italic; }
 .validation-error { color: #dc2626; font-size: 12px; margin-top: 4px; display: none; }
 .validation-error.show { display: block; }
@@ -1336,8 +1269,8 @@ func (h *DocsHandler) renderTryIt(baseURL string, spec *openapi.Spec) string {
 .btn-row { display: flex; gap: 8px; flex-wrap: wrap; }
 .btn { transition: all 0.15s ease; }
 .btn:disabled { opacity: 0.6; cursor: not-allowed; }
-.btn-secondary { background: #f3f4f6; color: #374151; border: 1px solid #e5e5e5; }
-.btn-secondary:hover:not(:disabled) { background: #e5e7eb; border-color: #d1d5db; }
+.btn-secondary { background: var(--docs-subtle); color: var(--docs-text); border: 1px solid var(--docs-border); }
+.btn-secondary:hover:not(:disabled) { background: var(--docs-border); border-color: var(--docs-muted); }
 .btn-icon { display: inline-flex; align-items: center; gap: 6px; }
 .spinner { width: 14px; height: 14px; border: 2px solid transparent; border-top-color: currentColor; border-radius: 50%%; animation: spin 0.8s linear infinite; }
 @keyframes spin { to { transform: rotate(360deg); } }
@@ -1345,13 +1278,21 @@ func (h *DocsHandler) renderTryIt(baseURL string, spec *openapi.Spec) string {
 .response-header h3 { margin: 0; }
 .response-actions { display: flex; gap: 8px; }
 .response-tabs { display: flex; gap: 4px; margin-bottom: 12px; }
-.response-tab { padding: 6px 12px; border: none; background: #e5e5e5; cursor: pointer; font-size: 13px; border-radius: 4px; }
-.response-tab.active { background: #111; color: white; }
+.response-tab { padding: 6px 12px; border: none; background: var(--docs-border); color: var(--docs-text); cursor: pointer; font-size: 13px; border-radius: 4px; }
+.response-tab.active { background: var(--docs-code-bg); color: white; }
 .response-content { display: none; }
 .response-content.active { display: block; }
 .curl-preview { background: #1e1e1e; color: #d4d4d4; padding: 12px; border-radius: 6px; font-family: ui-monospace, monospace; font-size: 12px; white-space: pre-wrap; word-break: break-all; margin-bottom: 16px; }
 .copy-feedback { position: fixed; bottom: 20px; right: 20px; background: #111; color: #fff; padding: 10px 16px; border-radius: 6px; font-size: 13px; opacity: 0; transition: opacity 0.2s ease; z-index: 1000; }
 .copy-feedback.show { opacity: 1; }
+.remember-key { display: flex; align-items: center; justify-content: space-between; gap: 8px; margin-top: 8px; font-size: 13px; }
+.request-history { margin-top: 24px; background: var(--docs-bg); padding: 20px; border-radius: 6px; border: 1px solid var(--docs-border); }
+.history-list { list-style: none; }
+.history-item { display: flex; align-items: center; gap: 10px; width: 100%%; padding: 8px 10px; border: none; border-bottom: 1px solid var(--docs-border); background: transparent; color: var(--docs-text); cursor: pointer; font-size: 13px; text-align: left; }
+.history-item:hover { background: var(--docs-subtle); }
+.history-path { flex: 1; font-family: ui-monospace, monospace; overflow: hidden; text-overflow: ellipsis; white-space: nowrap; }
+.history-time { color: var(--docs-muted); font-size: 12px; }
+.remember-key label { display: inline-flex; align-items: center; gap: 6px; font-weight: 400; margin: 0; }
 </style>
 </head>
 <body>
@@ -1370,6 +1311,11 @@ func (h *DocsHandler) renderTryIt(baseURL string, spec *openapi.Spec) string {
                     <label>API Key <span class="label-hint">(<a href="/portal" target="_blank">Get one here</a>)</span></label>
                     <input type="password" id="apiKey" placeholder="Paste your API key here" class="form-input" autocomplete="off">
                     <div class="validation-error" id="apiKeyError">API key is required to make requests</div>
+                    <div class="remember-key">
+                        <label><input type="checkbox" id="rememberKey"> Remember my key in this browser</label>
+                        <button type="button" id="forgetKey" class="btn btn-sm btn-secondary" hidden>Forget key</button>
+                    </div>
+                    <p class="label-hint">Remembered keys are stored unencrypted in this browser's local storage. Don't use this on a shared computer.</p>
                 </div>
 
                 %s
@@ -1395,16 +1341,16 @@ func (h *DocsHandler) renderTryIt(baseURL string, spec *openapi.Spec) string {
                     <div class="validation-error" id="bodyError">Invalid JSON format</div>
                 </div>
 
-                <div class="curl-preview" id="curlPreview"></div>
+                <div class="form-group code-lang">
+                    <label for="codeLang">Show request as</label>
+                    <select id="codeLang" class="form-input">%s</select>
+                </div>
+                %s
 
                 <div class="btn-row">
                     <button id="sendRequest" class="btn btn-primary btn-icon">
                         <span class="btn-text">Send Request</span>
-                    </button>
-                    <button id="copyCurl" class="btn btn-secondary btn-icon" title="Copy as cURL command">
-                        <svg width="14" height="14" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2"><rect x="9" y="9" width="13" height="13" rx="2"/><path d="M5 15H4a2 2 0 01-2-2V4a2 2 0 012-2h9a2 2 0 012 2v1"/></svg>
-                        Copy cURL
-                    </button>
+                    </button>%s
                 </div>
             </div>
 
@@ -1431,8 +1377,18 @@ func (h *DocsHandler) renderTryIt(baseURL string, spec *openapi.Spec) string {
                 </div>
             </div>
         </div>
+
+        <div class="request-history" id="requestHistory">
+            <div class="response-header">
+                <h3>History</h3>
+                <button type="button" id="clearHistory" class="btn btn-sm btn-secondary" hidden>Clear</button>
+            </div>
+            <p class="label-hint" id="historyEmpty">Requests you send appear here. Click one to run it again.</p>
+            <ul class="history-list" id="historyList"></ul>
+        </div>
     </main>
     <div class="copy-feedback" id="copyFeedback">Copied to clipboard!</div>
+    <script>%s</script>
     <script>
         const baseURL = '%s';
         let lastResponseBody = '';
@@ -1446,38 +1402,41 @@ func (h *DocsHandler) renderTryIt(baseURL string, spec *openapi.Spec) string {
             setTimeout(() => feedback.classList.remove('show'), 2000);
         }
 
-        // Generate cURL command
-        function generateCurl() {
+        // Generate the current request as code in every language
+        function updateSnippets() {
             const apiKey = document.getElementById('apiKey').value;
             const method = document.getElementById('method').value;
             const endpoint = document.getElementById('endpoint').value;
             const bodyInput = document.getElementById('requestBody').value;
 
-            let curl = 'curl -X ' + method + ' "' + baseURL + endpoint + '"';
-            curl += ' \\\n  -H "Content-Type: application/json"';
-            if (apiKey) {
-                curl += ' \\\n  -H "X-API-Key: ' + apiKey + '"';
-            } else {
-                curl += ' \\\n  -H "X-API-Key: YOUR_API_KEY"';
-            }
-
+            let body = '';
             if (['POST', 'PUT', 'PATCH'].includes(method) && bodyInput.trim()) {
                 try {
-                    const formatted = JSON.stringify(JSON.parse(bodyInput));
-                    curl += " \\\n  -d '" + formatted + "'";
+                    body = JSON.stringify(JSON.parse(bodyInput), null, 2);
                 } catch (e) {
-                    curl += " \\\n  -d '" + bodyInput.replace(/'/g, "'\\''") + "'";
+                    body = bodyInput;
                 }
             }
 
-            document.getElementById('curlPreview').textContent = curl;
-            return curl;
+            const snippets = {};
+            Object.keys(snippetGenerators).forEach(lang => {
+                snippets[lang] = snippetGenerators[lang](baseURL + endpoint, method, body, apiKey || 'your_api_key');
+                document.getElementById('preview-' + lang).textContent = snippets[lang];
+            });
+            return snippets;
         }
 
-        // Update cURL on any input change
+        // Update snippets on any input change
         ['apiKey', 'method', 'endpoint', 'requestBody'].forEach(id => {
-            document.getElementById(id).addEventListener('input', generateCurl);
-            document.getElementById(id).addEventListener('change', generateCurl);
+            document.getElementById(id).addEventListener('input', updateSnippets);
+            document.getElementById(id).addEventListener('change', updateSnippets);
+        });
+
+        // Show the snippet for the selected language
+        document.getElementById('codeLang').addEventListener('change', (e) => {
+            document.querySelectorAll('[id^="preview-"]').forEach(pre => {
+                pre.hidden = pre.id !== 'preview-' + e.target.value;
+            });
         });
 
         // Handle endpoint button clicks
@@ -1515,7 +1474,7 @@ func (h *DocsHandler) renderTryIt(baseURL string, spec *openapi.Spec) string {
                     bodyInput.value = '';
                 }
 
-                generateCurl();
+                updateSnippets();
             });
         });
 
@@ -1628,12 +1587,14 @@ func (h *DocsHandler) renderTryIt(baseURL string, spec *openapi.Spec) string {
                 // Show tabs and copy button
                 responseTabs.style.display = 'flex';
                 copyResponseBtn.style.display = 'inline-flex';
+                recordHistory(method, endpoint, bodyInput, response.status);
             } catch (err) {
                 responseMeta.innerHTML = '<span class="status-error">Network Error</span>';
                 responseBody.textContent = 'Request failed: ' + err.message + '\n\nThis could be due to:\n• CORS restrictions\n• Network connectivity issues\n• Invalid endpoint';
                 lastResponseBody = '';
                 responseTabs.style.display = 'none';
                 copyResponseBtn.style.display = 'none';
+                recordHistory(method, endpoint, bodyInput, 0);
             } finally {
                 sendBtn.disabled = false;
                 btnText.textContent = originalText;
@@ -1650,10 +1611,12 @@ func (h *DocsHandler) renderTryIt(baseURL string, spec *openapi.Spec) string {
             });
         });
 
-        // Copy cURL
-        document.getElementById('copyCurl').addEventListener('click', () => {
-            const curl = generateCurl();
-            navigator.clipboard.writeText(curl).then(() => showCopyFeedback('cURL command copied!'));
+        // Copy the request as code
+        document.querySelectorAll('.copy-code').forEach(btn => {
+            btn.addEventListener('click', () => {
+                const code = updateSnippets()[btn.dataset.lang];
+                navigator.clipboard.writeText(code).then(() => showCopyFeedback(btn.title.replace('Copy as ', '') + ' copied!'));
+            });
         });
 
         // Copy response
@@ -1671,7 +1634,7 @@ func (h *DocsHandler) renderTryIt(baseURL string, spec *openapi.Spec) string {
             } else {
                 bodyGroup.style.display = 'none';
             }
-            generateCurl();
+            updateSnippets();
         });
 
         // Clear error on input
@@ -1683,17 +1646,117 @@ func (h *DocsHandler) renderTryIt(baseURL string, spec *openapi.Spec) string {
             });
         });
 
+        // Remember the API key in localStorage if the user opts in
+        const apiKeyInput = document.getElementById('apiKey');
+        const rememberKey = document.getElementById('rememberKey');
+        const forgetKey = document.getElementById('forgetKey');
+        const storedKey = localStorage.getItem('docs-api-key');
+        if (storedKey) {
+            apiKeyInput.value = storedKey;
+            rememberKey.checked = true;
+            forgetKey.hidden = false;
+        }
+        function saveKey() {
+            if (rememberKey.checked && apiKeyInput.value) {
+                localStorage.setItem('docs-api-key', apiKeyInput.value);
+                forgetKey.hidden = false;
+            } else {
+                localStorage.removeItem('docs-api-key');
+                forgetKey.hidden = !rememberKey.checked;
+            }
+        }
+        apiKeyInput.addEventListener('input', saveKey);
+        rememberKey.addEventListener('change', saveKey);
+        forgetKey.addEventListener('click', () => {
+            localStorage.removeItem('docs-api-key');
+            apiKeyInput.value = '';
+            rememberKey.checked = false;
+            forgetKey.hidden = true;
+            updateSnippets();
+            showCopyFeedback('API key forgotten');
+        });
+
+        // Request history: the last HISTORY_LIMIT requests, newest first. The
+        // API key is never stored with them.
+        const HISTORY_LIMIT = 20;
+        function loadHistory() {
+            try {
+                return JSON.parse(localStorage.getItem('docs-request-history')) || [];
+            } catch (e) {
+                return [];
+            }
+        }
+        function recordHistory(method, path, body, status) {
+            const history = loadHistory();
+            history.unshift({ method: method, path: path, body: body, status: status, time: Date.now() });
+            localStorage.setItem('docs-request-history', JSON.stringify(history.slice(0, HISTORY_LIMIT)));
+            renderHistory();
+        }
+        function renderHistory() {
+            const history = loadHistory();
+            const list = document.getElementById('historyList');
+            list.innerHTML = '';
+            history.forEach(entry => {
+                const item = document.createElement('button');
+                item.type = 'button';
+                item.className = 'history-item';
+                item.title = 'Run ' + entry.method + ' ' + entry.path + ' again';
+
+                const badge = document.createElement('span');
+                badge.className = 'method-badge method-' + entry.method.toLowerCase();
+                badge.textContent = entry.method;
+                const path = document.createElement('span');
+                path.className = 'history-path';
+                path.textContent = entry.path;
+                const status = document.createElement('span');
+                status.className = entry.status >= 200 && entry.status < 300 ? 'status-success' : 'status-error';
+                status.textContent = entry.status || 'Error';
+                const time = document.createElement('span');
+                time.className = 'history-time';
+                time.textContent = new Date(entry.time).toLocaleTimeString();
+                item.append(badge, path, status, time);
+
+                item.addEventListener('click', () => replayRequest(entry));
+                const li = document.createElement('li');
+                li.appendChild(item);
+                list.appendChild(li);
+            });
+            document.getElementById('historyEmpty').hidden = history.length > 0;
+            document.getElementById('clearHistory').hidden = history.length === 0;
+        }
+        function replayRequest(entry) {
+            document.querySelectorAll('.endpoint-btn').forEach(b => {
+                b.classList.toggle('active', b.dataset.method === entry.method && b.dataset.path === entry.path);
+            });
+            document.getElementById('method').value = entry.method;
+            document.getElementById('endpoint').value = entry.path;
+            document.getElementById('requestBody').value = entry.body || '';
+            document.getElementById('bodyGroup').style.display = ['POST', 'PUT', 'PATCH'].includes(entry.method) ? 'block' : 'none';
+            clearErrors();
+            updateSnippets();
+            document.getElementById('sendRequest').click();
+        }
+        document.getElementById('clearHistory').addEventListener('click', () => {
+            localStorage.removeItem('docs-request-history');
+            renderHistory();
+        });
+
         // Initialize
-        generateCurl();
+        renderHistory();
+        updateSnippets();
     </script>
 </body>
-</html>`, h.appName, docsCSS, h.renderDocsNav("try-it"),
+</html>`, h.appName, docsCSS, h.renderDocsNav("try-it", spec),
 		endpointButtonsHTML,
 		defaultDescription,
 		methodOptionsHTML,
 		defaultEndpoint,
 		h.hiddenStyle(defaultMethod),
 		escapedExampleBody,
+		langOptions.String(),
+		previews.String(),
+		copyButtons.String(),
+		snippetsJS,
 		baseURL)
 }
 
@@ -1713,54 +1776,93 @@ func (h *DocsHandler) hiddenStyle(method string) string {
 	return ""
 }
 
-func (h *DocsHandler) renderDocsNav(active string) string {
-	links := []struct {
-		path  string
-		label string
-		key   string
-	}{
-		{"/docs", "Home", "home"},
-		{"/docs/quickstart", "Quickstart", "quickstart"},
-		{"/docs/authentication", "Authentication", "authentication"},
-		{"/docs/api-reference", "API Reference", "api-reference"},
-		{"/docs/examples", "Examples", "examples"},
-		{"/docs/try-it", "Try It", "try-it"},
+// docsSection is a page or download offered by the docs portal.
+type docsSection struct {
+	path           string
+	label          string
+	key            string // Nav key; empty for sections not in the nav
+	cardTitle      string // Homepage card title, if different from label
+	description    string // Homepage card text; empty for sections without a card
+	download       bool   // Opens in a new tab
+	needsEndpoints bool   // Has no content until an endpoint is documented
+}
+
+var docsSections = []docsSection{
+	{path: "/docs", label: "Home", key: "home"},
+	{path: "/docs/quickstart", label: "Quickstart", key: "quickstart", description: "Get started in a few minutes."},
+	{path: "/docs/authentication", label: "Authentication", key: "authentication", description: "How to authenticate your requests."},
+	{path: "/docs/api-reference", label: "API Reference", key: "api-reference", description: "Available endpoints and response codes.", needsEndpoints: true},
+	{path: "/docs/examples", label: "Examples", key: "examples", cardTitle: "Code Examples", description: "cURL, JavaScript, Python, and Go.", needsEndpoints: true},
+	{path: "/docs/try-it", label: "Try It", key: "try-it", description: "Test the API in your browser.", needsEndpoints: true},
+	{path: "/docs/openapi.json", label: "OpenAPI Spec", description: "Download the OpenAPI 3.0 spec.", download: true},
+	{path: "/docs/postman.json", label: "Postman Collection", description: "Import the API into Postman.", download: true, needsEndpoints: true},
+	{path: "/docs/api-reference.md", label: "Markdown Reference", description: "The API reference as Markdown.", download: true, needsEndpoints: true},
+}
+
+// hasDocumentedEndpoints reports whether the spec documents any operation.
+func hasDocumentedEndpoints(spec *openapi.Spec) bool {
+	for _, item := range spec.Paths {
+		if item.Get != nil || item.Post != nil || item.Put != nil || item.Patch != nil || item.Delete != nil {
+			return true
+		}
 	}
+	return false
+}
 
-	navItems := ""
-	for _, link := range links {
-		activeClass := ""
-		if link.key == active {
-			activeClass = "active"
+// renderDocsCards renders the homepage cards. Sections with no content yet
+// are shown disabled rather than linking to an empty page.
+func (h *DocsHandler) renderDocsCards(spec *openapi.Spec) string {
+	hasEndpoints := hasDocumentedEndpoints(spec)
+	var cards strings.Builder
+	for _, section := range docsSections {
+		if section.description == "" {
+			continue
+		}
+		label := section.label
+		if section.cardTitle != "" {
+			label = section.cardTitle
 		}
-		navItems += fmt.Sprintf(`<a href="%s" class="%s">%s</a>`, link.path, activeClass, link.label)
+		if section.needsEndpoints && !hasEndpoints {
+			fmt.Fprintf(&cards, `
+            <div class="docs-card disabled" aria-disabled="true">
+                <h3>%s</h3>
+                <p>Available once endpoints are documented.</p>
+            </div>
+`, label)
+			continue
+		}
+		target := ""
+		if section.download {
+			target = ` target="_blank"`
+		}
+		fmt.Fprintf(&cards, `
+            <a href="%s" class="docs-card"%s>
+                <h3>%s</h3>
+                <p>%s</p>
+            </a>
+`, section.path, target, label, section.description)
 	}
+	return cards.String()
+}
 
-	return h.renderDocsNavWithLogo(active, h.appName)
+func (h *DocsHandler) renderDocsNav(active string, spec *openapi.Spec) string {
+	return h.renderDocsNavWithLogo(active, h.appName, spec)
 }
 
 // renderDocsNavWithLogo renders the docs navigation with a custom logo/text.
-func (h *DocsHandler) renderDocsNavWithLogo(active string, logoHTML string) string {
-	links := []struct {
-		path  string
-		label string
-		key   string
-	}{
-		{"/docs", "Home", "home"},
-		{"/docs/quickstart", "Quickstart", "quickstart"},
-		{"/docs/authentication", "Authentication", "authentication"},
-		{"/docs/api-reference", "API Reference", "api-reference"},
-		{"/docs/examples", "Examples", "examples"},
-		{"/docs/try-it", "Try It", "try-it"},
-	}
-
+// Sections with no content yet are left out.
+func (h *DocsHandler) renderDocsNavWithLogo(active string, logoHTML string, spec *openapi.Spec) string {
+	hasEndpoints := hasDocumentedEndpoints(spec)
 	navItems := ""
-	for _, link := range links {
+	for _, section := range docsSections {
+		if section.key == "" || (section.needsEndpoints && !hasEndpoints) {
+			continue
+		}
 		activeClass := ""
-		if link.key == active {
+		if section.key == active {
 			activeClass = "active"
 		}
-		navItems += fmt.Sprintf(`<a href="%s" class="%s">%s</a>`, link.path, activeClass, link.label)
+		navItems += fmt.Sprintf(`<a href="%s" class="%s">%s</a>`, section.path, activeClass, section.label)
 	}
 
 	return fmt.Sprintf(`
@@ -1768,9 +1870,11 @@ func (h *DocsHandler) renderDocsNavWithLogo(active string, logoHTML string) stri
         <div class="docs-header-content">
             <a href="/docs" class="docs-logo">%s Docs</a>
             <nav class="docs-nav">%s</nav>
+            <button type="button" id="theme-toggle" class="theme-toggle" aria-label="Toggle dark mode" title="Toggle dark mode">&#9790;</button>
             <a href="/portal" class="btn btn-sm">Get API Key</a>
         </div>
-    </header>`, logoHTML, navItems)
+    </header>
+    <script>%s</script>`, logoHTML, navItems, docsThemeJS)
 }
 
 // =============================================================================
@@ -1778,74 +1882,103 @@ func (h *DocsHandler) renderDocsNavWithLogo(active string, logoHTML string) stri
 // =============================================================================
 
 const docsCSS = `
+:root {
+    --docs-bg: #fff; --docs-text: #111; --docs-muted: #666; --docs-border: #e5e5e5;
+    --docs-subtle: #f5f5f5; --docs-primary: #111; --docs-code-bg: #111; --docs-code-text: #e5e5e5;
+    --docs-warning-bg: #fffbeb;
+    color-scheme: light;
+}
+:root[data-theme="dark"] {
+    --docs-bg: #0f1115; --docs-text: #e5e7eb; --docs-muted: #9ca3af; --docs-border: #2a2e35;
+    --docs-subtle: #1a1d23; --docs-primary: #1f2329; --docs-code-bg: #07080a; --docs-code-text: #e5e7eb;
+    --docs-warning-bg: #2a2113;
+    color-scheme: dark;
+}
+@media (prefers-color-scheme: dark) {
+    :root:not([data-theme="light"]) {
+        --docs-bg: #0f1115; --docs-text: #e5e7eb; --docs-muted: #9ca3af; --docs-border: #2a2e35;
+        --docs-subtle: #1a1d23; --docs-primary: #1f2329; --docs-code-bg: #07080a; --docs-code-text: #e5e7eb;
+        --docs-warning-bg: #2a2113;
+        color-scheme: dark;
+    }
+}
+
 * { box-sizing: border-box; margin: 0; padding: 0; }
-body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; background: #fff; color: #111; line-height: 1.5; }
+body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; background: var(--docs-bg); color: var(--docs-text); line-height: 1.5; }
 
-.docs-header { background: #111; color: white; padding: 0 24px; position: sticky; top: 0; z-index: 100; }
+.docs-header { background: var(--primary-color, var(--docs-primary)); color: white; padding: 0 24px; position: sticky; top: 0; z-index: 100; }
 .docs-header-content { max-width: 960px; margin: 0 auto; display: flex; align-items: center; gap: 24px; height: 52px; }
 .docs-logo { color: white; text-decoration: none; font-weight: 600; font-size: 16px; }
 .docs-nav { display: flex; gap: 4px; flex: 1; }
 .docs-nav a { color: #999; text-decoration: none; padding: 6px 12px; border-radius: 4px; font-size: 14px; }
 .docs-nav a:hover, .docs-nav a.active { color: white; }
+.theme-toggle { background: transparent; border: 1px solid rgba(255,255,255,0.3); color: white; border-radius: 4px; padding: 4px 8px; cursor: pointer; font-size: 14px; line-height: 1; }
+.theme-toggle:hover { border-color: white; }
 
 .docs-content { max-width: 720px; margin: 0 auto; padding: 48px 24px; }
-.docs-breadcrumb { font-size: 13px; color: #666; margin-bottom: 24px; }
-.docs-breadcrumb a { color: #111; text-decoration: underline; }
+.docs-breadcrumb { font-size: 13px; color: var(--docs-muted); margin-bottom: 24px; }
+.docs-breadcrumb a { color: var(--docs-text); text-decoration: underline; }
 
 .docs-hero { text-align: center; padding: 40px 0; }
 .docs-hero h1 { font-size: 28px; font-weight: 600; margin-bottom: 8px; letter-spacing: -0.02em; }
-.docs-hero p { font-size: 16px; color: #666; }
+.docs-hero p { font-size: 16px; color: var(--docs-muted); }
 
 .docs-cards { display: grid; grid-template-columns: repeat(auto-fit, minmax(200px, 1fr)); gap: 16px; margin-top: 32px; }
-.docs-card { background: #fff; padding: 20px; border-radius: 6px; text-decoration: none; color: inherit; border: 1px solid #e5e5e5; }
-.docs-card:hover { border-color: #111; }
+.docs-card { background: var(--docs-bg); padding: 20px; border-radius: 6px; text-decoration: none; color: inherit; border: 1px solid var(--docs-border); }
+.docs-card:hover { border-color: var(--docs-text); }
 .docs-card h3 { font-size: 15px; font-weight: 500; margin-bottom: 4px; }
-.docs-card p { font-size: 13px; color: #666; }
+.docs-card p { font-size: 13px; color: var(--docs-muted); }
+.docs-card.disabled { opacity: 0.5; cursor: not-allowed; }
+.docs-card.disabled:hover { border-color: var(--docs-border); }
 
-.docs-lead { font-size: 16px; color: #666; margin-bottom: 24px; }
+.docs-lead { font-size: 16px; color: var(--docs-muted); margin-bottom: 24px; }
 .docs-section { margin-bottom: 40px; }
-.docs-section h2 { font-size: 18px; font-weight: 500; margin-bottom: 16px; padding-bottom: 8px; border-bottom: 1px solid #e5e5e5; }
+.docs-section h2 { font-size: 18px; font-weight: 500; margin-bottom: 16px; padding-bottom: 8px; border-bottom: 1px solid var(--docs-border); }
 
 .code-tabs { display: flex; gap: 4px; margin-bottom: 0; }
-.code-tab { padding: 6px 12px; border: none; background: #e5e5e5; cursor: pointer; font-size: 13px; border-radius: 4px 4px 0 0; }
-.code-tab.active { background: #111; color: white; }
+.code-tab { padding: 6px 12px; border: none; background: var(--docs-border); color: var(--docs-text); cursor: pointer; font-size: 13px; border-radius: 4px 4px 0 0; }
+.code-tab.active { background: var(--primary-color, var(--docs-code-bg)); color: white; }
 
-.code-block { background: #111; color: #e5e5e5; padding: 16px; border-radius: 0 4px 4px 4px; overflow-x: auto; font-family: ui-monospace, monospace; font-size: 13px; margin-bottom: 16px; }
+.code-block { background: var(--docs-code-bg); color: var(--docs-code-text); padding: 16px; border-radius: 0 4px 4px 4px; overflow-x: auto; font-family: ui-monospace, monospace; font-size: 13px; margin-bottom: 16px; }
 .code-block.hidden { display: none; }
 .code-block code { color: inherit; background: transparent; padding: 0; }
 
-code { background: #f5f5f5; padding: 2px 6px; border-radius: 3px; font-family: ui-monospace, monospace; font-size: 13px; color: #111; }
+code { background: var(--docs-subtle); padding: 2px 6px; border-radius: 3px; font-family: ui-monospace, monospace; font-size: 13px; color: var(--docs-text); }
 
 .docs-callout { padding: 12px 16px; border-radius: 4px; margin: 16px 0; font-size: 14px; }
-.docs-callout.info { background: #f5f5f5; border-left: 3px solid #111; }
-.docs-callout.warning { background: #fffbeb; border-left: 3px solid #92400e; }
+.docs-callout.info { background: var(--docs-subtle); border-left: 3px solid var(--primary-color, var(--docs-text)); }
+.docs-callout.warning { background: var(--docs-warning-bg); border-left: 3px solid #92400e; }
 
 .docs-table { width: 100%; border-collapse: collapse; margin: 16px 0; font-size: 14px; }
-.docs-table th, .docs-table td { padding: 10px 12px; text-align: left; border-bottom: 1px solid #e5e5e5; }
-.docs-table th { font-weight: 500; color: #666; font-size: 13px; }
+.docs-table th, .docs-table td { padding: 10px 12px; text-align: left; border-bottom: 1px solid var(--docs-border); }
+.docs-table th { font-weight: 500; color: var(--docs-muted); font-size: 13px; }
 
-.btn { display: inline-block; padding: 8px 16px; background: #111; color: white; border: none; border-radius: 4px; cursor: pointer; font-size: 14px; text-decoration: none; }
-.btn:hover { background: #333; }
+.btn { display: inline-block; padding: 8px 16px; background: var(--primary-color, var(--docs-primary)); color: white; border: none; border-radius: 4px; cursor: pointer; font-size: 14px; text-decoration: none; }
+.btn:hover { filter: brightness(1.2); }
 .btn-sm { padding: 6px 12px; font-size: 13px; }
 
 .try-it-console { display: grid; grid-template-columns: 1fr 1fr; gap: 24px; }
-.try-it-form, .try-it-response { background: #fff; padding: 20px; border-radius: 6px; border: 1px solid #e5e5e5; }
+.try-it-form, .try-it-response { background: var(--docs-bg); padding: 20px; border-radius: 6px; border: 1px solid var(--docs-border); }
 .form-group { margin-bottom: 16px; }
 .form-group label { display: block; font-size: 14px; font-weight: 500; margin-bottom: 6px; }
-.form-input { width: 100%; padding: 10px 12px; border: 1px solid #e5e5e5; border-radius: 4px; font-size: 14px; }
-.form-input:focus { outline: none; border-color: #111; }
+.form-input { width: 100%; padding: 10px 12px; border: 1px solid var(--docs-border); border-radius: 4px; font-size: 14px; background: var(--docs-bg); color: var(--docs-text); }
+.form-input:focus { outline: none; border-color: var(--docs-text); }
 .form-row { display: flex; gap: 16px; }
 
 .response-meta { font-size: 13px; margin-bottom: 12px; }
 .status-success { color: #166534; }
 .status-error { color: #991b1b; }
-.response-body { background: #111; color: #e5e5e5; padding: 16px; border-radius: 4px; overflow-x: auto; font-family: ui-monospace, monospace; font-size: 13px; min-height: 200px; white-space: pre-wrap; }
+.response-body { background: var(--docs-code-bg); color: var(--docs-code-text); padding: 16px; border-radius: 4px; overflow-x: auto; font-family: ui-monospace, monospace; font-size: 13px; min-height: 200px; white-space: pre-wrap; }
 
-.endpoint-card { background: #fff; border: 1px solid #e5e5e5; border-radius: 6px; padding: 16px; margin-bottom: 12px; }
-.endpoint-card:hover { border-color: #ccc; }
+.endpoint-card { background: var(--docs-bg); border: 1px solid var(--docs-border); border-radius: 6px; padding: 16px; margin-bottom: 12px; }
+.endpoint-card:hover { border-color: var(--docs-muted); }
 .endpoint-header { display: flex; align-items: center; gap: 10px; margin-bottom: 8px; }
-.endpoint-path { font-family: ui-monospace, monospace; font-size: 14px; color: #111; background: #f5f5f5; padding: 4px 8px; border-radius: 4px; }
-.endpoint-desc { color: #666; font-size: 14px; margin-bottom: 12px; }
+.endpoint-path { font-family: ui-monospace, monospace; font-size: 14px; color: var(--docs-text); background: var(--docs-subtle); padding: 4px 8px; border-radius: 4px; }
+.endpoint-desc { color: var(--docs-muted); font-size: 14px; margin-bottom: 12px; }
+.endpoint-anchor { margin-left: auto; color: var(--docs-muted); text-decoration: none; font-size: 14px; }
+.endpoint-anchor:hover { color: var(--docs-text); }
+.endpoint-card:target { border-color: var(--primary-color, var(--docs-text)); }
+.endpoint-search { margin-bottom: 16px; }
 
 .method-badge { display: inline-block; padding: 3px 8px; border-radius: 3px; font-size: 11px; font-weight: 600; text-transform: uppercase; font-family: ui-monospace, monospace; }
 .method-get { background: #dcfce7; color: #166534; }
@@ -1854,8 +1987,8 @@ code { background: #f5f5f5; padding: 2px 6px; border-radius: 3px; font-family: u
 .method-patch { background: #fef3c7; color: #92400e; }
 .method-delete { background: #fee2e2; color: #991b1b; }
 
-.example-section { margin-top: 12px; padding-top: 12px; border-top: 1px solid #f0f0f0; }
-.example-section h5 { font-size: 12px; font-weight: 500; color: #666; margin-bottom: 8px; text-transform: uppercase; letter-spacing: 0.05em; }
+.example-section { margin-top: 12px; padding-top: 12px; border-top: 1px solid var(--docs-border); }
+.example-section h5 { font-size: 12px; font-weight: 500; color: var(--docs-muted); margin-bottom: 8px; text-transform: uppercase; letter-spacing: 0.05em; }
 .example-section .code-block { margin-bottom: 0; border-radius: 4px; }
 
 @media (max-width: 768px) {
@@ -1864,6 +1997,31 @@ code { background: #f5f5f5; padding: 2px 6px; border-radius: 3px; font-family: u
 }
 `
 
+// docsThemeJS applies the saved theme (or the system preference on first
+// visit) and wires up the header's theme toggle. It runs inline in the header
+// so the page doesn't flash in the wrong theme.
+const docsThemeJS = `
+(function() {
+    const root = document.documentElement;
+    const saved = localStorage.getItem('docs-theme');
+    const prefersDark = window.matchMedia && window.matchMedia('(prefers-color-scheme: dark)').matches;
+    root.dataset.theme = saved || (prefersDark ? 'dark' : 'light');
+
+    const toggle = document.getElementById('theme-toggle');
+    const label = () => {
+        const dark = root.dataset.theme === 'dark';
+        toggle.textContent = dark ? '\u2600' : '\u263E';
+        toggle.setAttribute('aria-pressed', dark);
+    };
+    label();
+    toggle.addEventListener('click', () => {
+        root.dataset.theme = root.dataset.theme === 'dark' ? 'light' : 'dark';
+        localStorage.setItem('docs-theme', root.dataset.theme);
+        label();
+    });
+})();
+`
+
 const docsJS = `
 document.querySelectorAll('.code-tab').forEach(tab => {
     tab.addEventListener('click', () => {
@@ -6,6 +6,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/artpar/apigate/core/openapi"
+	"github.com/artpar/apigate/domain/settings"
 	"github.com/go-chi/chi/v5"
 	"github.com/rs/zerolog"
 )
@@ -56,6 +58,8 @@ func TestDocsHandler_Router(t *testing.T) {
 		{"GET", "/try-it"},
 		{"GET", "/openapi.json"},
 		{"GET", "/openapi.yaml"},
+		{"GET", "/postman.json"},
+		{"GET", "/api-reference.md"},
 	}
 
 	for _, rt := range routes {
@@ -101,6 +105,34 @@ func TestDocsHandler_DocsHome(t *testing.T) {
 	}
 }
 
+func TestDocsHandler_DocsHome_ThemeToggle(t *testing.T) {
+	store := newMockSettingsStore()
+	store.settings[settings.KeyCustomPrimaryColor] = "#7c3aed"
+	h := NewDocsHandler(DocsDeps{Settings: store, Logger: zerolog.Nop(), AppName: "TestAPI"})
+
+	w := httptest.NewRecorder()
+	h.DocsHome(w, httptest.NewRequest("GET", "/docs", nil))
+
+	body := w.Body.String()
+	for _, want := range []string{
+		// Toggle markup and its script
+		`id="theme-toggle"`,
+		`aria-label="Toggle dark mode"`,
+		`localStorage.setItem('docs-theme'`,
+		// Dark variant, chosen explicitly or by the system preference
+		`:root[data-theme="dark"]`,
+		`@media (prefers-color-scheme: dark)`,
+		`--docs-bg: #0f1115`,
+		// Custom primary color applies in both themes
+		`:root { --primary-color: #7c3aed; }`,
+		`background: var(--primary-color, var(--docs-primary))`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("home page missing %q", want)
+		}
+	}
+}
+
 func TestDocsHandler_QuickstartPage(t *testing.T) {
 	h := newTestDocsHandler()
 
@@ -164,6 +196,56 @@ func TestDocsHandler_APIReferencePage(t *testing.T) {
 	}
 }
 
+func TestDocsHandler_APIReferenceSearch(t *testing.T) {
+	h := newTestDocsHandler()
+	spec := &openapi.Spec{Paths: map[string]openapi.PathItem{
+		"/orders": {
+			Get:  &openapi.Operation{Summary: "List orders", Description: "Returns every order"},
+			Post: &openapi.Operation{Summary: "Create order"},
+		},
+		"/orders/{id}": {Delete: &openapi.Operation{Summary: "Cancel order"}},
+	}}
+
+	body := h.renderAPIReference(spec)
+
+	for _, want := range []string{
+		`<input type="search" id="endpoint-search"`,
+		`id="get-orders" data-search="get /orders list orders returns every order"`,
+		`<a href="#get-orders" class="endpoint-anchor"`,
+		`id="post-orders"`,
+		`<a href="#post-orders" class="endpoint-anchor"`,
+		`id="delete-orders-id"`,
+		`<a href="#delete-orders-id" class="endpoint-anchor"`,
+		`getElementById('endpoint-search')`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("API reference missing %q", want)
+		}
+	}
+
+	// No search box when there is nothing to search
+	empty := h.renderAPIReference(&openapi.Spec{Paths: map[string]openapi.PathItem{}})
+	if strings.Contains(empty, `id="endpoint-search"`) {
+		t.Error("search input should not render without endpoints")
+	}
+}
+
+func TestEndpointAnchor(t *testing.T) {
+	tests := []struct {
+		method, path, want string
+	}{
+		{"GET", "/users", "get-users"},
+		{"DELETE", "/users/{id}/keys", "delete-users-id-keys"},
+		{"POST", "/", "post"},
+		{"PATCH", "/v1/Items_list", "patch-v1-items-list"},
+	}
+	for _, tt := range tests {
+		if got := endpointAnchor(tt.method, tt.path); got != tt.want {
+			t.Errorf("endpointAnchor(%q, %q) = %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
 func TestDocsHandler_ExamplesPage(t *testing.T) {
 	h := newTestDocsHandler()
 
@@ -186,6 +268,66 @@ func TestDocsHandler_ExamplesPage(t *testing.T) {
 	}
 }
 
+func TestDocsHandler_ExamplesPage_PerEndpointSnippets(t *testing.T) {
+	h := newTestDocsHandler()
+	spec := &openapi.Spec{Paths: map[string]openapi.PathItem{
+		"/orders": {
+			Get: &openapi.Operation{Summary: "List orders"},
+			Post: &openapi.Operation{
+				Summary: "Create order",
+				RequestBody: &openapi.RequestBody{Content: map[string]openapi.MediaType{
+					"application/json": {Example: map[string]any{"sku": "ABC-1", "gift": true}},
+				}},
+			},
+		},
+		"/orders/{id}":  {Delete: &openapi.Operation{Summary: "Cancel order"}},
+		"/proxy/{path}": {Get: &openapi.Operation{Summary: "Wildcard"}},
+	}}
+
+	body := h.renderExamples("https://api.example.com", spec)
+
+	for _, want := range []string{
+		// cURL
+		`curl -X GET &#34;https://api.example.com/orders&#34;`,
+		`curl -X POST &#34;https://api.example.com/orders&#34;`,
+		`curl -X DELETE &#34;https://api.example.com/orders/{id}&#34;`,
+		// JavaScript
+		`fetch(&#39;https://api.example.com/orders&#39;, {
+  method: &#39;POST&#39;`,
+		`fetch(&#39;https://api.example.com/orders/{id}&#39;, {
+  method: &#39;DELETE&#39;`,
+		// Python
+		`&#39;GET&#39;,
+    &#39;https://api.example.com/orders&#39;`,
+		`&#39;DELETE&#39;,
+    &#39;https://api.example.com/orders/{id}&#39;`,
+		// Go
+		`http.NewRequest(&#34;GET&#34;, &#34;https://api.example.com/orders&#34;, nil)`,
+		`http.NewRequest(&#34;DELETE&#34;, &#34;https://api.example.com/orders/{id}&#34;, nil)`,
+		// Example body in each language
+		`-d &#39;{
+  &#34;gift&#34;: true,
+  &#34;sku&#34;: &#34;ABC-1&#34;
+}&#39;`,
+		`&#34;gift&#34;: True,`,
+		`strings.NewReader(`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Examples page missing %q", want)
+		}
+	}
+
+	if strings.Contains(body, "/proxy/{path}") {
+		t.Error("wildcard routes should not get snippets")
+	}
+	if strings.Contains(body, "No documented endpoints yet") {
+		t.Error("warning should only show when there are no endpoints")
+	}
+	if got := strings.Count(body, `<div class="docs-section">`); got != 4 {
+		t.Errorf("sections = %d, want one per operation plus error handling", got)
+	}
+}
+
 func TestDocsHandler_TryItPage(t *testing.T) {
 	h := newTestDocsHandler()
 
@@ -208,6 +350,87 @@ func TestDocsHandler_TryItPage(t *testing.T) {
 	}
 }
 
+func TestDocsHandler_TryItPage_CopyAsCode(t *testing.T) {
+	h := newTestDocsHandler()
+	spec := &openapi.Spec{Paths: map[string]openapi.PathItem{
+		"/orders": {Post: &openapi.Operation{
+			Summary: "Create order",
+			RequestBody: &openapi.RequestBody{Content: map[string]openapi.MediaType{
+				"application/json": {Example: map[string]any{"sku": "ABC-1"}},
+			}},
+		}},
+	}}
+
+	body := h.renderTryIt("https://api.example.com", spec)
+
+	for _, lang := range []string{"curl", "javascript", "python", "go"} {
+		if !strings.Contains(body, `class="btn btn-secondary btn-icon copy-code" data-lang="`+lang+`"`) {
+			t.Errorf("missing copy button for %s", lang)
+		}
+		if !strings.Contains(body, `<option value="`+lang+`">`) {
+			t.Errorf("missing language option for %s", lang)
+		}
+	}
+
+	// The current request is rendered in every language
+	for _, want := range []string{
+		`curl -X POST &#34;https://api.example.com/orders&#34;`,
+		`fetch(&#39;https://api.example.com/orders&#39;, {
+  method: &#39;POST&#39;`,
+		`&#39;POST&#39;,
+    &#39;https://api.example.com/orders&#39;`,
+		`http.NewRequest(&#34;POST&#34;, &#34;https://api.example.com/orders&#34;`,
+		`const snippetGenerators = {`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Try It page missing %q", want)
+		}
+	}
+}
+
+func TestDocsHandler_TryItPage_RememberKey(t *testing.T) {
+	h := newTestDocsHandler()
+
+	body := h.renderTryIt("https://api.example.com", &openapi.Spec{Paths: map[string]openapi.PathItem{}})
+
+	for _, want := range []string{
+		// Opt-in checkbox, forget button and warning
+		`<input type="checkbox" id="rememberKey">`,
+		`<button type="button" id="forgetKey"`,
+		`stored unencrypted in this browser's local storage`,
+		// A remembered key pre-fills the field and ticks the checkbox
+		`const storedKey = localStorage.getItem('docs-api-key');
+        if (storedKey) {
+            apiKeyInput.value = storedKey;
+            rememberKey.checked = true;`,
+		// Forgetting removes it
+		`localStorage.removeItem('docs-api-key');`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Try It page missing %q", want)
+		}
+	}
+}
+
+func TestDocsHandler_TryItPage_History(t *testing.T) {
+	h := newTestDocsHandler()
+
+	body := h.renderTryIt("https://api.example.com", &openapi.Spec{Paths: map[string]openapi.PathItem{}})
+
+	for _, want := range []string{
+		`<div class="request-history" id="requestHistory">`,
+		`<ul class="history-list" id="historyList"></ul>`,
+		`<button type="button" id="clearHistory"`,
+		`id="historyEmpty"`,
+		`localStorage.setItem('docs-request-history'`,
+		`recordHistory(method, endpoint, bodyInput, response.status);`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Try It page missing %q", want)
+		}
+	}
+}
+
 func TestDocsHandler_OpenAPISpec(t *testing.T) {
 	h := newTestDocsHandler()
 
@@ -249,6 +472,62 @@ func TestDocsHandler_OpenAPISpecYAML(t *testing.T) {
 	if contentType != "application/x-yaml" {
 		t.Errorf("Content-Type = %s, want application/x-yaml", contentType)
 	}
+
+	body := w.Body.String()
+	if strings.HasPrefix(strings.TrimSpace(body), "{") {
+		t.Error("Body should be YAML, not JSON")
+	}
+	if !strings.Contains(body, "openapi: 3.0.3") {
+		t.Errorf("Body should contain 'openapi: 3.0.3', got:\n%s", body)
+	}
+}
+
+func TestDocsHandler_PostmanCollection(t *testing.T) {
+	h := newTestDocsHandler()
+
+	req := httptest.NewRequest("GET", "/docs/postman.json", nil)
+	req.Host = "example.com"
+	w := httptest.NewRecorder()
+
+	h.PostmanCollection(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("Content-Type = %s, want application/json", contentType)
+	}
+
+	body := w.Body.String()
+	for _, want := range []string{
+		"https://schema.getpostman.com/json/collection/v2.1.0/collection.json",
+		`"X-API-Key"`,
+		"http://example.com",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Body should contain %s", want)
+		}
+	}
+}
+
+func TestDocsHandler_APIReferenceMarkdown(t *testing.T) {
+	h := newTestDocsHandler()
+
+	req := httptest.NewRequest("GET", "/docs/api-reference.md", nil)
+	req.Host = "example.com"
+	w := httptest.NewRecorder()
+
+	h.APIReferenceMarkdown(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "text/markdown; charset=utf-8" {
+		t.Errorf("Content-Type = %s, want text/markdown", contentType)
+	}
+	if body := w.Body.String(); !strings.HasPrefix(body, "# TestAPI API\n") {
+		t.Errorf("Body should start with the API title, got:\n%s", body)
+	}
 }
 
 func TestDocsHandler_GetBaseURL_HTTP(t *testing.T) {
@@ -278,12 +557,15 @@ func TestDocsHandler_GetBaseURL_HTTPS_Header(t *testing.T) {
 
 func TestDocsHandler_RenderDocsNav(t *testing.T) {
 	h := newTestDocsHandler()
+	spec := &openapi.Spec{Paths: map[string]openapi.PathItem{
+		"/orders": {Get: &openapi.Operation{Summary: "List orders"}},
+	}}
 
 	tests := []string{"home", "quickstart", "authentication", "api-reference", "examples", "try-it"}
 
 	for _, active := range tests {
 		t.Run(active, func(t *testing.T) {
-			nav := h.renderDocsNav(active)
+			nav := h.renderDocsNav(active, spec)
 
 			if !strings.Contains(nav, "TestAPI Docs") {
 				t.Error("Nav should contain app name")
@@ -295,6 +577,50 @@ func TestDocsHandler_RenderDocsNav(t *testing.T) {
 	}
 }
 
+func TestDocsHandler_SectionsFollowDocumentedEndpoints(t *testing.T) {
+	h := newTestDocsHandler()
+
+	t.Run("no endpoints", func(t *testing.T) {
+		spec := &openapi.Spec{Paths: map[string]openapi.PathItem{}}
+		home := h.renderDocsHome(spec)
+
+		if strings.Contains(home, `href="/docs/try-it"`) {
+			t.Error("Try It should not be linked when no endpoints are documented")
+		}
+		if !strings.Contains(home, `<div class="docs-card disabled" aria-disabled="true">
+                <h3>Try It</h3>`) {
+			t.Error("Try It card should be shown disabled")
+		}
+		for _, path := range []string{"/docs/api-reference", "/docs/examples", "/docs/postman.json", "/docs/api-reference.md"} {
+			if strings.Contains(home, `href="`+path+`"`) {
+				t.Errorf("%s should not be linked when no endpoints are documented", path)
+			}
+		}
+		for _, path := range []string{"/docs/quickstart", "/docs/authentication", "/docs/openapi.json"} {
+			if !strings.Contains(home, `href="`+path+`"`) {
+				t.Errorf("%s should always be linked", path)
+			}
+		}
+	})
+
+	t.Run("with endpoints", func(t *testing.T) {
+		spec := &openapi.Spec{Paths: map[string]openapi.PathItem{
+			"/orders": {Post: &openapi.Operation{Summary: "Create order"}},
+		}}
+		home := h.renderDocsHome(spec)
+
+		if !strings.Contains(home, `<a href="/docs/try-it" class="">Try It</a>`) {
+			t.Error("nav should link to Try It")
+		}
+		if !strings.Contains(home, `<a href="/docs/try-it" class="docs-card">`) {
+			t.Error("Try It card should be shown")
+		}
+		if strings.Contains(home, "docs-card disabled") {
+			t.Error("no card should be disabled")
+		}
+	})
+}
+
 func TestDocsHandler_RenderEndpoint(t *testing.T) {
 	h := newTestDocsHandler()
 
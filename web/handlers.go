@@ -15,6 +15,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/artpar/apigate/domain/audit"
 	domainAuth "github.com/artpar/apigate/domain/auth"
 	"github.com/artpar/apigate/domain/key"
 	"github.com/artpar/apigate/domain/route"
@@ -446,7 +447,7 @@ func (h *Handler) Dashboard(w http.ResponseWriter, r *http.Request) {
 	for _, u := range users {
 		keys, _ := h.keys.ListByUser(ctx, u.ID)
 		for _, k := range keys {
-			if k.RevokedAt == nil {
+			if !key.IsRevoked(k, time.Now()) {
 				firstKeyValue = k.Prefix + "..." // We don't have the full key, show prefix
 				break
 			}
@@ -631,7 +632,7 @@ func (h *Handler) countActiveKeys(ctx context.Context, users []ports.User) int {
 	for _, u := range users {
 		keys, _ := h.keys.ListByUser(ctx, u.ID)
 		for _, k := range keys {
-			if k.RevokedAt == nil {
+			if !key.IsRevoked(k, time.Now()) {
 				count++
 			}
 		}
@@ -681,6 +682,12 @@ type PlanInfo struct {
 	Name                string
 	Description         string
 	RateLimit           int
+	RateBurst           int
+	MaxConcurrent       int
+	SpikeThreshold      float64
+	SpikeMinRequests    int
+	SpikeThrottleRPM    int
+	AllowOverage        bool
 	MonthlyQuota        int64
 	PriceMonthly        float64
 	OveragePrice        float64
@@ -691,6 +698,7 @@ type PlanInfo struct {
 	Enabled             bool
 	MeterType           string
 	EstimatedCostPerReq float64
+	QuotaPeriod         string
 }
 
 // getPlans returns plans from database.
@@ -721,11 +729,21 @@ func planToInfo(p ports.Plan) PlanInfo {
 	if estimatedCost <= 0 {
 		estimatedCost = 1.0
 	}
+	quotaPeriod := string(p.QuotaPeriod)
+	if quotaPeriod == "" {
+		quotaPeriod = "calendar_month"
+	}
 	return PlanInfo{
 		ID:                  p.ID,
 		Name:                p.Name,
 		Description:         p.Description,
 		RateLimit:           p.RateLimitPerMinute,
+		RateBurst:           p.RateLimitBurst,
+		MaxConcurrent:       p.MaxConcurrent,
+		SpikeThreshold:      p.SpikeThreshold,
+		SpikeMinRequests:    p.SpikeMinRequests,
+		SpikeThrottleRPM:    p.SpikeThrottleRPM,
+		AllowOverage:        p.AllowOverage,
 		MonthlyQuota:        p.RequestsPerMonth,
 		PriceMonthly:        float64(p.PriceMonthly) / 100,
 		OveragePrice:        float64(p.OveragePrice) / 10000,
@@ -736,6 +754,7 @@ func planToInfo(p ports.Plan) PlanInfo {
 		Enabled:             p.Enabled,
 		MeterType:           meterType,
 		EstimatedCostPerReq: estimatedCost,
+		QuotaPeriod:         quotaPeriod,
 	}
 }
 
@@ -773,6 +792,7 @@ func (h *Handler) UserCreate(w http.ResponseWriter, r *http.Request) {
 		h.renderUserFormError(w, r, "Failed to create user", "", email, planID, status)
 		return
 	}
+	h.recordAudit(r, audit.ActionCreate, audit.ResourceUser, user.ID, nil, audit.Snapshot(user))
 
 	http.Redirect(w, r, "/users", http.StatusFound)
 }
@@ -824,6 +844,7 @@ func (h *Handler) UserUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before := audit.Snapshot(user)
 	user.PlanID = r.FormValue("plan_id")
 	user.Status = r.FormValue("status")
 	user.UpdatedAt = time.Now().UTC()
@@ -832,6 +853,7 @@ func (h *Handler) UserUpdate(w http.ResponseWriter, r *http.Request) {
 		h.renderUserFormError(w, r, "Failed to update user", id, user.Email, user.PlanID, user.Status)
 		return
 	}
+	h.recordAudit(r, audit.ActionUpdate, audit.ResourceUser, user.ID, before, audit.Snapshot(user))
 
 	http.Redirect(w, r, "/users", http.StatusFound)
 }
@@ -840,11 +862,13 @@ func (h *Handler) UserUpdate(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) UserDelete(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	id := chi.URLParam(r, "id")
+	before, _ := h.users.Get(ctx, id)
 
 	if err := h.users.Delete(ctx, id); err != nil {
 		http.Error(w, "Failed to delete user", http.StatusInternalServerError)
 		return
 	}
+	h.recordAudit(r, audit.ActionDelete, audit.ResourceUser, id, audit.Snapshot(before), nil)
 
 	// For HTMX, return updated users list
 	if r.Header.Get("HX-Request") == "true" {
@@ -918,6 +942,7 @@ func (h *Handler) KeyCreate(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to create key", http.StatusInternalServerError)
 		return
 	}
+	h.recordAudit(r, audit.ActionCreate, audit.ResourceKey, keyData.ID, nil, audit.Snapshot(keyData))
 
 	// Show the key to the user (only time it's visible)
 	users, _ := h.users.List(ctx, 1000, 0)
@@ -941,10 +966,12 @@ func (h *Handler) KeyRevoke(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	id := chi.URLParam(r, "id")
 
-	if err := h.keys.Revoke(ctx, id, time.Now().UTC()); err != nil {
+	now := time.Now().UTC()
+	if err := h.keys.Revoke(ctx, id, now); err != nil {
 		http.Error(w, "Key not found", http.StatusNotFound)
 		return
 	}
+	h.recordAudit(r, audit.ActionRevoke, audit.ResourceKey, id, nil, audit.Snapshot(struct{ RevokedAt time.Time }{now}))
 
 	// For HTMX, return updated keys list
 	if r.Header.Get("HX-Request") == "true" {
@@ -1009,6 +1036,7 @@ func (h *Handler) PlanNewPage(w http.ResponseWriter, r *http.Request) {
 	data.FormPlan.MonthlyQuota = 1000
 	data.FormPlan.MeterType = "requests"
 	data.FormPlan.EstimatedCostPerReq = 1.0
+	data.FormPlan.QuotaPeriod = "calendar_month"
 
 	h.render(w, "plan_form", data)
 }
@@ -1029,6 +1057,11 @@ func (h *Handler) PlanCreate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	rateLimit, _ := strconv.Atoi(r.FormValue("rate_limit"))
+	rateBurst, _ := strconv.Atoi(r.FormValue("rate_burst"))
+	maxConcurrent, _ := strconv.Atoi(r.FormValue("max_concurrent"))
+	spikeThreshold, _ := strconv.ParseFloat(r.FormValue("spike_threshold"), 64)
+	spikeMinRequests, _ := strconv.Atoi(r.FormValue("spike_min_requests"))
+	spikeThrottleRPM, _ := strconv.Atoi(r.FormValue("spike_throttle_rpm"))
 	monthlyQuota, _ := strconv.ParseInt(r.FormValue("monthly_quota"), 10, 64)
 	priceMonthly, _ := strconv.ParseFloat(r.FormValue("price_monthly"), 64)
 	overagePrice, _ := strconv.ParseFloat(r.FormValue("overage_price"), 64)
@@ -1042,11 +1075,22 @@ func (h *Handler) PlanCreate(w http.ResponseWriter, r *http.Request) {
 		meterType = ports.MeterTypeRequests
 	}
 
+	quotaPeriod := ports.QuotaPeriod(r.FormValue("quota_period"))
+	if quotaPeriod == "" {
+		quotaPeriod = ports.QuotaPeriodCalendarMonth
+	}
+
 	plan := ports.Plan{
 		ID:                  id,
 		Name:                name,
 		Description:         r.FormValue("description"),
 		RateLimitPerMinute:  rateLimit,
+		RateLimitBurst:      rateBurst,
+		MaxConcurrent:       maxConcurrent,
+		SpikeThreshold:      spikeThreshold,
+		SpikeMinRequests:    spikeMinRequests,
+		SpikeThrottleRPM:    spikeThrottleRPM,
+		AllowOverage:        r.FormValue("allow_overage") == "on",
 		RequestsPerMonth:    monthlyQuota,
 		PriceMonthly:        int64(priceMonthly * 100), // Convert to cents
 		OveragePrice:        int64(overagePrice * 10000), // Convert to hundredths of cents
@@ -1057,6 +1101,7 @@ func (h *Handler) PlanCreate(w http.ResponseWriter, r *http.Request) {
 		Enabled:             r.FormValue("enabled") == "on",
 		MeterType:           meterType,
 		EstimatedCostPerReq: estimatedCost,
+		QuotaPeriod:         quotaPeriod,
 	}
 
 	// Clear default flag on existing plans if creating a new default plan
@@ -1074,6 +1119,7 @@ func (h *Handler) PlanCreate(w http.ResponseWriter, r *http.Request) {
 		h.renderPlanFormError(w, r, "Failed to create plan: "+err.Error(), "", planToInfo(plan))
 		return
 	}
+	h.recordAudit(r, audit.ActionCreate, audit.ResourcePlan, plan.ID, nil, audit.Snapshot(plan))
 
 	http.Redirect(w, r, "/plans", http.StatusFound)
 }
@@ -1116,6 +1162,11 @@ func (h *Handler) PlanUpdate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	rateLimit, _ := strconv.Atoi(r.FormValue("rate_limit"))
+	rateBurst, _ := strconv.Atoi(r.FormValue("rate_burst"))
+	maxConcurrent, _ := strconv.Atoi(r.FormValue("max_concurrent"))
+	spikeThreshold, _ := strconv.ParseFloat(r.FormValue("spike_threshold"), 64)
+	spikeMinRequests, _ := strconv.Atoi(r.FormValue("spike_min_requests"))
+	spikeThrottleRPM, _ := strconv.Atoi(r.FormValue("spike_throttle_rpm"))
 	monthlyQuota, _ := strconv.ParseInt(r.FormValue("monthly_quota"), 10, 64)
 	priceMonthly, _ := strconv.ParseFloat(r.FormValue("price_monthly"), 64)
 	overagePrice, _ := strconv.ParseFloat(r.FormValue("overage_price"), 64)
@@ -1129,9 +1180,21 @@ func (h *Handler) PlanUpdate(w http.ResponseWriter, r *http.Request) {
 		meterType = ports.MeterTypeRequests
 	}
 
+	quotaPeriod := ports.QuotaPeriod(r.FormValue("quota_period"))
+	if quotaPeriod == "" {
+		quotaPeriod = ports.QuotaPeriodCalendarMonth
+	}
+
+	before := audit.Snapshot(plan)
 	plan.Name = r.FormValue("name")
 	plan.Description = r.FormValue("description")
 	plan.RateLimitPerMinute = rateLimit
+	plan.RateLimitBurst = rateBurst
+	plan.MaxConcurrent = maxConcurrent
+	plan.SpikeThreshold = spikeThreshold
+	plan.SpikeMinRequests = spikeMinRequests
+	plan.SpikeThrottleRPM = spikeThrottleRPM
+	plan.AllowOverage = r.FormValue("allow_overage") == "on"
 	plan.RequestsPerMonth = monthlyQuota
 	plan.PriceMonthly = int64(priceMonthly * 100)
 	plan.OveragePrice = int64(overagePrice * 10000) // Convert to hundredths of cents
@@ -1142,6 +1205,7 @@ func (h *Handler) PlanUpdate(w http.ResponseWriter, r *http.Request) {
 	plan.Enabled = r.FormValue("enabled") == "on"
 	plan.MeterType = meterType
 	plan.EstimatedCostPerReq = estimatedCost
+	plan.QuotaPeriod = quotaPeriod
 
 	// Clear default flag on other plans if setting this plan as default
 	if newIsDefault && !plan.IsDefault {
@@ -1159,6 +1223,7 @@ func (h *Handler) PlanUpdate(w http.ResponseWriter, r *http.Request) {
 		h.renderPlanFormError(w, r, "Failed to update plan: "+err.Error(), id, planToInfo(plan))
 		return
 	}
+	h.recordAudit(r, audit.ActionUpdate, audit.ResourcePlan, plan.ID, before, audit.Snapshot(plan))
 
 	http.Redirect(w, r, "/plans", http.StatusFound)
 }
@@ -1181,6 +1246,7 @@ func (h *Handler) PlanDelete(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	before, _ := h.plans.Get(ctx, id)
 	if err := h.plans.Delete(ctx, id); err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -1189,6 +1255,7 @@ func (h *Handler) PlanDelete(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	h.recordAudit(r, audit.ActionDelete, audit.ResourcePlan, id, audit.Snapshot(before), nil)
 
 	// For HTMX, return updated plans list
 	if r.Header.Get("HX-Request") == "true" {
@@ -1326,7 +1393,7 @@ func (h *Handler) UsagePage(w http.ResponseWriter, r *http.Request) {
 		// Get keys for this user to build usage data
 		keys, _ := h.keys.ListByUser(ctx, u.ID)
 		for _, k := range keys {
-			if k.RevokedAt != nil {
+			if key.IsRevoked(k, time.Now()) {
 				continue
 			}
 			// Get recent requests for this key
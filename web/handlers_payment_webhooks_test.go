@@ -6,11 +6,15 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/artpar/apigate/adapters/payment"
 	"github.com/artpar/apigate/domain/billing"
 	"github.com/rs/zerolog"
+	stripewebhook "github.com/stripe/stripe-go/v76/webhook"
 )
 
 // Mock payment provider for webhook tests
@@ -1024,3 +1028,109 @@ func TestPaymentWebhookHandler_extractSubscriptionID_PaddleFallback(t *testing.T
 		t.Errorf("expected sub_fallback, got %s", subscriptionID)
 	}
 }
+
+// signStripeFixture loads a Stripe event fixture and signs it the way Stripe
+// does, returning the body and the Stripe-Signature header value.
+func signStripeFixture(t *testing.T, name, secret string) ([]byte, string) {
+	t.Helper()
+	payload, err := os.ReadFile(filepath.Join("testdata", "stripe", name))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	signed := stripewebhook.GenerateTestSignedPayload(&stripewebhook.UnsignedPayload{
+		Payload:   payload,
+		Secret:    secret,
+		Timestamp: time.Now(),
+	})
+	return signed.Payload, signed.Header
+}
+
+func TestPaymentWebhookHandler_StripeSignedFixtures(t *testing.T) {
+	const secret = "whsec_fixture_secret"
+
+	t.Run("customer.subscription.deleted", func(t *testing.T) {
+		handler := &mockPaymentWebhookHandler{}
+		h := NewPaymentWebhookHandler(payment.NewStripeProvider(payment.StripeConfig{WebhookSecret: secret}), handler, zerolog.Nop())
+
+		body, sig := signStripeFixture(t, "customer.subscription.deleted.json", secret)
+		req := httptest.NewRequest("POST", "/stripe", bytes.NewReader(body))
+		req.Header.Set("Stripe-Signature", sig)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if handler.subscriptionCancelledCalls != 1 {
+			t.Errorf("expected 1 subscription cancelled call, got %d", handler.subscriptionCancelledCalls)
+		}
+		if handler.lastSubscriptionID != "sub_1PdeletedSub0001" {
+			t.Errorf("expected subscription sub_1PdeletedSub0001, got %s", handler.lastSubscriptionID)
+		}
+	})
+
+	t.Run("invoice.payment_failed", func(t *testing.T) {
+		handler := &mockPaymentWebhookHandler{}
+		h := NewPaymentWebhookHandler(payment.NewStripeProvider(payment.StripeConfig{WebhookSecret: secret}), handler, zerolog.Nop())
+
+		body, sig := signStripeFixture(t, "invoice.payment_failed.json", secret)
+		req := httptest.NewRequest("POST", "/stripe", bytes.NewReader(body))
+		req.Header.Set("Stripe-Signature", sig)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if handler.invoiceFailedCalls != 1 {
+			t.Errorf("expected 1 invoice failed call, got %d", handler.invoiceFailedCalls)
+		}
+		if handler.lastInvoiceID != "in_1PfailedInv0001" {
+			t.Errorf("expected invoice in_1PfailedInv0001, got %s", handler.lastInvoiceID)
+		}
+		if handler.lastCustomerID != "cus_Qfixture0001" {
+			t.Errorf("expected customer cus_Qfixture0001, got %s", handler.lastCustomerID)
+		}
+	})
+
+	t.Run("wrong secret is rejected", func(t *testing.T) {
+		handler := &mockPaymentWebhookHandler{}
+		h := NewPaymentWebhookHandler(payment.NewStripeProvider(payment.StripeConfig{WebhookSecret: secret}), handler, zerolog.Nop())
+
+		body, sig := signStripeFixture(t, "customer.subscription.deleted.json", "whsec_other")
+		req := httptest.NewRequest("POST", "/stripe", bytes.NewReader(body))
+		req.Header.Set("Stripe-Signature", sig)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, req)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %d", w.Code)
+		}
+		if handler.subscriptionCancelledCalls != 0 {
+			t.Errorf("expected no subscription cancelled calls, got %d", handler.subscriptionCancelledCalls)
+		}
+	})
+
+	t.Run("tampered payload is rejected", func(t *testing.T) {
+		handler := &mockPaymentWebhookHandler{}
+		h := NewPaymentWebhookHandler(payment.NewStripeProvider(payment.StripeConfig{WebhookSecret: secret}), handler, zerolog.Nop())
+
+		body, sig := signStripeFixture(t, "invoice.payment_failed.json", secret)
+		body = bytes.Replace(body, []byte("cus_Qfixture0001"), []byte("cus_Qattacker001"), 1)
+		req := httptest.NewRequest("POST", "/stripe", bytes.NewReader(body))
+		req.Header.Set("Stripe-Signature", sig)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, req)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %d", w.Code)
+		}
+		if handler.invoiceFailedCalls != 0 {
+			t.Errorf("expected no invoice failed calls, got %d", handler.invoiceFailedCalls)
+		}
+	})
+}
@@ -68,6 +68,8 @@ func (h *Handler) RouteCreate(w http.ResponseWriter, r *http.Request) {
 		Description:     r.FormValue("description"),
 		ExampleRequest:  r.FormValue("example_request"),
 		ExampleResponse: r.FormValue("example_response"),
+		HostPattern:     strings.TrimSpace(r.FormValue("host_pattern")),
+		HostMatchType:   route.HostMatchType(r.FormValue("host_match_type")),
 		PathPattern:     r.FormValue("path_pattern"),
 		MatchType:       route.MatchType(r.FormValue("match_type")),
 		Methods:         parseCSV(r.FormValue("methods")),
@@ -94,6 +96,11 @@ func (h *Handler) RouteCreate(w http.ResponseWriter, r *http.Request) {
 	rt.RequestTransform = parseTransform(r, "request_")
 	rt.ResponseTransform = parseTransform(r, "response_")
 
+	if err := route.ValidateHostPattern(rt.HostPattern, rt.HostMatchType); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	if err := h.routes.Create(r.Context(), rt); err != nil {
 		http.Error(w, "Failed to create route", http.StatusInternalServerError)
 		return
@@ -152,6 +159,8 @@ func (h *Handler) RouteUpdate(w http.ResponseWriter, r *http.Request) {
 		Description:     r.FormValue("description"),
 		ExampleRequest:  r.FormValue("example_request"),
 		ExampleResponse: r.FormValue("example_response"),
+		HostPattern:     strings.TrimSpace(r.FormValue("host_pattern")),
+		HostMatchType:   route.HostMatchType(r.FormValue("host_match_type")),
 		PathPattern:     r.FormValue("path_pattern"),
 		MatchType:       route.MatchType(r.FormValue("match_type")),
 		Methods:         parseCSV(r.FormValue("methods")),
@@ -178,6 +187,11 @@ func (h *Handler) RouteUpdate(w http.ResponseWriter, r *http.Request) {
 	rt.RequestTransform = parseTransform(r, "request_")
 	rt.ResponseTransform = parseTransform(r, "response_")
 
+	if err := route.ValidateHostPattern(rt.HostPattern, rt.HostMatchType); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	if err := h.routes.Update(r.Context(), rt); err != nil {
 		http.Error(w, "Failed to update route", http.StatusInternalServerError)
 		return
@@ -228,6 +228,47 @@ func TestHandler_RouteCreate(t *testing.T) {
 	}
 }
 
+func TestHandler_RouteCreate_HostPattern(t *testing.T) {
+	h, _, _, _ := newTestHandler()
+	routes := h.routes.(*mockRoutes)
+
+	create := func(hostPattern, hostMatchType string) *httptest.ResponseRecorder {
+		form := url.Values{
+			"name":            {"Tenant Apps"},
+			"host_pattern":    {hostPattern},
+			"host_match_type": {hostMatchType},
+			"path_pattern":    {"/*"},
+			"match_type":      {"prefix"},
+			"upstream_id":     {"upstream1"},
+			"protocol":        {"http"},
+			"enabled":         {"on"},
+		}
+		req := httptest.NewRequest("POST", "/routes", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		h.RouteCreate(w, req)
+		return w
+	}
+
+	if w := create("*.apps.example.com", ""); w.Code != http.StatusFound {
+		t.Fatalf("Status = %d, want %d: %s", w.Code, http.StatusFound, w.Body.String())
+	}
+	var saved route.Route
+	for _, rt := range routes.routes {
+		saved = rt
+	}
+	if saved.HostPattern != "*.apps.example.com" {
+		t.Errorf("HostPattern = %q, want *.apps.example.com", saved.HostPattern)
+	}
+
+	if w := create("api.*.example.com", "wildcard"); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid wildcard: Status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if len(routes.routes) != 1 {
+		t.Errorf("routes = %d, want 1", len(routes.routes))
+	}
+}
+
 func TestHandler_RouteCreate_InvalidForm(t *testing.T) {
 	h, _, _, _ := newTestHandler()
 
@@ -82,6 +82,10 @@ func (m *mockUsers) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+func (m *mockUsers) Restore(ctx context.Context, id string) error {
+	return nil
+}
+
 func (m *mockUsers) List(ctx context.Context, limit, offset int) ([]ports.User, error) {
 	var result []ports.User
 	for _, u := range m.users {
@@ -158,6 +162,10 @@ func (m *mockUsage) GetRecentRequests(ctx context.Context, userID string, limit
 	return nil, nil
 }
 
+func (m *mockUsage) GetTopPaths(ctx context.Context, userID string, start, end time.Time, limit int) ([]usage.PathUsage, error) {
+	return nil, nil
+}
+
 type mockPlans struct {
 	plans     map[string]ports.Plan
 	createErr error
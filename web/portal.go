@@ -2,10 +2,15 @@
 package web
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,8 +20,14 @@ import (
 	domainAuth "github.com/artpar/apigate/domain/auth"
 	"github.com/artpar/apigate/domain/billing"
 	"github.com/artpar/apigate/domain/entitlement"
+	"github.com/artpar/apigate/domain/group"
 	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/domain/quota"
 	"github.com/artpar/apigate/domain/settings"
+	"github.com/artpar/apigate/pkg/clientip"
+	"github.com/artpar/apigate/pkg/idempotency"
+	"github.com/artpar/apigate/pkg/invoicedoc"
+	"github.com/artpar/apigate/pkg/usageexport"
 	"github.com/artpar/apigate/ports"
 	"github.com/go-chi/chi/v5"
 	"github.com/rs/zerolog"
@@ -24,58 +35,88 @@ import (
 
 // PortalHandler provides the user portal endpoints.
 type PortalHandler struct {
-	tokens           *auth.TokenService
-	users            ports.UserStore
-	keys             ports.KeyStore
-	usage            ports.UsageStore
-	plans            ports.PlanStore
-	sessions         ports.SessionStore
-	authTokens       ports.TokenStore
-	emailSender      ports.EmailSender
-	settings         ports.SettingsStore
-	subscriptions    ports.SubscriptionStore
-	invoices         ports.InvoiceStore
-	entitlements     ports.EntitlementStore
-	planEntitlements ports.PlanEntitlementStore
-	webhooks         ports.WebhookStore
-	deliveries       ports.DeliveryStore
-	logger           zerolog.Logger
-	hasher           ports.Hasher
-	idGen            ports.IDGenerator
-	payment          ports.PaymentProvider
-	openAPIService   *openapi.Service
-	isSetup          func() bool
+	tokens            *auth.TokenService
+	users             ports.UserStore
+	keys              ports.KeyStore
+	usage             ports.UsageStore
+	plans             ports.PlanStore
+	sessions          ports.SessionStore
+	authTokens        ports.TokenStore
+	emailSender       ports.EmailSender
+	settings          ports.SettingsStore
+	subscriptions     ports.SubscriptionStore
+	invoices          ports.InvoiceStore
+	entitlements      ports.EntitlementStore
+	planEntitlements  ports.PlanEntitlementStore
+	webhooks          ports.WebhookStore
+	deliveries        ports.DeliveryStore
+	idempotency       ports.ResponseCache
+	logger            zerolog.Logger
+	hasher            ports.Hasher
+	idGen             ports.IDGenerator
+	payment           ports.PaymentProvider
+	billing           ports.BillingProvider
+	breachedPasswords ports.BreachedPasswordChecker
+	oauthProviders    map[string]ports.OAuthProvider
+	oauthIdentities   ports.OAuthIdentityStore
+	oauthStates       ports.OAuthStateStore
+	groups            ports.GroupStore
+	groupMembers      ports.GroupMemberStore
+	groupInvites      ports.GroupInviteStore
+	openAPIService    *openapi.Service
+	isSetup           func() bool
 
 	// Portal-specific settings
-	baseURL string
-	appName string
+	baseURL           string
+	appName           string
+	inviteSecret      string // Signs organization invite links
+	rotationGrace     time.Duration
+	sessionTTL        time.Duration
+	rememberMeTTL     time.Duration
+	oauthRedirectURLs map[string]string
+
+	// Wrong two-factor codes per user and per pending login
+	twoFactorFailures *failureCounter
 }
 
 // PortalDeps contains dependencies for the portal handler.
 type PortalDeps struct {
-	Users            ports.UserStore
-	Keys             ports.KeyStore
-	Usage            ports.UsageStore
-	Plans            ports.PlanStore
-	Sessions         ports.SessionStore
-	AuthTokens       ports.TokenStore
-	EmailSender      ports.EmailSender
-	Settings         ports.SettingsStore
-	Subscriptions    ports.SubscriptionStore
-	Invoices         ports.InvoiceStore
-	Entitlements     ports.EntitlementStore
-	PlanEntitlements ports.PlanEntitlementStore
-	Webhooks         ports.WebhookStore
-	Deliveries       ports.DeliveryStore
-	Logger           zerolog.Logger
-	Hasher           ports.Hasher
-	IDGen            ports.IDGenerator
-	Payment          ports.PaymentProvider
-	OpenAPIService   *openapi.Service
-	IsSetup          func() bool
-	JWTSecret        string
-	BaseURL          string
-	AppName          string
+	Users             ports.UserStore
+	Keys              ports.KeyStore
+	Usage             ports.UsageStore
+	Plans             ports.PlanStore
+	Sessions          ports.SessionStore
+	AuthTokens        ports.TokenStore
+	EmailSender       ports.EmailSender
+	Settings          ports.SettingsStore
+	Subscriptions     ports.SubscriptionStore
+	Invoices          ports.InvoiceStore
+	Entitlements      ports.EntitlementStore
+	PlanEntitlements  ports.PlanEntitlementStore
+	Webhooks          ports.WebhookStore
+	Deliveries        ports.DeliveryStore
+	Idempotency       ports.ResponseCache // Optional: replays repeated Idempotency-Key requests
+	Logger            zerolog.Logger
+	Hasher            ports.Hasher
+	IDGen             ports.IDGenerator
+	Payment           ports.PaymentProvider
+	Billing           ports.BillingProvider          // Optional: local billing; plan changes subscribe without checkout
+	BreachedPasswords ports.BreachedPasswordChecker  // Optional: used when auth.password_check_breached is on
+	OAuthProviders    map[string]ports.OAuthProvider // Optional: social login providers by name
+	OAuthIdentities   ports.OAuthIdentityStore
+	OAuthStates       ports.OAuthStateStore
+	OAuthRedirectURLs map[string]string      // Optional: callback URL per provider (default under BaseURL)
+	Groups            ports.GroupStore       // Optional: enables organizations
+	GroupMembers      ports.GroupMemberStore // Optional: enables organizations
+	GroupInvites      ports.GroupInviteStore // Optional: invites organization members by email
+	OpenAPIService    *openapi.Service
+	IsSetup           func() bool
+	JWTSecret         string
+	BaseURL           string
+	AppName           string
+	KeyRotationGrace  time.Duration // How long a rotated-out key keeps working (default 24h)
+	SessionTTL        time.Duration // Session lifetime without "remember me" (default 24h)
+	RememberMeTTL     time.Duration // Session lifetime with "remember me" (default 30 days)
 }
 
 // NewPortalHandler creates a new user portal handler.
@@ -84,31 +125,58 @@ func NewPortalHandler(deps PortalDeps) (*PortalHandler, error) {
 	if appName == "" {
 		appName = "APIGate"
 	}
+	rotationGrace := deps.KeyRotationGrace
+	if rotationGrace <= 0 {
+		rotationGrace = key.DefaultRotationGrace
+	}
+	sessionTTL := deps.SessionTTL
+	if sessionTTL <= 0 {
+		sessionTTL = domainAuth.DefaultSessionTTL
+	}
+	rememberMeTTL := deps.RememberMeTTL
+	if rememberMeTTL <= 0 {
+		rememberMeTTL = domainAuth.DefaultRememberMeTTL
+	}
 
 	return &PortalHandler{
-		tokens:           auth.NewTokenService(deps.JWTSecret, 7*24*time.Hour), // 7 day sessions
-		users:            deps.Users,
-		keys:             deps.Keys,
-		usage:            deps.Usage,
-		plans:            deps.Plans,
-		sessions:         deps.Sessions,
-		authTokens:       deps.AuthTokens,
-		emailSender:      deps.EmailSender,
-		settings:         deps.Settings,
-		subscriptions:    deps.Subscriptions,
-		invoices:         deps.Invoices,
-		entitlements:     deps.Entitlements,
-		planEntitlements: deps.PlanEntitlements,
-		webhooks:         deps.Webhooks,
-		deliveries:       deps.Deliveries,
-		logger:           deps.Logger,
-		hasher:           deps.Hasher,
-		idGen:            deps.IDGen,
-		payment:          deps.Payment,
-		openAPIService:   deps.OpenAPIService,
-		isSetup:          deps.IsSetup,
-		baseURL:          deps.BaseURL,
-		appName:          appName,
+		tokens:            auth.NewTokenService(deps.JWTSecret, sessionTTL),
+		users:             deps.Users,
+		keys:              deps.Keys,
+		usage:             deps.Usage,
+		plans:             deps.Plans,
+		sessions:          deps.Sessions,
+		authTokens:        deps.AuthTokens,
+		emailSender:       deps.EmailSender,
+		settings:          deps.Settings,
+		subscriptions:     deps.Subscriptions,
+		invoices:          deps.Invoices,
+		entitlements:      deps.Entitlements,
+		planEntitlements:  deps.PlanEntitlements,
+		webhooks:          deps.Webhooks,
+		deliveries:        deps.Deliveries,
+		idempotency:       deps.Idempotency,
+		logger:            deps.Logger,
+		hasher:            deps.Hasher,
+		idGen:             deps.IDGen,
+		payment:           deps.Payment,
+		billing:           deps.Billing,
+		breachedPasswords: deps.BreachedPasswords,
+		oauthProviders:    deps.OAuthProviders,
+		oauthIdentities:   deps.OAuthIdentities,
+		oauthStates:       deps.OAuthStates,
+		groups:            deps.Groups,
+		groupMembers:      deps.GroupMembers,
+		groupInvites:      deps.GroupInvites,
+		openAPIService:    deps.OpenAPIService,
+		isSetup:           deps.IsSetup,
+		baseURL:           deps.BaseURL,
+		appName:           appName,
+		inviteSecret:      deps.JWTSecret,
+		rotationGrace:     rotationGrace,
+		sessionTTL:        sessionTTL,
+		rememberMeTTL:     rememberMeTTL,
+		oauthRedirectURLs: deps.OAuthRedirectURLs,
+		twoFactorFailures: newFailureCounter(twoFactorFailureWindow),
 	}, nil
 }
 
@@ -124,6 +192,64 @@ func (h *PortalHandler) getLabels(ctx context.Context) terminology.Labels {
 	return terminology.ForUnit(setting.Value)
 }
 
+// defaultPlanID returns the plan new users start on, falling back to
+// "free" if no default plan is configured.
+func (h *PortalHandler) defaultPlanID(ctx context.Context) string {
+	if plans, err := h.plans.List(ctx); err == nil {
+		for _, p := range plans {
+			if p.IsDefault {
+				return p.ID
+			}
+		}
+	}
+	return "free"
+}
+
+// passwordPolicy returns the password policy from settings, falling back to
+// the default for any knob that isn't set.
+func (h *PortalHandler) passwordPolicy(ctx context.Context) domainAuth.PasswordPolicy {
+	policy := domainAuth.DefaultPasswordPolicy()
+	if h.settings == nil {
+		return policy
+	}
+	all, err := h.settings.GetAll(ctx)
+	if err != nil {
+		return policy
+	}
+	policy.MinLength = all.GetInt(settings.KeyAuthPasswordMinLength, policy.MinLength)
+	for key, knob := range map[string]*bool{
+		settings.KeyAuthPasswordRequireUpper:  &policy.RequireUpper,
+		settings.KeyAuthPasswordRequireLower:  &policy.RequireLower,
+		settings.KeyAuthPasswordRequireNumber: &policy.RequireNumber,
+		settings.KeyAuthPasswordRequireSymbol: &policy.RequireSymbol,
+		settings.KeyAuthPasswordCheckBreached: &policy.CheckBreached,
+	} {
+		if all.Get(key) != "" {
+			*knob = all.GetBool(key)
+		}
+	}
+	return policy
+}
+
+// checkBreachedPassword marks field invalid when policy asks for breach
+// checks and password is known to be breached. It only runs once password
+// passes the other rules, and fails open if the lookup errors so an outage
+// of the breach service doesn't block signups.
+func (h *PortalHandler) checkBreachedPassword(ctx context.Context, policy domainAuth.PasswordPolicy, result *domainAuth.SignupResult, field, password string) {
+	if !policy.CheckBreached || h.breachedPasswords == nil || result.Errors[field] != "" {
+		return
+	}
+	breached, err := h.breachedPasswords.IsBreached(ctx, password)
+	if err != nil {
+		h.logger.Warn().Err(err).Msg("breached password check failed")
+		return
+	}
+	if breached {
+		result.Errors[field] = domainAuth.BreachedPasswordMessage
+		result.Valid = false
+	}
+}
+
 // Router returns the portal router.
 func (h *PortalHandler) Router() chi.Router {
 	r := chi.NewRouter()
@@ -145,16 +271,27 @@ func (h *PortalHandler) Router() chi.Router {
 	r.Post("/signup", h.SignupSubmit)
 	r.Get("/login", h.PortalLoginPage)
 	r.Post("/login", h.PortalLoginSubmit)
+	r.Post("/login/two-factor", h.TwoFactorLoginSubmit)
+	r.Get("/oauth/{provider}", h.PortalOAuthStart)
+	r.Get("/oauth/{provider}/callback", h.PortalOAuthCallback)
 	r.Get("/forgot-password", h.ForgotPasswordPage)
 	r.Post("/forgot-password", h.ForgotPasswordSubmit)
 	r.Get("/reset-password", h.ResetPasswordPage)
 	r.Post("/reset-password", h.ResetPasswordSubmit)
 	r.Get("/verify-email", h.VerifyEmail)
+	r.Get("/orgs/join", h.JoinOrg)
 	r.Post("/resend-verification", h.ResendVerification)
+	r.Get("/impersonate", h.StartImpersonation)
 
 	// Protected routes (require auth)
 	r.Group(func(r chi.Router) {
 		r.Use(h.PortalAuthMiddleware)
+		if h.idempotency != nil {
+			r.Use(idempotency.Middleware(idempotency.Config{
+				Store: h.idempotency,
+				Scope: portalUserScope,
+			}))
+		}
 
 		// Dashboard
 		r.Get("/dashboard", h.PortalDashboard)
@@ -164,12 +301,16 @@ func (h *PortalHandler) Router() chi.Router {
 		r.Get("/api-keys/partial", h.APIKeysPartial)
 		r.Post("/api-keys", h.CreateAPIKey)
 		r.Post("/api-keys/{id}/revoke", h.RevokeAPIKey)
+		r.Post("/api-keys/{id}/rotate", h.RotateAPIKey)
+		r.Post("/api-keys/{id}/labels", h.SetAPIKeyLabels)
 
 		// Usage
 		r.Get("/usage", h.PortalUsagePage)
+		r.Get("/usage/export", h.PortalUsageExport)
 
 		// Billing
 		r.Get("/billing", h.BillingPage)
+		r.Get("/billing/invoices/{id}", h.PortalInvoiceDownload)
 
 		// Plans (upgrade/downgrade)
 		r.Get("/plans", h.PlansPage)
@@ -187,6 +328,22 @@ func (h *PortalHandler) Router() chi.Router {
 		r.Post("/settings", h.UpdateAccountSettings)
 		r.Post("/settings/password", h.ChangePassword)
 		r.Post("/settings/close-account", h.CloseAccount)
+		r.Get("/settings/two-factor", h.TwoFactorSetupPage)
+		r.Post("/settings/two-factor", h.EnableTwoFactor)
+		r.Post("/settings/two-factor/disable", h.DisableTwoFactor)
+		r.Get("/settings/sessions", h.SessionsPage)
+		r.Post("/settings/sessions/revoke-others", h.RevokeOtherSessions)
+		r.Post("/settings/sessions/{id}/revoke", h.RevokeSession)
+
+		// Organizations
+		r.Get("/orgs", h.OrgsPage)
+		r.Post("/orgs", h.CreateOrg)
+		r.Post("/orgs/switch", h.SwitchOrg)
+		r.Get("/orgs/members", h.OrgMembersPage)
+		r.Post("/orgs/members", h.AddOrgMember)
+		r.Post("/orgs/members/{id}/role", h.ChangeOrgMemberRole)
+		r.Post("/orgs/members/{id}/remove", h.RemoveOrgMember)
+		r.Post("/orgs/invites/{id}/revoke", h.RevokeOrgInvite)
 
 		// Webhooks
 		r.Get("/webhooks", h.PortalWebhooksPage)
@@ -218,6 +375,16 @@ func (h *PortalHandler) PortalAuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// Tokens backed by a session stop working once it is revoked
+		if claims.SessionID != "" {
+			session, err := h.sessions.Get(r.Context(), claims.SessionID)
+			if err != nil || session.UserID != claims.UserID || session.IsExpired() {
+				h.clearPortalCookie(w)
+				http.Redirect(w, r, "/portal/login", http.StatusFound)
+				return
+			}
+		}
+
 		// Verify user still exists and is active
 		user, err := h.users.Get(r.Context(), claims.UserID)
 		if err != nil || user.Status != "active" {
@@ -226,15 +393,33 @@ func (h *PortalHandler) PortalAuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		ctx := withPortalUser(r.Context(), &PortalUser{
-			ID:    user.ID,
-			Email: user.Email,
-			Name:  user.Name,
-		})
+		portalUser := &PortalUser{
+			ID:               user.ID,
+			Email:            user.Email,
+			Name:             user.Name,
+			UpdatedAt:        user.UpdatedAt,
+			ImpersonatedBy:   claims.ImpersonatorID,
+			TwoFactorEnabled: user.TOTPEnabled,
+			SessionID:        claims.SessionID,
+		}
+		if h.acceptPendingInvite(w, r, portalUser) {
+			return
+		}
+		h.applyActiveOrg(w, r, portalUser)
+
+		ctx := withPortalUser(r.Context(), portalUser)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// portalUserScope scopes idempotency keys to the logged-in portal user.
+func portalUserScope(r *http.Request) string {
+	if user := getPortalUser(r.Context()); user != nil {
+		return user.ID
+	}
+	return ""
+}
+
 // Portal page data
 type PortalPageData struct {
 	Title   string
@@ -249,6 +434,37 @@ type PortalUser struct {
 	ID    string
 	Email string
 	Name  string
+
+	// UpdatedAt is when the user record last changed. Forms that edit it
+	// send it back, so a save from a stale page can be refused.
+	UpdatedAt time.Time
+
+	// ImpersonatedBy is the ID of the admin acting as this user, if any.
+	ImpersonatedBy string
+
+	// TwoFactorEnabled reports whether login requires a TOTP code.
+	TwoFactorEnabled bool
+
+	// SessionID is the stored session behind the cookie. Empty for
+	// impersonation, which is not tied to a session.
+	SessionID string
+
+	// Org is the organization the portal is scoped to and OrgRole the
+	// user's role in it. Org is nil when working on the user's own account.
+	Org     *group.Group
+	OrgRole group.Role
+}
+
+// IsImpersonated reports whether an admin is acting as the user.
+func (u *PortalUser) IsImpersonated() bool {
+	return u != nil && u.ImpersonatedBy != ""
+}
+
+// CanManageKeys reports whether the user may create, rotate and revoke keys
+// in the portal's current scope. Only owners and admins manage the keys of
+// an organization.
+func (u *PortalUser) CanManageKeys() bool {
+	return u.Org == nil || u.OrgRole.CanManageKeys()
 }
 
 // Portal context key
@@ -273,8 +489,37 @@ func (h *PortalHandler) newPortalPageData(ctx context.Context, title string) Por
 	}
 }
 
-// setPortalCookie sets the JWT cookie for portal session.
-func (h *PortalHandler) setPortalCookie(w http.ResponseWriter, token string) {
+// startPortalSession logs a user in. It stores a session lasting the
+// "remember me" or normal session TTL and sets a cookie holding a token for
+// it, which is returned. Without "remember me" the cookie is dropped when
+// the browser closes.
+func (h *PortalHandler) startPortalSession(w http.ResponseWriter, r *http.Request, userID, email string, rememberMe bool) (string, error) {
+	ttl := h.sessionTTL
+	if rememberMe {
+		ttl = h.rememberMeTTL
+	}
+
+	session := domainAuth.GenerateSession(userID, email, clientip.FromRequest(r, nil), r.UserAgent(), ttl)
+	if err := h.sessions.Create(r.Context(), session); err != nil {
+		return "", fmt.Errorf("store session: %w", err)
+	}
+
+	token, _, err := h.tokens.GenerateSessionToken(userID, email, "user", session.ID, ttl)
+	if err != nil {
+		return "", fmt.Errorf("generate token: %w", err)
+	}
+
+	cookieTTL := time.Duration(0)
+	if rememberMe {
+		cookieTTL = ttl
+	}
+	h.setPortalCookieTTL(w, token, cookieTTL)
+	return token, nil
+}
+
+// setPortalCookieTTL sets the JWT cookie for a portal session lasting ttl,
+// or for the browser session if ttl is 0.
+func (h *PortalHandler) setPortalCookieTTL(w http.ResponseWriter, token string, ttl time.Duration) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     "portal_token",
 		Value:    token,
@@ -282,10 +527,35 @@ func (h *PortalHandler) setPortalCookie(w http.ResponseWriter, token string) {
 		HttpOnly: true,
 		Secure:   true,
 		SameSite: http.SameSiteLaxMode,
-		MaxAge:   7 * 24 * 60 * 60, // 7 days
+		MaxAge:   int(ttl.Seconds()),
 	})
 }
 
+// StartImpersonation exchanges an impersonation token minted by the admin
+// API for a portal session cookie. Regular session tokens are rejected so
+// the link cannot be used to plant an arbitrary session.
+func (h *PortalHandler) StartImpersonation(w http.ResponseWriter, r *http.Request) {
+	claims, err := h.tokens.ValidateToken(r.URL.Query().Get("token"))
+	if err != nil || !claims.IsImpersonated() {
+		http.Redirect(w, r, "/portal/login", http.StatusFound)
+		return
+	}
+
+	user, err := h.users.Get(r.Context(), claims.UserID)
+	if err != nil || user.Status != "active" {
+		http.Redirect(w, r, "/portal/login", http.StatusFound)
+		return
+	}
+
+	h.logger.Info().
+		Str("admin_id", claims.ImpersonatorID).
+		Str("user_id", user.ID).
+		Msg("impersonated portal session started")
+
+	h.setPortalCookieTTL(w, r.URL.Query().Get("token"), time.Until(claims.ExpiresAt.Time))
+	http.Redirect(w, r, "/portal/dashboard", http.StatusFound)
+}
+
 func (h *PortalHandler) clearPortalCookie(w http.ResponseWriter) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     "portal_token",
@@ -343,7 +613,8 @@ func (h *PortalHandler) SignupPage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.Write([]byte(h.renderSignupPageWithPlan("", "", defaultPlan, h.getLabels(r.Context()), nil)))
+	email := html.EscapeString(r.URL.Query().Get("email")) // Pre-fill email from an invite link
+	w.Write([]byte(h.renderSignupPageWithPlan("", email, defaultPlan, h.getLabels(r.Context()), nil)))
 }
 
 func (h *PortalHandler) SignupSubmit(w http.ResponseWriter, r *http.Request) {
@@ -373,7 +644,9 @@ func (h *PortalHandler) SignupSubmit(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate
-	result := domainAuth.ValidateSignup(req)
+	policy := h.passwordPolicy(ctx)
+	result := domainAuth.ValidateSignup(req, policy)
+	h.checkBreachedPassword(ctx, policy, &result, "password", req.Password)
 	if !result.Valid {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 		w.WriteHeader(http.StatusUnprocessableEntity)
@@ -413,16 +686,7 @@ func (h *PortalHandler) SignupSubmit(w http.ResponseWriter, r *http.Request) {
 		userStatus = "pending" // Not active until email verified
 	}
 
-	// Find default plan for new users
-	defaultPlanID := "free" // fallback if no default plan configured
-	if plans, err := h.plans.List(ctx); err == nil {
-		for _, p := range plans {
-			if p.IsDefault {
-				defaultPlanID = p.ID
-				break
-			}
-		}
-	}
+	defaultPlanID := h.defaultPlanID(ctx)
 
 	user := ports.User{
 		ID:           userID,
@@ -460,16 +724,14 @@ func (h *PortalHandler) SignupSubmit(w http.ResponseWriter, r *http.Request) {
 		// Redirect to login with verification message, pre-fill email
 		http.Redirect(w, r, "/portal/login?signup=success&email="+url.QueryEscape(req.Email), http.StatusFound)
 	} else {
-		// Auto-login: generate JWT and set cookie, then redirect to dashboard
-		token, _, err := h.tokens.GenerateToken(userID, req.Email, "user")
-		if err != nil {
-			h.logger.Error().Err(err).Msg("failed to generate token after signup")
+		// Auto-login: start a session, then redirect to dashboard
+		if _, err := h.startPortalSession(w, r, userID, req.Email, false); err != nil {
+			h.logger.Error().Err(err).Msg("failed to start session after signup")
 			// Fall back to login redirect
 			http.Redirect(w, r, "/portal/login?signup=ready&email="+url.QueryEscape(req.Email), http.StatusFound)
 			return
 		}
 
-		h.setPortalCookie(w, token)
 		h.logger.Info().Str("user_id", userID).Str("email", req.Email).Msg("user signed up and auto-logged in")
 
 		// Redirect to dashboard
@@ -501,6 +763,9 @@ func (h *PortalHandler) PortalLoginPage(w http.ResponseWriter, r *http.Request)
 	} else if r.URL.Query().Get("reset") == "success" {
 		message = "Password reset successful! You can now log in."
 		messageType = "success"
+	} else if msg, ok := oauthLoginErrors[r.URL.Query().Get("oauth_error")]; ok {
+		message = msg
+		messageType = "error"
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -519,6 +784,7 @@ func (h *PortalHandler) PortalLoginSubmit(w http.ResponseWriter, r *http.Request
 		Email:    r.FormValue("email"),
 		Password: r.FormValue("password"),
 	}
+	rememberMe := r.FormValue("remember_me") != ""
 
 	// Validate input
 	result := domainAuth.ValidateLogin(req)
@@ -560,19 +826,194 @@ func (h *PortalHandler) PortalLoginSubmit(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Generate JWT
-	token, _, err := h.tokens.GenerateToken(user.ID, user.Email, "user")
+	// Ask for the second factor before issuing a session
+	if user.TOTPEnabled {
+		h.promptSecondFactor(w, r, user, rememberMe)
+		return
+	}
+
+	if _, err := h.startPortalSession(w, r, user.ID, user.Email, rememberMe); err != nil {
+		h.logger.Error().Err(err).Msg("failed to start session")
+		h.renderError(w, http.StatusInternalServerError, "Failed to log in")
+		return
+	}
+
+	http.Redirect(w, r, "/portal/dashboard", http.StatusFound)
+}
+
+// promptSecondFactor stores a pending login for a user with two-factor
+// authentication and asks for their code.
+func (h *PortalHandler) promptSecondFactor(w http.ResponseWriter, r *http.Request, user ports.User, rememberMe bool) {
+	tokenResult := domainAuth.GenerateToken(user.ID, user.Email, domainAuth.TokenTypeTwoFactor, twoFactorLoginTTL)
+	if err := h.authTokens.Create(r.Context(), tokenResult.Token.WithHash(domainAuth.HashToken(tokenResult.RawToken))); err != nil {
+		h.logger.Error().Err(err).Msg("failed to store two-factor token")
+		h.renderError(w, http.StatusInternalServerError, "Failed to log in")
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(h.renderTwoFactorLoginPage(tokenResult.RawToken, rememberMe, "")))
+}
+
+// TwoFactorLoginSubmit completes a login for a user with two-factor
+// authentication, checking the TOTP or recovery code against the pending
+// login token issued by PortalLoginSubmit.
+func (h *PortalHandler) TwoFactorLoginSubmit(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := r.ParseForm(); err != nil {
+		h.renderError(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	rawToken := r.FormValue("token")
+	code := r.FormValue("code")
+	rememberMe := r.FormValue("remember_me") != ""
+
+	// The pending login expires quickly; start over once it has
+	token, err := h.authTokens.GetByHash(ctx, domainAuth.HashToken(rawToken))
+	if err != nil || token.Type != domainAuth.TokenTypeTwoFactor || !token.IsValid() {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(h.renderLoginPage("", "Your login has expired. Please log in again.", "error", nil)))
+		return
+	}
+
+	user, err := h.users.Get(ctx, token.UserID)
+	if err != nil || user.Status != "active" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(h.renderLoginPage("", "Your account is not active", "error", nil)))
+		return
+	}
+
+	ok, err := h.verifySecondFactor(ctx, &user, code)
+	if errors.Is(err, errTwoFactorLocked) {
+		h.discardPendingLogin(ctx, token.ID)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(h.renderLoginPage("", "Too many incorrect authentication codes. Please try again later.", "error", nil)))
+		return
+	}
 	if err != nil {
-		h.logger.Error().Err(err).Msg("failed to generate token")
+		h.logger.Error().Err(err).Msg("failed to verify two-factor code")
+		h.renderError(w, http.StatusInternalServerError, "Failed to log in")
+		return
+	}
+	if !ok {
+		// A pending login only gets a few tries before the password is needed again
+		if h.twoFactorFailures.Fail("login:"+token.ID, time.Now()) >= maxPendingLoginFailures {
+			h.discardPendingLogin(ctx, token.ID)
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(h.renderLoginPage("", "Too many incorrect authentication codes. Please log in again.", "error", nil)))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(h.renderTwoFactorLoginPage(rawToken, rememberMe, "Invalid authentication code")))
+		return
+	}
+	h.twoFactorFailures.Reset("login:" + token.ID)
+
+	if err := h.authTokens.MarkUsed(ctx, token.ID, time.Now().UTC()); err != nil {
+		h.logger.Error().Err(err).Msg("failed to mark two-factor token used")
+	}
+
+	if _, err := h.startPortalSession(w, r, user.ID, user.Email, rememberMe); err != nil {
+		h.logger.Error().Err(err).Msg("failed to start session")
 		h.renderError(w, http.StatusInternalServerError, "Failed to log in")
 		return
 	}
 
-	h.setPortalCookie(w, token)
 	http.Redirect(w, r, "/portal/dashboard", http.StatusFound)
 }
 
+// twoFactorLoginTTL is how long a user has to enter their code after the
+// password step of a two-factor login.
+const twoFactorLoginTTL = 5 * time.Minute
+
+// errTwoFactorLocked is returned by verifySecondFactor once a user has
+// entered too many wrong codes and has to wait before trying again.
+var errTwoFactorLocked = errors.New("too many incorrect two-factor codes")
+
+// discardPendingLogin uses up a pending two-factor login so its token can't
+// be tried again.
+func (h *PortalHandler) discardPendingLogin(ctx context.Context, tokenID string) {
+	if err := h.authTokens.MarkUsed(ctx, tokenID, time.Now().UTC()); err != nil {
+		h.logger.Error().Err(err).Msg("failed to discard two-factor token")
+	}
+	h.twoFactorFailures.Reset("login:" + tokenID)
+}
+
+// verifySecondFactor checks code as a TOTP code for user, or failing that
+// as one of their recovery codes. A TOTP code is accepted once: the time
+// step it belongs to is saved and codes from that step or earlier are
+// rejected. A recovery code is used up: it is removed from user and the
+// change is saved. The save only applies if user is unchanged since it was
+// read, so of two concurrent logins with the same code only one succeeds.
+// Wrong codes count towards the user's failure limit, and once it is
+// reached errTwoFactorLocked is returned without checking code.
+func (h *PortalHandler) verifySecondFactor(ctx context.Context, user *ports.User, code string) (bool, error) {
+	if code == "" {
+		return false, nil
+	}
+	now := time.Now()
+	userKey := "user:" + user.ID
+	if h.twoFactorFailures.Count(userKey, now) >= maxTwoFactorFailures {
+		return false, errTwoFactorLocked
+	}
+
+	if step, ok := domainAuth.MatchTOTP(user.TOTPSecret, code, now); ok && step > user.TOTPLastStep {
+		user.TOTPLastStep = step
+		if saved, err := h.saveSecondFactor(ctx, user); !saved || err != nil {
+			return false, err
+		}
+		h.twoFactorFailures.Reset(userKey)
+		return true, nil
+	}
+	remaining, ok := domainAuth.UseRecoveryCode(user.RecoveryCodes, code)
+	if !ok {
+		if h.twoFactorFailures.Fail(userKey, now) >= maxTwoFactorFailures {
+			h.logger.Warn().Str("user_id", user.ID).Msg("two-factor login locked after repeated incorrect codes")
+		}
+		return false, nil
+	}
+	user.RecoveryCodes = remaining
+	if saved, err := h.saveSecondFactor(ctx, user); !saved || err != nil {
+		return false, err
+	}
+	h.twoFactorFailures.Reset(userKey)
+	h.logger.Info().Str("user_id", user.ID).Int("remaining", len(remaining)).Msg("recovery code used")
+	return true, nil
+}
+
+// saveSecondFactor saves the TOTP step or recovery codes used by a login.
+// It reports false without an error if the user changed since it was read,
+// such as by another login with the same code.
+func (h *PortalHandler) saveSecondFactor(ctx context.Context, user *ports.User) (bool, error) {
+	since := user.UpdatedAt
+	user.UpdatedAt = time.Now().UTC()
+	versioned, ok := h.users.(ports.UserVersionStore)
+	if !ok {
+		return true, h.users.Update(ctx, *user)
+	}
+	err := versioned.UpdateIfUnchanged(ctx, *user, since)
+	if errors.Is(err, ports.ErrConflict) {
+		h.logger.Warn().Str("user_id", user.ID).Msg("two-factor code used by a concurrent login")
+		return false, nil
+	}
+	return err == nil, err
+}
+
 func (h *PortalHandler) PortalLogout(w http.ResponseWriter, r *http.Request) {
+	// Revoke the session so the token can't be reused
+	if cookie, err := r.Cookie("portal_token"); err == nil {
+		if claims, err := h.tokens.ValidateToken(cookie.Value); err == nil && claims.SessionID != "" {
+			if err := h.sessions.Delete(r.Context(), claims.SessionID); err != nil {
+				h.logger.Warn().Err(err).Msg("failed to delete session on logout")
+			}
+		}
+	}
 	h.clearPortalCookie(w)
 	http.Redirect(w, r, "/portal/login", http.StatusFound)
 }
@@ -774,7 +1215,9 @@ func (h *PortalHandler) ResetPasswordSubmit(w http.ResponseWriter, r *http.Reque
 
 	// Validate password strength
 	req := domainAuth.PasswordResetConfirm{Token: rawToken, NewPassword: password}
-	result := domainAuth.ValidatePasswordResetConfirm(req)
+	policy := h.passwordPolicy(ctx)
+	result := domainAuth.ValidatePasswordResetConfirm(req, policy)
+	h.checkBreachedPassword(ctx, policy, &result, "password", password)
 	if !result.Valid {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 		w.WriteHeader(http.StatusUnprocessableEntity)
@@ -848,35 +1291,32 @@ func (h *PortalHandler) PortalDashboard(w http.ResponseWriter, r *http.Request)
 	ctx := r.Context()
 	user := getPortalUser(ctx)
 
-	// Get user's API keys
-	keys, err := h.keys.ListByUser(ctx, user.ID)
+	// Get the account's API keys
+	keys, err := h.accountKeys(ctx, user)
 	if err != nil {
 		h.logger.Error().Err(err).Msg("failed to get keys")
 	}
 
-	// Get usage summary
+	// Get usage summary for the current quota window
 	now := time.Now().UTC()
-	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
-	summary, err := h.usage.GetSummary(ctx, user.ID, start, now)
+	account := h.usageAccount(ctx, user)
+	start, _ := h.quotaWindow(ctx, account, now)
+	summary, err := h.usage.GetSummary(ctx, account.ID, start, now)
 	if err != nil {
 		h.logger.Error().Err(err).Msg("failed to get usage")
 	}
 
-	// Get user's plan info
+	// Get the account's plan info
 	var planName string
-	var planID string
+	planID := account.PlanID
 	var requestsPerMonth int64
 	var rateLimitPerMinute int
-	if h.plans != nil {
-		dbUser, err := h.users.Get(ctx, user.ID)
-		if err == nil && dbUser.PlanID != "" {
-			planID = dbUser.PlanID
-			plan, err := h.plans.Get(ctx, dbUser.PlanID)
-			if err == nil {
-				planName = plan.Name
-				requestsPerMonth = plan.RequestsPerMonth
-				rateLimitPerMinute = plan.RateLimitPerMinute
-			}
+	if h.plans != nil && planID != "" {
+		plan, err := h.plans.Get(ctx, planID)
+		if err == nil {
+			planName = plan.Name
+			requestsPerMonth = plan.RequestsPerMonth
+			rateLimitPerMinute = plan.RateLimitPerMinute
 		}
 	}
 
@@ -908,7 +1348,13 @@ func (h *PortalHandler) APIKeysPage(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	user := getPortalUser(ctx)
 
-	keys, err := h.keys.ListByUser(ctx, user.ID)
+	sel, err := key.ParseLabelSelector(r.URL.Query().Get("labels"))
+	if err != nil {
+		h.renderError(w, http.StatusBadRequest, "Invalid label filter: "+err.Error())
+		return
+	}
+
+	keys, err := h.accountKeysByLabels(ctx, user, sel)
 	if err != nil {
 		h.logger.Error().Err(err).Msg("failed to get keys")
 		keys = nil
@@ -917,7 +1363,7 @@ func (h *PortalHandler) APIKeysPage(w http.ResponseWriter, r *http.Request) {
 	revokedMsg := r.URL.Query().Get("revoked") == "true"
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.Write([]byte(h.renderAPIKeysPage(user, keys, revokedMsg)))
+	w.Write([]byte(h.renderAPIKeysPage(user, keys, sel, revokedMsg)))
 }
 
 func (h *PortalHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
@@ -929,14 +1375,40 @@ func (h *PortalHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !user.CanManageKeys() {
+		h.renderError(w, http.StatusForbidden, "Only organization owners and admins can manage API keys")
+		return
+	}
+
 	keyName := r.FormValue("name")
 
-	// Generate API key
+	var monthlyQuota int64
+	if v := strings.TrimSpace(r.FormValue("monthly_quota")); v != "" {
+		q, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || q < 0 {
+			h.renderError(w, http.StatusBadRequest, "Monthly quota must be a non-negative whole number")
+			return
+		}
+		monthlyQuota = q
+	}
+
+	labels, err := key.ParseLabels(r.FormValue("labels"))
+	if err != nil {
+		h.renderError(w, http.StatusBadRequest, "Invalid labels: "+err.Error())
+		return
+	}
+
+	// Generate API key, owned by the active organization if there is one
 	rawKey, keyData := key.Generate("ak_")
 	keyData = keyData.WithUserID(user.ID)
+	if user.Org != nil {
+		keyData = keyData.WithGroupID(user.Org.ID)
+	}
 	if keyName != "" {
 		keyData.Name = keyName
 	}
+	keyData.MonthlyQuota = monthlyQuota
+	keyData.Labels = labels
 
 	// Store the key
 	if err := h.keys.Create(ctx, keyData); err != nil {
@@ -946,7 +1418,7 @@ func (h *PortalHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Show the key to the user (only shown once)
-	h.renderKeyCreatedPage(w, r, user, rawKey, keyName)
+	h.renderKeyCreatedPage(w, r, user, rawKey, keyName, "Your API key has been created.")
 }
 
 func (h *PortalHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
@@ -959,8 +1431,13 @@ func (h *PortalHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Verify the key belongs to this user (security check)
-	keys, err := h.keys.ListByUser(ctx, user.ID)
+	if !user.CanManageKeys() {
+		http.Error(w, "Only organization owners and admins can manage API keys", http.StatusForbidden)
+		return
+	}
+
+	// Verify the key belongs to this account (security check)
+	keys, err := h.accountKeys(ctx, user)
 	if err != nil {
 		h.logger.Error().Err(err).Msg("failed to list user keys")
 		http.Error(w, "Failed to verify key ownership", http.StatusInternalServerError)
@@ -991,20 +1468,143 @@ func (h *PortalHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/portal/api-keys?revoked=true", http.StatusFound)
 }
 
+// RotateAPIKey issues a replacement for one of the user's keys. The old key
+// keeps working for the rotation grace period so integrations can switch over
+// without downtime, then it is revoked automatically.
+func (h *PortalHandler) RotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getPortalUser(ctx)
+	keyID := chi.URLParam(r, "id")
+
+	if keyID == "" {
+		http.Error(w, "Key ID required", http.StatusBadRequest)
+		return
+	}
+
+	if !user.CanManageKeys() {
+		http.Error(w, "Only organization owners and admins can manage API keys", http.StatusForbidden)
+		return
+	}
+
+	// Verify the key belongs to this account (security check)
+	keys, err := h.accountKeys(ctx, user)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to list user keys")
+		http.Error(w, "Failed to verify key ownership", http.StatusInternalServerError)
+		return
+	}
+
+	var old *key.Key
+	for i := range keys {
+		if keys[i].ID == keyID {
+			old = &keys[i]
+			break
+		}
+	}
+	if old == nil {
+		http.Error(w, "Key not found", http.StatusNotFound)
+		return
+	}
+
+	now := time.Now().UTC()
+	if old.RevokedAt != nil || (old.ExpiresAt != nil && !now.Before(*old.ExpiresAt)) {
+		h.renderError(w, http.StatusConflict, "This key is revoked, expired or already being rotated")
+		return
+	}
+
+	rawKey, replacement, revokeAt := key.Rotate(*old, "ak_", now, h.rotationGrace)
+	if err := h.keys.Create(ctx, replacement); err != nil {
+		h.logger.Error().Err(err).Msg("failed to create replacement API key")
+		h.renderError(w, http.StatusInternalServerError, "Failed to rotate API key")
+		return
+	}
+	if err := h.keys.Revoke(ctx, old.ID, revokeAt); err != nil {
+		h.logger.Error().Err(err).Str("key_id", old.ID).Msg("failed to schedule revocation of rotated key")
+		h.renderError(w, http.StatusInternalServerError, "Failed to rotate API key")
+		return
+	}
+
+	notice := fmt.Sprintf("Your API key has been rotated. The old key <code>%s****</code> keeps working until %s, then it is revoked.",
+		old.Prefix, revokeAt.Format("Jan 2, 2006 15:04 MST"))
+	h.renderKeyCreatedPage(w, r, user, rawKey, replacement.Name, notice)
+}
+
+// SetAPIKeyLabels replaces the labels of one of the account's keys.
+func (h *PortalHandler) SetAPIKeyLabels(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getPortalUser(ctx)
+	keyID := chi.URLParam(r, "id")
+
+	if err := r.ParseForm(); err != nil {
+		h.renderError(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	if !user.CanManageKeys() {
+		h.renderError(w, http.StatusForbidden, "Only organization owners and admins can manage API keys")
+		return
+	}
+
+	labelKeys, ok := h.keys.(ports.KeyLabelStore)
+	if !ok {
+		h.renderError(w, http.StatusNotImplemented, "Key labels are not supported")
+		return
+	}
+
+	labels, err := key.ParseLabels(r.FormValue("labels"))
+	if err != nil {
+		h.renderError(w, http.StatusBadRequest, "Invalid labels: "+err.Error())
+		return
+	}
+
+	// Verify the key belongs to this account (security check)
+	keys, err := h.accountKeys(ctx, user)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to list user keys")
+		h.renderError(w, http.StatusInternalServerError, "Failed to verify key ownership")
+		return
+	}
+	found := false
+	for _, k := range keys {
+		if k.ID == keyID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		h.renderError(w, http.StatusNotFound, "Key not found")
+		return
+	}
+
+	if err := labelKeys.SetLabels(ctx, keyID, labels); err != nil {
+		h.logger.Error().Err(err).Str("key_id", keyID).Msg("failed to set key labels")
+		h.renderError(w, http.StatusInternalServerError, "Failed to update labels")
+		return
+	}
+
+	http.Redirect(w, r, "/portal/api-keys", http.StatusFound)
+}
+
 // APIKeysPartial returns just the API keys table rows for HTMX polling updates.
 func (h *PortalHandler) APIKeysPartial(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	user := getPortalUser(ctx)
 
-	keys, err := h.keys.ListByUser(ctx, user.ID)
+	sel, err := key.ParseLabelSelector(r.URL.Query().Get("labels"))
+	if err != nil {
+		http.Error(w, "Invalid label filter", http.StatusBadRequest)
+		return
+	}
+
+	keys, err := h.accountKeysByLabels(ctx, user, sel)
 	if err != nil {
 		h.logger.Error().Err(err).Msg("failed to get keys for partial")
 		keys = nil
 	}
 
-	rows := h.renderAPIKeysTableRows(keys)
+	rows := h.renderAPIKeysTableRows(keys, user.CanManageKeys())
 	if rows == "" {
-		rows = `<tr><td colspan="6" class="text-center">No API keys yet</td></tr>`
+		rows = `<tr><td colspan="8" class="text-center">No API keys yet</td></tr>`
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -1020,15 +1620,88 @@ func (h *PortalHandler) PortalUsagePage(w http.ResponseWriter, r *http.Request)
 	user := getPortalUser(ctx)
 
 	now := time.Now().UTC()
-	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	account := h.usageAccount(ctx, user)
+	start, end := h.quotaWindow(ctx, account, now)
 
-	summary, err := h.usage.GetSummary(ctx, user.ID, start, now)
+	summary, err := h.usage.GetSummary(ctx, account.ID, start, now)
 	if err != nil {
 		h.logger.Error().Err(err).Msg("failed to get usage")
 	}
+	summary.TopPaths, err = h.usage.GetTopPaths(ctx, account.ID, start, now, portalTopPaths)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to get usage by path")
+	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.Write([]byte(h.renderUsagePage(user, summary, h.getLabels(ctx))))
+	w.Write([]byte(h.renderUsagePage(user, summary, start, end, h.getLabels(ctx))))
+}
+
+// portalTopPaths is how many paths the usage page breaks usage down by.
+const portalTopPaths = 10
+
+// PortalUsageExport downloads the account's usage events as CSV or JSON. The
+// range defaults to the current quota window.
+func (h *PortalHandler) PortalUsageExport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getPortalUser(ctx)
+
+	now := time.Now().UTC()
+	account := h.usageAccount(ctx, user)
+	windowStart, _ := h.quotaWindow(ctx, account, now)
+	start, end, err := usageexport.ParseRange(r.URL.Query(), windowStart, now)
+	if err != nil {
+		h.renderError(w, http.StatusBadRequest, "Invalid date range: "+err.Error())
+		return
+	}
+
+	err = usageexport.Write(ctx, w, h.usage, account.ID, start, end, r.URL.Query().Get("format"))
+	if errors.Is(err, usageexport.ErrUnknownFormat) {
+		h.renderError(w, http.StatusBadRequest, "Export format must be csv or json")
+		return
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Str("account_id", account.ID).Msg("usage export failed")
+	}
+}
+
+// usageAccount is the account usage and quota are counted against in the
+// portal's current scope: the active organization or the user.
+type usageAccount struct {
+	ID        string
+	PlanID    string
+	CreatedAt time.Time // Anchors rolling quota windows
+}
+
+// usageAccount returns the account for the portal's current scope. An
+// organization without a plan of its own is shown on the user's plan, as
+// its keys are metered on their creator's plan.
+func (h *PortalHandler) usageAccount(ctx context.Context, user *PortalUser) usageAccount {
+	var account usageAccount
+	if dbUser, err := h.users.Get(ctx, user.ID); err == nil {
+		account = usageAccount{ID: dbUser.ID, PlanID: dbUser.PlanID, CreatedAt: dbUser.CreatedAt}
+	} else {
+		account.ID = user.ID
+	}
+	if user.Org != nil {
+		account.ID = user.Org.ID
+		account.CreatedAt = user.Org.CreatedAt
+		if user.Org.PlanID != "" {
+			account.PlanID = user.Org.PlanID
+		}
+	}
+	return account
+}
+
+// quotaWindow returns the quota window containing now for an account, based
+// on its plan's quota period. Rolling windows are anchored at its creation.
+func (h *PortalHandler) quotaWindow(ctx context.Context, account usageAccount, now time.Time) (start, end time.Time) {
+	period := quota.PeriodCalendarMonth
+	if h.plans != nil && account.PlanID != "" {
+		if p, err := h.plans.Get(ctx, account.PlanID); err == nil && p.QuotaPeriod != "" {
+			period = quota.Period(p.QuotaPeriod)
+		}
+	}
+	return quota.WindowBounds(period, now, account.CreatedAt)
 }
 
 // -----------------------------------------------------------------------------
@@ -1042,6 +1715,8 @@ func (h *PortalHandler) AccountSettingsPage(w http.ResponseWriter, r *http.Reque
 		success = "Password changed successfully"
 	} else if r.URL.Query().Get("profile") == "updated" {
 		success = "Profile updated successfully"
+	} else if r.URL.Query().Get("two_factor") == "disabled" {
+		success = "Two-factor authentication disabled"
 	}
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Write([]byte(h.renderAccountSettingsPage(user, nil, success)))
@@ -1059,19 +1734,19 @@ func (h *PortalHandler) UpdateAccountSettings(w http.ResponseWriter, r *http.Req
 	name := strings.TrimSpace(r.FormValue("name"))
 
 	// Validate name
-	errors := make(map[string]string)
+	fieldErrors := make(map[string]string)
 	if name == "" {
-		errors["name"] = "Name is required"
+		fieldErrors["name"] = "Name is required"
 	} else if len(name) < 2 {
-		errors["name"] = "Name must be at least 2 characters"
+		fieldErrors["name"] = "Name must be at least 2 characters"
 	} else if len(name) > 100 {
-		errors["name"] = "Name must be less than 100 characters"
+		fieldErrors["name"] = "Name must be less than 100 characters"
 	}
 
-	if len(errors) > 0 {
+	if len(fieldErrors) > 0 {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 		w.WriteHeader(http.StatusUnprocessableEntity)
-		w.Write([]byte(h.renderAccountSettingsPage(portalUser, errors, "")))
+		w.Write([]byte(h.renderAccountSettingsPage(portalUser, fieldErrors, "")))
 		return
 	}
 
@@ -1082,9 +1757,28 @@ func (h *PortalHandler) UpdateAccountSettings(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// Refuse a save from a page loaded before the last change, such as
+	// one in another tab; the page is re-rendered with the current values
+	if v := r.FormValue("version"); v != "" && v != strconv.FormatInt(user.UpdatedAt.UnixNano(), 10) {
+		h.renderProfileConflict(w, portalUser)
+		return
+	}
+
+	// The store repeats the version check so a save that raced with this
+	// one between the read above and the write can't be overwritten
+	since := user.UpdatedAt
 	user.Name = name
 	user.UpdatedAt = time.Now().UTC()
-	if err := h.users.Update(ctx, user); err != nil {
+	if versioned, ok := h.users.(ports.UserVersionStore); ok {
+		err = versioned.UpdateIfUnchanged(ctx, user, since)
+	} else {
+		err = h.users.Update(ctx, user)
+	}
+	if errors.Is(err, ports.ErrConflict) {
+		h.renderProfileConflict(w, portalUser)
+		return
+	}
+	if err != nil {
 		h.logger.Error().Err(err).Msg("failed to update profile")
 		h.renderError(w, http.StatusInternalServerError, "Failed to update profile")
 		return
@@ -1093,6 +1787,16 @@ func (h *PortalHandler) UpdateAccountSettings(w http.ResponseWriter, r *http.Req
 	http.Redirect(w, r, "/portal/settings?profile=updated", http.StatusSeeOther)
 }
 
+// renderProfileConflict re-renders the account settings page with the
+// current values after a save based on an outdated copy of the profile.
+func (h *PortalHandler) renderProfileConflict(w http.ResponseWriter, portalUser *PortalUser) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusConflict)
+	w.Write([]byte(h.renderAccountSettingsPage(portalUser, map[string]string{
+		"name": "Your profile was changed elsewhere. Review the current values and save again.",
+	}, "")))
+}
+
 func (h *PortalHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	portalUser := getPortalUser(ctx)
@@ -1111,7 +1815,9 @@ func (h *PortalHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 		CurrentPassword: currentPassword,
 		NewPassword:     newPassword,
 	}
-	result := domainAuth.ValidateChangePassword(req)
+	policy := h.passwordPolicy(ctx)
+	result := domainAuth.ValidateChangePassword(req, policy)
+	h.checkBreachedPassword(ctx, policy, &result, "new_password", newPassword)
 	if !result.Valid {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 		w.WriteHeader(http.StatusUnprocessableEntity)
@@ -1183,10 +1889,8 @@ func (h *PortalHandler) CloseAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Soft delete - mark as cancelled
-	dbUser.Status = "cancelled"
-	dbUser.UpdatedAt = time.Now().UTC()
-	if err := h.users.Update(ctx, dbUser); err != nil {
+	// Soft delete - an admin can restore the account later
+	if err := h.users.Delete(ctx, dbUser.ID); err != nil {
 		h.logger.Error().Err(err).Msg("failed to close account")
 		h.renderError(w, http.StatusInternalServerError, "Failed to close account")
 		return
@@ -1201,6 +1905,202 @@ func (h *PortalHandler) CloseAccount(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/portal/login?closed=true", http.StatusFound)
 }
 
+// -----------------------------------------------------------------------------
+// Two-Factor Authentication
+// -----------------------------------------------------------------------------
+
+// TwoFactorSetupPage starts TOTP enrollment. It gives the user a secret,
+// kept on their account until they confirm it with a code, and shows it as
+// a provisioning URI for their authenticator app.
+func (h *PortalHandler) TwoFactorSetupPage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	portalUser := getPortalUser(ctx)
+
+	user, err := h.users.Get(ctx, portalUser.ID)
+	if err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to get user")
+		return
+	}
+	if user.TOTPEnabled {
+		http.Redirect(w, r, "/portal/settings", http.StatusFound)
+		return
+	}
+
+	// Reuse a pending secret so reloading the page doesn't invalidate an
+	// app the user has already set up
+	if user.TOTPSecret == "" {
+		user.TOTPSecret = domainAuth.GenerateTOTPSecret()
+		if err := h.users.Update(ctx, user); err != nil {
+			h.logger.Error().Err(err).Msg("failed to store TOTP secret")
+			h.renderError(w, http.StatusInternalServerError, "Failed to set up two-factor authentication")
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(h.renderTwoFactorSetupPage(portalUser, user.TOTPSecret, "")))
+}
+
+// EnableTwoFactor confirms enrollment with a code from the user's app, turns
+// two-factor login on, and shows a fresh set of recovery codes once.
+func (h *PortalHandler) EnableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	portalUser := getPortalUser(ctx)
+
+	if err := r.ParseForm(); err != nil {
+		h.renderError(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	user, err := h.users.Get(ctx, portalUser.ID)
+	if err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to get user")
+		return
+	}
+	if user.TOTPEnabled || user.TOTPSecret == "" {
+		http.Redirect(w, r, "/portal/settings/two-factor", http.StatusFound)
+		return
+	}
+
+	step, ok := domainAuth.MatchTOTP(user.TOTPSecret, r.FormValue("code"), time.Now())
+	if !ok {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(h.renderTwoFactorSetupPage(portalUser, user.TOTPSecret, "Invalid authentication code. Check your app and try again.")))
+		return
+	}
+
+	recoveryCodes := domainAuth.GenerateRecoveryCodes(domainAuth.RecoveryCodeCount)
+	user.RecoveryCodes = make([]string, len(recoveryCodes))
+	for i, code := range recoveryCodes {
+		user.RecoveryCodes[i] = domainAuth.HashRecoveryCode(code)
+	}
+	user.TOTPEnabled = true
+	user.TOTPLastStep = step // The confirming code can't be reused to log in
+	if err := h.users.Update(ctx, user); err != nil {
+		h.logger.Error().Err(err).Msg("failed to enable two-factor authentication")
+		h.renderError(w, http.StatusInternalServerError, "Failed to enable two-factor authentication")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(h.renderRecoveryCodesPage(portalUser, recoveryCodes)))
+}
+
+// DisableTwoFactor turns two-factor login off after confirming the user's
+// password, and discards the secret and recovery codes.
+func (h *PortalHandler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	portalUser := getPortalUser(ctx)
+
+	if err := r.ParseForm(); err != nil {
+		h.renderError(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	user, err := h.users.Get(ctx, portalUser.ID)
+	if err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to get user")
+		return
+	}
+
+	if !h.hasher.Compare(user.PasswordHash, r.FormValue("password")) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(h.renderAccountSettingsPage(portalUser, map[string]string{"two_factor": "Incorrect password"}, "")))
+		return
+	}
+
+	user.TOTPSecret = ""
+	user.TOTPEnabled = false
+	user.RecoveryCodes = nil
+	user.TOTPLastStep = 0
+	if err := h.users.Update(ctx, user); err != nil {
+		h.logger.Error().Err(err).Msg("failed to disable two-factor authentication")
+		h.renderError(w, http.StatusInternalServerError, "Failed to disable two-factor authentication")
+		return
+	}
+
+	http.Redirect(w, r, "/portal/settings?two_factor=disabled", http.StatusFound)
+}
+
+// SessionsPage lists the user's active sessions.
+func (h *PortalHandler) SessionsPage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getPortalUser(ctx)
+
+	sessions, err := h.sessions.ListByUser(ctx, user.ID)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to list sessions")
+		h.renderError(w, http.StatusInternalServerError, "Failed to load sessions")
+		return
+	}
+
+	success := ""
+	switch r.URL.Query().Get("revoked") {
+	case "one":
+		success = "Session logged out"
+	case "others":
+		success = "All other sessions logged out"
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(h.renderSessionsPage(user, sessions, success)))
+}
+
+// RevokeSession logs out one of the user's sessions. Revoking the current
+// session logs the user out here too.
+func (h *PortalHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getPortalUser(ctx)
+	sessionID := chi.URLParam(r, "id")
+
+	session, err := h.sessions.Get(ctx, sessionID)
+	if err != nil || session.UserID != user.ID {
+		h.renderError(w, http.StatusNotFound, "Session not found")
+		return
+	}
+
+	if err := h.sessions.Delete(ctx, sessionID); err != nil {
+		h.logger.Error().Err(err).Msg("failed to revoke session")
+		h.renderError(w, http.StatusInternalServerError, "Failed to log out session")
+		return
+	}
+
+	if sessionID == user.SessionID {
+		h.clearPortalCookie(w)
+		http.Redirect(w, r, "/portal/login", http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, "/portal/settings/sessions?revoked=one", http.StatusFound)
+}
+
+// RevokeOtherSessions logs out every session except the current one.
+func (h *PortalHandler) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getPortalUser(ctx)
+
+	sessions, err := h.sessions.ListByUser(ctx, user.ID)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to list sessions")
+		h.renderError(w, http.StatusInternalServerError, "Failed to log out sessions")
+		return
+	}
+
+	for _, session := range sessions {
+		if session.ID == user.SessionID {
+			continue
+		}
+		if err := h.sessions.Delete(ctx, session.ID); err != nil {
+			h.logger.Error().Err(err).Str("session_id", session.ID).Msg("failed to revoke session")
+			h.renderError(w, http.StatusInternalServerError, "Failed to log out sessions")
+			return
+		}
+	}
+
+	http.Redirect(w, r, "/portal/settings/sessions?revoked=others", http.StatusFound)
+}
+
 // -----------------------------------------------------------------------------
 // Billing
 // -----------------------------------------------------------------------------
@@ -1272,6 +2172,50 @@ func (h *PortalHandler) BillingPage(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(h.renderBillingPage(user, subscription, currentPlan, invoices, successMsg, errorMsg)))
 }
 
+// PortalInvoiceDownload renders one of the user's invoices as a printable
+// HTML page, or as a PDF download with format=pdf.
+func (h *PortalHandler) PortalInvoiceDownload(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getPortalUser(ctx)
+
+	if h.invoices == nil {
+		h.renderError(w, http.StatusNotFound, "Invoice not found")
+		return
+	}
+	inv, err := h.invoices.Get(ctx, chi.URLParam(r, "id"))
+	if err != nil || inv.UserID != user.ID {
+		h.renderError(w, http.StatusNotFound, "Invoice not found")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = invoicedoc.FormatHTML
+	}
+	doc := invoicedoc.Document{
+		Invoice:  inv,
+		Seller:   h.appName,
+		Customer: invoicedoc.Customer{Name: user.Name, Email: user.Email},
+	}
+	var buf bytes.Buffer
+	if err := invoicedoc.Render(&buf, doc, format); err != nil {
+		if errors.Is(err, invoicedoc.ErrUnknownFormat) {
+			h.renderError(w, http.StatusBadRequest, "Invoice format must be html or pdf")
+			return
+		}
+		h.logger.Error().Err(err).Str("invoice_id", inv.ID).Msg("failed to render invoice")
+		h.renderError(w, http.StatusInternalServerError, "Failed to render invoice")
+		return
+	}
+
+	w.Header().Set("Content-Type", invoicedoc.ContentType(format))
+	w.Header().Set("Cache-Control", "no-store")
+	if format == invoicedoc.FormatPDF {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", invoicedoc.Filename(inv, format)))
+	}
+	buf.WriteTo(w)
+}
+
 // -----------------------------------------------------------------------------
 // Plans (Upgrade/Downgrade)
 // -----------------------------------------------------------------------------
@@ -1312,8 +2256,11 @@ func (h *PortalHandler) PlansPage(w http.ResponseWriter, r *http.Request) {
 	// Check for success/error messages
 	success := ""
 	errorMsg := ""
-	if r.URL.Query().Get("changed") == "true" {
+	switch r.URL.Query().Get("changed") {
+	case "true":
 		success = "Your plan has been changed successfully."
+	case "prorated":
+		success = "Your plan has been changed. The prorated difference for the rest of this billing period will be on your next invoice."
 	}
 	switch r.URL.Query().Get("error") {
 	case "no_provider":
@@ -1371,6 +2318,12 @@ func (h *PortalHandler) ChangePlan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// With local billing, subscribe directly; invoices are computed from usage
+	if h.billing != nil {
+		h.changeLocalPlan(w, r, dbUser, newPlan)
+		return
+	}
+
 	// If the new plan has a price > 0, redirect to payment checkout
 	if newPlan.PriceMonthly > 0 {
 		// Check if payment provider is configured (NoopProvider returns "none")
@@ -1447,6 +2400,61 @@ func (h *PortalHandler) ChangePlan(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/portal/plans?changed=true", http.StatusFound)
 }
 
+// changeLocalPlan moves the user to a plan through the local billing
+// provider. Paid plans switch the current subscription mid-period, prorated
+// on its next invoice, or start a new one; free plans end the current
+// subscription.
+func (h *PortalHandler) changeLocalPlan(w http.ResponseWriter, r *http.Request, dbUser ports.User, newPlan ports.Plan) {
+	ctx := r.Context()
+
+	if newPlan.PriceMonthly > 0 || newPlan.OveragePrice > 0 {
+		changed := "true"
+		if h.subscriptions != nil {
+			if sub, err := h.subscriptions.GetByUser(ctx, dbUser.ID); err == nil && sub.IsActive() && sub.PlanID != newPlan.ID {
+				changed = "prorated"
+			}
+		}
+
+		customerID, err := h.billing.CreateCustomer(ctx, dbUser.Email, dbUser.Name, dbUser.ID)
+		if err == nil {
+			_, err = h.billing.CreateSubscription(ctx, customerID, newPlan.ID)
+		}
+		if err != nil {
+			h.logger.Error().Err(err).Str("user_id", dbUser.ID).Str("plan_id", newPlan.ID).Msg("failed to create local subscription")
+			http.Redirect(w, r, "/portal/plans?error=internal", http.StatusFound)
+			return
+		}
+		h.logger.Info().Str("user_id", dbUser.ID).Str("new_plan", newPlan.ID).Msg("user subscribed with local billing")
+		http.Redirect(w, r, "/portal/plans?changed="+changed, http.StatusFound)
+		return
+	}
+
+	if h.subscriptions != nil {
+		if sub, err := h.subscriptions.GetByUser(ctx, dbUser.ID); err == nil {
+			if err := h.billing.CancelSubscription(ctx, sub.ID); err != nil {
+				h.logger.Error().Err(err).Str("subscription_id", sub.ID).Msg("failed to cancel local subscription")
+				http.Redirect(w, r, "/portal/plans?error=internal", http.StatusFound)
+				return
+			}
+			// Cancelling moved the user to the default plan
+			if dbUser, err = h.users.Get(ctx, dbUser.ID); err != nil {
+				h.logger.Error().Err(err).Msg("failed to get user for plan change")
+				http.Redirect(w, r, "/portal/plans?error=internal", http.StatusFound)
+				return
+			}
+		}
+	}
+
+	dbUser.PlanID = newPlan.ID
+	dbUser.UpdatedAt = time.Now().UTC()
+	if err := h.users.Update(ctx, dbUser); err != nil {
+		h.logger.Error().Err(err).Msg("failed to update user plan")
+		http.Redirect(w, r, "/portal/plans?error=internal", http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, "/portal/plans?changed=true", http.StatusFound)
+}
+
 // -----------------------------------------------------------------------------
 // Subscription Management
 // -----------------------------------------------------------------------------
@@ -1604,6 +2612,17 @@ func (h *PortalHandler) CancelSubscription(w http.ResponseWriter, r *http.Reques
 
 	now := time.Now().UTC()
 
+	// Local billing invoices the period so far and moves the user to the default plan
+	if h.billing != nil && cancelImmediately {
+		if err := h.billing.CancelSubscription(ctx, subscription.ID); err != nil {
+			h.logger.Error().Err(err).Msg("failed to cancel local subscription")
+			http.Redirect(w, r, "/portal/billing?error=internal", http.StatusFound)
+			return
+		}
+		http.Redirect(w, r, "/portal/billing?cancelled=now", http.StatusFound)
+		return
+	}
+
 	// If there's a payment provider and subscription has provider ID, cancel via provider
 	if h.payment != nil && subscription.ProviderID != "" {
 		if err := h.payment.CancelSubscription(ctx, subscription.ProviderID, cancelImmediately); err != nil {
@@ -1681,7 +2700,9 @@ func (h *PortalHandler) APIRegister(w http.ResponseWriter, r *http.Request) {
 		Password: req.Password,
 		Name:     req.Name,
 	}
-	result := domainAuth.ValidateSignup(signupReq)
+	policy := h.passwordPolicy(ctx)
+	result := domainAuth.ValidateSignup(signupReq, policy)
+	h.checkBreachedPassword(ctx, policy, &result, "password", req.Password)
 	if !result.Valid {
 		h.writeJSONValidationErrors(w, result.Errors)
 		return
@@ -1718,15 +2739,7 @@ func (h *PortalHandler) APIRegister(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Find default plan for new users
-	defaultPlanID := "free"
-	if plans, err := h.plans.List(ctx); err == nil {
-		for _, p := range plans {
-			if p.IsDefault {
-				defaultPlanID = p.ID
-				break
-			}
-		}
-	}
+	defaultPlanID := h.defaultPlanID(ctx)
 
 	user := ports.User{
 		ID:           userID,
@@ -1763,10 +2776,10 @@ func (h *PortalHandler) APIRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Auto-login: generate JWT
-	token, _, err := h.tokens.GenerateToken(userID, req.Email, "user")
+	// Auto-login: start a session
+	token, err := h.startPortalSession(w, r, userID, req.Email, false)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("failed to generate token after signup")
+		h.logger.Error().Err(err).Msg("failed to start session after signup")
 		h.writeJSON(w, http.StatusCreated, map[string]interface{}{
 			"success": true,
 			"message": "Account created. Please log in.",
@@ -1774,7 +2787,6 @@ func (h *PortalHandler) APIRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.setPortalCookie(w, token)
 	h.logger.Info().Str("user_id", userID).Str("email", req.Email).Msg("user signed up via API")
 
 	h.writeJSON(w, http.StatusCreated, map[string]interface{}{
@@ -1796,8 +2808,10 @@ func (h *PortalHandler) APILogin(w http.ResponseWriter, r *http.Request) {
 
 	// Parse JSON body
 	var req struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Email      string `json:"email"`
+		Password   string `json:"password"`
+		Code       string `json:"code"` // TOTP or recovery code, for users with two-factor authentication
+		RememberMe bool   `json:"remember_me"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.writeJSONError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON body")
@@ -1838,16 +2852,35 @@ func (h *PortalHandler) APILogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate JWT
-	token, _, err := h.tokens.GenerateToken(user.ID, user.Email, "user")
+	// Check the second factor
+	if user.TOTPEnabled {
+		if req.Code == "" {
+			h.writeJSONError(w, http.StatusUnauthorized, "two_factor_required", "Two-factor authentication code required")
+			return
+		}
+		ok, err := h.verifySecondFactor(ctx, &user, req.Code)
+		if errors.Is(err, errTwoFactorLocked) {
+			h.writeJSONError(w, http.StatusTooManyRequests, "too_many_attempts", "Too many incorrect authentication codes. Please try again later.")
+			return
+		}
+		if err != nil {
+			h.logger.Error().Err(err).Msg("failed to verify two-factor code")
+			h.writeJSONError(w, http.StatusInternalServerError, "server_error", "Failed to log in")
+			return
+		}
+		if !ok {
+			h.writeJSONError(w, http.StatusUnauthorized, "invalid_two_factor_code", "Invalid authentication code")
+			return
+		}
+	}
+
+	token, err := h.startPortalSession(w, r, user.ID, user.Email, req.RememberMe)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("failed to generate token")
+		h.logger.Error().Err(err).Msg("failed to start session")
 		h.writeJSONError(w, http.StatusInternalServerError, "server_error", "Failed to log in")
 		return
 	}
 
-	h.setPortalCookie(w, token)
-
 	h.writeJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
 		"user": map[string]interface{}{
@@ -1931,7 +2964,9 @@ func (h *PortalHandler) APIResetPassword(w http.ResponseWriter, r *http.Request)
 
 	// Validate password strength
 	resetReq := domainAuth.PasswordResetConfirm{Token: req.Token, NewPassword: req.Password}
-	result := domainAuth.ValidatePasswordResetConfirm(resetReq)
+	policy := h.passwordPolicy(ctx)
+	result := domainAuth.ValidatePasswordResetConfirm(resetReq, policy)
+	h.checkBreachedPassword(ctx, policy, &result, "password", req.Password)
 	if !result.Valid {
 		h.writeJSONValidationErrors(w, result.Errors)
 		return
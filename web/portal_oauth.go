@@ -0,0 +1,315 @@
+package web
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"html"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/artpar/apigate/domain/oauth"
+	"github.com/artpar/apigate/domain/settings"
+	"github.com/artpar/apigate/ports"
+	"github.com/go-chi/chi/v5"
+)
+
+// oauthStateTTL is how long a user has to finish signing in with a provider.
+const oauthStateTTL = 10 * time.Minute
+
+// oauthStateCookie binds an OAuth login to the browser that started it. The
+// callback only accepts the state stored in it, so an attacker can't log a
+// victim into the attacker's account with a callback URL of their own.
+const oauthStateCookie = "portal_oauth_state"
+
+// oauthLoginErrors are the login page messages for the oauth_error codes
+// PortalOAuthCallback redirects with.
+var oauthLoginErrors = map[string]string{
+	"denied":                "Sign-in was cancelled",
+	"failed":                "Couldn't sign you in with that provider. Please try again.",
+	"no_email":              "Your account with that provider has no email address we can use",
+	"email_unverified":      "Please verify your email address with that provider first",
+	"email_in_use":          "An account with this email already exists. Log in with your password instead.",
+	"registration_disabled": "No account is linked to that login. Please sign up first.",
+	"unverified":            "Please verify your email before logging in",
+	"inactive":              "Your account is not active",
+}
+
+// oauthProviderNames returns the configured OAuth providers in a stable order.
+func (h *PortalHandler) oauthProviderNames() []string {
+	names := make([]string, 0, len(h.oauthProviders))
+	for name := range h.oauthProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// oauthCallbackURL returns the redirect URL registered with the provider.
+// It is the configured URL if there is one, otherwise the portal callback
+// under the base URL, or under the request's host if no base URL is set.
+func (h *PortalHandler) oauthCallbackURL(r *http.Request, provider string) string {
+	if u := h.oauthRedirectURLs[provider]; u != "" {
+		return u
+	}
+	base := strings.TrimSuffix(h.baseURL, "/")
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		base = scheme + "://" + r.Host
+	}
+	return base + "/portal/oauth/" + provider + "/callback"
+}
+
+// PortalOAuthStart redirects to the provider to sign in.
+func (h *PortalHandler) PortalOAuthStart(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	providerName := chi.URLParam(r, "provider")
+
+	provider, ok := h.oauthProviders[providerName]
+	if !ok || h.oauthStates == nil || h.oauthIdentities == nil {
+		h.renderError(w, http.StatusNotFound, "Unknown login provider")
+		return
+	}
+
+	state := oauth.GenerateState(oauth.Provider(providerName), "/portal/dashboard", oauthStateTTL)
+	if err := h.oauthStates.Create(ctx, state); err != nil {
+		h.logger.Error().Err(err).Msg("failed to store OAuth state")
+		h.renderError(w, http.StatusInternalServerError, "Failed to start login")
+		return
+	}
+
+	authURL, err := provider.GetAuthURL(ctx, state.State, state.CodeChallenge(), state.Nonce, h.oauthCallbackURL(r, providerName))
+	if err != nil {
+		h.logger.Error().Err(err).Str("provider", providerName).Msg("failed to build OAuth URL")
+		h.renderError(w, http.StatusInternalServerError, "Failed to start login")
+		return
+	}
+
+	// Lax so the cookie comes back on the provider's top-level redirect
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state.State,
+		Path:     "/portal/oauth",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   int(oauthStateTTL.Seconds()),
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// PortalOAuthCallback finishes signing in with a provider. The user linked
+// to the provider identity is logged in. On first login the identity is
+// linked to the user with the same verified email, or a new user is created.
+func (h *PortalHandler) PortalOAuthCallback(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	providerName := chi.URLParam(r, "provider")
+	q := r.URL.Query()
+
+	provider, ok := h.oauthProviders[providerName]
+	if !ok || h.oauthStates == nil || h.oauthIdentities == nil {
+		h.renderError(w, http.StatusNotFound, "Unknown login provider")
+		return
+	}
+
+	fail := func(code string) {
+		http.Redirect(w, r, "/portal/login?oauth_error="+code, http.StatusFound)
+	}
+
+	// The state cookie is single use, like the state itself
+	var cookieState string
+	if c, err := r.Cookie(oauthStateCookie); err == nil {
+		cookieState = c.Value
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    "",
+		Path:     "/portal/oauth",
+		HttpOnly: true,
+		Secure:   true,
+		MaxAge:   -1,
+	})
+
+	cb := oauth.ValidateCallback(q.Get("code"), q.Get("state"), q.Get("error"), q.Get("error_description"))
+	if !cb.Valid {
+		h.logger.Warn().Str("provider", providerName).Str("error", cb.Error).Str("description", cb.ErrorDesc).Msg("OAuth login rejected")
+		fail("denied")
+		return
+	}
+
+	// The login must have been started in this browser
+	if cookieState == "" || subtle.ConstantTimeCompare([]byte(cookieState), []byte(cb.State)) != 1 {
+		h.logger.Warn().Str("provider", providerName).Msg("OAuth callback state does not match this browser")
+		fail("failed")
+		return
+	}
+
+	// The state is single use and must belong to this provider
+	state, err := h.oauthStates.Get(ctx, cb.State)
+	if err != nil {
+		fail("failed")
+		return
+	}
+	if err := h.oauthStates.Delete(ctx, cb.State); err != nil {
+		h.logger.Warn().Err(err).Msg("failed to delete OAuth state")
+	}
+	if state.IsExpired() || state.Provider != oauth.Provider(providerName) {
+		fail("failed")
+		return
+	}
+
+	tokens, err := provider.ExchangeCode(ctx, cb.Code, state.CodeVerifier, h.oauthCallbackURL(r, providerName))
+	if err != nil || tokens.Error != "" {
+		h.logger.Error().Err(err).Str("provider", providerName).Str("error", tokens.Error).Msg("failed to exchange OAuth code")
+		fail("failed")
+		return
+	}
+
+	profile, err := provider.GetUserProfile(ctx, tokens.AccessToken)
+	if err != nil {
+		h.logger.Error().Err(err).Str("provider", providerName).Msg("failed to get OAuth profile")
+		fail("failed")
+		return
+	}
+
+	user, errCode := h.oauthUser(ctx, oauth.Provider(providerName), profile, tokens)
+	if errCode != "" {
+		fail(errCode)
+		return
+	}
+
+	switch {
+	case user.Status == "pending":
+		fail("unverified")
+		return
+	case user.Status != "active":
+		fail("inactive")
+		return
+	}
+
+	if user.TOTPEnabled {
+		h.promptSecondFactor(w, r, user, false)
+		return
+	}
+
+	if _, err := h.startPortalSession(w, r, user.ID, user.Email, false); err != nil {
+		h.logger.Error().Err(err).Msg("failed to start session")
+		h.renderError(w, http.StatusInternalServerError, "Failed to log in")
+		return
+	}
+
+	http.Redirect(w, r, state.RedirectURI, http.StatusFound)
+}
+
+// oauthUser finds or creates the user for a provider profile, storing the
+// provider identity. On failure it returns an oauthLoginErrors code.
+func (h *PortalHandler) oauthUser(ctx context.Context, provider oauth.Provider, profile oauth.UserProfile, tokens oauth.TokenResponse) (ports.User, string) {
+	now := time.Now().UTC()
+
+	// Returning user
+	if identity, err := h.oauthIdentities.GetByProviderUser(ctx, provider, profile.ProviderUserID); err == nil {
+		user, err := h.users.Get(ctx, identity.UserID)
+		if err != nil {
+			h.logger.Error().Err(err).Str("user_id", identity.UserID).Msg("failed to get user for OAuth identity")
+			return ports.User{}, "failed"
+		}
+		identity = identity.WithTokens(tokens.AccessToken, tokens.RefreshToken, tokens.ExpiresAt())
+		identity.UpdatedAt = now
+		if err := h.oauthIdentities.Update(ctx, identity); err != nil {
+			h.logger.Warn().Err(err).Msg("failed to update OAuth identity")
+		}
+		return user, ""
+	}
+
+	if profile.Email == "" {
+		return ports.User{}, "no_email"
+	}
+
+	autoLink, allowRegistration := true, true
+	if h.settings != nil {
+		if all, err := h.settings.GetAll(ctx); err == nil {
+			if all.Get(settings.KeyOAuthAutoLinkEmail) != "" {
+				autoLink = all.GetBool(settings.KeyOAuthAutoLinkEmail)
+			}
+			if all.Get(settings.KeyOAuthAllowRegistration) != "" {
+				allowRegistration = all.GetBool(settings.KeyOAuthAllowRegistration)
+			}
+		}
+	}
+
+	user, err := h.users.GetByEmail(ctx, profile.Email)
+	switch {
+	case err == nil:
+		// Only link to an existing account when the provider vouches for
+		// the email, or anyone could take over an account by claiming it
+		if !autoLink || !profile.EmailVerified {
+			return ports.User{}, "email_in_use"
+		}
+	case !allowRegistration:
+		return ports.User{}, "registration_disabled"
+	case !profile.EmailVerified:
+		return ports.User{}, "email_unverified"
+	default:
+		name := profile.Name
+		if name == "" {
+			name = strings.Split(profile.Email, "@")[0]
+		}
+		user = ports.User{
+			ID:        h.idGen.New(),
+			Email:     profile.Email,
+			Name:      name,
+			PlanID:    h.defaultPlanID(ctx),
+			Status:    "active",
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if err := h.users.Create(ctx, user); err != nil {
+			h.logger.Error().Err(err).Msg("failed to create user from OAuth login")
+			return ports.User{}, "failed"
+		}
+		h.logger.Info().Str("user_id", user.ID).Str("provider", string(provider)).Msg("user signed up with OAuth")
+	}
+
+	identity := oauth.Identity{
+		ID:             oauth.GenerateIdentityID(),
+		UserID:         user.ID,
+		Provider:       provider,
+		ProviderUserID: profile.ProviderUserID,
+		Email:          profile.Email,
+		Name:           profile.Name,
+		AvatarURL:      profile.AvatarURL,
+		RawData:        profile.RawData,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}.WithTokens(tokens.AccessToken, tokens.RefreshToken, tokens.ExpiresAt())
+	if err := h.oauthIdentities.Create(ctx, identity); err != nil {
+		h.logger.Error().Err(err).Msg("failed to store OAuth identity")
+		return ports.User{}, "failed"
+	}
+
+	return user, ""
+}
+
+// renderOAuthButtons renders a "Continue with ..." button per configured
+// provider for the login and signup pages.
+func (h *PortalHandler) renderOAuthButtons() string {
+	names := h.oauthProviderNames()
+	if len(names) == 0 || h.oauthStates == nil || h.oauthIdentities == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(`
+            <div class="auth-divider" style="text-align: center; color: #6b7280; font-size: 13px; margin: 16px 0;">or</div>`)
+	for _, name := range names {
+		fmt.Fprintf(&b, `
+            <a href="/portal/oauth/%s" class="btn btn-secondary btn-block" style="margin-bottom: 8px;">Continue with %s</a>`,
+			html.EscapeString(name), html.EscapeString(oauth.Provider(name).DisplayName()))
+	}
+	return b.String()
+}
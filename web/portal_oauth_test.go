@@ -0,0 +1,416 @@
+package web
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	oauthadapter "github.com/artpar/apigate/adapters/oauth"
+	"github.com/artpar/apigate/domain/oauth"
+	"github.com/artpar/apigate/domain/settings"
+	"github.com/artpar/apigate/ports"
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+)
+
+// mockOAuthIdentityStore implements ports.OAuthIdentityStore for testing.
+type mockOAuthIdentityStore struct {
+	identities map[string]oauth.Identity
+}
+
+func (m *mockOAuthIdentityStore) Get(ctx context.Context, id string) (oauth.Identity, error) {
+	if i, ok := m.identities[id]; ok {
+		return i, nil
+	}
+	return oauth.Identity{}, errNotFound
+}
+
+func (m *mockOAuthIdentityStore) GetByProviderUser(ctx context.Context, provider oauth.Provider, providerUserID string) (oauth.Identity, error) {
+	for _, i := range m.identities {
+		if i.Provider == provider && i.ProviderUserID == providerUserID {
+			return i, nil
+		}
+	}
+	return oauth.Identity{}, errNotFound
+}
+
+func (m *mockOAuthIdentityStore) Create(ctx context.Context, identity oauth.Identity) error {
+	m.identities[identity.ID] = identity
+	return nil
+}
+
+func (m *mockOAuthIdentityStore) Update(ctx context.Context, identity oauth.Identity) error {
+	m.identities[identity.ID] = identity
+	return nil
+}
+
+func (m *mockOAuthIdentityStore) Delete(ctx context.Context, id string) error {
+	delete(m.identities, id)
+	return nil
+}
+
+func (m *mockOAuthIdentityStore) ListByUser(ctx context.Context, userID string) ([]oauth.Identity, error) {
+	var out []oauth.Identity
+	for _, i := range m.identities {
+		if i.UserID == userID {
+			out = append(out, i)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockOAuthIdentityStore) GetByUserAndProvider(ctx context.Context, userID string, provider oauth.Provider) (oauth.Identity, error) {
+	for _, i := range m.identities {
+		if i.UserID == userID && i.Provider == provider {
+			return i, nil
+		}
+	}
+	return oauth.Identity{}, errNotFound
+}
+
+// mockOAuthStateStore implements ports.OAuthStateStore for testing.
+type mockOAuthStateStore struct {
+	states map[string]oauth.State
+}
+
+func (m *mockOAuthStateStore) Create(ctx context.Context, state oauth.State) error {
+	m.states[state.State] = state
+	return nil
+}
+
+func (m *mockOAuthStateStore) Get(ctx context.Context, state string) (oauth.State, error) {
+	if s, ok := m.states[state]; ok {
+		return s, nil
+	}
+	return oauth.State{}, errNotFound
+}
+
+func (m *mockOAuthStateStore) Delete(ctx context.Context, state string) error {
+	delete(m.states, state)
+	return nil
+}
+
+func (m *mockOAuthStateStore) DeleteExpired(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+// oauthTestEnv is a portal wired to a Google provider whose token and
+// userinfo endpoints are served by a local mock.
+type oauthTestEnv struct {
+	router     http.Handler
+	users      *mockUserStore
+	identities *mockOAuthIdentityStore
+	settings   *mockSettingsStore
+
+	// profile is the userinfo response; challenge is the PKCE challenge
+	// from the last authorization redirect.
+	profile   string
+	challenge string
+}
+
+func newOAuthTestEnv(t *testing.T) *oauthTestEnv {
+	t.Helper()
+	env := &oauthTestEnv{
+		users:      newMockUserStore(),
+		identities: &mockOAuthIdentityStore{identities: make(map[string]oauth.Identity)},
+		settings:   newMockSettingsStore(),
+		profile:    `{"id":"g-42","email":"ada@example.com","verified_email":true,"name":"Ada Lovelace"}`,
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/token":
+			r.ParseForm()
+			sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+			if r.Form.Get("code") != "good-code" || base64.RawURLEncoding.EncodeToString(sum[:]) != env.challenge ||
+				r.Form.Get("redirect_uri") != "https://portal.example.com/portal/oauth/google/callback" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"invalid_grant"}`))
+				return
+			}
+			w.Write([]byte(`{"access_token":"at-123","token_type":"Bearer","expires_in":3600}`))
+		case "/userinfo":
+			if r.Header.Get("Authorization") != "Bearer at-123" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(env.profile))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	handler, err := NewPortalHandler(PortalDeps{
+		Users:      env.users,
+		Keys:       &mockKeyStore{},
+		Usage:      &mockUsageStore{},
+		AuthTokens: newMockTokenStore(),
+		Sessions:   newMockSessionStore(),
+		Plans:      newMockPlanStore(),
+		Settings:   env.settings,
+		Logger:     zerolog.Nop(),
+		Hasher:     &mockHasher{},
+		IDGen:      &mockIDGen{},
+		JWTSecret:  "test-secret",
+		BaseURL:    "https://portal.example.com",
+		AppName:    "TestApp",
+		OAuthProviders: map[string]ports.OAuthProvider{
+			"google": oauthadapter.NewGoogleProvider(oauthadapter.GoogleConfig{
+				ClientID:     "client",
+				ClientSecret: "secret",
+				AuthURL:      srv.URL + "/auth",
+				TokenURL:     srv.URL + "/token",
+				UserInfoURL:  srv.URL + "/userinfo",
+			}),
+		},
+		OAuthIdentities: env.identities,
+		OAuthStates:     &mockOAuthStateStore{states: make(map[string]oauth.State)},
+	})
+	if err != nil {
+		t.Fatalf("NewPortalHandler: %v", err)
+	}
+
+	router := chi.NewRouter()
+	router.Mount("/portal", handler.Router())
+	env.router = router
+	return env
+}
+
+// login runs the OAuth flow through the portal and returns the callback
+// response.
+func (env *oauthTestEnv) login(t *testing.T) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	env.router.ServeHTTP(w, httptest.NewRequest("GET", "/portal/oauth/google", nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("start Status = %d, want %d", w.Code, http.StatusFound)
+	}
+	authURL, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("parse auth URL: %v", err)
+	}
+	q := authURL.Query()
+	env.challenge = q.Get("code_challenge")
+
+	// The provider redirects the same browser back with a code
+	req := httptest.NewRequest("GET", "/portal/oauth/google/callback?"+url.Values{
+		"code":  {"good-code"},
+		"state": {q.Get("state")},
+	}.Encode(), nil)
+	for _, c := range w.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	w = httptest.NewRecorder()
+	env.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestPortalOAuth_CreatesUserOnFirstLogin(t *testing.T) {
+	env := newOAuthTestEnv(t)
+
+	w := env.login(t)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/portal/dashboard" {
+		t.Fatalf("Status = %d, Location = %s", w.Code, w.Header().Get("Location"))
+	}
+	if !hasPortalCookie(w) {
+		t.Error("expected a session cookie")
+	}
+
+	if len(env.users.users) != 1 {
+		t.Fatalf("users = %d, want 1", len(env.users.users))
+	}
+	var user ports.User
+	for _, u := range env.users.users {
+		user = u
+	}
+	if user.Email != "ada@example.com" || user.Name != "Ada Lovelace" || user.Status != "active" {
+		t.Errorf("user = %+v", user)
+	}
+	if len(user.PasswordHash) != 0 {
+		t.Error("OAuth users should not get a password")
+	}
+
+	if len(env.identities.identities) != 1 {
+		t.Fatalf("identities = %d, want 1", len(env.identities.identities))
+	}
+	for _, identity := range env.identities.identities {
+		if identity.UserID != user.ID || identity.Provider != oauth.ProviderGoogle || identity.ProviderUserID != "g-42" {
+			t.Errorf("identity = %+v", identity)
+		}
+		if identity.AccessToken != "at-123" || identity.TokenExpiresAt == nil {
+			t.Errorf("identity tokens = %q, %v", identity.AccessToken, identity.TokenExpiresAt)
+		}
+	}
+
+	// Logging in again finds the same user by identity, even if the email changed
+	env.profile = `{"id":"g-42","email":"ada@new.example.com","verified_email":true,"name":"Ada"}`
+	if w := env.login(t); w.Code != http.StatusFound || !hasPortalCookie(w) {
+		t.Errorf("second login Status = %d", w.Code)
+	}
+	if len(env.users.users) != 1 || len(env.identities.identities) != 1 {
+		t.Errorf("second login created users = %d, identities = %d", len(env.users.users), len(env.identities.identities))
+	}
+}
+
+func TestPortalOAuth_LinksExistingEmail(t *testing.T) {
+	env := newOAuthTestEnv(t)
+	env.users.users["user1"] = ports.User{
+		ID:           "user1",
+		Email:        "ada@example.com",
+		PasswordHash: []byte("hashed_Password123"),
+		Status:       "active",
+	}
+
+	w := env.login(t)
+	if w.Code != http.StatusFound || !hasPortalCookie(w) {
+		t.Fatalf("Status = %d, Location = %s", w.Code, w.Header().Get("Location"))
+	}
+	if len(env.users.users) != 1 {
+		t.Errorf("users = %d, want the existing user only", len(env.users.users))
+	}
+	for _, identity := range env.identities.identities {
+		if identity.UserID != "user1" {
+			t.Errorf("identity linked to %s, want user1", identity.UserID)
+		}
+	}
+	if len(env.identities.identities) != 1 {
+		t.Errorf("identities = %d, want 1", len(env.identities.identities))
+	}
+}
+
+func TestPortalOAuth_RefusesToLinkUnverifiedEmail(t *testing.T) {
+	env := newOAuthTestEnv(t)
+	env.profile = `{"id":"g-66","email":"ada@example.com","verified_email":false,"name":"Not Ada"}`
+	env.users.users["user1"] = ports.User{ID: "user1", Email: "ada@example.com", Status: "active"}
+
+	w := env.login(t)
+	if w.Header().Get("Location") != "/portal/login?oauth_error=email_in_use" || hasPortalCookie(w) {
+		t.Errorf("Location = %s, want email_in_use", w.Header().Get("Location"))
+	}
+	if len(env.identities.identities) != 0 {
+		t.Error("unverified email must not be linked")
+	}
+
+	// Auto-linking can be turned off entirely
+	env.profile = `{"id":"g-42","email":"ada@example.com","verified_email":true}`
+	env.settings.settings[settings.KeyOAuthAutoLinkEmail] = "false"
+	if w := env.login(t); w.Header().Get("Location") != "/portal/login?oauth_error=email_in_use" {
+		t.Errorf("auto-link off Location = %s, want email_in_use", w.Header().Get("Location"))
+	}
+}
+
+func TestPortalOAuth_RegistrationDisabled(t *testing.T) {
+	env := newOAuthTestEnv(t)
+	env.settings.settings[settings.KeyOAuthAllowRegistration] = "false"
+
+	w := env.login(t)
+	if w.Header().Get("Location") != "/portal/login?oauth_error=registration_disabled" {
+		t.Errorf("Location = %s, want registration_disabled", w.Header().Get("Location"))
+	}
+	if len(env.users.users) != 0 {
+		t.Error("no user should be created")
+	}
+
+	// The login page explains why
+	lw := httptest.NewRecorder()
+	env.router.ServeHTTP(lw, httptest.NewRequest("GET", w.Header().Get("Location"), nil))
+	if !strings.Contains(lw.Body.String(), oauthLoginErrors["registration_disabled"]) {
+		t.Error("login page should show the OAuth error")
+	}
+}
+
+func TestPortalOAuth_InvalidState(t *testing.T) {
+	env := newOAuthTestEnv(t)
+
+	w := httptest.NewRecorder()
+	env.router.ServeHTTP(w, httptest.NewRequest("GET", "/portal/oauth/google/callback?code=good-code&state=forged", nil))
+	if w.Header().Get("Location") != "/portal/login?oauth_error=failed" || hasPortalCookie(w) {
+		t.Errorf("Location = %s, want failed", w.Header().Get("Location"))
+	}
+
+	w = httptest.NewRecorder()
+	env.router.ServeHTTP(w, httptest.NewRequest("GET", "/portal/oauth/google/callback?error=access_denied", nil))
+	if w.Header().Get("Location") != "/portal/login?oauth_error=denied" {
+		t.Errorf("Location = %s, want denied", w.Header().Get("Location"))
+	}
+
+	w = httptest.NewRecorder()
+	env.router.ServeHTTP(w, httptest.NewRequest("GET", "/portal/oauth/facebook", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown provider Status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestPortalOAuth_StateBoundToBrowser(t *testing.T) {
+	env := newOAuthTestEnv(t)
+
+	// An attacker starts a login and stops before the callback
+	w := httptest.NewRecorder()
+	env.router.ServeHTTP(w, httptest.NewRequest("GET", "/portal/oauth/google", nil))
+	var stateCookie *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == oauthStateCookie {
+			stateCookie = c
+		}
+	}
+	if stateCookie == nil || !stateCookie.HttpOnly || stateCookie.MaxAge <= 0 {
+		t.Fatalf("state cookie = %+v, want a short-lived HttpOnly cookie", stateCookie)
+	}
+	authURL, _ := url.Parse(w.Header().Get("Location"))
+	state := authURL.Query().Get("state")
+	env.challenge = authURL.Query().Get("code_challenge")
+	callback := "/portal/oauth/google/callback?" + url.Values{"code": {"good-code"}, "state": {state}}.Encode()
+
+	// The victim's browser follows the attacker's callback URL
+	w = httptest.NewRecorder()
+	env.router.ServeHTTP(w, httptest.NewRequest("GET", callback, nil))
+	if w.Header().Get("Location") != "/portal/login?oauth_error=failed" || hasPortalCookie(w) {
+		t.Errorf("callback without state cookie: Location = %s, want failed", w.Header().Get("Location"))
+	}
+
+	// A state cookie from another login doesn't match either
+	req := httptest.NewRequest("GET", callback, nil)
+	req.AddCookie(&http.Cookie{Name: oauthStateCookie, Value: "other-state"})
+	w = httptest.NewRecorder()
+	env.router.ServeHTTP(w, req)
+	if w.Header().Get("Location") != "/portal/login?oauth_error=failed" || hasPortalCookie(w) {
+		t.Errorf("callback with mismatched state cookie: Location = %s, want failed", w.Header().Get("Location"))
+	}
+	if len(env.users.users) != 0 {
+		t.Error("no user should be created")
+	}
+
+	// The browser that started the login finishes it, and the cookie is cleared
+	w = env.login(t)
+	if w.Code != http.StatusFound || !hasPortalCookie(w) {
+		t.Fatalf("login Status = %d, Location = %s", w.Code, w.Header().Get("Location"))
+	}
+	for _, c := range w.Result().Cookies() {
+		if c.Name == oauthStateCookie && c.MaxAge >= 0 {
+			t.Errorf("state cookie not cleared after the callback: %+v", c)
+		}
+	}
+}
+
+func TestPortalOAuth_LoginPageButtons(t *testing.T) {
+	env := newOAuthTestEnv(t)
+
+	w := httptest.NewRecorder()
+	env.router.ServeHTTP(w, httptest.NewRequest("GET", "/portal/login", nil))
+	if !strings.Contains(w.Body.String(), `href="/portal/oauth/google"`) || !strings.Contains(w.Body.String(), "Continue with Google") {
+		t.Error("login page should offer Google login")
+	}
+
+	// Without providers there are no buttons
+	handler, _, _, _ := newTestPortalHandler()
+	if strings.Contains(handler.renderLoginPage("", "", "", nil), "/portal/oauth/") {
+		t.Error("login page should not offer OAuth without providers")
+	}
+}
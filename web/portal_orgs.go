@@ -0,0 +1,906 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/artpar/apigate/domain/group"
+	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/ports"
+	"github.com/go-chi/chi/v5"
+)
+
+// portalOrgCookie holds the ID of the organization the portal is scoped to.
+const portalOrgCookie = "portal_org"
+
+// portalInviteCookie holds the signed token of an organization invite the
+// user followed a link for, until they are logged in to accept it.
+const portalInviteCookie = "portal_invite"
+
+// orgInviteTTL is how long an organization invite link stays valid.
+const orgInviteTTL = 7 * 24 * time.Hour
+
+// orgMessages are the organization page notices for the redirect query
+// params the organization handlers set.
+var orgMessages = map[string]string{
+	"created": "Organization created",
+	"added":   "Member added",
+	"role":    "Member role updated",
+	"removed": "Member removed",
+	"invited": "Invitation sent",
+	"revoked": "Invitation revoked",
+	"joined":  "You joined the organization",
+}
+
+// orgsEnabled reports whether the group stores backing organizations are
+// configured.
+func (h *PortalHandler) orgsEnabled() bool {
+	return h.groups != nil && h.groupMembers != nil
+}
+
+// invitesEnabled reports whether members are invited by email rather than
+// added directly.
+func (h *PortalHandler) invitesEnabled() bool {
+	return h.orgsEnabled() && h.groupInvites != nil
+}
+
+// applyActiveOrg scopes the portal to the organization in the org cookie,
+// if the user is still a member of it and it is active. A stale cookie is
+// cleared so the portal falls back to the user's own account.
+func (h *PortalHandler) applyActiveOrg(w http.ResponseWriter, r *http.Request, user *PortalUser) {
+	cookie, err := r.Cookie(portalOrgCookie)
+	if err != nil || cookie.Value == "" || !h.orgsEnabled() {
+		return
+	}
+
+	ctx := r.Context()
+	if member, err := h.groupMembers.GetByGroupAndUser(ctx, cookie.Value, user.ID); err == nil {
+		if g, err := h.groups.Get(ctx, cookie.Value); err == nil && g.IsActive() {
+			user.Org = &g
+			user.OrgRole = member.Role
+			return
+		}
+	}
+	h.setOrgCookie(w, "")
+}
+
+// setOrgCookie scopes the portal to an organization, or back to the user's
+// own account if orgID is empty.
+func (h *PortalHandler) setOrgCookie(w http.ResponseWriter, orgID string) {
+	maxAge := 0
+	if orgID == "" {
+		maxAge = -1
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     portalOrgCookie,
+		Value:    orgID,
+		Path:     "/portal",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   maxAge,
+	})
+}
+
+// accountKeys returns the keys of the portal's current scope: the active
+// organization's keys, or the user's own keys outside any organization.
+func (h *PortalHandler) accountKeys(ctx context.Context, user *PortalUser) ([]key.Key, error) {
+	if user.Org != nil {
+		groupKeys, ok := h.keys.(ports.GroupKeyStore)
+		if !ok {
+			return nil, errors.New("key store does not support organization keys")
+		}
+		return groupKeys.ListByGroup(ctx, user.Org.ID)
+	}
+
+	keys, err := h.keys.ListByUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	var own []key.Key
+	for _, k := range keys {
+		if k.GroupID == "" {
+			own = append(own, k)
+		}
+	}
+	return own, nil
+}
+
+// accountKeysByLabels returns the active account's keys matching the label
+// selector, using the key store's label filter for personal keys if it has one.
+func (h *PortalHandler) accountKeysByLabels(ctx context.Context, user *PortalUser, sel key.LabelSelector) ([]key.Key, error) {
+	labelKeys, ok := h.keys.(ports.KeyLabelStore)
+	if user.Org != nil || !ok || len(sel) == 0 {
+		keys, err := h.accountKeys(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		return key.FilterByLabels(keys, sel), nil
+	}
+
+	keys, err := labelKeys.ListByUserLabels(ctx, user.ID, sel)
+	if err != nil {
+		return nil, err
+	}
+	var own []key.Key
+	for _, k := range keys {
+		if k.GroupID == "" {
+			own = append(own, k)
+		}
+	}
+	return own, nil
+}
+
+// orgMember is a membership with the member's account details for display.
+type orgMember struct {
+	group.Member
+	Email string
+	Name  string
+}
+
+// -----------------------------------------------------------------------------
+// Organizations
+// -----------------------------------------------------------------------------
+
+// OrgsPage lists the user's organizations, to switch between them and the
+// user's own account, and to create a new one.
+func (h *PortalHandler) OrgsPage(w http.ResponseWriter, r *http.Request) {
+	h.renderOrgs(w, r, http.StatusOK, orgMessages[r.URL.Query().Get("done")], "")
+}
+
+// renderOrgs renders the organizations page with a notice or error.
+func (h *PortalHandler) renderOrgs(w http.ResponseWriter, r *http.Request, status int, success, errMsg string) {
+	ctx := r.Context()
+	user := getPortalUser(ctx)
+
+	if !h.orgsEnabled() {
+		h.renderError(w, http.StatusNotFound, "Organizations are not available")
+		return
+	}
+
+	orgs, err := h.groups.ListByUser(ctx, user.ID)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to list organizations")
+	}
+	roles := make(map[string]group.Role)
+	if members, err := h.groupMembers.ListByUser(ctx, user.ID); err == nil {
+		for _, m := range members {
+			roles[m.GroupID] = m.Role
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write([]byte(h.renderOrgsPage(user, orgs, roles, success, errMsg)))
+}
+
+// CreateOrg creates an organization owned by the user and switches the
+// portal to it. New organizations start on the default plan.
+func (h *PortalHandler) CreateOrg(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getPortalUser(ctx)
+
+	if !h.orgsEnabled() {
+		h.renderError(w, http.StatusNotFound, "Organizations are not available")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		h.renderError(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	name := strings.TrimSpace(r.FormValue("name"))
+	req := group.CreateGroupRequest{Name: name, Slug: group.GenerateSlug(name)}
+	if result := group.ValidateCreateGroup(req); !result.Valid {
+		h.renderOrgs(w, r, http.StatusBadRequest, "", getFirstError(result.Errors))
+		return
+	}
+
+	// Slugs are unique across all organizations, so disambiguate a taken one
+	id := group.GenerateID()
+	if _, err := h.groups.GetBySlug(ctx, req.Slug); err == nil {
+		req.Slug += "-" + strings.TrimPrefix(id, "grp_")[:6]
+	}
+
+	now := time.Now().UTC()
+	org := group.Group{
+		ID:        id,
+		Name:      name,
+		Slug:      req.Slug,
+		OwnerID:   user.ID,
+		PlanID:    h.defaultPlanID(ctx),
+		Status:    group.StatusActive,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := h.groups.Create(ctx, org); err != nil {
+		h.logger.Error().Err(err).Msg("failed to create organization")
+		h.renderError(w, http.StatusInternalServerError, "Failed to create organization")
+		return
+	}
+
+	owner := group.Member{
+		ID:       group.GenerateMemberID(),
+		GroupID:  org.ID,
+		UserID:   user.ID,
+		Role:     group.RoleOwner,
+		JoinedAt: now,
+	}
+	if err := h.groupMembers.Create(ctx, owner); err != nil {
+		h.logger.Error().Err(err).Msg("failed to add organization owner")
+		h.renderError(w, http.StatusInternalServerError, "Failed to create organization")
+		return
+	}
+
+	h.logger.Info().Str("group_id", org.ID).Str("user_id", user.ID).Msg("organization created")
+	h.setOrgCookie(w, org.ID)
+	http.Redirect(w, r, "/portal/orgs?done=created", http.StatusFound)
+}
+
+// SwitchOrg scopes the portal to one of the user's organizations, or back to
+// their own account when no organization is given.
+func (h *PortalHandler) SwitchOrg(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getPortalUser(ctx)
+
+	orgID := r.FormValue("org_id")
+	if orgID != "" {
+		if !h.orgsEnabled() {
+			h.renderError(w, http.StatusNotFound, "Organization not found")
+			return
+		}
+		if _, err := h.groupMembers.GetByGroupAndUser(ctx, orgID, user.ID); err != nil {
+			h.renderError(w, http.StatusNotFound, "Organization not found")
+			return
+		}
+		if g, err := h.groups.Get(ctx, orgID); err != nil || !g.IsActive() {
+			h.renderError(w, http.StatusNotFound, "Organization not found")
+			return
+		}
+	}
+
+	h.setOrgCookie(w, orgID)
+	http.Redirect(w, r, "/portal/dashboard", http.StatusFound)
+}
+
+// OrgMembersPage lists the members of the active organization.
+func (h *PortalHandler) OrgMembersPage(w http.ResponseWriter, r *http.Request) {
+	h.renderOrgMembers(w, r, http.StatusOK, orgMessages[r.URL.Query().Get("done")], "")
+}
+
+// renderOrgMembers renders the active organization's members page with a
+// notice or error. Without an active organization it goes to the
+// organizations page instead.
+func (h *PortalHandler) renderOrgMembers(w http.ResponseWriter, r *http.Request, status int, success, errMsg string) {
+	ctx := r.Context()
+	user := getPortalUser(ctx)
+
+	if user.Org == nil {
+		http.Redirect(w, r, "/portal/orgs", http.StatusFound)
+		return
+	}
+
+	members, err := h.groupMembers.ListByGroup(ctx, user.Org.ID)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to list organization members")
+		h.renderError(w, http.StatusInternalServerError, "Failed to load members")
+		return
+	}
+
+	rows := make([]orgMember, 0, len(members))
+	for _, m := range members {
+		row := orgMember{Member: m}
+		if u, err := h.users.Get(ctx, m.UserID); err == nil {
+			row.Email, row.Name = u.Email, u.Name
+		}
+		rows = append(rows, row)
+	}
+
+	var invites []group.Invite
+	if h.invitesEnabled() && user.OrgRole.CanInvite() {
+		if invites, err = h.groupInvites.ListByGroup(ctx, user.Org.ID); err != nil {
+			h.logger.Error().Err(err).Msg("failed to list organization invites")
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write([]byte(h.renderOrgMembersPage(user, rows, invites, success, errMsg)))
+}
+
+// AddOrgMember adds the user with the given email to the active
+// organization, or emails them an invite if invites are enabled. Only owners
+// and admins may add members, and no one is added as an owner.
+func (h *PortalHandler) AddOrgMember(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getPortalUser(ctx)
+
+	if user.Org == nil {
+		http.Redirect(w, r, "/portal/orgs", http.StatusFound)
+		return
+	}
+	if !user.OrgRole.CanInvite() {
+		h.renderError(w, http.StatusForbidden, "Only organization owners and admins can add members")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		h.renderError(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	req := group.InviteRequest{
+		Email: strings.TrimSpace(r.FormValue("email")),
+		Role:  group.Role(r.FormValue("role")),
+	}
+	if result := group.ValidateInvite(req); !result.Valid {
+		h.renderOrgMembers(w, r, http.StatusBadRequest, "", getFirstError(result.Errors))
+		return
+	}
+	if h.invitesEnabled() {
+		h.inviteOrgMember(w, r, user, req)
+		return
+	}
+
+	invitee, err := h.users.GetByEmail(ctx, req.Email)
+	if err != nil {
+		h.renderOrgMembers(w, r, http.StatusBadRequest, "", "No account found for that email. Ask them to sign up first.")
+		return
+	}
+	if _, err := h.groupMembers.GetByGroupAndUser(ctx, user.Org.ID, invitee.ID); err == nil {
+		h.renderOrgMembers(w, r, http.StatusBadRequest, "", "That user is already a member")
+		return
+	}
+
+	now := time.Now().UTC()
+	member := group.Member{
+		ID:        group.GenerateMemberID(),
+		GroupID:   user.Org.ID,
+		UserID:    invitee.ID,
+		Role:      req.Role,
+		InvitedBy: user.ID,
+		InvitedAt: &now,
+		JoinedAt:  now,
+	}
+	if err := h.groupMembers.Create(ctx, member); err != nil {
+		h.logger.Error().Err(err).Msg("failed to add organization member")
+		h.renderError(w, http.StatusInternalServerError, "Failed to add member")
+		return
+	}
+
+	h.logger.Info().Str("group_id", user.Org.ID).Str("user_id", invitee.ID).Str("role", string(req.Role)).Msg("organization member added")
+	http.Redirect(w, r, "/portal/orgs/members?done=added", http.StatusFound)
+}
+
+// inviteOrgMember emails a link to join the active organization. It replaces
+// any earlier invite for the same email, so inviting again resends the link.
+func (h *PortalHandler) inviteOrgMember(w http.ResponseWriter, r *http.Request, user *PortalUser, req group.InviteRequest) {
+	ctx := r.Context()
+
+	if invitee, err := h.users.GetByEmail(ctx, req.Email); err == nil {
+		if _, err := h.groupMembers.GetByGroupAndUser(ctx, user.Org.ID, invitee.ID); err == nil {
+			h.renderOrgMembers(w, r, http.StatusBadRequest, "", "That user is already a member")
+			return
+		}
+	}
+
+	if pending, err := h.groupInvites.ListByGroup(ctx, user.Org.ID); err == nil {
+		for _, inv := range pending {
+			if strings.EqualFold(inv.Email, req.Email) {
+				if err := h.groupInvites.Delete(ctx, inv.ID); err != nil {
+					h.logger.Warn().Err(err).Str("invite_id", inv.ID).Msg("failed to delete replaced invite")
+				}
+			}
+		}
+	}
+	if _, err := h.groupInvites.DeleteExpired(ctx); err != nil {
+		h.logger.Warn().Err(err).Msg("failed to delete expired invites")
+	}
+
+	now := time.Now().UTC()
+	inv := group.Invite{
+		ID:        group.GenerateInviteID(),
+		GroupID:   user.Org.ID,
+		Email:     req.Email,
+		Role:      req.Role,
+		InvitedBy: user.ID,
+		Token:     group.GenerateInviteToken(),
+		ExpiresAt: now.Add(orgInviteTTL),
+		CreatedAt: now,
+	}
+	if err := h.groupInvites.Create(ctx, inv); err != nil {
+		h.logger.Error().Err(err).Msg("failed to create organization invite")
+		h.renderError(w, http.StatusInternalServerError, "Failed to invite member")
+		return
+	}
+
+	if err := h.emailSender.Send(ctx, h.orgInviteEmail(user, inv)); err != nil {
+		h.logger.Error().Err(err).Str("email", inv.Email).Msg("failed to send organization invite")
+		if err := h.groupInvites.Delete(ctx, inv.ID); err != nil {
+			h.logger.Warn().Err(err).Str("invite_id", inv.ID).Msg("failed to delete unsent invite")
+		}
+		h.renderOrgMembers(w, r, http.StatusInternalServerError, "", "Failed to send the invitation email. Please try again.")
+		return
+	}
+
+	h.logger.Info().Str("group_id", user.Org.ID).Str("invite_id", inv.ID).Str("role", string(req.Role)).Msg("organization member invited")
+	http.Redirect(w, r, "/portal/orgs/members?done=invited", http.StatusFound)
+}
+
+// orgInviteEmail builds the email with the link to accept an invite.
+func (h *PortalHandler) orgInviteEmail(inviter *PortalUser, inv group.Invite) ports.EmailMessage {
+	link := strings.TrimSuffix(h.baseURL, "/") + "/portal/orgs/join?token=" + url.QueryEscape(group.SignInviteToken(inv.Token, h.inviteSecret))
+	inviterName := inviter.Name
+	if inviterName == "" {
+		inviterName = inviter.Email
+	}
+	orgName := inviter.Org.Name
+	days := int(orgInviteTTL.Hours() / 24)
+
+	return ports.EmailMessage{
+		To:      inv.Email,
+		Subject: fmt.Sprintf("You're invited to join %s on %s", orgName, h.appName),
+		HTMLBody: fmt.Sprintf(`<h2>Join %s on %s</h2>
+<p>%s has invited you to join the %s organization as %s.</p>
+<p><a href="%s" style="display:inline-block;background:#4f46e5;color:white;padding:12px 24px;text-decoration:none;border-radius:4px;">Accept Invitation</a></p>
+<p>This invitation expires in %d days. If you don't have an account yet, you'll be asked to sign up first.</p>
+<p style="color:#666;font-size:12px;">If you didn't expect this invitation, you can ignore this email.</p>`,
+			html.EscapeString(orgName), html.EscapeString(h.appName), html.EscapeString(inviterName), html.EscapeString(orgName), inv.Role, html.EscapeString(link), days),
+		TextBody: fmt.Sprintf("%s has invited you to join the %s organization on %s as %s.\n\nAccept invitation: %s\n\nThis invitation expires in %d days.",
+			inviterName, orgName, h.appName, inv.Role, link, days),
+	}
+}
+
+// RevokeOrgInvite cancels a pending invite to the active organization.
+func (h *PortalHandler) RevokeOrgInvite(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getPortalUser(ctx)
+
+	if user.Org == nil || !h.invitesEnabled() {
+		http.Redirect(w, r, "/portal/orgs", http.StatusFound)
+		return
+	}
+	if !user.OrgRole.CanInvite() {
+		h.renderError(w, http.StatusForbidden, "Only organization owners and admins can manage invitations")
+		return
+	}
+
+	inv, err := h.groupInvites.Get(ctx, chi.URLParam(r, "id"))
+	if err != nil || inv.GroupID != user.Org.ID {
+		h.renderError(w, http.StatusNotFound, "Invitation not found")
+		return
+	}
+	if err := h.groupInvites.Delete(ctx, inv.ID); err != nil {
+		h.logger.Error().Err(err).Str("invite_id", inv.ID).Msg("failed to revoke organization invite")
+		h.renderError(w, http.StatusInternalServerError, "Failed to revoke invitation")
+		return
+	}
+
+	http.Redirect(w, r, "/portal/orgs/members?done=revoked", http.StatusFound)
+}
+
+// JoinOrg follows an invite link. The invite is kept in a cookie and
+// accepted once the user is logged in, so new users can sign up first.
+func (h *PortalHandler) JoinOrg(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if !h.invitesEnabled() {
+		h.renderError(w, http.StatusNotFound, "Organizations are not available")
+		return
+	}
+
+	signed := r.URL.Query().Get("token")
+	inv, status, errMsg := h.orgInvite(ctx, signed)
+	if errMsg != "" {
+		h.renderError(w, status, errMsg)
+		return
+	}
+
+	h.setInviteCookie(w, signed, inv.ExpiresAt)
+
+	// Logged-in users accept right away; the rest log in or sign up
+	next := "/portal/orgs"
+	if cookie, err := r.Cookie("portal_token"); err != nil || cookie.Value == "" {
+		if _, err := h.users.GetByEmail(ctx, inv.Email); err == nil {
+			next = "/portal/login?email=" + url.QueryEscape(inv.Email)
+		} else {
+			next = "/portal/signup?email=" + url.QueryEscape(inv.Email)
+		}
+	}
+	http.Redirect(w, r, next, http.StatusFound)
+}
+
+// orgInvite looks up the invite for a signed invite link token. An expired
+// invite is deleted. On failure it returns the status and message to show.
+func (h *PortalHandler) orgInvite(ctx context.Context, signed string) (group.Invite, int, string) {
+	token, ok := group.VerifyInviteToken(signed, h.inviteSecret)
+	if !ok {
+		return group.Invite{}, http.StatusBadRequest, "Invalid invitation link"
+	}
+	inv, err := h.groupInvites.GetByToken(ctx, token)
+	if err != nil {
+		return group.Invite{}, http.StatusBadRequest, "Invalid invitation link. It may have been revoked or already used."
+	}
+	if inv.IsExpired() {
+		if err := h.groupInvites.Delete(ctx, inv.ID); err != nil {
+			h.logger.Warn().Err(err).Str("invite_id", inv.ID).Msg("failed to delete expired invite")
+		}
+		return group.Invite{}, http.StatusGone, "This invitation has expired. Ask for a new one."
+	}
+	return inv, 0, ""
+}
+
+// acceptPendingInvite accepts the invite in the invite cookie, making the
+// user a member of its organization and switching the portal to it. It
+// reports whether it has responded to the request.
+func (h *PortalHandler) acceptPendingInvite(w http.ResponseWriter, r *http.Request, user *PortalUser) bool {
+	cookie, err := r.Cookie(portalInviteCookie)
+	if err != nil || cookie.Value == "" || !h.invitesEnabled() {
+		return false
+	}
+	h.setInviteCookie(w, "", time.Time{})
+
+	ctx := r.Context()
+	inv, status, errMsg := h.orgInvite(ctx, cookie.Value)
+	if errMsg != "" {
+		h.renderError(w, status, errMsg)
+		return true
+	}
+
+	// The link may have been forwarded; only the invited email can use it
+	if !strings.EqualFold(inv.Email, user.Email) {
+		h.renderError(w, http.StatusForbidden, fmt.Sprintf("This invitation was sent to %s. Log in with that account to accept it.", inv.Email))
+		return true
+	}
+
+	g, err := h.groups.Get(ctx, inv.GroupID)
+	if err != nil || !g.IsActive() {
+		h.renderError(w, http.StatusNotFound, "Organization not found")
+		return true
+	}
+
+	if _, err := h.groupMembers.GetByGroupAndUser(ctx, g.ID, user.ID); err != nil {
+		invitedAt := inv.CreatedAt
+		member := group.Member{
+			ID:        group.GenerateMemberID(),
+			GroupID:   g.ID,
+			UserID:    user.ID,
+			Role:      inv.Role,
+			InvitedBy: inv.InvitedBy,
+			InvitedAt: &invitedAt,
+			JoinedAt:  time.Now().UTC(),
+		}
+		if err := h.groupMembers.Create(ctx, member); err != nil {
+			h.logger.Error().Err(err).Msg("failed to add organization member from invite")
+			h.renderError(w, http.StatusInternalServerError, "Failed to join organization")
+			return true
+		}
+		h.logger.Info().Str("group_id", g.ID).Str("user_id", user.ID).Str("role", string(inv.Role)).Msg("organization invite accepted")
+	}
+
+	if err := h.groupInvites.Delete(ctx, inv.ID); err != nil {
+		h.logger.Warn().Err(err).Str("invite_id", inv.ID).Msg("failed to delete accepted invite")
+	}
+
+	h.setOrgCookie(w, g.ID)
+	http.Redirect(w, r, "/portal/orgs?done=joined", http.StatusFound)
+	return true
+}
+
+// setInviteCookie keeps a signed invite token until the invite expires, or
+// clears it if token is empty.
+func (h *PortalHandler) setInviteCookie(w http.ResponseWriter, token string, expiresAt time.Time) {
+	maxAge := -1
+	if token != "" {
+		maxAge = int(time.Until(expiresAt).Seconds())
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     portalInviteCookie,
+		Value:    token,
+		Path:     "/portal",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   maxAge,
+	})
+}
+
+// ChangeOrgMemberRole makes a member of the active organization an admin or
+// a regular member. Only the owner may change roles.
+func (h *PortalHandler) ChangeOrgMemberRole(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getPortalUser(ctx)
+
+	member, ok := h.orgMemberForChange(w, r, user, user.OrgRole.CanChangeRoles(), "Only the organization owner can change roles")
+	if !ok {
+		return
+	}
+
+	role := group.Role(r.FormValue("role"))
+	if role != group.RoleAdmin && role != group.RoleMember {
+		h.renderOrgMembers(w, r, http.StatusBadRequest, "", "Invalid role")
+		return
+	}
+
+	if err := h.groupMembers.Update(ctx, member.WithRole(role)); err != nil {
+		h.logger.Error().Err(err).Msg("failed to change organization member role")
+		h.renderError(w, http.StatusInternalServerError, "Failed to change role")
+		return
+	}
+	http.Redirect(w, r, "/portal/orgs/members?done=role", http.StatusFound)
+}
+
+// RemoveOrgMember removes a member from the active organization. Only owners
+// and admins may remove members, and the owner cannot be removed.
+func (h *PortalHandler) RemoveOrgMember(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getPortalUser(ctx)
+
+	member, ok := h.orgMemberForChange(w, r, user, user.OrgRole.CanRemoveMembers(), "Only organization owners and admins can remove members")
+	if !ok {
+		return
+	}
+
+	if err := h.groupMembers.Delete(ctx, member.ID); err != nil {
+		h.logger.Error().Err(err).Msg("failed to remove organization member")
+		h.renderError(w, http.StatusInternalServerError, "Failed to remove member")
+		return
+	}
+
+	h.logger.Info().Str("group_id", member.GroupID).Str("user_id", member.UserID).Msg("organization member removed")
+	http.Redirect(w, r, "/portal/orgs/members?done=removed", http.StatusFound)
+}
+
+// orgMemberForChange returns the member of the active organization named in
+// the URL, if the user is allowed to change them. The owner cannot be
+// changed. Otherwise it writes the response and returns false.
+func (h *PortalHandler) orgMemberForChange(w http.ResponseWriter, r *http.Request, user *PortalUser, allowed bool, forbidden string) (group.Member, bool) {
+	if user.Org == nil {
+		http.Redirect(w, r, "/portal/orgs", http.StatusFound)
+		return group.Member{}, false
+	}
+	if !allowed {
+		h.renderError(w, http.StatusForbidden, forbidden)
+		return group.Member{}, false
+	}
+
+	member, err := h.groupMembers.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil || member.GroupID != user.Org.ID {
+		h.renderError(w, http.StatusNotFound, "Member not found")
+		return group.Member{}, false
+	}
+	if member.Role == group.RoleOwner {
+		h.renderOrgMembers(w, r, http.StatusBadRequest, "", "The organization owner cannot be changed")
+		return group.Member{}, false
+	}
+	return member, true
+}
+
+// -----------------------------------------------------------------------------
+// Templates
+// -----------------------------------------------------------------------------
+
+func (h *PortalHandler) renderOrgsPage(user *PortalUser, orgs []group.Group, roles map[string]group.Role, success, errMsg string) string {
+	alertHTML := ""
+	if success != "" {
+		alertHTML = fmt.Sprintf(`<div class="alert alert-success">%s</div>`, html.EscapeString(success))
+	}
+	if errMsg != "" {
+		alertHTML = fmt.Sprintf(`<div class="alert alert-error">%s</div>`, html.EscapeString(errMsg))
+	}
+
+	switchBtn := func(orgID string) string {
+		return fmt.Sprintf(`<form method="POST" action="/portal/orgs/switch" style="display:inline"><input type="hidden" name="org_id" value="%s"><button type="submit" class="btn btn-sm btn-secondary">Switch</button></form>`, html.EscapeString(orgID))
+	}
+
+	var rows strings.Builder
+	personalAction := switchBtn("")
+	if user.Org == nil {
+		personalAction = `<span class="status-active">Current</span>`
+	}
+	fmt.Fprintf(&rows, `
+                    <tr>
+                        <td>Personal account</td>
+                        <td>-</td>
+                        <td>%s</td>
+                    </tr>`, personalAction)
+	for _, org := range orgs {
+		action := switchBtn(org.ID)
+		if user.Org != nil && user.Org.ID == org.ID {
+			action = `<span class="status-active">Current</span> <a href="/portal/orgs/members" class="btn btn-sm btn-secondary">Members</a>`
+		} else if !org.IsActive() {
+			action = `<span class="status-revoked">Suspended</span>`
+		}
+		fmt.Fprintf(&rows, `
+                    <tr>
+                        <td>%s</td>
+                        <td>%s</td>
+                        <td>%s</td>
+                    </tr>`, html.EscapeString(org.Name), html.EscapeString(string(roles[org.ID])), action)
+	}
+
+	return fmt.Sprintf(`
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Organizations - %s</title>
+    <style>%s</style>
+</head>
+<body>
+    %s
+    <main class="main-content">
+        <div class="page-header">
+            <h1>Organizations</h1>
+        </div>
+        %s
+        <div class="card">
+            <table class="table">
+                <thead>
+                    <tr>
+                        <th>Account</th>
+                        <th>Your Role</th>
+                        <th>Actions</th>
+                    </tr>
+                </thead>
+                <tbody>%s
+                </tbody>
+            </table>
+        </div>
+
+        <div class="card" style="margin-top: 24px;">
+            <h3 style="margin-bottom: 16px;">Create Organization</h3>
+            <p style="color: #666; margin-bottom: 12px;">Organizations share API keys, usage and quota between their members.</p>
+            <form method="POST" action="/portal/orgs">
+                <div class="form-group">
+                    <label for="org-name">Name</label>
+                    <input type="text" id="org-name" name="name" required placeholder="e.g., Acme Engineering">
+                </div>
+                <button type="submit" class="btn btn-primary">Create Organization</button>
+            </form>
+        </div>
+    </main>
+</body>
+</html>`, h.appName, portalCSS, h.renderPortalNav(user), alertHTML, rows.String())
+}
+
+func (h *PortalHandler) renderOrgMembersPage(user *PortalUser, members []orgMember, invites []group.Invite, success, errMsg string) string {
+	alertHTML := ""
+	if success != "" {
+		alertHTML = fmt.Sprintf(`<div class="alert alert-success">%s</div>`, html.EscapeString(success))
+	}
+	if errMsg != "" {
+		alertHTML = fmt.Sprintf(`<div class="alert alert-error">%s</div>`, html.EscapeString(errMsg))
+	}
+
+	var rows strings.Builder
+	for _, m := range members {
+		var actions []string
+		if m.Role != group.RoleOwner {
+			if user.OrgRole.CanChangeRoles() {
+				newRole, label := group.RoleAdmin, "Make admin"
+				if m.Role == group.RoleAdmin {
+					newRole, label = group.RoleMember, "Make member"
+				}
+				actions = append(actions, fmt.Sprintf(`<form method="POST" action="/portal/orgs/members/%s/role" style="display:inline"><input type="hidden" name="role" value="%s"><button type="submit" class="btn btn-sm btn-secondary">%s</button></form>`,
+					html.EscapeString(m.ID), newRole, label))
+			}
+			if user.OrgRole.CanRemoveMembers() {
+				actions = append(actions, fmt.Sprintf(`<form method="POST" action="/portal/orgs/members/%s/remove" style="display:inline" onsubmit="showConfirmModal(this, 'Remove this member from the organization?', 'Remove Member'); return false;"><button type="submit" class="btn btn-sm btn-danger">Remove</button></form>`,
+					html.EscapeString(m.ID)))
+			}
+		}
+		if len(actions) == 0 {
+			actions = append(actions, "-")
+		}
+
+		fmt.Fprintf(&rows, `
+                    <tr>
+                        <td>%s</td>
+                        <td>%s</td>
+                        <td>%s</td>
+                        <td>%s</td>
+                        <td>%s</td>
+                    </tr>`, html.EscapeString(m.Name), html.EscapeString(m.Email), html.EscapeString(string(m.Role)), m.JoinedAt.Format("Jan 2, 2006"), strings.Join(actions, " "))
+	}
+
+	invitesHTML := ""
+	if len(invites) > 0 {
+		var inviteRows strings.Builder
+		for _, inv := range invites {
+			fmt.Fprintf(&inviteRows, `
+                    <tr>
+                        <td>%s</td>
+                        <td>%s</td>
+                        <td>%s</td>
+                        <td><form method="POST" action="/portal/orgs/invites/%s/revoke" style="display:inline"><button type="submit" class="btn btn-sm btn-danger">Revoke</button></form></td>
+                    </tr>`, html.EscapeString(inv.Email), html.EscapeString(string(inv.Role)), inv.ExpiresAt.Format("Jan 2, 2006"), html.EscapeString(inv.ID))
+		}
+		invitesHTML = fmt.Sprintf(`
+        <div class="card" style="margin-top: 24px;">
+            <h3 style="margin-bottom: 16px;">Pending Invitations</h3>
+            <table class="table">
+                <thead>
+                    <tr>
+                        <th>Email</th>
+                        <th>Role</th>
+                        <th>Expires</th>
+                        <th>Actions</th>
+                    </tr>
+                </thead>
+                <tbody>%s
+                </tbody>
+            </table>
+        </div>`, inviteRows.String())
+	}
+
+	addForm := ""
+	if user.OrgRole.CanInvite() {
+		title, button := "Add Member", "Add Member"
+		if h.invitesEnabled() {
+			title, button = "Invite Member", "Send Invitation"
+		}
+		addForm = fmt.Sprintf(`
+        <div class="card" style="margin-top: 24px;">
+            <h3 style="margin-bottom: 16px;">%s</h3>
+            <form method="POST" action="/portal/orgs/members">
+                <div class="form-group">
+                    <label for="member-email">Email</label>
+                    <input type="email" id="member-email" name="email" required placeholder="teammate@example.com">
+                </div>
+                <div class="form-group">
+                    <label for="member-role">Role</label>
+                    <select id="member-role" name="role">
+                        <option value="member">Member - uses keys and views usage</option>
+                        <option value="admin">Admin - also manages keys and members</option>
+                    </select>
+                </div>
+                <button type="submit" class="btn btn-primary">%s</button>
+            </form>
+        </div>`, title, button)
+	}
+
+	return fmt.Sprintf(`
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Members - %s</title>
+    <style>%s</style>
+</head>
+<body>
+    %s
+    <main class="main-content">
+        <div class="page-header">
+            <h1>%s Members</h1>
+        </div>
+        %s
+        <div class="card">
+            <table class="table">
+                <thead>
+                    <tr>
+                        <th>Name</th>
+                        <th>Email</th>
+                        <th>Role</th>
+                        <th>Joined</th>
+                        <th>Actions</th>
+                    </tr>
+                </thead>
+                <tbody>%s
+                </tbody>
+            </table>
+        </div>
+        %s%s
+        <a href="/portal/orgs" class="btn btn-secondary" style="margin-top: 24px;">Back to Organizations</a>
+    </main>
+    %s
+</body>
+</html>`, h.appName, portalCSS, h.renderPortalNav(user), html.EscapeString(user.Org.Name), alertHTML, rows.String(), invitesHTML, addForm, portalConfirmJS)
+}
@@ -0,0 +1,813 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artpar/apigate/adapters/email"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/domain/group"
+	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/domain/usage"
+	"github.com/artpar/apigate/ports"
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+)
+
+var errGroupNotFound = errors.New("not found")
+
+type mockGroupStore struct {
+	mu      sync.Mutex
+	groups  map[string]group.Group
+	members *mockGroupMemberStore
+}
+
+func (m *mockGroupStore) Get(ctx context.Context, id string) (group.Group, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	g, ok := m.groups[id]
+	if !ok {
+		return group.Group{}, errGroupNotFound
+	}
+	return g, nil
+}
+
+func (m *mockGroupStore) GetBySlug(ctx context.Context, slug string) (group.Group, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, g := range m.groups {
+		if g.Slug == slug {
+			return g, nil
+		}
+	}
+	return group.Group{}, errGroupNotFound
+}
+
+func (m *mockGroupStore) Create(ctx context.Context, g group.Group) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.groups[g.ID] = g
+	return nil
+}
+
+func (m *mockGroupStore) Update(ctx context.Context, g group.Group) error {
+	return m.Create(ctx, g)
+}
+
+func (m *mockGroupStore) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.groups, id)
+	return nil
+}
+
+func (m *mockGroupStore) ListByUser(ctx context.Context, userID string) ([]group.Group, error) {
+	members, _ := m.members.ListByUser(ctx, userID)
+	var out []group.Group
+	for _, mem := range members {
+		if g, err := m.Get(ctx, mem.GroupID); err == nil {
+			out = append(out, g)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockGroupStore) ListOwned(ctx context.Context, userID string) ([]group.Group, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []group.Group
+	for _, g := range m.groups {
+		if g.OwnerID == userID {
+			out = append(out, g)
+		}
+	}
+	return out, nil
+}
+
+type mockGroupInviteStore struct {
+	mu      sync.Mutex
+	invites map[string]group.Invite
+}
+
+func (m *mockGroupInviteStore) Get(ctx context.Context, id string) (group.Invite, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	inv, ok := m.invites[id]
+	if !ok {
+		return group.Invite{}, errGroupNotFound
+	}
+	return inv, nil
+}
+
+func (m *mockGroupInviteStore) GetByToken(ctx context.Context, token string) (group.Invite, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, inv := range m.invites {
+		if inv.Token == token {
+			return inv, nil
+		}
+	}
+	return group.Invite{}, errGroupNotFound
+}
+
+func (m *mockGroupInviteStore) Create(ctx context.Context, inv group.Invite) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.invites[inv.ID] = inv
+	return nil
+}
+
+func (m *mockGroupInviteStore) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.invites[id]; !ok {
+		return errGroupNotFound
+	}
+	delete(m.invites, id)
+	return nil
+}
+
+func (m *mockGroupInviteStore) ListByGroup(ctx context.Context, groupID string) ([]group.Invite, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []group.Invite
+	for _, inv := range m.invites {
+		if inv.GroupID == groupID && inv.IsValid() {
+			out = append(out, inv)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockGroupInviteStore) ListByEmail(ctx context.Context, email string) ([]group.Invite, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []group.Invite
+	for _, inv := range m.invites {
+		if inv.Email == email && inv.IsValid() {
+			out = append(out, inv)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockGroupInviteStore) DeleteExpired(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for id, inv := range m.invites {
+		if inv.IsExpired() {
+			delete(m.invites, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+type mockGroupMemberStore struct {
+	mu      sync.Mutex
+	members map[string]group.Member
+}
+
+func (m *mockGroupMemberStore) Get(ctx context.Context, id string) (group.Member, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mem, ok := m.members[id]
+	if !ok {
+		return group.Member{}, errGroupNotFound
+	}
+	return mem, nil
+}
+
+func (m *mockGroupMemberStore) GetByGroupAndUser(ctx context.Context, groupID, userID string) (group.Member, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, mem := range m.members {
+		if mem.GroupID == groupID && mem.UserID == userID {
+			return mem, nil
+		}
+	}
+	return group.Member{}, errGroupNotFound
+}
+
+func (m *mockGroupMemberStore) Create(ctx context.Context, mem group.Member) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.members[mem.ID] = mem
+	return nil
+}
+
+func (m *mockGroupMemberStore) Update(ctx context.Context, mem group.Member) error {
+	return m.Create(ctx, mem)
+}
+
+func (m *mockGroupMemberStore) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.members, id)
+	return nil
+}
+
+func (m *mockGroupMemberStore) ListByGroup(ctx context.Context, groupID string) ([]group.Member, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []group.Member
+	for _, mem := range m.members {
+		if mem.GroupID == groupID {
+			out = append(out, mem)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockGroupMemberStore) ListByUser(ctx context.Context, userID string) ([]group.Member, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []group.Member
+	for _, mem := range m.members {
+		if mem.UserID == userID {
+			out = append(out, mem)
+		}
+	}
+	return out, nil
+}
+
+// orgFixture is a portal with an organization "Acme" (grp_acme) owned by
+// alice, with bob as admin and carol as member. dave has no membership.
+type orgFixture struct {
+	handler *PortalHandler
+	router  http.Handler
+	users   *mockUserStore
+	keys    *memory.KeyStore
+	usage   *memory.UsageStore
+	groups  *mockGroupStore
+	members *mockGroupMemberStore
+	invites *mockGroupInviteStore
+	mail    *email.MockSender
+}
+
+func newOrgFixture(t *testing.T) *orgFixture {
+	t.Helper()
+	users := newMockUserStore()
+	for _, u := range []struct{ id, email string }{
+		{"alice", "alice@example.com"},
+		{"bob", "bob@example.com"},
+		{"carol", "carol@example.com"},
+		{"dave", "dave@example.com"},
+	} {
+		users.users[u.id] = ports.User{ID: u.id, Email: u.email, Name: u.id, PlanID: "plan_default", Status: "active"}
+	}
+
+	members := &mockGroupMemberStore{members: map[string]group.Member{}}
+	groups := &mockGroupStore{groups: map[string]group.Group{}, members: members}
+	groups.groups["grp_acme"] = group.Group{ID: "grp_acme", Name: "Acme", Slug: "acme", OwnerID: "alice", Status: group.StatusActive}
+	for _, m := range []group.Member{
+		{ID: "mem_alice", GroupID: "grp_acme", UserID: "alice", Role: group.RoleOwner},
+		{ID: "mem_bob", GroupID: "grp_acme", UserID: "bob", Role: group.RoleAdmin},
+		{ID: "mem_carol", GroupID: "grp_acme", UserID: "carol", Role: group.RoleMember},
+	} {
+		members.members[m.ID] = m
+	}
+
+	keys := memory.NewKeyStore()
+	usageStore := memory.NewUsageStore()
+	mail := email.NewMockSender("https://test.com", "TestApp")
+	handler, err := NewPortalHandler(PortalDeps{
+		Users:        users,
+		Keys:         keys,
+		Usage:        usageStore,
+		AuthTokens:   newMockTokenStore(),
+		Sessions:     newMockSessionStore(),
+		Plans:        newMockPlanStore(),
+		Groups:       groups,
+		GroupMembers: members,
+		EmailSender:  mail,
+		Logger:       zerolog.Nop(),
+		Hasher:       &mockHasher{},
+		IDGen:        &mockIDGen{},
+		JWTSecret:    "test-secret",
+		BaseURL:      "https://test.com",
+		AppName:      "TestApp",
+	})
+	if err != nil {
+		t.Fatalf("NewPortalHandler() error = %v", err)
+	}
+
+	router := chi.NewRouter()
+	router.Mount("/portal", handler.Router())
+	return &orgFixture{
+		handler: handler,
+		router:  router,
+		users:   users,
+		keys:    keys,
+		usage:   usageStore,
+		groups:  groups,
+		members: members,
+		invites: &mockGroupInviteStore{invites: map[string]group.Invite{}},
+		mail:    mail,
+	}
+}
+
+// enableInvites makes adding a member email them an invite.
+func (f *orgFixture) enableInvites() {
+	f.handler.groupInvites = f.invites
+}
+
+// do sends a request as userID, scoped to orgID if it is not empty. An empty
+// userID sends it logged out.
+func (f *orgFixture) do(t *testing.T, method, path, userID, orgID string, form url.Values, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	t.Helper()
+	var req *http.Request
+	if form != nil {
+		req = httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		req = httptest.NewRequest(method, path, nil)
+	}
+	if userID != "" {
+		user := f.users.users[userID]
+		token, _, err := f.handler.tokens.GenerateToken(user.ID, user.Email, "user")
+		if err != nil {
+			t.Fatalf("GenerateToken() error = %v", err)
+		}
+		req.AddCookie(&http.Cookie{Name: "portal_token", Value: token})
+	}
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	if orgID != "" {
+		req.AddCookie(&http.Cookie{Name: portalOrgCookie, Value: orgID})
+	}
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+	return w
+}
+
+func orgCookieOf(w *httptest.ResponseRecorder) *http.Cookie {
+	return cookieOf(w, portalOrgCookie)
+}
+
+func cookieOf(w *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, c := range w.Result().Cookies() {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestPortalOrgs_CreateOrg(t *testing.T) {
+	f := newOrgFixture(t)
+
+	w := f.do(t, "POST", "/portal/orgs", "dave", "", url.Values{"name": {"Dave Co"}})
+	if w.Code != http.StatusFound {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusFound, w.Body.String())
+	}
+
+	g, err := f.groups.GetBySlug(context.Background(), "dave-co")
+	if err != nil {
+		t.Fatalf("organization not created: %v", err)
+	}
+	if g.OwnerID != "dave" || g.PlanID != "plan_default" {
+		t.Errorf("organization = %+v, want owned by dave on plan_default", g)
+	}
+	m, err := f.members.GetByGroupAndUser(context.Background(), g.ID, "dave")
+	if err != nil || m.Role != group.RoleOwner {
+		t.Errorf("owner membership = %+v, %v", m, err)
+	}
+	if c := orgCookieOf(w); c == nil || c.Value != g.ID {
+		t.Errorf("org cookie = %+v, want switched to %s", c, g.ID)
+	}
+}
+
+func TestPortalOrgs_SwitchOrg(t *testing.T) {
+	f := newOrgFixture(t)
+
+	w := f.do(t, "POST", "/portal/orgs/switch", "carol", "", url.Values{"org_id": {"grp_acme"}})
+	if c := orgCookieOf(w); w.Code != http.StatusFound || c == nil || c.Value != "grp_acme" {
+		t.Fatalf("switch: status = %d, cookie = %+v", w.Code, c)
+	}
+
+	// Not a member
+	w = f.do(t, "POST", "/portal/orgs/switch", "dave", "", url.Values{"org_id": {"grp_acme"}})
+	if w.Code != http.StatusNotFound {
+		t.Errorf("non-member switch status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	// A cookie for an organization the user isn't in is cleared
+	w = f.do(t, "GET", "/portal/api-keys", "dave", "grp_acme", nil)
+	if c := orgCookieOf(w); c == nil || c.MaxAge >= 0 {
+		t.Errorf("stale org cookie = %+v, want cleared", c)
+	}
+	if strings.Contains(w.Body.String(), "Acme API Keys") {
+		t.Error("non-member should not see the organization's keys")
+	}
+}
+
+func TestPortalOrgs_AddMember(t *testing.T) {
+	tests := []struct {
+		name       string
+		userID     string
+		email      string
+		role       string
+		wantStatus int
+		wantRole   group.Role
+	}{
+		{"owner adds member", "alice", "dave@example.com", "member", http.StatusFound, group.RoleMember},
+		{"admin adds admin", "bob", "dave@example.com", "admin", http.StatusFound, group.RoleAdmin},
+		{"member cannot add", "carol", "dave@example.com", "member", http.StatusForbidden, ""},
+		{"unknown email", "alice", "nobody@example.com", "member", http.StatusBadRequest, ""},
+		{"already a member", "alice", "carol@example.com", "member", http.StatusBadRequest, ""},
+		{"cannot add owner", "alice", "dave@example.com", "owner", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOrgFixture(t)
+			w := f.do(t, "POST", "/portal/orgs/members", tt.userID, "grp_acme", url.Values{"email": {tt.email}, "role": {tt.role}})
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantRole == "" {
+				return
+			}
+			m, err := f.members.GetByGroupAndUser(context.Background(), "grp_acme", "dave")
+			if err != nil {
+				t.Fatalf("membership not created: %v", err)
+			}
+			if m.Role != tt.wantRole || m.InvitedBy != tt.userID {
+				t.Errorf("membership = %+v, want role %s invited by %s", m, tt.wantRole, tt.userID)
+			}
+		})
+	}
+}
+
+func TestPortalOrgs_ChangeAndRemoveMember(t *testing.T) {
+	f := newOrgFixture(t)
+	ctx := context.Background()
+
+	// Only the owner changes roles
+	if w := f.do(t, "POST", "/portal/orgs/members/mem_carol/role", "bob", "grp_acme", url.Values{"role": {"admin"}}); w.Code != http.StatusForbidden {
+		t.Errorf("admin change role status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if w := f.do(t, "POST", "/portal/orgs/members/mem_carol/role", "alice", "grp_acme", url.Values{"role": {"admin"}}); w.Code != http.StatusFound {
+		t.Fatalf("owner change role status = %d: %s", w.Code, w.Body.String())
+	}
+	if m, _ := f.members.Get(ctx, "mem_carol"); m.Role != group.RoleAdmin {
+		t.Errorf("carol role = %s, want admin", m.Role)
+	}
+
+	// The owner can't be removed
+	if w := f.do(t, "POST", "/portal/orgs/members/mem_alice/remove", "bob", "grp_acme", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("remove owner status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if w := f.do(t, "POST", "/portal/orgs/members/mem_carol/remove", "bob", "grp_acme", nil); w.Code != http.StatusFound {
+		t.Fatalf("admin remove status = %d: %s", w.Code, w.Body.String())
+	}
+	if _, err := f.members.Get(ctx, "mem_carol"); err == nil {
+		t.Error("carol should have been removed")
+	}
+}
+
+func TestPortalOrgs_KeyManagementByRole(t *testing.T) {
+	tests := []struct {
+		userID    string
+		canManage bool
+	}{
+		{"alice", true},
+		{"bob", true},
+		{"carol", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.userID, func(t *testing.T) {
+			f := newOrgFixture(t)
+			ctx := context.Background()
+
+			_, shared := key.Generate("ak_")
+			shared = shared.WithUserID("alice").WithGroupID("grp_acme")
+			f.keys.Create(ctx, shared)
+
+			// Every member sees the organization's keys
+			w := f.do(t, "GET", "/portal/api-keys", tt.userID, "grp_acme", nil)
+			if !strings.Contains(w.Body.String(), shared.Prefix) {
+				t.Errorf("organization key not listed for %s", tt.userID)
+			}
+
+			wantStatus := http.StatusForbidden
+			if tt.canManage {
+				wantStatus = http.StatusOK
+			}
+			w = f.do(t, "POST", "/portal/api-keys", tt.userID, "grp_acme", url.Values{"name": {"ci"}})
+			if w.Code != wantStatus {
+				t.Errorf("create status = %d, want %d", w.Code, wantStatus)
+			}
+			if tt.canManage {
+				orgKeys, _ := f.keys.ListByGroup(ctx, "grp_acme")
+				if len(orgKeys) != 2 {
+					t.Fatalf("organization keys = %d, want 2", len(orgKeys))
+				}
+				for _, k := range orgKeys {
+					if k.Name == "ci" && k.UserID != tt.userID {
+						t.Errorf("created key UserID = %s, want %s", k.UserID, tt.userID)
+					}
+				}
+			}
+
+			wantStatus = http.StatusForbidden
+			if tt.canManage {
+				wantStatus = http.StatusFound
+			}
+			w = f.do(t, "POST", "/portal/api-keys/"+shared.ID+"/revoke", tt.userID, "grp_acme", nil)
+			if w.Code != wantStatus {
+				t.Errorf("revoke status = %d, want %d", w.Code, wantStatus)
+			}
+			if tt.canManage {
+				return
+			}
+			w = f.do(t, "POST", "/portal/api-keys/"+shared.ID+"/rotate", tt.userID, "grp_acme", nil)
+			if w.Code != http.StatusForbidden {
+				t.Errorf("rotate status = %d, want %d", w.Code, http.StatusForbidden)
+			}
+		})
+	}
+}
+
+func TestPortalOrgs_PersonalScopeExcludesOrgKeys(t *testing.T) {
+	f := newOrgFixture(t)
+	ctx := context.Background()
+
+	_, own := key.Generate("ak_")
+	own = own.WithUserID("alice")
+	_, shared := key.Generate("ak_")
+	shared = shared.WithUserID("alice").WithGroupID("grp_acme")
+	f.keys.Create(ctx, own)
+	f.keys.Create(ctx, shared)
+
+	body := f.do(t, "GET", "/portal/api-keys", "alice", "", nil).Body.String()
+	if !strings.Contains(body, own.Prefix) || strings.Contains(body, shared.Prefix) {
+		t.Error("personal scope should list only the user's own keys")
+	}
+
+	// An organization key can't be revoked from the personal scope
+	if w := f.do(t, "POST", "/portal/api-keys/"+shared.ID+"/revoke", "alice", "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("revoke status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestPortalOrgs_UsageAggregatesAcrossMembers(t *testing.T) {
+	f := newOrgFixture(t)
+	now := time.Now().UTC()
+
+	// Two members' organization keys record usage against the organization,
+	// alice's own key against alice
+	f.usage.RecordBatch(context.Background(), []usage.Event{
+		{ID: "e1", KeyID: "k-alice-org", UserID: "grp_acme", Method: "GET", Path: "/a", StatusCode: 200, Timestamp: now},
+		{ID: "e2", KeyID: "k-bob-org", UserID: "grp_acme", Method: "GET", Path: "/b", StatusCode: 200, Timestamp: now},
+		{ID: "e3", KeyID: "k-bob-org", UserID: "grp_acme", Method: "GET", Path: "/b", StatusCode: 500, Timestamp: now},
+		{ID: "e4", KeyID: "k-alice", UserID: "alice", Method: "GET", Path: "/c", StatusCode: 200, Timestamp: now},
+	})
+
+	tests := []struct {
+		name      string
+		userID    string
+		orgID     string
+		wantPaths []string
+		notPaths  []string
+	}{
+		{"organization", "carol", "grp_acme", []string{"/a", "/b"}, []string{"/c"}},
+		{"personal", "alice", "", []string{"/c"}, []string{"/a", "/b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := f.do(t, "GET", "/portal/usage", tt.userID, tt.orgID, nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d", w.Code)
+			}
+			body := w.Body.String()
+			for _, p := range tt.wantPaths {
+				if !strings.Contains(body, p+"<") {
+					t.Errorf("usage page missing path %s", p)
+				}
+			}
+			for _, p := range tt.notPaths {
+				if strings.Contains(body, p+"<") {
+					t.Errorf("usage page should not include path %s", p)
+				}
+			}
+		})
+	}
+}
+
+// inviteLinkToken returns the signed token from the link in an invite email.
+func inviteLinkToken(t *testing.T, sent email.SentEmail) string {
+	t.Helper()
+	_, rest, ok := strings.Cut(sent.TextBody, "/portal/orgs/join?token=")
+	if !ok {
+		t.Fatalf("invite email has no join link: %s", sent.TextBody)
+	}
+	token, err := url.QueryUnescape(strings.Fields(rest)[0])
+	if err != nil {
+		t.Fatalf("invalid token in join link: %v", err)
+	}
+	return token
+}
+
+func TestPortalOrgs_InviteSendsEmail(t *testing.T) {
+	f := newOrgFixture(t)
+	f.enableInvites()
+
+	for i := 0; i < 2; i++ {
+		w := f.do(t, "POST", "/portal/orgs/members", "bob", "grp_acme", url.Values{"email": {"new@example.com"}, "role": {"admin"}})
+		if w.Code != http.StatusFound || w.Header().Get("Location") != "/portal/orgs/members?done=invited" {
+			t.Fatalf("status = %d, location = %q: %s", w.Code, w.Header().Get("Location"), w.Body.String())
+		}
+	}
+
+	// Inviting again replaces the earlier invite
+	invites, _ := f.invites.ListByGroup(context.Background(), "grp_acme")
+	if len(invites) != 1 {
+		t.Fatalf("pending invites = %d, want 1", len(invites))
+	}
+	inv := invites[0]
+	if inv.Email != "new@example.com" || inv.Role != group.RoleAdmin || inv.InvitedBy != "bob" {
+		t.Errorf("invite = %+v", inv)
+	}
+
+	sent := f.mail.FindByTo("new@example.com")
+	if len(sent) != 2 {
+		t.Fatalf("invite emails sent = %d, want 2", len(sent))
+	}
+	if !strings.Contains(sent[1].Subject, "Acme") {
+		t.Errorf("subject = %q, want organization name", sent[1].Subject)
+	}
+	if token, ok := group.VerifyInviteToken(inviteLinkToken(t, sent[1]), "test-secret"); !ok || token != inv.Token {
+		t.Error("invite link should carry the signed token of the current invite")
+	}
+
+	// Members don't invite, and members aren't invited again
+	if w := f.do(t, "POST", "/portal/orgs/members", "carol", "grp_acme", url.Values{"email": {"x@example.com"}, "role": {"member"}}); w.Code != http.StatusForbidden {
+		t.Errorf("member invite status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if w := f.do(t, "POST", "/portal/orgs/members", "alice", "grp_acme", url.Values{"email": {"carol@example.com"}, "role": {"member"}}); w.Code != http.StatusBadRequest {
+		t.Errorf("existing member invite status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestPortalOrgs_InviteSendFailure(t *testing.T) {
+	f := newOrgFixture(t)
+	f.enableInvites()
+	f.mail.SetShouldFail(true, nil)
+
+	w := f.do(t, "POST", "/portal/orgs/members", "alice", "grp_acme", url.Values{"email": {"new@example.com"}, "role": {"member"}})
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	if len(f.invites.invites) != 0 {
+		t.Error("unsent invite should be deleted")
+	}
+}
+
+func TestPortalOrgs_InviteJoinsOrg(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		wantNext string
+	}{
+		{"new user signs up", "new@example.com", "/portal/signup?email=new%40example.com"},
+		{"existing user logs in", "dave@example.com", "/portal/login?email=dave%40example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOrgFixture(t)
+			f.enableInvites()
+			ctx := context.Background()
+
+			f.do(t, "POST", "/portal/orgs/members", "alice", "grp_acme", url.Values{"email": {tt.email}, "role": {"admin"}})
+			sent, ok := f.mail.GetLastEmail()
+			if !ok {
+				t.Fatal("no invite email sent")
+			}
+
+			// Following the link logged out keeps the invite for after login
+			w := f.do(t, "GET", "/portal/orgs/join?token="+url.QueryEscape(inviteLinkToken(t, sent)), "", "", nil)
+			if w.Code != http.StatusFound || w.Header().Get("Location") != tt.wantNext {
+				t.Fatalf("join status = %d, location = %q, want redirect to %s", w.Code, w.Header().Get("Location"), tt.wantNext)
+			}
+			inviteCookie := cookieOf(w, portalInviteCookie)
+			if inviteCookie == nil || inviteCookie.Value == "" {
+				t.Fatal("invite cookie not set")
+			}
+
+			if _, err := f.users.GetByEmail(ctx, tt.email); err != nil {
+				f.users.users["new"] = ports.User{ID: "new", Email: tt.email, Status: "active"}
+			}
+			user, _ := f.users.GetByEmail(ctx, tt.email)
+
+			w = f.do(t, "GET", "/portal/dashboard", user.ID, "", nil, inviteCookie)
+			if w.Code != http.StatusFound || w.Header().Get("Location") != "/portal/orgs?done=joined" {
+				t.Fatalf("accept status = %d, location = %q: %s", w.Code, w.Header().Get("Location"), w.Body.String())
+			}
+			m, err := f.members.GetByGroupAndUser(ctx, "grp_acme", user.ID)
+			if err != nil {
+				t.Fatalf("membership not created: %v", err)
+			}
+			if m.Role != group.RoleAdmin || m.InvitedBy != "alice" {
+				t.Errorf("membership = %+v, want admin invited by alice", m)
+			}
+			if c := orgCookieOf(w); c == nil || c.Value != "grp_acme" {
+				t.Errorf("org cookie = %+v, want switched to grp_acme", c)
+			}
+			if c := cookieOf(w, portalInviteCookie); c == nil || c.MaxAge >= 0 {
+				t.Errorf("invite cookie = %+v, want cleared", c)
+			}
+			if len(f.invites.invites) != 0 {
+				t.Error("accepted invite should be deleted")
+			}
+		})
+	}
+}
+
+func TestPortalOrgs_InviteRejected(t *testing.T) {
+	now := time.Now().UTC()
+	tests := []struct {
+		name       string
+		expiresAt  time.Time
+		token      func(inv group.Invite) string
+		userID     string
+		wantStatus int
+	}{
+		{
+			name:       "expired",
+			expiresAt:  now.Add(-time.Hour),
+			token:      func(inv group.Invite) string { return group.SignInviteToken(inv.Token, "test-secret") },
+			userID:     "dave",
+			wantStatus: http.StatusGone,
+		},
+		{
+			name:       "bad signature",
+			expiresAt:  now.Add(time.Hour),
+			token:      func(inv group.Invite) string { return group.SignInviteToken(inv.Token, "other-secret") },
+			userID:     "dave",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "different account",
+			expiresAt:  now.Add(time.Hour),
+			token:      func(inv group.Invite) string { return group.SignInviteToken(inv.Token, "test-secret") },
+			userID:     "carol",
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOrgFixture(t)
+			f.enableInvites()
+			ctx := context.Background()
+
+			// carol is moved out of Acme so any membership would be new
+			f.members.Delete(ctx, "mem_carol")
+			inv := group.Invite{
+				ID:        "gi_1",
+				GroupID:   "grp_acme",
+				Email:     "dave@example.com",
+				Role:      group.RoleMember,
+				InvitedBy: "alice",
+				Token:     group.GenerateInviteToken(),
+				ExpiresAt: tt.expiresAt,
+				CreatedAt: now.Add(-2 * time.Hour),
+			}
+			f.invites.Create(ctx, inv)
+			signed := tt.token(inv)
+
+			if tt.wantStatus != http.StatusForbidden {
+				w := f.do(t, "GET", "/portal/orgs/join?token="+url.QueryEscape(signed), "", "", nil)
+				if w.Code != tt.wantStatus {
+					t.Errorf("join status = %d, want %d", w.Code, tt.wantStatus)
+				}
+				f.invites.Create(ctx, inv)
+			}
+
+			w := f.do(t, "GET", "/portal/dashboard", tt.userID, "", nil, &http.Cookie{Name: portalInviteCookie, Value: signed})
+			if w.Code != tt.wantStatus {
+				t.Errorf("accept status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if _, err := f.members.GetByGroupAndUser(ctx, "grp_acme", tt.userID); err == nil {
+				t.Error("rejected invite should not create a membership")
+			}
+			if _, err := f.invites.Get(ctx, inv.ID); tt.name == "expired" && err == nil {
+				t.Error("expired invite should be deleted")
+			}
+		})
+	}
+}
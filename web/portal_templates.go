@@ -3,16 +3,21 @@ package web
 import (
 	"context"
 	"fmt"
+	"html"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/artpar/apigate/core/terminology"
+	domainAuth "github.com/artpar/apigate/domain/auth"
 	"github.com/artpar/apigate/domain/billing"
 	"github.com/artpar/apigate/domain/entitlement"
 	"github.com/artpar/apigate/domain/key"
 	"github.com/artpar/apigate/domain/settings"
 	"github.com/artpar/apigate/domain/usage"
+	"github.com/artpar/apigate/pkg/idempotency"
 	"github.com/artpar/apigate/ports"
 )
 
@@ -160,6 +165,7 @@ func (h *PortalHandler) renderSignupPage(name, email string, errors map[string]s
 }
 
 func (h *PortalHandler) renderSignupPageWithPlan(name, email string, defaultPlan *ports.Plan, labels terminology.Labels, errors map[string]string) string {
+	policy := h.passwordPolicy(context.Background())
 	errorHTML := ""
 	if len(errors) > 0 {
 		var msgs []string
@@ -225,8 +231,8 @@ func (h *PortalHandler) renderSignupPageWithPlan(name, email string, defaultPlan
                 </div>
                 <div class="form-group">
                     <label for="password">Password</label>
-                    <input type="password" id="password" name="password" required minlength="8">
-                    <small>At least 8 characters with uppercase, lowercase, and number</small>
+                    <input type="password" id="password" name="password" required minlength="%d">
+                    <small>%s</small>
                 </div>
                 <div class="form-group" style="margin-top: 16px;">
                     <label style="display: flex; align-items: flex-start; gap: 8px; cursor: pointer; font-weight: normal;">
@@ -239,6 +245,7 @@ func (h *PortalHandler) renderSignupPageWithPlan(name, email string, defaultPlan
                 </div>
                 <button type="submit" class="btn btn-primary btn-block">Create Account</button>
             </form>
+            %s
             <div class="auth-footer">
                 <p>Already have an account? <a href="/portal/login">Log in</a></p>
             </div>
@@ -257,7 +264,7 @@ func (h *PortalHandler) renderSignupPageWithPlan(name, email string, defaultPlan
     })();
     </script>
 </body>
-</html>`, h.appName, portalCSS, h.appName, planInfoHTML, errorHTML, name, email)
+</html>`, h.appName, portalCSS, h.appName, planInfoHTML, errorHTML, name, email, policy.MinLength, policy.Describe(), h.renderOAuthButtons())
 }
 
 func (h *PortalHandler) renderLoginPage(email, message, messageType string, errors map[string]string) string {
@@ -299,8 +306,15 @@ func (h *PortalHandler) renderLoginPage(email, message, messageType string, erro
                     <label for="password">Password</label>
                     <input type="password" id="password" name="password" required>
                 </div>
+                <div class="form-group">
+                    <label style="display: flex; align-items: center; gap: 8px; cursor: pointer; font-weight: normal;">
+                        <input type="checkbox" name="remember_me" value="1">
+                        <span style="font-size: 13px; color: #4b5563;">Remember me</span>
+                    </label>
+                </div>
                 <button type="submit" class="btn btn-primary btn-block">Log In</button>
             </form>
+            %s
             <div class="auth-footer">
                 <p><a href="/portal/forgot-password">Forgot your password?</a></p>
                 <p>Don't have an account? <a href="/portal/signup">Sign up</a></p>
@@ -320,7 +334,7 @@ func (h *PortalHandler) renderLoginPage(email, message, messageType string, erro
     })();
     </script>
 </body>
-</html>`, h.appName, portalCSS, h.appName, alertHTML, email)
+</html>`, h.appName, portalCSS, h.appName, alertHTML, email, h.renderOAuthButtons())
 }
 
 func (h *PortalHandler) renderForgotPasswordPage(email, message, messageType string) string {
@@ -363,6 +377,7 @@ func (h *PortalHandler) renderForgotPasswordPage(email, message, messageType str
 }
 
 func (h *PortalHandler) renderResetPasswordPage(token string, errors map[string]string) string {
+	policy := h.passwordPolicy(context.Background())
 	errorHTML := ""
 	if len(errors) > 0 {
 		var msgs []string
@@ -393,8 +408,8 @@ func (h *PortalHandler) renderResetPasswordPage(token string, errors map[string]
                 <input type="hidden" name="token" value="%s">
                 <div class="form-group">
                     <label for="password">New Password</label>
-                    <input type="password" id="password" name="password" required minlength="8">
-                    <small>At least 8 characters with uppercase, lowercase, and number</small>
+                    <input type="password" id="password" name="password" required minlength="%d">
+                    <small>%s</small>
                 </div>
                 <div class="form-group">
                     <label for="confirm_password">Confirm Password</label>
@@ -405,7 +420,7 @@ func (h *PortalHandler) renderResetPasswordPage(token string, errors map[string]
         </div>
     </div>
 </body>
-</html>`, h.appName, portalCSS, h.appName, errorHTML, token)
+</html>`, h.appName, portalCSS, h.appName, errorHTML, token, policy.MinLength, policy.Describe())
 }
 
 func (h *PortalHandler) renderDashboardPage(user *PortalUser, keyCount int, requestCount int64, planName string, requestsPerMonth int64, rateLimitPerMinute int, userEntitlements []entitlement.UserEntitlement, labels terminology.Labels) string {
@@ -577,18 +592,40 @@ func (h *PortalHandler) renderDashboardPage(user *PortalUser, keyCount int, requ
 </html>`, h.appName, portalCSS, customCSS, h.renderPortalNav(user), user.Name, customWelcome, quotaSection, gettingStartedSection, keyCount, requestCount, labels.QuotaLabel, entitlementsSection)
 }
 
-func (h *PortalHandler) renderAPIKeysPage(user *PortalUser, keys []key.Key, revokedMsg bool) string {
-	keyRows := h.renderAPIKeysTableRows(keys)
+func (h *PortalHandler) renderAPIKeysPage(user *PortalUser, keys []key.Key, sel key.LabelSelector, revokedMsg bool) string {
+	keyRows := h.renderAPIKeysTableRows(keys, user.CanManageKeys())
 
-	if keyRows == "" {
-		keyRows = `<tr><td colspan="6" class="text-center">No API keys yet</td></tr>`
+	if keyRows == "" && len(sel) > 0 {
+		keyRows = `<tr><td colspan="8" class="text-center">No API keys match these labels</td></tr>`
+	} else if keyRows == "" {
+		keyRows = `<tr><td colspan="8" class="text-center">No API keys yet</td></tr>`
 	}
 
+	partialURL := "/portal/api-keys/partial"
+	clearFilter := ""
+	if len(sel) > 0 {
+		partialURL += "?labels=" + url.QueryEscape(sel.String())
+		clearFilter = ` <a href="/portal/api-keys" class="btn btn-sm btn-secondary">Clear</a>`
+	}
+	filterForm := fmt.Sprintf(`<form method="GET" action="/portal/api-keys" style="display: flex; gap: 8px; align-items: center; margin-bottom: 16px;">
+            <input type="text" name="labels" value="%s" placeholder="Filter by labels, e.g. env=prod,service" style="max-width: 360px;">
+            <button type="submit" class="btn btn-sm btn-secondary">Filter</button>%s
+        </form>`, html.EscapeString(sel.String()), clearFilter)
+
 	successMsg := ""
 	if revokedMsg {
 		successMsg = `<div class="alert alert-success" style="background: #d4edda; border: 1px solid #c3e6cb; color: #155724; padding: 12px 16px; border-radius: 6px; margin-bottom: 16px;">API key has been revoked successfully.</div>`
 	}
 
+	heading := "API Keys"
+	createBtn := `<button class="btn btn-primary" onclick="document.getElementById('create-modal').style.display='block'">Create New Key</button>`
+	if user.Org != nil {
+		heading = html.EscapeString(user.Org.Name) + " API Keys"
+	}
+	if !user.CanManageKeys() {
+		createBtn = `<span style="color: #6b7280;">Only organization owners and admins can manage keys</span>`
+	}
+
 	return fmt.Sprintf(`
 <!DOCTYPE html>
 <html lang="en">
@@ -604,22 +641,25 @@ func (h *PortalHandler) renderAPIKeysPage(user *PortalUser, keys []key.Key, revo
     <main class="main-content">
         %s
         <div class="page-header">
-            <h1>API Keys</h1>
-            <button class="btn btn-primary" onclick="document.getElementById('create-modal').style.display='block'">Create New Key</button>
+            <h1>%s</h1>
+            %s
         </div>
+        %s
         <div class="card">
             <table class="table">
                 <thead>
                     <tr>
                         <th>Name</th>
                         <th>Key</th>
+                        <th>Labels</th>
                         <th>Status</th>
+                        <th>Quota</th>
                         <th>Last Used</th>
                         <th>Created</th>
                         <th>Actions</th>
                     </tr>
                 </thead>
-                <tbody id="api-keys-table" hx-get="/portal/api-keys/partial" hx-trigger="every 30s" hx-swap="innerHTML">
+                <tbody id="api-keys-table" hx-get="%s" hx-trigger="every 30s" hx-swap="innerHTML">
                     %s
                 </tbody>
             </table>
@@ -634,11 +674,22 @@ func (h *PortalHandler) renderAPIKeysPage(user *PortalUser, keys []key.Key, revo
                 <button onclick="document.getElementById('create-modal').style.display='none'" class="modal-close">&times;</button>
             </div>
             <form action="/portal/api-keys" method="POST">
+                <input type="hidden" name="idempotency_key" value="%s">
                 <div class="form-group">
                     <label for="key-name">Key Name (optional)</label>
                     <input type="text" id="key-name" name="name" placeholder="e.g., Production API Key">
                     <small>A friendly name to identify this key</small>
                 </div>
+                <div class="form-group">
+                    <label for="key-monthly-quota">Monthly Quota (optional)</label>
+                    <input type="number" id="key-monthly-quota" name="monthly_quota" min="0" placeholder="e.g., 10000">
+                    <small>Cap requests made with this key per quota period. Your plan's quota still applies; leave empty to use the plan quota only.</small>
+                </div>
+                <div class="form-group">
+                    <label for="key-labels">Labels (optional)</label>
+                    <input type="text" id="key-labels" name="labels" placeholder="e.g., env=prod, service=billing">
+                    <small>Comma-separated name=value tags to organize and filter your keys</small>
+                </div>
                 <div class="modal-actions">
                     <button type="button" onclick="document.getElementById('create-modal').style.display='none'" class="btn btn-secondary">Cancel</button>
                     <button type="submit" class="btn btn-primary">Create Key</button>
@@ -648,11 +699,12 @@ func (h *PortalHandler) renderAPIKeysPage(user *PortalUser, keys []key.Key, revo
     </div>
     %s
 </body>
-</html>`, h.appName, portalCSS, h.renderPortalNav(user), successMsg, keyRows, portalConfirmJS)
+</html>`, h.appName, portalCSS, h.renderPortalNav(user), successMsg, heading, createBtn, filterForm, partialURL, keyRows, idempotency.NewKey(), portalConfirmJS)
 }
 
 // renderAPIKeysTableRows renders just the table rows for API keys (used for HTMX partial updates).
-func (h *PortalHandler) renderAPIKeysTableRows(keys []key.Key) string {
+// Rotate and revoke buttons are only shown if canManage.
+func (h *PortalHandler) renderAPIKeysTableRows(keys []key.Key, canManage bool) string {
 	if len(keys) == 0 {
 		return ""
 	}
@@ -662,33 +714,69 @@ func (h *PortalHandler) renderAPIKeysTableRows(keys []key.Key) string {
 		status := "Active"
 		statusClass := "status-active"
 		revokeBtn := ""
-		if k.RevokedAt != nil {
+		if key.IsRevoked(k, time.Now()) {
 			status = "Revoked"
 			statusClass = "status-revoked"
 			revokeBtn = "-"
+		} else if k.RevokedAt != nil {
+			// Rotated out: still valid until the scheduled revocation
+			status = "Revokes " + k.RevokedAt.Format("Jan 2 15:04")
+			statusClass = "status-revoked"
+			revokeBtn = fmt.Sprintf(`<form method="POST" action="/portal/api-keys/%s/revoke" style="display:inline" onsubmit="showConfirmModal(this, 'Revoke this API key now instead of at the end of its grace period?', 'Revoke API Key'); return false;"><button type="submit" class="btn btn-sm btn-danger">Revoke now</button></form>`, k.ID)
 		} else {
-			revokeBtn = fmt.Sprintf(`<form method="POST" action="/portal/api-keys/%s/revoke" style="display:inline" onsubmit="showConfirmModal(this, 'Are you sure you want to revoke this API key? This cannot be undone.', 'Revoke API Key'); return false;"><button type="submit" class="btn btn-sm btn-danger">Revoke</button></form>`, k.ID)
+			revokeBtn = fmt.Sprintf(`<form method="POST" action="/portal/api-keys/%s/rotate" style="display:inline" onsubmit="showConfirmModal(this, 'Issue a replacement key? This key keeps working for a grace period, then it is revoked.', 'Rotate API Key'); return false;"><button type="submit" class="btn btn-sm btn-secondary">Rotate</button></form> `, k.ID) + fmt.Sprintf(`<form method="POST" action="/portal/api-keys/%s/revoke" style="display:inline" onsubmit="showConfirmModal(this, 'Are you sure you want to revoke this API key? This cannot be undone.', 'Revoke API Key'); return false;"><button type="submit" class="btn btn-sm btn-danger">Revoke</button></form>`, k.ID)
+		}
+		if !canManage {
+			revokeBtn = "-"
 		}
 
 		lastUsed := "Never"
 		if k.LastUsed != nil {
-			lastUsed = timeAgo(*k.LastUsed)
+			lastUsed = fmt.Sprintf(`<span title="%s">%s</span>`, k.LastUsed.UTC().Format("Jan 2, 2006 15:04 MST"), timeAgo(*k.LastUsed))
+			if k.LastUsedIP != "" {
+				lastUsed += `<br><small style="color: #6b7280;">from ` + html.EscapeString(k.LastUsedIP) + `</small>`
+			}
+		}
+
+		quota := "Plan"
+		if k.MonthlyQuota > 0 {
+			quota = strconv.FormatInt(k.MonthlyQuota, 10) + " / period"
+		}
+
+		labels := renderKeyLabels(k.Labels)
+		if canManage && !key.IsRevoked(k, time.Now()) {
+			labels += fmt.Sprintf(`<details style="margin-top: 4px;"><summary style="cursor: pointer; color: #6b7280; font-size: 12px;">Edit</summary><form method="POST" action="/portal/api-keys/%s/labels" style="display: flex; gap: 4px; margin-top: 4px;"><input type="text" name="labels" value="%s" placeholder="env=prod, service=billing" style="min-width: 180px;"><button type="submit" class="btn btn-sm btn-secondary">Save</button></form></details>`,
+				k.ID, html.EscapeString(key.FormatLabels(k.Labels)))
 		}
 
 		rows += fmt.Sprintf(`
             <tr>
                 <td>%s</td>
                 <td><code>%s****</code></td>
+                <td>%s</td>
                 <td><span class="%s">%s</span></td>
                 <td>%s</td>
                 <td>%s</td>
                 <td>%s</td>
+                <td>%s</td>
             </tr>
-        `, k.Name, k.Prefix, statusClass, status, lastUsed, k.CreatedAt.Format("Jan 2, 2006"), revokeBtn)
+        `, k.Name, k.Prefix, labels, statusClass, status, quota, lastUsed, k.CreatedAt.Format("Jan 2, 2006"), revokeBtn)
 	}
 	return rows
 }
 
+// renderKeyLabels renders a key's labels as name=value tags sorted by name.
+func renderKeyLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return `<span style="color: #9ca3af;">-</span>`
+	}
+	var b strings.Builder
+	for _, pair := range strings.Split(key.FormatLabels(labels), ", ") {
+		fmt.Fprintf(&b, `<span style="display: inline-block; background: #eef2ff; color: #3730a3; padding: 1px 6px; border-radius: 4px; font-size: 12px; margin: 0 4px 4px 0;">%s</span>`, html.EscapeString(pair))
+	}
+	return b.String()
+}
+
 // timeAgo returns a human-readable time ago string.
 func timeAgo(t time.Time) string {
 	d := time.Since(t)
@@ -716,7 +804,7 @@ func timeAgo(t time.Time) string {
 	}
 }
 
-func (h *PortalHandler) renderKeyCreatedPage(w http.ResponseWriter, r *http.Request, user *PortalUser, rawKey, keyName string) {
+func (h *PortalHandler) renderKeyCreatedPage(w http.ResponseWriter, r *http.Request, user *PortalUser, rawKey, keyName, notice string) {
 	displayName := keyName
 	if displayName == "" {
 		displayName = "API Key"
@@ -786,7 +874,7 @@ func (h *PortalHandler) renderKeyCreatedPage(w http.ResponseWriter, r *http.Requ
     <main class="main-content">
         <div class="card">
             <div class="alert alert-success">
-                <strong>Success!</strong> Your API key has been created.
+                <strong>Success!</strong> %s
             </div>
             <h2>%s</h2>
             <p>Copy your API key now. You won't be able to see it again!</p>
@@ -817,13 +905,13 @@ func (h *PortalHandler) renderKeyCreatedPage(w http.ResponseWriter, r *http.Requ
         </div>
     </main>
 </body>
-</html>`, h.appName, portalCSS, h.renderPortalNav(user), displayName, rawKey, curlExample, baseURL, exampleEndpoint, helpText)
+</html>`, h.appName, portalCSS, h.renderPortalNav(user), notice, displayName, rawKey, curlExample, baseURL, exampleEndpoint, helpText)
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Write([]byte(html))
 }
 
-func (h *PortalHandler) renderUsagePage(user *PortalUser, summary usage.Summary, labels terminology.Labels) string {
+func (h *PortalHandler) renderUsagePage(user *PortalUser, summary usage.Summary, windowStart, windowEnd time.Time, labels terminology.Labels) string {
 	return fmt.Sprintf(`
 <!DOCTYPE html>
 <html lang="en">
@@ -838,7 +926,7 @@ func (h *PortalHandler) renderUsagePage(user *PortalUser, summary usage.Summary,
     <main class="main-content">
         <div class="page-header">
             <h1>Usage</h1>
-            <p>Current billing period</p>
+            <p>Current quota window: %s &ndash; %s</p>
         </div>
         <div class="stats-grid">
             <div class="stat-card">
@@ -858,12 +946,73 @@ func (h *PortalHandler) renderUsagePage(user *PortalUser, summary usage.Summary,
                 <div class="stat-label">Data Out</div>
             </div>
         </div>
+        <div class="card">
+            <h2>Top Endpoints</h2>
+            <table class="table">
+                <thead>
+                    <tr>
+                        <th>Path</th>
+                        <th>%s</th>
+                        <th>Errors</th>
+                        <th>Data In</th>
+                        <th>Data Out</th>
+                    </tr>
+                </thead>
+                <tbody>
+                    %s
+                </tbody>
+            </table>
+        </div>
+        <div class="card">
+            <h2>Export Usage</h2>
+            <p>Download your individual requests to reconcile your bill.</p>
+            <form method="GET" action="/portal/usage/export">
+                <div class="form-group">
+                    <label for="start">From</label>
+                    <input type="date" id="start" name="start" value="%s" required>
+                </div>
+                <div class="form-group">
+                    <label for="end">To (inclusive)</label>
+                    <input type="date" id="end" name="end" value="%s" required>
+                </div>
+                <div class="form-group">
+                    <label for="format">Format</label>
+                    <select id="format" name="format">
+                        <option value="csv">CSV</option>
+                        <option value="json">JSON</option>
+                    </select>
+                </div>
+                <button type="submit" class="btn btn-primary">Download</button>
+            </form>
+        </div>
     </main>
 </body>
-</html>`, h.appName, portalCSS, h.renderPortalNav(user), summary.RequestCount, labels.QuotaLabel, summary.ErrorCount, float64(summary.BytesIn)/1024, float64(summary.BytesOut)/1024)
+</html>`, h.appName, portalCSS, h.renderPortalNav(user), windowStart.Format("Jan 2, 2006 15:04 MST"), windowEnd.Format("Jan 2, 2006 15:04 MST"), summary.RequestCount, labels.QuotaLabel, summary.ErrorCount, float64(summary.BytesIn)/1024, float64(summary.BytesOut)/1024,
+		labels.QuotaLabel, renderTopPathRows(summary.TopPaths),
+		windowStart.Format("2006-01-02"), windowEnd.Add(-time.Nanosecond).Format("2006-01-02"))
+}
+
+// renderTopPathRows renders the per-endpoint breakdown rows of the usage page.
+func renderTopPathRows(paths []usage.PathUsage) string {
+	if len(paths) == 0 {
+		return `<tr><td colspan="5" class="text-center">No requests in this window yet</td></tr>`
+	}
+	var rows strings.Builder
+	for _, p := range paths {
+		fmt.Fprintf(&rows, `
+                    <tr>
+                        <td><code>%s</code></td>
+                        <td>%d</td>
+                        <td>%d</td>
+                        <td>%.2f KB</td>
+                        <td>%.2f KB</td>
+                    </tr>`, html.EscapeString(p.Path), p.RequestCount, p.ErrorCount, float64(p.BytesIn)/1024, float64(p.BytesOut)/1024)
+	}
+	return rows.String()
 }
 
 func (h *PortalHandler) renderAccountSettingsPage(user *PortalUser, errors map[string]string, success string) string {
+	policy := h.passwordPolicy(context.Background())
 	errorHTML := ""
 	if len(errors) > 0 {
 		var msgs []string
@@ -897,6 +1046,7 @@ func (h *PortalHandler) renderAccountSettingsPage(user *PortalUser, errors map[s
         <div class="card">
             <h2>Profile</h2>
             <form method="POST" action="/portal/settings">
+                <input type="hidden" name="version" value="%d">
                 <div class="form-group">
                     <label for="name">Name</label>
                     <input type="text" id="name" name="name" value="%s" required minlength="2" maxlength="100">
@@ -919,7 +1069,8 @@ func (h *PortalHandler) renderAccountSettingsPage(user *PortalUser, errors map[s
                 </div>
                 <div class="form-group">
                     <label for="new_password">New Password</label>
-                    <input type="password" id="new_password" name="new_password" required minlength="8">
+                    <input type="password" id="new_password" name="new_password" required minlength="%d">
+                    <small>%s</small>
                 </div>
                 <div class="form-group">
                     <label for="confirm_password">Confirm New Password</label>
@@ -929,10 +1080,18 @@ func (h *PortalHandler) renderAccountSettingsPage(user *PortalUser, errors map[s
             </form>
         </div>
 
+        %s
+
+        <div class="card">
+            <h2>Active Sessions</h2>
+            <p>See where you're logged in and log out devices you don't recognize.</p>
+            <a href="/portal/settings/sessions" class="btn btn-secondary" style="margin-top: 12px;">Manage Sessions</a>
+        </div>
+
         <div class="card card-danger">
             <h2>Danger Zone</h2>
-            <p>Closing your account will revoke all API keys and delete your data.</p>
-            <form method="POST" action="/portal/settings/close-account" onsubmit="showConfirmModal(this, 'Are you sure you want to close your account? This will revoke all API keys and delete your data. This cannot be undone.', 'Close Account'); return false;">
+            <p>Closing your account signs you out and stops all of your API keys from working. Contact support if you want the account restored.</p>
+            <form method="POST" action="/portal/settings/close-account" onsubmit="showConfirmModal(this, 'Are you sure you want to close your account? All of your API keys will stop working immediately.', 'Close Account'); return false;">
                 <div class="form-group">
                     <label for="password">Confirm with your password</label>
                     <input type="password" id="password" name="password" required>
@@ -943,9 +1102,244 @@ func (h *PortalHandler) renderAccountSettingsPage(user *PortalUser, errors map[s
     </main>
     %s
 </body>
-</html>`, h.appName, portalCSS, h.renderPortalNav(user), successHTML, errorHTML, user.Name, user.Email, portalConfirmJS)
+</html>`, h.appName, portalCSS, h.renderPortalNav(user), successHTML, errorHTML, user.UpdatedAt.UnixNano(), user.Name, user.Email, policy.MinLength, policy.Describe(), renderTwoFactorCard(user), portalConfirmJS)
+}
+
+// renderTwoFactorCard renders the account settings card that turns
+// two-factor authentication on or off.
+func renderTwoFactorCard(user *PortalUser) string {
+	if !user.TwoFactorEnabled {
+		return `<div class="card">
+            <h2>Two-Factor Authentication</h2>
+            <p>Protect your account with a code from an authenticator app, such as Google Authenticator or 1Password, in addition to your password.</p>
+            <a href="/portal/settings/two-factor" class="btn btn-primary" style="margin-top: 12px;">Set Up Two-Factor Authentication</a>
+        </div>`
+	}
+	return `<div class="card">
+            <h2>Two-Factor Authentication</h2>
+            <p><span class="status-active">Enabled.</span> Logging in asks for a code from your authenticator app or a recovery code.</p>
+            <form method="POST" action="/portal/settings/two-factor/disable" style="margin-top: 12px;">
+                <div class="form-group">
+                    <label for="two_factor_password">Confirm with your password to disable</label>
+                    <input type="password" id="two_factor_password" name="password" required>
+                </div>
+                <button type="submit" class="btn btn-secondary">Disable Two-Factor Authentication</button>
+            </form>
+        </div>`
+}
+
+// renderTwoFactorLoginPage asks for the second factor after the password
+// step of a login. token identifies the pending login.
+func (h *PortalHandler) renderTwoFactorLoginPage(token string, rememberMe bool, errMsg string) string {
+	alertHTML := ""
+	if errMsg != "" {
+		alertHTML = fmt.Sprintf(`<div class="alert alert-error">%s</div>`, html.EscapeString(errMsg))
+	}
+	rememberHTML := ""
+	if rememberMe {
+		rememberHTML = `<input type="hidden" name="remember_me" value="1">`
+	}
+
+	return fmt.Sprintf(`
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Two-Factor Authentication - %s</title>
+    <style>%s</style>
+</head>
+<body>
+    <div class="auth-container">
+        <div class="auth-box">
+            <div class="auth-header">
+                <h1>%s</h1>
+                <p>Enter the code from your authenticator app</p>
+            </div>
+            %s
+            <form method="POST" action="/portal/login/two-factor" class="auth-form">
+                <input type="hidden" name="token" value="%s">
+                %s
+                <div class="form-group">
+                    <label for="code">Authentication Code</label>
+                    <input type="text" id="code" name="code" required autofocus autocomplete="one-time-code" inputmode="numeric">
+                    <small>Lost your device? Enter one of your recovery codes instead.</small>
+                </div>
+                <button type="submit" class="btn btn-primary btn-block">Verify</button>
+            </form>
+            <div class="auth-footer">
+                <p><a href="/portal/login">Back to log in</a></p>
+            </div>
+        </div>
+    </div>
+</body>
+</html>`, h.appName, portalCSS, h.appName, alertHTML, html.EscapeString(token), rememberHTML)
 }
 
+// renderTwoFactorSetupPage shows the TOTP secret to enroll and asks for a
+// code to confirm it.
+func (h *PortalHandler) renderTwoFactorSetupPage(user *PortalUser, secret, errMsg string) string {
+	alertHTML := ""
+	if errMsg != "" {
+		alertHTML = fmt.Sprintf(`<div class="alert alert-error">%s</div>`, html.EscapeString(errMsg))
+	}
+	uri := domainAuth.TOTPProvisioningURI(h.appName, user.Email, secret)
+
+	return fmt.Sprintf(`
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Two-Factor Authentication - %s</title>
+    <style>%s</style>
+</head>
+<body>
+    %s
+    <main class="main-content">
+        <div class="page-header">
+            <h1>Set Up Two-Factor Authentication</h1>
+        </div>
+        %s
+        <div class="card">
+            <h2>1. Add your account to an authenticator app</h2>
+            <p>Scan this provisioning URI as a QR code, or open it on the device with your authenticator app:</p>
+            <div class="key-display"><a href="%s" id="totp-uri"><code>%s</code></a></div>
+            <p>Or enter this key manually:</p>
+            <div class="key-display"><code id="totp-secret">%s</code></div>
+        </div>
+        <div class="card">
+            <h2>2. Enter the code from the app</h2>
+            <form method="POST" action="/portal/settings/two-factor">
+                <div class="form-group">
+                    <label for="code">Authentication Code</label>
+                    <input type="text" id="code" name="code" required autocomplete="one-time-code" inputmode="numeric" pattern="[0-9 ]*">
+                </div>
+                <button type="submit" class="btn btn-primary">Enable Two-Factor Authentication</button>
+                <a href="/portal/settings" class="btn btn-secondary">Cancel</a>
+            </form>
+        </div>
+    </main>
+</body>
+</html>`, h.appName, portalCSS, h.renderPortalNav(user), alertHTML, html.EscapeString(uri), html.EscapeString(uri), html.EscapeString(secret))
+}
+
+// renderRecoveryCodesPage shows newly issued recovery codes. They are only
+// stored hashed, so this is the only time the user sees them.
+func (h *PortalHandler) renderRecoveryCodesPage(user *PortalUser, codes []string) string {
+	var list strings.Builder
+	for _, code := range codes {
+		fmt.Fprintf(&list, "<li><code>%s</code></li>", html.EscapeString(code))
+	}
+
+	return fmt.Sprintf(`
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Recovery Codes - %s</title>
+    <style>%s</style>
+</head>
+<body>
+    %s
+    <main class="main-content">
+        <div class="page-header">
+            <h1>Two-Factor Authentication Enabled</h1>
+        </div>
+        <div class="card">
+            <h2>Save your recovery codes</h2>
+            <p>If you lose access to your authenticator app, you can log in with one of these codes. Each code works once.</p>
+            <div class="key-display">
+                <ul id="recovery-codes" style="list-style: none; columns: 2;">%s</ul>
+            </div>
+            <p class="key-warning">Store these somewhere safe. They won't be shown again.</p>
+            <a href="/portal/settings" class="btn btn-primary" style="margin-top: 16px;">Done</a>
+        </div>
+    </main>
+</body>
+</html>`, h.appName, portalCSS, h.renderPortalNav(user), list.String())
+}
+
+// renderSessionsPage lists the user's active sessions, marking the one
+// making this request.
+func (h *PortalHandler) renderSessionsPage(user *PortalUser, sessions []domainAuth.Session, success string) string {
+	successHTML := ""
+	if success != "" {
+		successHTML = fmt.Sprintf(`<div class="alert alert-success">%s</div>`, html.EscapeString(success))
+	}
+
+	var rows strings.Builder
+	for _, s := range sessions {
+		device := s.UserAgent
+		if device == "" {
+			device = "Unknown device"
+		}
+		ip := s.IPAddress
+		if ip == "" {
+			ip = "-"
+		}
+		action := fmt.Sprintf(`<form method="POST" action="/portal/settings/sessions/%s/revoke" style="display:inline" onsubmit="showConfirmModal(this, 'Log out this session?', 'Log Out Session'); return false;"><button type="submit" class="btn btn-sm btn-danger">Log out</button></form>`, html.EscapeString(s.ID))
+		if s.ID == user.SessionID {
+			device += " (this session)"
+			action = `<span class="status-active">Current</span>`
+		}
+		fmt.Fprintf(&rows, `
+                    <tr>
+                        <td>%s</td>
+                        <td>%s</td>
+                        <td>%s</td>
+                        <td>%s</td>
+                        <td>%s</td>
+                    </tr>`, html.EscapeString(device), html.EscapeString(ip), s.CreatedAt.Format("Jan 2, 2006 15:04"), s.ExpiresAt.Format("Jan 2, 2006 15:04"), action)
+	}
+	if len(sessions) == 0 {
+		rows.WriteString(`<tr><td colspan="5" class="text-center">No active sessions</td></tr>`)
+	}
+
+	return fmt.Sprintf(`
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Active Sessions - %s</title>
+    <style>%s</style>
+</head>
+<body>
+    %s
+    <main class="main-content">
+        <div class="page-header">
+            <h1>Active Sessions</h1>
+            <form method="POST" action="/portal/settings/sessions/revoke-others" onsubmit="showConfirmModal(this, 'Log out of every other browser and device?', 'Log Out Other Sessions'); return false;">
+                <button type="submit" class="btn btn-danger">Log Out All Other Sessions</button>
+            </form>
+        </div>
+        %s
+        <div class="card">
+            <table class="table">
+                <thead>
+                    <tr>
+                        <th>Device</th>
+                        <th>IP Address</th>
+                        <th>Logged In</th>
+                        <th>Expires</th>
+                        <th>Actions</th>
+                    </tr>
+                </thead>
+                <tbody>%s
+                </tbody>
+            </table>
+        </div>
+        <a href="/portal/settings" class="btn btn-secondary">Back to Settings</a>
+    </main>
+    %s
+</body>
+</html>`, h.appName, portalCSS, h.renderPortalNav(user), successHTML, rows.String(), portalConfirmJS)
+}
+
+// renderErrorPage renders a standalone error page. The message is plain text
+// and is escaped, so it may include user input.
 func (h *PortalHandler) renderErrorPage(message string) string {
 	return fmt.Sprintf(`
 <!DOCTYPE html>
@@ -969,11 +1363,25 @@ func (h *PortalHandler) renderErrorPage(message string) string {
         </div>
     </div>
 </body>
-</html>`, h.appName, portalCSS, h.appName, message)
+</html>`, h.appName, portalCSS, h.appName, html.EscapeString(message))
 }
 
 func (h *PortalHandler) renderPortalNav(user *PortalUser) string {
-	return fmt.Sprintf(`
+	impersonationBanner := ""
+	if user.IsImpersonated() {
+		impersonationBanner = fmt.Sprintf(`
+    <div class="alert alert-warning" style="margin: 0; border-radius: 0; text-align: center;">
+        You are viewing the portal as <strong>%s</strong> (impersonated by admin %s). Actions you take affect this user's account.
+    </div>`, html.EscapeString(user.Email), html.EscapeString(user.ImpersonatedBy))
+	}
+
+	// Show which organization the portal is scoped to
+	account := html.EscapeString(user.Email)
+	if user.Org != nil {
+		account = html.EscapeString(user.Org.Name) + " &middot; " + account
+	}
+
+	return impersonationBanner + fmt.Sprintf(`
     <nav class="portal-nav">
         <div class="nav-brand">
             <a href="/portal/dashboard">%s</a>
@@ -985,6 +1393,7 @@ func (h *PortalHandler) renderPortalNav(user *PortalUser) string {
             <a href="/portal/usage">Usage</a>
             <a href="/portal/plans">Plans</a>
             <a href="/portal/webhooks">Webhooks</a>
+            <a href="/portal/orgs">Organizations</a>
             <a href="/docs" target="_blank">Docs</a>
             <a href="/portal/settings">Settings</a>
         </div>
@@ -995,7 +1404,7 @@ func (h *PortalHandler) renderPortalNav(user *PortalUser) string {
             </form>
         </div>
     </nav>
-`, h.appName, user.Email)
+`, h.appName, account)
 }
 
 func (h *PortalHandler) renderPlansPage(user *PortalUser, plans []ports.Plan, currentPlan *ports.Plan, success, errorMsg string, hasStripeSubscription bool, labels terminology.Labels) string {
@@ -1068,15 +1477,17 @@ func (h *PortalHandler) renderPlansPage(user *PortalUser, plans []ports.Plan, cu
 			actionBtn = fmt.Sprintf(`
 				<form method="POST" action="/portal/plans/change" onsubmit="showConfirmModal(this, 'Upgrade to the %s plan?', 'Confirm Plan Change'); return false;">
 					<input type="hidden" name="plan_id" value="%s">
+					<input type="hidden" name="idempotency_key" value="%s">
 					<button type="submit" class="btn btn-primary">%s</button>
-				</form>`, p.Name, p.ID, buttonText)
+				</form>`, p.Name, p.ID, idempotency.NewKey(), buttonText)
 		} else {
 			// Free plan - show downgrade button
 			actionBtn = fmt.Sprintf(`
 				<form method="POST" action="/portal/plans/change" onsubmit="showConfirmModal(this, 'Switch to the %s plan? You will lose access to higher limits.', 'Confirm Plan Change'); return false;">
 					<input type="hidden" name="plan_id" value="%s">
+					<input type="hidden" name="idempotency_key" value="%s">
 					<button type="submit" class="btn btn-secondary">Switch Plan</button>
-				</form>`, p.Name, p.ID)
+				</form>`, p.Name, p.ID, idempotency.NewKey())
 		}
 
 		// Card highlight for current plan
@@ -1203,6 +1614,8 @@ func (h *PortalHandler) renderBillingPage(user *PortalUser, subscription *billin
 			statusBadge = `<span style="background: #dbeafe; color: #1d4ed8; padding: 4px 12px; border-radius: 20px; font-size: 12px; font-weight: 500;">Trial</span>`
 		case billing.SubscriptionStatusPastDue:
 			statusBadge = `<span style="background: #fef3c7; color: #b45309; padding: 4px 12px; border-radius: 20px; font-size: 12px; font-weight: 500;">Past Due</span>`
+		case billing.SubscriptionStatusUnpaid:
+			statusBadge = `<span style="background: #fee2e2; color: #b91c1c; padding: 4px 12px; border-radius: 20px; font-size: 12px; font-weight: 500;">Suspended</span>`
 		case billing.SubscriptionStatusCancelled:
 			statusBadge = `<span style="background: #fee2e2; color: #b91c1c; padding: 4px 12px; border-radius: 20px; font-size: 12px; font-weight: 500;">Cancelled</span>`
 		default:
@@ -1217,6 +1630,20 @@ func (h *PortalHandler) renderBillingPage(user *PortalUser, subscription *billin
 					<span style="color: #92400e;">Your subscription will end on %s</span>
 				</div>`, subscription.CurrentPeriodEnd.Format("January 2, 2006"))
 		}
+		switch subscription.Status {
+		case billing.SubscriptionStatusPastDue:
+			cancelNotice += `
+				<div style="margin-top: 16px; padding: 12px; background: #fef3c7; border-radius: 8px; display: flex; align-items: center; gap: 8px;">
+					<span style="color: #b45309;">&#9888;</span>
+					<span style="color: #92400e;">Your last payment failed. Update your payment method to keep your subscription.</span>
+				</div>`
+		case billing.SubscriptionStatusUnpaid:
+			cancelNotice += `
+				<div style="margin-top: 16px; padding: 12px; background: #fee2e2; border-radius: 8px; display: flex; align-items: center; gap: 8px;">
+					<span style="color: #b91c1c;">&#9888;</span>
+					<span style="color: #991b1b;">Your subscription is suspended for non-payment. Pay the outstanding invoice to restore your plan.</span>
+				</div>`
+		}
 
 		planName := "Unknown Plan"
 		if plan != nil {
@@ -1325,9 +1752,13 @@ func (h *PortalHandler) renderBillingPage(user *PortalUser, subscription *billin
 				statusBadge = fmt.Sprintf(`<span style="background: #f3f4f6; color: #6b7280; padding: 2px 8px; border-radius: 4px; font-size: 12px;">%s</span>`, inv.Status)
 			}
 
-			downloadLink := ""
+			invoicePath := "/portal/billing/invoices/" + url.PathEscape(inv.ID)
+			downloadLink := fmt.Sprintf(`<a href="%s?format=pdf" style="color: #3b82f6; text-decoration: none; font-size: 14px;">PDF</a>
+					<a href="%s" target="_blank" style="color: #3b82f6; text-decoration: none; font-size: 14px; margin-left: 8px;">Print</a>`,
+				html.EscapeString(invoicePath), html.EscapeString(invoicePath))
 			if inv.InvoiceURL != "" {
-				downloadLink = fmt.Sprintf(`<a href="%s" target="_blank" style="color: #3b82f6; text-decoration: none; font-size: 14px;">Download</a>`, inv.InvoiceURL)
+				downloadLink += fmt.Sprintf(`
+					<a href="%s" target="_blank" style="color: #3b82f6; text-decoration: none; font-size: 14px; margin-left: 8px;">View online</a>`, html.EscapeString(inv.InvoiceURL))
 			}
 
 			invoiceRows += fmt.Sprintf(`
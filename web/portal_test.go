@@ -6,15 +6,23 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/artpar/apigate/adapters/auth"
+	"github.com/artpar/apigate/adapters/clock"
 	"github.com/artpar/apigate/adapters/email"
+	"github.com/artpar/apigate/adapters/memory"
+	"github.com/artpar/apigate/adapters/sqlite"
 	domainAuth "github.com/artpar/apigate/domain/auth"
 	"github.com/artpar/apigate/domain/billing"
 	"github.com/artpar/apigate/domain/key"
+	"github.com/artpar/apigate/domain/quota"
+	"github.com/artpar/apigate/domain/settings"
 	"github.com/artpar/apigate/domain/usage"
 	"github.com/artpar/apigate/domain/webhook"
 	"github.com/artpar/apigate/ports"
@@ -69,11 +77,22 @@ func (m *mockUserStore) Update(ctx context.Context, u ports.User) error {
 	return nil
 }
 
+func (m *mockUserStore) UpdateIfUnchanged(ctx context.Context, u ports.User, since time.Time) error {
+	if old, ok := m.users[u.ID]; ok && !old.UpdatedAt.Equal(since) {
+		return ports.ErrConflict
+	}
+	return m.Update(ctx, u)
+}
+
 func (m *mockUserStore) Delete(ctx context.Context, id string) error {
 	delete(m.users, id)
 	return nil
 }
 
+func (m *mockUserStore) Restore(ctx context.Context, id string) error {
+	return nil
+}
+
 func (m *mockUserStore) List(ctx context.Context, limit, offset int) ([]ports.User, error) {
 	var result []ports.User
 	for _, u := range m.users {
@@ -161,6 +180,17 @@ func (m *mockSessionStore) Get(ctx context.Context, id string) (domainAuth.Sessi
 	return domainAuth.Session{}, errNotFound
 }
 
+func (m *mockSessionStore) ListByUser(ctx context.Context, userID string) ([]domainAuth.Session, error) {
+	var sessions []domainAuth.Session
+	for _, s := range m.sessions {
+		if s.UserID == userID && !s.IsExpired() {
+			sessions = append(sessions, s)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].CreatedAt.After(sessions[j].CreatedAt) })
+	return sessions, nil
+}
+
 func (m *mockSessionStore) Delete(ctx context.Context, id string) error {
 	delete(m.sessions, id)
 	return nil
@@ -229,6 +259,10 @@ func (m *mockUsageStore) GetRecentRequests(ctx context.Context, userID string, l
 	return nil, nil
 }
 
+func (m *mockUsageStore) GetTopPaths(ctx context.Context, userID string, start, end time.Time, limit int) ([]usage.PathUsage, error) {
+	return nil, nil
+}
+
 // mockPlanStore implements ports.PlanStore for testing.
 type mockPlanStore struct {
 	plans []ports.Plan
@@ -358,6 +392,15 @@ func (m *mockInvoiceStore) Create(ctx context.Context, inv billing.Invoice) erro
 	return nil
 }
 
+func (m *mockInvoiceStore) Get(ctx context.Context, id string) (billing.Invoice, error) {
+	for _, inv := range m.invoices {
+		if inv.ID == id {
+			return inv, nil
+		}
+	}
+	return billing.Invoice{}, errors.New("not found")
+}
+
 func (m *mockInvoiceStore) ListByUser(ctx context.Context, userID string, limit int) ([]billing.Invoice, error) {
 	var result []billing.Invoice
 	for _, inv := range m.invoices {
@@ -563,6 +606,264 @@ func TestPortalHandler_SignupSubmit_Success(t *testing.T) {
 	}
 }
 
+func TestPortalHandler_SignupSubmit_RequireEmailVerification(t *testing.T) {
+	userStore := newMockUserStore()
+	tokenStore := newMockTokenStore()
+	emailSender := email.NewMockSender("https://test.com", "TestApp")
+	settingsStore := newMockSettingsStore()
+	settingsStore.settings[settings.KeyAuthRequireEmailVerification] = "true"
+
+	handler, _ := NewPortalHandler(PortalDeps{
+		Users:       userStore,
+		Keys:        &mockKeyStore{},
+		Usage:       &mockUsageStore{},
+		AuthTokens:  tokenStore,
+		Sessions:    newMockSessionStore(),
+		Plans:       newMockPlanStore(),
+		EmailSender: emailSender,
+		Settings:    settingsStore,
+		Logger:      zerolog.Nop(),
+		Hasher:      &mockHasher{},
+		IDGen:       &mockIDGen{},
+		JWTSecret:   "test-secret",
+		BaseURL:     "https://test.com",
+		AppName:     "TestApp",
+	})
+
+	form := url.Values{
+		"email":    {"pending@example.com"},
+		"password": {"Password123"},
+		"name":     {"Pending User"},
+	}
+	req := httptest.NewRequest("POST", "/portal/signup", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	handler.SignupSubmit(w, req)
+
+	// Redirects to login instead of logging in
+	if w.Code != http.StatusFound {
+		t.Fatalf("Status = %d, want %d", w.Code, http.StatusFound)
+	}
+	if location := w.Header().Get("Location"); !strings.HasPrefix(location, "/portal/login?signup=success") {
+		t.Errorf("Location = %s, want the login page", location)
+	}
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "portal_token" && c.Value != "" {
+			t.Error("pending users should not be logged in")
+		}
+	}
+
+	// User is pending until verified
+	user, err := userStore.GetByEmail(context.Background(), "pending@example.com")
+	if err != nil {
+		t.Fatalf("user not created: %v", err)
+	}
+	if user.Status != "pending" {
+		t.Errorf("Status = %s, want pending", user.Status)
+	}
+
+	// A verification token is stored and emailed
+	if len(tokenStore.tokens) != 1 {
+		t.Fatalf("token count = %d, want 1", len(tokenStore.tokens))
+	}
+	for _, tok := range tokenStore.tokens {
+		if tok.Type != domainAuth.TokenTypeEmailVerification || tok.UserID != user.ID {
+			t.Errorf("token = %+v, want an email verification token for the user", tok)
+		}
+	}
+	sent, ok := emailSender.GetLastEmail()
+	if !ok || sent.Type != "verification" || sent.To != "pending@example.com" {
+		t.Errorf("last email = %+v, want a verification email", sent)
+	}
+
+	// Login is refused until the email is verified
+	loginForm := url.Values{"email": {"pending@example.com"}, "password": {"Password123"}}
+	loginReq := httptest.NewRequest("POST", "/portal/login", strings.NewReader(loginForm.Encode()))
+	loginReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	loginW := httptest.NewRecorder()
+
+	handler.PortalLoginSubmit(loginW, loginReq)
+
+	if loginW.Code != http.StatusForbidden {
+		t.Errorf("login Status = %d, want %d", loginW.Code, http.StatusForbidden)
+	}
+	if !strings.Contains(loginW.Body.String(), "verify your email") {
+		t.Error("login page should ask the user to verify their email")
+	}
+}
+
+// fakeBreachedPasswords reports the listed passwords as breached.
+type fakeBreachedPasswords struct {
+	breached map[string]bool
+	err      error
+	calls    int
+}
+
+func (f *fakeBreachedPasswords) IsBreached(ctx context.Context, password string) (bool, error) {
+	f.calls++
+	return f.breached[password], f.err
+}
+
+func newPasswordPolicyHandler(settingsStore *mockSettingsStore, breached *fakeBreachedPasswords) *PortalHandler {
+	handler, _ := NewPortalHandler(PortalDeps{
+		Users:             newMockUserStore(),
+		Keys:              &mockKeyStore{},
+		Usage:             &mockUsageStore{},
+		AuthTokens:        newMockTokenStore(),
+		Sessions:          newMockSessionStore(),
+		Plans:             newMockPlanStore(),
+		Settings:          settingsStore,
+		BreachedPasswords: breached,
+		Logger:            zerolog.Nop(),
+		Hasher:            &mockHasher{},
+		IDGen:             &mockIDGen{},
+		JWTSecret:         "test-secret",
+		AppName:           "TestApp",
+	})
+	return handler
+}
+
+func signupForm(password string) *http.Request {
+	form := url.Values{
+		"email":    {"policy@example.com"},
+		"password": {password},
+		"name":     {"Policy User"},
+	}
+	req := httptest.NewRequest("POST", "/portal/signup", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func TestPortalHandler_SignupSubmit_ConfiguredPasswordPolicy(t *testing.T) {
+	settingsStore := newMockSettingsStore()
+	settingsStore.settings[settings.KeyAuthPasswordMinLength] = "12"
+	settingsStore.settings[settings.KeyAuthPasswordRequireUpper] = "false"
+	settingsStore.settings[settings.KeyAuthPasswordRequireSymbol] = "true"
+	handler := newPasswordPolicyHandler(settingsStore, nil)
+
+	w := httptest.NewRecorder()
+	handler.SignupSubmit(w, signupForm("Password123"))
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "Password must be at least 12 characters") {
+		t.Error("expected min length error")
+	}
+	if !strings.Contains(body, `minlength="12"`) || !strings.Contains(body, "At least 12 characters with lowercase, number, and symbol") {
+		t.Error("expected the form hint to describe the configured policy")
+	}
+
+	w = httptest.NewRecorder()
+	handler.SignupSubmit(w, signupForm("lowercase-only-42"))
+
+	if w.Code != http.StatusFound {
+		t.Errorf("Status = %d, want %d for a password meeting the policy", w.Code, http.StatusFound)
+	}
+}
+
+func TestPortalHandler_SignupSubmit_BreachedPassword(t *testing.T) {
+	settingsStore := newMockSettingsStore()
+	settingsStore.settings[settings.KeyAuthPasswordCheckBreached] = "true"
+	breached := &fakeBreachedPasswords{breached: map[string]bool{"Password123": true}}
+	handler := newPasswordPolicyHandler(settingsStore, breached)
+
+	w := httptest.NewRecorder()
+	handler.SignupSubmit(w, signupForm("Password123"))
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+	if !strings.Contains(w.Body.String(), "appeared in a data breach") {
+		t.Error("expected breached password error")
+	}
+
+	// Passwords failing the other rules are not looked up
+	breached.calls = 0
+	handler.SignupSubmit(httptest.NewRecorder(), signupForm("short"))
+	if breached.calls != 0 {
+		t.Errorf("breach checks = %d, want 0 for an invalid password", breached.calls)
+	}
+
+	w = httptest.NewRecorder()
+	handler.SignupSubmit(w, signupForm("Unbreached123"))
+	if w.Code != http.StatusFound {
+		t.Errorf("Status = %d, want %d for an unbreached password", w.Code, http.StatusFound)
+	}
+}
+
+func TestPortalHandler_SignupSubmit_BreachCheckDisabledOrFailing(t *testing.T) {
+	// Disabled by default
+	breached := &fakeBreachedPasswords{breached: map[string]bool{"Password123": true}}
+	handler := newPasswordPolicyHandler(newMockSettingsStore(), breached)
+
+	w := httptest.NewRecorder()
+	handler.SignupSubmit(w, signupForm("Password123"))
+	if w.Code != http.StatusFound || breached.calls != 0 {
+		t.Errorf("Status = %d, checks = %d; want signup without a breach check", w.Code, breached.calls)
+	}
+
+	// Lookup errors fail open
+	settingsStore := newMockSettingsStore()
+	settingsStore.settings[settings.KeyAuthPasswordCheckBreached] = "true"
+	failing := &fakeBreachedPasswords{err: errors.New("service unavailable")}
+	handler = newPasswordPolicyHandler(settingsStore, failing)
+
+	w = httptest.NewRecorder()
+	handler.SignupSubmit(w, signupForm("Password123"))
+	if w.Code != http.StatusFound {
+		t.Errorf("Status = %d, want %d when the breach check fails", w.Code, http.StatusFound)
+	}
+}
+
+func TestPortalHandler_ChangePassword_BreachedPassword(t *testing.T) {
+	settingsStore := newMockSettingsStore()
+	settingsStore.settings[settings.KeyAuthPasswordCheckBreached] = "true"
+	breached := &fakeBreachedPasswords{breached: map[string]bool{"Password123": true}}
+	handler := newPasswordPolicyHandler(settingsStore, breached)
+
+	form := url.Values{
+		"current_password": {"OldPassword1"},
+		"new_password":     {"Password123"},
+		"confirm_password": {"Password123"},
+	}
+	req := httptest.NewRequest("POST", "/portal/settings/password", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req = req.WithContext(context.WithValue(req.Context(), portalUserKey, &PortalUser{ID: "user-1", Email: "user@example.com", Name: "User"}))
+	w := httptest.NewRecorder()
+
+	handler.ChangePassword(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+	if !strings.Contains(w.Body.String(), "appeared in a data breach") {
+		t.Error("expected breached password error")
+	}
+}
+
+func TestPortalHandler_APIResetPassword_PasswordPolicy(t *testing.T) {
+	settingsStore := newMockSettingsStore()
+	settingsStore.settings[settings.KeyAuthPasswordRequireSymbol] = "true"
+	handler := newPasswordPolicyHandler(settingsStore, nil)
+
+	body := `{"token":"tok","password":"Password123","confirm_password":"Password123"}`
+	req := httptest.NewRequest("POST", "/portal/api/reset-password", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	handler.APIResetPassword(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+	if !strings.Contains(w.Body.String(), "Password must contain uppercase, lowercase, number, and symbol") {
+		t.Errorf("body = %s, want symbol requirement error", w.Body.String())
+	}
+}
+
 func TestPortalHandler_SignupSubmit_ValidationError(t *testing.T) {
 	handler, _, _, _ := newTestPortalHandler()
 
@@ -893,6 +1194,78 @@ func TestPortalHandler_PortalAuthMiddleware_NoToken(t *testing.T) {
 	}
 }
 
+func TestPortalHandler_StartImpersonation(t *testing.T) {
+	handler, userStore, _, _ := newTestPortalHandler()
+	userStore.users["user1"] = ports.User{ID: "user1", Email: "user@example.com", Status: "active"}
+
+	tokenService := auth.NewTokenService("test-secret", 24*time.Hour)
+	token, _, _ := tokenService.GenerateImpersonationToken("user1", "user@example.com", "admin1", 15*time.Minute)
+
+	req := httptest.NewRequest("GET", "/portal/impersonate?token="+url.QueryEscape(token), nil)
+	w := httptest.NewRecorder()
+	handler.StartImpersonation(w, req)
+
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/portal/dashboard" {
+		t.Fatalf("Status = %d, Location = %s, want redirect to dashboard", w.Code, w.Header().Get("Location"))
+	}
+	var cookie *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "portal_token" {
+			cookie = c
+		}
+	}
+	if cookie == nil || cookie.Value != token {
+		t.Fatal("Expected the impersonation token to be set as the portal session")
+	}
+	if cookie.MaxAge <= 0 || cookie.MaxAge > 15*60 {
+		t.Errorf("Cookie MaxAge = %d, want at most 15 minutes", cookie.MaxAge)
+	}
+
+	// The session resolves to the target user and is flagged as impersonated
+	var gotUser *PortalUser
+	protected := handler.PortalAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUser = getPortalUser(r.Context())
+	}))
+	req = httptest.NewRequest("GET", "/portal/dashboard", nil)
+	req.AddCookie(cookie)
+	protected.ServeHTTP(httptest.NewRecorder(), req)
+
+	if gotUser == nil || gotUser.ID != "user1" {
+		t.Fatalf("User = %+v, want user1", gotUser)
+	}
+	if !gotUser.IsImpersonated() || gotUser.ImpersonatedBy != "admin1" {
+		t.Errorf("ImpersonatedBy = %q, want admin1", gotUser.ImpersonatedBy)
+	}
+	if nav := handler.renderPortalNav(gotUser); !strings.Contains(nav, "impersonated by admin admin1") {
+		t.Error("Portal nav should show the impersonation banner")
+	}
+
+	// The banner escapes the email and admin it shows
+	nav := handler.renderPortalNav(&PortalUser{ID: "user1", Email: "<b>x</b>@example.com", ImpersonatedBy: "<script>alert(1)</script>"})
+	if strings.Contains(nav, "<b>x</b>") || strings.Contains(nav, "<script>") {
+		t.Error("Impersonation banner should escape the user email and admin ID")
+	}
+}
+
+func TestPortalHandler_StartImpersonation_RejectsRegularToken(t *testing.T) {
+	handler, userStore, _, _ := newTestPortalHandler()
+	userStore.users["user1"] = ports.User{ID: "user1", Email: "user@example.com", Status: "active"}
+
+	tokenService := auth.NewTokenService("test-secret", 24*time.Hour)
+	token, _, _ := tokenService.GenerateToken("user1", "user@example.com", "user")
+
+	req := httptest.NewRequest("GET", "/portal/impersonate?token="+url.QueryEscape(token), nil)
+	w := httptest.NewRecorder()
+	handler.StartImpersonation(w, req)
+
+	if w.Header().Get("Location") != "/portal/login" {
+		t.Errorf("Location = %s, want /portal/login", w.Header().Get("Location"))
+	}
+	if len(w.Result().Cookies()) != 0 {
+		t.Error("A regular session token must not be accepted as an impersonation link")
+	}
+}
+
 func TestPortalHandler_PortalAuthMiddleware_ValidToken(t *testing.T) {
 	handler, userStore, _, _ := newTestPortalHandler()
 
@@ -1219,6 +1592,49 @@ func TestPortalHandler_CreateAPIKey(t *testing.T) {
 	}
 }
 
+func TestPortalHandler_CreateAPIKey_IdempotencyKey(t *testing.T) {
+	handler, userStore, _, _ := newTestPortalHandler()
+	keyStore := &mockKeyStoreWithStorage{keys: make(map[string]key.Key)}
+	handler.keys = keyStore
+	handler.idempotency = memory.NewResponseCache(clock.Real{}, 0)
+
+	userStore.users["user1"] = ports.User{
+		ID:     "user1",
+		Email:  "user@example.com",
+		Status: "active",
+	}
+	tokenService := auth.NewTokenService("test-secret", 24*time.Hour)
+	token, _, _ := tokenService.GenerateToken("user1", "user@example.com", "user")
+	router := handler.Router()
+
+	post := func() *httptest.ResponseRecorder {
+		form := url.Values{"name": {"My API Key"}}
+		req := httptest.NewRequest("POST", "/api-keys", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Idempotency-Key", "double-click-1")
+		req.AddCookie(&http.Cookie{Name: "portal_token", Value: token})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := post()
+	second := post()
+
+	if first.Code != http.StatusOK || second.Code != http.StatusOK {
+		t.Fatalf("Status = %d/%d, want %d", first.Code, second.Code, http.StatusOK)
+	}
+	if len(keyStore.keys) != 1 {
+		t.Errorf("keys created = %d, want 1", len(keyStore.keys))
+	}
+	if first.Body.String() != second.Body.String() {
+		t.Error("duplicate request should replay the first response")
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("duplicate response should be marked as replayed")
+	}
+}
+
 func TestPortalHandler_PortalUsagePage(t *testing.T) {
 	handler, userStore, _, _ := newTestPortalHandler()
 
@@ -1245,6 +1661,31 @@ func TestPortalHandler_PortalUsagePage(t *testing.T) {
 	}
 }
 
+func TestPortalHandler_PortalUsagePage_QuotaWindow(t *testing.T) {
+	handler, userStore, _, _ := newTestPortalHandler()
+	planStore := handler.plans.(*mockPlanStore)
+	planStore.plans = append(planStore.plans, ports.Plan{ID: "weekly", Name: "Weekly", QuotaPeriod: ports.QuotaPeriodCalendarWeek, Enabled: true})
+
+	userStore.users["user1"] = ports.User{
+		ID:     "user1",
+		Email:  "user@example.com",
+		PlanID: "weekly",
+		Status: "active",
+	}
+
+	req := httptest.NewRequest("GET", "/portal/usage", nil)
+	req = req.WithContext(withPortalUser(req.Context(), &PortalUser{ID: "user1", Email: "user@example.com"}))
+	w := httptest.NewRecorder()
+
+	handler.PortalUsagePage(w, req)
+
+	start, end := quota.WindowBounds(quota.PeriodCalendarWeek, time.Now().UTC(), time.Time{})
+	want := start.Format("Jan 2, 2006 15:04 MST") + " &ndash; " + end.Format("Jan 2, 2006 15:04 MST")
+	if body := w.Body.String(); !strings.Contains(body, want) {
+		t.Errorf("usage page does not show quota window %q", want)
+	}
+}
+
 func TestPortalHandler_AccountSettingsPage(t *testing.T) {
 	handler, userStore, _, _ := newTestPortalHandler()
 
@@ -1308,6 +1749,116 @@ func TestPortalHandler_UpdateAccountSettings_Success(t *testing.T) {
 	}
 }
 
+func TestPortalHandler_UpdateAccountSettings_StaleVersion(t *testing.T) {
+	handler, userStore, _, _ := newTestPortalHandler()
+
+	loaded := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	userStore.users["user1"] = ports.User{
+		ID:        "user1",
+		Email:     "user@example.com",
+		Name:      "Old Name",
+		Status:    "active",
+		UpdatedAt: loaded,
+	}
+
+	// Both tabs rendered the form at the same version
+	version := strconv.FormatInt(loaded.UnixNano(), 10)
+	submit := func(name string) *httptest.ResponseRecorder {
+		form := url.Values{"name": {name}, "version": {version}}
+		req := httptest.NewRequest("POST", "/portal/settings", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		user := userStore.users["user1"]
+		req = req.WithContext(withPortalUser(req.Context(), &PortalUser{
+			ID:        user.ID,
+			Email:     user.Email,
+			Name:      user.Name,
+			UpdatedAt: user.UpdatedAt,
+		}))
+		w := httptest.NewRecorder()
+		handler.UpdateAccountSettings(w, req)
+		return w
+	}
+
+	if w := submit("Tab One"); w.Code != http.StatusSeeOther {
+		t.Fatalf("first save = %d, want %d", w.Code, http.StatusSeeOther)
+	}
+	w := submit("Tab Two")
+	if w.Code != http.StatusConflict {
+		t.Fatalf("stale save = %d, want %d", w.Code, http.StatusConflict)
+	}
+	if got := userStore.users["user1"].Name; got != "Tab One" {
+		t.Errorf("Name = %q, stale save should not apply", got)
+	}
+	// The page offers the current values with the current version
+	current := strconv.FormatInt(userStore.users["user1"].UpdatedAt.UnixNano(), 10)
+	if body := w.Body.String(); !strings.Contains(body, `value="Tab One"`) || !strings.Contains(body, `value="`+current+`"`) {
+		t.Error("conflict page should show the current name and version")
+	}
+}
+
+// interleavingUserStore runs beforeUpdate once, just before the first
+// conditional update is written, to simulate a concurrent save.
+type interleavingUserStore struct {
+	*mockUserStore
+	beforeUpdate func()
+}
+
+func (s *interleavingUserStore) UpdateIfUnchanged(ctx context.Context, u ports.User, since time.Time) error {
+	if f := s.beforeUpdate; f != nil {
+		s.beforeUpdate = nil
+		f()
+	}
+	return s.mockUserStore.UpdateIfUnchanged(ctx, u, since)
+}
+
+func TestPortalHandler_UpdateAccountSettings_InterleavedSaves(t *testing.T) {
+	handler, userStore, _, _ := newTestPortalHandler()
+
+	loaded := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	userStore.users["user1"] = ports.User{
+		ID:        "user1",
+		Email:     "user@example.com",
+		Name:      "Old Name",
+		Status:    "active",
+		UpdatedAt: loaded,
+	}
+
+	version := strconv.FormatInt(loaded.UnixNano(), 10)
+	submit := func(name string) *httptest.ResponseRecorder {
+		form := url.Values{"name": {name}, "version": {version}}
+		req := httptest.NewRequest("POST", "/portal/settings", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req = req.WithContext(withPortalUser(req.Context(), &PortalUser{
+			ID:        "user1",
+			Email:     "user@example.com",
+			Name:      "Old Name",
+			UpdatedAt: loaded,
+		}))
+		w := httptest.NewRecorder()
+		handler.UpdateAccountSettings(w, req)
+		return w
+	}
+
+	// The second save lands after the first has passed its version check
+	// but before it is written
+	var second *httptest.ResponseRecorder
+	handler.users = &interleavingUserStore{
+		mockUserStore: userStore,
+		beforeUpdate:  func() { second = submit("Tab Two") },
+	}
+
+	first := submit("Tab One")
+	if second == nil || second.Code != http.StatusSeeOther {
+		t.Fatalf("second save did not succeed: %v", second)
+	}
+	if first.Code != http.StatusConflict {
+		t.Fatalf("first save = %d, want %d", first.Code, http.StatusConflict)
+	}
+	if got := userStore.users["user1"].Name; got != "Tab Two" {
+		t.Errorf("Name = %q, the outdated save should not overwrite Tab Two", got)
+	}
+}
+
 func TestPortalHandler_UpdateAccountSettings_EmptyName(t *testing.T) {
 	handler, userStore, _, _ := newTestPortalHandler()
 
@@ -1574,9 +2125,9 @@ func TestPortalHandler_CloseAccount_Success(t *testing.T) {
 		t.Errorf("Status = %d, want %d", w.Code, http.StatusFound)
 	}
 
-	// Verify user is marked as cancelled
-	if userStore.users["user1"].Status != "cancelled" {
-		t.Error("User status should be cancelled")
+	// Verify user is deleted
+	if _, ok := userStore.users["user1"]; ok {
+		t.Error("User should be deleted")
 	}
 }
 
@@ -1611,21 +2162,77 @@ func TestPortalHandler_CloseAccount_WrongPassword(t *testing.T) {
 	}
 }
 
-// mockKeyStoreWithStorage stores keys for testing
-type mockKeyStoreWithStorage struct {
-	keys map[string]key.Key
-}
+func TestPortalHandler_CloseAccount_EmailReusable(t *testing.T) {
+	db, err := sqlite.Open(t.TempDir() + "/portal.db")
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	defer db.Close()
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
 
-func (m *mockKeyStoreWithStorage) Get(ctx context.Context, prefix string) ([]key.Key, error) {
-	return nil, nil
-}
+	// The real store keeps closed accounts as soft-deleted rows
+	handler, _, _, _ := newTestPortalHandler()
+	handler.users = sqlite.NewUserStore(db)
+	if err := handler.users.Create(context.Background(), ports.User{
+		ID: "user1", Email: "user@example.com", PasswordHash: []byte("hashed_Password123"), PlanID: "free", Status: "active",
+	}); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
 
-func (m *mockKeyStoreWithStorage) Create(ctx context.Context, k key.Key) error {
-	m.keys[k.ID] = k
+	form := url.Values{"password": {"Password123"}}
+	req := httptest.NewRequest("POST", "/portal/settings/close-account", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req = req.WithContext(withPortalUser(req.Context(), &PortalUser{ID: "user1", Email: "user@example.com"}))
+	w := httptest.NewRecorder()
+	handler.CloseAccount(w, req)
+	if w.Code != http.StatusFound {
+		t.Fatalf("close account status = %d, want %d", w.Code, http.StatusFound)
+	}
+
+	// Signing up again with the same email starts a fresh account
+	form = url.Values{
+		"email":    {"user@example.com"},
+		"password": {"Password123"},
+		"name":     {"Returning User"},
+	}
+	req = httptest.NewRequest("POST", "/portal/signup", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	handler.SignupSubmit(w, req)
+	if w.Code != http.StatusFound {
+		t.Fatalf("signup status = %d, want %d: %s", w.Code, http.StatusFound, w.Body.String())
+	}
+
+	u, err := handler.users.GetByEmail(context.Background(), "user@example.com")
+	if err != nil {
+		t.Fatalf("get new user: %v", err)
+	}
+	if u.ID == "user1" || u.Name != "Returning User" {
+		t.Errorf("user = %s %q, want a new account", u.ID, u.Name)
+	}
+}
+
+// mockKeyStoreWithStorage stores keys for testing
+type mockKeyStoreWithStorage struct {
+	keys map[string]key.Key
+}
+
+func (m *mockKeyStoreWithStorage) Get(ctx context.Context, prefix string) ([]key.Key, error) {
+	return nil, nil
+}
+
+func (m *mockKeyStoreWithStorage) Create(ctx context.Context, k key.Key) error {
+	m.keys[k.ID] = k
 	return nil
 }
 
 func (m *mockKeyStoreWithStorage) Revoke(ctx context.Context, id string, at time.Time) error {
+	if k, ok := m.keys[id]; ok {
+		k.RevokedAt = &at
+		m.keys[id] = k
+	}
 	return nil
 }
 
@@ -1968,6 +2575,66 @@ func TestPortalHandler_RevokeAPIKey_NotOwned(t *testing.T) {
 	}
 }
 
+func TestPortalHandler_RotateAPIKey(t *testing.T) {
+	handler, userStore, keyStore := newTestPortalHandlerWithKeyStore()
+
+	userStore.users["user1"] = ports.User{
+		ID:    "user1",
+		Email: "user@example.com",
+	}
+	keyStore.keys["key1"] = key.Key{
+		ID:     "key1",
+		UserID: "user1",
+		Prefix: "ak_oldprefix",
+		Name:   "Test Key",
+	}
+
+	r := chi.NewRouter()
+	r.Post("/portal/api-keys/{id}/rotate", handler.RotateAPIKey)
+
+	rotate := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/portal/api-keys/key1/rotate", nil)
+		req = req.WithContext(withPortalUser(req.Context(), &PortalUser{ID: "user1", Email: "user@example.com"}))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := rotate()
+	if w.Code != http.StatusOK {
+		t.Fatalf("Status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if !strings.Contains(w.Body.String(), "ak_oldprefix") {
+		t.Error("Page should mention the old key prefix")
+	}
+
+	var replacement *key.Key
+	for _, k := range keyStore.keys {
+		if k.RotatedFrom == "key1" {
+			replacement = &k
+		}
+	}
+	if replacement == nil {
+		t.Fatal("Replacement key not created")
+	}
+	if replacement.UserID != "user1" || replacement.Name != "Test Key" {
+		t.Errorf("Replacement = %+v, want same owner and name", replacement)
+	}
+	if !strings.Contains(w.Body.String(), replacement.Prefix) {
+		t.Error("Page should show the new key")
+	}
+
+	old := keyStore.keys["key1"]
+	if old.RevokedAt == nil || !old.RevokedAt.After(time.Now().Add(23*time.Hour)) {
+		t.Errorf("Old key RevokedAt = %v, want about 24h from now", old.RevokedAt)
+	}
+
+	// A key already being rotated can't be rotated again
+	if w := rotate(); w.Code != http.StatusConflict {
+		t.Errorf("Second rotation status = %d, want %d", w.Code, http.StatusConflict)
+	}
+}
+
 func TestPortalHandler_CheckoutSuccess(t *testing.T) {
 	handler, userStore, _ := newTestPortalHandlerWithKeyStore()
 
@@ -3059,6 +3726,132 @@ func TestPortalHandler_CreateAPIKey_Success(t *testing.T) {
 	}
 }
 
+func TestPortalHandler_CreateAPIKey_MonthlyQuota(t *testing.T) {
+	handler, userStore, keys := newTestPortalHandlerWithKeyStore()
+	userStore.users["user1"] = ports.User{ID: "user1", Email: "test@example.com", Status: "active"}
+
+	create := func(quota string) int {
+		form := url.Values{"name": {"Capped"}, "monthly_quota": {quota}}
+		req := httptest.NewRequest("POST", "/portal/api-keys", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req = req.WithContext(withPortalUser(req.Context(), &PortalUser{ID: "user1", Email: "test@example.com"}))
+		w := httptest.NewRecorder()
+		handler.CreateAPIKey(w, req)
+		return w.Code
+	}
+
+	for _, bad := range []string{"-1", "lots"} {
+		if code := create(bad); code != http.StatusBadRequest {
+			t.Errorf("monthly_quota %q: status = %d, want %d", bad, code, http.StatusBadRequest)
+		}
+	}
+	if len(keys.keys) != 0 {
+		t.Fatalf("keys created = %d, want 0", len(keys.keys))
+	}
+
+	if code := create("5000"); code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", code, http.StatusOK)
+	}
+	var created key.Key
+	for _, k := range keys.keys {
+		created = k
+	}
+	if created.MonthlyQuota != 5000 {
+		t.Errorf("MonthlyQuota = %d, want 5000", created.MonthlyQuota)
+	}
+
+	rows := handler.renderAPIKeysTableRows([]key.Key{created, {ID: "k2", Name: "Uncapped"}}, true)
+	if !strings.Contains(rows, "5000 / period") {
+		t.Error("key quota not shown")
+	}
+	if !strings.Contains(rows, "<td>Plan</td>") {
+		t.Error("plan quota not shown for key without an override")
+	}
+}
+
+func TestPortalHandler_APIKeyLabels(t *testing.T) {
+	f := newOrgFixture(t)
+	ctx := context.Background()
+
+	w := f.do(t, "POST", "/portal/api-keys", "alice", "", url.Values{"name": {"billing-prod"}, "labels": {"env=prod, service=billing"}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("create status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if w := f.do(t, "POST", "/portal/api-keys", "alice", "", url.Values{"labels": {"env"}}); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid labels: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	_, dev := key.Generate("ak_")
+	dev = dev.WithUserID("alice").WithName("billing-dev").WithLabels(map[string]string{"env": "dev"})
+	f.keys.Create(ctx, dev)
+
+	keys, _ := f.keys.ListByUserLabels(ctx, "alice", key.LabelSelector{"env": "prod"})
+	if len(keys) != 1 || keys[0].Labels["service"] != "billing" {
+		t.Fatalf("keys labelled env=prod = %+v, want the created key", keys)
+	}
+	prod := keys[0]
+
+	body := f.do(t, "GET", "/portal/api-keys?labels=env%3Dprod", "alice", "", nil).Body.String()
+	if !strings.Contains(body, prod.Prefix) || strings.Contains(body, dev.Prefix) {
+		t.Error("label filter should list only the env=prod key")
+	}
+	if !strings.Contains(body, "service=billing") {
+		t.Error("key labels not shown")
+	}
+	if w := f.do(t, "GET", "/portal/api-keys?labels=bad%20label", "alice", "", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid filter: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	// The rejected filter is echoed back escaped
+	xss := "<img src=x onerror=alert(1)>"
+	w = f.do(t, "GET", "/portal/api-keys?labels="+url.QueryEscape(xss), "alice", "", nil)
+	if w.Code != http.StatusBadRequest || strings.Contains(w.Body.String(), "<img") {
+		t.Errorf("invalid filter error page should escape the filter, got status %d body %q", w.Code, w.Body.String())
+	}
+	w = f.do(t, "POST", "/portal/api-keys/"+dev.ID+"/labels", "alice", "", url.Values{"labels": {xss}})
+	if w.Code != http.StatusBadRequest || strings.Contains(w.Body.String(), "<img") {
+		t.Errorf("invalid labels error page should escape the labels, got status %d", w.Code)
+	}
+
+	// Relabel the dev key so it matches the filter
+	w = f.do(t, "POST", "/portal/api-keys/"+dev.ID+"/labels", "alice", "", url.Values{"labels": {"env=prod"}})
+	if w.Code != http.StatusFound {
+		t.Fatalf("set labels status = %d, want %d", w.Code, http.StatusFound)
+	}
+	body = f.do(t, "GET", "/portal/api-keys/partial?labels=env%3Dprod", "alice", "", nil).Body.String()
+	if !strings.Contains(body, prod.Prefix) || !strings.Contains(body, dev.Prefix) {
+		t.Error("relabelled key missing from filtered list")
+	}
+
+	// Only the account's own keys can be relabelled
+	if w := f.do(t, "POST", "/portal/api-keys/"+dev.ID+"/labels", "bob", "", url.Values{"labels": {"env=x"}}); w.Code != http.StatusNotFound {
+		t.Errorf("other account: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestPortalHandler_APIKeysLastUsed(t *testing.T) {
+	f := newOrgFixture(t)
+	ctx := context.Background()
+
+	_, used := key.Generate("ak_")
+	used = used.WithUserID("alice").WithName("used")
+	_, unused := key.Generate("ak_")
+	unused = unused.WithUserID("alice").WithName("unused")
+	f.keys.Create(ctx, used)
+	f.keys.Create(ctx, unused)
+	f.keys.RecordUses(ctx, []key.Use{{KeyID: used.ID, At: time.Now().Add(-3 * time.Hour), IP: "203.0.113.9"}})
+
+	body := f.do(t, "GET", "/portal/api-keys", "alice", "", nil).Body.String()
+	if !strings.Contains(body, "3 hours ago") {
+		t.Error("last-used time not shown")
+	}
+	if !strings.Contains(body, "from 203.0.113.9") {
+		t.Error("last-seen IP not shown")
+	}
+	if !strings.Contains(body, "Never") {
+		t.Error("unused key should show Never")
+	}
+}
+
 func TestPortalHandler_PortalWebhookEditPage_NotFound(t *testing.T) {
 	handler, userStore, _ := newTestPortalHandlerWithWebhooks()
 
@@ -5385,6 +6178,66 @@ func TestPortalHandler_BillingPage_WithInvoices(t *testing.T) {
 	}
 }
 
+func TestPortalHandler_PortalInvoiceDownload(t *testing.T) {
+	handler, _, _, invoices, _ := newTestPortalHandlerWithBilling()
+	invoices.invoices = append(invoices.invoices,
+		billing.Invoice{
+			ID:     "inv1",
+			UserID: "user1",
+			Items: []billing.InvoiceItem{
+				{Description: "Pro Plan - Monthly subscription", Quantity: 1, UnitPrice: 2900, Amount: 2900},
+				{Description: "API overage (1,500 requests)", Quantity: 1500, UnitPrice: 1, Amount: 1500},
+			},
+			Subtotal: 4400,
+			Total:    4400,
+			Status:   billing.InvoiceStatusOpen,
+		},
+		billing.Invoice{ID: "inv2", UserID: "user2", Total: 100},
+	)
+
+	get := func(id, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/portal/billing/invoices/"+id+query, nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+		ctx = context.WithValue(ctx, portalUserKey, &PortalUser{ID: "user1", Email: "test@test.com", Name: "Test User"})
+		w := httptest.NewRecorder()
+		handler.PortalInvoiceDownload(w, req.WithContext(ctx))
+		return w
+	}
+
+	w := get("inv1", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Status = %d, want OK", w.Code)
+	}
+	for _, want := range []string{"Pro Plan - Monthly subscription", "API overage (1,500 requests)", "$44", "test@test.com"} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("invoice page is missing %q", want)
+		}
+	}
+
+	w = get("inv1", "?format=pdf")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/pdf" {
+		t.Fatalf("PDF: status %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(w.Header().Get("Content-Disposition"), "invoice_inv1.pdf") {
+		t.Errorf("Content-Disposition = %q", w.Header().Get("Content-Disposition"))
+	}
+	if !strings.HasPrefix(w.Body.String(), "%PDF-") || !strings.Contains(w.Body.String(), "(API overage \\(1,500 requests\\)) Tj") {
+		t.Error("PDF is missing the overage line item")
+	}
+
+	if w := get("inv1", "?format=docx"); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown format: Status = %d, want BadRequest", w.Code)
+	}
+	if w := get("inv2", ""); w.Code != http.StatusNotFound {
+		t.Errorf("another user's invoice: Status = %d, want NotFound", w.Code)
+	}
+	if w := get("missing", ""); w.Code != http.StatusNotFound {
+		t.Errorf("missing invoice: Status = %d, want NotFound", w.Code)
+	}
+}
+
 // =============================================================================
 // Additional Coverage Tests
 // =============================================================================
@@ -6458,3 +7311,773 @@ func TestPortalHandler_APIKeysPartial_WithKeys(t *testing.T) {
 	}
 }
 
+func TestRenderTopPathRows(t *testing.T) {
+	rows := renderTopPathRows([]usage.PathUsage{
+		{Path: "/v1/search", RequestCount: 42, ErrorCount: 3, BytesIn: 2048, BytesOut: 4096},
+		{Path: "/v1/<script>", RequestCount: 1},
+	})
+	if !strings.Contains(rows, "<code>/v1/search</code>") || !strings.Contains(rows, "<td>42</td>") || !strings.Contains(rows, "<td>2.00 KB</td>") {
+		t.Errorf("rows missing search usage:\n%s", rows)
+	}
+	if strings.Contains(rows, "<script>") || !strings.Contains(rows, "/v1/&lt;script&gt;") {
+		t.Errorf("paths must be escaped:\n%s", rows)
+	}
+	if empty := renderTopPathRows(nil); !strings.Contains(empty, "No requests") {
+		t.Errorf("empty rows = %s", empty)
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Two-Factor Authentication
+// -----------------------------------------------------------------------------
+
+func newTwoFactorHandler(t *testing.T) (*PortalHandler, *mockUserStore, *mockTokenStore) {
+	t.Helper()
+	userStore := newMockUserStore()
+	tokenStore := newMockTokenStore()
+	userStore.users["user-2fa"] = ports.User{
+		ID:           "user-2fa",
+		Email:        "2fa@example.com",
+		Name:         "Two Factor",
+		PasswordHash: []byte("hashed_Password123"),
+		Status:       "active",
+	}
+	handler, err := NewPortalHandler(PortalDeps{
+		Users:      userStore,
+		Keys:       &mockKeyStore{},
+		Usage:      &mockUsageStore{},
+		AuthTokens: tokenStore,
+		Sessions:   newMockSessionStore(),
+		Plans:      newMockPlanStore(),
+		Logger:     zerolog.Nop(),
+		Hasher:     &mockHasher{},
+		IDGen:      &mockIDGen{},
+		JWTSecret:  "test-secret",
+		AppName:    "TestApp",
+	})
+	if err != nil {
+		t.Fatalf("NewPortalHandler: %v", err)
+	}
+	return handler, userStore, tokenStore
+}
+
+// enableTwoFactor enrolls user-2fa with a known secret and recovery codes.
+func enableTwoFactor(userStore *mockUserStore, secret string, recoveryCodes ...string) {
+	user := userStore.users["user-2fa"]
+	user.TOTPSecret = secret
+	user.TOTPEnabled = true
+	for _, code := range recoveryCodes {
+		user.RecoveryCodes = append(user.RecoveryCodes, domainAuth.HashRecoveryCode(code))
+	}
+	userStore.users["user-2fa"] = user
+}
+
+func postForm(handler http.HandlerFunc, path string, form url.Values, user *PortalUser) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if user != nil {
+		req = req.WithContext(context.WithValue(req.Context(), portalUserKey, user))
+	}
+	w := httptest.NewRecorder()
+	handler(w, req)
+	return w
+}
+
+// startTwoFactorLogin submits the password step and returns the pending
+// login token from the two-factor form.
+func startTwoFactorLogin(t *testing.T, handler *PortalHandler) string {
+	t.Helper()
+	w := postForm(handler.PortalLoginSubmit, "/portal/login", url.Values{
+		"email":    {"2fa@example.com"},
+		"password": {"Password123"},
+	}, nil)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("login Status = %d, want %d", w.Code, http.StatusOK)
+	}
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "portal_token" && c.Value != "" {
+			t.Fatal("password alone should not log in a user with two-factor authentication")
+		}
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, `action="/portal/login/two-factor"`) {
+		t.Fatal("expected the two-factor code form")
+	}
+	m := regexp.MustCompile(`name="token" value="([0-9a-f]+)"`).FindStringSubmatch(body)
+	if m == nil {
+		t.Fatal("pending login token not found")
+	}
+	return m[1]
+}
+
+func hasPortalCookie(w *httptest.ResponseRecorder) bool {
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "portal_token" && c.Value != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPortalHandler_TwoFactorEnrollment(t *testing.T) {
+	handler, userStore, _ := newTwoFactorHandler(t)
+	portalUser := &PortalUser{ID: "user-2fa", Email: "2fa@example.com", Name: "Two Factor"}
+
+	// Setup page issues a secret and shows its provisioning URI
+	req := httptest.NewRequest("GET", "/portal/settings/two-factor", nil)
+	req = req.WithContext(context.WithValue(req.Context(), portalUserKey, portalUser))
+	w := httptest.NewRecorder()
+	handler.TwoFactorSetupPage(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("setup Status = %d, want %d", w.Code, http.StatusOK)
+	}
+	secret := userStore.users["user-2fa"].TOTPSecret
+	if secret == "" {
+		t.Fatal("expected a pending TOTP secret")
+	}
+	if userStore.users["user-2fa"].TOTPEnabled {
+		t.Error("two-factor should not be enabled before a code is confirmed")
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "otpauth://totp/TestApp:2fa@example.com?") || !strings.Contains(body, secret) {
+		t.Error("expected the provisioning URI and secret")
+	}
+
+	// Reloading keeps the same secret
+	w = httptest.NewRecorder()
+	handler.TwoFactorSetupPage(w, req)
+	if got := userStore.users["user-2fa"].TOTPSecret; got != secret {
+		t.Errorf("secret changed on reload: %s -> %s", secret, got)
+	}
+
+	// A wrong code doesn't enable it
+	w = postForm(handler.EnableTwoFactor, "/portal/settings/two-factor", url.Values{"code": {"000000"}}, portalUser)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("wrong code Status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+	if userStore.users["user-2fa"].TOTPEnabled {
+		t.Fatal("wrong code should not enable two-factor")
+	}
+
+	// The current code enables it and shows recovery codes once
+	code, _ := domainAuth.TOTPCode(secret, time.Now())
+	w = postForm(handler.EnableTwoFactor, "/portal/settings/two-factor", url.Values{"code": {code}}, portalUser)
+	if w.Code != http.StatusOK {
+		t.Fatalf("enable Status = %d, want %d", w.Code, http.StatusOK)
+	}
+	user := userStore.users["user-2fa"]
+	if !user.TOTPEnabled {
+		t.Fatal("expected two-factor to be enabled")
+	}
+	shown := regexp.MustCompile(`<code>([a-z2-7]{5}-[a-z2-7]{5})</code>`).FindAllStringSubmatch(w.Body.String(), -1)
+	if len(shown) != domainAuth.RecoveryCodeCount || len(user.RecoveryCodes) != domainAuth.RecoveryCodeCount {
+		t.Fatalf("shown %d recovery codes, stored %d; want %d", len(shown), len(user.RecoveryCodes), domainAuth.RecoveryCodeCount)
+	}
+	for i, m := range shown {
+		if user.RecoveryCodes[i] != domainAuth.HashRecoveryCode(m[1]) {
+			t.Errorf("recovery code %d is not stored hashed", i)
+		}
+	}
+}
+
+func TestPortalHandler_TwoFactorLogin_ValidCode(t *testing.T) {
+	handler, userStore, tokenStore := newTwoFactorHandler(t)
+	secret := domainAuth.GenerateTOTPSecret()
+	enableTwoFactor(userStore, secret)
+
+	token := startTwoFactorLogin(t, handler)
+
+	code, _ := domainAuth.TOTPCode(secret, time.Now())
+	w := postForm(handler.TwoFactorLoginSubmit, "/portal/login/two-factor", url.Values{"token": {token}, "code": {code}}, nil)
+
+	if w.Code != http.StatusFound {
+		t.Fatalf("Status = %d, want %d", w.Code, http.StatusFound)
+	}
+	if w.Header().Get("Location") != "/portal/dashboard" {
+		t.Errorf("Location = %s, want /portal/dashboard", w.Header().Get("Location"))
+	}
+	if !hasPortalCookie(w) {
+		t.Error("expected a session cookie")
+	}
+
+	// The pending login can't be reused
+	for _, tok := range tokenStore.tokens {
+		if tok.Type == domainAuth.TokenTypeTwoFactor && !tok.IsUsed() {
+			t.Error("pending login token should be used up")
+		}
+	}
+	w = postForm(handler.TwoFactorLoginSubmit, "/portal/login/two-factor", url.Values{"token": {token}, "code": {code}}, nil)
+	if w.Code != http.StatusUnauthorized || hasPortalCookie(w) {
+		t.Errorf("reused token Status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestPortalHandler_TwoFactorLogin_InvalidCode(t *testing.T) {
+	handler, userStore, _ := newTwoFactorHandler(t)
+	enableTwoFactor(userStore, domainAuth.GenerateTOTPSecret(), "aaaaa-bbbbb")
+
+	token := startTwoFactorLogin(t, handler)
+
+	for _, code := range []string{"000000", "", "ccccc-ddddd"} {
+		w := postForm(handler.TwoFactorLoginSubmit, "/portal/login/two-factor", url.Values{"token": {token}, "code": {code}}, nil)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("code %q Status = %d, want %d", code, w.Code, http.StatusUnauthorized)
+		}
+		if hasPortalCookie(w) {
+			t.Errorf("code %q should not log in", code)
+		}
+		if !strings.Contains(w.Body.String(), "Invalid authentication code") || !strings.Contains(w.Body.String(), token) {
+			t.Errorf("code %q should re-prompt for the code", code)
+		}
+	}
+
+	// Without a valid pending login, even a correct code is refused
+	w := postForm(handler.TwoFactorLoginSubmit, "/portal/login/two-factor", url.Values{"token": {"bogus"}, "code": {"aaaaa-bbbbb"}}, nil)
+	if w.Code != http.StatusUnauthorized || hasPortalCookie(w) {
+		t.Errorf("bogus token Status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestPortalHandler_TwoFactorLogin_TooManyAttempts(t *testing.T) {
+	handler, userStore, _ := newTwoFactorHandler(t)
+	secret := domainAuth.GenerateTOTPSecret()
+	enableTwoFactor(userStore, secret)
+
+	token := startTwoFactorLogin(t, handler)
+	for i := 1; i < maxPendingLoginFailures; i++ {
+		w := postForm(handler.TwoFactorLoginSubmit, "/portal/login/two-factor", url.Values{"token": {token}, "code": {"000000"}}, nil)
+		if !strings.Contains(w.Body.String(), token) {
+			t.Fatalf("attempt %d should re-prompt with the pending login", i)
+		}
+	}
+
+	// The last allowed wrong code discards the pending login
+	w := postForm(handler.TwoFactorLoginSubmit, "/portal/login/two-factor", url.Values{"token": {token}, "code": {"000000"}}, nil)
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "Please log in again") {
+		t.Fatalf("Status = %d, want %d asking to log in again", w.Code, http.StatusUnauthorized)
+	}
+	code, _ := domainAuth.TOTPCode(secret, time.Now())
+	w = postForm(handler.TwoFactorLoginSubmit, "/portal/login/two-factor", url.Values{"token": {token}, "code": {code}}, nil)
+	if w.Code != http.StatusUnauthorized || hasPortalCookie(w) {
+		t.Errorf("discarded login Status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestPortalHandler_TwoFactorLogin_Lockout(t *testing.T) {
+	handler, userStore, _ := newTwoFactorHandler(t)
+	secret := domainAuth.GenerateTOTPSecret()
+	enableTwoFactor(userStore, secret)
+
+	login := func(code string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/portal/api/login", strings.NewReader(`{"email":"2fa@example.com","password":"Password123","code":"`+code+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler.APILogin(w, req)
+		return w
+	}
+
+	// Logging in again doesn't reset the count of wrong codes
+	for i := 0; i < maxTwoFactorFailures; i++ {
+		if w := login("000000"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("wrong code %d: Status = %d, want %d", i, w.Code, http.StatusUnauthorized)
+		}
+	}
+	code, _ := domainAuth.TOTPCode(secret, time.Now())
+	w := login(code)
+	if w.Code != http.StatusTooManyRequests || hasPortalCookie(w) {
+		t.Errorf("locked out: Status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+
+	// The form login is locked too
+	token := startTwoFactorLogin(t, handler)
+	w = postForm(handler.TwoFactorLoginSubmit, "/portal/login/two-factor", url.Values{"token": {token}, "code": {code}}, nil)
+	if w.Code != http.StatusTooManyRequests || hasPortalCookie(w) {
+		t.Errorf("locked out form login: Status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+}
+
+func TestPortalHandler_TwoFactorLogin_Replay(t *testing.T) {
+	handler, userStore, _ := newTwoFactorHandler(t)
+	secret := domainAuth.GenerateTOTPSecret()
+	enableTwoFactor(userStore, secret)
+	code, _ := domainAuth.TOTPCode(secret, time.Now())
+
+	token := startTwoFactorLogin(t, handler)
+	w := postForm(handler.TwoFactorLoginSubmit, "/portal/login/two-factor", url.Values{"token": {token}, "code": {code}}, nil)
+	if w.Code != http.StatusFound {
+		t.Fatalf("first use Status = %d, want %d", w.Code, http.StatusFound)
+	}
+
+	// The same code can't log in again within its validity window
+	token = startTwoFactorLogin(t, handler)
+	w = postForm(handler.TwoFactorLoginSubmit, "/portal/login/two-factor", url.Values{"token": {token}, "code": {code}}, nil)
+	if w.Code != http.StatusUnauthorized || hasPortalCookie(w) {
+		t.Errorf("replayed code Status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestPortalHandler_TwoFactorLogin_ConcurrentReplay(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		code func(secret string) string
+	}{
+		{"totp", func(secret string) string { code, _ := domainAuth.TOTPCode(secret, time.Now()); return code }},
+		{"recovery code", func(string) string { return "aaaaa-bbbbb" }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			handler, userStore, _ := newTwoFactorHandler(t)
+			secret := domainAuth.GenerateTOTPSecret()
+			enableTwoFactor(userStore, secret, "aaaaa-bbbbb")
+			code := tc.code(secret)
+			first, second := startTwoFactorLogin(t, handler), startTwoFactorLogin(t, handler)
+
+			// The second login checks and saves the same code after the
+			// first has checked it but before the first saves it
+			var secondW *httptest.ResponseRecorder
+			handler.users = &interleavingUserStore{
+				mockUserStore: userStore,
+				beforeUpdate: func() {
+					secondW = postForm(handler.TwoFactorLoginSubmit, "/portal/login/two-factor", url.Values{"token": {second}, "code": {code}}, nil)
+				},
+			}
+			firstW := postForm(handler.TwoFactorLoginSubmit, "/portal/login/two-factor", url.Values{"token": {first}, "code": {code}}, nil)
+
+			if secondW == nil || secondW.Code != http.StatusFound || !hasPortalCookie(secondW) {
+				t.Fatalf("second login did not succeed: %v", secondW)
+			}
+			if firstW.Code != http.StatusUnauthorized || hasPortalCookie(firstW) {
+				t.Errorf("first login Status = %d, want %d: the code was already used", firstW.Code, http.StatusUnauthorized)
+			}
+		})
+	}
+}
+
+func TestPortalHandler_TwoFactorLogin_RecoveryCode(t *testing.T) {
+	handler, userStore, _ := newTwoFactorHandler(t)
+	enableTwoFactor(userStore, domainAuth.GenerateTOTPSecret(), "aaaaa-bbbbb", "ccccc-ddddd")
+
+	token := startTwoFactorLogin(t, handler)
+	w := postForm(handler.TwoFactorLoginSubmit, "/portal/login/two-factor", url.Values{"token": {token}, "code": {"AAAAA-BBBBB"}}, nil)
+
+	if w.Code != http.StatusFound || !hasPortalCookie(w) {
+		t.Fatalf("Status = %d, want %d with a session", w.Code, http.StatusFound)
+	}
+
+	// The recovery code is consumed
+	remaining := userStore.users["user-2fa"].RecoveryCodes
+	if len(remaining) != 1 || remaining[0] != domainAuth.HashRecoveryCode("ccccc-ddddd") {
+		t.Errorf("remaining recovery codes = %v, want only the unused one", remaining)
+	}
+
+	token = startTwoFactorLogin(t, handler)
+	w = postForm(handler.TwoFactorLoginSubmit, "/portal/login/two-factor", url.Values{"token": {token}, "code": {"aaaaa-bbbbb"}}, nil)
+	if w.Code != http.StatusUnauthorized || hasPortalCookie(w) {
+		t.Errorf("reused recovery code Status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestPortalHandler_APILogin_TwoFactor(t *testing.T) {
+	handler, userStore, _ := newTwoFactorHandler(t)
+	secret := domainAuth.GenerateTOTPSecret()
+	enableTwoFactor(userStore, secret, "aaaaa-bbbbb")
+
+	login := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/portal/api/login", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler.APILogin(w, req)
+		return w
+	}
+
+	w := login(`{"email":"2fa@example.com","password":"Password123"}`)
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "two_factor_required") {
+		t.Errorf("no code: Status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	w = login(`{"email":"2fa@example.com","password":"Password123","code":"000000"}`)
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "invalid_two_factor_code") {
+		t.Errorf("wrong code: Status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	code, _ := domainAuth.TOTPCode(secret, time.Now())
+	w = login(`{"email":"2fa@example.com","password":"Password123","code":"` + code + `"}`)
+	if w.Code != http.StatusOK || !hasPortalCookie(w) {
+		t.Errorf("valid code: Status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	w = login(`{"email":"2fa@example.com","password":"Password123","code":"aaaaa-bbbbb"}`)
+	if w.Code != http.StatusOK {
+		t.Errorf("recovery code: Status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if len(userStore.users["user-2fa"].RecoveryCodes) != 0 {
+		t.Error("recovery code should be consumed")
+	}
+}
+
+func TestPortalHandler_DisableTwoFactor(t *testing.T) {
+	handler, userStore, _ := newTwoFactorHandler(t)
+	enableTwoFactor(userStore, domainAuth.GenerateTOTPSecret(), "aaaaa-bbbbb")
+	portalUser := &PortalUser{ID: "user-2fa", Email: "2fa@example.com", TwoFactorEnabled: true}
+
+	w := postForm(handler.DisableTwoFactor, "/portal/settings/two-factor/disable", url.Values{"password": {"wrong"}}, portalUser)
+	if w.Code != http.StatusUnauthorized || !userStore.users["user-2fa"].TOTPEnabled {
+		t.Fatalf("wrong password Status = %d, want %d and 2FA kept", w.Code, http.StatusUnauthorized)
+	}
+
+	w = postForm(handler.DisableTwoFactor, "/portal/settings/two-factor/disable", url.Values{"password": {"Password123"}}, portalUser)
+	if w.Code != http.StatusFound {
+		t.Fatalf("Status = %d, want %d", w.Code, http.StatusFound)
+	}
+	user := userStore.users["user-2fa"]
+	if user.TOTPEnabled || user.TOTPSecret != "" || len(user.RecoveryCodes) != 0 {
+		t.Errorf("2FA after disable = %v/%q/%v, want cleared", user.TOTPEnabled, user.TOTPSecret, user.RecoveryCodes)
+	}
+
+	// Password alone logs in again
+	w = postForm(handler.PortalLoginSubmit, "/portal/login", url.Values{"email": {"2fa@example.com"}, "password": {"Password123"}}, nil)
+	if w.Code != http.StatusFound || !hasPortalCookie(w) {
+		t.Errorf("login Status = %d, want %d with a session", w.Code, http.StatusFound)
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Session Duration Tests
+// -----------------------------------------------------------------------------
+
+func newSessionHandler(t *testing.T) (*PortalHandler, *mockSessionStore) {
+	t.Helper()
+	userStore := newMockUserStore()
+	userStore.users["user1"] = ports.User{
+		ID:           "user1",
+		Email:        "user@example.com",
+		PasswordHash: []byte("hashed_Password123"),
+		Status:       "active",
+	}
+	sessionStore := newMockSessionStore()
+	handler, err := NewPortalHandler(PortalDeps{
+		Users:         userStore,
+		Keys:          &mockKeyStore{},
+		Usage:         &mockUsageStore{},
+		AuthTokens:    newMockTokenStore(),
+		Sessions:      sessionStore,
+		Plans:         newMockPlanStore(),
+		Logger:        zerolog.Nop(),
+		Hasher:        &mockHasher{},
+		IDGen:         &mockIDGen{},
+		JWTSecret:     "test-secret",
+		AppName:       "TestApp",
+		SessionTTL:    2 * time.Hour,
+		RememberMeTTL: 14 * 24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewPortalHandler: %v", err)
+	}
+	return handler, sessionStore
+}
+
+func portalCookie(t *testing.T, w *httptest.ResponseRecorder) *http.Cookie {
+	t.Helper()
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "portal_token" && c.Value != "" {
+			return c
+		}
+	}
+	t.Fatal("portal_token cookie not set")
+	return nil
+}
+
+func TestPortalHandler_Login_SessionExpiry(t *testing.T) {
+	tests := []struct {
+		name       string
+		rememberMe string
+		wantTTL    time.Duration
+		wantMaxAge int
+	}{
+		{"browser session", "", 2 * time.Hour, 0},
+		{"remember me", "1", 14 * 24 * time.Hour, int((14 * 24 * time.Hour).Seconds())},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, sessionStore := newSessionHandler(t)
+
+			w := postForm(handler.PortalLoginSubmit, "/portal/login", url.Values{
+				"email":       {"user@example.com"},
+				"password":    {"Password123"},
+				"remember_me": {tt.rememberMe},
+			}, nil)
+			if w.Code != http.StatusFound {
+				t.Fatalf("Status = %d, want %d", w.Code, http.StatusFound)
+			}
+
+			cookie := portalCookie(t, w)
+			if cookie.MaxAge != tt.wantMaxAge {
+				t.Errorf("cookie MaxAge = %d, want %d", cookie.MaxAge, tt.wantMaxAge)
+			}
+
+			claims, err := handler.tokens.ValidateToken(cookie.Value)
+			if err != nil {
+				t.Fatalf("ValidateToken: %v", err)
+			}
+			session, ok := sessionStore.sessions[claims.SessionID]
+			if !ok {
+				t.Fatal("login should store a session")
+			}
+			if session.UserID != "user1" {
+				t.Errorf("session UserID = %s, want user1", session.UserID)
+			}
+			if got := time.Until(session.ExpiresAt); got < tt.wantTTL-time.Minute || got > tt.wantTTL {
+				t.Errorf("session expires in %v, want %v", got, tt.wantTTL)
+			}
+			if got := time.Until(claims.ExpiresAt.Time); got < tt.wantTTL-time.Minute || got > tt.wantTTL {
+				t.Errorf("token expires in %v, want %v", got, tt.wantTTL)
+			}
+		})
+	}
+}
+
+func TestPortalHandler_APILogin_RememberMe(t *testing.T) {
+	handler, sessionStore := newSessionHandler(t)
+
+	req := httptest.NewRequest("POST", "/portal/api/login", strings.NewReader(`{"email":"user@example.com","password":"Password123","remember_me":true}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handler.APILogin(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if cookie := portalCookie(t, w); cookie.MaxAge != int((14 * 24 * time.Hour).Seconds()) {
+		t.Errorf("cookie MaxAge = %d, want the remember me TTL", cookie.MaxAge)
+	}
+	if len(sessionStore.sessions) != 1 {
+		t.Errorf("sessions = %d, want 1", len(sessionStore.sessions))
+	}
+}
+
+func TestPortalHandler_RevokedSessionInvalidatesCookie(t *testing.T) {
+	handler, sessionStore := newSessionHandler(t)
+
+	w := postForm(handler.PortalLoginSubmit, "/portal/login", url.Values{
+		"email":    {"user@example.com"},
+		"password": {"Password123"},
+	}, nil)
+	cookie := portalCookie(t, w)
+
+	protected := handler.PortalAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	get := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/portal/dashboard", nil)
+		req.AddCookie(&http.Cookie{Name: "portal_token", Value: cookie.Value})
+		w := httptest.NewRecorder()
+		protected.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get(); w.Code != http.StatusOK {
+		t.Fatalf("active session Status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	// Revoking the session rejects the still-unexpired token
+	for id := range sessionStore.sessions {
+		sessionStore.Delete(context.Background(), id)
+	}
+	w = get()
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/portal/login" {
+		t.Errorf("revoked session Status = %d, Location = %s, want redirect to login", w.Code, w.Header().Get("Location"))
+	}
+	if hasPortalCookie(w) {
+		t.Error("revoked session cookie should be cleared")
+	}
+}
+
+func TestPortalHandler_PortalLogout_RevokesSession(t *testing.T) {
+	handler, sessionStore := newSessionHandler(t)
+
+	w := postForm(handler.PortalLoginSubmit, "/portal/login", url.Values{
+		"email":    {"user@example.com"},
+		"password": {"Password123"},
+	}, nil)
+	cookie := portalCookie(t, w)
+	if len(sessionStore.sessions) != 1 {
+		t.Fatalf("sessions = %d, want 1", len(sessionStore.sessions))
+	}
+
+	req := httptest.NewRequest("POST", "/portal/logout", nil)
+	req.AddCookie(&http.Cookie{Name: "portal_token", Value: cookie.Value})
+	handler.PortalLogout(httptest.NewRecorder(), req)
+
+	if len(sessionStore.sessions) != 0 {
+		t.Errorf("sessions = %d, want 0 after logout", len(sessionStore.sessions))
+	}
+}
+
+func TestPortalHandler_TwoFactorLogin_RememberMe(t *testing.T) {
+	handler, userStore, _ := newTwoFactorHandler(t)
+	secret := domainAuth.GenerateTOTPSecret()
+	enableTwoFactor(userStore, secret)
+
+	w := postForm(handler.PortalLoginSubmit, "/portal/login", url.Values{
+		"email":       {"2fa@example.com"},
+		"password":    {"Password123"},
+		"remember_me": {"1"},
+	}, nil)
+	body := w.Body.String()
+	if !strings.Contains(body, `name="remember_me" value="1"`) {
+		t.Fatal("two-factor form should carry remember me")
+	}
+	token := regexp.MustCompile(`name="token" value="([0-9a-f]+)"`).FindStringSubmatch(body)[1]
+
+	code, _ := domainAuth.TOTPCode(secret, time.Now())
+	w = postForm(handler.TwoFactorLoginSubmit, "/portal/login/two-factor", url.Values{
+		"token":       {token},
+		"code":        {code},
+		"remember_me": {"1"},
+	}, nil)
+	if cookie := portalCookie(t, w); cookie.MaxAge != int(domainAuth.DefaultRememberMeTTL.Seconds()) {
+		t.Errorf("cookie MaxAge = %d, want the remember me TTL", cookie.MaxAge)
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Active Sessions Tests
+// -----------------------------------------------------------------------------
+
+// loginSession logs user1 in through the router and returns the session
+// cookie.
+func loginSession(t *testing.T, router http.Handler, userAgent string) *http.Cookie {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/portal/login", strings.NewReader(url.Values{
+		"email":    {"user@example.com"},
+		"password": {"Password123"},
+	}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", userAgent)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return portalCookie(t, w)
+}
+
+func sessionIDOf(t *testing.T, handler *PortalHandler, cookie *http.Cookie) string {
+	t.Helper()
+	claims, err := handler.tokens.ValidateToken(cookie.Value)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	return claims.SessionID
+}
+
+func serveWithCookie(router http.Handler, method, path string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func newSessionsRouter(t *testing.T) (*PortalHandler, *mockSessionStore, http.Handler) {
+	t.Helper()
+	handler, sessionStore := newSessionHandler(t)
+	router := chi.NewRouter()
+	router.Mount("/portal", handler.Router())
+	return handler, sessionStore, router
+}
+
+func TestPortalHandler_SessionsPage_ListsCurrentSession(t *testing.T) {
+	handler, _, router := newSessionsRouter(t)
+	laptop := loginSession(t, router, "Laptop Browser")
+	loginSession(t, router, "Phone Browser")
+
+	w := serveWithCookie(router, "GET", "/portal/settings/sessions", laptop)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	body := w.Body.String()
+	if !strings.Contains(body, "Laptop Browser (this session)") {
+		t.Error("page should mark the current session")
+	}
+	if !strings.Contains(body, "Phone Browser") {
+		t.Error("page should list the other session")
+	}
+	if !strings.Contains(body, "192.0.2.1") {
+		t.Error("page should show the session IP address")
+	}
+	if strings.Contains(body, "/sessions/"+sessionIDOf(t, handler, laptop)+"/revoke") {
+		t.Error("current session should not have a log out button")
+	}
+}
+
+func TestPortalHandler_RevokeSession(t *testing.T) {
+	handler, sessionStore, router := newSessionsRouter(t)
+	laptop := loginSession(t, router, "Laptop Browser")
+	phone := loginSession(t, router, "Phone Browser")
+	phoneID := sessionIDOf(t, handler, phone)
+
+	w := serveWithCookie(router, "POST", "/portal/settings/sessions/"+phoneID+"/revoke", laptop)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/portal/settings/sessions?revoked=one" {
+		t.Fatalf("Status = %d, Location = %s", w.Code, w.Header().Get("Location"))
+	}
+	if _, ok := sessionStore.sessions[phoneID]; ok {
+		t.Error("revoked session should be deleted")
+	}
+
+	// The revoked device is logged out; this one isn't
+	if w := serveWithCookie(router, "GET", "/portal/settings/sessions", phone); w.Code != http.StatusFound {
+		t.Errorf("revoked cookie Status = %d, want redirect to login", w.Code)
+	}
+	w = serveWithCookie(router, "GET", "/portal/settings/sessions", laptop)
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "Phone Browser") {
+		t.Errorf("revoked session should no longer be listed")
+	}
+
+	// Revoking the current session logs out here
+	w = serveWithCookie(router, "POST", "/portal/settings/sessions/"+sessionIDOf(t, handler, laptop)+"/revoke", laptop)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/portal/login" {
+		t.Errorf("current session Status = %d, Location = %s", w.Code, w.Header().Get("Location"))
+	}
+	if len(sessionStore.sessions) != 0 {
+		t.Errorf("sessions = %d, want 0", len(sessionStore.sessions))
+	}
+}
+
+func TestPortalHandler_RevokeSession_OtherUser(t *testing.T) {
+	_, sessionStore, router := newSessionsRouter(t)
+	cookie := loginSession(t, router, "Laptop Browser")
+
+	other := domainAuth.GenerateSession("user2", "other@example.com", "", "", time.Hour)
+	sessionStore.sessions[other.ID] = other
+
+	w := serveWithCookie(router, "POST", "/portal/settings/sessions/"+other.ID+"/revoke", cookie)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if _, ok := sessionStore.sessions[other.ID]; !ok {
+		t.Error("another user's session must not be revoked")
+	}
+}
+
+func TestPortalHandler_RevokeOtherSessions(t *testing.T) {
+	handler, sessionStore, router := newSessionsRouter(t)
+	laptop := loginSession(t, router, "Laptop Browser")
+	loginSession(t, router, "Phone Browser")
+	loginSession(t, router, "Tablet Browser")
+
+	w := serveWithCookie(router, "POST", "/portal/settings/sessions/revoke-others", laptop)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/portal/settings/sessions?revoked=others" {
+		t.Fatalf("Status = %d, Location = %s", w.Code, w.Header().Get("Location"))
+	}
+
+	if len(sessionStore.sessions) != 1 {
+		t.Fatalf("sessions = %d, want only the current one", len(sessionStore.sessions))
+	}
+	if _, ok := sessionStore.sessions[sessionIDOf(t, handler, laptop)]; !ok {
+		t.Error("current session should survive")
+	}
+	if w := serveWithCookie(router, "GET", "/portal/settings/sessions", laptop); w.Code != http.StatusOK {
+		t.Errorf("current session Status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
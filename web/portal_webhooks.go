@@ -381,7 +381,7 @@ func (h *PortalHandler) renderWebhookFormPage(user *PortalUser, wh webhook.Webho
 		deliveryRows := ""
 		for _, d := range deliveries {
 			statusClass := "status-active"
-			if d.Status == webhook.DeliveryFailed {
+			if d.Status == webhook.DeliveryFailed || d.Status == webhook.DeliveryDeadLetter {
 				statusClass = "status-revoked"
 			} else if d.Status == webhook.DeliveryRetrying {
 				statusClass = "status-pending"
@@ -0,0 +1,233 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// codeSnippet is an example request in one language.
+type codeSnippet struct {
+	Lang  string // Tab key, e.g. "curl"
+	Label string // Tab label, e.g. "cURL"
+	Code  string
+}
+
+// requestSnippets returns ready-to-run snippets that call method path on
+// baseURL with the X-API-Key header, in cURL, JavaScript (fetch), Python
+// (requests) and Go (net/http). body is a JSON request body, or "" for none.
+func requestSnippets(baseURL, method, path, body string) []codeSnippet {
+	url := baseURL + path
+	return []codeSnippet{
+		{Lang: "curl", Label: "cURL", Code: curlSnippet(url, method, body)},
+		{Lang: "javascript", Label: "JavaScript", Code: fetchSnippet(url, method, body)},
+		{Lang: "python", Label: "Python", Code: pythonSnippet(url, method, body)},
+		{Lang: "go", Label: "Go", Code: goSnippet(url, method, body)},
+	}
+}
+
+func curlSnippet(url, method, body string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "curl -X %s \"%s\" \\\n  -H \"X-API-Key: your_api_key\"", method, url)
+	if body != "" {
+		b.WriteString(" \\\n  -H \"Content-Type: application/json\"")
+		fmt.Fprintf(&b, " \\\n  -d '%s'", strings.ReplaceAll(body, "'", `'\''`))
+	}
+	return b.String()
+}
+
+func fetchSnippet(url, method, body string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "const response = await fetch('%s', {\n  method: '%s',\n  headers: {\n    'X-API-Key': 'your_api_key'", url, method)
+	if body != "" {
+		b.WriteString(",\n    'Content-Type': 'application/json'\n  },\n")
+		fmt.Fprintf(&b, "  body: JSON.stringify(%s)\n", indentLines(body, "  "))
+	} else {
+		b.WriteString("\n  }\n")
+	}
+	b.WriteString("});\n\nif (!response.ok) {\n  throw new Error('HTTP error! status: ' + response.status);\n}\nconst data = await response.json();\nconsole.log(data);")
+	return b.String()
+}
+
+func pythonSnippet(url, method, body string) string {
+	var b strings.Builder
+	b.WriteString("import requests\n\n")
+	args := fmt.Sprintf("    '%s',\n    headers={'X-API-Key': 'your_api_key'},\n", url)
+	if body != "" {
+		fmt.Fprintf(&b, "payload = %s\n\n", pythonLiteral(body))
+		args += "    json=payload,\n"
+	}
+	fmt.Fprintf(&b, "response = requests.request(\n    '%s',\n%s)\nresponse.raise_for_status()\nprint(response.json())", method, args)
+	return b.String()
+}
+
+func goSnippet(url, method, body string) string {
+	var b strings.Builder
+	b.WriteString("package main\n\nimport (\n    \"fmt\"\n    \"io\"\n    \"net/http\"\n")
+	reqBody := "nil"
+	if body != "" {
+		b.WriteString("    \"strings\"\n")
+		reqBody = "strings.NewReader(" + goStringLiteral(body) + ")"
+	}
+	b.WriteString(")\n\nfunc main() {\n")
+	fmt.Fprintf(&b, "    req, err := http.NewRequest(\"%s\", \"%s\", %s)\n", method, url, reqBody)
+	b.WriteString("    if err != nil {\n        panic(err)\n    }\n")
+	b.WriteString("    req.Header.Set(\"X-API-Key\", \"your_api_key\")\n")
+	if body != "" {
+		b.WriteString("    req.Header.Set(\"Content-Type\", \"application/json\")\n")
+	}
+	b.WriteString("\n    resp, err := http.DefaultClient.Do(req)\n    if err != nil {\n        panic(err)\n    }\n    defer resp.Body.Close()\n\n")
+	b.WriteString("    data, _ := io.ReadAll(resp.Body)\n    fmt.Println(resp.Status, string(data))\n}")
+	return b.String()
+}
+
+// goStringLiteral quotes s as a raw string literal when it can.
+func goStringLiteral(s string) string {
+	if strings.Contains(s, "`") {
+		return strconv.Quote(s)
+	}
+	return "`" + s + "`"
+}
+
+// pythonLiteral converts a JSON document to an equivalent Python literal, so
+// true, false and null become True, False and None. Bodies that are not
+// valid JSON are passed as a string.
+func pythonLiteral(body string) string {
+	var v any
+	if err := json.Unmarshal([]byte(body), &v); err != nil {
+		return strconv.Quote(body)
+	}
+	var b strings.Builder
+	writePython(&b, v, "")
+	return b.String()
+}
+
+func writePython(b *strings.Builder, v any, indent string) {
+	inner := indent + "    "
+	switch val := v.(type) {
+	case nil:
+		b.WriteString("None")
+	case bool:
+		if val {
+			b.WriteString("True")
+		} else {
+			b.WriteString("False")
+		}
+	case float64:
+		b.WriteString(strconv.FormatFloat(val, 'f', -1, 64))
+	case string:
+		b.WriteString(strconv.Quote(val))
+	case []any:
+		if len(val) == 0 {
+			b.WriteString("[]")
+			return
+		}
+		b.WriteString("[\n")
+		for _, item := range val {
+			b.WriteString(inner)
+			writePython(b, item, inner)
+			b.WriteString(",\n")
+		}
+		b.WriteString(indent + "]")
+	case map[string]any:
+		if len(val) == 0 {
+			b.WriteString("{}")
+			return
+		}
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b.WriteString("{\n")
+		for _, k := range keys {
+			fmt.Fprintf(b, "%s%s: ", inner, strconv.Quote(k))
+			writePython(b, val[k], inner)
+			b.WriteString(",\n")
+		}
+		b.WriteString(indent + "}")
+	}
+}
+
+// indentLines indents every line of s after the first.
+func indentLines(s, indent string) string {
+	return strings.ReplaceAll(s, "\n", "\n"+indent)
+}
+
+// snippetsJS mirrors requestSnippets in the browser so the Try It console can
+// regenerate its snippets as the request is edited. Keep the two in sync.
+const snippetsJS = `
+const snippetGenerators = {
+    curl(url, method, body, key) {
+        let s = 'curl -X ' + method + ' "' + url + '" \\\n  -H "X-API-Key: ' + key + '"';
+        if (body) {
+            s += ' \\\n  -H "Content-Type: application/json"';
+            s += " \\\n  -d '" + body.replace(/'/g, "'\\''") + "'";
+        }
+        return s;
+    },
+    javascript(url, method, body, key) {
+        let s = "const response = await fetch('" + url + "', {\n  method: '" + method + "',\n  headers: {\n    'X-API-Key': '" + key + "'";
+        if (body) {
+            s += ",\n    'Content-Type': 'application/json'\n  },\n";
+            s += '  body: JSON.stringify(' + body.replace(/\n/g, '\n  ') + ')\n';
+        } else {
+            s += '\n  }\n';
+        }
+        return s + "});\n\nif (!response.ok) {\n  throw new Error('HTTP error! status: ' + response.status);\n}\nconst data = await response.json();\nconsole.log(data);";
+    },
+    python(url, method, body, key) {
+        let s = 'import requests\n\n';
+        let args = "    '" + url + "',\n    headers={'X-API-Key': '" + key + "'},\n";
+        if (body) {
+            s += 'payload = ' + pythonLiteral(body) + '\n\n';
+            args += '    json=payload,\n';
+        }
+        return s + "response = requests.request(\n    '" + method + "',\n" + args + ")\nresponse.raise_for_status()\nprint(response.json())";
+    },
+    go(url, method, body, key) {
+        let s = 'package main\n\nimport (\n    "fmt"\n    "io"\n    "net/http"\n';
+        let reqBody = 'nil';
+        if (body) {
+            s += '    "strings"\n';
+            reqBody = 'strings.NewReader(' + (body.includes(String.fromCharCode(96)) ? JSON.stringify(body) : String.fromCharCode(96) + body + String.fromCharCode(96)) + ')';
+        }
+        s += ')\n\nfunc main() {\n';
+        s += '    req, err := http.NewRequest("' + method + '", "' + url + '", ' + reqBody + ')\n';
+        s += '    if err != nil {\n        panic(err)\n    }\n';
+        s += '    req.Header.Set("X-API-Key", "' + key + '")\n';
+        if (body) {
+            s += '    req.Header.Set("Content-Type", "application/json")\n';
+        }
+        s += '\n    resp, err := http.DefaultClient.Do(req)\n    if err != nil {\n        panic(err)\n    }\n    defer resp.Body.Close()\n\n';
+        return s + '    data, _ := io.ReadAll(resp.Body)\n    fmt.Println(resp.Status, string(data))\n}';
+    },
+};
+
+function pythonLiteral(body) {
+    let value;
+    try {
+        value = JSON.parse(body);
+    } catch (e) {
+        return JSON.stringify(body);
+    }
+    const write = (v, indent) => {
+        const inner = indent + '    ';
+        if (v === null) return 'None';
+        if (v === true) return 'True';
+        if (v === false) return 'False';
+        if (typeof v === 'number') return String(v);
+        if (typeof v === 'string') return JSON.stringify(v);
+        if (Array.isArray(v)) {
+            if (v.length === 0) return '[]';
+            return '[\n' + v.map(item => inner + write(item, inner) + ',\n').join('') + indent + ']';
+        }
+        const keys = Object.keys(v).sort();
+        if (keys.length === 0) return '{}';
+        return '{\n' + keys.map(k => inner + JSON.stringify(k) + ': ' + write(v[k], inner) + ',\n').join('') + indent + '}';
+    };
+    return write(value, '');
+}
+`
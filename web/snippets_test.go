@@ -0,0 +1,71 @@
+package web
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRequestSnippets(t *testing.T) {
+	snippets := requestSnippets("https://api.example.com", "PATCH", "/orders/42", "{\n  \"status\": \"shipped\"\n}")
+
+	want := map[string][]string{
+		"curl": {
+			`curl -X PATCH "https://api.example.com/orders/42"`,
+			`-d '{`,
+		},
+		"javascript": {
+			`fetch('https://api.example.com/orders/42', {`,
+			`method: 'PATCH'`,
+			`body: JSON.stringify({`,
+		},
+		"python": {
+			`'PATCH',`,
+			`'https://api.example.com/orders/42',`,
+			`payload = {
+    "status": "shipped",
+}`,
+			`json=payload`,
+		},
+		"go": {
+			`http.NewRequest("PATCH", "https://api.example.com/orders/42", strings.NewReader(` + "`{",
+			`req.Header.Set("Content-Type", "application/json")`,
+		},
+	}
+
+	if len(snippets) != len(want) {
+		t.Fatalf("got %d snippets, want %d", len(snippets), len(want))
+	}
+	for _, s := range snippets {
+		for _, w := range want[s.Lang] {
+			if !strings.Contains(s.Code, w) {
+				t.Errorf("%s snippet missing %q:\n%s", s.Lang, w, s.Code)
+			}
+		}
+	}
+}
+
+func TestRequestSnippets_NoBody(t *testing.T) {
+	for _, s := range requestSnippets("https://api.example.com", "GET", "/orders", "") {
+		if strings.Contains(s.Code, "Content-Type") {
+			t.Errorf("%s snippet should not send a body:\n%s", s.Lang, s.Code)
+		}
+	}
+}
+
+func TestPythonLiteral(t *testing.T) {
+	got := pythonLiteral(`{"b": [true, null], "a": 1.5, "c": {}}`)
+	want := `{
+    "a": 1.5,
+    "b": [
+        True,
+        None,
+    ],
+    "c": {},
+}`
+	if got != want {
+		t.Errorf("pythonLiteral =\n%s\nwant\n%s", got, want)
+	}
+	if got := pythonLiteral("not json"); got != `"not json"` {
+		t.Errorf("pythonLiteral(invalid) = %s", got)
+	}
+}
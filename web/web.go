@@ -81,6 +81,7 @@ type Handler struct {
 	appSettings         AppSettings
 	logger              zerolog.Logger
 	hasher              ports.Hasher
+	audit               ports.AuditRecorder
 	isSetup             func() bool                        // Returns true if initial setup is complete
 	onPlanChange        func(ctx context.Context) error    // Callback for plan changes (reloads proxy)
 	onRouteChange       func(ctx context.Context) error    // Callback for route changes (reloads routes)
@@ -116,6 +117,7 @@ type Deps struct {
 	AppSettings         AppSettings
 	Logger              zerolog.Logger
 	Hasher              ports.Hasher
+	Audit               ports.AuditRecorder // Optional: records admin mutations
 	JWTSecret           string
 	IsSetup             func() bool
 	OnPlanChange        func(ctx context.Context) error // Callback when plans are created/updated
@@ -160,6 +162,7 @@ func NewHandler(deps Deps) (*Handler, error) {
 		appSettings:         deps.AppSettings,
 		logger:              deps.Logger,
 		hasher:              deps.Hasher,
+		audit:               deps.Audit,
 		isSetup:             deps.IsSetup,
 		onPlanChange:        deps.OnPlanChange,
 		onRouteChange:       deps.OnRouteChange,